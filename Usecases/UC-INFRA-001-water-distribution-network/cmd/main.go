@@ -1,28 +1,54 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/aosanya/CodeValdCortex/internal/app"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/sirupsen/logrus"
+)
 
 // UC-INFRA-001: Water Distribution Network
 //
 // This use case runs the CodeValdCortex framework with use case-specific configuration.
-// The framework automatically loads agent types from config/agents/*.json
+// The framework loads roles, agents, agencies, workflows, templates and topics from
+// the use case directory at startup and logs a summary report (see internal/usecase).
 
 func main() {
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println("  UC-INFRA-001: Water Distribution Network")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println()
-	fmt.Println("This use case must be run through the framework.")
-	fmt.Println()
-	fmt.Println("Use the start script instead:")
-	fmt.Println("  ./start.sh")
-	fmt.Println()
-	fmt.Println("Or run manually:")
-	fmt.Println("  cd /workspaces/CodeValdCortex")
-	fmt.Println("  export USECASE_CONFIG_DIR=$(pwd)/usecases/UC-INFRA-001-water-distribution-network")
-	fmt.Println("  export $(cat usecases/UC-INFRA-001-water-distribution-network/.env | xargs)")
-	fmt.Println("  ./bin/codevaldcortex")
-	fmt.Println()
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fmt.Println()
+	var (
+		configPath = flag.String("config", "config.yaml", "Path to framework configuration file")
+		useCaseDir = flag.String("usecase-dir", "Usecases/UC-INFRA-001-water-distribution-network", "Path to the use case directory")
+	)
+	flag.Parse()
+
+	dir, err := filepath.Abs(*useCaseDir)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid use case directory")
+	}
+	if err := os.Setenv("USECASE_CONFIG_DIR", dir); err != nil {
+		logrus.WithError(err).Fatal("Failed to set use case directory")
+	}
+
+	// Load configuration
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to load configuration")
+	}
+
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logrus.WithError(err).Warn("Invalid log level, using info")
+		level = logrus.InfoLevel
+	}
+	logrus.SetLevel(level)
+
+	logrus.WithField("usecase_dir", dir).Info("Starting UC-INFRA-001: Water Distribution Network")
+
+	// Initialize and start application
+	application := app.New(cfg)
+	if err := application.Run(); err != nil {
+		logrus.WithError(err).Fatal("Application failed to start")
+	}
 }
@@ -19,8 +19,6 @@ const (
 	// Agent IDs for the scenario
 	sensorID = "SENSOR-001"
 	pipeID   = "PIPE-001"
-	valve1ID = "VALVE-001"
-	valve2ID = "VALVE-002"
 	coordID  = "COORD-NORTH"
 
 	// Topics for pub/sub communication
@@ -28,6 +26,23 @@ const (
 	leakConfirmedTopic = "zone.north.leak.confirmed"
 )
 
+// Fallback isolation valves used when the framework cannot propose an isolation
+var fallbackIsolationValves = []string{"VALVE-001", "VALVE-002"}
+
+// isolationValves are the valves closed to isolate the leaking pipe
+var isolationValves = fallbackIsolationValves
+
+// isolationImpact is the impact of the chosen isolation, if known
+var isolationImpact *IsolationImpact
+
+// IsolationImpact is the part of the framework's isolation impact analysis used here
+type IsolationImpact struct {
+	ClosedValves            []string `json:"closed_valves"`
+	PipesWithoutSupply      []string `json:"pipes_without_supply"`
+	AffectedCustomers       float64  `json:"affected_customers"`
+	AffectedDemandM3PerHour float64  `json:"affected_demand_m3_per_hour"`
+}
+
 // Message represents a message to be sent between agents
 type Message struct {
 	FromAgentID   string                 `json:"from_agent_id"`
@@ -136,7 +151,7 @@ func simulateSensorAnomaly() {
 		FromAgentID: sensorID,
 		ToAgentID:   pipeID,
 		MessageType: "PRESSURE_ANOMALY_ALERT",
-		Priority:    1,
+		Priority:    9,
 		Payload: map[string]interface{}{
 			"sensor_id":         sensorID,
 			"pressure_drop":     1.5,
@@ -154,99 +169,105 @@ func simulateSensorAnomaly() {
 }
 
 func simulatePipeAnalysis() {
+	// Choose the isolation that cuts off the least demand
+	chooseIsolationValves()
+
+	payload := map[string]interface{}{
+		"pipe_id":            pipeID,
+		"analysis_result":    "LEAK_CONFIRMED",
+		"leak_probability":   85,
+		"estimated_loss_lpm": 50,
+		"location":           "North Main Pipeline, Section A",
+		"severity":           "MODERATE",
+		"isolation_required": true,
+		"isolation_valves":   isolationValves,
+		"timestamp":          time.Now().Format(time.RFC3339),
+	}
+	if isolationImpact != nil {
+		payload["pipes_without_supply"] = isolationImpact.PipesWithoutSupply
+		payload["affected_customers"] = isolationImpact.AffectedCustomers
+		payload["affected_demand_m3_per_hour"] = isolationImpact.AffectedDemandM3PerHour
+	}
+
 	// Pipe agent analyzes and confirms leak
 	pubsubMsg := PubSubMessage{
 		PublisherAgentID:   pipeID,
 		PublisherAgentType: "pipe",
 		EventName:          leakConfirmedTopic,
 		PublicationType:    "event",
-		Payload: map[string]interface{}{
-			"pipe_id":            pipeID,
-			"analysis_result":    "LEAK_CONFIRMED",
-			"leak_probability":   85,
-			"estimated_loss_lpm": 50,
-			"location":           "North Main Pipeline, Section A",
-			"severity":           "MODERATE",
-			"isolation_required": true,
-			"upstream_valve":     valve1ID,
-			"downstream_valve":   valve2ID,
-			"timestamp":          time.Now().Format(time.RFC3339),
-		},
+		Payload:            payload,
 	}
 
 	publishMessage(pubsubMsg)
 
 	// Send isolation commands to valves
-	valve1Cmd := Message{
-		FromAgentID: pipeID,
-		ToAgentID:   valve1ID,
-		MessageType: "ISOLATION_COMMAND",
-		Priority:    1,
-		Payload: map[string]interface{}{
-			"command":       "CLOSE",
-			"reason":        "LEAK_ISOLATION",
-			"urgency":       "HIGH",
-			"leak_location": "downstream",
-		},
+	for _, valveID := range isolationValves {
+		sendDirectMessage(Message{
+			FromAgentID: pipeID,
+			ToAgentID:   valveID,
+			MessageType: "ISOLATION_COMMAND",
+			Priority:    10,
+			Payload: map[string]interface{}{
+				"command": "CLOSE",
+				"reason":  "LEAK_ISOLATION",
+				"urgency": "HIGH",
+			},
+		})
 	}
 
-	valve2Cmd := Message{
-		FromAgentID: pipeID,
-		ToAgentID:   valve2ID,
-		MessageType: "ISOLATION_COMMAND",
-		Priority:    1,
-		Payload: map[string]interface{}{
-			"command":       "CLOSE",
-			"reason":        "LEAK_ISOLATION",
-			"urgency":       "HIGH",
-			"leak_location": "upstream",
-		},
-	}
-
-	sendDirectMessage(valve1Cmd)
-	sendDirectMessage(valve2Cmd)
-
 	fmt.Printf("   🔧 %s: Leak analysis complete\n", pipeID)
 	fmt.Printf("   📊 Result: 85%% leak probability, estimated 50 L/min loss\n")
 	fmt.Printf("   📤 Published confirmation to topic: %s\n", leakConfirmedTopic)
-	fmt.Printf("   🚰 Sent CLOSE commands to %s and %s\n", valve1ID, valve2ID)
+	fmt.Printf("   🚰 Sent CLOSE commands to %v\n", isolationValves)
 }
 
-func simulateValveIsolation() {
-	// Simulate valve responses
-	valve1Response := Message{
-		FromAgentID: valve1ID,
-		ToAgentID:   pipeID,
-		MessageType: "COMMAND_RESPONSE",
-		Priority:    1,
-		Payload: map[string]interface{}{
-			"command_executed": "CLOSE",
-			"status":           "SUCCESS",
-			"position":         "CLOSED",
-			"isolation_time":   time.Now().Format(time.RFC3339),
-			"flow_stopped":     true,
-		},
+// chooseIsolationValves asks the framework for the isolation sets of the
+// leaking pipe and picks the first, which has the least affected demand
+func chooseIsolationValves() {
+	resp, err := http.Get(baseURL + "/topology/pipes/" + pipeID + "/isolations")
+	if err != nil {
+		log.Printf("Error requesting isolation sets: %v", err)
+		return
 	}
+	defer resp.Body.Close()
 
-	valve2Response := Message{
-		FromAgentID: valve2ID,
-		ToAgentID:   pipeID,
-		MessageType: "COMMAND_RESPONSE",
-		Priority:    1,
-		Payload: map[string]interface{}{
-			"command_executed": "CLOSE",
-			"status":           "SUCCESS",
-			"position":         "CLOSED",
-			"isolation_time":   time.Now().Format(time.RFC3339),
-			"flow_stopped":     true,
-		},
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Printf("No isolation proposed (status %d): %s; using %v", resp.StatusCode, body, fallbackIsolationValves)
+		return
 	}
 
-	sendDirectMessage(valve1Response)
-	sendDirectMessage(valve2Response)
+	var isolations []IsolationImpact
+	if err := json.NewDecoder(resp.Body).Decode(&isolations); err != nil || len(isolations) == 0 {
+		log.Printf("No isolation proposed; using %v", fallbackIsolationValves)
+		return
+	}
+
+	isolationImpact = &isolations[0]
+	isolationValves = isolationImpact.ClosedValves
+	fmt.Printf("   🗺️  Minimal-impact isolation: %v (%.0f customers, %.1f m³/h affected)\n",
+		isolationValves, isolationImpact.AffectedCustomers, isolationImpact.AffectedDemandM3PerHour)
+}
+
+func simulateValveIsolation() {
+	// Simulate valve responses
+	for _, valveID := range isolationValves {
+		sendDirectMessage(Message{
+			FromAgentID: valveID,
+			ToAgentID:   pipeID,
+			MessageType: "COMMAND_RESPONSE",
+			Priority:    9,
+			Payload: map[string]interface{}{
+				"command_executed": "CLOSE",
+				"status":           "SUCCESS",
+				"position":         "CLOSED",
+				"isolation_time":   time.Now().Format(time.RFC3339),
+				"flow_stopped":     true,
+			},
+		})
+		fmt.Printf("   🚰 %s: Valve closed successfully\n", valveID)
+	}
 
-	fmt.Printf("   🚰 %s: Valve closed successfully\n", valve1ID)
-	fmt.Printf("   🚰 %s: Valve closed successfully\n", valve2ID)
 	fmt.Printf("   ✅ Pipe section isolated - leak contained\n")
 }
 
@@ -256,13 +277,13 @@ func simulateZoneCoordinatorEscalation() {
 		FromAgentID: coordID,
 		ToAgentID:   "CONTROL-ROOM",
 		MessageType: "INCIDENT_ESCALATION",
-		Priority:    1,
+		Priority:    9,
 		Payload: map[string]interface{}{
 			"incident_type":        "WATER_LEAK",
 			"severity":             "MODERATE",
 			"location":             "North Main Pipeline, Section A",
 			"affected_pipes":       []string{pipeID},
-			"isolated_valves":      []string{valve1ID, valve2ID},
+			"isolated_valves":      isolationValves,
 			"estimated_loss":       "50 L/min",
 			"maintenance_required": true,
 			"repair_priority":      "HIGH",
@@ -284,7 +305,7 @@ func simulateZoneCoordinatorEscalation() {
 			"incident_id":     fmt.Sprintf("LEAK-%d", time.Now().Unix()),
 			"status":          "CONTAINED",
 			"response_time":   "2 minutes",
-			"agents_involved": []string{sensorID, pipeID, valve1ID, valve2ID, coordID},
+			"agents_involved": append(append([]string{sensorID, pipeID}, isolationValves...), coordID),
 			"summary":         "Leak detected and isolated successfully via multi-agent coordination",
 		},
 	}
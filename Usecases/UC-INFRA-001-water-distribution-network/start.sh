@@ -41,7 +41,7 @@ export USECASE_CONFIG_DIR="$USECASE_DIR"
 
 echo ""
 echo "Starting server..."
-echo "  - Agent types will be loaded from: $USECASE_DIR/config/agents/"
+echo "  - Use case configuration will be loaded from: $USECASE_DIR"
 echo "  - Database: ${CVXC_DATABASE_DATABASE:-codevaldcortex}"
 echo "  - Server: http://${CVXC_SERVER_HOST:-0.0.0.0}:${CVXC_SERVER_PORT:-8080}"
 echo ""
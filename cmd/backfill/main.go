@@ -0,0 +1,161 @@
+// Command backfill starts a backfill of historical pub/sub traffic on a
+// CodeValdCortex instance and waits for its reconciliation report.
+//
+//	backfill -target http://prod:8080 -pipelines derived_metrics -since 2025-01-01T00:00:00Z -until 2025-02-01T00:00:00Z
+//	backfill -target http://prod:8080 -pipelines usage -archive incident.json -dry-run
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/backfill"
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	target := flag.String("target", "http://localhost:8080", "Base URL of the instance to backfill")
+	pipelines := flag.String("pipelines", "", "Comma-separated pipelines to run (see /api/v1/backfill/pipelines)")
+	topics := flag.String("topics", "", "Comma-separated event patterns of stored publications (default: all)")
+	since := flag.String("since", "", "RFC3339 start of stored publications")
+	until := flag.String("until", "", "RFC3339 end of stored publications (default: now)")
+	archive := flag.String("archive", "", "Capture file in the instance's archive directory, instead of stored publications")
+	dryRun := flag.Bool("dry-run", false, "Report what would be applied without applying it")
+	poll := flag.Duration("poll", 2*time.Second, "How often to check the run")
+	flag.Parse()
+
+	req, err := buildRequest(*pipelines, *topics, *since, *until, *archive, *dryRun)
+	if err != nil {
+		logrus.WithError(err).Fatal("Invalid flags")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, strings.TrimRight(*target, "/"), req, *poll); err != nil {
+		logrus.WithError(err).Fatal("backfill failed")
+	}
+}
+
+// buildRequest turns the flags into a backfill request
+func buildRequest(pipelines, topics, since, until, archive string, dryRun bool) (backfill.Request, error) {
+	req := backfill.Request{
+		Source:    backfill.SourceStored,
+		Pipelines: splitList(pipelines),
+		Topics:    splitList(topics),
+		DryRun:    dryRun,
+	}
+	if len(req.Pipelines) == 0 {
+		return req, fmt.Errorf("-pipelines is required")
+	}
+
+	if archive != "" {
+		req.Source = backfill.SourceArchive
+		req.Archive = archive
+		return req, nil
+	}
+
+	if since == "" {
+		return req, fmt.Errorf("-since or -archive is required")
+	}
+	var err error
+	if req.Since, err = time.Parse(time.RFC3339, since); err != nil {
+		return req, fmt.Errorf("invalid -since: %w", err)
+	}
+	if until != "" {
+		if req.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return req, fmt.Errorf("invalid -until: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// run starts the backfill and prints its report once the job finishes
+func run(ctx context.Context, target string, req backfill.Request, poll time.Duration) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var job jobs.Job
+	if err := call(ctx, http.MethodPost, target+"/api/v1/backfill", body, http.StatusAccepted, &job); err != nil {
+		return err
+	}
+	logrus.WithField("job_id", job.ID).Info("Backfill queued")
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.WithField("job_id", job.ID).Warn("Stopped waiting; the backfill keeps running")
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := call(ctx, http.MethodGet, target+"/api/v1/jobs/"+job.ID, nil, http.StatusOK, &job); err != nil {
+			return err
+		}
+
+		switch job.Status {
+		case jobs.StatusSucceeded:
+			report, _ := json.MarshalIndent(job.Result, "", "  ")
+			fmt.Println(string(report))
+			return nil
+		case jobs.StatusDead, jobs.StatusCanceled:
+			return fmt.Errorf("backfill %s is %s: %s", job.ID, job.Status, job.LastError)
+		case jobs.StatusQueued:
+			if job.LastError != "" {
+				logrus.WithField("attempts", job.Attempts).Warn("Backfill attempt failed, retrying: " + job.LastError)
+			}
+		}
+	}
+}
+
+// call sends a request to the instance and decodes the response into out
+func call(ctx context.Context, method, url string, body []byte, wantStatus int, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("%s %s returned status %d: %s", method, url, resp.StatusCode, data)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// splitList parses a comma-separated list
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/app"
 	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/scenario"
+	"github.com/aosanya/CodeValdCortex/pkg/client"
 	"github.com/sirupsen/logrus"
 )
 
@@ -17,6 +24,10 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "scenario" {
+		os.Exit(runScenarioCommand(os.Args[2:]))
+	}
+
 	var (
 		configPath  = flag.String("config", "config.yaml", "Path to configuration file")
 		showVersion = flag.Bool("version", false, "Show version information")
@@ -61,3 +72,129 @@ func main() {
 		logrus.WithError(err).Fatal("Application failed to start")
 	}
 }
+
+// varFlags collects repeated -var name=value flags
+type varFlags map[string]string
+
+func (v varFlags) String() string { return "" }
+
+func (v varFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	v[name] = val
+	return nil
+}
+
+// runScenarioCommand runs "codevaldcortex scenario run file.yaml" against a
+// running instance and returns the exit code: 1 if a step failed
+func runScenarioCommand(args []string) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprintln(os.Stderr, "Usage: codevaldcortex scenario run [flags] <file.yaml|file.json>")
+		return 2
+	}
+
+	vars := varFlags{}
+	fs := flag.NewFlagSet("scenario run", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "Base URL of the running instance")
+	apiKey := fs.String("api-key", "", "API key sent as a bearer token")
+	keepGoing := fs.Bool("keep-going", false, "Run the remaining steps after a step fails")
+	reportPath := fs.String("report", "", "Write the run report as JSON to this file")
+	junitPath := fs.String("junit", "", "Write the run report as JUnit XML to this file")
+	fs.Var(vars, "var", "Override a scenario variable, name=value (repeatable)")
+	fs.Parse(args[1:])
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: codevaldcortex scenario run [flags] <file.yaml|file.json>")
+		return 2
+	}
+
+	s, err := scenario.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	api, err := client.New(client.Config{BaseURL: *server, APIKey: *apiKey, UserAgent: "codevaldcortex-scenario"})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Running scenario %s (%d steps) against %s\n", s.Name, len(s.Steps), *server)
+	readyCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := api.WaitReady(readyCtx, time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "Framework not ready: %v\n", err)
+		return 1
+	}
+
+	runner := scenario.NewRunner(api, scenario.RunnerConfig{
+		Vars:      vars,
+		KeepGoing: *keepGoing,
+		OnStep: func(result scenario.StepResult) {
+			mark := "PASS"
+			if !result.Passed {
+				mark = "FAIL"
+			}
+			fmt.Printf("  [%s] %d. %s (%s, %s)", mark, result.Index, result.Name, result.Action, result.Duration.Round(time.Millisecond))
+			switch {
+			case result.Error != "":
+				fmt.Printf(": %s", result.Error)
+			case result.Detail != "":
+				fmt.Printf(": %s", result.Detail)
+			}
+			fmt.Println()
+			for _, a := range result.Assertions {
+				mark, outcome := "ok", a.Detail
+				if !a.Passed {
+					mark, outcome = "FAILED", a.Error
+				}
+				fmt.Printf("      %s %s: %s\n", mark, a.Description, outcome)
+			}
+		},
+	})
+	report, err := runner.Run(ctx, s)
+	if err != nil && report == nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *reportPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*reportPath, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+		}
+	}
+	if *junitPath != "" {
+		if err := writeJUnit(*junitPath, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write JUnit report: %v\n", err)
+		}
+	}
+
+	if err != nil || !report.Passed {
+		if len(report.Skipped) > 0 {
+			fmt.Printf("  %d steps skipped after the failure\n", len(report.Skipped))
+		}
+		fmt.Printf("Scenario %s FAILED (run %s)\n", s.Name, report.RunID)
+		return 1
+	}
+	fmt.Printf("Scenario %s passed in %s (run %s)\n", s.Name, report.Duration.Round(time.Millisecond), report.RunID)
+	return 0
+}
+
+// writeJUnit writes a scenario report as JUnit XML
+func writeJUnit(path string, report *scenario.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJUnit(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,185 @@
+// Command traffic-replay captures pub/sub traffic from one CodeValdCortex
+// instance and replays it into another.
+//
+//	traffic-replay capture -source http://prod:8080 -topics 'alert.*,reading.*' -since 2025-01-01T10:00:00Z -until 2025-01-01T11:00:00Z -out incident.json
+//	traffic-replay replay -target http://staging:8080 -in incident.json -speed 10 -id-map PUMP-001=STG-PUMP-001
+//
+// Instances that require API keys are called with -api-key, or with the key in
+// the CVXC_API_KEY environment variable. Capturing needs the read scope and
+// replaying the communications scope.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+)
+
+// apiKeyEnv is the environment variable holding the default -api-key
+const apiKeyEnv = "CVXC_API_KEY"
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "capture":
+		err = runCapture(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logrus.WithError(err).Fatal("traffic-replay failed")
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: traffic-replay <capture|replay> [flags]")
+	fmt.Fprintln(os.Stderr, "Run 'traffic-replay <command> -h' for command flags.")
+}
+
+// runCapture downloads a capture file from the source instance
+func runCapture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+	source := fs.String("source", "http://localhost:8080", "Base URL of the instance to capture from")
+	topics := fs.String("topics", "", "Comma-separated event patterns to capture")
+	since := fs.String("since", "", "RFC3339 window start")
+	until := fs.String("until", "", "RFC3339 window end (default: now)")
+	out := fs.String("out", "capture.json", "Output file")
+	apiKey := fs.String("api-key", os.Getenv(apiKeyEnv), "API key of the source instance")
+	fs.Parse(args)
+
+	if *topics == "" || *since == "" {
+		return fmt.Errorf("-topics and -since are required")
+	}
+
+	query := url.Values{}
+	query.Set("topics", *topics)
+	query.Set("since", *since)
+	if *until != "" {
+		query.Set("until", *until)
+	}
+
+	endpoint := strings.TrimRight(*source, "/") + "/api/v1/communications/capture?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create capture request: %w", err)
+	}
+	if *apiKey != "" {
+		req.Header.Set("X-API-Key", *apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request capture: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read capture: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("capture failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var capture communication.TrafficCapture
+	if err := json.Unmarshal(body, &capture); err != nil {
+		return fmt.Errorf("invalid capture response: %w", err)
+	}
+
+	if err := os.WriteFile(*out, body, 0o644); err != nil {
+		return fmt.Errorf("failed to write capture file: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"publications": len(capture.Publications),
+		"file":         *out,
+	}).Info("Capture saved")
+	return nil
+}
+
+// runReplay republishes a capture file into the target instance
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := fs.String("target", "http://localhost:8080", "Base URL of the instance to replay into")
+	in := fs.String("in", "capture.json", "Capture file to replay")
+	speed := fs.Float64("speed", 1, "Replay speed multiplier (0 replays without delays)")
+	idMap := fs.String("id-map", "", "Comma-separated agent ID remappings, e.g. OLD-1=NEW-1,OLD-2=NEW-2")
+	apiKey := fs.String("api-key", os.Getenv(apiKeyEnv), "API key of the target instance")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("failed to read capture file: %w", err)
+	}
+
+	var capture communication.TrafficCapture
+	if err := json.Unmarshal(data, &capture); err != nil {
+		return fmt.Errorf("invalid capture file: %w", err)
+	}
+
+	mapping, err := parseIDMap(*idMap)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logrus.WithFields(logrus.Fields{
+		"publications": len(capture.Publications),
+		"target":       *target,
+		"speed":        *speed,
+	}).Info("Replaying capture")
+
+	report, err := communication.ReplayTraffic(ctx, communication.NewHTTPTrafficPublisher(*target, *apiKey), &capture, communication.ReplayOptions{
+		Speed: *speed,
+		IDMap: mapping,
+	})
+	if report != nil {
+		for _, e := range report.Errors {
+			logrus.Warn(e)
+		}
+		logrus.WithFields(logrus.Fields{
+			"published": report.Published,
+			"failed":    report.Failed,
+			"duration":  report.Duration.Round(time.Millisecond),
+		}).Info("Replay finished")
+	}
+	return err
+}
+
+// parseIDMap parses "a=b,c=d" into a map
+func parseIDMap(raw string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if raw == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid -id-map entry: %q", pair)
+		}
+		mapping[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return mapping, nil
+}
@@ -177,6 +177,11 @@ func buildListQuery(filters agency.AgencyFilters) (string, map[string]interface{
 		bindVars["tags"] = filters.Tags
 	}
 
+	if filters.TenantID != "" {
+		conditions = append(conditions, "agency.tenant_id == @tenant_id")
+		bindVars["tenant_id"] = filters.TenantID
+	}
+
 	// Add filter conditions
 	if len(conditions) > 0 {
 		query += " FILTER " + strings.Join(conditions, " AND ")
@@ -0,0 +1,127 @@
+package arangodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/arangodb/go-driver"
+)
+
+// CreateExplanation stores an AI explanation in the agency's explanations collection
+func (r *Repository) CreateExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	agencyDB, err := r.agencyDatabase(ctx, explanation.AgencyID)
+	if err != nil {
+		return err
+	}
+
+	// Ensure explanations collection exists
+	explanationsColl, err := ensureExplanationsCollection(ctx, agencyDB)
+	if err != nil {
+		return fmt.Errorf("failed to ensure explanations collection: %w", err)
+	}
+
+	meta, err := explanationsColl.CreateDocument(ctx, explanation)
+	if err != nil {
+		return fmt.Errorf("failed to create explanation: %w", err)
+	}
+
+	explanation.Key = meta.Key
+	return nil
+}
+
+// GetExplanations retrieves the explanations attached to an entity, newest first
+func (r *Repository) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	agencyDB, err := r.agencyDatabase(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure explanations collection exists
+	explanationsColl, err := ensureExplanationsCollection(ctx, agencyDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure explanations collection: %w", err)
+	}
+
+	query := `
+		FOR e IN @@collection
+			FILTER e.agency_id == @agencyId AND e.entity_type == @entityType AND e.entity_key == @entityKey
+			SORT e.created_at DESC
+			RETURN e
+	`
+	bindVars := map[string]interface{}{
+		"@collection": explanationsColl.Name(),
+		"agencyId":    agencyID,
+		"entityType":  string(entityType),
+		"entityKey":   entityKey,
+	}
+
+	cursor, err := agencyDB.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query explanations: %w", err)
+	}
+	defer cursor.Close()
+
+	var explanations []*agency.Explanation
+	for cursor.HasMore() {
+		var explanation agency.Explanation
+		_, err := cursor.ReadDocument(ctx, &explanation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read explanation: %w", err)
+		}
+		explanations = append(explanations, &explanation)
+	}
+
+	return explanations, nil
+}
+
+// agencyDatabase opens the agency-specific database
+func (r *Repository) agencyDatabase(ctx context.Context, agencyID string) (driver.Database, error) {
+	agencyDoc, err := r.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agency: %w", err)
+	}
+
+	// Use agency ID as database name if not set
+	dbName := agencyDoc.Database
+	if dbName == "" {
+		dbName = agencyDoc.ID
+	}
+
+	agencyDB, err := r.client.Database(ctx, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agency database: %w", err)
+	}
+
+	return agencyDB, nil
+}
+
+// ensureExplanationsCollection ensures the explanations collection exists with an entity index
+func ensureExplanationsCollection(ctx context.Context, db driver.Database) (driver.Collection, error) {
+	const collectionName = "explanations"
+
+	exists, err := db.CollectionExists(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var collection driver.Collection
+	if !exists {
+		collection, err = db.CreateCollection(ctx, collectionName, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+
+		_, _, err = collection.EnsurePersistentIndex(ctx, []string{"entity_type", "entity_key"}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create entity index: %w", err)
+		}
+	} else {
+		collection, err = db.Collection(ctx, collectionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get collection: %w", err)
+		}
+	}
+
+	return collection, nil
+}
@@ -0,0 +1,170 @@
+package arangodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/arangodb/go-driver"
+)
+
+// goalConsolidationsCollectionName is the collection holding goal consolidation records
+const goalConsolidationsCollectionName = "goal_consolidations"
+
+// CreateGoalConsolidation removes the consolidation's Before goals, creates
+// its After goals and stores the record in one transaction
+func (r *Repository) CreateGoalConsolidation(ctx context.Context, consolidation *agency.GoalConsolidation) error {
+	agencyDB, err := r.agencyDatabase(ctx, consolidation.AgencyID)
+	if err != nil {
+		return err
+	}
+	goalsColl, err := ensureGoalsCollection(ctx, agencyDB)
+	if err != nil {
+		return fmt.Errorf("failed to ensure goals collection: %w", err)
+	}
+	consolidationsColl, err := ensureGoalConsolidationsCollection(ctx, agencyDB)
+	if err != nil {
+		return fmt.Errorf("failed to ensure goal consolidations collection: %w", err)
+	}
+
+	collections := []string{goalsColl.Name(), consolidationsColl.Name()}
+	return withTransaction(ctx, agencyDB, collections, func(ctx context.Context) (string, error) {
+		if err := replaceGoals(ctx, agencyDB, goalsColl, consolidation.AgencyID, consolidation.Before, consolidation.After); err != nil {
+			return "", err
+		}
+
+		meta, err := consolidationsColl.CreateDocument(ctx, consolidation)
+		if err != nil {
+			return "", fmt.Errorf("failed to store goal consolidation: %w", err)
+		}
+		consolidation.Key = meta.Key
+		return consolidation.Key, nil
+	})
+}
+
+// GetGoalConsolidation retrieves a goal consolidation record
+func (r *Repository) GetGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	agencyDB, err := r.agencyDatabase(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+	consolidationsColl, err := ensureGoalConsolidationsCollection(ctx, agencyDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure goal consolidations collection: %w", err)
+	}
+
+	var consolidation agency.GoalConsolidation
+	if _, err := consolidationsColl.ReadDocument(ctx, key, &consolidation); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", agency.ErrGoalConsolidationNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to read goal consolidation: %w", err)
+	}
+	if consolidation.AgencyID != agencyID {
+		return nil, fmt.Errorf("%w: %s", agency.ErrGoalConsolidationNotFound, key)
+	}
+	return &consolidation, nil
+}
+
+// UndoGoalConsolidation removes the consolidation's After goals, restores
+// its Before goals under their original keys and saves the record in one
+// transaction
+func (r *Repository) UndoGoalConsolidation(ctx context.Context, consolidation *agency.GoalConsolidation) error {
+	agencyDB, err := r.agencyDatabase(ctx, consolidation.AgencyID)
+	if err != nil {
+		return err
+	}
+	goalsColl, err := ensureGoalsCollection(ctx, agencyDB)
+	if err != nil {
+		return fmt.Errorf("failed to ensure goals collection: %w", err)
+	}
+	consolidationsColl, err := ensureGoalConsolidationsCollection(ctx, agencyDB)
+	if err != nil {
+		return fmt.Errorf("failed to ensure goal consolidations collection: %w", err)
+	}
+
+	collections := []string{goalsColl.Name(), consolidationsColl.Name()}
+	return withTransaction(ctx, agencyDB, collections, func(ctx context.Context) (string, error) {
+		if err := replaceGoals(ctx, agencyDB, goalsColl, consolidation.AgencyID, consolidation.After, consolidation.Before); err != nil {
+			return "", err
+		}
+
+		if _, err := consolidationsColl.ReplaceDocument(ctx, consolidation.Key, consolidation); err != nil {
+			return "", fmt.Errorf("failed to save goal consolidation: %w", err)
+		}
+		return consolidation.Key, nil
+	})
+}
+
+// replaceGoals removes one set of goals and creates another, keeping goal
+// numbers consecutive. Goals already removed are skipped. Created goals keep
+// their key when it is set and are numbered after the remaining goals.
+func replaceGoals(ctx context.Context, db driver.Database, goalsColl driver.Collection, agencyID string, remove, create []*agency.Goal) error {
+	for _, goal := range remove {
+		if _, err := goalsColl.RemoveDocument(ctx, goal.Key); err != nil && !driver.IsNotFound(err) {
+			return fmt.Errorf("failed to remove goal %s: %w", goal.Code, err)
+		}
+	}
+
+	query := `
+		LET remaining = (
+			FOR p IN @@collection
+			FILTER p.agency_id == @agencyId
+			SORT p.number ASC
+			RETURN p._key
+		)
+		LET renumbered = (
+			FOR key IN remaining
+			UPDATE key WITH { number: POSITION(remaining, key, true) + 1 } IN @@collection
+		)
+		RETURN LENGTH(remaining)
+	`
+	cursor, err := db.Query(ctx, query, map[string]interface{}{
+		"@collection": goalsColl.Name(),
+		"agencyId":    agencyID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renumber goals: %w", err)
+	}
+	defer cursor.Close()
+
+	var count int
+	if cursor.HasMore() {
+		if _, err := cursor.ReadDocument(ctx, &count); err != nil {
+			return fmt.Errorf("failed to read goal count: %w", err)
+		}
+	}
+
+	for i, goal := range create {
+		goal.ID = ""
+		goal.Number = count + i + 1
+		meta, err := goalsColl.CreateDocument(ctx, goal)
+		if err != nil {
+			return fmt.Errorf("failed to create goal %s: %w", goal.Code, err)
+		}
+		goal.Key = meta.Key
+	}
+	return nil
+}
+
+// ensureGoalConsolidationsCollection ensures the goal consolidations collection exists
+func ensureGoalConsolidationsCollection(ctx context.Context, db driver.Database) (driver.Collection, error) {
+	exists, err := db.CollectionExists(ctx, goalConsolidationsCollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	if exists {
+		collection, err := db.Collection(ctx, goalConsolidationsCollectionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get collection: %w", err)
+		}
+		return collection, nil
+	}
+
+	collection, err := db.CreateCollection(ctx, goalConsolidationsCollectionName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return collection, nil
+}
@@ -40,34 +40,37 @@ func (r *Repository) CreateGoal(ctx context.Context, goal *agency.Goal) error {
 	goal.CreatedAt = now
 	goal.UpdatedAt = now
 
-	// If number is not set, get the next number
-	if goal.Number == 0 {
-		// Count existing goals
-		query := "FOR p IN goals FILTER p.agency_id == @agencyId COLLECT WITH COUNT INTO length RETURN length"
-		cursor, err := agencyDB.Query(ctx, query, map[string]interface{}{"agencyId": goal.AgencyID})
-		if err != nil {
-			return fmt.Errorf("failed to count goals: %w", err)
-		}
-		defer cursor.Close()
-
-		var count int
-		if cursor.HasMore() {
-			_, err := cursor.ReadDocument(ctx, &count)
+	// Number and create the goal together with any outbox entries
+	return withOutbox(ctx, agencyDB, []string{goalsColl.Name()}, func(ctx context.Context) (string, error) {
+		// If number is not set, get the next number
+		if goal.Number == 0 {
+			// Count existing goals
+			query := "FOR p IN goals FILTER p.agency_id == @agencyId COLLECT WITH COUNT INTO length RETURN length"
+			cursor, err := agencyDB.Query(ctx, query, map[string]interface{}{"agencyId": goal.AgencyID})
 			if err != nil {
-				return fmt.Errorf("failed to read count: %w", err)
+				return "", fmt.Errorf("failed to count goals: %w", err)
+			}
+			defer cursor.Close()
+
+			var count int
+			if cursor.HasMore() {
+				_, err := cursor.ReadDocument(ctx, &count)
+				if err != nil {
+					return "", fmt.Errorf("failed to read count: %w", err)
+				}
 			}
+			goal.Number = count + 1
 		}
-		goal.Number = count + 1
-	}
 
-	// Create the document
-	meta, err := goalsColl.CreateDocument(ctx, goal)
-	if err != nil {
-		return fmt.Errorf("failed to create goal: %w", err)
-	}
+		// Create the document
+		meta, err := goalsColl.CreateDocument(ctx, goal)
+		if err != nil {
+			return "", fmt.Errorf("failed to create goal: %w", err)
+		}
 
-	goal.Key = meta.Key
-	return nil
+		goal.Key = meta.Key
+		return goal.Key, nil
+	})
 }
 
 // GetGoals retrieves all goals for an agency
@@ -187,13 +190,13 @@ func (r *Repository) UpdateGoal(ctx context.Context, goal *agency.Goal) error {
 	// Update timestamp
 	goal.UpdatedAt = time.Now()
 
-	// Update the document
-	_, err = goalsColl.UpdateDocument(ctx, goal.Key, goal)
-	if err != nil {
-		return fmt.Errorf("failed to update goal: %w", err)
-	}
-
-	return nil
+	// Update the document together with any outbox entries
+	return withOutbox(ctx, agencyDB, []string{goalsColl.Name()}, func(ctx context.Context) (string, error) {
+		if _, err := goalsColl.UpdateDocument(ctx, goal.Key, goal); err != nil {
+			return "", fmt.Errorf("failed to update goal: %w", err)
+		}
+		return goal.Key, nil
+	})
 }
 
 // DeleteGoal deletes a goal and renumbers remaining goals
@@ -222,37 +225,40 @@ func (r *Repository) DeleteGoal(ctx context.Context, agencyID string, key string
 		return fmt.Errorf("failed to ensure goals collection: %w", err)
 	}
 
-	// Get the goal to find its number
-	var goalToDelete agency.Goal
-	_, err = goalsColl.ReadDocument(ctx, key, &goalToDelete)
-	if err != nil {
-		return fmt.Errorf("failed to read goal: %w", err)
-	}
+	// Delete and renumber together with any outbox entries
+	return withOutbox(ctx, agencyDB, []string{goalsColl.Name()}, func(ctx context.Context) (string, error) {
+		// Get the goal to find its number
+		var goalToDelete agency.Goal
+		_, err := goalsColl.ReadDocument(ctx, key, &goalToDelete)
+		if err != nil {
+			return "", fmt.Errorf("failed to read goal: %w", err)
+		}
 
-	// Delete the document
-	_, err = goalsColl.RemoveDocument(ctx, key)
-	if err != nil {
-		return fmt.Errorf("failed to delete goal: %w", err)
-	}
+		// Delete the document
+		_, err = goalsColl.RemoveDocument(ctx, key)
+		if err != nil {
+			return "", fmt.Errorf("failed to delete goal: %w", err)
+		}
 
-	// Renumber goals with higher numbers
-	query := `
-		FOR p IN @@collection 
-		FILTER p.agency_id == @agencyId AND p.number > @deletedNumber
-		UPDATE p WITH { number: p.number - 1 } IN @@collection
-	`
-	bindVars := map[string]interface{}{
-		"@collection":   goalsColl.Name(),
-		"agencyId":      agencyID,
-		"deletedNumber": goalToDelete.Number,
-	}
+		// Renumber goals with higher numbers
+		query := `
+			FOR p IN @@collection 
+			FILTER p.agency_id == @agencyId AND p.number > @deletedNumber
+			UPDATE p WITH { number: p.number - 1 } IN @@collection
+		`
+		bindVars := map[string]interface{}{
+			"@collection":   goalsColl.Name(),
+			"agencyId":      agencyID,
+			"deletedNumber": goalToDelete.Number,
+		}
 
-	_, err = agencyDB.Query(ctx, query, bindVars)
-	if err != nil {
-		return fmt.Errorf("failed to renumber goals: %w", err)
-	}
+		_, err = agencyDB.Query(ctx, query, bindVars)
+		if err != nil {
+			return "", fmt.Errorf("failed to renumber goals: %w", err)
+		}
 
-	return nil
+		return key, nil
+	})
 }
 
 // ensureGoalsCollection ensures the goals collection exists in an agency database
@@ -0,0 +1,309 @@
+package arangodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+	"github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// outboxCollectionName is the collection holding outbox entries in each agency database
+const outboxCollectionName = "outbox"
+
+// withOutbox runs a change and stores the outbox entries attached to ctx in
+// the same stream transaction, so the entries exist only if the change is
+// committed. The change returns the key of the document it created or
+// changed, which becomes the entries' subject. Without entries the change runs
+// directly.
+func withOutbox(ctx context.Context, db driver.Database, collections []string, change func(ctx context.Context) (string, error)) error {
+	if len(outbox.EntriesFrom(ctx)) == 0 {
+		_, err := change(ctx)
+		return err
+	}
+	return withTransaction(ctx, db, collections, change)
+}
+
+// withTransaction runs a change in a stream transaction over collections,
+// storing the outbox entries attached to ctx as withOutbox does
+func withTransaction(ctx context.Context, db driver.Database, collections []string, change func(ctx context.Context) (string, error)) error {
+	entries := outbox.EntriesFrom(ctx)
+
+	outboxColl, err := ensureOutboxCollection(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to ensure outbox collection: %w", err)
+	}
+
+	tid, err := db.BeginTransaction(ctx, driver.TransactionCollections{
+		Write: append(append([]string{}, collections...), outboxColl.Name()),
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	txCtx := driver.WithTransactionID(ctx, tid)
+
+	abort := func(err error) error {
+		if abortErr := db.AbortTransaction(ctx, tid, nil); abortErr != nil {
+			log.WithError(abortErr).Error("Failed to abort transaction")
+		}
+		return err
+	}
+
+	subjectKey, err := change(txCtx)
+	if err != nil {
+		return abort(err)
+	}
+	for _, entry := range entries {
+		if entry.SubjectKey == "" {
+			entry.SubjectKey = subjectKey
+		}
+		entry.Key = entry.ID
+		if _, err := outboxColl.CreateDocument(txCtx, entry); err != nil {
+			return abort(fmt.Errorf("failed to store outbox entry: %w", err))
+		}
+	}
+
+	if err := db.CommitTransaction(ctx, tid, nil); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ensureOutboxCollection ensures the outbox collection exists with a due index
+func ensureOutboxCollection(ctx context.Context, db driver.Database) (driver.Collection, error) {
+	exists, err := db.CollectionExists(ctx, outboxCollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	if exists {
+		collection, err := db.Collection(ctx, outboxCollectionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get collection: %w", err)
+		}
+		return collection, nil
+	}
+
+	collection, err := db.CreateCollection(ctx, outboxCollectionName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	_, _, err = collection.EnsurePersistentIndex(ctx, []string{"status", "next_attempt_at"}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create due index: %w", err)
+	}
+	return collection, nil
+}
+
+// OutboxStore implements outbox.Store over the outbox collections of the
+// agency databases
+type OutboxStore struct {
+	repo *Repository
+}
+
+// NewOutboxStore creates an outbox store for agencies kept by an ArangoDB
+// agency repository
+func NewOutboxStore(repo agency.Repository) (*OutboxStore, error) {
+	r, ok := repo.(*Repository)
+	if !ok {
+		return nil, fmt.Errorf("outbox store requires the ArangoDB agency repository")
+	}
+	return &OutboxStore{repo: r}, nil
+}
+
+// outboxCollection returns an agency's outbox collection
+func (s *OutboxStore) outboxCollection(ctx context.Context, agencyID string) (driver.Database, driver.Collection, error) {
+	agencyDB, err := s.repo.agencyDatabase(ctx, agencyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	coll, err := ensureOutboxCollection(ctx, agencyDB)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to ensure outbox collection: %w", err)
+	}
+	return agencyDB, coll, nil
+}
+
+// Enqueue stores entries, ignoring ones already stored
+func (s *OutboxStore) Enqueue(ctx context.Context, entries ...*outbox.Entry) error {
+	for _, entry := range entries {
+		_, coll, err := s.outboxCollection(ctx, entry.AgencyID)
+		if err != nil {
+			return err
+		}
+		entry.Key = entry.ID
+		if _, err := coll.CreateDocument(ctx, entry); err != nil && !driver.IsConflict(err) {
+			return fmt.Errorf("failed to store outbox entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Due returns pending entries whose next attempt is at or before now across
+// all agencies, oldest first
+func (s *OutboxStore) Due(ctx context.Context, now time.Time, limit int) ([]*outbox.Entry, error) {
+	query := `
+		FOR o IN @@collection
+			FILTER o.status == @status AND o.next_attempt_at <= @now
+			SORT o.created_at ASC
+			LIMIT @limit
+			RETURN o
+	`
+	bindVars := map[string]interface{}{
+		"@collection": outboxCollectionName,
+		"status":      outbox.StatusPending,
+		"now":         now.UTC(),
+		"limit":       limit,
+	}
+
+	var due []*outbox.Entry
+	err := s.eachOutbox(ctx, "", func(db driver.Database) error {
+		entries, err := readEntries(ctx, db, query, bindVars)
+		due = append(due, entries...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Update saves an entry's delivery state
+func (s *OutboxStore) Update(ctx context.Context, entry *outbox.Entry) error {
+	_, coll, err := s.outboxCollection(ctx, entry.AgencyID)
+	if err != nil {
+		return err
+	}
+	entry.Key = entry.ID
+	if _, err := coll.ReplaceDocument(ctx, entry.ID, entry); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", outbox.ErrEntryNotFound, entry.ID)
+		}
+		return fmt.Errorf("failed to update outbox entry: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an entry
+func (s *OutboxStore) Get(ctx context.Context, agencyID, id string) (*outbox.Entry, error) {
+	_, coll, err := s.outboxCollection(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+	var entry outbox.Entry
+	if _, err := coll.ReadDocument(ctx, id, &entry); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", outbox.ErrEntryNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read outbox entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// List returns entries matching the filter, newest first
+func (s *OutboxStore) List(ctx context.Context, filter outbox.Filter) ([]*outbox.Entry, error) {
+	query := `
+		FOR o IN @@collection
+			FILTER @status == "" OR o.status == @status
+			SORT o.created_at DESC
+			LIMIT @limit
+			RETURN o
+	`
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+	bindVars := map[string]interface{}{
+		"@collection": outboxCollectionName,
+		"status":      filter.Status,
+		"limit":       limit,
+	}
+
+	var listed []*outbox.Entry
+	err := s.eachOutbox(ctx, filter.AgencyID, func(db driver.Database) error {
+		entries, err := readEntries(ctx, db, query, bindVars)
+		listed = append(listed, entries...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].CreatedAt.After(listed[j].CreatedAt)
+	})
+	if len(listed) > limit {
+		listed = listed[:limit]
+	}
+	return listed, nil
+}
+
+// eachOutbox calls fn with the database of every agency (or only agencyID)
+// that has an outbox collection
+func (s *OutboxStore) eachOutbox(ctx context.Context, agencyID string, fn func(db driver.Database) error) error {
+	var agencyIDs []string
+	if agencyID != "" {
+		agencyIDs = []string{agencyID}
+	} else {
+		agencies, err := s.repo.List(ctx, agency.AgencyFilters{})
+		if err != nil {
+			return fmt.Errorf("failed to list agencies: %w", err)
+		}
+		for _, a := range agencies {
+			agencyIDs = append(agencyIDs, a.ID)
+		}
+	}
+
+	for _, id := range agencyIDs {
+		db, err := s.repo.agencyDatabase(ctx, id)
+		if err != nil {
+			log.WithError(err).WithField("agency_id", id).Debug("Skipping agency outbox")
+			continue
+		}
+		exists, err := db.CollectionExists(ctx, outboxCollectionName)
+		if err != nil {
+			return fmt.Errorf("failed to check outbox collection: %w", err)
+		}
+		if !exists {
+			continue
+		}
+		if err := fn(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEntries runs an outbox query
+func readEntries(ctx context.Context, db driver.Database, query string, bindVars map[string]interface{}) ([]*outbox.Entry, error) {
+	cursor, err := db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer cursor.Close()
+
+	var entries []*outbox.Entry
+	for {
+		var entry outbox.Entry
+		_, err := cursor.ReadDocument(ctx, &entry)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outbox entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+package agency
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleFormatVersion is the version of the bundle format written by exports
+const BundleFormatVersion = 1
+
+// ErrUnsupportedBundle is returned when importing a bundle of an unknown format version
+var ErrUnsupportedBundle = errors.New("unsupported agency bundle format")
+
+// AgencyBundle is a portable export of an agency design: its descriptive
+// fields, overview, goals and work items. Keys and installation-specific
+// fields are left out so that the bundle can be imported into another
+// installation. Work item dependencies refer to work item codes.
+type AgencyBundle struct {
+	FormatVersion int               `json:"format_version"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Agency        BundledAgency     `json:"agency"`
+	Introduction  string            `json:"introduction,omitempty"`
+	Goals         []BundledGoal     `json:"goals"`
+	WorkItems     []BundledWorkItem `json:"work_items"`
+}
+
+// BundledAgency holds the exported agency fields. ID is the agency's ID in
+// the exporting installation and is informational only.
+type BundledAgency struct {
+	ID          string         `json:"id,omitempty"`
+	Name        string         `json:"name"`
+	DisplayName string         `json:"display_name"`
+	Description string         `json:"description"`
+	Category    string         `json:"category"`
+	Icon        string         `json:"icon"`
+	Metadata    AgencyMetadata `json:"metadata"`
+	Settings    AgencySettings `json:"settings"`
+}
+
+// BundledGoal holds the exported fields of a goal
+type BundledGoal struct {
+	Code              string   `json:"code"`
+	Description       string   `json:"description"`
+	Scope             string   `json:"scope,omitempty"`
+	SuccessMetrics    []string `json:"success_metrics,omitempty"`
+	Priority          string   `json:"priority,omitempty"`
+	Status            string   `json:"status,omitempty"`
+	Category          string   `json:"category,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	Rank              int      `json:"rank,omitempty"`
+	PriorityRationale string   `json:"priority_rationale,omitempty"`
+}
+
+// BundledWorkItem holds the exported fields of a work item
+type BundledWorkItem struct {
+	Code                 string   `json:"code"`
+	Title                string   `json:"title"`
+	Description          string   `json:"description"`
+	Deliverables         []string `json:"deliverables,omitempty"`
+	Dependencies         []string `json:"dependencies,omitempty"`
+	Tags                 []string `json:"tags,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+	Goals                []string `json:"goals,omitempty"` // Codes of the goals the work item contributes to
+}
+
+// ImportResult reports the agency and items created from a bundle
+type ImportResult struct {
+	Agency    *Agency     `json:"agency"`
+	Goals     []*Goal     `json:"goals"`
+	WorkItems []*WorkItem `json:"work_items"`
+
+	// DroppedDependencies lists, per new work item code, dependencies on
+	// work items that were not part of the bundle
+	DroppedDependencies map[string][]string `json:"dropped_dependencies,omitempty"`
+}
+
+// MarshalYAML writes the bundle with the same field names as its JSON form
+func (b AgencyBundle) MarshalYAML() (interface{}, error) {
+	type plain AgencyBundle
+	data, err := json.Marshal(plain(b))
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// UnmarshalYAML reads a bundle written by MarshalYAML
+func (b *AgencyBundle) UnmarshalYAML(value *yaml.Node) error {
+	var doc interface{}
+	if err := value.Decode(&doc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	type plain AgencyBundle
+	return json.Unmarshal(data, (*plain)(b))
+}
@@ -0,0 +1,60 @@
+package agency
+
+import "time"
+
+// ExplanationEntityType identifies the kind of entity an explanation is attached to
+type ExplanationEntityType string
+
+const (
+	// ExplanationEntityGoal attaches an explanation to a goal
+	ExplanationEntityGoal ExplanationEntityType = "goal"
+	// ExplanationEntityWorkItem attaches an explanation to a work item
+	ExplanationEntityWorkItem ExplanationEntityType = "work_item"
+)
+
+// IsValid reports whether the entity type is known
+func (t ExplanationEntityType) IsValid() bool {
+	return t == ExplanationEntityGoal || t == ExplanationEntityWorkItem
+}
+
+// ExplanationAction describes what the AI did to the entity
+type ExplanationAction string
+
+const (
+	// ExplanationActionCreated marks an entity the AI created
+	ExplanationActionCreated ExplanationAction = "created"
+	// ExplanationActionUpdated marks an entity the AI changed
+	ExplanationActionUpdated ExplanationAction = "updated"
+	// ExplanationActionRemoved marks an entity the AI removed
+	ExplanationActionRemoved ExplanationAction = "removed"
+)
+
+// Explanation records why the AI created, changed or removed a goal or work
+// item, so the reasoning stays attached to the entity instead of only living
+// in the designer chat
+type Explanation struct {
+	Key        string                `json:"_key,omitempty"`
+	AgencyID   string                `json:"agency_id"`
+	EntityType ExplanationEntityType `json:"entity_type"`
+	EntityKey  string                `json:"entity_key"`
+	EntityCode string                `json:"entity_code,omitempty"` // Code at the time of the change
+	Action     ExplanationAction     `json:"action"`
+
+	// Text is the AI's explanation for this entity
+	Text string `json:"text"`
+
+	// Operation is the AI operation that produced the change (refine, generate, remove, ...)
+	Operation string `json:"operation,omitempty"`
+
+	// UserMessage is the designer request that triggered the change
+	UserMessage string `json:"user_message,omitempty"`
+
+	// ConversationID links back to the designer conversation, if any
+	ConversationID string `json:"conversation_id,omitempty"`
+
+	// Before and After hold the changed fields' previous and new values
+	Before map[string]interface{} `json:"before,omitempty"`
+	After  map[string]interface{} `json:"after,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package agency
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrGoalConsolidationNotFound is returned when a consolidation does not exist
+	ErrGoalConsolidationNotFound = errors.New("goal consolidation not found")
+
+	// ErrGoalConsolidationUndone is returned when undoing a consolidation a second time
+	ErrGoalConsolidationUndone = errors.New("goal consolidation already undone")
+)
+
+// GoalConsolidation records goals replaced by an AI consolidation or
+// removal so that the change can be undone. Before holds the removed goals
+// as they were; After holds the goals created in their place.
+type GoalConsolidation struct {
+	Key       string     `json:"_key,omitempty"`
+	AgencyID  string     `json:"agency_id"`
+	Operation string     `json:"operation,omitempty"` // AI operation that made the change, e.g. consolidate or remove
+	Before    []*Goal    `json:"before"`
+	After     []*Goal    `json:"after"`
+	CreatedAt time.Time  `json:"created_at"`
+	UndoneAt  *time.Time `json:"undone_at,omitempty"`
+}
+
+// ConsolidateGoalsRequest replaces a set of goals with new ones. Goals may be
+// empty to only remove the listed goals.
+type ConsolidateGoalsRequest struct {
+	RemoveKeys []string            `json:"remove_keys" binding:"required"`
+	Goals      []CreateGoalRequest `json:"goals"`
+	Operation  string              `json:"operation"`
+}
@@ -0,0 +1,50 @@
+package agency
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrInvalidGoalPriorities is returned when an accepted goal ordering is malformed
+var ErrInvalidGoalPriorities = errors.New("invalid goal priorities")
+
+// Goal priority levels
+const (
+	GoalPriorityHigh   = "High"
+	GoalPriorityMedium = "Medium"
+	GoalPriorityLow    = "Low"
+)
+
+// IsValidGoalPriority reports whether priority is a known priority level
+func IsValidGoalPriority(priority string) bool {
+	return priority == GoalPriorityHigh || priority == GoalPriorityMedium || priority == GoalPriorityLow
+}
+
+// GoalPriority is the accepted rank and priority of one goal
+type GoalPriority struct {
+	GoalKey   string `json:"goal_key" binding:"required"`
+	Rank      int    `json:"rank" binding:"required"`
+	Priority  string `json:"priority"` // High, Medium, Low; empty keeps the current priority
+	Rationale string `json:"rationale"`
+}
+
+// ReprioritizeGoalsRequest is the request body for accepting a goal ordering,
+// either the AI recommendation as is or after adjusting it
+type ReprioritizeGoalsRequest struct {
+	Priorities []GoalPriority `json:"priorities" binding:"required,dive"`
+}
+
+// SortGoalsByRank orders ranked goals by rank followed by unranked goals by code
+func SortGoalsByRank(goals []*Goal) {
+	sort.SliceStable(goals, func(i, j int) bool {
+		a, b := goals[i], goals[j]
+		switch {
+		case a.Rank > 0 && b.Rank > 0:
+			return a.Rank < b.Rank
+		case a.Rank > 0 || b.Rank > 0:
+			return a.Rank > 0
+		default:
+			return a.Code < b.Code
+		}
+	})
+}
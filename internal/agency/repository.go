@@ -25,6 +25,13 @@ type Repository interface {
 	UpdateGoal(ctx context.Context, goal *Goal) error
 	DeleteGoal(ctx context.Context, agencyID string, key string) error
 
+	// Goal consolidation methods. Creating a consolidation removes its Before
+	// goals and creates its After goals; undoing it reverses that and saves
+	// the record. Both happen atomically.
+	CreateGoalConsolidation(ctx context.Context, consolidation *GoalConsolidation) error
+	GetGoalConsolidation(ctx context.Context, agencyID string, key string) (*GoalConsolidation, error)
+	UndoGoalConsolidation(ctx context.Context, consolidation *GoalConsolidation) error
+
 	// WorkItem methods
 	CreateWorkItem(ctx context.Context, workItem *WorkItem) error
 	GetWorkItems(ctx context.Context, agencyID string) ([]*WorkItem, error)
@@ -34,6 +41,10 @@ type Repository interface {
 	DeleteWorkItem(ctx context.Context, agencyID string, key string) error
 	ValidateDependencies(ctx context.Context, agencyID string, workItemCode string, dependencies []string) error
 
+	// Explanation methods
+	CreateExplanation(ctx context.Context, explanation *Explanation) error
+	GetExplanations(ctx context.Context, agencyID string, entityType ExplanationEntityType, entityKey string) ([]*Explanation, error)
+
 	// RACI Matrix methods
 	SaveRACIMatrix(ctx context.Context, agencyID string, matrix *RACIMatrix) error
 	GetRACIMatrix(ctx context.Context, agencyID string, key string) (*RACIMatrix, error)
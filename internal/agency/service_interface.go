@@ -23,7 +23,11 @@ type Service interface {
 	GetGoals(ctx context.Context, agencyID string) ([]*Goal, error)
 	GetGoal(ctx context.Context, agencyID string, key string) (*Goal, error)
 	UpdateGoal(ctx context.Context, agencyID string, key string, code string, description string) error
+	UpdateGoalFull(ctx context.Context, agencyID string, key string, req UpdateGoalRequest) error
 	DeleteGoal(ctx context.Context, agencyID string, key string) error
+	ReprioritizeGoals(ctx context.Context, agencyID string, priorities []GoalPriority) ([]*Goal, error)
+	ConsolidateGoals(ctx context.Context, agencyID string, req ConsolidateGoalsRequest) (*GoalConsolidation, error)
+	UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*GoalConsolidation, error)
 
 	// WorkItem methods
 	CreateWorkItem(ctx context.Context, agencyID string, req CreateWorkItemRequest) (*WorkItem, error)
@@ -33,6 +37,24 @@ type Service interface {
 	UpdateWorkItem(ctx context.Context, agencyID string, key string, req UpdateWorkItemRequest) error
 	DeleteWorkItem(ctx context.Context, agencyID string, key string) error
 	ValidateWorkItemDependencies(ctx context.Context, agencyID string, workItemCode string, dependencies []string) error
+	TransitionWorkItem(ctx context.Context, agencyID string, key string, req TransitionWorkItemRequest) (*WorkItem, error)
+	AssignWorkItem(ctx context.Context, agencyID string, key string, req AssignWorkItemRequest) (*WorkItem, error)
+	GetWorkload(ctx context.Context, agencyID string) (*Workload, error)
+
+	// AI explanation methods
+	RecordExplanation(ctx context.Context, explanation *Explanation) error
+	GetExplanations(ctx context.Context, agencyID string, entityType ExplanationEntityType, entityKey string) ([]*Explanation, error)
+
+	// Cross-agency transfer methods
+	CopyItems(ctx context.Context, targetAgencyID string, req TransferItemsRequest, adapter ItemAdapter) (*TransferResult, error)
+	LinkItems(ctx context.Context, targetAgencyID string, req TransferItemsRequest) (*TransferResult, error)
+
+	// Portable bundle methods
+	ExportAgency(ctx context.Context, agencyID string) (*AgencyBundle, error)
+	ImportAgency(ctx context.Context, bundle *AgencyBundle) (*ImportResult, error)
+
+	// Goal traceability methods
+	GetTraceability(ctx context.Context, agencyID string) (*TraceabilityReport, error)
 
 	// RACI Assignment methods (graph-based)
 	CreateRACIAssignment(ctx context.Context, agencyID string, assignment *RACIAssignment) error
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 )
 
 // AgencyService handles core agency CRUD operations
@@ -14,6 +15,8 @@ type AgencyService struct {
 	validator agency.Validator
 	dbInit    agency.DatabaseInitializer
 	active    string // Currently active agency ID
+
+	validation *policyValidator
 }
 
 // NewAgencyService creates a new agency service
@@ -37,6 +40,11 @@ func (s *AgencyService) CreateAgency(ctx context.Context, agencyDoc *agency.Agen
 	agencyDoc.CreatedAt = now
 	agencyDoc.UpdatedAt = now
 
+	// Agencies created by a tenant belong to it
+	if tenantID := tenant.FromContext(ctx); tenantID != "" {
+		agencyDoc.TenantID = tenantID
+	}
+
 	// Set default status if not provided
 	if agencyDoc.Status == "" {
 		agencyDoc.Status = agency.AgencyStatusActive
@@ -90,6 +98,7 @@ func (s *AgencyService) UpdateAgency(ctx context.Context, id string, updates age
 	}
 
 	// Apply updates
+	wasActive := existing.Status == agency.AgencyStatusActive
 	s.applyUpdates(existing, updates)
 	existing.UpdatedAt = time.Now()
 
@@ -97,6 +106,11 @@ func (s *AgencyService) UpdateAgency(ctx context.Context, id string, updates age
 	if err := s.validator.ValidateAgency(existing); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
+	if !wasActive && existing.Status == agency.AgencyStatusActive {
+		if err := s.validation.checkActivation(ctx, existing); err != nil {
+			return err
+		}
+	}
 
 	// Update in repository
 	if err := s.repo.Update(ctx, existing); err != nil {
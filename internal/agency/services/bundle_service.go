@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/google/uuid"
+)
+
+// BundleService exports agency designs as portable bundles and recreates
+// agencies from them
+type BundleService struct {
+	repo     agency.Repository
+	agencies *AgencyService
+}
+
+// NewBundleService creates a new bundle service. Imported agencies are
+// created through agencies so that they are validated and get a database.
+func NewBundleService(repo agency.Repository, agencies *AgencyService) *BundleService {
+	return &BundleService{
+		repo:     repo,
+		agencies: agencies,
+	}
+}
+
+// ExportAgency collects an agency's overview, goals and work items into a
+// bundle. Linked items are exported with their source's current content.
+func (s *BundleService) ExportAgency(ctx context.Context, agencyID string) (*agency.AgencyBundle, error) {
+	agencyDoc, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agency: %w", err)
+	}
+
+	bundle := &agency.AgencyBundle{
+		FormatVersion: agency.BundleFormatVersion,
+		ExportedAt:    time.Now().UTC(),
+		Agency: agency.BundledAgency{
+			ID:          agencyDoc.ID,
+			Name:        agencyDoc.Name,
+			DisplayName: agencyDoc.DisplayName,
+			Description: agencyDoc.Description,
+			Category:    agencyDoc.Category,
+			Icon:        agencyDoc.Icon,
+			Metadata:    agencyDoc.Metadata,
+			Settings:    agencyDoc.Settings,
+		},
+		Goals:     []agency.BundledGoal{},
+		WorkItems: []agency.BundledWorkItem{},
+	}
+	bundle.Agency.Metadata.APIEndpoint = ""
+
+	// An agency without an overview is exported without an introduction
+	if overview, err := s.repo.GetOverview(ctx, agencyID); err == nil && overview != nil {
+		bundle.Introduction = overview.Introduction
+	}
+
+	goals, err := s.repo.GetGoals(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+	goalCodes := make(map[string]string, len(goals))
+	for _, goal := range goals {
+		resolveLinkedGoal(ctx, s.repo, goal)
+		goalCodes[goal.Key] = goal.Code
+		bundle.Goals = append(bundle.Goals, agency.BundledGoal{
+			Code:              goal.Code,
+			Description:       goal.Description,
+			Scope:             goal.Scope,
+			SuccessMetrics:    goal.SuccessMetrics,
+			Priority:          goal.Priority,
+			Status:            goal.Status,
+			Category:          goal.Category,
+			Tags:              goal.Tags,
+			Rank:              goal.Rank,
+			PriorityRationale: goal.PriorityRationale,
+		})
+	}
+
+	workItems, err := s.repo.GetWorkItems(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work items: %w", err)
+	}
+	for _, workItem := range workItems {
+		resolveLinkedWorkItem(ctx, s.repo, workItem)
+
+		// Goal keys are replaced on import, so goals are referenced by code
+		var workItemGoals []string
+		for _, key := range workItem.GoalKeys {
+			if code, ok := goalCodes[key]; ok {
+				workItemGoals = append(workItemGoals, code)
+			}
+		}
+
+		bundle.WorkItems = append(bundle.WorkItems, agency.BundledWorkItem{
+			Code:                 workItem.Code,
+			Title:                workItem.Title,
+			Description:          workItem.Description,
+			Deliverables:         workItem.Deliverables,
+			Dependencies:         workItem.Dependencies,
+			Tags:                 workItem.Tags,
+			RequiredCapabilities: workItem.RequiredCapabilities,
+			Goals:                workItemGoals,
+		})
+	}
+
+	return bundle, nil
+}
+
+// ImportAgency creates a new agency from a bundle. The agency gets a new ID
+// and its goals and work items new keys; codes are kept unless the bundle
+// repeats them. Dependencies on work items missing from the bundle are
+// dropped and reported.
+func (s *BundleService) ImportAgency(ctx context.Context, bundle *agency.AgencyBundle) (*agency.ImportResult, error) {
+	if bundle.FormatVersion < 1 || bundle.FormatVersion > agency.BundleFormatVersion {
+		return nil, fmt.Errorf("%w: version %d", agency.ErrUnsupportedBundle, bundle.FormatVersion)
+	}
+
+	id := "agency_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	agencyDoc := &agency.Agency{
+		ID:          id,
+		Name:        bundle.Agency.Name,
+		DisplayName: bundle.Agency.DisplayName,
+		Description: bundle.Agency.Description,
+		Category:    bundle.Agency.Category,
+		Icon:        bundle.Agency.Icon,
+		Metadata:    bundle.Agency.Metadata,
+		Settings:    bundle.Agency.Settings,
+	}
+	agencyDoc.Metadata.APIEndpoint = fmt.Sprintf("/api/v1/agencies/%s", id)
+
+	if err := s.agencies.CreateAgency(ctx, agencyDoc); err != nil {
+		return nil, err
+	}
+
+	result := &agency.ImportResult{
+		Agency:    agencyDoc,
+		Goals:     []*agency.Goal{},
+		WorkItems: []*agency.WorkItem{},
+	}
+
+	if bundle.Introduction != "" {
+		overview := &agency.Overview{
+			AgencyID:     id,
+			Introduction: bundle.Introduction,
+			UpdatedAt:    time.Now(),
+		}
+		if err := s.repo.UpdateOverview(ctx, overview); err != nil {
+			return result, fmt.Errorf("failed to create overview: %w", err)
+		}
+	}
+
+	usedCodes := make(map[string]bool, len(bundle.Goals))
+	goalKeys := make(map[string]string, len(bundle.Goals))
+	for _, src := range bundle.Goals {
+		goal := &agency.Goal{
+			AgencyID:          id,
+			Code:              uniqueCode(src.Code, usedCodes),
+			Description:       src.Description,
+			Scope:             src.Scope,
+			SuccessMetrics:    src.SuccessMetrics,
+			Priority:          src.Priority,
+			Status:            src.Status,
+			Category:          src.Category,
+			Tags:              src.Tags,
+			Rank:              src.Rank,
+			PriorityRationale: src.PriorityRationale,
+		}
+		usedCodes[goal.Code] = true
+
+		if err := s.repo.CreateGoal(ctx, goal); err != nil {
+			return result, fmt.Errorf("failed to create goal %s: %w", src.Code, err)
+		}
+		result.Goals = append(result.Goals, goal)
+		if _, seen := goalKeys[src.Code]; !seen {
+			goalKeys[src.Code] = goal.Key
+		}
+	}
+
+	// Every new code is known up front, so dependencies are remapped before
+	// the work items are created
+	codes := make([]string, len(bundle.WorkItems))
+	codeMap := make(map[string]string, len(bundle.WorkItems))
+	usedCodes = make(map[string]bool, len(bundle.WorkItems))
+	for i, src := range bundle.WorkItems {
+		if src.Code == "" {
+			continue
+		}
+		codes[i] = uniqueCode(src.Code, usedCodes)
+		usedCodes[codes[i]] = true
+		if _, seen := codeMap[src.Code]; !seen {
+			codeMap[src.Code] = codes[i]
+		}
+	}
+
+	for i, src := range bundle.WorkItems {
+		workItem := &agency.WorkItem{
+			AgencyID:     id,
+			Code:         codes[i],
+			Title:        src.Title,
+			Description:  src.Description,
+			Deliverables: src.Deliverables,
+			Tags:         src.Tags,
+
+			RequiredCapabilities: src.RequiredCapabilities,
+		}
+		for _, code := range src.Goals {
+			if key, ok := goalKeys[code]; ok {
+				workItem.GoalKeys = append(workItem.GoalKeys, key)
+			}
+		}
+
+		var dropped []string
+		for _, dep := range src.Dependencies {
+			if newCode, ok := codeMap[dep]; ok {
+				workItem.Dependencies = append(workItem.Dependencies, newCode)
+			} else {
+				dropped = append(dropped, dep)
+			}
+		}
+
+		if err := s.repo.CreateWorkItem(ctx, workItem); err != nil {
+			return result, fmt.Errorf("failed to create work item %s: %w", src.Code, err)
+		}
+		result.WorkItems = append(result.WorkItems, workItem)
+
+		if len(dropped) > 0 {
+			if result.DroppedDependencies == nil {
+				result.DroppedDependencies = make(map[string][]string)
+			}
+			result.DroppedDependencies[workItem.Code] = dropped
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"gopkg.in/yaml.v3"
+)
+
+func (r *fakeRepo) Create(ctx context.Context, a *agency.Agency) error {
+	r.agencies[a.ID] = a
+	return nil
+}
+
+func (r *fakeRepo) UpdateOverview(ctx context.Context, overview *agency.Overview) error {
+	if r.overviews == nil {
+		r.overviews = make(map[string]*agency.Overview)
+	}
+	r.overviews[overview.AgencyID] = overview
+	return nil
+}
+
+func TestBundleService_ExportImportRoundTrip(t *testing.T) {
+	repo := newFakeRepo("source")
+	repo.agencies["source"].Name = "water-utility"
+	repo.agencies["source"].Category = "infrastructure"
+	repo.agencies["source"].Metadata.APIEndpoint = "/api/v1/agencies/source"
+	repo.UpdateOverview(context.Background(), &agency.Overview{AgencyID: "source", Introduction: "Keep the water flowing"})
+	seedSource(t, repo)
+	ctx := context.Background()
+
+	agencies := NewAgencyService(repo, agency.NewValidator(), nil)
+	service := NewBundleService(repo, agencies)
+	bundle, err := service.ExportAgency(ctx, "source")
+	if err != nil {
+		t.Fatalf("ExportAgency failed: %v", err)
+	}
+	if bundle.Introduction != "Keep the water flowing" || len(bundle.Goals) != 1 || len(bundle.WorkItems) != 2 {
+		t.Fatalf("unexpected bundle: %+v", bundle)
+	}
+	if bundle.Agency.Metadata.APIEndpoint != "" {
+		t.Errorf("expected installation-specific endpoint to be left out, got %s", bundle.Agency.Metadata.APIEndpoint)
+	}
+
+	// The bundle survives YAML
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded agency.AgencyBundle
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to read YAML bundle: %v\n%s", err, data)
+	}
+
+	result, err := service.ImportAgency(ctx, &decoded)
+	if err != nil {
+		t.Fatalf("ImportAgency failed: %v", err)
+	}
+	imported := result.Agency
+	if imported.ID == "source" || imported.Name != "water-utility" {
+		t.Errorf("expected a new agency with the bundle's name, got %s (%s)", imported.ID, imported.Name)
+	}
+	if imported.Metadata.APIEndpoint != "/api/v1/agencies/"+imported.ID {
+		t.Errorf("unexpected API endpoint %s", imported.Metadata.APIEndpoint)
+	}
+	if overview, _ := repo.GetOverview(ctx, imported.ID); overview == nil || overview.Introduction != "Keep the water flowing" {
+		t.Errorf("expected the overview to be imported, got %+v", overview)
+	}
+
+	goal := result.Goals[0]
+	if goal.Code != "G-001" || goal.Description != "Reduce water loss" || goal.Key == repo.goals["source"][0].Key {
+		t.Errorf("expected a new goal with the bundle's code, got %+v", goal)
+	}
+
+	meters, leaks := result.WorkItems[0], result.WorkItems[1]
+	if meters.Code != "WI-001" || leaks.Code != "WI-002" {
+		t.Fatalf("expected work item codes to be kept, got %s/%s", meters.Code, leaks.Code)
+	}
+	if len(leaks.Dependencies) != 1 || leaks.Dependencies[0] != "WI-001" {
+		t.Errorf("expected dependency on WI-001, got %v", leaks.Dependencies)
+	}
+	if dropped := result.DroppedDependencies["WI-002"]; len(dropped) != 1 || dropped[0] != "WI-009" {
+		t.Errorf("expected WI-009 reported as dropped, got %v", result.DroppedDependencies)
+	}
+}
+
+func TestBundleService_ImportRejectsUnknownFormat(t *testing.T) {
+	repo := newFakeRepo()
+	service := NewBundleService(repo, NewAgencyService(repo, agency.NewValidator(), nil))
+
+	_, err := service.ImportAgency(context.Background(), &agency.AgencyBundle{FormatVersion: agency.BundleFormatVersion + 1})
+	if !errors.Is(err, agency.ErrUnsupportedBundle) {
+		t.Errorf("expected ErrUnsupportedBundle, got %v", err)
+	}
+	if len(repo.agencies) != 0 {
+		t.Errorf("expected no agency to be created, got %v", repo.agencies)
+	}
+}
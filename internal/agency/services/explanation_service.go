@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// ExplanationService handles AI explanations attached to goals and work items
+type ExplanationService struct {
+	repo agency.Repository
+}
+
+// NewExplanationService creates a new explanation service
+func NewExplanationService(repo agency.Repository) *ExplanationService {
+	return &ExplanationService{
+		repo: repo,
+	}
+}
+
+// RecordExplanation stores an explanation for a goal or work item
+func (s *ExplanationService) RecordExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	if !explanation.EntityType.IsValid() {
+		return fmt.Errorf("invalid entity type: %s", explanation.EntityType)
+	}
+	if explanation.EntityKey == "" {
+		return fmt.Errorf("entity key is required")
+	}
+	if explanation.Text == "" {
+		return fmt.Errorf("explanation text is required")
+	}
+
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, explanation.AgencyID)
+	if err != nil {
+		return fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	if explanation.CreatedAt.IsZero() {
+		explanation.CreatedAt = time.Now()
+	}
+
+	if err := s.repo.CreateExplanation(ctx, explanation); err != nil {
+		return fmt.Errorf("failed to create explanation: %w", err)
+	}
+
+	return nil
+}
+
+// GetExplanations retrieves the explanations for an entity, newest first
+func (s *ExplanationService) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	if !entityType.IsValid() {
+		return nil, fmt.Errorf("invalid entity type: %s", entityType)
+	}
+
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	explanations, err := s.repo.GetExplanations(ctx, agencyID, entityType, entityKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get explanations: %w", err)
+	}
+
+	sort.SliceStable(explanations, func(i, j int) bool {
+		return explanations[i].CreatedAt.After(explanations[j].CreatedAt)
+	})
+	if explanations == nil {
+		explanations = []*agency.Explanation{}
+	}
+
+	return explanations, nil
+}
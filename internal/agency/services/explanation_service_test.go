@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func (r *fakeRepo) CreateExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	explanation.Key = r.key()
+	r.explanations = append(r.explanations, explanation)
+	return nil
+}
+
+func (r *fakeRepo) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	var result []*agency.Explanation
+	for _, e := range r.explanations {
+		if e.AgencyID == agencyID && e.EntityType == entityType && e.EntityKey == entityKey {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func TestExplanationService_RecordAndGet(t *testing.T) {
+	repo := newFakeRepo("agency-1")
+	service := NewExplanationService(repo)
+	ctx := context.Background()
+
+	created := time.Now().Add(-time.Hour)
+	for _, e := range []*agency.Explanation{
+		{AgencyID: "agency-1", EntityType: agency.ExplanationEntityGoal, EntityKey: "g1", Action: agency.ExplanationActionCreated, Text: "Added to cover leak detection", CreatedAt: created},
+		{AgencyID: "agency-1", EntityType: agency.ExplanationEntityGoal, EntityKey: "g1", Action: agency.ExplanationActionUpdated, Text: "Made the metric measurable"},
+		{AgencyID: "agency-1", EntityType: agency.ExplanationEntityWorkItem, EntityKey: "g1", Action: agency.ExplanationActionCreated, Text: "Unrelated work item"},
+	} {
+		if err := service.RecordExplanation(ctx, e); err != nil {
+			t.Fatalf("RecordExplanation failed: %v", err)
+		}
+	}
+
+	explanations, err := service.GetExplanations(ctx, "agency-1", agency.ExplanationEntityGoal, "g1")
+	if err != nil {
+		t.Fatalf("GetExplanations failed: %v", err)
+	}
+	if len(explanations) != 2 {
+		t.Fatalf("expected 2 goal explanations, got %d", len(explanations))
+	}
+	if explanations[0].Action != agency.ExplanationActionUpdated {
+		t.Errorf("expected newest explanation first, got %s", explanations[0].Action)
+	}
+
+	none, err := service.GetExplanations(ctx, "agency-1", agency.ExplanationEntityGoal, "missing")
+	if err != nil || none == nil || len(none) != 0 {
+		t.Errorf("expected empty non-nil list, got %v (err %v)", none, err)
+	}
+}
+
+func TestExplanationService_Validation(t *testing.T) {
+	service := NewExplanationService(newFakeRepo("agency-1"))
+	ctx := context.Background()
+
+	cases := map[string]*agency.Explanation{
+		"bad entity type": {AgencyID: "agency-1", EntityType: "role", EntityKey: "r1", Text: "x"},
+		"no entity key":   {AgencyID: "agency-1", EntityType: agency.ExplanationEntityGoal, Text: "x"},
+		"no text":         {AgencyID: "agency-1", EntityType: agency.ExplanationEntityGoal, EntityKey: "g1"},
+		"unknown agency":  {AgencyID: "missing", EntityType: agency.ExplanationEntityGoal, EntityKey: "g1", Text: "x"},
+	}
+	for name, e := range cases {
+		if err := service.RecordExplanation(ctx, e); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// ConsolidateGoals replaces goals with the requested ones and records the
+// change so that it can be undone. Consolidated goals are not checked for
+// duplicates, since they usually restate the goals they replace.
+func (s *GoalService) ConsolidateGoals(ctx context.Context, agencyID string, req agency.ConsolidateGoalsRequest) (*agency.GoalConsolidation, error) {
+	if len(req.RemoveKeys) == 0 {
+		return nil, fmt.Errorf("at least one goal to replace is required")
+	}
+
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	consolidation := &agency.GoalConsolidation{
+		AgencyID:  agencyID,
+		Operation: req.Operation,
+		Before:    make([]*agency.Goal, 0, len(req.RemoveKeys)),
+		After:     make([]*agency.Goal, 0, len(req.Goals)),
+		CreatedAt: time.Now(),
+	}
+
+	removing := make(map[string]bool, len(req.RemoveKeys))
+	for _, key := range req.RemoveKeys {
+		if removing[key] {
+			continue
+		}
+		removing[key] = true
+
+		goal, err := s.repo.GetGoal(ctx, agencyID, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get goal %s: %w", key, err)
+		}
+		consolidation.Before = append(consolidation.Before, goal)
+	}
+
+	for _, g := range req.Goals {
+		if g.Description == "" {
+			return nil, fmt.Errorf("consolidated goal %s has no description", g.Code)
+		}
+		consolidation.After = append(consolidation.After, &agency.Goal{
+			AgencyID:       agencyID,
+			Code:           g.Code,
+			Description:    g.Description,
+			Scope:          g.Scope,
+			SuccessMetrics: g.SuccessMetrics,
+			Priority:       g.Priority,
+			Status:         g.Status,
+			Category:       g.Category,
+			Tags:           g.Tags,
+			CreatedAt:      consolidation.CreatedAt,
+			UpdatedAt:      consolidation.CreatedAt,
+		})
+	}
+
+	if err := s.repo.CreateGoalConsolidation(ctx, consolidation); err != nil {
+		return nil, fmt.Errorf("failed to consolidate goals: %w", err)
+	}
+
+	return consolidation, nil
+}
+
+// UndoGoalConsolidation restores the goals a consolidation replaced, under
+// their original keys, and removes the goals it created that still exist.
+// Edits made to the created goals since are lost.
+func (s *GoalService) UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	consolidation, err := s.repo.GetGoalConsolidation(ctx, agencyID, key)
+	if err != nil {
+		return nil, err
+	}
+	if consolidation.UndoneAt != nil {
+		return nil, fmt.Errorf("%w: %s", agency.ErrGoalConsolidationUndone, key)
+	}
+
+	now := time.Now()
+	consolidation.UndoneAt = &now
+	if err := s.repo.UndoGoalConsolidation(ctx, consolidation); err != nil {
+		return nil, fmt.Errorf("failed to undo goal consolidation: %w", err)
+	}
+
+	return consolidation, nil
+}
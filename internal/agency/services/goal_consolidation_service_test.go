@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func (r *fakeRepo) CreateGoalConsolidation(ctx context.Context, consolidation *agency.GoalConsolidation) error {
+	r.replaceGoals(consolidation.AgencyID, consolidation.Before, consolidation.After)
+	if r.consolidations == nil {
+		r.consolidations = make(map[string]*agency.GoalConsolidation)
+	}
+	consolidation.Key = r.key()
+	r.consolidations[consolidation.Key] = consolidation
+	return nil
+}
+
+func (r *fakeRepo) GetGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	consolidation, ok := r.consolidations[key]
+	if !ok || consolidation.AgencyID != agencyID {
+		return nil, agency.ErrGoalConsolidationNotFound
+	}
+	copied := *consolidation
+	return &copied, nil
+}
+
+func (r *fakeRepo) UndoGoalConsolidation(ctx context.Context, consolidation *agency.GoalConsolidation) error {
+	r.replaceGoals(consolidation.AgencyID, consolidation.After, consolidation.Before)
+	r.consolidations[consolidation.Key] = consolidation
+	return nil
+}
+
+func (r *fakeRepo) replaceGoals(agencyID string, remove, create []*agency.Goal) {
+	removed := make(map[string]bool, len(remove))
+	for _, goal := range remove {
+		removed[goal.Key] = true
+	}
+	var kept []*agency.Goal
+	for _, goal := range r.goals[agencyID] {
+		if !removed[goal.Key] {
+			kept = append(kept, goal)
+		}
+	}
+	for _, goal := range create {
+		if goal.Key == "" {
+			goal.Key = r.key()
+		}
+		copied := *goal
+		kept = append(kept, &copied)
+	}
+	r.goals[agencyID] = kept
+}
+
+func TestConsolidateGoalsAndUndo(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewGoalService(repo)
+
+	var originals []*agency.Goal
+	for _, code := range []string{"G-001", "G-002", "G-003"} {
+		goal := &agency.Goal{AgencyID: "a1", Code: code, Description: "Reduce response times"}
+		if err := repo.CreateGoal(ctx, goal); err != nil {
+			t.Fatal(err)
+		}
+		originals = append(originals, goal)
+	}
+
+	consolidation, err := service.ConsolidateGoals(ctx, "a1", agency.ConsolidateGoalsRequest{
+		RemoveKeys: []string{originals[0].Key, originals[1].Key},
+		Goals:      []agency.CreateGoalRequest{{Code: "G-004", Description: "Respond to every request within a minute"}},
+		Operation:  "consolidate",
+	})
+	if err != nil {
+		t.Fatalf("ConsolidateGoals failed: %v", err)
+	}
+	if len(consolidation.Before) != 2 || len(consolidation.After) != 1 || consolidation.After[0].Key == "" {
+		t.Fatalf("Unexpected consolidation %+v", consolidation)
+	}
+	if codes := goalCodes(repo.goals["a1"]); codes != "G-003,G-004" {
+		t.Errorf("Expected G-003 and G-004 after consolidating, got %s", codes)
+	}
+
+	undone, err := service.UndoGoalConsolidation(ctx, "a1", consolidation.Key)
+	if err != nil {
+		t.Fatalf("UndoGoalConsolidation failed: %v", err)
+	}
+	if undone.UndoneAt == nil {
+		t.Error("Expected the consolidation to be marked undone")
+	}
+	if codes := goalCodes(repo.goals["a1"]); codes != "G-003,G-001,G-002" {
+		t.Errorf("Expected the original goals after undoing, got %s", codes)
+	}
+	for _, goal := range repo.goals["a1"][1:] {
+		if goal.Key != originals[0].Key && goal.Key != originals[1].Key {
+			t.Errorf("Expected restored goals to keep their keys, got %s", goal.Key)
+		}
+	}
+
+	if _, err := service.UndoGoalConsolidation(ctx, "a1", consolidation.Key); !errors.Is(err, agency.ErrGoalConsolidationUndone) {
+		t.Errorf("Expected ErrGoalConsolidationUndone, got %v", err)
+	}
+	if _, err := service.UndoGoalConsolidation(ctx, "a1", "missing"); !errors.Is(err, agency.ErrGoalConsolidationNotFound) {
+		t.Errorf("Expected ErrGoalConsolidationNotFound, got %v", err)
+	}
+	if _, err := service.ConsolidateGoals(ctx, "a1", agency.ConsolidateGoalsRequest{RemoveKeys: []string{"missing"}}); err == nil {
+		t.Error("Expected an error when replacing an unknown goal")
+	}
+}
+
+func goalCodes(goals []*agency.Goal) string {
+	codes := ""
+	for i, goal := range goals {
+		if i > 0 {
+			codes += ","
+		}
+		codes += goal.Code
+	}
+	return codes
+}
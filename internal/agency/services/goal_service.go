@@ -9,7 +9,8 @@ import (
 
 // GoalService handles goal operations
 type GoalService struct {
-	repo agency.Repository
+	repo       agency.Repository
+	validation *policyValidator
 }
 
 // NewGoalService creates a new goal service
@@ -27,6 +28,10 @@ func (s *GoalService) CreateGoal(ctx context.Context, agencyID string, code stri
 		return nil, fmt.Errorf("failed to verify agency: %w", err)
 	}
 
+	if err := s.validation.checkGoal(ctx, agencyID, "", description); err != nil {
+		return nil, err
+	}
+
 	goal := &agency.Goal{
 		AgencyID:    agencyID,
 		Code:        code,
@@ -53,6 +58,10 @@ func (s *GoalService) GetGoals(ctx context.Context, agencyID string) ([]*agency.
 		return nil, fmt.Errorf("failed to get goals: %w", err)
 	}
 
+	for _, goal := range goals {
+		resolveLinkedGoal(ctx, s.repo, goal)
+	}
+
 	return goals, nil
 }
 
@@ -69,11 +78,37 @@ func (s *GoalService) GetGoal(ctx context.Context, agencyID string, key string)
 		return nil, fmt.Errorf("failed to get goal: %w", err)
 	}
 
+	resolveLinkedGoal(ctx, s.repo, goal)
+
 	return goal, nil
 }
 
 // UpdateGoal updates a goal's code and description
 func (s *GoalService) UpdateGoal(ctx context.Context, agencyID string, key string, code string, description string) error {
+	return s.updateGoal(ctx, agencyID, key, description, func(goal *agency.Goal) {
+		goal.Code = code
+		goal.Description = description
+	})
+}
+
+// UpdateGoalFull replaces every editable field of a goal, including the
+// scope, success metrics, priority, status, category and tags
+func (s *GoalService) UpdateGoalFull(ctx context.Context, agencyID string, key string, req agency.UpdateGoalRequest) error {
+	return s.updateGoal(ctx, agencyID, key, req.Description, func(goal *agency.Goal) {
+		goal.Code = req.Code
+		goal.Description = req.Description
+		goal.Scope = req.Scope
+		goal.SuccessMetrics = req.SuccessMetrics
+		goal.Priority = req.Priority
+		goal.Status = req.Status
+		goal.Category = req.Category
+		goal.Tags = req.Tags
+	})
+}
+
+// updateGoal validates the new description, applies the changes to the
+// stored goal and saves it
+func (s *GoalService) updateGoal(ctx context.Context, agencyID string, key string, description string, apply func(goal *agency.Goal)) error {
 	// Verify agency exists
 	_, err := s.repo.GetByID(ctx, agencyID)
 	if err != nil {
@@ -85,10 +120,15 @@ func (s *GoalService) UpdateGoal(ctx context.Context, agencyID string, key strin
 	if err != nil {
 		return fmt.Errorf("failed to get goal: %w", err)
 	}
+	if goal.Provenance.IsLink() {
+		return agency.ErrReadOnlyLink
+	}
+
+	if err := s.validation.checkGoal(ctx, agencyID, key, description); err != nil {
+		return err
+	}
 
-	// Update code and description
-	goal.Code = code
-	goal.Description = description
+	apply(goal)
 
 	// Save
 	if err := s.repo.UpdateGoal(ctx, goal); err != nil {
@@ -112,3 +152,75 @@ func (s *GoalService) DeleteGoal(ctx context.Context, agencyID string, key strin
 
 	return nil
 }
+
+// ReprioritizeGoals stores an accepted priority ordering. The priorities
+// replace the agency's previous ordering: goals left out become unranked.
+// Linked goals take their rank locally but keep the priority of their source.
+func (s *GoalService) ReprioritizeGoals(ctx context.Context, agencyID string, priorities []agency.GoalPriority) ([]*agency.Goal, error) {
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	goals, err := s.repo.GetGoals(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+
+	goalsByKey := make(map[string]*agency.Goal, len(goals))
+	for _, goal := range goals {
+		goalsByKey[goal.Key] = goal
+	}
+
+	accepted := make(map[string]agency.GoalPriority, len(priorities))
+	ranks := make(map[int]string, len(priorities))
+	for _, p := range priorities {
+		if _, exists := goalsByKey[p.GoalKey]; !exists {
+			return nil, fmt.Errorf("%w: unknown goal %s", agency.ErrInvalidGoalPriorities, p.GoalKey)
+		}
+		if _, duplicate := accepted[p.GoalKey]; duplicate {
+			return nil, fmt.Errorf("%w: goal %s is ranked more than once", agency.ErrInvalidGoalPriorities, p.GoalKey)
+		}
+		if p.Rank < 1 {
+			return nil, fmt.Errorf("%w: rank of goal %s must be at least 1", agency.ErrInvalidGoalPriorities, p.GoalKey)
+		}
+		if other, taken := ranks[p.Rank]; taken {
+			return nil, fmt.Errorf("%w: goals %s and %s share rank %d", agency.ErrInvalidGoalPriorities, other, p.GoalKey, p.Rank)
+		}
+		if p.Priority != "" && !agency.IsValidGoalPriority(p.Priority) {
+			return nil, fmt.Errorf("%w: unknown priority %q for goal %s", agency.ErrInvalidGoalPriorities, p.Priority, p.GoalKey)
+		}
+		accepted[p.GoalKey] = p
+		ranks[p.Rank] = p.GoalKey
+	}
+
+	for _, goal := range goals {
+		p, ranked := accepted[goal.Key]
+
+		rank, priority, rationale := 0, goal.Priority, ""
+		if ranked {
+			rank, rationale = p.Rank, p.Rationale
+			if p.Priority != "" && !goal.Provenance.IsLink() {
+				priority = p.Priority
+			}
+		}
+		if goal.Rank == rank && goal.Priority == priority && goal.PriorityRationale == rationale {
+			continue
+		}
+
+		goal.Rank = rank
+		goal.Priority = priority
+		goal.PriorityRationale = rationale
+		if err := s.repo.UpdateGoal(ctx, goal); err != nil {
+			return nil, fmt.Errorf("failed to update goal %s: %w", goal.Code, err)
+		}
+	}
+
+	for _, goal := range goals {
+		resolveLinkedGoal(ctx, s.repo, goal)
+	}
+	agency.SortGoalsByRank(goals)
+
+	return goals, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func TestReprioritizeGoals(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewGoalService(repo)
+
+	g1, _ := service.CreateGoal(ctx, "a1", "G001", "Reduce outages")
+	g2, _ := service.CreateGoal(ctx, "a1", "G002", "Improve reporting")
+	g3, _ := service.CreateGoal(ctx, "a1", "G003", "Automate billing")
+
+	goals, err := service.ReprioritizeGoals(ctx, "a1", []agency.GoalPriority{
+		{GoalKey: g2.Key, Rank: 1, Priority: "High", Rationale: "Blocks every other goal"},
+		{GoalKey: g1.Key, Rank: 2, Priority: "Medium"},
+	})
+	if err != nil {
+		t.Fatalf("ReprioritizeGoals failed: %v", err)
+	}
+
+	if len(goals) != 3 || goals[0].Key != g2.Key || goals[1].Key != g1.Key || goals[2].Key != g3.Key {
+		t.Fatalf("Expected goals ordered G002, G001, G003, got %v, %v, %v", goals[0].Code, goals[1].Code, goals[2].Code)
+	}
+	if goals[0].Priority != "High" || goals[0].PriorityRationale != "Blocks every other goal" {
+		t.Errorf("Expected accepted priority to persist, got %+v", goals[0])
+	}
+	if goals[2].Rank != 0 {
+		t.Errorf("Expected goal left out of the ordering to be unranked, got rank %d", goals[2].Rank)
+	}
+
+	// A new ordering replaces the previous one
+	goals, err = service.ReprioritizeGoals(ctx, "a1", []agency.GoalPriority{
+		{GoalKey: g3.Key, Rank: 1},
+	})
+	if err != nil {
+		t.Fatalf("ReprioritizeGoals failed: %v", err)
+	}
+	if goals[0].Key != g3.Key || g2.Rank != 0 || g2.Priority != "High" {
+		t.Errorf("Expected G003 first and G002 unranked with its priority kept, got %+v", g2)
+	}
+}
+
+func TestReprioritizeGoals_Invalid(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewGoalService(repo)
+
+	g1, _ := service.CreateGoal(ctx, "a1", "G001", "Reduce outages")
+	g2, _ := service.CreateGoal(ctx, "a1", "G002", "Improve reporting")
+
+	cases := map[string][]agency.GoalPriority{
+		"unknown goal":   {{GoalKey: "missing", Rank: 1}},
+		"duplicate goal": {{GoalKey: g1.Key, Rank: 1}, {GoalKey: g1.Key, Rank: 2}},
+		"shared rank":    {{GoalKey: g1.Key, Rank: 1}, {GoalKey: g2.Key, Rank: 1}},
+		"zero rank":      {{GoalKey: g1.Key, Rank: 0}},
+		"bad priority":   {{GoalKey: g1.Key, Rank: 1, Priority: "Urgent"}},
+	}
+
+	for name, priorities := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.ReprioritizeGoals(ctx, "a1", priorities)
+			if !errors.Is(err, agency.ErrInvalidGoalPriorities) {
+				t.Errorf("Expected ErrInvalidGoalPriorities, got %v", err)
+			}
+		})
+	}
+
+	if g1.Rank != 0 || g2.Rank != 0 {
+		t.Error("Expected rejected orderings to leave goals unchanged")
+	}
+}
+
+func TestUpdateGoalFull(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewGoalService(repo)
+
+	goal, _ := service.CreateGoal(ctx, "a1", "G001", "Reduce outages")
+	err := service.UpdateGoalFull(ctx, "a1", goal.Key, agency.UpdateGoalRequest{
+		Code:           "G001",
+		Description:    "Reduce unplanned outages",
+		Scope:          "Distribution network",
+		SuccessMetrics: []string{"Outage minutes down 30%"},
+		Priority:       "High",
+		Status:         "Active",
+		Category:       "Operational",
+		Tags:           []string{"reliability"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateGoalFull failed: %v", err)
+	}
+
+	updated, err := service.GetGoal(ctx, "a1", goal.Key)
+	if err != nil {
+		t.Fatalf("GetGoal failed: %v", err)
+	}
+	if updated.Description != "Reduce unplanned outages" || updated.Scope != "Distribution network" ||
+		len(updated.SuccessMetrics) != 1 || updated.Priority != "High" || updated.Status != "Active" ||
+		updated.Category != "Operational" || len(updated.Tags) != 1 {
+		t.Errorf("Expected every field to persist, got %+v", updated)
+	}
+
+	// UpdateGoal keeps the fields it does not take
+	if err := service.UpdateGoal(ctx, "a1", goal.Key, "G002", "Reduce outages"); err != nil {
+		t.Fatalf("UpdateGoal failed: %v", err)
+	}
+	updated, _ = service.GetGoal(ctx, "a1", goal.Key)
+	if updated.Code != "G002" || updated.Scope != "Distribution network" {
+		t.Errorf("Expected code change with scope kept, got %+v", updated)
+	}
+}
@@ -13,30 +13,58 @@ type CompositeService struct {
 	*GoalService
 	*WorkItemService
 	*RACIService
+	*TransferService
+	*BundleService
+	*TraceabilityService
+	*ExplanationService
 }
 
 // New creates a new composite service with all sub-services
 func New(repo agency.Repository, validator agency.Validator) agency.Service {
+	agencies := NewAgencyService(repo, validator, nil)
 	return &CompositeService{
-		AgencyService:   NewAgencyService(repo, validator, nil),
-		OverviewService: NewOverviewService(repo),
-		GoalService:     NewGoalService(repo),
-		WorkItemService: NewWorkItemService(repo),
-		RACIService:     NewRACIService(repo),
+		AgencyService:       agencies,
+		OverviewService:     NewOverviewService(repo),
+		GoalService:         NewGoalService(repo),
+		WorkItemService:     NewWorkItemService(repo),
+		RACIService:         NewRACIService(repo),
+		TransferService:     NewTransferService(repo),
+		BundleService:       NewBundleService(repo, agencies),
+		TraceabilityService: NewTraceabilityService(repo),
+		ExplanationService:  NewExplanationService(repo),
 	}
 }
 
 // NewWithDBInit creates a new composite service with database initialization support
 func NewWithDBInit(repo agency.Repository, validator agency.Validator, dbInit agency.DatabaseInitializer) agency.Service {
+	agencies := NewAgencyService(repo, validator, dbInit)
 	return &CompositeService{
-		AgencyService:   NewAgencyService(repo, validator, dbInit),
-		OverviewService: NewOverviewService(repo),
-		GoalService:     NewGoalService(repo),
-		WorkItemService: NewWorkItemService(repo),
-		RACIService:     NewRACIService(repo),
+		AgencyService:       agencies,
+		OverviewService:     NewOverviewService(repo),
+		GoalService:         NewGoalService(repo),
+		WorkItemService:     NewWorkItemService(repo),
+		RACIService:         NewRACIService(repo),
+		TransferService:     NewTransferService(repo),
+		BundleService:       NewBundleService(repo, agencies),
+		TraceabilityService: NewTraceabilityService(repo),
+		ExplanationService:  NewExplanationService(repo),
 	}
 }
 
+// SetValidationPolicy enables the strict checks of the policy on goals, work
+// items and agency activation. The duplicate checker, if not nil, is asked
+// for near-duplicate descriptions that word overlap misses.
+func (c *CompositeService) SetValidationPolicy(policy agency.ValidationPolicy, duplicates agency.DuplicateChecker) {
+	validation := &policyValidator{
+		repo:       c.GoalService.repo,
+		policy:     policy,
+		duplicates: duplicates,
+	}
+	c.AgencyService.validation = validation
+	c.GoalService.validation = validation
+	c.WorkItemService.validation = validation
+}
+
 // Ensure CompositeService implements agency.Service
 var _ agency.Service = (*CompositeService)(nil)
 
@@ -98,10 +126,26 @@ func (c *CompositeService) UpdateGoal(ctx context.Context, agencyID string, key
 	return c.GoalService.UpdateGoal(ctx, agencyID, key, code, description)
 }
 
+func (c *CompositeService) UpdateGoalFull(ctx context.Context, agencyID string, key string, req agency.UpdateGoalRequest) error {
+	return c.GoalService.UpdateGoalFull(ctx, agencyID, key, req)
+}
+
 func (c *CompositeService) DeleteGoal(ctx context.Context, agencyID string, key string) error {
 	return c.GoalService.DeleteGoal(ctx, agencyID, key)
 }
 
+func (c *CompositeService) ReprioritizeGoals(ctx context.Context, agencyID string, priorities []agency.GoalPriority) ([]*agency.Goal, error) {
+	return c.GoalService.ReprioritizeGoals(ctx, agencyID, priorities)
+}
+
+func (c *CompositeService) ConsolidateGoals(ctx context.Context, agencyID string, req agency.ConsolidateGoalsRequest) (*agency.GoalConsolidation, error) {
+	return c.GoalService.ConsolidateGoals(ctx, agencyID, req)
+}
+
+func (c *CompositeService) UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	return c.GoalService.UndoGoalConsolidation(ctx, agencyID, key)
+}
+
 // WorkItem forwarding methods
 
 func (c *CompositeService) CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error) {
@@ -131,3 +175,51 @@ func (c *CompositeService) DeleteWorkItem(ctx context.Context, agencyID string,
 func (c *CompositeService) ValidateWorkItemDependencies(ctx context.Context, agencyID string, workItemCode string, dependencies []string) error {
 	return c.WorkItemService.ValidateDependencies(ctx, agencyID, workItemCode, dependencies)
 }
+
+func (c *CompositeService) TransitionWorkItem(ctx context.Context, agencyID string, key string, req agency.TransitionWorkItemRequest) (*agency.WorkItem, error) {
+	return c.WorkItemService.TransitionWorkItem(ctx, agencyID, key, req)
+}
+
+func (c *CompositeService) AssignWorkItem(ctx context.Context, agencyID string, key string, req agency.AssignWorkItemRequest) (*agency.WorkItem, error) {
+	return c.WorkItemService.AssignWorkItem(ctx, agencyID, key, req)
+}
+
+func (c *CompositeService) GetWorkload(ctx context.Context, agencyID string) (*agency.Workload, error) {
+	return c.WorkItemService.GetWorkload(ctx, agencyID)
+}
+
+// Transfer forwarding methods
+
+func (c *CompositeService) CopyItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest, adapter agency.ItemAdapter) (*agency.TransferResult, error) {
+	return c.TransferService.CopyItems(ctx, targetAgencyID, req, adapter)
+}
+
+func (c *CompositeService) LinkItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest) (*agency.TransferResult, error) {
+	return c.TransferService.LinkItems(ctx, targetAgencyID, req)
+}
+
+// Bundle forwarding methods
+
+func (c *CompositeService) ExportAgency(ctx context.Context, agencyID string) (*agency.AgencyBundle, error) {
+	return c.BundleService.ExportAgency(ctx, agencyID)
+}
+
+func (c *CompositeService) ImportAgency(ctx context.Context, bundle *agency.AgencyBundle) (*agency.ImportResult, error) {
+	return c.BundleService.ImportAgency(ctx, bundle)
+}
+
+// Traceability forwarding methods
+
+func (c *CompositeService) GetTraceability(ctx context.Context, agencyID string) (*agency.TraceabilityReport, error) {
+	return c.TraceabilityService.GetTraceability(ctx, agencyID)
+}
+
+// Explanation forwarding methods
+
+func (c *CompositeService) RecordExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	return c.ExplanationService.RecordExplanation(ctx, explanation)
+}
+
+func (c *CompositeService) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	return c.ExplanationService.GetExplanations(ctx, agencyID, entityType, entityKey)
+}
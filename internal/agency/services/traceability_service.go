@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// TraceabilityService reports how an agency's goals are covered by work items
+type TraceabilityService struct {
+	repo agency.Repository
+}
+
+// NewTraceabilityService creates a new traceability service
+func NewTraceabilityService(repo agency.Repository) *TraceabilityService {
+	return &TraceabilityService{
+		repo: repo,
+	}
+}
+
+// GetTraceability lists, per goal, the work items linked to it and how many
+// of them are done. Goals without work items and work items linked to
+// deleted goals are flagged.
+func (s *TraceabilityService) GetTraceability(ctx context.Context, agencyID string) (*agency.TraceabilityReport, error) {
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	goals, err := s.repo.GetGoals(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goals: %w", err)
+	}
+	workItems, err := s.repo.GetWorkItems(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work items: %w", err)
+	}
+
+	// Goals are reported in their accepted priority order
+	agency.SortGoalsByRank(goals)
+
+	report := &agency.TraceabilityReport{
+		AgencyID:          agencyID,
+		Goals:             make([]*agency.GoalTrace, 0, len(goals)),
+		UntracedGoals:     []string{},
+		OrphanedWorkItems: []*agency.OrphanedWorkItem{},
+	}
+	traces := make(map[string]*agency.GoalTrace, len(goals))
+	for _, goal := range goals {
+		resolveLinkedGoal(ctx, s.repo, goal)
+		trace := &agency.GoalTrace{
+			GoalKey:     goal.Key,
+			GoalCode:    goal.Code,
+			Description: goal.Description,
+			WorkItems:   []*agency.TracedWorkItem{},
+		}
+		traces[goal.Key] = trace
+		report.Goals = append(report.Goals, trace)
+	}
+
+	for _, workItem := range workItems {
+		resolveLinkedWorkItem(ctx, s.repo, workItem)
+
+		var missing []string
+		for _, goalKey := range workItem.GoalKeys {
+			trace, exists := traces[goalKey]
+			if !exists {
+				missing = append(missing, goalKey)
+				continue
+			}
+			status := workItem.CurrentStatus()
+			trace.WorkItems = append(trace.WorkItems, &agency.TracedWorkItem{
+				Key:    workItem.Key,
+				Code:   workItem.Code,
+				Title:  workItem.Title,
+				Status: status,
+			})
+			if status == agency.WorkItemStatusDone {
+				trace.Done++
+			}
+		}
+
+		if len(missing) > 0 {
+			report.OrphanedWorkItems = append(report.OrphanedWorkItems, &agency.OrphanedWorkItem{
+				Key:             workItem.Key,
+				Code:            workItem.Code,
+				Title:           workItem.Title,
+				MissingGoalKeys: missing,
+			})
+		}
+	}
+
+	for _, trace := range report.Goals {
+		if len(trace.WorkItems) == 0 {
+			report.UntracedGoals = append(report.UntracedGoals, trace.GoalCode)
+			continue
+		}
+		trace.CompletionPercent = trace.Done * 100 / len(trace.WorkItems)
+	}
+
+	return report, nil
+}
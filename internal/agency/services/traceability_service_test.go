@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func TestGetTraceability(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	workItems := NewWorkItemService(repo)
+
+	covered := &agency.Goal{AgencyID: "a1", Code: "G-001", Description: "Ship the MVP"}
+	untraced := &agency.Goal{AgencyID: "a1", Code: "G-002", Description: "Grow the user base"}
+	for _, goal := range []*agency.Goal{covered, untraced} {
+		if err := repo.CreateGoal(ctx, goal); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two of three items for G-001 are done; the last one also points at a deleted goal
+	var keys []string
+	for i := 0; i < 3; i++ {
+		goalKeys := []string{covered.Key}
+		if i == 2 {
+			goalKeys = append(goalKeys, "deleted-goal")
+		}
+		workItem, err := workItems.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Task", Description: "Do it", GoalKeys: goalKeys})
+		if err != nil {
+			t.Fatalf("CreateWorkItem failed: %v", err)
+		}
+		keys = append(keys, workItem.Key)
+	}
+	for _, key := range keys[:2] {
+		workItems.TransitionWorkItem(ctx, "a1", key, agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusInProgress})
+		workItems.TransitionWorkItem(ctx, "a1", key, agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusDone})
+	}
+
+	report, err := NewTraceabilityService(repo).GetTraceability(ctx, "a1")
+	if err != nil {
+		t.Fatalf("GetTraceability failed: %v", err)
+	}
+
+	if len(report.Goals) != 2 {
+		t.Fatalf("Expected both goals in the report, got %+v", report.Goals)
+	}
+	trace := report.Goals[0]
+	if trace.GoalCode != "G-001" || len(trace.WorkItems) != 3 || trace.Done != 2 || trace.CompletionPercent != 66 {
+		t.Errorf("Unexpected trace for G-001: %+v", trace)
+	}
+	if len(report.UntracedGoals) != 1 || report.UntracedGoals[0] != "G-002" {
+		t.Errorf("Expected G-002 to be flagged as untraced, got %v", report.UntracedGoals)
+	}
+	if len(report.OrphanedWorkItems) != 1 || report.OrphanedWorkItems[0].Key != keys[2] ||
+		len(report.OrphanedWorkItems[0].MissingGoalKeys) != 1 || report.OrphanedWorkItems[0].MissingGoalKeys[0] != "deleted-goal" {
+		t.Errorf("Expected the third work item to be flagged as orphaned, got %+v", report.OrphanedWorkItems)
+	}
+
+	if _, err := NewTraceabilityService(repo).GetTraceability(ctx, "missing"); err == nil {
+		t.Error("Expected an error for an unknown agency")
+	}
+}
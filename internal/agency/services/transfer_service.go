@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// TransferService handles copying and linking goals and work items between agencies
+type TransferService struct {
+	repo agency.Repository
+}
+
+// NewTransferService creates a new transfer service
+func NewTransferService(repo agency.Repository) *TransferService {
+	return &TransferService{
+		repo: repo,
+	}
+}
+
+// CopyItems copies the selected goals and work items from the source agency
+// into the target agency as independent items with new keys. Dependencies
+// between copied work items are remapped to the new codes. When req.Adapt is
+// set, adapter rewrites each copy for the target agency's context.
+func (s *TransferService) CopyItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest, adapter agency.ItemAdapter) (*agency.TransferResult, error) {
+	if req.Adapt && adapter == nil {
+		return nil, fmt.Errorf("AI adaptation is not available")
+	}
+
+	var adaptCtx *agency.AdaptationContext
+	if req.Adapt {
+		var err error
+		adaptCtx, err = s.adaptationContext(ctx, req.SourceAgencyID, targetAgencyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.transfer(ctx, targetAgencyID, req, agency.ProvenanceModeCopy, func(result *agency.TransferResult, goal *agency.Goal, workItem *agency.WorkItem) {
+		if adaptCtx == nil {
+			return
+		}
+		if goal != nil {
+			if err := adapter.AdaptGoal(ctx, goal, *adaptCtx); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("goal %s was copied without adaptation: %v", goal.Provenance.SourceCode, err))
+				return
+			}
+			goal.Provenance.Adapted = true
+		}
+		if workItem != nil {
+			if err := adapter.AdaptWorkItem(ctx, workItem, *adaptCtx); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("work item %s was copied without adaptation: %v", workItem.Provenance.SourceCode, err))
+				return
+			}
+			workItem.Provenance.Adapted = true
+		}
+	})
+}
+
+// LinkItems creates read-only links in the target agency that mirror the
+// selected source goals and work items. Linked content is refreshed from the
+// source on read and cannot be edited in the target agency.
+func (s *TransferService) LinkItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest) (*agency.TransferResult, error) {
+	return s.transfer(ctx, targetAgencyID, req, agency.ProvenanceModeLink, nil)
+}
+
+// transfer creates the target items. adapt, if set, may modify each item
+// before it is stored.
+func (s *TransferService) transfer(
+	ctx context.Context,
+	targetAgencyID string,
+	req agency.TransferItemsRequest,
+	mode agency.ProvenanceMode,
+	adapt func(result *agency.TransferResult, goal *agency.Goal, workItem *agency.WorkItem),
+) (*agency.TransferResult, error) {
+	if req.SourceAgencyID == "" {
+		return nil, fmt.Errorf("source agency ID is required")
+	}
+	if req.SourceAgencyID == targetAgencyID {
+		return nil, fmt.Errorf("source and target agency must differ")
+	}
+	if len(req.GoalKeys) == 0 && len(req.WorkItemKeys) == 0 {
+		return nil, fmt.Errorf("at least one goal or work item must be selected")
+	}
+
+	// Verify both agencies exist
+	if _, err := s.repo.GetByID(ctx, req.SourceAgencyID); err != nil {
+		return nil, fmt.Errorf("failed to verify source agency: %w", err)
+	}
+	if _, err := s.repo.GetByID(ctx, targetAgencyID); err != nil {
+		return nil, fmt.Errorf("failed to verify target agency: %w", err)
+	}
+
+	// Load all source items before writing anything
+	sourceGoals := make([]*agency.Goal, 0, len(req.GoalKeys))
+	for _, key := range req.GoalKeys {
+		goal, err := s.repo.GetGoal(ctx, req.SourceAgencyID, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get source goal %s: %w", key, err)
+		}
+		sourceGoals = append(sourceGoals, goal)
+	}
+
+	sourceWorkItems := make([]*agency.WorkItem, 0, len(req.WorkItemKeys))
+	for _, key := range req.WorkItemKeys {
+		workItem, err := s.repo.GetWorkItem(ctx, req.SourceAgencyID, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get source work item %s: %w", key, err)
+		}
+		sourceWorkItems = append(sourceWorkItems, workItem)
+	}
+
+	result := &agency.TransferResult{
+		Mode:      mode,
+		Goals:     []*agency.Goal{},
+		WorkItems: []*agency.WorkItem{},
+	}
+	now := time.Now()
+
+	existingGoals, err := s.repo.GetGoals(ctx, targetAgencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target goals: %w", err)
+	}
+	usedCodes := make(map[string]bool, len(existingGoals))
+	for _, goal := range existingGoals {
+		usedCodes[goal.Code] = true
+	}
+
+	for _, src := range sourceGoals {
+		goal := &agency.Goal{
+			AgencyID:       targetAgencyID,
+			Code:           uniqueCode(src.Code, usedCodes),
+			Description:    src.Description,
+			Scope:          src.Scope,
+			SuccessMetrics: src.SuccessMetrics,
+			Priority:       src.Priority,
+			Status:         src.Status,
+			Category:       src.Category,
+			Tags:           src.Tags,
+			Provenance:     newProvenance(mode, req.SourceAgencyID, src.Key, src.Code, now),
+		}
+		usedCodes[goal.Code] = true
+
+		if adapt != nil {
+			adapt(result, goal, nil)
+		}
+
+		if err := s.repo.CreateGoal(ctx, goal); err != nil {
+			return result, fmt.Errorf("failed to create goal from %s: %w", src.Code, err)
+		}
+		result.Goals = append(result.Goals, goal)
+	}
+
+	// Work items get fresh codes in the target agency, so they are created
+	// first and their dependencies remapped once every new code is known
+	codeMap := make(map[string]string, len(sourceWorkItems))
+	for _, src := range sourceWorkItems {
+		workItem := &agency.WorkItem{
+			AgencyID:     targetAgencyID,
+			Title:        src.Title,
+			Description:  src.Description,
+			Deliverables: src.Deliverables,
+			Tags:         src.Tags,
+			Provenance:   newProvenance(mode, req.SourceAgencyID, src.Key, src.Code, now),
+
+			RequiredCapabilities: src.RequiredCapabilities,
+		}
+
+		if adapt != nil {
+			adapt(result, nil, workItem)
+		}
+
+		if err := s.repo.CreateWorkItem(ctx, workItem); err != nil {
+			return result, fmt.Errorf("failed to create work item from %s: %w", src.Code, err)
+		}
+		codeMap[src.Code] = workItem.Code
+		result.WorkItems = append(result.WorkItems, workItem)
+	}
+
+	for i, src := range sourceWorkItems {
+		if len(src.Dependencies) == 0 {
+			continue
+		}
+
+		workItem := result.WorkItems[i]
+		var dropped []string
+		for _, dep := range src.Dependencies {
+			if newCode, ok := codeMap[dep]; ok {
+				workItem.Dependencies = append(workItem.Dependencies, newCode)
+			} else {
+				dropped = append(dropped, dep)
+			}
+		}
+		if len(dropped) > 0 {
+			if result.DroppedDependencies == nil {
+				result.DroppedDependencies = make(map[string][]string)
+			}
+			result.DroppedDependencies[workItem.Code] = dropped
+		}
+		if len(workItem.Dependencies) == 0 {
+			continue
+		}
+
+		if err := s.repo.UpdateWorkItem(ctx, workItem); err != nil {
+			return result, fmt.Errorf("failed to set dependencies for work item %s: %w", workItem.Code, err)
+		}
+	}
+
+	return result, nil
+}
+
+// adaptationContext loads the agencies involved in an AI adaptation
+func (s *TransferService) adaptationContext(ctx context.Context, sourceAgencyID, targetAgencyID string) (*agency.AdaptationContext, error) {
+	source, err := s.repo.GetByID(ctx, sourceAgencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source agency: %w", err)
+	}
+	target, err := s.repo.GetByID(ctx, targetAgencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target agency: %w", err)
+	}
+
+	adaptCtx := &agency.AdaptationContext{Source: source, Target: target}
+	if overview, err := s.repo.GetOverview(ctx, targetAgencyID); err == nil && overview != nil {
+		adaptCtx.TargetIntroduction = overview.Introduction
+	}
+	return adaptCtx, nil
+}
+
+// resolveLinkedGoal refreshes a linked goal's content from its source. The
+// stored content is kept if the source cannot be read.
+func resolveLinkedGoal(ctx context.Context, repo agency.Repository, goal *agency.Goal) {
+	if !goal.Provenance.IsLink() {
+		return
+	}
+
+	src, err := repo.GetGoal(ctx, goal.Provenance.SourceAgencyID, goal.Provenance.SourceKey)
+	if err != nil {
+		goal.Provenance.SourceUnavailable = true
+		return
+	}
+
+	goal.Description = src.Description
+	goal.Scope = src.Scope
+	goal.SuccessMetrics = src.SuccessMetrics
+	goal.Priority = src.Priority
+	goal.Status = src.Status
+	goal.Category = src.Category
+	goal.Tags = src.Tags
+}
+
+// resolveLinkedWorkItem refreshes a linked work item's content from its
+// source. Dependencies are local to the target agency and are not refreshed.
+func resolveLinkedWorkItem(ctx context.Context, repo agency.Repository, workItem *agency.WorkItem) {
+	if !workItem.Provenance.IsLink() {
+		return
+	}
+
+	src, err := repo.GetWorkItem(ctx, workItem.Provenance.SourceAgencyID, workItem.Provenance.SourceKey)
+	if err != nil {
+		workItem.Provenance.SourceUnavailable = true
+		return
+	}
+
+	workItem.Title = src.Title
+	workItem.Description = src.Description
+	workItem.Deliverables = src.Deliverables
+	workItem.Tags = src.Tags
+	workItem.RequiredCapabilities = src.RequiredCapabilities
+}
+
+func newProvenance(mode agency.ProvenanceMode, sourceAgencyID, sourceKey, sourceCode string, at time.Time) *agency.Provenance {
+	return &agency.Provenance{
+		Mode:           mode,
+		SourceAgencyID: sourceAgencyID,
+		SourceKey:      sourceKey,
+		SourceCode:     sourceCode,
+		CreatedAt:      at,
+	}
+}
+
+// uniqueCode returns code, or code with a numeric suffix if it is already used
+func uniqueCode(code string, used map[string]bool) string {
+	if !used[code] {
+		return code
+	}
+	for i := 2; ; i++ {
+		candidate := code + "-" + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// fakeRepo keeps goals and work items in memory. Methods not used by the
+// transfer service panic via the embedded nil interface.
+type fakeRepo struct {
+	agency.Repository
+	agencies  map[string]*agency.Agency
+	goals     map[string][]*agency.Goal
+	workItems map[string][]*agency.WorkItem
+	overviews map[string]*agency.Overview
+	nextKey   int
+
+	explanations   []*agency.Explanation
+	consolidations map[string]*agency.GoalConsolidation
+}
+
+func newFakeRepo(agencyIDs ...string) *fakeRepo {
+	r := &fakeRepo{
+		agencies:  make(map[string]*agency.Agency),
+		goals:     make(map[string][]*agency.Goal),
+		workItems: make(map[string][]*agency.WorkItem),
+	}
+	for _, id := range agencyIDs {
+		r.agencies[id] = &agency.Agency{ID: id, DisplayName: id}
+	}
+	return r
+}
+
+func (r *fakeRepo) GetByID(ctx context.Context, id string) (*agency.Agency, error) {
+	if a, ok := r.agencies[id]; ok {
+		return a, nil
+	}
+	return nil, errors.New("agency not found")
+}
+
+func (r *fakeRepo) GetOverview(ctx context.Context, agencyID string) (*agency.Overview, error) {
+	if o, ok := r.overviews[agencyID]; ok {
+		return o, nil
+	}
+	return nil, errors.New("no overview")
+}
+
+func (r *fakeRepo) key() string {
+	r.nextKey++
+	return fmt.Sprintf("k%d", r.nextKey)
+}
+
+func (r *fakeRepo) CreateGoal(ctx context.Context, goal *agency.Goal) error {
+	goal.Key = r.key()
+	r.goals[goal.AgencyID] = append(r.goals[goal.AgencyID], goal)
+	return nil
+}
+
+func (r *fakeRepo) GetGoals(ctx context.Context, agencyID string) ([]*agency.Goal, error) {
+	return r.goals[agencyID], nil
+}
+
+func (r *fakeRepo) GetGoal(ctx context.Context, agencyID string, key string) (*agency.Goal, error) {
+	for _, g := range r.goals[agencyID] {
+		if g.Key == key {
+			copied := *g
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("goal not found")
+}
+
+func (r *fakeRepo) UpdateGoal(ctx context.Context, goal *agency.Goal) error {
+	for i, g := range r.goals[goal.AgencyID] {
+		if g.Key == goal.Key {
+			r.goals[goal.AgencyID][i] = goal
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) CreateWorkItem(ctx context.Context, workItem *agency.WorkItem) error {
+	workItem.Key = r.key()
+	workItem.Number = len(r.workItems[workItem.AgencyID]) + 1
+	if workItem.Code == "" {
+		workItem.Code = fmt.Sprintf("WI-%03d", workItem.Number)
+	}
+	r.workItems[workItem.AgencyID] = append(r.workItems[workItem.AgencyID], workItem)
+	return nil
+}
+
+func (r *fakeRepo) GetWorkItem(ctx context.Context, agencyID string, key string) (*agency.WorkItem, error) {
+	for _, w := range r.workItems[agencyID] {
+		if w.Key == key {
+			copied := *w
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("work item not found")
+}
+
+func (r *fakeRepo) UpdateWorkItem(ctx context.Context, workItem *agency.WorkItem) error {
+	for i, w := range r.workItems[workItem.AgencyID] {
+		if w.Key == workItem.Key {
+			r.workItems[workItem.AgencyID][i] = workItem
+		}
+	}
+	return nil
+}
+
+type fakeAdapter struct{}
+
+func (fakeAdapter) AdaptGoal(ctx context.Context, goal *agency.Goal, adaptCtx agency.AdaptationContext) error {
+	goal.Description = "adapted for " + adaptCtx.Target.DisplayName
+	return nil
+}
+
+func (fakeAdapter) AdaptWorkItem(ctx context.Context, workItem *agency.WorkItem, adaptCtx agency.AdaptationContext) error {
+	return errors.New("model unavailable")
+}
+
+func seedSource(t *testing.T, repo *fakeRepo) (goalKey string, workItemKeys []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	goal := &agency.Goal{AgencyID: "source", Code: "G-001", Description: "Reduce water loss"}
+	repo.CreateGoal(ctx, goal)
+
+	for _, wi := range []*agency.WorkItem{
+		{AgencyID: "source", Title: "Install meters"},
+		{AgencyID: "source", Title: "Detect leaks", Dependencies: []string{"WI-001", "WI-009"}},
+	} {
+		repo.CreateWorkItem(ctx, wi)
+		workItemKeys = append(workItemKeys, wi.Key)
+	}
+	return goal.Key, workItemKeys
+}
+
+func TestTransferService_CopyItems(t *testing.T) {
+	repo := newFakeRepo("source", "target")
+	goalKey, workItemKeys := seedSource(t, repo)
+	ctx := context.Background()
+
+	// Occupy the target's first work item code and goal code
+	repo.CreateWorkItem(ctx, &agency.WorkItem{AgencyID: "target", Title: "Existing"})
+	repo.CreateGoal(ctx, &agency.Goal{AgencyID: "target", Code: "G-001"})
+
+	service := NewTransferService(repo)
+	result, err := service.CopyItems(ctx, "target", agency.TransferItemsRequest{
+		SourceAgencyID: "source",
+		GoalKeys:       []string{goalKey},
+		WorkItemKeys:   workItemKeys,
+		Adapt:          true,
+	}, fakeAdapter{})
+	if err != nil {
+		t.Fatalf("CopyItems failed: %v", err)
+	}
+
+	goal := result.Goals[0]
+	if goal.Code != "G-001-2" {
+		t.Errorf("expected colliding goal code to be suffixed, got %s", goal.Code)
+	}
+	if goal.Description != "adapted for target" || !goal.Provenance.Adapted {
+		t.Errorf("expected adapted goal, got %q (adapted=%v)", goal.Description, goal.Provenance.Adapted)
+	}
+	if goal.Provenance.Mode != agency.ProvenanceModeCopy || goal.Provenance.SourceKey != goalKey || goal.Provenance.SourceCode != "G-001" {
+		t.Errorf("unexpected provenance: %+v", goal.Provenance)
+	}
+
+	meters, leaks := result.WorkItems[0], result.WorkItems[1]
+	if meters.Code != "WI-002" || leaks.Code != "WI-003" {
+		t.Fatalf("expected new work item codes WI-002/WI-003, got %s/%s", meters.Code, leaks.Code)
+	}
+	if len(leaks.Dependencies) != 1 || leaks.Dependencies[0] != "WI-002" {
+		t.Errorf("expected dependency remapped to WI-002, got %v", leaks.Dependencies)
+	}
+	if dropped := result.DroppedDependencies["WI-003"]; len(dropped) != 1 || dropped[0] != "WI-009" {
+		t.Errorf("expected WI-009 reported as dropped, got %v", result.DroppedDependencies)
+	}
+	if len(result.Warnings) != 2 || leaks.Provenance.Adapted {
+		t.Errorf("expected work item adaptation failures as warnings, got %v", result.Warnings)
+	}
+}
+
+func TestTransferService_LinkItemsAreReadOnly(t *testing.T) {
+	repo := newFakeRepo("source", "target")
+	goalKey, _ := seedSource(t, repo)
+	ctx := context.Background()
+
+	result, err := NewTransferService(repo).LinkItems(ctx, "target", agency.TransferItemsRequest{
+		SourceAgencyID: "source",
+		GoalKeys:       []string{goalKey},
+	})
+	if err != nil {
+		t.Fatalf("LinkItems failed: %v", err)
+	}
+	linkKey := result.Goals[0].Key
+
+	// Linked content follows the source
+	repo.goals["source"][0].Description = "Reduce water loss by 20%"
+	goals := NewGoalService(repo)
+	linked, err := goals.GetGoal(ctx, "target", linkKey)
+	if err != nil {
+		t.Fatalf("GetGoal failed: %v", err)
+	}
+	if linked.Description != "Reduce water loss by 20%" {
+		t.Errorf("expected linked goal to mirror source, got %q", linked.Description)
+	}
+
+	if err := goals.UpdateGoal(ctx, "target", linkKey, "G-009", "edited"); !errors.Is(err, agency.ErrReadOnlyLink) {
+		t.Errorf("expected ErrReadOnlyLink, got %v", err)
+	}
+
+	// Source removed: last known content is kept and flagged
+	repo.goals["source"] = nil
+	linked, err = goals.GetGoal(ctx, "target", linkKey)
+	if err != nil {
+		t.Fatalf("GetGoal failed: %v", err)
+	}
+	if !linked.Provenance.SourceUnavailable {
+		t.Error("expected SourceUnavailable when the source goal is gone")
+	}
+}
+
+func TestTransferService_Validation(t *testing.T) {
+	repo := newFakeRepo("source", "target")
+	service := NewTransferService(repo)
+	ctx := context.Background()
+
+	cases := map[string]agency.TransferItemsRequest{
+		"same agency":    {SourceAgencyID: "target", GoalKeys: []string{"k1"}},
+		"nothing":        {SourceAgencyID: "source"},
+		"unknown agency": {SourceAgencyID: "missing", GoalKeys: []string{"k1"}},
+		"unknown item":   {SourceAgencyID: "source", GoalKeys: []string{"nope"}},
+	}
+	for name, req := range cases {
+		if _, err := service.LinkItems(ctx, "target", req); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := service.CopyItems(ctx, "target", agency.TransferItemsRequest{SourceAgencyID: "source", GoalKeys: []string{"k1"}, Adapt: true}, nil); err == nil {
+		t.Error("expected error when adaptation is requested without an adapter")
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	log "github.com/sirupsen/logrus"
+)
+
+// policyValidator applies the agency validation policy. One instance is
+// shared by the sub-services so that a policy set on the composite service
+// reaches all of them. A nil validator checks nothing.
+type policyValidator struct {
+	repo       agency.Repository
+	policy     agency.ValidationPolicy
+	duplicates agency.DuplicateChecker
+}
+
+// violations collects the violations found for a change
+type violations []agency.Violation
+
+func (v *violations) add(field, rule, format string, args ...interface{}) *agency.Violation {
+	*v = append(*v, agency.Violation{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	return &(*v)[len(*v)-1]
+}
+
+func (v violations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &agency.ValidationError{Violations: v}
+}
+
+// checkGoal validates a goal being created (key empty) or updated
+func (p *policyValidator) checkGoal(ctx context.Context, agencyID, key, description string) error {
+	if p == nil || !p.policy.Strict {
+		return nil
+	}
+
+	goals, err := p.repo.GetGoals(ctx, agencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get goals: %w", err)
+	}
+
+	var found violations
+	if key == "" && p.policy.MaxGoalsPerAgency > 0 && len(goals) >= p.policy.MaxGoalsPerAgency {
+		found.add("code", agency.RuleMaxGoals, "agency already has the maximum of %d goals", p.policy.MaxGoalsPerAgency)
+	}
+	p.checkDescriptionLength(&found, description)
+
+	candidates := make([]agency.DescriptionCandidate, 0, len(goals))
+	for _, goal := range goals {
+		if goal.Key != key {
+			candidates = append(candidates, agency.DescriptionCandidate{Key: goal.Key, Code: goal.Code, Description: goal.Description})
+		}
+	}
+	p.checkDuplicates(ctx, &found, "goal", description, candidates)
+
+	return found.err()
+}
+
+// checkWorkItem validates a work item being created (key empty) or updated
+func (p *policyValidator) checkWorkItem(ctx context.Context, agencyID, key string, item *agency.WorkItem) error {
+	if p == nil || !p.policy.Strict {
+		return nil
+	}
+
+	workItems, err := p.repo.GetWorkItems(ctx, agencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get work items: %w", err)
+	}
+
+	var found violations
+	p.checkDescriptionLength(&found, item.Description)
+
+	if p.policy.MaxWorkItemsPerGoal > 0 {
+		goals, err := p.repo.GetGoals(ctx, agencyID)
+		if err != nil {
+			return fmt.Errorf("failed to get goals: %w", err)
+		}
+		for _, goal := range goals {
+			if !agency.WorkItemReferencesGoal(item, goal) {
+				continue
+			}
+			count := 1
+			for _, other := range workItems {
+				if other.Key != key && agency.WorkItemReferencesGoal(other, goal) {
+					count++
+				}
+			}
+			if count > p.policy.MaxWorkItemsPerGoal {
+				found.add("description", agency.RuleMaxWorkItemsPerGoal,
+					"goal %s already has the maximum of %d work items", goal.Code, p.policy.MaxWorkItemsPerGoal).RelatedKey = goal.Key
+			}
+		}
+	}
+
+	candidates := make([]agency.DescriptionCandidate, 0, len(workItems))
+	for _, other := range workItems {
+		if other.Key != key {
+			candidates = append(candidates, agency.DescriptionCandidate{Key: other.Key, Code: other.Code, Description: other.Description})
+		}
+	}
+	p.checkDuplicates(ctx, &found, "work item", item.Description, candidates)
+
+	return found.err()
+}
+
+// checkActivation validates that an agency becoming active has the fields
+// the policy requires
+func (p *policyValidator) checkActivation(ctx context.Context, agencyDoc *agency.Agency) error {
+	if p == nil || !p.policy.Strict {
+		return nil
+	}
+
+	var found violations
+	for _, field := range p.policy.RequiredForActivation {
+		var missing bool
+		switch field {
+		case agency.ActivationFieldDescription:
+			missing = strings.TrimSpace(agencyDoc.Description) == ""
+		case agency.ActivationFieldIcon:
+			missing = agencyDoc.Icon == ""
+		case agency.ActivationFieldLocation:
+			missing = agencyDoc.Metadata.Location == ""
+		case agency.ActivationFieldTags:
+			missing = len(agencyDoc.Metadata.Tags) == 0
+		case agency.ActivationFieldOverview:
+			overview, err := p.repo.GetOverview(ctx, agencyDoc.ID)
+			missing = err != nil || strings.TrimSpace(overview.Introduction) == ""
+		case agency.ActivationFieldGoals:
+			goals, err := p.repo.GetGoals(ctx, agencyDoc.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get goals: %w", err)
+			}
+			missing = len(goals) == 0
+		case agency.ActivationFieldWorkItems:
+			workItems, err := p.repo.GetWorkItems(ctx, agencyDoc.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get work items: %w", err)
+			}
+			missing = len(workItems) == 0
+		default:
+			continue
+		}
+		if missing {
+			found.add(field, agency.RuleRequiredForActivation, "%s is required before the agency can be activated", strings.ReplaceAll(field, "_", " "))
+		}
+	}
+
+	return found.err()
+}
+
+// checkDescriptionLength checks a goal or work item description against the length bounds
+func (p *policyValidator) checkDescriptionLength(found *violations, description string) {
+	length := utf8.RuneCountInString(strings.TrimSpace(description))
+	if p.policy.MinDescriptionLength > 0 && length < p.policy.MinDescriptionLength {
+		found.add("description", agency.RuleDescriptionMinLength, "description must be at least %d characters", p.policy.MinDescriptionLength)
+	}
+	if p.policy.MaxDescriptionLength > 0 && length > p.policy.MaxDescriptionLength {
+		found.add("description", agency.RuleDescriptionMaxLength, "description must be at most %d characters", p.policy.MaxDescriptionLength)
+	}
+}
+
+// checkDuplicates reports candidates whose description overlaps the new one
+// by at least the policy's similarity. When word overlap finds none, the
+// duplicate checker is asked for paraphrases; its failures do not block the
+// change.
+func (p *policyValidator) checkDuplicates(ctx context.Context, found *violations, kind, description string, candidates []agency.DescriptionCandidate) {
+	threshold := p.policy.DuplicateSimilarity
+	if threshold <= 0 || len(candidates) == 0 || strings.TrimSpace(description) == "" {
+		return
+	}
+
+	byKey := make(map[string]agency.DescriptionCandidate, len(candidates))
+	var matches []agency.DuplicateMatch
+	for _, candidate := range candidates {
+		byKey[candidate.Key] = candidate
+		if similarity := agency.DescriptionSimilarity(description, candidate.Description); similarity >= threshold {
+			matches = append(matches, agency.DuplicateMatch{Key: candidate.Key, Similarity: similarity})
+		}
+	}
+
+	if len(matches) == 0 && p.duplicates != nil {
+		aiMatches, err := p.duplicates.FindDuplicates(ctx, description, candidates)
+		if err != nil {
+			log.WithError(err).Warn("Near-duplicate check failed, relying on word overlap")
+		}
+		for _, match := range aiMatches {
+			if _, known := byKey[match.Key]; known && match.Similarity >= threshold {
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	for _, match := range matches {
+		v := found.add("description", agency.RuleDuplicateDescription,
+			"description duplicates %s %s (%.0f%% similar)", kind, byKey[match.Key].Code, match.Similarity*100)
+		v.RelatedKey = match.Key
+		v.Similarity = match.Similarity
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func (r *fakeRepo) GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error) {
+	return r.workItems[agencyID], nil
+}
+
+type fakeDuplicateChecker struct {
+	matches []agency.DuplicateMatch
+	calls   int
+}
+
+func (f *fakeDuplicateChecker) FindDuplicates(ctx context.Context, description string, candidates []agency.DescriptionCandidate) ([]agency.DuplicateMatch, error) {
+	f.calls++
+	return f.matches, nil
+}
+
+func newStrictService(repo *fakeRepo, policy agency.ValidationPolicy, duplicates agency.DuplicateChecker) *CompositeService {
+	policy.Strict = true
+	service := New(repo, agency.NewValidator()).(*CompositeService)
+	service.SetValidationPolicy(policy, duplicates)
+	return service
+}
+
+// violationRules returns the rules of a validation error's violations
+func violationRules(t *testing.T, err error) []string {
+	t.Helper()
+	var validationErr *agency.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	rules := make([]string, len(validationErr.Violations))
+	for i, v := range validationErr.Violations {
+		rules[i] = v.Rule
+	}
+	return rules
+}
+
+func TestStrictValidation_Goals(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := newStrictService(repo, agency.ValidationPolicy{
+		MaxGoalsPerAgency:    2,
+		MinDescriptionLength: 10,
+		MaxDescriptionLength: 60,
+		DuplicateSimilarity:  0.7,
+	}, nil)
+
+	if _, err := service.CreateGoal(ctx, "a1", "G001", "Reduce water outages in the northern zone"); err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+
+	_, err := service.CreateGoal(ctx, "a1", "G002", "Short")
+	if rules := violationRules(t, err); len(rules) != 1 || rules[0] != agency.RuleDescriptionMinLength {
+		t.Errorf("Expected a min length violation, got %v", rules)
+	}
+
+	_, err = service.CreateGoal(ctx, "a1", "G002", "Reduce outages of water in the northern zone")
+	var validationErr *agency.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Violations[0].Rule != agency.RuleDuplicateDescription {
+		t.Fatalf("Expected a duplicate violation, got %v", err)
+	}
+	if validationErr.Violations[0].RelatedKey != repo.goals["a1"][0].Key {
+		t.Errorf("Expected the duplicate to point at G001, got %+v", validationErr.Violations[0])
+	}
+
+	if _, err := service.CreateGoal(ctx, "a1", "G002", "Publish monthly billing reports"); err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+	_, err = service.CreateGoal(ctx, "a1", "G003", "Automate meter reading for customers")
+	if rules := violationRules(t, err); len(rules) != 1 || rules[0] != agency.RuleMaxGoals {
+		t.Errorf("Expected a max goals violation, got %v", rules)
+	}
+
+	// Updating a goal does not count it as its own duplicate or against the limit
+	g1 := repo.goals["a1"][0]
+	if err := service.UpdateGoal(ctx, "a1", g1.Key, "G001", "Reduce water outages in the northern zone quickly"); err != nil {
+		t.Errorf("UpdateGoal failed: %v", err)
+	}
+}
+
+func TestStrictValidation_WorkItemsPerGoal(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := newStrictService(repo, agency.ValidationPolicy{MaxWorkItemsPerGoal: 1}, nil)
+
+	if _, err := service.CreateGoal(ctx, "a1", "G001", "Reduce water outages"); err != nil {
+		t.Fatalf("CreateGoal failed: %v", err)
+	}
+	if _, err := service.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Install sensors", Description: "Sensors for G001"}); err != nil {
+		t.Fatalf("CreateWorkItem failed: %v", err)
+	}
+
+	_, err := service.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Repair mains", Description: "Repairs", Tags: []string{"G001"}})
+	if rules := violationRules(t, err); len(rules) != 1 || rules[0] != agency.RuleMaxWorkItemsPerGoal {
+		t.Errorf("Expected a max work items per goal violation, got %v", rules)
+	}
+
+	if _, err := service.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Repair mains", Description: "Repairs"}); err != nil {
+		t.Errorf("Expected work item without a goal reference to pass, got %v", err)
+	}
+}
+
+func TestStrictValidation_AIDuplicateCheck(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	checker := &fakeDuplicateChecker{}
+	service := newStrictService(repo, agency.ValidationPolicy{DuplicateSimilarity: 0.8}, checker)
+
+	g1, _ := service.CreateGoal(ctx, "a1", "G001", "Cut non-revenue water losses")
+	checker.matches = []agency.DuplicateMatch{{Key: g1.Key, Similarity: 0.9}, {Key: "invented", Similarity: 1}}
+
+	_, err := service.CreateGoal(ctx, "a1", "G002", "Stop leaks that nobody is billed for")
+	var validationErr *agency.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 || validationErr.Violations[0].RelatedKey != g1.Key {
+		t.Fatalf("Expected one AI duplicate violation for G001, got %v", err)
+	}
+	if checker.calls != 1 {
+		t.Errorf("Expected the checker to be asked once, got %d", checker.calls)
+	}
+}
+
+func TestStrictValidation_Activation(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo()
+	repo.agencies["a1"] = &agency.Agency{
+		ID:          "agency_0123456789abcdef0123456789abcdef",
+		Name:        "water",
+		DisplayName: "Water",
+		Category:    "infrastructure",
+		Status:      agency.AgencyStatusInactive,
+	}
+	service := newStrictService(repo, agency.ValidationPolicy{
+		RequiredForActivation: []string{agency.ActivationFieldDescription, agency.ActivationFieldGoals},
+	}, nil)
+
+	active := agency.AgencyStatusActive
+	err := service.AgencyService.UpdateAgency(ctx, "a1", agency.AgencyUpdates{Status: &active})
+	rules := violationRules(t, err)
+	if len(rules) != 2 || rules[0] != agency.RuleRequiredForActivation {
+		t.Errorf("Expected two required-for-activation violations, got %v", rules)
+	}
+	if !errors.Is(err, agency.ErrValidation) {
+		t.Error("Expected the error to match ErrValidation")
+	}
+}
+
+func TestPolicyOff_NoChecks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := New(repo, agency.NewValidator())
+
+	service.CreateGoal(ctx, "a1", "G001", "Reduce water outages")
+	if _, err := service.CreateGoal(ctx, "a1", "G002", "Reduce water outages"); err != nil {
+		t.Errorf("Expected no validation without a policy, got %v", err)
+	}
+}
@@ -3,13 +3,16 @@ package services
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
 )
 
 // WorkItemService handles work item operations
 type WorkItemService struct {
-	repo agency.Repository
+	repo       agency.Repository
+	validation *policyValidator
 }
 
 // NewWorkItemService creates a new work item service
@@ -41,6 +44,14 @@ func (s *WorkItemService) CreateWorkItem(ctx context.Context, agencyID string, r
 		Deliverables: req.Deliverables,
 		Dependencies: req.Dependencies,
 		Tags:         req.Tags,
+		Status:       agency.WorkItemStatusTodo,
+
+		RequiredCapabilities: req.RequiredCapabilities,
+		GoalKeys:             req.GoalKeys,
+	}
+
+	if err := s.validation.checkWorkItem(ctx, agencyID, "", workItem); err != nil {
+		return nil, err
 	}
 
 	if err := s.repo.CreateWorkItem(ctx, workItem); err != nil {
@@ -63,6 +74,10 @@ func (s *WorkItemService) GetWorkItems(ctx context.Context, agencyID string) ([]
 		return nil, fmt.Errorf("failed to get work items: %w", err)
 	}
 
+	for _, workItem := range workItems {
+		resolveLinkedWorkItem(ctx, s.repo, workItem)
+	}
+
 	return workItems, nil
 }
 
@@ -79,6 +94,8 @@ func (s *WorkItemService) GetWorkItem(ctx context.Context, agencyID string, key
 		return nil, fmt.Errorf("failed to get work item: %w", err)
 	}
 
+	resolveLinkedWorkItem(ctx, s.repo, workItem)
+
 	return workItem, nil
 }
 
@@ -95,6 +112,8 @@ func (s *WorkItemService) GetWorkItemByCode(ctx context.Context, agencyID string
 		return nil, fmt.Errorf("failed to get work item: %w", err)
 	}
 
+	resolveLinkedWorkItem(ctx, s.repo, workItem)
+
 	return workItem, nil
 }
 
@@ -111,6 +130,9 @@ func (s *WorkItemService) UpdateWorkItem(ctx context.Context, agencyID string, k
 	if err != nil {
 		return fmt.Errorf("failed to get work item: %w", err)
 	}
+	if workItem.Provenance.IsLink() {
+		return agency.ErrReadOnlyLink
+	}
 
 	// Validate dependencies if changed
 	if len(req.Dependencies) > 0 {
@@ -125,6 +147,15 @@ func (s *WorkItemService) UpdateWorkItem(ctx context.Context, agencyID string, k
 	workItem.Deliverables = req.Deliverables
 	workItem.Dependencies = req.Dependencies
 	workItem.Tags = req.Tags
+	workItem.RequiredCapabilities = req.RequiredCapabilities
+	// Goal links are kept when the request leaves them out
+	if req.GoalKeys != nil {
+		workItem.GoalKeys = req.GoalKeys
+	}
+
+	if err := s.validation.checkWorkItem(ctx, agencyID, key, workItem); err != nil {
+		return err
+	}
 
 	// Save
 	if err := s.repo.UpdateWorkItem(ctx, workItem); err != nil {
@@ -134,6 +165,125 @@ func (s *WorkItemService) UpdateWorkItem(ctx context.Context, agencyID string, k
 	return nil
 }
 
+// TransitionWorkItem moves a work item to another Kanban status and records
+// the transition. The status is local to the agency, so linked work items
+// can be moved too.
+func (s *WorkItemService) TransitionWorkItem(ctx context.Context, agencyID string, key string, req agency.TransitionWorkItemRequest) (*agency.WorkItem, error) {
+	if !req.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", agency.ErrInvalidTransition, req.Status)
+	}
+
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	workItem, err := s.repo.GetWorkItem(ctx, agencyID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work item: %w", err)
+	}
+
+	current := workItem.CurrentStatus()
+	if !current.CanTransitionTo(req.Status) {
+		return nil, fmt.Errorf("%w: %s to %s", agency.ErrInvalidTransition, current, req.Status)
+	}
+
+	workItem.Status = req.Status
+	workItem.StatusHistory = append(workItem.StatusHistory, agency.WorkItemTransition{
+		From:   current,
+		To:     req.Status,
+		Reason: req.Reason,
+		At:     time.Now(),
+	})
+
+	if err := s.repo.UpdateWorkItem(ctx, workItem); err != nil {
+		return nil, fmt.Errorf("failed to update work item status: %w", err)
+	}
+
+	resolveLinkedWorkItem(ctx, s.repo, workItem)
+
+	return workItem, nil
+}
+
+// AssignWorkItem sets the agent carrying out a work item, or clears it when
+// req.AgentID is empty. Like the status, the assignee is local to the agency.
+func (s *WorkItemService) AssignWorkItem(ctx context.Context, agencyID string, key string, req agency.AssignWorkItemRequest) (*agency.WorkItem, error) {
+	// Verify agency exists
+	_, err := s.repo.GetByID(ctx, agencyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify agency: %w", err)
+	}
+
+	workItem, err := s.repo.GetWorkItem(ctx, agencyID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work item: %w", err)
+	}
+
+	workItem.Assignee = req.AgentID
+	workItem.AssignedAt = nil
+	if req.AgentID != "" {
+		now := time.Now()
+		workItem.AssignedAt = &now
+	}
+
+	if err := s.repo.UpdateWorkItem(ctx, workItem); err != nil {
+		return nil, fmt.Errorf("failed to update work item assignee: %w", err)
+	}
+
+	resolveLinkedWorkItem(ctx, s.repo, workItem)
+
+	return workItem, nil
+}
+
+// GetWorkload summarizes the open work items of an agency per assigned agent
+func (s *WorkItemService) GetWorkload(ctx context.Context, agencyID string) (*agency.Workload, error) {
+	workItems, err := s.GetWorkItems(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+
+	workload := &agency.Workload{
+		AgencyID:   agencyID,
+		Agents:     []*agency.AgentWorkload{},
+		Unassigned: []string{},
+	}
+	agents := make(map[string]*agency.AgentWorkload)
+	for _, workItem := range workItems {
+		status := workItem.CurrentStatus()
+		if status == agency.WorkItemStatusDone {
+			continue
+		}
+		if workItem.Assignee == "" {
+			workload.Unassigned = append(workload.Unassigned, workItem.Code)
+			continue
+		}
+
+		agentWorkload, exists := agents[workItem.Assignee]
+		if !exists {
+			agentWorkload = &agency.AgentWorkload{
+				AgentID:   workItem.Assignee,
+				ByStatus:  make(map[agency.WorkItemStatus]int),
+				WorkItems: []string{},
+			}
+			agents[workItem.Assignee] = agentWorkload
+			workload.Agents = append(workload.Agents, agentWorkload)
+		}
+		agentWorkload.Open++
+		agentWorkload.ByStatus[status]++
+		agentWorkload.WorkItems = append(agentWorkload.WorkItems, workItem.Code)
+	}
+
+	sort.SliceStable(workload.Agents, func(i, j int) bool {
+		if workload.Agents[i].Open != workload.Agents[j].Open {
+			return workload.Agents[i].Open > workload.Agents[j].Open
+		}
+		return workload.Agents[i].AgentID < workload.Agents[j].AgentID
+	})
+
+	return workload, nil
+}
+
 // DeleteWorkItem deletes a work item
 func (s *WorkItemService) DeleteWorkItem(ctx context.Context, agencyID string, key string) error {
 	// Verify agency exists
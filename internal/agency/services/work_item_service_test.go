@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+func TestTransitionWorkItem(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewWorkItemService(repo)
+
+	workItem, err := service.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Install meters", Description: "Fit smart meters"})
+	if err != nil {
+		t.Fatalf("CreateWorkItem failed: %v", err)
+	}
+	if workItem.Status != agency.WorkItemStatusTodo {
+		t.Fatalf("Expected new work item in todo, got %q", workItem.Status)
+	}
+
+	for _, status := range []agency.WorkItemStatus{agency.WorkItemStatusInProgress, agency.WorkItemStatusBlocked, agency.WorkItemStatusInProgress, agency.WorkItemStatusDone} {
+		if _, err := service.TransitionWorkItem(ctx, "a1", workItem.Key, agency.TransitionWorkItemRequest{Status: status, Reason: "moved by agent"}); err != nil {
+			t.Fatalf("Transition to %s failed: %v", status, err)
+		}
+	}
+
+	stored, _ := service.GetWorkItem(ctx, "a1", workItem.Key)
+	if stored.Status != agency.WorkItemStatusDone || len(stored.StatusHistory) != 4 {
+		t.Fatalf("Expected done with 4 transitions, got %q with %v", stored.Status, stored.StatusHistory)
+	}
+	last := stored.StatusHistory[3]
+	if last.From != agency.WorkItemStatusInProgress || last.To != agency.WorkItemStatusDone || last.At.IsZero() || last.Reason != "moved by agent" {
+		t.Errorf("Unexpected transition record %+v", last)
+	}
+
+	// Done work items can only be reopened
+	_, err = service.TransitionWorkItem(ctx, "a1", workItem.Key, agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusBlocked})
+	if !errors.Is(err, agency.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestTransitionWorkItem_LegacyItemStartsInTodo(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	legacy := &agency.WorkItem{AgencyID: "a1", Title: "Detect leaks"}
+	repo.CreateWorkItem(ctx, legacy)
+
+	service := NewWorkItemService(repo)
+	if _, err := service.TransitionWorkItem(ctx, "a1", legacy.Key, agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusDone}); !errors.Is(err, agency.ErrInvalidTransition) {
+		t.Errorf("Expected todo to done to be rejected, got %v", err)
+	}
+	workItem, err := service.TransitionWorkItem(ctx, "a1", legacy.Key, agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusInProgress})
+	if err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	if workItem.StatusHistory[0].From != agency.WorkItemStatusTodo {
+		t.Errorf("Expected legacy item to move from todo, got %+v", workItem.StatusHistory[0])
+	}
+}
+
+func TestGetWorkload(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepo("a1")
+	service := NewWorkItemService(repo)
+
+	keys := make([]string, 4)
+	for i := range keys {
+		workItem, err := service.CreateWorkItem(ctx, "a1", agency.CreateWorkItemRequest{Title: "Task", Description: "Do it"})
+		if err != nil {
+			t.Fatalf("CreateWorkItem failed: %v", err)
+		}
+		keys[i] = workItem.Key
+	}
+
+	// analyst-1 holds two open items and a finished one; WI-004 is unassigned
+	for _, assignment := range []struct{ key, agent string }{
+		{keys[0], "analyst-1"}, {keys[1], "analyst-1"}, {keys[2], "analyst-1"}, {keys[3], "planner-1"},
+	} {
+		if _, err := service.AssignWorkItem(ctx, "a1", assignment.key, agency.AssignWorkItemRequest{AgentID: assignment.agent}); err != nil {
+			t.Fatalf("AssignWorkItem failed: %v", err)
+		}
+	}
+	service.TransitionWorkItem(ctx, "a1", keys[0], agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusInProgress})
+	service.TransitionWorkItem(ctx, "a1", keys[2], agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusInProgress})
+	service.TransitionWorkItem(ctx, "a1", keys[2], agency.TransitionWorkItemRequest{Status: agency.WorkItemStatusDone})
+
+	unassigned, err := service.AssignWorkItem(ctx, "a1", keys[3], agency.AssignWorkItemRequest{})
+	if err != nil {
+		t.Fatalf("AssignWorkItem failed: %v", err)
+	}
+	if unassigned.Assignee != "" || unassigned.AssignedAt != nil {
+		t.Errorf("Expected the work item to be unassigned, got %+v", unassigned)
+	}
+
+	workload, err := service.GetWorkload(ctx, "a1")
+	if err != nil {
+		t.Fatalf("GetWorkload failed: %v", err)
+	}
+	if len(workload.Agents) != 1 {
+		t.Fatalf("Expected one agent with open work, got %+v", workload.Agents)
+	}
+	analyst := workload.Agents[0]
+	if analyst.AgentID != "analyst-1" || analyst.Open != 2 ||
+		analyst.ByStatus[agency.WorkItemStatusInProgress] != 1 || analyst.ByStatus[agency.WorkItemStatusTodo] != 1 {
+		t.Errorf("Unexpected workload %+v", analyst)
+	}
+	if len(workload.Unassigned) != 1 || workload.Unassigned[0] != "WI-004" {
+		t.Errorf("Expected WI-004 unassigned, got %v", workload.Unassigned)
+	}
+}
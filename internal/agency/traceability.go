@@ -0,0 +1,43 @@
+package agency
+
+// TracedWorkItem is a work item linked to a goal in a traceability report
+type TracedWorkItem struct {
+	Key    string         `json:"key"`
+	Code   string         `json:"code"`
+	Title  string         `json:"title"`
+	Status WorkItemStatus `json:"status"`
+}
+
+// GoalTrace lists the work items linked to a goal and how many are done
+type GoalTrace struct {
+	GoalKey     string            `json:"goal_key"`
+	GoalCode    string            `json:"goal_code"`
+	Description string            `json:"description"`
+	WorkItems   []*TracedWorkItem `json:"work_items"`
+	Done        int               `json:"done"`
+
+	// CompletionPercent is the share of linked work items that are done,
+	// rounded down. It is 0 for goals without linked work items.
+	CompletionPercent int `json:"completion_percent"`
+}
+
+// OrphanedWorkItem is a work item linked to goals that no longer exist
+type OrphanedWorkItem struct {
+	Key             string   `json:"key"`
+	Code            string   `json:"code"`
+	Title           string   `json:"title"`
+	MissingGoalKeys []string `json:"missing_goal_keys"`
+}
+
+// TraceabilityReport traces an agency's goals to the work items that
+// deliver them
+type TraceabilityReport struct {
+	AgencyID string       `json:"agency_id"`
+	Goals    []*GoalTrace `json:"goals"`
+
+	// UntracedGoals lists the codes of goals without linked work items
+	UntracedGoals []string `json:"untraced_goals"`
+
+	// OrphanedWorkItems lists work items linked to deleted goals
+	OrphanedWorkItems []*OrphanedWorkItem `json:"orphaned_work_items"`
+}
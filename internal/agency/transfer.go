@@ -0,0 +1,79 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrReadOnlyLink is returned when modifying an item linked from another agency
+var ErrReadOnlyLink = errors.New("item is a read-only link to another agency")
+
+// ProvenanceMode describes how an item arrived in an agency
+type ProvenanceMode string
+
+const (
+	// ProvenanceModeCopy marks an independent copy of a source item
+	ProvenanceModeCopy ProvenanceMode = "copy"
+	// ProvenanceModeLink marks a read-only reference that mirrors a source item
+	ProvenanceModeLink ProvenanceMode = "link"
+)
+
+// Provenance records where a copied or linked goal/work item came from
+type Provenance struct {
+	Mode           ProvenanceMode `json:"mode"`
+	SourceAgencyID string         `json:"source_agency_id"`
+	SourceKey      string         `json:"source_key"`
+	SourceCode     string         `json:"source_code"`
+	Adapted        bool           `json:"adapted,omitempty"` // Content was rewritten by AI for the target agency
+	CreatedAt      time.Time      `json:"created_at"`
+
+	// SourceUnavailable is set on read when a linked source item can no
+	// longer be resolved; the last known content is shown instead
+	SourceUnavailable bool `json:"source_unavailable,omitempty"`
+}
+
+// IsLink reports whether the provenance marks a read-only link
+func (p *Provenance) IsLink() bool {
+	return p != nil && p.Mode == ProvenanceModeLink
+}
+
+// TransferItemsRequest selects goals and work items to copy or link from a
+// source agency
+type TransferItemsRequest struct {
+	SourceAgencyID string   `json:"source_agency_id" binding:"required"`
+	GoalKeys       []string `json:"goal_keys"`
+	WorkItemKeys   []string `json:"work_item_keys"`
+
+	// Adapt asks the AI to rewrite copied items for the target agency's
+	// context. Ignored for links.
+	Adapt bool `json:"adapt"`
+}
+
+// TransferResult reports the items created by a copy or link operation
+type TransferResult struct {
+	Mode      ProvenanceMode `json:"mode"`
+	Goals     []*Goal        `json:"goals"`
+	WorkItems []*WorkItem    `json:"work_items"`
+
+	// DroppedDependencies lists, per new work item code, dependencies on
+	// source work items that were not part of the transfer
+	DroppedDependencies map[string][]string `json:"dropped_dependencies,omitempty"`
+
+	// Warnings are non-fatal issues such as failed AI adaptation
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// AdaptationContext describes the agencies involved in an AI adaptation
+type AdaptationContext struct {
+	Source             *Agency
+	Target             *Agency
+	TargetIntroduction string
+}
+
+// ItemAdapter rewrites copied goals and work items to fit a target agency.
+// Implementations update the item's content fields in place.
+type ItemAdapter interface {
+	AdaptGoal(ctx context.Context, goal *Goal, adaptCtx AdaptationContext) error
+	AdaptWorkItem(ctx context.Context, workItem *WorkItem, adaptCtx AdaptationContext) error
+}
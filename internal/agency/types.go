@@ -14,7 +14,8 @@ type Agency struct {
 	Category    string         `json:"category"`
 	Icon        string         `json:"icon"`
 	Status      AgencyStatus   `json:"status"`
-	Database    string         `json:"database"` // Database name for this agency
+	Database    string         `json:"database"`            // Database name for this agency
+	TenantID    string         `json:"tenant_id,omitempty"` // Tenant that owns the agency; empty for operator agencies
 	Metadata    AgencyMetadata `json:"metadata"`
 	Settings    AgencySettings `json:"settings"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -56,6 +57,7 @@ type AgencyFilters struct {
 	Status   AgencyStatus
 	Search   string // Search in name/description
 	Tags     []string
+	TenantID string // Only agencies of this tenant
 	Limit    int
 	Offset   int
 }
@@ -132,6 +134,16 @@ type Goal struct {
 	Tags           []string  `json:"tags"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Rank is the goal's position in the agency's accepted priority order,
+	// starting at 1. Zero means the goal has not been ranked.
+	Rank int `json:"rank,omitempty"`
+
+	// PriorityRationale explains the accepted rank and priority
+	PriorityRationale string `json:"priority_rationale,omitempty"`
+
+	// Provenance is set when the goal was copied or linked from another agency
+	Provenance *Provenance `json:"provenance,omitempty"`
 }
 
 // CreateGoalRequest is the request body for creating a goal
@@ -184,24 +196,46 @@ type WorkItem struct {
 	Tags         []string  `json:"tags,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// RequiredCapabilities lists capability IDs an agent needs to carry out the work item
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+
+	// GoalKeys lists the keys of the goals the work item contributes to
+	GoalKeys []string `json:"goal_keys,omitempty"`
+
+	// Status is the work item's Kanban column. StatusHistory records every
+	// transition, oldest first.
+	Status        WorkItemStatus       `json:"status,omitempty"`
+	StatusHistory []WorkItemTransition `json:"status_history,omitempty"`
+
+	// Assignee is the ID of the agent carrying out the work item
+	Assignee   string     `json:"assignee,omitempty"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+
+	// Provenance is set when the work item was copied or linked from another agency
+	Provenance *Provenance `json:"provenance,omitempty"`
 }
 
 // CreateWorkItemRequest is the request body for creating a work item
 type CreateWorkItemRequest struct {
-	Title        string   `json:"title" binding:"required"`
-	Description  string   `json:"description" binding:"required"`
-	Deliverables []string `json:"deliverables"`
-	Dependencies []string `json:"dependencies"`
-	Tags         []string `json:"tags,omitempty"`
+	Title                string   `json:"title" binding:"required"`
+	Description          string   `json:"description" binding:"required"`
+	Deliverables         []string `json:"deliverables"`
+	Dependencies         []string `json:"dependencies"`
+	Tags                 []string `json:"tags,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+	GoalKeys             []string `json:"goal_keys,omitempty"`
 }
 
 // UpdateWorkItemRequest is the request body for updating a work item
 type UpdateWorkItemRequest struct {
-	Title        string   `json:"title" binding:"required"`
-	Description  string   `json:"description" binding:"required"`
-	Deliverables []string `json:"deliverables"`
-	Dependencies []string `json:"dependencies"`
-	Tags         []string `json:"tags,omitempty"`
+	Title                string   `json:"title" binding:"required"`
+	Description          string   `json:"description" binding:"required"`
+	Deliverables         []string `json:"deliverables"`
+	Dependencies         []string `json:"dependencies"`
+	Tags                 []string `json:"tags,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+	GoalKeys             []string `json:"goal_keys,omitempty"` // Omit to keep the current goal links
 }
 
 // WorkItemRefineRequest is the request body for AI work item refinement
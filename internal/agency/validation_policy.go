@@ -0,0 +1,168 @@
+package agency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+// ErrValidation matches every *ValidationError
+var ErrValidation = errors.New("validation failed")
+
+// Validation rules reported in violations
+const (
+	RuleMaxGoals              = "max_goals_per_agency"
+	RuleMaxWorkItemsPerGoal   = "max_work_items_per_goal"
+	RuleDescriptionMinLength  = "description_min_length"
+	RuleDescriptionMaxLength  = "description_max_length"
+	RuleRequiredForActivation = "required_for_activation"
+	RuleDuplicateDescription  = "duplicate_description"
+)
+
+// Fields that can be required before an agency is activated
+const (
+	ActivationFieldDescription = "description"
+	ActivationFieldIcon        = "icon"
+	ActivationFieldLocation    = "location"
+	ActivationFieldTags        = "tags"
+	ActivationFieldOverview    = "overview"
+	ActivationFieldGoals       = "goals"
+	ActivationFieldWorkItems   = "work_items"
+)
+
+// ValidationPolicy configures the strict checks the agency service applies
+// to goals, work items and agency activation. Nothing is checked unless
+// Strict is set; a zero limit disables its check.
+type ValidationPolicy struct {
+	Strict bool
+
+	MaxGoalsPerAgency   int
+	MaxWorkItemsPerGoal int
+
+	// MinDescriptionLength and MaxDescriptionLength bound goal and work item
+	// descriptions in characters
+	MinDescriptionLength int
+	MaxDescriptionLength int
+
+	// RequiredForActivation lists the Activation* fields that must be set
+	// before an agency's status becomes active
+	RequiredForActivation []string
+
+	// DuplicateSimilarity is the word overlap (0-1) at which a description
+	// counts as a duplicate of another goal's or work item's
+	DuplicateSimilarity float64
+}
+
+// PolicyFromConfig converts application config into a ValidationPolicy
+func PolicyFromConfig(cfg config.AgencyValidationConfig) ValidationPolicy {
+	return ValidationPolicy{
+		Strict:                cfg.Strict,
+		MaxGoalsPerAgency:     cfg.MaxGoalsPerAgency,
+		MaxWorkItemsPerGoal:   cfg.MaxWorkItemsPerGoal,
+		MinDescriptionLength:  cfg.MinDescriptionLength,
+		MaxDescriptionLength:  cfg.MaxDescriptionLength,
+		RequiredForActivation: cfg.RequiredForActivation,
+		DuplicateSimilarity:   cfg.DuplicateSimilarity,
+	}
+}
+
+// Violation is one failed check, addressed to the field the designer UI
+// renders it next to
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+
+	// RelatedKey is the goal or work item the violation refers to, such as
+	// the existing item a description duplicates
+	RelatedKey string `json:"related_key,omitempty"`
+
+	// Similarity is set for duplicate descriptions
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+// ValidationError reports every violation found for a change
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrValidation) match
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// DescriptionCandidate is an existing goal or work item a new description is
+// compared against
+type DescriptionCandidate struct {
+	Key         string `json:"key"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// DuplicateMatch is a candidate judged to describe the same thing
+type DuplicateMatch struct {
+	Key        string  `json:"key"`
+	Similarity float64 `json:"similarity"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// DuplicateChecker finds near-duplicate descriptions that word overlap
+// misses, such as paraphrases
+type DuplicateChecker interface {
+	FindDuplicates(ctx context.Context, description string, candidates []DescriptionCandidate) ([]DuplicateMatch, error)
+}
+
+// DescriptionSimilarity returns the overlap of the two descriptions' words,
+// from 0 (no shared words) to 1 (same words)
+func DescriptionSimilarity(a, b string) float64 {
+	wordsA, wordsB := descriptionWords(a), descriptionWords(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+// descriptionWords returns the distinct lowercase words of a description,
+// ignoring words of one or two letters
+func descriptionWords(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 2 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// WorkItemReferencesGoal reports whether a work item mentions the goal's code
+// in its title, description, deliverables or tags
+func WorkItemReferencesGoal(item *WorkItem, goal *Goal) bool {
+	if goal.Code == "" {
+		return false
+	}
+	fields := []string{item.Title, item.Description}
+	fields = append(fields, item.Deliverables...)
+	fields = append(fields, item.Tags...)
+	return strings.Contains(strings.ToLower(strings.Join(fields, " ")), strings.ToLower(goal.Code))
+}
@@ -0,0 +1,67 @@
+package agency
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidTransition is returned when a work item cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid work item status transition")
+
+// WorkItemStatus is the Kanban column of a work item
+type WorkItemStatus string
+
+const (
+	WorkItemStatusTodo       WorkItemStatus = "todo"
+	WorkItemStatusInProgress WorkItemStatus = "in_progress"
+	WorkItemStatusBlocked    WorkItemStatus = "blocked"
+	WorkItemStatusDone       WorkItemStatus = "done"
+)
+
+// workItemTransitions lists the statuses each status may move to. Done work
+// items can be reopened.
+var workItemTransitions = map[WorkItemStatus][]WorkItemStatus{
+	WorkItemStatusTodo:       {WorkItemStatusInProgress, WorkItemStatusBlocked},
+	WorkItemStatusInProgress: {WorkItemStatusTodo, WorkItemStatusBlocked, WorkItemStatusDone},
+	WorkItemStatusBlocked:    {WorkItemStatusTodo, WorkItemStatusInProgress},
+	WorkItemStatusDone:       {WorkItemStatusInProgress},
+}
+
+// IsValid reports whether the status is one of the Kanban statuses
+func (s WorkItemStatus) IsValid() bool {
+	_, exists := workItemTransitions[s]
+	return exists
+}
+
+// CanTransitionTo reports whether a work item may move from s to next
+func (s WorkItemStatus) CanTransitionTo(next WorkItemStatus) bool {
+	for _, allowed := range workItemTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkItemTransition records a status change of a work item
+type WorkItemTransition struct {
+	From   WorkItemStatus `json:"from"`
+	To     WorkItemStatus `json:"to"`
+	Reason string         `json:"reason,omitempty"`
+	At     time.Time      `json:"at"`
+}
+
+// TransitionWorkItemRequest is the request body for moving a work item to another status
+type TransitionWorkItemRequest struct {
+	Status WorkItemStatus `json:"status" binding:"required"`
+	Reason string         `json:"reason"`
+}
+
+// CurrentStatus returns the work item's status. Work items created before
+// statuses existed are treated as todo.
+func (w *WorkItem) CurrentStatus() WorkItemStatus {
+	if w.Status == "" {
+		return WorkItemStatusTodo
+	}
+	return w.Status
+}
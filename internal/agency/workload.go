@@ -0,0 +1,23 @@
+package agency
+
+// AssignWorkItemRequest is the request body for assigning a work item to an
+// agent. An empty agent ID unassigns the work item.
+type AssignWorkItemRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+// AgentWorkload summarizes the open work items assigned to one agent
+type AgentWorkload struct {
+	AgentID   string                 `json:"agent_id"`
+	Open      int                    `json:"open"`
+	ByStatus  map[WorkItemStatus]int `json:"by_status"`
+	WorkItems []string               `json:"work_items"` // Codes of the open work items
+}
+
+// Workload summarizes open work items per agent. Work items are open until
+// they are done.
+type Workload struct {
+	AgencyID   string           `json:"agency_id"`
+	Agents     []*AgentWorkload `json:"agents"`     // Busiest agent first
+	Unassigned []string         `json:"unassigned"` // Codes of open work items without an assignee
+}
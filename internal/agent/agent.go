@@ -2,7 +2,9 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/communication"
@@ -20,12 +22,18 @@ const (
 	StateRunning State = "running"
 	// StatePaused indicates agent is paused and not processing tasks
 	StatePaused State = "paused"
+	// StateDraining indicates agent finishes its queued tasks but accepts no new ones
+	StateDraining State = "draining"
 	// StateStopped indicates agent has been stopped gracefully
 	StateStopped State = "stopped"
 	// StateFailed indicates agent has encountered an error
 	StateFailed State = "failed"
 )
 
+// CapabilitiesMetadataKey is the metadata entry listing an agent's
+// capabilities, separated by commas
+const CapabilitiesMetadataKey = "capabilities"
+
 // Agent represents a single agent instance
 type Agent struct {
 	// ID is the unique identifier for the agent
@@ -67,6 +75,9 @@ type Agent struct {
 	// taskChan is the channel for receiving tasks
 	taskChan chan Task
 
+	// unfinishedTasks counts the submitted tasks not yet finished
+	unfinishedTasks atomic.Int64
+
 	// done signals agent shutdown completion
 	done chan struct{}
 
@@ -165,6 +176,24 @@ type TaskResult struct {
 
 	// Duration is the execution time
 	Duration time.Duration
+
+	// Usage reports the resources the task consumed, if measured
+	Usage *TaskUsage
+}
+
+// TaskUsage reports the resources consumed by a task
+type TaskUsage struct {
+	// CPU in millicores
+	CPU int
+
+	// Memory in megabytes
+	Memory int
+
+	// NetworkIO in bytes
+	NetworkIO int64
+
+	// DiskIO in bytes
+	DiskIO int64
 }
 
 // New creates a new agent with the given configuration
@@ -235,23 +264,54 @@ func (a *Agent) IsHealthy() bool {
 	return time.Since(a.LastHeartbeat) < threshold
 }
 
+// Capabilities returns the capabilities the agent declares in its metadata
+func (a *Agent) Capabilities() []string {
+	capabilities := make([]string, 0)
+	for _, capability := range strings.Split(a.Metadata[CapabilitiesMetadataKey], ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return capabilities
+}
+
 // Context returns the agent's context
 func (a *Agent) Context() context.Context {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	return a.ctx
 }
 
 // SubmitTask adds a task to the agent's queue
 func (a *Agent) SubmitTask(task Task) error {
+	if a.GetState() == StateDraining {
+		return ErrAgentDraining
+	}
+
+	a.unfinishedTasks.Add(1)
 	select {
 	case a.taskChan <- task:
 		return nil
-	case <-a.ctx.Done():
+	case <-a.Context().Done():
+		a.unfinishedTasks.Add(-1)
 		return ErrAgentStopped
 	default:
+		a.unfinishedTasks.Add(-1)
 		return ErrTaskQueueFull
 	}
 }
 
+// UnfinishedTasks returns the number of queued and running tasks
+func (a *Agent) UnfinishedTasks() int {
+	return int(a.unfinishedTasks.Load())
+}
+
+// TaskFinished records that a task taken from the task channel finished (for
+// runtime manager use)
+func (a *Agent) TaskFinished() {
+	a.unfinishedTasks.Add(-1)
+}
+
 // Done returns a channel that closes when agent shuts down
 func (a *Agent) Done() <-chan struct{} {
 	return a.done
@@ -269,9 +329,20 @@ func (a *Agent) TaskChan() <-chan Task {
 
 // Cancel cancels the agent's context (for runtime manager use)
 func (a *Agent) Cancel() {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	a.cancel()
 }
 
+// Reset prepares a stopped agent to run again with a fresh context (for
+// runtime manager use). Queued tasks are kept.
+func (a *Agent) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	a.State = StateCreated
+}
+
 // Communication methods
 
 // SetupCommunication initializes communication services for the agent
@@ -391,6 +462,12 @@ func (a *Agent) SetupMemory(memoryService *memory.Service, memorySynchronizer *m
 	a.memorySynchronizer = memorySynchronizer
 }
 
+// memoryContext returns the agent context with memory accesses attributed to
+// the agent, for the memory audit log
+func (a *Agent) memoryContext() context.Context {
+	return memory.WithAccessor(a.ctx, memory.MemoryAccessor{AgentInstance: a.ID})
+}
+
 // StartMemorySync starts periodic memory synchronization
 func (a *Agent) StartMemorySync() error {
 	a.mu.Lock()
@@ -415,13 +492,24 @@ func (a *Agent) StopMemorySync() error {
 	return a.memorySynchronizer.StopPeriodicSync()
 }
 
+// RegisterMemoryMigration registers fn to upconvert this agent's memory values
+// for keys matching keyPattern from fromVersion to the next schema version.
+// Stored values are migrated lazily when read.
+func (a *Agent) RegisterMemoryMigration(keyPattern string, fromVersion int, fn memory.UpconvertFunc) error {
+	if a.memoryService == nil {
+		return ErrMemoryNotSetup
+	}
+
+	return a.memoryService.Schemas().RegisterMigration(a.ID, keyPattern, fromVersion, fn)
+}
+
 // Remember stores a value in long-term memory
 func (a *Agent) Remember(key string, value interface{}, category string, metadata map[string]interface{}) error {
 	if a.memoryService == nil {
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.Remember(a.ctx, a.ID, key, value, category, metadata)
+	return a.memoryService.Remember(a.memoryContext(), a.ID, key, value, category, metadata)
 }
 
 // Recall retrieves a value from long-term memory
@@ -430,7 +518,7 @@ func (a *Agent) Recall(key string) (interface{}, error) {
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.Recall(a.ctx, a.ID, key)
+	return a.memoryService.Recall(a.memoryContext(), a.ID, key)
 }
 
 // Forget removes a long-term memory entry
@@ -439,7 +527,34 @@ func (a *Agent) Forget(key string) error {
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.Forget(a.ctx, a.ID, key)
+	return a.memoryService.Forget(a.memoryContext(), a.ID, key)
+}
+
+// SaveCheckpoint persists the progress of a long-running task execution so it
+// can be resumed if the agent restarts
+func (a *Agent) SaveCheckpoint(executionID, taskID string, percent float64, cursor map[string]interface{}, message string) error {
+	if a.memoryService == nil {
+		return ErrMemoryNotSetup
+	}
+
+	return a.memoryService.SaveCheckpoint(a.memoryContext(), &memory.TaskCheckpoint{
+		AgentID:     a.ID,
+		ExecutionID: executionID,
+		TaskID:      taskID,
+		Percent:     percent,
+		Cursor:      cursor,
+		Message:     message,
+	})
+}
+
+// LoadCheckpoint returns the last checkpoint saved for a task execution, or
+// memory.ErrNoCheckpoint if the task should start from the beginning
+func (a *Agent) LoadCheckpoint(executionID, taskID string) (*memory.TaskCheckpoint, error) {
+	if a.memoryService == nil {
+		return nil, ErrMemoryNotSetup
+	}
+
+	return a.memoryService.LoadCheckpoint(a.memoryContext(), a.ID, executionID, taskID)
 }
 
 // StoreWorking stores a value in working memory with TTL
@@ -448,7 +563,7 @@ func (a *Agent) StoreWorking(key string, value interface{}, ttl time.Duration) e
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.StoreWorking(a.ctx, a.ID, key, value, ttl)
+	return a.memoryService.StoreWorking(a.memoryContext(), a.ID, key, value, ttl)
 }
 
 // RetrieveWorking retrieves a value from working memory
@@ -457,7 +572,7 @@ func (a *Agent) RetrieveWorking(key string) (interface{}, error) {
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.RetrieveWorking(a.ctx, a.ID, key)
+	return a.memoryService.RetrieveWorking(a.memoryContext(), a.ID, key)
 }
 
 // UpdateWorking updates an existing working memory value
@@ -466,7 +581,7 @@ func (a *Agent) UpdateWorking(key string, value interface{}) error {
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.UpdateWorking(a.ctx, a.ID, key, value)
+	return a.memoryService.UpdateWorking(a.memoryContext(), a.ID, key, value)
 }
 
 // DeleteWorking deletes a working memory entry
@@ -475,7 +590,7 @@ func (a *Agent) DeleteWorking(key string) error {
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.DeleteWorking(a.ctx, a.ID, key)
+	return a.memoryService.DeleteWorking(a.memoryContext(), a.ID, key)
 }
 
 // ClearWorking removes all working memory
@@ -484,7 +599,7 @@ func (a *Agent) ClearWorking() error {
 		return ErrMemoryNotSetup
 	}
 
-	return a.memoryService.ClearWorking(a.ctx, a.ID)
+	return a.memoryService.ClearWorking(a.memoryContext(), a.ID)
 }
 
 // SearchMemory searches long-term memory based on query criteria
@@ -493,7 +608,7 @@ func (a *Agent) SearchMemory(query memory.MemoryQuery) ([]*memory.LongtermMemory
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.Search(a.ctx, a.ID, query)
+	return a.memoryService.Search(a.memoryContext(), a.ID, query)
 }
 
 // CreateMemorySnapshot creates a point-in-time snapshot of agent state
@@ -502,7 +617,17 @@ func (a *Agent) CreateMemorySnapshot(snapshotType, reason string) (*memory.State
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.CreateSnapshot(a.ctx, a.ID, snapshotType, reason)
+	return a.memoryService.CreateSnapshot(a.memoryContext(), a.ID, snapshotType, reason)
+}
+
+// RestoreMemorySnapshot restores working memory from a snapshot and returns
+// the snapshot taken just before, which rolls the restore back
+func (a *Agent) RestoreMemorySnapshot(snapshotID string) (*memory.StateSnapshot, error) {
+	if a.memoryService == nil {
+		return nil, ErrMemoryNotSetup
+	}
+
+	return a.memoryService.RestoreSnapshot(a.memoryContext(), a.ID, snapshotID)
 }
 
 // ListMemorySnapshots lists snapshots with optional filters
@@ -511,7 +636,7 @@ func (a *Agent) ListMemorySnapshots(filters memory.SnapshotFilters) ([]*memory.S
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.ListSnapshots(a.ctx, a.ID, filters)
+	return a.memoryService.ListSnapshots(a.memoryContext(), a.ID, filters)
 }
 
 // GetMemoryStats retrieves memory usage statistics
@@ -520,7 +645,7 @@ func (a *Agent) GetMemoryStats() (*memory.MemoryStats, error) {
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.GetMemoryStats(a.ctx, a.ID)
+	return a.memoryService.GetMemoryStats(a.memoryContext(), a.ID)
 }
 
 // SyncMemory performs a manual memory synchronization
@@ -529,5 +654,5 @@ func (a *Agent) SyncMemory() (*memory.SyncResult, error) {
 		return nil, ErrMemoryNotSetup
 	}
 
-	return a.memoryService.SyncMemory(a.ctx, a.ID)
+	return a.memoryService.SyncMemory(a.memoryContext(), a.ID)
 }
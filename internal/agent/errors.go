@@ -6,6 +6,9 @@ var (
 	// ErrAgentStopped is returned when attempting operations on a stopped agent
 	ErrAgentStopped = errors.New("agent is stopped")
 
+	// ErrAgentDraining is returned when submitting tasks to a draining agent
+	ErrAgentDraining = errors.New("agent is draining")
+
 	// ErrTaskQueueFull is returned when task queue is at capacity
 	ErrTaskQueueFull = errors.New("task queue is full")
 
@@ -0,0 +1,383 @@
+// Package alertrouting routes alerts to destinations using declarative policies.
+//
+// A policy matches alerts by severity, zone and alert type and lists the
+// destinations (coordinator agents, topics, webhooks or notification
+// channels) matching alerts are sent to. Schedules override a policy's
+// destinations during time windows such as on-call rotations. Policies are
+// evaluated by priority; the first match wins unless it sets Continue.
+package alertrouting
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+// DestinationType identifies how an alert is delivered
+type DestinationType string
+
+const (
+	// DestinationAgent sends the alert as a notification message to an agent
+	DestinationAgent DestinationType = "agent"
+	// DestinationTopic publishes the alert under an event name
+	DestinationTopic DestinationType = "topic"
+	// DestinationWebhook POSTs the alert as JSON to a URL
+	DestinationWebhook DestinationType = "webhook"
+	// DestinationNotification sends the alert to an operator notification channel
+	DestinationNotification DestinationType = "notification"
+)
+
+// IsValid checks if the destination type is known
+func (t DestinationType) IsValid() bool {
+	switch t {
+	case DestinationAgent, DestinationTopic, DestinationWebhook, DestinationNotification:
+		return true
+	}
+	return false
+}
+
+// Alert is the routable view of an alert
+type Alert struct {
+	ID        string                 `json:"id,omitempty"`
+	Severity  string                 `json:"severity"`
+	Zone      string                 `json:"zone,omitempty"`
+	AlertType string                 `json:"alert_type"`
+	Source    string                 `json:"source,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+	Payload   map[string]interface{} `json:"payload,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Destination is a place an alert is delivered to
+type Destination struct {
+	Type   DestinationType `json:"type"`
+	Target string          `json:"target"`
+}
+
+// String formats the destination as type:target
+func (d Destination) String() string {
+	return string(d.Type) + ":" + d.Target
+}
+
+// ScheduleMode controls how a schedule combines with the policy destinations
+type ScheduleMode string
+
+const (
+	// ScheduleReplace uses only the schedule's destinations while it is active
+	ScheduleReplace ScheduleMode = "replace"
+	// ScheduleAdd sends to the schedule's destinations in addition to the policy's
+	ScheduleAdd ScheduleMode = "add"
+)
+
+// Schedule overrides a policy's destinations during a daily time window
+type Schedule struct {
+	Name         string         `json:"name"`
+	Days         []time.Weekday `json:"-"`
+	DayNames     []string       `json:"days,omitempty"`
+	Start        string         `json:"start"`
+	End          string         `json:"end"`
+	Location     *time.Location `json:"-"`
+	Mode         ScheduleMode   `json:"mode"`
+	Destinations []Destination  `json:"destinations"`
+
+	startMinute int
+	endMinute   int
+}
+
+// Active reports whether the schedule window contains t. A window whose end
+// is before its start wraps past midnight and belongs to the day it starts on.
+func (s *Schedule) Active(t time.Time) bool {
+	local := t.In(s.Location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	if s.startMinute <= s.endMinute {
+		return s.onDay(day) && minute >= s.startMinute && minute < s.endMinute
+	}
+	if minute >= s.startMinute {
+		return s.onDay(day)
+	}
+	if minute < s.endMinute {
+		return s.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+func (s *Schedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy maps alert attributes to destinations
+type Policy struct {
+	Name         string        `json:"name"`
+	Priority     int           `json:"priority"`
+	Severities   []string      `json:"severities,omitempty"`
+	Zones        []string      `json:"zones,omitempty"`
+	AlertTypes   []string      `json:"alert_types,omitempty"`
+	Continue     bool          `json:"continue"`
+	Destinations []Destination `json:"destinations"`
+	Schedules    []*Schedule   `json:"schedules,omitempty"`
+}
+
+// Matches reports whether the alert has a matching severity, zone and type
+func (p *Policy) Matches(alert *Alert) bool {
+	return matchAny(p.Severities, alert.Severity) &&
+		matchAny(p.Zones, alert.Zone) &&
+		matchAny(p.AlertTypes, alert.AlertType)
+}
+
+// matchAny matches a value case-insensitively against glob patterns; no patterns match anything
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyMatch describes one policy that matched an alert
+type PolicyMatch struct {
+	Policy       string        `json:"policy"`
+	Schedule     string        `json:"schedule,omitempty"`
+	Destinations []Destination `json:"destinations"`
+}
+
+// Decision is where an alert is routed
+type Decision struct {
+	Alert        *Alert        `json:"alert"`
+	EvaluatedAt  time.Time     `json:"evaluated_at"`
+	Matches      []PolicyMatch `json:"matches"`
+	Defaulted    bool          `json:"defaulted"`
+	Destinations []Destination `json:"destinations"`
+}
+
+// Policies is an ordered set of routing policies
+type Policies struct {
+	policies []*Policy
+	defaults []Destination
+}
+
+// NewPolicies orders policies by priority (highest first, ties in declaration order)
+func NewPolicies(policies []*Policy, defaults []Destination) *Policies {
+	ordered := append([]*Policy(nil), policies...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return &Policies{policies: ordered, defaults: defaults}
+}
+
+// List returns the policies in evaluation order
+func (p *Policies) List() []*Policy {
+	return p.policies
+}
+
+// Defaults returns the destinations used when no policy matches
+func (p *Policies) Defaults() []Destination {
+	return p.defaults
+}
+
+// Evaluate decides where an alert goes at time at
+func (p *Policies) Evaluate(alert *Alert, at time.Time) *Decision {
+	decision := &Decision{
+		Alert:        alert,
+		EvaluatedAt:  at,
+		Matches:      []PolicyMatch{},
+		Destinations: []Destination{},
+	}
+
+	for _, policy := range p.policies {
+		if !policy.Matches(alert) {
+			continue
+		}
+
+		match := PolicyMatch{Policy: policy.Name, Destinations: policy.Destinations}
+		for _, schedule := range policy.Schedules {
+			if !schedule.Active(at) {
+				continue
+			}
+			match.Schedule = schedule.Name
+			if schedule.Mode == ScheduleAdd {
+				match.Destinations = append(append([]Destination(nil), policy.Destinations...), schedule.Destinations...)
+			} else {
+				match.Destinations = schedule.Destinations
+			}
+			break
+		}
+
+		decision.Matches = append(decision.Matches, match)
+		decision.Destinations = appendUnique(decision.Destinations, match.Destinations...)
+		if !policy.Continue {
+			break
+		}
+	}
+
+	if len(decision.Matches) == 0 {
+		decision.Defaulted = true
+		decision.Destinations = appendUnique(decision.Destinations, p.defaults...)
+	}
+
+	return decision
+}
+
+func appendUnique(destinations []Destination, more ...Destination) []Destination {
+	for _, d := range more {
+		duplicate := false
+		for _, existing := range destinations {
+			if existing == d {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			destinations = append(destinations, d)
+		}
+	}
+	return destinations
+}
+
+// PoliciesFromConfig validates and converts application config into policies
+func PoliciesFromConfig(cfg config.AlertRoutingConfig) (*Policies, error) {
+	location := time.UTC
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+		}
+		location = loc
+	}
+
+	defaults, err := destinationsFromConfig(cfg.DefaultDestinations)
+	if err != nil {
+		return nil, fmt.Errorf("default destinations: %w", err)
+	}
+
+	policies := make([]*Policy, 0, len(cfg.Policies))
+	for i, policyCfg := range cfg.Policies {
+		name := policyCfg.Name
+		if name == "" {
+			name = fmt.Sprintf("policy-%d", i+1)
+		}
+
+		destinations, err := destinationsFromConfig(policyCfg.Destinations)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+
+		policy := &Policy{
+			Name:         name,
+			Priority:     policyCfg.Priority,
+			Severities:   policyCfg.Severities,
+			Zones:        policyCfg.Zones,
+			AlertTypes:   policyCfg.AlertTypes,
+			Continue:     policyCfg.Continue,
+			Destinations: destinations,
+		}
+
+		for _, scheduleCfg := range policyCfg.Schedules {
+			schedule, err := scheduleFromConfig(scheduleCfg, location)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %w", name, err)
+			}
+			policy.Schedules = append(policy.Schedules, schedule)
+		}
+
+		if len(policy.Destinations) == 0 && len(policy.Schedules) == 0 {
+			return nil, fmt.Errorf("policy %s: at least one destination or schedule is required", name)
+		}
+		policies = append(policies, policy)
+	}
+
+	return NewPolicies(policies, defaults), nil
+}
+
+func destinationsFromConfig(cfgs []config.AlertDestinationConfig) ([]Destination, error) {
+	destinations := make([]Destination, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		d := Destination{Type: DestinationType(strings.ToLower(cfg.Type)), Target: cfg.Target}
+		if !d.Type.IsValid() {
+			return nil, fmt.Errorf("invalid destination type %q", cfg.Type)
+		}
+		if d.Target == "" {
+			return nil, fmt.Errorf("destination %s requires a target", d.Type)
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func scheduleFromConfig(cfg config.AlertScheduleConfig, location *time.Location) (*Schedule, error) {
+	schedule := &Schedule{
+		Name:     cfg.Name,
+		DayNames: cfg.Days,
+		Start:    cfg.Start,
+		End:      cfg.End,
+		Location: location,
+		Mode:     ScheduleMode(strings.ToLower(cfg.Mode)),
+	}
+	if schedule.Mode == "" {
+		schedule.Mode = ScheduleReplace
+	}
+	if schedule.Mode != ScheduleReplace && schedule.Mode != ScheduleAdd {
+		return nil, fmt.Errorf("schedule %s: invalid mode %q", cfg.Name, cfg.Mode)
+	}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: invalid timezone %q: %w", cfg.Name, cfg.Timezone, err)
+		}
+		schedule.Location = loc
+	}
+
+	var err error
+	if schedule.startMinute, err = parseClock(cfg.Start); err != nil {
+		return nil, fmt.Errorf("schedule %s: invalid start: %w", cfg.Name, err)
+	}
+	if schedule.endMinute, err = parseClock(cfg.End); err != nil {
+		return nil, fmt.Errorf("schedule %s: invalid end: %w", cfg.Name, err)
+	}
+
+	for _, day := range cfg.Days {
+		weekday, ok := weekdays[strings.ToLower(day)[:min(3, len(day))]]
+		if !ok {
+			return nil, fmt.Errorf("schedule %s: invalid day %q", cfg.Name, day)
+		}
+		schedule.Days = append(schedule.Days, weekday)
+	}
+
+	if schedule.Destinations, err = destinationsFromConfig(cfg.Destinations); err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", cfg.Name, err)
+	}
+	return schedule, nil
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
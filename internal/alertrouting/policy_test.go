@@ -0,0 +1,179 @@
+package alertrouting
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRoutingConfig() config.AlertRoutingConfig {
+	return config.AlertRoutingConfig{
+		Policies: []config.AlertPolicyConfig{
+			{
+				Name:       "all-critical",
+				Priority:   10,
+				Severities: []string{"critical"},
+				Continue:   true,
+				Destinations: []config.AlertDestinationConfig{
+					{Type: "topic", Target: "alerts.critical"},
+				},
+			},
+			{
+				Name:       "north-pressure",
+				Priority:   50,
+				Zones:      []string{"zone-north"},
+				AlertTypes: []string{"pressure.*"},
+				Destinations: []config.AlertDestinationConfig{
+					{Type: "agent", Target: "coordinator-north"},
+				},
+				Schedules: []config.AlertScheduleConfig{
+					{
+						Name:  "night-on-call",
+						Days:  []string{"mon", "tue", "wed", "thu", "fri"},
+						Start: "22:00",
+						End:   "06:00",
+						Mode:  "add",
+						Destinations: []config.AlertDestinationConfig{
+							{Type: "notification", Target: "on-call"},
+						},
+					},
+				},
+			},
+		},
+		DefaultDestinations: []config.AlertDestinationConfig{
+			{Type: "topic", Target: "alerts.unrouted"},
+		},
+	}
+}
+
+func TestPolicies_Evaluate(t *testing.T) {
+	policies, err := PoliciesFromConfig(testRoutingConfig())
+	require.NoError(t, err)
+	require.Equal(t, "north-pressure", policies.List()[0].Name, "higher priority policies are evaluated first")
+
+	// Monday 12:00 UTC, outside the on-call window
+	noon := time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC)
+	decision := policies.Evaluate(&Alert{Severity: "HIGH", Zone: "zone-north", AlertType: "pressure.low"}, noon)
+	require.Len(t, decision.Matches, 1)
+	assert.Equal(t, "north-pressure", decision.Matches[0].Policy)
+	assert.Empty(t, decision.Matches[0].Schedule)
+	assert.Equal(t, []Destination{{Type: DestinationAgent, Target: "coordinator-north"}}, decision.Destinations)
+
+	// Monday 23:00 UTC adds the on-call channel
+	night := time.Date(2026, 10, 12, 23, 0, 0, 0, time.UTC)
+	decision = policies.Evaluate(&Alert{Severity: "HIGH", Zone: "zone-north", AlertType: "pressure.low"}, night)
+	assert.Equal(t, "night-on-call", decision.Matches[0].Schedule)
+	assert.Contains(t, decision.Destinations, Destination{Type: DestinationNotification, Target: "on-call"})
+	assert.Contains(t, decision.Destinations, Destination{Type: DestinationAgent, Target: "coordinator-north"})
+
+	// Saturday 02:00 belongs to Friday night's window
+	saturday := time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC)
+	decision = policies.Evaluate(&Alert{Zone: "zone-north", AlertType: "pressure.high"}, saturday)
+	assert.Equal(t, "night-on-call", decision.Matches[0].Schedule)
+
+	// Sunday 02:00 follows Saturday, which has no window
+	sunday := time.Date(2026, 10, 18, 2, 0, 0, 0, time.UTC)
+	decision = policies.Evaluate(&Alert{Zone: "zone-north", AlertType: "pressure.high"}, sunday)
+	assert.Empty(t, decision.Matches[0].Schedule)
+
+	// Lower priority policies match when higher ones do not
+	decision = policies.Evaluate(&Alert{Severity: "CRITICAL", Zone: "zone-south", AlertType: "leak"}, noon)
+	require.Len(t, decision.Matches, 1)
+	assert.Equal(t, "all-critical", decision.Matches[0].Policy)
+
+	// Unmatched alerts use the default destinations
+	decision = policies.Evaluate(&Alert{Severity: "LOW", Zone: "zone-south", AlertType: "leak"}, noon)
+	assert.True(t, decision.Defaulted)
+	assert.Equal(t, []Destination{{Type: DestinationTopic, Target: "alerts.unrouted"}}, decision.Destinations)
+}
+
+func TestPoliciesFromConfig_Invalid(t *testing.T) {
+	cfg := config.AlertRoutingConfig{
+		Policies: []config.AlertPolicyConfig{
+			{Name: "bad", Destinations: []config.AlertDestinationConfig{{Type: "pager", Target: "x"}}},
+		},
+	}
+	_, err := PoliciesFromConfig(cfg)
+	assert.Error(t, err)
+
+	cfg.Policies[0].Destinations = nil
+	cfg.Policies[0].Schedules = []config.AlertScheduleConfig{{Name: "s", Start: "25:00", End: "06:00"}}
+	_, err = PoliciesFromConfig(cfg)
+	assert.Error(t, err)
+}
+
+// recordingPublisher captures publications for testing
+type recordingPublisher struct {
+	events []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *communication.PublicationOptions) (string, error) {
+	p.events = append(p.events, eventName)
+	return "pub-1", nil
+}
+
+func TestService_Route(t *testing.T) {
+	var webhookCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookCalls++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := testRoutingConfig()
+	cfg.Policies[0].Destinations = append(cfg.Policies[0].Destinations, config.AlertDestinationConfig{Type: "webhook", Target: server.URL})
+	policies, err := PoliciesFromConfig(cfg)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	publisher := &recordingPublisher{}
+	service := NewService(policies, logger)
+	service.SetPublisher(publisher)
+	// Monday 12:00 UTC, outside the on-call window
+	fake := clock.NewFake(time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC))
+	service.SetClock(fake)
+
+	alert := AlertFromPublication(&communication.Publication{
+		ID:               "pub-42",
+		PublisherAgentID: "sensor-7",
+		EventName:        "alert.leak",
+		Payload:          map[string]interface{}{"severity": "critical", "zone": "zone-south"},
+	})
+	assert.Equal(t, "CRITICAL", alert.Severity)
+	assert.Equal(t, "alert.leak", alert.AlertType)
+
+	result := service.Route(context.Background(), alert)
+	require.Len(t, result.Deliveries, 2)
+	for _, d := range result.Deliveries {
+		assert.True(t, d.Delivered, d.Error)
+	}
+	assert.Equal(t, []string{"alerts.critical"}, publisher.events)
+	assert.Equal(t, 1, webhookCalls)
+
+	// Agent destinations fail without a messenger but do not stop routing
+	alert = &Alert{Zone: "zone-north", AlertType: "pressure.low"}
+	result = service.Route(context.Background(), alert)
+	require.Len(t, result.Deliveries, 1)
+	assert.False(t, result.Deliveries[0].Delivered)
+	assert.Equal(t, fake.Now(), alert.Timestamp)
+
+	// At 23:00 the on-call notification is added
+	fake.Advance(11 * time.Hour)
+	result = service.Route(context.Background(), &Alert{Zone: "zone-north", AlertType: "pressure.low"})
+	require.Len(t, result.Deliveries, 2)
+	assert.Equal(t, "night-on-call", result.Decision.Matches[0].Schedule)
+
+	// The router ignores its own publications
+	assert.Nil(t, service.HandlePublication(context.Background(), &communication.Publication{PublisherAgentID: RouterAgentID}))
+}
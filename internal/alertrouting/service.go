@@ -0,0 +1,334 @@
+package alertrouting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RouterAgentID is the agent ID the router subscribes and publishes under
+	RouterAgentID = "alert-router"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// AgentMessenger sends direct messages to agents. MessageService implements it.
+type AgentMessenger interface {
+	SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error)
+}
+
+// AlertSubscriber delivers alert publications to the router. PubSubService implements it.
+type AlertSubscriber interface {
+	Subscribe(ctx context.Context, subscriberAgentID, subscriberAgentType, eventPattern string, filters *communication.SubscriptionFilters) (string, error)
+	GetActiveSubscriptions(ctx context.Context, agentID string) ([]*communication.Subscription, error)
+	RegisterPushHandler(agentID string, handler communication.PublicationHandler)
+}
+
+// Notifier delivers alerts to operator notification channels
+type Notifier interface {
+	Notify(ctx context.Context, channel string, alert *Alert) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+// NewLogNotifier creates a notifier that logs notifications
+func NewLogNotifier(logger *log.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify logs the alert
+func (n *LogNotifier) Notify(ctx context.Context, channel string, alert *Alert) error {
+	n.logger.WithFields(log.Fields{
+		"channel":    channel,
+		"severity":   alert.Severity,
+		"zone":       alert.Zone,
+		"alert_type": alert.AlertType,
+	}).Warn(alert.Message)
+	return nil
+}
+
+// Delivery is the outcome of sending an alert to one destination
+type Delivery struct {
+	Destination Destination `json:"destination"`
+	Delivered   bool        `json:"delivered"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// RouteResult is a routing decision and its deliveries
+type RouteResult struct {
+	Decision   *Decision  `json:"decision"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Service evaluates routing policies and delivers alerts
+type Service struct {
+	policies  *Policies
+	messenger AgentMessenger
+	publisher communication.TrafficPublisher
+	notifier  Notifier
+	client    *http.Client
+	clock     clock.Clock
+	logger    *log.Logger
+}
+
+// NewService creates a new alert routing service
+func NewService(policies *Policies, logger *log.Logger) *Service {
+	return &Service{
+		policies: policies,
+		client:   &http.Client{Timeout: webhookTimeout},
+		clock:    clock.Real(),
+		logger:   logger,
+	}
+}
+
+// SetMessenger sets the service used for agent destinations
+func (s *Service) SetMessenger(messenger AgentMessenger) {
+	s.messenger = messenger
+}
+
+// SetPublisher sets the service used for topic destinations
+func (s *Service) SetPublisher(publisher communication.TrafficPublisher) {
+	s.publisher = publisher
+}
+
+// SetNotifier sets the notifier used for notification destinations
+func (s *Service) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetClock sets the clock used to timestamp alerts and pick the schedules in
+// effect when they are routed
+func (s *Service) SetClock(c clock.Clock) {
+	if c != nil {
+		s.clock = c
+	}
+}
+
+// Policies returns the routing policies
+func (s *Service) Policies() *Policies {
+	return s.policies
+}
+
+// Evaluate decides where an alert would be routed at the given time without delivering it
+func (s *Service) Evaluate(alert *Alert, at time.Time) *Decision {
+	return s.Policies().Evaluate(alert, at)
+}
+
+// Route evaluates the policies for an alert and delivers it to every destination
+func (s *Service) Route(ctx context.Context, alert *Alert) *RouteResult {
+	now := s.clock.Now()
+	if alert.Timestamp.IsZero() {
+		alert.Timestamp = now
+	}
+
+	decision := s.Evaluate(alert, now)
+	result := &RouteResult{
+		Decision:   decision,
+		Deliveries: make([]Delivery, 0, len(decision.Destinations)),
+	}
+
+	for _, destination := range decision.Destinations {
+		delivery := Delivery{Destination: destination, Delivered: true}
+		if err := s.deliver(ctx, destination, alert); err != nil {
+			delivery.Delivered = false
+			delivery.Error = err.Error()
+			s.logger.WithError(err).WithFields(log.Fields{
+				"alert_id":    alert.ID,
+				"destination": destination.String(),
+			}).Warn("Failed to deliver alert")
+		}
+		result.Deliveries = append(result.Deliveries, delivery)
+	}
+
+	s.logger.WithFields(log.Fields{
+		"alert_id":     alert.ID,
+		"severity":     alert.Severity,
+		"zone":         alert.Zone,
+		"alert_type":   alert.AlertType,
+		"destinations": len(result.Deliveries),
+		"defaulted":    decision.Defaulted,
+	}).Debug("Alert routed")
+
+	return result
+}
+
+// deliver sends an alert to one destination
+func (s *Service) deliver(ctx context.Context, destination Destination, alert *Alert) error {
+	payload := alertPayload(alert)
+
+	switch destination.Type {
+	case DestinationAgent:
+		if s.messenger == nil {
+			return fmt.Errorf("agent messaging is not available")
+		}
+		_, err := s.messenger.SendMessage(ctx, RouterAgentID, destination.Target, communication.MessageTypeNotification, payload, &communication.MessageOptions{
+			Priority: severityPriority(alert.Severity),
+		})
+		return err
+
+	case DestinationTopic:
+		if s.publisher == nil {
+			return fmt.Errorf("pub/sub is not available")
+		}
+		// Published as an event rather than an alert so the router does not route it again
+		_, err := s.publisher.Publish(ctx, RouterAgentID, RouterAgentID, destination.Target, payload, &communication.PublicationOptions{
+			Type: communication.PublicationTypeEvent,
+		})
+		return err
+
+	case DestinationWebhook:
+		return s.postWebhook(ctx, destination.Target, payload)
+
+	case DestinationNotification:
+		if s.notifier == nil {
+			return fmt.Errorf("notifications are not available")
+		}
+		return s.notifier.Notify(ctx, destination.Target, alert)
+	}
+
+	return fmt.Errorf("unknown destination type: %s", destination.Type)
+}
+
+func (s *Service) postWebhook(ctx context.Context, url string, payload map[string]interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Start subscribes the router to alert publications, reusing an existing subscription
+func (s *Service) Start(ctx context.Context, subscriber AlertSubscriber) error {
+	subscriptions, err := subscriber.GetActiveSubscriptions(ctx, RouterAgentID)
+	if err != nil {
+		return fmt.Errorf("failed to load router subscriptions: %w", err)
+	}
+
+	subscribed := false
+	for _, sub := range subscriptions {
+		if sub.DeliveryMode == communication.DeliveryModePush {
+			subscribed = true
+			break
+		}
+	}
+
+	if !subscribed {
+		_, err := subscriber.Subscribe(ctx, RouterAgentID, RouterAgentID, "#", &communication.SubscriptionFilters{
+			Types:        []communication.PublicationType{communication.PublicationTypeAlert},
+			DeliveryMode: communication.DeliveryModePush,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to alerts: %w", err)
+		}
+	}
+
+	// Route asynchronously so slow webhooks do not hold up the publisher
+	subscriber.RegisterPushHandler(RouterAgentID, func(pub *communication.Publication) error {
+		go s.HandlePublication(context.Background(), pub)
+		return nil
+	})
+
+	s.logger.WithField("policies", len(s.Policies().List())).Info("Alert routing started")
+	return nil
+}
+
+// HandlePublication routes an alert publication
+func (s *Service) HandlePublication(ctx context.Context, pub *communication.Publication) *RouteResult {
+	if pub.PublisherAgentID == RouterAgentID {
+		return nil
+	}
+	return s.Route(ctx, AlertFromPublication(pub))
+}
+
+// AlertFromPublication reads alert attributes from a publication. Severity,
+// zone and alert_type come from the payload; zone may also come from the
+// publication metadata and the alert type defaults to the event name.
+func AlertFromPublication(pub *communication.Publication) *Alert {
+	alert := &Alert{
+		ID:        pub.ID,
+		Severity:  strings.ToUpper(payloadString(pub.Payload, "severity")),
+		Zone:      payloadString(pub.Payload, "zone"),
+		AlertType: payloadString(pub.Payload, "alert_type"),
+		Source:    pub.PublisherAgentID,
+		Message:   payloadString(pub.Payload, "message"),
+		Payload:   pub.Payload,
+		Timestamp: pub.PublishedAt,
+	}
+	if alert.Zone == "" && pub.Metadata != nil {
+		alert.Zone = pub.Metadata["zone"]
+	}
+	if alert.AlertType == "" {
+		alert.AlertType = pub.EventName
+	}
+	if alert.Message == "" {
+		alert.Message = fmt.Sprintf("%s alert %s from %s", alert.Severity, alert.AlertType, alert.Source)
+	}
+	return alert
+}
+
+func payloadString(payload map[string]interface{}, key string) string {
+	if payload == nil {
+		return ""
+	}
+	if value, ok := payload[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// alertPayload is the body sent to every destination
+func alertPayload(alert *Alert) map[string]interface{} {
+	return map[string]interface{}{
+		"alert_id":   alert.ID,
+		"severity":   alert.Severity,
+		"zone":       alert.Zone,
+		"alert_type": alert.AlertType,
+		"source":     alert.Source,
+		"message":    alert.Message,
+		"payload":    alert.Payload,
+		"timestamp":  alert.Timestamp,
+	}
+}
+
+// severityPriority maps a severity onto the 1-10 message priority scale
+func severityPriority(severity string) int {
+	switch strings.ToUpper(severity) {
+	case "CRITICAL":
+		return 10
+	case "HIGH":
+		return 8
+	case "MEDIUM":
+		return 6
+	case "LOW":
+		return 4
+	}
+	return 5
+}
@@ -37,6 +37,7 @@ func NewDefaultServices() (*Services, error) {
 
 	// Memory service (using in-memory implementation for now)
 	memoryService := memory.NewService(nil) // nil for in-memory
+	memoryService.SetAuditLog(memory.NewInMemoryAuditLog(0))
 
 	// Lifecycle manager (will need repository implementation)
 	lifecycleManager := lifecycle.NewManager(nil) // nil for now
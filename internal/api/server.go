@@ -254,7 +254,7 @@ func (s *Server) setupCommunicationRoutes(rg *gin.RouterGroup) {
 		// Pub/sub messaging
 		comm.POST("/publish", s.publishMessage)
 
-		// Channels and stats (not yet implemented)
+		// Channels (not yet implemented) and stats
 		comm.GET("/channels", s.listChannels)
 		comm.POST("/channels", s.createChannel)
 		comm.GET("/stats", s.getCommunicationStats)
@@ -519,10 +519,19 @@ func (s *Server) publishMessage(c *gin.Context) {
 	})
 }
 
-func (s *Server) getMessage(c *gin.Context)            { NotImplementedError(c) }
-func (s *Server) listChannels(c *gin.Context)          { NotImplementedError(c) }
-func (s *Server) createChannel(c *gin.Context)         { NotImplementedError(c) }
-func (s *Server) getCommunicationStats(c *gin.Context) { NotImplementedError(c) }
+// getCommunicationStats handles GET /api/v1/communications/stats
+func (s *Server) getCommunicationStats(c *gin.Context) {
+	if s.services.PubSubService == nil {
+		ErrorResponse(c, 503, "SERVICE_UNAVAILABLE", "Pub/sub service not initialized", nil)
+		return
+	}
+
+	c.JSON(200, s.services.PubSubService.TopicStats())
+}
+
+func (s *Server) getMessage(c *gin.Context)    { NotImplementedError(c) }
+func (s *Server) listChannels(c *gin.Context)  { NotImplementedError(c) }
+func (s *Server) createChannel(c *gin.Context) { NotImplementedError(c) }
 
 func (s *Server) getSystemMetrics(c *gin.Context)   { NotImplementedError(c) }
 func (s *Server) getResourceMetrics(c *gin.Context) { NotImplementedError(c) }
@@ -0,0 +1,269 @@
+package apiversion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestedVersionKey carries the version a client asked for through a fallback
+type requestedVersionKey struct{}
+
+// Middleware negotiates the API version of /api/v<N> requests and applies
+// the registry: it sets the API-Version header, announces deprecated versions,
+// routes and fields, rejects sunset ones with 410 Gone, and runs the renamed
+// field shim. Requests outside /api/v<N> pass through untouched.
+func Middleware(registry *Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested, rest, ok := ParsePath(c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+		if v, ok := c.Request.Context().Value(requestedVersionKey{}).(Version); ok {
+			requested = v
+		}
+
+		if !requested.IsSupported() {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error":     "unsupported_api_version",
+				"message":   fmt.Sprintf("API version %s is not supported", requested),
+				"supported": Supported,
+			})
+			return
+		}
+		c.Header(HeaderAPIVersion, string(requested))
+
+		now := time.Now()
+		if d, ok := registry.Version(requested); ok {
+			if announce(c, d.DeprecatedAt, d.SunsetAt, Latest.Prefix()+rest, now) {
+				return
+			}
+		}
+
+		// Route deprecations apply only when the requested version's own route served
+		// the request, not when it fell back to an older version
+		route := c.FullPath()
+		if route != "" && strings.HasPrefix(route, requested.Prefix()+"/") {
+			if d, ok := registry.Route(c.Request.Method, route); ok {
+				if announce(c, d.DeprecatedAt, d.SunsetAt, d.Replacement, now) {
+					return
+				}
+			}
+		}
+
+		renames := activeRenames(registry.Fields(c.Request.Method, route), now)
+		if len(renames) == 0 {
+			c.Next()
+			return
+		}
+
+		if used := renameRequestFields(c, renames); len(used) > 0 {
+			announce(c, earliestDeprecation(used), nil, "", now)
+		}
+
+		var legacy []FieldRename
+		for _, f := range renames {
+			if requested.Before(f.Since) {
+				legacy = append(legacy, f)
+			}
+		}
+		if len(legacy) == 0 {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		writer.flush(legacy)
+	}
+}
+
+// Fallback is the NoRoute handler that serves a route missing from a version
+// with the previous version's handler, keeping the requested version for the
+// middleware. Other unmatched requests get gin's default 404.
+func Fallback(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version, rest, ok := ParsePath(c.Request.URL.Path)
+		if !ok {
+			return
+		}
+		previous, ok := version.Previous()
+		if !ok {
+			return
+		}
+
+		ctx := c.Request.Context()
+		if _, ok := ctx.Value(requestedVersionKey{}).(Version); !ok {
+			ctx = context.WithValue(ctx, requestedVersionKey{}, version)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Request.URL.Path = previous.Prefix() + rest
+		c.Request.URL.RawPath = ""
+
+		engine.HandleContext(c)
+	}
+}
+
+// announce sets the deprecation headers, or answers 410 Gone when the sunset
+// date has passed. It reports whether the request was aborted.
+func announce(c *gin.Context, deprecatedAt time.Time, sunsetAt *time.Time, successor string, now time.Time) bool {
+	if sunsetAt != nil && !now.Before(*sunsetAt) {
+		c.AbortWithStatusJSON(http.StatusGone, gin.H{
+			"error":     "sunset",
+			"message":   "This API has been removed",
+			"sunset_at": sunsetAt,
+			"successor": successor,
+		})
+		return true
+	}
+
+	c.Header("Deprecation", fmt.Sprintf("@%d", deprecatedAt.Unix()))
+	if sunsetAt != nil {
+		c.Header("Sunset", sunsetAt.UTC().Format(http.TimeFormat))
+	}
+	link := fmt.Sprintf(`<%s/deprecations>; rel="deprecation"`, Latest.Prefix())
+	if successor != "" {
+		link += fmt.Sprintf(`, <%s>; rel="successor-version"`, successor)
+	}
+	c.Header("Link", link)
+	return false
+}
+
+// activeRenames drops renames whose sunset date has passed
+func activeRenames(renames []FieldRename, now time.Time) []FieldRename {
+	var active []FieldRename
+	for _, f := range renames {
+		if f.SunsetAt == nil || now.Before(*f.SunsetAt) {
+			active = append(active, f)
+		}
+	}
+	return active
+}
+
+func earliestDeprecation(renames []FieldRename) time.Time {
+	earliest := renames[0].DeprecatedAt
+	for _, f := range renames[1:] {
+		if f.DeprecatedAt.Before(earliest) {
+			earliest = f.DeprecatedAt
+		}
+	}
+	return earliest
+}
+
+// renameRequestFields moves old field names in a JSON object body to their new
+// names and returns the renames the client relied on
+func renameRequestFields(c *gin.Context, renames []FieldRename) []FieldRename {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body.Close()
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil
+	}
+
+	var used []FieldRename
+	for _, f := range renames {
+		value, hasOld := object[f.Old]
+		if !hasOld {
+			continue
+		}
+		if _, hasNew := object[f.New]; !hasNew {
+			object[f.New] = value
+		}
+		delete(object, f.Old)
+		used = append(used, f)
+	}
+	if len(used) == 0 {
+		return nil
+	}
+
+	rewritten, err := json.Marshal(object)
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(rewritten))
+	c.Request.ContentLength = int64(len(rewritten))
+	return used
+}
+
+// bufferedWriter holds a response so renamed fields can be added before it is sent
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// flush writes the buffered response, copying each new field back to its old
+// name in JSON objects (or arrays of objects) for clients on older versions
+func (w *bufferedWriter) flush(legacy []FieldRename) {
+	body := w.body.Bytes()
+	if strings.Contains(w.Header().Get("Content-Type"), "json") {
+		if rewritten, ok := addLegacyFields(body, legacy); ok {
+			body = rewritten
+		}
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.Write(body)
+}
+
+func addLegacyFields(body []byte, legacy []FieldRename) ([]byte, bool) {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, false
+	}
+
+	var objects []map[string]interface{}
+	switch v := decoded.(type) {
+	case map[string]interface{}:
+		objects = append(objects, v)
+	case []interface{}:
+		for _, item := range v {
+			if object, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, object)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	for _, object := range objects {
+		for _, f := range legacy {
+			if value, ok := object[f.New]; ok {
+				if _, exists := object[f.Old]; !exists {
+					object[f.Old] = value
+				}
+			}
+		}
+	}
+
+	rewritten, err := json.Marshal(decoded)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
@@ -0,0 +1,159 @@
+package apiversion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(registry *Registry) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(registry))
+	router.NoRoute(Fallback(router))
+
+	router.GET("/api/v1/items", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"name": "v1"})
+	})
+	router.GET("/api/v2/items", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"name": "v2"})
+	})
+	router.GET("/api/v1/things/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id"), "display_name": "Thing"})
+	})
+	router.POST("/api/v1/things", func(c *gin.Context) {
+		var body map[string]interface{}
+		_ = c.ShouldBindJSON(&body)
+		c.JSON(http.StatusOK, body)
+	})
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestParsePath(t *testing.T) {
+	v, rest, ok := ParsePath("/api/v2/agencies/a1")
+	assert.True(t, ok)
+	assert.Equal(t, V2, v)
+	assert.Equal(t, "/agencies/a1", rest)
+
+	v, rest, ok = ParsePath("/api/v1")
+	assert.True(t, ok)
+	assert.Equal(t, V1, v)
+	assert.Equal(t, "", rest)
+
+	_, _, ok = ParsePath("/api/web/agencies")
+	assert.False(t, ok)
+	_, _, ok = ParsePath("/health")
+	assert.False(t, ok)
+}
+
+func TestMiddleware_VersionNegotiation(t *testing.T) {
+	router := setupRouter(NewRegistry())
+
+	w := serve(router, http.MethodGet, "/api/v2/items", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v2", w.Header().Get(HeaderAPIVersion))
+	assert.Contains(t, w.Body.String(), `"v2"`)
+
+	// v2 falls back to the v1 route when it has no route of its own
+	w = serve(router, http.MethodGet, "/api/v2/things/t1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v2", w.Header().Get(HeaderAPIVersion))
+	assert.Contains(t, w.Body.String(), `"t1"`)
+
+	w = serve(router, http.MethodGet, "/api/v9/items", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "unsupported_api_version")
+
+	w = serve(router, http.MethodGet, "/api/v2/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMiddleware_Deprecations(t *testing.T) {
+	registry := NewRegistry()
+	deprecatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunsetAt := time.Now().Add(30 * 24 * time.Hour)
+	pastSunset := time.Now().Add(-time.Hour)
+
+	registry.DeprecateVersion(VersionDeprecation{Version: V1, DeprecatedAt: deprecatedAt, SunsetAt: &sunsetAt})
+	registry.DeprecateRoute(RouteDeprecation{Method: http.MethodGet, Path: "/api/v2/items", DeprecatedAt: deprecatedAt, SunsetAt: &pastSunset})
+	router := setupRouter(registry)
+
+	w := serve(router, http.MethodGet, "/api/v1/items", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+	assert.Equal(t, sunsetAt.UTC().Format(http.TimeFormat), w.Header().Get("Sunset"))
+	assert.Contains(t, w.Header().Get("Link"), `</api/v2/items>; rel="successor-version"`)
+
+	w = serve(router, http.MethodGet, "/api/v2/items", "")
+	assert.Equal(t, http.StatusGone, w.Code)
+
+	// v2 clients served by a v1 route are not told v1 is deprecated
+	w = serve(router, http.MethodGet, "/api/v2/things/t1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+}
+
+func TestMiddleware_FieldRenames(t *testing.T) {
+	registry := NewRegistry()
+	deprecatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry.RenameField(FieldRename{Method: http.MethodPost, Path: "/api/v1/things", Old: "title", New: "display_name", Since: V2, DeprecatedAt: deprecatedAt})
+	registry.RenameField(FieldRename{Method: http.MethodGet, Path: "/api/v1/things/:id", Old: "title", New: "display_name", Since: V2, DeprecatedAt: deprecatedAt})
+	router := setupRouter(registry)
+
+	// Old request field names are moved to the new name
+	w := serve(router, http.MethodPost, "/api/v2/things", `{"title": "Pump"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Pump", body["display_name"])
+	assert.NotContains(t, body, "title")
+	assert.NotEmpty(t, w.Header().Get("Deprecation"))
+
+	// v1 responses keep the old field name alongside the new one
+	w = serve(router, http.MethodGet, "/api/v1/things/t1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	body = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Thing", body["title"])
+	assert.Equal(t, "Thing", body["display_name"])
+
+	w = serve(router, http.MethodGet, "/api/v2/things/t1", "")
+	body = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.NotContains(t, body, "title")
+}
+
+func TestRegistry_List(t *testing.T) {
+	registry := NewRegistry()
+	later := time.Now().Add(60 * 24 * time.Hour)
+	sooner := time.Now().Add(10 * 24 * time.Hour)
+	registry.DeprecateRoute(RouteDeprecation{Method: http.MethodGet, Path: "/api/v1/b", SunsetAt: &later})
+	registry.DeprecateRoute(RouteDeprecation{Method: http.MethodGet, Path: "/api/v1/a"})
+	registry.DeprecateRoute(RouteDeprecation{Method: http.MethodGet, Path: "/api/v1/c", SunsetAt: &sooner})
+
+	listing := registry.List()
+	require.Len(t, listing.Versions, len(Supported))
+	assert.True(t, listing.Versions[len(listing.Versions)-1].Latest)
+	require.Len(t, listing.Routes, 3)
+	assert.Equal(t, "/api/v1/c", listing.Routes[0].Path)
+	assert.Equal(t, "/api/v1/b", listing.Routes[1].Path)
+	assert.Equal(t, "/api/v1/a", listing.Routes[2].Path)
+	assert.NotNil(t, listing.FieldRenames)
+}
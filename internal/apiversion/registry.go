@@ -0,0 +1,185 @@
+package apiversion
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// VersionDeprecation marks a whole API version as deprecated
+type VersionDeprecation struct {
+	Version      Version    `json:"version"`
+	DeprecatedAt time.Time  `json:"deprecated_at"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+}
+
+// RouteDeprecation marks a single route as deprecated. Path is the gin route
+// pattern including the version prefix, e.g. "/api/v1/agencies/:id/overview".
+type RouteDeprecation struct {
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Replacement  string     `json:"replacement,omitempty"`
+	DeprecatedAt time.Time  `json:"deprecated_at"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+}
+
+// FieldRename declares a JSON field renamed on a route. Requests that still
+// send Old have it moved to New before the handler runs, and responses to
+// clients on versions before Since also carry the value under Old.
+type FieldRename struct {
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Old          string     `json:"old"`
+	New          string     `json:"new"`
+	Since        Version    `json:"since"`
+	DeprecatedAt time.Time  `json:"deprecated_at"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
+}
+
+// Registry holds the declared deprecations. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	versions map[Version]VersionDeprecation
+	routes   map[string]RouteDeprecation
+	fields   map[string][]FieldRename
+}
+
+// NewRegistry creates an empty deprecation registry
+func NewRegistry() *Registry {
+	return &Registry{
+		versions: make(map[Version]VersionDeprecation),
+		routes:   make(map[string]RouteDeprecation),
+		fields:   make(map[string][]FieldRename),
+	}
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// DeprecateVersion declares an API version deprecated
+func (r *Registry) DeprecateVersion(d VersionDeprecation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions[d.Version] = d
+}
+
+// DeprecateRoute declares a route deprecated
+func (r *Registry) DeprecateRoute(d RouteDeprecation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[routeKey(d.Method, d.Path)] = d
+}
+
+// RenameField declares a renamed JSON field on a route
+func (r *Registry) RenameField(f FieldRename) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := routeKey(f.Method, f.Path)
+	r.fields[key] = append(r.fields[key], f)
+}
+
+// Version returns the deprecation of an API version, if any
+func (r *Registry) Version(v Version) (VersionDeprecation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.versions[v]
+	return d, ok
+}
+
+// Route returns the deprecation of a route, if any
+func (r *Registry) Route(method, path string) (RouteDeprecation, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.routes[routeKey(method, path)]
+	return d, ok
+}
+
+// Fields returns the renamed fields of a route
+func (r *Registry) Fields(method, path string) []FieldRename {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]FieldRename(nil), r.fields[routeKey(method, path)]...)
+}
+
+// VersionInfo describes an API version in the registry listing
+type VersionInfo struct {
+	Version     Version             `json:"version"`
+	Latest      bool                `json:"latest"`
+	Deprecation *VersionDeprecation `json:"deprecation,omitempty"`
+}
+
+// Listing is the full deprecation registry as served by the API
+type Listing struct {
+	Versions     []VersionInfo      `json:"versions"`
+	Routes       []RouteDeprecation `json:"routes"`
+	FieldRenames []FieldRename      `json:"field_renames"`
+}
+
+// List returns every version and deprecation, routes and fields ordered by sunset date
+func (r *Registry) List() Listing {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	listing := Listing{
+		Versions:     make([]VersionInfo, 0, len(Supported)),
+		Routes:       make([]RouteDeprecation, 0, len(r.routes)),
+		FieldRenames: []FieldRename{},
+	}
+
+	for _, v := range Supported {
+		info := VersionInfo{Version: v, Latest: v == Latest}
+		if d, ok := r.versions[v]; ok {
+			d := d
+			info.Deprecation = &d
+		}
+		listing.Versions = append(listing.Versions, info)
+	}
+
+	for _, d := range r.routes {
+		listing.Routes = append(listing.Routes, d)
+	}
+	sort.Slice(listing.Routes, func(i, j int) bool {
+		a, b := listing.Routes[i], listing.Routes[j]
+		if !sunsetEqual(a.SunsetAt, b.SunsetAt) {
+			return sunsetBefore(a.SunsetAt, b.SunsetAt)
+		}
+		return routeKey(a.Method, a.Path) < routeKey(b.Method, b.Path)
+	})
+
+	for _, fields := range r.fields {
+		listing.FieldRenames = append(listing.FieldRenames, fields...)
+	}
+	sort.Slice(listing.FieldRenames, func(i, j int) bool {
+		a, b := listing.FieldRenames[i], listing.FieldRenames[j]
+		if !sunsetEqual(a.SunsetAt, b.SunsetAt) {
+			return sunsetBefore(a.SunsetAt, b.SunsetAt)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Old < b.Old
+	})
+
+	return listing
+}
+
+// sunsetBefore orders sunset dates with unscheduled (nil) dates last
+func sunsetBefore(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return a.Before(*b)
+}
+
+func sunsetEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
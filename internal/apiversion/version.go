@@ -0,0 +1,79 @@
+// Package apiversion implements REST API version negotiation and deprecation.
+//
+// Versions are selected by path (/api/v1, /api/v2). A route that a newer
+// version does not override is served by the previous version's handler, so
+// a version only has to register the routes that changed. Deprecated versions,
+// routes and renamed fields are declared in a Registry; the middleware
+// announces them with Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// and answers 410 Gone once the sunset date has passed.
+package apiversion
+
+import (
+	"strings"
+)
+
+// Version is a path-based API version such as "v1"
+type Version string
+
+// Supported API versions, oldest first
+const (
+	V1 Version = "v1"
+	V2 Version = "v2"
+)
+
+// Latest is the newest API version
+const Latest = V2
+
+// Supported lists the API versions served, oldest first
+var Supported = []Version{V1, V2}
+
+// HeaderAPIVersion reports the API version that served a response
+const HeaderAPIVersion = "API-Version"
+
+// IsSupported reports whether the version is served
+func (v Version) IsSupported() bool {
+	return v.index() >= 0
+}
+
+// Previous returns the version before v, or false for the oldest version
+func (v Version) Previous() (Version, bool) {
+	i := v.index()
+	if i <= 0 {
+		return "", false
+	}
+	return Supported[i-1], true
+}
+
+// Before reports whether v is older than other
+func (v Version) Before(other Version) bool {
+	return v.index() < other.index()
+}
+
+// Prefix returns the route prefix for the version, e.g. "/api/v1"
+func (v Version) Prefix() string {
+	return "/api/" + string(v)
+}
+
+func (v Version) index() int {
+	for i, s := range Supported {
+		if s == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParsePath splits an API path into its version and the remainder. It returns
+// false for paths outside /api/v<N>; the version is not checked for support.
+func ParsePath(path string) (Version, string, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok || len(rest) < 2 || rest[0] != 'v' || rest[1] < '0' || rest[1] > '9' {
+		return "", "", false
+	}
+
+	segment, remainder, found := strings.Cut(rest, "/")
+	if found {
+		remainder = "/" + remainder
+	}
+	return Version(segment), remainder, true
+}
@@ -2,30 +2,55 @@ package app
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
 	"github.com/aosanya/CodeValdCortex/internal/agency/arangodb"
 	"github.com/aosanya/CodeValdCortex/internal/agency/services"
-	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/alertrouting"
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/aosanya/CodeValdCortex/internal/audit"
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/backfill"
+	"github.com/aosanya/CodeValdCortex/internal/bootstrap"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/capability"
+	"github.com/aosanya/CodeValdCortex/internal/changefeed"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	"github.com/aosanya/CodeValdCortex/internal/communication"
 	"github.com/aosanya/CodeValdCortex/internal/config"
 	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/aosanya/CodeValdCortex/internal/derivedmetrics"
 	"github.com/aosanya/CodeValdCortex/internal/handlers"
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/incident"
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+	"github.com/aosanya/CodeValdCortex/internal/ratelimit"
 	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/aosanya/CodeValdCortex/internal/rules"
 	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/aosanya/CodeValdCortex/internal/templates"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/aosanya/CodeValdCortex/internal/topology"
+	"github.com/aosanya/CodeValdCortex/internal/usage"
+	"github.com/aosanya/CodeValdCortex/internal/usecase"
 	webhandlers "github.com/aosanya/CodeValdCortex/internal/web/handlers"
 	"github.com/aosanya/CodeValdCortex/internal/web/handlers/ai_refine"
 	webmiddleware "github.com/aosanya/CodeValdCortex/internal/web/middleware"
 	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/aosanya/CodeValdCortex/internal/workorder"
+	"github.com/aosanya/CodeValdCortex/internal/zonesummary"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -44,6 +69,7 @@ type App struct {
 	runtimeManager      *runtime.Manager
 	messageService      *communication.MessageService
 	pubSubService       *communication.PubSubService
+	publicationExpiry   *communication.ExpirySweeper
 	aiDesignerService   *ai.AgencyDesignerService
 	introductionRefiner *ai.IntroductionBuilder
 	goalRefiner         *ai.GoalsBuilder
@@ -51,7 +77,37 @@ type App struct {
 	roleBuilder         *ai.RolesBuilder
 	raciBuilder         *ai.RACIBuilder
 	workflowBuilder     *ai.WorkflowsBuilder
+	itemAdapter         *ai.ItemAdapter
 	workflowService     *workflow.Service
+	statusHistory       *health.StatusHistoryService
+	healthScores        *health.HealthScoreService
+	healthMonitor       *health.Monitor
+	zoneSummaryService  *zonesummary.Service
+	bootstrapService    *bootstrap.Service
+	templateEngine      *templates.Engine
+	apiVersions         *apiversion.Registry
+	alertRouter         *alertrouting.Service
+	usageService        *usage.Service
+	llmCaptures         *ai.CaptureStore
+	simClock            *clock.Virtual
+	capabilities        *capability.Service
+	derivedMetrics      *derivedmetrics.Service
+	telemetry           *telemetry.Service
+	rules               *rules.Service
+	incidents           *incident.Service
+	workOrders          *workorder.Service
+	outbox              *outbox.Dispatcher
+	auth                *auth.Service
+	auditLog            *audit.Log
+	rateLimiter         *ratelimit.Limiter
+	topology            *topology.Service
+	jobs                *jobs.Queue
+	changeFeed          *changefeed.Feed
+	backfill            *backfill.Migrator
+	memory              *memory.Service
+	memorySync          *memory.Synchronizer
+	memorySnapshots     *memory.SnapshotScheduler
+	orchestration       *workflowOrchestration
 }
 
 // New creates a new application instance
@@ -89,21 +145,6 @@ func New(cfg *config.Config) *App {
 		logger.WithError(err).Warn("Failed to initialize default roles")
 	}
 
-	// Load use case-specific roles from config directory
-	useCaseConfigDir := os.Getenv("USECASE_CONFIG_DIR")
-	if useCaseConfigDir != "" {
-		rolesDir := filepath.Join(useCaseConfigDir, "config", "agents")
-		if err := loadRolesFromDirectory(ctx, rolesDir, roleService, logger); err != nil {
-			logger.WithError(err).Warn("Failed to load use case roles")
-		}
-
-		// Load use case-specific agent instances from data directory
-		agentDataDir := filepath.Join(useCaseConfigDir, "data")
-		if err := loadAgentInstancesFromDirectory(ctx, agentDataDir, reg, logger); err != nil {
-			logger.WithError(err).Warn("Failed to load use case agent instances")
-		}
-	}
-
 	// Initialize communication repository and services
 	logger.Info("Initializing communication services")
 	commRepo, err := communication.NewRepository(dbClient)
@@ -113,10 +154,72 @@ func New(cfg *config.Config) *App {
 
 	var messageService *communication.MessageService
 	var pubSubService *communication.PubSubService
+	var publicationExpiry *communication.ExpirySweeper
+	var masking *communication.PayloadMasking
+
+	// Simulation time replaces the wall clock of time-dependent services
+	var simClock *clock.Virtual
+	if cfg.Simulation.Enabled {
+		start := time.Now()
+		if cfg.Simulation.StartTime != "" {
+			if start, err = time.Parse(time.RFC3339, cfg.Simulation.StartTime); err != nil {
+				logger.WithError(err).Fatal("Invalid simulation start_time")
+			}
+		}
+		simClock = clock.NewVirtual(clock.VirtualConfig{
+			Start:  start,
+			Speed:  cfg.Simulation.Speed,
+			Paused: cfg.Simulation.Speed <= 0,
+		})
+		logger.WithFields(logrus.Fields{
+			"start_time": start,
+			"speed":      cfg.Simulation.Speed,
+		}).Info("Simulation time enabled")
+	}
 
 	if commRepo != nil {
 		messageService = communication.NewMessageService(commRepo)
 		pubSubService = communication.NewPubSubService(commRepo)
+		if len(cfg.MessageOrdering.MessageTypes) > 0 {
+			if err := messageService.SetOrdering(communication.OrderingConfig{
+				MessageTypes: cfg.MessageOrdering.MessageTypes,
+				GapTimeout:   time.Duration(cfg.MessageOrdering.GapTimeoutSeconds) * time.Second,
+				TraceSize:    cfg.MessageOrdering.TraceSize,
+			}); err != nil {
+				logger.WithError(err).Fatal("Invalid message ordering configuration")
+			}
+		}
+		if err := messageService.SetPriority(communication.PriorityConfig{
+			PreemptPriority: cfg.MessagePriority.PreemptPriority,
+			Aging:           time.Duration(cfg.MessagePriority.AgingSeconds) * time.Second,
+		}); err != nil {
+			logger.WithError(err).Fatal("Invalid message priority configuration")
+		}
+		if simClock != nil {
+			commRepo.SetClock(simClock)
+			messageService.SetClock(simClock)
+			pubSubService.SetClock(simClock)
+		}
+		if len(cfg.Masking.Rules) > 0 {
+			masking, err = payloadMasking(cfg.Masking)
+			if err != nil {
+				logger.WithError(err).Fatal("Invalid payload masking configuration")
+			}
+			messageService.SetMasking(masking)
+			pubSubService.SetMasking(masking)
+			logger.WithField("rules", len(cfg.Masking.Rules)).Info("Payload masking enabled")
+		}
+		// Push subscribers without an in-process handler get publications in their inbox
+		pubSubService.SetInbox(messageService)
+		if err := pubSubService.SetTopicStore(ctx, commRepo); err != nil {
+			logger.WithError(err).Warn("Failed to load topic aliases and retention policies")
+		}
+		if !cfg.PublicationExpiry.Disabled {
+			publicationExpiry = communication.NewExpirySweeper(pubSubService, communication.ExpiryConfig{
+				Interval:  time.Duration(cfg.PublicationExpiry.IntervalSeconds) * time.Second,
+				BatchSize: cfg.PublicationExpiry.BatchSize,
+			})
+		}
 		logger.Info("Communication services initialized successfully")
 	}
 
@@ -126,8 +229,34 @@ func New(cfg *config.Config) *App {
 		HealthCheckInterval: 30 * time.Second,
 		ShutdownTimeout:     30 * time.Second,
 		EnableMetrics:       true,
+		Liveness: registry.LivenessConfig{
+			DegradedAfter: time.Duration(cfg.Agent.DegradedAfterSeconds) * time.Second,
+			OfflineAfter:  time.Duration(cfg.Agent.OfflineAfterSeconds) * time.Second,
+		},
 	}, reg)
 
+	if messageService != nil && len(cfg.Guardrails.Policies) > 0 {
+		if err := messageService.SetGuardrails(guardrailConfig(cfg.Guardrails), func(agentID string) (string, bool) {
+			a, err := runtimeManager.GetAgent(agentID)
+			if err != nil {
+				return "", false
+			}
+			return a.Type, true
+		}); err != nil {
+			logger.WithError(err).Fatal("Invalid guardrail configuration")
+		}
+		logger.WithField("policies", len(cfg.Guardrails.Policies)).Info("Command guardrails enabled")
+	}
+
+	// Initialize agent status history (falls back to in-memory storage)
+	var statusHistoryRepo health.StatusHistoryRepository
+	statusHistoryRepo, err = health.NewArangoStatusHistoryRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize status history repository, using in-memory storage")
+		statusHistoryRepo = health.NewInMemoryStatusHistoryRepository()
+	}
+	statusHistory := health.NewStatusHistoryService(statusHistoryRepo, logger)
+
 	// Initialize agency management
 	logger.Info("Initializing agency management service")
 	agencyRepo, err := arangodb.New(dbClient.Client(), dbClient.Database())
@@ -139,6 +268,26 @@ func New(cfg *config.Config) *App {
 	agencyService := services.NewWithDBInit(agencyRepo, agencyValidator, agencyDBInit)
 	logger.Info("Agency management service initialized successfully")
 
+	// Initialize usage metering (falls back to in-memory storage)
+	var usageRepo usage.Repository
+	usageRepo, err = usage.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize usage repository, using in-memory storage")
+		usageRepo = usage.NewInMemoryRepository()
+	}
+	usageService := usage.NewService(usageRepo, usage.ConfigFromConfig(cfg.Usage), logger)
+	usageService.SetStorageSource(usage.NewArangoStorageSource(dbClient.Client(), agencyService))
+	llmCallRepo, err := usage.NewArangoLLMCallRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize LLM call repository, using in-memory storage")
+		usageService.SetLLMCallRepository(usage.NewInMemoryLLMCallRepository())
+	} else {
+		usageService.SetLLMCallRepository(llmCallRepo)
+	}
+	if cfg.Usage.Enabled && pubSubService != nil {
+		pubSubService.AddPublishObserver(usageService.ObservePublications())
+	}
+
 	// Initialize AI services
 	var aiDesignerService *ai.AgencyDesignerService
 	var introductionRefiner *ai.IntroductionBuilder
@@ -147,35 +296,96 @@ func New(cfg *config.Config) *App {
 	var roleBuilder *ai.RolesBuilder
 	var raciBuilder *ai.RACIBuilder
 	var workflowBuilder *ai.WorkflowsBuilder
-	if cfg.AI.Provider != "" {
-		// Build LLM config from app config
-		llmConfig := &ai.LLMConfig{
-			Provider:    ai.Provider(cfg.AI.Provider),
-			APIKey:      cfg.AI.APIKey,
-			Model:       cfg.AI.Model,
-			BaseURL:     cfg.AI.BaseURL,
-			Temperature: cfg.AI.Temperature,
-			MaxTokens:   cfg.AI.MaxTokens,
-			Timeout:     cfg.AI.Timeout,
-		}
-
-		llmClient, err := ai.NewLLMClient(llmConfig)
+	var itemAdapter *ai.ItemAdapter
+	var llmClient ai.LLMClient
+	var llmCaptures *ai.CaptureStore
+	if cfg.AI.Enabled() {
+		// Providers, per-feature models and failover come from the ai config
+		client, err := newLLMRegistry(cfg.AI, logger)
 		if err != nil {
 			logger.WithError(err).Error("Failed to initialize LLM client")
 		} else {
+			llmClient = client
+			if cfg.Usage.Enabled {
+				llmClient = usage.NewMeteredLLMClient(client, usageService)
+			}
+			if cfg.AI.Capture.Enabled {
+				llmCaptures, err = ai.NewCaptureStore(ai.CaptureConfig{
+					MaxEntries:     cfg.AI.Capture.MaxEntries,
+					MaxAge:         time.Duration(cfg.AI.Capture.MaxAgeMinutes) * time.Minute,
+					Secrets:        llmSecrets(cfg.AI),
+					SecretPatterns: cfg.AI.Capture.RedactPatterns,
+				})
+				if err != nil {
+					logger.WithError(err).Fatal("Invalid LLM capture redact pattern")
+				}
+				llmClient = ai.NewCapturingLLMClient(llmClient, llmCaptures)
+				logger.Warn("LLM call capture enabled: prompts and responses are kept in memory")
+			}
+			if cfg.AI.Cache.Enabled {
+				llmClient = ai.NewCachingLLMClient(llmClient, newResponseCache(cfg.AI.Cache, dbClient, logger), ai.ResponseCacheConfig{
+					TTL:        time.Duration(cfg.AI.Cache.TTLSeconds) * time.Second,
+					Operations: cfg.AI.Cache.Operations,
+				}, logger)
+				logger.WithField("store", cfg.AI.Cache.Store).Info("LLM response cache enabled")
+			}
 			aiDesignerService = ai.NewAgencyDesignerService(llmClient, logger)
+			if conversationStore, err := ai.NewArangoConversationStore(dbClient); err != nil {
+				logger.WithError(err).Warn("Failed to initialize conversation store, designer conversations will not survive restarts")
+			} else {
+				aiDesignerService.SetConversationStore(conversationStore)
+			}
 			introductionRefiner = ai.NewAIIntroductionBuilder(llmClient, logger)
 			goalRefiner = ai.NewGoalRefiner(llmClient, logger)
 			workItemBuilder = ai.NewAIWorkItemsBuilder(llmClient, logger)
 			roleBuilder = ai.NewAIRolesBuilder(llmClient, logger)
+			if cfg.AI.RepairAttempts != 0 {
+				goalRefiner.SetRepairAttempts(cfg.AI.RepairAttempts)
+				workItemBuilder.SetRepairAttempts(cfg.AI.RepairAttempts)
+				roleBuilder.SetRepairAttempts(cfg.AI.RepairAttempts)
+			}
 			raciBuilder = ai.NewAIRACIBuilder(llmClient, logger)
 			workflowBuilder = ai.NewAIWorkflowsBuilder(llmClient, logger)
+			itemAdapter = ai.NewItemAdapter(llmClient, logger)
 			logger.Info("AI agency designer service initialized successfully")
 		}
 	} else {
 		logger.Info("AI configuration not provided, AI designer will not be available")
 	}
 
+	// Apply strict agency validation
+	if cfg.AgencyValidation.Strict {
+		if composite, ok := agencyService.(*services.CompositeService); ok {
+			var duplicates agency.DuplicateChecker
+			if cfg.AgencyValidation.AIDuplicateCheck && llmClient != nil {
+				duplicates = ai.NewDuplicateChecker(llmClient, logger)
+			}
+			composite.SetValidationPolicy(agency.PolicyFromConfig(cfg.AgencyValidation), duplicates)
+			logger.Info("Strict agency validation enabled")
+		}
+	}
+
+	// Broadcast design changes to open designer sessions
+	changeFeed := changefeed.NewFeed(changefeed.ConfigFromConfig(cfg.ChangeFeed), logger)
+	agencyService = changefeed.WrapAgencyService(agencyService, changeFeed)
+
+	// Confine tenant-scoped requests to their tenant's agencies
+	agencyService = tenant.WrapAgencyService(agencyService)
+	if aiDesignerService != nil {
+		aiDesignerService.AddMessageObserver(func(conversation *ai.ConversationContext, message ai.Message) {
+			if message.Role == "system" {
+				return
+			}
+			changeFeed.Publish(changefeed.Change{
+				AgencyID:  conversation.AgencyID,
+				Entity:    changefeed.EntityConversationMessage,
+				Action:    changefeed.ActionCreated,
+				EntityKey: conversation.ID,
+				Data:      map[string]interface{}{"role": message.Role},
+			})
+		})
+	}
+
 	// Initialize workflow service
 	workflowRepo, err := workflow.NewArangoRepository(dbClient.Database(), logger)
 	if err != nil {
@@ -183,6 +393,290 @@ func New(cfg *config.Config) *App {
 	}
 	workflowService := workflow.NewService(workflowRepo, logger)
 	logger.Info("Workflow service initialized successfully")
+	usageService.SetTaskSource(usage.NewWorkflowTaskSource(agencyService, workflowService))
+
+	// Initialize agent health scoring (falls back to in-memory storage)
+	var healthScoreRepo health.HealthScoreRepository
+	healthScoreRepo, err = health.NewArangoHealthScoreRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize health score repository, using in-memory storage")
+		healthScoreRepo = health.NewInMemoryHealthScoreRepository()
+	}
+	var trafficSource health.TrafficSource
+	if pubSubService != nil {
+		trafficSource = pubSubService
+	}
+	healthScores := health.NewHealthScoreService(healthScoreRepo, trafficSource, statusHistory, runtimeManager,
+		health.ScoreConfigFromConfig(cfg.HealthScoring), logger)
+	if pubSubService != nil {
+		healthScores.SetPublisher(pubSubService)
+	}
+	if messageService != nil {
+		healthScores.SetMessageSource(messageService)
+	}
+	healthScores.SetLivenessSource(runtimeManager.Liveness())
+	runtimeManager.OnTaskResult(healthScores.RecordTaskResult)
+
+	// Record agent status transitions when agents change lifecycle state and
+	// when the health monitor, which checks agents while they run, sees their
+	// health change
+	healthMonitor := health.NewMonitor(health.DefaultHealthMonitorConfig(),
+		health.NewStatusHistoryPublisher(statusHistory, nil), logger)
+	runtimeManager.OnStateChange(statusHistory.RecordStateChange)
+	runtimeManager.OnStateChange(healthMonitor.FollowState)
+
+	// Initialize zone summary service
+	var zoneSummaryService *zonesummary.Service
+	if len(cfg.ZoneSummaries) > 0 {
+		zoneSummaryService = zonesummary.NewService(pubSubService, pubSubService, llmClient, zonesummary.NewLogNotifier(logger), logger)
+		for _, zoneCfg := range cfg.ZoneSummaries {
+			if err := zoneSummaryService.AddZone(zonesummary.ZoneConfigFromConfig(zoneCfg)); err != nil {
+				logger.WithError(err).WithField("zone", zoneCfg.Zone).Warn("Skipping invalid zone summary configuration")
+			}
+		}
+		logger.WithField("zones", len(zoneSummaryService.Zones())).Info("Zone summary service initialized successfully")
+	}
+
+	// Initialize derived metrics; ingest metrics are recomputed as their inputs are published
+	var derivedSource derivedmetrics.TrafficSource
+	if pubSubService != nil {
+		derivedSource = pubSubService
+	}
+	derivedMetrics := derivedmetrics.NewService(derivedmetrics.ConfigFromConfig(cfg.DerivedMetrics), derivedSource, logger)
+	for _, metricCfg := range cfg.DerivedMetrics.Metrics {
+		if err := derivedMetrics.Define(derivedmetrics.DefinitionFromConfig(metricCfg)); err != nil {
+			logger.WithError(err).WithField("metric", metricCfg.Name).Warn("Skipping invalid derived metric configuration")
+		}
+	}
+	if pubSubService != nil {
+		derivedMetrics.SetPublisher(pubSubService)
+		pubSubService.AddPublishObserver(derivedMetrics.ObservePublications())
+	}
+
+	// Initialize telemetry storage (falls back to in-memory storage)
+	var telemetryRepo telemetry.Repository
+	telemetryRepo, err = telemetry.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize telemetry repository, using in-memory storage")
+		telemetryRepo = telemetry.NewInMemoryRepository()
+	}
+	telemetryService := telemetry.NewService(telemetryRepo, telemetry.ConfigFromConfig(cfg.Telemetry), logger)
+	if simClock != nil {
+		telemetryService.SetClock(simClock)
+	}
+
+	// Initialize alerting rules over telemetry and publications (falls back to in-memory storage)
+	var rulesRepo rules.Repository
+	rulesRepo, err = rules.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize rule repository, using in-memory storage")
+		rulesRepo = rules.NewInMemoryRepository()
+	}
+	rulesService := rules.NewService(rulesRepo, logger)
+	if err := rulesService.Load(ctx); err != nil {
+		logger.WithError(err).Warn("Failed to load rules")
+	}
+	if messageService != nil {
+		rulesService.SetMessenger(messageService)
+	}
+	if pubSubService != nil {
+		rulesService.SetPublisher(pubSubService)
+		pubSubService.AddPublishObserver(rulesService.ObservePublications())
+	}
+	telemetryService.AddIngestObserver(rulesService.ObserveTelemetry())
+
+	// Initialize incident records (falls back to in-memory storage)
+	var incidentRepo incident.Repository
+	incidentRepo, err = incident.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize incident repository, using in-memory storage")
+		incidentRepo = incident.NewInMemoryRepository()
+	}
+	incidentService := incident.NewService(incidentRepo, incident.ConfigFromConfig(cfg.Incidents), logger)
+	if simClock != nil {
+		incidentService.SetClock(simClock)
+	}
+	if pubSubService != nil {
+		pubSubService.AddPublishObserver(incidentService.ObservePublications())
+	}
+
+	// Initialize maintenance work orders (falls back to in-memory storage)
+	var workOrderRepo workorder.Repository
+	workOrderRepo, err = workorder.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize work order repository, using in-memory storage")
+		workOrderRepo = workorder.NewInMemoryRepository()
+	}
+	workOrderService := workorder.NewService(workOrderRepo, logger)
+	if simClock != nil {
+		workOrderService.SetClock(simClock)
+	}
+
+	// Initialize the outbox for side effects of agency design changes. Side
+	// effects pending in memory would be lost on restart, so there is no
+	// in-memory fallback.
+	outboxStore, err := arangodb.NewOutboxStore(agencyRepo)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize outbox store")
+	}
+	outboxDispatcher := outbox.NewDispatcher(outboxStore, outbox.ConfigFromConfig(cfg.Outbox), logger)
+	outboxDispatcher.Register(outbox.KindWebhook, outbox.NewWebhookHandler(nil))
+
+	// Initialize API key authentication
+	authService, err := newAuthService(cfg.Auth, dbClient, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize API authentication")
+	}
+	auditLog, err := newAuditLog(cfg.Audit, dbClient, logger)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize API audit log")
+	}
+	if masking != nil {
+		// Keys created through the API may only grant the masking rules' permissions
+		var permissions []string
+		for _, rule := range masking.Rules() {
+			permissions = append(permissions, rule.Permission)
+		}
+		authService.SetPermissions(permissions...)
+		if auditLog != nil {
+			masking.SetAuditLog(audit.NewUnmaskLog(auditLog))
+		} else {
+			logger.Warn("Audit logging is disabled; unmask requests are only recorded in memory")
+		}
+	}
+
+	// Initialize API rate limiting
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		rateLimiter, err = ratelimit.NewLimiter(ratelimit.ConfigFromConfig(cfg.RateLimit), nil)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to initialize API rate limiting")
+		}
+	}
+
+	// Initialize the background job queue
+	var jobStore jobs.Store
+	if store, err := jobs.NewArangoStore(dbClient); err != nil {
+		logger.WithError(err).Warn("Failed to initialize job store, using in-memory store")
+		jobStore = jobs.NewInMemoryStore()
+	} else {
+		jobStore = store
+	}
+	jobQueue := jobs.NewQueue(jobStore, jobs.ConfigFromConfig(cfg.Jobs), logger)
+
+	// Initialize backfills of historical traffic, run as background jobs
+	var backfillLedger backfill.Ledger
+	if ledger, err := backfill.NewArangoLedger(dbClient); err != nil {
+		logger.WithError(err).Warn("Failed to initialize backfill ledger, using in-memory ledger")
+		backfillLedger = backfill.NewInMemoryLedger()
+	} else {
+		backfillLedger = ledger
+	}
+	var backfillSource backfill.TrafficSource
+	if pubSubService != nil {
+		backfillSource = pubSubService
+	}
+	backfillMigrator := backfill.NewMigrator(backfillLedger, backfillSource, backfill.ConfigFromConfig(cfg.Backfill), logger)
+	backfillMigrator.Register(backfill.NewDerivedMetricsPipeline(derivedMetrics))
+	backfillMigrator.Register(backfill.NewUsagePipeline(usageService))
+	jobQueue.Register(backfill.JobType, backfillMigrator.HandleJob, jobs.TypeOptions{Timeout: time.Hour})
+
+	// Initialize alert routing
+	alertPolicies, err := alertrouting.PoliciesFromConfig(cfg.AlertRouting)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid alert routing configuration, no routing policies loaded")
+		alertPolicies = alertrouting.NewPolicies(nil, nil)
+	}
+	alertRouter := alertrouting.NewService(alertPolicies, logger)
+	if messageService != nil {
+		alertRouter.SetMessenger(messageService)
+	}
+	if pubSubService != nil {
+		alertRouter.SetPublisher(pubSubService)
+	}
+	alertRouter.SetNotifier(alertrouting.NewLogNotifier(logger))
+	if simClock != nil {
+		alertRouter.SetClock(simClock)
+	}
+
+	// Initialize bootstrap service (subscriptions are only provisioned when pub/sub is available)
+	var subscriptionStore bootstrap.SubscriptionStore
+	if pubSubService != nil {
+		subscriptionStore = pubSubService
+	}
+	bootstrapService := bootstrap.NewService(runtimeManager, subscriptionStore, logger)
+
+	// Initialize agent template engine
+	templateEngine := templates.NewEngine(templates.NewInMemoryRepository(), templates.NewDefaultValidatorWithTypeService(roleService))
+
+	// Initialize the capability catalog (falls back to in-memory storage)
+	var capabilityRepo capability.Repository
+	capabilityRepo, err = capability.NewArangoRepository(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize capability repository, using in-memory storage")
+		capabilityRepo = capability.NewInMemoryRepository()
+	}
+	capabilityService := capability.NewService(capabilityRepo, capability.Sources{
+		Roles:     roleService,
+		Templates: templateEngine,
+		WorkItems: agencyService,
+		Workflows: workflowService,
+		Agents:    runtimeManager,
+	}, logger)
+
+	// Initialize the network topology built from pipe, valve and source agents,
+	// and the asset graph (falls back to in-memory storage)
+	topologyService := topology.NewService(runtimeManager, topology.ConfigFromConfig(cfg.Topology))
+	assetGraph, err := topology.NewArangoGraph(dbClient)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize asset graph, using in-memory storage")
+	} else {
+		topologyService.SetGraph(assetGraph)
+	}
+
+	// Initialize agent memory
+	memoryService, memorySync, memorySnapshots, err := newMemoryService(cfg, dbClient, func() []memory.SnapshotTarget {
+		agents := runtimeManager.ListAgents()
+		targets := make([]memory.SnapshotTarget, 0, len(agents))
+		for _, a := range agents {
+			targets = append(targets, memory.SnapshotTarget{AgentID: a.ID, AgentType: a.Type})
+		}
+		return targets
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Agent memory unavailable, memory endpoints and MCP memory tools disabled")
+	}
+
+	// Initialize the workflow orchestration engine
+	workflowEngine, err := newWorkflowOrchestration(cfg.Orchestration, dbClient, runtimeManager, memoryService, logger)
+	if err != nil {
+		logger.WithError(err).Warn("Workflow engine unavailable, execution endpoints disabled and no workflows are run for work orders, rules or health scores")
+	} else {
+		// Approved work orders, rule actions and health score rules run their
+		// designer workflows on the engine
+		designLauncher := orchestration.NewDesignLauncher(workflowEngine.engine, workflowService)
+		workOrderService.SetWorkflows(designLauncher)
+		rulesService.SetWorkflows(designLauncher)
+		healthScores.SetWorkflowStarter(designLauncher)
+	}
+
+	// Load the use case configured by USECASE_CONFIG_DIR
+	if useCaseConfigDir := os.Getenv("USECASE_CONFIG_DIR"); useCaseConfigDir != "" {
+		loader := usecase.NewLoader(usecase.Dependencies{
+			Roles:     roleService,
+			Agents:    reg,
+			Agencies:  agencyService,
+			Workflows: workflowService,
+			Templates: templateEngine,
+			Bootstrap: bootstrapService,
+		}, logger)
+		report, err := loader.Load(ctx, useCaseConfigDir)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to load use case")
+		} else {
+			report.Log(logger)
+		}
+	}
 
 	return &App{
 		config:              cfg,
@@ -196,6 +690,7 @@ func New(cfg *config.Config) *App {
 		runtimeManager:      runtimeManager,
 		messageService:      messageService,
 		pubSubService:       pubSubService,
+		publicationExpiry:   publicationExpiry,
 		aiDesignerService:   aiDesignerService,
 		introductionRefiner: introductionRefiner,
 		goalRefiner:         goalRefiner,
@@ -203,7 +698,37 @@ func New(cfg *config.Config) *App {
 		roleBuilder:         roleBuilder,
 		raciBuilder:         raciBuilder,
 		workflowBuilder:     workflowBuilder,
+		itemAdapter:         itemAdapter,
 		workflowService:     workflowService,
+		statusHistory:       statusHistory,
+		healthMonitor:       healthMonitor,
+		healthScores:        healthScores,
+		zoneSummaryService:  zoneSummaryService,
+		bootstrapService:    bootstrapService,
+		templateEngine:      templateEngine,
+		apiVersions:         apiversion.NewRegistry(),
+		alertRouter:         alertRouter,
+		usageService:        usageService,
+		llmCaptures:         llmCaptures,
+		simClock:            simClock,
+		capabilities:        capabilityService,
+		derivedMetrics:      derivedMetrics,
+		telemetry:           telemetryService,
+		rules:               rulesService,
+		incidents:           incidentService,
+		workOrders:          workOrderService,
+		outbox:              outboxDispatcher,
+		auth:                authService,
+		auditLog:            auditLog,
+		rateLimiter:         rateLimiter,
+		topology:            topologyService,
+		jobs:                jobQueue,
+		changeFeed:          changeFeed,
+		backfill:            backfillMigrator,
+		memory:              memoryService,
+		memorySync:          memorySync,
+		memorySnapshots:     memorySnapshots,
+		orchestration:       workflowEngine,
 	}
 }
 
@@ -230,6 +755,66 @@ func (a *App) Run() error {
 		}
 	}()
 
+	// Run simulated time
+	if a.simClock != nil {
+		a.simClock.Start(ctx)
+	}
+
+	// Start periodic zone summaries
+	if a.zoneSummaryService != nil {
+		a.zoneSummaryService.Start(ctx)
+	}
+
+	// Start scheduled agent health scoring
+	if a.config.HealthScoring.Enabled {
+		a.healthScores.Start(ctx)
+	}
+
+	// Start routing alert publications
+	if a.config.AlertRouting.Enabled {
+		if a.pubSubService == nil {
+			a.logger.Warn("Alert routing enabled but pub/sub is not available")
+		} else if err := a.alertRouter.Start(ctx, a.pubSubService); err != nil {
+			a.logger.WithError(err).Warn("Failed to start alert routing")
+		}
+	}
+
+	// Start daily usage aggregation
+	if a.config.Usage.Enabled {
+		a.usageService.Start(ctx)
+	}
+
+	// Remove expired publications
+	if a.publicationExpiry != nil {
+		a.publicationExpiry.Start(ctx)
+	}
+
+	// Downsample and expire telemetry
+	a.telemetry.Start(ctx)
+
+	// Retry pending outbox entries
+	a.outbox.Start(ctx)
+
+	// Run background jobs
+	a.jobs.Start(ctx)
+
+	// Sync the asset graph from the distribution network
+	a.topology.Start(ctx)
+
+	// Take periodic memory snapshots
+	if a.memorySnapshots != nil {
+		if err := a.memorySnapshots.Start(ctx); err != nil {
+			a.logger.WithError(err).Warn("Failed to start memory snapshot scheduler")
+		}
+	}
+
+	// Run workflow executions
+	if a.orchestration != nil {
+		if err := a.orchestration.start(); err != nil {
+			a.logger.WithError(err).Warn("Failed to start workflow engine")
+		}
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -237,6 +822,31 @@ func (a *App) Run() error {
 
 	a.logger.Info("Shutting down server...")
 
+	if a.zoneSummaryService != nil {
+		a.zoneSummaryService.Stop()
+	}
+	a.healthScores.Stop()
+	a.healthMonitor.Shutdown()
+	if a.config.Usage.Enabled {
+		a.usageService.Stop()
+	}
+	if a.publicationExpiry != nil {
+		a.publicationExpiry.Stop()
+	}
+	a.telemetry.Stop()
+	a.outbox.Stop()
+	a.jobs.Stop()
+	a.topology.Stop()
+	if a.memorySnapshots != nil {
+		a.memorySnapshots.Stop()
+	}
+	if a.orchestration != nil {
+		a.orchestration.stop(a.logger)
+	}
+	if a.simClock != nil {
+		a.simClock.Stop()
+	}
+
 	// Shutdown runtime manager first
 	a.logger.Info("Shutting down runtime manager")
 	if err := a.runtimeManager.Shutdown(); err != nil {
@@ -272,13 +882,57 @@ func (a *App) setupServer() error {
 	}
 
 	router := gin.New()
+	// Client addresses only come from X-Forwarded-For behind a trusted proxy,
+	// so unauthenticated clients cannot pick their own rate limit bucket
+	if err := router.SetTrustedProxies(a.config.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid server.trusted_proxies: %w", err)
+	}
 
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// API versioning: newer versions fall back to the previous version's routes
+	router.Use(apiversion.Middleware(a.apiVersions))
+	router.Use(changefeed.ActorMiddleware())
+	if a.auditLog != nil {
+		router.Use(audit.Middleware(a.auditLog))
+		a.logger.Info("API audit log enabled")
+	}
+	if a.config.Auth.Enabled && a.rateLimiter != nil {
+		// Requests refused by authentication never reach the per-client
+		// limit, so their addresses are throttled before keys are looked up
+		router.Use(ratelimit.UnauthenticatedMiddleware(a.rateLimiter))
+	}
+	if a.config.Auth.Enabled {
+		// MCP clients authenticate with the MCP server's own tokens
+		var exempt []string
+		if path := a.mcpPath(); path != "" {
+			exempt = append(exempt, path)
+		}
+		router.Use(auth.Middleware(a.auth, exempt...))
+		router.Use(tenant.Middleware(a.agencyService))
+		a.logger.Info("API key authentication enabled")
+	}
+	if a.rateLimiter != nil {
+		router.Use(ratelimit.Middleware(a.rateLimiter))
+		a.logger.Info("API rate limiting enabled")
+	}
+
+	// Request IDs tie captured and metered LLM calls to the request that made them
+	if a.llmCaptures != nil || a.config.Usage.Enabled {
+		router.Use(ai.RequestIDMiddleware())
+	}
+	if a.config.AI.Cache.Enabled {
+		router.Use(ai.CacheBypassMiddleware())
+	}
+	router.NoRoute(apiversion.Fallback(router))
+	apiVersionHandler := handlers.NewAPIVersionHandler(a.apiVersions, a.logger)
+	apiVersionHandler.RegisterRoutes(router)
+
 	// Register agent handler routes
 	agentHandler := handlers.NewAgentHandler(a.runtimeManager, a.logger)
+	agentHandler.SetHealthScores(a.healthScores)
 	agentHandler.RegisterRoutes(router)
 
 	// Register task handler routes
@@ -288,16 +942,131 @@ func (a *App) setupServer() error {
 	// Register communication handler routes (if services are available)
 	if a.messageService != nil && a.pubSubService != nil {
 		commHandler := handlers.NewCommunicationHandler(a.messageService, a.pubSubService, a.logger)
+		commHandler.SetExpirySweeper(a.publicationExpiry)
 		commHandler.RegisterRoutes(router)
+
+		// Lifecycle commands are delivered as command messages
+		commandHandler := handlers.NewAgentCommandHandler(runtime.NewCommander(a.runtimeManager, a.messageService, a.logger), a.runtimeManager, a.logger)
+		commandHandler.RegisterRoutes(router)
 		a.logger.Info("Communication endpoints registered")
 	} else {
 		a.logger.Warn("Communication services not available, endpoints not registered")
 	}
 
+	// Register agent status history routes
+	statusHistoryHandler := handlers.NewStatusHistoryHandler(a.statusHistory, a.runtimeManager, a.logger)
+	statusHistoryHandler.RegisterRoutes(router)
+
+	// Register agent health score routes
+	healthScoreHandler := handlers.NewHealthScoreHandler(a.healthScores, a.runtimeManager, a.logger)
+	healthScoreHandler.RegisterRoutes(router)
+
+	// Register alert routing routes
+	alertRoutingHandler := handlers.NewAlertRoutingHandler(a.alertRouter, a.logger)
+	alertRoutingHandler.RegisterRoutes(router)
+
+	usageHandler := handlers.NewUsageHandler(a.usageService, a.logger)
+	usageHandler.RegisterRoutes(router)
+
+	// Register capability catalog and gap analysis routes
+	capabilityHandler := handlers.NewCapabilityHandler(a.capabilities, a.logger)
+	capabilityHandler.RegisterRoutes(router)
+
+	// Register derived metric routes
+	derivedMetricsHandler := handlers.NewDerivedMetricsHandler(a.derivedMetrics, a.logger)
+	derivedMetricsHandler.RegisterRoutes(router)
+
+	// Register telemetry ingestion and query routes
+	telemetryHandler := handlers.NewTelemetryHandler(a.telemetry, a.logger)
+	telemetryHandler.RegisterRoutes(router)
+
+	// Register alerting rule routes
+	rulesHandler := handlers.NewRulesHandler(a.rules, a.logger)
+	rulesHandler.RegisterRoutes(router)
+
+	// Register incident routes
+	incidentHandler := handlers.NewIncidentHandler(a.incidents, a.logger)
+	incidentHandler.RegisterRoutes(router)
+
+	// Register work order routes
+	workOrderHandler := handlers.NewWorkOrderHandler(a.workOrders, a.logger)
+	workOrderHandler.RegisterRoutes(router)
+
+	// Register topology, isolation impact and asset graph routes
+	topologyHandler := handlers.NewTopologyHandler(a.topology, a.logger)
+	topologyHandler.RegisterRoutes(router)
+
+	// Register outbox routes
+	outboxHandler := handlers.NewOutboxHandler(a.outbox, a.logger)
+	outboxHandler.RegisterRoutes(router)
+
+	// Register API key management and web UI session routes
+	authHandler := handlers.NewAuthHandler(a.auth, a.logger)
+	authHandler.RegisterRoutes(router)
+
+	// Register the audit log query route
+	if a.auditLog != nil {
+		auditHandler := handlers.NewAuditHandler(a.auditLog, a.logger)
+		auditHandler.RegisterRoutes(router)
+	}
+
+	// Register the Prometheus scrape endpoint
+	metricsHandler := handlers.NewMetricsHandler(a.logger)
+	if a.rateLimiter != nil {
+		metricsHandler.AddExporter(a.rateLimiter)
+	}
+	if a.orchestration != nil {
+		metricsHandler.AddExporter(a.orchestration.engine)
+	}
+	metricsHandler.RegisterRoutes(router)
+
+	// Register background job routes
+	jobsHandler := handlers.NewJobsHandler(a.jobs, a.logger)
+	jobsHandler.RegisterRoutes(router)
+
+	changeFeedHandler := handlers.NewChangeFeedHandler(a.changeFeed, a.logger)
+	changeFeedHandler.RegisterRoutes(router)
+
+	backfillHandler := handlers.NewBackfillHandler(a.backfill, a.jobs, a.logger)
+	backfillHandler.RegisterRoutes(router)
+
+	// Register workflow execution routes
+	if a.orchestration != nil {
+		executionHandler := handlers.NewExecutionHandler(a.orchestration.engine, a.logger)
+		executionHandler.RegisterRoutes(router)
+	}
+
+	// Register agent memory routes
+	if a.memory != nil {
+		memoryHandler := handlers.NewMemoryHandler(a.memory, a.logger)
+		memoryHandler.SetSynchronizer(a.memorySync)
+		memoryHandler.RegisterRoutes(router)
+	}
+
+	// Register LLM capture routes
+	if a.llmCaptures != nil {
+		llmCaptureHandler := handlers.NewLLMCaptureHandler(a.llmCaptures, a.logger)
+		llmCaptureHandler.RegisterRoutes(router)
+	}
+
+	// Register simulation clock routes
+	if a.simClock != nil {
+		simulationHandler := handlers.NewSimulationHandler(a.simClock, a.logger)
+		simulationHandler.RegisterRoutes(router)
+	}
+
+	// Register bootstrap routes
+	bootstrapHandler := handlers.NewBootstrapHandler(a.bootstrapService, a.logger)
+	bootstrapHandler.RegisterRoutes(router)
+
 	// Register web dashboard handler
 	dashboardHandler := webhandlers.NewDashboardHandler(a.runtimeManager, a.logger)
+	dashboardHandler.SetHealthScores(a.healthScores)
 	rolesWebHandler := webhandlers.NewRolesWebHandler(a.roleService, a.logger)
 	topologyVisualizerHandler := webhandlers.NewTopologyVisualizerHandler(a.runtimeManager, a.logger)
+	controlRoomHandler := webhandlers.NewControlRoomHandler(a.logger)
+	jobsWebHandler := webhandlers.NewJobsWebHandler(a.jobs, a.logger)
+	incidentsWebHandler := webhandlers.NewIncidentsWebHandler(a.incidents, a.logger)
 	// Initialize homepage handler
 	homepageHandler := webhandlers.NewHomepageHandler(a.agencyService, a.runtimeManager, a.dbClient, a.registry, a.logger)
 
@@ -321,8 +1090,15 @@ func (a *App) setupServer() error {
 			a.logger,
 		)
 
+		// Inject the live asset inventory into AI prompt context
+		infrastructureSource := builder.NewRuntimeInfrastructureSource(a.runtimeManager, a.statusHistory)
+		infrastructureSource.SetTelemetry(a.telemetry, 0)
+		aiRefineHandler.SetInfrastructureSource(infrastructureSource)
+		aiRefineHandler.SetOutbox(a.outbox)
+
 		aiDesignerWebHandler = webhandlers.NewAgencyDesignerWebHandler(a.aiDesignerService, a.agencyRepository, a.logger)
 		chatHandler = webhandlers.NewChatHandler(a.aiDesignerService, a.agencyService, a.roleService, a.introductionRefiner, a.goalRefiner, aiRefineHandler, a.logger)
+		chatHandler.SetInfrastructureSource(infrastructureSource)
 		a.logger.Info("AI Agency Designer web handler initialized")
 	} // Agency middleware
 	agencyMiddleware := webmiddleware.NewAgencyMiddleware(a.agencyService, a.logger)
@@ -331,10 +1107,18 @@ func (a *App) setupServer() error {
 	router.Static("/static", "./static")
 
 	// Web dashboard routes
+	router.GET(auth.LoginPath, webhandlers.NewLoginWebHandler(a.logger).ShowLogin)
 	router.GET("/", homepageHandler.ShowHomepage)
 	router.GET("/roles", rolesWebHandler.ShowRoles)
 	router.GET("/topology", topologyVisualizerHandler.ShowTopologyVisualizer)
 	router.GET("/geo-network", topologyVisualizerHandler.ShowGeographicVisualizer)
+	router.GET("/control-room", controlRoomHandler.ShowControlRoom)
+	router.GET("/jobs", jobsWebHandler.ShowJobs)
+	router.GET("/incidents", incidentsWebHandler.ShowIncidents)
+	if a.orchestration != nil {
+		approvalsWebHandler := webhandlers.NewApprovalsWebHandler(a.orchestration.engine, a.logger)
+		router.GET("/approvals", approvalsWebHandler.ShowApprovals)
+	}
 
 	// Agency routes
 	router.POST("/agencies/:id/select", homepageHandler.SelectAgency)
@@ -400,6 +1184,10 @@ func (a *App) setupServer() error {
 
 		// Agency endpoints
 		agencyHandler := handlers.NewAgencyHandler(a.agencyService, a.roleService, a.logger)
+		if a.itemAdapter != nil {
+			agencyHandler.SetItemAdapter(a.itemAdapter)
+		}
+		agencyHandler.SetOutbox(a.outbox)
 		v1.GET("/agencies", agencyHandler.ListAgencies)
 		v1.GET("/agencies/:id", agencyHandler.GetAgency)
 		v1.POST("/agencies", agencyHandler.CreateAgency)
@@ -413,17 +1201,36 @@ func (a *App) setupServer() error {
 		v1.GET("/agencies/:id/goals", agencyHandler.GetGoals)
 		v1.GET("/agencies/:id/goals/html", agencyHandler.GetGoalsHTML)
 		v1.POST("/agencies/:id/goals", agencyHandler.CreateGoal)
+		v1.PUT("/agencies/:id/goals/priorities", agencyHandler.ReprioritizeGoals)
+		v1.POST("/agencies/:id/goals/consolidations", agencyHandler.ConsolidateGoals)
+		v1.POST("/agencies/:id/goals/consolidations/:txId/undo", agencyHandler.UndoGoalConsolidation)
 		v1.PUT("/agencies/:id/goals/:goalKey", agencyHandler.UpdateGoal)
 		v1.DELETE("/agencies/:id/goals/:goalKey", agencyHandler.DeleteGoal)
+		v1.GET("/agencies/:id/goals/:goalKey/explanations", agencyHandler.GetGoalExplanations)
+		v1.GET("/agencies/:id/goals/:goalKey/explanations/html", agencyHandler.GetGoalExplanationsHTML)
+		v1.GET("/agencies/:id/traceability", agencyHandler.GetTraceability)
+		v1.GET("/agencies/:id/traceability/html", agencyHandler.GetTraceabilityHTML)
 
 		// Work Items endpoints
 		v1.GET("/agencies/:id/work-items", agencyHandler.GetWorkItems)
 		v1.GET("/agencies/:id/work-items/html", agencyHandler.GetWorkItemsHTML)
 		v1.POST("/agencies/:id/work-items", agencyHandler.CreateWorkItem)
 		v1.PUT("/agencies/:id/work-items/:key", agencyHandler.UpdateWorkItem)
+		v1.PATCH("/agencies/:id/work-items/:key/status", agencyHandler.TransitionWorkItem)
+		v1.PATCH("/agencies/:id/work-items/:key/assignee", agencyHandler.AssignWorkItem)
+		v1.GET("/agencies/:id/workload", agencyHandler.GetWorkload)
 		v1.DELETE("/agencies/:id/work-items/:key", agencyHandler.DeleteWorkItem)
+		v1.GET("/agencies/:id/work-items/:key/explanations", agencyHandler.GetWorkItemExplanations)
+		v1.GET("/agencies/:id/work-items/:key/explanations/html", agencyHandler.GetWorkItemExplanationsHTML)
 		v1.POST("/agencies/:id/work-items/validate-deps", agencyHandler.ValidateWorkItemDependencies)
 
+		// Cross-agency goal/work item copy and link endpoints
+		v1.POST("/agencies/:id/items/copy", agencyHandler.CopyItems)
+		v1.POST("/agencies/:id/items/link", agencyHandler.LinkItems)
+		v1.GET("/agencies/:id/export", agencyHandler.ExportAgency)
+		v1.GET("/agencies/:id/materialize", agencyHandler.MaterializeAgency)
+		v1.POST("/agencies/import", agencyHandler.ImportAgency)
+
 		// Roles endpoints
 		v1.GET("/agencies/:id/roles", agencyHandler.GetAgencyRoles)
 		v1.GET("/agencies/:id/roles/html", agencyHandler.GetAgencyRolesHTML)
@@ -453,46 +1260,56 @@ func (a *App) setupServer() error {
 
 		// AI Refine endpoints (if AI services are available)
 		if aiRefineHandler != nil {
-			v1.POST("/agencies/:id/overview/refine", aiRefineHandler.RefineIntroduction)
+			// AI requests are refused once the agency's monthly LLM token budget is spent
+			aiRoutes := v1.Group("", usage.LLMBudgetMiddleware(a.usageService))
+			aiRoutes.POST("/agencies/:id/overview/refine", aiRefineHandler.RefineIntroduction)
 			if a.goalRefiner != nil {
 				// Main dynamic router - handles all goal operations through natural language prompts
-				v1.POST("/agencies/:id/goals/refine-dynamic", aiRefineHandler.RefineGoals)
+				aiRoutes.POST("/agencies/:id/goals/refine-dynamic", aiRefineHandler.RefineGoals)
+				// Same operation for the designer chat, streamed as server-sent events
+				aiRoutes.GET("/agencies/:id/goals/refine-stream", aiRefineHandler.StreamGoalRefinement)
 				// Convenience routes that use RefineGoals with preset prompts
-				v1.POST("/agencies/:id/goals/:goalKey/refine", aiRefineHandler.RefineSpecificGoal)
-				v1.POST("/agencies/:id/goals/generate", aiRefineHandler.GenerateGoalWithPrompt)
-				v1.POST("/agencies/:id/goals/consolidate", aiRefineHandler.ConsolidateGoalsWithPrompt)
+				aiRoutes.POST("/agencies/:id/goals/:goalKey/refine", aiRefineHandler.RefineSpecificGoal)
+				aiRoutes.POST("/agencies/:id/goals/generate", aiRefineHandler.GenerateGoalWithPrompt)
+				aiRoutes.POST("/agencies/:id/goals/consolidate", aiRefineHandler.ConsolidateGoalsWithPrompt)
+				// AI-recommended priority ordering, accepted via PUT /goals/priorities
+				aiRoutes.POST("/agencies/:id/goals/rank", aiRefineHandler.RankGoals)
+				// Goal panel operations; "preview" returns a proposal instead of applying it
+				aiRoutes.POST("/agencies/:id/goals/ai-process", aiRefineHandler.ProcessAIGoalRequest)
 			}
 			if a.workItemBuilder != nil {
 				// Main dynamic router - handles all work item operations through natural language prompts
-				v1.POST("/agencies/:id/work-items/refine-dynamic", aiRefineHandler.RefineWorkItems)
+				aiRoutes.POST("/agencies/:id/work-items/refine-dynamic", aiRefineHandler.RefineWorkItems)
 				// Convenience routes that use RefineWorkItems with preset prompts
-				v1.POST("/agencies/:id/work-items/refine-specific", aiRefineHandler.RefineSpecificWorkItem)
-				v1.POST("/agencies/:id/work-items/generate", aiRefineHandler.GenerateWorkItemWithPrompt)
-				v1.POST("/agencies/:id/work-items/consolidate", aiRefineHandler.ConsolidateWorkItemsWithPrompt)
-				v1.POST("/agencies/:id/work-items/enhance-all", aiRefineHandler.EnhanceAllWorkItems)
+				aiRoutes.POST("/agencies/:id/work-items/refine-specific", aiRefineHandler.RefineSpecificWorkItem)
+				aiRoutes.POST("/agencies/:id/work-items/generate", aiRefineHandler.GenerateWorkItemWithPrompt)
+				aiRoutes.POST("/agencies/:id/work-items/consolidate", aiRefineHandler.ConsolidateWorkItemsWithPrompt)
+				aiRoutes.POST("/agencies/:id/work-items/enhance-all", aiRefineHandler.EnhanceAllWorkItems)
 			}
 			if a.roleBuilder != nil {
 				// Main dynamic router - handles all role operations through natural language prompts
-				v1.POST("/agencies/:id/roles/refine-dynamic", aiRefineHandler.RefineRoles)
+				aiRoutes.POST("/agencies/:id/roles/refine-dynamic", aiRefineHandler.RefineRoles)
 				// Convenience routes that use RefineRoles with preset prompts
-				v1.POST("/agencies/:id/roles/refine-specific", aiRefineHandler.RefineSpecificRole)
-				v1.POST("/agencies/:id/roles/generate", aiRefineHandler.GenerateRoleWithPrompt)
-				v1.POST("/agencies/:id/roles/consolidate", aiRefineHandler.ConsolidateRolesWithPrompt)
-				v1.POST("/agencies/:id/roles/enhance-all", aiRefineHandler.EnhanceAllRolesWithPrompt)
+				aiRoutes.POST("/agencies/:id/roles/refine-specific", aiRefineHandler.RefineSpecificRole)
+				aiRoutes.POST("/agencies/:id/roles/generate", aiRefineHandler.GenerateRoleWithPrompt)
+				aiRoutes.POST("/agencies/:id/roles/consolidate", aiRefineHandler.ConsolidateRolesWithPrompt)
+				aiRoutes.POST("/agencies/:id/roles/enhance-all", aiRefineHandler.EnhanceAllRolesWithPrompt)
 			}
 			if a.raciBuilder != nil {
 				// Main dynamic router - handles all RACI operations through natural language prompts
-				v1.POST("/agencies/:id/raci-matrix/refine-dynamic", aiRefineHandler.RefineRACIMappings)
+				aiRoutes.POST("/agencies/:id/raci-matrix/refine-dynamic", aiRefineHandler.RefineRACIMappings)
 				// Convenience routes that use RefineRACIMappings with preset prompts
-				v1.POST("/agencies/:id/raci-matrix/refine-specific", aiRefineHandler.RefineSpecificRACIMapping)
-				v1.POST("/agencies/:id/raci-matrix/generate", aiRefineHandler.GenerateRACIMappingWithPrompt)
-				v1.POST("/agencies/:id/raci-matrix/consolidate", aiRefineHandler.ConsolidateRACIMappingsWithPrompt)
-				v1.POST("/agencies/:id/raci-matrix/create-complete", aiRefineHandler.CreateCompleteRACIMatrixWithPrompt)
+				aiRoutes.POST("/agencies/:id/raci-matrix/refine-specific", aiRefineHandler.RefineSpecificRACIMapping)
+				aiRoutes.POST("/agencies/:id/raci-matrix/generate", aiRefineHandler.GenerateRACIMappingWithPrompt)
+				aiRoutes.POST("/agencies/:id/raci-matrix/consolidate", aiRefineHandler.ConsolidateRACIMappingsWithPrompt)
+				aiRoutes.POST("/agencies/:id/raci-matrix/create-complete", aiRefineHandler.CreateCompleteRACIMatrixWithPrompt)
 			}
 			if a.workflowBuilder != nil {
 				// Main dynamic router - handles all workflow operations through natural language prompts
-				v1.POST("/agencies/:id/workflows/refine-dynamic", aiRefineHandler.RefineWorkflows)
+				aiRoutes.POST("/agencies/:id/workflows/refine-dynamic", aiRefineHandler.RefineWorkflows)
 			}
+			// Applies a proposal returned by a preview request
+			aiRoutes.POST("/agencies/:id/ai/proposals/:proposalId/confirm", aiRefineHandler.ConfirmProposal)
 			a.logger.Info("AI Refine endpoints registered")
 		}
 
@@ -510,6 +1327,16 @@ func (a *App) setupServer() error {
 				"version":  "dev",
 			})
 		})
+
+		// Read replica state and how analytical query routes are being served
+		v1.GET("/database/routing", func(c *gin.Context) {
+			c.JSON(http.StatusOK, a.dbClient.Router().Status())
+		})
+	}
+
+	// Model Context Protocol server for external AI assistants and IDEs
+	if err := a.registerMCP(router); err != nil {
+		return fmt.Errorf("failed to set up MCP server: %w", err)
 	}
 
 	// Create server
@@ -523,167 +1350,45 @@ func (a *App) setupServer() error {
 	return nil
 }
 
-// loadRolesFromDirectory loads role definitions from JSON files in a directory
-func loadRolesFromDirectory(ctx context.Context, dir string, service registry.RoleService, logger *logrus.Logger) error {
-	// Check if directory exists
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		logger.WithField("dir", dir).Debug("Roles directory does not exist, skipping")
-		return nil
-	}
-
-	// Read all JSON files from directory
-	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
-	if err != nil {
-		return fmt.Errorf("failed to read roles directory: %w", err)
-	}
-
-	if len(files) == 0 {
-		logger.WithField("dir", dir).Debug("No role files found")
-		return nil
+// guardrailConfig converts the guardrail configuration into communication policies
+func guardrailConfig(cfg config.GuardrailsConfig) communication.GuardrailConfig {
+	guardrails := communication.GuardrailConfig{
+		CommandField:  cfg.CommandField,
+		MaxViolations: cfg.MaxViolations,
 	}
-
-	logger.WithFields(logrus.Fields{
-		"dir":   dir,
-		"count": len(files),
-	}).Info("Loading use case roles")
-
-	// Load each role file
-	for _, file := range files {
-		if err := loadRoleFromFile(ctx, file, service, logger); err != nil {
-			logger.WithError(err).WithField("file", file).Error("Failed to load role")
-			continue
+	for _, policy := range cfg.Policies {
+		ranges := make(map[string]communication.ValueRange, len(policy.ValueRanges))
+		for path, bounds := range policy.ValueRanges {
+			ranges[path] = communication.ValueRange{Min: bounds.Min, Max: bounds.Max}
 		}
+		guardrails.Policies = append(guardrails.Policies, communication.GuardrailPolicy{
+			AgentType:       policy.AgentType,
+			MessageTypes:    policy.MessageTypes,
+			AllowedCommands: policy.AllowedCommands,
+			ValueRanges:     ranges,
+			Preconditions:   policy.Preconditions,
+		})
 	}
-
-	return nil
-}
-
-// loadRoleFromFile loads a single role from a JSON file
-func loadRoleFromFile(ctx context.Context, filename string, service registry.RoleService, logger *logrus.Logger) error {
-	// Read file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse JSON
-	var role registry.Role
-	if err := json.Unmarshal(data, &role); err != nil {
-		return fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	// Register role
-	if err := service.RegisterType(ctx, &role); err != nil {
-		return fmt.Errorf("failed to register role: %w", err)
-	}
-
-	logger.WithFields(logrus.Fields{
-		"id":   role.ID,
-		"name": role.Name,
-		"tags": role.Tags,
-		"file": filepath.Base(filename),
-	}).Info("Loaded role")
-
-	return nil
+	return guardrails
 }
 
-// loadAgentInstancesFromDirectory loads agent instance definitions from JSON files in a directory
-func loadAgentInstancesFromDirectory(ctx context.Context, dir string, repo *registry.Repository, logger *logrus.Logger) error {
-	// Check if directory exists
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		logger.WithField("dir", dir).Debug("Agent instances directory does not exist, skipping")
-		return nil
-	}
-
-	// Read all JSON files from directory
-	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+// payloadMasking converts the masking configuration into payload masking
+func payloadMasking(cfg config.MaskingConfig) (*communication.PayloadMasking, error) {
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
 	if err != nil {
-		return fmt.Errorf("failed to read agent instances directory: %w", err)
-	}
-
-	if len(files) == 0 {
-		logger.WithField("dir", dir).Debug("No agent instance files found")
-		return nil
+		return nil, fmt.Errorf("masking key must be base64: %w", err)
 	}
-
-	logger.WithFields(logrus.Fields{
-		"dir":   dir,
-		"count": len(files),
-	}).Info("Loading use case agent instances")
-
-	loadedCount := 0
-	skippedCount := 0
-
-	// Load each agent instance file
-	for _, file := range files {
-		count, skipped, err := loadAgentInstancesFromFile(ctx, file, repo, logger)
-		if err != nil {
-			logger.WithError(err).WithField("file", file).Error("Failed to load agent instances")
-			continue
-		}
-		loadedCount += count
-		skippedCount += skipped
+	masking := communication.MaskingConfig{
+		Key:             key,
+		Mask:            cfg.Mask,
+		MaxAuditEntries: cfg.MaxAuditEntries,
 	}
-
-	logger.WithFields(logrus.Fields{
-		"loaded":  loadedCount,
-		"skipped": skippedCount,
-	}).Info("Completed loading agent instances")
-
-	return nil
-}
-
-// loadAgentInstancesFromFile loads agent instances from a JSON file
-func loadAgentInstancesFromFile(ctx context.Context, filename string, repo *registry.Repository, logger *logrus.Logger) (int, int, error) {
-	// Read file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Parse JSON as array of agents
-	var agents []agent.Agent
-	if err := json.Unmarshal(data, &agents); err != nil {
-		return 0, 0, fmt.Errorf("failed to parse JSON: %w", err)
-	}
-
-	loadedCount := 0
-	skippedCount := 0
-
-	// Create each agent instance
-	for i := range agents {
-		ag := &agents[i]
-		// Check if agent already exists
-		existing, err := repo.Get(ctx, ag.ID)
-		if err == nil && existing != nil {
-			logger.WithFields(logrus.Fields{
-				"id":   ag.ID,
-				"name": ag.Name,
-				"type": ag.Type,
-			}).Debug("Agent instance already exists, skipping")
-			skippedCount++
-			continue
-		}
-
-		// Create the agent
-		if err := repo.Create(ctx, ag); err != nil {
-			logger.WithError(err).WithFields(logrus.Fields{
-				"id":   ag.ID,
-				"name": ag.Name,
-				"type": ag.Type,
-			}).Error("Failed to create agent instance")
-			continue
-		}
-
-		logger.WithFields(logrus.Fields{
-			"id":   ag.ID,
-			"name": ag.Name,
-			"type": ag.Type,
-			"file": filepath.Base(filename),
-		}).Info("Loaded agent instance")
-
-		loadedCount++
+	for _, rule := range cfg.Rules {
+		masking.Rules = append(masking.Rules, communication.MaskingRule{
+			Topics:     rule.Topics,
+			Fields:     rule.Fields,
+			Permission: rule.Permission,
+		})
 	}
-
-	return loadedCount, skippedCount, nil
+	return communication.NewPayloadMasking(masking)
 }
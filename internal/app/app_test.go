@@ -0,0 +1,214 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/aosanya/CodeValdCortex/internal/ratelimit"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp returns an application without a database; tests set the
+// services they exercise before calling serve
+func newTestApp() *App {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return &App{
+		config:      &config.Config{LogLevel: "error"},
+		logger:      logger,
+		apiVersions: apiversion.NewRegistry(),
+	}
+}
+
+// serve sends a request through the application router
+func serve(t *testing.T, a *App, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveAs(t, a, "", method, path, body)
+}
+
+// serveAs sends a request authenticated with an API key secret through the
+// application router
+func serveAs(t *testing.T, a *App, secret, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	if a.server == nil {
+		require.NoError(t, a.setupServer())
+	}
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	w := httptest.NewRecorder()
+	a.server.Handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouter_AgentMemoryExportImport(t *testing.T) {
+	a := newTestApp()
+	a.memory = memory.NewService(memory.NewMockRepository())
+	require.NoError(t, a.memory.StoreWorking(context.Background(), "PUMP-001", "setpoint", 42.0, time.Hour))
+
+	w := serve(t, a, http.MethodGet, "/api/v1/agents/PUMP-001/memory/export", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var archive memory.MemoryArchive
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &archive))
+	assert.Equal(t, "PUMP-001", archive.AgentID)
+	require.Len(t, archive.Working, 1)
+
+	w = serve(t, a, http.MethodPost, "/api/v1/agents/PUMP-002/memory/import", map[string]interface{}{
+		"archive": archive,
+		"key_map": map[string]string{"setpoint": "target"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result memory.ImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "PUMP-002", result.TargetAgentID)
+	assert.Equal(t, 1, result.Working)
+
+	imported, err := a.memory.RetrieveWorking(context.Background(), "PUMP-002", "target")
+	require.NoError(t, err)
+	assert.EqualValues(t, 42.0, imported)
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, a, http.MethodPost, "/api/v1/agents/PUMP-002/memory/import", map[string]interface{}{}).Code)
+}
+
+func TestRouter_AgentMemoryMigrations(t *testing.T) {
+	a := newTestApp()
+	a.memory = memory.NewService(memory.NewMockRepository())
+	require.NoError(t, a.memory.Schemas().RegisterMigration("PUMP-001", "reading.*", 1, func(v interface{}) (interface{}, error) {
+		return v, nil
+	}))
+
+	w := serve(t, a, http.MethodGet, "/api/v1/agents/PUMP-001/memory/migrations", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		AgentID    string                  `json:"agent_id"`
+		Migrations []memory.MigrationStats `json:"migrations"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "PUMP-001", body.AgentID)
+	require.Len(t, body.Migrations, 1)
+	assert.Equal(t, 2, body.Migrations[0].CurrentVersion)
+}
+
+func TestRouter_AgentMemorySync(t *testing.T) {
+	ctx := context.Background()
+	exchange := memory.NewMemoryExchange()
+	a := newTestApp()
+	repo := memory.NewMockRepository()
+	a.memory = memory.NewService(repo)
+	a.memorySync = memory.NewSynchronizer(a.memory, repo, memory.ConflictStrategyLastWriteWins, 0)
+	a.memorySync.SetExchange(exchange)
+
+	// Another instance of the agent changes its working memory
+	otherRepo := memory.NewMockRepository()
+	other := memory.NewService(otherRepo)
+	otherSync := memory.NewSynchronizer(other, otherRepo, memory.ConflictStrategyLastWriteWins, 0)
+	otherSync.SetExchange(exchange)
+	require.NoError(t, other.StoreWorking(ctx, "PUMP-001", "plan", "step-1", time.Hour))
+	_, err := otherSync.SyncAgent(ctx, "PUMP-001")
+	require.NoError(t, err)
+
+	w := serve(t, a, http.MethodPost, "/api/v1/agents/PUMP-001/memory/sync", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result memory.SyncResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.Success)
+	value, err := a.memory.RetrieveWorking(ctx, "PUMP-001", "plan")
+	require.NoError(t, err)
+	assert.Equal(t, "step-1", value)
+
+	w = serve(t, a, http.MethodGet, "/api/v1/agents/PUMP-001/memory/sync", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var status memory.SyncStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, memory.SyncStateSynced, status.Status)
+}
+
+func TestRouter_AgentMemoryAudit(t *testing.T) {
+	a := newTestApp()
+	a.config.Auth.Enabled = true
+	a.auth = auth.NewService(auth.NewInMemoryStore(), a.logger)
+	require.NoError(t, a.auth.AddStaticKey("operator", "cvxc_operator", "", []auth.Scope{auth.ScopeRead}))
+	a.memory = memory.NewService(memory.NewMockRepository())
+	a.memory.SetAuditLog(memory.NewInMemoryAuditLog(0))
+	require.NoError(t, a.memory.StoreWorking(context.Background(), "PUMP-001", "setpoint", 42.0, time.Hour))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/agents/PUMP-001/memory/export", nil)
+	req.Header.Set("Authorization", "Bearer cvxc_operator")
+	req.Header.Set("X-User-ID", "someone-else")
+	require.NoError(t, a.setupServer())
+	w := httptest.NewRecorder()
+	a.server.Handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serveAs(t, a, "cvxc_operator", http.MethodGet, "/api/v1/agents/PUMP-001/memory/audit?caller=key:static:operator", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Records []memory.AuditRecord `json:"records"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.NotEmpty(t, body.Records, "accesses are attributed to the API key, not the X-User-ID header")
+	assert.Equal(t, memory.AuditOpRead, body.Records[0].Operation)
+
+	assert.Equal(t, http.StatusBadRequest, serveAs(t, a, "cvxc_operator", http.MethodGet, "/api/v1/agents/PUMP-001/memory/audit?since=yesterday", nil).Code)
+}
+
+func TestRouter_AgentMemoryRequiresService(t *testing.T) {
+	a := newTestApp()
+	assert.Equal(t, http.StatusNotFound, serve(t, a, http.MethodGet, "/api/v1/agents/PUMP-001/memory/export", nil).Code)
+}
+
+func TestRouter_WebActionsRequireAPIKey(t *testing.T) {
+	a := newTestApp()
+	a.config.Auth.Enabled = true
+	a.auth = auth.NewService(auth.NewInMemoryStore(), a.logger)
+	require.NoError(t, a.auth.AddStaticKey("viewer", "cvxc_viewer", "", []auth.Scope{auth.ScopeRead}))
+
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodPost, "/api/web/agents/PUMP-001/stop", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodPost, "/agencies/agency-1/select", nil).Code)
+	assert.Equal(t, http.StatusForbidden, serveAs(t, a, "cvxc_viewer", http.MethodPost, "/api/web/roles/role-1/disable", nil).Code)
+}
+
+func TestRouter_RateLimitIgnoresUntrustedForwardedFor(t *testing.T) {
+	a := newTestApp()
+	limiter, err := ratelimit.NewLimiter(ratelimit.Config{RequestsPerSecond: 1, Burst: 1}, nil)
+	require.NoError(t, err)
+	a.rateLimiter = limiter
+	require.NoError(t, a.setupServer())
+
+	serveFrom := func(forwardedFor, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		a.server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serveFrom("203.0.113.1", "/api/v1/deprecations").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom("203.0.113.2", "/api/v1/deprecations").Code,
+		"a forged X-Forwarded-For does not give the client a new bucket")
+
+	w := serveFrom("203.0.113.3", "/metrics")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "cortex_api_throttled_requests_total{rule=\"default\"} 1\n")
+}
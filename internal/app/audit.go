@@ -0,0 +1,24 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/audit"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// newAuditLog creates the audit log of mutating API operations, or returns
+// nil when it is disabled. An audit log that would not survive a restart is
+// no audit log, so it fails when the audit store cannot be initialized.
+func newAuditLog(cfg config.AuditConfig, dbClient *database.ArangoClient, logger *logrus.Logger) (*audit.Log, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	store, err := audit.NewArangoStore(dbClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audit log store: %w", err)
+	}
+	return audit.NewLog(store, logger), nil
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// adminKeyName names the key configured by auth.admin_key
+const adminKeyName = "admin"
+
+// newAuthService creates the API key service with the keys defined in
+// configuration. It fails when authentication is enabled and the API key
+// store cannot be initialized, since keys created through the API would be
+// lost on restart; otherwise those keys are kept in memory.
+func newAuthService(cfg config.AuthConfig, dbClient *database.ArangoClient, logger *logrus.Logger) (*auth.Service, error) {
+	var store auth.Store
+	if arangoStore, err := auth.NewArangoStore(dbClient); err != nil {
+		if cfg.Enabled {
+			return nil, fmt.Errorf("failed to initialize API key store: %w", err)
+		}
+		logger.WithError(err).Warn("Failed to initialize API key store, keeping API keys in memory until restart")
+		store = auth.NewInMemoryStore()
+	} else {
+		store = arangoStore
+	}
+	service := auth.NewService(store, logger)
+
+	if cfg.AdminKey != "" {
+		if err := service.AddStaticKey(adminKeyName, cfg.AdminKey, "", []auth.Scope{auth.ScopeDesignerAdmin}); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range cfg.Keys {
+		scopes := make([]auth.Scope, 0, len(key.Scopes))
+		for _, scope := range key.Scopes {
+			scopes = append(scopes, auth.Scope(scope))
+		}
+		if err := service.AddStaticKey(key.Name, key.Key, key.Tenant, scopes, key.Permissions...); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Enabled && cfg.AdminKey == "" {
+		logger.Warn("API authentication is enabled without auth.admin_key; only existing designer-admin keys can manage API keys")
+	}
+	return service, nil
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultProviderName names the provider configured by the top-level ai settings
+const defaultProviderName = "default"
+
+// newLLMRegistry creates the configured LLM providers and routes AI
+// operations to them. The top-level provider, if any, is the default;
+// otherwise the first named provider is.
+func newLLMRegistry(cfg config.AIConfig, logger *logrus.Logger) (*ai.ProviderRegistry, error) {
+	registry := ai.NewProviderRegistry(logger)
+
+	var defaultProvider string
+	if cfg.Provider != "" {
+		client, err := ai.NewLLMClient(&ai.LLMConfig{
+			Provider:    ai.Provider(cfg.Provider),
+			APIKey:      cfg.APIKey,
+			Model:       cfg.Model,
+			BaseURL:     cfg.BaseURL,
+			Temperature: cfg.Temperature,
+			MaxTokens:   cfg.MaxTokens,
+			Timeout:     cfg.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(defaultProviderName, client)
+		defaultProvider = defaultProviderName
+	}
+
+	for _, p := range cfg.Providers {
+		name := p.Name
+		if name == "" {
+			name = p.Provider
+		}
+		client, err := ai.NewLLMClient(&ai.LLMConfig{
+			Provider:    ai.Provider(p.Provider),
+			APIKey:      p.APIKey,
+			Model:       p.Model,
+			BaseURL:     p.BaseURL,
+			APIVersion:  p.APIVersion,
+			Temperature: p.Temperature,
+			MaxTokens:   p.MaxTokens,
+			Timeout:     p.Timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		registry.Register(name, client)
+		if defaultProvider == "" {
+			defaultProvider = name
+		}
+	}
+
+	if defaultProvider == "" {
+		return nil, fmt.Errorf("no LLM provider configured")
+	}
+
+	if err := registry.SetDefaultRoute(ai.ProviderRoute{
+		Providers: withFallbacks(defaultProvider, cfg.Fallbacks),
+	}); err != nil {
+		return nil, err
+	}
+
+	for operation, feature := range cfg.Features {
+		provider := feature.Provider
+		if provider == "" {
+			provider = defaultProvider
+		}
+		fallbacks := feature.Fallbacks
+		if fallbacks == nil {
+			fallbacks = cfg.Fallbacks
+		}
+		if err := registry.SetRoute(operation, ai.ProviderRoute{
+			Providers: withFallbacks(provider, fallbacks),
+			Model:     feature.Model,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// withFallbacks lists the primary provider followed by its fallbacks,
+// leaving out repeats
+func withFallbacks(primary string, fallbacks []string) []string {
+	providers := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, name := range fallbacks {
+		if !seen[name] {
+			seen[name] = true
+			providers = append(providers, name)
+		}
+	}
+	return providers
+}
+
+// llmSecrets lists the configured API keys, which are redacted from captures
+func llmSecrets(cfg config.AIConfig) []string {
+	secrets := []string{cfg.APIKey}
+	for _, p := range cfg.Providers {
+		secrets = append(secrets, p.APIKey)
+	}
+	return secrets
+}
+
+// newResponseCache creates the configured LLM response cache, falling back to
+// memory when the database cache cannot be set up
+func newResponseCache(cfg config.LLMCacheConfig, dbClient *database.ArangoClient, logger *logrus.Logger) ai.ResponseCache {
+	if cfg.Store == "arango" {
+		cache, err := ai.NewArangoResponseCache(dbClient)
+		if err == nil {
+			return cache
+		}
+		logger.WithError(err).Warn("Failed to initialize LLM response cache collection, using in-memory cache")
+	}
+	return ai.NewMemoryResponseCache(cfg.MaxEntries)
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"github.com/aosanya/CodeValdCortex/internal/mcp"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMCPPath is where the MCP server is served unless configured otherwise
+const defaultMCPPath = "/mcp"
+
+// registerMCP serves the Model Context Protocol server when it is enabled
+func (a *App) registerMCP(router gin.IRouter) error {
+	cfg := a.config.MCP
+	if !cfg.Enabled {
+		return nil
+	}
+
+	tokens := make([]mcp.Token, 0, len(cfg.Tokens))
+	for _, t := range cfg.Tokens {
+		tokens = append(tokens, mcp.Token{Name: t.Name, Secret: t.Token, Write: t.Write, Agencies: t.Agencies, Tenant: t.Tenant})
+	}
+
+	serverCfg := mcp.Config{
+		Agencies: a.agencyService,
+		Outbox:   a.outbox,
+		Tokens:   tokens,
+		Logger:   a.logger,
+	}
+	if a.memory != nil {
+		serverCfg.Memory = a.memory
+	}
+
+	server, err := mcp.NewServer(serverCfg)
+	if err != nil {
+		return err
+	}
+
+	path := a.mcpPath()
+	server.RegisterRoutes(router, path)
+	a.logger.WithField("path", path).WithField("tokens", len(tokens)).Info("MCP server enabled")
+	return nil
+}
+
+// mcpPath returns where the MCP server is served, or "" when it is disabled
+func (a *App) mcpPath() string {
+	cfg := a.config.MCP
+	if !cfg.Enabled {
+		return ""
+	}
+	if cfg.Path == "" {
+		return defaultMCPPath
+	}
+	return cfg.Path
+}
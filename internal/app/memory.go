@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+)
+
+// newMemoryService creates the agent memory service backed by the database,
+// with every access recorded in the memory audit log and working memory held
+// to the configured quotas, the synchronizer that exchanges working memory
+// changes with the agent's instances on other nodes through the database
+// change log, and the scheduler snapshotting the agents listed by agents,
+// which is nil without snapshot policies. Agent memory has no in-memory
+// fallback: without the database the memory endpoints and MCP memory tools
+// are not served.
+func newMemoryService(cfg *config.Config, dbClient *database.ArangoClient, agents memory.SnapshotTargetLister) (*memory.Service, *memory.Synchronizer, *memory.SnapshotScheduler, error) {
+	repo, err := memory.NewRepository(dbClient)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encryption, err := memory.ValueEncryptionFromConfig(cfg.MemoryEncryption)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid memory encryption config: %w", err)
+	}
+	repo.SetValueEncryption(encryption)
+	repo.SetSnapshotCompressionThreshold(cfg.MemorySnapshots.CompressionThresholdBytes)
+
+	auditLog, err := memory.NewArangoAuditLog(dbClient)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to initialize memory audit log: %w", err)
+	}
+
+	cached := memory.WithWorkingCache(repo, cfg.MemoryCache)
+	service := memory.NewService(cached)
+	service.SetAuditLog(auditLog)
+	if err := service.SetWorkingMemoryQuota(memory.WorkingMemoryQuotaConfigFromConfig(cfg.MemoryQuota)); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid memory quota config: %w", err)
+	}
+	synchronizer := memory.NewSynchronizer(service, cached, memory.ConflictStrategyLastWriteWins, 0)
+	synchronizer.SetExchange(repo)
+
+	var snapshots *memory.SnapshotScheduler
+	if len(cfg.MemorySnapshots.Policies) > 0 {
+		snapshots, err = memory.NewSnapshotScheduler(service, cached, agents, memory.SnapshotSchedulerConfigFromConfig(cfg.MemorySnapshots))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid memory snapshot config: %w", err)
+		}
+	}
+	return service, synchronizer, snapshots, nil
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+// workflowOrchestration is the workflow engine with the agent coordinator and
+// execution monitor it runs on
+type workflowOrchestration struct {
+	engine      *orchestration.Engine
+	coordinator *orchestration.Coordinator
+	monitor     *orchestration.Monitor
+}
+
+// newWorkflowOrchestration creates the workflow engine that runs workflow
+// tasks on the runtime's agents. Executions are kept in the database and
+// leased to this instance, so another instance takes over the executions of
+// one that stops; running tasks checkpoint to agent memory when it is
+// available. Executions have no in-memory fallback: without the database the
+// execution endpoints are not served. The worker pool and the agent
+// assignment rate limits come from the orchestration config.
+func newWorkflowOrchestration(cfg config.OrchestrationConfig, dbClient *database.ArangoClient, runtimeManager *runtime.Manager, memoryService *memory.Service, logger *logrus.Logger) (*workflowOrchestration, error) {
+	repo, err := orchestration.NewRepository(dbClient.Database(), orchestration.DefaultRepositoryConfig(), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	coordinator := orchestration.NewCoordinator(orchestration.CoordinatorConfigFromConfig(cfg), runtimeManager, nil, logger)
+	monitor := orchestration.NewMonitor(orchestration.DefaultMonitorConfig(), logger)
+	engine := orchestration.NewEngine(orchestration.ConfigFromConfig(cfg), coordinator, monitor, repo, logger)
+
+	leases, err := orchestration.NewArangoLeaseStore(context.Background(), dbClient.Database())
+	if err != nil {
+		logger.WithError(err).Warn("Failed to initialize execution leases, executions of a stopped instance are not taken over")
+	} else {
+		engine.SetLeaseStore(leases)
+	}
+	if memoryService != nil {
+		engine.SetCheckpointStore(memoryService)
+	}
+
+	return &workflowOrchestration{engine: engine, coordinator: coordinator, monitor: monitor}, nil
+}
+
+// start starts the coordinator, the monitor and then the engine
+func (w *workflowOrchestration) start() error {
+	if err := w.coordinator.Start(); err != nil {
+		return err
+	}
+	if err := w.monitor.Start(); err != nil {
+		return err
+	}
+	return w.engine.Start()
+}
+
+// stop stops the engine before the coordinator and monitor it uses
+func (w *workflowOrchestration) stop(logger *logrus.Logger) {
+	if err := w.engine.Stop(); err != nil {
+		logger.WithError(err).Error("Workflow engine shutdown error")
+	}
+	if err := w.monitor.Stop(); err != nil {
+		logger.WithError(err).Error("Execution monitor shutdown error")
+	}
+	if err := w.coordinator.Stop(); err != nil {
+		logger.WithError(err).Error("Agent coordinator shutdown error")
+	}
+}
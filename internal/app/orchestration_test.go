@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// executionStore keeps workflow executions in memory for the router tests
+type executionStore struct {
+	orchestration.WorkflowRepository
+	mu         sync.Mutex
+	executions map[string]*orchestration.WorkflowExecution
+	versions   map[string][]*orchestration.Workflow
+	filters    orchestration.ExecutionFilters
+}
+
+func newExecutionStore(executions ...*orchestration.WorkflowExecution) *executionStore {
+	s := &executionStore{executions: make(map[string]*orchestration.WorkflowExecution)}
+	for _, execution := range executions {
+		s.executions[execution.ID] = execution
+	}
+	return s
+}
+
+func (s *executionStore) ListExecutions(ctx context.Context, filters orchestration.ExecutionFilters) ([]*orchestration.WorkflowExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters = filters
+	executions := make([]*orchestration.WorkflowExecution, 0)
+	for _, execution := range s.executions {
+		if filters.WorkflowID == "" || execution.WorkflowID == filters.WorkflowID {
+			executions = append(executions, execution)
+		}
+	}
+	return executions, nil
+}
+
+func (s *executionStore) GetExecution(ctx context.Context, executionID string) (*orchestration.WorkflowExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	execution, exists := s.executions[executionID]
+	if !exists {
+		return nil, fmt.Errorf("execution %s not found", executionID)
+	}
+	// Copy so that responses do not share state with the engine
+	executionCopy := *execution
+	return &executionCopy, nil
+}
+
+func (s *executionStore) ListWorkflowVersions(ctx context.Context, workflowID string) ([]*orchestration.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.versions[workflowID], nil
+}
+
+// newTestOrchestration returns a workflow engine without agents on the store
+func newTestOrchestration(logger *logrus.Logger, store orchestration.WorkflowRepository) *workflowOrchestration {
+	monitor := orchestration.NewMonitor(orchestration.DefaultMonitorConfig(), logger)
+	return &workflowOrchestration{
+		engine:  orchestration.NewEngine(orchestration.OrchestrationConfig{}, nil, monitor, store, logger),
+		monitor: monitor,
+	}
+}
+
+func TestRouter_ListExecutions(t *testing.T) {
+	a := newTestApp()
+	store := newExecutionStore(
+		&orchestration.WorkflowExecution{ID: "exec-1", WorkflowID: "flush-mains", Status: orchestration.WorkflowStatusCompleted},
+		&orchestration.WorkflowExecution{ID: "exec-2", WorkflowID: "inspect-valves", Status: orchestration.WorkflowStatusRunning},
+	)
+	a.orchestration = newTestOrchestration(a.logger, store)
+
+	w := serve(t, a, http.MethodGet, "/api/v1/executions?workflow_id=flush-mains&status=completed,failed&started_after=2026-01-01T00:00:00Z&limit=10&offset=0", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Executions []orchestration.WorkflowExecution `json:"executions"`
+		Count      int                               `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 1, body.Count)
+	require.Len(t, body.Executions, 1)
+	assert.Equal(t, "exec-1", body.Executions[0].ID)
+
+	assert.Equal(t, []orchestration.WorkflowStatus{orchestration.WorkflowStatusCompleted, orchestration.WorkflowStatusFailed}, store.filters.Status)
+	assert.Equal(t, 10, store.filters.Limit)
+	require.NotNil(t, store.filters.StartTimeAfter)
+	assert.True(t, store.filters.StartTimeAfter.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, a, http.MethodGet, "/api/v1/executions?started_before=yesterday", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(t, a, http.MethodGet, "/api/v1/executions?limit=1000", nil).Code)
+}
+
+// flushRecorder signals the first flush of a streamed response
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	flushed chan struct{}
+}
+
+func (r *flushRecorder) Flush() {
+	r.ResponseRecorder.Flush()
+	r.once.Do(func() { close(r.flushed) })
+}
+
+func TestRouter_StreamExecutionEvents(t *testing.T) {
+	a := newTestApp()
+	running := &orchestration.WorkflowExecution{ID: "exec-1", WorkflowID: "flush-mains", Status: orchestration.WorkflowStatusRunning, TaskExecutions: map[string]*orchestration.TaskExecution{}}
+	finished := &orchestration.WorkflowExecution{ID: "exec-2", WorkflowID: "flush-mains", Status: orchestration.WorkflowStatusCompleted}
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore(running, finished))
+	require.NoError(t, a.setupServer())
+
+	// A finished execution gets only its snapshot
+	w := serve(t, a, http.MethodGet, "/api/v1/executions/exec-2/events", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "event: "))
+	assert.Contains(t, w.Body.String(), "event: snapshot\ndata: {\"id\":\"exec-2\"")
+
+	// A running execution streams until it stops
+	ctx := context.Background()
+	require.NoError(t, a.orchestration.monitor.StartMonitoring(ctx, running))
+	recorder := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan struct{})}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.server.Handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/executions/exec-1/events", nil))
+	}()
+
+	select {
+	case <-recorder.flushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the snapshot to be streamed")
+	}
+	running.Status = orchestration.WorkflowStatusCompleted
+	require.NoError(t, a.orchestration.monitor.StopMonitoring(ctx, "exec-1"))
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the stream to end when the execution stops")
+	}
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, "event: snapshot\n")
+	assert.Contains(t, body, "event: "+string(orchestration.EventExecutionCompleted)+"\n")
+
+	assert.Equal(t, http.StatusNotFound, serve(t, a, http.MethodGet, "/api/v1/executions/missing/events", nil).Code)
+}
+
+func TestRouter_ListWorkflowVersions(t *testing.T) {
+	a := newTestApp()
+	store := newExecutionStore()
+	store.versions = map[string][]*orchestration.Workflow{
+		"flush-mains": {
+			{ID: "flush-mains", Version: "2"},
+			{ID: "flush-mains", Version: "1"},
+		},
+	}
+	a.orchestration = newTestOrchestration(a.logger, store)
+
+	w := serve(t, a, http.MethodGet, "/api/v1/workflows/flush-mains/versions", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Versions []orchestration.Workflow `json:"versions"`
+		Count    int                      `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Count)
+	require.Len(t, body.Versions, 2)
+	assert.Equal(t, "2", body.Versions[0].Version)
+
+	assert.Equal(t, http.StatusNotFound, serve(t, a, http.MethodGet, "/api/v1/workflows/missing/versions", nil).Code)
+}
+
+func TestRouter_MigrateExecution(t *testing.T) {
+	a := newTestApp()
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore())
+
+	assert.Equal(t, http.StatusBadRequest, serve(t, a, http.MethodPost, "/api/v1/executions/exec-1/migrate", map[string]string{}).Code)
+
+	// Only executions paused on this instance can be migrated
+	w := serve(t, a, http.MethodPost, "/api/v1/executions/exec-1/migrate", map[string]string{"version": "2"})
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+}
+
+func TestRouter_ApproveTask(t *testing.T) {
+	a := newTestApp()
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore())
+	decision := map[string]interface{}{"approved": true, "comment": "valve isolated"}
+
+	// Without authentication there is no approver
+	assert.Equal(t, http.StatusUnauthorized, serve(t, a, http.MethodPost, "/api/v1/executions/exec-1/tasks/sign-off/approve", decision).Code)
+
+	a = newTestApp()
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore())
+	a.config.Auth.Enabled = true
+	a.auth = auth.NewService(auth.NewInMemoryStore(), a.logger)
+	require.NoError(t, a.auth.AddStaticKey("control-room", "cvxc_control_room", "", []auth.Scope{auth.ScopeDesignerAdmin}))
+
+	assert.Equal(t, http.StatusBadRequest, serveAs(t, a, "cvxc_control_room", http.MethodPost, "/api/v1/executions/exec-1/tasks/sign-off/approve", map[string]string{"comment": "no decision"}).Code)
+	w := serveAs(t, a, "cvxc_control_room", http.MethodPost, "/api/v1/executions/exec-1/tasks/sign-off/approve", decision)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+}
+
+func TestRouter_ApprovalsPage(t *testing.T) {
+	a := newTestApp()
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore(
+		&orchestration.WorkflowExecution{ID: "exec-1", WorkflowID: "replace-valve", Status: orchestration.WorkflowStatusRunning, TaskExecutions: map[string]*orchestration.TaskExecution{
+			"isolate":  {TaskID: "isolate", Status: orchestration.TaskStatusCompleted},
+			"sign-off": {TaskID: "sign-off", Status: orchestration.TaskStatusAwaitingApproval},
+		}},
+	))
+
+	w := serve(t, a, http.MethodGet, "/approvals", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `hx-post="/api/v1/executions/exec-1/tasks/sign-off/approve"`)
+	assert.NotContains(t, w.Body.String(), "/tasks/isolate/approve")
+}
+
+func TestRouter_MetricsIncludeWorkflowEngine(t *testing.T) {
+	a := newTestApp()
+	a.orchestration = newTestOrchestration(a.logger, newExecutionStore())
+
+	w := serve(t, a, http.MethodGet, "/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "# TYPE cortex_workflow_engine_workers gauge")
+	assert.Contains(t, w.Body.String(), "cortex_workflow_engine_task_queue_depth 0")
+}
+
+func TestRouter_ExecutionsRequireEngine(t *testing.T) {
+	a := newTestApp()
+	assert.Equal(t, http.StatusNotFound, serve(t, a, http.MethodGet, "/api/v1/executions", nil).Code)
+}
@@ -0,0 +1,153 @@
+// Package audit records mutating API operations in an append-only log.
+//
+// Every POST, PUT, PATCH and DELETE request to the /api endpoints is recorded
+// with who made it, the route, a SHA-256 digest of its payload, the response
+// status and its latency. Services add events the request records cannot
+// tell, such as which masked payload fields an unmask request revealed.
+// Records are never updated or deleted through the service; retention is left
+// to the database.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Audit query limits
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// ErrInvalidQuery is returned for audit queries with invalid parameters
+var ErrInvalidQuery = errors.New("invalid audit query")
+
+// Actor identifies who made a request
+type Actor struct {
+	KeyID      string `json:"key_id,omitempty"`   // API key the request authenticated with
+	KeyName    string `json:"key_name,omitempty"` // Name of that key
+	Tenant     string `json:"tenant,omitempty"`   // Tenant the request was scoped to
+	UserID     string `json:"user_id,omitempty"`  // User named in the X-User-ID header
+	Session    string `json:"session,omitempty"`  // Designer session named in the request headers
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// Record is one mutating API operation
+type Record struct {
+	ID            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`                     // Requested path
+	Route         string    `json:"route,omitempty"`          // Route pattern that served the request, if any
+	Status        int       `json:"status"`                   // Response status
+	LatencyMs     int64     `json:"latency_ms"`               // Time taken to serve the request
+	PayloadDigest string    `json:"payload_digest,omitempty"` // "sha256:<hex>" of the request body; empty without one
+	PayloadBytes  int64     `json:"payload_bytes"`
+	Actor         Actor     `json:"actor"`
+
+	// Event names what a service recorded, e.g. "unmask"; empty for API requests
+	Event   string                 `json:"event,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Query selects audit records. Zero fields do not filter.
+type Query struct {
+	Event      string
+	Method     string
+	PathPrefix string
+	KeyID      string
+	UserID     string
+	Tenant     string
+	MinStatus  int // e.g. 400 for failed operations only
+	Since      time.Time
+	Until      time.Time
+	Limit      int // Default 100, at most 1000
+}
+
+// validate applies defaults and rejects invalid parameters
+func (q *Query) validate() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidQuery)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultQueryLimit
+	}
+	if q.Limit > MaxQueryLimit {
+		return fmt.Errorf("%w: limit must be at most %d", ErrInvalidQuery, MaxQueryLimit)
+	}
+	if q.MinStatus < 0 {
+		return fmt.Errorf("%w: min_status must not be negative", ErrInvalidQuery)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return fmt.Errorf("%w: until must not be before since", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// matches reports whether a record is selected by the query
+func (q *Query) matches(record *Record) bool {
+	switch {
+	case q.Event != "" && record.Event != q.Event,
+		q.Method != "" && record.Method != q.Method,
+		q.PathPrefix != "" && !strings.HasPrefix(record.Path, q.PathPrefix),
+		q.KeyID != "" && record.Actor.KeyID != q.KeyID,
+		q.UserID != "" && record.Actor.UserID != q.UserID,
+		q.Tenant != "" && record.Actor.Tenant != q.Tenant,
+		record.Status < q.MinStatus,
+		!q.Since.IsZero() && record.Timestamp.Before(q.Since),
+		!q.Until.IsZero() && record.Timestamp.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// Store is an append-only store of audit records
+type Store interface {
+	// Append stores a record
+	Append(ctx context.Context, record *Record) error
+
+	// Query returns matching records, newest first
+	Query(ctx context.Context, query Query) ([]*Record, error)
+}
+
+// Log records API operations and answers queries about them
+type Log struct {
+	store  Store
+	logger *logrus.Logger
+	now    func() time.Time
+}
+
+// NewLog creates an audit log backed by store
+func NewLog(store Store, logger *logrus.Logger) *Log {
+	return &Log{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Query returns the records matching the query, newest first
+func (l *Log) Query(ctx context.Context, query Query) ([]*Record, error) {
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	return l.store.Query(ctx, query)
+}
+
+// RecordEvent appends an event a service records while serving a request,
+// attributed to the request's caller
+func (l *Log) RecordEvent(ctx context.Context, event string, at time.Time, details map[string]interface{}) error {
+	return l.store.Append(ctx, &Record{
+		ID:        uuid.New().String(),
+		Timestamp: at.UTC(),
+		Actor:     actorFromContext(ctx),
+		Event:     event,
+		Details:   details,
+	})
+}
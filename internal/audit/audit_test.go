@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLog() *Log {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewLog(NewInMemoryStore(0), logger)
+}
+
+func newTestRouter(l *Log) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(l))
+	// Stands in for the authentication middleware
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		key := &auth.APIKey{ID: "key-1", Name: "ci"}
+		c.Request = c.Request.WithContext(auth.WithKey(c.Request.Context(), key))
+	})
+	router.POST("/api/v1/agencies/:id/goals", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+		c.Status(http.StatusCreated)
+	})
+	router.DELETE("/api/v1/agencies/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.GET("/api/v1/agencies", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.POST("/api/web/agents/:id/:action", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string, authorized bool) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if authorized {
+		req.Header.Set("Authorization", "Bearer secret")
+	}
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestMiddleware_RecordsMutatingRequests(t *testing.T) {
+	l := newTestLog()
+	router := newTestRouter(l)
+
+	payload := `{"code":"G1"}`
+	serve(router, http.MethodPost, "/api/v1/agencies/a1/goals", payload, true)
+	serve(router, http.MethodGet, "/api/v1/agencies", "", true)
+	serve(router, http.MethodPost, "/api/web/agents/agent-1/stop", "", true)
+	serve(router, http.MethodDelete, "/api/v1/agencies/a1", "", false)
+
+	records, err := l.Query(context.Background(), Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 3, "only mutating requests are recorded")
+
+	refused := records[0]
+	assert.Equal(t, http.MethodDelete, refused.Method)
+	assert.Equal(t, http.StatusUnauthorized, refused.Status)
+	assert.Empty(t, refused.Actor.KeyID)
+	assert.Empty(t, refused.PayloadDigest)
+
+	// Web UI actions outside the versioned API are recorded too
+	action := records[1]
+	assert.Equal(t, "/api/web/agents/agent-1/stop", action.Path)
+	assert.Equal(t, "/api/web/agents/:id/:action", action.Route)
+	assert.Equal(t, "key-1", action.Actor.KeyID)
+
+	created := records[2]
+	sum := sha256.Sum256([]byte(payload))
+	assert.Equal(t, "/api/v1/agencies/a1/goals", created.Path)
+	assert.Equal(t, "/api/v1/agencies/:id/goals", created.Route)
+	assert.Equal(t, http.StatusCreated, created.Status)
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), created.PayloadDigest)
+	assert.Equal(t, int64(len(payload)), created.PayloadBytes)
+	assert.Equal(t, "key-1", created.Actor.KeyID)
+	assert.Equal(t, "ci", created.Actor.KeyName)
+}
+
+func TestMiddleware_DigestsUnreadPayloads(t *testing.T) {
+	l := newTestLog()
+	router := newTestRouter(l)
+
+	serve(router, http.MethodDelete, "/api/v1/agencies/a1", "unread", true)
+
+	records, err := l.Query(context.Background(), Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	sum := sha256.Sum256([]byte("unread"))
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), records[0].PayloadDigest)
+}
+
+func TestLog_Query(t *testing.T) {
+	l := newTestLog()
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{ID: "1", Timestamp: base, Method: http.MethodPost, Path: "/api/v1/agencies", Status: 201, Actor: Actor{KeyID: "k1"}},
+		{ID: "2", Timestamp: base.Add(time.Minute), Method: http.MethodDelete, Path: "/api/v1/agents/x", Status: 404, Actor: Actor{KeyID: "k2", Tenant: "acme"}},
+		{ID: "3", Timestamp: base.Add(2 * time.Minute), Method: http.MethodPost, Path: "/api/v1/agencies/a/goals", Status: 500, Actor: Actor{KeyID: "k1"}},
+	}
+	for _, record := range records {
+		require.NoError(t, l.store.Append(ctx, record))
+	}
+
+	ids := func(query Query) []string {
+		found, err := l.Query(ctx, query)
+		require.NoError(t, err)
+		var ids []string
+		for _, record := range found {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"3", "2", "1"}, ids(Query{}))
+	assert.Equal(t, []string{"3", "1"}, ids(Query{KeyID: "k1"}))
+	assert.Equal(t, []string{"3", "2"}, ids(Query{MinStatus: 400}))
+	assert.Equal(t, []string{"3", "1"}, ids(Query{PathPrefix: "/api/v1/agencies"}))
+	assert.Equal(t, []string{"2"}, ids(Query{Tenant: "acme"}))
+	assert.Equal(t, []string{"2", "1"}, ids(Query{Until: base.Add(time.Minute)}))
+	assert.Equal(t, []string{"3"}, ids(Query{Limit: 1}))
+
+	_, err := l.Query(ctx, Query{Limit: MaxQueryLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = l.Query(ctx, Query{Since: base, Until: base.Add(-time.Second)})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+func TestUnmaskLog(t *testing.T) {
+	l := newTestLog()
+	unmasks := NewUnmaskLog(l)
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	allowed := &communication.UnmaskRecord{ID: "u1", Actor: "key:k1", Reason: "incident review", SubjectType: "message", SubjectID: "m1", Fields: []string{"assignee"}, Allowed: true, At: at}
+	denied := &communication.UnmaskRecord{ID: "u2", Actor: "key:k2", Reason: "curious", SubjectType: "message", SubjectID: "m1", Fields: []string{"assignee"}, Missing: []string{communication.DefaultUnmaskPermission}, At: at.Add(time.Minute)}
+	for _, record := range []*communication.UnmaskRecord{allowed, denied} {
+		keyID := strings.TrimPrefix(record.Actor, "key:")
+		ctx := auth.WithKey(context.Background(), &auth.APIKey{ID: keyID, Name: keyID})
+		require.NoError(t, unmasks.RecordUnmask(ctx, record))
+	}
+	// Request records are not unmask records
+	require.NoError(t, l.store.Append(context.Background(), &Record{ID: "request", Timestamp: at, Method: http.MethodPost, Actor: Actor{KeyID: "k1"}}))
+
+	records, err := unmasks.UnmaskRecords(context.Background(), "", 0)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "u2", records[0].ID)
+	assert.Equal(t, []string{communication.DefaultUnmaskPermission}, records[0].Missing)
+	assert.True(t, records[1].At.Equal(at))
+
+	records, err = unmasks.UnmaskRecords(context.Background(), "key:k1", 0)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.True(t, records[0].Allowed)
+	assert.Equal(t, []string{"assignee"}, records[0].Fields)
+
+	stored, err := l.Query(context.Background(), Query{Event: EventUnmask, KeyID: "k1"})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "k1", stored[0].Actor.KeyName)
+}
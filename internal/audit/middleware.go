@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/changefeed"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxUnreadPayload bounds how much of a body left unread by the handler is
+// read to complete its digest
+const maxUnreadPayload = 10 << 20
+
+type recordedKey struct{}
+
+// Mutating reports whether requests with the method change state
+func Mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// digestReader hashes a request body as the handler reads it
+type digestReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	n    int64
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	r.n += int64(n)
+	return n, err
+}
+
+// Middleware records every mutating request once it has been served,
+// including requests refused by authentication and the web UI's actions
+// outside the versioned API. It must run before the
+// authentication middleware so refused requests are recorded, and reads the
+// actor from the request context when the request completes. Failing to
+// record a request is logged and does not fail it.
+func Middleware(l *Log) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if !Mutating(c.Request.Method) || ctx.Value(recordedKey{}) != nil {
+			c.Next()
+			return
+		}
+
+		// Requests served through an older version's route pass through
+		// again; only the outer pass records them
+		c.Request = c.Request.WithContext(context.WithValue(ctx, recordedKey{}, true))
+		path := c.Request.URL.Path
+		var body *digestReader
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body = &digestReader{ReadCloser: c.Request.Body, hash: sha256.New()}
+			c.Request.Body = body
+		}
+		start := l.now()
+
+		c.Next()
+
+		record := &Record{
+			ID:        uuid.New().String(),
+			Timestamp: start.UTC(),
+			Method:    c.Request.Method,
+			Path:      path,
+			Route:     c.FullPath(),
+			Status:    c.Writer.Status(),
+			LatencyMs: l.now().Sub(start).Milliseconds(),
+			Actor:     actorFromRequest(c),
+		}
+		if body != nil {
+			// Complete the digest of a body the handler did not read to the end
+			_, _ = io.CopyN(io.Discard, body, maxUnreadPayload)
+			if body.n > 0 {
+				record.PayloadDigest = "sha256:" + hex.EncodeToString(body.hash.Sum(nil))
+				record.PayloadBytes = body.n
+			}
+		}
+
+		if err := l.store.Append(c.Request.Context(), record); err != nil {
+			l.logger.WithError(err).WithField("method", record.Method).WithField("path", record.Path).Error("Failed to record API operation in the audit log")
+		}
+	}
+}
+
+// actorFromRequest identifies who made a request from what the middleware
+// before the handler attached to its context
+func actorFromRequest(c *gin.Context) Actor {
+	actor := actorFromContext(c.Request.Context())
+	actor.RemoteAddr = c.ClientIP()
+	return actor
+}
+
+// actorFromContext identifies who made a request from its context
+func actorFromContext(ctx context.Context) Actor {
+	changeActor := changefeed.ActorFromContext(ctx)
+	actor := Actor{
+		Tenant:  tenant.FromContext(ctx),
+		UserID:  changeActor.UserID,
+		Session: changeActor.Session,
+	}
+	if key, ok := auth.KeyFromContext(ctx); ok {
+		actor.KeyID = key.ID
+		actor.KeyName = key.Name
+	}
+	return actor
+}
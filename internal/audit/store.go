@@ -0,0 +1,189 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionAuditLog is the API audit log collection name
+const CollectionAuditLog = "api_audit_log"
+
+// InMemoryStore keeps the most recent audit records in memory, dropping the
+// oldest beyond its limit. It is not tamper-evident and does not survive a
+// restart, so the application never falls back to it.
+type InMemoryStore struct {
+	mu         sync.RWMutex
+	records    []*Record
+	maxRecords int
+}
+
+// NewInMemoryStore creates an in-memory store keeping at most maxRecords
+// records (default 10000)
+func NewInMemoryStore(maxRecords int) *InMemoryStore {
+	if maxRecords <= 0 {
+		maxRecords = 10000
+	}
+	return &InMemoryStore{maxRecords: maxRecords}
+}
+
+// Append stores a record, dropping the oldest beyond the limit
+func (s *InMemoryStore) Append(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *record
+	s.records = append(s.records, &stored)
+	if drop := len(s.records) - s.maxRecords; drop > 0 {
+		s.records = append([]*Record(nil), s.records[drop:]...)
+	}
+	return nil
+}
+
+// Query returns matching records, newest first
+func (s *InMemoryStore) Query(ctx context.Context, query Query) ([]*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]*Record, 0)
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(records) >= query.Limit {
+			break
+		}
+		if query.matches(s.records[i]) {
+			record := *s.records[i]
+			records = append(records, &record)
+		}
+	}
+	return records, nil
+}
+
+// ArangoStore stores audit records in ArangoDB. It only ever creates
+// documents.
+type ArangoStore struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoStore creates an ArangoDB-backed audit store
+func NewArangoStore(dbClient *database.ArangoClient) (*ArangoStore, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionAuditLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionAuditLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionAuditLog, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionAuditLog).Info("Created new collection")
+	}
+
+	indexes := map[string][]string{
+		"idx_api_audit_timestamp": {"timestamp"},
+		"idx_api_audit_key":       {"actor.key_id", "timestamp"},
+		"idx_api_audit_user":      {"actor.user_id", "timestamp"},
+		"idx_api_audit_tenant":    {"actor.tenant", "timestamp"},
+	}
+	for name, fields := range indexes {
+		if _, _, err := col.EnsurePersistentIndex(ctx, fields, &driver.EnsurePersistentIndexOptions{Name: name}); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+	}
+
+	return &ArangoStore{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// recordDocument is an audit record as stored in ArangoDB
+type recordDocument struct {
+	Key string `json:"_key"`
+	*Record
+}
+
+// Append stores a record
+func (s *ArangoStore) Append(ctx context.Context, record *Record) error {
+	if _, err := s.collection.CreateDocument(ctx, recordDocument{Key: record.ID, Record: record}); err != nil {
+		return fmt.Errorf("failed to create audit record: %w", err)
+	}
+	return nil
+}
+
+// Query returns matching records, newest first
+func (s *ArangoStore) Query(ctx context.Context, query Query) ([]*Record, error) {
+	bindVars := map[string]interface{}{
+		"@collection": CollectionAuditLog,
+		"limit":       query.Limit,
+	}
+	filters := ""
+	filter := func(clause, name string, value interface{}) {
+		filters += "\n\t\tFILTER " + clause
+		bindVars[name] = value
+	}
+	if query.Event != "" {
+		filter("r.event == @event", "event", query.Event)
+	}
+	if query.Method != "" {
+		filter("r.method == @method", "method", query.Method)
+	}
+	if query.PathPrefix != "" {
+		filter("STARTS_WITH(r.path, @pathPrefix)", "pathPrefix", query.PathPrefix)
+	}
+	if query.KeyID != "" {
+		filter("r.actor.key_id == @keyID", "keyID", query.KeyID)
+	}
+	if query.UserID != "" {
+		filter("r.actor.user_id == @userID", "userID", query.UserID)
+	}
+	if query.Tenant != "" {
+		filter("r.actor.tenant == @tenant", "tenant", query.Tenant)
+	}
+	if query.MinStatus > 0 {
+		filter("r.status >= @minStatus", "minStatus", query.MinStatus)
+	}
+	if !query.Since.IsZero() {
+		filter("r.timestamp >= @since", "since", query.Since)
+	}
+	if !query.Until.IsZero() {
+		filter("r.timestamp <= @until", "until", query.Until)
+	}
+
+	aql := fmt.Sprintf(`
+		FOR r IN @@collection%s
+		SORT r.timestamp DESC
+		LIMIT @limit
+		RETURN r
+	`, filters)
+
+	cursor, err := s.db.Query(ctx, aql, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer cursor.Close()
+
+	records := make([]*Record, 0)
+	for cursor.HasMore() {
+		var record Record
+		if _, err := cursor.ReadDocument(ctx, &record); err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+)
+
+// EventUnmask records an attempt to reveal masked payload fields
+const EventUnmask = "unmask"
+
+// UnmaskLog keeps the unmask records of payload masking in the audit log
+type UnmaskLog struct {
+	log *Log
+}
+
+// NewUnmaskLog creates an unmask audit log backed by the audit log
+func NewUnmaskLog(l *Log) *UnmaskLog {
+	return &UnmaskLog{log: l}
+}
+
+// RecordUnmask appends an unmask record to the audit log
+func (u *UnmaskLog) RecordUnmask(ctx context.Context, record *communication.UnmaskRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode unmask record: %w", err)
+	}
+	var details map[string]interface{}
+	if err := json.Unmarshal(data, &details); err != nil {
+		return fmt.Errorf("failed to encode unmask record: %w", err)
+	}
+	return u.log.RecordEvent(ctx, EventUnmask, record.At, details)
+}
+
+// UnmaskRecords returns unmask records, newest first. Unmask actors name the
+// caller's API key as auth.Principal does, so an actor selects the records of
+// that key.
+func (u *UnmaskLog) UnmaskRecords(ctx context.Context, actor string, limit int) ([]*communication.UnmaskRecord, error) {
+	query := Query{Event: EventUnmask, Limit: min(limit, MaxQueryLimit)}
+	if actor != "" {
+		keyID, ok := strings.CutPrefix(actor, "key:")
+		if !ok {
+			return []*communication.UnmaskRecord{}, nil
+		}
+		query.KeyID = keyID
+	}
+	records, err := u.log.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	unmasks := make([]*communication.UnmaskRecord, 0, len(records))
+	for _, record := range records {
+		data, err := json.Marshal(record.Details)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode unmask record %s: %w", record.ID, err)
+		}
+		var unmask communication.UnmaskRecord
+		if err := json.Unmarshal(data, &unmask); err != nil {
+			return nil, fmt.Errorf("failed to decode unmask record %s: %w", record.ID, err)
+		}
+		unmasks = append(unmasks, &unmask)
+	}
+	return unmasks, nil
+}
@@ -0,0 +1,158 @@
+// Package auth authenticates HTTP API requests with API keys.
+//
+// Each key carries scopes that decide what it may do: ScopeRead allows
+// reading, ScopeCommunications additionally allows sending messages and
+// managing subscriptions, and ScopeDesignerAdmin allows everything, including
+// agency design changes and key management. Revealing masked payloads and
+// overriding command guardrails need ScopeUnmask and ScopeGuardrailOverride,
+// which ScopeCommunications does not grant. Secrets are only returned when a
+// key is created; stores keep a SHA-256 hash of them. Keys issued to a tenant
+// scope their requests to that tenant.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Scope is a permission granted to an API key
+type Scope string
+
+const (
+	// ScopeRead allows reading through the API
+	ScopeRead Scope = "read"
+	// ScopeCommunications allows reading and changes to agent communications
+	ScopeCommunications Scope = "communications"
+	// ScopeUnmask allows requests to reveal masked message and publication payloads
+	ScopeUnmask Scope = "unmask"
+	// ScopeGuardrailOverride allows delivering commands blocked by guardrails
+	ScopeGuardrailOverride Scope = "guardrail-override"
+	// ScopeDesignerAdmin allows every request, including key management
+	ScopeDesignerAdmin Scope = "designer-admin"
+)
+
+// Scopes lists the valid scopes, least privileged first
+var Scopes = []Scope{ScopeRead, ScopeCommunications, ScopeUnmask, ScopeGuardrailOverride, ScopeDesignerAdmin}
+
+// implies lists the scopes each scope grants besides itself
+var implies = map[Scope][]Scope{
+	ScopeCommunications:    {ScopeRead},
+	ScopeUnmask:            {ScopeRead},
+	ScopeGuardrailOverride: {ScopeRead},
+	ScopeDesignerAdmin:     {ScopeRead, ScopeCommunications, ScopeUnmask, ScopeGuardrailOverride},
+}
+
+// SecretPrefix starts every generated API key secret
+const SecretPrefix = "cvxc_"
+
+// displayPrefixLength is how much of a secret is kept to recognize the key
+const displayPrefixLength = len(SecretPrefix) + 6
+
+var (
+	// ErrInvalidKey is returned for unknown, revoked and expired keys
+	ErrInvalidKey = errors.New("invalid API key")
+
+	// ErrKeyNotFound is returned when no key has the requested ID
+	ErrKeyNotFound = errors.New("API key not found")
+
+	// ErrStaticKey is returned when changing a key defined in configuration
+	ErrStaticKey = errors.New("API key is defined in configuration")
+
+	// ErrInvalidKeyRequest is returned when a key cannot be created as requested
+	ErrInvalidKeyRequest = errors.New("invalid API key request")
+)
+
+// APIKey is an API key without its secret
+type APIKey struct {
+	Key         string     `json:"_key,omitempty"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"` // Start of the secret, to recognize the key
+	Scopes      []Scope    `json:"scopes"`
+	Static      bool       `json:"static,omitempty"`      // Defined in configuration
+	Tenant      string     `json:"tenant,omitempty"`      // Tenant the key's requests are scoped to; empty for operator keys
+	Permissions []string   `json:"permissions,omitempty"` // Reveal masked payload fields, e.g. customer.pii
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key grants a scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || slices.Contains(implies[granted], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key may be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// ValidateScopes checks that scopes are known and not empty
+func ValidateScopes(scopes []Scope) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("unknown scope %q (valid scopes: %v)", scope, Scopes)
+		}
+	}
+	return nil
+}
+
+// Store persists API keys by the hash of their secret
+type Store interface {
+	// Create stores a new key
+	Create(ctx context.Context, key *APIKey, hash string) error
+
+	// GetByHash retrieves the key whose secret has the hash, or ErrKeyNotFound
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+
+	// Get retrieves a key by ID, or ErrKeyNotFound
+	Get(ctx context.Context, id string) (*APIKey, error)
+
+	// List returns all keys, oldest first
+	List(ctx context.Context) ([]*APIKey, error)
+
+	// Update saves a key's revocation and last use
+	Update(ctx context.Context, key *APIKey) error
+}
+
+// generateSecret returns a new random API key secret
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecret returns the hash stores keep of a secret
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// displayPrefix returns the recognizable start of a secret
+func displayPrefix(secret string) string {
+	if len(secret) <= displayPrefixLength {
+		return ""
+	}
+	return secret[:displayPrefixLength]
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) *Service {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	service := NewService(NewInMemoryStore(), logger)
+	require.NoError(t, service.AddStaticKey("admin", "admin-secret", "", []Scope{ScopeDesignerAdmin}))
+	return service
+}
+
+func TestService_CreateAuthenticateRevoke(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	key, secret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "dashboards", Scopes: []Scope{ScopeRead}})
+	require.NoError(t, err)
+	assert.Contains(t, secret, SecretPrefix)
+	assert.Equal(t, secret[:displayPrefixLength], key.Prefix)
+
+	authenticated, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	assert.NotNil(t, authenticated.LastUsedAt)
+
+	_, err = service.RevokeKey(ctx, key.ID)
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = service.RevokeKey(ctx, "static:admin")
+	assert.ErrorIs(t, err, ErrStaticKey)
+
+	keys, err := service.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.True(t, keys[0].Static)
+}
+
+func TestService_RejectsInvalidRequests(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	_, _, err := service.CreateKey(ctx, CreateKeyRequest{Name: "k", Scopes: []Scope{"root"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = service.CreateKey(ctx, CreateKeyRequest{Name: "k", Scopes: []Scope{ScopeRead}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+
+	_, err = service.Authenticate(ctx, "cvxc_unknown")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestService_ExpiredKeysAreRejected(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour)
+	_, secret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "temp", Scopes: []Scope{ScopeRead}, ExpiresAt: &expires})
+	require.NoError(t, err)
+
+	service.now = func() time.Time { return expires.Add(time.Second) }
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestService_TenantKeys(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+
+	_, _, err := service.CreateKey(ctx, CreateKeyRequest{Name: "bad", Scopes: []Scope{ScopeRead}, Tenant: "Acme"})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+
+	acmeKey, acmeSecret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "acme", Scopes: []Scope{ScopeDesignerAdmin}, Tenant: "acme"})
+	require.NoError(t, err)
+	globexKey, _, err := service.CreateKey(ctx, CreateKeyRequest{Name: "globex", Scopes: []Scope{ScopeRead}, Tenant: "globex"})
+	require.NoError(t, err)
+
+	authenticated, err := service.Authenticate(ctx, acmeSecret)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", authenticated.Tenant)
+
+	acme := tenant.WithTenant(ctx, "acme")
+	_, _, err = service.CreateKey(acme, CreateKeyRequest{Name: "escape", Scopes: []Scope{ScopeRead}, Tenant: "globex"})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+	created, _, err := service.CreateKey(acme, CreateKeyRequest{Name: "reader", Scopes: []Scope{ScopeRead}})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", created.Tenant)
+
+	keys, err := service.ListKeys(acme)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, acmeKey.ID, keys[0].ID)
+
+	_, err = service.RevokeKey(acme, globexKey.ID)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestRequiredScope(t *testing.T) {
+	assert.Equal(t, ScopeRead, RequiredScope(http.MethodGet, "/agencies"))
+	assert.Equal(t, ScopeCommunications, RequiredScope(http.MethodPost, "/communications/messages"))
+	assert.Equal(t, ScopeDesignerAdmin, RequiredScope(http.MethodPost, "/agencies/a/goals"))
+	assert.Equal(t, ScopeDesignerAdmin, RequiredScope(http.MethodGet, "/auth/keys"))
+	assert.Equal(t, ScopeDesignerAdmin, RequiredScope(http.MethodGet, "/audit"))
+	assert.Equal(t, ScopeUnmask, RequiredScope(http.MethodPost, "/communications/messages/m1/unmask"))
+	assert.Equal(t, ScopeUnmask, RequiredScope(http.MethodPost, "/communications/publications/p1/unmask"))
+	assert.Equal(t, ScopeGuardrailOverride, RequiredScope(http.MethodPost, "/communications/guardrails/violations/v1/override"))
+	assert.Equal(t, ScopeCommunications, RequiredScope(http.MethodPost, "/communications/guardrails/violations/v1/dismiss"))
+	assert.Equal(t, ScopeDesignerAdmin, RequiredScope(http.MethodPost, "/api/web/agents/a1/stop"))
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := newTestService(t)
+	ctx := context.Background()
+
+	_, readSecret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "reader", Scopes: []Scope{ScopeRead}})
+	require.NoError(t, err)
+	_, commsSecret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "agent", Scopes: []Scope{ScopeCommunications}})
+	require.NoError(t, err)
+	_, unmaskSecret, err := service.CreateKey(ctx, CreateKeyRequest{Name: "auditor", Scopes: []Scope{ScopeUnmask}})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(Middleware(service, "/mcp"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/health", ok)
+	router.GET("/api/v1/health", ok)
+	router.GET("/api/v1/agencies", ok)
+	router.POST("/api/v1/agencies", ok)
+	router.POST("/api/v1/communications/messages", ok)
+	router.POST("/api/v1/communications/messages/:id/unmask", ok)
+	router.POST("/api/v1/communications/guardrails/violations/:id/override", ok)
+	router.GET("/dashboard", ok)
+	router.GET("/login", ok)
+	router.GET("/metrics", ok)
+	router.GET("/static/css/styles.css", ok)
+	router.POST("/agencies/:id/select", ok)
+	router.GET("/api/web/agents/live", ok)
+	router.POST("/api/web/agents/:id/:action", ok)
+	router.POST("/mcp", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"non-API routes are open", http.MethodGet, "/health", "", "", http.StatusOK},
+		{"public API routes are open", http.MethodGet, "/api/v1/health", "", "", http.StatusOK},
+		{"missing key", http.MethodGet, "/api/v1/agencies", "", "", http.StatusUnauthorized},
+		{"wrong key", http.MethodGet, "/api/v1/agencies", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"read key reads", http.MethodGet, "/api/v1/agencies", "Authorization", "Bearer " + readSecret, http.StatusOK},
+		{"read key cannot write", http.MethodPost, "/api/v1/agencies", "Authorization", "Bearer " + readSecret, http.StatusForbidden},
+		{"communications key sends messages", http.MethodPost, "/api/v1/communications/messages", "X-API-Key", commsSecret, http.StatusOK},
+		{"communications key cannot change agencies", http.MethodPost, "/api/v1/agencies", "X-API-Key", commsSecret, http.StatusForbidden},
+		{"admin key writes", http.MethodPost, "/api/v1/agencies", "Authorization", "Bearer admin-secret", http.StatusOK},
+		{"session cookie", http.MethodGet, "/api/v1/agencies", "Cookie", SessionCookie + "=" + readSecret, http.StatusOK},
+		{"communications key cannot unmask", http.MethodPost, "/api/v1/communications/messages/m1/unmask", "X-API-Key", commsSecret, http.StatusForbidden},
+		{"unmask key unmasks", http.MethodPost, "/api/v1/communications/messages/m1/unmask", "X-API-Key", unmaskSecret, http.StatusOK},
+		{"communications key cannot override guardrails", http.MethodPost, "/api/v1/communications/guardrails/violations/v1/override", "X-API-Key", commsSecret, http.StatusForbidden},
+		{"admin key overrides guardrails", http.MethodPost, "/api/v1/communications/guardrails/violations/v1/override", "Authorization", "Bearer admin-secret", http.StatusOK},
+		{"web pages need a key", http.MethodGet, "/dashboard", "", "", http.StatusSeeOther},
+		{"session cookie opens web pages", http.MethodGet, "/dashboard", "Cookie", SessionCookie + "=" + readSecret, http.StatusOK},
+		{"sign-in page is open", http.MethodGet, "/login", "", "", http.StatusOK},
+		{"machine endpoints are refused rather than redirected", http.MethodGet, "/metrics", "", "", http.StatusUnauthorized},
+		{"read key scrapes metrics", http.MethodGet, "/metrics", "Authorization", "Bearer " + readSecret, http.StatusOK},
+		{"static assets are open", http.MethodGet, "/static/css/styles.css", "", "", http.StatusOK},
+		{"web actions need a key", http.MethodPost, "/agencies/a1/select", "", "", http.StatusUnauthorized},
+		{"web API reads need a key", http.MethodGet, "/api/web/agents/live", "", "", http.StatusUnauthorized},
+		{"web API changes need a key", http.MethodPost, "/api/web/agents/a1/stop", "", "", http.StatusUnauthorized},
+		{"read key cannot change through the web API", http.MethodPost, "/api/web/agents/a1/stop", "Cookie", SessionCookie + "=" + readSecret, http.StatusForbidden},
+		{"admin key changes through the web API", http.MethodPost, "/api/web/agents/a1/stop", "Cookie", SessionCookie + "=admin-secret", http.StatusOK},
+		{"exempt paths authenticate themselves", http.MethodPost, "/mcp", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+
+	// Page requests without a key are sent to sign in and back
+	req := httptest.NewRequest(http.MethodGet, "/dashboard?agency=a1", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, "/login?next=%2Fdashboard%3Fagency%3Da1", rec.Header().Get("Location"))
+}
+
+func TestService_KeysCannotGrantPermissionsTheCallerLacks(t *testing.T) {
+	service := newTestService(t)
+	service.SetPermissions("customer.pii", "billing.pii")
+	ctx := context.Background()
+
+	admin := WithKey(ctx, &APIKey{ID: "admin", Scopes: []Scope{ScopeDesignerAdmin}})
+	_, _, err := service.CreateKey(admin, CreateKeyRequest{Name: "escalate", Scopes: []Scope{ScopeRead}, Permissions: []string{"*"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+	_, _, err = service.CreateKey(admin, CreateKeyRequest{Name: "escalate", Scopes: []Scope{ScopeRead}, Permissions: []string{"customer.pii"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+	_, _, err = service.CreateKey(ctx, CreateKeyRequest{Name: "escalate", Scopes: []Scope{ScopeRead}, Permissions: []string{"customer.pii"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+
+	pii := WithKey(ctx, &APIKey{ID: "pii", Scopes: []Scope{ScopeDesignerAdmin}, Permissions: []string{"customer.pii"}})
+	_, _, err = service.CreateKey(pii, CreateKeyRequest{Name: "wider", Scopes: []Scope{ScopeRead}, Permissions: []string{"customer.pii", "billing.pii"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+	key, _, err := service.CreateKey(pii, CreateKeyRequest{Name: "reader", Scopes: []Scope{ScopeRead}, Permissions: []string{"customer.pii"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customer.pii"}, key.Permissions)
+
+	// Permissions outside the masking rules are rejected even for "*" holders
+	root := WithKey(ctx, &APIKey{ID: "root", Scopes: []Scope{ScopeDesignerAdmin}, Permissions: []string{"*"}})
+	_, _, err = service.CreateKey(root, CreateKeyRequest{Name: "typo", Scopes: []Scope{ScopeRead}, Permissions: []string{"customer.ppi"}})
+	assert.ErrorIs(t, err, ErrInvalidKeyRequest)
+	_, _, err = service.CreateKey(root, CreateKeyRequest{Name: "billing", Scopes: []Scope{ScopeRead}, Permissions: []string{"billing.pii"}})
+	assert.NoError(t, err)
+}
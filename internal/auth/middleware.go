@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderAPIKey carries an API key as an alternative to a bearer token
+	HeaderAPIKey = "X-API-Key"
+
+	// SessionCookie carries the API key of a signed-in web UI session
+	SessionCookie = "cvxc_session"
+)
+
+// LoginPath is the web UI sign-in page, where page requests without a key
+// are sent
+const LoginPath = "/login"
+
+// publicRoutes are API routes, without the version prefix, that need no key
+var publicRoutes = map[string]bool{
+	"/health":       true,
+	"/auth/session": true,
+}
+
+// publicPages are routes outside the API that need no key: the sign-in page
+// and the liveness probe. Static assets under publicAssets need none either.
+var publicPages = map[string]bool{
+	LoginPath: true,
+	"/health": true,
+}
+
+const publicAssets = "/static/"
+
+// dedicatedScopes are the scopes of changes that the communications scope
+// does not grant, by route pattern
+var dedicatedScopes = map[string]Scope{
+	"/communications/messages/*/unmask":                ScopeUnmask,
+	"/communications/publications/*/unmask":            ScopeUnmask,
+	"/communications/guardrails/violations/*/override": ScopeGuardrailOverride,
+}
+
+type keyContextKey struct{}
+
+// WithKey attaches the authenticated key to ctx
+func WithKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the authenticated key, if any
+func KeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(*APIKey)
+	return key, ok
+}
+
+// Principal names the caller of an authenticated request by its API key, as
+// "key:<id>", or returns "" when the request was not authenticated
+func Principal(ctx context.Context) string {
+	if key, ok := KeyFromContext(ctx); ok {
+		return "key:" + key.ID
+	}
+	return ""
+}
+
+// RequiredScope returns the scope a request needs. route is the request path
+// without its API version prefix.
+func RequiredScope(method, route string) Scope {
+	if route == "/auth/keys" || strings.HasPrefix(route, "/auth/keys/") || route == "/audit" {
+		return ScopeDesignerAdmin
+	}
+	if isReadMethod(method) {
+		return ScopeRead
+	}
+	for pattern, scope := range dedicatedScopes {
+		if matched, _ := path.Match(pattern, route); matched {
+			return scope
+		}
+	}
+	if route == "/communications" || strings.HasPrefix(route, "/communications/") {
+		return ScopeCommunications
+	}
+	return ScopeDesignerAdmin
+}
+
+// isReadMethod reports whether a request method only reads
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// pageRoutes are the web UI pages besides the homepage, with the pages below
+// them. Other routes outside the API, such as /metrics, serve machines.
+var pageRoutes = []string{
+	"/dashboard",
+	"/agencies",
+	"/roles",
+	"/topology",
+	"/geo-network",
+	"/control-room",
+	"/jobs",
+	"/incidents",
+	"/approvals",
+}
+
+// isPage reports whether a request is for a web UI page
+func isPage(method, requestPath string, versioned bool) bool {
+	if versioned || !isReadMethod(method) {
+		return false
+	}
+	return requestPath == "/" || underAny(requestPath, pageRoutes)
+}
+
+// secretFromRequest returns the API key a request carries: a bearer token,
+// the X-API-Key header or the web UI session cookie, in that order
+func secretFromRequest(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Middleware requires an API key with the needed scope and scopes requests to
+// the key's tenant. The web UI pages need a key with the read scope, usually
+// from the session cookie; requests for them without one are redirected to
+// the sign-in page, while other requests, such as Prometheus scrapes of
+// /metrics, are refused with 401. Changes through other routes, such as the web UI's
+// actions, need the designer-admin scope. The sign-in page, static assets,
+// CORS preflight requests, which never carry credentials, and routes under
+// the exempt path prefixes, which authenticate their callers themselves, pass
+// through.
+func Middleware(service *Service, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestPath := c.Request.URL.Path
+		_, route, versioned := apiversion.ParsePath(requestPath)
+		if !versioned {
+			route = requestPath
+		}
+		switch {
+		case c.Request.Method == http.MethodOptions,
+			versioned && publicRoutes[route],
+			!versioned && (publicPages[requestPath] || strings.HasPrefix(requestPath, publicAssets)),
+			underAny(requestPath, exempt):
+			c.Next()
+			return
+		}
+
+		key, authenticated := KeyFromContext(c.Request.Context())
+		if !authenticated {
+			var err error
+			key, err = service.Authenticate(c.Request.Context(), secretFromRequest(c.Request))
+			if err != nil {
+				if !errors.Is(err, ErrInvalidKey) {
+					service.logger.WithError(err).Error("Failed to authenticate API key")
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+					return
+				}
+				if isPage(c.Request.Method, requestPath, versioned) {
+					c.Redirect(http.StatusSeeOther, LoginPath+"?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+					c.Abort()
+					return
+				}
+				c.Header("WWW-Authenticate", `Bearer realm="codevaldcortex"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "unauthorized",
+					"message": "A valid API key is required",
+				})
+				return
+			}
+			ctx := tenant.WithTenant(WithKey(c.Request.Context(), key), key.Tenant)
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		if required := RequiredScope(c.Request.Method, route); !key.HasScope(required) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":          "forbidden",
+				"message":        "The API key does not have the required scope",
+				"required_scope": required,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// underAny reports whether a path is one of the prefixes or below one
+func underAny(requestPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if requestPath == prefix || strings.HasPrefix(requestPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// lastUsedResolution is how often a key's last use is saved at most
+const lastUsedResolution = time.Minute
+
+// staticKey is a key defined in configuration
+type staticKey struct {
+	key  *APIKey
+	hash string
+}
+
+// CreateKeyRequest is the request to create an API key
+type CreateKeyRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Scopes      []Scope    `json:"scopes" binding:"required"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Tenant      string     `json:"tenant,omitempty"`      // Defaults to the caller's tenant
+	Permissions []string   `json:"permissions,omitempty"` // Reveal masked payload fields
+}
+
+// Service manages API keys and authenticates their secrets
+type Service struct {
+	store  Store
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu          sync.RWMutex
+	static      []staticKey
+	permissions map[string]bool
+}
+
+// NewService creates a new API key service
+func NewService(store Store, logger *logrus.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// AddStaticKey accepts a key defined in configuration, scoped to tenantID
+// unless it is empty. Static keys are not stored and cannot be revoked
+// through the API.
+func (s *Service) AddStaticKey(name, secret, tenantID string, scopes []Scope, permissions ...string) error {
+	if name == "" || secret == "" {
+		return errors.New("static API keys need a name and a secret")
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return fmt.Errorf("static API key %s: %w", name, err)
+	}
+	if tenantID != "" {
+		if err := tenant.ValidateID(tenantID); err != nil {
+			return fmt.Errorf("static API key %s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.static = append(s.static, staticKey{
+		key: &APIKey{
+			ID:          "static:" + name,
+			Name:        name,
+			Prefix:      displayPrefix(secret),
+			Scopes:      scopes,
+			Static:      true,
+			Tenant:      tenantID,
+			Permissions: permissions,
+		},
+		hash: hashSecret(secret),
+	})
+	return nil
+}
+
+// SetPermissions sets the permissions keys created through the API may grant,
+// those of the configured masking rules. Without any, such keys grant none.
+func (s *Service) SetPermissions(permissions ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.permissions = make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		s.permissions[permission] = true
+	}
+}
+
+// checkPermissions returns ErrInvalidKeyRequest unless every requested
+// permission is known and held by the caller's key. The "*" permission holds
+// every permission.
+func (s *Service) checkPermissions(ctx context.Context, requested []string) error {
+	if len(requested) == 0 {
+		return nil
+	}
+	var held []string
+	if caller, ok := KeyFromContext(ctx); ok {
+		held = caller.Permissions
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, permission := range requested {
+		if permission != "*" && !s.permissions[permission] {
+			return fmt.Errorf("%w: unknown permission %q", ErrInvalidKeyRequest, permission)
+		}
+		if !slices.Contains(held, "*") && !slices.Contains(held, permission) {
+			return fmt.Errorf("%w: permission %q is not held by the calling key", ErrInvalidKeyRequest, permission)
+		}
+	}
+	return nil
+}
+
+// CreateKey creates a key and returns it with its secret. The secret cannot
+// be retrieved later. Tenant-scoped callers can only create keys for their
+// own tenant, and keys can only grant permissions the caller's key holds.
+func (s *Service) CreateKey(ctx context.Context, req CreateKeyRequest) (*APIKey, string, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidKeyRequest)
+	}
+	if err := ValidateScopes(req.Scopes); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidKeyRequest, err)
+	}
+	if callerTenant := tenant.FromContext(ctx); callerTenant != "" {
+		if req.Tenant != "" && req.Tenant != callerTenant {
+			return nil, "", fmt.Errorf("%w: keys can only be created for tenant %s", ErrInvalidKeyRequest, callerTenant)
+		}
+		req.Tenant = callerTenant
+	}
+	if req.Tenant != "" {
+		if err := tenant.ValidateID(req.Tenant); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidKeyRequest, err)
+		}
+	}
+	if err := s.checkPermissions(ctx, req.Permissions); err != nil {
+		return nil, "", err
+	}
+	now := s.now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidKeyRequest)
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &APIKey{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Prefix:      displayPrefix(secret),
+		Scopes:      req.Scopes,
+		Tenant:      req.Tenant,
+		Permissions: req.Permissions,
+		CreatedAt:   now,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if err := s.store.Create(ctx, key, hashSecret(secret)); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.WithField("key_id", key.ID).WithField("name", key.Name).WithField("scopes", key.Scopes).WithField("tenant", key.Tenant).Info("API key created")
+	return key, secret, nil
+}
+
+// ListKeys returns the static keys followed by the stored keys. Tenant-scoped
+// callers only see their tenant's keys.
+func (s *Service) ListKeys(ctx context.Context) ([]*APIKey, error) {
+	stored, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	keys := make([]*APIKey, 0, len(s.static)+len(stored))
+	for _, static := range s.static {
+		key := *static.key
+		keys = append(keys, &key)
+	}
+	s.mu.RUnlock()
+
+	keys = append(keys, stored...)
+	return slices.DeleteFunc(keys, func(key *APIKey) bool {
+		return !tenant.Allows(ctx, key.Tenant)
+	}), nil
+}
+
+// RevokeKey revokes a stored key. Revoked keys are kept for auditing.
+func (s *Service) RevokeKey(ctx context.Context, id string) (*APIKey, error) {
+	if strings.HasPrefix(id, "static:") {
+		return nil, ErrStaticKey
+	}
+	key, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.Allows(ctx, key.Tenant) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := s.now().UTC()
+	key.RevokedAt = &now
+	if err := s.store.Update(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithField("key_id", key.ID).WithField("name", key.Name).Info("API key revoked")
+	return key, nil
+}
+
+// Authenticate returns the active key with the secret, or ErrInvalidKey
+func (s *Service) Authenticate(ctx context.Context, secret string) (*APIKey, error) {
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+	hash := hashSecret(secret)
+
+	s.mu.RLock()
+	for _, static := range s.static {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(static.hash)) == 1 {
+			key := *static.key
+			s.mu.RUnlock()
+			return &key, nil
+		}
+	}
+	s.mu.RUnlock()
+
+	key, err := s.store.GetByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	now := s.now().UTC()
+	if !key.Active(now) {
+		return nil, ErrInvalidKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		key.LastUsedAt = &now
+		if err := s.store.Update(ctx, key); err != nil {
+			s.logger.WithError(err).WithField("key_id", key.ID).Warn("Failed to record API key use")
+		}
+	}
+	return key, nil
+}
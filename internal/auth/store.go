@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionAPIKeys is the API key collection name
+const CollectionAPIKeys = "api_keys"
+
+// InMemoryStore keeps API keys in memory. Keys created through the API are
+// lost on restart, so it only backs tests and deployments without
+// authentication.
+type InMemoryStore struct {
+	mu     sync.RWMutex
+	keys   map[string]*APIKey
+	hashes map[string]string // hash -> key ID
+}
+
+// NewInMemoryStore creates a new in-memory API key store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		keys:   make(map[string]*APIKey),
+		hashes: make(map[string]string),
+	}
+}
+
+// Create stores a new key
+func (s *InMemoryStore) Create(ctx context.Context, key *APIKey, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[key.ID]; exists {
+		return fmt.Errorf("API key %s already exists", key.ID)
+	}
+	stored := *key
+	s.keys[key.ID] = &stored
+	s.hashes[hash] = key.ID
+	return nil
+}
+
+// GetByHash retrieves the key whose secret has the hash
+func (s *InMemoryStore) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, exists := s.hashes[hash]
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+	key := *s.keys[id]
+	return &key, nil
+}
+
+// Get retrieves a key by ID
+func (s *InMemoryStore) Get(ctx context.Context, id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, exists := s.keys[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	key := *stored
+	return &key, nil
+}
+
+// List returns all keys, oldest first
+func (s *InMemoryStore) List(ctx context.Context) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, stored := range s.keys {
+		key := *stored
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Update saves a key's revocation and last use
+func (s *InMemoryStore) Update(ctx context.Context, key *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.keys[key.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, key.ID)
+	}
+	stored.RevokedAt = key.RevokedAt
+	stored.LastUsedAt = key.LastUsedAt
+	return nil
+}
+
+// keyDocument is an API key as stored in ArangoDB
+type keyDocument struct {
+	*APIKey
+	Hash string `json:"hash"`
+}
+
+// ArangoStore persists API keys in ArangoDB
+type ArangoStore struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoStore creates a new ArangoDB-backed API key store
+func NewArangoStore(dbClient *database.ArangoClient) (*ArangoStore, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionAPIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionAPIKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionAPIKeys, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionAPIKeys).Info("Created new collection")
+	}
+
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"hash"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_api_keys_hash",
+		Unique: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index idx_api_keys_hash: %w", err)
+	}
+
+	return &ArangoStore{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create stores a new key
+func (s *ArangoStore) Create(ctx context.Context, key *APIKey, hash string) error {
+	key.Key = key.ID
+	if _, err := s.collection.CreateDocument(ctx, keyDocument{APIKey: key, Hash: hash}); err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves the key whose secret has the hash
+func (s *ArangoStore) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	keys, err := s.query(ctx, `
+		FOR k IN @@collection
+			FILTER k.hash == @hash
+			LIMIT 1
+			RETURN UNSET(k, "hash")
+	`, map[string]interface{}{"@collection": CollectionAPIKeys, "hash": hash})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	return keys[0], nil
+}
+
+// Get retrieves a key by ID
+func (s *ArangoStore) Get(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	if _, err := s.collection.ReadDocument(ctx, id, &key); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read API key: %w", err)
+	}
+	return &key, nil
+}
+
+// List returns all keys, oldest first
+func (s *ArangoStore) List(ctx context.Context) ([]*APIKey, error) {
+	return s.query(ctx, `
+		FOR k IN @@collection
+			SORT k.created_at ASC
+			RETURN UNSET(k, "hash")
+	`, map[string]interface{}{"@collection": CollectionAPIKeys})
+}
+
+// Update saves a key's revocation and last use
+func (s *ArangoStore) Update(ctx context.Context, key *APIKey) error {
+	patch := map[string]interface{}{
+		"revoked_at":   key.RevokedAt,
+		"last_used_at": key.LastUsedAt,
+	}
+	if _, err := s.collection.UpdateDocument(ctx, key.ID, patch); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrKeyNotFound, key.ID)
+		}
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+func (s *ArangoStore) query(ctx context.Context, query string, bindVars map[string]interface{}) ([]*APIKey, error) {
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer cursor.Close()
+
+	var keys []*APIKey
+	for cursor.HasMore() {
+		var key APIKey
+		if _, err := cursor.ReadDocument(ctx, &key); err != nil {
+			return nil, fmt.Errorf("failed to read API key: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,471 @@
+// Package backfill migrates historical pub/sub traffic into subsystems that
+// were added after the traffic was recorded.
+//
+// A run reads publications from the stored pub/sub history or from an
+// archived traffic capture file and replays them, oldest first, through the
+// selected pipelines. Each pipeline ingests publications into one subsystem,
+// such as derived metric history or daily usage totals. A ledger records
+// which publications each pipeline has applied, so a run can be repeated or
+// resumed without applying a publication twice. Every run ends with a
+// reconciliation report comparing the publications each pipeline matched
+// with those it applied.
+//
+// Runs execute as background jobs of type JobType.
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	log "github.com/sirupsen/logrus"
+)
+
+// JobType is the job type that runs backfills
+const JobType = "backfill.run"
+
+// Sources of the publications a run replays
+const (
+	// SourceStored reads publications still kept by the pub/sub service
+	SourceStored = "stored"
+
+	// SourceArchive reads a traffic capture file from the archive directory
+	SourceArchive = "archive"
+)
+
+// maxReportErrors caps the errors listed per pipeline in a report
+const maxReportErrors = 20
+
+var (
+	// ErrInvalidRequest is returned for backfill requests that cannot run
+	ErrInvalidRequest = errors.New("invalid backfill request")
+
+	// ErrUnknownPipeline is returned for pipeline names that are not registered
+	ErrUnknownPipeline = errors.New("unknown backfill pipeline")
+)
+
+// Pipeline ingests historical publications into a subsystem
+type Pipeline interface {
+	// Name identifies the pipeline in requests, the ledger and reports
+	Name() string
+
+	// Accepts reports whether the pipeline ingests the publication
+	Accepts(pub *communication.Publication) bool
+
+	// Apply ingests the publication
+	Apply(ctx context.Context, pub *communication.Publication) error
+}
+
+// Flusher is implemented by pipelines that buffer their writes. Flush is
+// called once all publications of a run have been applied.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// TrafficSource reads recorded pub/sub traffic. PubSubService implements it.
+type TrafficSource interface {
+	CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error)
+}
+
+// Config configures backfills
+type Config struct {
+	// ArchiveDir holds traffic capture files that archive runs may read.
+	// Archive runs are disabled when it is empty.
+	ArchiveDir string
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.BackfillConfig) Config {
+	return Config{ArchiveDir: cfg.ArchiveDir}
+}
+
+// Request describes a backfill run
+type Request struct {
+	// Source is SourceStored or SourceArchive
+	Source string `json:"source"`
+
+	// Topics, Since and Until select stored publications. Topics are event
+	// patterns and default to all events.
+	Topics []string  `json:"topics,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+	Until  time.Time `json:"until,omitempty"`
+
+	// Archive is the name of a capture file in the archive directory
+	Archive string `json:"archive,omitempty"`
+
+	// Pipelines are the pipelines to run. They must be named explicitly
+	// because replaying traffic a subsystem has already seen live would
+	// count it twice.
+	Pipelines []string `json:"pipelines"`
+
+	// DryRun reports what would be applied without applying anything
+	DryRun bool `json:"dry_run"`
+}
+
+// Payload returns the request as a job payload
+func (r Request) Payload() map[string]interface{} {
+	var payload map[string]interface{}
+	data, _ := json.Marshal(r)
+	json.Unmarshal(data, &payload)
+	return payload
+}
+
+// RequestFromPayload reads a request from a job payload
+func RequestFromPayload(payload map[string]interface{}) (Request, error) {
+	var r Request
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return r, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	return r, nil
+}
+
+// PipelineReport reconciles one pipeline's part of a run
+type PipelineReport struct {
+	Pipeline string `json:"pipeline"`
+
+	// Matched is the number of publications the pipeline accepts
+	Matched int `json:"matched"`
+
+	// Applied is the number of publications applied by this run (in a dry
+	// run, the number that would be applied)
+	Applied int `json:"applied"`
+
+	// AlreadyApplied is the number of publications skipped because an
+	// earlier run applied them
+	AlreadyApplied int `json:"already_applied"`
+
+	Failed int      `json:"failed"`
+	Errors []string `json:"errors,omitempty"`
+
+	// Reconciled is true when every matched publication is applied
+	Reconciled bool `json:"reconciled"`
+}
+
+// Report summarises a backfill run
+type Report struct {
+	RunID  string `json:"run_id"`
+	Source string `json:"source"`
+	DryRun bool   `json:"dry_run"`
+
+	// Since and Until bound the replayed publications
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	// Total is the number of publications read
+	Total int `json:"total"`
+
+	// Unmatched is the number of publications no selected pipeline accepts
+	Unmatched int `json:"unmatched"`
+
+	Pipelines  []*PipelineReport `json:"pipelines"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+}
+
+// Failed returns the number of publications that failed in any pipeline
+func (r *Report) Failed() int {
+	failed := 0
+	for _, p := range r.Pipelines {
+		failed += p.Failed
+	}
+	return failed
+}
+
+// Map returns the report as a job result
+func (r *Report) Map() map[string]interface{} {
+	var result map[string]interface{}
+	data, _ := json.Marshal(r)
+	json.Unmarshal(data, &result)
+	return result
+}
+
+// Migrator replays historical traffic through registered pipelines
+type Migrator struct {
+	ledger Ledger
+	source TrafficSource
+	config Config
+	logger *log.Logger
+
+	mu        sync.RWMutex
+	pipelines map[string]Pipeline
+	order     []string
+}
+
+// NewMigrator creates a new migrator. The source may be nil, in which case
+// only archive runs are possible.
+func NewMigrator(ledger Ledger, source TrafficSource, cfg Config, logger *log.Logger) *Migrator {
+	return &Migrator{
+		ledger:    ledger,
+		source:    source,
+		config:    cfg,
+		logger:    logger,
+		pipelines: make(map[string]Pipeline),
+	}
+}
+
+// Register adds a pipeline, replacing one with the same name
+func (m *Migrator) Register(pipeline Pipeline) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.pipelines[pipeline.Name()]; !exists {
+		m.order = append(m.order, pipeline.Name())
+	}
+	m.pipelines[pipeline.Name()] = pipeline
+}
+
+// Pipelines returns the names of the registered pipelines
+func (m *Migrator) Pipelines() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.order...)
+}
+
+// Validate checks that a request can run
+func (m *Migrator) Validate(r Request) error {
+	switch r.Source {
+	case SourceStored:
+		if m.source == nil {
+			return fmt.Errorf("%w: stored publications are not available", ErrInvalidRequest)
+		}
+		if r.Since.IsZero() {
+			return fmt.Errorf("%w: since is required", ErrInvalidRequest)
+		}
+		if !r.Until.IsZero() && !r.Until.After(r.Since) {
+			return fmt.Errorf("%w: until must be after since", ErrInvalidRequest)
+		}
+	case SourceArchive:
+		if m.config.ArchiveDir == "" {
+			return fmt.Errorf("%w: no archive directory is configured", ErrInvalidRequest)
+		}
+		if r.Archive == "" || filepath.Base(r.Archive) != r.Archive || strings.HasPrefix(r.Archive, ".") {
+			return fmt.Errorf("%w: archive must be a file name in the archive directory", ErrInvalidRequest)
+		}
+	default:
+		return fmt.Errorf("%w: source must be %q or %q", ErrInvalidRequest, SourceStored, SourceArchive)
+	}
+
+	if len(r.Pipelines) == 0 {
+		return fmt.Errorf("%w: at least one pipeline is required", ErrInvalidRequest)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, name := range r.Pipelines {
+		if _, ok := m.pipelines[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownPipeline, name)
+		}
+	}
+	return nil
+}
+
+// Load reads the publications a request replays
+func (m *Migrator) Load(ctx context.Context, r Request) (*communication.TrafficCapture, error) {
+	if err := m.Validate(r); err != nil {
+		return nil, err
+	}
+
+	if r.Source == SourceStored {
+		topics := r.Topics
+		if len(topics) == 0 {
+			topics = []string{"#"}
+		}
+		until := r.Until
+		if until.IsZero() {
+			until = time.Now()
+		}
+		return m.source.CaptureTraffic(ctx, topics, r.Since, until)
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.config.ArchiveDir, r.Archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", r.Archive, err)
+	}
+	var capture communication.TrafficCapture
+	if err := json.Unmarshal(data, &capture); err != nil {
+		return nil, fmt.Errorf("%w: archive %s is not a traffic capture: %v", ErrInvalidRequest, r.Archive, err)
+	}
+	sort.SliceStable(capture.Publications, func(i, j int) bool {
+		return capture.Publications[i].PublishedAt.Before(capture.Publications[j].PublishedAt)
+	})
+	return &capture, nil
+}
+
+// Run replays a capture through the request's pipelines. Publications a
+// pipeline applied in an earlier run are skipped. An error is returned when
+// the ledger or a pipeline flush fails; failures of single publications
+// are only reported.
+func (m *Migrator) Run(ctx context.Context, runID string, r Request, capture *communication.TrafficCapture) (*Report, error) {
+	if err := m.Validate(r); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		RunID:     runID,
+		Source:    r.Source,
+		DryRun:    r.DryRun,
+		Since:     capture.Since,
+		Until:     capture.Until,
+		Total:     len(capture.Publications),
+		StartedAt: time.Now().UTC(),
+	}
+
+	m.mu.RLock()
+	pipelines := make([]Pipeline, len(r.Pipelines))
+	for i, name := range r.Pipelines {
+		pipelines[i] = m.pipelines[name]
+		report.Pipelines = append(report.Pipelines, &PipelineReport{Pipeline: name})
+	}
+	m.mu.RUnlock()
+
+	for _, captured := range capture.Publications {
+		if err := ctx.Err(); err != nil {
+			return m.finish(report), err
+		}
+
+		pub := publicationFromCapture(captured)
+		matched := false
+		for i, pipeline := range pipelines {
+			if !pipeline.Accepts(pub) {
+				continue
+			}
+			matched = true
+			if err := m.apply(ctx, pipeline, report.Pipelines[i], runID, pub, r.DryRun); err != nil {
+				return m.finish(report), err
+			}
+		}
+		if !matched {
+			report.Unmatched++
+		}
+	}
+
+	if !r.DryRun {
+		for _, pipeline := range pipelines {
+			if flusher, ok := pipeline.(Flusher); ok {
+				if err := flusher.Flush(ctx); err != nil {
+					return m.finish(report), fmt.Errorf("failed to flush pipeline %s: %w", pipeline.Name(), err)
+				}
+			}
+		}
+	}
+
+	m.finish(report)
+	m.logger.WithFields(log.Fields{
+		"run_id":    runID,
+		"source":    r.Source,
+		"total":     report.Total,
+		"unmatched": report.Unmatched,
+		"failed":    report.Failed(),
+		"dry_run":   r.DryRun,
+	}).Info("Backfill finished")
+	return report, nil
+}
+
+// apply replays one publication through a pipeline. Only ledger errors are
+// returned, since without the ledger the run is no longer idempotent.
+func (m *Migrator) apply(ctx context.Context, pipeline Pipeline, pr *PipelineReport, runID string, pub *communication.Publication, dryRun bool) error {
+	pr.Matched++
+
+	if pub.ID == "" {
+		pr.fail("publication without an ID cannot be applied idempotently")
+		return nil
+	}
+
+	applied, err := m.ledger.Applied(ctx, pipeline.Name(), pub.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check backfill ledger: %w", err)
+	}
+	if applied {
+		pr.AlreadyApplied++
+		return nil
+	}
+	if dryRun {
+		pr.Applied++
+		return nil
+	}
+
+	if err := pipeline.Apply(ctx, pub); err != nil {
+		pr.fail(fmt.Sprintf("%s: %v", pub.ID, err))
+		return nil
+	}
+	if err := m.ledger.Record(ctx, pipeline.Name(), pub.ID, runID); err != nil {
+		return fmt.Errorf("failed to record %s in backfill ledger: %w", pub.ID, err)
+	}
+	pr.Applied++
+	return nil
+}
+
+// finish completes a report's reconciliation
+func (m *Migrator) finish(report *Report) *Report {
+	for _, pr := range report.Pipelines {
+		pr.Reconciled = pr.Failed == 0 && pr.Matched == pr.Applied+pr.AlreadyApplied
+	}
+	report.FinishedAt = time.Now().UTC()
+	return report
+}
+
+// fail counts a failed publication and keeps its error
+func (pr *PipelineReport) fail(message string) {
+	pr.Failed++
+	if len(pr.Errors) < maxReportErrors {
+		pr.Errors = append(pr.Errors, message)
+	}
+}
+
+// HandleJob runs the backfill described by a job's payload and saves the
+// report as the job's result. Runs with failed publications are retried;
+// publications applied by earlier attempts are skipped.
+func (m *Migrator) HandleJob(ctx context.Context, job *jobs.Job) error {
+	r, err := RequestFromPayload(job.Payload)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	capture, err := m.Load(ctx, r)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrUnknownPipeline) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+
+	report, err := m.Run(ctx, job.ID, r, capture)
+	if report != nil {
+		job.Result = report.Map()
+	}
+	if err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("%d publications failed to apply", failed)
+	}
+	return nil
+}
+
+// publicationFromCapture rebuilds a publication under its original ID
+func publicationFromCapture(captured communication.CapturedPublication) *communication.Publication {
+	return &communication.Publication{
+		ID:                 captured.OriginalID,
+		PublisherAgentID:   captured.PublisherAgentID,
+		PublisherAgentType: captured.PublisherAgentType,
+		PublicationType:    captured.PublicationType,
+		EventName:          captured.EventName,
+		Payload:            captured.Payload,
+		TTLSeconds:         captured.TTLSeconds,
+		Metadata:           captured.Metadata,
+		PublishedAt:        captured.PublishedAt,
+	}
+}
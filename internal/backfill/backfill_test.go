@@ -0,0 +1,170 @@
+package backfill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/derivedmetrics"
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	"github.com/aosanya/CodeValdCortex/internal/usage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+type fakeTraffic struct {
+	capture *communication.TrafficCapture
+}
+
+func (f *fakeTraffic) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error) {
+	return f.capture, nil
+}
+
+// failingPipeline fails publications of one event
+type failingPipeline struct {
+	event   string
+	applied int
+}
+
+func (p *failingPipeline) Name() string                                { return "failing" }
+func (p *failingPipeline) Accepts(pub *communication.Publication) bool { return true }
+func (p *failingPipeline) Apply(ctx context.Context, pub *communication.Publication) error {
+	if pub.EventName == p.event {
+		return errors.New("rejected")
+	}
+	p.applied++
+	return nil
+}
+
+func readings(start time.Time) *communication.TrafficCapture {
+	capture := &communication.TrafficCapture{Since: start, Until: start.Add(48 * time.Hour)}
+	for i, pressure := range []float64{5, 6, 7} {
+		capture.Publications = append(capture.Publications, communication.CapturedPublication{
+			OriginalID:       []string{"p1", "p2", "p3"}[i],
+			PublisherAgentID: []string{"SENSOR-001", "SENSOR-002", "SENSOR-001"}[i],
+			EventName:        "reading.pressure",
+			Payload:          map[string]interface{}{"pressure_bar": pressure},
+			Metadata:         map[string]string{"agency_id": "agency-a"},
+			PublishedAt:      start.Add(time.Duration(i) * 24 * time.Hour),
+		})
+	}
+	return capture
+}
+
+func newTestMigrator(t *testing.T, source TrafficSource, cfg Config) (*Migrator, *derivedmetrics.Service, usage.Repository) {
+	t.Helper()
+	metrics := derivedmetrics.NewService(derivedmetrics.Config{}, nil, testLogger())
+	require.NoError(t, metrics.Define(derivedmetrics.Definition{Name: "zone_avg_pressure", Expression: "avg(SENSOR-001..002.pressure_bar)"}))
+	usageRepo := usage.NewInMemoryRepository()
+
+	migrator := NewMigrator(NewInMemoryLedger(), source, cfg, testLogger())
+	migrator.Register(NewDerivedMetricsPipeline(metrics))
+	migrator.Register(NewUsagePipeline(usage.NewService(usageRepo, usage.Config{}, testLogger())))
+	return migrator, metrics, usageRepo
+}
+
+func TestRun_AppliesOnceAndReconciles(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	capture := readings(start)
+	migrator, metrics, usageRepo := newTestMigrator(t, &fakeTraffic{capture: capture}, Config{})
+	req := Request{Source: SourceStored, Since: start, Pipelines: []string{PipelineDerivedMetrics, PipelineUsage}}
+
+	// A dry run applies nothing
+	dry := req
+	dry.DryRun = true
+	report, err := migrator.Run(ctx, "run-0", dry, capture)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Pipelines[0].Applied)
+	totals, _ := usageRepo.ListUsage(ctx, "", "2025-01-01", "2025-01-03")
+	assert.Empty(t, totals)
+
+	report, err = migrator.Run(ctx, "run-1", req, capture)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	for _, pr := range report.Pipelines {
+		assert.Equal(t, 3, pr.Matched, pr.Pipeline)
+		assert.Equal(t, 3, pr.Applied, pr.Pipeline)
+		assert.True(t, pr.Reconciled, pr.Pipeline)
+	}
+
+	history, err := metrics.History(ctx, "zone_avg_pressure", start, start.Add(72*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, 6.5, history[2].Value)
+	assert.Equal(t, start.Add(48*time.Hour), history[2].ComputedAt, "values keep the publication time")
+
+	totals, err = usageRepo.ListUsage(ctx, "agency-a", "2025-01-01", "2025-01-03")
+	require.NoError(t, err)
+	require.Len(t, totals, 3, "usage is counted on the day of each publication")
+	assert.Equal(t, 1.0, totals[0].Metrics[usage.MetricMessagesPublished])
+
+	// Running again skips everything already applied
+	report, err = migrator.Run(ctx, "run-2", req, capture)
+	require.NoError(t, err)
+	for _, pr := range report.Pipelines {
+		assert.Equal(t, 0, pr.Applied, pr.Pipeline)
+		assert.Equal(t, 3, pr.AlreadyApplied, pr.Pipeline)
+		assert.True(t, pr.Reconciled, pr.Pipeline)
+	}
+	totals, _ = usageRepo.ListUsage(ctx, "agency-a", "2025-01-01", "2025-01-01")
+	assert.Equal(t, 1.0, totals[0].Metrics[usage.MetricMessagesPublished])
+}
+
+func TestHandleJob_RetriesOnlyFailedPublications(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	capture := readings(start)
+	capture.Publications[1].EventName = "reading.bad"
+	migrator, _, _ := newTestMigrator(t, &fakeTraffic{capture: capture}, Config{})
+	failing := &failingPipeline{event: "reading.bad"}
+	migrator.Register(failing)
+
+	job := jobs.NewJob(JobType, Request{Source: SourceStored, Since: start, Pipelines: []string{"failing"}}.Payload(), start)
+	err := migrator.HandleJob(ctx, job)
+	require.Error(t, err)
+	assert.False(t, jobs.IsPermanent(err), "failed publications are retried")
+	assert.Equal(t, 2, failing.applied)
+	assert.Equal(t, false, job.Result["pipelines"].([]interface{})[0].(map[string]interface{})["reconciled"])
+
+	failing.event = ""
+	require.NoError(t, migrator.HandleJob(ctx, job))
+	assert.Equal(t, 3, failing.applied, "only the failed publication is applied again")
+}
+
+func TestArchiveSource(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(readings(start))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "incident.json"), data, 0o644))
+
+	migrator, _, _ := newTestMigrator(t, nil, Config{ArchiveDir: dir})
+	req := Request{Source: SourceArchive, Archive: "incident.json", Pipelines: []string{PipelineUsage}}
+	capture, err := migrator.Load(ctx, req)
+	require.NoError(t, err)
+	assert.Len(t, capture.Publications, 3)
+
+	for _, invalid := range []Request{
+		{Source: SourceArchive, Archive: "../etc/passwd", Pipelines: []string{PipelineUsage}},
+		{Source: SourceStored, Since: start, Pipelines: []string{PipelineUsage}},
+		{Source: SourceArchive, Archive: "incident.json"},
+	} {
+		assert.ErrorIs(t, migrator.Validate(invalid), ErrInvalidRequest)
+	}
+	assert.ErrorIs(t, migrator.Validate(Request{Source: SourceArchive, Archive: "incident.json", Pipelines: []string{"incidents"}}), ErrUnknownPipeline)
+}
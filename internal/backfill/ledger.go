@@ -0,0 +1,138 @@
+package backfill
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionLedger is the backfill ledger collection name
+const CollectionLedger = "backfill_ledger"
+
+// Ledger records which publications each pipeline has applied
+type Ledger interface {
+	// Applied reports whether the pipeline has applied the publication
+	Applied(ctx context.Context, pipeline, publicationID string) (bool, error)
+
+	// Record marks the publication as applied by the pipeline in a run.
+	// Recording an entry twice is not an error.
+	Record(ctx context.Context, pipeline, publicationID, runID string) error
+}
+
+// LedgerEntry is a publication applied by a pipeline
+type LedgerEntry struct {
+	Key           string    `json:"_key,omitempty"`
+	Pipeline      string    `json:"pipeline"`
+	PublicationID string    `json:"publication_id"`
+	RunID         string    `json:"run_id"`
+	AppliedAt     time.Time `json:"applied_at"`
+}
+
+// ledgerKey derives a document key from a pipeline and publication ID,
+// which may contain characters keys do not allow
+func ledgerKey(pipeline, publicationID string) string {
+	sum := sha1.Sum([]byte(pipeline + "|" + publicationID))
+	return hex.EncodeToString(sum[:])
+}
+
+// ArangoLedger persists the ledger in ArangoDB
+type ArangoLedger struct {
+	collection driver.Collection
+}
+
+// NewArangoLedger creates a new ArangoDB-backed ledger
+func NewArangoLedger(dbClient *database.ArangoClient) (*ArangoLedger, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionLedger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionLedger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionLedger, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionLedger).Info("Created new collection")
+	}
+
+	return &ArangoLedger{collection: col}, nil
+}
+
+// Applied reports whether the pipeline has applied the publication
+func (l *ArangoLedger) Applied(ctx context.Context, pipeline, publicationID string) (bool, error) {
+	exists, err := l.collection.DocumentExists(ctx, ledgerKey(pipeline, publicationID))
+	if err != nil {
+		return false, fmt.Errorf("failed to read ledger entry: %w", err)
+	}
+	return exists, nil
+}
+
+// Record marks the publication as applied by the pipeline
+func (l *ArangoLedger) Record(ctx context.Context, pipeline, publicationID, runID string) error {
+	entry := &LedgerEntry{
+		Key:           ledgerKey(pipeline, publicationID),
+		Pipeline:      pipeline,
+		PublicationID: publicationID,
+		RunID:         runID,
+		AppliedAt:     time.Now().UTC(),
+	}
+	if _, err := l.collection.CreateDocument(ctx, entry); err != nil && !driver.IsConflict(err) {
+		return fmt.Errorf("failed to create ledger entry: %w", err)
+	}
+	return nil
+}
+
+// InMemoryLedger keeps the ledger in memory
+type InMemoryLedger struct {
+	mu      sync.RWMutex
+	entries map[string]*LedgerEntry
+}
+
+// NewInMemoryLedger creates a new in-memory ledger
+func NewInMemoryLedger() *InMemoryLedger {
+	return &InMemoryLedger{
+		entries: make(map[string]*LedgerEntry),
+	}
+}
+
+// Applied reports whether the pipeline has applied the publication
+func (l *InMemoryLedger) Applied(ctx context.Context, pipeline, publicationID string) (bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.entries[ledgerKey(pipeline, publicationID)]
+	return ok, nil
+}
+
+// Record marks the publication as applied by the pipeline
+func (l *InMemoryLedger) Record(ctx context.Context, pipeline, publicationID, runID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := ledgerKey(pipeline, publicationID)
+	if _, ok := l.entries[key]; !ok {
+		l.entries[key] = &LedgerEntry{
+			Key:           key,
+			Pipeline:      pipeline,
+			PublicationID: publicationID,
+			RunID:         runID,
+			AppliedAt:     time.Now().UTC(),
+		}
+	}
+	return nil
+}
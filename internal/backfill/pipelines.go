@@ -0,0 +1,70 @@
+package backfill
+
+import (
+	"context"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/derivedmetrics"
+	"github.com/aosanya/CodeValdCortex/internal/usage"
+)
+
+// Pipeline names
+const (
+	PipelineDerivedMetrics = "derived_metrics"
+	PipelineUsage          = "usage"
+)
+
+// derivedMetricsPipeline feeds historical payloads into derived metric
+// series, recording the values they produce in the metrics' history
+type derivedMetricsPipeline struct {
+	service *derivedmetrics.Service
+}
+
+// NewDerivedMetricsPipeline creates a pipeline into the derived metric store.
+// Backfilled values are not published.
+func NewDerivedMetricsPipeline(service *derivedmetrics.Service) Pipeline {
+	return &derivedMetricsPipeline{service: service}
+}
+
+func (p *derivedMetricsPipeline) Name() string {
+	return PipelineDerivedMetrics
+}
+
+func (p *derivedMetricsPipeline) Accepts(pub *communication.Publication) bool {
+	return pub.PublisherAgentID != derivedmetrics.PublisherAgentID
+}
+
+func (p *derivedMetricsPipeline) Apply(ctx context.Context, pub *communication.Publication) error {
+	p.service.Backfill(pub)
+	return nil
+}
+
+// usagePipeline counts historical publications into the daily usage totals
+// of their tenant and day. It is meant for traffic from before usage
+// metering was enabled, which was never counted.
+type usagePipeline struct {
+	service *usage.Service
+}
+
+// NewUsagePipeline creates a pipeline into the daily usage totals
+func NewUsagePipeline(service *usage.Service) Pipeline {
+	return &usagePipeline{service: service}
+}
+
+func (p *usagePipeline) Name() string {
+	return PipelineUsage
+}
+
+func (p *usagePipeline) Accepts(pub *communication.Publication) bool {
+	return true
+}
+
+func (p *usagePipeline) Apply(ctx context.Context, pub *communication.Publication) error {
+	p.service.RecordTenantAt(usage.PublicationTenant(ctx, pub), usage.MetricMessagesPublished, 1, pub.PublishedAt)
+	return nil
+}
+
+// Flush writes the counted publications to the daily totals
+func (p *usagePipeline) Flush(ctx context.Context) error {
+	return p.service.Flush(ctx)
+}
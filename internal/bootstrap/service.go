@@ -0,0 +1,362 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidManifest is returned when a manifest fails validation
+var ErrInvalidManifest = errors.New("invalid bootstrap manifest")
+
+// AgentStore provides the agent operations needed by the bootstrap
+type AgentStore interface {
+	GetAgent(agentID string) (*agent.Agent, error)
+	RegisterAgent(a *agent.Agent) error
+	UpdateAgent(a *agent.Agent) error
+}
+
+// SubscriptionStore provides the subscription operations needed by the bootstrap
+type SubscriptionStore interface {
+	GetActiveSubscriptions(ctx context.Context, agentID string) ([]*communication.Subscription, error)
+	Subscribe(ctx context.Context, subscriberAgentID, subscriberAgentType, eventPattern string, filters *communication.SubscriptionFilters) (string, error)
+	Unsubscribe(ctx context.Context, subscriptionID string) error
+}
+
+// Service idempotently provisions agents and subscriptions from a manifest
+type Service struct {
+	agents        AgentStore
+	subscriptions SubscriptionStore
+	matcher       *communication.SubscriptionMatcher
+	logger        *logrus.Logger
+}
+
+// NewService creates a new bootstrap service.
+// subscriptions may be nil when pub/sub is unavailable; subscription
+// declarations are then reported as failed.
+func NewService(agents AgentStore, subscriptions SubscriptionStore, logger *logrus.Logger) *Service {
+	return &Service{
+		agents:        agents,
+		subscriptions: subscriptions,
+		matcher:       communication.NewSubscriptionMatcher(),
+		logger:        logger,
+	}
+}
+
+// Apply ensures every resource in the manifest exists as declared.
+// Resources are processed in manifest order so repeated runs produce the
+// same result; a second run of the same manifest reports no changes.
+func (s *Service) Apply(ctx context.Context, manifest *Manifest) (*Result, error) {
+	if err := s.validate(manifest); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Agents:        make([]ItemResult, 0, len(manifest.Agents)),
+		Topics:        make([]ItemResult, 0, len(manifest.Topics)),
+		Subscriptions: make([]ItemResult, 0, len(manifest.Subscriptions)),
+	}
+
+	for _, spec := range manifest.Agents {
+		item := s.ensureAgent(spec)
+		result.Summary.add(item.Action)
+		result.Agents = append(result.Agents, item)
+	}
+
+	for _, topic := range manifest.Topics {
+		// Topics are implicit in pub/sub event names, there is nothing to store
+		item := ItemResult{ID: topic, Action: ActionNoop}
+		if !s.topicHasSubscriber(topic, manifest.Subscriptions) {
+			item.Message = "no declared subscription matches this topic"
+		}
+		result.Summary.add(item.Action)
+		result.Topics = append(result.Topics, item)
+	}
+
+	for _, spec := range manifest.Subscriptions {
+		item := s.ensureSubscription(ctx, spec)
+		result.Summary.add(item.Action)
+		result.Subscriptions = append(result.Subscriptions, item)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"created":   result.Summary.Created,
+		"updated":   result.Summary.Updated,
+		"unchanged": result.Summary.Unchanged,
+		"failed":    result.Summary.Failed,
+	}).Info("Bootstrap manifest applied")
+
+	return result, nil
+}
+
+// ensureAgent creates the agent if missing or updates it if it differs
+func (s *Service) ensureAgent(spec AgentSpec) ItemResult {
+	item := ItemResult{ID: spec.ID}
+
+	name := spec.Name
+	if name == "" {
+		name = spec.ID
+	}
+
+	existing, err := s.agents.GetAgent(spec.ID)
+	if err != nil {
+		if !errors.Is(err, agent.ErrAgentNotFound) {
+			item.Action = ActionFailed
+			item.Message = err.Error()
+			return item
+		}
+
+		a := agent.New(name, spec.Type, agent.Config{})
+		a.ID = spec.ID
+		for k, v := range spec.Metadata {
+			a.Metadata[k] = v
+		}
+
+		if err := s.agents.RegisterAgent(a); err != nil {
+			item.Action = ActionFailed
+			item.Message = err.Error()
+			return item
+		}
+
+		item.Action = ActionCreated
+		return item
+	}
+
+	// The registered agent is shared with the runtime, so changes are made to
+	// a copy and saved back through the store
+	updated := describe(existing)
+	if updated.Name != name {
+		item.Changes = append(item.Changes, "name")
+		updated.Name = name
+	}
+	if updated.Type != spec.Type {
+		item.Changes = append(item.Changes, "type")
+		updated.Type = spec.Type
+	}
+	metadataChanged := false
+	for k, v := range spec.Metadata {
+		if current, ok := updated.Metadata[k]; !ok || current != v {
+			updated.Metadata[k] = v
+			metadataChanged = true
+		}
+	}
+	if metadataChanged {
+		item.Changes = append(item.Changes, "metadata")
+	}
+
+	if len(item.Changes) == 0 {
+		item.Action = ActionUnchanged
+		return item
+	}
+
+	if err := s.agents.UpdateAgent(updated); err != nil {
+		item.Action = ActionFailed
+		item.Message = err.Error()
+		return item
+	}
+
+	item.Action = ActionUpdated
+	return item
+}
+
+// describe copies the descriptive fields of an agent: its identity, metadata
+// and configuration, without the runtime state of the agent instance
+func describe(a *agent.Agent) *agent.Agent {
+	metadata := make(map[string]string, len(a.Metadata))
+	for k, v := range a.Metadata {
+		metadata[k] = v
+	}
+	return &agent.Agent{
+		ID:        a.ID,
+		Name:      a.Name,
+		Type:      a.Type,
+		State:     a.GetState(),
+		Metadata:  metadata,
+		Config:    a.Config,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+// ensureSubscription creates the subscription if missing or replaces it if its filters differ
+func (s *Service) ensureSubscription(ctx context.Context, spec SubscriptionSpec) ItemResult {
+	item := ItemResult{ID: subscriptionKey(spec)}
+
+	if s.subscriptions == nil {
+		item.Action = ActionFailed
+		item.Message = "pub/sub service is not available"
+		return item
+	}
+
+	active, err := s.subscriptions.GetActiveSubscriptions(ctx, spec.AgentID)
+	if err != nil {
+		item.Action = ActionFailed
+		item.Message = err.Error()
+		return item
+	}
+
+	var existing *communication.Subscription
+	for _, sub := range active {
+		if sub.EventPattern == spec.EventPattern {
+			existing = sub
+			break
+		}
+	}
+
+	if existing != nil {
+		item.Changes = subscriptionChanges(existing, spec)
+		if len(item.Changes) == 0 {
+			item.Action = ActionUnchanged
+			item.SubscriptionID = existing.ID
+			return item
+		}
+
+		if err := s.subscriptions.Unsubscribe(ctx, existing.ID); err != nil {
+			item.Action = ActionFailed
+			item.Message = err.Error()
+			return item
+		}
+	}
+
+	subID, err := s.subscriptions.Subscribe(ctx, spec.AgentID, spec.AgentType, spec.EventPattern, subscriptionFilters(spec))
+	if err != nil {
+		item.Action = ActionFailed
+		item.Message = err.Error()
+		return item
+	}
+
+	item.SubscriptionID = subID
+	if existing != nil {
+		item.Action = ActionUpdated
+	} else {
+		item.Action = ActionCreated
+	}
+	return item
+}
+
+// topicHasSubscriber reports whether any declared subscription matches the topic
+func (s *Service) topicHasSubscriber(topic string, specs []SubscriptionSpec) bool {
+	for _, spec := range specs {
+		if s.matcher.MatchesPattern(topic, spec.EventPattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the manifest for missing fields and duplicate declarations
+func (s *Service) validate(manifest *Manifest) error {
+	if manifest == nil {
+		return fmt.Errorf("%w: manifest is required", ErrInvalidManifest)
+	}
+
+	agentIDs := make(map[string]bool, len(manifest.Agents))
+	for i, spec := range manifest.Agents {
+		if spec.ID == "" {
+			return fmt.Errorf("%w: agents[%d].id is required", ErrInvalidManifest, i)
+		}
+		if spec.Type == "" {
+			return fmt.Errorf("%w: agents[%d].type is required", ErrInvalidManifest, i)
+		}
+		if agentIDs[spec.ID] {
+			return fmt.Errorf("%w: duplicate agent id %q", ErrInvalidManifest, spec.ID)
+		}
+		agentIDs[spec.ID] = true
+	}
+
+	topics := make(map[string]bool, len(manifest.Topics))
+	for i, topic := range manifest.Topics {
+		if strings.TrimSpace(topic) == "" {
+			return fmt.Errorf("%w: topics[%d] is empty", ErrInvalidManifest, i)
+		}
+		if err := communication.ValidateTopicName(topic); err != nil {
+			return fmt.Errorf("%w: topics[%d]: %v", ErrInvalidManifest, i, err)
+		}
+		if topics[topic] {
+			return fmt.Errorf("%w: duplicate topic %q", ErrInvalidManifest, topic)
+		}
+		topics[topic] = true
+	}
+
+	subscriptions := make(map[string]bool, len(manifest.Subscriptions))
+	for i, spec := range manifest.Subscriptions {
+		if spec.AgentID == "" {
+			return fmt.Errorf("%w: subscriptions[%d].agent_id is required", ErrInvalidManifest, i)
+		}
+		if spec.EventPattern == "" {
+			return fmt.Errorf("%w: subscriptions[%d].event_pattern is required", ErrInvalidManifest, i)
+		}
+		if err := communication.ValidateTopicPattern(spec.EventPattern); err != nil {
+			return fmt.Errorf("%w: subscriptions[%d].event_pattern: %v", ErrInvalidManifest, i, err)
+		}
+		key := subscriptionKey(spec)
+		if subscriptions[key] {
+			return fmt.Errorf("%w: duplicate subscription %q", ErrInvalidManifest, key)
+		}
+		subscriptions[key] = true
+	}
+
+	return nil
+}
+
+// subscriptionKey identifies a declared subscription by subscriber and pattern
+func subscriptionKey(spec SubscriptionSpec) string {
+	return spec.AgentID + ":" + spec.EventPattern
+}
+
+// subscriptionFilters converts a subscription spec into service filters
+func subscriptionFilters(spec SubscriptionSpec) *communication.SubscriptionFilters {
+	filters := &communication.SubscriptionFilters{}
+	if spec.PublisherAgentID != "" {
+		publisherID := spec.PublisherAgentID
+		filters.PublisherID = &publisherID
+	}
+	if spec.PublisherAgentType != "" {
+		publisherType := spec.PublisherAgentType
+		filters.PublisherType = &publisherType
+	}
+	for _, t := range spec.PublicationTypes {
+		filters.Types = append(filters.Types, communication.PublicationType(t))
+	}
+	return filters
+}
+
+// subscriptionChanges lists filter fields that differ between an active subscription and its spec
+func subscriptionChanges(sub *communication.Subscription, spec SubscriptionSpec) []string {
+	var changes []string
+
+	if derefString(sub.PublisherAgentID) != spec.PublisherAgentID {
+		changes = append(changes, "publisher_agent_id")
+	}
+	if derefString(sub.PublisherAgentType) != spec.PublisherAgentType {
+		changes = append(changes, "publisher_agent_type")
+	}
+
+	current := make([]string, 0, len(sub.PublicationTypes))
+	for _, t := range sub.PublicationTypes {
+		current = append(current, string(t))
+	}
+	declared := append([]string(nil), spec.PublicationTypes...)
+	sort.Strings(current)
+	sort.Strings(declared)
+	if len(current) != len(declared) || (len(current) > 0 && !reflect.DeepEqual(current, declared)) {
+		changes = append(changes, "publication_types")
+	}
+
+	return changes
+}
+
+// derefString returns the pointed-to string or empty when nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,206 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAgentStore is an in-memory AgentStore for testing
+type mockAgentStore struct {
+	agents map[string]*agent.Agent
+}
+
+func newMockAgentStore() *mockAgentStore {
+	return &mockAgentStore{agents: make(map[string]*agent.Agent)}
+}
+
+func (m *mockAgentStore) GetAgent(agentID string) (*agent.Agent, error) {
+	a, ok := m.agents[agentID]
+	if !ok {
+		return nil, agent.ErrAgentNotFound
+	}
+	return a, nil
+}
+
+func (m *mockAgentStore) RegisterAgent(a *agent.Agent) error {
+	if _, ok := m.agents[a.ID]; ok {
+		return fmt.Errorf("agent already exists: %s", a.ID)
+	}
+	m.agents[a.ID] = a
+	return nil
+}
+
+func (m *mockAgentStore) UpdateAgent(a *agent.Agent) error {
+	m.agents[a.ID] = a
+	return nil
+}
+
+// mockSubscriptionStore is an in-memory SubscriptionStore for testing
+type mockSubscriptionStore struct {
+	subs   map[string]*communication.Subscription
+	nextID int
+}
+
+func newMockSubscriptionStore() *mockSubscriptionStore {
+	return &mockSubscriptionStore{subs: make(map[string]*communication.Subscription)}
+}
+
+func (m *mockSubscriptionStore) GetActiveSubscriptions(ctx context.Context, agentID string) ([]*communication.Subscription, error) {
+	var result []*communication.Subscription
+	for _, sub := range m.subs {
+		if sub.SubscriberAgentID == agentID && sub.Active {
+			result = append(result, sub)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockSubscriptionStore) Subscribe(ctx context.Context, subscriberAgentID, subscriberAgentType, eventPattern string, filters *communication.SubscriptionFilters) (string, error) {
+	m.nextID++
+	sub := &communication.Subscription{
+		ID:                  fmt.Sprintf("sub-%d", m.nextID),
+		SubscriberAgentID:   subscriberAgentID,
+		SubscriberAgentType: subscriberAgentType,
+		EventPattern:        eventPattern,
+		Active:              true,
+	}
+	if filters != nil {
+		sub.PublisherAgentID = filters.PublisherID
+		sub.PublisherAgentType = filters.PublisherType
+		sub.PublicationTypes = filters.Types
+	}
+	m.subs[sub.ID] = sub
+	return sub.ID, nil
+}
+
+func (m *mockSubscriptionStore) Unsubscribe(ctx context.Context, subscriptionID string) error {
+	sub, ok := m.subs[subscriptionID]
+	if !ok {
+		return fmt.Errorf("subscription not found: %s", subscriptionID)
+	}
+	sub.Active = false
+	return nil
+}
+
+func newTestService() (*Service, *mockAgentStore, *mockSubscriptionStore) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	agents := newMockAgentStore()
+	subs := newMockSubscriptionStore()
+	return NewService(agents, subs, logger), agents, subs
+}
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Agents: []AgentSpec{
+			{ID: "PUMP-001", Name: "Pump 1", Type: "pump", Metadata: map[string]string{"zone": "north"}},
+			{ID: "COORD-NORTH", Type: "zone_coordinator"},
+		},
+		Topics: []string{"zone.north.pump.efficiency"},
+		Subscriptions: []SubscriptionSpec{
+			{AgentID: "COORD-NORTH", AgentType: "zone_coordinator", EventPattern: "zone.north.pump.*"},
+		},
+	}
+}
+
+func TestApply_CreatesMissingResources(t *testing.T) {
+	svc, agents, subs := newTestService()
+
+	result, err := svc.Apply(context.Background(), testManifest())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, result.Summary.Created)
+	assert.Equal(t, 1, result.Summary.Noop)
+	assert.Equal(t, ActionCreated, result.Agents[0].Action)
+	assert.Equal(t, "PUMP-001", agents.agents["PUMP-001"].ID)
+	assert.Equal(t, "north", agents.agents["PUMP-001"].Metadata["zone"])
+	assert.Equal(t, "COORD-NORTH", agents.agents["COORD-NORTH"].Name)
+	assert.Len(t, subs.subs, 1)
+	assert.Empty(t, result.Topics[0].Message)
+}
+
+func TestApply_IsIdempotent(t *testing.T) {
+	svc, _, subs := newTestService()
+
+	_, err := svc.Apply(context.Background(), testManifest())
+	require.NoError(t, err)
+
+	result, err := svc.Apply(context.Background(), testManifest())
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, result.Summary.Created)
+	assert.Equal(t, 0, result.Summary.Updated)
+	assert.Equal(t, 3, result.Summary.Unchanged)
+	assert.Len(t, subs.subs, 1)
+}
+
+func TestApply_UpdatesChangedResources(t *testing.T) {
+	svc, agents, subs := newTestService()
+
+	_, err := svc.Apply(context.Background(), testManifest())
+	require.NoError(t, err)
+	registered := agents.agents["PUMP-001"]
+
+	manifest := testManifest()
+	manifest.Agents[0].Metadata["zone"] = "south"
+	manifest.Subscriptions[0].PublicationTypes = []string{"metric"}
+
+	result, err := svc.Apply(context.Background(), manifest)
+	require.NoError(t, err)
+
+	assert.Equal(t, ActionUpdated, result.Agents[0].Action)
+	assert.Equal(t, []string{"metadata"}, result.Agents[0].Changes)
+	assert.Equal(t, "south", agents.agents["PUMP-001"].Metadata["zone"])
+	assert.Equal(t, "north", registered.Metadata["zone"], "the registered agent is not changed in place")
+	assert.Equal(t, ActionUnchanged, result.Agents[1].Action)
+
+	assert.Equal(t, ActionUpdated, result.Subscriptions[0].Action)
+	assert.Equal(t, []string{"publication_types"}, result.Subscriptions[0].Changes)
+
+	active, _ := subs.GetActiveSubscriptions(context.Background(), "COORD-NORTH")
+	require.Len(t, active, 1)
+	assert.Equal(t, result.Subscriptions[0].SubscriptionID, active[0].ID)
+}
+
+func TestApply_RejectsInvalidManifest(t *testing.T) {
+	svc, _, _ := newTestService()
+
+	tests := []struct {
+		name     string
+		manifest *Manifest
+	}{
+		{"nil manifest", nil},
+		{"missing agent id", &Manifest{Agents: []AgentSpec{{Type: "pump"}}}},
+		{"duplicate agent", &Manifest{Agents: []AgentSpec{{ID: "A", Type: "pump"}, {ID: "A", Type: "pump"}}}},
+		{"wildcard topic", &Manifest{Topics: []string{"zone.*"}}},
+		{"multi-segment wildcard topic", &Manifest{Topics: []string{"zone.#"}}},
+		{"missing pattern", &Manifest{Subscriptions: []SubscriptionSpec{{AgentID: "A"}}}},
+		{"invalid pattern", &Manifest{Subscriptions: []SubscriptionSpec{{AgentID: "A", EventPattern: "zone.[north"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Apply(context.Background(), tt.manifest)
+			assert.ErrorIs(t, err, ErrInvalidManifest)
+		})
+	}
+}
+
+func TestApply_SubscriptionsWithoutPubSub(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	svc := NewService(newMockAgentStore(), nil, logger)
+
+	result, err := svc.Apply(context.Background(), testManifest())
+	require.NoError(t, err)
+
+	assert.Equal(t, ActionFailed, result.Subscriptions[0].Action)
+	assert.Equal(t, 1, result.Summary.Failed)
+}
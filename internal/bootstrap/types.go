@@ -0,0 +1,98 @@
+package bootstrap
+
+// Action describes what the bootstrap did with a declared resource
+type Action string
+
+const (
+	// ActionCreated indicates the resource did not exist and was created
+	ActionCreated Action = "created"
+	// ActionUpdated indicates the resource existed but differed and was updated
+	ActionUpdated Action = "updated"
+	// ActionUnchanged indicates the resource already matched the declaration
+	ActionUnchanged Action = "unchanged"
+	// ActionNoop indicates there was nothing to provision for the resource
+	ActionNoop Action = "noop"
+	// ActionFailed indicates the resource could not be provisioned
+	ActionFailed Action = "failed"
+)
+
+// Manifest is a declarative description of the environment a scenario expects
+type Manifest struct {
+	// Agents are agent instances that must exist with the given IDs
+	Agents []AgentSpec `json:"agents"`
+
+	// Topics are the event names the scenario publishes on
+	Topics []string `json:"topics"`
+
+	// Subscriptions are pub/sub subscriptions that must be active
+	Subscriptions []SubscriptionSpec `json:"subscriptions"`
+}
+
+// AgentSpec declares a single agent instance
+type AgentSpec struct {
+	ID       string            `json:"id" binding:"required"`
+	Name     string            `json:"name"`
+	Type     string            `json:"type" binding:"required"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// SubscriptionSpec declares a single pub/sub subscription
+type SubscriptionSpec struct {
+	AgentID            string   `json:"agent_id" binding:"required"`
+	AgentType          string   `json:"agent_type"`
+	EventPattern       string   `json:"event_pattern" binding:"required"`
+	PublisherAgentID   string   `json:"publisher_agent_id,omitempty"`
+	PublisherAgentType string   `json:"publisher_agent_type,omitempty"`
+	PublicationTypes   []string `json:"publication_types,omitempty"`
+}
+
+// ItemResult reports the outcome for one declared resource
+type ItemResult struct {
+	// ID identifies the resource (agent ID, topic name, or subscription key)
+	ID string `json:"id"`
+
+	// Action is what the bootstrap did
+	Action Action `json:"action"`
+
+	// Changes lists the fields that differed for updated resources
+	Changes []string `json:"changes,omitempty"`
+
+	// SubscriptionID is the active subscription ID (subscriptions only)
+	SubscriptionID string `json:"subscription_id,omitempty"`
+
+	// Message carries additional detail, including failure reasons
+	Message string `json:"message,omitempty"`
+}
+
+// Summary counts results by action
+type Summary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Noop      int `json:"noop"`
+	Failed    int `json:"failed"`
+}
+
+// Result is the outcome of applying a manifest
+type Result struct {
+	Agents        []ItemResult `json:"agents"`
+	Topics        []ItemResult `json:"topics"`
+	Subscriptions []ItemResult `json:"subscriptions"`
+	Summary       Summary      `json:"summary"`
+}
+
+// add records an item result and updates the summary
+func (s *Summary) add(action Action) {
+	switch action {
+	case ActionCreated:
+		s.Created++
+	case ActionUpdated:
+		s.Updated++
+	case ActionUnchanged:
+		s.Unchanged++
+	case ActionNoop:
+		s.Noop++
+	case ActionFailed:
+		s.Failed++
+	}
+}
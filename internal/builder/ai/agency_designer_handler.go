@@ -2,7 +2,9 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +34,13 @@ func (h *AgencyDesignerHandler) RegisterRoutes(router *gin.RouterGroup) {
 		designer.GET("/conversations/:conversationId", h.GetConversation)
 		designer.POST("/conversations/:conversationId/generate", h.GenerateDesign)
 	}
+
+	conversations := router.Group("/agencies/:id/conversations")
+	{
+		conversations.GET("", h.ListConversations)
+		conversations.POST("", h.CreateConversation)
+		conversations.GET("/:conversationId/messages", h.ListMessages)
+	}
 }
 
 // StartConversation handles POST /agencies/:id/designer/conversations
@@ -117,3 +126,80 @@ func (h *AgencyDesignerHandler) GenerateDesign(c *gin.Context) {
 
 	c.JSON(200, design)
 }
+
+// ListConversations handles GET /agencies/:id/conversations
+func (h *AgencyDesignerHandler) ListConversations(c *gin.Context) {
+	agencyID := c.Param("id")
+
+	conversations, err := h.service.ListConversations(c.Request.Context(), agencyID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list conversations")
+		c.JSON(500, gin.H{"error": "Failed to list conversations"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"agency_id":     agencyID,
+		"conversations": conversations,
+	})
+}
+
+// CreateConversation handles POST /agencies/:id/conversations
+func (h *AgencyDesignerHandler) CreateConversation(c *gin.Context) {
+	agencyID := c.Param("id")
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
+
+	conversation, err := h.service.StartNamedConversation(c.Request.Context(), agencyID, req.Name)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to start conversation")
+		c.JSON(500, gin.H{"error": "Failed to start conversation"})
+		return
+	}
+
+	c.JSON(201, summarizeConversation(conversation))
+}
+
+// ListMessages handles GET /agencies/:id/conversations/:conversationId/messages.
+// It returns the latest messages, or those before the message ID given by
+// "before", oldest first.
+func (h *AgencyDesignerHandler) ListMessages(c *gin.Context) {
+	agencyID := c.Param("id")
+	conversationID := c.Param("conversationId")
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(400, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	conversation, err := h.service.GetConversation(conversationID)
+	if err != nil || conversation.AgencyID != agencyID {
+		c.JSON(404, gin.H{"error": "Conversation not found"})
+		return
+	}
+
+	page, err := h.service.ListMessages(c.Request.Context(), conversationID, c.Query("before"), limit)
+	if err != nil {
+		if errors.Is(err, ErrConversationNotFound) {
+			c.JSON(404, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, page)
+}
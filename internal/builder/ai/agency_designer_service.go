@@ -4,19 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/builder/markdown"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
 // AgencyDesignerService manages AI-powered agency design conversations
 type AgencyDesignerService struct {
-	llmClient     LLMClient
-	logger        *logrus.Logger
-	conversations map[string]*ConversationContext // In-memory for MVP, should be persistent
+	llmClient LLMClient
+	logger    *logrus.Logger
+	observers []MessageObserver
+
+	// conversations caches conversations in memory; with a store they are
+	// also persisted and loaded from it on first use
+	mu            sync.RWMutex
+	conversations map[string]*ConversationContext
+	store         ConversationStore
 }
 
+// MessageObserver is called after a message is added to a conversation
+type MessageObserver func(conversation *ConversationContext, message Message)
+
 // NewAgencyDesignerService creates a new agency designer service
 func NewAgencyDesignerService(llmClient LLMClient, logger *logrus.Logger) *AgencyDesignerService {
 	return &AgencyDesignerService{
@@ -26,13 +37,33 @@ func NewAgencyDesignerService(llmClient LLMClient, logger *logrus.Logger) *Agenc
 	}
 }
 
+// AddMessageObserver registers a function called for every message added to
+// a conversation, such as to notify open designer sessions
+func (s *AgencyDesignerService) AddMessageObserver(observer MessageObserver) {
+	s.observers = append(s.observers, observer)
+}
+
+// notifyMessage passes an added message to the observers
+func (s *AgencyDesignerService) notifyMessage(conversation *ConversationContext, message Message) {
+	for _, observer := range s.observers {
+		observer(conversation, message)
+	}
+}
+
 // StartConversation begins a new agency design conversation
 func (s *AgencyDesignerService) StartConversation(ctx context.Context, agencyID string) (*ConversationContext, error) {
+	return s.StartNamedConversation(ctx, agencyID, "")
+}
+
+// StartNamedConversation begins a new agency design conversation with a name
+// that tells it apart from the agency's other conversations
+func (s *AgencyDesignerService) StartNamedConversation(ctx context.Context, agencyID, name string) (*ConversationContext, error) {
 	conversationID := uuid.New().String()
 
 	conversation := &ConversationContext{
 		ID:        conversationID,
 		AgencyID:  agencyID,
+		Name:      name,
 		Phase:     PhaseInitial,
 		Messages:  []Message{},
 		State:     make(map[string]interface{}),
@@ -40,18 +71,20 @@ func (s *AgencyDesignerService) StartConversation(ctx context.Context, agencyID
 		UpdatedAt: time.Now(),
 	}
 
-	// Add initial system message
-	systemPrompt := s.getSystemPrompt(PhaseInitial)
-	conversation.Messages = append(conversation.Messages, Message{
-		Role:      "system",
-		Content:   systemPrompt,
-		Timestamp: time.Now(),
-	})
-
 	// Note: We don't add an initial AI greeting here because the UI shows a welcome message
 	// when there are no conversation messages
 
+	s.mu.Lock()
 	s.conversations[conversationID] = conversation
+	s.mu.Unlock()
+	s.saveConversation(ctx, conversation)
+
+	// Add initial system message
+	s.appendMessage(ctx, conversation, Message{
+		Role:      "system",
+		Content:   s.getSystemPrompt(PhaseInitial),
+		Timestamp: time.Now(),
+	})
 
 	s.logger.WithFields(logrus.Fields{
 		"conversation_id": conversationID,
@@ -63,20 +96,17 @@ func (s *AgencyDesignerService) StartConversation(ctx context.Context, agencyID
 
 // SendMessage sends a user message and gets AI response
 func (s *AgencyDesignerService) SendMessage(ctx context.Context, conversationID, userMessage string) (*Message, error) {
-	conversation, exists := s.conversations[conversationID]
+	conversation, exists := s.lookup(ctx, conversationID)
 	if !exists {
 		return nil, fmt.Errorf("conversation not found: %s", conversationID)
 	}
 
 	// Add user message
-	conversation.Messages = append(conversation.Messages, Message{
-		Role:      "user",
-		Content:   userMessage,
-		Timestamp: time.Now(),
-	})
+	userMsg := s.appendMessage(ctx, conversation, newChatMessage("user", userMessage))
+	s.notifyMessage(conversation, userMsg)
 
 	// Get AI response
-	response, err := s.llmClient.Chat(ctx, &ChatRequest{
+	response, err := s.llmClient.Chat(WithOperation(ctx, "designer.send_message"), &ChatRequest{
 		Messages:    conversation.Messages,
 		Temperature: 0.7,
 		MaxTokens:   2048,
@@ -86,17 +116,13 @@ func (s *AgencyDesignerService) SendMessage(ctx context.Context, conversationID,
 	}
 
 	// Add assistant response
-	assistantMsg := Message{
-		Role:      "assistant",
-		Content:   response.Content,
-		Timestamp: time.Now(),
-	}
-	conversation.Messages = append(conversation.Messages, assistantMsg)
-	conversation.UpdatedAt = time.Now()
+	assistantMsg := s.appendMessage(ctx, conversation, newChatMessage("assistant", response.Content))
+	s.notifyMessage(conversation, assistantMsg)
 
 	// Extract information and update phase
 	s.extractInformation(conversation, userMessage, response.Content)
 	s.updatePhase(conversation)
+	s.saveConversation(ctx, conversation)
 
 	s.logger.WithFields(logrus.Fields{
 		"conversation_id": conversationID,
@@ -109,7 +135,7 @@ func (s *AgencyDesignerService) SendMessage(ctx context.Context, conversationID,
 
 // GetConversation retrieves a conversation by ID
 func (s *AgencyDesignerService) GetConversation(conversationID string) (*ConversationContext, error) {
-	conversation, exists := s.conversations[conversationID]
+	conversation, exists := s.lookup(context.Background(), conversationID)
 	if !exists {
 		return nil, fmt.Errorf("conversation not found: %s", conversationID)
 	}
@@ -118,25 +144,59 @@ func (s *AgencyDesignerService) GetConversation(conversationID string) (*Convers
 
 // AddMessage adds a message to an existing conversation without AI processing
 func (s *AgencyDesignerService) AddMessage(conversationID string, role string, content string) error {
-	conversation, exists := s.conversations[conversationID]
+	ctx := context.Background()
+	conversation, exists := s.lookup(ctx, conversationID)
 	if !exists {
 		return fmt.Errorf("conversation not found: %s", conversationID)
 	}
 
-	conversation.Messages = append(conversation.Messages, Message{
-		Role:      role,
-		Content:   content,
-		Timestamp: time.Now(),
-	})
-	conversation.UpdatedAt = time.Now()
+	msg := s.appendMessage(ctx, conversation, newChatMessage(role, content))
+	s.notifyMessage(conversation, msg)
+
+	return nil
+}
+
+// AddMessageOnce adds a message identified by messageID unless the
+// conversation already has it, so a redelivered outbox entry is ignored
+func (s *AgencyDesignerService) AddMessageOnce(conversationID, messageID, role, content string) error {
+	ctx := context.Background()
+	conversation, exists := s.lookup(ctx, conversationID)
+	if !exists {
+		return fmt.Errorf("conversation not found: %s", conversationID)
+	}
+
+	for _, msg := range conversation.Messages {
+		if msg.ID == messageID {
+			return nil
+		}
+	}
+
+	msg := newChatMessage(role, content)
+	msg.ID = messageID
+	msg = s.appendMessage(ctx, conversation, msg)
+	s.notifyMessage(conversation, msg)
 
 	return nil
 }
 
+// newChatMessage creates a chat message with normalized content. The raw
+// markdown is kept in Content and the sanitized HTML in RenderedHTML.
+func newChatMessage(role, content string) Message {
+	content = markdown.NormalizeText(content)
+	return Message{
+		Role:         role,
+		Content:      content,
+		RenderedHTML: markdown.Render(content),
+		Timestamp:    time.Now(),
+	}
+}
+
 // GetConversationByAgencyID finds the most recent conversation for an agency
 func (s *AgencyDesignerService) GetConversationByAgencyID(agencyID string) (*ConversationContext, error) {
+	ctx := context.Background()
 	var latestConversation *ConversationContext
 
+	s.mu.RLock()
 	for _, conversation := range s.conversations {
 		if conversation.AgencyID == agencyID {
 			if latestConversation == nil || conversation.UpdatedAt.After(latestConversation.UpdatedAt) {
@@ -144,6 +204,19 @@ func (s *AgencyDesignerService) GetConversationByAgencyID(agencyID string) (*Con
 			}
 		}
 	}
+	s.mu.RUnlock()
+
+	// Conversations from before a restart are only in the store
+	if s.store != nil {
+		summaries, err := s.store.ListConversations(ctx, agencyID)
+		if err != nil {
+			s.logger.WithError(err).WithField("agency_id", agencyID).Warn("Failed to list stored conversations")
+		} else if len(summaries) > 0 && (latestConversation == nil || summaries[0].UpdatedAt.After(latestConversation.UpdatedAt)) {
+			if stored, ok := s.lookup(ctx, summaries[0].ID); ok {
+				latestConversation = stored
+			}
+		}
+	}
 
 	if latestConversation == nil {
 		return nil, fmt.Errorf("no conversation found for agency: %s", agencyID)
@@ -154,7 +227,7 @@ func (s *AgencyDesignerService) GetConversationByAgencyID(agencyID string) (*Con
 
 // GenerateAgencyDesign creates the final agency design from conversation
 func (s *AgencyDesignerService) GenerateAgencyDesign(ctx context.Context, conversationID string) (*AgencyDesign, error) {
-	conversation, exists := s.conversations[conversationID]
+	conversation, exists := s.lookup(ctx, conversationID)
 	if !exists {
 		return nil, fmt.Errorf("conversation not found: %s", conversationID)
 	}
@@ -171,7 +244,7 @@ func (s *AgencyDesignerService) GenerateAgencyDesign(ctx context.Context, conver
 		Content: designPrompt,
 	})
 
-	response, err := s.llmClient.Chat(ctx, &ChatRequest{
+	response, err := s.llmClient.Chat(WithOperation(ctx, "designer.generate_design"), &ChatRequest{
 		Messages:    messages,
 		Temperature: 0.3, // Lower temperature for structured output
 		MaxTokens:   4096,
@@ -182,6 +255,7 @@ func (s *AgencyDesignerService) GenerateAgencyDesign(ctx context.Context, conver
 
 	// Parse the JSON response
 	design, err := s.parseAgencyDesign(response.Content)
+	response.RecordParseOutcome(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse design: %w", err)
 	}
@@ -220,7 +294,8 @@ func (s *AgencyDesignerService) extractInformation(conversation *ConversationCon
 	if state["agent_types"] == nil {
 		state["agent_types"] = []string{}
 	}
-	agentTypes := state["agent_types"].([]string)
+	// Conversations loaded from the store decode the list as []interface{}
+	agentTypes, _ := state["agent_types"].([]string)
 
 	// TODO: Implement agent type extraction from AI response
 	// For now, agent types will be managed separately
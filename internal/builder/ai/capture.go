@@ -0,0 +1,384 @@
+package ai
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Parse outcomes of a captured LLM call
+const (
+	ParseUnreported = "unreported" // The caller did not report whether it could use the response
+	ParseOK         = "ok"
+	ParseFailed     = "failed"
+)
+
+// redacted replaces secrets in captured text
+const redacted = "[REDACTED]"
+
+// defaultSecretPatterns match credentials commonly found in prompts and responses
+var defaultSecretPatterns = []string{
+	`sk-(?:ant-)?[A-Za-z0-9_\-]{16,}`,                                      // OpenAI and Anthropic keys
+	`AKIA[0-9A-Z]{16}`,                                                     // AWS access key IDs
+	`(?i)bearer\s+[A-Za-z0-9\-._~+/]{16,}=*`,                               // Bearer tokens
+	`(?i)("?(?:api[_-]?key|secret|password|token)"?\s*[:=]\s*"?)[^\s",}]+`, // key=value and JSON fields
+}
+
+type captureOperationKey struct{}
+type captureRequestIDKey struct{}
+
+// WithOperation names the AI operation that LLM calls made with ctx belong to
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, captureOperationKey{}, operation)
+}
+
+// WithRequestID sets the request ID that LLM calls made with ctx are captured under
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, captureRequestIDKey{}, requestID)
+}
+
+// OperationFromContext returns the AI operation set by WithOperation, if any
+func OperationFromContext(ctx context.Context) string {
+	operation, _ := ctx.Value(captureOperationKey{}).(string)
+	return operation
+}
+
+// RequestIDFromContext returns the request ID set by WithRequestID, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(captureRequestIDKey{}).(string)
+	return requestID
+}
+
+// RequestIDMiddleware assigns each HTTP request an X-Request-ID, or keeps the
+// one supplied by the client, so captured LLM calls can be found by request
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// LLMCapture is the record of a single LLM call
+type LLMCapture struct {
+	ID          string                 `json:"id"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	Operation   string                 `json:"operation,omitempty"`
+	Provider    Provider               `json:"provider"`
+	Model       string                 `json:"model"`
+	Temperature float32                `json:"temperature,omitempty"`
+	MaxTokens   int                    `json:"max_tokens,omitempty"`
+	Stream      bool                   `json:"stream"`
+	Messages    []Message              `json:"messages"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	Response     string      `json:"response"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Usage        *TokenUsage `json:"usage,omitempty"`
+	Error        string      `json:"error,omitempty"`
+
+	ParseOutcome string `json:"parse_outcome"`
+	ParseError   string `json:"parse_error,omitempty"`
+
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// CaptureConfig configures LLM call capture
+type CaptureConfig struct {
+	MaxEntries     int           // Oldest captures are dropped beyond this count
+	MaxAge         time.Duration // Captures older than this are dropped
+	Secrets        []string      // Literal values to redact, such as the provider API key
+	SecretPatterns []string      // Additional regular expressions to redact
+}
+
+// CaptureFilter selects captures to list
+type CaptureFilter struct {
+	RequestID string
+	Operation string
+	Limit     int
+}
+
+// CaptureStore keeps recent LLM captures in memory with secrets redacted
+type CaptureStore struct {
+	mu       sync.RWMutex
+	captures []*LLMCapture
+	byID     map[string]*LLMCapture
+
+	maxEntries int
+	maxAge     time.Duration
+	secrets    []string
+	patterns   []*regexp.Regexp
+}
+
+// NewCaptureStore creates a capture store. It fails if a secret pattern does
+// not compile.
+func NewCaptureStore(config CaptureConfig) (*CaptureStore, error) {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 500
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 24 * time.Hour
+	}
+
+	store := &CaptureStore{
+		byID:       make(map[string]*LLMCapture),
+		maxEntries: config.MaxEntries,
+		maxAge:     config.MaxAge,
+	}
+	for _, secret := range config.Secrets {
+		if secret != "" {
+			store.secrets = append(store.secrets, secret)
+		}
+	}
+	for _, pattern := range append(append([]string{}, defaultSecretPatterns...), config.SecretPatterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		store.patterns = append(store.patterns, re)
+	}
+
+	return store, nil
+}
+
+// Get returns the capture with the given ID
+func (s *CaptureStore) Get(id string) (*LLMCapture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+
+	capture, ok := s.byID[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *capture
+	return &copied, true
+}
+
+// List returns the captures matching the filter, newest first
+func (s *CaptureStore) List(filter CaptureFilter) []*LLMCapture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire(time.Now())
+
+	captures := make([]*LLMCapture, 0)
+	for i := len(s.captures) - 1; i >= 0; i-- {
+		capture := s.captures[i]
+		if filter.RequestID != "" && capture.RequestID != filter.RequestID {
+			continue
+		}
+		if filter.Operation != "" && capture.Operation != filter.Operation {
+			continue
+		}
+		copied := *capture
+		captures = append(captures, &copied)
+		if filter.Limit > 0 && len(captures) == filter.Limit {
+			break
+		}
+	}
+	return captures
+}
+
+// Operations returns the names of the operations with captures
+func (s *CaptureStore) Operations() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	operations := make([]string, 0)
+	for _, capture := range s.captures {
+		if capture.Operation != "" && !seen[capture.Operation] {
+			seen[capture.Operation] = true
+			operations = append(operations, capture.Operation)
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// add redacts and stores a capture, dropping captures beyond the retention limits
+func (s *CaptureStore) add(capture *LLMCapture) {
+	capture.Messages = s.redactMessages(capture.Messages)
+	capture.Response = s.redact(capture.Response)
+	capture.Error = s.redact(capture.Error)
+	for key, value := range capture.Metadata {
+		if text, ok := value.(string); ok {
+			capture.Metadata[key] = s.redact(text)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.captures = append(s.captures, capture)
+	s.byID[capture.ID] = capture
+	s.expire(time.Now())
+}
+
+// recordParse records whether the caller could parse a captured response
+func (s *CaptureStore) recordParse(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capture, ok := s.byID[id]
+	if !ok {
+		return
+	}
+	if err != nil {
+		capture.ParseOutcome = ParseFailed
+		capture.ParseError = s.redact(err.Error())
+		return
+	}
+	capture.ParseOutcome = ParseOK
+	capture.ParseError = ""
+}
+
+// expire drops captures beyond the retention limits. Callers must hold s.mu.
+func (s *CaptureStore) expire(now time.Time) {
+	cutoff := now.Add(-s.maxAge)
+	drop := 0
+	for drop < len(s.captures) && (len(s.captures)-drop > s.maxEntries || s.captures[drop].StartedAt.Before(cutoff)) {
+		delete(s.byID, s.captures[drop].ID)
+		drop++
+	}
+	if drop > 0 {
+		s.captures = append([]*LLMCapture(nil), s.captures[drop:]...)
+	}
+}
+
+func (s *CaptureStore) redactMessages(messages []Message) []Message {
+	redactedMessages := make([]Message, len(messages))
+	for i, msg := range messages {
+		redactedMessages[i] = msg
+		redactedMessages[i].Content = s.redact(msg.Content)
+	}
+	return redactedMessages
+}
+
+// redact replaces configured secrets and anything matching a secret pattern.
+// Patterns with a capture group keep the group, such as the name of a key.
+func (s *CaptureStore) redact(text string) string {
+	for _, secret := range s.secrets {
+		text = strings.ReplaceAll(text, secret, redacted)
+	}
+	for _, re := range s.patterns {
+		if re.NumSubexp() > 0 {
+			text = re.ReplaceAllString(text, "${1}"+redacted)
+		} else {
+			text = re.ReplaceAllString(text, redacted)
+		}
+	}
+	return text
+}
+
+// CapturingLLMClient records every call made through an LLM client
+type CapturingLLMClient struct {
+	LLMClient
+	store *CaptureStore
+}
+
+// NewCapturingLLMClient wraps an LLM client so its calls are captured in the store
+func NewCapturingLLMClient(client LLMClient, store *CaptureStore) *CapturingLLMClient {
+	return &CapturingLLMClient{
+		LLMClient: client,
+		store:     store,
+	}
+}
+
+// Chat sends messages and captures the prompt, parameters and raw response.
+// The response carries the capture ID so the caller can report its parse outcome.
+func (c *CapturingLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	capture := c.newCapture(ctx, req)
+
+	resp, err := c.LLMClient.Chat(ctx, req)
+	capture.DurationMs = time.Since(capture.StartedAt).Milliseconds()
+	if err != nil {
+		capture.Error = err.Error()
+	}
+	if resp != nil {
+		capture.Response = resp.Content
+		capture.FinishReason = resp.FinishReason
+		capture.Usage = resp.Usage
+		if resp.Model != "" {
+			capture.Model = resp.Model
+		}
+		resp.CaptureID = capture.ID
+		resp.captures = c.store
+	}
+
+	c.store.add(capture)
+	return resp, err
+}
+
+// ChatStream streams a response and captures the prompt and the streamed text
+func (c *CapturingLLMClient) ChatStream(ctx context.Context, req *ChatRequest, callback StreamCallback) error {
+	capture := c.newCapture(ctx, req)
+	capture.Stream = true
+
+	var response strings.Builder
+	err := c.LLMClient.ChatStream(ctx, req, func(chunk string) error {
+		response.WriteString(chunk)
+		return callback(chunk)
+	})
+
+	capture.DurationMs = time.Since(capture.StartedAt).Milliseconds()
+	capture.Response = response.String()
+	if err != nil {
+		capture.Error = err.Error()
+	}
+
+	c.store.add(capture)
+	return err
+}
+
+func (c *CapturingLLMClient) newCapture(ctx context.Context, req *ChatRequest) *LLMCapture {
+	capture := &LLMCapture{
+		ID:           uuid.New().String(),
+		Provider:     c.GetProvider(),
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		MaxTokens:    req.MaxTokens,
+		Stream:       req.Stream,
+		Messages:     req.Messages,
+		ParseOutcome: ParseUnreported,
+		StartedAt:    time.Now(),
+	}
+	if capture.Model == "" {
+		capture.Model = c.GetModel()
+	}
+	if requestID, ok := ctx.Value(captureRequestIDKey{}).(string); ok {
+		capture.RequestID = requestID
+	}
+	if operation, ok := ctx.Value(captureOperationKey{}).(string); ok {
+		capture.Operation = operation
+	}
+	if len(req.Metadata) > 0 {
+		capture.Metadata = make(map[string]interface{}, len(req.Metadata))
+		for key, value := range req.Metadata {
+			capture.Metadata[key] = value
+		}
+	}
+	return capture
+}
+
+// RecordParseOutcome reports whether the response could be parsed. It does
+// nothing unless the response was captured.
+func (r *ChatResponse) RecordParseOutcome(err error) {
+	if r == nil || r.captures == nil {
+		return
+	}
+	r.captures.recordParse(r.CaptureID, err)
+}
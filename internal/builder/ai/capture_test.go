@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubLLMClient struct {
+	content string
+}
+
+func (s *stubLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return &ChatResponse{Content: s.content, FinishReason: "stop", Usage: &TokenUsage{TotalTokens: 42}}, nil
+}
+
+func (s *stubLLMClient) ChatStream(ctx context.Context, req *ChatRequest, callback StreamCallback) error {
+	return callback(s.content)
+}
+
+func (s *stubLLMClient) GetProvider() Provider { return ProviderOpenAI }
+func (s *stubLLMClient) GetModel() string      { return "gpt-test" }
+
+func TestCapturingLLMClient_CapturesAndRedacts(t *testing.T) {
+	store, err := NewCaptureStore(CaptureConfig{Secrets: []string{"super-secret-value"}})
+	require.NoError(t, err)
+
+	client := NewCapturingLLMClient(&stubLLMClient{content: `not json, token=abc123`}, store)
+	builder := NewItemAdapter(client, logrus.New())
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	_, err = client.Chat(WithOperation(ctx, "test.chat"), &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: "Use key sk-abcdefghijklmnopqrstuvwx"},
+			{Role: "user", Content: `{"api_key": "super-secret-value"}`},
+		},
+		Temperature: 0.2,
+		MaxTokens:   100,
+	})
+	require.NoError(t, err)
+
+	captures := store.List(CaptureFilter{RequestID: "req-1"})
+	require.Len(t, captures, 1)
+	capture := captures[0]
+	assert.Equal(t, "test.chat", capture.Operation)
+	assert.Equal(t, "gpt-test", capture.Model)
+	assert.Equal(t, float32(0.2), capture.Temperature)
+	assert.Equal(t, 42, capture.Usage.TotalTokens)
+	assert.Equal(t, ParseUnreported, capture.ParseOutcome)
+	assert.Equal(t, "Use key [REDACTED]", capture.Messages[0].Content)
+	assert.NotContains(t, capture.Messages[1].Content, "super-secret-value")
+	assert.Equal(t, "not json, token=[REDACTED]", capture.Response)
+
+	// Builders report whether they could parse the response
+	err = builder.adapt(ctx, "goal", map[string]string{"title": "x"}, &map[string]interface{}{}, agency.AdaptationContext{})
+	require.Error(t, err)
+
+	adapted := store.List(CaptureFilter{Operation: "items.adapt"})
+	require.Len(t, adapted, 1)
+	assert.Equal(t, ParseFailed, adapted[0].ParseOutcome)
+	assert.NotEmpty(t, adapted[0].ParseError)
+
+	got, ok := store.Get(adapted[0].ID)
+	require.True(t, ok)
+	assert.Equal(t, "req-1", got.RequestID)
+	assert.Equal(t, []string{"items.adapt", "test.chat"}, store.Operations())
+}
+
+func TestCaptureStore_Retention(t *testing.T) {
+	store, err := NewCaptureStore(CaptureConfig{MaxEntries: 2})
+	require.NoError(t, err)
+
+	client := NewCapturingLLMClient(&stubLLMClient{content: "ok"}, store)
+	var last *ChatResponse
+	for i := 0; i < 3; i++ {
+		last, err = client.Chat(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, store.List(CaptureFilter{}), 2)
+
+	last.RecordParseOutcome(nil)
+	got, ok := store.Get(last.CaptureID)
+	require.True(t, ok)
+	assert.Equal(t, ParseOK, got.ParseOutcome)
+
+	// Uncaptured responses ignore parse outcomes
+	(&ChatResponse{}).RecordParseOutcome(errors.New("ignored"))
+
+	_, err = NewCaptureStore(CaptureConfig{SecretPatterns: []string{"("}})
+	assert.Error(t, err)
+}
@@ -13,9 +13,11 @@ func NewLLMClient(config *LLMConfig) (LLMClient, error) {
 	switch config.Provider {
 	case ProviderOpenAI:
 		return NewOpenAIClient(config)
-	case ProviderClaude:
+	case ProviderAzure:
+		return NewAzureOpenAIClient(config)
+	case ProviderClaude, "anthropic":
 		return NewClaudeClient(config)
-	case ProviderLocal:
+	case ProviderLocal, "ollama":
 		return NewLocalClient(config)
 	case ProviderCustom:
 		return NewCustomClient(config)
@@ -0,0 +1,219 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultMessagePageSize is the number of messages in a page of conversation history
+	DefaultMessagePageSize = 50
+
+	// MaxMessagePageSize bounds the page size callers may ask for
+	MaxMessagePageSize = 200
+)
+
+// ErrConversationNotFound is returned by conversation stores for unknown conversations
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationSummary describes a conversation without its messages
+type ConversationSummary struct {
+	ID           string      `json:"id"`
+	AgencyID     string      `json:"agency_id"`
+	Name         string      `json:"name,omitempty"`
+	Phase        DesignPhase `json:"phase"`
+	MessageCount int         `json:"message_count"` // Messages shown in the chat, excluding system prompts
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// MessagePage is a page of a conversation's chat history, oldest first
+type MessagePage struct {
+	Messages []Message `json:"messages"`
+	HasMore  bool      `json:"has_more"`              // Older messages exist
+	Before   string    `json:"next_before,omitempty"` // Pass as "before" to get the previous page
+}
+
+// ConversationStore persists designer conversations so chat history
+// survives restarts
+type ConversationStore interface {
+	// SaveConversation creates or updates a conversation, not including its messages
+	SaveConversation(ctx context.Context, conversation *ConversationContext) error
+
+	// AppendMessage stores a message as the seq-th message of a conversation
+	AppendMessage(ctx context.Context, conversationID string, seq int, message Message) error
+
+	// GetConversation returns a conversation with all its messages, or
+	// ErrConversationNotFound
+	GetConversation(ctx context.Context, conversationID string) (*ConversationContext, error)
+
+	// ListConversations returns an agency's conversations, most recently updated first
+	ListConversations(ctx context.Context, agencyID string) ([]*ConversationSummary, error)
+}
+
+// SetConversationStore persists conversations to store. Conversations not in
+// memory, such as those from before a restart, are loaded from it.
+func (s *AgencyDesignerService) SetConversationStore(store ConversationStore) {
+	s.store = store
+}
+
+// lookup returns a conversation from memory, or loads it from the store
+func (s *AgencyDesignerService) lookup(ctx context.Context, conversationID string) (*ConversationContext, bool) {
+	s.mu.RLock()
+	conversation, exists := s.conversations[conversationID]
+	s.mu.RUnlock()
+	if exists || s.store == nil {
+		return conversation, exists
+	}
+
+	stored, err := s.store.GetConversation(ctx, conversationID)
+	if err != nil {
+		if !errors.Is(err, ErrConversationNotFound) {
+			s.logger.WithError(err).WithField("conversation_id", conversationID).Warn("Failed to load conversation")
+		}
+		return nil, false
+	}
+	if stored.State == nil {
+		stored.State = make(map[string]interface{})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another request may have loaded it meanwhile
+	if conversation, exists := s.conversations[conversationID]; exists {
+		return conversation, true
+	}
+	s.conversations[conversationID] = stored
+	return stored, true
+}
+
+// appendMessage adds a message to a conversation and persists it. Messages
+// without an ID are given one.
+func (s *AgencyDesignerService) appendMessage(ctx context.Context, conversation *ConversationContext, message Message) Message {
+	if message.ID == "" {
+		message.ID = uuid.New().String()
+	}
+
+	seq := len(conversation.Messages)
+	conversation.Messages = append(conversation.Messages, message)
+	conversation.UpdatedAt = time.Now()
+
+	if s.store != nil {
+		if err := s.store.AppendMessage(ctx, conversation.ID, seq, message); err != nil {
+			s.logger.WithError(err).WithField("conversation_id", conversation.ID).Warn("Failed to persist conversation message")
+		}
+		s.saveConversation(ctx, conversation)
+	}
+	return message
+}
+
+// saveConversation persists a conversation's details, if there is a store
+func (s *AgencyDesignerService) saveConversation(ctx context.Context, conversation *ConversationContext) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.SaveConversation(ctx, conversation); err != nil {
+		s.logger.WithError(err).WithField("conversation_id", conversation.ID).Warn("Failed to persist conversation")
+	}
+}
+
+// ListConversations returns an agency's conversations, most recently updated first
+func (s *AgencyDesignerService) ListConversations(ctx context.Context, agencyID string) ([]*ConversationSummary, error) {
+	byID := make(map[string]*ConversationSummary)
+	if s.store != nil {
+		stored, err := s.store.ListConversations(ctx, agencyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list conversations: %w", err)
+		}
+		for _, summary := range stored {
+			byID[summary.ID] = summary
+		}
+	}
+
+	// Conversations in memory are at least as recent as their stored copy
+	s.mu.RLock()
+	for _, conversation := range s.conversations {
+		if conversation.AgencyID == agencyID {
+			byID[conversation.ID] = summarizeConversation(conversation)
+		}
+	}
+	s.mu.RUnlock()
+
+	summaries := make([]*ConversationSummary, 0, len(byID))
+	for _, summary := range byID {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+// ListMessages returns the chat messages of a conversation that precede the
+// message with ID before, or the latest messages when before is empty.
+// System prompts are not part of the chat history.
+func (s *AgencyDesignerService) ListMessages(ctx context.Context, conversationID, before string, limit int) (*MessagePage, error) {
+	conversation, exists := s.lookup(ctx, conversationID)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+	}
+	if limit <= 0 {
+		limit = DefaultMessagePageSize
+	}
+	limit = min(limit, MaxMessagePageSize)
+
+	var chat []Message
+	for _, message := range conversation.Messages {
+		if message.Role != "system" {
+			chat = append(chat, message)
+		}
+	}
+
+	end := len(chat)
+	if before != "" {
+		end = -1
+		for i, message := range chat {
+			if message.ID == before {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("message not found: %s", before)
+		}
+	}
+
+	start := max(end-limit, 0)
+	page := &MessagePage{
+		Messages: append([]Message{}, chat[start:end]...),
+		HasMore:  start > 0,
+	}
+	if page.HasMore {
+		page.Before = chat[start].ID
+	}
+	return page, nil
+}
+
+// summarizeConversation describes a conversation without its messages
+func summarizeConversation(conversation *ConversationContext) *ConversationSummary {
+	count := 0
+	for _, message := range conversation.Messages {
+		if message.Role != "system" {
+			count++
+		}
+	}
+	return &ConversationSummary{
+		ID:           conversation.ID,
+		AgencyID:     conversation.AgencyID,
+		Name:         conversation.Name,
+		Phase:        conversation.Phase,
+		MessageCount: count,
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+	}
+}
@@ -0,0 +1,222 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionDesignerConversations is the agency designer conversation collection name
+	CollectionDesignerConversations = "designer_conversations"
+
+	// CollectionDesignerMessages is the agency designer message collection name
+	CollectionDesignerMessages = "designer_messages"
+)
+
+// ArangoConversationStore persists agency designer conversations in ArangoDB.
+// Messages are stored one document each so a turn only writes what changed.
+type ArangoConversationStore struct {
+	db            driver.Database
+	conversations driver.Collection
+	messages      driver.Collection
+}
+
+// conversationDocument is a conversation as stored in ArangoDB
+type conversationDocument struct {
+	Key       string                 `json:"_key"`
+	AgencyID  string                 `json:"agency_id"`
+	Name      string                 `json:"name,omitempty"`
+	Phase     DesignPhase            `json:"phase"`
+	State     map[string]interface{} `json:"state,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// messageDocument is a conversation message as stored in ArangoDB
+type messageDocument struct {
+	Key            string  `json:"_key"`
+	ConversationID string  `json:"conversation_id"`
+	Seq            int     `json:"seq"`
+	Message        Message `json:"message"`
+}
+
+// NewArangoConversationStore creates an ArangoDB-backed conversation store
+func NewArangoConversationStore(dbClient *database.ArangoClient) (*ArangoConversationStore, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	conversations, err := ensureCollection(ctx, db, CollectionDesignerConversations)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := conversations.EnsurePersistentIndex(ctx, []string{"agency_id", "updated_at"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_designer_conversations_agency",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	messages, err := ensureCollection(ctx, db, CollectionDesignerMessages)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := messages.EnsurePersistentIndex(ctx, []string{"conversation_id", "seq"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_designer_messages_conversation",
+		Unique: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoConversationStore{db: db, conversations: conversations, messages: messages}, nil
+}
+
+// ensureCollection opens a collection, creating it if needed
+func ensureCollection(ctx context.Context, db driver.Database, name string) (driver.Collection, error) {
+	exists, err := db.CollectionExists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	if exists {
+		col, err := db.Collection(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+		return col, nil
+	}
+
+	col, err := db.CreateCollection(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	logrus.WithField("collection", name).Info("Created new collection")
+	return col, nil
+}
+
+// SaveConversation creates or replaces a conversation's details
+func (a *ArangoConversationStore) SaveConversation(ctx context.Context, conversation *ConversationContext) error {
+	doc := conversationDocument{
+		Key:       conversation.ID,
+		AgencyID:  conversation.AgencyID,
+		Name:      conversation.Name,
+		Phase:     conversation.Phase,
+		State:     conversation.State,
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+	}
+
+	ctx = driver.WithOverwriteMode(ctx, driver.OverwriteModeReplace)
+	if _, err := a.conversations.CreateDocument(ctx, doc); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// AppendMessage stores a conversation message. Storing a message at the same
+// position again replaces it.
+func (a *ArangoConversationStore) AppendMessage(ctx context.Context, conversationID string, seq int, message Message) error {
+	doc := messageDocument{
+		Key:            fmt.Sprintf("%s_%d", conversationID, seq),
+		ConversationID: conversationID,
+		Seq:            seq,
+		Message:        message,
+	}
+
+	ctx = driver.WithOverwriteMode(ctx, driver.OverwriteModeReplace)
+	if _, err := a.messages.CreateDocument(ctx, doc); err != nil {
+		return fmt.Errorf("failed to save message: %w", err)
+	}
+	return nil
+}
+
+// GetConversation returns a conversation with its messages in order
+func (a *ArangoConversationStore) GetConversation(ctx context.Context, conversationID string) (*ConversationContext, error) {
+	var doc conversationDocument
+	if _, err := a.conversations.ReadDocument(ctx, conversationID, &doc); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+
+	query := `
+		FOR m IN @@collection
+			FILTER m.conversation_id == @conversation_id
+			SORT m.seq ASC
+			RETURN m.message
+	`
+	cursor, err := a.db.Query(ctx, query, map[string]interface{}{
+		"@collection":     CollectionDesignerMessages,
+		"conversation_id": conversationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer cursor.Close()
+
+	messages := []Message{}
+	for cursor.HasMore() {
+		var message Message
+		if _, err := cursor.ReadDocument(ctx, &message); err != nil {
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return &ConversationContext{
+		ID:        doc.Key,
+		AgencyID:  doc.AgencyID,
+		Name:      doc.Name,
+		Phase:     doc.Phase,
+		Messages:  messages,
+		State:     doc.State,
+		CreatedAt: doc.CreatedAt,
+		UpdatedAt: doc.UpdatedAt,
+	}, nil
+}
+
+// ListConversations returns an agency's conversations, most recently updated first
+func (a *ArangoConversationStore) ListConversations(ctx context.Context, agencyID string) ([]*ConversationSummary, error) {
+	query := `
+		FOR c IN @@conversations
+			FILTER c.agency_id == @agency_id
+			SORT c.updated_at DESC
+			LET count = LENGTH(
+				FOR m IN @@messages
+					FILTER m.conversation_id == c._key AND m.message.role != "system"
+					RETURN 1
+			)
+			RETURN {
+				id: c._key,
+				agency_id: c.agency_id,
+				name: c.name,
+				phase: c.phase,
+				message_count: count,
+				created_at: c.created_at,
+				updated_at: c.updated_at
+			}
+	`
+	cursor, err := a.db.Query(ctx, query, map[string]interface{}{
+		"@conversations": CollectionDesignerConversations,
+		"@messages":      CollectionDesignerMessages,
+		"agency_id":      agencyID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer cursor.Close()
+
+	summaries := []*ConversationSummary{}
+	for cursor.HasMore() {
+		var summary ConversationSummary
+		if _, err := cursor.ReadDocument(ctx, &summary); err != nil {
+			return nil, fmt.Errorf("failed to read conversation: %w", err)
+		}
+		summaries = append(summaries, &summary)
+	}
+	return summaries, nil
+}
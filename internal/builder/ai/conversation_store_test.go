@@ -0,0 +1,140 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryConversationStore keeps conversations in memory the way a database would
+type memoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[string]ConversationContext
+	messages      map[string]map[int]Message
+}
+
+func newMemoryConversationStore() *memoryConversationStore {
+	return &memoryConversationStore{
+		conversations: make(map[string]ConversationContext),
+		messages:      make(map[string]map[int]Message),
+	}
+}
+
+func (m *memoryConversationStore) SaveConversation(ctx context.Context, conversation *ConversationContext) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *conversation
+	stored.Messages = nil
+	m.conversations[conversation.ID] = stored
+	return nil
+}
+
+func (m *memoryConversationStore) AppendMessage(ctx context.Context, conversationID string, seq int, message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.messages[conversationID] == nil {
+		m.messages[conversationID] = make(map[int]Message)
+	}
+	m.messages[conversationID][seq] = message
+	return nil
+}
+
+func (m *memoryConversationStore) GetConversation(ctx context.Context, conversationID string) (*ConversationContext, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.conversations[conversationID]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+	for seq := 0; seq < len(m.messages[conversationID]); seq++ {
+		stored.Messages = append(stored.Messages, m.messages[conversationID][seq])
+	}
+	return &stored, nil
+}
+
+func (m *memoryConversationStore) ListConversations(ctx context.Context, agencyID string) ([]*ConversationSummary, error) {
+	var summaries []*ConversationSummary
+	for id := range m.conversations {
+		if m.conversations[id].AgencyID == agencyID {
+			conversation, _ := m.GetConversation(ctx, id)
+			summaries = append(summaries, summarizeConversation(conversation))
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt)
+	})
+	return summaries, nil
+}
+
+func TestAgencyDesignerService_ConversationsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryConversationStore()
+
+	service := NewAgencyDesignerService(&stubLLMClient{content: "Tell me more"}, logrus.New())
+	service.SetConversationStore(store)
+
+	conversation, err := service.StartNamedConversation(ctx, "agency-1", "Billing")
+	require.NoError(t, err)
+	_, err = service.SendMessage(ctx, conversation.ID, "We need invoicing")
+	require.NoError(t, err)
+	_, err = service.StartNamedConversation(ctx, "agency-1", "Support")
+	require.NoError(t, err)
+
+	// A new service sharing the store sees the same history
+	restarted := NewAgencyDesignerService(&stubLLMClient{}, logrus.New())
+	restarted.SetConversationStore(store)
+
+	summaries, err := restarted.ListConversations(ctx, "agency-1")
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "Support", summaries[0].Name)
+	assert.Equal(t, "Billing", summaries[1].Name)
+	assert.Equal(t, 2, summaries[1].MessageCount)
+
+	loaded, err := restarted.GetConversation(conversation.ID)
+	require.NoError(t, err)
+	require.Len(t, loaded.Messages, 3)
+	assert.Equal(t, "system", loaded.Messages[0].Role)
+	assert.Equal(t, "We need invoicing", loaded.Messages[1].Content)
+	assert.Equal(t, "Tell me more", loaded.Messages[2].Content)
+}
+
+func TestAgencyDesignerService_ListMessagesPages(t *testing.T) {
+	ctx := context.Background()
+	service := NewAgencyDesignerService(&stubLLMClient{}, logrus.New())
+
+	conversation, err := service.StartConversation(ctx, "agency-1")
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, service.AddMessage(conversation.ID, "user", fmt.Sprintf("message %d", i)))
+	}
+
+	page, err := service.ListMessages(ctx, conversation.ID, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 2)
+	assert.Equal(t, "message 4", page.Messages[0].Content)
+	assert.Equal(t, "message 5", page.Messages[1].Content)
+	assert.True(t, page.HasMore)
+
+	page, err = service.ListMessages(ctx, conversation.ID, page.Before, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "message 2", page.Messages[0].Content)
+	assert.True(t, page.HasMore)
+
+	// The system prompt is not part of the chat history
+	page, err = service.ListMessages(ctx, conversation.ID, page.Before, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Messages, 1)
+	assert.Equal(t, "message 1", page.Messages[0].Content)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.Before)
+
+	_, err = service.ListMessages(ctx, "missing", "", 0)
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
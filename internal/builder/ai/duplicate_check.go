@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+)
+
+// DuplicateChecker asks the LLM which existing goals or work items describe
+// the same thing as a new description. It implements agency.DuplicateChecker
+// for strict validation.
+type DuplicateChecker struct {
+	llmClient LLMClient
+	logger    *logrus.Logger
+}
+
+// NewDuplicateChecker creates a new AI near-duplicate checker
+func NewDuplicateChecker(llmClient LLMClient, logger *logrus.Logger) *DuplicateChecker {
+	return &DuplicateChecker{
+		llmClient: llmClient,
+		logger:    logger,
+	}
+}
+
+// FindDuplicates returns the candidates the model judges to be paraphrases of
+// the description. Keys the model invents are dropped.
+func (d *DuplicateChecker) FindDuplicates(ctx context.Context, description string, candidates []agency.DescriptionCandidate) ([]agency.DuplicateMatch, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	response, err := d.llmClient.Chat(WithOperation(ctx, "validation.duplicates"), &ChatRequest{
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: duplicateCheckSystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: buildDuplicateCheckPrompt(description, candidates),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AI duplicate check failed: %w", err)
+	}
+
+	cleanedContent := stripMarkdownFences(response.Content)
+	var result struct {
+		Duplicates []agency.DuplicateMatch `json:"duplicates"`
+	}
+	err = json.Unmarshal([]byte(cleanedContent), &result)
+	response.RecordParseOutcome(err)
+	if err != nil {
+		d.logger.WithError(err).WithField("response", cleanedContent).Error("Failed to parse duplicate check response")
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	known := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		known[candidate.Key] = true
+	}
+	matches := make([]agency.DuplicateMatch, 0, len(result.Duplicates))
+	for _, match := range result.Duplicates {
+		if known[match.Key] {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// buildDuplicateCheckPrompt lists the new description and the existing ones
+func buildDuplicateCheckPrompt(description string, candidates []agency.DescriptionCandidate) string {
+	var builder strings.Builder
+
+	builder.WriteString("### NEW DESCRIPTION\n")
+	builder.WriteString(description)
+	builder.WriteString("\n\n### EXISTING ITEMS\n")
+	for _, candidate := range candidates {
+		builder.WriteString(fmt.Sprintf("- key %s (%s): %s\n", candidate.Key, candidate.Code, candidate.Description))
+	}
+
+	return builder.String()
+}
+
+const duplicateCheckSystemPrompt = `Act as a reviewer that finds duplicated goals and work items in an agency design.
+
+Compare the new description with each existing item. An item is a duplicate when it asks for the same outcome, even if worded differently. Items that merely share a topic or could be done together are NOT duplicates.
+
+Respond with JSON in this exact format:
+
+{
+  "duplicates": [
+    {
+      "key": "existing item key",
+      "similarity": 0.9,
+      "reason": "Why the two describe the same thing"
+    }
+  ]
+}
+
+Use the keys from the list, give similarity between 0 and 1, and return an empty list when nothing is duplicated.`
@@ -0,0 +1,234 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/sirupsen/logrus"
+)
+
+// RankGoals recommends a priority ordering for all goals in the agency, with a
+// rationale per goal. The recommendation is not persisted; the caller accepts
+// or adjusts it.
+func (r *GoalsBuilder) RankGoals(ctx context.Context, req *builder.RankGoalsRequest, builderContext builder.BuilderContext) (*builder.RankGoalsResponse, error) {
+	r.logger.WithFields(logrus.Fields{
+		"agency_id": req.AgencyID,
+		"goals":     len(builderContext.Goals),
+	}).Info("Starting goal ranking")
+
+	if len(builderContext.Goals) == 0 {
+		return &builder.RankGoalsResponse{
+			Rankings:    []builder.GoalRanking{},
+			Explanation: "The agency has no goals to rank.",
+		}, nil
+	}
+
+	response, err := r.llmClient.Chat(WithOperation(ctx, "goals.rank"), &ChatRequest{
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: rankGoalsSystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: r.buildRankGoalsPrompt(builderContext),
+			},
+		},
+	})
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get AI response for goal ranking")
+		return nil, fmt.Errorf("AI ranking failed: %w", err)
+	}
+
+	cleanedContent := stripMarkdownFences(response.Content)
+	var result builder.RankGoalsResponse
+	err = json.Unmarshal([]byte(cleanedContent), &result)
+	response.RecordParseOutcome(err)
+	if err != nil {
+		r.logger.WithError(err).WithField("response", cleanedContent).Error("Failed to parse goal ranking response")
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	result.Rankings = normalizeGoalRankings(result.Rankings, builderContext.Goals)
+
+	r.logger.WithFields(logrus.Fields{
+		"agency_id": req.AgencyID,
+		"ranked":    len(result.Rankings),
+	}).Info("Goal ranking completed")
+
+	return &result, nil
+}
+
+// buildRankGoalsPrompt creates the ranking prompt with a summary of how well
+// each goal is covered by work items
+func (r *GoalsBuilder) buildRankGoalsPrompt(contextData builder.BuilderContext) string {
+	var builder strings.Builder
+
+	builder.WriteString(FormatAgencyContextBlock(contextData))
+
+	builder.WriteString("\n### WORK ITEM COVERAGE\n")
+	for _, goal := range contextData.Goals {
+		covering, dependents := goalCoverage(goal, contextData.WorkItems)
+		builder.WriteString(fmt.Sprintf("- **%s** (key %s, current priority %s): ", goal.Code, goal.Key, orDefault(goal.Priority, "unset")))
+		if len(covering) == 0 {
+			builder.WriteString("no work items reference this goal\n")
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("referenced by %s", strings.Join(covering, ", ")))
+		if dependents > 0 {
+			builder.WriteString(fmt.Sprintf("; %d other work items depend on these", dependents))
+		}
+		builder.WriteString("\n")
+	}
+
+	builder.WriteString("\nRank every goal listed above from highest to lowest priority.")
+
+	return builder.String()
+}
+
+// goalCoverage returns the codes of work items that mention the goal's code
+// and how many other work items depend on them
+func goalCoverage(goal *agency.Goal, workItems []*agency.WorkItem) ([]string, int) {
+	covering := make(map[string]bool)
+	var codes []string
+	for _, item := range workItems {
+		if agency.WorkItemReferencesGoal(item, goal) {
+			covering[item.Code] = true
+			codes = append(codes, item.Code)
+		}
+	}
+
+	dependents := 0
+	for _, item := range workItems {
+		if covering[item.Code] {
+			continue
+		}
+		for _, dep := range item.Dependencies {
+			if covering[dep] {
+				dependents++
+				break
+			}
+		}
+	}
+
+	sort.Strings(codes)
+	return codes, dependents
+}
+
+// normalizeGoalRankings turns the model's ranking into a complete ordering of
+// the agency's goals: unknown and repeated goals are dropped, goals the model
+// left out are appended, ranks are renumbered from 1 and priorities are
+// limited to High, Medium and Low
+func normalizeGoalRankings(rankings []builder.GoalRanking, goals []*agency.Goal) []builder.GoalRanking {
+	byKey := make(map[string]*agency.Goal, len(goals))
+	byCode := make(map[string]*agency.Goal, len(goals))
+	for _, goal := range goals {
+		byKey[goal.Key] = goal
+		byCode[strings.ToUpper(goal.Code)] = goal
+	}
+
+	sort.SliceStable(rankings, func(i, j int) bool {
+		if rankings[i].Rank <= 0 || rankings[j].Rank <= 0 {
+			return rankings[i].Rank > 0
+		}
+		return rankings[i].Rank < rankings[j].Rank
+	})
+
+	seen := make(map[string]bool, len(goals))
+	result := make([]builder.GoalRanking, 0, len(goals))
+	for _, ranking := range rankings {
+		goal, exists := byKey[ranking.GoalKey]
+		if !exists {
+			goal, exists = byCode[strings.ToUpper(ranking.Code)]
+		}
+		if !exists || seen[goal.Key] {
+			continue
+		}
+		seen[goal.Key] = true
+
+		ranking.GoalKey = goal.Key
+		ranking.Code = goal.Code
+		result = append(result, ranking)
+	}
+
+	// Goals the model skipped keep their current relative order after the ranked ones
+	var missing []*agency.Goal
+	for _, goal := range goals {
+		if !seen[goal.Key] {
+			missing = append(missing, goal)
+		}
+	}
+	agency.SortGoalsByRank(missing)
+	for _, goal := range missing {
+		result = append(result, builder.GoalRanking{
+			GoalKey:   goal.Key,
+			Code:      goal.Code,
+			Priority:  goal.Priority,
+			Rationale: "Not ranked by the AI; kept after the ranked goals.",
+		})
+	}
+
+	for i := range result {
+		result[i].Rank = i + 1
+		result[i].Priority = normalizeGoalPriority(result[i].Priority, i, len(result))
+	}
+	return result
+}
+
+// normalizeGoalPriority maps a priority onto High, Medium or Low. Unknown
+// values fall back to the goal's position: top third High, bottom third Low.
+func normalizeGoalPriority(priority string, index, total int) string {
+	for _, known := range []string{agency.GoalPriorityHigh, agency.GoalPriorityMedium, agency.GoalPriorityLow} {
+		if strings.EqualFold(strings.TrimSpace(priority), known) {
+			return known
+		}
+	}
+
+	switch {
+	case index*3 < total:
+		return agency.GoalPriorityHigh
+	case index*3 < total*2:
+		return agency.GoalPriorityMedium
+	default:
+		return agency.GoalPriorityLow
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+const rankGoalsSystemPrompt = `Act as a strategic planning AI that prioritizes an agency's goals.
+
+Rank ALL of the agency's goals from highest to lowest priority. Base the ordering on:
+1. ALIGNMENT with the agency introduction and purpose - goals central to the mission rank higher
+2. DEPENDENCIES - goals whose work enables other goals or work items rank higher
+3. WORK ITEM COVERAGE - well covered goals are ready to deliver; critical goals with no coverage are gaps worth surfacing, but say so in the rationale
+4. IMPACT versus effort as described by the goal scope and success metrics
+
+Assign each goal a priority of High, Medium or Low consistent with its rank, and give a one or two sentence rationale that refers to the factors above.
+
+Respond with JSON in this exact format:
+
+{
+  "rankings": [
+    {
+      "goal_key": "goal_key",
+      "code": "G001",
+      "rank": 1,
+      "priority": "High",
+      "rationale": "Why the goal has this rank"
+    }
+  ],
+  "explanation": "Overall reasoning behind the ordering"
+}
+
+Include every goal exactly once, use the goal keys from the context, and number ranks from 1 without gaps.`
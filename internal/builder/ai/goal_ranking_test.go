@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rankingGoals() []*agency.Goal {
+	return []*agency.Goal{
+		{Key: "k1", Code: "G001", Description: "Reduce outages", Priority: "Low"},
+		{Key: "k2", Code: "G002", Description: "Improve reporting"},
+		{Key: "k3", Code: "G003", Description: "Automate billing"},
+	}
+}
+
+func TestRankGoals_NormalizesModelRanking(t *testing.T) {
+	client := &stubLLMClient{content: "```json\n" + `{
+		"rankings": [
+			{"goal_key": "k3", "code": "G003", "rank": 2, "priority": "medium", "rationale": "Depends on reporting"},
+			{"goal_key": "unknown", "code": "G999", "rank": 3, "priority": "High", "rationale": "Hallucinated"},
+			{"code": "g002", "rank": 1, "priority": "High", "rationale": "Enables everything else"},
+			{"goal_key": "k3", "rank": 4, "priority": "Low", "rationale": "Duplicate"}
+		],
+		"explanation": "Reporting first"
+	}` + "\n```"}
+	goalsBuilder := NewGoalRefiner(client, logrus.New())
+
+	result, err := goalsBuilder.RankGoals(context.Background(), &builder.RankGoalsRequest{AgencyID: "a1"}, builder.BuilderContext{Goals: rankingGoals()})
+	require.NoError(t, err)
+
+	require.Len(t, result.Rankings, 3)
+	assert.Equal(t, "Reporting first", result.Explanation)
+
+	assert.Equal(t, builder.GoalRanking{GoalKey: "k2", Code: "G002", Rank: 1, Priority: "High", Rationale: "Enables everything else"}, result.Rankings[0])
+	assert.Equal(t, builder.GoalRanking{GoalKey: "k3", Code: "G003", Rank: 2, Priority: "Medium", Rationale: "Depends on reporting"}, result.Rankings[1])
+
+	// The goal the model skipped is appended and keeps its priority
+	assert.Equal(t, "k1", result.Rankings[2].GoalKey)
+	assert.Equal(t, 3, result.Rankings[2].Rank)
+	assert.Equal(t, "Low", result.Rankings[2].Priority)
+}
+
+func TestRankGoals_NoGoals(t *testing.T) {
+	goalsBuilder := NewGoalRefiner(&stubLLMClient{content: "not json"}, logrus.New())
+
+	result, err := goalsBuilder.RankGoals(context.Background(), &builder.RankGoalsRequest{AgencyID: "a1"}, builder.BuilderContext{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Rankings)
+}
+
+func TestGoalCoverage(t *testing.T) {
+	workItems := []*agency.WorkItem{
+		{Code: "WI-001", Title: "Build outage dashboard", Description: "Supports g001"},
+		{Code: "WI-002", Title: "Alerting", Tags: []string{"G001"}},
+		{Code: "WI-003", Title: "Runbooks", Dependencies: []string{"WI-002"}},
+		{Code: "WI-004", Title: "Invoices"},
+	}
+
+	covering, dependents := goalCoverage(&agency.Goal{Code: "G001"}, workItems)
+	assert.Equal(t, []string{"WI-001", "WI-002"}, covering)
+	assert.Equal(t, 1, dependents)
+
+	covering, _ = goalCoverage(&agency.Goal{Code: "G003"}, workItems)
+	assert.Empty(t, covering)
+}
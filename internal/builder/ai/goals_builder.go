@@ -2,7 +2,6 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -15,18 +14,58 @@ var _ builder.GoalBuilderInterface = (*GoalsBuilder)(nil)
 
 // GoalsBuilder handles AI-powered goal definition and refinement
 type GoalsBuilder struct {
-	llmClient LLMClient
-	logger    *logrus.Logger
+	llmClient      LLMClient
+	logger         *logrus.Logger
+	repairAttempts int
 }
 
 // NewGoalRefiner creates a new goal refiner service
 func NewGoalRefiner(llmClient LLMClient, logger *logrus.Logger) *GoalsBuilder {
 	return &GoalsBuilder{
-		llmClient: llmClient,
-		logger:    logger,
+		llmClient:      llmClient,
+		logger:         logger,
+		repairAttempts: DefaultRepairAttempts,
 	}
 }
 
+// SetRepairAttempts sets how many times a response that does not match the
+// goals schema is sent back to the model for repair (0 disables repairs)
+func (r *GoalsBuilder) SetRepairAttempts(attempts int) {
+	r.repairAttempts = max(attempts, 0)
+}
+
+// goalProperties describe the fields of a refined, generated or consolidated goal
+var goalProperties = map[string]*Schema{
+	"original_key":        stringSchema(),
+	"description":         stringSchema(),
+	"refined_description": stringSchema(),
+	"scope":               stringSchema(),
+	"refined_scope":       stringSchema(),
+	"success_metrics":     stringArray,
+	"refined_metrics":     stringArray,
+	"suggested_code":      stringSchema(),
+	"suggested_priority":  stringSchema(),
+	"suggested_category":  stringSchema(),
+	"suggested_tags":      stringArray,
+	"was_changed":         booleanSchema(),
+	"explanation":         stringSchema(),
+}
+
+// dynamicGoalsSchema describes the answer to a dynamic goals request
+var dynamicGoalsSchema = objectSchema(map[string]*Schema{
+	"action":          stringSchema("remove", "refine", "generate", "consolidate", "enhance_all", "no_action"),
+	"refined_goals":   arraySchema(objectSchema(goalProperties, "original_key")),
+	"generated_goals": arraySchema(objectSchema(goalProperties, "description")),
+	"consolidated_data": nullable(objectSchema(map[string]*Schema{
+		"consolidated_goals": arraySchema(objectSchema(goalProperties, "description")),
+		"removed_goals":      stringArray,
+		"summary":            stringSchema(),
+		"explanation":        stringSchema(),
+	})),
+	"explanation":      stringSchema(),
+	"no_action_needed": booleanSchema(),
+}, "action", "explanation")
+
 // stripMarkdownFences removes markdown code fences from JSON responses
 // Some LLMs wrap JSON in ```json ... ``` blocks which need to be removed
 // Also handles cases where explanatory text appears before the JSON
@@ -80,11 +119,50 @@ func (r *GoalsBuilder) RefineGoals(ctx context.Context, req *builder.RefineGoals
 		"existing_goals": len(req.ExistingGoals),
 	}).Info("Starting dynamic goal refinement")
 
-	// Build the prompt to determine what action to take
-	prompt := r.buildDynamicGoalsPrompt(req, builderContext)
-
 	// Make the LLM request to determine action
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	ctx = WithOperation(ctx, "goals.refine")
+	chatReq := r.dynamicGoalsChatRequest(req, builderContext)
+	response, err := r.llmClient.Chat(ctx, chatReq)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get AI response for dynamic goal refinement")
+		return nil, fmt.Errorf("AI refinement failed: %w", err)
+	}
+
+	return r.parseDynamicGoalsResponse(ctx, chatReq, response)
+}
+
+// RefineGoalsStream works like RefineGoals but passes the model's output to
+// onChunk as it is generated. The parsed result is returned once the stream
+// has ended.
+func (r *GoalsBuilder) RefineGoalsStream(ctx context.Context, req *builder.RefineGoalsRequest, builderContext builder.BuilderContext, onChunk StreamCallback) (*builder.RefineGoalsResponse, error) {
+	r.logger.WithFields(logrus.Fields{
+		"agency_id":      req.AgencyID,
+		"user_message":   req.UserMessage,
+		"target_goals":   len(req.TargetGoals),
+		"existing_goals": len(req.ExistingGoals),
+	}).Info("Starting streamed dynamic goal refinement")
+
+	chatReq := r.dynamicGoalsChatRequest(req, builderContext)
+	chatReq.Stream = true
+
+	ctx = WithOperation(ctx, "goals.refine")
+	var content strings.Builder
+	err := r.llmClient.ChatStream(ctx, chatReq, func(chunk string) error {
+		content.WriteString(chunk)
+		return onChunk(chunk)
+	})
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to stream AI response for dynamic goal refinement")
+		return nil, fmt.Errorf("AI refinement failed: %w", err)
+	}
+
+	// Repairs are not streamed; the caller only sees the original output
+	return r.parseDynamicGoalsResponse(ctx, chatReq, &ChatResponse{Content: content.String()})
+}
+
+// dynamicGoalsChatRequest builds the LLM request for dynamic goal processing
+func (r *GoalsBuilder) dynamicGoalsChatRequest(req *builder.RefineGoalsRequest, builderContext builder.BuilderContext) *ChatRequest {
+	return &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -92,21 +170,17 @@ func (r *GoalsBuilder) RefineGoals(ctx context.Context, req *builder.RefineGoals
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: r.buildDynamicGoalsPrompt(req, builderContext),
 			},
 		},
-	})
-
-	if err != nil {
-		r.logger.WithError(err).Error("Failed to get AI response for dynamic goal refinement")
-		return nil, fmt.Errorf("AI refinement failed: %w", err)
 	}
+}
 
-	// Parse the AI response
-	cleanedContent := stripMarkdownFences(response.Content)
+// parseDynamicGoalsResponse parses the model's answer to a dynamic goals
+// request, asking the model to repair answers that do not match the schema
+func (r *GoalsBuilder) parseDynamicGoalsResponse(ctx context.Context, chatReq *ChatRequest, response *ChatResponse) (*builder.RefineGoalsResponse, error) {
 	var result builder.RefineGoalsResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &result); err != nil {
-		r.logger.WithError(err).WithField("response", cleanedContent).Error("Failed to parse dynamic goals response")
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, dynamicGoalsSchema, r.repairAttempts, &result); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
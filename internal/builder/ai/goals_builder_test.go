@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefineGoalsStream(t *testing.T) {
+	content := "```json\n" + `{
+		"action": "generate",
+		"generated_goals": [{"description": "Automate billing", "suggested_code": "G004"}],
+		"explanation": "Billing was missing"
+	}` + "\n```"
+	goalsBuilder := NewGoalRefiner(&stubLLMClient{content: content}, logrus.New())
+
+	var streamed string
+	result, err := goalsBuilder.RefineGoalsStream(context.Background(), &builder.RefineGoalsRequest{AgencyID: "a1", UserMessage: "add billing"}, builder.BuilderContext{Goals: rankingGoals()}, func(chunk string) error {
+		streamed += chunk
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, content, streamed)
+	assert.Equal(t, "generate", result.Action)
+	require.Len(t, result.GeneratedGoals, 1)
+	assert.Equal(t, "G004", result.GeneratedGoals[0].SuggestedCode)
+}
+
+func TestRefineGoalsStream_InvalidResponse(t *testing.T) {
+	goalsBuilder := NewGoalRefiner(&stubLLMClient{content: "not json"}, logrus.New())
+
+	_, err := goalsBuilder.RefineGoalsStream(context.Background(), &builder.RefineGoalsRequest{AgencyID: "a1"}, builder.BuilderContext{}, func(string) error { return nil })
+	assert.Error(t, err)
+}
@@ -56,7 +56,7 @@ func (r *IntroductionBuilder) RefineIntroduction(ctx context.Context, req *build
 	}).Info("==== SENDING TO AI - Built refinement prompt ====")
 
 	// Request AI refinement with strict JSON enforcement
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	response, err := r.llmClient.Chat(WithOperation(ctx, "introduction.refine"), &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -0,0 +1,131 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+)
+
+// Compile-time check to ensure ItemAdapter implements agency.ItemAdapter
+var _ agency.ItemAdapter = (*ItemAdapter)(nil)
+
+const itemAdapterSystemPrompt = `You adapt goals and work items copied from one agency so they fit another agency's domain.
+Keep the intent, structure and level of detail of the original. Replace domain-specific terms, actors and
+examples with ones that match the target agency. Do not add new requirements.
+
+Return ONLY a valid JSON object with the same fields you were given.`
+
+// ItemAdapter rewrites copied goals and work items for a target agency using the LLM
+type ItemAdapter struct {
+	llmClient LLMClient
+	logger    *logrus.Logger
+}
+
+// NewItemAdapter creates a new AI item adapter
+func NewItemAdapter(llmClient LLMClient, logger *logrus.Logger) *ItemAdapter {
+	return &ItemAdapter{
+		llmClient: llmClient,
+		logger:    logger,
+	}
+}
+
+// adaptedGoal holds the goal fields the LLM may rewrite
+type adaptedGoal struct {
+	Description    string   `json:"description"`
+	Scope          string   `json:"scope"`
+	SuccessMetrics []string `json:"success_metrics"`
+}
+
+// adaptedWorkItem holds the work item fields the LLM may rewrite
+type adaptedWorkItem struct {
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Deliverables []string `json:"deliverables"`
+}
+
+// AdaptGoal rewrites a goal's description, scope and success metrics for the target agency
+func (a *ItemAdapter) AdaptGoal(ctx context.Context, goal *agency.Goal, adaptCtx agency.AdaptationContext) error {
+	var result adaptedGoal
+	original := adaptedGoal{
+		Description:    goal.Description,
+		Scope:          goal.Scope,
+		SuccessMetrics: goal.SuccessMetrics,
+	}
+	if err := a.adapt(ctx, "goal", original, &result, adaptCtx); err != nil {
+		return err
+	}
+	if result.Description == "" {
+		return fmt.Errorf("adapted goal has no description")
+	}
+
+	goal.Description = result.Description
+	goal.Scope = result.Scope
+	goal.SuccessMetrics = result.SuccessMetrics
+	return nil
+}
+
+// AdaptWorkItem rewrites a work item's title, description and deliverables for the target agency
+func (a *ItemAdapter) AdaptWorkItem(ctx context.Context, workItem *agency.WorkItem, adaptCtx agency.AdaptationContext) error {
+	var result adaptedWorkItem
+	original := adaptedWorkItem{
+		Title:        workItem.Title,
+		Description:  workItem.Description,
+		Deliverables: workItem.Deliverables,
+	}
+	if err := a.adapt(ctx, "work item", original, &result, adaptCtx); err != nil {
+		return err
+	}
+	if result.Title == "" || result.Description == "" {
+		return fmt.Errorf("adapted work item is missing a title or description")
+	}
+
+	workItem.Title = result.Title
+	workItem.Description = result.Description
+	workItem.Deliverables = result.Deliverables
+	return nil
+}
+
+// adapt sends the original item to the LLM and decodes the rewritten item into result
+func (a *ItemAdapter) adapt(ctx context.Context, kind string, original interface{}, result interface{}, adaptCtx agency.AdaptationContext) error {
+	originalJSON, err := json.MarshalIndent(original, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", kind, err)
+	}
+
+	var prompt strings.Builder
+	if adaptCtx.Source != nil {
+		prompt.WriteString(fmt.Sprintf("### SOURCE AGENCY\n%s: %s\n\n", adaptCtx.Source.DisplayName, adaptCtx.Source.Description))
+	}
+	if adaptCtx.Target != nil {
+		prompt.WriteString(fmt.Sprintf("### TARGET AGENCY\n%s: %s\n", adaptCtx.Target.DisplayName, adaptCtx.Target.Description))
+	}
+	if adaptCtx.TargetIntroduction != "" {
+		prompt.WriteString(adaptCtx.TargetIntroduction + "\n")
+	}
+	prompt.WriteString(fmt.Sprintf("\n### %s TO ADAPT\n%s\n", strings.ToUpper(kind), originalJSON))
+
+	response, err := a.llmClient.Chat(WithOperation(ctx, "items.adapt"), &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: itemAdapterSystemPrompt},
+			{Role: "user", Content: prompt.String()},
+		},
+	})
+	if err != nil {
+		a.logger.WithError(err).WithField("kind", kind).Error("Failed to get AI response for item adaptation")
+		return fmt.Errorf("AI adaptation failed: %w", err)
+	}
+
+	cleanedContent := stripMarkdownFences(response.Content)
+	err = json.Unmarshal([]byte(cleanedContent), result)
+	response.RecordParseOutcome(err)
+	if err != nil {
+		a.logger.WithError(err).WithField("response", cleanedContent).Error("Failed to parse item adaptation response")
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
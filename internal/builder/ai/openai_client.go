@@ -7,12 +7,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// defaultAzureAPIVersion is used when an Azure OpenAI config has no API version
+const defaultAzureAPIVersion = "2024-06-01"
+
 type openAIClient struct {
 	config     *LLMConfig
 	httpClient *http.Client
+	azure      bool
 }
 
 // NewOpenAIClient creates a new OpenAI client
@@ -42,6 +47,36 @@ func NewOpenAIClient(config *LLMConfig) (LLMClient, error) {
 	}, nil
 }
 
+// NewAzureOpenAIClient creates a client for an Azure OpenAI resource. BaseURL
+// is the resource endpoint and Model the deployment name.
+func NewAzureOpenAIClient(config *LLMConfig) (LLMClient, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("Azure OpenAI API key is required")
+	}
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("Azure OpenAI endpoint (base_url) is required")
+	}
+	if config.Model == "" {
+		return nil, fmt.Errorf("Azure OpenAI deployment (model) is required")
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = defaultAzureAPIVersion
+	}
+
+	timeout := 60 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	return &openAIClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		azure: true,
+	}, nil
+}
+
 func (c *openAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	// Build OpenAI request
 	openAIReq := map[string]interface{}{
@@ -67,13 +102,24 @@ func (c *openAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatRespons
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/chat/completions", bytes.NewReader(body))
+	// Azure selects the model by deployment in the URL and authenticates with an api-key header
+	url := c.config.BaseURL + "/chat/completions"
+	if c.azure {
+		url = fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			strings.TrimSuffix(c.config.BaseURL, "/"), c.getModel(req), c.config.APIVersion)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	if c.azure {
+		httpReq.Header.Set("api-key", c.config.APIKey)
+	} else {
+		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -133,6 +179,9 @@ func (c *openAIClient) ChatStream(ctx context.Context, req *ChatRequest, callbac
 }
 
 func (c *openAIClient) GetProvider() Provider {
+	if c.azure {
+		return ProviderAzure
+	}
 	return ProviderOpenAI
 }
 
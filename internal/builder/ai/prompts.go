@@ -229,6 +229,14 @@ func FormatAgencyContextBlock(contextData builder.BuilderContext) string {
 		builder.WriteString("\n")
 	}
 
+	// Point the model at the live asset inventory when it is available
+	if contextData.Infrastructure != nil {
+		builder.WriteString(fmt.Sprintf("**Live Infrastructure:** %d assets across %d types and %d zones. ",
+			contextData.Infrastructure.TotalAssets, len(contextData.Infrastructure.AssetTypes), len(contextData.Infrastructure.Zones)))
+		builder.WriteString("Reference the real asset types, zones, IDs and KPI values from the \"infrastructure\" section of the context data instead of generic placeholders. ")
+		builder.WriteString("KPIs are the assets' latest telemetry readings; do not invent values for assets listed as missing or asset types listed in kpis_unavailable.\n\n")
+	}
+
 	// Add JSON data block with visual separators
 	// Marshal the complete context data to JSON
 	jsonData, err := json.MarshalIndent(contextData, "", "  ")
@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Compile-time check that the registry can stand in for a single client
+var _ LLMClient = (*ProviderRegistry)(nil)
+
+// ProviderRoute selects the providers used for an AI operation
+type ProviderRoute struct {
+	// Providers are provider names tried in order until one succeeds
+	Providers []string
+
+	// Model overrides the first provider's model. Fallback providers use
+	// their own model, since model names differ between providers.
+	Model string
+}
+
+// ProviderRegistry is an LLMClient that sends each call to the providers
+// routed for its operation (see WithOperation) and fails over to the next
+// provider when a call fails. Routes are matched on the operation and then
+// on its prefix, so "work_items" covers "work_items.consolidate" unless the
+// latter has its own route.
+type ProviderRegistry struct {
+	clients      map[string]LLMClient
+	defaultRoute ProviderRoute
+	routes       map[string]ProviderRoute
+	logger       *logrus.Logger
+}
+
+// NewProviderRegistry creates an empty provider registry
+func NewProviderRegistry(logger *logrus.Logger) *ProviderRegistry {
+	return &ProviderRegistry{
+		clients: make(map[string]LLMClient),
+		routes:  make(map[string]ProviderRoute),
+		logger:  logger,
+	}
+}
+
+// Register adds a provider under name. The first provider registered is
+// the default route until SetDefaultRoute is called.
+func (r *ProviderRegistry) Register(name string, client LLMClient) {
+	r.clients[name] = client
+	if len(r.defaultRoute.Providers) == 0 {
+		r.defaultRoute = ProviderRoute{Providers: []string{name}}
+	}
+}
+
+// SetDefaultRoute sets the route for operations without their own route
+func (r *ProviderRegistry) SetDefaultRoute(route ProviderRoute) error {
+	if err := r.validateRoute(route); err != nil {
+		return fmt.Errorf("default route: %w", err)
+	}
+	r.defaultRoute = route
+	return nil
+}
+
+// SetRoute sets the route for an operation or operation prefix, e.g.
+// "goals" or "work_items.consolidate"
+func (r *ProviderRegistry) SetRoute(operation string, route ProviderRoute) error {
+	if err := r.validateRoute(route); err != nil {
+		return fmt.Errorf("route %s: %w", operation, err)
+	}
+	r.routes[operation] = route
+	return nil
+}
+
+func (r *ProviderRegistry) validateRoute(route ProviderRoute) error {
+	if len(route.Providers) == 0 {
+		return errors.New("no providers")
+	}
+	for _, name := range route.Providers {
+		if _, exists := r.clients[name]; !exists {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+	}
+	return nil
+}
+
+// Route returns the route used for an operation
+func (r *ProviderRegistry) Route(operation string) ProviderRoute {
+	for operation != "" {
+		if route, exists := r.routes[operation]; exists {
+			return route
+		}
+		i := strings.LastIndex(operation, ".")
+		if i < 0 {
+			break
+		}
+		operation = operation[:i]
+	}
+	return r.defaultRoute
+}
+
+// Chat sends the request to the operation's providers in order and returns
+// the first successful response
+func (r *ProviderRegistry) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	operation := OperationFromContext(ctx)
+	route := r.Route(operation)
+
+	var errs []error
+	for i, name := range route.Providers {
+		resp, err := r.clients[name].Chat(ctx, routedRequest(req, route, i))
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if ctx.Err() != nil {
+			break
+		}
+		r.logFailure(operation, name, i, route, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ChatStream streams from the operation's providers in order. Once a
+// provider has streamed output the call no longer fails over, since the
+// caller has already received part of a response.
+func (r *ProviderRegistry) ChatStream(ctx context.Context, req *ChatRequest, callback StreamCallback) error {
+	operation := OperationFromContext(ctx)
+	route := r.Route(operation)
+
+	var errs []error
+	for i, name := range route.Providers {
+		streamed := false
+		err := r.clients[name].ChatStream(ctx, routedRequest(req, route, i), func(chunk string) error {
+			streamed = true
+			return callback(chunk)
+		})
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if streamed || ctx.Err() != nil {
+			break
+		}
+		r.logFailure(operation, name, i, route, err)
+	}
+	return errors.Join(errs...)
+}
+
+// GetProvider returns the default route's first provider
+func (r *ProviderRegistry) GetProvider() Provider {
+	if client := r.primary(); client != nil {
+		return client.GetProvider()
+	}
+	return ""
+}
+
+// GetModel returns the default route's model
+func (r *ProviderRegistry) GetModel() string {
+	if r.defaultRoute.Model != "" {
+		return r.defaultRoute.Model
+	}
+	if client := r.primary(); client != nil {
+		return client.GetModel()
+	}
+	return ""
+}
+
+func (r *ProviderRegistry) primary() LLMClient {
+	if len(r.defaultRoute.Providers) == 0 {
+		return nil
+	}
+	return r.clients[r.defaultRoute.Providers[0]]
+}
+
+func (r *ProviderRegistry) logFailure(operation, provider string, attempt int, route ProviderRoute, err error) {
+	if attempt == len(route.Providers)-1 {
+		return
+	}
+	r.logger.WithError(err).WithFields(logrus.Fields{
+		"operation": operation,
+		"provider":  provider,
+		"next":      route.Providers[attempt+1],
+	}).Warn("LLM provider failed, failing over")
+}
+
+// routedRequest applies the route's model to the request sent to the
+// route's attempt-th provider. A model set by the caller is kept.
+func routedRequest(req *ChatRequest, route ProviderRoute, attempt int) *ChatRequest {
+	if attempt > 0 || route.Model == "" || req.Model != "" {
+		return req
+	}
+	routed := *req
+	routed.Model = route.Model
+	return &routed
+}
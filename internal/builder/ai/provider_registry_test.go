@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLLMClient answers with its name, or fails, and records the models it was asked for
+type recordingLLMClient struct {
+	name   string
+	fail   bool
+	models []string
+}
+
+func (c *recordingLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.models = append(c.models, req.Model)
+	if c.fail {
+		return nil, errors.New(c.name + " unavailable")
+	}
+	return &ChatResponse{Content: c.name}, nil
+}
+
+func (c *recordingLLMClient) ChatStream(ctx context.Context, req *ChatRequest, callback StreamCallback) error {
+	resp, err := c.Chat(ctx, req)
+	if err != nil {
+		return err
+	}
+	return callback(resp.Content)
+}
+
+func (c *recordingLLMClient) GetProvider() Provider { return Provider(c.name) }
+func (c *recordingLLMClient) GetModel() string      { return c.name + "-model" }
+
+func TestProviderRegistry_RoutesByOperation(t *testing.T) {
+	primary := &recordingLLMClient{name: "primary"}
+	cheap := &recordingLLMClient{name: "cheap"}
+	registry := NewProviderRegistry(logrus.New())
+	registry.Register("primary", primary)
+	registry.Register("cheap", cheap)
+	require.NoError(t, registry.SetRoute("work_items.consolidate", ProviderRoute{Providers: []string{"cheap"}, Model: "mini"}))
+	require.NoError(t, registry.SetRoute("goals", ProviderRoute{Providers: []string{"primary"}, Model: "large"}))
+
+	ctx := context.Background()
+	resp, err := registry.Chat(WithOperation(ctx, "work_items.consolidate"), &ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "cheap", resp.Content)
+	assert.Equal(t, []string{"mini"}, cheap.models)
+
+	// Prefix routes cover sub-operations; other operations use the default
+	resp, err = registry.Chat(WithOperation(ctx, "goals.refine"), &ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Content)
+	resp, err = registry.Chat(WithOperation(ctx, "work_items.generate"), &ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Content)
+	assert.Equal(t, []string{"large", ""}, primary.models)
+
+	assert.Equal(t, Provider("primary"), registry.GetProvider())
+	assert.Error(t, registry.SetRoute("roles", ProviderRoute{Providers: []string{"missing"}}))
+}
+
+func TestProviderRegistry_FailsOver(t *testing.T) {
+	down := &recordingLLMClient{name: "down", fail: true}
+	backup := &recordingLLMClient{name: "backup"}
+	registry := NewProviderRegistry(logrus.New())
+	registry.Register("down", down)
+	registry.Register("backup", backup)
+	require.NoError(t, registry.SetDefaultRoute(ProviderRoute{Providers: []string{"down", "backup"}, Model: "gpt-test"}))
+
+	resp, err := registry.Chat(context.Background(), &ChatRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "backup", resp.Content)
+
+	// The route's model only applies to the first provider
+	assert.Equal(t, []string{"gpt-test"}, down.models)
+	assert.Equal(t, []string{""}, backup.models)
+
+	var streamed string
+	require.NoError(t, registry.ChatStream(context.Background(), &ChatRequest{}, func(chunk string) error {
+		streamed += chunk
+		return nil
+	}))
+	assert.Equal(t, "backup", streamed)
+
+	backup.fail = true
+	_, err = registry.Chat(context.Background(), &ChatRequest{})
+	assert.ErrorContains(t, err, "down unavailable")
+	assert.ErrorContains(t, err, "backup unavailable")
+}
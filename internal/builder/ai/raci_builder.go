@@ -100,7 +100,7 @@ func (r *RACIBuilder) CreateRACIMappings(ctx context.Context, req *builder.Creat
 	prompt := r.buildRACICreationPrompt(req, builderContext)
 
 	// Make the LLM request
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	response, err := r.llmClient.Chat(WithOperation(ctx, "raci.create"), &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -121,7 +121,9 @@ func (r *RACIBuilder) CreateRACIMappings(ctx context.Context, req *builder.Creat
 	// Parse the AI response
 	cleanedContent := stripMarkdownFences(response.Content)
 	var aiResponse aiRACIMappingResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &aiResponse); err != nil {
+	err = json.Unmarshal([]byte(cleanedContent), &aiResponse)
+	response.RecordParseOutcome(err)
+	if err != nil {
 		r.logger.WithError(err).WithField("response", response.Content).Error("Failed to parse AI RACI response")
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
@@ -0,0 +1,274 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultResponseCacheTTL is how long a cached response is served by default
+	DefaultResponseCacheTTL = time.Hour
+
+	// DefaultResponseCacheEntries is the default size of the in-memory response cache
+	DefaultResponseCacheEntries = 1000
+
+	// CacheBypassHeader skips the response cache for a request when set to "true"
+	CacheBypassHeader = "X-AI-Cache-Bypass"
+)
+
+// ResponseCache stores LLM responses by the hash of the request that produced them
+type ResponseCache interface {
+	// Get returns the cached response for key, if it has not expired
+	Get(ctx context.Context, key string) (*ChatResponse, bool, error)
+
+	// Set caches a response for ttl
+	Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error
+}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass makes LLM calls made with ctx skip the response cache.
+// Their responses still replace any cached response.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether LLM calls made with ctx skip the response cache
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CacheBypassMiddleware skips the response cache for requests with the
+// X-AI-Cache-Bypass: true header or the no_cache=true query parameter
+func CacheBypassMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(CacheBypassHeader) == "true" || c.Query("no_cache") == "true" {
+			c.Request = c.Request.WithContext(WithCacheBypass(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
+
+// ResponseCacheConfig configures response caching
+type ResponseCacheConfig struct {
+	// TTL is how long a response is served from the cache (default 1h)
+	TTL time.Duration
+
+	// Operations limits caching to these operations or operation prefixes,
+	// e.g. "goals" or "work_items.refine". Empty caches every operation.
+	Operations []string
+}
+
+// CachingLLMClient serves repeated LLM requests from a cache. Requests are
+// identical when their operation, model, parameters and messages are, so a
+// builder asked to refine the same context twice gets the first answer back
+// without calling the provider.
+type CachingLLMClient struct {
+	LLMClient
+	cache  ResponseCache
+	config ResponseCacheConfig
+	logger *logrus.Logger
+}
+
+// NewCachingLLMClient wraps an LLM client with a response cache
+func NewCachingLLMClient(client LLMClient, cache ResponseCache, config ResponseCacheConfig, logger *logrus.Logger) *CachingLLMClient {
+	if config.TTL <= 0 {
+		config.TTL = DefaultResponseCacheTTL
+	}
+	return &CachingLLMClient{
+		LLMClient: client,
+		cache:     cache,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Chat returns the cached response to an identical request, or sends the
+// request and caches the response
+func (c *CachingLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	operation := OperationFromContext(ctx)
+	if !c.cacheable(operation) {
+		return c.LLMClient.Chat(ctx, req)
+	}
+
+	key := c.cacheKey(operation, req)
+	if !CacheBypassed(ctx) {
+		if cached, ok := c.get(ctx, operation, key); ok {
+			return cached, nil
+		}
+	}
+
+	resp, err := c.LLMClient.Chat(ctx, req)
+	if err == nil && resp != nil && resp.FinishReason != "length" {
+		c.set(ctx, operation, key, resp)
+	}
+	return resp, err
+}
+
+// ChatStream replays a cached response as a single chunk, or streams the
+// request and caches the complete response
+func (c *CachingLLMClient) ChatStream(ctx context.Context, req *ChatRequest, callback StreamCallback) error {
+	operation := OperationFromContext(ctx)
+	if !c.cacheable(operation) {
+		return c.LLMClient.ChatStream(ctx, req, callback)
+	}
+
+	key := c.cacheKey(operation, req)
+	if !CacheBypassed(ctx) {
+		if cached, ok := c.get(ctx, operation, key); ok {
+			return callback(cached.Content)
+		}
+	}
+
+	var response strings.Builder
+	err := c.LLMClient.ChatStream(ctx, req, func(chunk string) error {
+		response.WriteString(chunk)
+		return callback(chunk)
+	})
+	if err == nil {
+		c.set(ctx, operation, key, &ChatResponse{Content: response.String()})
+	}
+	return err
+}
+
+// cacheable reports whether calls of an operation are cached
+func (c *CachingLLMClient) cacheable(operation string) bool {
+	if len(c.config.Operations) == 0 {
+		return true
+	}
+	for _, prefix := range c.config.Operations {
+		if operation == prefix || strings.HasPrefix(operation, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey hashes everything that determines a response. Streaming and
+// non-streaming calls share entries.
+func (c *CachingLLMClient) cacheKey(operation string, req *ChatRequest) string {
+	model := req.Model
+	if model == "" {
+		model = c.GetModel()
+	}
+
+	data, _ := json.Marshal(struct {
+		Operation   string    `json:"operation"`
+		Provider    Provider  `json:"provider"`
+		Model       string    `json:"model"`
+		Temperature float32   `json:"temperature"`
+		MaxTokens   int       `json:"max_tokens"`
+		Messages    []Message `json:"messages"`
+	}{operation, c.GetProvider(), model, req.Temperature, req.MaxTokens, req.Messages})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *CachingLLMClient) get(ctx context.Context, operation, key string) (*ChatResponse, bool) {
+	cached, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		c.logger.WithError(err).WithField("operation", operation).Warn("Failed to read LLM response cache")
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	c.logger.WithField("operation", operation).Debug("Serving LLM response from cache")
+	if cached.Metadata == nil {
+		cached.Metadata = make(map[string]interface{})
+	}
+	cached.Metadata["cached"] = true
+	return cached, true
+}
+
+func (c *CachingLLMClient) set(ctx context.Context, operation, key string, resp *ChatResponse) {
+	// Captures belong to the call that produced the response, not to later hits
+	cached := *resp
+	cached.CaptureID = ""
+	cached.captures = nil
+	cached.Metadata = nil
+
+	if err := c.cache.Set(ctx, key, &cached, c.config.TTL); err != nil {
+		c.logger.WithError(err).WithField("operation", operation).Warn("Failed to write LLM response cache")
+	}
+}
+
+// MemoryResponseCache is an in-process, size-bounded response cache
+type MemoryResponseCache struct {
+	mu         sync.Mutex
+	entries    map[string]cachedResponse
+	maxEntries int
+}
+
+type cachedResponse struct {
+	resp      ChatResponse
+	expiresAt time.Time
+}
+
+// NewMemoryResponseCache creates an in-process response cache holding up to
+// maxEntries responses (default 1000)
+func NewMemoryResponseCache(maxEntries int) *MemoryResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResponseCacheEntries
+	}
+	return &MemoryResponseCache{
+		entries:    make(map[string]cachedResponse),
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns the cached response for key, if it has not expired
+func (m *MemoryResponseCache) Get(ctx context.Context, key string) (*ChatResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	resp := entry.resp
+	return &resp, true, nil
+}
+
+// Set caches a response for ttl. When the cache is full, expired responses
+// are dropped first, then the one closest to expiry.
+func (m *MemoryResponseCache) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := m.entries[key]; !exists && len(m.entries) >= m.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range m.entries {
+			if now.After(entry.expiresAt) {
+				delete(m.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expiresAt.Before(oldest) {
+				oldestKey, oldest = k, entry.expiresAt
+			}
+		}
+		if len(m.entries) >= m.maxEntries {
+			delete(m.entries, oldestKey)
+		}
+	}
+
+	m.entries[key] = cachedResponse{resp: *resp, expiresAt: now.Add(ttl)}
+	return nil
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionLLMResponseCache is the LLM response cache collection name
+	CollectionLLMResponseCache = "llm_response_cache"
+)
+
+// ArangoResponseCache shares cached LLM responses between instances through
+// ArangoDB. Expired entries are removed by a TTL index.
+type ArangoResponseCache struct {
+	collection driver.Collection
+}
+
+// cacheDocument is a cached response as stored in ArangoDB
+type cacheDocument struct {
+	Key       string        `json:"_key"`
+	Response  *ChatResponse `json:"response"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// NewArangoResponseCache creates an ArangoDB-backed response cache
+func NewArangoResponseCache(dbClient *database.ArangoClient) (*ArangoResponseCache, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionLLMResponseCache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionLLMResponseCache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionLLMResponseCache, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		logrus.WithField("collection", CollectionLLMResponseCache).Info("Created new collection")
+	}
+
+	_, _, err = col.EnsureTTLIndex(ctx, "expires_at", 0, &driver.EnsureTTLIndexOptions{
+		Name: "idx_llm_response_cache_expiry",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoResponseCache{collection: col}, nil
+}
+
+// Get returns the cached response for key, if it has not expired
+func (a *ArangoResponseCache) Get(ctx context.Context, key string) (*ChatResponse, bool, error) {
+	var doc cacheDocument
+	if _, err := a.collection.ReadDocument(ctx, key, &doc); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cached response: %w", err)
+	}
+
+	// The TTL index removes expired entries periodically, not immediately
+	if doc.Response == nil || time.Now().After(doc.ExpiresAt) {
+		return nil, false, nil
+	}
+	return doc.Response, true, nil
+}
+
+// Set caches a response for ttl
+func (a *ArangoResponseCache) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error {
+	doc := cacheDocument{
+		Key:       key,
+		Response:  resp,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+
+	ctx = driver.WithOverwriteMode(ctx, driver.OverwriteModeReplace)
+	if _, err := a.collection.CreateDocument(ctx, doc); err != nil {
+		return fmt.Errorf("failed to cache response: %w", err)
+	}
+	return nil
+}
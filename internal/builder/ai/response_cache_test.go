@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingLLMClient_ReusesIdenticalRequests(t *testing.T) {
+	provider := &recordingLLMClient{name: "provider"}
+	client := NewCachingLLMClient(provider, NewMemoryResponseCache(0), ResponseCacheConfig{Operations: []string{"goals"}}, logrus.New())
+
+	ctx := WithOperation(context.Background(), "goals.refine")
+	req := &ChatRequest{Messages: []Message{{Role: "user", Content: "refine G001"}}}
+
+	_, err := client.Chat(ctx, req)
+	require.NoError(t, err)
+	resp, err := client.Chat(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "provider", resp.Content)
+	assert.Equal(t, true, resp.Metadata["cached"])
+	assert.Len(t, provider.models, 1, "the second identical request is served from the cache")
+
+	// Streams replay cached responses
+	var streamed string
+	require.NoError(t, client.ChatStream(ctx, req, func(chunk string) error {
+		streamed += chunk
+		return nil
+	}))
+	assert.Equal(t, "provider", streamed)
+	assert.Len(t, provider.models, 1)
+
+	// Different messages, a bypass or an uncached operation reach the provider
+	_, err = client.Chat(ctx, &ChatRequest{Messages: []Message{{Role: "user", Content: "refine G002"}}})
+	require.NoError(t, err)
+	_, err = client.Chat(WithCacheBypass(ctx), req)
+	require.NoError(t, err)
+	_, err = client.Chat(WithOperation(ctx, "work_items.refine"), req)
+	require.NoError(t, err)
+	_, err = client.Chat(WithOperation(ctx, "work_items.refine"), req)
+	require.NoError(t, err)
+	assert.Len(t, provider.models, 5)
+}
+
+func TestMemoryResponseCache_ExpiresAndEvicts(t *testing.T) {
+	cache := NewMemoryResponseCache(2)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "expired", &ChatResponse{Content: "old"}, -time.Second))
+	_, ok, err := cache.Get(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Set(ctx, "a", &ChatResponse{Content: "a"}, time.Minute))
+	require.NoError(t, cache.Set(ctx, "b", &ChatResponse{Content: "b"}, time.Hour))
+	require.NoError(t, cache.Set(ctx, "c", &ChatResponse{Content: "c"}, time.Hour))
+
+	// "a" expires soonest, so it makes room for "c"
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.False(t, ok)
+	resp, ok, _ := cache.Get(ctx, "c")
+	require.True(t, ok)
+	assert.Equal(t, "c", resp.Content)
+}
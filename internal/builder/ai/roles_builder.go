@@ -2,6 +2,8 @@ package ai
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/sirupsen/logrus"
@@ -12,32 +14,289 @@ var _ builder.RoleBuilderInterface = (*RolesBuilder)(nil)
 
 // RolesBuilder handles AI-powered role operations (generation, refinement, consolidation)
 type RolesBuilder struct {
-	llmClient LLMClient
-	logger    *logrus.Logger
+	llmClient      LLMClient
+	logger         *logrus.Logger
+	repairAttempts int
 }
 
 // NewAIRolesBuilder creates a new AI roles builder
 func NewAIRolesBuilder(llmClient LLMClient, logger *logrus.Logger) *RolesBuilder {
 	return &RolesBuilder{
-		llmClient: llmClient,
-		logger:    logger,
+		llmClient:      llmClient,
+		logger:         logger,
+		repairAttempts: DefaultRepairAttempts,
 	}
 }
 
-// RefineRoles is the main dynamic method for all role operations
-// It analyzes the user message to determine what action to take and handles
-// role refinement, generation, consolidation, and enhancement
+// SetRepairAttempts sets how many times a response that does not match the
+// role schemas is sent back to the model for repair (0 disables repairs)
+func (r *RolesBuilder) SetRepairAttempts(attempts int) {
+	r.repairAttempts = max(attempts, 0)
+}
+
+// roleProperties describe the fields of a generated or consolidated role
+var roleProperties = map[string]*Schema{
+	"name":              stringSchema(),
+	"description":       stringSchema(),
+	"responsibilities":  stringArray,
+	"tags":              stringArray,
+	"autonomy_level":    stringSchema("L0", "L1", "L2", "L3", "L4"),
+	"capabilities":      stringArray,
+	"required_skills":   stringArray,
+	"token_budget":      integerSchema(0, 10000000),
+	"goal_keys":         stringArray,
+	"consolidated_from": stringArray,
+	"explanation":       stringSchema(),
+	"rationale":         stringSchema(),
+}
+
+// Schemas of the role builder responses
+var (
+	roleSchema = objectSchema(roleProperties, "name", "description")
+
+	rolesSchema = objectSchema(map[string]*Schema{
+		"roles":       arraySchema(roleSchema),
+		"explanation": stringSchema(),
+	}, "roles")
+
+	roleConsolidationSchema = objectSchema(map[string]*Schema{
+		"consolidated_roles": arraySchema(roleSchema),
+		"removed_roles":      stringArray,
+		"summary":            stringSchema(),
+		"explanation":        stringSchema(),
+	}, "consolidated_roles", "removed_roles")
+)
+
+// RefineRoles is the main dynamic method for all role operations. Several
+// target roles are consolidated; otherwise new roles are generated from the
+// user message, goals and work items.
 func (r *RolesBuilder) RefineRoles(ctx context.Context, req *builder.RefineRolesRequest, builderContext builder.BuilderContext) (*builder.RefineRolesResponse, error) {
 	r.logger.WithField("agency_id", req.AgencyID).Info("Starting dynamic role processing")
 
-	// For now, return a placeholder response
-	// TODO: Implement dynamic role processing following the pattern from goals_builder.go
-	response := &builder.RefineRolesResponse{
-		Action:         "under_construction",
-		Explanation:    "Role processing is under construction. This will analyze the user message to determine whether to refine existing roles, generate new roles, consolidate duplicate roles, or enhance all roles.",
-		NoActionNeeded: false,
+	if len(req.TargetRoles) > 1 {
+		consolidated, err := r.ConsolidateRoles(ctx, &builder.ConsolidateRolesRequest{
+			AgencyID:      req.AgencyID,
+			AgencyContext: req.AgencyContext,
+			CurrentRoles:  req.TargetRoles,
+			WorkItems:     req.WorkItems,
+		}, builderContext)
+		if err != nil {
+			return nil, err
+		}
+		return &builder.RefineRolesResponse{
+			Action:           "consolidate",
+			ConsolidatedData: consolidated,
+			Explanation:      consolidated.Explanation,
+			NoActionNeeded:   len(consolidated.ConsolidatedRoles) == 0 && len(consolidated.RemovedRoles) == 0,
+		}, nil
+	}
+
+	generated, err := r.GenerateRoles(ctx, &builder.GenerateRolesRequest{
+		AgencyID:      req.AgencyID,
+		AgencyContext: req.AgencyContext,
+		ExistingRoles: req.ExistingRoles,
+		WorkItems:     req.WorkItems,
+		Goals:         req.Goals,
+		UserInput:     req.UserMessage,
+	}, builderContext)
+	if err != nil {
+		return nil, err
+	}
+	return &builder.RefineRolesResponse{
+		Action:         "generate",
+		GeneratedRoles: generated.Roles,
+		Explanation:    generated.Explanation,
+		NoActionNeeded: len(generated.Roles) == 0,
+	}, nil
+}
+
+// GenerateRoles uses AI to propose the roles an agency needs for its goals and work items
+func (r *RolesBuilder) GenerateRoles(ctx context.Context, req *builder.GenerateRolesRequest, builderContext builder.BuilderContext) (*builder.GenerateRolesResponse, error) {
+	r.logger.WithField("agency_id", req.AgencyID).Info("Starting AI roles generation")
+
+	// Build the prompt for roles generation
+	prompt := r.buildRolesGenerationPrompt(req, builderContext)
+
+	// Make the LLM request
+	ctx = WithOperation(ctx, "roles.generate")
+	chatReq := &ChatRequest{
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: rolesGenerationSystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+	}
+
+	response, err := r.llmClient.Chat(ctx, chatReq)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get AI response for roles generation")
+		return nil, fmt.Errorf("AI generation failed: %w", err)
+	}
+
+	// Parse the AI response
+	var aiResponse builder.GenerateRolesResponse
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, rolesSchema, r.repairAttempts, &aiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response: %w", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"agency_id":   req.AgencyID,
+		"roles_count": len(aiResponse.Roles),
+	}).Info("AI roles generation completed")
+
+	return &aiResponse, nil
+}
+
+// ConsolidateRoles analyzes roles and merges those that overlap
+func (r *RolesBuilder) ConsolidateRoles(ctx context.Context, req *builder.ConsolidateRolesRequest, builderContext builder.BuilderContext) (*builder.ConsolidateRolesResponse, error) {
+	r.logger.WithFields(logrus.Fields{
+		"agency_id":   req.AgencyID,
+		"total_roles": len(req.CurrentRoles),
+	}).Info("Starting role consolidation")
+
+	// Build the prompt for role consolidation
+	prompt := r.buildRoleConsolidationPrompt(req, builderContext)
+
+	// Make the LLM request
+	ctx = WithOperation(ctx, "roles.consolidate")
+	chatReq := &ChatRequest{
+		Messages: []Message{
+			{
+				Role:    "system",
+				Content: roleConsolidationSystemPrompt,
+			},
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+	}
+
+	response, err := r.llmClient.Chat(ctx, chatReq)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get AI response for role consolidation")
+		return nil, fmt.Errorf("AI consolidation failed: %w", err)
+	}
+
+	// Parse the AI response
+	var consolidationResp builder.ConsolidateRolesResponse
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, roleConsolidationSchema, r.repairAttempts, &consolidationResp); err != nil {
+		return nil, fmt.Errorf("failed to parse consolidation response: %w", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"original_count":     len(req.CurrentRoles),
+		"consolidated_count": len(consolidationResp.ConsolidatedRoles),
+		"removed_count":      len(consolidationResp.RemovedRoles),
+	}).Info("Role consolidation completed")
+
+	return &consolidationResp, nil
+}
+
+// buildRolesGenerationPrompt creates a prompt for generating roles
+func (r *RolesBuilder) buildRolesGenerationPrompt(req *builder.GenerateRolesRequest, contextData builder.BuilderContext) string {
+	var builder strings.Builder
+
+	// Use the reusable agency context formatter
+	builder.WriteString(FormatAgencyContextBlock(contextData))
+
+	if req.UserInput != "" {
+		builder.WriteString(fmt.Sprintf("User request: %s\n\n", req.UserInput))
 	}
+	builder.WriteString("Please propose the roles this agency needs to deliver its work items and achieve its goals. ")
+	builder.WriteString("Do not repeat roles that already exist; only add roles that fill a gap.")
 
-	r.logger.Info("Dynamic role processing completed (placeholder)")
-	return response, nil
+	return builder.String()
 }
+
+// buildRoleConsolidationPrompt creates the prompt for role consolidation
+func (r *RolesBuilder) buildRoleConsolidationPrompt(req *builder.ConsolidateRolesRequest, contextData builder.BuilderContext) string {
+	var builder strings.Builder
+
+	// Use the reusable agency context formatter
+	builder.WriteString(FormatAgencyContextBlock(contextData))
+
+	builder.WriteString("Consolidate only these roles (by id): ")
+	keys := make([]string, 0, len(req.CurrentRoles))
+	for _, role := range req.CurrentRoles {
+		keys = append(keys, role.ID)
+	}
+	builder.WriteString(strings.Join(keys, ", "))
+	builder.WriteString("\n\nMerge roles whose responsibilities overlap and keep distinct roles separate.")
+
+	return builder.String()
+}
+
+// System prompts for role operations
+const rolesGenerationSystemPrompt = `You are an expert organizational designer helping to define the roles (agent personas) of an agency.
+
+Your task is to propose roles that:
+1. Cover the agency's work items and goals without gaps
+2. Have clear, non-overlapping responsibilities
+3. List the capabilities and skills an agent in the role needs
+4. Use an autonomy level suited to the risk of the work (L0 = fully supervised, L4 = fully autonomous)
+
+Return your response as a JSON object with this structure:
+{
+  "roles": [
+    {
+      "name": "Clear role name",
+      "description": "What this role does and why the agency needs it",
+      "responsibilities": ["Responsibility 1", "Responsibility 2"],
+      "tags": ["tag1", "tag2"],
+      "autonomy_level": "L0|L1|L2|L3|L4",
+      "capabilities": ["capability1", "capability2"],
+      "required_skills": ["skill1", "skill2"],
+      "token_budget": 100000,
+      "goal_keys": ["_key of each goal this role works towards"],
+      "explanation": "Why this role is needed"
+    }
+  ],
+  "explanation": "How these roles together cover the agency's work"
+}
+
+Prefer a small number of well-scoped roles over many narrow ones.`
+
+const roleConsolidationSystemPrompt = `Act as an experienced organizational designer. Your task is to analyze roles and determine if consolidation is beneficial.
+
+IMPORTANT: Only consolidate roles when it truly adds value. If roles are already distinct, keep them separate.
+
+1. **When consolidation IS beneficial**:
+   - Merge duplicate roles or roles with largely overlapping responsibilities
+   - Preserve every responsibility, capability and linked goal of the merged roles
+   - Use the lowest autonomy level of the merged roles unless there is a clear reason not to
+
+2. **When consolidation is NOT beneficial**:
+   - Return empty arrays for consolidated_roles and removed_roles
+   - Explain why the roles should stay separate
+
+3. **Track merges accurately**:
+   - Record the id of every merged role in "consolidated_from"
+   - List the id of every role to delete in "removed_roles"
+
+Respond ONLY with valid JSON (no markdown, no explanations outside JSON) in this format:
+{
+  "consolidated_roles": [
+    {
+      "name": "Clear role name",
+      "description": "What this role does",
+      "responsibilities": ["Responsibility 1", "Responsibility 2"],
+      "tags": ["tag1"],
+      "autonomy_level": "L0|L1|L2|L3|L4",
+      "capabilities": ["capability1"],
+      "required_skills": ["skill1"],
+      "token_budget": 100000,
+      "goal_keys": ["goal_key1"],
+      "consolidated_from": ["original_id1", "original_id2"],
+      "rationale": "Why these roles were merged"
+    }
+  ],
+  "removed_roles": ["original_id1", "original_id2"],
+  "summary": "Consolidated X roles into Y",
+  "explanation": "Overall consolidation strategy"
+}`
@@ -0,0 +1,49 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefineRoles_GeneratesRoles(t *testing.T) {
+	client := &scriptedLLMClient{responses: []string{
+		`{"roles": [{"name": "Billing Clerk", "description": "Issues invoices", "autonomy_level": "L5"}]}`,
+		`{"roles": [{"name": "Billing Clerk", "description": "Issues invoices", "autonomy_level": "L2", "responsibilities": ["Send invoices"], "goal_keys": ["g1"]}], "explanation": "Billing had no owner"}`,
+	}}
+	rolesBuilder := NewAIRolesBuilder(client, logrus.New())
+
+	result, err := rolesBuilder.RefineRoles(context.Background(), &builder.RefineRolesRequest{AgencyID: "a1", UserMessage: "Who sends invoices?"}, builder.BuilderContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "generate", result.Action)
+	require.Len(t, result.GeneratedRoles, 1)
+	assert.Equal(t, "L2", result.GeneratedRoles[0].AutonomyLevel)
+	assert.Equal(t, []string{"Send invoices"}, result.GeneratedRoles[0].Responsibilities)
+	assert.Equal(t, []string{"g1"}, result.GeneratedRoles[0].GoalKeys)
+
+	// The invalid autonomy level was sent back for repair
+	require.Len(t, client.requests, 2)
+	assert.Contains(t, client.requests[1].Messages[3].Content, `"L5" is not one of`)
+}
+
+func TestRefineRoles_ConsolidatesTargetRoles(t *testing.T) {
+	client := &scriptedLLMClient{responses: []string{
+		`{"consolidated_roles": [{"name": "Finance Officer", "description": "Owns billing and payments", "consolidated_from": ["billing", "payments"]}], "removed_roles": ["billing", "payments"], "explanation": "Same responsibilities"}`,
+	}}
+	rolesBuilder := NewAIRolesBuilder(client, logrus.New())
+
+	result, err := rolesBuilder.RefineRoles(context.Background(), &builder.RefineRolesRequest{
+		AgencyID:    "a1",
+		TargetRoles: []*registry.Role{{ID: "billing"}, {ID: "payments"}},
+	}, builder.BuilderContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "consolidate", result.Action)
+	require.NotNil(t, result.ConsolidatedData)
+	assert.Equal(t, []string{"billing", "payments"}, result.ConsolidatedData.RemovedRoles)
+	assert.Contains(t, client.requests[0].Messages[1].Content, "billing, payments")
+}
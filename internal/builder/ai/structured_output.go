@@ -0,0 +1,253 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRepairAttempts is how many times a builder asks the model to fix a
+// response that does not match its schema before giving up
+const DefaultRepairAttempts = 2
+
+// maxReportedProblems caps the validation problems sent back to the model
+const maxReportedProblems = 20
+
+// Schema is the subset of JSON Schema used to validate builder responses:
+// type, properties, required, items, enum, minimum and maximum
+type Schema struct {
+	Type       []string           `json:"type,omitempty"` // Allowed types; several make a value nullable, e.g. object and null
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+}
+
+// objectSchema describes an object with the given properties
+func objectSchema(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: []string{"object"}, Properties: properties, Required: required}
+}
+
+// arraySchema describes an optional array of items
+func arraySchema(items *Schema) *Schema {
+	return &Schema{Type: []string{"array", "null"}, Items: items}
+}
+
+// stringSchema describes a string, limited to enum when given
+func stringSchema(enum ...string) *Schema {
+	return &Schema{Type: []string{"string"}, Enum: enum}
+}
+
+// integerSchema describes an integer in [minimum, maximum]
+func integerSchema(minimum, maximum float64) *Schema {
+	return &Schema{Type: []string{"integer"}, Minimum: &minimum, Maximum: &maximum}
+}
+
+// booleanSchema describes a boolean
+func booleanSchema() *Schema {
+	return &Schema{Type: []string{"boolean"}}
+}
+
+// nullable allows a schema's value to be null
+func nullable(schema *Schema) *Schema {
+	copied := *schema
+	copied.Type = append(append([]string{}, schema.Type...), "null")
+	return &copied
+}
+
+// stringArray is an optional list of strings
+var stringArray = arraySchema(stringSchema())
+
+// Validate returns the problems that keep value, decoded with UseNumber,
+// from matching the schema. Each problem starts with the path of the value.
+func (s *Schema) Validate(value interface{}) []string {
+	var problems []string
+	s.validate("$", value, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, value interface{}, problems *[]string) {
+	if len(s.Type) > 0 && !s.allows(value) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), jsonType(value)))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(path+"."+name, v[name], problems)
+			}
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+
+	case string:
+		if len(s.Enum) > 0 {
+			for _, allowed := range s.Enum {
+				if v == allowed {
+					return
+				}
+			}
+			*problems = append(*problems, fmt.Sprintf("%s: %q is not one of %s", path, v, strings.Join(s.Enum, ", ")))
+		}
+
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			*problems = append(*problems, fmt.Sprintf("%s: %s is less than the minimum %g", path, v, *s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			*problems = append(*problems, fmt.Sprintf("%s: %s is greater than the maximum %g", path, v, *s.Maximum))
+		}
+	}
+}
+
+// allows reports whether the value has one of the schema's types
+func (s *Schema) allows(value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range s.Type {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a value decoded with UseNumber
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// SchemaError is returned when a model's response still does not match the
+// builder's schema after the repair attempts
+type SchemaError struct {
+	Problems []string
+	Attempts int // Responses received, including repairs
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("response did not match the expected schema after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// checkJSON validates content, which may be wrapped in markdown fences,
+// against the schema and decodes it into out
+func checkJSON(content string, schema *Schema, out interface{}) []string {
+	cleaned := []byte(stripMarkdownFences(content))
+
+	decoder := json.NewDecoder(bytes.NewReader(cleaned))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []string{"response is not valid JSON: " + err.Error()}
+	}
+
+	if problems := schema.Validate(value); len(problems) > 0 {
+		return problems
+	}
+	if err := json.Unmarshal(cleaned, out); err != nil {
+		return []string{"response could not be decoded: " + err.Error()}
+	}
+	return nil
+}
+
+// decodeResponse decodes the model's response to req into out once it
+// matches the schema. A response that does not is sent back to the model with
+// the problems found, up to repairAttempts times.
+func decodeResponse(ctx context.Context, client LLMClient, logger *logrus.Logger, req *ChatRequest, resp *ChatResponse, schema *Schema, repairAttempts int, out interface{}) error {
+	messages := append([]Message{}, req.Messages...)
+
+	for attempt := 1; ; attempt++ {
+		problems := checkJSON(resp.Content, schema, out)
+		if len(problems) == 0 {
+			resp.RecordParseOutcome(nil)
+			return nil
+		}
+
+		schemaErr := &SchemaError{Problems: problems, Attempts: attempt}
+		resp.RecordParseOutcome(schemaErr)
+		if attempt > repairAttempts {
+			logger.WithError(schemaErr).WithField("response", resp.Content).Error("LLM response did not match the expected schema")
+			return schemaErr
+		}
+
+		logger.WithFields(logrus.Fields{
+			"operation": OperationFromContext(ctx),
+			"attempt":   attempt,
+			"problems":  len(problems),
+		}).Warn("LLM response did not match the expected schema, asking for a repair")
+
+		messages = append(messages,
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: repairPrompt(problems, schema)},
+		)
+		repairReq := *req
+		repairReq.Messages = messages
+		repairReq.Stream = false
+
+		var err error
+		resp, err = client.Chat(ctx, &repairReq)
+		if err != nil {
+			return fmt.Errorf("repair request failed: %w", err)
+		}
+	}
+}
+
+// repairPrompt asks the model to answer again without the problems found
+func repairPrompt(problems []string, schema *Schema) string {
+	if len(problems) > maxReportedProblems {
+		problems = append(problems[:maxReportedProblems:maxReportedProblems], fmt.Sprintf("... and %d more", len(problems)-maxReportedProblems))
+	}
+	schemaJSON, _ := json.MarshalIndent(schema, "", "  ")
+
+	var prompt strings.Builder
+	prompt.WriteString("Your previous response could not be used because it does not match the required JSON schema:\n")
+	for _, problem := range problems {
+		prompt.WriteString("- " + problem + "\n")
+	}
+	prompt.WriteString("\nRespond again with only the corrected JSON object, with no markdown or explanation outside it. It must match this schema:\n")
+	prompt.Write(schemaJSON)
+	return prompt.String()
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedLLMClient answers calls with its responses in order and keeps the requests
+type scriptedLLMClient struct {
+	stubLLMClient
+	responses []string
+	requests  []*ChatRequest
+}
+
+func (s *scriptedLLMClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	content := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return &ChatResponse{Content: content}, nil
+}
+
+func TestSchema_Validate(t *testing.T) {
+	var problems []string
+	for _, content := range []string{
+		`{"work_items": [{"title": "Billing", "description": "Invoices", "suggested_effort": 5}]}`,
+		`{"work_items": null}`,
+	} {
+		problems = checkJSON(content, workItemsSchema, &builder.GenerateWorkItemsResponse{})
+		assert.Empty(t, problems, content)
+	}
+
+	problems = checkJSON(`{"work_items": [{"title": 7, "suggested_effort": 21}], "explanation": ["x"]}`, workItemsSchema, &builder.GenerateWorkItemsResponse{})
+	assert.Equal(t, []string{
+		`$.explanation: expected string, got array`,
+		`$.work_items[0]: missing required property "description"`,
+		`$.work_items[0].suggested_effort: 21 is greater than the maximum 13`,
+		`$.work_items[0].title: expected string, got integer`,
+	}, problems)
+
+	problems = checkJSON(`{"action": "delete_everything", "explanation": "x"}`, dynamicGoalsSchema, &builder.RefineGoalsResponse{})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], `"delete_everything" is not one of`)
+
+	problems = checkJSON("not json", dynamicGoalsSchema, &builder.RefineGoalsResponse{})
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "not valid JSON")
+}
+
+func TestRefineGoals_RepairsInvalidResponse(t *testing.T) {
+	client := &scriptedLLMClient{responses: []string{
+		`{"action": "generate", "generated_goals": [{"scope": "billing"}]}`,
+		`{"action": "generate", "generated_goals": [{"description": "Automate billing"}], "explanation": "Billing was missing"}`,
+	}}
+	goalsBuilder := NewGoalRefiner(client, logrus.New())
+
+	result, err := goalsBuilder.RefineGoals(context.Background(), &builder.RefineGoalsRequest{AgencyID: "a1"}, builder.BuilderContext{})
+	require.NoError(t, err)
+	require.Len(t, result.GeneratedGoals, 1)
+	assert.Equal(t, "Automate billing", result.GeneratedGoals[0].Description)
+
+	// The repair request continues the conversation with the problems found
+	require.Len(t, client.requests, 2)
+	repair := client.requests[1].Messages
+	require.Len(t, repair, 4)
+	assert.Equal(t, "assistant", repair[2].Role)
+	assert.Contains(t, repair[3].Content, `$: missing required property "explanation"`)
+	assert.Contains(t, repair[3].Content, `$.generated_goals[0]: missing required property "description"`)
+}
+
+func TestRefineGoals_GivesUpAfterRepairAttempts(t *testing.T) {
+	client := &scriptedLLMClient{responses: []string{"not json"}}
+	goalsBuilder := NewGoalRefiner(client, logrus.New())
+	goalsBuilder.SetRepairAttempts(1)
+
+	_, err := goalsBuilder.RefineGoals(context.Background(), &builder.RefineGoalsRequest{AgencyID: "a1"}, builder.BuilderContext{})
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, 2, schemaErr.Attempts)
+	assert.Len(t, client.requests, 2)
+}
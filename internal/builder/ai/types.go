@@ -15,6 +15,7 @@ const (
 	ProviderClaude Provider = "claude"
 	ProviderLocal  Provider = "local"
 	ProviderCustom Provider = "custom"
+	ProviderAzure  Provider = "azure" // Azure OpenAI; Model is the deployment name
 )
 
 // Message is an alias to builder.Message to avoid circular dependencies
@@ -37,6 +38,9 @@ type ChatResponse struct {
 	Usage        *TokenUsage            `json:"usage,omitempty"`
 	Model        string                 `json:"model,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	CaptureID    string                 `json:"capture_id,omitempty"` // Set when the call was captured
+
+	captures *CaptureStore
 }
 
 // TokenUsage tracks token consumption
@@ -73,12 +77,14 @@ type LLMConfig struct {
 	Temperature float32  `json:"temperature,omitempty"` // Default temperature
 	MaxTokens   int      `json:"max_tokens,omitempty"`  // Default max tokens
 	Timeout     int      `json:"timeout,omitempty"`     // Request timeout in seconds
+	APIVersion  string   `json:"api_version,omitempty"` // Azure OpenAI API version
 }
 
 // ConversationContext holds the state of an ongoing conversation
 type ConversationContext struct {
 	ID            string                 `json:"id"`
 	AgencyID      string                 `json:"agency_id"`
+	Name          string                 `json:"name,omitempty"` // Tells an agency's conversations apart
 	Phase         DesignPhase            `json:"phase"`
 	Messages      []Message              `json:"messages"`
 	State         map[string]interface{} `json:"state"` // Extracted information
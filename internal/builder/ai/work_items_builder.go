@@ -2,7 +2,6 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -15,18 +14,71 @@ var _ builder.WorkItemBuilderInterface = (*WorkItemsBuilder)(nil)
 
 // WorkItemsBuilder handles AI-powered work item definition and refinement
 type WorkItemsBuilder struct {
-	llmClient LLMClient
-	logger    *logrus.Logger
+	llmClient      LLMClient
+	logger         *logrus.Logger
+	repairAttempts int
 }
 
 // NewAIWorkItemsBuilder creates a new AI-powered work item builder
 func NewAIWorkItemsBuilder(llmClient LLMClient, logger *logrus.Logger) *WorkItemsBuilder {
 	return &WorkItemsBuilder{
-		llmClient: llmClient,
-		logger:    logger,
+		llmClient:      llmClient,
+		logger:         logger,
+		repairAttempts: DefaultRepairAttempts,
 	}
 }
 
+// SetRepairAttempts sets how many times a response that does not match the
+// work item schemas is sent back to the model for repair (0 disables repairs)
+func (w *WorkItemsBuilder) SetRepairAttempts(attempts int) {
+	w.repairAttempts = max(attempts, 0)
+}
+
+// workItemProperties describe the fields of a generated or consolidated work item
+var workItemProperties = map[string]*Schema{
+	"title":              stringSchema(),
+	"description":        stringSchema(),
+	"deliverables":       stringArray,
+	"suggested_code":     stringSchema(),
+	"suggested_type":     stringSchema(),
+	"suggested_priority": stringSchema(),
+	"suggested_effort":   integerSchema(1, 13),
+	"suggested_tags":     stringArray,
+	"goal_keys":          stringArray,
+	"consolidated_from":  stringArray,
+	"explanation":        stringSchema(),
+	"rationale":          stringSchema(),
+}
+
+// Schemas of the work item builder responses
+var (
+	workItemRefinementSchema = objectSchema(map[string]*Schema{
+		"refined_title":        stringSchema(),
+		"refined_description":  stringSchema(),
+		"refined_deliverables": stringArray,
+		"suggested_type":       stringSchema(),
+		"suggested_priority":   stringSchema(),
+		"suggested_effort":     integerSchema(1, 13),
+		"suggested_tags":       stringArray,
+		"explanation":          stringSchema(),
+		"changed":              booleanSchema(),
+	}, "changed")
+
+	workItemSchema = objectSchema(workItemProperties, "title", "description")
+
+	workItemsSchema = objectSchema(map[string]*Schema{
+		"work_items":  arraySchema(workItemSchema),
+		"explanation": stringSchema(),
+	}, "work_items")
+
+	workItemConsolidationSchema = objectSchema(map[string]*Schema{
+		"consolidated_work_items": arraySchema(workItemSchema),
+		"removed_work_items":      stringArray,
+		"summary":                 stringSchema(),
+		"explanation":             stringSchema(),
+	}, "consolidated_work_items", "removed_work_items")
+)
+
 // RefineWorkItems is the main dynamic method for all work item operations
 // It analyzes the user message to determine what action to take and handles
 // work item refinement, generation, consolidation, and enhancement
@@ -66,7 +118,8 @@ func (r *WorkItemsBuilder) RefineWorkItem(ctx context.Context, req *builder.Refi
 	prompt := r.buildWorkItemRefinementPrompt(req, builderContext)
 
 	// Make the LLM request
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	ctx = WithOperation(ctx, "work_items.refine")
+	chatReq := &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -77,18 +130,17 @@ func (r *WorkItemsBuilder) RefineWorkItem(ctx context.Context, req *builder.Refi
 				Content: prompt,
 			},
 		},
-	})
+	}
 
+	response, err := r.llmClient.Chat(ctx, chatReq)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get AI response for work item refinement")
 		return nil, fmt.Errorf("AI refinement failed: %w", err)
 	}
 
 	// Parse the AI response
-	cleanedContent := stripMarkdownFences(response.Content)
 	var aiResponse aiWorkItemRefinementResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &aiResponse); err != nil {
-		r.logger.WithError(err).WithField("response", response.Content).Error("Failed to parse AI response")
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, workItemRefinementSchema, r.repairAttempts, &aiResponse); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
@@ -124,7 +176,8 @@ func (r *WorkItemsBuilder) GenerateWorkItem(ctx context.Context, req *builder.Ge
 	prompt := r.buildWorkItemGenerationPrompt(req, builderContext)
 
 	// Make the LLM request
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	ctx = WithOperation(ctx, "work_items.generate")
+	chatReq := &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -135,18 +188,17 @@ func (r *WorkItemsBuilder) GenerateWorkItem(ctx context.Context, req *builder.Ge
 				Content: prompt,
 			},
 		},
-	})
+	}
 
+	response, err := r.llmClient.Chat(ctx, chatReq)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get AI response for work item generation")
 		return nil, fmt.Errorf("AI generation failed: %w", err)
 	}
 
 	// Parse the AI response
-	cleanedContent := stripMarkdownFences(response.Content)
 	var aiResponse builder.GenerateWorkItemResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &aiResponse); err != nil {
-		r.logger.WithError(err).WithField("response", response.Content).Error("Failed to parse AI response")
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, workItemSchema, r.repairAttempts, &aiResponse); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
@@ -169,7 +221,8 @@ func (r *WorkItemsBuilder) GenerateWorkItems(ctx context.Context, req *builder.G
 	prompt := r.buildWorkItemsGenerationPrompt(req, builderContext)
 
 	// Make the LLM request
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	ctx = WithOperation(ctx, "work_items.generate_many")
+	chatReq := &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -180,18 +233,17 @@ func (r *WorkItemsBuilder) GenerateWorkItems(ctx context.Context, req *builder.G
 				Content: prompt,
 			},
 		},
-	})
+	}
 
+	response, err := r.llmClient.Chat(ctx, chatReq)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get AI response for work items generation")
 		return nil, fmt.Errorf("AI generation failed: %w", err)
 	}
 
 	// Parse the AI response
-	cleanedContent := stripMarkdownFences(response.Content)
 	var aiResponse builder.GenerateWorkItemsResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &aiResponse); err != nil {
-		r.logger.WithError(err).WithField("response", response.Content).Error("Failed to parse AI response")
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, workItemsSchema, r.repairAttempts, &aiResponse); err != nil {
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
@@ -250,7 +302,8 @@ func (r *WorkItemsBuilder) ConsolidateWorkItems(ctx context.Context, req *builde
 	prompt := r.buildWorkItemConsolidationPrompt(req, builderContext)
 
 	// Make the LLM request
-	response, err := r.llmClient.Chat(ctx, &ChatRequest{
+	ctx = WithOperation(ctx, "work_items.consolidate")
+	chatReq := &ChatRequest{
 		Messages: []Message{
 			{
 				Role:    "system",
@@ -261,19 +314,17 @@ func (r *WorkItemsBuilder) ConsolidateWorkItems(ctx context.Context, req *builde
 				Content: prompt,
 			},
 		},
-	})
+	}
 
+	response, err := r.llmClient.Chat(ctx, chatReq)
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get AI response for work item consolidation")
 		return nil, fmt.Errorf("AI consolidation failed: %w", err)
 	}
 
 	// Parse the AI response
-	cleanedContent := stripMarkdownFences(response.Content)
-
 	var consolidationResp builder.ConsolidateWorkItemsResponse
-	if err := json.Unmarshal([]byte(cleanedContent), &consolidationResp); err != nil {
-		r.logger.WithError(err).WithField("response", cleanedContent).Error("Failed to parse work item consolidation response")
+	if err := decodeResponse(ctx, r.llmClient, r.logger, chatReq, response, workItemConsolidationSchema, r.repairAttempts, &consolidationResp); err != nil {
 		return nil, fmt.Errorf("failed to parse consolidation response: %w", err)
 	}
 
@@ -346,6 +397,7 @@ Return your response as a JSON object with this structure:
   "suggested_priority": "P0|P1|P2|P3",
   "suggested_effort": 1-13,
   "suggested_tags": ["tag1", "tag2"],
+  "goal_keys": ["_key of each goal this work item contributes to"],
   "explanation": "Brief explanation of the work item"
 }
 
@@ -373,6 +425,7 @@ Return your response as a JSON object with this structure:
       "suggested_priority": "P0|P1|P2|P3",
       "suggested_effort": 1-13,
       "suggested_tags": ["tag1", "tag2"],
+      "goal_keys": ["_key of each goal this work item contributes to"],
       "explanation": "How this contributes to goals"
     }
   ],
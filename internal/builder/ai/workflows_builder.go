@@ -77,7 +77,7 @@ Create ONLY 1 simple workflow that makes sense for this agency. Keep it minimal
 - Focus on the single most important workflow for this agency
 - Return a JSON array with ONLY 1 workflow object`
 
-	response, err := b.llmClient.Chat(ctx, &ChatRequest{
+	response, err := b.llmClient.Chat(WithOperation(ctx, "workflows.generate_from_context"), &ChatRequest{
 		Messages: []Message{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: prompt},
@@ -91,6 +91,7 @@ Create ONLY 1 simple workflow that makes sense for this agency. Keep it minimal
 
 	// Parse response
 	workflows, err := b.parseWorkflowsResponse(response.Content)
+	response.RecordParseOutcome(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse workflows: %w", err)
 	}
@@ -123,7 +124,7 @@ Ensure the workflow:
 - Has proper sequential or conditional connections
 - Positions nodes for left-to-right flow`
 
-	response, err := b.llmClient.Chat(ctx, &ChatRequest{
+	response, err := b.llmClient.Chat(WithOperation(ctx, "workflows.generate_from_prompt"), &ChatRequest{
 		Messages: []Message{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: prompt},
@@ -137,6 +138,7 @@ Ensure the workflow:
 
 	// Parse single workflow
 	wf, err := b.parseSingleWorkflowResponse(response.Content)
+	response.RecordParseOutcome(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse workflow: %w", err)
 	}
@@ -164,7 +166,7 @@ Modify the workflow according to the user's request while maintaining valid stru
 Return ONLY the modified workflow as valid JSON (not an array).
 Preserve the workflow structure and ensure all connections remain valid.`
 
-	response, err := b.llmClient.Chat(ctx, &ChatRequest{
+	response, err := b.llmClient.Chat(WithOperation(ctx, "workflows.refine"), &ChatRequest{
 		Messages: []Message{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: prompt},
@@ -177,6 +179,7 @@ Preserve the workflow structure and ensure all connections remain valid.`
 	}
 
 	refined, err := b.parseSingleWorkflowResponse(response.Content)
+	response.RecordParseOutcome(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse refined workflow: %w", err)
 	}
@@ -297,7 +300,7 @@ Focus on: efficiency, error handling, parallel execution opportunities, better b
 	systemPrompt := `You are a workflow optimization expert. Analyze workflows and suggest concrete improvements.
 Return ONLY a JSON array of suggestion strings. Example: ["Add error handling after step X", "Parallelize tasks Y and Z"]`
 
-	response, err := b.llmClient.Chat(ctx, &ChatRequest{
+	response, err := b.llmClient.Chat(WithOperation(ctx, "workflows.suggest_improvements"), &ChatRequest{
 		Messages: []Message{
 			{Role: "system", Content: systemPrompt},
 			{Role: "user", Content: prompt},
@@ -312,7 +315,9 @@ Return ONLY a JSON array of suggestion strings. Example: ["Add error handling af
 	cleaned := b.cleanJSONResponse(response.Content)
 
 	var suggestions []string
-	if err := json.Unmarshal([]byte(cleaned), &suggestions); err != nil {
+	err = json.Unmarshal([]byte(cleaned), &suggestions)
+	response.RecordParseOutcome(err)
+	if err != nil {
 		b.logger.WithError(err).Error("Failed to parse suggestions")
 		return nil, fmt.Errorf("invalid suggestions response: %w", err)
 	}
@@ -9,10 +9,17 @@ import (
 
 // Message represents a chat message (shared type for AI interactions)
 type Message struct {
+	// ID identifies a message within its conversation. Messages added through
+	// the outbox use the entry's ID so redelivery is ignored.
+	ID string `json:"id,omitempty"`
+
 	Role      string    `json:"role"`           // "system", "user", "assistant"
 	Content   string    `json:"content"`        // Message content
 	Name      string    `json:"name,omitempty"` // Optional speaker name
 	Timestamp time.Time `json:"timestamp"`      // When message was created
+
+	// RenderedHTML is the sanitized HTML rendering of Content for chat display
+	RenderedHTML string `json:"rendered_html,omitempty"`
 }
 
 // BuilderContext is a shared context structure used when building prompts for AI calls.
@@ -31,4 +38,7 @@ type BuilderContext struct {
 	Roles        []*registry.Role         `json:"roles,omitempty"`
 	Assignments  []*agency.RACIAssignment `json:"assignments,omitempty"`
 	UserInput    string                   `json:"user_input,omitempty"`
+
+	// Infrastructure is an optional summary of the live asset inventory
+	Infrastructure *InfrastructureContext `json:"infrastructure,omitempty"`
 }
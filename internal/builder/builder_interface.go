@@ -30,9 +30,10 @@ type IntroductionBuilderInterface interface {
 	RefineIntroduction(ctx context.Context, req *RefineIntroductionRequest, builderContext BuilderContext) (*RefineIntroductionResponse, error)
 }
 
-// GoalBuilderInterface defines the contract for all goal-related AI operations (refinement, generation, consolidation)
+// GoalBuilderInterface defines the contract for all goal-related AI operations (refinement, generation, consolidation, ranking)
 type GoalBuilderInterface interface {
 	RefineGoals(ctx context.Context, req *RefineGoalsRequest, builderContext BuilderContext) (*RefineGoalsResponse, error)
+	RankGoals(ctx context.Context, req *RankGoalsRequest, builderContext BuilderContext) (*RankGoalsResponse, error)
 }
 
 // WorkItemBuilderInterface defines the contract for all work item-related AI operations (refinement, generation, consolidation)
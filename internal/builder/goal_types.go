@@ -117,3 +117,23 @@ type RefinedGoalResult struct {
 	WasChanged         bool     `json:"was_changed"`
 	Explanation        string   `json:"explanation"`
 }
+
+// RankGoalsRequest asks for a recommended priority ordering of all goals in an agency
+type RankGoalsRequest struct {
+	AgencyID string `json:"agency_id"`
+}
+
+// RankGoalsResponse contains the recommended goal ordering, highest priority first
+type RankGoalsResponse struct {
+	Rankings    []GoalRanking `json:"rankings"`
+	Explanation string        `json:"explanation"` // How the ordering was decided
+}
+
+// GoalRanking is the recommended rank and priority of a single goal
+type GoalRanking struct {
+	GoalKey   string `json:"goal_key"`
+	Code      string `json:"code"`
+	Rank      int    `json:"rank"`     // 1 is the highest priority
+	Priority  string `json:"priority"` // High, Medium, Low
+	Rationale string `json:"rationale"`
+}
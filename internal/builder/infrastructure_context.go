@@ -0,0 +1,241 @@
+package builder
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxSampleAssetIDs caps the number of example IDs listed per asset type
+const maxSampleAssetIDs = 5
+
+// defaultKPIWindow is how far back KPI readings are taken from by default
+const defaultKPIWindow = time.Hour
+
+// InfrastructureSource provides an infrastructure summary for prompt context
+type InfrastructureSource interface {
+	InfrastructureContext(ctx context.Context) (*InfrastructureContext, error)
+}
+
+// AgentLister lists the registered agents
+type AgentLister interface {
+	ListAgents() []*agent.Agent
+}
+
+// AgentStatusReader reads the current operational status of an agent
+type AgentStatusReader interface {
+	CurrentStatus(ctx context.Context, agentID string) (health.OperationalStatus, error)
+}
+
+// TelemetryReader reads the latest reading of every agent and metric.
+// telemetry.Service implements it.
+type TelemetryReader interface {
+	Latest(ctx context.Context, since time.Time) ([]*telemetry.Point, error)
+}
+
+// RuntimeInfrastructureSource summarises the agents known to the runtime.
+// Agent metadata is used for zones ("zone" key); KPIs are aggregated from the
+// agents' latest telemetry readings.
+type RuntimeInfrastructureSource struct {
+	agents    AgentLister
+	statuses  AgentStatusReader
+	telemetry TelemetryReader
+	kpiWindow time.Duration
+}
+
+// NewRuntimeInfrastructureSource creates an infrastructure source backed by the runtime.
+// statuses is optional; when nil no status counts are included.
+func NewRuntimeInfrastructureSource(agents AgentLister, statuses AgentStatusReader) *RuntimeInfrastructureSource {
+	return &RuntimeInfrastructureSource{
+		agents:    agents,
+		statuses:  statuses,
+		kpiWindow: defaultKPIWindow,
+	}
+}
+
+// SetTelemetry sets the store KPIs are read from. Only readings from the last
+// window count; a zero window uses one hour. Without a store every asset type
+// is reported as having no KPIs.
+func (s *RuntimeInfrastructureSource) SetTelemetry(reader TelemetryReader, window time.Duration) {
+	s.telemetry = reader
+	if window > 0 {
+		s.kpiWindow = window
+	}
+}
+
+// InfrastructureContext builds the summary of the agents ctx's tenant may see.
+// It returns nil when there are none so that prompts for non-infrastructure
+// agencies are left untouched.
+func (s *RuntimeInfrastructureSource) InfrastructureContext(ctx context.Context) (*InfrastructureContext, error) {
+	var agents []*agent.Agent
+	for _, a := range s.agents.ListAgents() {
+		if tenant.AllowsID(ctx, a.ID) {
+			agents = append(agents, a)
+		}
+	}
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	typeCounts := make(map[string]*AssetTypeSummary)
+	zones := make(map[string]*ZoneSummary)
+	statusCounts := make(map[string]int)
+
+	for _, a := range agents {
+		summary, ok := typeCounts[a.Type]
+		if !ok {
+			summary = &AssetTypeSummary{Type: a.Type, SampleIDs: []string{}}
+			typeCounts[a.Type] = summary
+		}
+		summary.Count++
+		if len(summary.SampleIDs) < maxSampleAssetIDs {
+			summary.SampleIDs = append(summary.SampleIDs, a.ID)
+		}
+
+		if zone := a.Metadata["zone"]; zone != "" {
+			z, ok := zones[zone]
+			if !ok {
+				z = &ZoneSummary{Zone: zone, AssetTypes: make(map[string]int)}
+				zones[zone] = z
+			}
+			z.AssetCount++
+			z.AssetTypes[a.Type]++
+		}
+
+		if s.statuses != nil {
+			if status, err := s.statuses.CurrentStatus(ctx, a.ID); err == nil && status != "" {
+				statusCounts[string(status)]++
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	result := &InfrastructureContext{
+		TotalAssets:      len(agents),
+		AssetTypes:       make([]AssetTypeSummary, 0, len(typeCounts)),
+		KPIWindowSeconds: int64(s.kpiWindow / time.Second),
+		GeneratedAt:      now,
+	}
+
+	for _, summary := range typeCounts {
+		result.AssetTypes = append(result.AssetTypes, *summary)
+	}
+	sort.Slice(result.AssetTypes, func(i, j int) bool {
+		return result.AssetTypes[i].Type < result.AssetTypes[j].Type
+	})
+
+	for _, z := range zones {
+		result.Zones = append(result.Zones, *z)
+	}
+	sort.Slice(result.Zones, func(i, j int) bool {
+		return result.Zones[i].Zone < result.Zones[j].Zone
+	})
+
+	result.KPIs, result.KPIsUnavailable = s.kpis(ctx, agents, now)
+
+	if len(statusCounts) > 0 {
+		result.StatusCounts = statusCounts
+	}
+
+	return result, nil
+}
+
+// kpis aggregates the latest reading of each asset within the KPI window per
+// asset type and metric. Assets of the type without a reading of the metric
+// are counted as missing, and asset types without any reading are returned
+// as unavailable.
+func (s *RuntimeInfrastructureSource) kpis(ctx context.Context, agents []*agent.Agent, now time.Time) ([]KPIAggregate, []string) {
+	byType := make(map[string][]string) // type -> asset IDs
+	types := make(map[string]string)    // asset ID -> type
+	for _, a := range agents {
+		byType[a.Type] = append(byType[a.Type], a.ID)
+		types[a.ID] = a.Type
+	}
+
+	var points []*telemetry.Point
+	if s.telemetry != nil {
+		var err error
+		points, err = s.telemetry.Latest(ctx, now.Add(-s.kpiWindow))
+		if err != nil {
+			// The summary is still useful without KPIs, which are reported as unavailable
+			log.WithError(err).Warn("Failed to read telemetry for infrastructure KPIs")
+			points = nil
+		}
+	}
+
+	readings := make(map[string]map[string][]*telemetry.Point) // type -> metric -> points
+	for _, p := range points {
+		assetType, ok := types[p.AgentID]
+		if !ok || !tenant.AllowsID(ctx, p.AgentID) {
+			continue
+		}
+		if readings[assetType] == nil {
+			readings[assetType] = make(map[string][]*telemetry.Point)
+		}
+		readings[assetType][p.Metric] = append(readings[assetType][p.Metric], p)
+	}
+
+	var kpis []KPIAggregate
+	var unavailable []string
+	for assetType, ids := range byType {
+		if len(readings[assetType]) == 0 {
+			unavailable = append(unavailable, assetType)
+			continue
+		}
+		for metric, latest := range readings[assetType] {
+			kpis = append(kpis, aggregateKPI(assetType, metric, ids, latest))
+		}
+	}
+	sort.Slice(kpis, func(i, j int) bool {
+		if kpis[i].AssetType != kpis[j].AssetType {
+			return kpis[i].AssetType < kpis[j].AssetType
+		}
+		return kpis[i].Metric < kpis[j].Metric
+	})
+	sort.Strings(unavailable)
+
+	return kpis, unavailable
+}
+
+// aggregateKPI computes average, min and max of the latest readings of the
+// assets of a type, and which of its assets have no reading
+func aggregateKPI(assetType, metric string, assetIDs []string, latest []*telemetry.Point) KPIAggregate {
+	kpi := KPIAggregate{
+		AssetType: assetType,
+		Metric:    metric,
+		Min:       latest[0].Value,
+		Max:       latest[0].Value,
+		Samples:   len(latest),
+	}
+
+	var sum float64
+	reported := make(map[string]bool, len(latest))
+	for _, p := range latest {
+		reported[p.AgentID] = true
+		sum += p.Value
+		kpi.Min = min(kpi.Min, p.Value)
+		kpi.Max = max(kpi.Max, p.Value)
+		if p.Timestamp.After(kpi.LastReportedAt) {
+			kpi.LastReportedAt = p.Timestamp
+		}
+	}
+	kpi.Average = sum / float64(len(latest))
+
+	for _, id := range assetIDs {
+		if reported[id] {
+			continue
+		}
+		kpi.Missing++
+		if len(kpi.MissingIDs) < maxSampleAssetIDs {
+			kpi.MissingIDs = append(kpi.MissingIDs, id)
+		}
+	}
+
+	return kpi
+}
@@ -0,0 +1,139 @@
+package builder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticAgentLister []*agent.Agent
+
+func (l staticAgentLister) ListAgents() []*agent.Agent { return l }
+
+type staticStatusReader map[string]health.OperationalStatus
+
+func (r staticStatusReader) CurrentStatus(ctx context.Context, agentID string) (health.OperationalStatus, error) {
+	return r[agentID], nil
+}
+
+// staticTelemetry returns its points that were reported since the time
+type staticTelemetry []*telemetry.Point
+
+func (r staticTelemetry) Latest(ctx context.Context, since time.Time) ([]*telemetry.Point, error) {
+	var points []*telemetry.Point
+	for _, p := range r {
+		if !p.Timestamp.Before(since) {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
+
+func newTestAsset(id, assetType string, metadata map[string]string) *agent.Agent {
+	return &agent.Agent{ID: id, Type: assetType, Metadata: metadata}
+}
+
+func TestRuntimeInfrastructureSource_Summarises(t *testing.T) {
+	agents := staticAgentLister{
+		newTestAsset("PUMP-001", "pump", map[string]string{"zone": "north", "efficiency_percent": "80", "latitude": "-1.2"}),
+		newTestAsset("PUMP-002", "pump", map[string]string{"zone": "south", "efficiency_percent": "90"}),
+		newTestAsset("VALVE-001", "valve", map[string]string{"zone": "north"}),
+		newTestAsset("COORD-NORTH", "zone_coordinator", nil),
+	}
+	statuses := staticStatusReader{"PUMP-001": health.OperationalStatusWatch}
+	now := time.Now().UTC()
+	readings := staticTelemetry{
+		{AgentID: "PUMP-001", Metric: "efficiency_percent", Value: 80, Timestamp: now.Add(-10 * time.Minute)},
+		{AgentID: "PUMP-002", Metric: "efficiency_percent", Value: 90, Timestamp: now.Add(-5 * time.Minute)},
+		{AgentID: "PUMP-002", Metric: "pressure_bar", Value: 4.2, Timestamp: now.Add(-5 * time.Minute)},
+		{AgentID: "VALVE-001", Metric: "position_percent", Value: 100, Timestamp: now.Add(-2 * time.Hour)},
+	}
+
+	source := NewRuntimeInfrastructureSource(agents, statuses)
+	source.SetTelemetry(readings, 0)
+	infra, err := source.InfrastructureContext(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, infra)
+
+	assert.Equal(t, 4, infra.TotalAssets)
+	require.Len(t, infra.AssetTypes, 3)
+	assert.Equal(t, "pump", infra.AssetTypes[0].Type)
+	assert.Equal(t, 2, infra.AssetTypes[0].Count)
+	assert.Equal(t, []string{"PUMP-001", "PUMP-002"}, infra.AssetTypes[0].SampleIDs)
+
+	require.Len(t, infra.Zones, 2)
+	assert.Equal(t, "north", infra.Zones[0].Zone)
+	assert.Equal(t, 2, infra.Zones[0].AssetCount)
+
+	// Metadata values are not KPIs; telemetry older than the window is ignored
+	require.Len(t, infra.KPIs, 2)
+	assert.Equal(t, "efficiency_percent", infra.KPIs[0].Metric)
+	assert.Equal(t, 85.0, infra.KPIs[0].Average)
+	assert.Equal(t, 80.0, infra.KPIs[0].Min)
+	assert.Equal(t, 90.0, infra.KPIs[0].Max)
+	assert.Equal(t, 2, infra.KPIs[0].Samples)
+	assert.Zero(t, infra.KPIs[0].Missing)
+	assert.Equal(t, "pressure_bar", infra.KPIs[1].Metric)
+	assert.Equal(t, 1, infra.KPIs[1].Missing)
+	assert.Equal(t, []string{"PUMP-001"}, infra.KPIs[1].MissingIDs)
+	assert.Equal(t, []string{"valve", "zone_coordinator"}, infra.KPIsUnavailable)
+	assert.Equal(t, int64(3600), infra.KPIWindowSeconds)
+
+	assert.Equal(t, map[string]int{"WATCH": 1}, infra.StatusCounts)
+}
+
+func TestRuntimeInfrastructureSource_NoTelemetry(t *testing.T) {
+	agents := staticAgentLister{newTestAsset("PUMP-001", "pump", map[string]string{"efficiency_percent": "80"})}
+
+	infra, err := NewRuntimeInfrastructureSource(agents, nil).InfrastructureContext(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, infra.KPIs)
+	assert.Equal(t, []string{"pump"}, infra.KPIsUnavailable)
+}
+
+func TestRuntimeInfrastructureSource_NoAgents(t *testing.T) {
+	source := NewRuntimeInfrastructureSource(staticAgentLister{}, nil)
+	infra, err := source.InfrastructureContext(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, infra)
+}
+
+func TestRuntimeInfrastructureSource_TenantScoped(t *testing.T) {
+	agents := staticAgentLister{
+		newTestAsset("acme--PUMP-001", "pump", map[string]string{"zone": "north"}),
+		newTestAsset("globex--PUMP-001", "pump", map[string]string{"zone": "south"}),
+		newTestAsset("globex--VALVE-001", "valve", nil),
+	}
+	now := time.Now().UTC()
+	readings := staticTelemetry{
+		{AgentID: "acme--PUMP-001", Metric: "pressure_bar", Value: 4, Timestamp: now},
+		{AgentID: "globex--PUMP-001", Metric: "pressure_bar", Value: 9, Timestamp: now},
+		{AgentID: "globex--VALVE-001", Metric: "position_percent", Value: 100, Timestamp: now},
+	}
+
+	source := NewRuntimeInfrastructureSource(agents, nil)
+	source.SetTelemetry(readings, 0)
+	infra, err := source.InfrastructureContext(tenant.WithTenant(context.Background(), "acme"))
+	require.NoError(t, err)
+	require.NotNil(t, infra)
+
+	// Other tenants' assets, zones and readings stay out of the prompt
+	assert.Equal(t, 1, infra.TotalAssets)
+	require.Len(t, infra.AssetTypes, 1)
+	assert.Equal(t, []string{"acme--PUMP-001"}, infra.AssetTypes[0].SampleIDs)
+	require.Len(t, infra.Zones, 1)
+	assert.Equal(t, "north", infra.Zones[0].Zone)
+	require.Len(t, infra.KPIs, 1)
+	assert.Equal(t, 4.0, infra.KPIs[0].Max)
+
+	infra, err = source.InfrastructureContext(tenant.WithTenant(context.Background(), "initech"))
+	require.NoError(t, err)
+	assert.Nil(t, infra)
+}
@@ -0,0 +1,64 @@
+package builder
+
+import (
+	"time"
+)
+
+// InfrastructureContext summarises the live asset inventory and recent KPIs so
+// that AI prompts can reference real assets instead of generic placeholders.
+type InfrastructureContext struct {
+	// TotalAssets is the number of registered agents/assets
+	TotalAssets int `json:"total_assets"`
+
+	// AssetTypes lists asset counts per type with a few example IDs
+	AssetTypes []AssetTypeSummary `json:"asset_types"`
+
+	// Zones lists asset counts per zone
+	Zones []ZoneSummary `json:"zones,omitempty"`
+
+	// StatusCounts is the number of assets currently in each operational status
+	StatusCounts map[string]int `json:"status_counts,omitempty"`
+
+	// KPIs aggregate the assets' latest telemetry readings per asset type
+	KPIs []KPIAggregate `json:"kpis,omitempty"`
+
+	// KPIsUnavailable lists asset types without any telemetry reading in the
+	// KPI window; their current operating values are unknown
+	KPIsUnavailable []string `json:"kpis_unavailable,omitempty"`
+
+	// KPIWindowSeconds is how far back KPI readings are taken from
+	KPIWindowSeconds int64 `json:"kpi_window_seconds"`
+
+	// GeneratedAt is when the summary was built
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// AssetTypeSummary counts assets of a single type
+type AssetTypeSummary struct {
+	Type      string   `json:"type"`
+	Count     int      `json:"count"`
+	SampleIDs []string `json:"sample_ids"`
+}
+
+// ZoneSummary counts assets in a single zone
+type ZoneSummary struct {
+	Zone       string         `json:"zone"`
+	AssetCount int            `json:"asset_count"`
+	AssetTypes map[string]int `json:"asset_types"`
+}
+
+// KPIAggregate aggregates the latest readings of a metric across one asset type
+type KPIAggregate struct {
+	AssetType      string    `json:"asset_type"`
+	Metric         string    `json:"metric"`
+	Average        float64   `json:"average"`
+	Min            float64   `json:"min"`
+	Max            float64   `json:"max"`
+	Samples        int       `json:"samples"` // Assets with a reading in the window
+	LastReportedAt time.Time `json:"last_reported_at"`
+
+	// Missing counts the assets of the type without a reading of the metric
+	// in the window, and MissingIDs lists a few of them
+	Missing    int      `json:"missing,omitempty"`
+	MissingIDs []string `json:"missing_ids,omitempty"`
+}
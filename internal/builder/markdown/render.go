@@ -0,0 +1,192 @@
+// Package markdown renders the markdown subset produced by AI assistants into
+// sanitized HTML for chat messages.
+//
+// The renderer escapes all input before applying formatting, so the only tags
+// in the output are the ones it emits itself. Links are restricted to safe
+// URL schemes.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	orderedItemPattern = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	boldPattern        = regexp.MustCompile(`\*\*([^*]+?)\*\*|__([^_]+?)__`)
+	italicPattern      = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*?)\*([^*\w]|$)`)
+	codePattern        = regexp.MustCompile("`([^`]+)`")
+	linkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	hrPattern          = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+)
+
+// allowedLinkPrefixes are the URL prefixes permitted in rendered links
+var allowedLinkPrefixes = []string{"http://", "https://", "mailto:", "/", "#"}
+
+// Render converts markdown to sanitized HTML. Supported syntax: headings,
+// paragraphs, bold, italic, inline code, fenced code blocks, links, ordered and
+// unordered lists, blockquotes and horizontal rules.
+func Render(src string) string {
+	src = NormalizeText(src)
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	r := &renderer{}
+	for _, line := range lines {
+		r.line(line)
+	}
+	r.closeBlocks()
+
+	return strings.TrimSpace(r.out.String())
+}
+
+// renderer tracks the open block while walking lines
+type renderer struct {
+	out       strings.Builder
+	paragraph []string
+	list      string // "ul", "ol" or ""
+	inCode    bool
+	code      []string
+}
+
+func (r *renderer) line(line string) {
+	trimmed := strings.TrimSpace(line)
+
+	if strings.HasPrefix(trimmed, "```") {
+		if r.inCode {
+			r.out.WriteString("<pre><code>")
+			r.out.WriteString(html.EscapeString(strings.Join(r.code, "\n")))
+			r.out.WriteString("</code></pre>\n")
+			r.inCode = false
+			r.code = nil
+		} else {
+			r.closeBlocks()
+			r.inCode = true
+		}
+		return
+	}
+	if r.inCode {
+		r.code = append(r.code, line)
+		return
+	}
+
+	switch {
+	case trimmed == "":
+		r.closeBlocks()
+
+	case hrPattern.MatchString(trimmed):
+		r.closeBlocks()
+		r.out.WriteString("<hr/>\n")
+
+	case headingPattern.MatchString(trimmed):
+		r.closeBlocks()
+		m := headingPattern.FindStringSubmatch(trimmed)
+		level := strconv.Itoa(len(m[1]))
+		r.out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+	case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+		r.listItem("ul", trimmed[2:])
+
+	case orderedItemPattern.MatchString(trimmed):
+		r.listItem("ol", orderedItemPattern.FindStringSubmatch(trimmed)[1])
+
+	case strings.HasPrefix(trimmed, ">"):
+		r.closeBlocks()
+		r.out.WriteString("<blockquote>" + renderInline(strings.TrimSpace(trimmed[1:])) + "</blockquote>\n")
+
+	default:
+		r.closeList()
+		r.paragraph = append(r.paragraph, renderInline(trimmed))
+	}
+}
+
+func (r *renderer) listItem(kind, content string) {
+	r.closeParagraph()
+	if r.list != kind {
+		r.closeList()
+		r.out.WriteString("<" + kind + ">\n")
+		r.list = kind
+	}
+	r.out.WriteString("<li>" + renderInline(strings.TrimSpace(content)) + "</li>\n")
+}
+
+func (r *renderer) closeParagraph() {
+	if len(r.paragraph) == 0 {
+		return
+	}
+	r.out.WriteString("<p>" + strings.Join(r.paragraph, "<br/>") + "</p>\n")
+	r.paragraph = nil
+}
+
+func (r *renderer) closeList() {
+	if r.list == "" {
+		return
+	}
+	r.out.WriteString("</" + r.list + ">\n")
+	r.list = ""
+}
+
+func (r *renderer) closeBlocks() {
+	r.closeParagraph()
+	r.closeList()
+	if r.inCode {
+		// Unterminated fence: render what we have
+		r.out.WriteString("<pre><code>" + html.EscapeString(strings.Join(r.code, "\n")) + "</code></pre>\n")
+		r.inCode = false
+		r.code = nil
+	}
+}
+
+// renderInline escapes text and applies inline formatting. Code spans are
+// extracted first so their contents are not formatted.
+func renderInline(text string) string {
+	var spans []string
+	text = codePattern.ReplaceAllStringFunc(text, func(m string) string {
+		spans = append(spans, "<code>"+html.EscapeString(m[1:len(m)-1])+"</code>")
+		return "\x00" + strconv.Itoa(len(spans)-1) + "\x00"
+	})
+
+	text = html.EscapeString(text)
+
+	text = linkPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		if !isSafeURL(href) {
+			return parts[1]
+		}
+		return `<a href="` + html.EscapeString(href) + `" target="_blank" rel="noopener noreferrer">` + parts[1] + `</a>`
+	})
+
+	text = boldPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := boldPattern.FindStringSubmatch(m)
+		inner := parts[1]
+		if inner == "" {
+			inner = parts[2]
+		}
+		return "<strong>" + inner + "</strong>"
+	})
+	text = italicPattern.ReplaceAllString(text, "$1<em>$2</em>$3")
+
+	for i, span := range spans {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", span, 1)
+	}
+
+	return text
+}
+
+// isSafeURL reports whether a link target uses an allowed scheme
+func isSafeURL(href string) bool {
+	lower := strings.ToLower(strings.TrimSpace(href))
+	// Reject protocol-relative URLs ("//host"); browsers read "/\host" the same way
+	if strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, "/\\") {
+		return false
+	}
+	for _, prefix := range allowedLinkPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
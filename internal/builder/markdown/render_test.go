@@ -0,0 +1,67 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender_Formatting(t *testing.T) {
+	src := "## Summary\n\n**Bold** and *italic* with `a < b`\nsecond line\n\n- one\n- two\n\n1. first\n2. second\n\n```\n<script>x</script>\n```"
+
+	out := Render(src)
+
+	assert.Contains(t, out, "<h2>Summary</h2>")
+	assert.Contains(t, out, "<p><strong>Bold</strong> and <em>italic</em> with <code>a &lt; b</code><br/>second line</p>")
+	assert.Contains(t, out, "<ul>\n<li>one</li>\n<li>two</li>\n</ul>")
+	assert.Contains(t, out, "<ol>\n<li>first</li>\n<li>second</li>\n</ol>")
+	assert.Contains(t, out, "<pre><code>&lt;script&gt;x&lt;/script&gt;</code></pre>")
+}
+
+func TestRender_Sanitizes(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		forbidden string
+	}{
+		{name: "raw script tag", src: "<script>alert(1)</script>", forbidden: "<script"},
+		{name: "event handler", src: `<img src=x onerror="alert(1)">`, forbidden: "<img"},
+		{name: "javascript link", src: "[click](javascript:alert(1))", forbidden: "javascript:"},
+		{name: "protocol-relative link", src: "[click](//evil.example)", forbidden: "href"},
+		{name: "protocol-relative link with backslash", src: `[click](/\evil.example)`, forbidden: "href"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotContains(t, Render(tt.src), tt.forbidden)
+		})
+	}
+
+	assert.Contains(t, Render("[guide](/docs/guide)"), `<a href="/docs/guide"`)
+	assert.Contains(t, Render("[docs](https://example.com/a?b=1&c=2)"),
+		`<a href="https://example.com/a?b=1&amp;c=2" target="_blank" rel="noopener noreferrer">docs</a>`)
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "repairs sparkles mojibake", in: "âœ¨ **Introduction Refined**", want: "✨ **Introduction Refined**"},
+		{name: "repairs check mark mojibake", in: "âœ… Done", want: "✅ Done"},
+		{name: "keeps correct emoji", in: "🎯 Goals ✅", want: "🎯 Goals ✅"},
+		{name: "keeps latin-1 text", in: "café résumé", want: "café résumé"},
+		{name: "drops control characters", in: "a\x00b\x07c\r\nd", want: "abc\nd"},
+		{name: "drops invalid utf-8", in: "ok\xffok", want: "okok"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeText(tt.in))
+		})
+	}
+
+	assert.True(t, strings.HasPrefix(Render("âœ¨ hi"), "<p>✨ hi"))
+}
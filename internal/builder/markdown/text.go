@@ -0,0 +1,87 @@
+package markdown
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// cp1252Specials maps the Windows-1252 characters in 0x80-0x9F back to their
+// byte values. Text that was UTF-8 but decoded as Windows-1252 ("mojibake",
+// e.g. "âœ¨" for "✨") contains these characters.
+var cp1252Specials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// NormalizeText cleans chat text before storage and rendering: invalid UTF-8
+// is dropped, control characters other than newlines and tabs are removed, and
+// mojibake produced by decoding UTF-8 as Windows-1252 is repaired.
+func NormalizeText(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if r < 0x20 || r == 0x7F || r == '\uFEFF' {
+			return -1
+		}
+		return r
+	}, s)
+
+	return repairMojibake(s)
+}
+
+// repairMojibake re-decodes runs of Windows-1252 characters that form valid
+// multi-byte UTF-8 sequences. Runs that do not decode cleanly (such as
+// genuine Latin-1 text like "café") are left untouched.
+func repairMojibake(s string) string {
+	var out strings.Builder
+	var run []byte
+	runStart := 0
+
+	flush := func(end int) {
+		if len(run) == 0 {
+			return
+		}
+		if utf8.Valid(run) {
+			out.Write(run)
+		} else {
+			out.WriteString(s[runStart:end])
+		}
+		run = run[:0]
+	}
+
+	for i, r := range s {
+		// Mojibake runs never contain ASCII: every byte of a multi-byte
+		// UTF-8 sequence is >= 0x80
+		b, ok := cp1252Byte(r)
+		if !ok || b < 0x80 {
+			flush(i)
+			out.WriteRune(r)
+			runStart = i + utf8.RuneLen(r)
+			continue
+		}
+		if len(run) == 0 {
+			runStart = i
+		}
+		run = append(run, b)
+	}
+	flush(len(s))
+
+	return out.String()
+}
+
+// cp1252Byte returns the Windows-1252 byte for a rune, if it has one. C1
+// control characters are accepted as well since some decoders pass the five
+// undefined Windows-1252 bytes through unchanged.
+func cp1252Byte(r rune) (byte, bool) {
+	if r <= 0xFF {
+		return byte(r), true
+	}
+	b, ok := cp1252Specials[r]
+	return b, ok
+}
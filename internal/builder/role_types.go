@@ -5,16 +5,42 @@ import (
 	"github.com/aosanya/CodeValdCortex/internal/registry"
 )
 
+// GenerateRolesRequest contains the context for generating roles
+type GenerateRolesRequest struct {
+	AgencyID      string             `json:"agency_id"`
+	AgencyContext *agency.Agency     `json:"agency_context"`
+	ExistingRoles []*registry.Role   `json:"existing_roles"`
+	WorkItems     []*agency.WorkItem `json:"work_items"`
+	Goals         []*agency.Goal     `json:"goals"`
+	UserInput     string             `json:"user_input"`
+}
+
 // GenerateRoleResponse contains the AI-generated role (used in dynamic responses)
 type GenerateRoleResponse struct {
-	Name           string   `json:"name"`
-	Description    string   `json:"description"`
-	Tags           []string `json:"tags"`
-	AutonomyLevel  string   `json:"autonomy_level"`
-	Capabilities   []string `json:"capabilities"`
-	RequiredSkills []string `json:"required_skills"`
-	TokenBudget    int64    `json:"token_budget"`
-	Explanation    string   `json:"explanation"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	Responsibilities []string `json:"responsibilities"`
+	Tags             []string `json:"tags"`
+	AutonomyLevel    string   `json:"autonomy_level"`
+	Capabilities     []string `json:"capabilities"`
+	RequiredSkills   []string `json:"required_skills"`
+	TokenBudget      int64    `json:"token_budget"`
+	GoalKeys         []string `json:"goal_keys"` // Keys of the goals the role works towards
+	Explanation      string   `json:"explanation"`
+}
+
+// GenerateRolesResponse contains multiple AI-generated roles
+type GenerateRolesResponse struct {
+	Roles       []GenerateRoleResponse `json:"roles"`
+	Explanation string                 `json:"explanation"`
+}
+
+// ConsolidateRolesRequest contains the context for consolidating roles
+type ConsolidateRolesRequest struct {
+	AgencyID      string             `json:"agency_id"`
+	AgencyContext *agency.Agency     `json:"agency_context"`
+	CurrentRoles  []*registry.Role   `json:"current_roles"`
+	WorkItems     []*agency.WorkItem `json:"work_items"`
 }
 
 // ConsolidateRolesResponse contains the consolidated roles (used in dynamic responses)
@@ -29,11 +55,13 @@ type ConsolidateRolesResponse struct {
 type ConsolidatedRole struct {
 	Name             string   `json:"name"`
 	Description      string   `json:"description"`
+	Responsibilities []string `json:"responsibilities"`
 	Tags             []string `json:"tags"`
 	AutonomyLevel    string   `json:"autonomy_level"`
 	Capabilities     []string `json:"capabilities"`
 	RequiredSkills   []string `json:"required_skills"`
 	TokenBudget      int64    `json:"token_budget"`
+	GoalKeys         []string `json:"goal_keys"`
 	ConsolidatedFrom []string `json:"consolidated_from"` // Keys of original roles
 	Rationale        string   `json:"rationale"`
 }
@@ -45,6 +73,7 @@ type RefineRolesRequest struct {
 	TargetRoles   []*registry.Role   `json:"target_roles"`   // Specific roles to operate on (nil means all)
 	ExistingRoles []*registry.Role   `json:"existing_roles"` // All current roles for context
 	WorkItems     []*agency.WorkItem `json:"work_items"`     // Work items for context
+	Goals         []*agency.Goal     `json:"goals"`          // Agency goals for context
 	AgencyContext *agency.Agency     `json:"agency_context"`
 }
 
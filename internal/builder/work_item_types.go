@@ -48,6 +48,7 @@ type GenerateWorkItemResponse struct {
 	SuggestedPriority string   `json:"suggested_priority"`
 	SuggestedEffort   int      `json:"suggested_effort"`
 	SuggestedTags     []string `json:"suggested_tags"`
+	GoalKeys          []string `json:"goal_keys"` // Keys of the goals the work item contributes to
 	Explanation       string   `json:"explanation"`
 }
 
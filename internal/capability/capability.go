@@ -0,0 +1,158 @@
+package capability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrCapabilityNotFound is returned when a capability is not in the catalog
+	ErrCapabilityNotFound = errors.New("capability not found")
+
+	// ErrCapabilityExists is returned when registering a capability ID twice
+	ErrCapabilityExists = errors.New("capability already exists")
+
+	// ErrInvalidCapability is returned for capabilities that fail validation
+	ErrInvalidCapability = errors.New("invalid capability")
+
+	idPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// Capability is something an agent can do, such as "can_close_valve" or
+// "can_run_security_scan". Agent types and templates declare the capabilities
+// they provide; work items and workflow tasks declare the ones they require.
+type Capability struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	// ID is the snake_case identifier referenced by agent types, templates and work items
+	ID string `json:"id"`
+
+	// Name is a human-readable name
+	Name string `json:"name"`
+
+	// Description explains what an agent with the capability can do
+	Description string `json:"description,omitempty"`
+
+	// Category groups related capabilities, e.g. "control" or "security"
+	Category string `json:"category,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the capability's ID and name
+func (c *Capability) Validate() error {
+	if !idPattern.MatchString(c.ID) {
+		return fmt.Errorf("%w: id %q must be snake_case, e.g. can_close_valve", ErrInvalidCapability, c.ID)
+	}
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCapability)
+	}
+	return nil
+}
+
+// Repository stores the capability catalog
+type Repository interface {
+	// Create registers a new capability
+	Create(ctx context.Context, capability *Capability) error
+
+	// Get retrieves a capability by ID
+	Get(ctx context.Context, id string) (*Capability, error)
+
+	// Update modifies an existing capability
+	Update(ctx context.Context, capability *Capability) error
+
+	// Delete removes a capability
+	Delete(ctx context.Context, id string) error
+
+	// List returns every registered capability
+	List(ctx context.Context) ([]*Capability, error)
+}
+
+// CatalogEntry is a capability as seen across the platform: registered in the
+// catalog, declared by agent types and templates, or both
+type CatalogEntry struct {
+	*Capability
+
+	// Registered is false for capabilities only declared by agent types or templates
+	Registered bool `json:"registered"`
+
+	// DeclaredBy lists the agent types ("role:<id>") and templates ("template:<id>") providing it
+	DeclaredBy []string `json:"declared_by,omitempty"`
+}
+
+// Requirement is a work item or workflow task that needs capabilities
+type Requirement struct {
+	// Kind is "work_item" or "workflow_task"
+	Kind string `json:"kind"`
+
+	// ID is the work item code, or "<workflow id>/<node id>" for workflow tasks
+	ID string `json:"id"`
+
+	// Name is the work item title or task name
+	Name string `json:"name"`
+
+	// Capabilities are the capability IDs required
+	Capabilities []string `json:"capabilities"`
+}
+
+const (
+	// RequirementWorkItem marks requirements declared by work items
+	RequirementWorkItem = "work_item"
+
+	// RequirementWorkflowTask marks requirements declared by workflow work item nodes
+	RequirementWorkflowTask = "workflow_task"
+)
+
+// Coverage reports who needs and who provides one capability in an agency
+type Coverage struct {
+	Capability string `json:"capability"`
+
+	// Registered reports whether the capability is in the catalog
+	Registered bool `json:"registered"`
+
+	// RequiredBy lists work items and tasks needing it, as "<kind>:<id>"
+	RequiredBy []string `json:"required_by"`
+
+	// ProvidedBy lists the IDs of the agency's deployed agents that have it
+	ProvidedBy []string `json:"provided_by"`
+
+	// DeclaredBy lists agent types and templates that could provide it when deployed
+	DeclaredBy []string `json:"declared_by,omitempty"`
+}
+
+// UnmetRequirement is a work item or task that no deployed agent can fully serve
+type UnmetRequirement struct {
+	Requirement
+
+	// Missing are the required capabilities no deployed agent provides
+	Missing []string `json:"missing"`
+}
+
+// GapAnalysis compares the capabilities an agency's work needs with those its
+// deployed agents provide
+type GapAnalysis struct {
+	AgencyID string `json:"agency_id"`
+
+	// Covered is true when every requirement is met by some deployed agent
+	Covered bool `json:"covered"`
+
+	// DeployedAgents is the number of the agency's agents considered
+	DeployedAgents int `json:"deployed_agents"`
+
+	// Capabilities is the coverage of every required capability, sorted by ID
+	Capabilities []Coverage `json:"capabilities"`
+
+	// Unmet lists the requirements with missing capabilities
+	Unmet []UnmetRequirement `json:"unmet"`
+
+	// Unregistered lists required capability IDs that are neither in the
+	// catalog nor declared by any agent type or template, usually typos
+	Unregistered []string `json:"unregistered,omitempty"`
+
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
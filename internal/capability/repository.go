@@ -0,0 +1,234 @@
+package capability
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionCapabilities is the capability catalog collection name
+const CollectionCapabilities = "capabilities"
+
+// ArangoRepository stores the capability catalog in ArangoDB
+type ArangoRepository struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed capability repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionCapabilities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionCapabilities)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionCapabilities, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionCapabilities).Info("Created new collection")
+	}
+
+	return &ArangoRepository{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create registers a new capability
+func (r *ArangoRepository) Create(ctx context.Context, capability *Capability) error {
+	now := time.Now()
+	capability.Key = capability.ID
+	capability.CreatedAt = now
+	capability.UpdatedAt = now
+
+	if _, err := r.collection.CreateDocument(ctx, capability); err != nil {
+		if driver.IsConflict(err) {
+			return fmt.Errorf("%w: %s", ErrCapabilityExists, capability.ID)
+		}
+		return fmt.Errorf("failed to create capability: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a capability by ID
+func (r *ArangoRepository) Get(ctx context.Context, id string) (*Capability, error) {
+	var capability Capability
+	if _, err := r.collection.ReadDocument(ctx, id, &capability); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrCapabilityNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read capability: %w", err)
+	}
+	return &capability, nil
+}
+
+// Update modifies an existing capability
+func (r *ArangoRepository) Update(ctx context.Context, capability *Capability) error {
+	existing, err := r.Get(ctx, capability.ID)
+	if err != nil {
+		return err
+	}
+
+	capability.Key = capability.ID
+	capability.CreatedAt = existing.CreatedAt
+	capability.UpdatedAt = time.Now()
+
+	if _, err := r.collection.ReplaceDocument(ctx, capability.ID, capability); err != nil {
+		return fmt.Errorf("failed to update capability: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a capability
+func (r *ArangoRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.collection.RemoveDocument(ctx, id); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrCapabilityNotFound, id)
+		}
+		return fmt.Errorf("failed to delete capability: %w", err)
+	}
+	return nil
+}
+
+// List returns every registered capability ordered by ID
+func (r *ArangoRepository) List(ctx context.Context) ([]*Capability, error) {
+	query := `
+		FOR c IN @@collection
+			SORT c.id ASC
+			RETURN c
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionCapabilities,
+	}
+
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capabilities: %w", err)
+	}
+	defer cursor.Close()
+
+	var capabilities []*Capability
+	for {
+		var c Capability
+		_, err := cursor.ReadDocument(ctx, &c)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read capability: %w", err)
+		}
+		capabilities = append(capabilities, &c)
+	}
+
+	return capabilities, nil
+}
+
+// InMemoryRepository keeps the capability catalog in memory. Registered
+// capabilities are lost on restart and must be registered again.
+type InMemoryRepository struct {
+	mu           sync.RWMutex
+	capabilities map[string]*Capability
+}
+
+// NewInMemoryRepository creates a new in-memory capability repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		capabilities: make(map[string]*Capability),
+	}
+}
+
+// Create registers a new capability
+func (r *InMemoryRepository) Create(ctx context.Context, capability *Capability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.capabilities[capability.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrCapabilityExists, capability.ID)
+	}
+
+	now := time.Now()
+	capability.Key = capability.ID
+	capability.CreatedAt = now
+	capability.UpdatedAt = now
+
+	stored := *capability
+	r.capabilities[capability.ID] = &stored
+	return nil
+}
+
+// Get retrieves a capability by ID
+func (r *InMemoryRepository) Get(ctx context.Context, id string) (*Capability, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	capability, exists := r.capabilities[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrCapabilityNotFound, id)
+	}
+	result := *capability
+	return &result, nil
+}
+
+// Update modifies an existing capability
+func (r *InMemoryRepository) Update(ctx context.Context, capability *Capability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.capabilities[capability.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCapabilityNotFound, capability.ID)
+	}
+
+	capability.Key = capability.ID
+	capability.CreatedAt = existing.CreatedAt
+	capability.UpdatedAt = time.Now()
+
+	stored := *capability
+	r.capabilities[capability.ID] = &stored
+	return nil
+}
+
+// Delete removes a capability
+func (r *InMemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.capabilities[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrCapabilityNotFound, id)
+	}
+	delete(r.capabilities, id)
+	return nil
+}
+
+// List returns every registered capability ordered by ID
+func (r *InMemoryRepository) List(ctx context.Context) ([]*Capability, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	capabilities := make([]*Capability, 0, len(r.capabilities))
+	for _, capability := range r.capabilities {
+		c := *capability
+		capabilities = append(capabilities, &c)
+	}
+	sort.Slice(capabilities, func(i, j int) bool {
+		return capabilities[i].ID < capabilities[j].ID
+	})
+	return capabilities, nil
+}
@@ -0,0 +1,341 @@
+package capability
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/aosanya/CodeValdCortex/internal/templates"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/sirupsen/logrus"
+)
+
+// RoleSource lists agent types. registry.RoleService implements it.
+type RoleSource interface {
+	ListTypes(ctx context.Context) ([]*registry.Role, error)
+}
+
+// TemplateSource lists agent templates. templates.Engine implements it.
+type TemplateSource interface {
+	ListTemplates(ctx context.Context, filter *templates.ListFilter) ([]*templates.Template, error)
+}
+
+// WorkItemSource lists an agency's work items. agency.Service implements it.
+type WorkItemSource interface {
+	GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error)
+}
+
+// WorkflowSource lists an agency's workflows. workflow.Service implements it.
+type WorkflowSource interface {
+	GetWorkflowsByAgency(ctx context.Context, agencyID string) ([]*workflow.Workflow, error)
+}
+
+// AgentSource lists running agents. runtime.Manager implements it.
+type AgentSource interface {
+	ListAgents() []*agent.Agent
+}
+
+// Sources are where capability declarations and requirements are read from.
+// Nil sources are skipped.
+type Sources struct {
+	Roles     RoleSource
+	Templates TemplateSource
+	WorkItems WorkItemSource
+	Workflows WorkflowSource
+	Agents    AgentSource
+}
+
+// Service manages the capability catalog and checks whether an agency's
+// deployed agents cover the capabilities its work needs
+type Service struct {
+	repo    Repository
+	sources Sources
+	logger  *logrus.Logger
+}
+
+// NewService creates a new capability service
+func NewService(repo Repository, sources Sources, logger *logrus.Logger) *Service {
+	return &Service{
+		repo:    repo,
+		sources: sources,
+		logger:  logger,
+	}
+}
+
+// Register adds a capability to the catalog
+func (s *Service) Register(ctx context.Context, capability *Capability) error {
+	if err := capability.Validate(); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, capability); err != nil {
+		return err
+	}
+
+	s.logger.WithField("capability", capability.ID).Info("Registered capability")
+	return nil
+}
+
+// Get returns a registered capability
+func (s *Service) Get(ctx context.Context, id string) (*Capability, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Update replaces a registered capability's name, description and category
+func (s *Service) Update(ctx context.Context, capability *Capability) error {
+	if err := capability.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, capability)
+}
+
+// Delete removes a capability from the catalog. Agent types, templates and
+// work items referencing it are left unchanged.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Catalog returns registered capabilities together with those only declared
+// by agent types and templates, ordered by ID
+func (s *Service) Catalog(ctx context.Context) ([]*CatalogEntry, error) {
+	registered, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capabilities: %w", err)
+	}
+	declared, _, err := s.declarations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*CatalogEntry, len(registered)+len(declared))
+	for _, capability := range registered {
+		entries[capability.ID] = &CatalogEntry{Capability: capability, Registered: true}
+	}
+	for id, declaredBy := range declared {
+		entry, ok := entries[id]
+		if !ok {
+			entry = &CatalogEntry{Capability: &Capability{ID: id, Name: id}}
+			entries[id] = entry
+		}
+		entry.DeclaredBy = declaredBy
+	}
+
+	catalog := make([]*CatalogEntry, 0, len(entries))
+	for _, entry := range entries {
+		catalog = append(catalog, entry)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].ID < catalog[j].ID
+	})
+	return catalog, nil
+}
+
+// Analyze compares the capabilities required by an agency's work items and
+// workflow tasks with those provided by its deployed agents. A requirement is
+// met when each of its capabilities is provided by at least one running or
+// paused agent of the agency.
+func (s *Service) Analyze(ctx context.Context, agencyID string) (*GapAnalysis, error) {
+	requirements, err := s.requirements(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+	declared, typeCapabilities, err := s.declarations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capabilities: %w", err)
+	}
+	isRegistered := make(map[string]bool, len(registered))
+	for _, capability := range registered {
+		isRegistered[capability.ID] = true
+	}
+
+	// Capabilities provided by each of the agency's deployed agents
+	providers := make(map[string][]string)
+	deployed := 0
+	if s.sources.Agents != nil {
+		for _, a := range s.sources.Agents.ListAgents() {
+			if a.Metadata["agency_id"] != agencyID {
+				continue
+			}
+			if state := a.GetState(); state == agent.StateStopped || state == agent.StateFailed {
+				continue
+			}
+			deployed++
+			for id := range typeCapabilities[a.Type] {
+				providers[id] = append(providers[id], a.ID)
+			}
+		}
+	}
+
+	analysis := &GapAnalysis{
+		AgencyID:       agencyID,
+		DeployedAgents: deployed,
+		Capabilities:   []Coverage{},
+		Unmet:          []UnmetRequirement{},
+		AnalyzedAt:     time.Now().UTC(),
+	}
+
+	coverage := make(map[string]*Coverage)
+	for _, req := range requirements {
+		var missing []string
+		for _, id := range req.Capabilities {
+			c, ok := coverage[id]
+			if !ok {
+				provided := providers[id]
+				sort.Strings(provided)
+				c = &Coverage{
+					Capability: id,
+					Registered: isRegistered[id],
+					ProvidedBy: append([]string{}, provided...),
+					DeclaredBy: declared[id],
+				}
+				coverage[id] = c
+			}
+			c.RequiredBy = append(c.RequiredBy, req.Kind+":"+req.ID)
+			if len(c.ProvidedBy) == 0 {
+				missing = append(missing, id)
+			}
+		}
+		if len(missing) > 0 {
+			analysis.Unmet = append(analysis.Unmet, UnmetRequirement{Requirement: req, Missing: missing})
+		}
+	}
+
+	for id, c := range coverage {
+		analysis.Capabilities = append(analysis.Capabilities, *c)
+		if !c.Registered && len(c.DeclaredBy) == 0 {
+			analysis.Unregistered = append(analysis.Unregistered, id)
+		}
+	}
+	sort.Slice(analysis.Capabilities, func(i, j int) bool {
+		return analysis.Capabilities[i].Capability < analysis.Capabilities[j].Capability
+	})
+	sort.Strings(analysis.Unregistered)
+	analysis.Covered = len(analysis.Unmet) == 0
+
+	return analysis, nil
+}
+
+// declarations returns the agent types and templates declaring each capability,
+// and the capabilities provided by each agent type
+func (s *Service) declarations(ctx context.Context) (map[string][]string, map[string]map[string]bool, error) {
+	declared := make(map[string][]string)
+	byType := make(map[string]map[string]bool)
+
+	provide := func(agentType, declarer string, ids []string) {
+		if byType[agentType] == nil {
+			byType[agentType] = make(map[string]bool)
+		}
+		for _, id := range normalizeIDs(ids) {
+			byType[agentType][id] = true
+			declared[id] = append(declared[id], declarer)
+		}
+	}
+
+	if s.sources.Roles != nil {
+		roles, err := s.sources.Roles.ListTypes(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list agent types: %w", err)
+		}
+		for _, role := range roles {
+			ids := append(append([]string{}, role.RequiredCapabilities...), role.OptionalCapabilities...)
+			provide(role.ID, "role:"+role.ID, ids)
+		}
+	}
+
+	if s.sources.Templates != nil {
+		tmpls, err := s.sources.Templates.ListTemplates(ctx, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, tmpl := range tmpls {
+			provide(tmpl.AgentType, "template:"+tmpl.ID, tmpl.Capabilities)
+		}
+	}
+
+	for id := range declared {
+		sort.Strings(declared[id])
+	}
+	return declared, byType, nil
+}
+
+// requirements collects the capability requirements of an agency's work items
+// and workflow work item nodes
+func (s *Service) requirements(ctx context.Context, agencyID string) ([]Requirement, error) {
+	var requirements []Requirement
+
+	if s.sources.WorkItems != nil {
+		workItems, err := s.sources.WorkItems.GetWorkItems(ctx, agencyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get work items: %w", err)
+		}
+		sort.Slice(workItems, func(i, j int) bool {
+			return workItems[i].Code < workItems[j].Code
+		})
+		for _, item := range workItems {
+			if ids := normalizeIDs(item.RequiredCapabilities); len(ids) > 0 {
+				requirements = append(requirements, Requirement{
+					Kind:         RequirementWorkItem,
+					ID:           item.Code,
+					Name:         item.Title,
+					Capabilities: ids,
+				})
+			}
+		}
+	}
+
+	if s.sources.Workflows != nil {
+		workflows, err := s.sources.Workflows.GetWorkflowsByAgency(ctx, agencyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get workflows: %w", err)
+		}
+		sort.Slice(workflows, func(i, j int) bool {
+			return workflows[i].ID < workflows[j].ID
+		})
+		for _, wf := range workflows {
+			for _, node := range wf.Nodes {
+				if node.Type != workflow.NodeTypeWorkItem {
+					continue
+				}
+				ids := normalizeIDs(node.Data.RequiredCapabilities)
+				if len(ids) == 0 {
+					continue
+				}
+				name := node.Data.Name
+				if name == "" {
+					name = node.ID
+				}
+				requirements = append(requirements, Requirement{
+					Kind:         RequirementWorkflowTask,
+					ID:           wf.ID + "/" + node.ID,
+					Name:         name,
+					Capabilities: ids,
+				})
+			}
+		}
+	}
+
+	return requirements, nil
+}
+
+// normalizeIDs trims capability IDs and drops blanks and duplicates
+func normalizeIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	var result []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package capability
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/aosanya/CodeValdCortex/internal/templates"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeSources struct {
+	roles     []*registry.Role
+	templates []*templates.Template
+	workItems []*agency.WorkItem
+	workflows []*workflow.Workflow
+	agents    []*agent.Agent
+}
+
+func (f *fakeSources) ListTypes(ctx context.Context) ([]*registry.Role, error) {
+	return f.roles, nil
+}
+
+func (f *fakeSources) ListTemplates(ctx context.Context, filter *templates.ListFilter) ([]*templates.Template, error) {
+	return f.templates, nil
+}
+
+func (f *fakeSources) GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error) {
+	return f.workItems, nil
+}
+
+func (f *fakeSources) GetWorkflowsByAgency(ctx context.Context, agencyID string) ([]*workflow.Workflow, error) {
+	return f.workflows, nil
+}
+
+func (f *fakeSources) ListAgents() []*agent.Agent {
+	return f.agents
+}
+
+func newTestService(f *fakeSources) *Service {
+	return NewService(NewInMemoryRepository(), Sources{
+		Roles:     f,
+		Templates: f,
+		WorkItems: f,
+		Workflows: f,
+		Agents:    f,
+	}, logrus.New())
+}
+
+func deployedAgent(agentType, agencyID string, state agent.State) *agent.Agent {
+	a := agent.New(agentType, agentType, agent.Config{})
+	a.Metadata["agency_id"] = agencyID
+	a.SetState(state)
+	return a
+}
+
+func TestAnalyze_ReportsUnmetRequirements(t *testing.T) {
+	valve := deployedAgent("valve", "a1", agent.StateRunning)
+	f := &fakeSources{
+		roles: []*registry.Role{
+			{ID: "valve", RequiredCapabilities: []string{"can_close_valve"}},
+			{ID: "scanner", OptionalCapabilities: []string{"can_run_security_scan"}},
+		},
+		templates: []*templates.Template{
+			{ID: "valve-remote", AgentType: "valve", Capabilities: []string{"can_report_pressure"}},
+		},
+		workItems: []*agency.WorkItem{
+			{Code: "WI-002", Title: "Audit", RequiredCapabilities: []string{"can_run_security_scan"}},
+			{Code: "WI-001", Title: "Isolate leak", RequiredCapabilities: []string{"can_close_valve", "can_report_pressure"}},
+			{Code: "WI-003", Title: "Plan"},
+		},
+		workflows: []*workflow.Workflow{
+			{ID: "wf1", Nodes: []workflow.Node{
+				{ID: "n1", Type: workflow.NodeTypeWorkItem, Data: workflow.NodeData{Name: "Dispatch", RequiredCapabilities: []string{"can_dispatch_crew"}}},
+				{ID: "n2", Type: workflow.NodeTypeDecision},
+			}},
+		},
+		agents: []*agent.Agent{
+			valve,
+			deployedAgent("scanner", "a1", agent.StateStopped),
+			deployedAgent("scanner", "other-agency", agent.StateRunning),
+		},
+	}
+	service := newTestService(f)
+
+	analysis, err := service.Analyze(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if analysis.Covered || analysis.DeployedAgents != 1 {
+		t.Errorf("Expected an uncovered agency with 1 deployed agent, got covered=%v deployed=%d", analysis.Covered, analysis.DeployedAgents)
+	}
+
+	var unmet []string
+	for _, u := range analysis.Unmet {
+		unmet = append(unmet, u.Kind+":"+u.ID)
+	}
+	if want := []string{"work_item:WI-002", "workflow_task:wf1/n1"}; !reflect.DeepEqual(unmet, want) {
+		t.Errorf("Expected unmet %v, got %v", want, unmet)
+	}
+
+	coverage := make(map[string]Coverage)
+	for _, c := range analysis.Capabilities {
+		coverage[c.Capability] = c
+	}
+	if got := coverage["can_report_pressure"].ProvidedBy; !reflect.DeepEqual(got, []string{valve.ID}) {
+		t.Errorf("Expected template capability provided by the valve agent, got %v", got)
+	}
+	if got := coverage["can_run_security_scan"].DeclaredBy; !reflect.DeepEqual(got, []string{"role:scanner"}) {
+		t.Errorf("Expected scanner role to be suggested, got %v", got)
+	}
+	if !reflect.DeepEqual(analysis.Unregistered, []string{"can_dispatch_crew"}) {
+		t.Errorf("Expected can_dispatch_crew to be unregistered, got %v", analysis.Unregistered)
+	}
+}
+
+func TestAnalyze_Covered(t *testing.T) {
+	f := &fakeSources{
+		roles:     []*registry.Role{{ID: "valve", RequiredCapabilities: []string{"can_close_valve"}}},
+		workItems: []*agency.WorkItem{{Code: "WI-001", RequiredCapabilities: []string{"can_close_valve"}}},
+		agents:    []*agent.Agent{deployedAgent("valve", "a1", agent.StateRunning)},
+	}
+
+	analysis, err := newTestService(f).Analyze(context.Background(), "a1")
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !analysis.Covered || len(analysis.Unmet) != 0 {
+		t.Errorf("Expected every requirement to be covered, got %+v", analysis.Unmet)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeSources{
+		roles: []*registry.Role{{ID: "valve", RequiredCapabilities: []string{"can_close_valve"}}},
+	}
+	service := newTestService(f)
+
+	if err := service.Register(ctx, &Capability{ID: "Close Valve", Name: "Close valve"}); !errors.Is(err, ErrInvalidCapability) {
+		t.Errorf("Expected ErrInvalidCapability for a non snake_case ID, got %v", err)
+	}
+	if err := service.Register(ctx, &Capability{ID: "can_close_valve", Name: "Close valve"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := service.Register(ctx, &Capability{ID: "can_close_valve", Name: "Close valve"}); !errors.Is(err, ErrCapabilityExists) {
+		t.Errorf("Expected ErrCapabilityExists, got %v", err)
+	}
+	if err := service.Register(ctx, &Capability{ID: "can_open_valve", Name: "Open valve"}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	catalog, err := service.Catalog(ctx)
+	if err != nil {
+		t.Fatalf("Catalog failed: %v", err)
+	}
+	if len(catalog) != 2 {
+		t.Fatalf("Expected 2 catalog entries, got %d", len(catalog))
+	}
+	if !catalog[0].Registered || !reflect.DeepEqual(catalog[0].DeclaredBy, []string{"role:valve"}) {
+		t.Errorf("Expected can_close_valve to be registered and declared by the valve role, got %+v", catalog[0])
+	}
+}
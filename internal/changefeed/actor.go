@@ -0,0 +1,51 @@
+package changefeed
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Request headers that identify who makes a change
+const (
+	UserIDHeader  = "X-User-ID"
+	SessionHeader = "X-Designer-Session"
+)
+
+// Actor identifies the user and designer session behind a change
+type Actor struct {
+	UserID  string `json:"user_id,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose changes are attributed to the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor changes are attributed to, if any
+func ActorFromContext(ctx context.Context) Actor {
+	if ctx == nil {
+		return Actor{}
+	}
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// ActorMiddleware attributes changes made by a request to the user and
+// designer session named in its headers, so that a session can ignore the
+// notifications of its own changes
+func ActorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := Actor{
+			UserID:  c.GetHeader(UserIDHeader),
+			Session: c.GetHeader(SessionHeader),
+		}
+		if actor != (Actor{}) {
+			c.Request = c.Request.WithContext(WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package changefeed
+
+import (
+	"context"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// agencyService publishes the changes made through an agency service
+type agencyService struct {
+	agency.Service
+	feed *Feed
+}
+
+// WrapAgencyService returns an agency service that publishes successful
+// goal, work item and overview changes to the feed
+func WrapAgencyService(service agency.Service, feed *Feed) agency.Service {
+	return &agencyService{Service: service, feed: feed}
+}
+
+func (s *agencyService) publish(ctx context.Context, agencyID, entity, action, key string, data map[string]interface{}) {
+	s.feed.Publish(Change{
+		AgencyID:  agencyID,
+		Entity:    entity,
+		Action:    action,
+		EntityKey: key,
+		Actor:     ActorFromContext(ctx),
+		Data:      data,
+	})
+}
+
+func (s *agencyService) UpdateAgencyOverview(ctx context.Context, agencyID string, introduction string) error {
+	if err := s.Service.UpdateAgencyOverview(ctx, agencyID, introduction); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityOverview, ActionUpdated, agencyID, nil)
+	return nil
+}
+
+func (s *agencyService) CreateGoal(ctx context.Context, agencyID string, code string, description string) (*agency.Goal, error) {
+	goal, err := s.Service.CreateGoal(ctx, agencyID, code, description)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, agencyID, EntityGoal, ActionCreated, goal.Key, map[string]interface{}{"code": goal.Code})
+	return goal, nil
+}
+
+func (s *agencyService) UpdateGoal(ctx context.Context, agencyID string, key string, code string, description string) error {
+	if err := s.Service.UpdateGoal(ctx, agencyID, key, code, description); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityGoal, ActionUpdated, key, map[string]interface{}{"code": code})
+	return nil
+}
+
+func (s *agencyService) UpdateGoalFull(ctx context.Context, agencyID string, key string, req agency.UpdateGoalRequest) error {
+	if err := s.Service.UpdateGoalFull(ctx, agencyID, key, req); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityGoal, ActionUpdated, key, map[string]interface{}{"code": req.Code})
+	return nil
+}
+
+func (s *agencyService) DeleteGoal(ctx context.Context, agencyID string, key string) error {
+	if err := s.Service.DeleteGoal(ctx, agencyID, key); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityGoal, ActionDeleted, key, nil)
+	return nil
+}
+
+func (s *agencyService) ReprioritizeGoals(ctx context.Context, agencyID string, priorities []agency.GoalPriority) ([]*agency.Goal, error) {
+	goals, err := s.Service.ReprioritizeGoals(ctx, agencyID, priorities)
+	if err != nil {
+		return nil, err
+	}
+	for _, goal := range goals {
+		s.publish(ctx, agencyID, EntityGoal, ActionUpdated, goal.Key, map[string]interface{}{"code": goal.Code})
+	}
+	return goals, nil
+}
+
+func (s *agencyService) ConsolidateGoals(ctx context.Context, agencyID string, req agency.ConsolidateGoalsRequest) (*agency.GoalConsolidation, error) {
+	consolidation, err := s.Service.ConsolidateGoals(ctx, agencyID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.publishGoalReplacement(ctx, agencyID, consolidation.Before, consolidation.After)
+	return consolidation, nil
+}
+
+func (s *agencyService) UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	consolidation, err := s.Service.UndoGoalConsolidation(ctx, agencyID, key)
+	if err != nil {
+		return nil, err
+	}
+	s.publishGoalReplacement(ctx, agencyID, consolidation.After, consolidation.Before)
+	return consolidation, nil
+}
+
+// publishGoalReplacement publishes the goals deleted and created by a consolidation or its undo
+func (s *agencyService) publishGoalReplacement(ctx context.Context, agencyID string, deleted, created []*agency.Goal) {
+	for _, goal := range deleted {
+		s.publish(ctx, agencyID, EntityGoal, ActionDeleted, goal.Key, nil)
+	}
+	for _, goal := range created {
+		s.publish(ctx, agencyID, EntityGoal, ActionCreated, goal.Key, map[string]interface{}{"code": goal.Code})
+	}
+}
+
+func (s *agencyService) CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error) {
+	workItem, err := s.Service.CreateWorkItem(ctx, agencyID, req)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, agencyID, EntityWorkItem, ActionCreated, workItem.Key, map[string]interface{}{"code": workItem.Code})
+	return workItem, nil
+}
+
+func (s *agencyService) UpdateWorkItem(ctx context.Context, agencyID string, key string, req agency.UpdateWorkItemRequest) error {
+	if err := s.Service.UpdateWorkItem(ctx, agencyID, key, req); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityWorkItem, ActionUpdated, key, nil)
+	return nil
+}
+
+func (s *agencyService) TransitionWorkItem(ctx context.Context, agencyID string, key string, req agency.TransitionWorkItemRequest) (*agency.WorkItem, error) {
+	workItem, err := s.Service.TransitionWorkItem(ctx, agencyID, key, req)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, agencyID, EntityWorkItem, ActionUpdated, key, map[string]interface{}{"code": workItem.Code, "status": workItem.Status})
+	return workItem, nil
+}
+
+func (s *agencyService) AssignWorkItem(ctx context.Context, agencyID string, key string, req agency.AssignWorkItemRequest) (*agency.WorkItem, error) {
+	workItem, err := s.Service.AssignWorkItem(ctx, agencyID, key, req)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, agencyID, EntityWorkItem, ActionUpdated, key, map[string]interface{}{"code": workItem.Code, "assignee": workItem.Assignee})
+	return workItem, nil
+}
+
+func (s *agencyService) DeleteWorkItem(ctx context.Context, agencyID string, key string) error {
+	if err := s.Service.DeleteWorkItem(ctx, agencyID, key); err != nil {
+		return err
+	}
+	s.publish(ctx, agencyID, EntityWorkItem, ActionDeleted, key, nil)
+	return nil
+}
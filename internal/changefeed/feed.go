@@ -0,0 +1,356 @@
+// Package changefeed broadcasts changes to an agency's design, such as goals,
+// work items and designer conversation messages, so that open designer
+// sessions update live.
+//
+// Every change gets a sequence number that serves as a cursor: a client that
+// reconnects with the last sequence it saw receives the changes it missed
+// from a bounded per-agency history. Changes to the same entity within the
+// coalescing window are merged into one.
+package changefeed
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Entities whose changes are broadcast
+const (
+	EntityGoal                = "goal"
+	EntityWorkItem            = "work_item"
+	EntityOverview            = "overview"
+	EntityConversationMessage = "conversation_message"
+)
+
+// Change actions
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+const (
+	defaultCoalesceWindow   = 250 * time.Millisecond
+	defaultHistorySize      = 500
+	defaultSubscriberBuffer = 64
+	defaultHeartbeat        = 15 * time.Second
+)
+
+// Change is a notification that an entity of an agency changed
+type Change struct {
+	// Seq orders changes across all agencies and is the reconnect cursor
+	Seq uint64 `json:"seq"`
+
+	AgencyID  string `json:"agency_id"`
+	Entity    string `json:"entity"`
+	Action    string `json:"action"`
+	EntityKey string `json:"entity_key,omitempty"`
+
+	// Actor identifies who made the change, so a session can skip its own
+	Actor Actor `json:"actor"`
+
+	// Data carries a small summary of the change, such as a goal's code
+	Data map[string]interface{} `json:"data,omitempty"`
+
+	// Coalesced is the number of changes merged into this one
+	Coalesced int       `json:"coalesced"`
+	Time      time.Time `json:"time"`
+}
+
+// coalesceKey identifies changes that are merged within the window
+func (c *Change) coalesceKey() string {
+	return c.AgencyID + "|" + c.Entity + "|" + c.EntityKey
+}
+
+// merge folds a later change to the same entity into c. A created entity
+// stays created when it is updated; one that is deleted ends deleted.
+func (c *Change) merge(later *Change) {
+	if !(c.Action == ActionCreated && later.Action == ActionUpdated) {
+		c.Action = later.Action
+	}
+	if later.Actor != (Actor{}) {
+		c.Actor = later.Actor
+	}
+	if later.Data != nil {
+		if c.Data == nil {
+			c.Data = make(map[string]interface{}, len(later.Data))
+		}
+		for k, v := range later.Data {
+			c.Data[k] = v
+		}
+	}
+	c.Coalesced += later.Coalesced
+	c.Time = later.Time
+}
+
+// Config configures the feed
+type Config struct {
+	// CoalesceWindow delays changes so that repeated changes to an entity
+	// are sent once (negative sends every change immediately)
+	CoalesceWindow time.Duration
+
+	// HistorySize is the number of changes kept per agency for reconnects
+	HistorySize int
+
+	// SubscriberBuffer is the number of changes queued for a slow client
+	// before it is told to resync
+	SubscriberBuffer int
+
+	// Heartbeat is how often idle streams send a keep-alive
+	Heartbeat time.Duration
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.ChangeFeedConfig) Config {
+	return Config{
+		CoalesceWindow:   time.Duration(cfg.CoalesceMillis) * time.Millisecond,
+		HistorySize:      cfg.HistorySize,
+		SubscriberBuffer: cfg.SubscriberBuffer,
+		Heartbeat:        time.Duration(cfg.HeartbeatSeconds) * time.Second,
+	}
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.CoalesceWindow == 0 {
+		c.CoalesceWindow = defaultCoalesceWindow
+	}
+	if c.HistorySize <= 0 {
+		c.HistorySize = defaultHistorySize
+	}
+	if c.SubscriberBuffer <= 0 {
+		c.SubscriberBuffer = defaultSubscriberBuffer
+	}
+	if c.Heartbeat <= 0 {
+		c.Heartbeat = defaultHeartbeat
+	}
+	return c
+}
+
+// Subscription receives an agency's changes
+type Subscription struct {
+	// Backlog holds the changes after the subscriber's cursor that were
+	// published before it subscribed
+	Backlog []Change
+
+	// Reset is set when changes after the cursor are no longer in the
+	// history; the client must reload instead of applying the backlog
+	Reset bool
+
+	// C delivers new changes. It is closed when the subscription is closed
+	// or the subscriber fell too far behind, in which case Lagged is true.
+	C <-chan Change
+
+	feed     *Feed
+	agencyID string
+	ch       chan Change
+	lagged   bool
+	closed   bool
+}
+
+// Lagged reports whether the subscription was dropped for falling behind.
+// It is valid once C is closed.
+func (s *Subscription) Lagged() bool {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.lagged
+}
+
+// Close stops delivery to the subscription
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	s.feed.unsubscribe(s)
+}
+
+// Feed distributes agency changes to subscribers
+type Feed struct {
+	config Config
+	logger *log.Logger
+
+	mu          sync.Mutex
+	seq         uint64
+	history     map[string][]Change
+	subscribers map[string]map[*Subscription]bool
+	pending     map[string]*Change
+	order       []string
+	timer       *time.Timer
+}
+
+// NewFeed creates a new change feed
+func NewFeed(cfg Config, logger *log.Logger) *Feed {
+	return &Feed{
+		config:      cfg.withDefaults(),
+		logger:      logger,
+		history:     make(map[string][]Change),
+		subscribers: make(map[string]map[*Subscription]bool),
+		pending:     make(map[string]*Change),
+	}
+}
+
+// Heartbeat returns how often idle streams send a keep-alive
+func (f *Feed) Heartbeat() time.Duration {
+	return f.config.Heartbeat
+}
+
+// Publish records a change. Within the coalescing window it is merged with
+// earlier changes to the same entity that have not been sent yet.
+func (f *Feed) Publish(change Change) {
+	if change.AgencyID == "" {
+		return
+	}
+	if change.Time.IsZero() {
+		change.Time = time.Now().UTC()
+	}
+	change.Coalesced = 1
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.config.CoalesceWindow < 0 {
+		f.emit(&change)
+		return
+	}
+
+	key := change.coalesceKey()
+	if change.EntityKey == "" {
+		// Changes without an entity key cannot be merged; keep them apart
+		key += "#" + strconv.Itoa(len(f.order))
+	} else if existing, ok := f.pending[key]; ok {
+		existing.merge(&change)
+		return
+	}
+	f.pending[key] = &change
+	f.order = append(f.order, key)
+
+	if f.timer == nil {
+		f.timer = time.AfterFunc(f.config.CoalesceWindow, f.Flush)
+	}
+}
+
+// Flush sends the changes waiting in the coalescing window
+func (f *Feed) Flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	for _, key := range f.order {
+		f.emit(f.pending[key])
+	}
+	f.pending = make(map[string]*Change)
+	f.order = nil
+}
+
+// emit assigns the change its sequence, records it and delivers it.
+// Callers hold the lock.
+func (f *Feed) emit(change *Change) {
+	f.seq++
+	change.Seq = f.seq
+
+	history := append(f.history[change.AgencyID], *change)
+	if len(history) > f.config.HistorySize {
+		history = history[len(history)-f.config.HistorySize:]
+	}
+	f.history[change.AgencyID] = history
+
+	for sub := range f.subscribers[change.AgencyID] {
+		select {
+		case sub.ch <- *change:
+		default:
+			sub.lagged = true
+			f.unsubscribe(sub)
+			f.logger.WithField("agency_id", change.AgencyID).Warn("Change feed subscriber fell behind and was dropped")
+		}
+	}
+}
+
+// Subscribe starts receiving an agency's changes after the cursor (0 for
+// only new changes)
+func (f *Feed) Subscribe(agencyID string, after uint64) *Subscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan Change, f.config.SubscriberBuffer)
+	sub := &Subscription{
+		C:        ch,
+		feed:     f,
+		agencyID: agencyID,
+		ch:       ch,
+	}
+	if after > 0 {
+		sub.Backlog, sub.Reset = f.since(agencyID, after)
+	}
+
+	if f.subscribers[agencyID] == nil {
+		f.subscribers[agencyID] = make(map[*Subscription]bool)
+	}
+	f.subscribers[agencyID][sub] = true
+	return sub
+}
+
+// Since returns an agency's changes after the cursor and whether changes
+// after it were dropped from the history
+func (f *Feed) Since(agencyID string, after uint64) ([]Change, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.since(agencyID, after)
+}
+
+// Seq returns the sequence of the latest change
+func (f *Feed) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+// since implements Since. Callers hold the lock.
+func (f *Feed) since(agencyID string, after uint64) ([]Change, bool) {
+	history := f.history[agencyID]
+
+	// The history misses changes when its oldest entry is not the first
+	// change after the cursor and older ones were trimmed
+	reset := len(history) == f.config.HistorySize && history[0].Seq > after+1
+	if after > f.seq {
+		// A cursor from before a restart cannot be resumed
+		reset = true
+	}
+
+	var changes []Change
+	for _, change := range history {
+		if change.Seq > after {
+			changes = append(changes, change)
+		}
+	}
+	return changes, reset
+}
+
+// unsubscribe removes a subscription and closes its channel. Callers hold the lock.
+func (f *Feed) unsubscribe(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(f.subscribers[sub.agencyID], sub)
+	if len(f.subscribers[sub.agencyID]) == 0 {
+		delete(f.subscribers, sub.agencyID)
+	}
+	close(sub.ch)
+}
+
+// Subscribers returns the number of open subscriptions per agency
+func (f *Feed) Subscribers() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := make(map[string]int, len(f.subscribers))
+	for agencyID, subs := range f.subscribers {
+		counts[agencyID] = len(subs)
+	}
+	return counts
+}
@@ -0,0 +1,150 @@
+package changefeed
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newTestFeed returns a feed that only sends coalesced changes on Flush
+func newTestFeed(cfg Config) *Feed {
+	if cfg.CoalesceWindow == 0 {
+		cfg.CoalesceWindow = time.Hour
+	}
+	return NewFeed(cfg, testLogger())
+}
+
+func receive(t *testing.T, sub *Subscription) Change {
+	t.Helper()
+	select {
+	case change, ok := <-sub.C:
+		require.True(t, ok, "subscription closed")
+		return change
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+	}
+	return Change{}
+}
+
+func TestFeed_CoalescesChangesToAnEntity(t *testing.T) {
+	feed := newTestFeed(Config{})
+	sub := feed.Subscribe("a1", 0)
+	defer sub.Close()
+
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g1", Data: map[string]interface{}{"code": "G001"}})
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionUpdated, EntityKey: "g1", Data: map[string]interface{}{"code": "G002"}})
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityWorkItem, Action: ActionUpdated, EntityKey: "w1"})
+	feed.Publish(Change{AgencyID: "a2", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g9"})
+	assert.Empty(t, sub.C, "changes are held for the coalescing window")
+
+	feed.Flush()
+
+	goal := receive(t, sub)
+	assert.Equal(t, ActionCreated, goal.Action, "a created entity stays created")
+	assert.Equal(t, "G002", goal.Data["code"])
+	assert.Equal(t, 2, goal.Coalesced)
+
+	workItem := receive(t, sub)
+	assert.Equal(t, "w1", workItem.EntityKey)
+	assert.Greater(t, workItem.Seq, goal.Seq)
+	assert.Empty(t, sub.C, "other agencies' changes are not delivered")
+}
+
+func TestFeed_WindowSendsChangesWithoutFlush(t *testing.T) {
+	feed := NewFeed(Config{CoalesceWindow: 10 * time.Millisecond}, testLogger())
+	sub := feed.Subscribe("a1", 0)
+	defer sub.Close()
+
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityOverview, Action: ActionUpdated, EntityKey: "a1"})
+	assert.Equal(t, EntityOverview, receive(t, sub).Entity)
+}
+
+func TestFeed_ResumesFromCursor(t *testing.T) {
+	feed := newTestFeed(Config{CoalesceWindow: -1, HistorySize: 3})
+	for _, key := range []string{"g1", "g2", "g3"} {
+		feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: key})
+	}
+
+	sub := feed.Subscribe("a1", 1)
+	require.False(t, sub.Reset)
+	require.Len(t, sub.Backlog, 2)
+	assert.Equal(t, "g2", sub.Backlog[0].EntityKey)
+	sub.Close()
+
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g4"})
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g5"})
+
+	// Changes 2 and 3 have been trimmed from the history
+	_, reset := feed.Since("a1", 1)
+	assert.True(t, reset)
+	changes, reset := feed.Since("a1", 3)
+	assert.False(t, reset)
+	assert.Len(t, changes, 2)
+
+	// A cursor ahead of the feed predates a restart
+	_, reset = feed.Since("a1", 99)
+	assert.True(t, reset)
+}
+
+func TestFeed_DropsLaggingSubscriber(t *testing.T) {
+	feed := newTestFeed(Config{CoalesceWindow: -1, SubscriberBuffer: 1})
+	sub := feed.Subscribe("a1", 0)
+
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g1"})
+	feed.Publish(Change{AgencyID: "a1", Entity: EntityGoal, Action: ActionCreated, EntityKey: "g2"})
+
+	<-sub.C
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.True(t, sub.Lagged())
+	assert.Empty(t, feed.Subscribers())
+
+	sub.Close()
+}
+
+// goalService is the part of agency.Service the wrapper is tested with
+type goalService struct {
+	agency.Service
+	err error
+}
+
+func (s *goalService) CreateGoal(ctx context.Context, agencyID, code, description string) (*agency.Goal, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &agency.Goal{Key: "g1", Code: code, Description: description}, nil
+}
+
+func TestWrapAgencyService_PublishesSuccessfulChanges(t *testing.T) {
+	feed := newTestFeed(Config{CoalesceWindow: -1})
+	sub := feed.Subscribe("a1", 0)
+	defer sub.Close()
+
+	inner := &goalService{}
+	service := WrapAgencyService(inner, feed)
+	ctx := WithActor(context.Background(), Actor{UserID: "u1", Session: "s1"})
+
+	_, err := service.CreateGoal(ctx, "a1", "G001", "Reduce outages")
+	require.NoError(t, err)
+	change := receive(t, sub)
+	assert.Equal(t, EntityGoal, change.Entity)
+	assert.Equal(t, "G001", change.Data["code"])
+	assert.Equal(t, Actor{UserID: "u1", Session: "s1"}, change.Actor)
+
+	inner.err = assert.AnError
+	_, err = service.CreateGoal(ctx, "a1", "G002", "Reduce outages")
+	assert.Error(t, err)
+	assert.Empty(t, sub.C, "failed changes are not published")
+}
@@ -0,0 +1,60 @@
+// Package clock provides an injectable source of time so that TTLs, retries
+// and timeouts can be driven by a controllable clock in tests and simulations.
+package clock
+
+import "time"
+
+// Clock tells the time and waits for durations to pass
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel
+	After(d time.Duration) <-chan time.Time
+
+	// NewTicker returns a ticker that sends the time every period
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a Clock at intervals
+type Ticker interface {
+	// C returns the channel on which ticks are delivered
+	C() <-chan time.Time
+
+	// Stop turns off the ticker
+	Stop()
+}
+
+// Real returns the clock backed by the time package
+func Real() Clock {
+	return realClock{}
+}
+
+// OrReal returns c, or the real clock if c is nil
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time { return t.ticker.C }
+func (t *realTicker) Stop()               { t.ticker.Stop() }
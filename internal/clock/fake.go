@@ -0,0 +1,149 @@
+package clock
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. Timers and tickers created
+// from it fire as Advance or Set moves the time past their deadlines.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// waiter is a pending After channel or ticker
+type waiter struct {
+	deadline time.Time
+	period   time.Duration // zero for one-shot waiters
+	ch       chan time.Time
+}
+
+// NewFake creates a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// After returns a channel that receives the fake time once it has advanced by d
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &waiter{deadline: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- f.now
+		return w.ch
+	}
+	f.waiters = append(f.waiters, w)
+	return w.ch
+}
+
+// NewTicker returns a ticker that ticks every d of fake time. Like
+// time.Ticker, ticks are dropped when the receiver falls behind.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &waiter{deadline: f.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.waiters = append(f.waiters, w)
+	return &fakeTicker{clock: f, waiter: w}
+}
+
+// Advance moves the fake time forward by d, firing due timers and tickers
+func (f *Fake) Advance(d time.Duration) time.Time {
+	if d < 0 {
+		panic("clock: negative duration for Advance")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.moveTo(f.now.Add(d))
+	return f.now
+}
+
+// Set moves the fake time to t, firing due timers and tickers. The clock
+// cannot go backwards.
+func (f *Fake) Set(t time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t.Before(f.now) {
+		return fmt.Errorf("cannot set clock back from %s to %s", f.now.Format(time.RFC3339), t.Format(time.RFC3339))
+	}
+	f.moveTo(t)
+	return nil
+}
+
+// Waiters returns the number of pending timers and tickers, which lets
+// tests wait until a goroutine is blocked on the clock before advancing it
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// moveTo fires waiters in deadline order up to t. Callers must hold f.mu.
+func (f *Fake) moveTo(t time.Time) {
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool {
+			return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+		})
+		if len(f.waiters) == 0 || f.waiters[0].deadline.After(t) {
+			break
+		}
+
+		w := f.waiters[0]
+		f.now = w.deadline
+		select {
+		case w.ch <- w.deadline:
+		default:
+		}
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+		}
+	}
+	f.now = t
+}
+
+// remove drops a stopped ticker from the pending waiters
+func (f *Fake) remove(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *waiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.waiter.ch }
+func (t *fakeTicker) Stop()               { t.clock.remove(t.waiter) }
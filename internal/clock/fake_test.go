@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake_AfterAndTicker(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFake(start)
+
+	timer := clk.After(time.Minute)
+	ticker := clk.NewTicker(20 * time.Second)
+	defer ticker.Stop()
+
+	if clk.Waiters() != 2 {
+		t.Fatalf("expected 2 waiters, got %d", clk.Waiters())
+	}
+
+	clk.Advance(30 * time.Second)
+	select {
+	case <-timer:
+		t.Fatal("timer fired early")
+	default:
+	}
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(20 * time.Second)) {
+			t.Errorf("expected tick at 20s, got %s", tick)
+		}
+	default:
+		t.Fatal("expected a tick")
+	}
+
+	clk.Advance(30 * time.Second)
+	select {
+	case fired := <-timer:
+		if !fired.Equal(start.Add(time.Minute)) {
+			t.Errorf("expected timer at 1m, got %s", fired)
+		}
+	default:
+		t.Fatal("expected timer to fire")
+	}
+	if got := clk.Since(start); got != time.Minute {
+		t.Errorf("expected 1m elapsed, got %s", got)
+	}
+
+	ticker.Stop()
+	if clk.Waiters() != 0 {
+		t.Errorf("expected no waiters after stop, got %d", clk.Waiters())
+	}
+}
+
+func TestFake_Set(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := NewFake(start)
+	timer := clk.After(time.Hour)
+
+	if err := clk.Set(start.Add(-time.Second)); err == nil {
+		t.Error("expected setting the clock back to fail")
+	}
+	if err := clk.Set(start.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-timer:
+	default:
+		t.Fatal("expected timer to fire")
+	}
+	if !clk.Now().Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("unexpected time %s", clk.Now())
+	}
+
+	select {
+	case <-clk.After(0):
+	default:
+		t.Error("expected zero duration to fire immediately")
+	}
+}
@@ -0,0 +1,215 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// VirtualConfig configures a virtual clock
+type VirtualConfig struct {
+	// Start is the initial simulated time (default: the current time)
+	Start time.Time
+
+	// Speed is how many simulated seconds pass per real second (default 1)
+	Speed float64
+
+	// Paused starts the clock paused, so it only moves when advanced
+	Paused bool
+
+	// Resolution is how often a running clock fires due timers (default 10ms)
+	Resolution time.Duration
+
+	// Base is the real time the clock runs against (default: the real clock)
+	Base Clock
+}
+
+func (c VirtualConfig) withDefaults() VirtualConfig {
+	c.Base = OrReal(c.Base)
+	if c.Start.IsZero() {
+		c.Start = c.Base.Now()
+	}
+	if c.Speed <= 0 {
+		c.Speed = 1
+	}
+	if c.Resolution <= 0 {
+		c.Resolution = 10 * time.Millisecond
+	}
+	return c
+}
+
+// VirtualStatus is the state of a virtual clock
+type VirtualStatus struct {
+	Now    time.Time `json:"now"`
+	Speed  float64   `json:"speed"` // Simulated seconds per real second while running
+	Paused bool      `json:"paused"`
+}
+
+// Virtual is a Clock for simulations. It runs at a multiple of real time,
+// can be paused, and can be stepped forward; timers and tickers created from
+// it fire as simulated time passes their deadlines either way.
+type Virtual struct {
+	fake   *Fake
+	config VirtualConfig
+
+	mu     sync.Mutex
+	speed  float64
+	paused bool
+	synced time.Time // Real time the simulated time was last brought up to date
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewVirtual creates a virtual clock
+func NewVirtual(config VirtualConfig) *Virtual {
+	config = config.withDefaults()
+	return &Virtual{
+		fake:   NewFake(config.Start),
+		config: config,
+		speed:  config.Speed,
+		paused: config.Paused,
+		synced: config.Base.Now(),
+	}
+}
+
+// Start fires due timers and tickers while the clock runs, until Stop is
+// called or the context ends. Without it, timers only fire when the clock
+// is read or advanced.
+func (v *Virtual) Start(ctx context.Context) {
+	ctx, v.cancel = context.WithCancel(ctx)
+	v.done = make(chan struct{})
+
+	go func() {
+		defer close(v.done)
+
+		ticker := v.config.Base.NewTicker(v.config.Resolution)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				v.sync()
+			}
+		}
+	}()
+}
+
+// Stop stops firing timers in the background
+func (v *Virtual) Stop() {
+	if v.cancel == nil {
+		return
+	}
+	v.cancel()
+	<-v.done
+}
+
+// Now returns the simulated time
+func (v *Virtual) Now() time.Time {
+	v.sync()
+	return v.fake.Now()
+}
+
+// Since returns the simulated time elapsed since t
+func (v *Virtual) Since(t time.Time) time.Duration {
+	return v.Now().Sub(t)
+}
+
+// After returns a channel that receives the simulated time once d of it has
+// passed
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	v.sync()
+	return v.fake.After(d)
+}
+
+// NewTicker returns a ticker that ticks every d of simulated time
+func (v *Virtual) NewTicker(d time.Duration) Ticker {
+	v.sync()
+	return v.fake.NewTicker(d)
+}
+
+// Pause stops simulated time until Resume is called. Advance and Set still
+// move a paused clock.
+func (v *Virtual) Pause() VirtualStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	v.paused = true
+	return v.statusLocked()
+}
+
+// Resume runs a paused clock again at its speed
+func (v *Virtual) Resume() VirtualStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	v.paused = false
+	return v.statusLocked()
+}
+
+// SetSpeed sets how many simulated seconds pass per real second
+func (v *Virtual) SetSpeed(speed float64) (VirtualStatus, error) {
+	if speed <= 0 {
+		return VirtualStatus{}, errors.New("speed must be positive")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	v.speed = speed
+	return v.statusLocked(), nil
+}
+
+// Advance steps the simulated time forward by d, firing due timers and
+// tickers, whether or not the clock is paused
+func (v *Virtual) Advance(d time.Duration) VirtualStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	v.fake.Advance(d)
+	return v.statusLocked()
+}
+
+// Set moves the simulated time to t. The clock cannot go backwards.
+func (v *Virtual) Set(t time.Time) (VirtualStatus, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	if err := v.fake.Set(t); err != nil {
+		return VirtualStatus{}, err
+	}
+	return v.statusLocked(), nil
+}
+
+// Status returns the simulated time, speed and whether the clock is paused
+func (v *Virtual) Status() VirtualStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+	return v.statusLocked()
+}
+
+// sync brings the simulated time up to date with real time
+func (v *Virtual) sync() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.syncLocked()
+}
+
+// syncLocked moves a running clock forward by the real time elapsed since
+// the last sync times its speed. Callers must hold v.mu.
+func (v *Virtual) syncLocked() {
+	now := v.config.Base.Now()
+	elapsed := now.Sub(v.synced)
+	v.synced = now
+	if v.paused || elapsed <= 0 {
+		return
+	}
+	v.fake.Advance(time.Duration(float64(elapsed) * v.speed))
+}
+
+func (v *Virtual) statusLocked() VirtualStatus {
+	return VirtualStatus{Now: v.fake.Now(), Speed: v.speed, Paused: v.paused}
+}
@@ -0,0 +1,90 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestVirtual_RunsAtSpeed(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	wall := NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	clk := NewVirtual(VirtualConfig{Start: start, Speed: 60, Base: wall})
+
+	timer := clk.After(time.Hour)
+	wall.Advance(30 * time.Second)
+	if got := clk.Since(start); got != 30*time.Minute {
+		t.Errorf("expected 30m at 60x, got %s", got)
+	}
+	select {
+	case <-timer:
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	if _, err := clk.SetSpeed(0); err == nil {
+		t.Error("expected a zero speed to be refused")
+	}
+	if _, err := clk.SetSpeed(120); err != nil {
+		t.Fatal(err)
+	}
+	wall.Advance(15 * time.Second)
+	status := clk.Status()
+	if !status.Now.Equal(start.Add(time.Hour)) || status.Speed != 120 || status.Paused {
+		t.Errorf("unexpected status %+v", status)
+	}
+	select {
+	case <-timer:
+	default:
+		t.Fatal("expected timer to fire")
+	}
+}
+
+func TestVirtual_PauseAndStep(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	wall := NewFake(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	clk := NewVirtual(VirtualConfig{Start: start, Paused: true, Base: wall})
+
+	ticker := clk.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	wall.Advance(time.Hour)
+	if !clk.Now().Equal(start) {
+		t.Errorf("expected a paused clock to stand still, got %s", clk.Now())
+	}
+
+	// Four weeks in one step fires the daily ticker; dropped ticks are not queued
+	status := clk.Advance(28 * 24 * time.Hour)
+	if !status.Now.Equal(start.Add(28*24*time.Hour)) || !status.Paused {
+		t.Errorf("unexpected status %+v", status)
+	}
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("expected a tick")
+	}
+
+	if _, err := clk.Set(start); err == nil {
+		t.Error("expected setting the clock back to fail")
+	}
+
+	clk.Resume()
+	wall.Advance(time.Minute)
+	if got := clk.Since(start); got != 28*24*time.Hour+time.Minute {
+		t.Errorf("expected the resumed clock to run at 1x, got %s", got)
+	}
+}
+
+func TestVirtual_StartFiresTimers(t *testing.T) {
+	clk := NewVirtual(VirtualConfig{Speed: 1000, Resolution: time.Millisecond})
+	timer := clk.After(time.Minute)
+
+	clk.Start(context.Background())
+	defer clk.Stop()
+
+	select {
+	case <-timer:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the running clock to fire the timer")
+	}
+}
@@ -0,0 +1,156 @@
+package communication
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ExpiryConfig configures the sweeper removing expired publications
+type ExpiryConfig struct {
+	// Interval between sweeps (default 1m)
+	Interval time.Duration
+
+	// BatchSize is the number of publications removed per delete; a sweep
+	// keeps deleting until fewer are left (default 1000)
+	BatchSize int
+}
+
+func (c ExpiryConfig) withDefaults() ExpiryConfig {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 1000
+	}
+	return c
+}
+
+// ExpiryStats reports the publications removed by the expiry sweeper. Counts
+// per topic are part of the topic statistics.
+type ExpiryStats struct {
+	Sweeps      int64     `json:"sweeps"`
+	Expired     int64     `json:"expired"`
+	LastSweepAt time.Time `json:"last_sweep_at,omitempty"`
+	LastExpired int       `json:"last_expired"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// ExpirySweeper periodically removes publications whose TTL has passed.
+// Expired publications are never delivered, whether or not they were swept.
+type ExpirySweeper struct {
+	pubsub *PubSubService
+	config ExpiryConfig
+
+	mu    sync.Mutex
+	stats ExpiryStats
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewExpirySweeper creates a sweeper of the service's expired publications
+func NewExpirySweeper(pubsub *PubSubService, config ExpiryConfig) *ExpirySweeper {
+	return &ExpirySweeper{
+		pubsub: pubsub,
+		config: config.withDefaults(),
+	}
+}
+
+// Start sweeps on the configured interval until Stop is called
+func (s *ExpirySweeper) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep(ctx)
+			}
+		}
+	}()
+
+	log.WithFields(log.Fields{
+		"interval":   s.config.Interval,
+		"batch_size": s.config.BatchSize,
+	}).Info("Publication expiry sweeper started")
+}
+
+// Stop stops sweeping and waits for a running sweep to finish
+func (s *ExpirySweeper) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Sweep removes every expired publication and returns how many were removed
+func (s *ExpirySweeper) Sweep(ctx context.Context) (int, error) {
+	removed := 0
+	var sweepErr error
+	for ctx.Err() == nil {
+		counts, err := s.pubsub.repo.DeleteExpiredPublications(ctx, s.config.BatchSize)
+		if err != nil {
+			sweepErr = err
+			break
+		}
+		batch := s.pubsub.recordExpired(counts)
+		removed += batch
+
+		s.mu.Lock()
+		s.stats.Expired += int64(batch)
+		s.mu.Unlock()
+
+		if batch < s.config.BatchSize {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	s.stats.Sweeps++
+	s.stats.LastSweepAt = s.pubsub.clock.Now()
+	s.stats.LastExpired = removed
+	s.stats.LastError = ""
+	if sweepErr != nil {
+		s.stats.LastError = sweepErr.Error()
+	}
+	s.mu.Unlock()
+
+	if sweepErr != nil {
+		log.WithError(sweepErr).WithField("removed", removed).Error("Failed to sweep expired publications")
+		return removed, sweepErr
+	}
+	if removed > 0 {
+		log.WithField("count", removed).Info("Swept expired publications")
+	}
+	return removed, nil
+}
+
+// Stats returns the sweeper's expired publication counts
+func (s *ExpirySweeper) Stats() ExpiryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// recordExpired adds removed publications to the topic statistics and
+// returns the total removed
+func (ps *PubSubService) recordExpired(counts map[string]int) int {
+	total := 0
+	for topic, count := range counts {
+		ps.topics.recordExpired(topic, count)
+		total += count
+	}
+	return total
+}
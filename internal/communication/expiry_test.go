@@ -0,0 +1,60 @@
+package communication
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestExpirySweeper_Sweep tests removal of expired publications in batches
+func TestExpirySweeper_Sweep(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	publish := func(event string, expired bool) string {
+		id, err := svc.Publish(ctx, "SENSOR-001", "sensor", event, map[string]interface{}{}, nil)
+		if err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+		if expired {
+			repo.publications[id].ExpiresAt = time.Now().Add(-time.Minute)
+		}
+		return id
+	}
+	for i := 0; i < 3; i++ {
+		publish("zone.north.pressure", true)
+	}
+	publish("zone.south.pressure", true)
+	live := publish("zone.north.pressure", false)
+
+	sweeper := NewExpirySweeper(svc, ExpiryConfig{BatchSize: 2})
+	removed, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("Sweep removed %d, want 4", removed)
+	}
+	if len(repo.publications) != 1 || repo.publications[live] == nil {
+		t.Errorf("Expected only the live publication to remain, got %d", len(repo.publications))
+	}
+
+	stats := sweeper.Stats()
+	if stats.Sweeps != 1 || stats.Expired != 4 || stats.LastExpired != 4 || stats.LastError != "" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	expired := make(map[string]int64)
+	for _, topic := range svc.TopicStats().Topics {
+		expired[topic.Topic] = topic.Expired
+	}
+	if expired["zone.north.pressure"] != 3 || expired["zone.south.pressure"] != 1 {
+		t.Errorf("Unexpected expired counts per topic: %v", expired)
+	}
+
+	removed, _ = sweeper.Sweep(ctx)
+	if removed != 0 || sweeper.Stats().Sweeps != 2 || sweeper.Stats().Expired != 4 {
+		t.Errorf("Second sweep removed %d, stats %+v", removed, sweeper.Stats())
+	}
+}
@@ -0,0 +1,178 @@
+package communication
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// severityRanks orders well-known severity levels so that expressions like
+// "severity >= HIGH" compare by rank rather than alphabetically
+var severityRanks = map[string]int{
+	"DEBUG":    0,
+	"INFO":     1,
+	"LOW":      2,
+	"MEDIUM":   3,
+	"WARNING":  3,
+	"HIGH":     4,
+	"CRITICAL": 5,
+}
+
+// filterOperators are checked longest first so ">=" is not parsed as ">"
+var filterOperators = []string{">=", "<=", "!=", "==", ">", "<"}
+
+// filterClause is a single "field op value" comparison
+type filterClause struct {
+	path     []string
+	operator string
+	value    string
+}
+
+// FilterExpression is a parsed payload filter such as
+// "severity >= HIGH && zone == north". Clauses are joined with "&&" and
+// alternatives with "||" ("&&" binds tighter). Fields may use dot notation
+// to reach nested payload values.
+type FilterExpression struct {
+	source string
+	anyOf  [][]filterClause
+}
+
+// ParseFilterExpression parses a payload filter expression
+func ParseFilterExpression(expr string) (*FilterExpression, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("filter expression is empty")
+	}
+
+	parsed := &FilterExpression{source: expr}
+	for _, alternative := range strings.Split(expr, "||") {
+		var clauses []filterClause
+		for _, raw := range strings.Split(alternative, "&&") {
+			clause, err := parseFilterClause(raw)
+			if err != nil {
+				return nil, err
+			}
+			clauses = append(clauses, clause)
+		}
+		parsed.anyOf = append(parsed.anyOf, clauses)
+	}
+
+	return parsed, nil
+}
+
+// parseFilterClause parses a single comparison
+func parseFilterClause(raw string) (filterClause, error) {
+	raw = strings.TrimSpace(raw)
+	for _, op := range filterOperators {
+		idx := strings.Index(raw, op)
+		if idx < 0 {
+			continue
+		}
+
+		field := strings.TrimSpace(raw[:idx])
+		value := strings.Trim(strings.TrimSpace(raw[idx+len(op):]), `"'`)
+		if field == "" || value == "" {
+			return filterClause{}, fmt.Errorf("invalid filter clause: %q", raw)
+		}
+
+		return filterClause{
+			path:     strings.Split(field, "."),
+			operator: op,
+			value:    value,
+		}, nil
+	}
+
+	return filterClause{}, fmt.Errorf("filter clause has no operator: %q", raw)
+}
+
+// String returns the original expression
+func (fe *FilterExpression) String() string {
+	return fe.source
+}
+
+// Matches evaluates the expression against a publication payload.
+// Clauses referencing missing fields never match.
+func (fe *FilterExpression) Matches(payload map[string]interface{}) bool {
+	for _, clauses := range fe.anyOf {
+		matched := true
+		for _, clause := range clauses {
+			if !clause.matches(payload) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matches evaluates a single clause
+func (fc filterClause) matches(payload map[string]interface{}) bool {
+	actual, ok := lookupPayloadValue(payload, fc.path)
+	if !ok {
+		return false
+	}
+
+	cmp, ok := compareFilterValues(fmt.Sprintf("%v", actual), fc.value)
+	if !ok {
+		return false
+	}
+
+	switch fc.operator {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// lookupPayloadValue resolves a dotted path within the payload
+func lookupPayloadValue(payload map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = payload
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// compareFilterValues compares two values numerically, by severity rank, or
+// case-insensitively as strings, in that order of preference
+func compareFilterValues(actual, expected string) (int, bool) {
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if e, err := strconv.ParseFloat(expected, 64); err == nil {
+			switch {
+			case a < e:
+				return -1, true
+			case a > e:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	if a, ok := severityRanks[strings.ToUpper(actual)]; ok {
+		if e, ok := severityRanks[strings.ToUpper(expected)]; ok {
+			return a - e, true
+		}
+	}
+
+	return strings.Compare(strings.ToLower(actual), strings.ToLower(expected)), true
+}
@@ -0,0 +1,54 @@
+package communication
+
+import (
+	"testing"
+)
+
+// TestFilterExpression_Matches tests payload filter expression evaluation
+func TestFilterExpression_Matches(t *testing.T) {
+	payload := map[string]interface{}{
+		"severity": "HIGH",
+		"zone":     "North",
+		"pressure": 4.5,
+		"asset":    map[string]interface{}{"type": "pump"},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{name: "severity rank at threshold", expr: "severity >= HIGH", want: true},
+		{name: "severity rank above", expr: "severity > MEDIUM", want: true},
+		{name: "severity rank below", expr: "severity >= CRITICAL", want: false},
+		{name: "case-insensitive equality", expr: "zone == north", want: true},
+		{name: "numeric comparison", expr: "pressure < 5", want: true},
+		{name: "numeric comparison fails", expr: "pressure >= 10", want: false},
+		{name: "nested field", expr: "asset.type == 'pump'", want: true},
+		{name: "conjunction", expr: "severity >= HIGH && zone != south", want: true},
+		{name: "conjunction fails", expr: "severity >= HIGH && zone == south", want: false},
+		{name: "disjunction", expr: "zone == south || pressure > 4", want: true},
+		{name: "missing field never matches", expr: "temperature > 0", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseFilterExpression(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilterExpression(%q) error = %v", tt.expr, err)
+			}
+			if got := expr.Matches(payload); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseFilterExpression_Invalid tests rejection of malformed expressions
+func TestParseFilterExpression_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "severity", "== HIGH", "severity >= HIGH && "} {
+		if _, err := ParseFilterExpression(expr); err == nil {
+			t.Errorf("ParseFilterExpression(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrGuardrailViolation is returned when a command is blocked by a guardrail policy
+	ErrGuardrailViolation = errors.New("command blocked by guardrail policy")
+	// ErrViolationNotFound is returned for unknown or expired guardrail violations
+	ErrViolationNotFound = errors.New("guardrail violation not found")
+	// ErrViolationResolved is returned when a violation was already overridden or dismissed
+	ErrViolationResolved = errors.New("guardrail violation already resolved")
+)
+
+// GuardrailConfig configures the policies commands are checked against before delivery
+type GuardrailConfig struct {
+	// Policies constrain the commands sent by each agent type
+	Policies []GuardrailPolicy
+
+	// CommandField is the payload field naming the command (default "command")
+	CommandField string
+
+	// MaxViolations is the number of violations kept in the log (default 500)
+	MaxViolations int
+}
+
+// GuardrailPolicy declares the commands agents of a type may send
+type GuardrailPolicy struct {
+	// AgentType is the sender agent type the policy applies to ("*" for every type)
+	AgentType string `json:"agent_type"`
+
+	// MessageTypes are glob patterns of the message types the policy checks (default "command")
+	MessageTypes []string `json:"message_types,omitempty"`
+
+	// AllowedCommands are glob patterns of permitted command names; empty allows any command
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+
+	// ValueRanges bound numeric payload fields, keyed by dotted payload path
+	ValueRanges map[string]ValueRange `json:"value_ranges,omitempty"`
+
+	// Preconditions are filter expressions the payload must satisfy,
+	// e.g. "flow_rate < 50 && mode == manual"
+	Preconditions []string `json:"preconditions,omitempty"`
+
+	preconditions []*FilterExpression
+}
+
+// ValueRange bounds a numeric payload field. Nil bounds are unchecked.
+type ValueRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// ViolationStatus is the state of a guardrail violation in the override workflow
+type ViolationStatus string
+
+const (
+	// ViolationStatusBlocked means the command was held back and awaits review
+	ViolationStatusBlocked ViolationStatus = "blocked"
+	// ViolationStatusOverridden means an operator approved the command and it was delivered
+	ViolationStatusOverridden ViolationStatus = "overridden"
+	// ViolationStatusDismissed means an operator confirmed the command should not be delivered
+	ViolationStatusDismissed ViolationStatus = "dismissed"
+)
+
+// GuardrailViolation records a command that failed a guardrail policy
+type GuardrailViolation struct {
+	ID          string          `json:"id"`
+	AgentID     string          `json:"agent_id"`
+	AgentType   string          `json:"agent_type,omitempty"`
+	ToAgentID   string          `json:"to_agent_id"`
+	MessageType MessageType     `json:"message_type"`
+	Command     string          `json:"command,omitempty"`
+	Reasons     []string        `json:"reasons"`
+	Status      ViolationStatus `json:"status"`
+	DetectedAt  time.Time       `json:"detected_at"`
+
+	// ResolvedBy, Resolution and ResolvedAt record the operator decision
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+
+	// Message is the blocked message, delivered unchanged if overridden
+	Message *Message `json:"message"`
+}
+
+// GuardrailViolationError reports the violation that blocked a command
+type GuardrailViolationError struct {
+	Violation *GuardrailViolation
+}
+
+func (e *GuardrailViolationError) Error() string {
+	return fmt.Sprintf("%s (violation %s): %s", ErrGuardrailViolation, e.Violation.ID, strings.Join(e.Violation.Reasons, "; "))
+}
+
+// Unwrap makes the error match ErrGuardrailViolation
+func (e *GuardrailViolationError) Unwrap() error {
+	return ErrGuardrailViolation
+}
+
+// ViolationFilter selects violations to list
+type ViolationFilter struct {
+	AgentID string
+	Status  ViolationStatus
+	Limit   int
+}
+
+// AgentTypeResolver returns the type of an agent, or false if the agent is unknown
+type AgentTypeResolver func(agentID string) (string, bool)
+
+// commandGuardrails checks commands against policies and keeps the violations log
+type commandGuardrails struct {
+	policies      []GuardrailPolicy
+	commandField  string
+	maxViolations int
+	resolveType   AgentTypeResolver
+
+	mu         sync.RWMutex
+	violations []*GuardrailViolation
+	byID       map[string]*GuardrailViolation
+}
+
+func newCommandGuardrails(config GuardrailConfig, resolveType AgentTypeResolver) (*commandGuardrails, error) {
+	if config.CommandField == "" {
+		config.CommandField = "command"
+	}
+	if config.MaxViolations <= 0 {
+		config.MaxViolations = 500
+	}
+
+	policies := make([]GuardrailPolicy, 0, len(config.Policies))
+	for _, policy := range config.Policies {
+		if policy.AgentType == "" {
+			return nil, fmt.Errorf("guardrail policy requires an agent type")
+		}
+		if len(policy.MessageTypes) == 0 {
+			policy.MessageTypes = []string{string(MessageTypeCommand)}
+		}
+		for _, pattern := range append(append([]string{}, policy.MessageTypes...), policy.AllowedCommands...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q in guardrail policy for %s: %w", pattern, policy.AgentType, err)
+			}
+		}
+		for path, bounds := range policy.ValueRanges {
+			if bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max {
+				return nil, fmt.Errorf("invalid range for %s in guardrail policy for %s: min exceeds max", path, policy.AgentType)
+			}
+		}
+		policy.preconditions = nil
+		for _, expr := range policy.Preconditions {
+			parsed, err := ParseFilterExpression(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid precondition in guardrail policy for %s: %w", policy.AgentType, err)
+			}
+			policy.preconditions = append(policy.preconditions, parsed)
+		}
+		policies = append(policies, policy)
+	}
+
+	return &commandGuardrails{
+		policies:      policies,
+		commandField:  config.CommandField,
+		maxViolations: config.MaxViolations,
+		resolveType:   resolveType,
+		byID:          make(map[string]*GuardrailViolation),
+	}, nil
+}
+
+// check returns a violation if the message breaks a policy of its sender's type
+func (g *commandGuardrails) check(msg *Message, now time.Time) *GuardrailViolation {
+	agentType := ""
+	if g.resolveType != nil {
+		agentType, _ = g.resolveType(msg.FromAgentID)
+	}
+
+	command := ""
+	if value, ok := msg.Payload[g.commandField]; ok {
+		command = fmt.Sprintf("%v", value)
+	}
+
+	var reasons []string
+	for i := range g.policies {
+		policy := &g.policies[i]
+		if !policy.covers(agentType, msg.MessageType) {
+			continue
+		}
+		reasons = append(reasons, policy.evaluate(command, msg.Payload)...)
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	violation := &GuardrailViolation{
+		ID:          uuid.New().String(),
+		AgentID:     msg.FromAgentID,
+		AgentType:   agentType,
+		ToAgentID:   msg.ToAgentID,
+		MessageType: msg.MessageType,
+		Command:     command,
+		Reasons:     reasons,
+		Status:      ViolationStatusBlocked,
+		DetectedAt:  now,
+		Message:     msg,
+	}
+	g.record(violation)
+	return violation
+}
+
+// covers reports whether the policy applies to a message type sent by an agent type
+func (p *GuardrailPolicy) covers(agentType string, msgType MessageType) bool {
+	if p.AgentType != "*" && p.AgentType != agentType {
+		return false
+	}
+	for _, pattern := range p.MessageTypes {
+		if ok, _ := filepath.Match(pattern, string(msgType)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns the reasons a command breaks the policy
+func (p *GuardrailPolicy) evaluate(command string, payload map[string]interface{}) []string {
+	var reasons []string
+
+	if len(p.AllowedCommands) > 0 {
+		allowed := false
+		for _, pattern := range p.AllowedCommands {
+			if ok, _ := filepath.Match(pattern, command); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			reasons = append(reasons, fmt.Sprintf("command %q is not allowed for agent type %s", command, p.AgentType))
+		}
+	}
+
+	paths := make([]string, 0, len(p.ValueRanges))
+	for path := range p.ValueRanges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		bounds := p.ValueRanges[path]
+		raw, ok := lookupPayloadValue(payload, strings.Split(path, "."))
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(fmt.Sprintf("%v", raw), 64)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s must be numeric, got %v", path, raw))
+			continue
+		}
+		if bounds.Min != nil && value < *bounds.Min {
+			reasons = append(reasons, fmt.Sprintf("%s %v is below the minimum %v", path, value, *bounds.Min))
+		}
+		if bounds.Max != nil && value > *bounds.Max {
+			reasons = append(reasons, fmt.Sprintf("%s %v is above the maximum %v", path, value, *bounds.Max))
+		}
+	}
+
+	for _, precondition := range p.preconditions {
+		if !precondition.Matches(payload) {
+			reasons = append(reasons, fmt.Sprintf("precondition not met: %s", precondition))
+		}
+	}
+
+	return reasons
+}
+
+// record adds a violation to the log, dropping the oldest beyond the limit
+func (g *commandGuardrails) record(violation *GuardrailViolation) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.violations = append(g.violations, violation)
+	g.byID[violation.ID] = violation
+	if drop := len(g.violations) - g.maxViolations; drop > 0 {
+		for _, old := range g.violations[:drop] {
+			delete(g.byID, old.ID)
+		}
+		g.violations = append([]*GuardrailViolation(nil), g.violations[drop:]...)
+	}
+}
+
+// resolve moves a blocked violation to its final status
+func (g *commandGuardrails) resolve(violationID string, status ViolationStatus, by, resolution string, now time.Time) (*GuardrailViolation, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	violation, ok := g.byID[violationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrViolationNotFound, violationID)
+	}
+	if violation.Status != ViolationStatusBlocked {
+		return nil, fmt.Errorf("%w: %s is %s", ErrViolationResolved, violationID, violation.Status)
+	}
+
+	violation.Status = status
+	violation.ResolvedBy = by
+	violation.Resolution = resolution
+	violation.ResolvedAt = &now
+	copied := *violation
+	return &copied, nil
+}
+
+// reopen returns a violation to blocked after an override could not be delivered
+func (g *commandGuardrails) reopen(violationID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if violation, ok := g.byID[violationID]; ok {
+		violation.Status = ViolationStatusBlocked
+		violation.ResolvedBy = ""
+		violation.Resolution = ""
+		violation.ResolvedAt = nil
+	}
+}
+
+// SetGuardrails enables guardrail enforcement for commands. resolveType maps
+// sender agent IDs to agent types; senders it does not know are only checked
+// against "*" policies.
+func (ms *MessageService) SetGuardrails(config GuardrailConfig, resolveType AgentTypeResolver) error {
+	guardrails, err := newCommandGuardrails(config, resolveType)
+	if err != nil {
+		return err
+	}
+	ms.guardrails = guardrails
+	return nil
+}
+
+// GuardrailPolicies returns the configured guardrail policies
+func (ms *MessageService) GuardrailPolicies() []GuardrailPolicy {
+	if ms.guardrails == nil {
+		return []GuardrailPolicy{}
+	}
+	return append([]GuardrailPolicy(nil), ms.guardrails.policies...)
+}
+
+// ListViolations returns guardrail violations matching the filter, newest first
+func (ms *MessageService) ListViolations(filter ViolationFilter) []*GuardrailViolation {
+	violations := make([]*GuardrailViolation, 0)
+	if ms.guardrails == nil {
+		return violations
+	}
+
+	ms.guardrails.mu.RLock()
+	defer ms.guardrails.mu.RUnlock()
+
+	for i := len(ms.guardrails.violations) - 1; i >= 0; i-- {
+		violation := ms.guardrails.violations[i]
+		if filter.AgentID != "" && violation.AgentID != filter.AgentID {
+			continue
+		}
+		if filter.Status != "" && violation.Status != filter.Status {
+			continue
+		}
+		copied := *violation
+		violations = append(violations, &copied)
+		if filter.Limit > 0 && len(violations) == filter.Limit {
+			break
+		}
+	}
+	return violations
+}
+
+// GetViolation returns a guardrail violation by ID
+func (ms *MessageService) GetViolation(violationID string) (*GuardrailViolation, error) {
+	if ms.guardrails == nil {
+		return nil, fmt.Errorf("guardrails are not enabled")
+	}
+
+	ms.guardrails.mu.RLock()
+	defer ms.guardrails.mu.RUnlock()
+
+	violation, ok := ms.guardrails.byID[violationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrViolationNotFound, violationID)
+	}
+	copied := *violation
+	return &copied, nil
+}
+
+// OverrideViolation delivers a blocked command after an operator approves it.
+// The delivered message is marked with the violation it overrides.
+func (ms *MessageService) OverrideViolation(ctx context.Context, violationID, approvedBy, reason string) (*GuardrailViolation, error) {
+	if ms.guardrails == nil {
+		return nil, fmt.Errorf("guardrails are not enabled")
+	}
+	if approvedBy == "" || reason == "" {
+		return nil, fmt.Errorf("an approver and a reason are required to override a guardrail")
+	}
+
+	violation, err := ms.guardrails.resolve(violationID, ViolationStatusOverridden, approvedBy, reason, ms.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	msg := *violation.Message
+	msg.Metadata = make(map[string]string, len(violation.Message.Metadata)+2)
+	for key, value := range violation.Message.Metadata {
+		msg.Metadata[key] = value
+	}
+	msg.Metadata["guardrail_override"] = violation.ID
+	msg.Metadata["guardrail_approved_by"] = approvedBy
+
+	if err := ms.deliver(ctx, &msg); err != nil {
+		ms.guardrails.reopen(violationID)
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"violation_id": violationID,
+		"message_id":   msg.ID,
+		"approved_by":  approvedBy,
+	}).Warn("Guardrail overridden, command delivered")
+
+	return violation, nil
+}
+
+// DismissViolation closes a blocked command without delivering it
+func (ms *MessageService) DismissViolation(violationID, dismissedBy, reason string) (*GuardrailViolation, error) {
+	if ms.guardrails == nil {
+		return nil, fmt.Errorf("guardrails are not enabled")
+	}
+
+	return ms.guardrails.resolve(violationID, ViolationStatusDismissed, dismissedBy, reason, ms.clock.Now())
+}
@@ -0,0 +1,99 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMessageService_Guardrails(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockMessageRepo()
+	service := NewMessageService(repo)
+
+	maxSpeed := 3000.0
+	types := map[string]string{"llm-pump-1": "pump_controller"}
+	err := service.SetGuardrails(GuardrailConfig{
+		Policies: []GuardrailPolicy{{
+			AgentType:       "pump_controller",
+			AllowedCommands: []string{"set_pump_speed", "valve_*"},
+			ValueRanges:     map[string]ValueRange{"speed_rpm": {Max: &maxSpeed}},
+			Preconditions:   []string{"mode == automatic"},
+		}},
+	}, func(agentID string) (string, bool) {
+		agentType, ok := types[agentID]
+		return agentType, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands within policy are delivered
+	if _, err := service.SendMessage(ctx, "llm-pump-1", "pump-7", MessageTypeCommand, map[string]interface{}{"command": "set_pump_speed", "speed_rpm": 1200, "mode": "automatic"}, nil); err != nil {
+		t.Fatalf("expected command within policy to be sent, got %v", err)
+	}
+
+	// Other agent types and message types are not constrained
+	if _, err := service.SendMessage(ctx, "operator", "pump-7", MessageTypeCommand, map[string]interface{}{"command": "shutdown"}, nil); err != nil {
+		t.Fatalf("expected unconstrained agent type to be sent, got %v", err)
+	}
+	if _, err := service.SendMessage(ctx, "llm-pump-1", "pump-7", MessageTypeNotification, map[string]interface{}{"command": "shutdown"}, nil); err != nil {
+		t.Fatalf("expected notification to be sent, got %v", err)
+	}
+
+	_, err = service.SendMessage(ctx, "llm-pump-1", "pump-7", MessageTypeCommand, map[string]interface{}{"command": "set_pump_speed", "speed_rpm": 4500, "mode": "manual"}, nil)
+	var violationErr *GuardrailViolationError
+	if !errors.As(err, &violationErr) || !errors.Is(err, ErrGuardrailViolation) {
+		t.Fatalf("expected a guardrail violation, got %v", err)
+	}
+	violation := violationErr.Violation
+	if violation.AgentType != "pump_controller" || len(violation.Reasons) != 2 {
+		t.Errorf("expected range and precondition reasons, got %+v", violation.Reasons)
+	}
+	if len(repo.messages) != 3 {
+		t.Fatalf("expected the blocked command not to be stored, got %d messages", len(repo.messages))
+	}
+
+	_, err = service.SendMessage(ctx, "llm-pump-1", "pump-7", MessageTypeCommand, map[string]interface{}{"command": "drain_tank", "mode": "automatic"}, nil)
+	if !errors.Is(err, ErrGuardrailViolation) {
+		t.Fatalf("expected a disallowed command to be blocked, got %v", err)
+	}
+
+	blocked := service.ListViolations(ViolationFilter{Status: ViolationStatusBlocked})
+	if len(blocked) != 2 || blocked[0].Command != "drain_tank" {
+		t.Fatalf("expected two blocked violations newest first, got %+v", blocked)
+	}
+
+	// Overrides need an approver and a reason, and deliver the held command
+	if _, err := service.OverrideViolation(ctx, violation.ID, "", ""); err == nil {
+		t.Error("expected an override without approver to be rejected")
+	}
+	overridden, err := service.OverrideViolation(ctx, violation.ID, "shift-lead", "manual flushing procedure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overridden.Status != ViolationStatusOverridden || overridden.ResolvedAt == nil {
+		t.Errorf("unexpected overridden violation %+v", overridden)
+	}
+	delivered := repo.messages[violation.Message.ID]
+	if delivered == nil || delivered.Metadata["guardrail_override"] != violation.ID {
+		t.Fatalf("expected the command to be delivered with the override recorded, got %+v", delivered)
+	}
+	if _, err := service.OverrideViolation(ctx, violation.ID, "shift-lead", "again"); !errors.Is(err, ErrViolationResolved) {
+		t.Errorf("expected a second override to fail, got %v", err)
+	}
+
+	if _, err := service.DismissViolation(blocked[0].ID, "shift-lead", "not needed"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.DismissViolation("missing", "shift-lead", "x"); !errors.Is(err, ErrViolationNotFound) {
+		t.Errorf("expected unknown violation error, got %v", err)
+	}
+	if len(service.ListViolations(ViolationFilter{Status: ViolationStatusBlocked})) != 0 {
+		t.Error("expected no blocked violations left")
+	}
+
+	if err := service.SetGuardrails(GuardrailConfig{Policies: []GuardrailPolicy{{AgentType: "x", Preconditions: []string{"no operator"}}}}, nil); err == nil {
+		t.Error("expected an invalid precondition to be rejected")
+	}
+}
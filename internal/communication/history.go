@@ -0,0 +1,119 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	log "github.com/sirupsen/logrus"
+)
+
+// Message history query limits
+const (
+	DefaultMessageQueryLimit = 100
+	MaxMessageQueryLimit     = 1000
+)
+
+// ErrInvalidMessageQuery is returned for message queries with invalid bounds
+var ErrInvalidMessageQuery = errors.New("invalid message query")
+
+// MessageQuery selects stored messages for auditing. Empty fields match every
+// message; the time range applies to the message creation time and is
+// inclusive.
+type MessageQuery struct {
+	FromAgentID string
+	ToAgentID   string
+	MessageType MessageType
+	Since       time.Time
+	Until       time.Time
+	MinPriority int
+	MaxPriority int
+
+	// TenantID keeps the messages sent from or to the tenant's agents. The
+	// service sets it for callers scoped to a tenant.
+	TenantID string
+
+	// Limit is the maximum number of messages returned (default 100, at most 1000)
+	Limit int
+
+	// Offset skips that many matching messages, for paging
+	Offset int
+}
+
+// validate applies defaults and checks the query bounds
+func (q *MessageQuery) validate() error {
+	if q.Limit == 0 {
+		q.Limit = DefaultMessageQueryLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxMessageQueryLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidMessageQuery, MaxMessageQueryLimit)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidMessageQuery)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return fmt.Errorf("%w: until must not be before since", ErrInvalidMessageQuery)
+	}
+	for _, priority := range []int{q.MinPriority, q.MaxPriority} {
+		if priority != 0 && (priority < MinMessagePriority || priority > MaxMessagePriority) {
+			return fmt.Errorf("%w: priority must be between %d and %d", ErrInvalidMessageQuery, MinMessagePriority, MaxMessagePriority)
+		}
+	}
+	if q.MinPriority != 0 && q.MaxPriority != 0 && q.MaxPriority < q.MinPriority {
+		return fmt.Errorf("%w: max priority must not be below min priority", ErrInvalidMessageQuery)
+	}
+	return nil
+}
+
+// matches reports whether a message is selected by the query, ignoring paging
+func (q *MessageQuery) matches(msg *Message) bool {
+	switch {
+	case q.FromAgentID != "" && msg.FromAgentID != q.FromAgentID,
+		q.ToAgentID != "" && msg.ToAgentID != q.ToAgentID,
+		q.MessageType != "" && msg.MessageType != q.MessageType,
+		!q.Since.IsZero() && msg.CreatedAt.Before(q.Since),
+		!q.Until.IsZero() && msg.CreatedAt.After(q.Until),
+		q.MinPriority != 0 && msg.Priority < q.MinPriority,
+		q.MaxPriority != 0 && msg.Priority > q.MaxPriority,
+		q.TenantID != "" && !tenantMessage(q.TenantID, msg):
+		return false
+	}
+	return true
+}
+
+// tenantMessage reports whether a message was sent from or to one of the
+// tenant's agents
+func tenantMessage(tenantID string, msg *Message) bool {
+	prefix := tenantID + tenant.Separator
+	return strings.HasPrefix(msg.FromAgentID, prefix) || strings.HasPrefix(msg.ToAgentID, prefix)
+}
+
+// QueryMessages returns stored messages matching the query, newest first,
+// whatever their delivery status. Callers scoped to a tenant only see their
+// tenant's messages.
+func (ms *MessageService) QueryMessages(ctx context.Context, query MessageQuery) ([]*Message, error) {
+	if tenantID := tenant.FromContext(ctx); tenantID != "" {
+		query.TenantID = tenantID
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+
+	messages, err := ms.repo.QueryMessages(ctx, query)
+	if err != nil {
+		log.WithError(err).Error("Failed to query message history")
+		return nil, err
+	}
+	ms.openMessages(messages...)
+
+	log.WithFields(log.Fields{
+		"from_agent_id": query.FromAgentID,
+		"to_agent_id":   query.ToAgentID,
+		"count":         len(messages),
+	}).Debug("Queried message history")
+
+	return messages, nil
+}
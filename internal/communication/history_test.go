@@ -0,0 +1,165 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+)
+
+// TestMessageService_QueryMessages tests filtering the stored message history
+func TestMessageService_QueryMessages(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMessageService(newMockMessageRepo())
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	svc.SetClock(clk)
+
+	send := func(from, to string, msgType MessageType, priority int) string {
+		clk.Advance(time.Minute)
+		id, err := svc.SendMessage(ctx, from, to, msgType, map[string]interface{}{}, &MessageOptions{Priority: priority})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		return id
+	}
+	first := send("PUMP-002", "COORD-NORTH", "status_report", 5)
+	alarm := send("PUMP-002", "COORD-NORTH", "pressure_alarm", 9)
+	send("PUMP-002", "COORD-SOUTH", "status_report", 5)
+	send("PUMP-003", "COORD-NORTH", "status_report", 5)
+	last := send("PUMP-002", "COORD-NORTH", "status_report", 5)
+
+	ids := func(messages []*Message) []string {
+		var out []string
+		for _, msg := range messages {
+			out = append(out, msg.ID)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		query MessageQuery
+		want  []string
+	}{
+		{
+			name:  "sender and recipient, newest first",
+			query: MessageQuery{FromAgentID: "PUMP-002", ToAgentID: "COORD-NORTH"},
+			want:  []string{last, alarm, first},
+		},
+		{
+			name:  "time range",
+			query: MessageQuery{FromAgentID: "PUMP-002", ToAgentID: "COORD-NORTH", Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)},
+			want:  []string{alarm, first},
+		},
+		{
+			name:  "message type and priority",
+			query: MessageQuery{ToAgentID: "COORD-NORTH", MessageType: "pressure_alarm", MinPriority: 8},
+			want:  []string{alarm},
+		},
+		{
+			name:  "paging",
+			query: MessageQuery{FromAgentID: "PUMP-002", ToAgentID: "COORD-NORTH", Limit: 1, Offset: 1},
+			want:  []string{alarm},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := svc.QueryMessages(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("QueryMessages failed: %v", err)
+			}
+			got := ids(messages)
+			if len(got) != len(tt.want) {
+				t.Fatalf("messages = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("messages = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	invalid := []MessageQuery{
+		{Limit: MaxMessageQueryLimit + 1},
+		{Offset: -1},
+		{Since: start.Add(time.Hour), Until: start},
+		{MinPriority: 11},
+		{MinPriority: 8, MaxPriority: 3},
+	}
+	for _, query := range invalid {
+		if _, err := svc.QueryMessages(ctx, query); !errors.Is(err, ErrInvalidMessageQuery) {
+			t.Errorf("Expected ErrInvalidMessageQuery for %+v, got %v", query, err)
+		}
+	}
+}
+
+// TestMessageService_QueryMessages_TenantPaging tests that tenants page
+// through their own messages only, with every page but the last one full
+func TestMessageService_QueryMessages_TenantPaging(t *testing.T) {
+	repo := newMockMessageRepo()
+	svc := NewMessageService(repo)
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	svc.SetClock(clk)
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	var want []string
+	for i := 0; i < 7; i++ {
+		clk.Advance(time.Minute)
+		id, err := svc.SendMessage(acme, "acme--pump", "acme--coord", "status_report", map[string]interface{}{}, &MessageOptions{CorrelationID: "shift-1"})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		want = append([]string{id}, want...)
+		for j := 0; j < 2; j++ {
+			clk.Advance(time.Minute)
+			if _, err := svc.SendMessage(globex, "globex--pump", "globex--coord", "status_report", map[string]interface{}{}, &MessageOptions{CorrelationID: "shift-1"}); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+		}
+	}
+
+	var got []string
+	for offset := 0; ; offset += 3 {
+		page, err := svc.QueryMessages(acme, MessageQuery{Limit: 3, Offset: offset})
+		if err != nil {
+			t.Fatalf("QueryMessages failed: %v", err)
+		}
+		for _, msg := range page {
+			got = append(got, msg.ID)
+		}
+		if len(page) < 3 {
+			if len(got) < len(want) {
+				t.Fatalf("page at offset %d has %d messages before the last acme message", offset, len(page))
+			}
+			break
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("messages = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("messages = %v, want %v", got, want)
+		}
+	}
+
+	conversation, err := svc.GetConversationHistory(acme, "shift-1")
+	if err != nil {
+		t.Fatalf("GetConversationHistory failed: %v", err)
+	}
+	if len(conversation) != len(want) {
+		t.Errorf("conversation has %d messages, want %d", len(conversation), len(want))
+	}
+	for _, msg := range conversation {
+		if msg.FromAgentID != "acme--pump" {
+			t.Errorf("conversation includes message from %s", msg.FromAgentID)
+		}
+	}
+}
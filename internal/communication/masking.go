@@ -0,0 +1,552 @@
+package communication
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultUnmaskPermission reveals fields of rules that do not name a permission
+	DefaultUnmaskPermission = "payload.unmask"
+
+	// sealedPrefix marks payload values encrypted at rest
+	sealedPrefix = "sealed:v1:"
+)
+
+var (
+	// ErrUnmaskForbidden is returned when the caller lacks a permission needed to unmask a payload
+	ErrUnmaskForbidden = errors.New("missing permission to unmask payload")
+	// ErrMaskingDisabled is returned for unmask requests when no masking rules are configured
+	ErrMaskingDisabled = errors.New("payload masking is not enabled")
+)
+
+// MaskingConfig configures field-level masking of sensitive payload data
+type MaskingConfig struct {
+	// Rules select the payload fields to mask
+	Rules []MaskingRule
+
+	// Key is the AES key (16, 24 or 32 bytes) encrypting masked fields at rest
+	Key []byte
+
+	// Mask replaces masked values on read (default "***")
+	Mask string
+
+	// MaxAuditEntries is the number of unmask operations kept in memory
+	// when no audit log is set (default 1000)
+	MaxAuditEntries int
+}
+
+// MaskingRule masks payload fields of matching topics for readers lacking a permission
+type MaskingRule struct {
+	// Topics are glob patterns of publication event names and message types
+	Topics []string `json:"topics"`
+
+	// Fields are dotted payload paths, e.g. "customer.address"
+	Fields []string `json:"fields"`
+
+	// Permission reveals the fields (default "payload.unmask")
+	Permission string `json:"permission"`
+}
+
+// UnmaskRecord audits an unmask operation, whether or not it was allowed
+type UnmaskRecord struct {
+	ID          string    `json:"id"`
+	Actor       string    `json:"actor"`
+	Reason      string    `json:"reason"`
+	SubjectType string    `json:"subject_type"` // "message" or "publication"
+	SubjectID   string    `json:"subject_id"`
+	Topic       string    `json:"topic"`
+	Fields      []string  `json:"fields"`
+	Allowed     bool      `json:"allowed"`
+	Missing     []string  `json:"missing_permissions,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// UnmaskAuditLog stores unmask records. Without one, PayloadMasking keeps the
+// most recent records in memory.
+type UnmaskAuditLog interface {
+	// RecordUnmask stores an unmask record
+	RecordUnmask(ctx context.Context, record *UnmaskRecord) error
+
+	// UnmaskRecords returns unmask records, newest first, optionally limited to an actor
+	UnmaskRecords(ctx context.Context, actor string, limit int) ([]*UnmaskRecord, error)
+}
+
+// Permissions is the set of permissions held by a reader. The "*"
+// permission holds every permission.
+type Permissions map[string]bool
+
+// ParsePermissions builds a permission set from a comma-separated list
+func ParsePermissions(list string) Permissions {
+	permissions := make(Permissions)
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			permissions[p] = true
+		}
+	}
+	return permissions
+}
+
+// Has reports whether the set holds a permission
+func (p Permissions) Has(permission string) bool {
+	return p["*"] || p[permission]
+}
+
+// PayloadMasking encrypts the fields selected by masking rules before payloads
+// are stored, decrypts them for in-process consumers, and masks them in API
+// responses for readers lacking the rule's permission.
+type PayloadMasking struct {
+	rules []MaskingRule
+	aead  cipher.AEAD
+	mask  string
+	audit UnmaskAuditLog
+}
+
+// NewPayloadMasking validates the masking configuration
+func NewPayloadMasking(config MaskingConfig) (*PayloadMasking, error) {
+	if config.Mask == "" {
+		config.Mask = "***"
+	}
+	if config.MaxAuditEntries <= 0 {
+		config.MaxAuditEntries = 1000
+	}
+
+	block, err := aes.NewCipher(config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid masking key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid masking key: %w", err)
+	}
+
+	rules := make([]MaskingRule, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		if len(rule.Topics) == 0 || len(rule.Fields) == 0 {
+			return nil, fmt.Errorf("masking rule requires topics and fields")
+		}
+		for _, pattern := range rule.Topics {
+			if err := ValidateTopicPattern(pattern); err != nil {
+				return nil, fmt.Errorf("invalid topic pattern %q in masking rule: %w", pattern, err)
+			}
+		}
+		if rule.Permission == "" {
+			rule.Permission = DefaultUnmaskPermission
+		}
+		rules = append(rules, rule)
+	}
+
+	return &PayloadMasking{
+		rules: rules,
+		aead:  aead,
+		mask:  config.Mask,
+		audit: &memoryUnmaskLog{maxRecords: config.MaxAuditEntries},
+	}, nil
+}
+
+// SetAuditLog stores unmask records in the audit log instead of in memory
+func (m *PayloadMasking) SetAuditLog(audit UnmaskAuditLog) {
+	if audit != nil {
+		m.audit = audit
+	}
+}
+
+// Rules returns the configured masking rules
+func (m *PayloadMasking) Rules() []MaskingRule {
+	return append([]MaskingRule(nil), m.rules...)
+}
+
+// fields returns the masked fields of a topic with the permission revealing each
+func (m *PayloadMasking) fields(topic string) map[string]string {
+	fields := make(map[string]string)
+	for _, rule := range m.rules {
+		for _, pattern := range rule.Topics {
+			if MatchTopic(pattern, topic) {
+				for _, field := range rule.Fields {
+					fields[field] = rule.Permission
+				}
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// seal returns a copy of the payload with the topic's masked fields encrypted
+func (m *PayloadMasking) seal(topic string, payload map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	sealed := replacePayloadFields(payload, m.fields(topic), func(field string, value interface{}) interface{} {
+		if err != nil {
+			return value
+		}
+		var ciphertext string
+		ciphertext, err = m.encrypt(value)
+		if err != nil {
+			err = fmt.Errorf("failed to encrypt %s: %w", field, err)
+		}
+		return ciphertext
+	})
+	return sealed, err
+}
+
+// open returns a copy of the payload with every sealed value decrypted.
+// Values that fail to decrypt are logged and left sealed.
+func (m *PayloadMasking) open(payload map[string]interface{}) map[string]interface{} {
+	opened, _ := m.openValue(payload).(map[string]interface{})
+	return opened
+}
+
+func (m *PayloadMasking) openValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		opened := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			opened[key] = m.openValue(item)
+		}
+		return opened
+	case []interface{}:
+		opened := make([]interface{}, len(v))
+		for i, item := range v {
+			opened[i] = m.openValue(item)
+		}
+		return opened
+	case string:
+		if !strings.HasPrefix(v, sealedPrefix) {
+			return v
+		}
+		plain, err := m.decrypt(v)
+		if err != nil {
+			log.WithError(err).Warn("Failed to decrypt masked payload field")
+			return v
+		}
+		return plain
+	default:
+		return v
+	}
+}
+
+// Mask returns a copy of a topic's payload with the fields the reader lacks
+// permission for replaced by the mask, and the paths that were masked
+func (m *PayloadMasking) Mask(topic string, payload map[string]interface{}, permissions Permissions) (map[string]interface{}, []string) {
+	hidden := make(map[string]string)
+	for field, permission := range m.fields(topic) {
+		if !permissions.Has(permission) {
+			hidden[field] = permission
+		}
+	}
+
+	var masked []string
+	result := replacePayloadFields(payload, hidden, func(field string, value interface{}) interface{} {
+		masked = append(masked, field)
+		return m.mask
+	})
+	sort.Strings(masked)
+	return result, masked
+}
+
+// authorize checks that the reader may unmask the topic's fields present in
+// the payload and records the attempt in the audit log. An attempt that
+// cannot be recorded is refused.
+func (m *PayloadMasking) authorize(ctx context.Context, subjectType, subjectID, topic string, payload map[string]interface{}, actor, reason string, permissions Permissions, now time.Time) error {
+	record := &UnmaskRecord{
+		ID:          uuid.New().String(),
+		Actor:       actor,
+		Reason:      reason,
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Topic:       topic,
+		Fields:      []string{},
+		At:          now,
+	}
+
+	missing := make(map[string]bool)
+	for field, permission := range m.fields(topic) {
+		if _, ok := lookupPayloadValue(payload, strings.Split(field, ".")); !ok {
+			continue
+		}
+		record.Fields = append(record.Fields, field)
+		if !permissions.Has(permission) && !missing[permission] {
+			missing[permission] = true
+			record.Missing = append(record.Missing, permission)
+		}
+	}
+	sort.Strings(record.Fields)
+	sort.Strings(record.Missing)
+	record.Allowed = len(record.Missing) == 0
+
+	logger := log.WithFields(log.Fields{
+		"actor":        actor,
+		"subject_type": subjectType,
+		"subject_id":   subjectID,
+		"fields":       record.Fields,
+	})
+	if err := m.audit.RecordUnmask(ctx, record); err != nil {
+		logger.WithError(err).Error("Failed to audit unmask request")
+		return fmt.Errorf("failed to audit unmask request: %w", err)
+	}
+	if !record.Allowed {
+		logger.WithField("missing", record.Missing).Warn("Unmask denied")
+		return fmt.Errorf("%w: %s", ErrUnmaskForbidden, strings.Join(record.Missing, ", "))
+	}
+	logger.Info("Payload unmasked")
+	return nil
+}
+
+// AuditLog returns unmask records, newest first, optionally limited to an actor
+func (m *PayloadMasking) AuditLog(ctx context.Context, actor string, limit int) ([]*UnmaskRecord, error) {
+	return m.audit.UnmaskRecords(ctx, actor, limit)
+}
+
+// memoryUnmaskLog keeps the most recent unmask records in memory
+type memoryUnmaskLog struct {
+	mu         sync.RWMutex
+	records    []*UnmaskRecord
+	maxRecords int
+}
+
+// RecordUnmask adds an unmask record, dropping the oldest beyond the limit
+func (l *memoryUnmaskLog) RecordUnmask(ctx context.Context, record *UnmaskRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, record)
+	if drop := len(l.records) - l.maxRecords; drop > 0 {
+		l.records = append([]*UnmaskRecord(nil), l.records[drop:]...)
+	}
+	return nil
+}
+
+// UnmaskRecords returns unmask records, newest first, optionally limited to an actor
+func (l *memoryUnmaskLog) UnmaskRecords(ctx context.Context, actor string, limit int) ([]*UnmaskRecord, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	records := make([]*UnmaskRecord, 0)
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if actor != "" && l.records[i].Actor != actor {
+			continue
+		}
+		records = append(records, l.records[i])
+		if limit > 0 && len(records) == limit {
+			break
+		}
+	}
+	return records, nil
+}
+
+func (m *PayloadMasking) encrypt(value interface{}) (string, error) {
+	plain, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := m.aead.Seal(nonce, nonce, plain, nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (m *PayloadMasking) decrypt(value string) (interface{}, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < m.aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value too short")
+	}
+	nonce, ciphertext := raw[:m.aead.NonceSize()], raw[m.aead.NonceSize():]
+	plain, err := m.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(plain, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// replacePayloadFields returns a copy of the payload with the values at the
+// given dotted paths replaced. Maps along each path are copied so the
+// original payload is left unchanged.
+func replacePayloadFields(payload map[string]interface{}, fields map[string]string, replace func(field string, value interface{}) interface{}) map[string]interface{} {
+	if payload == nil || len(fields) == 0 {
+		return payload
+	}
+
+	paths := make([]string, 0, len(fields))
+	for field := range fields {
+		paths = append(paths, field)
+	}
+	sort.Strings(paths)
+
+	result := copyPayloadMap(payload)
+	for _, field := range paths {
+		keys := strings.Split(field, ".")
+		current := result
+		for i, key := range keys {
+			value, ok := current[key]
+			if !ok {
+				break
+			}
+			if i == len(keys)-1 {
+				current[key] = replace(field, value)
+				break
+			}
+			next, ok := value.(map[string]interface{})
+			if !ok {
+				break
+			}
+			next = copyPayloadMap(next)
+			current[key] = next
+			current = next
+		}
+	}
+	return result
+}
+
+func copyPayloadMap(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// SetMasking encrypts masked message fields at rest. Pass the same masking
+// to the pub/sub service so both share one key and audit log.
+func (ms *MessageService) SetMasking(masking *PayloadMasking) {
+	ms.masking = masking
+}
+
+// Masking returns the payload masking, or nil when masking is not enabled
+func (ms *MessageService) Masking() *PayloadMasking {
+	return ms.masking
+}
+
+// sealMessage returns the copy of a message to store, with masked fields encrypted
+func (ms *MessageService) sealMessage(msg *Message) (*Message, error) {
+	if ms.masking == nil {
+		return msg, nil
+	}
+	payload, err := ms.masking.seal(string(msg.MessageType), msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	stored := *msg
+	stored.Payload = payload
+	return &stored, nil
+}
+
+// openMessages decrypts the masked fields of stored messages
+func (ms *MessageService) openMessages(messages ...*Message) {
+	if ms.masking == nil {
+		return
+	}
+	for _, msg := range messages {
+		if msg != nil {
+			msg.Payload = ms.masking.open(msg.Payload)
+		}
+	}
+}
+
+// UnmaskMessage returns a message with its payload in the clear if the reader
+// holds the permissions of every masked field it contains. The attempt is
+// audited whether or not it is allowed.
+func (ms *MessageService) UnmaskMessage(ctx context.Context, messageID, actor, reason string, permissions Permissions) (*Message, error) {
+	if ms.masking == nil {
+		return nil, ErrMaskingDisabled
+	}
+	msg, err := ms.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.masking.authorize(ctx, "message", msg.ID, string(msg.MessageType), msg.Payload, actor, reason, permissions, ms.clock.Now()); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SetMasking encrypts masked publication fields at rest
+func (ps *PubSubService) SetMasking(masking *PayloadMasking) {
+	ps.masking = masking
+}
+
+// Masking returns the payload masking, or nil when masking is not enabled
+func (ps *PubSubService) Masking() *PayloadMasking {
+	return ps.masking
+}
+
+// sealPublication returns the copy of a publication to store, with masked fields encrypted
+func (ps *PubSubService) sealPublication(pub *Publication) (*Publication, error) {
+	if ps.masking == nil {
+		return pub, nil
+	}
+	payload, err := ps.masking.seal(pub.EventName, pub.Payload)
+	if err != nil {
+		return nil, err
+	}
+	stored := *pub
+	stored.Payload = payload
+	return &stored, nil
+}
+
+// openPublications decrypts the masked fields of stored publications
+func (ps *PubSubService) openPublications(publications ...*Publication) {
+	if ps.masking == nil {
+		return
+	}
+	for _, pub := range publications {
+		if pub != nil {
+			pub.Payload = ps.masking.open(pub.Payload)
+		}
+	}
+}
+
+// GetPublication retrieves a publication by ID
+func (ps *PubSubService) GetPublication(ctx context.Context, publicationID string) (*Publication, error) {
+	pub, err := ps.repo.GetPublication(ctx, publicationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.CheckIDs(ctx, pub.PublisherAgentID); err != nil {
+		return nil, err
+	}
+	ps.openPublications(pub)
+	return pub, nil
+}
+
+// UnmaskPublication returns a publication with its payload in the clear if
+// the reader holds the permissions of every masked field it contains. The
+// attempt is audited whether or not it is allowed.
+func (ps *PubSubService) UnmaskPublication(ctx context.Context, publicationID, actor, reason string, permissions Permissions) (*Publication, error) {
+	if ps.masking == nil {
+		return nil, ErrMaskingDisabled
+	}
+	pub, err := ps.GetPublication(ctx, publicationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.masking.authorize(ctx, "publication", pub.ID, pub.EventName, pub.Payload, actor, reason, permissions, ps.clock.Now()); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
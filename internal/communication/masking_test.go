@@ -0,0 +1,195 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestMasking(t *testing.T) *PayloadMasking {
+	t.Helper()
+	masking, err := NewPayloadMasking(MaskingConfig{
+		Key: []byte("0123456789abcdef0123456789abcdef"),
+		Rules: []MaskingRule{
+			{Topics: []string{"leak.*"}, Fields: []string{"customer.address", "customer.name"}, Permission: "customer.pii"},
+			{Topics: []string{"work_order"}, Fields: []string{"assignee"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return masking
+}
+
+func TestPubSubService_MaskingEncryptsAtRest(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockPubSubRepo()
+	service := NewPubSubService(repo)
+	masking := newTestMasking(t)
+	service.SetMasking(masking)
+
+	var observed map[string]interface{}
+	service.AddPublishObserver(func(ctx context.Context, pub *Publication) {
+		observed = pub.Payload
+	})
+
+	payload := map[string]interface{}{
+		"pressure": 2.5,
+		"customer": map[string]interface{}{"name": "A. Customer", "address": "1 Main St", "account": "42"},
+	}
+	id, err := service.Publish(ctx, "sensor-1", "sensor", "leak.detected", payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stored payload is sealed; the caller's payload and observers are not
+	stored := repo.publications[id].Payload["customer"].(map[string]interface{})
+	if address, _ := stored["address"].(string); !strings.HasPrefix(address, sealedPrefix) {
+		t.Errorf("expected the stored address to be sealed, got %v", stored["address"])
+	}
+	if stored["account"] != "42" || repo.publications[id].Payload["pressure"] != 2.5 {
+		t.Errorf("expected unmasked fields to be stored as is, got %v", repo.publications[id].Payload)
+	}
+	if payload["customer"].(map[string]interface{})["address"] != "1 Main St" {
+		t.Error("expected the published payload to be left unchanged")
+	}
+	if observed["customer"].(map[string]interface{})["name"] != "A. Customer" {
+		t.Error("expected observers to receive the clear payload")
+	}
+
+	// Service reads decrypt for in-process consumers
+	pub, err := service.GetPublication(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.Payload["customer"].(map[string]interface{})["address"] != "1 Main St" {
+		t.Errorf("expected the read payload to be decrypted, got %v", pub.Payload)
+	}
+
+	// API reads mask the fields the reader lacks permission for
+	masked, fields := masking.Mask(pub.EventName, pub.Payload, ParsePermissions("reports.read"))
+	customer := masked["customer"].(map[string]interface{})
+	if customer["address"] != "***" || customer["name"] != "***" || customer["account"] != "42" {
+		t.Errorf("expected name and address to be masked, got %v", customer)
+	}
+	if len(fields) != 2 {
+		t.Errorf("expected two masked fields, got %v", fields)
+	}
+	if pub.Payload["customer"].(map[string]interface{})["name"] != "A. Customer" {
+		t.Error("expected masking to leave the source payload unchanged")
+	}
+	if _, fields := masking.Mask(pub.EventName, pub.Payload, ParsePermissions("customer.pii")); len(fields) != 0 {
+		t.Errorf("expected nothing masked for a reader with the permission, got %v", fields)
+	}
+}
+
+func TestPayloadMasking_UnmaskIsAudited(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockMessageRepo()
+	service := NewMessageService(repo)
+	masking := newTestMasking(t)
+	service.SetMasking(masking)
+
+	id, err := service.SendMessage(ctx, "dispatcher", "crew-1", MessageType("work_order"), map[string]interface{}{"assignee": "J. Smith", "task": "repair"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assignee, _ := repo.messages[id].Payload["assignee"].(string); !strings.HasPrefix(assignee, sealedPrefix) {
+		t.Fatalf("expected the stored assignee to be sealed, got %v", repo.messages[id].Payload["assignee"])
+	}
+
+	_, err = service.UnmaskMessage(ctx, id, "alice", "incident review", ParsePermissions("customer.pii"))
+	if !errors.Is(err, ErrUnmaskForbidden) {
+		t.Fatalf("expected unmask without payload.unmask to be forbidden, got %v", err)
+	}
+
+	msg, err := service.UnmaskMessage(ctx, id, "bob", "crew audit", ParsePermissions("payload.unmask"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Payload["assignee"] != "J. Smith" {
+		t.Errorf("expected the clear assignee, got %v", msg.Payload["assignee"])
+	}
+
+	records, err := masking.AuditLog(ctx, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected both attempts to be audited, got %d", len(records))
+	}
+	if records[0].Actor != "bob" || !records[0].Allowed || records[0].Reason != "crew audit" {
+		t.Errorf("expected the allowed unmask first, got %+v", records[0])
+	}
+	if records[1].Allowed || len(records[1].Missing) != 1 || records[1].Missing[0] != DefaultUnmaskPermission {
+		t.Errorf("expected the denied unmask to name the missing permission, got %+v", records[1])
+	}
+	if got, _ := masking.AuditLog(ctx, "alice", 0); len(got) != 1 {
+		t.Errorf("expected one record for alice, got %d", len(got))
+	}
+}
+
+func TestPayloadMasking_AuditLogLimit(t *testing.T) {
+	masking, err := NewPayloadMasking(MaskingConfig{
+		Key:             []byte("0123456789abcdef"),
+		MaxAuditEntries: 2,
+		Rules:           []MaskingRule{{Topics: []string{"*"}, Fields: []string{"name"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, actor := range []string{"a", "b", "c"} {
+		masking.authorize(context.Background(), "message", "m1", "note", map[string]interface{}{"name": "x"}, actor, "check", Permissions{"*": true}, time.Now())
+	}
+
+	records, _ := masking.AuditLog(context.Background(), "", 0)
+	if len(records) != 2 || records[0].Actor != "c" || records[1].Actor != "b" {
+		t.Errorf("expected the two newest records, got %+v", records)
+	}
+}
+
+// failingUnmaskLog is an unmask audit log whose store is unavailable
+type failingUnmaskLog struct{}
+
+func (failingUnmaskLog) RecordUnmask(context.Context, *UnmaskRecord) error {
+	return errors.New("audit store unavailable")
+}
+
+func (failingUnmaskLog) UnmaskRecords(context.Context, string, int) ([]*UnmaskRecord, error) {
+	return nil, errors.New("audit store unavailable")
+}
+
+func TestPayloadMasking_UnauditedUnmaskIsRefused(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockMessageRepo()
+	service := NewMessageService(repo)
+	masking := newTestMasking(t)
+	masking.SetAuditLog(failingUnmaskLog{})
+	service.SetMasking(masking)
+
+	id, err := service.SendMessage(ctx, "dispatcher", "crew-1", MessageType("work_order"), map[string]interface{}{"assignee": "J. Smith"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := service.UnmaskMessage(ctx, id, "bob", "crew audit", ParsePermissions("payload.unmask"))
+	if err == nil {
+		t.Fatalf("expected an unmask that cannot be audited to fail, got %+v", msg.Payload)
+	}
+}
+
+func TestNewPayloadMasking_Invalid(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	cases := map[string]MaskingConfig{
+		"short key":    {Key: []byte("short"), Rules: []MaskingRule{{Topics: []string{"*"}, Fields: []string{"name"}}}},
+		"no fields":    {Key: key, Rules: []MaskingRule{{Topics: []string{"*"}}}},
+		"no topics":    {Key: key, Rules: []MaskingRule{{Fields: []string{"name"}}}},
+		"invalid glob": {Key: key, Rules: []MaskingRule{{Topics: []string{"["}, Fields: []string{"name"}}}},
+	}
+	for name, config := range cases {
+		if _, err := NewPayloadMasking(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package communication
 import (
 	"path/filepath"
 	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 )
 
 // SubscriptionMatcher handles pattern matching for pub/sub subscriptions
@@ -14,20 +16,68 @@ func NewSubscriptionMatcher() *SubscriptionMatcher {
 	return &SubscriptionMatcher{}
 }
 
-// MatchesPattern checks if an event name matches a glob pattern
-// Supports patterns like:
-// - "state.*" matches "state.changed", "state.updated"
+// MatchesPattern checks if an event name matches a topic pattern.
+//
+// Topics are dot-separated segments and patterns are matched segment by
+// segment, where "*" matches exactly one segment, "#" zero or more, and other
+// segments are globs within a segment:
 // - "task.completed" matches exactly "task.completed"
-// - "*" matches everything
-// - "*.error" matches "validation.error", "processing.error"
+// - "state.*" matches "state.changed" but not "state.changed.extra"
+// - "state.*.#" matches "state.changed" and "state.changed.extra"
+// - "zone.*.pump.#" matches "zone.north.pump.efficiency" but not "zone.north.east.pump"
+// - "#.alarm" matches "alarm", "zone.north.alarm"
+// - "alert.leak*" matches "alert.leak", "alert.leak-detected"
+// - "#" matches everything
 func (sm *SubscriptionMatcher) MatchesPattern(eventName, pattern string) bool {
-	// Use filepath.Match for glob-style pattern matching
-	matched, err := filepath.Match(pattern, eventName)
-	if err != nil {
-		// If pattern is invalid, return false
-		return false
+	return MatchTopic(pattern, eventName)
+}
+
+// MatchTopic reports whether a topic matches a topic pattern. See
+// SubscriptionMatcher.MatchesPattern for the pattern syntax. Invalid patterns
+// match nothing.
+func MatchTopic(pattern, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+// ValidateTopicPattern checks the syntax of a topic pattern
+func ValidateTopicPattern(pattern string) error {
+	for _, segment := range strings.Split(pattern, ".") {
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return err
+		}
 	}
-	return matched
+	return nil
+}
+
+// matchSegments matches topic segments against pattern segments, where "#"
+// matches zero or more segments and other segments are globs within a segment
+func matchSegments(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "#" {
+			// Collapse consecutive "#" and try every split of the remaining topic
+			for len(pattern) > 0 && pattern[0] == "#" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(topic); i++ {
+				if matchSegments(pattern, topic[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(topic) == 0 {
+			return false
+		}
+		if matched, err := filepath.Match(pattern[0], topic[0]); err != nil || !matched {
+			return false
+		}
+		pattern, topic = pattern[1:], topic[1:]
+	}
+	return len(topic) == 0
 }
 
 // MatchesSubscription checks if a publication matches a subscription
@@ -37,6 +87,11 @@ func (sm *SubscriptionMatcher) MatchesSubscription(pub *Publication, sub *Subscr
 		return false
 	}
 
+	// Publications never cross tenants
+	if !tenant.SameTenant(pub.PublisherAgentID, sub.SubscriberAgentID) {
+		return false
+	}
+
 	// Check publisher agent ID filter (if specified)
 	if sub.PublisherAgentID != nil && *sub.PublisherAgentID != "" {
 		if pub.PublisherAgentID != *sub.PublisherAgentID {
@@ -77,6 +132,14 @@ func (sm *SubscriptionMatcher) MatchesSubscription(pub *Publication, sub *Subscr
 		}
 	}
 
+	// Check filter expression (if any)
+	if sub.FilterExpression != "" {
+		expr, err := ParseFilterExpression(sub.FilterExpression)
+		if err != nil || !expr.Matches(pub.Payload) {
+			return false
+		}
+	}
+
 	return true
 }
 
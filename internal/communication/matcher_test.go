@@ -21,11 +21,17 @@ func TestMatchesPattern(t *testing.T) {
 			want:      true,
 		},
 		{
-			name:      "wildcard all",
-			eventName: "state.changed",
+			name:      "star is one segment",
+			eventName: "state",
 			pattern:   "*",
 			want:      true,
 		},
+		{
+			name:      "star does not span segments",
+			eventName: "state.changed",
+			pattern:   "*",
+			want:      false,
+		},
 		{
 			name:      "prefix wildcard",
 			eventName: "state.changed",
@@ -34,16 +40,58 @@ func TestMatchesPattern(t *testing.T) {
 		},
 		{
 			name:      "suffix wildcard",
-			eventName: "task.processing.completed",
+			eventName: "task.completed",
 			pattern:   "*.completed",
 			want:      true,
 		},
+		{
+			name:      "suffix wildcard is one segment",
+			eventName: "task.processing.completed",
+			pattern:   "*.completed",
+			want:      false,
+		},
 		{
 			name:      "middle wildcard",
 			eventName: "task.processing.completed",
 			pattern:   "task.*.completed",
 			want:      true,
 		},
+		{
+			name:      "prefix wildcard does not span segments",
+			eventName: "state.changed.extra",
+			pattern:   "state.*",
+			want:      false,
+		},
+		{
+			name:      "prefix wildcard needs a segment",
+			eventName: "state",
+			pattern:   "state.*",
+			want:      false,
+		},
+		{
+			name:      "star and trailing hash",
+			eventName: "state.changed",
+			pattern:   "state.*.#",
+			want:      true,
+		},
+		{
+			name:      "star and trailing hash spans segments",
+			eventName: "state.changed.extra",
+			pattern:   "state.*.#",
+			want:      true,
+		},
+		{
+			name:      "star and trailing hash needs a segment",
+			eventName: "state",
+			pattern:   "state.*.#",
+			want:      false,
+		},
+		{
+			name:      "segment glob",
+			eventName: "alert.leak-detected",
+			pattern:   "alert.leak*",
+			want:      true,
+		},
 		{
 			name:      "no match",
 			eventName: "state.changed",
@@ -56,6 +104,72 @@ func TestMatchesPattern(t *testing.T) {
 			pattern:   "state.changed",
 			want:      false,
 		},
+		{
+			name:      "hierarchical trailing hash",
+			eventName: "zone.north.pump.efficiency",
+			pattern:   "zone.north.#",
+			want:      true,
+		},
+		{
+			name:      "hierarchical hash matches zero segments",
+			eventName: "zone.north",
+			pattern:   "zone.north.#",
+			want:      true,
+		},
+		{
+			name:      "hierarchical star and hash",
+			eventName: "zone.north.pump.efficiency",
+			pattern:   "zone.*.pump.#",
+			want:      true,
+		},
+		{
+			name:      "hierarchical star is one segment",
+			eventName: "zone.north.east.pump.efficiency",
+			pattern:   "zone.*.pump.#",
+			want:      false,
+		},
+		{
+			name:      "hierarchical leading hash",
+			eventName: "zone.north.alarm",
+			pattern:   "#.alarm",
+			want:      true,
+		},
+		{
+			name:      "hierarchical middle hash",
+			eventName: "zone.north.pump.3.alarm",
+			pattern:   "zone.#.alarm",
+			want:      true,
+		},
+		{
+			name:      "hierarchical middle hash no match",
+			eventName: "zone.north.pump.alarm.cleared",
+			pattern:   "zone.#.alarm",
+			want:      false,
+		},
+		{
+			name:      "hierarchical segment glob",
+			eventName: "zone.north.pump-3.efficiency",
+			pattern:   "zone.north.pump-*.#",
+			want:      true,
+		},
+		{
+			name:      "hierarchical other zone",
+			eventName: "zone.south.pump.efficiency",
+			pattern:   "zone.north.#",
+			want:      false,
+		},
+		{
+			name:      "hash alone matches everything",
+			eventName: "zone.north.pump.efficiency",
+			pattern:   "#",
+			want:      true,
+		},
+		{
+			name:      "invalid pattern",
+			eventName: "zone.north",
+			pattern:   "zone.[north",
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,6 +183,20 @@ func TestMatchesPattern(t *testing.T) {
 	}
 }
 
+// TestValidateTopicPattern tests topic pattern syntax checks
+func TestValidateTopicPattern(t *testing.T) {
+	for _, pattern := range []string{"*", "state.*", "zone.*.pump.#", "#", "zone.pump-[0-9].#"} {
+		if err := ValidateTopicPattern(pattern); err != nil {
+			t.Errorf("ValidateTopicPattern(%q) = %v, want nil", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"zone.[north", "zone.[north.#"} {
+		if err := ValidateTopicPattern(pattern); err == nil {
+			t.Errorf("ValidateTopicPattern(%q) = nil, want error", pattern)
+		}
+	}
+}
+
 // TestMatchesSubscription tests subscription matching logic
 func TestMatchesSubscription(t *testing.T) {
 	matcher := NewSubscriptionMatcher()
@@ -174,6 +302,32 @@ func TestMatchesSubscription(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "publisher in another tenant",
+			pub: &Publication{
+				PublisherAgentID: "globex--agent-1",
+				EventName:        "state.changed",
+			},
+			sub: &Subscription{
+				SubscriberAgentID: "acme--agent-2",
+				EventPattern:      "state.*",
+				Active:            true,
+			},
+			want: false,
+		},
+		{
+			name: "publisher in the same tenant",
+			pub: &Publication{
+				PublisherAgentID: "acme--agent-1",
+				EventName:        "state.changed",
+			},
+			sub: &Subscription{
+				SubscriberAgentID: "acme--agent-2",
+				EventPattern:      "state.*",
+				Active:            true,
+			},
+			want: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -260,14 +414,14 @@ func TestGetMatchingSubscriptions(t *testing.T) {
 		},
 		{
 			ID:           "sub-3",
-			EventPattern: "*",
+			EventPattern: "#",
 			Active:       true,
 		},
 	}
 
 	matched := matcher.GetMatchingSubscriptions(pub, subscriptions)
 
-	// Should match sub-1 (state.*) and sub-3 (*), but not sub-2 (task.*)
+	// Should match sub-1 (state.*) and sub-3 (#), but not sub-2 (task.*)
 	if len(matched) != 2 {
 		t.Errorf("GetMatchingSubscriptions() returned %d matches, want 2", len(matched))
 	}
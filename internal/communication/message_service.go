@@ -5,29 +5,53 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 // MessageService handles direct agent-to-agent messaging
 type MessageService struct {
-	repo MessageRepository
+	repo       MessageRepository
+	clock      clock.Clock
+	ordering   *messageOrdering   // nil unless ordered delivery is enabled
+	guardrails *commandGuardrails // nil unless guardrails are enabled
+	masking    *PayloadMasking    // nil unless payload masking is enabled
+	priority   PriorityConfig
+	preempt    preemptWatchers
+	replies    replyWaiters
 }
 
 // NewMessageService creates a new message service
 func NewMessageService(repo MessageRepository) *MessageService {
-	return &MessageService{repo: repo}
+	return &MessageService{repo: repo, clock: clock.Real(), priority: PriorityConfig{}.withDefaults()}
+}
+
+// SetClock sets the clock used for message timestamps and expiry
+func (ms *MessageService) SetClock(c clock.Clock) {
+	if c != nil {
+		ms.clock = c
+	}
 }
 
 // SendMessage sends a direct message from one agent to another
 func (ms *MessageService) SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType MessageType, payload map[string]interface{}, opts *MessageOptions) (string, error) {
+	// Messages never cross tenants
+	if err := tenant.CheckIDs(ctx, fromAgentID); err != nil {
+		return "", err
+	}
+	if !tenant.SameTenant(fromAgentID, toAgentID) {
+		return "", fmt.Errorf("%w: %s", tenant.ErrForbidden, toAgentID)
+	}
+
 	msg := &Message{
 		FromAgentID: fromAgentID,
 		ToAgentID:   toAgentID,
 		MessageType: msgType,
 		Payload:     payload,
 		Status:      MessageStatusPending,
-		CreatedAt:   time.Now(),
+		CreatedAt:   ms.clock.Now(),
 	}
 
 	// Apply options
@@ -62,34 +86,89 @@ func (ms *MessageService) SendMessage(ctx context.Context, fromAgentID, toAgentI
 	// Generate message ID
 	msg.ID = fmt.Sprintf("msg-%s", uuid.New().String())
 
-	// Store message in database
-	if err := ms.repo.CreateMessage(ctx, msg); err != nil {
+	// Hold back commands that break a guardrail policy until an operator reviews them
+	if ms.guardrails != nil {
+		if violation := ms.guardrails.check(msg, ms.clock.Now()); violation != nil {
+			log.WithFields(log.Fields{
+				"violation_id": violation.ID,
+				"from":         fromAgentID,
+				"to":           toAgentID,
+				"type":         msgType,
+				"reasons":      violation.Reasons,
+			}).Warn("Command blocked by guardrail policy")
+			return "", &GuardrailViolationError{Violation: violation}
+		}
+	}
+
+	if err := ms.deliver(ctx, msg); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// deliver sequences and stores a validated message
+func (ms *MessageService) deliver(ctx context.Context, msg *Message) error {
+	// Sequence messages that are delivered in order
+	ordered := ms.ordering != nil && ms.ordering.applies(msg.MessageType)
+	if ordered {
+		if err := ms.sequence(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	// Store message in database, with masked fields encrypted
+	stored, err := ms.sealMessage(msg)
+	if err == nil {
+		err = ms.repo.CreateMessage(ctx, stored)
+		msg.ID, msg.Rev = stored.ID, stored.Rev
+	}
+	if err != nil {
+		if ordered {
+			ms.ordering.lostSequence(msg, err, ms.clock.Now())
+		}
 		log.WithError(err).WithFields(log.Fields{
-			"from": fromAgentID,
-			"to":   toAgentID,
-			"type": msgType,
+			"from": msg.FromAgentID,
+			"to":   msg.ToAgentID,
+			"type": msg.MessageType,
 		}).Error("Failed to send message")
-		return "", fmt.Errorf("failed to store message: %w", err)
+		return fmt.Errorf("failed to store message: %w", err)
+	}
+	if ordered {
+		ms.ordering.accepted(msg, msg.CreatedAt)
 	}
+	ms.notifyPreempt(msg)
+	ms.notifyReply(msg)
 
 	log.WithFields(log.Fields{
 		"message_id": msg.ID,
-		"from":       fromAgentID,
-		"to":         toAgentID,
-		"type":       msgType,
+		"from":       msg.FromAgentID,
+		"to":         msg.ToAgentID,
+		"type":       msg.MessageType,
 		"priority":   msg.Priority,
+		"sequence":   msg.Sequence,
 	}).Debug("Message sent successfully")
 
-	return msg.ID, nil
+	return nil
 }
 
 // GetMessage retrieves a specific message by ID
 func (ms *MessageService) GetMessage(ctx context.Context, messageID string) (*Message, error) {
-	return ms.repo.GetMessage(ctx, messageID)
+	msg, err := ms.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !tenant.AllowsID(ctx, msg.FromAgentID) && !tenant.AllowsID(ctx, msg.ToAgentID) {
+		return nil, fmt.Errorf("%w: %s", tenant.ErrForbidden, messageID)
+	}
+	ms.openMessages(msg)
+	return msg, nil
 }
 
 // GetPendingMessages retrieves pending messages for an agent
 func (ms *MessageService) GetPendingMessages(ctx context.Context, agentID string, limit int) ([]*Message, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		limit = 50 // Default limit
 	}
@@ -99,6 +178,11 @@ func (ms *MessageService) GetPendingMessages(ctx context.Context, agentID string
 		log.WithError(err).WithField("agent_id", agentID).Error("Failed to get pending messages")
 		return nil, err
 	}
+	ms.openMessages(messages...)
+
+	if ms.ordering != nil {
+		messages = ms.ordering.release(agentID, messages, ms.clock.Now())
+	}
 
 	log.WithFields(log.Fields{
 		"agent_id": agentID,
@@ -110,30 +194,41 @@ func (ms *MessageService) GetPendingMessages(ctx context.Context, agentID string
 
 // MarkDelivered marks a message as delivered
 func (ms *MessageService) MarkDelivered(ctx context.Context, messageID string) error {
-	now := time.Now()
+	if err := ms.checkMessage(ctx, messageID); err != nil {
+		return err
+	}
+	now := ms.clock.Now()
 	if err := ms.repo.UpdateMessageStatus(ctx, messageID, MessageStatusDelivered, &now); err != nil {
 		log.WithError(err).WithField("message_id", messageID).Error("Failed to mark message as delivered")
 		return err
 	}
 
+	ms.recordProcessed(ctx, messageID, DeliveryEventDelivered)
 	log.WithField("message_id", messageID).Debug("Message marked as delivered")
 	return nil
 }
 
 // MarkFailed marks a message as failed
 func (ms *MessageService) MarkFailed(ctx context.Context, messageID string) error {
+	if err := ms.checkMessage(ctx, messageID); err != nil {
+		return err
+	}
 	if err := ms.repo.UpdateMessageStatus(ctx, messageID, MessageStatusFailed, nil); err != nil {
 		log.WithError(err).WithField("message_id", messageID).Error("Failed to mark message as failed")
 		return err
 	}
 
+	ms.recordProcessed(ctx, messageID, DeliveryEventFailed)
 	log.WithField("message_id", messageID).Warn("Message marked as failed")
 	return nil
 }
 
 // AcknowledgeMessage marks a message as acknowledged
 func (ms *MessageService) AcknowledgeMessage(ctx context.Context, messageID string) error {
-	now := time.Now()
+	if err := ms.checkMessage(ctx, messageID); err != nil {
+		return err
+	}
+	now := ms.clock.Now()
 	if err := ms.repo.UpdateMessageAcknowledgment(ctx, messageID, &now); err != nil {
 		log.WithError(err).WithField("message_id", messageID).Error("Failed to acknowledge message")
 		return err
@@ -143,13 +238,15 @@ func (ms *MessageService) AcknowledgeMessage(ctx context.Context, messageID stri
 	return nil
 }
 
-// GetConversationHistory retrieves messages by correlation ID (conversation thread)
+// GetConversationHistory retrieves messages by correlation ID (conversation
+// thread). Callers scoped to a tenant only see their tenant's messages.
 func (ms *MessageService) GetConversationHistory(ctx context.Context, correlationID string) ([]*Message, error) {
-	messages, err := ms.repo.GetMessagesByCorrelation(ctx, correlationID)
+	messages, err := ms.repo.GetMessagesByCorrelation(ctx, correlationID, tenant.FromContext(ctx))
 	if err != nil {
 		log.WithError(err).WithField("correlation_id", correlationID).Error("Failed to get conversation history")
 		return nil, err
 	}
+	ms.openMessages(messages...)
 
 	log.WithFields(log.Fields{
 		"correlation_id": correlationID,
@@ -159,6 +256,16 @@ func (ms *MessageService) GetConversationHistory(ctx context.Context, correlatio
 	return messages, nil
 }
 
+// checkMessage returns tenant.ErrForbidden unless ctx may access the message.
+// Unscoped callers skip the lookup.
+func (ms *MessageService) checkMessage(ctx context.Context, messageID string) error {
+	if tenant.FromContext(ctx) == "" {
+		return nil
+	}
+	_, err := ms.GetMessage(ctx, messageID)
+	return err
+}
+
 // CleanupExpiredMessages removes expired messages from the database
 func (ms *MessageService) CleanupExpiredMessages(ctx context.Context) (int, error) {
 	count, err := ms.repo.DeleteExpiredMessages(ctx)
@@ -185,8 +292,8 @@ func (ms *MessageService) validateMessage(msg *Message) error {
 	if msg.MessageType == "" {
 		return fmt.Errorf("message_type is required")
 	}
-	if msg.Priority < 1 || msg.Priority > 10 {
-		return fmt.Errorf("priority must be between 1 and 10")
+	if msg.Priority < MinMessagePriority || msg.Priority > MaxMessagePriority {
+		return fmt.Errorf("priority must be between %d and %d", MinMessagePriority, MaxMessagePriority)
 	}
 	if msg.Payload == nil {
 		return fmt.Errorf("payload is required")
@@ -2,9 +2,12 @@ package communication
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	driver "github.com/arangodb/go-driver"
 )
 
@@ -83,19 +86,42 @@ func (m *mockMessageRepo) UpdateMessageAcknowledgment(ctx context.Context, id st
 	return nil
 }
 
-func (m *mockMessageRepo) GetMessagesByCorrelation(ctx context.Context, correlationID string) ([]*Message, error) {
+func (m *mockMessageRepo) GetMessagesByCorrelation(ctx context.Context, correlationID, tenantID string) ([]*Message, error) {
 	if m.queryErr != nil {
 		return nil, m.queryErr
 	}
 	var messages []*Message
 	for _, msg := range m.messages {
-		if msg.CorrelationID == correlationID {
+		if msg.CorrelationID == correlationID && (tenantID == "" || tenantMessage(tenantID, msg)) {
 			messages = append(messages, msg)
 		}
 	}
 	return messages, nil
 }
 
+func (m *mockMessageRepo) QueryMessages(ctx context.Context, query MessageQuery) ([]*Message, error) {
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	messages := make([]*Message, 0)
+	for _, msg := range m.messages {
+		if query.matches(msg) {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.After(messages[j].CreatedAt)
+	})
+	if query.Offset >= len(messages) {
+		return []*Message{}, nil
+	}
+	messages = messages[query.Offset:]
+	if len(messages) > query.Limit {
+		messages = messages[:query.Limit]
+	}
+	return messages, nil
+}
+
 func (m *mockMessageRepo) DeleteExpiredMessages(ctx context.Context) (int, error) {
 	count := 0
 	now := time.Now()
@@ -406,6 +432,39 @@ func TestMessageService_GetConversationHistory(t *testing.T) {
 	}
 }
 
+// TestMessageService_TenantIsolation tests that messages never cross tenants
+func TestMessageService_TenantIsolation(t *testing.T) {
+	repo := newMockMessageRepo()
+	svc := NewMessageService(repo)
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	if _, err := svc.SendMessage(context.Background(), "acme--a", "globex--b", MessageTypeTaskRequest, nil, nil); !errors.Is(err, tenant.ErrForbidden) {
+		t.Errorf("cross-tenant message: got %v, want ErrForbidden", err)
+	}
+	if _, err := svc.SendMessage(acme, "globex--a", "globex--b", MessageTypeTaskRequest, nil, nil); !errors.Is(err, tenant.ErrForbidden) {
+		t.Errorf("message from another tenant's agent: got %v, want ErrForbidden", err)
+	}
+
+	msgID, err := svc.SendMessage(acme, "acme--a", "acme--b", MessageTypeTaskRequest, map[string]interface{}{"task": "test"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := svc.GetMessage(acme, msgID); err != nil {
+		t.Errorf("GetMessage in own tenant: %v", err)
+	}
+
+	globex := tenant.WithTenant(context.Background(), "globex")
+	if _, err := svc.GetMessage(globex, msgID); !errors.Is(err, tenant.ErrForbidden) {
+		t.Errorf("GetMessage from another tenant: got %v, want ErrForbidden", err)
+	}
+	if err := svc.AcknowledgeMessage(globex, msgID); !errors.Is(err, tenant.ErrForbidden) {
+		t.Errorf("AcknowledgeMessage from another tenant: got %v, want ErrForbidden", err)
+	}
+	if _, err := svc.GetPendingMessages(globex, "acme--b", 10); !errors.Is(err, tenant.ErrForbidden) {
+		t.Errorf("GetPendingMessages for another tenant's agent: got %v, want ErrForbidden", err)
+	}
+}
+
 // TestMessageService_CleanupExpiredMessages tests cleanup of expired messages
 func TestMessageService_CleanupExpiredMessages(t *testing.T) {
 	repo := newMockMessageRepo()
@@ -0,0 +1,421 @@
+package communication
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// OrderingConfig configures per-recipient FIFO delivery of direct messages
+type OrderingConfig struct {
+	// MessageTypes are glob patterns of the message types delivered in order,
+	// e.g. "your_turn" or "*" for every type
+	MessageTypes []string
+
+	// GapTimeout is how long a missing sequence number holds back the
+	// messages after it before the gap is skipped (default 30s)
+	GapTimeout time.Duration
+
+	// TraceSize is the number of delivery events kept per recipient (default 200)
+	TraceSize int
+}
+
+// MessageSequencer assigns per-recipient sequence numbers that survive restarts.
+// Repositories that do not implement it fall back to in-memory counters.
+type MessageSequencer interface {
+	NextMessageSequence(ctx context.Context, agentID string) (int64, error)
+}
+
+// DeliveryEventType identifies an event in a recipient's message trace
+type DeliveryEventType string
+
+const (
+	// DeliveryEventAccepted means a message was accepted and given a sequence number
+	DeliveryEventAccepted DeliveryEventType = "accepted"
+	// DeliveryEventLost means a sequenced message could not be stored
+	DeliveryEventLost DeliveryEventType = "lost"
+	// DeliveryEventReleased means a message was handed to the recipient
+	DeliveryEventReleased DeliveryEventType = "released"
+	// DeliveryEventDelivered means the recipient processed a message
+	DeliveryEventDelivered DeliveryEventType = "delivered"
+	// DeliveryEventFailed means the recipient failed to process a message
+	DeliveryEventFailed DeliveryEventType = "failed"
+	// DeliveryEventGapDetected means later messages are held back for a missing one
+	DeliveryEventGapDetected DeliveryEventType = "gap_detected"
+	// DeliveryEventGapSkipped means missing sequence numbers were given up on
+	DeliveryEventGapSkipped DeliveryEventType = "gap_skipped"
+	// DeliveryEventOutOfOrder means a message was processed out of sequence
+	DeliveryEventOutOfOrder DeliveryEventType = "out_of_order"
+)
+
+// DeliveryEvent is an entry in a recipient's message trace
+type DeliveryEvent struct {
+	Type        DeliveryEventType `json:"type"`
+	MessageID   string            `json:"message_id,omitempty"`
+	MessageType MessageType       `json:"message_type,omitempty"`
+	Sequence    int64             `json:"sequence,omitempty"`
+	Expected    int64             `json:"expected,omitempty"`
+	Detail      string            `json:"detail,omitempty"`
+	At          time.Time         `json:"at"`
+}
+
+// MessageTrace reports the ordered delivery state of a recipient
+type MessageTrace struct {
+	AgentID       string          `json:"agent_id"`
+	Ordered       []string        `json:"ordered_message_types"`
+	LastAccepted  int64           `json:"last_accepted_sequence"`
+	NextRelease   int64           `json:"next_release_sequence"`
+	Outstanding   []int64         `json:"outstanding_sequences"`
+	HeldBack      bool            `json:"held_back"`
+	GapsDetected  int             `json:"gaps_detected"`
+	GapsSkipped   int             `json:"gaps_skipped"`
+	OutOfOrder    int             `json:"out_of_order"`
+	Events        []DeliveryEvent `json:"events"`
+	EventsDropped int             `json:"events_dropped,omitempty"`
+}
+
+// recipientOrder is the ordered delivery state of one recipient
+type recipientOrder struct {
+	lastAccepted int64
+	nextRelease  int64          // 0 until the first release
+	outstanding  map[int64]bool // released but not yet delivered or failed
+	lost         map[int64]bool
+	gapSeq       int64
+	gapSince     time.Time
+
+	gapsDetected  int
+	gapsSkipped   int
+	outOfOrder    int
+	events        []DeliveryEvent
+	eventsDropped int
+}
+
+// messageOrdering sequences and releases ordered messages per recipient
+type messageOrdering struct {
+	mu         sync.Mutex
+	types      []string
+	gapTimeout time.Duration
+	traceSize  int
+	recipients map[string]*recipientOrder
+}
+
+func newMessageOrdering(config OrderingConfig) (*messageOrdering, error) {
+	for _, pattern := range config.MessageTypes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid message type pattern %q: %w", pattern, err)
+		}
+	}
+	if config.GapTimeout <= 0 {
+		config.GapTimeout = 30 * time.Second
+	}
+	if config.TraceSize <= 0 {
+		config.TraceSize = 200
+	}
+
+	return &messageOrdering{
+		types:      append([]string(nil), config.MessageTypes...),
+		gapTimeout: config.GapTimeout,
+		traceSize:  config.TraceSize,
+		recipients: make(map[string]*recipientOrder),
+	}, nil
+}
+
+// applies reports whether messages of the type are delivered in order
+func (o *messageOrdering) applies(msgType MessageType) bool {
+	for _, pattern := range o.types {
+		if ok, _ := filepath.Match(pattern, string(msgType)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recipient returns the state of a recipient. Callers must hold o.mu.
+func (o *messageOrdering) recipient(agentID string) *recipientOrder {
+	state, ok := o.recipients[agentID]
+	if !ok {
+		state = &recipientOrder{lost: make(map[int64]bool), outstanding: make(map[int64]bool)}
+		o.recipients[agentID] = state
+	}
+	return state
+}
+
+// record appends an event to the recipient's trace. Callers must hold o.mu.
+func (o *messageOrdering) record(state *recipientOrder, event DeliveryEvent) {
+	state.events = append(state.events, event)
+	if len(state.events) > o.traceSize {
+		state.eventsDropped += len(state.events) - o.traceSize
+		state.events = append([]DeliveryEvent(nil), state.events[len(state.events)-o.traceSize:]...)
+	}
+}
+
+// next assigns the next in-memory sequence number of a recipient
+func (o *messageOrdering) next(agentID string) int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.recipient(agentID)
+	state.lastAccepted++
+	return state.lastAccepted
+}
+
+// accepted records a stored message and its sequence number
+func (o *messageOrdering) accepted(msg *Message, at time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.recipient(msg.ToAgentID)
+	if msg.Sequence > state.lastAccepted {
+		state.lastAccepted = msg.Sequence
+	}
+	o.record(state, DeliveryEvent{Type: DeliveryEventAccepted, MessageID: msg.ID, MessageType: msg.MessageType, Sequence: msg.Sequence, At: at})
+}
+
+// lostSequence marks a sequence number whose message was never stored so it
+// does not hold back later messages
+func (o *messageOrdering) lostSequence(msg *Message, err error, at time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.recipient(msg.ToAgentID)
+	state.lost[msg.Sequence] = true
+	o.record(state, DeliveryEvent{Type: DeliveryEventLost, MessageType: msg.MessageType, Sequence: msg.Sequence, Detail: err.Error(), At: at})
+}
+
+// release orders a recipient's pending messages. Unordered messages keep
+// their priority order; ordered messages are released in sequence up to the
+// first gap, which holds back the rest until it is filled or times out.
+func (o *messageOrdering) release(agentID string, pending []*Message, now time.Time) []*Message {
+	var unordered, ordered []*Message
+	for _, msg := range pending {
+		if msg.Sequence > 0 {
+			ordered = append(ordered, msg)
+		} else {
+			unordered = append(unordered, msg)
+		}
+	}
+	sort.SliceStable(unordered, func(i, j int) bool {
+		if unordered[i].Priority != unordered[j].Priority {
+			return unordered[i].Priority > unordered[j].Priority
+		}
+		return unordered[i].CreatedAt.Before(unordered[j].CreatedAt)
+	})
+	if len(ordered) == 0 {
+		return unordered
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Sequence < ordered[j].Sequence })
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.recipient(agentID)
+	if state.nextRelease == 0 {
+		// After a restart nothing is known of earlier releases
+		state.nextRelease = ordered[0].Sequence
+	}
+
+	released := make([]*Message, 0, len(ordered))
+	for _, msg := range ordered {
+		if msg.Sequence < state.nextRelease {
+			// Released before but not yet delivered
+			released = append(released, msg)
+			continue
+		}
+
+		if msg.Sequence > state.nextRelease && !o.skipGap(state, msg, now) {
+			break
+		}
+
+		released = append(released, msg)
+		state.nextRelease = msg.Sequence + 1
+		state.outstanding[msg.Sequence] = true
+		if state.gapSeq != 0 && state.gapSeq <= msg.Sequence {
+			// The missing message arrived
+			state.gapSeq = 0
+		}
+		o.record(state, DeliveryEvent{Type: DeliveryEventReleased, MessageID: msg.ID, MessageType: msg.MessageType, Sequence: msg.Sequence, At: now})
+	}
+
+	return mergeByPriority(unordered, released)
+}
+
+// skipGap reports whether the gap before msg may be skipped: the missing
+// messages were lost or the gap has outlasted the timeout. Callers must hold o.mu.
+func (o *messageOrdering) skipGap(state *recipientOrder, msg *Message, now time.Time) bool {
+	expected := state.nextRelease
+
+	allLost := true
+	for seq := expected; seq < msg.Sequence; seq++ {
+		if !state.lost[seq] {
+			allLost = false
+			break
+		}
+	}
+
+	if !allLost {
+		if state.gapSeq != expected {
+			state.gapSeq = expected
+			state.gapSince = now
+			state.gapsDetected++
+			o.record(state, DeliveryEvent{
+				Type: DeliveryEventGapDetected, MessageID: msg.ID, MessageType: msg.MessageType,
+				Sequence: msg.Sequence, Expected: expected, At: now,
+				Detail: fmt.Sprintf("holding back sequence %d until %d arrives", msg.Sequence, expected),
+			})
+		}
+		if now.Sub(state.gapSince) < o.gapTimeout {
+			return false
+		}
+	}
+
+	detail := fmt.Sprintf("missing sequences %d-%d timed out", expected, msg.Sequence-1)
+	if allLost {
+		detail = fmt.Sprintf("missing sequences %d-%d were never stored", expected, msg.Sequence-1)
+	}
+	for seq := expected; seq < msg.Sequence; seq++ {
+		delete(state.lost, seq)
+	}
+	state.gapSeq = 0
+	state.gapsSkipped++
+	o.record(state, DeliveryEvent{
+		Type: DeliveryEventGapSkipped, MessageID: msg.ID, MessageType: msg.MessageType,
+		Sequence: msg.Sequence, Expected: expected, Detail: detail, At: now,
+	})
+	return true
+}
+
+// processed records that the recipient delivered or failed an ordered
+// message and flags it if it was processed out of sequence
+func (o *messageOrdering) processed(msg *Message, eventType DeliveryEventType, now time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state := o.recipient(msg.ToAgentID)
+	o.record(state, DeliveryEvent{Type: eventType, MessageID: msg.ID, MessageType: msg.MessageType, Sequence: msg.Sequence, At: now})
+
+	delete(state.outstanding, msg.Sequence)
+	var earliest int64
+	for seq := range state.outstanding {
+		if seq < msg.Sequence && (earliest == 0 || seq < earliest) {
+			earliest = seq
+		}
+	}
+	if earliest != 0 {
+		state.outOfOrder++
+		o.record(state, DeliveryEvent{
+			Type: DeliveryEventOutOfOrder, MessageID: msg.ID, MessageType: msg.MessageType,
+			Sequence: msg.Sequence, Expected: earliest, At: now,
+			Detail: fmt.Sprintf("processed before sequence %d", earliest),
+		})
+	}
+}
+
+// trace returns the ordered delivery state of a recipient
+func (o *messageOrdering) trace(agentID string) *MessageTrace {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	trace := &MessageTrace{
+		AgentID:     agentID,
+		Ordered:     append([]string{}, o.types...),
+		Events:      []DeliveryEvent{},
+		Outstanding: []int64{},
+	}
+	state, ok := o.recipients[agentID]
+	if !ok {
+		return trace
+	}
+
+	trace.LastAccepted = state.lastAccepted
+	trace.NextRelease = state.nextRelease
+	for seq := range state.outstanding {
+		trace.Outstanding = append(trace.Outstanding, seq)
+	}
+	sort.Slice(trace.Outstanding, func(i, j int) bool { return trace.Outstanding[i] < trace.Outstanding[j] })
+	trace.HeldBack = state.gapSeq != 0
+	trace.GapsDetected = state.gapsDetected
+	trace.GapsSkipped = state.gapsSkipped
+	trace.OutOfOrder = state.outOfOrder
+	trace.Events = append(trace.Events, state.events...)
+	trace.EventsDropped = state.eventsDropped
+	return trace
+}
+
+// mergeByPriority merges priority-sorted unordered messages with ordered ones
+// without reordering either list. Ties favour the ordered stream.
+func mergeByPriority(unordered, ordered []*Message) []*Message {
+	merged := make([]*Message, 0, len(unordered)+len(ordered))
+	for len(unordered) > 0 && len(ordered) > 0 {
+		if unordered[0].Priority > ordered[0].Priority {
+			merged = append(merged, unordered[0])
+			unordered = unordered[1:]
+		} else {
+			merged = append(merged, ordered[0])
+			ordered = ordered[1:]
+		}
+	}
+	merged = append(merged, unordered...)
+	return append(merged, ordered...)
+}
+
+// SetOrdering enables per-recipient FIFO delivery for the configured message
+// types. Messages of those types get a sequence number when they are accepted
+// and are released to the recipient in sequence.
+func (ms *MessageService) SetOrdering(config OrderingConfig) error {
+	ordering, err := newMessageOrdering(config)
+	if err != nil {
+		return err
+	}
+	ms.ordering = ordering
+
+	log.WithFields(log.Fields{
+		"message_types": config.MessageTypes,
+		"gap_timeout":   ordering.gapTimeout,
+	}).Info("Ordered message delivery enabled")
+	return nil
+}
+
+// MessageTrace returns the ordered delivery trace of a recipient, including
+// detected gaps and messages processed out of order
+func (ms *MessageService) MessageTrace(agentID string) (*MessageTrace, error) {
+	if ms.ordering == nil {
+		return nil, fmt.Errorf("ordered message delivery is not enabled")
+	}
+	return ms.ordering.trace(agentID), nil
+}
+
+// sequence assigns the next sequence number of the message's recipient
+func (ms *MessageService) sequence(ctx context.Context, msg *Message) error {
+	if sequencer, ok := ms.repo.(MessageSequencer); ok {
+		seq, err := sequencer.NextMessageSequence(ctx, msg.ToAgentID)
+		if err != nil {
+			return fmt.Errorf("failed to assign sequence number: %w", err)
+		}
+		msg.Sequence = seq
+		return nil
+	}
+
+	msg.Sequence = ms.ordering.next(msg.ToAgentID)
+	return nil
+}
+
+// recordProcessed notes a delivered or failed ordered message in the trace
+func (ms *MessageService) recordProcessed(ctx context.Context, messageID string, eventType DeliveryEventType) {
+	if ms.ordering == nil {
+		return
+	}
+
+	msg, err := ms.repo.GetMessage(ctx, messageID)
+	if err != nil {
+		log.WithError(err).WithField("message_id", messageID).Warn("Failed to load message for delivery trace")
+		return
+	}
+	if msg.Sequence > 0 {
+		ms.ordering.processed(msg, eventType, ms.clock.Now())
+	}
+}
@@ -0,0 +1,160 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestMessageService_OrderedDelivery(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockMessageRepo()
+	service := NewMessageService(repo)
+	clk := clock.NewFake(time.Now())
+	service.SetClock(clk)
+	if err := service.SetOrdering(OrderingConfig{MessageTypes: []string{"your_turn"}, GapTimeout: 10 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := service.SendMessage(ctx, "pump-1", "pump-2", "your_turn", map[string]interface{}{"turn": i}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	alertID, err := service.SendMessage(ctx, "monitor", "pump-2", MessageTypeNotification, map[string]interface{}{"alert": "high"}, &MessageOptions{Priority: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.messages[alertID].Sequence != 0 {
+		t.Error("expected unordered message types to have no sequence")
+	}
+	for i, id := range ids {
+		if got := repo.messages[id].Sequence; got != int64(i+1) {
+			t.Errorf("expected sequence %d, got %d", i+1, got)
+		}
+	}
+
+	// The second handoff has not arrived yet, so the third is held back
+	delayed := repo.messages[ids[1]]
+	delete(repo.messages, ids[1])
+
+	pending, err := service.GetPendingMessages(ctx, "pump-2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].ID != alertID || pending[1].ID != ids[0] {
+		t.Fatalf("expected the alert and the first handoff, got %v", messageIDs(pending))
+	}
+	trace, _ := service.MessageTrace("pump-2")
+	if !trace.HeldBack || trace.GapsDetected != 1 {
+		t.Errorf("expected a held back gap, got %+v", trace)
+	}
+
+	// Once it arrives the rest are released in order
+	repo.messages[delayed.ID] = delayed
+	pending, _ = service.GetPendingMessages(ctx, "pump-2", 10)
+	if got := messageIDs(pending); len(got) != 4 || got[1] != ids[0] || got[2] != ids[1] || got[3] != ids[2] {
+		t.Fatalf("expected handoffs in order, got %v", got)
+	}
+
+	// Processing the third handoff before the second is flagged
+	if err := service.MarkDelivered(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.MarkDelivered(ctx, ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.MarkFailed(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	trace, _ = service.MessageTrace("pump-2")
+	if trace.HeldBack || trace.OutOfOrder != 1 || len(trace.Outstanding) != 0 {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	last := lastEvent(trace, DeliveryEventOutOfOrder)
+	if last == nil || last.Sequence != 3 || last.Expected != 2 {
+		t.Errorf("expected sequence 3 to be flagged ahead of 2, got %+v", last)
+	}
+}
+
+func TestMessageService_OrderedDeliveryGaps(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockMessageRepo()
+	service := NewMessageService(repo)
+	clk := clock.NewFake(time.Now())
+	service.SetClock(clk)
+	if err := service.SetOrdering(OrderingConfig{MessageTypes: []string{"*"}, GapTimeout: 10 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := service.SendMessage(ctx, "a", "b", MessageTypeCommand, map[string]interface{}{"n": 1}, nil)
+
+	// A message that could not be stored does not hold back the next one
+	repo.createErr = errors.New("write failed")
+	if _, err := service.SendMessage(ctx, "a", "b", MessageTypeCommand, map[string]interface{}{"n": 2}, nil); err == nil {
+		t.Fatal("expected store failure")
+	}
+	repo.createErr = nil
+	third, _ := service.SendMessage(ctx, "a", "b", MessageTypeCommand, map[string]interface{}{"n": 3}, nil)
+	fourth, _ := service.SendMessage(ctx, "a", "b", MessageTypeCommand, map[string]interface{}{"n": 4}, nil)
+	fifth, _ := service.SendMessage(ctx, "a", "b", MessageTypeCommand, map[string]interface{}{"n": 5}, nil)
+
+	// The fourth message expires, holding back the fifth until the gap times out
+	delete(repo.messages, fourth)
+
+	pending, _ := service.GetPendingMessages(ctx, "b", 10)
+	if got := messageIDs(pending); len(got) != 2 || got[0] != first || got[1] != third {
+		t.Fatalf("expected the lost sequence to be skipped, got %v", got)
+	}
+
+	clk.Advance(5 * time.Second)
+	pending, _ = service.GetPendingMessages(ctx, "b", 10)
+	if len(pending) != 2 {
+		t.Fatalf("expected the fifth message to be held back, got %v", messageIDs(pending))
+	}
+
+	clk.Advance(6 * time.Second)
+	pending, _ = service.GetPendingMessages(ctx, "b", 10)
+	if got := messageIDs(pending); len(got) != 3 || got[2] != fifth {
+		t.Fatalf("expected the gap to time out, got %v", got)
+	}
+
+	trace, _ := service.MessageTrace("b")
+	if trace.GapsSkipped != 2 || trace.GapsDetected != 1 || trace.HeldBack {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	if lastEvent(trace, DeliveryEventLost) == nil {
+		t.Error("expected the lost message in the trace")
+	}
+
+	if err := service.SetOrdering(OrderingConfig{MessageTypes: []string{"["}}); err == nil {
+		t.Error("expected invalid message type pattern to be rejected")
+	}
+	if _, err := NewMessageService(repo).MessageTrace("b"); err == nil {
+		t.Error("expected trace to fail when ordering is disabled")
+	}
+}
+
+func messageIDs(messages []*Message) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	return ids
+}
+
+func lastEvent(trace *MessageTrace, eventType DeliveryEventType) *DeliveryEvent {
+	for i := len(trace.Events) - 1; i >= 0; i-- {
+		if trace.Events[i].Type == eventType {
+			return &trace.Events[i]
+		}
+	}
+	return nil
+}
@@ -21,6 +21,8 @@ type MessagePoller struct {
 	interval       time.Duration
 	batchSize      int
 	handler        MessageHandler
+	queue          *messageQueue
+	preempt        <-chan struct{}
 	ctx            context.Context
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
@@ -53,6 +55,7 @@ func NewMessagePoller(config MessagePollerConfig, messageService *MessageService
 		interval:       config.Interval,
 		batchSize:      config.BatchSize,
 		handler:        handler,
+		queue:          newMessageQueue(messageService.priority),
 		ctx:            ctx,
 		cancel:         cancel,
 		running:        false,
@@ -106,6 +109,11 @@ func (mp *MessagePoller) IsRunning() bool {
 func (mp *MessagePoller) run() {
 	defer mp.wg.Done()
 
+	// Preempting messages are fetched as soon as they are sent
+	var unwatch func()
+	mp.preempt, unwatch = mp.messageService.watchPreempt(mp.agentID)
+	defer unwatch()
+
 	ticker := time.NewTicker(mp.interval)
 	defer ticker.Stop()
 
@@ -116,13 +124,32 @@ func (mp *MessagePoller) run() {
 		select {
 		case <-ticker.C:
 			mp.poll()
+		case <-mp.preempt:
+			mp.poll()
 		case <-mp.ctx.Done():
 			return
 		}
 	}
 }
 
+// poll fetches pending messages into the queue and handles them highest
+// priority first. Preempting messages sent meanwhile are fetched before the
+// next message is handled.
 func (mp *MessagePoller) poll() {
+	mp.fetch()
+
+	for mp.queue.Len() > 0 && mp.ctx.Err() == nil {
+		select {
+		case <-mp.preempt:
+			mp.fetch()
+		default:
+		}
+		mp.handle(mp.queue.pop())
+	}
+}
+
+// fetch adds the agent's pending messages to the queue
+func (mp *MessagePoller) fetch() {
 	messages, err := mp.messageService.GetPendingMessages(mp.ctx, mp.agentID, mp.batchSize)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -141,31 +168,33 @@ func (mp *MessagePoller) poll() {
 		"count":    len(messages),
 	}).Debug("Received messages")
 
-	for _, msg := range messages {
-		// Handle message
-		if err := mp.handler(msg); err != nil {
-			log.WithFields(log.Fields{
-				"agent_id":   mp.agentID,
-				"message_id": msg.ID,
-				"error":      err,
-			}).Error("Failed to handle message")
-			// Mark as failed
-			if err := mp.messageService.MarkFailed(mp.ctx, msg.ID); err != nil {
-				log.WithFields(log.Fields{
-					"message_id": msg.ID,
-					"error":      err,
-				}).Error("Failed to mark message as failed")
-			}
-			continue
-		}
+	mp.queue.push(messages)
+}
 
-		// Mark as delivered
-		if err := mp.messageService.MarkDelivered(mp.ctx, msg.ID); err != nil {
+// handle processes one message and records the outcome
+func (mp *MessagePoller) handle(msg *Message) {
+	if err := mp.handler(msg); err != nil {
+		log.WithFields(log.Fields{
+			"agent_id":   mp.agentID,
+			"message_id": msg.ID,
+			"error":      err,
+		}).Error("Failed to handle message")
+		// Mark as failed
+		if err := mp.messageService.MarkFailed(mp.ctx, msg.ID); err != nil {
 			log.WithFields(log.Fields{
 				"message_id": msg.ID,
 				"error":      err,
-			}).Error("Failed to mark message as delivered")
+			}).Error("Failed to mark message as failed")
 		}
+		return
+	}
+
+	// Mark as delivered
+	if err := mp.messageService.MarkDelivered(mp.ctx, msg.ID); err != nil {
+		log.WithFields(log.Fields{
+			"message_id": msg.ID,
+			"error":      err,
+		}).Error("Failed to mark message as delivered")
 	}
 }
 
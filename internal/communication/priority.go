@@ -0,0 +1,187 @@
+package communication
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Message priority bounds. Higher priorities are delivered first.
+const (
+	MinMessagePriority = 1
+	MaxMessagePriority = 10
+)
+
+// PriorityConfig configures how pollers order a recipient's pending messages
+type PriorityConfig struct {
+	// PreemptPriority is the lowest priority that preempts the messages a
+	// poller has already fetched: the recipient's poller fetches it right away
+	// and handles it next instead of at its next poll (default 9; a negative
+	// value disables preemption)
+	PreemptPriority int
+
+	// Aging protects lower priorities from starvation: every Aging a message
+	// waits counts as one more priority level. Zero uses the default of one
+	// minute; a negative value disables aging.
+	Aging time.Duration
+}
+
+func (c PriorityConfig) withDefaults() PriorityConfig {
+	if c.PreemptPriority == 0 {
+		c.PreemptPriority = 9
+	}
+	if c.Aging == 0 {
+		c.Aging = time.Minute
+	}
+	return c
+}
+
+// SetPriority configures preemption and starvation protection of message delivery
+func (ms *MessageService) SetPriority(config PriorityConfig) error {
+	config = config.withDefaults()
+	if config.PreemptPriority > MaxMessagePriority {
+		return fmt.Errorf("preempt priority must be at most %d", MaxMessagePriority)
+	}
+	ms.priority = config
+	return nil
+}
+
+// preemptWatchers wakes the pollers of recipients that receive a preempting message
+type preemptWatchers struct {
+	mu       sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+}
+
+// watchPreempt returns a channel signalled when the agent receives a message
+// at or above the preempt priority, and a function to stop watching
+func (ms *MessageService) watchPreempt(agentID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	w := &ms.preempt
+	w.mu.Lock()
+	if w.watchers == nil {
+		w.watchers = make(map[string]map[chan struct{}]struct{})
+	}
+	if w.watchers[agentID] == nil {
+		w.watchers[agentID] = make(map[chan struct{}]struct{})
+	}
+	w.watchers[agentID][ch] = struct{}{}
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.watchers[agentID], ch)
+		if len(w.watchers[agentID]) == 0 {
+			delete(w.watchers, agentID)
+		}
+	}
+}
+
+// notifyPreempt wakes the recipient's pollers if the message preempts
+func (ms *MessageService) notifyPreempt(msg *Message) {
+	if ms.priority.PreemptPriority < 0 || msg.Priority < ms.priority.PreemptPriority {
+		return
+	}
+
+	w := &ms.preempt
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for ch := range w.watchers[msg.ToAgentID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// messageQueue is a recipient's fetched but unhandled messages. Unordered
+// messages are kept in a priority heap; ordered messages keep their release
+// order and are merged by priority like mergeByPriority.
+type messageQueue struct {
+	aging   time.Duration
+	heap    messageHeap
+	ordered []*Message
+	queued  map[string]bool
+}
+
+func newMessageQueue(config PriorityConfig) *messageQueue {
+	config = config.withDefaults()
+	q := &messageQueue{
+		aging:  config.Aging,
+		queued: make(map[string]bool),
+	}
+	q.heap.less = q.before
+	return q
+}
+
+// Len returns the number of queued messages
+func (q *messageQueue) Len() int {
+	return q.heap.Len() + len(q.ordered)
+}
+
+// push adds fetched messages in their release order, skipping queued ones
+func (q *messageQueue) push(messages []*Message) {
+	for _, msg := range messages {
+		if q.queued[msg.ID] {
+			continue
+		}
+		q.queued[msg.ID] = true
+		if msg.Sequence > 0 {
+			q.ordered = append(q.ordered, msg)
+		} else {
+			heap.Push(&q.heap, msg)
+		}
+	}
+}
+
+// pop removes the message to handle next. Ties favour the ordered stream.
+func (q *messageQueue) pop() *Message {
+	var msg *Message
+	switch {
+	case len(q.ordered) > 0 && (q.heap.Len() == 0 || !q.before(q.heap.items[0], q.ordered[0])):
+		msg = q.ordered[0]
+		q.ordered = q.ordered[1:]
+	case q.heap.Len() > 0:
+		msg = heap.Pop(&q.heap).(*Message)
+	default:
+		return nil
+	}
+	delete(q.queued, msg.ID)
+	return msg
+}
+
+// before reports whether a is handled before b. With aging, a message is
+// treated as if it had been created one aging interval earlier per priority
+// level, so a message that waited long enough overtakes newer, higher ones.
+func (q *messageQueue) before(a, b *Message) bool {
+	if q.aging > 0 {
+		ka := a.CreatedAt.Add(-time.Duration(a.Priority) * q.aging)
+		kb := b.CreatedAt.Add(-time.Duration(b.Priority) * q.aging)
+		if !ka.Equal(kb) {
+			return ka.Before(kb)
+		}
+	} else if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// messageHeap implements heap.Interface over messages
+type messageHeap struct {
+	items []*Message
+	less  func(a, b *Message) bool
+}
+
+func (h messageHeap) Len() int            { return len(h.items) }
+func (h messageHeap) Less(i, j int) bool  { return h.less(h.items[i], h.items[j]) }
+func (h messageHeap) Swap(i, j int)       { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *messageHeap) Push(x interface{}) { h.items = append(h.items, x.(*Message)) }
+func (h *messageHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
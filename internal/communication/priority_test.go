@@ -0,0 +1,125 @@
+package communication
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMessageQueue_Order tests priority order, aging and ordered streams
+func TestMessageQueue_Order(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	msg := func(id string, priority int, createdAt time.Time, sequence int64) *Message {
+		return &Message{ID: id, Priority: priority, CreatedAt: createdAt, Sequence: sequence}
+	}
+	drain := func(q *messageQueue) []string {
+		var ids []string
+		for q.Len() > 0 {
+			ids = append(ids, q.pop().ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		config   PriorityConfig
+		messages []*Message
+		want     []string
+	}{
+		{
+			name:   "higher priority first, then oldest",
+			config: PriorityConfig{Aging: -1},
+			messages: []*Message{
+				msg("report-1", 5, start, 0),
+				msg("report-2", 5, start.Add(time.Second), 0),
+				msg("isolation", 10, start.Add(2*time.Second), 0),
+				msg("low", 1, start, 0),
+			},
+			want: []string{"isolation", "report-1", "report-2", "low"},
+		},
+		{
+			name:   "aged message overtakes newer higher priority",
+			config: PriorityConfig{Aging: time.Minute},
+			messages: []*Message{
+				msg("isolation", 10, start.Add(10*time.Minute), 0),
+				msg("starved", 5, start, 0),
+				msg("recent", 5, start.Add(9*time.Minute), 0),
+			},
+			want: []string{"starved", "isolation", "recent"},
+		},
+		{
+			name:   "ordered stream keeps its order",
+			config: PriorityConfig{Aging: -1},
+			messages: []*Message{
+				msg("turn-1", 3, start, 1),
+				msg("turn-2", 8, start, 2),
+				msg("alert", 6, start, 0),
+			},
+			want: []string{"alert", "turn-1", "turn-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newMessageQueue(tt.config)
+			q.push(tt.messages)
+			q.push(tt.messages[:1])
+
+			got := drain(q)
+			if len(got) != len(tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("order = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestMessagePoller_Preempt tests that preempting messages are handled before
+// messages fetched earlier
+func TestMessagePoller_Preempt(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMessageService(newMockMessageRepo())
+	if err := svc.SetPriority(PriorityConfig{PreemptPriority: 9}); err != nil {
+		t.Fatalf("SetPriority failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.SendMessage(ctx, "sensor-1", "valve-1", "report", map[string]interface{}{"n": i}, &MessageOptions{Priority: 5}); err != nil {
+			t.Fatalf("Failed to send report: %v", err)
+		}
+	}
+
+	var handled []string
+	poller := NewMessagePoller(MessagePollerConfig{AgentID: "valve-1"}, svc, func(msg *Message) error {
+		handled = append(handled, string(msg.MessageType))
+		if len(handled) == 1 {
+			if _, err := svc.SendMessage(ctx, "pipe-1", "valve-1", "ISOLATION_COMMAND", map[string]interface{}{"command": "CLOSE"}, &MessageOptions{Priority: 10}); err != nil {
+				t.Errorf("Failed to send isolation command: %v", err)
+			}
+		}
+		return nil
+	})
+	var unwatch func()
+	poller.preempt, unwatch = svc.watchPreempt("valve-1")
+	defer unwatch()
+
+	poller.poll()
+
+	want := []string{"report", "ISOLATION_COMMAND", "report", "report"}
+	if len(handled) != len(want) {
+		t.Fatalf("handled = %v, want %v", handled, want)
+	}
+	for i := range want {
+		if handled[i] != want[i] {
+			t.Fatalf("handled = %v, want %v", handled, want)
+		}
+	}
+
+	if err := svc.SetPriority(PriorityConfig{PreemptPriority: 11}); err == nil {
+		t.Error("Expected an error for a preempt priority above the maximum")
+	}
+}
@@ -0,0 +1,122 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxPublishBatch is the largest number of publications accepted in one batch
+const MaxPublishBatch = 10000
+
+// Batch publication statuses
+const (
+	// BatchStatusPublished publications were stored and delivered
+	BatchStatusPublished = "published"
+
+	// BatchStatusInvalid publications failed validation
+	BatchStatusInvalid = "invalid"
+
+	// BatchStatusRejected publications were valid but not stored because
+	// another publication of the batch was invalid or the batch failed to store
+	BatchStatusRejected = "rejected"
+)
+
+// ErrBatchRejected is returned when a batch is not stored. The batch results
+// tell which publications caused it.
+var ErrBatchRejected = errors.New("publication batch rejected")
+
+// BatchPublication is one publication of a batch
+type BatchPublication struct {
+	PublisherAgentID   string
+	PublisherAgentType string
+	EventName          string
+	Payload            map[string]interface{}
+	Options            *PublicationOptions
+}
+
+// BatchResult is the outcome of one publication of a batch
+type BatchResult struct {
+	Index         int    `json:"index"`
+	PublicationID string `json:"publication_id,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// PublishBatch publishes several publications atomically: all of them are
+// validated first and stored in one write, so either every publication is
+// published or none is. Results are in batch order. When the batch is not
+// stored the error wraps ErrBatchRejected.
+func (ps *PubSubService) PublishBatch(ctx context.Context, batch []BatchPublication) ([]BatchResult, error) {
+	if len(batch) == 0 {
+		return nil, fmt.Errorf("%w: batch is empty", ErrBatchRejected)
+	}
+	if len(batch) > MaxPublishBatch {
+		return nil, fmt.Errorf("%w: batch has %d publications, the limit is %d", ErrBatchRejected, len(batch), MaxPublishBatch)
+	}
+
+	results := make([]BatchResult, len(batch))
+	pubs := make([]*Publication, len(batch))
+	aliases := make([]*TopicAlias, len(batch))
+	invalid := 0
+	for i, item := range batch {
+		results[i].Index = i
+		pub, alias, err := ps.newPublication(item.PublisherAgentID, item.PublisherAgentType, item.EventName, item.Payload, item.Options)
+		if err == nil {
+			err = tenant.CheckIDs(ctx, item.PublisherAgentID)
+		}
+		if err != nil {
+			results[i].Status = BatchStatusInvalid
+			results[i].Error = err.Error()
+			invalid++
+			continue
+		}
+		pubs[i], aliases[i] = pub, alias
+	}
+	if invalid > 0 {
+		rejectValid(results)
+		return results, fmt.Errorf("%w: %d of %d publications are invalid", ErrBatchRejected, invalid, len(batch))
+	}
+
+	// Store publications, with masked fields encrypted
+	stored := make([]*Publication, len(pubs))
+	for i, pub := range pubs {
+		sealed, err := ps.sealPublication(pub)
+		if err != nil {
+			results[i].Status = BatchStatusInvalid
+			results[i].Error = err.Error()
+			rejectValid(results)
+			return results, fmt.Errorf("%w: %v", ErrBatchRejected, err)
+		}
+		stored[i] = sealed
+	}
+	if err := ps.repo.CreatePublications(ctx, stored); err != nil {
+		log.WithError(err).WithField("publications", len(batch)).Error("Failed to publish batch")
+		for i := range results {
+			results[i].Status = BatchStatusRejected
+		}
+		return results, fmt.Errorf("%w: failed to store publications: %v", ErrBatchRejected, err)
+	}
+
+	for i, pub := range pubs {
+		pub.ID, pub.Rev = stored[i].ID, stored[i].Rev
+		ps.published(ctx, pub, batch[i].EventName, aliases[i])
+		results[i].PublicationID = pub.ID
+		results[i].Status = BatchStatusPublished
+	}
+
+	log.WithField("publications", len(batch)).Debug("Batch published successfully")
+	return results, nil
+}
+
+// rejectValid marks the publications without a status as rejected
+func rejectValid(results []BatchResult) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i].Status = BatchStatusRejected
+		}
+	}
+}
@@ -3,8 +3,11 @@ package communication
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -13,24 +16,122 @@ import (
 type PubSubService struct {
 	repo    PubSubRepository
 	matcher *SubscriptionMatcher
+
+	// pushHandlers receive publications for push subscriptions, keyed by agent ID;
+	// inbox receives them for push subscribers without a handler
+	pushHandlers map[string]PublicationHandler
+	inbox        Inbox
+	handlersMu   sync.RWMutex
+
+	// pushIndex holds the push subscriptions by topic prefix and pushes
+	// delivers publications to them outside the publish call
+	pushIndex *pushIndex
+	pushes    *pushDispatcher
+
+	// observers are notified of every stored publication
+	observers []PublishObserver
+
+	// streams are the open live publication streams
+	streams *streamRegistry
+
+	// topics holds aliases, retention policies and topic traffic counters;
+	// topicsMu serialises changes to them
+	topics     *topicRegistry
+	topicStore TopicStore
+	topicsMu   sync.Mutex
+
+	// masking encrypts sensitive payload fields at rest; nil unless enabled
+	masking *PayloadMasking
+
+	clock clock.Clock
 }
 
+// PublishObserver is notified after a publication has been stored. It receives
+// the publisher's context and must not block.
+type PublishObserver func(ctx context.Context, pub *Publication)
+
 // NewPubSubService creates a new pub/sub service
 func NewPubSubService(repo PubSubRepository) *PubSubService {
+	matcher := NewSubscriptionMatcher()
 	return &PubSubService{
-		repo:    repo,
-		matcher: NewSubscriptionMatcher(),
+		repo:         repo,
+		matcher:      matcher,
+		pushHandlers: make(map[string]PublicationHandler),
+		pushIndex:    newPushIndex(),
+		pushes:       newPushDispatcher(),
+		streams:      newStreamRegistry(matcher),
+		topics:       newTopicRegistry(),
+		clock:        clock.Real(),
+	}
+}
+
+// SetClock sets the clock used for publication timestamps, expiry and
+// subscription bookkeeping
+func (ps *PubSubService) SetClock(c clock.Clock) {
+	if c != nil {
+		ps.clock = c
+	}
+}
+
+// RegisterPushHandler registers the in-process handler that receives publications
+// matching the agent's push subscriptions. Passing a nil handler removes it.
+func (ps *PubSubService) RegisterPushHandler(agentID string, handler PublicationHandler) {
+	ps.handlersMu.Lock()
+	defer ps.handlersMu.Unlock()
+
+	if handler == nil {
+		delete(ps.pushHandlers, agentID)
+		return
 	}
+	ps.pushHandlers[agentID] = handler
+}
+
+// AddPublishObserver registers an observer notified of every stored publication
+func (ps *PubSubService) AddPublishObserver(observer PublishObserver) {
+	ps.handlersMu.Lock()
+	defer ps.handlersMu.Unlock()
+
+	ps.observers = append(ps.observers, observer)
 }
 
 // Publish publishes an event/status update
 func (ps *PubSubService) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *PublicationOptions) (string, error) {
+	if err := tenant.CheckIDs(ctx, publisherAgentID); err != nil {
+		return "", err
+	}
+
+	pub, alias, err := ps.newPublication(publisherAgentID, publisherAgentType, eventName, payload, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Store publication, with masked fields encrypted
+	stored, err := ps.sealPublication(pub)
+	if err == nil {
+		err = ps.repo.CreatePublication(ctx, stored)
+		pub.ID, pub.Rev = stored.ID, stored.Rev
+	}
+	if err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"publisher": publisherAgentID,
+			"event":     eventName,
+		}).Error("Failed to publish event")
+		return "", fmt.Errorf("failed to store publication: %w", err)
+	}
+
+	ps.published(ctx, pub, eventName, alias)
+	return pub.ID, nil
+}
+
+// newPublication builds and validates a publication, resolving topic aliases
+// and applying defaults. The alias is nil unless eventName is deprecated.
+func (ps *PubSubService) newPublication(publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *PublicationOptions) (*Publication, *TopicAlias, error) {
 	pub := &Publication{
 		PublisherAgentID:   publisherAgentID,
 		PublisherAgentType: publisherAgentType,
 		EventName:          eventName,
 		Payload:            payload,
-		PublishedAt:        time.Now(),
+		PublishedAt:        ps.clock.Now(),
 	}
 
 	// Apply options
@@ -40,13 +141,35 @@ func (ps *PubSubService) Publish(ctx context.Context, publisherAgentID, publishe
 		pub.Metadata = opts.Metadata
 	}
 
+	// Redirect deprecated topic names
+	resolved, alias := ps.topics.resolve(eventName)
+	if alias != nil {
+		pub.EventName = resolved
+		metadata := make(map[string]string, len(pub.Metadata)+1)
+		for k, v := range pub.Metadata {
+			metadata[k] = v
+		}
+		metadata["aliased_from"] = eventName
+		pub.Metadata = metadata
+
+		log.WithFields(log.Fields{
+			"publisher": publisherAgentID,
+			"alias":     eventName,
+			"topic":     resolved,
+		}).Warn("Publication to deprecated topic alias")
+	}
+
 	// Set defaults
 	if pub.PublicationType == "" {
 		pub.PublicationType = PublicationTypeEvent
 	}
 
 	if pub.TTLSeconds == 0 {
-		pub.TTLSeconds = 3600 // Default: 1 hour
+		if ttl, ok := ps.topics.retentionTTL(pub.EventName); ok {
+			pub.TTLSeconds = ttl
+		} else {
+			pub.TTLSeconds = 3600 // Default: 1 hour
+		}
 	}
 
 	// Set expiration based on TTL
@@ -54,39 +177,50 @@ func (ps *PubSubService) Publish(ctx context.Context, publisherAgentID, publishe
 
 	// Validate publication
 	if err := ps.validatePublication(pub); err != nil {
-		return "", fmt.Errorf("invalid publication: %w", err)
+		return nil, nil, fmt.Errorf("invalid publication: %w", err)
 	}
 
 	// Generate publication ID
 	pub.ID = fmt.Sprintf("pub-%s", uuid.New().String())
 
-	// Store publication
-	if err := ps.repo.CreatePublication(ctx, pub); err != nil {
-		log.WithError(err).WithFields(log.Fields{
-			"publisher": publisherAgentID,
-			"event":     eventName,
-		}).Error("Failed to publish event")
-		return "", fmt.Errorf("failed to store publication: %w", err)
-	}
+	return pub, alias, nil
+}
 
+// published records topic traffic for a stored publication, notifies the
+// observers and delivers it to push subscribers
+func (ps *PubSubService) published(ctx context.Context, pub *Publication, eventName string, alias *TopicAlias) {
 	log.WithFields(log.Fields{
 		"publication_id": pub.ID,
-		"publisher":      publisherAgentID,
+		"publisher":      pub.PublisherAgentID,
 		"event":          eventName,
 		"type":           pub.PublicationType,
 	}).Debug("Event published successfully")
 
-	return pub.ID, nil
+	ps.topics.record(pub.EventName, eventName, pub.PublisherAgentID, alias, pub.PublishedAt)
+
+	ps.handlersMu.RLock()
+	observers := ps.observers
+	ps.handlersMu.RUnlock()
+	for _, observer := range observers {
+		observer(ctx, pub)
+	}
+
+	ps.streams.publish(pub)
+	ps.fanOut(ctx, pub)
 }
 
 // Subscribe creates a new subscription
 func (ps *PubSubService) Subscribe(ctx context.Context, subscriberAgentID, subscriberAgentType, eventPattern string, filters *SubscriptionFilters) (string, error) {
+	if err := tenant.CheckIDs(ctx, subscriberAgentID); err != nil {
+		return "", err
+	}
+
 	sub := &Subscription{
 		SubscriberAgentID:   subscriberAgentID,
 		SubscriberAgentType: subscriberAgentType,
 		EventPattern:        eventPattern,
-		CreatedAt:           time.Now(),
-		UpdatedAt:           time.Now(),
+		CreatedAt:           ps.clock.Now(),
+		UpdatedAt:           ps.clock.Now(),
 		Active:              true,
 	}
 
@@ -96,9 +230,15 @@ func (ps *PubSubService) Subscribe(ctx context.Context, subscriberAgentID, subsc
 		sub.PublisherAgentType = filters.PublisherType
 		sub.PublicationTypes = filters.Types
 		sub.FilterConditions = filters.Conditions
+		sub.FilterExpression = filters.Expression
+		sub.DeliveryMode = filters.DeliveryMode
 		sub.Metadata = filters.Metadata
 	}
 
+	if sub.DeliveryMode == "" {
+		sub.DeliveryMode = DeliveryModePull
+	}
+
 	// Validate subscription
 	if err := ps.validateSubscription(sub); err != nil {
 		return "", fmt.Errorf("invalid subscription: %w", err)
@@ -115,6 +255,7 @@ func (ps *PubSubService) Subscribe(ctx context.Context, subscriberAgentID, subsc
 		}).Error("Failed to create subscription")
 		return "", fmt.Errorf("failed to store subscription: %w", err)
 	}
+	ps.pushIndex.invalidate()
 
 	log.WithFields(log.Fields{
 		"subscription_id": sub.ID,
@@ -125,12 +266,66 @@ func (ps *PubSubService) Subscribe(ctx context.Context, subscriberAgentID, subsc
 	return sub.ID, nil
 }
 
+// GetSubscription retrieves a subscription by ID
+func (ps *PubSubService) GetSubscription(ctx context.Context, subscriptionID string) (*Subscription, error) {
+	sub, err := ps.repo.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.CheckIDs(ctx, sub.SubscriberAgentID); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// UpdateSubscription changes a subscription's pattern, filters and delivery mode
+func (ps *PubSubService) UpdateSubscription(ctx context.Context, subscriptionID, eventPattern string, filters *SubscriptionFilters) (*Subscription, error) {
+	sub, err := ps.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if eventPattern != "" {
+		sub.EventPattern = eventPattern
+	}
+	if filters != nil {
+		sub.PublisherAgentID = filters.PublisherID
+		sub.PublisherAgentType = filters.PublisherType
+		sub.PublicationTypes = filters.Types
+		sub.FilterConditions = filters.Conditions
+		sub.FilterExpression = filters.Expression
+		if filters.DeliveryMode != "" {
+			sub.DeliveryMode = filters.DeliveryMode
+		}
+		if filters.Metadata != nil {
+			sub.Metadata = filters.Metadata
+		}
+	}
+
+	if err := ps.validateSubscription(sub); err != nil {
+		return nil, fmt.Errorf("invalid subscription: %w", err)
+	}
+
+	if err := ps.repo.UpdateSubscription(ctx, sub); err != nil {
+		log.WithError(err).WithField("subscription_id", subscriptionID).Error("Failed to update subscription")
+		return nil, err
+	}
+	ps.pushIndex.invalidate()
+
+	log.WithField("subscription_id", subscriptionID).Debug("Subscription updated successfully")
+	return sub, nil
+}
+
 // Unsubscribe deactivates a subscription
 func (ps *PubSubService) Unsubscribe(ctx context.Context, subscriptionID string) error {
+	if err := ps.checkSubscription(ctx, subscriptionID); err != nil {
+		return err
+	}
 	if err := ps.repo.DeactivateSubscription(ctx, subscriptionID); err != nil {
 		log.WithError(err).WithField("subscription_id", subscriptionID).Error("Failed to unsubscribe")
 		return err
 	}
+	ps.pushIndex.invalidate()
 
 	log.WithField("subscription_id", subscriptionID).Debug("Unsubscribed successfully")
 	return nil
@@ -138,17 +333,35 @@ func (ps *PubSubService) Unsubscribe(ctx context.Context, subscriptionID string)
 
 // DeleteSubscription permanently deletes a subscription
 func (ps *PubSubService) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	if err := ps.checkSubscription(ctx, subscriptionID); err != nil {
+		return err
+	}
 	if err := ps.repo.DeleteSubscription(ctx, subscriptionID); err != nil {
 		log.WithError(err).WithField("subscription_id", subscriptionID).Error("Failed to delete subscription")
 		return err
 	}
+	ps.pushIndex.invalidate()
 
 	log.WithField("subscription_id", subscriptionID).Debug("Subscription deleted successfully")
 	return nil
 }
 
+// checkSubscription returns tenant.ErrForbidden unless ctx may access the
+// subscription's subscriber. Unscoped callers skip the lookup.
+func (ps *PubSubService) checkSubscription(ctx context.Context, subscriptionID string) error {
+	if tenant.FromContext(ctx) == "" {
+		return nil
+	}
+	_, err := ps.GetSubscription(ctx, subscriptionID)
+	return err
+}
+
 // GetActiveSubscriptions retrieves all active subscriptions for an agent
 func (ps *PubSubService) GetActiveSubscriptions(ctx context.Context, agentID string) ([]*Subscription, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
+
 	subscriptions, err := ps.repo.GetActiveSubscriptions(ctx, agentID)
 	if err != nil {
 		log.WithError(err).WithField("agent_id", agentID).Error("Failed to get active subscriptions")
@@ -165,6 +378,10 @@ func (ps *PubSubService) GetActiveSubscriptions(ctx context.Context, agentID str
 
 // GetMatchingPublications retrieves publications matching agent's subscriptions since a given time
 func (ps *PubSubService) GetMatchingPublications(ctx context.Context, agentID string, since time.Time) ([]*Publication, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
+
 	// Get agent's active subscriptions
 	subscriptions, err := ps.repo.GetActiveSubscriptions(ctx, agentID)
 	if err != nil {
@@ -172,6 +389,17 @@ func (ps *PubSubService) GetMatchingPublications(ctx context.Context, agentID st
 		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
 	}
 
+	// Push subscriptions are delivered at publish time, to the agent's handler
+	// or inbox
+	pushed := ps.pushedAtPublish(agentID)
+	pullSubscriptions := subscriptions[:0]
+	for _, sub := range subscriptions {
+		if sub.DeliveryMode != DeliveryModePush || !pushed {
+			pullSubscriptions = append(pullSubscriptions, sub)
+		}
+	}
+	subscriptions = pullSubscriptions
+
 	if len(subscriptions) == 0 {
 		return []*Publication{}, nil
 	}
@@ -182,12 +410,13 @@ func (ps *PubSubService) GetMatchingPublications(ctx context.Context, agentID st
 		log.WithError(err).WithField("agent_id", agentID).Error("Failed to get matching publications")
 		return nil, err
 	}
+	ps.openPublications(publications...)
 
 	// Filter publications using matcher to ensure they match subscription patterns
 	matched := ps.matcher.FilterMatchingPublications(publications, subscriptions)
 
 	// Update last matched timestamp for subscriptions
-	now := time.Now()
+	now := ps.clock.Now()
 	for _, pub := range matched {
 		matchingSubs := ps.matcher.GetMatchingSubscriptions(pub, subscriptions)
 		for _, sub := range matchingSubs {
@@ -215,11 +444,12 @@ func (ps *PubSubService) GetMatchingPublications(ctx context.Context, agentID st
 
 // CleanupExpiredPublications removes expired publications from the database
 func (ps *PubSubService) CleanupExpiredPublications(ctx context.Context) (int, error) {
-	count, err := ps.repo.DeleteExpiredPublications(ctx)
+	counts, err := ps.repo.DeleteExpiredPublications(ctx, 0)
 	if err != nil {
 		log.WithError(err).Error("Failed to cleanup expired publications")
 		return 0, err
 	}
+	count := ps.recordExpired(counts)
 
 	if count > 0 {
 		log.WithField("count", count).Info("Cleaned up expired publications")
@@ -256,9 +486,17 @@ func (ps *PubSubService) validateSubscription(sub *Subscription) error {
 	if sub.EventPattern == "" {
 		return fmt.Errorf("event_pattern is required")
 	}
-	// Validate pattern syntax by trying to match it
-	if ps.matcher.MatchesPattern("test", sub.EventPattern) {
-		// Pattern is valid
+	// Validate pattern syntax
+	if err := ValidateTopicPattern(sub.EventPattern); err != nil {
+		return fmt.Errorf("invalid event_pattern: %w", err)
+	}
+	if sub.DeliveryMode != "" && sub.DeliveryMode != DeliveryModePull && sub.DeliveryMode != DeliveryModePush {
+		return fmt.Errorf("invalid delivery_mode: %s", sub.DeliveryMode)
+	}
+	if sub.FilterExpression != "" {
+		if _, err := ParseFilterExpression(sub.FilterExpression); err != nil {
+			return fmt.Errorf("invalid filter_expression: %w", err)
+		}
 	}
 	return nil
 }
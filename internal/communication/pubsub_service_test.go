@@ -2,6 +2,10 @@ package communication
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +17,7 @@ type mockPubSubRepo struct {
 	publications  map[string]*Publication
 	subscriptions map[string]*Subscription
 	deliveries    map[string]map[string]bool // pubID -> subID -> delivered
+	mu            sync.Mutex                 // guards deliveries and match times, which are written asynchronously
 	createErr     error
 	getErr        error
 	updateErr     error
@@ -36,6 +41,16 @@ func (m *mockPubSubRepo) CreatePublication(ctx context.Context, pub *Publication
 	return nil
 }
 
+func (m *mockPubSubRepo) CreatePublications(ctx context.Context, pubs []*Publication) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	for _, pub := range pubs {
+		m.publications[pub.ID] = pub
+	}
+	return nil
+}
+
 func (m *mockPubSubRepo) GetPublication(ctx context.Context, id string) (*Publication, error) {
 	if m.getErr != nil {
 		return nil, m.getErr
@@ -71,16 +86,34 @@ func (m *mockPubSubRepo) GetMatchingPublications(ctx context.Context, subscripti
 	return matching, nil
 }
 
-func (m *mockPubSubRepo) DeleteExpiredPublications(ctx context.Context) (int, error) {
-	count := 0
+func (m *mockPubSubRepo) GetPublicationsInRange(ctx context.Context, since, until time.Time) ([]*Publication, error) {
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	var result []*Publication
+	for _, pub := range m.publications {
+		if !pub.PublishedAt.Before(since) && !pub.PublishedAt.After(until) {
+			result = append(result, pub)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPubSubRepo) DeleteExpiredPublications(ctx context.Context, limit int) (map[string]int, error) {
+	counts := make(map[string]int)
+	removed := 0
 	now := time.Now()
 	for id, pub := range m.publications {
+		if limit > 0 && removed == limit {
+			break
+		}
 		if pub.ExpiresAt.Before(now) {
 			delete(m.publications, id)
-			count++
+			counts[pub.EventName]++
+			removed++
 		}
 	}
-	return count, nil
+	return counts, nil
 }
 
 func (m *mockPubSubRepo) CreateSubscription(ctx context.Context, sub *Subscription) error {
@@ -115,6 +148,30 @@ func (m *mockPubSubRepo) GetActiveSubscriptions(ctx context.Context, agentID str
 	return active, nil
 }
 
+func (m *mockPubSubRepo) ListActiveSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	var active []*Subscription
+	for _, sub := range m.subscriptions {
+		if sub.Active {
+			active = append(active, sub)
+		}
+	}
+	return active, nil
+}
+
+func (m *mockPubSubRepo) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	if _, exists := m.subscriptions[sub.ID]; !exists {
+		return driver.ArangoError{Code: 404}
+	}
+	m.subscriptions[sub.ID] = sub
+	return nil
+}
+
 func (m *mockPubSubRepo) DeactivateSubscription(ctx context.Context, id string) error {
 	if m.updateErr != nil {
 		return m.updateErr
@@ -142,6 +199,8 @@ func (m *mockPubSubRepo) CreateDelivery(ctx context.Context, delivery *Publicati
 	if m.createErr != nil {
 		return m.createErr
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	// Extract pubID from delivery.From (format: "collection/id")
 	// For testing, we'll assume From contains the publication ID
 	pubID := delivery.From
@@ -156,6 +215,8 @@ func (m *mockPubSubRepo) UpdateSubscriptionLastMatched(ctx context.Context, id s
 	if m.updateErr != nil {
 		return m.updateErr
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	sub, exists := m.subscriptions[id]
 	if !exists {
 		return driver.ArangoError{Code: 404}
@@ -165,6 +226,8 @@ func (m *mockPubSubRepo) UpdateSubscriptionLastMatched(ctx context.Context, id s
 }
 
 func (m *mockPubSubRepo) hasBeenDelivered(pubID, subID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if subs, exists := m.deliveries[pubID]; exists {
 		return subs[subID]
 	}
@@ -282,6 +345,62 @@ func TestPubSubService_Publish(t *testing.T) {
 	}
 }
 
+// TestPubSubService_PublishBatch tests that batches are stored all or nothing
+func TestPubSubService_PublishBatch(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	reading := func(agentID string) BatchPublication {
+		return BatchPublication{
+			PublisherAgentID:   agentID,
+			PublisherAgentType: "sensor",
+			EventName:          "reading.pressure",
+			Payload:            map[string]interface{}{"pressure_bar": 5.2},
+		}
+	}
+
+	// One invalid publication rejects the whole batch
+	results, err := svc.PublishBatch(ctx, []BatchPublication{reading("SENSOR-001"), reading(""), reading("SENSOR-002")})
+	if !errors.Is(err, ErrBatchRejected) {
+		t.Fatalf("Expected ErrBatchRejected, got %v", err)
+	}
+	wantStatuses := []string{BatchStatusRejected, BatchStatusInvalid, BatchStatusRejected}
+	for i, result := range results {
+		if result.Index != i || result.Status != wantStatuses[i] {
+			t.Errorf("results[%d] = %+v, want status %s", i, result, wantStatuses[i])
+		}
+	}
+	if results[1].Error == "" {
+		t.Error("Expected an error for the invalid publication")
+	}
+	if len(repo.publications) != 0 {
+		t.Errorf("Stored %d publications, want 0", len(repo.publications))
+	}
+
+	// A failed write rejects every publication
+	repo.createErr = errors.New("write failed")
+	results, err = svc.PublishBatch(ctx, []BatchPublication{reading("SENSOR-001")})
+	if !errors.Is(err, ErrBatchRejected) || results[0].Status != BatchStatusRejected {
+		t.Errorf("Expected rejected batch, got %+v, %v", results, err)
+	}
+	repo.createErr = nil
+
+	results, err = svc.PublishBatch(ctx, []BatchPublication{reading("SENSOR-001"), reading("SENSOR-002")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, result := range results {
+		if result.Status != BatchStatusPublished || repo.publications[result.PublicationID] == nil {
+			t.Errorf("results[%d] = %+v, want stored publication", i, result)
+		}
+	}
+
+	if _, err := svc.PublishBatch(ctx, nil); !errors.Is(err, ErrBatchRejected) {
+		t.Errorf("Expected ErrBatchRejected for an empty batch, got %v", err)
+	}
+}
+
 // TestPubSubService_Subscribe tests creating subscriptions
 func TestPubSubService_Subscribe(t *testing.T) {
 	repo := newMockPubSubRepo()
@@ -533,3 +652,186 @@ func TestPubSubService_GetActiveSubscriptions(t *testing.T) {
 		t.Error("sub-4 should not be in results (wrong agent)")
 	}
 }
+
+// TestPubSubService_PushFanOut tests push delivery with payload filter expressions
+func TestPubSubService_PushFanOut(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	pushID, err := svc.Subscribe(ctx, "coordinator-1", "coordinator", "alert.*", &SubscriptionFilters{
+		Expression:   "severity >= HIGH",
+		DeliveryMode: DeliveryModePush,
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	var received []*Publication
+	svc.RegisterPushHandler("coordinator-1", func(pub *Publication) error {
+		received = append(received, pub)
+		return nil
+	})
+
+	lowID, _ := svc.Publish(ctx, "sensor-1", "sensor", "alert.pressure", map[string]interface{}{"severity": "LOW"}, nil)
+	highID, _ := svc.Publish(ctx, "sensor-1", "sensor", "alert.pressure", map[string]interface{}{"severity": "CRITICAL"}, nil)
+	svc.pushes.wait()
+
+	if len(received) != 1 || received[0].ID != highID {
+		t.Fatalf("push handler received %d publications, want only %s", len(received), highID)
+	}
+	if !repo.hasBeenDelivered(CollectionPublications+"/"+highID, pushID) {
+		t.Errorf("expected delivery record for %s", highID)
+	}
+	if repo.hasBeenDelivered(CollectionPublications+"/"+lowID, pushID) {
+		t.Errorf("unexpected delivery record for %s", lowID)
+	}
+
+	// Push subscriptions are not returned to pollers
+	matched, err := svc.GetMatchingPublications(ctx, "coordinator-1", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetMatchingPublications() error = %v", err)
+	}
+	if len(matched) != 0 {
+		t.Errorf("GetMatchingPublications() = %d publications, want 0", len(matched))
+	}
+}
+
+// fakeInbox records the messages sent to it
+type fakeInbox struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func (f *fakeInbox) SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType MessageType, payload map[string]interface{}, opts *MessageOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, &Message{FromAgentID: fromAgentID, ToAgentID: toAgentID, MessageType: msgType, Payload: payload, CorrelationID: opts.CorrelationID})
+	return fmt.Sprintf("msg-%d", len(f.messages)), nil
+}
+
+// TestPubSubService_PushWithoutHandler tests that push subscribers without an
+// in-process handler receive publications through their inbox, or by polling
+// when there is no inbox
+func TestPubSubService_PushWithoutHandler(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	subID, err := svc.Subscribe(ctx, "dispatcher-1", "dispatcher", "alert.#", &SubscriptionFilters{DeliveryMode: DeliveryModePush})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	// Without an inbox the subscriber polls
+	polledID, _ := svc.Publish(ctx, "sensor-1", "sensor", "alert.pressure.low", map[string]interface{}{"psi": 20}, nil)
+	svc.pushes.wait()
+	if repo.hasBeenDelivered(CollectionPublications+"/"+polledID, subID) {
+		t.Error("unexpected push delivery without a handler or inbox")
+	}
+	matched, err := svc.GetMatchingPublications(ctx, "dispatcher-1", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GetMatchingPublications() error = %v", err)
+	}
+	if len(matched) != 1 || matched[0].ID != polledID {
+		t.Errorf("GetMatchingPublications() = %v, want %s", matched, polledID)
+	}
+
+	// With an inbox the publication is queued as a message
+	inbox := &fakeInbox{}
+	svc.SetInbox(inbox)
+	queuedID, _ := svc.Publish(ctx, "sensor-1", "sensor", "alert.pressure.low", map[string]interface{}{"psi": 18}, nil)
+	svc.Publish(ctx, "sensor-1", "sensor", "status.ok", nil, nil)
+	svc.pushes.wait()
+
+	if len(inbox.messages) != 1 {
+		t.Fatalf("inbox received %d messages, want 1", len(inbox.messages))
+	}
+	msg := inbox.messages[0]
+	if msg.ToAgentID != "dispatcher-1" || msg.FromAgentID != "sensor-1" || msg.MessageType != MessageTypeNotification || msg.CorrelationID != queuedID {
+		t.Errorf("unexpected inbox message %+v", msg)
+	}
+	if msg.Payload["event_name"] != "alert.pressure.low" || msg.Payload["subscription_id"] != subID {
+		t.Errorf("unexpected inbox payload %v", msg.Payload)
+	}
+	if !repo.hasBeenDelivered(CollectionPublications+"/"+queuedID, subID) {
+		t.Errorf("expected delivery record for %s", queuedID)
+	}
+}
+
+// TestPubSubService_PushOrder tests that push handlers run outside the publish
+// call and receive each agent's publications in order
+func TestPubSubService_PushOrder(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	var received []string
+	svc.RegisterPushHandler("logger-1", func(pub *Publication) error {
+		<-release
+		received = append(received, pub.Payload["seq"].(string))
+		return nil
+	})
+
+	// The index picks up subscriptions made after it was loaded
+	svc.Publish(ctx, "sensor-1", "sensor", "reading.flow", nil, nil)
+	if _, err := svc.Subscribe(ctx, "logger-1", "logger", "*.flow", &SubscriptionFilters{DeliveryMode: DeliveryModePush}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	for _, seq := range []string{"1", "2", "3"} {
+		if _, err := svc.Publish(ctx, "sensor-1", "sensor", "reading.flow", map[string]interface{}{"seq": seq}, nil); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	close(release)
+	svc.pushes.wait()
+
+	if strings.Join(received, ",") != "1,2,3" {
+		t.Errorf("push handler received %v, want 1,2,3", received)
+	}
+}
+
+func TestPatternPrefix(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"alert.*":   "alert",
+		"alert":     "alert",
+		"#.alarm":   "",
+		"*.flow":    "",
+		"alert*.#":  "",
+		"zone.*.#":  "zone",
+		"[ab].pump": "",
+	} {
+		if got := patternPrefix(pattern); got != want {
+			t.Errorf("patternPrefix(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+// TestPubSubService_UpdateSubscription tests changing subscription filters
+func TestPubSubService_UpdateSubscription(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	subID, err := svc.Subscribe(ctx, "agent-1", "worker", "state.*", nil)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	sub, err := svc.UpdateSubscription(ctx, subID, "task.*", &SubscriptionFilters{Expression: "priority > 5", DeliveryMode: DeliveryModePush})
+	if err != nil {
+		t.Fatalf("UpdateSubscription() error = %v", err)
+	}
+	if sub.EventPattern != "task.*" || sub.FilterExpression != "priority > 5" || sub.DeliveryMode != DeliveryModePush {
+		t.Errorf("UpdateSubscription() = %+v", sub)
+	}
+
+	if _, err := svc.UpdateSubscription(ctx, subID, "", &SubscriptionFilters{Expression: "priority"}); err == nil {
+		t.Error("UpdateSubscription() expected error for invalid expression")
+	}
+	if _, err := svc.UpdateSubscription(ctx, subID, "", &SubscriptionFilters{DeliveryMode: "email"}); err == nil {
+		t.Error("UpdateSubscription() expected error for invalid delivery mode")
+	}
+}
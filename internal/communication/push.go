@@ -0,0 +1,260 @@
+package communication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// pushIndexTTL is how long the push subscription index is used before it is
+// reloaded, picking up subscriptions made through other instances
+const pushIndexTTL = 30 * time.Second
+
+// Inbox queues publications for push subscribers that have no in-process
+// handler. MessageService implements it.
+type Inbox interface {
+	SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType MessageType, payload map[string]interface{}, opts *MessageOptions) (string, error)
+}
+
+// SetInbox sets the inbox that receives publications for push subscribers
+// without an in-process handler. Without an inbox such subscribers poll for
+// their publications like pull subscribers.
+func (ps *PubSubService) SetInbox(inbox Inbox) {
+	ps.handlersMu.Lock()
+	defer ps.handlersMu.Unlock()
+
+	ps.inbox = inbox
+}
+
+// pushedAtPublish reports whether publications for the agent's push
+// subscriptions are delivered when they are published rather than polled for
+func (ps *PubSubService) pushedAtPublish(agentID string) bool {
+	ps.handlersMu.RLock()
+	defer ps.handlersMu.RUnlock()
+
+	return ps.inbox != nil || ps.pushHandlers[agentID] != nil
+}
+
+// pushIndex holds the active push subscriptions by the first segment of their
+// pattern, so a publication is only matched against the subscriptions that
+// can match its topic. Patterns starting with a wildcard are kept under "".
+type pushIndex struct {
+	mu       sync.Mutex
+	byPrefix map[string][]*Subscription
+	loadedAt time.Time
+	stale    bool
+}
+
+func newPushIndex() *pushIndex {
+	return &pushIndex{stale: true}
+}
+
+// invalidate makes the next publication reload the index
+func (idx *pushIndex) invalidate() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.stale = true
+}
+
+// candidates returns the push subscriptions that may match the topic,
+// loading the index when it is stale or older than pushIndexTTL
+func (idx *pushIndex) candidates(ctx context.Context, repo PubSubRepository, now time.Time, topic string) ([]*Subscription, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.stale || now.Sub(idx.loadedAt) > pushIndexTTL {
+		subscriptions, err := repo.ListActiveSubscriptions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		idx.byPrefix = make(map[string][]*Subscription)
+		for _, sub := range subscriptions {
+			if sub.DeliveryMode == DeliveryModePush {
+				prefix := patternPrefix(sub.EventPattern)
+				idx.byPrefix[prefix] = append(idx.byPrefix[prefix], sub)
+			}
+		}
+		idx.loadedAt = now
+		idx.stale = false
+	}
+
+	first, _, _ := strings.Cut(topic, ".")
+	candidates := append([]*Subscription(nil), idx.byPrefix[first]...)
+	return append(candidates, idx.byPrefix[""]...), nil
+}
+
+// patternPrefix returns the literal first segment of a topic pattern, or ""
+// when it is a wildcard
+func patternPrefix(pattern string) string {
+	first, _, _ := strings.Cut(pattern, ".")
+	if first == "#" || strings.ContainsAny(first, `*?[\`) {
+		return ""
+	}
+	return first
+}
+
+// pushJob is a publication waiting to be delivered to a push subscriber
+type pushJob struct {
+	ctx context.Context
+	pub *Publication
+	sub *Subscription
+}
+
+// pushDispatcher delivers publications to push subscribers outside the publish
+// call. Each agent's publications are delivered one at a time, in the order
+// they were published.
+type pushDispatcher struct {
+	mu      sync.Mutex
+	queues  map[string][]pushJob
+	pending sync.WaitGroup
+}
+
+func newPushDispatcher() *pushDispatcher {
+	return &pushDispatcher{queues: make(map[string][]pushJob)}
+}
+
+// enqueue queues a job for the agent, starting a delivery goroutine when the
+// agent has none
+func (d *pushDispatcher) enqueue(agentID string, job pushJob, deliver func(pushJob)) {
+	d.pending.Add(1)
+	d.mu.Lock()
+	queue, running := d.queues[agentID]
+	d.queues[agentID] = append(queue, job)
+	d.mu.Unlock()
+	if running {
+		return
+	}
+
+	go func() {
+		for {
+			d.mu.Lock()
+			queue := d.queues[agentID]
+			if len(queue) == 0 {
+				delete(d.queues, agentID)
+				d.mu.Unlock()
+				return
+			}
+			next := queue[0]
+			d.queues[agentID] = queue[1:]
+			d.mu.Unlock()
+
+			deliver(next)
+			d.pending.Done()
+		}
+	}()
+}
+
+// wait blocks until every queued publication has been delivered
+func (d *pushDispatcher) wait() {
+	d.pending.Wait()
+}
+
+// fanOut queues a publication for every matching push subscription. Delivery
+// failures are logged rather than returned since the publication itself has
+// been stored.
+func (ps *PubSubService) fanOut(ctx context.Context, pub *Publication) {
+	candidates, err := ps.pushIndex.candidates(ctx, ps.repo, ps.clock.Now(), pub.EventName)
+	if err != nil {
+		log.WithError(err).WithField("publication_id", pub.ID).Warn("Failed to load subscriptions for fan-out")
+		return
+	}
+
+	// Deliveries outlive the publish call but keep its tenant
+	deliveryCtx := context.WithoutCancel(ctx)
+	for _, sub := range ps.matcher.GetMatchingSubscriptions(pub, candidates) {
+		if !ps.pushedAtPublish(sub.SubscriberAgentID) {
+			continue
+		}
+		ps.pushes.enqueue(sub.SubscriberAgentID, pushJob{ctx: deliveryCtx, pub: pub, sub: sub}, ps.push)
+	}
+}
+
+// push delivers a publication to the subscriber's handler, or to its inbox
+// when it has no handler in this process, and records the delivery
+func (ps *PubSubService) push(job pushJob) {
+	pub, sub := job.pub, job.sub
+	delivery := &PublicationDelivery{
+		From:           fmt.Sprintf("%s/%s", CollectionPublications, pub.ID),
+		To:             fmt.Sprintf("agents/%s", sub.SubscriberAgentID),
+		SubscriptionID: sub.ID,
+		DeliveredAt:    ps.clock.Now(),
+	}
+
+	ps.handlersMu.RLock()
+	handler := ps.pushHandlers[sub.SubscriberAgentID]
+	inbox := ps.inbox
+	ps.handlersMu.RUnlock()
+
+	var err error
+	switch {
+	case handler != nil:
+		delivery.Acknowledged = true
+		delivery.Processed = true
+		delivery.ProcessingResult = "success"
+		err = handler(pub)
+	case inbox != nil:
+		delivery.ProcessingResult = "queued"
+		var messageID string
+		messageID, err = inbox.SendMessage(job.ctx, pub.PublisherAgentID, sub.SubscriberAgentID, MessageTypeNotification, inboxPayload(pub, sub), inboxOptions(pub, sub, delivery.DeliveredAt))
+		if err == nil {
+			delivery.Metadata = map[string]interface{}{"message_id": messageID}
+		}
+	default:
+		// The handler was removed after the publication was queued
+		delivery.ProcessingResult = "skipped"
+	}
+	if err != nil {
+		delivery.ProcessingResult = "failed"
+		delivery.Metadata = map[string]interface{}{"error": err.Error()}
+		log.WithError(err).WithFields(log.Fields{
+			"publication_id":  pub.ID,
+			"subscription_id": sub.ID,
+		}).Warn("Push delivery failed")
+	}
+
+	if err := ps.repo.CreateDelivery(job.ctx, delivery); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"publication_id":  pub.ID,
+			"subscription_id": sub.ID,
+		}).Warn("Failed to record push delivery")
+		return
+	}
+
+	if err := ps.repo.UpdateSubscriptionLastMatched(job.ctx, sub.ID, delivery.DeliveredAt); err != nil {
+		log.WithError(err).WithField("subscription_id", sub.ID).Warn("Failed to update subscription last matched")
+	}
+}
+
+// inboxPayload is the payload of the message that carries a publication to a
+// subscriber's inbox
+func inboxPayload(pub *Publication, sub *Subscription) map[string]interface{} {
+	return map[string]interface{}{
+		"publication_id":       pub.ID,
+		"subscription_id":      sub.ID,
+		"event_name":           pub.EventName,
+		"publication_type":     string(pub.PublicationType),
+		"publisher_agent_id":   pub.PublisherAgentID,
+		"publisher_agent_type": pub.PublisherAgentType,
+		"published_at":         pub.PublishedAt,
+		"payload":              pub.Payload,
+	}
+}
+
+// inboxOptions makes the inbox message expire with its publication
+func inboxOptions(pub *Publication, sub *Subscription, now time.Time) *MessageOptions {
+	opts := &MessageOptions{
+		CorrelationID: pub.ID,
+		Metadata:      map[string]string{"subscription_id": sub.ID},
+	}
+	if !pub.ExpiresAt.IsZero() {
+		if ttl := int(pub.ExpiresAt.Sub(now).Seconds()); ttl > 0 {
+			opts.TTL = ttl
+		}
+	}
+	return opts
+}
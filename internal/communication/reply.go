@@ -0,0 +1,175 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// replyPollInterval is how often a waiting request checks storage for replies
+// sent through another instance
+const replyPollInterval = time.Second
+
+// ErrReplyTimeout is returned when no reply arrives before the timeout
+var ErrReplyTimeout = errors.New("timed out waiting for reply")
+
+// ReplyTimeoutError reports the request that was not answered in time
+type ReplyTimeoutError struct {
+	RequestID     string
+	CorrelationID string
+	Timeout       time.Duration
+}
+
+func (e *ReplyTimeoutError) Error() string {
+	return fmt.Sprintf("%s to %s (correlation %s) after %s", ErrReplyTimeout, e.RequestID, e.CorrelationID, e.Timeout)
+}
+
+// Unwrap makes the error match ErrReplyTimeout
+func (e *ReplyTimeoutError) Unwrap() error {
+	return ErrReplyTimeout
+}
+
+// replyWaiters holds the requests waiting for a reply, by correlation ID
+type replyWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]map[*replyWaiter]struct{}
+}
+
+// replyWaiter waits for a message with its correlation ID addressed to
+// replyTo. Requests are never addressed to replyTo, so they do not match.
+type replyWaiter struct {
+	replyTo string
+	ch      chan *Message
+}
+
+func (w *replyWaiter) matches(msg *Message) bool {
+	return msg.ToAgentID == w.replyTo
+}
+
+// SendAndWait sends a request and waits until a reply with the request's
+// correlation ID reaches the sender (or opts.ReplyTo), or the timeout passes.
+// A correlation ID is generated unless opts sets one. The reply is marked
+// delivered so the requester's poller does not handle it again. When no reply
+// arrives the error is a *ReplyTimeoutError.
+func (ms *MessageService) SendAndWait(ctx context.Context, fromAgentID, toAgentID string, msgType MessageType, payload map[string]interface{}, opts *MessageOptions, timeout time.Duration) (*Message, error) {
+	if timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+
+	request := MessageOptions{}
+	if opts != nil {
+		request = *opts
+	}
+	if request.CorrelationID == "" {
+		request.CorrelationID = fmt.Sprintf("corr-%s", uuid.New().String())
+	}
+	if request.ReplyTo == "" {
+		request.ReplyTo = fromAgentID
+	}
+	if request.ReplyTo == toAgentID {
+		return nil, fmt.Errorf("replies must go to an agent other than the recipient")
+	}
+
+	// Wait before sending so a fast reply is not missed
+	waiter := &replyWaiter{replyTo: request.ReplyTo, ch: make(chan *Message, 1)}
+	ms.addReplyWaiter(request.CorrelationID, waiter)
+	defer ms.removeReplyWaiter(request.CorrelationID, waiter)
+
+	sentAt := ms.clock.Now()
+	requestID, err := ms.SendMessage(ctx, fromAgentID, toAgentID, msgType, payload, &request)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	poll := time.NewTicker(replyPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case reply := <-waiter.ch:
+			return ms.acceptReply(ctx, reply), nil
+		case <-poll.C:
+			if reply := ms.storedReply(ctx, request.CorrelationID, waiter, sentAt); reply != nil {
+				return ms.acceptReply(ctx, reply), nil
+			}
+		case <-timer.C:
+			return nil, &ReplyTimeoutError{RequestID: requestID, CorrelationID: request.CorrelationID, Timeout: timeout}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (ms *MessageService) addReplyWaiter(correlationID string, waiter *replyWaiter) {
+	ms.replies.mu.Lock()
+	defer ms.replies.mu.Unlock()
+
+	if ms.replies.waiters == nil {
+		ms.replies.waiters = make(map[string]map[*replyWaiter]struct{})
+	}
+	if ms.replies.waiters[correlationID] == nil {
+		ms.replies.waiters[correlationID] = make(map[*replyWaiter]struct{})
+	}
+	ms.replies.waiters[correlationID][waiter] = struct{}{}
+}
+
+func (ms *MessageService) removeReplyWaiter(correlationID string, waiter *replyWaiter) {
+	ms.replies.mu.Lock()
+	defer ms.replies.mu.Unlock()
+
+	delete(ms.replies.waiters[correlationID], waiter)
+	if len(ms.replies.waiters[correlationID]) == 0 {
+		delete(ms.replies.waiters, correlationID)
+	}
+}
+
+// notifyReply hands a stored message to the requests waiting for it
+func (ms *MessageService) notifyReply(msg *Message) {
+	if msg.CorrelationID == "" {
+		return
+	}
+
+	ms.replies.mu.Lock()
+	defer ms.replies.mu.Unlock()
+
+	for waiter := range ms.replies.waiters[msg.CorrelationID] {
+		if !waiter.matches(msg) {
+			continue
+		}
+		select {
+		case waiter.ch <- msg:
+		default:
+		}
+	}
+}
+
+// storedReply looks up a reply stored since the request was sent
+func (ms *MessageService) storedReply(ctx context.Context, correlationID string, waiter *replyWaiter, sentAt time.Time) *Message {
+	messages, err := ms.GetConversationHistory(ctx, correlationID)
+	if err != nil {
+		return nil
+	}
+	for _, msg := range messages {
+		if !msg.CreatedAt.Before(sentAt) && waiter.matches(msg) {
+			return msg
+		}
+	}
+	return nil
+}
+
+// acceptReply marks a reply delivered to the waiting requester
+func (ms *MessageService) acceptReply(ctx context.Context, reply *Message) *Message {
+	if err := ms.MarkDelivered(ctx, reply.ID); err != nil {
+		log.WithError(err).WithField("message_id", reply.ID).Warn("Failed to mark reply delivered")
+	}
+	opened := *reply
+	ms.openMessages(&opened)
+	return &opened
+}
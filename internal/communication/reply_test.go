@@ -0,0 +1,82 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedMessageRepo makes the mock safe for concurrent senders
+type lockedMessageRepo struct {
+	mu sync.Mutex
+	*mockMessageRepo
+}
+
+func (m *lockedMessageRepo) CreateMessage(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mockMessageRepo.CreateMessage(ctx, msg)
+}
+
+func (m *lockedMessageRepo) UpdateMessageStatus(ctx context.Context, id string, status MessageStatus, deliveredAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mockMessageRepo.UpdateMessageStatus(ctx, id, status, deliveredAt)
+}
+
+func (m *lockedMessageRepo) GetMessagesByCorrelation(ctx context.Context, correlationID, tenantID string) ([]*Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mockMessageRepo.GetMessagesByCorrelation(ctx, correlationID, tenantID)
+}
+
+// TestMessageService_SendAndWait tests request/reply by correlation ID
+func TestMessageService_SendAndWait(t *testing.T) {
+	ctx := context.Background()
+	repo := &lockedMessageRepo{mockMessageRepo: newMockMessageRepo()}
+	svc := NewMessageService(repo)
+
+	go func() {
+		// Neither a message to another agent nor one without the correlation
+		// ID is the reply
+		svc.SendMessage(ctx, "valve-1", "pipe-2", "COMMAND_RESPONSE", map[string]interface{}{"status": "OTHER"}, &MessageOptions{CorrelationID: "close-1"})
+		svc.SendMessage(ctx, "valve-1", "pipe-1", "COMMAND_RESPONSE", map[string]interface{}{"status": "UNRELATED"}, nil)
+		svc.SendMessage(ctx, "valve-1", "pipe-1", "COMMAND_RESPONSE", map[string]interface{}{"status": "CLOSED"}, &MessageOptions{CorrelationID: "close-1"})
+	}()
+
+	reply, err := svc.SendAndWait(ctx, "pipe-1", "valve-1", "ISOLATION_COMMAND", map[string]interface{}{"command": "CLOSE"}, &MessageOptions{CorrelationID: "close-1"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("SendAndWait failed: %v", err)
+	}
+	if reply.Payload["status"] != "CLOSED" || reply.CorrelationID != "close-1" {
+		t.Errorf("Unexpected reply: %+v", reply)
+	}
+
+	repo.mu.Lock()
+	status := repo.messages[reply.ID].Status
+	repo.mu.Unlock()
+	if status != MessageStatusDelivered {
+		t.Errorf("Reply status = %v, want delivered", status)
+	}
+}
+
+// TestMessageService_SendAndWaitTimeout tests requests without a reply
+func TestMessageService_SendAndWaitTimeout(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMessageService(newMockMessageRepo())
+
+	_, err := svc.SendAndWait(ctx, "pipe-1", "valve-1", "ISOLATION_COMMAND", map[string]interface{}{"command": "CLOSE"}, nil, 20*time.Millisecond)
+	var timeoutErr *ReplyTimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrReplyTimeout) {
+		t.Fatalf("Expected ReplyTimeoutError, got %v", err)
+	}
+	if timeoutErr.RequestID == "" || timeoutErr.CorrelationID == "" {
+		t.Errorf("Expected request and correlation IDs, got %+v", timeoutErr)
+	}
+
+	if _, err := svc.SendAndWait(ctx, "pipe-1", "valve-1", "ISOLATION_COMMAND", map[string]interface{}{}, &MessageOptions{ReplyTo: "valve-1"}, time.Second); err == nil {
+		t.Error("Expected an error for replies to the recipient")
+	}
+}
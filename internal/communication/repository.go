@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	driver "github.com/arangodb/go-driver"
 	log "github.com/sirupsen/logrus"
 )
@@ -19,6 +21,12 @@ const (
 	CollectionSubscriptions = "agent_subscriptions"
 	// CollectionDeliveries is the deliveries collection name (edge)
 	CollectionDeliveries = "agent_publication_deliveries"
+	// CollectionTopicAliases is the topic aliases collection name
+	CollectionTopicAliases = "pubsub_topic_aliases"
+	// CollectionRetentionPolicies is the topic retention policies collection name
+	CollectionRetentionPolicies = "pubsub_retention_policies"
+	// CollectionMessageSequences is the per-recipient message sequence counters collection name
+	CollectionMessageSequences = "agent_message_sequences"
 )
 
 // Repository handles communication persistence in ArangoDB
@@ -28,6 +36,9 @@ type Repository struct {
 	publicationsCol  driver.Collection
 	subscriptionsCol driver.Collection
 	deliveriesCol    driver.Collection
+	aliasesCol       driver.Collection
+	retentionCol     driver.Collection
+	clock            clock.Clock
 }
 
 // NewRepository creates a new communication repository
@@ -56,10 +67,34 @@ func NewRepository(dbClient *database.ArangoClient) (*Repository, error) {
 		return nil, fmt.Errorf("failed to ensure deliveries collection: %w", err)
 	}
 
+	aliasesCol, err := ensureCollection(ctx, db, CollectionTopicAliases, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure topic aliases collection: %w", err)
+	}
+
+	retentionCol, err := ensureCollection(ctx, db, CollectionRetentionPolicies, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure retention policies collection: %w", err)
+	}
+
+	sequencesCol, err := ensureCollection(ctx, db, CollectionMessageSequences, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure message sequences collection: %w", err)
+	}
+	if _, _, err := sequencesCol.EnsurePersistentIndex(ctx, []string{"agent_id"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_message_sequences_agent",
+		Unique: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index idx_message_sequences_agent: %w", err)
+	}
+
 	// Create indexes
 	if err := createIndexes(ctx, messagesCol, publicationsCol, subscriptionsCol); err != nil {
 		return nil, fmt.Errorf("failed to create indexes: %w", err)
 	}
+	if err := createTopicIndexes(ctx, aliasesCol, retentionCol); err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
 
 	log.Info("Communication repository initialized successfully")
 
@@ -69,9 +104,20 @@ func NewRepository(dbClient *database.ArangoClient) (*Repository, error) {
 		publicationsCol:  publicationsCol,
 		subscriptionsCol: subscriptionsCol,
 		deliveriesCol:    deliveriesCol,
+		aliasesCol:       aliasesCol,
+		retentionCol:     retentionCol,
+		clock:            clock.Real(),
 	}, nil
 }
 
+// SetClock sets the clock used for timestamps and to decide which messages
+// and publications have expired
+func (r *Repository) SetClock(c clock.Clock) {
+	if c != nil {
+		r.clock = c
+	}
+}
+
 // ensureCollection creates a collection if it doesn't exist
 func ensureCollection(ctx context.Context, db driver.Database, name string, isEdge bool) (driver.Collection, error) {
 	exists, err := db.CollectionExists(ctx, name)
@@ -115,6 +161,10 @@ func createIndexes(ctx context.Context, messages, publications, subscriptions dr
 		{"idx_messages_priority", []string{"to_agent_id", "priority", "created_at"}, false},
 		{"idx_messages_expiration", []string{"expires_at"}, false},
 		{"idx_messages_correlation", []string{"correlation_id"}, false},
+		{"idx_messages_sender", []string{"from_agent_id", "to_agent_id", "created_at"}, false},
+		{"idx_messages_history", []string{"to_agent_id", "created_at"}, false},
+		{"idx_messages_type", []string{"message_type", "created_at"}, false},
+		{"idx_messages_created", []string{"created_at"}, false},
 	}
 
 	for _, idx := range messageIndexes {
@@ -210,7 +260,7 @@ func (r *Repository) GetPendingMessages(ctx context.Context, agentID string, lim
 		FILTER msg.to_agent_id == @agentID
 		FILTER msg.status == @status
 		FILTER msg.expires_at == null OR msg.expires_at > @now
-		SORT msg.priority DESC, msg.created_at ASC
+		SORT msg.sequence > 0 ? 0 : 1, msg.sequence ASC, msg.priority DESC, msg.created_at ASC
 		LIMIT @limit
 		RETURN msg
 	`
@@ -219,7 +269,7 @@ func (r *Repository) GetPendingMessages(ctx context.Context, agentID string, lim
 		"@collection": CollectionMessages,
 		"agentID":     agentID,
 		"status":      MessageStatusPending,
-		"now":         time.Now(),
+		"now":         r.clock.Now(),
 		"limit":       limit,
 	}
 
@@ -242,6 +292,36 @@ func (r *Repository) GetPendingMessages(ctx context.Context, agentID string, lim
 	return messages, nil
 }
 
+// NextMessageSequence atomically increments and returns the message sequence
+// number of a recipient
+func (r *Repository) NextMessageSequence(ctx context.Context, agentID string) (int64, error) {
+	query := `
+		UPSERT { agent_id: @agentID }
+		INSERT { agent_id: @agentID, sequence: 1 }
+		UPDATE { sequence: OLD.sequence + 1 }
+		IN @@collection
+		RETURN NEW.sequence
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": CollectionMessageSequences,
+		"agentID":     agentID,
+	}
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment message sequence: %w", err)
+	}
+	defer cursor.Close()
+
+	var sequence int64
+	if _, err := cursor.ReadDocument(ctx, &sequence); err != nil {
+		return 0, fmt.Errorf("failed to read message sequence: %w", err)
+	}
+
+	return sequence, nil
+}
+
 // UpdateMessageStatus updates the status of a message
 func (r *Repository) UpdateMessageStatus(ctx context.Context, id string, status MessageStatus, deliveredAt *time.Time) error {
 	update := map[string]interface{}{
@@ -274,19 +354,25 @@ func (r *Repository) UpdateMessageAcknowledgment(ctx context.Context, id string,
 	return nil
 }
 
-// GetMessagesByCorrelation retrieves messages by correlation ID
-func (r *Repository) GetMessagesByCorrelation(ctx context.Context, correlationID string) ([]*Message, error) {
-	query := `
-		FOR msg IN @@collection
-		FILTER msg.correlation_id == @correlationID
-		SORT msg.created_at ASC
-		RETURN msg
-	`
-
+// GetMessagesByCorrelation retrieves messages by correlation ID. A non-empty
+// tenantID keeps the messages sent from or to the tenant's agents.
+func (r *Repository) GetMessagesByCorrelation(ctx context.Context, correlationID, tenantID string) ([]*Message, error) {
 	bindVars := map[string]interface{}{
 		"@collection":   CollectionMessages,
 		"correlationID": correlationID,
 	}
+	filters := ""
+	if tenantID != "" {
+		filters = "\n\t\tFILTER " + tenantFilter
+		bindVars["tenantPrefix"] = tenantID + tenant.Separator
+	}
+
+	query := fmt.Sprintf(`
+		FOR msg IN @@collection
+		FILTER msg.correlation_id == @correlationID%s
+		SORT msg.created_at ASC
+		RETURN msg
+	`, filters)
 
 	cursor, err := r.db.Database().Query(ctx, query, bindVars)
 	if err != nil {
@@ -307,6 +393,75 @@ func (r *Repository) GetMessagesByCorrelation(ctx context.Context, correlationID
 	return messages, nil
 }
 
+// tenantFilter keeps the messages sent from or to agents in the namespace
+// bound to @tenantPrefix
+const tenantFilter = "(STARTS_WITH(msg.from_agent_id, @tenantPrefix) OR STARTS_WITH(msg.to_agent_id, @tenantPrefix))"
+
+// QueryMessages retrieves messages matching the query, newest first. Only the
+// filters that are set are added, so the optimizer can pick the sender,
+// recipient, type or creation time index.
+func (r *Repository) QueryMessages(ctx context.Context, query MessageQuery) ([]*Message, error) {
+	bindVars := map[string]interface{}{
+		"@collection": CollectionMessages,
+		"offset":      query.Offset,
+		"limit":       query.Limit,
+	}
+	filters := ""
+	filter := func(clause, name string, value interface{}) {
+		filters += "\n\t\tFILTER " + clause
+		bindVars[name] = value
+	}
+	if query.FromAgentID != "" {
+		filter("msg.from_agent_id == @fromAgentID", "fromAgentID", query.FromAgentID)
+	}
+	if query.ToAgentID != "" {
+		filter("msg.to_agent_id == @toAgentID", "toAgentID", query.ToAgentID)
+	}
+	if query.MessageType != "" {
+		filter("msg.message_type == @messageType", "messageType", query.MessageType)
+	}
+	if !query.Since.IsZero() {
+		filter("msg.created_at >= @since", "since", query.Since)
+	}
+	if !query.Until.IsZero() {
+		filter("msg.created_at <= @until", "until", query.Until)
+	}
+	if query.MinPriority != 0 {
+		filter("msg.priority >= @minPriority", "minPriority", query.MinPriority)
+	}
+	if query.MaxPriority != 0 {
+		filter("msg.priority <= @maxPriority", "maxPriority", query.MaxPriority)
+	}
+	if query.TenantID != "" {
+		filter(tenantFilter, "tenantPrefix", query.TenantID+tenant.Separator)
+	}
+
+	aql := fmt.Sprintf(`
+		FOR msg IN @@collection%s
+		SORT msg.created_at DESC
+		LIMIT @offset, @limit
+		RETURN msg
+	`, filters)
+
+	cursor, err := r.db.Database().Query(ctx, aql, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer cursor.Close()
+
+	messages := make([]*Message, 0)
+	for cursor.HasMore() {
+		var msg Message
+		_, err := cursor.ReadDocument(ctx, &msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message from cursor: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+
+	return messages, nil
+}
+
 // DeleteExpiredMessages deletes messages that have expired
 func (r *Repository) DeleteExpiredMessages(ctx context.Context) (int, error) {
 	query := `
@@ -318,7 +473,7 @@ func (r *Repository) DeleteExpiredMessages(ctx context.Context) (int, error) {
 
 	bindVars := map[string]interface{}{
 		"@collection": CollectionMessages,
-		"now":         time.Now(),
+		"now":         r.clock.Now(),
 	}
 
 	cursor, err := r.db.Database().Query(ctx, query, bindVars)
@@ -350,6 +505,44 @@ func (r *Repository) CreatePublication(ctx context.Context, pub *Publication) er
 	return nil
 }
 
+// CreatePublications creates publications in a single stream transaction, so
+// either all of them are stored or none
+func (r *Repository) CreatePublications(ctx context.Context, pubs []*Publication) error {
+	if len(pubs) == 0 {
+		return nil
+	}
+	db := r.db.Database()
+
+	tid, err := db.BeginTransaction(ctx, driver.TransactionCollections{
+		Write: []string{CollectionPublications},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	txCtx := driver.WithTransactionID(ctx, tid)
+
+	metas, errs, err := r.publicationsCol.CreateDocuments(txCtx, pubs)
+	if err == nil {
+		err = errs.FirstNonNil()
+	}
+	if err != nil {
+		if abortErr := db.AbortTransaction(ctx, tid, nil); abortErr != nil {
+			log.WithError(abortErr).Error("Failed to abort publication batch transaction")
+		}
+		return fmt.Errorf("failed to create publications: %w", err)
+	}
+
+	if err := db.CommitTransaction(ctx, tid, nil); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for i, meta := range metas {
+		pubs[i].ID = meta.Key
+		pubs[i].Rev = meta.Rev
+	}
+	return nil
+}
+
 // GetPublication retrieves a publication by ID
 func (r *Repository) GetPublication(ctx context.Context, id string) (*Publication, error) {
 	var pub Publication
@@ -394,7 +587,7 @@ func (r *Repository) GetMatchingPublications(ctx context.Context, subscriptions
 	bindVars := map[string]interface{}{
 		"@collection": CollectionPublications,
 		"since":       since,
-		"now":         time.Now(),
+		"now":         r.clock.Now(),
 		"pattern":     combinedPattern,
 	}
 
@@ -417,33 +610,84 @@ func (r *Repository) GetMatchingPublications(ctx context.Context, subscriptions
 	return publications, nil
 }
 
-// DeleteExpiredPublications deletes publications that have expired
-func (r *Repository) DeleteExpiredPublications(ctx context.Context) (int, error) {
+// GetPublicationsInRange retrieves publications published in [since, until], oldest first
+func (r *Repository) GetPublicationsInRange(ctx context.Context, since, until time.Time) ([]*Publication, error) {
 	query := `
 		FOR pub IN @@collection
-		FILTER pub.expires_at < @now
-		REMOVE pub IN @@collection
-		RETURN OLD
+		FILTER pub.published_at >= @since
+		FILTER pub.published_at <= @until
+		SORT pub.published_at ASC
+		RETURN pub
 	`
 
 	bindVars := map[string]interface{}{
 		"@collection": CollectionPublications,
-		"now":         time.Now(),
+		"since":       since,
+		"until":       until,
 	}
 
 	cursor, err := r.db.Database().Query(ctx, query, bindVars)
 	if err != nil {
-		return 0, fmt.Errorf("failed to delete expired publications: %w", err)
+		return nil, fmt.Errorf("failed to query publications: %w", err)
 	}
 	defer cursor.Close()
 
-	count := 0
+	var publications []*Publication
 	for cursor.HasMore() {
-		cursor.ReadDocument(ctx, &Publication{})
-		count++
+		var pub Publication
+		meta, err := cursor.ReadDocument(ctx, &pub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read publication from cursor: %w", err)
+		}
+		pub.ID = meta.Key
+		publications = append(publications, &pub)
 	}
 
-	return count, nil
+	return publications, nil
+}
+
+// DeleteExpiredPublications removes up to limit expired publications, or every
+// expired publication when limit is zero or less, and returns the number
+// removed per topic
+func (r *Repository) DeleteExpiredPublications(ctx context.Context, limit int) (map[string]int, error) {
+	bindVars := map[string]interface{}{
+		"@collection": CollectionPublications,
+		"now":         r.clock.Now(),
+	}
+	limitClause := ""
+	if limit > 0 {
+		limitClause = "LIMIT @limit"
+		bindVars["limit"] = limit
+	}
+
+	query := fmt.Sprintf(`
+		FOR pub IN @@collection
+		FILTER pub.expires_at < @now
+		%s
+		REMOVE pub IN @@collection
+		COLLECT topic = OLD.event_name WITH COUNT INTO removed
+		RETURN { topic, removed }
+	`, limitClause)
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired publications: %w", err)
+	}
+	defer cursor.Close()
+
+	counts := make(map[string]int)
+	for cursor.HasMore() {
+		var row struct {
+			Topic   string `json:"topic"`
+			Removed int    `json:"removed"`
+		}
+		if _, err := cursor.ReadDocument(ctx, &row); err != nil {
+			return counts, fmt.Errorf("failed to read expired publication counts: %w", err)
+		}
+		counts[row.Topic] = row.Removed
+	}
+
+	return counts, nil
 }
 
 // Subscription operations
@@ -509,11 +753,58 @@ func (r *Repository) GetActiveSubscriptions(ctx context.Context, agentID string)
 	return subscriptions, nil
 }
 
+// ListActiveSubscriptions retrieves all active subscriptions across agents
+func (r *Repository) ListActiveSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	query := `
+		FOR sub IN @@collection
+		FILTER sub.active == true
+		RETURN sub
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": CollectionSubscriptions,
+	}
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active subscriptions: %w", err)
+	}
+	defer cursor.Close()
+
+	var subscriptions []*Subscription
+	for cursor.HasMore() {
+		var sub Subscription
+		_, err := cursor.ReadDocument(ctx, &sub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read subscription from cursor: %w", err)
+		}
+		subscriptions = append(subscriptions, &sub)
+	}
+
+	return subscriptions, nil
+}
+
+// UpdateSubscription replaces the mutable fields of a subscription
+func (r *Repository) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	sub.UpdatedAt = r.clock.Now()
+
+	meta, err := r.subscriptionsCol.ReplaceDocument(ctx, sub.ID, sub)
+	if err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("subscription not found: %s", sub.ID)
+		}
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	sub.Rev = meta.Rev
+	return nil
+}
+
 // DeactivateSubscription deactivates a subscription
 func (r *Repository) DeactivateSubscription(ctx context.Context, id string) error {
 	update := map[string]interface{}{
 		"active":     false,
-		"updated_at": time.Now(),
+		"updated_at": r.clock.Now(),
 	}
 
 	_, err := r.subscriptionsCol.UpdateDocument(ctx, id, update)
@@ -528,7 +819,7 @@ func (r *Repository) DeactivateSubscription(ctx context.Context, id string) erro
 func (r *Repository) UpdateSubscriptionLastMatched(ctx context.Context, id string, matchedAt time.Time) error {
 	update := map[string]interface{}{
 		"last_matched_at": matchedAt,
-		"updated_at":      time.Now(),
+		"updated_at":      r.clock.Now(),
 	}
 
 	_, err := r.subscriptionsCol.UpdateDocument(ctx, id, update)
@@ -393,7 +393,7 @@ func TestRepository_GetMessagesByCorrelation(t *testing.T) {
 	}
 
 	// Get messages by correlation ID
-	related, err := repo.GetMessagesByCorrelation(ctx, correlationID)
+	related, err := repo.GetMessagesByCorrelation(ctx, correlationID, "")
 	if err != nil {
 		t.Fatalf("Failed to get messages by correlation: %v", err)
 	}
@@ -0,0 +1,139 @@
+package communication
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultStreamBuffer is the number of publications a stream holds for a slow reader
+const DefaultStreamBuffer = 256
+
+// ErrNoStreamPatterns is returned when a stream is opened without topic patterns
+var ErrNoStreamPatterns = errors.New("at least one topic pattern is required")
+
+// PublicationStream receives publications matching its topic patterns as they
+// are published. Unlike a Subscription it is not stored: it only lives while
+// its reader is connected and sees nothing published before it was opened.
+type PublicationStream struct {
+	// C receives matching publications. It is closed when the stream is closed
+	// or when the reader fell behind by more than the stream's buffer.
+	C <-chan *Publication
+
+	patterns []string
+	ch       chan *Publication
+	registry *streamRegistry
+	lagged   bool
+	closed   bool
+}
+
+// Patterns returns the stream's topic patterns
+func (s *PublicationStream) Patterns() []string {
+	return append([]string(nil), s.patterns...)
+}
+
+// Lagged reports whether the stream was closed because its reader fell behind
+func (s *PublicationStream) Lagged() bool {
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+
+	return s.lagged
+}
+
+// Close stops the stream
+func (s *PublicationStream) Close() {
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+
+	s.registry.remove(s)
+}
+
+// streamRegistry holds the open publication streams
+type streamRegistry struct {
+	mu      sync.Mutex
+	matcher *SubscriptionMatcher
+	streams map[*PublicationStream]struct{}
+}
+
+func newStreamRegistry(matcher *SubscriptionMatcher) *streamRegistry {
+	return &streamRegistry{
+		matcher: matcher,
+		streams: make(map[*PublicationStream]struct{}),
+	}
+}
+
+// remove closes a stream; the caller holds the lock
+func (r *streamRegistry) remove(s *PublicationStream) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(r.streams, s)
+	close(s.ch)
+}
+
+// publish hands a publication to every matching stream without blocking.
+// Streams whose buffer is full are closed as lagged.
+func (r *streamRegistry) publish(pub *Publication) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for s := range r.streams {
+		if !r.matches(s, pub.EventName) {
+			continue
+		}
+		select {
+		case s.ch <- pub:
+		default:
+			s.lagged = true
+			r.remove(s)
+		}
+	}
+}
+
+func (r *streamRegistry) matches(s *PublicationStream, eventName string) bool {
+	for _, pattern := range s.patterns {
+		if r.matcher.MatchesPattern(eventName, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stream opens a live stream of the publications whose topic matches any of
+// the patterns. A buffer of zero or less uses DefaultStreamBuffer. Streams
+// receive publications in the clear; readers must mask them.
+func (ps *PubSubService) Stream(patterns []string, buffer int) (*PublicationStream, error) {
+	if len(patterns) == 0 {
+		return nil, ErrNoStreamPatterns
+	}
+	for _, pattern := range patterns {
+		if err := ValidateTopicPattern(pattern); err != nil {
+			return nil, fmt.Errorf("invalid topic pattern %q: %w", pattern, err)
+		}
+	}
+	if buffer <= 0 {
+		buffer = DefaultStreamBuffer
+	}
+
+	ch := make(chan *Publication, buffer)
+	s := &PublicationStream{
+		C:        ch,
+		patterns: append([]string(nil), patterns...),
+		ch:       ch,
+		registry: ps.streams,
+	}
+
+	ps.streams.mu.Lock()
+	ps.streams.streams[s] = struct{}{}
+	ps.streams.mu.Unlock()
+	return s, nil
+}
+
+// Streams returns the number of open publication streams
+func (ps *PubSubService) Streams() int {
+	ps.streams.mu.Lock()
+	defer ps.streams.mu.Unlock()
+
+	return len(ps.streams.streams)
+}
@@ -0,0 +1,83 @@
+package communication
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestPubSubService_Stream tests live streams of matching publications
+func TestPubSubService_Stream(t *testing.T) {
+	svc := NewPubSubService(newMockPubSubRepo())
+	ctx := context.Background()
+	payload := map[string]interface{}{"pressure_bar": 5.2}
+
+	if _, err := svc.Stream(nil, 0); !errors.Is(err, ErrNoStreamPatterns) {
+		t.Errorf("Expected ErrNoStreamPatterns, got %v", err)
+	}
+	if _, err := svc.Stream([]string{"zone.[north"}, 0); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+
+	stream, err := svc.Stream([]string{"zone.north.*", "alarm.*"}, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, event := range []string{"zone.north.pressure", "zone.south.pressure", "alarm.leak"} {
+		if _, err := svc.Publish(ctx, "SENSOR-001", "sensor", event, payload, nil); err != nil {
+			t.Fatalf("Failed to publish %s: %v", event, err)
+		}
+	}
+
+	for _, want := range []string{"zone.north.pressure", "alarm.leak"} {
+		select {
+		case pub := <-stream.C:
+			if pub.EventName != want {
+				t.Errorf("EventName = %v, want %v", pub.EventName, want)
+			}
+		default:
+			t.Fatalf("Expected %s on the stream", want)
+		}
+	}
+	if len(stream.C) != 0 {
+		t.Errorf("Stream has %d unexpected publications", len(stream.C))
+	}
+
+	stream.Close()
+	stream.Close()
+	if _, open := <-stream.C; open {
+		t.Error("Expected closed stream")
+	}
+	if svc.Streams() != 0 {
+		t.Errorf("Streams() = %d, want 0", svc.Streams())
+	}
+}
+
+// TestPubSubService_StreamLagged tests that slow streams are closed
+func TestPubSubService_StreamLagged(t *testing.T) {
+	svc := NewPubSubService(newMockPubSubRepo())
+	ctx := context.Background()
+
+	stream, err := svc.Stream([]string{"*"}, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Publish(ctx, "SENSOR-001", "sensor", "reading", map[string]interface{}{}, nil); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	if _, open := <-stream.C; !open {
+		t.Fatal("Expected the buffered publication")
+	}
+	if _, open := <-stream.C; open {
+		t.Fatal("Expected the stream to be closed")
+	}
+	if !stream.Lagged() {
+		t.Error("Expected a lagged stream")
+	}
+	stream.Close()
+}
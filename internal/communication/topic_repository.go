@@ -0,0 +1,213 @@
+package communication
+
+import (
+	"context"
+	"fmt"
+
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// createTopicIndexes creates the unique indexes of the topic settings collections
+func createTopicIndexes(ctx context.Context, aliases, retention driver.Collection) error {
+	if _, _, err := aliases.EnsurePersistentIndex(ctx, []string{"alias"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_topic_aliases_alias",
+		Unique: true,
+	}); err != nil {
+		return fmt.Errorf("failed to create index idx_topic_aliases_alias: %w", err)
+	}
+
+	if _, _, err := retention.EnsurePersistentIndex(ctx, []string{"topic"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_retention_policies_topic",
+		Unique: true,
+	}); err != nil {
+		return fmt.Errorf("failed to create index idx_retention_policies_topic: %w", err)
+	}
+
+	return nil
+}
+
+// Topic operations
+
+// ListTopicAliases retrieves all topic aliases
+func (r *Repository) ListTopicAliases(ctx context.Context) ([]*TopicAlias, error) {
+	query := `
+		FOR a IN @@collection
+		SORT a.alias
+		RETURN a
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionTopicAliases,
+	}
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query topic aliases: %w", err)
+	}
+	defer cursor.Close()
+
+	var aliases []*TopicAlias
+	for cursor.HasMore() {
+		var alias TopicAlias
+		if _, err := cursor.ReadDocument(ctx, &alias); err != nil {
+			return nil, fmt.Errorf("failed to read topic alias from cursor: %w", err)
+		}
+		aliases = append(aliases, &alias)
+	}
+
+	return aliases, nil
+}
+
+// PutTopicAlias creates or replaces a topic alias
+func (r *Repository) PutTopicAlias(ctx context.Context, alias *TopicAlias) error {
+	return r.upsertAlias(ctx, alias)
+}
+
+// DeleteTopicAlias deletes a topic alias
+func (r *Repository) DeleteTopicAlias(ctx context.Context, alias string) error {
+	query := `
+		FOR a IN @@collection
+		FILTER a.alias == @alias
+		REMOVE a IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionTopicAliases,
+		"alias":       alias,
+	}
+	return r.exec(ctx, query, bindVars, "failed to delete topic alias")
+}
+
+// ListRetentionPolicies retrieves all topic retention policies
+func (r *Repository) ListRetentionPolicies(ctx context.Context) ([]*RetentionPolicy, error) {
+	query := `
+		FOR p IN @@collection
+		SORT p.topic
+		RETURN p
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionRetentionPolicies,
+	}
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention policies: %w", err)
+	}
+	defer cursor.Close()
+
+	var policies []*RetentionPolicy
+	for cursor.HasMore() {
+		var policy RetentionPolicy
+		if _, err := cursor.ReadDocument(ctx, &policy); err != nil {
+			return nil, fmt.Errorf("failed to read retention policy from cursor: %w", err)
+		}
+		policies = append(policies, &policy)
+	}
+
+	return policies, nil
+}
+
+// PutRetentionPolicy creates or replaces a topic retention policy
+func (r *Repository) PutRetentionPolicy(ctx context.Context, policy *RetentionPolicy) error {
+	return r.upsertRetention(ctx, policy)
+}
+
+// DeleteRetentionPolicy deletes a topic retention policy
+func (r *Repository) DeleteRetentionPolicy(ctx context.Context, topic string) error {
+	query := `
+		FOR p IN @@collection
+		FILTER p.topic == @topic
+		REMOVE p IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionRetentionPolicies,
+		"topic":       topic,
+	}
+	return r.exec(ctx, query, bindVars, "failed to delete retention policy")
+}
+
+// ApplyTopicChanges applies subscription updates, aliases and retention
+// changes in a single stream transaction
+func (r *Repository) ApplyTopicChanges(ctx context.Context, changes *TopicChangeSet) error {
+	db := r.db.Database()
+
+	tid, err := db.BeginTransaction(ctx, driver.TransactionCollections{
+		Write: []string{CollectionSubscriptions, CollectionTopicAliases, CollectionRetentionPolicies},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	txCtx := driver.WithTransactionID(ctx, tid)
+
+	if err := r.applyTopicChanges(txCtx, changes); err != nil {
+		if abortErr := db.AbortTransaction(ctx, tid, nil); abortErr != nil {
+			log.WithError(abortErr).Error("Failed to abort topic change transaction")
+		}
+		return err
+	}
+
+	if err := db.CommitTransaction(ctx, tid, nil); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) applyTopicChanges(ctx context.Context, changes *TopicChangeSet) error {
+	for _, sub := range changes.Subscriptions {
+		if err := r.UpdateSubscription(ctx, sub); err != nil {
+			return err
+		}
+	}
+	for _, topic := range changes.DeleteRetention {
+		if err := r.DeleteRetentionPolicy(ctx, topic); err != nil {
+			return err
+		}
+	}
+	for _, policy := range changes.PutRetention {
+		if err := r.upsertRetention(ctx, policy); err != nil {
+			return err
+		}
+	}
+	for _, alias := range changes.PutAliases {
+		if err := r.upsertAlias(ctx, alias); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) upsertAlias(ctx context.Context, alias *TopicAlias) error {
+	query := `
+		UPSERT { alias: @doc.alias }
+		INSERT @doc
+		REPLACE @doc
+		IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionTopicAliases,
+		"doc":         alias,
+	}
+	return r.exec(ctx, query, bindVars, "failed to store topic alias")
+}
+
+func (r *Repository) upsertRetention(ctx context.Context, policy *RetentionPolicy) error {
+	query := `
+		UPSERT { topic: @doc.topic }
+		INSERT @doc
+		REPLACE @doc
+		IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionRetentionPolicies,
+		"doc":         policy,
+	}
+	return r.exec(ctx, query, bindVars, "failed to store retention policy")
+}
+
+// exec runs a query that returns no documents
+func (r *Repository) exec(ctx context.Context, query string, bindVars map[string]interface{}, errMsg string) error {
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+	return cursor.Close()
+}
@@ -0,0 +1,653 @@
+package communication
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxDeprecationPublishers caps the publishers remembered per deprecated alias
+const maxDeprecationPublishers = 20
+
+// TopicAlias keeps a deprecated topic name working after a rename. Publications
+// to the alias, or to any topic beneath it, are redirected to the target.
+type TopicAlias struct {
+	Alias     string    `json:"alias"`
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RetentionPolicy sets how long publications on a topic, and the topics beneath
+// it, are kept when the publisher does not set a TTL
+type RetentionPolicy struct {
+	Topic      string    `json:"topic"`
+	TTLSeconds int       `json:"ttl_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TopicRename renames a topic and every topic beneath it
+// (e.g. "zone.north.pump" -> "north.pumps" also renames "zone.north.pump.efficiency")
+type TopicRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TopicRenameRequest describes a bulk rename
+type TopicRenameRequest struct {
+	Renames []TopicRename `json:"renames"`
+
+	// DropAliases skips creating aliases for the old names. By default the old
+	// names keep working as deprecated aliases.
+	DropAliases bool `json:"drop_aliases"`
+
+	// Reason is recorded on the created aliases
+	Reason string `json:"reason,omitempty"`
+
+	// DryRun reports the changes without applying them
+	DryRun bool `json:"dry_run"`
+}
+
+// SubscriptionRename records a subscription pattern changed by a rename
+type SubscriptionRename struct {
+	SubscriptionID    string `json:"subscription_id"`
+	SubscriberAgentID string `json:"subscriber_agent_id"`
+	From              string `json:"from"`
+	To                string `json:"to"`
+}
+
+// TopicRenameResult reports the changes made (or planned) by a bulk rename
+type TopicRenameResult struct {
+	Subscriptions     []SubscriptionRename `json:"subscriptions"`
+	RetentionPolicies []TopicRename        `json:"retention_policies"`
+	Aliases           []*TopicAlias        `json:"aliases"`
+	Warnings          []string             `json:"warnings,omitempty"`
+	DryRun            bool                 `json:"dry_run"`
+}
+
+// TopicChangeSet is a set of topic changes a TopicStore applies atomically
+type TopicChangeSet struct {
+	Subscriptions   []*Subscription
+	PutAliases      []*TopicAlias
+	PutRetention    []*RetentionPolicy
+	DeleteRetention []string
+}
+
+// TopicStore persists topic aliases and retention policies
+type TopicStore interface {
+	ListTopicAliases(ctx context.Context) ([]*TopicAlias, error)
+	PutTopicAlias(ctx context.Context, alias *TopicAlias) error
+	DeleteTopicAlias(ctx context.Context, alias string) error
+	ListRetentionPolicies(ctx context.Context) ([]*RetentionPolicy, error)
+	PutRetentionPolicy(ctx context.Context, policy *RetentionPolicy) error
+	DeleteRetentionPolicy(ctx context.Context, topic string) error
+
+	// ApplyTopicChanges applies subscription updates, aliases and retention
+	// changes in a single transaction
+	ApplyTopicChanges(ctx context.Context, changes *TopicChangeSet) error
+}
+
+// TopicStats counts publications on a topic
+type TopicStats struct {
+	Topic           string    `json:"topic"`
+	Publications    int64     `json:"publications"`
+	Expired         int64     `json:"expired"`
+	LastPublishedAt time.Time `json:"last_published_at"`
+}
+
+// DeprecationWarning reports publications made to a deprecated alias
+type DeprecationWarning struct {
+	Alias           string    `json:"alias"`
+	Target          string    `json:"target"`
+	Message         string    `json:"message"`
+	Publications    int64     `json:"publications"`
+	Publishers      []string  `json:"publishers"`
+	LastTopic       string    `json:"last_topic"`
+	LastPublishedAt time.Time `json:"last_published_at"`
+}
+
+// TopicStatsReport summarises topic traffic, aliases and deprecation warnings
+type TopicStatsReport struct {
+	Topics            []TopicStats         `json:"topics"`
+	Aliases           []*TopicAlias        `json:"aliases"`
+	RetentionPolicies []*RetentionPolicy   `json:"retention_policies"`
+	Deprecations      []DeprecationWarning `json:"deprecations"`
+}
+
+// topicRegistry holds aliases, retention policies and traffic counters in memory
+type topicRegistry struct {
+	mu           sync.RWMutex
+	aliases      map[string]*TopicAlias
+	retention    map[string]*RetentionPolicy
+	stats        map[string]*TopicStats
+	deprecations map[string]*DeprecationWarning
+}
+
+func newTopicRegistry() *topicRegistry {
+	return &topicRegistry{
+		aliases:      make(map[string]*TopicAlias),
+		retention:    make(map[string]*RetentionPolicy),
+		stats:        make(map[string]*TopicStats),
+		deprecations: make(map[string]*DeprecationWarning),
+	}
+}
+
+// underTopic reports whether topic is prefix or a topic beneath it
+func underTopic(topic, prefix string) bool {
+	return topic == prefix || strings.HasPrefix(topic, prefix+".")
+}
+
+// renameTopic rewrites the prefix of a topic (or literal pattern prefix)
+func renameTopic(topic string, rename TopicRename) (string, bool) {
+	if !underTopic(topic, rename.From) {
+		return topic, false
+	}
+	return rename.To + strings.TrimPrefix(topic, rename.From), true
+}
+
+// resolve returns the topic a name is published to and the alias applied, if any.
+// The longest matching alias wins.
+func (r *topicRegistry) resolve(topic string) (string, *TopicAlias) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *TopicAlias
+	for name, alias := range r.aliases {
+		if underTopic(topic, name) && (best == nil || len(name) > len(best.Alias)) {
+			best = alias
+		}
+	}
+	if best == nil {
+		return topic, nil
+	}
+
+	resolved, _ := renameTopic(topic, TopicRename{From: best.Alias, To: best.Target})
+	return resolved, best
+}
+
+// retentionTTL returns the TTL of the most specific retention policy for a topic
+func (r *topicRegistry) retentionTTL(topic string) (int, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *RetentionPolicy
+	for name, policy := range r.retention {
+		if underTopic(topic, name) && (best == nil || len(name) > len(best.Topic)) {
+			best = policy
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.TTLSeconds, true
+}
+
+// record counts a publication and, when it used an alias, a deprecation warning
+func (r *topicRegistry) record(topic, publishedAs, publisher string, alias *TopicAlias, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.stats[topic]
+	if !ok {
+		stats = &TopicStats{Topic: topic}
+		r.stats[topic] = stats
+	}
+	stats.Publications++
+	stats.LastPublishedAt = at
+
+	if alias == nil {
+		return
+	}
+
+	warning, ok := r.deprecations[alias.Alias]
+	if !ok {
+		warning = &DeprecationWarning{
+			Alias:   alias.Alias,
+			Message: fmt.Sprintf("topic %q is deprecated, publish to %q instead", alias.Alias, alias.Target),
+		}
+		r.deprecations[alias.Alias] = warning
+	}
+	warning.Target = alias.Target
+	warning.Publications++
+	warning.LastTopic = publishedAs
+	warning.LastPublishedAt = at
+	if len(warning.Publishers) < maxDeprecationPublishers && !containsString(warning.Publishers, publisher) {
+		warning.Publishers = append(warning.Publishers, publisher)
+	}
+}
+
+// recordExpired counts publications of a topic removed after their TTL
+func (r *topicRegistry) recordExpired(topic string, count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.stats[topic]
+	if !ok {
+		stats = &TopicStats{Topic: topic}
+		r.stats[topic] = stats
+	}
+	stats.Expired += int64(count)
+}
+
+func (r *topicRegistry) report() *TopicStatsReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := &TopicStatsReport{
+		Topics:            make([]TopicStats, 0, len(r.stats)),
+		Aliases:           r.aliasList(),
+		RetentionPolicies: r.retentionList(),
+		Deprecations:      make([]DeprecationWarning, 0, len(r.deprecations)),
+	}
+	for _, stats := range r.stats {
+		report.Topics = append(report.Topics, *stats)
+	}
+	sort.Slice(report.Topics, func(i, j int) bool { return report.Topics[i].Topic < report.Topics[j].Topic })
+
+	for _, warning := range r.deprecations {
+		copied := *warning
+		copied.Publishers = append([]string(nil), warning.Publishers...)
+		report.Deprecations = append(report.Deprecations, copied)
+	}
+	sort.Slice(report.Deprecations, func(i, j int) bool { return report.Deprecations[i].Alias < report.Deprecations[j].Alias })
+
+	return report
+}
+
+// aliasList returns the aliases sorted by name. Callers hold the lock.
+func (r *topicRegistry) aliasList() []*TopicAlias {
+	aliases := make([]*TopicAlias, 0, len(r.aliases))
+	for _, alias := range r.aliases {
+		copied := *alias
+		aliases = append(aliases, &copied)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return aliases
+}
+
+// retentionList returns the retention policies sorted by topic. Callers hold the lock.
+func (r *topicRegistry) retentionList() []*RetentionPolicy {
+	policies := make([]*RetentionPolicy, 0, len(r.retention))
+	for _, policy := range r.retention {
+		copied := *policy
+		policies = append(policies, &copied)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Topic < policies[j].Topic })
+	return policies
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTopicName rejects empty names and topic patterns, whether globs or
+// the "#" multi-segment wildcard
+func ValidateTopicName(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if strings.ContainsAny(topic, "*?[]#") {
+		return fmt.Errorf("topic %q must not contain wildcards", topic)
+	}
+	if strings.HasPrefix(topic, ".") || strings.HasSuffix(topic, ".") {
+		return fmt.Errorf("topic %q must not start or end with a dot", topic)
+	}
+	return nil
+}
+
+// SetTopicStore persists topic aliases and retention policies in store and
+// loads the ones already stored
+func (ps *PubSubService) SetTopicStore(ctx context.Context, store TopicStore) error {
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	ps.topicStore = store
+
+	aliases, err := store.ListTopicAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load topic aliases: %w", err)
+	}
+	policies, err := store.ListRetentionPolicies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	ps.topics.mu.Lock()
+	for _, alias := range aliases {
+		ps.topics.aliases[alias.Alias] = alias
+	}
+	for _, policy := range policies {
+		ps.topics.retention[policy.Topic] = policy
+	}
+	ps.topics.mu.Unlock()
+
+	return nil
+}
+
+// ListTopicAliases returns the topic aliases sorted by name
+func (ps *PubSubService) ListTopicAliases() []*TopicAlias {
+	ps.topics.mu.RLock()
+	defer ps.topics.mu.RUnlock()
+	return ps.topics.aliasList()
+}
+
+// AddTopicAlias makes alias, and the topics beneath it, publish to target
+func (ps *PubSubService) AddTopicAlias(ctx context.Context, aliasName, target, reason string) (*TopicAlias, error) {
+	if err := ValidateTopicName(aliasName); err != nil {
+		return nil, fmt.Errorf("invalid alias: %w", err)
+	}
+	if err := ValidateTopicName(target); err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+	if underTopic(target, aliasName) || underTopic(aliasName, target) {
+		return nil, fmt.Errorf("alias %q and target %q must not contain one another", aliasName, target)
+	}
+
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	if resolved, existing := ps.topics.resolve(target); existing != nil {
+		return nil, fmt.Errorf("target %q is itself aliased to %q", target, resolved)
+	}
+
+	alias := &TopicAlias{Alias: aliasName, Target: target, Reason: reason, CreatedAt: ps.clock.Now()}
+	if ps.topicStore != nil {
+		if err := ps.topicStore.PutTopicAlias(ctx, alias); err != nil {
+			return nil, fmt.Errorf("failed to store topic alias: %w", err)
+		}
+	}
+
+	ps.topics.mu.Lock()
+	ps.topics.aliases[aliasName] = alias
+	ps.topics.mu.Unlock()
+
+	log.WithFields(log.Fields{"alias": aliasName, "target": target}).Info("Topic alias added")
+	return alias, nil
+}
+
+// RemoveTopicAlias removes an alias; publications to the old name are no longer redirected
+func (ps *PubSubService) RemoveTopicAlias(ctx context.Context, aliasName string) error {
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	ps.topics.mu.RLock()
+	_, exists := ps.topics.aliases[aliasName]
+	ps.topics.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("topic alias not found: %s", aliasName)
+	}
+
+	if ps.topicStore != nil {
+		if err := ps.topicStore.DeleteTopicAlias(ctx, aliasName); err != nil {
+			return fmt.Errorf("failed to delete topic alias: %w", err)
+		}
+	}
+
+	ps.topics.mu.Lock()
+	delete(ps.topics.aliases, aliasName)
+	delete(ps.topics.deprecations, aliasName)
+	ps.topics.mu.Unlock()
+
+	log.WithField("alias", aliasName).Info("Topic alias removed")
+	return nil
+}
+
+// ListRetentionPolicies returns the retention policies sorted by topic
+func (ps *PubSubService) ListRetentionPolicies() []*RetentionPolicy {
+	ps.topics.mu.RLock()
+	defer ps.topics.mu.RUnlock()
+	return ps.topics.retentionList()
+}
+
+// SetRetentionPolicy sets the default TTL of publications on a topic and the topics beneath it
+func (ps *PubSubService) SetRetentionPolicy(ctx context.Context, topic string, ttlSeconds int) (*RetentionPolicy, error) {
+	if err := ValidateTopicName(topic); err != nil {
+		return nil, err
+	}
+	if ttlSeconds <= 0 {
+		return nil, fmt.Errorf("ttl_seconds must be positive")
+	}
+
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	policy := &RetentionPolicy{Topic: topic, TTLSeconds: ttlSeconds, UpdatedAt: ps.clock.Now()}
+	if ps.topicStore != nil {
+		if err := ps.topicStore.PutRetentionPolicy(ctx, policy); err != nil {
+			return nil, fmt.Errorf("failed to store retention policy: %w", err)
+		}
+	}
+
+	ps.topics.mu.Lock()
+	ps.topics.retention[topic] = policy
+	ps.topics.mu.Unlock()
+
+	return policy, nil
+}
+
+// RemoveRetentionPolicy removes a topic's retention policy
+func (ps *PubSubService) RemoveRetentionPolicy(ctx context.Context, topic string) error {
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	ps.topics.mu.RLock()
+	_, exists := ps.topics.retention[topic]
+	ps.topics.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("retention policy not found: %s", topic)
+	}
+
+	if ps.topicStore != nil {
+		if err := ps.topicStore.DeleteRetentionPolicy(ctx, topic); err != nil {
+			return fmt.Errorf("failed to delete retention policy: %w", err)
+		}
+	}
+
+	ps.topics.mu.Lock()
+	delete(ps.topics.retention, topic)
+	ps.topics.mu.Unlock()
+	return nil
+}
+
+// TopicStats returns publication counts per topic, the aliases and retention
+// policies in force, and warnings for publications made to deprecated aliases
+func (ps *PubSubService) TopicStats() *TopicStatsReport {
+	return ps.topics.report()
+}
+
+// RenameTopics renames topics across subscriptions, retention policies and
+// existing aliases in one step. Unless DropAliases is set, each old name
+// becomes a deprecated alias of its new name so existing publishers keep
+// working. With a topic store the changes are applied in a single
+// transaction; otherwise subscription updates are rolled back on failure.
+func (ps *PubSubService) RenameTopics(ctx context.Context, req TopicRenameRequest) (*TopicRenameResult, error) {
+	if err := validateRenames(req.Renames); err != nil {
+		return nil, err
+	}
+
+	ps.topicsMu.Lock()
+	defer ps.topicsMu.Unlock()
+
+	result := &TopicRenameResult{
+		Subscriptions:     []SubscriptionRename{},
+		RetentionPolicies: []TopicRename{},
+		Aliases:           []*TopicAlias{},
+		DryRun:            req.DryRun,
+	}
+	changes := &TopicChangeSet{}
+	now := ps.clock.Now()
+
+	applyRenames := func(topic string) (string, bool) {
+		for _, rename := range req.Renames {
+			if renamed, ok := renameTopic(topic, rename); ok {
+				return renamed, true
+			}
+		}
+		return topic, false
+	}
+
+	ps.topics.mu.RLock()
+	for _, rename := range req.Renames {
+		if alias, ok := ps.topics.aliases[rename.To]; ok {
+			ps.topics.mu.RUnlock()
+			return nil, fmt.Errorf("target %q is an alias of %q", rename.To, alias.Target)
+		}
+	}
+
+	// Move retention policies to the new names
+	for topic, policy := range ps.topics.retention {
+		renamed, ok := applyRenames(topic)
+		if !ok {
+			continue
+		}
+		if _, exists := ps.topics.retention[renamed]; exists {
+			ps.topics.mu.RUnlock()
+			return nil, fmt.Errorf("retention policy for %q conflicts with the existing policy for %q", topic, renamed)
+		}
+		moved := *policy
+		moved.Topic = renamed
+		moved.UpdatedAt = now
+		changes.PutRetention = append(changes.PutRetention, &moved)
+		changes.DeleteRetention = append(changes.DeleteRetention, topic)
+		result.RetentionPolicies = append(result.RetentionPolicies, TopicRename{From: topic, To: renamed})
+	}
+
+	// Point existing aliases at the new names so aliases never chain
+	for _, alias := range ps.topics.aliases {
+		if renamed, ok := applyRenames(alias.Target); ok {
+			updated := *alias
+			updated.Target = renamed
+			changes.PutAliases = append(changes.PutAliases, &updated)
+			result.Aliases = append(result.Aliases, &updated)
+		}
+	}
+	ps.topics.mu.RUnlock()
+
+	if !req.DropAliases {
+		for _, rename := range req.Renames {
+			alias := &TopicAlias{Alias: rename.From, Target: rename.To, Reason: req.Reason, CreatedAt: now}
+			changes.PutAliases = append(changes.PutAliases, alias)
+			result.Aliases = append(result.Aliases, alias)
+		}
+	}
+
+	subscriptions, err := ps.repo.ListActiveSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	originals := make(map[string]string)
+	for _, sub := range subscriptions {
+		renamed, ok := applyRenames(sub.EventPattern)
+		if !ok {
+			for _, rename := range req.Renames {
+				if ps.matcher.MatchesPattern(rename.From, sub.EventPattern) {
+					result.Warnings = append(result.Warnings, fmt.Sprintf(
+						"subscription %s pattern %q matches %q but is not renamed", sub.ID, sub.EventPattern, rename.From))
+				}
+			}
+			continue
+		}
+
+		originals[sub.ID] = sub.EventPattern
+		result.Subscriptions = append(result.Subscriptions, SubscriptionRename{
+			SubscriptionID:    sub.ID,
+			SubscriberAgentID: sub.SubscriberAgentID,
+			From:              sub.EventPattern,
+			To:                renamed,
+		})
+		updated := *sub
+		updated.EventPattern = renamed
+		updated.UpdatedAt = now
+		changes.Subscriptions = append(changes.Subscriptions, &updated)
+	}
+
+	if req.DryRun {
+		return result, nil
+	}
+
+	if ps.topicStore != nil {
+		if err := ps.topicStore.ApplyTopicChanges(ctx, changes); err != nil {
+			return nil, fmt.Errorf("failed to apply topic rename: %w", err)
+		}
+	} else if err := ps.updateSubscriptionsWithRollback(ctx, changes.Subscriptions, originals); err != nil {
+		return nil, err
+	}
+
+	ps.topics.mu.Lock()
+	for _, topic := range changes.DeleteRetention {
+		delete(ps.topics.retention, topic)
+	}
+	for _, policy := range changes.PutRetention {
+		ps.topics.retention[policy.Topic] = policy
+	}
+	for _, alias := range changes.PutAliases {
+		ps.topics.aliases[alias.Alias] = alias
+	}
+	ps.topics.mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"renames":            len(req.Renames),
+		"subscriptions":      len(result.Subscriptions),
+		"retention_policies": len(result.RetentionPolicies),
+		"aliases":            len(result.Aliases),
+	}).Info("Topics renamed")
+
+	return result, nil
+}
+
+// updateSubscriptionsWithRollback updates subscriptions one by one, restoring
+// the original patterns if any update fails
+func (ps *PubSubService) updateSubscriptionsWithRollback(ctx context.Context, subscriptions []*Subscription, originals map[string]string) error {
+	for i, sub := range subscriptions {
+		if err := ps.repo.UpdateSubscription(ctx, sub); err != nil {
+			for _, done := range subscriptions[:i] {
+				restored := *done
+				restored.EventPattern = originals[done.ID]
+				if rollbackErr := ps.repo.UpdateSubscription(ctx, &restored); rollbackErr != nil {
+					log.WithError(rollbackErr).WithField("subscription_id", done.ID).Error("Failed to roll back subscription rename")
+				}
+			}
+			return fmt.Errorf("failed to rename subscription %s: %w", sub.ID, err)
+		}
+	}
+	return nil
+}
+
+// validateRenames checks names and rejects overlapping renames
+func validateRenames(renames []TopicRename) error {
+	if len(renames) == 0 {
+		return fmt.Errorf("at least one rename is required")
+	}
+
+	for i, rename := range renames {
+		if err := ValidateTopicName(rename.From); err != nil {
+			return fmt.Errorf("invalid rename %d: %w", i, err)
+		}
+		if err := ValidateTopicName(rename.To); err != nil {
+			return fmt.Errorf("invalid rename %d: %w", i, err)
+		}
+		if underTopic(rename.To, rename.From) || underTopic(rename.From, rename.To) {
+			return fmt.Errorf("invalid rename %d: %q and %q must not contain one another", i, rename.From, rename.To)
+		}
+		for j, other := range renames {
+			if j == i {
+				continue
+			}
+			if underTopic(rename.From, other.From) || underTopic(rename.To, other.From) {
+				return fmt.Errorf("renames of %q and %q overlap", other.From, rename.From)
+			}
+		}
+	}
+	return nil
+}
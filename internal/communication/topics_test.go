@@ -0,0 +1,137 @@
+package communication
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPubSubService_RenameTopics(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockPubSubRepo()
+	service := NewPubSubService(repo)
+
+	efficiencyID, err := service.Subscribe(ctx, "analyst-1", "analyst", "zone.north.pump.efficiency", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildcardID, err := service.Subscribe(ctx, "analyst-2", "analyst", "zone.north.pump.*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Subscribe(ctx, "monitor-1", "monitor", "zone.#", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SetRetentionPolicy(ctx, "zone.north.pump", 7200); err != nil {
+		t.Fatal(err)
+	}
+
+	req := TopicRenameRequest{
+		Renames: []TopicRename{{From: "zone.north.pump", To: "north.pumps"}},
+		Reason:  "zone restructuring",
+		DryRun:  true,
+	}
+	plan, err := service.RenameTopics(ctx, req)
+	if err != nil {
+		t.Fatalf("RenameTopics dry run failed: %v", err)
+	}
+	if len(plan.Subscriptions) != 2 || len(plan.RetentionPolicies) != 1 || len(plan.Aliases) != 1 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if repo.subscriptions[efficiencyID].EventPattern != "zone.north.pump.efficiency" {
+		t.Error("dry run must not change subscriptions")
+	}
+
+	req.DryRun = false
+	result, err := service.RenameTopics(ctx, req)
+	if err != nil {
+		t.Fatalf("RenameTopics failed: %v", err)
+	}
+	// zone.# still matches the old names but is not under the renamed topic
+	if len(result.Warnings) != 1 {
+		t.Errorf("expected a warning for the zone.# subscription, got %v", result.Warnings)
+	}
+	if got := repo.subscriptions[efficiencyID].EventPattern; got != "north.pumps.efficiency" {
+		t.Errorf("expected renamed subscription pattern, got %q", got)
+	}
+	if got := repo.subscriptions[wildcardID].EventPattern; got != "north.pumps.*" {
+		t.Errorf("expected renamed wildcard pattern, got %q", got)
+	}
+
+	policies := service.ListRetentionPolicies()
+	if len(policies) != 1 || policies[0].Topic != "north.pumps" || policies[0].TTLSeconds != 7200 {
+		t.Errorf("expected retention policy to move, got %+v", policies)
+	}
+
+	// Publishing to the old name still works, is redirected and warned about
+	pubID, err := service.Publish(ctx, "pump-7", "pump", "zone.north.pump.efficiency", map[string]interface{}{"value": 0.82}, nil)
+	if err != nil {
+		t.Fatalf("Publish to alias failed: %v", err)
+	}
+	pub := repo.publications[pubID]
+	if pub.EventName != "north.pumps.efficiency" {
+		t.Errorf("expected publication on the new topic, got %q", pub.EventName)
+	}
+	if pub.Metadata["aliased_from"] != "zone.north.pump.efficiency" {
+		t.Errorf("expected aliased_from metadata, got %v", pub.Metadata)
+	}
+	if pub.TTLSeconds != 7200 {
+		t.Errorf("expected retention policy TTL, got %d", pub.TTLSeconds)
+	}
+
+	if _, err := service.Publish(ctx, "pump-8", "pump", "north.pumps.efficiency", map[string]interface{}{"value": 0.9}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := service.TopicStats()
+	if len(stats.Topics) != 1 || stats.Topics[0].Publications != 2 {
+		t.Errorf("expected 2 publications on north.pumps.efficiency, got %+v", stats.Topics)
+	}
+	if len(stats.Deprecations) != 1 {
+		t.Fatalf("expected 1 deprecation warning, got %d", len(stats.Deprecations))
+	}
+	warning := stats.Deprecations[0]
+	if warning.Alias != "zone.north.pump" || warning.Target != "north.pumps" || warning.Publications != 1 {
+		t.Errorf("unexpected deprecation warning: %+v", warning)
+	}
+	if len(warning.Publishers) != 1 || warning.Publishers[0] != "pump-7" {
+		t.Errorf("expected deprecated publisher pump-7, got %v", warning.Publishers)
+	}
+
+	// A second rename re-points the existing alias instead of chaining
+	if _, err := service.RenameTopics(ctx, TopicRenameRequest{
+		Renames: []TopicRename{{From: "north.pumps", To: "north.pumping"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if resolved, _ := service.topics.resolve("zone.north.pump.efficiency"); resolved != "north.pumping.efficiency" {
+		t.Errorf("expected alias to follow the second rename, got %q", resolved)
+	}
+}
+
+func TestPubSubService_RenameTopicsValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewPubSubService(newMockPubSubRepo())
+
+	invalid := [][]TopicRename{
+		nil,
+		{{From: "zone.*", To: "zones"}},
+		{{From: "zone", To: "zone.north"}},
+		{{From: "zone.north", To: "north"}, {From: "zone", To: "zones"}},
+		{{From: "a", To: "b"}, {From: "b", To: "c"}},
+	}
+	for _, renames := range invalid {
+		if _, err := service.RenameTopics(ctx, TopicRenameRequest{Renames: renames}); err == nil {
+			t.Errorf("expected renames %v to be rejected", renames)
+		}
+	}
+
+	if _, err := service.AddTopicAlias(ctx, "legacy", "current", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.AddTopicAlias(ctx, "older", "legacy", ""); err == nil {
+		t.Error("expected alias to an alias to be rejected")
+	}
+	if _, err := service.RenameTopics(ctx, TopicRenameRequest{Renames: []TopicRename{{From: "other", To: "legacy"}}}); err == nil {
+		t.Error("expected rename onto an alias to be rejected")
+	}
+}
@@ -0,0 +1,316 @@
+package communication
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CaptureFormatVersion identifies the layout of traffic capture files
+const CaptureFormatVersion = "1"
+
+// TrafficCapture is a portable recording of pub/sub traffic
+type TrafficCapture struct {
+	FormatVersion string    `json:"format_version"`
+	CapturedAt    time.Time `json:"captured_at"`
+
+	// Topics are the event patterns that were captured
+	Topics []string `json:"topics"`
+
+	// Since and Until bound the captured time window
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	// Publications are the captured publications, oldest first
+	Publications []CapturedPublication `json:"publications"`
+}
+
+// CapturedPublication is a publication as recorded in a capture file
+type CapturedPublication struct {
+	OriginalID         string                 `json:"original_id"`
+	PublisherAgentID   string                 `json:"publisher_agent_id"`
+	PublisherAgentType string                 `json:"publisher_agent_type"`
+	PublicationType    PublicationType        `json:"publication_type"`
+	EventName          string                 `json:"event_name"`
+	Payload            map[string]interface{} `json:"payload"`
+	TTLSeconds         int                    `json:"ttl_seconds"`
+	Metadata           map[string]string      `json:"metadata,omitempty"`
+	PublishedAt        time.Time              `json:"published_at"`
+
+	// OffsetMs is the time since the start of the capture window
+	OffsetMs int64 `json:"offset_ms"`
+}
+
+// TrafficPublisher publishes replayed traffic. PubSubService implements it for
+// in-process replay; HTTPTrafficPublisher replays into a remote instance.
+type TrafficPublisher interface {
+	Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *PublicationOptions) (string, error)
+}
+
+// ReplayOptions configures a traffic replay
+type ReplayOptions struct {
+	// Speed scales the original inter-publication delays (2 = twice as fast).
+	// Zero or negative replays without delays.
+	Speed float64 `json:"speed"`
+
+	// IDMap remaps agent IDs. It is applied to publisher IDs and to string
+	// payload values that exactly equal a mapped ID.
+	IDMap map[string]string `json:"id_map,omitempty"`
+}
+
+// ReplayReport summarises a traffic replay
+type ReplayReport struct {
+	Total     int           `json:"total"`
+	Published int           `json:"published"`
+	Failed    int           `json:"failed"`
+	Errors    []string      `json:"errors,omitempty"`
+	Duration  time.Duration `json:"duration"`
+
+	// IDs maps original publication IDs to the IDs assigned on replay
+	IDs map[string]string `json:"ids"`
+}
+
+// CaptureTraffic records all publications on the given topics (event patterns)
+// published in [since, until]. Publications are only available until their TTL
+// expires, so captures should be taken promptly after an incident.
+func (ps *PubSubService) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*TrafficCapture, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("at least one topic is required")
+	}
+	if !until.After(since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+
+	publications, err := ps.repo.GetPublicationsInRange(ctx, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get publications: %w", err)
+	}
+	ps.openPublications(publications...)
+
+	capture := &TrafficCapture{
+		FormatVersion: CaptureFormatVersion,
+		CapturedAt:    time.Now(),
+		Topics:        topics,
+		Since:         since,
+		Until:         until,
+		Publications:  []CapturedPublication{},
+	}
+
+	for _, pub := range publications {
+		if !ps.matchesAnyTopic(pub.EventName, topics) {
+			continue
+		}
+		capture.Publications = append(capture.Publications, CapturedPublication{
+			OriginalID:         pub.ID,
+			PublisherAgentID:   pub.PublisherAgentID,
+			PublisherAgentType: pub.PublisherAgentType,
+			PublicationType:    pub.PublicationType,
+			EventName:          pub.EventName,
+			Payload:            pub.Payload,
+			TTLSeconds:         pub.TTLSeconds,
+			Metadata:           pub.Metadata,
+			PublishedAt:        pub.PublishedAt,
+			OffsetMs:           pub.PublishedAt.Sub(since).Milliseconds(),
+		})
+	}
+
+	sort.SliceStable(capture.Publications, func(i, j int) bool {
+		return capture.Publications[i].PublishedAt.Before(capture.Publications[j].PublishedAt)
+	})
+
+	log.WithFields(log.Fields{
+		"topics":       topics,
+		"since":        since,
+		"until":        until,
+		"publications": len(capture.Publications),
+	}).Info("Captured pub/sub traffic")
+
+	return capture, nil
+}
+
+// matchesAnyTopic reports whether an event name matches any captured topic
+func (ps *PubSubService) matchesAnyTopic(eventName string, topics []string) bool {
+	for _, topic := range topics {
+		if ps.matcher.MatchesPattern(eventName, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplayTraffic republishes a capture through the given publisher, preserving the
+// original spacing between publications scaled by opts.Speed. Replayed
+// publications carry "replay_of" metadata pointing at the original ID.
+func ReplayTraffic(ctx context.Context, publisher TrafficPublisher, capture *TrafficCapture, opts ReplayOptions) (*ReplayReport, error) {
+	if capture == nil {
+		return nil, fmt.Errorf("capture is required")
+	}
+
+	started := time.Now()
+	report := &ReplayReport{
+		Total: len(capture.Publications),
+		IDs:   make(map[string]string),
+	}
+
+	var previousOffset int64
+	for i, pub := range capture.Publications {
+		if i > 0 && opts.Speed > 0 {
+			delay := time.Duration(float64(pub.OffsetMs-previousOffset)/opts.Speed) * time.Millisecond
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					report.Duration = time.Since(started)
+					return report, ctx.Err()
+				}
+			}
+		}
+		previousOffset = pub.OffsetMs
+
+		metadata := make(map[string]string, len(pub.Metadata)+1)
+		for k, v := range pub.Metadata {
+			metadata[k] = v
+		}
+		metadata["replay_of"] = pub.OriginalID
+
+		publicationOpts := &PublicationOptions{
+			Type:       pub.PublicationType,
+			TTLSeconds: pub.TTLSeconds,
+			Metadata:   metadata,
+		}
+
+		payload := remapPayload(pub.Payload, opts.IDMap)
+		newID, err := publisher.Publish(ctx, remapID(pub.PublisherAgentID, opts.IDMap), pub.PublisherAgentType, pub.EventName, payload, publicationOpts)
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", pub.OriginalID, err))
+			continue
+		}
+
+		report.Published++
+		report.IDs[pub.OriginalID] = newID
+	}
+
+	report.Duration = time.Since(started)
+
+	log.WithFields(log.Fields{
+		"total":     report.Total,
+		"published": report.Published,
+		"failed":    report.Failed,
+		"speed":     opts.Speed,
+	}).Info("Replayed pub/sub traffic")
+
+	return report, nil
+}
+
+// remapID returns the mapped ID, or the original if it is not mapped
+func remapID(id string, idMap map[string]string) string {
+	if mapped, ok := idMap[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// remapPayload copies a payload, replacing string values that are mapped IDs
+func remapPayload(payload map[string]interface{}, idMap map[string]string) map[string]interface{} {
+	if payload == nil {
+		return map[string]interface{}{}
+	}
+
+	result := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		result[k] = remapValue(v, idMap)
+	}
+	return result
+}
+
+func remapValue(value interface{}, idMap map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return remapID(v, idMap)
+	case map[string]interface{}:
+		return remapPayload(v, idMap)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = remapValue(item, idMap)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// HTTPTrafficPublisher replays traffic into a remote instance through its
+// /api/v1/communications/publish endpoint
+type HTTPTrafficPublisher struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewHTTPTrafficPublisher creates a publisher for the instance at baseURL.
+// apiKey needs the communications scope when the instance requires keys; it
+// may be empty otherwise.
+func NewHTTPTrafficPublisher(baseURL, apiKey string) *HTTPTrafficPublisher {
+	return &HTTPTrafficPublisher{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Publish posts a publication to the remote instance
+func (p *HTTPTrafficPublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *PublicationOptions) (string, error) {
+	body := map[string]interface{}{
+		"publisher_agent_id":   publisherAgentID,
+		"publisher_agent_type": publisherAgentType,
+		"event_name":           eventName,
+		"payload":              payload,
+	}
+	if opts != nil {
+		body["publication_type"] = string(opts.Type)
+		body["ttl_seconds"] = opts.TTLSeconds
+		body["metadata"] = opts.Metadata
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode publication: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/v1/communications/publish", bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("X-API-Key", p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		PublicationID string `json:"publication_id"`
+		Error         string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("publish failed with status %d: %s", resp.StatusCode, result.Error)
+	}
+
+	return result.PublicationID, nil
+}
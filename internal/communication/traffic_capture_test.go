@@ -0,0 +1,121 @@
+package communication
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// recordingPublisher records replayed publications
+type recordingPublisher struct {
+	published []*Publication
+	times     []time.Time
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *PublicationOptions) (string, error) {
+	p.published = append(p.published, &Publication{
+		PublisherAgentID:   publisherAgentID,
+		PublisherAgentType: publisherAgentType,
+		EventName:          eventName,
+		Payload:            payload,
+		Metadata:           opts.Metadata,
+	})
+	p.times = append(p.times, time.Now())
+	return "replayed-" + eventName, nil
+}
+
+// TestCaptureAndReplayTraffic tests capturing topics and replaying with ID remapping
+func TestCaptureAndReplayTraffic(t *testing.T) {
+	repo := newMockPubSubRepo()
+	svc := NewPubSubService(repo)
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Minute)
+	add := func(id, event string, offset time.Duration, payload map[string]interface{}) {
+		repo.publications[id] = &Publication{
+			ID:                 id,
+			PublisherAgentID:   "PUMP-001",
+			PublisherAgentType: "pump",
+			PublicationType:    PublicationTypeAlert,
+			EventName:          event,
+			Payload:            payload,
+			PublishedAt:        base.Add(offset),
+			TTLSeconds:         3600,
+		}
+	}
+	add("pub-2", "alert.pressure", 40*time.Millisecond, map[string]interface{}{"asset": "PUMP-001", "nested": map[string]interface{}{"peer": "VALVE-001"}})
+	add("pub-1", "reading.pressure", 0, map[string]interface{}{"value": 4.2})
+	add("pub-3", "task.completed", 20*time.Millisecond, map[string]interface{}{})
+	add("pub-old", "alert.pressure", -time.Hour, map[string]interface{}{})
+
+	capture, err := svc.CaptureTraffic(ctx, []string{"alert.*", "reading.*"}, base, base.Add(time.Second))
+	if err != nil {
+		t.Fatalf("CaptureTraffic() error = %v", err)
+	}
+	if len(capture.Publications) != 2 {
+		t.Fatalf("captured %d publications, want 2", len(capture.Publications))
+	}
+	if capture.Publications[0].OriginalID != "pub-1" || capture.Publications[1].OffsetMs != 40 {
+		t.Errorf("captured publications out of order: %+v", capture.Publications)
+	}
+
+	publisher := &recordingPublisher{}
+	report, err := ReplayTraffic(ctx, publisher, capture, ReplayOptions{
+		Speed: 2,
+		IDMap: map[string]string{"PUMP-001": "STAGING-PUMP-001", "VALVE-001": "STAGING-VALVE-001"},
+	})
+	if err != nil {
+		t.Fatalf("ReplayTraffic() error = %v", err)
+	}
+	if report.Published != 2 || report.Failed != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	alert := publisher.published[1]
+	if alert.PublisherAgentID != "STAGING-PUMP-001" || alert.Payload["asset"] != "STAGING-PUMP-001" {
+		t.Errorf("IDs not remapped: %+v", alert)
+	}
+	if alert.Payload["nested"].(map[string]interface{})["peer"] != "STAGING-VALVE-001" {
+		t.Errorf("nested ID not remapped: %+v", alert.Payload)
+	}
+	if alert.Metadata["replay_of"] != "pub-2" {
+		t.Errorf("replay_of = %q, want pub-2", alert.Metadata["replay_of"])
+	}
+
+	// 40ms original spacing at 2x speed
+	if gap := publisher.times[1].Sub(publisher.times[0]); gap < 15*time.Millisecond {
+		t.Errorf("replay gap = %v, want ~20ms", gap)
+	}
+}
+
+func TestHTTPTrafficPublisher_SendsAPIKey(t *testing.T) {
+	var gotKey, gotEvent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-API-Key")
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotEvent, _ = body["event_name"].(string)
+		if gotKey != "comms-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"publication_id": "pub-1"})
+	}))
+	defer server.Close()
+
+	id, err := NewHTTPTrafficPublisher(server.URL+"/", "comms-key").Publish(context.Background(), "sensor-1", "sensor", "alert.leak", nil, nil)
+	if err != nil || id != "pub-1" {
+		t.Fatalf("Publish() = %q, %v", id, err)
+	}
+	if gotEvent != "alert.leak" {
+		t.Errorf("server received event %q", gotEvent)
+	}
+
+	if _, err := NewHTTPTrafficPublisher(server.URL, "").Publish(context.Background(), "sensor-1", "sensor", "alert.leak", nil, nil); err == nil {
+		t.Error("expected publishing without a key to fail")
+	}
+}
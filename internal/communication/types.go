@@ -80,6 +80,10 @@ type Message struct {
 
 	// Metadata contains additional message metadata
 	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Sequence orders the messages of a recipient whose type is delivered in
+	// order (0 for unordered messages)
+	Sequence int64 `json:"sequence,omitempty"`
 }
 
 // PublicationType defines the type of publication
@@ -98,6 +102,16 @@ const (
 	PublicationTypeBroadcast PublicationType = "broadcast"
 )
 
+// DeliveryMode defines how matching publications reach a subscriber
+type DeliveryMode string
+
+const (
+	// DeliveryModePull means the subscriber polls for matching publications
+	DeliveryModePull DeliveryMode = "pull"
+	// DeliveryModePush means publications go to the subscriber's handler or inbox as they are published
+	DeliveryModePush DeliveryMode = "push"
+)
+
 // Publication represents a broadcast event or status update
 type Publication struct {
 	// ID is the unique publication identifier (ArangoDB _key)
@@ -163,6 +177,12 @@ type Subscription struct {
 	// FilterConditions contains additional filtering rules
 	FilterConditions map[string]interface{} `json:"filter_conditions,omitempty"`
 
+	// FilterExpression filters on payload fields (e.g., "severity >= HIGH && zone == north")
+	FilterExpression string `json:"filter_expression,omitempty"`
+
+	// DeliveryMode is push or pull (empty means pull)
+	DeliveryMode DeliveryMode `json:"delivery_mode,omitempty"`
+
 	// CreatedAt is when the subscription was created
 	CreatedAt time.Time `json:"created_at"`
 
@@ -256,6 +276,12 @@ type SubscriptionFilters struct {
 	// Conditions contains custom filter conditions
 	Conditions map[string]interface{}
 
+	// Expression is a payload filter expression
+	Expression string
+
+	// DeliveryMode is push or pull (default pull)
+	DeliveryMode DeliveryMode
+
 	// Metadata for additional context
 	Metadata map[string]string
 }
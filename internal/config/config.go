@@ -28,6 +28,90 @@ type Config struct {
 
 	// AI configuration
 	AI AIConfig `mapstructure:"ai"`
+
+	// Zone summary configuration
+	ZoneSummaries []ZoneSummaryConfig `mapstructure:"zone_summaries"`
+
+	// Agent health scoring configuration
+	HealthScoring HealthScoringConfig `mapstructure:"health_scoring"`
+
+	// Alert routing configuration
+	AlertRouting AlertRoutingConfig `mapstructure:"alert_routing"`
+
+	// Per-tenant usage metering configuration
+	Usage UsageConfig `mapstructure:"usage"`
+
+	// Simulation time configuration
+	Simulation SimulationConfig `mapstructure:"simulation"`
+
+	// Ordered direct message delivery configuration
+	MessageOrdering MessageOrderingConfig `mapstructure:"message_ordering"`
+
+	// Priority delivery of direct messages
+	MessagePriority MessagePriorityConfig `mapstructure:"message_priority"`
+
+	// Guardrail policies for commands sent between agents
+	Guardrails GuardrailsConfig `mapstructure:"guardrails"`
+
+	// Operator-defined metrics computed from telemetry series
+	DerivedMetrics DerivedMetricsConfig `mapstructure:"derived_metrics"`
+
+	// Storage, retention and downsampling of ingested telemetry points
+	Telemetry TelemetryConfig `mapstructure:"telemetry"`
+
+	// Incidents opened from alert publications
+	Incidents IncidentsConfig `mapstructure:"incidents"`
+
+	// Delivery of side effects recorded with data changes
+	Outbox OutboxConfig `mapstructure:"outbox"`
+
+	// Field-level masking of sensitive message and publication payloads
+	Masking MaskingConfig `mapstructure:"masking"`
+
+	// Network topology used for valve isolation impact analysis
+	Topology TopologyConfig `mapstructure:"topology"`
+
+	// Persistent queue for background jobs
+	Jobs JobsConfig `mapstructure:"jobs"`
+
+	// Workflow engine workers and agent task assignment limits
+	Orchestration OrchestrationConfig `mapstructure:"orchestration"`
+
+	// Strict validation of agency designs
+	AgencyValidation AgencyValidationConfig `mapstructure:"agency_validation"`
+
+	// Live change stream for agency designer sessions
+	ChangeFeed ChangeFeedConfig `mapstructure:"change_feed"`
+
+	// Migration of historical pub/sub traffic into newer subsystems
+	Backfill BackfillConfig `mapstructure:"backfill"`
+
+	// Removal of publications whose TTL has passed
+	PublicationExpiry PublicationExpiryConfig `mapstructure:"publication_expiry"`
+
+	// Cache of agent working memory in front of the memory repository
+	MemoryCache MemoryCacheConfig `mapstructure:"memory_cache"`
+
+	// Encryption of agent memory values at rest
+	MemoryEncryption MemoryEncryptionConfig `mapstructure:"memory_encryption"`
+
+	// Per-agent limits on working memory
+	MemoryQuota MemoryQuotaConfig `mapstructure:"memory_quota"`
+
+	// Compression and periodic snapshots of agent memory
+	MemorySnapshots MemorySnapshotsConfig `mapstructure:"memory_snapshots"`
+
+	// Model Context Protocol server for external AI assistants and IDEs
+	MCP MCPConfig `mapstructure:"mcp"`
+
+	// API key authentication of the HTTP API
+	Auth AuthConfig `mapstructure:"auth"`
+
+	// Append-only log of mutating API operations
+	Audit AuditConfig `mapstructure:"audit"`
+
+	// Token bucket rate limits of API clients
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 }
 
 // ServerConfig holds server-related configuration
@@ -39,6 +123,10 @@ type ServerConfig struct {
 	TLSEnabled   bool   `mapstructure:"tls_enabled"`
 	TLSCertFile  string `mapstructure:"tls_cert_file"`
 	TLSKeyFile   string `mapstructure:"tls_key_file"`
+
+	// TrustedProxies are the addresses or CIDRs of reverse proxies whose
+	// X-Forwarded-For header gives the client address; empty trusts none
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig holds database connection configuration
@@ -50,6 +138,26 @@ type DatabaseConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	SSLMode  string `mapstructure:"ssl_mode"`
+
+	ReadReplica ReadReplicaConfig           `mapstructure:"read_replica"` // Endpoint for heavy analytical reads
+	QueryRoutes map[string]QueryRouteConfig `mapstructure:"query_routes"` // Per-route overrides, keyed by route name
+}
+
+// ReadReplicaConfig points analytical queries at a read replica (or follower)
+// of the primary database. Credentials default to the primary's.
+type ReadReplicaConfig struct {
+	Host                       string `mapstructure:"host"`                          // Replica host; empty disables the replica
+	Port                       int    `mapstructure:"port"`                          // Replica port (defaults to the primary port)
+	Username                   string `mapstructure:"username"`                      // Defaults to the primary username
+	Password                   string `mapstructure:"password"`                      // Defaults to the primary password
+	HeartbeatIntervalSeconds   int    `mapstructure:"heartbeat_interval_seconds"`    // How often replica staleness is measured (default 5)
+	DefaultMaxStalenessSeconds int    `mapstructure:"default_max_staleness_seconds"` // Staleness accepted by routes without an override (default 30)
+}
+
+// QueryRouteConfig tunes where one designated query route is served from
+type QueryRouteConfig struct {
+	PrimaryOnly         bool `mapstructure:"primary_only"`          // Always query the primary
+	MaxStalenessSeconds int  `mapstructure:"max_staleness_seconds"` // Replica staleness accepted by this route
 }
 
 // KubernetesConfig holds Kubernetes client configuration
@@ -65,6 +173,11 @@ type AgentConfig struct {
 	DefaultResources map[string]string `mapstructure:"default_resources"`
 	MaxInstances     int               `mapstructure:"max_instances"`
 	HealthCheckPath  string            `mapstructure:"health_check_path"`
+
+	// Liveness thresholds: how long after its last heartbeat an agent is
+	// degraded (default 60) and offline (default 150)
+	DegradedAfterSeconds int `mapstructure:"degraded_after_seconds"`
+	OfflineAfterSeconds  int `mapstructure:"offline_after_seconds"`
 }
 
 // AIConfig holds AI/LLM configuration
@@ -76,6 +189,448 @@ type AIConfig struct {
 	Temperature float32 `mapstructure:"temperature"` // Default temperature
 	MaxTokens   int     `mapstructure:"max_tokens"`  // Default max tokens
 	Timeout     int     `mapstructure:"timeout"`     // Request timeout in seconds
+
+	// RepairAttempts is how many times goal and work item builders send a
+	// response that does not match their JSON schema back to the model with
+	// the validation errors (0 uses the default of 2, -1 disables repairs)
+	RepairAttempts int `mapstructure:"repair_attempts"`
+
+	// Providers are additional named providers. Without a top-level provider
+	// the first one is the default.
+	Providers []AIProviderConfig `mapstructure:"providers"`
+	Fallbacks []string           `mapstructure:"fallbacks"` // Providers tried in order when the default fails
+
+	// Features route AI operations (e.g. "goals", "work_items.consolidate")
+	// to a provider and model
+	Features map[string]AIFeatureConfig `mapstructure:"features"`
+
+	Capture LLMCaptureConfig `mapstructure:"capture"` // Debug capture of LLM calls
+	Cache   LLMCacheConfig   `mapstructure:"cache"`   // Reuse of responses to identical LLM requests
+}
+
+// AIProviderConfig configures a named LLM provider
+type AIProviderConfig struct {
+	Name        string  `mapstructure:"name"`        // Name used by fallbacks and features (defaults to provider)
+	Provider    string  `mapstructure:"provider"`    // "openai", "azure", "claude", "local", "custom"
+	APIKey      string  `mapstructure:"api_key"`     // API key for provider
+	Model       string  `mapstructure:"model"`       // Model to use (deployment name for Azure)
+	BaseURL     string  `mapstructure:"base_url"`    // Custom base URL (resource endpoint for Azure)
+	APIVersion  string  `mapstructure:"api_version"` // Azure OpenAI API version
+	Temperature float32 `mapstructure:"temperature"` // Default temperature
+	MaxTokens   int     `mapstructure:"max_tokens"`  // Default max tokens
+	Timeout     int     `mapstructure:"timeout"`     // Request timeout in seconds
+}
+
+// AIFeatureConfig selects the provider and model for an AI operation
+type AIFeatureConfig struct {
+	Provider  string   `mapstructure:"provider"`  // Provider name (defaults to the default provider)
+	Model     string   `mapstructure:"model"`     // Model override for that provider
+	Fallbacks []string `mapstructure:"fallbacks"` // Providers tried when it fails (defaults to the global fallbacks)
+}
+
+// Enabled reports whether any LLM provider is configured
+func (c AIConfig) Enabled() bool {
+	return c.Provider != "" || len(c.Providers) > 0
+}
+
+// LLMCaptureConfig configures the opt-in capture of LLM prompts and responses
+type LLMCaptureConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`         // Capture every LLM call
+	MaxEntries     int      `mapstructure:"max_entries"`     // Captures kept in memory (default 500)
+	MaxAgeMinutes  int      `mapstructure:"max_age_minutes"` // Captures older than this are dropped (default 1440)
+	RedactPatterns []string `mapstructure:"redact_patterns"` // Extra regular expressions redacted from captures
+}
+
+// LLMCacheConfig configures the cache of LLM responses. Identical builder
+// requests (same operation, model, parameters and prompt) are answered from
+// the cache until the TTL passes or the request asks to bypass it.
+type LLMCacheConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`     // Cache LLM responses
+	Store      string   `mapstructure:"store"`       // "memory" (default) or "arango" to share the cache between instances
+	TTLSeconds int      `mapstructure:"ttl_seconds"` // How long a response is reused (default 3600)
+	MaxEntries int      `mapstructure:"max_entries"` // Responses kept by the memory store (default 1000)
+	Operations []string `mapstructure:"operations"`  // Operations or prefixes to cache, e.g. "goals"; empty caches all
+}
+
+// ZoneSummaryConfig configures periodic status summaries for a zone
+type ZoneSummaryConfig struct {
+	Zone            string   `mapstructure:"zone"`             // Zone identifier
+	IntervalSeconds int      `mapstructure:"interval_seconds"` // How often to summarize
+	LookbackSeconds int      `mapstructure:"lookback_seconds"` // Activity window (defaults to interval)
+	SourceTopics    []string `mapstructure:"source_topics"`    // Event patterns to aggregate
+	SummaryTopic    string   `mapstructure:"summary_topic"`    // Event name for published summaries
+	PromptTemplate  string   `mapstructure:"prompt_template"`  // text/template for the LLM prompt
+	Notify          bool     `mapstructure:"notify"`           // Also send summaries as notifications
+}
+
+// HealthScoringConfig configures periodic 0-100 health scores per agent
+type HealthScoringConfig struct {
+	Enabled           bool                    `mapstructure:"enabled"`            // Compute scores on a schedule
+	IntervalSeconds   int                     `mapstructure:"interval_seconds"`   // How often to score all agents
+	LookbackSeconds   int                     `mapstructure:"lookback_seconds"`   // Signal window (defaults to 24h)
+	AssetPayloadKeys  []string                `mapstructure:"asset_payload_keys"` // Payload keys that attribute a publication to an agent
+	AnomalyTopics     []string                `mapstructure:"anomaly_topics"`     // Event patterns counted as anomalies
+	MaintenanceTopics []string                `mapstructure:"maintenance_topics"` // Event patterns counted as maintenance history
+	Rules             []HealthScoreRuleConfig `mapstructure:"rules"`              // Actions taken when a score drops below a threshold
+	AnomalyFlags      HealthAnomalyFlagConfig `mapstructure:"anomaly_flags"`      // When degrading agents are flagged
+}
+
+// HealthAnomalyFlagConfig sets the thresholds that flag a degrading agent
+type HealthAnomalyFlagConfig struct {
+	MissedHeartbeats int     `mapstructure:"missed_heartbeats"`  // Heartbeat intervals missed (default 2)
+	TaskFailureRate  float64 `mapstructure:"task_failure_rate"`  // Fraction of tasks failed (default 0.2)
+	MessageErrorRate float64 `mapstructure:"message_error_rate"` // Fraction of messages failed or expired (default 0.1)
+	ScoreDrop        int     `mapstructure:"score_drop"`         // Score drop since the previous score (default 20)
+}
+
+// HealthScoreRuleConfig fires when an agent's score drops below a threshold
+type HealthScoreRuleConfig struct {
+	Name       string `mapstructure:"name"`        // Rule name, included in events and workflow context
+	Below      int    `mapstructure:"below"`       // Fires when the score drops below this value
+	EventName  string `mapstructure:"event_name"`  // Event to publish (defaults to health.score.low)
+	WorkflowID string `mapstructure:"workflow_id"` // Workflow to start, if any
+}
+
+// AlertRoutingConfig routes alerts to destinations by severity, zone and type
+type AlertRoutingConfig struct {
+	Enabled             bool                     `mapstructure:"enabled"`              // Route alert publications as they are published
+	Timezone            string                   `mapstructure:"timezone"`             // Default timezone for schedules (defaults to UTC)
+	Policies            []AlertPolicyConfig      `mapstructure:"policies"`             // Evaluated by priority, highest first
+	DefaultDestinations []AlertDestinationConfig `mapstructure:"default_destinations"` // Used when no policy matches
+}
+
+// AlertPolicyConfig maps alert attributes to destinations. Empty match lists match anything.
+type AlertPolicyConfig struct {
+	Name         string                   `mapstructure:"name"`         // Policy name
+	Priority     int                      `mapstructure:"priority"`     // Higher priorities are evaluated first
+	Severities   []string                 `mapstructure:"severities"`   // Severities to match, e.g. CRITICAL
+	Zones        []string                 `mapstructure:"zones"`        // Zone patterns to match, e.g. zone-north or zone-*
+	AlertTypes   []string                 `mapstructure:"alert_types"`  // Alert type patterns to match, e.g. pressure.*
+	Continue     bool                     `mapstructure:"continue"`     // Keep evaluating lower priority policies after a match
+	Destinations []AlertDestinationConfig `mapstructure:"destinations"` // Where matching alerts are sent
+	Schedules    []AlertScheduleConfig    `mapstructure:"schedules"`    // Time-of-day overrides such as on-call rotations
+}
+
+// AlertDestinationConfig is a place an alert is delivered to
+type AlertDestinationConfig struct {
+	Type   string `mapstructure:"type"`   // agent, topic, webhook or notification
+	Target string `mapstructure:"target"` // Agent ID, event name, URL or channel name
+}
+
+// AlertScheduleConfig overrides a policy's destinations during a time window
+type AlertScheduleConfig struct {
+	Name         string                   `mapstructure:"name"`         // Schedule name, e.g. night-on-call
+	Days         []string                 `mapstructure:"days"`         // Weekdays (mon..sun); empty means every day
+	Start        string                   `mapstructure:"start"`        // Window start, HH:MM
+	End          string                   `mapstructure:"end"`          // Window end, HH:MM; may wrap past midnight
+	Timezone     string                   `mapstructure:"timezone"`     // Overrides the routing timezone
+	Mode         string                   `mapstructure:"mode"`         // replace (default) or add
+	Destinations []AlertDestinationConfig `mapstructure:"destinations"` // Destinations while the window is active
+}
+
+// UsageConfig configures per-tenant (agency) usage metering for billing
+type UsageConfig struct {
+	Enabled                  bool                          `mapstructure:"enabled"`                    // Aggregate usage on a schedule
+	AggregateIntervalSeconds int                           `mapstructure:"aggregate_interval_seconds"` // How often daily totals are updated
+	SoftLimits               map[string]float64            `mapstructure:"soft_limits"`                // Daily limit per metric for every tenant
+	TenantLimits             map[string]map[string]float64 `mapstructure:"tenant_limits"`              // Per-tenant overrides of soft_limits
+	LimitWebhooks            []string                      `mapstructure:"limit_webhooks"`             // URLs notified when a soft limit is crossed
+	LLMMonthlyTokenBudget    float64                       `mapstructure:"llm_monthly_token_budget"`   // LLM tokens each tenant may use per calendar month (0 means unlimited)
+	LLMTenantBudgets         map[string]float64            `mapstructure:"llm_tenant_budgets"`         // Per-tenant overrides of llm_monthly_token_budget (0 means unlimited)
+	LLMPricing               map[string]LLMPriceConfig     `mapstructure:"llm_pricing"`                // Per-model prices used to estimate LLM cost
+}
+
+// LLMPriceConfig is the price of a model's tokens in US dollars
+type LLMPriceConfig struct {
+	PromptPer1K     float64 `mapstructure:"prompt_per_1k"`     // Cost of 1000 prompt tokens
+	CompletionPer1K float64 `mapstructure:"completion_per_1k"` // Cost of 1000 completion tokens
+}
+
+// SimulationConfig runs the framework on a controllable clock instead of wall time
+type SimulationConfig struct {
+	Enabled   bool    `mapstructure:"enabled"`    // Use a simulated clock
+	StartTime string  `mapstructure:"start_time"` // RFC3339 start of simulated time (defaults to the current time)
+	Speed     float64 `mapstructure:"speed"`      // Simulated seconds per real second; 0 starts the clock paused, moving only when advanced
+}
+
+// MessageOrderingConfig delivers direct messages of some types to each
+// recipient in the order they were accepted
+type MessageOrderingConfig struct {
+	MessageTypes      []string `mapstructure:"message_types"`       // Glob patterns of ordered message types ("*" for all)
+	GapTimeoutSeconds int      `mapstructure:"gap_timeout_seconds"` // How long a missing message holds back later ones (default 30)
+	TraceSize         int      `mapstructure:"trace_size"`          // Delivery events kept per recipient (default 200)
+}
+
+// MessagePriorityConfig orders a recipient's pending direct messages by
+// priority (1-10, higher first)
+type MessagePriorityConfig struct {
+	PreemptPriority int `mapstructure:"preempt_priority"` // Lowest priority handled before already fetched messages (default 9, -1 disables)
+	AgingSeconds    int `mapstructure:"aging_seconds"`    // Waiting this long raises a message one priority level (default 60, -1 disables)
+}
+
+// GuardrailsConfig constrains the commands agents may send. Commands that break
+// a policy are held back and logged as violations for operator review.
+type GuardrailsConfig struct {
+	CommandField  string                  `mapstructure:"command_field"`  // Payload field naming the command (default "command")
+	MaxViolations int                     `mapstructure:"max_violations"` // Violations kept in the log (default 500)
+	Policies      []GuardrailPolicyConfig `mapstructure:"policies"`
+}
+
+// GuardrailPolicyConfig declares the commands agents of one type may send
+type GuardrailPolicyConfig struct {
+	AgentType       string                      `mapstructure:"agent_type"`       // Sender agent type ("*" for every type)
+	MessageTypes    []string                    `mapstructure:"message_types"`    // Message types checked (default ["command"])
+	AllowedCommands []string                    `mapstructure:"allowed_commands"` // Glob patterns of permitted commands (empty allows any)
+	ValueRanges     map[string]ValueRangeConfig `mapstructure:"value_ranges"`     // Bounds of numeric payload fields by dotted path
+	Preconditions   []string                    `mapstructure:"preconditions"`    // Filter expressions the payload must satisfy
+}
+
+// ValueRangeConfig bounds a numeric payload field
+type ValueRangeConfig struct {
+	Min *float64 `mapstructure:"min"`
+	Max *float64 `mapstructure:"max"`
+}
+
+// DerivedMetricsConfig defines metrics computed from the numeric payload fields
+// agents publish, such as a zone average over several sensors
+type DerivedMetricsConfig struct {
+	Topics          []string              `mapstructure:"topics"`           // Event patterns whose payloads feed series (default ["#"])
+	HistorySize     int                   `mapstructure:"history_size"`     // Values kept per ingest metric (default 500)
+	LookbackSeconds int                   `mapstructure:"lookback_seconds"` // How far back query metrics look for series values (default 3600)
+	Metrics         []DerivedMetricConfig `mapstructure:"metrics"`          // Metrics may reference metrics defined before them
+}
+
+// DerivedMetricConfig defines one derived metric
+type DerivedMetricConfig struct {
+	Name        string `mapstructure:"name"`        // snake_case metric name
+	Expression  string `mapstructure:"expression"`  // e.g. "avg(SENSOR-001..003.pressure_bar)"
+	Mode        string `mapstructure:"mode"`        // "ingest" (default) or "query"
+	Unit        string `mapstructure:"unit"`        // Unit reported with values
+	Description string `mapstructure:"description"` // Human-readable description
+	EventName   string `mapstructure:"event_name"`  // Event published with ingest values (default metric.derived.<name>)
+}
+
+// TelemetryConfig configures storage of the time series posted to
+// /api/v1/telemetry
+type TelemetryConfig struct {
+	RawRetentionHours         int `mapstructure:"raw_retention_hours"`         // How long raw points are kept before they are downsampled (default 24)
+	DownsampleIntervalSeconds int `mapstructure:"downsample_interval_seconds"` // Width of the rollups raw points are downsampled into (default 300)
+	RetentionDays             int `mapstructure:"retention_days"`              // How long rollups are kept (default 90)
+	SweepIntervalSeconds      int `mapstructure:"sweep_interval_seconds"`      // How often retention is applied (default 300)
+	SweepBatchSize            int `mapstructure:"sweep_batch_size"`            // Points downsampled per batch (default 5000)
+	MaxPointsPerRequest       int `mapstructure:"max_points_per_request"`      // Most points one ingestion request may carry (default 1000)
+	MaxBuckets                int `mapstructure:"max_buckets"`                 // Most buckets one query may return (default 2000)
+}
+
+// IncidentsConfig configures how incidents are opened from alert publications.
+// Incidents can always be opened through /api/v1/incidents.
+type IncidentsConfig struct {
+	AutoOpen    bool     `mapstructure:"auto_open"`    // Open incidents from alert publications
+	MinSeverity string   `mapstructure:"min_severity"` // Least severe alert that opens an incident (default HIGH)
+	AlertTypes  []string `mapstructure:"alert_types"`  // Alert type patterns that open incidents, e.g. leak.*
+}
+
+// OutboxConfig configures delivery of side effects, such as chat messages and
+// webhooks, that are recorded together with the change producing them
+type OutboxConfig struct {
+	IntervalSeconds    int      `mapstructure:"interval_seconds"`     // How often pending entries are retried (default 10)
+	BatchSize          int      `mapstructure:"batch_size"`           // Entries retried per pass (default 50)
+	MaxAttempts        int      `mapstructure:"max_attempts"`         // Attempts before an entry fails (default 10)
+	BaseBackoffSeconds int      `mapstructure:"base_backoff_seconds"` // Delay after the first failure, doubling per attempt (default 5)
+	MaxBackoffSeconds  int      `mapstructure:"max_backoff_seconds"`  // Longest delay between attempts (default 900)
+	ChangeWebhooks     []string `mapstructure:"change_webhooks"`      // URLs notified of committed goal changes
+}
+
+// MaskingConfig masks sensitive payload fields for readers lacking a
+// permission. Masked fields are stored encrypted with the key.
+type MaskingConfig struct {
+	Key             string              `mapstructure:"key"`               // Base64 AES key of 16, 24 or 32 bytes
+	Mask            string              `mapstructure:"mask"`              // Replacement shown to readers (default "***")
+	MaxAuditEntries int                 `mapstructure:"max_audit_entries"` // Unmask operations kept in the audit log (default 1000)
+	Rules           []MaskingRuleConfig `mapstructure:"rules"`
+}
+
+// MaskingRuleConfig masks payload fields of matching topics and message types
+type MaskingRuleConfig struct {
+	Topics     []string `mapstructure:"topics"`     // Glob patterns of event names and message types
+	Fields     []string `mapstructure:"fields"`     // Dotted payload paths
+	Permission string   `mapstructure:"permission"` // Permission revealing the fields (default "payload.unmask")
+}
+
+// TopologyConfig configures how the distribution network is built from agent
+// metadata for isolation impact analysis
+type TopologyConfig struct {
+	SourceTypes            []string `mapstructure:"source_types"`             // Agent types that feed the network (default pump, reservoir, treatment_plant)
+	PerCapitaDemandLPD     float64  `mapstructure:"per_capita_demand_lpd"`    // Litres per person per day when a pipe has no demand_m3_per_hour (default 100)
+	MaxIsolationCandidates int      `mapstructure:"max_isolation_candidates"` // Isolation sets proposed per pipe (default 5)
+	MaxTraversalDepth      int      `mapstructure:"max_traversal_depth"`      // Connections followed by asset graph queries (default 50)
+	SyncIntervalSeconds    int      `mapstructure:"sync_interval_seconds"`    // How often the asset graph is synced from the network (default 60)
+}
+
+// JobsConfig configures the background job queue. Type options default to
+// the queue-wide values.
+type JobsConfig struct {
+	PollIntervalSeconds int                      `mapstructure:"poll_interval_seconds"` // How often due jobs are claimed (default 2)
+	Concurrency         int                      `mapstructure:"concurrency"`           // Jobs of one type run at once per process (default 1)
+	MaxAttempts         int                      `mapstructure:"max_attempts"`          // Attempts before a job is dead-lettered (default 5)
+	TimeoutSeconds      int                      `mapstructure:"timeout_seconds"`       // Longest single attempt (default 600)
+	BaseBackoffSeconds  int                      `mapstructure:"base_backoff_seconds"`  // Delay after the first failure, doubling per attempt (default 10)
+	MaxBackoffSeconds   int                      `mapstructure:"max_backoff_seconds"`   // Longest delay between attempts (default 3600)
+	RetentionHours      int                      `mapstructure:"retention_hours"`       // Succeeded and canceled jobs are purged after this (0 keeps them)
+	Types               map[string]JobTypeConfig `mapstructure:"types"`                 // Overrides by job type
+}
+
+// JobTypeConfig overrides queue options for one job type
+type JobTypeConfig struct {
+	Concurrency    int `mapstructure:"concurrency"`
+	MaxAttempts    int `mapstructure:"max_attempts"`
+	TimeoutSeconds int `mapstructure:"timeout_seconds"`
+}
+
+// OrchestrationConfig configures the workflow engine's task workers and how
+// fast tasks are assigned to agents of each type
+type OrchestrationConfig struct {
+	WorkerCount                int            `mapstructure:"worker_count"`                  // Tasks run at once (default 10)
+	TaskQueueSize              int            `mapstructure:"task_queue_size"`               // Tasks waiting for a worker (default 1000)
+	CompletionQueueSize        int            `mapstructure:"completion_queue_size"`         // Finished tasks waiting to be recorded (default 1000)
+	QueueFullPolicy            string         `mapstructure:"queue_full_policy"`             // "block" waits for room, "reject" fails the task (default block)
+	AssignmentRateLimits       map[string]int `mapstructure:"assignment_rate_limits"`        // Task assignments per minute by agent type
+	DefaultAssignmentRateLimit int            `mapstructure:"default_assignment_rate_limit"` // Assignments per minute of other agent types (0 is unlimited)
+}
+
+// AgencyValidationConfig configures strict validation of goals, work items
+// and agency activation. Limits of 0 are not enforced.
+type AgencyValidationConfig struct {
+	Strict                bool     `mapstructure:"strict"`                  // Enforce the checks below
+	MaxGoalsPerAgency     int      `mapstructure:"max_goals_per_agency"`    // Goals an agency may have
+	MaxWorkItemsPerGoal   int      `mapstructure:"max_work_items_per_goal"` // Work items that may reference one goal's code
+	MinDescriptionLength  int      `mapstructure:"min_description_length"`  // Characters in goal and work item descriptions
+	MaxDescriptionLength  int      `mapstructure:"max_description_length"`
+	RequiredForActivation []string `mapstructure:"required_for_activation"` // description, icon, location, tags, overview, goals, work_items
+	DuplicateSimilarity   float64  `mapstructure:"duplicate_similarity"`    // Word overlap (0-1) flagging duplicate descriptions
+	AIDuplicateCheck      bool     `mapstructure:"ai_duplicate_check"`      // Ask the LLM for paraphrased duplicates
+}
+
+// ChangeFeedConfig configures the per-agency stream of design changes
+type ChangeFeedConfig struct {
+	CoalesceMillis   int `mapstructure:"coalesce_millis"`   // Changes to one entity within this window are sent once (default 250, -1 disables)
+	HistorySize      int `mapstructure:"history_size"`      // Changes kept per agency for reconnecting clients (default 500)
+	SubscriberBuffer int `mapstructure:"subscriber_buffer"` // Changes queued for a slow client before it must resync (default 64)
+	HeartbeatSeconds int `mapstructure:"heartbeat_seconds"` // Keep-alive interval of idle streams (default 15)
+}
+
+// BackfillConfig configures backfills of historical traffic
+type BackfillConfig struct {
+	ArchiveDir string `mapstructure:"archive_dir"` // Directory of traffic capture files backfills may read (archive backfills are off when empty)
+}
+
+// PublicationExpiryConfig configures the sweeper removing expired publications
+type PublicationExpiryConfig struct {
+	Disabled        bool `mapstructure:"disabled"`         // Keep expired publications in storage
+	IntervalSeconds int  `mapstructure:"interval_seconds"` // How often expired publications are removed (default 60)
+	BatchSize       int  `mapstructure:"batch_size"`       // Publications removed per delete (default 1000)
+}
+
+// MemoryCacheConfig configures the working memory cache
+type MemoryCacheConfig struct {
+	Enabled    bool `mapstructure:"enabled"`     // Serve working memory reads from an in-process cache
+	MaxEntries int  `mapstructure:"max_entries"` // Entries kept before the least recently used is dropped (default 10000)
+	TTLSeconds int  `mapstructure:"ttl_seconds"` // Longest time an entry is served from the cache (default 300)
+}
+
+// MemoryEncryptionConfig configures AES-GCM encryption of memory values at rest
+type MemoryEncryptionConfig struct {
+	Key          string   `mapstructure:"key"`           // Base64 AES key of 16, 24 or 32 bytes; empty stores values in plaintext
+	PreviousKeys []string `mapstructure:"previous_keys"` // Base64 keys of values stored before a key rotation
+}
+
+// MemoryQuotaConfig limits agents' working memory. Storing an entry that takes
+// an agent over its quota evicts its least recently accessed entries. Zero
+// limits are unlimited.
+type MemoryQuotaConfig struct {
+	MaxEntries int                      `mapstructure:"max_entries"` // Entries per agent without its own quota
+	MaxBytes   int64                    `mapstructure:"max_bytes"`   // Total JSON value size per agent without its own quota
+	Agents     []AgentMemoryQuotaConfig `mapstructure:"agents"`      // Quotas of individual agents
+}
+
+// AgentMemoryQuotaConfig is the working memory quota of one agent
+type AgentMemoryQuotaConfig struct {
+	AgentID    string `mapstructure:"agent_id"`
+	MaxEntries int    `mapstructure:"max_entries"`
+	MaxBytes   int64  `mapstructure:"max_bytes"`
+}
+
+// MemorySnapshotsConfig configures how agent memory snapshots are stored and
+// periodic snapshots of agent memory. No periodic snapshots are taken without
+// policies.
+type MemorySnapshotsConfig struct {
+	CompressionThresholdBytes int                                   `mapstructure:"compression_threshold_bytes"` // State size above which snapshots are stored gzip-compressed (default 65536; negative disables)
+	CheckIntervalSeconds      int                                   `mapstructure:"check_interval_seconds"`      // How often agents are checked for a due snapshot (default 60)
+	Policies                  map[string]MemorySnapshotPolicyConfig `mapstructure:"policies"`                    // By agent type; "*" applies to types without their own
+}
+
+// MemorySnapshotPolicyConfig configures periodic snapshots of one agent type
+type MemorySnapshotPolicyConfig struct {
+	IntervalSeconds int `mapstructure:"interval_seconds"` // Time between snapshots of an agent
+	KeepLast        int `mapstructure:"keep_last"`        // Most recent periodic snapshots kept (default 5)
+	KeepDailyDays   int `mapstructure:"keep_daily_days"`  // Also keep the newest snapshot of each of the last days
+}
+
+// AuthConfig configures API key authentication of the /api endpoints
+type AuthConfig struct {
+	Enabled  bool            `mapstructure:"enabled"`   // Require an API key on /api endpoints
+	AdminKey string          `mapstructure:"admin_key"` // Designer-admin key for bootstrapping key management
+	Keys     []AuthKeyConfig `mapstructure:"keys"`      // Further keys defined in configuration
+}
+
+// AuditConfig configures the audit log of mutating API operations
+type AuditConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Record every POST, PUT, PATCH and DELETE to /api endpoints
+}
+
+// RateLimitConfig configures the rate limits of API clients. Clients are
+// identified by API key, or by address when they do not present one.
+type RateLimitConfig struct {
+	Enabled           bool                      `mapstructure:"enabled"`             // Throttle /api and /api/web requests
+	RequestsPerSecond float64                   `mapstructure:"requests_per_second"` // Rate each client may sustain (default 20)
+	Burst             int                       `mapstructure:"burst"`               // Requests a client may make at once (default twice the rate)
+	Endpoints         []EndpointRateLimitConfig `mapstructure:"endpoints"`           // Tighter limits of some endpoints
+}
+
+// EndpointRateLimitConfig limits the requests to some endpoints, in addition
+// to the limit of the whole API
+type EndpointRateLimitConfig struct {
+	Name              string  `mapstructure:"name"`                // Labels throttled requests in metrics
+	Method            string  `mapstructure:"method"`              // HTTP method (any when empty)
+	Path              string  `mapstructure:"path"`                // Route without the /api/v<N> prefix, e.g. /agents/:id/tasks; covers the routes below it
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"` // Rate each client may sustain
+	Burst             int     `mapstructure:"burst"`               // Requests a client may make at once
+}
+
+// AuthKeyConfig is an API key defined in configuration. Such keys are not
+// stored and cannot be revoked through the API.
+type AuthKeyConfig struct {
+	Name        string   `mapstructure:"name"`        // Identifies the key in logs and listings
+	Key         string   `mapstructure:"key"`         // The secret
+	Scopes      []string `mapstructure:"scopes"`      // read, communications, unmask, guardrail-override and/or designer-admin
+	Tenant      string   `mapstructure:"tenant"`      // Scopes the key to a tenant (operator key when empty)
+	Permissions []string `mapstructure:"permissions"` // Reveal masked payload fields (see masking.rules)
+}
+
+// MCPConfig configures the Model Context Protocol server
+type MCPConfig struct {
+	Enabled bool             `mapstructure:"enabled"` // Serve MCP over HTTP
+	Path    string           `mapstructure:"path"`    // Endpoint path (default /mcp)
+	Tokens  []MCPTokenConfig `mapstructure:"tokens"`  // Bearer tokens clients authenticate with
+}
+
+// MCPTokenConfig is a bearer token accepted by the MCP server
+type MCPTokenConfig struct {
+	Name     string   `mapstructure:"name"`     // Identifies the client in logs
+	Token    string   `mapstructure:"token"`    // The bearer token
+	Write    bool     `mapstructure:"write"`    // Allows creating goals and work items
+	Agencies []string `mapstructure:"agencies"` // Agencies the token may access (all when empty)
+	Tenant   string   `mapstructure:"tenant"`   // Scopes the token to a tenant (operator token when empty)
 }
 
 // Load loads configuration from file and environment variables
@@ -154,6 +709,16 @@ func Load(configPath string) (*Config, error) {
 	viper.BindEnv("database.database", "CVXC_DATABASE_DATABASE")
 	viper.BindEnv("database.username", "CVXC_DATABASE_USERNAME")
 	viper.BindEnv("database.password", "CVXC_DATABASE_PASSWORD")
+	viper.BindEnv("database.read_replica.host", "CVXC_DATABASE_READ_REPLICA_HOST")
+	viper.BindEnv("database.read_replica.port", "CVXC_DATABASE_READ_REPLICA_PORT")
+	viper.BindEnv("database.read_replica.username", "CVXC_DATABASE_READ_REPLICA_USERNAME")
+	viper.BindEnv("database.read_replica.password", "CVXC_DATABASE_READ_REPLICA_PASSWORD")
+	viper.BindEnv("masking.key", "CVXC_MASKING_KEY")
+	viper.BindEnv("memory_encryption.key", "CVXC_MEMORY_ENCRYPTION_KEY")
+	viper.BindEnv("auth.enabled", "CVXC_AUTH_ENABLED")
+	viper.BindEnv("auth.admin_key", "CVXC_AUTH_ADMIN_KEY")
+	viper.BindEnv("audit.enabled", "CVXC_AUDIT_ENABLED")
+	viper.BindEnv("rate_limit.enabled", "CVXC_RATE_LIMIT_ENABLED")
 
 	// AI configuration bindings
 	viper.BindEnv("ai.provider", "CVXC_AI_PROVIDER")
@@ -15,6 +15,7 @@ type ArangoClient struct {
 	client   driver.Client
 	db       driver.Database
 	config   *config.DatabaseConfig
+	router   *QueryRouter
 	ctx      context.Context
 	cancelFn context.CancelFunc
 }
@@ -24,28 +25,10 @@ func NewArangoClient(cfg *config.DatabaseConfig) (*ArangoClient, error) {
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Create HTTP connection
-	connConfig := http.ConnectionConfig{
-		Endpoints: []string{fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port)},
-	}
-
-	conn, err := http.NewConnection(connConfig)
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create connection: %w", err)
-	}
-
-	// Create client configuration
-	clientConfig := driver.ClientConfig{
-		Connection:     conn,
-		Authentication: driver.BasicAuthentication(cfg.Username, cfg.Password),
-	}
-
-	// Create client
-	client, err := driver.NewClient(clientConfig)
+	client, err := newClient(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to create client: %w", err)
+		return nil, err
 	}
 
 	// Get or create database
@@ -61,15 +44,82 @@ func NewArangoClient(cfg *config.DatabaseConfig) (*ArangoClient, error) {
 		"database": cfg.Database,
 	}).Info("Connected to ArangoDB")
 
+	router := NewQueryRouter(db, connectReplica(ctx, cfg), cfg)
+	if err := router.Start(ctx); err != nil {
+		log.WithError(err).Warn("Failed to start read replica monitoring, analytical queries will use the primary")
+	}
+
 	return &ArangoClient{
 		client:   client,
 		db:       db,
 		config:   cfg,
+		router:   router,
 		ctx:      ctx,
 		cancelFn: cancel,
 	}, nil
 }
 
+// newClient creates an HTTP client for one ArangoDB endpoint
+func newClient(host string, port int, username, password string) (driver.Client, error) {
+	// Create HTTP connection
+	connConfig := http.ConnectionConfig{
+		Endpoints: []string{fmt.Sprintf("http://%s:%d", host, port)},
+	}
+
+	conn, err := http.NewConnection(connConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection: %w", err)
+	}
+
+	// Create client configuration
+	clientConfig := driver.ClientConfig{
+		Connection:     conn,
+		Authentication: driver.BasicAuthentication(username, password),
+	}
+
+	// Create client
+	client, err := driver.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return client, nil
+}
+
+// connectReplica opens the configured read replica. It returns nil when no
+// replica is configured or it cannot be opened, leaving every query on the primary.
+func connectReplica(ctx context.Context, cfg *config.DatabaseConfig) driver.Database {
+	replica := cfg.ReadReplica
+	if replica.Host == "" {
+		return nil
+	}
+
+	port := replica.Port
+	if port == 0 {
+		port = cfg.Port
+	}
+	username, password := replica.Username, replica.Password
+	if username == "" {
+		username, password = cfg.Username, cfg.Password
+	}
+
+	client, err := newClient(replica.Host, port, username, password)
+	if err == nil {
+		var db driver.Database
+		db, err = client.Database(driver.WithAllowDirtyReads(ctx, nil), cfg.Database)
+		if err == nil {
+			log.WithFields(log.Fields{
+				"host":     replica.Host,
+				"port":     port,
+				"database": cfg.Database,
+			}).Info("Connected to ArangoDB read replica")
+			return db
+		}
+	}
+
+	log.WithError(err).WithField("host", replica.Host).Warn("Failed to connect to read replica, analytical queries will use the primary")
+	return nil
+}
+
 // ensureDatabase creates the database if it doesn't exist
 func ensureDatabase(ctx context.Context, client driver.Client, dbName string) (driver.Database, error) {
 	// Check if database exists
@@ -128,6 +178,11 @@ func (ac *ArangoClient) GetDatabase(ctx context.Context, dbName string) (driver.
 	return db, nil
 }
 
+// Router returns the query router for heavy analytical reads
+func (ac *ArangoClient) Router() *QueryRouter {
+	return ac.router
+}
+
 // Client returns the client instance
 func (ac *ArangoClient) Client() driver.Client {
 	return ac.client
@@ -140,6 +195,9 @@ func (ac *ArangoClient) Context() context.Context {
 
 // Close closes the client connection
 func (ac *ArangoClient) Close() error {
+	if ac.router != nil {
+		ac.router.Stop()
+	}
 	if ac.cancelFn != nil {
 		ac.cancelFn()
 	}
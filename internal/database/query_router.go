@@ -0,0 +1,354 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionReplicationHeartbeat holds the heartbeat used to measure replica staleness
+	CollectionReplicationHeartbeat = "db_replication_heartbeat"
+
+	heartbeatKey = "heartbeat"
+
+	defaultHeartbeatInterval = 5 * time.Second
+	defaultMaxStaleness      = 30 * time.Second
+)
+
+type queryRouteKey struct{}
+
+// WithQueryRoute marks reads made with ctx as part of a designated analytical
+// route. The route name selects the route's staleness configuration.
+func WithQueryRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, queryRouteKey{}, route)
+}
+
+// QueryRouteFrom returns the query route set on ctx, if any
+func QueryRouteFrom(ctx context.Context) string {
+	route, _ := ctx.Value(queryRouteKey{}).(string)
+	return route
+}
+
+// QueryRouter sends designated read-only queries to a read replica while the
+// replica is reachable and fresh enough for the route, and to the primary
+// otherwise. Without a replica every query goes to the primary.
+//
+// Staleness is measured by writing a heartbeat to the primary and reading it
+// back from the replica, so it is only as precise as the heartbeat interval.
+type QueryRouter struct {
+	primary           driver.Database
+	replica           driver.Database
+	routes            map[string]config.QueryRouteConfig
+	defaultStaleness  time.Duration
+	heartbeatInterval time.Duration
+
+	mu        sync.RWMutex
+	healthy   bool
+	staleness time.Duration
+	lastCheck time.Time
+	lastError string
+	stats     map[string]*routeStats
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type routeStats struct {
+	replica   int64
+	primary   int64
+	fallbacks int64
+}
+
+// RouteStatus reports how one query route is being served
+type RouteStatus struct {
+	PrimaryOnly         bool    `json:"primary_only"`
+	MaxStalenessSeconds float64 `json:"max_staleness_seconds"`
+	UsesReplica         bool    `json:"uses_replica"`
+	ReplicaQueries      int64   `json:"replica_queries"`
+	PrimaryQueries      int64   `json:"primary_queries"`
+	Fallbacks           int64   `json:"fallbacks"`
+}
+
+// RoutingStatus reports the replica's state and per-route query counts
+type RoutingStatus struct {
+	ReplicaConfigured bool                   `json:"replica_configured"`
+	ReplicaHealthy    bool                   `json:"replica_healthy"`
+	StalenessSeconds  float64                `json:"staleness_seconds"`
+	LastCheck         time.Time              `json:"last_check,omitempty"`
+	LastError         string                 `json:"last_error,omitempty"`
+	Routes            map[string]RouteStatus `json:"routes"`
+}
+
+// NewQueryRouter creates a router over the primary and an optional replica
+func NewQueryRouter(primary, replica driver.Database, cfg *config.DatabaseConfig) *QueryRouter {
+	r := &QueryRouter{
+		primary:           primary,
+		replica:           replica,
+		routes:            cfg.QueryRoutes,
+		defaultStaleness:  defaultMaxStaleness,
+		heartbeatInterval: defaultHeartbeatInterval,
+		stats:             make(map[string]*routeStats),
+	}
+	if cfg.ReadReplica.DefaultMaxStalenessSeconds > 0 {
+		r.defaultStaleness = time.Duration(cfg.ReadReplica.DefaultMaxStalenessSeconds) * time.Second
+	}
+	if cfg.ReadReplica.HeartbeatIntervalSeconds > 0 {
+		r.heartbeatInterval = time.Duration(cfg.ReadReplica.HeartbeatIntervalSeconds) * time.Second
+	}
+	return r
+}
+
+// Query runs a read-only query. Queries whose context carries a route set by
+// WithQueryRoute may be served by the replica; replica failures fall back to
+// the primary. All other queries go to the primary.
+func (r *QueryRouter) Query(ctx context.Context, query string, bindVars map[string]interface{}) (driver.Cursor, error) {
+	route := QueryRouteFrom(ctx)
+	if route == "" {
+		return r.primary.Query(ctx, query, bindVars)
+	}
+
+	if r.useReplica(route) {
+		cursor, err := r.replica.Query(driver.WithAllowDirtyReads(ctx, nil), query, bindVars)
+		if err == nil {
+			r.record(route, func(s *routeStats) { s.replica++ })
+			return cursor, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		log.WithError(err).WithField("route", route).Warn("Replica query failed, falling back to primary")
+		r.setHealth(false, r.currentStaleness(), err)
+		r.record(route, func(s *routeStats) { s.fallbacks++ })
+	}
+
+	r.record(route, func(s *routeStats) { s.primary++ })
+	return r.primary.Query(ctx, query, bindVars)
+}
+
+// useReplica reports whether the route may currently be served by the replica
+func (r *QueryRouter) useReplica(route string) bool {
+	if r.replica == nil {
+		return false
+	}
+	primaryOnly, maxStaleness := r.routeConfig(route)
+	if primaryOnly {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy && r.staleness <= maxStaleness
+}
+
+func (r *QueryRouter) routeConfig(route string) (bool, time.Duration) {
+	cfg, ok := r.routes[route]
+	if !ok {
+		return false, r.defaultStaleness
+	}
+	if cfg.MaxStalenessSeconds > 0 {
+		return cfg.PrimaryOnly, time.Duration(cfg.MaxStalenessSeconds) * time.Second
+	}
+	return cfg.PrimaryOnly, r.defaultStaleness
+}
+
+func (r *QueryRouter) record(route string, update func(*routeStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, ok := r.stats[route]
+	if !ok {
+		stats = &routeStats{}
+		r.stats[route] = stats
+	}
+	update(stats)
+}
+
+func (r *QueryRouter) currentStaleness() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.staleness
+}
+
+func (r *QueryRouter) setHealth(healthy bool, staleness time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.healthy = healthy
+	r.staleness = staleness
+	r.lastCheck = time.Now()
+	r.lastError = ""
+	if err != nil {
+		r.lastError = err.Error()
+	}
+}
+
+// Start measures replica staleness on the heartbeat interval until Stop is called
+func (r *QueryRouter) Start(ctx context.Context) error {
+	if r.replica == nil {
+		return nil
+	}
+
+	if err := r.ensureHeartbeatCollection(ctx); err != nil {
+		return err
+	}
+	r.checkReplica(ctx)
+
+	r.stopCh = make(chan struct{})
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.checkReplica(ctx)
+			case <-r.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.WithFields(log.Fields{
+		"heartbeat_interval": r.heartbeatInterval,
+		"max_staleness":      r.defaultStaleness,
+	}).Info("Started read replica monitoring")
+	return nil
+}
+
+// Stop ends replica monitoring
+func (r *QueryRouter) Stop() {
+	if r.stopCh == nil {
+		return
+	}
+	close(r.stopCh)
+	r.wg.Wait()
+	r.stopCh = nil
+}
+
+func (r *QueryRouter) ensureHeartbeatCollection(ctx context.Context) error {
+	exists, err := r.primary.CollectionExists(ctx, CollectionReplicationHeartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	if _, err := r.primary.CreateCollection(ctx, CollectionReplicationHeartbeat, nil); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	log.WithField("collection", CollectionReplicationHeartbeat).Info("Created new collection")
+	return nil
+}
+
+// checkReplica reads the last replicated heartbeat from the replica, then
+// writes a new one to the primary
+func (r *QueryRouter) checkReplica(ctx context.Context) {
+	staleness, err := r.replicaStaleness(ctx)
+	if err != nil {
+		r.setHealth(false, 0, err)
+	} else {
+		r.setHealth(true, staleness, nil)
+	}
+
+	if err := r.writeHeartbeat(ctx); err != nil {
+		log.WithError(err).Warn("Failed to write replication heartbeat")
+	}
+}
+
+func (r *QueryRouter) replicaStaleness(ctx context.Context) (time.Duration, error) {
+	query := `RETURN DOCUMENT(@@collection, @key).written_at`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionReplicationHeartbeat,
+		"key":         heartbeatKey,
+	}
+
+	cursor, err := r.replica.Query(driver.WithAllowDirtyReads(ctx, nil), query, bindVars)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replication heartbeat: %w", err)
+	}
+	defer cursor.Close()
+
+	var writtenAt *time.Time
+	if _, err := cursor.ReadDocument(ctx, &writtenAt); err != nil {
+		return 0, fmt.Errorf("failed to read replication heartbeat: %w", err)
+	}
+	if writtenAt == nil {
+		return 0, errors.New("replication heartbeat has not reached the replica yet")
+	}
+
+	staleness := time.Since(*writtenAt)
+	if staleness < 0 {
+		staleness = 0
+	}
+	return staleness, nil
+}
+
+func (r *QueryRouter) writeHeartbeat(ctx context.Context) error {
+	query := `
+		UPSERT { _key: @key }
+			INSERT { _key: @key, written_at: @now }
+			UPDATE { written_at: @now }
+			IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionReplicationHeartbeat,
+		"key":         heartbeatKey,
+		"now":         time.Now().UTC(),
+	}
+
+	cursor, err := r.primary.Query(ctx, query, bindVars)
+	if err != nil {
+		return err
+	}
+	return cursor.Close()
+}
+
+// Status reports the replica's state and how each route has been served
+func (r *QueryRouter) Status() RoutingStatus {
+	r.mu.RLock()
+	status := RoutingStatus{
+		ReplicaConfigured: r.replica != nil,
+		ReplicaHealthy:    r.replica != nil && r.healthy,
+		StalenessSeconds:  r.staleness.Seconds(),
+		LastCheck:         r.lastCheck,
+		LastError:         r.lastError,
+		Routes:            make(map[string]RouteStatus),
+	}
+	names := make(map[string]routeStats, len(r.stats)+len(r.routes))
+	for name, stats := range r.stats {
+		names[name] = *stats
+	}
+	r.mu.RUnlock()
+
+	for name := range r.routes {
+		if _, ok := names[name]; !ok {
+			names[name] = routeStats{}
+		}
+	}
+
+	for name, stats := range names {
+		primaryOnly, maxStaleness := r.routeConfig(name)
+		status.Routes[name] = RouteStatus{
+			PrimaryOnly:         primaryOnly,
+			MaxStalenessSeconds: maxStaleness.Seconds(),
+			UsesReplica:         r.useReplica(name),
+			ReplicaQueries:      stats.replica,
+			PrimaryQueries:      stats.primary,
+			Fallbacks:           stats.fallbacks,
+		}
+	}
+	return status
+}
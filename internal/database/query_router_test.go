@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	driver "github.com/arangodb/go-driver"
+)
+
+// fakeDatabase counts queries and optionally fails them
+type fakeDatabase struct {
+	driver.Database
+	queries int
+	err     error
+}
+
+func (f *fakeDatabase) Query(ctx context.Context, query string, bindVars map[string]interface{}) (driver.Cursor, error) {
+	f.queries++
+	return nil, f.err
+}
+
+func newTestRouter(primary, replica *fakeDatabase) *QueryRouter {
+	return NewQueryRouter(primary, replica, &config.DatabaseConfig{
+		QueryRoutes: map[string]config.QueryRouteConfig{
+			"strict":  {MaxStalenessSeconds: 5},
+			"primary": {PrimaryOnly: true},
+		},
+	})
+}
+
+func TestQueryRouter_RoutesByStaleness(t *testing.T) {
+	primary, replica := &fakeDatabase{}, &fakeDatabase{}
+	router := newTestRouter(primary, replica)
+	router.setHealth(true, 10*time.Second, nil)
+
+	ctx := context.Background()
+	router.Query(WithQueryRoute(ctx, "relaxed"), "RETURN 1", nil)
+	if replica.queries != 1 {
+		t.Errorf("Expected route within default staleness to use the replica, got %d replica queries", replica.queries)
+	}
+
+	router.Query(WithQueryRoute(ctx, "strict"), "RETURN 1", nil)
+	router.Query(WithQueryRoute(ctx, "primary"), "RETURN 1", nil)
+	router.Query(ctx, "RETURN 1", nil)
+	if primary.queries != 3 || replica.queries != 1 {
+		t.Errorf("Expected stale, primary-only and unrouted queries on the primary, got primary=%d replica=%d", primary.queries, replica.queries)
+	}
+
+	status := router.Status()
+	if !status.Routes["relaxed"].UsesReplica || status.Routes["strict"].UsesReplica {
+		t.Errorf("Unexpected route status: %+v", status.Routes)
+	}
+	if status.Routes["strict"].PrimaryQueries != 1 || status.Routes["relaxed"].ReplicaQueries != 1 {
+		t.Errorf("Unexpected route counts: %+v", status.Routes)
+	}
+}
+
+func TestQueryRouter_FallsBackToPrimary(t *testing.T) {
+	primary, replica := &fakeDatabase{}, &fakeDatabase{err: errors.New("connection refused")}
+	router := newTestRouter(primary, replica)
+	router.setHealth(true, 0, nil)
+
+	ctx := WithQueryRoute(context.Background(), "relaxed")
+	if _, err := router.Query(ctx, "RETURN 1", nil); err != nil {
+		t.Fatalf("Expected fallback to succeed, got %v", err)
+	}
+	if primary.queries != 1 {
+		t.Fatalf("Expected the failed replica query to be retried on the primary")
+	}
+
+	// The replica is skipped until a heartbeat check succeeds again
+	router.Query(ctx, "RETURN 1", nil)
+	if replica.queries != 1 || primary.queries != 2 {
+		t.Errorf("Expected unhealthy replica to be skipped, got primary=%d replica=%d", primary.queries, replica.queries)
+	}
+
+	status := router.Status()
+	if status.ReplicaHealthy || status.LastError == "" || status.Routes["relaxed"].Fallbacks != 1 {
+		t.Errorf("Unexpected status after fallback: %+v", status)
+	}
+}
+
+func TestQueryRouter_NoReplica(t *testing.T) {
+	primary := &fakeDatabase{}
+	router := NewQueryRouter(primary, nil, &config.DatabaseConfig{})
+	router.setHealth(true, 0, nil)
+
+	router.Query(WithQueryRoute(context.Background(), "relaxed"), "RETURN 1", nil)
+	if primary.queries != 1 {
+		t.Errorf("Expected every query on the primary without a replica")
+	}
+	if router.Status().ReplicaConfigured {
+		t.Error("Expected replica to be reported as not configured")
+	}
+}
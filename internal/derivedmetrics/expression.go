@@ -0,0 +1,574 @@
+package derivedmetrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrNoData is returned when an expression references series or metrics that
+// have no value yet
+var ErrNoData = errors.New("no data")
+
+// maxRangeSize bounds the number of series an "AGENT-001..500.field" range expands to
+const maxRangeSize = 1000
+
+// SeriesRef identifies a numeric payload field published by one agent
+type SeriesRef struct {
+	AgentID string `json:"agent_id"`
+
+	// Field is the payload field, using dot notation for nested values
+	Field string `json:"field"`
+}
+
+// String returns the reference as "agent.field"
+func (r SeriesRef) String() string {
+	return r.AgentID + "." + r.Field
+}
+
+// Resolver supplies the values an expression is evaluated against
+type Resolver interface {
+	// Series returns the latest value of a series, if any
+	Series(ref SeriesRef) (float64, bool)
+
+	// Metric evaluates another derived metric
+	Metric(name string) (float64, error)
+}
+
+// Expression is a parsed derived metric formula such as
+// "avg(SENSOR-001..003.pressure_bar)" or "current - baseline".
+//
+// Operands are numbers, series references ("AGENT.field"), series ranges
+// ("SENSOR-001..003.field" expands to SENSOR-001, SENSOR-002 and SENSOR-003)
+// and names of other derived metrics. They combine with + - * / and
+// parentheses, and the aggregates avg, sum, min, max and count, which skip
+// series without data. abs takes a single argument. Since agent IDs may
+// contain hyphens, subtraction next to a series reference needs spaces.
+type Expression struct {
+	source  string
+	root    node
+	series  []SeriesRef
+	metrics []string
+}
+
+// Parse parses a derived metric expression
+func Parse(expr string) (*Expression, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, seenSeries: make(map[SeriesRef]bool), seenMetrics: make(map[string]bool)}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+	}
+	if err := requireScalar(root); err != nil {
+		return nil, err
+	}
+
+	return &Expression{
+		source:  expr,
+		root:    root,
+		series:  p.series,
+		metrics: p.metrics,
+	}, nil
+}
+
+// String returns the original expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Series returns the distinct series the expression reads, with ranges expanded
+func (e *Expression) Series() []SeriesRef {
+	return append([]SeriesRef{}, e.series...)
+}
+
+// Metrics returns the names of the derived metrics the expression references
+func (e *Expression) Metrics() []string {
+	return append([]string{}, e.metrics...)
+}
+
+// Evaluate computes the expression. It returns an error wrapping ErrNoData
+// when a referenced series or metric has no value.
+func (e *Expression) Evaluate(r Resolver) (float64, error) {
+	values, err := e.root.values(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("%w: none of the series in %q have values", ErrNoData, e.source)
+	}
+	return values[0], nil
+}
+
+// node is an element of the expression tree. Series ranges evaluate to one
+// value per series with data; every other node evaluates to a single value.
+type node interface {
+	values(r Resolver) ([]float64, error)
+}
+
+type numberNode float64
+
+func (n numberNode) values(r Resolver) ([]float64, error) {
+	return []float64{float64(n)}, nil
+}
+
+type seriesNode struct {
+	source string
+	refs   []SeriesRef
+}
+
+func (n *seriesNode) values(r Resolver) ([]float64, error) {
+	var values []float64
+	for _, ref := range n.refs {
+		if v, ok := r.Series(ref); ok {
+			values = append(values, v)
+		}
+	}
+	if len(n.refs) == 1 && len(values) == 0 {
+		return nil, fmt.Errorf("%w: series %s", ErrNoData, n.source)
+	}
+	return values, nil
+}
+
+type metricNode string
+
+func (n metricNode) values(r Resolver) ([]float64, error) {
+	v, err := r.Metric(string(n))
+	if err != nil {
+		return nil, err
+	}
+	return []float64{v}, nil
+}
+
+type unaryNode struct {
+	operand node
+}
+
+func (n *unaryNode) values(r Resolver) ([]float64, error) {
+	v, err := scalar(n.operand, r)
+	if err != nil {
+		return nil, err
+	}
+	return []float64{-v}, nil
+}
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n *binaryNode) values(r Resolver) ([]float64, error) {
+	left, err := scalar(n.left, r)
+	if err != nil {
+		return nil, err
+	}
+	right, err := scalar(n.right, r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case '+':
+		return []float64{left + right}, nil
+	case '-':
+		return []float64{left - right}, nil
+	case '*':
+		return []float64{left * right}, nil
+	default:
+		if right == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return []float64{left / right}, nil
+	}
+}
+
+type callNode struct {
+	fn   string
+	args []node
+}
+
+// functions are the supported function names
+var functions = map[string]bool{
+	"avg":   true,
+	"sum":   true,
+	"min":   true,
+	"max":   true,
+	"count": true,
+	"abs":   true,
+}
+
+func (n *callNode) values(r Resolver) ([]float64, error) {
+	if n.fn == "abs" {
+		v, err := scalar(n.args[0], r)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{math.Abs(v)}, nil
+	}
+
+	// Aggregates skip arguments without data
+	var inputs []float64
+	for _, arg := range n.args {
+		values, err := arg.values(r)
+		if errors.Is(err, ErrNoData) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, values...)
+	}
+
+	if n.fn == "count" {
+		return []float64{float64(len(inputs))}, nil
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: no inputs to %s()", ErrNoData, n.fn)
+	}
+
+	result := inputs[0]
+	for _, v := range inputs[1:] {
+		switch n.fn {
+		case "sum", "avg":
+			result += v
+		case "min":
+			result = math.Min(result, v)
+		case "max":
+			result = math.Max(result, v)
+		}
+	}
+	if n.fn == "avg" {
+		result /= float64(len(inputs))
+	}
+	return []float64{result}, nil
+}
+
+// scalar evaluates a node that must produce exactly one value
+func scalar(n node, r Resolver) (float64, error) {
+	values, err := n.values(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		if s, ok := n.(*seriesNode); ok {
+			return 0, fmt.Errorf("%w: series %s", ErrNoData, s.source)
+		}
+		return 0, ErrNoData
+	}
+	return values[0], nil
+}
+
+// token is a lexical element of an expression
+type token struct {
+	kind byte // 'n' number, 'i' identifier, or the operator/punctuation character
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into numbers, identifiers and operators.
+// Identifiers may contain dots and hyphens so that series references such as
+// "SENSOR-001..003.pressure_bar" form a single token.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/(),", c):
+			tokens = append(tokens, token{kind: byte(c), text: string(c), pos: i})
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: 'n', text: expr[start:i], pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) && isIdentChar(rune(expr[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: 'i', text: expr[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '-'
+}
+
+// parser is a recursive descent parser over expression tokens
+type parser struct {
+	tokens      []token
+	pos         int
+	series      []SeriesRef
+	metrics     []string
+	seenSeries  map[SeriesRef]bool
+	seenMetrics map[string]bool
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) expect(kind byte) error {
+	t, ok := p.peek()
+	if !ok {
+		return fmt.Errorf("expected %q at end of expression", kind)
+	}
+	if t.kind != kind {
+		return fmt.Errorf("expected %q at position %d, found %q", kind, t.pos, t.text)
+	}
+	p.pos++
+	return nil
+}
+
+// parseExpr parses additions and subtractions
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || (t.kind != '+' && t.kind != '-') {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireScalar(left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: t.kind, left: left, right: right}
+	}
+}
+
+// parseTerm parses multiplications and divisions
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || (t.kind != '*' && t.kind != '/') {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireScalar(left, right); err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: t.kind, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if t, ok := p.peek(); ok && t.kind == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireScalar(operand); err != nil {
+			return nil, err
+		}
+		return &unaryNode{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case 'n':
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.pos)
+		}
+		return numberNode(v), nil
+
+	case '(':
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return inner, nil
+
+	case 'i':
+		if next, ok := p.peek(); ok && next.kind == '(' {
+			return p.parseCall(t)
+		}
+		if strings.Contains(t.text, ".") {
+			refs, err := parseSeriesRef(t.text)
+			if err != nil {
+				return nil, err
+			}
+			for _, ref := range refs {
+				if !p.seenSeries[ref] {
+					p.seenSeries[ref] = true
+					p.series = append(p.series, ref)
+				}
+			}
+			return &seriesNode{source: t.text, refs: refs}, nil
+		}
+		if !p.seenMetrics[t.text] {
+			p.seenMetrics[t.text] = true
+			p.metrics = append(p.metrics, t.text)
+		}
+		return metricNode(t.text), nil
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+}
+
+// parseCall parses a function call whose name token has been consumed
+func (p *parser) parseCall(name token) (node, error) {
+	fn := strings.ToLower(name.text)
+	if !functions[fn] {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+	p.pos++ // "("
+
+	call := &callNode{fn: fn}
+	if t, ok := p.peek(); !ok || t.kind != ')' {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if t, ok := p.peek(); ok && t.kind == ',' {
+				p.pos++
+				continue
+			}
+			break
+		}
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+
+	if len(call.args) == 0 {
+		return nil, fmt.Errorf("%s() needs at least one argument", fn)
+	}
+	if fn == "abs" {
+		if len(call.args) != 1 {
+			return nil, fmt.Errorf("abs() takes exactly one argument")
+		}
+		if err := requireScalar(call.args[0]); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+// requireScalar rejects series ranges used where a single value is expected
+func requireScalar(nodes ...node) error {
+	for _, n := range nodes {
+		if s, ok := n.(*seriesNode); ok && len(s.refs) > 1 {
+			return fmt.Errorf("series range %q must be aggregated, e.g. avg(%s)", s.source, s.source)
+		}
+	}
+	return nil
+}
+
+// parseSeriesRef parses "AGENT.field" or the range form "AGENT-001..003.field"
+func parseSeriesRef(text string) ([]SeriesRef, error) {
+	if i := strings.Index(text, ".."); i >= 0 {
+		start, rest := text[:i], text[i+2:]
+		j := strings.Index(rest, ".")
+		if start == "" || j <= 0 || j == len(rest)-1 {
+			return nil, fmt.Errorf("invalid series range %q, expected AGENT-001..003.field", text)
+		}
+		end, field := rest[:j], rest[j+1:]
+
+		agents, err := expandRange(start, end)
+		if err != nil {
+			return nil, fmt.Errorf("invalid series range %q: %w", text, err)
+		}
+		refs := make([]SeriesRef, 0, len(agents))
+		for _, agentID := range agents {
+			refs = append(refs, SeriesRef{AgentID: agentID, Field: field})
+		}
+		return refs, nil
+	}
+
+	i := strings.Index(text, ".")
+	if i <= 0 || i == len(text)-1 {
+		return nil, fmt.Errorf("invalid series reference %q, expected AGENT.field", text)
+	}
+	return []SeriesRef{{AgentID: text[:i], Field: text[i+1:]}}, nil
+}
+
+// expandRange expands agent IDs sharing a prefix and ending in a number,
+// keeping the start's zero padding. The end may be just the number.
+func expandRange(start, end string) ([]string, error) {
+	prefix, startDigits := splitTrailingDigits(start)
+	if startDigits == "" {
+		return nil, fmt.Errorf("%q does not end in a number", start)
+	}
+
+	endPrefix, endDigits := splitTrailingDigits(end)
+	if endDigits == "" || (endPrefix != "" && endPrefix != prefix) {
+		return nil, fmt.Errorf("%q must be a number or share the prefix %q", end, prefix)
+	}
+
+	from, _ := strconv.Atoi(startDigits)
+	to, _ := strconv.Atoi(endDigits)
+	if to < from {
+		return nil, fmt.Errorf("range end %d is before start %d", to, from)
+	}
+	if to-from+1 > maxRangeSize {
+		return nil, fmt.Errorf("range covers more than %d series", maxRangeSize)
+	}
+
+	ids := make([]string, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		ids = append(ids, fmt.Sprintf("%s%0*d", prefix, len(startDigits), n))
+	}
+	return ids, nil
+}
+
+func splitTrailingDigits(s string) (string, string) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,121 @@
+package derivedmetrics
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type mapResolver struct {
+	series  map[string]float64
+	metrics map[string]float64
+}
+
+func (r mapResolver) Series(ref SeriesRef) (float64, bool) {
+	v, ok := r.series[ref.String()]
+	return v, ok
+}
+
+func (r mapResolver) Metric(name string) (float64, error) {
+	v, ok := r.metrics[name]
+	if !ok {
+		return 0, ErrNoData
+	}
+	return v, nil
+}
+
+func TestParse_Evaluate(t *testing.T) {
+	r := mapResolver{
+		series: map[string]float64{
+			"SENSOR-001.pressure_bar": 5.0,
+			"SENSOR-002.pressure_bar": 6.0,
+			"SENSOR-003.pressure_bar": 7.0,
+			"PUMP-001.flow.rate":      12.5,
+		},
+		metrics: map[string]float64{"current": 0.82, "baseline": 0.9},
+	}
+
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"avg(SENSOR-001..003.pressure_bar)", 6.0},
+		{"sum(SENSOR-001..SENSOR-003.pressure_bar)", 18.0},
+		{"max(SENSOR-001..003.pressure_bar) - min(SENSOR-001..003.pressure_bar)", 2.0},
+		{"count(SENSOR-001..005.pressure_bar)", 3},
+		{"current - baseline", 0.82 - 0.9},
+		{"abs(current - baseline) * 100", math.Abs(0.82-0.9) * 100},
+		{"PUMP-001.flow.rate / (2 + 3)", 2.5},
+		{"-SENSOR-001.pressure_bar + 1", -4.0},
+		{"avg(SENSOR-001.pressure_bar, SENSOR-009.pressure_bar, 9)", 7.0},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Evaluate(r)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParse_References(t *testing.T) {
+	expr, err := Parse("avg(SENSOR-008..010.pressure_bar) - baseline + SENSOR-008.pressure_bar")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []SeriesRef{
+		{AgentID: "SENSOR-008", Field: "pressure_bar"},
+		{AgentID: "SENSOR-009", Field: "pressure_bar"},
+		{AgentID: "SENSOR-010", Field: "pressure_bar"},
+	}
+	if !reflect.DeepEqual(expr.Series(), want) {
+		t.Errorf("Expected series %v, got %v", want, expr.Series())
+	}
+	if !reflect.DeepEqual(expr.Metrics(), []string{"baseline"}) {
+		t.Errorf("Expected metrics [baseline], got %v", expr.Metrics())
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"SENSOR-001..003.pressure_bar",
+		"SENSOR-001..003.pressure_bar + 1",
+		"abs(SENSOR-001..003.pressure_bar)",
+		"SENSOR-003..001.pressure_bar",
+		"SENSOR..003.pressure_bar",
+		"median(SENSOR-001.pressure_bar)",
+		"avg()",
+		"(1 + 2",
+		"1 + 2)",
+		"current $ baseline",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", expr)
+		}
+	}
+}
+
+func TestEvaluate_NoData(t *testing.T) {
+	r := mapResolver{series: map[string]float64{}}
+
+	for _, src := range []string{"SENSOR-001.pressure_bar * 2", "avg(SENSOR-001..003.pressure_bar)"} {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", src, err)
+		}
+		if _, err := expr.Evaluate(r); !errors.Is(err, ErrNoData) {
+			t.Errorf("Expected ErrNoData for %q, got %v", src, err)
+		}
+	}
+}
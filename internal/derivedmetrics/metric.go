@@ -0,0 +1,84 @@
+package derivedmetrics
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+const (
+	// ModeIngest recomputes a metric whenever one of its input series is published
+	ModeIngest = "ingest"
+
+	// ModeQuery computes a metric from recorded traffic when it is requested
+	ModeQuery = "query"
+
+	// DefaultEventPrefix prefixes the event name ingest metric values are published under
+	DefaultEventPrefix = "metric.derived."
+)
+
+var (
+	// ErrMetricNotFound is returned for metrics that are not defined
+	ErrMetricNotFound = errors.New("derived metric not found")
+
+	// ErrInvalidMetric is returned for definitions that fail validation
+	ErrInvalidMetric = errors.New("invalid derived metric")
+
+	namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// Definition is an operator-defined metric computed from published series
+type Definition struct {
+	// Name is the snake_case metric name other expressions reference it by
+	Name string `json:"name"`
+
+	// Expression is the formula, see Expression
+	Expression string `json:"expression"`
+
+	// Mode is ModeIngest or ModeQuery
+	Mode string `json:"mode"`
+
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// EventName is the event ingest values are published under
+	EventName string `json:"event_name,omitempty"`
+
+	// Inputs are the series the metric reads, including through referenced metrics
+	Inputs []string `json:"inputs"`
+
+	expr   *Expression
+	inputs map[SeriesRef]bool
+}
+
+// DefinitionFromConfig converts application config into a Definition
+func DefinitionFromConfig(cfg config.DerivedMetricConfig) Definition {
+	return Definition{
+		Name:        cfg.Name,
+		Expression:  cfg.Expression,
+		Mode:        cfg.Mode,
+		Unit:        cfg.Unit,
+		Description: cfg.Description,
+		EventName:   cfg.EventName,
+	}
+}
+
+// Value is one computed value of a derived metric
+type Value struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+
+	// ComputedAt is the publication time of the input that produced the value
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// MetricStatus is a definition with its most recent value
+type MetricStatus struct {
+	Definition
+
+	// Latest is the last ingested value; always nil for query metrics
+	Latest *Value `json:"latest,omitempty"`
+}
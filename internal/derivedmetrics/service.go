@@ -0,0 +1,521 @@
+package derivedmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// PublisherAgentID is the agent ID derived metric values are published under
+	PublisherAgentID = "derived-metrics"
+
+	defaultHistorySize = 500
+	defaultLookback    = time.Hour
+)
+
+// TrafficSource reads recorded pub/sub traffic. PubSubService implements it.
+type TrafficSource interface {
+	CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error)
+}
+
+// Config configures derived metric evaluation
+type Config struct {
+	// Topics are the event patterns whose payloads feed series
+	Topics []string
+
+	// HistorySize is the number of values kept per ingest metric
+	HistorySize int
+
+	// Lookback is how far back query metrics look for series values
+	Lookback time.Duration
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.DerivedMetricsConfig) Config {
+	return Config{
+		Topics:      cfg.Topics,
+		HistorySize: cfg.HistorySize,
+		Lookback:    time.Duration(cfg.LookbackSeconds) * time.Second,
+	}
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if len(c.Topics) == 0 {
+		c.Topics = []string{"#"}
+	}
+	if c.HistorySize <= 0 {
+		c.HistorySize = defaultHistorySize
+	}
+	if c.Lookback <= 0 {
+		c.Lookback = defaultLookback
+	}
+	return c
+}
+
+// Service computes derived metrics so that rules, dashboards and anomaly
+// detection share one definition instead of each recomputing it. Ingest
+// metrics are recomputed from the latest value of each input series as
+// publications arrive and republished as metric publications; query metrics
+// are computed from recorded traffic on request.
+type Service struct {
+	config    Config
+	source    TrafficSource
+	publisher communication.TrafficPublisher
+	logger    *log.Logger
+
+	mu          sync.RWMutex
+	definitions map[string]*Definition
+	order       []string
+	watched     map[string]map[string]bool // agent ID -> fields read by ingest metrics
+	series      map[SeriesRef]float64      // latest ingested series values
+	latest      map[string]*Value
+	history     map[string][]*Value
+}
+
+// NewService creates a new derived metric service. The source may be nil,
+// in which case only ingest metrics produce values.
+func NewService(cfg Config, source TrafficSource, logger *log.Logger) *Service {
+	return &Service{
+		config:      cfg.withDefaults(),
+		source:      source,
+		logger:      logger,
+		definitions: make(map[string]*Definition),
+		watched:     make(map[string]map[string]bool),
+		series:      make(map[SeriesRef]float64),
+		latest:      make(map[string]*Value),
+		history:     make(map[string][]*Value),
+	}
+}
+
+// SetPublisher sets the publisher used for ingest metric values
+func (s *Service) SetPublisher(publisher communication.TrafficPublisher) {
+	s.publisher = publisher
+}
+
+// Define adds a metric. Expressions may only reference metrics defined
+// earlier, which rules out cycles.
+func (s *Service) Define(def Definition) error {
+	if !namePattern.MatchString(def.Name) {
+		return fmt.Errorf("%w: name %q must be snake_case, e.g. zone_avg_pressure", ErrInvalidMetric, def.Name)
+	}
+	switch def.Mode {
+	case "":
+		def.Mode = ModeIngest
+	case ModeIngest, ModeQuery:
+	default:
+		return fmt.Errorf("%w: %s: mode must be %q or %q", ErrInvalidMetric, def.Name, ModeIngest, ModeQuery)
+	}
+	expr, err := Parse(def.Expression)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMetric, def.Name, err)
+	}
+	if def.EventName == "" {
+		def.EventName = DefaultEventPrefix + def.Name
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.definitions[def.Name]; exists {
+		return fmt.Errorf("%w: %s is already defined", ErrInvalidMetric, def.Name)
+	}
+	inputs, err := s.inputs(expr)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMetric, def.Name, err)
+	}
+
+	def.expr = expr
+	def.inputs = inputs
+	def.Inputs = make([]string, 0, len(inputs))
+	for ref := range inputs {
+		def.Inputs = append(def.Inputs, ref.String())
+	}
+	sort.Strings(def.Inputs)
+
+	s.definitions[def.Name] = &def
+	s.order = append(s.order, def.Name)
+	if def.Mode == ModeIngest {
+		for ref := range inputs {
+			if s.watched[ref.AgentID] == nil {
+				s.watched[ref.AgentID] = make(map[string]bool)
+			}
+			s.watched[ref.AgentID][ref.Field] = true
+		}
+	}
+
+	s.logger.WithFields(log.Fields{
+		"metric": def.Name,
+		"mode":   def.Mode,
+		"inputs": len(def.Inputs),
+	}).Info("Defined derived metric")
+	return nil
+}
+
+// inputs collects the series an expression reads directly and through the
+// metrics it references. Callers hold the lock.
+func (s *Service) inputs(expr *Expression) (map[SeriesRef]bool, error) {
+	inputs := make(map[SeriesRef]bool)
+	for _, ref := range expr.Series() {
+		inputs[ref] = true
+	}
+	for _, name := range expr.Metrics() {
+		dep, ok := s.definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("references unknown metric %q (metrics may only reference metrics defined before them)", name)
+		}
+		for ref := range dep.inputs {
+			inputs[ref] = true
+		}
+	}
+	return inputs, nil
+}
+
+// Definitions returns every metric in definition order with its latest value
+func (s *Service) Definitions() []*MetricStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]*MetricStatus, 0, len(s.order))
+	for _, name := range s.order {
+		statuses = append(statuses, &MetricStatus{
+			Definition: *s.definitions[name],
+			Latest:     s.latest[name],
+		})
+	}
+	return statuses
+}
+
+// Definition returns a metric's definition and latest value
+func (s *Service) Definition(name string) (*MetricStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	def, ok := s.definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	return &MetricStatus{Definition: *def, Latest: s.latest[name]}, nil
+}
+
+// ObservePublications returns a publish observer that feeds Ingest
+func (s *Service) ObservePublications() communication.PublishObserver {
+	return func(ctx context.Context, pub *communication.Publication) {
+		s.Ingest(ctx, pub)
+	}
+}
+
+// Ingest records the input series values carried by a publication and
+// recomputes the ingest metrics reading them. New values are returned and,
+// when a publisher is set, published as metric publications.
+func (s *Service) Ingest(ctx context.Context, pub *communication.Publication) []*Value {
+	values := s.ingest(pub)
+
+	// Publish outside the lock since observers run synchronously
+	if s.publisher != nil {
+		for _, value := range values {
+			s.publish(ctx, value)
+		}
+	}
+	return values
+}
+
+// Backfill ingests a historical publication like Ingest but does not
+// publish the values it computes, which are timestamped at the publication
+func (s *Service) Backfill(pub *communication.Publication) []*Value {
+	return s.ingest(pub)
+}
+
+// ingest records a publication's series values and recomputes the ingest
+// metrics reading them
+func (s *Service) ingest(pub *communication.Publication) []*Value {
+	if pub.PublisherAgentID == PublisherAgentID || !matchesAny(pub.EventName, s.config.Topics) {
+		return nil
+	}
+
+	s.mu.Lock()
+	updated := updateSeries(s.series, s.watched[pub.PublisherAgentID], pub.PublisherAgentID, pub.Payload)
+	if len(updated) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+
+	r := &resolver{definitions: s.definitions, series: s.series}
+	var values []*Value
+	for _, name := range s.order {
+		def := s.definitions[name]
+		if def.Mode != ModeIngest || !readsAny(def, updated) {
+			continue
+		}
+		v, err := def.expr.Evaluate(r)
+		if err != nil {
+			if !errors.Is(err, ErrNoData) {
+				s.logger.WithError(err).WithField("metric", name).Warn("Failed to evaluate derived metric")
+			}
+			continue
+		}
+
+		value := &Value{Metric: name, Value: v, Unit: def.Unit, ComputedAt: pub.PublishedAt}
+		s.latest[name] = value
+		history := append(s.history[name], value)
+		if len(history) > s.config.HistorySize {
+			history = history[len(history)-s.config.HistorySize:]
+		}
+		s.history[name] = history
+		values = append(values, value)
+	}
+	s.mu.Unlock()
+	return values
+}
+
+// publish announces an ingest metric value. The value is also carried under
+// the metric's name so payload filters such as "zone_avg_pressure > 6" work.
+func (s *Service) publish(ctx context.Context, value *Value) {
+	s.mu.RLock()
+	def := s.definitions[value.Metric]
+	s.mu.RUnlock()
+
+	payload := map[string]interface{}{
+		"metric":     value.Metric,
+		"value":      value.Value,
+		value.Metric: value.Value,
+	}
+	if value.Unit != "" {
+		payload["unit"] = value.Unit
+	}
+
+	_, err := s.publisher.Publish(ctx, PublisherAgentID, PublisherAgentID, def.EventName, payload, &communication.PublicationOptions{
+		Type: communication.PublicationTypeMetric,
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("metric", value.Metric).Warn("Failed to publish derived metric")
+	}
+}
+
+// Evaluate returns a metric's current value: the latest ingested value for
+// ingest metrics, or a value computed from recorded traffic for query metrics
+func (s *Service) Evaluate(ctx context.Context, name string) (*Value, error) {
+	s.mu.RLock()
+	def, ok := s.definitions[name]
+	latest := s.latest[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if def.Mode == ModeIngest {
+		if latest == nil {
+			return nil, fmt.Errorf("%w: %s has not been computed yet", ErrNoData, name)
+		}
+		return latest, nil
+	}
+
+	until := time.Now().UTC()
+	values, err := s.compute(ctx, def, until.Add(-s.config.Lookback), until)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: no inputs of %s published in the last %s", ErrNoData, name, s.config.Lookback)
+	}
+	return values[len(values)-1], nil
+}
+
+// History returns a metric's values computed in [since, until], oldest first.
+// Query metric values are recomputed from recorded traffic, seeding the
+// inputs from up to one lookback period before since.
+func (s *Service) History(ctx context.Context, name string, since, until time.Time) ([]*Value, error) {
+	s.mu.RLock()
+	def, ok := s.definitions[name]
+	var stored []*Value
+	if ok && def.Mode == ModeIngest {
+		stored = append(stored, s.history[name]...)
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	if def.Mode == ModeQuery {
+		var err error
+		stored, err = s.compute(ctx, def, since.Add(-s.config.Lookback), until)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := []*Value{}
+	for _, value := range stored {
+		if !value.ComputedAt.Before(since) && !value.ComputedAt.After(until) {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// EvaluateExpression computes an ad hoc expression from recorded traffic, for
+// trying out a formula before defining it. It may reference defined metrics.
+func (s *Service) EvaluateExpression(ctx context.Context, expression string) (*Value, error) {
+	expr, err := Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetric, err)
+	}
+
+	s.mu.RLock()
+	inputs, err := s.inputs(expr)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetric, err)
+	}
+
+	def := &Definition{Name: expr.String(), Expression: expr.String(), Mode: ModeQuery, expr: expr, inputs: inputs}
+	until := time.Now().UTC()
+	values, err := s.compute(ctx, def, until.Add(-s.config.Lookback), until)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: no inputs published in the last %s", ErrNoData, s.config.Lookback)
+	}
+	return values[len(values)-1], nil
+}
+
+// compute replays recorded traffic in (since, until], evaluating the metric
+// after each publication that updates one of its inputs
+func (s *Service) compute(ctx context.Context, def *Definition, since, until time.Time) ([]*Value, error) {
+	if s.source == nil {
+		return nil, fmt.Errorf("query metrics need recorded traffic, which is not available")
+	}
+	capture, err := s.source.CaptureTraffic(ctx, s.config.Topics, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic: %w", err)
+	}
+
+	s.mu.RLock()
+	definitions := make(map[string]*Definition, len(s.definitions))
+	for name, d := range s.definitions {
+		definitions[name] = d
+	}
+	s.mu.RUnlock()
+
+	fields := make(map[string]map[string]bool)
+	for ref := range def.inputs {
+		if fields[ref.AgentID] == nil {
+			fields[ref.AgentID] = make(map[string]bool)
+		}
+		fields[ref.AgentID][ref.Field] = true
+	}
+
+	r := &resolver{definitions: definitions, series: make(map[SeriesRef]float64)}
+	var values []*Value
+	for _, pub := range capture.Publications {
+		if pub.PublisherAgentID == PublisherAgentID {
+			continue
+		}
+		if len(updateSeries(r.series, fields[pub.PublisherAgentID], pub.PublisherAgentID, pub.Payload)) == 0 {
+			continue
+		}
+		v, err := def.expr.Evaluate(r)
+		if errors.Is(err, ErrNoData) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s: %w", def.Name, err)
+		}
+		values = append(values, &Value{Metric: def.Name, Value: v, Unit: def.Unit, ComputedAt: pub.PublishedAt})
+	}
+	return values, nil
+}
+
+// resolver evaluates expressions against a set of latest series values.
+// Referenced metrics are recomputed from the same values so that a metric and
+// the metrics it builds on are always consistent.
+type resolver struct {
+	definitions map[string]*Definition
+	series      map[SeriesRef]float64
+}
+
+func (r *resolver) Series(ref SeriesRef) (float64, bool) {
+	v, ok := r.series[ref]
+	return v, ok
+}
+
+func (r *resolver) Metric(name string) (float64, error) {
+	def, ok := r.definitions[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMetricNotFound, name)
+	}
+	return def.expr.Evaluate(r)
+}
+
+// updateSeries stores the numeric payload fields of one agent's publication
+// and returns the series that were updated
+func updateSeries(series map[SeriesRef]float64, fields map[string]bool, agentID string, payload map[string]interface{}) []SeriesRef {
+	var updated []SeriesRef
+	for field := range fields {
+		v, ok := lookupNumber(payload, field)
+		if !ok {
+			continue
+		}
+		ref := SeriesRef{AgentID: agentID, Field: field}
+		series[ref] = v
+		updated = append(updated, ref)
+	}
+	return updated
+}
+
+// readsAny reports whether a metric reads any of the series
+func readsAny(def *Definition, refs []SeriesRef) bool {
+	for _, ref := range refs {
+		if def.inputs[ref] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupNumber resolves a dotted payload path to a number
+func lookupNumber(payload map[string]interface{}, path string) (float64, bool) {
+	var current interface{} = payload
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return 0, false
+		}
+		if current, ok = m[key]; !ok {
+			return 0, false
+		}
+	}
+
+	switch n := current.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// matchesAny reports whether an event name matches any topic pattern
+func matchesAny(eventName string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if communication.MatchTopic(pattern, eventName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package derivedmetrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeTraffic struct {
+	publications []communication.CapturedPublication
+}
+
+func (f *fakeTraffic) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error) {
+	capture := &communication.TrafficCapture{Topics: topics, Since: since, Until: until}
+	for _, pub := range f.publications {
+		if pub.PublishedAt.After(since) && !pub.PublishedAt.After(until) {
+			capture.Publications = append(capture.Publications, pub)
+		}
+	}
+	return capture, nil
+}
+
+type recordingPublisher struct {
+	events   []string
+	payloads []map[string]interface{}
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *communication.PublicationOptions) (string, error) {
+	p.events = append(p.events, eventName)
+	p.payloads = append(p.payloads, payload)
+	return "pub", nil
+}
+
+func reading(agentID string, pressure float64, at time.Time) *communication.Publication {
+	return &communication.Publication{
+		PublisherAgentID: agentID,
+		PublicationType:  communication.PublicationTypeMetric,
+		EventName:        "reading.pressure",
+		Payload:          map[string]interface{}{"pressure_bar": pressure},
+		PublishedAt:      at,
+	}
+}
+
+func TestIngest_RecomputesAndPublishes(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(Config{}, nil, logrus.New())
+	publisher := &recordingPublisher{}
+	service.SetPublisher(publisher)
+
+	if err := service.Define(Definition{Name: "zone_avg_pressure", Expression: "avg(SENSOR-001..003.pressure_bar)", Unit: "bar"}); err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+	if err := service.Define(Definition{Name: "zone_pressure_drop", Expression: "6 - zone_avg_pressure"}); err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+
+	now := time.Now()
+	service.Ingest(ctx, reading("SENSOR-001", 5.0, now))
+	values := service.Ingest(ctx, reading("SENSOR-002", 6.0, now.Add(time.Second)))
+	if len(values) != 2 {
+		t.Fatalf("Expected both metrics to be recomputed, got %d values", len(values))
+	}
+	if values[0].Value != 5.5 || values[1].Value != 0.5 {
+		t.Errorf("Expected 5.5 and 0.5, got %v and %v", values[0].Value, values[1].Value)
+	}
+
+	// Unrelated agents and the service's own publications are ignored
+	if values := service.Ingest(ctx, reading("SENSOR-009", 9.0, now)); len(values) != 0 {
+		t.Errorf("Expected no values for an unrelated sensor, got %d", len(values))
+	}
+	own := reading(PublisherAgentID, 1.0, now)
+	own.Payload["zone_avg_pressure"] = 1.0
+	if values := service.Ingest(ctx, own); len(values) != 0 {
+		t.Errorf("Expected own publications to be ignored, got %d values", len(values))
+	}
+
+	if len(publisher.events) != 4 || publisher.events[2] != "metric.derived.zone_avg_pressure" {
+		t.Errorf("Unexpected published events %v", publisher.events)
+	}
+	if publisher.payloads[2]["zone_avg_pressure"] != 5.5 || publisher.payloads[2]["unit"] != "bar" {
+		t.Errorf("Unexpected published payload %v", publisher.payloads[2])
+	}
+
+	latest, err := service.Evaluate(ctx, "zone_avg_pressure")
+	if err != nil || latest.Value != 5.5 {
+		t.Errorf("Expected latest value 5.5, got %+v (%v)", latest, err)
+	}
+	history, err := service.History(ctx, "zone_avg_pressure", now.Add(-time.Minute), now.Add(time.Minute))
+	if err != nil || len(history) != 2 {
+		t.Errorf("Expected 2 history values, got %d (%v)", len(history), err)
+	}
+}
+
+func TestDefine_Validation(t *testing.T) {
+	service := NewService(Config{}, nil, logrus.New())
+
+	for _, def := range []Definition{
+		{Name: "Zone Avg", Expression: "1"},
+		{Name: "bad_mode", Expression: "1", Mode: "stream"},
+		{Name: "bad_expr", Expression: "avg("},
+		{Name: "forward_ref", Expression: "later + 1"},
+	} {
+		if err := service.Define(def); !errors.Is(err, ErrInvalidMetric) {
+			t.Errorf("Expected ErrInvalidMetric for %+v, got %v", def, err)
+		}
+	}
+
+	if err := service.Define(Definition{Name: "ok", Expression: "PUMP-001.flow"}); err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+	if err := service.Define(Definition{Name: "ok", Expression: "PUMP-002.flow"}); !errors.Is(err, ErrInvalidMetric) {
+		t.Errorf("Expected duplicate definition to fail, got %v", err)
+	}
+	if _, err := service.Evaluate(context.Background(), "missing"); !errors.Is(err, ErrMetricNotFound) {
+		t.Errorf("Expected ErrMetricNotFound, got %v", err)
+	}
+}
+
+func TestQueryMode_ComputesFromTraffic(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	traffic := &fakeTraffic{}
+	for i, p := range []struct {
+		agent string
+		field string
+		value float64
+	}{
+		{"PUMP-001", "baseline", 0.9},
+		{"PUMP-001", "efficiency", 0.8},
+		{"PUMP-001", "efficiency", 0.85},
+	} {
+		traffic.publications = append(traffic.publications, communication.CapturedPublication{
+			PublisherAgentID: p.agent,
+			EventName:        "reading.efficiency",
+			Payload:          map[string]interface{}{p.field: p.value},
+			PublishedAt:      now.Add(time.Duration(i-3) * time.Minute),
+		})
+	}
+
+	service := NewService(Config{Lookback: time.Hour}, traffic, logrus.New())
+	if err := service.Define(Definition{
+		Name:       "efficiency_delta",
+		Expression: "PUMP-001.efficiency - PUMP-001.baseline",
+		Mode:       ModeQuery,
+	}); err != nil {
+		t.Fatalf("Define failed: %v", err)
+	}
+
+	// Query metrics are not computed on ingest
+	if values := service.Ingest(ctx, &communication.Publication{PublisherAgentID: "PUMP-001", Payload: map[string]interface{}{"efficiency": 1.0}}); len(values) != 0 {
+		t.Errorf("Expected query metrics to be skipped on ingest, got %d values", len(values))
+	}
+
+	value, err := service.Evaluate(ctx, "efficiency_delta")
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if diff := value.Value - (-0.05); diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected -0.05, got %v", value.Value)
+	}
+
+	history, err := service.History(ctx, "efficiency_delta", now.Add(-time.Hour), now)
+	if err != nil || len(history) != 2 {
+		t.Errorf("Expected 2 recomputed values, got %d (%v)", len(history), err)
+	}
+
+	adhoc, err := service.EvaluateExpression(ctx, "efficiency_delta * 100")
+	if err != nil {
+		t.Fatalf("EvaluateExpression failed: %v", err)
+	}
+	if diff := adhoc.Value - (-5); diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected -5, got %v", adhoc.Value)
+	}
+}
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
 	"github.com/aosanya/CodeValdCortex/internal/registry"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -16,6 +19,8 @@ import (
 type AgencyHandler struct {
 	service     agency.Service
 	roleService registry.RoleService
+	itemAdapter agency.ItemAdapter
+	outbox      *outbox.Dispatcher
 	logger      *logrus.Logger
 }
 
@@ -28,6 +33,44 @@ func NewAgencyHandler(service agency.Service, roleService registry.RoleService,
 	}
 }
 
+// SetItemAdapter enables AI adaptation when copying items between agencies
+func (h *AgencyHandler) SetItemAdapter(adapter agency.ItemAdapter) {
+	h.itemAdapter = adapter
+}
+
+// SetOutbox enables change webhooks for committed goal changes
+func (h *AgencyHandler) SetOutbox(dispatcher *outbox.Dispatcher) {
+	h.outbox = dispatcher
+}
+
+// withGoalChange attaches change webhooks for a goal event to ctx. The
+// returned function delivers them once the change has been committed.
+func (h *AgencyHandler) withGoalChange(ctx context.Context, agencyID, event string, data map[string]interface{}) (context.Context, func()) {
+	if h.outbox == nil {
+		return ctx, func() {}
+	}
+
+	entries := h.outbox.ChangeNotifications(agencyID, "agency.goal."+event, data)
+	return outbox.WithEntries(ctx, entries...), func() {
+		go h.outbox.Deliver(context.WithoutCancel(ctx), entries...)
+	}
+}
+
+// respondValidationError responds with 422 and the violations when err is a
+// strict validation failure, so the designer UI can show them next to the
+// fields. It reports whether it responded.
+func respondValidationError(c *gin.Context, err error) bool {
+	var validationErr *agency.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":      validationErr.Error(),
+		"violations": validationErr.Violations,
+	})
+	return true
+}
+
 // RegisterRoutes registers agency routes with the router
 func (h *AgencyHandler) RegisterRoutes(router *gin.RouterGroup) {
 	agencies := router.Group("/agencies")
@@ -50,16 +93,36 @@ func (h *AgencyHandler) RegisterRoutes(router *gin.RouterGroup) {
 		agencies.GET("/:id/goals", h.GetGoals)
 		agencies.GET("/:id/goals/html", h.GetGoalsHTML)
 		agencies.POST("/:id/goals", h.CreateGoal)
+		agencies.PUT("/:id/goals/priorities", h.ReprioritizeGoals)
+		agencies.POST("/:id/goals/consolidations", h.ConsolidateGoals)
+		agencies.POST("/:id/goals/consolidations/:txId/undo", h.UndoGoalConsolidation)
 		agencies.PUT("/:id/goals/:goalKey", h.UpdateGoal)
 		agencies.DELETE("/:id/goals/:goalKey", h.DeleteGoal)
+		agencies.GET("/:id/goals/:goalKey/explanations", h.GetGoalExplanations)
+		agencies.GET("/:id/goals/:goalKey/explanations/html", h.GetGoalExplanationsHTML)
+		agencies.GET("/:id/traceability", h.GetTraceability)
+		agencies.GET("/:id/traceability/html", h.GetTraceabilityHTML)
 
 		// Work items routes
 		agencies.GET("/:id/work-items", h.GetWorkItems)
 		agencies.GET("/:id/work-items/html", h.GetWorkItemsHTML)
 		agencies.POST("/:id/work-items", h.CreateWorkItem)
 		agencies.PUT("/:id/work-items/:key", h.UpdateWorkItem)
+		agencies.PATCH("/:id/work-items/:key/status", h.TransitionWorkItem)
+		agencies.PATCH("/:id/work-items/:key/assignee", h.AssignWorkItem)
+		agencies.GET("/:id/workload", h.GetWorkload)
 		agencies.DELETE("/:id/work-items/:key", h.DeleteWorkItem)
+		agencies.GET("/:id/work-items/:key/explanations", h.GetWorkItemExplanations)
+		agencies.GET("/:id/work-items/:key/explanations/html", h.GetWorkItemExplanationsHTML)
 		agencies.POST("/:id/work-items/validate-deps", h.ValidateWorkItemDependencies)
+
+		// Cross-agency copy and link routes
+		agencies.POST("/:id/items/copy", h.CopyItems)
+		agencies.POST("/:id/items/link", h.LinkItems)
+
+		// Portable bundle routes
+		agencies.GET("/:id/export", h.ExportAgency)
+		agencies.POST("/import", h.ImportAgency)
 	}
 }
 
@@ -213,6 +276,9 @@ func (h *AgencyHandler) UpdateAgency(c *gin.Context) {
 	updates := agency.AgencyUpdates(req)
 
 	if err := h.service.UpdateAgency(c.Request.Context(), id, updates); err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
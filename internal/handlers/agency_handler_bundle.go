@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportAgency handles GET /api/v1/agencies/:id/export
+// Downloads the agency's overview, goals and work items as a single bundle.
+// Set "format=yaml" for YAML instead of JSON.
+func (h *AgencyHandler) ExportAgency(c *gin.Context) {
+	id := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or yaml"})
+		return
+	}
+
+	bundle, err := h.service.ExportAgency(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("agency_id", id).Error("Failed to export agency")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("%s.agency.%s", bundle.Agency.Name, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "yaml" {
+		data, err := yaml.Marshal(bundle)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", data)
+		return
+	}
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportAgency handles POST /api/v1/agencies/import
+// Creates a new agency from an exported bundle. YAML bundles are read when
+// the Content-Type names YAML; anything else is read as JSON.
+func (h *AgencyHandler) ImportAgency(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var bundle agency.AgencyBundle
+	if strings.Contains(c.ContentType(), "yaml") {
+		err = yaml.Unmarshal(body, &bundle)
+	} else {
+		err = json.Unmarshal(body, &bundle)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid agency bundle", "details": err.Error()})
+		return
+	}
+
+	result, err := h.service.ImportAgency(c.Request.Context(), &bundle)
+	if err != nil {
+		h.logger.WithError(err).WithField("source_agency_id", bundle.Agency.ID).Error("Failed to import agency")
+
+		// Without a result nothing was created
+		if result == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "partial": result})
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"source_agency_id": bundle.Agency.ID,
+		"agency_id":        result.Agency.ID,
+		"goals":            len(result.Goals),
+		"work_items":       len(result.WorkItems),
+	}).Info("Imported agency from bundle")
+
+	c.JSON(http.StatusCreated, result)
+}
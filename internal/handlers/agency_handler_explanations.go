@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/web/pages/agency_designer"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGoalExplanations handles GET /api/v1/agencies/:id/goals/:goalKey/explanations
+// Returns the AI explanations for why a goal exists or was changed, newest first
+func (h *AgencyHandler) GetGoalExplanations(c *gin.Context) {
+	h.getExplanations(c, agency.ExplanationEntityGoal, c.Param("goalKey"))
+}
+
+// GetGoalExplanationsHTML handles GET /api/v1/agencies/:id/goals/:goalKey/explanations/html
+// Returns the goal's explanation history as an HTML fragment for the designer
+func (h *AgencyHandler) GetGoalExplanationsHTML(c *gin.Context) {
+	h.getExplanationsHTML(c, agency.ExplanationEntityGoal, c.Param("goalKey"))
+}
+
+// GetWorkItemExplanations handles GET /api/v1/agencies/:id/work-items/:key/explanations
+// Returns the AI explanations for why a work item exists or was changed, newest first
+func (h *AgencyHandler) GetWorkItemExplanations(c *gin.Context) {
+	h.getExplanations(c, agency.ExplanationEntityWorkItem, c.Param("key"))
+}
+
+// GetWorkItemExplanationsHTML handles GET /api/v1/agencies/:id/work-items/:key/explanations/html
+// Returns the work item's explanation history as an HTML fragment for the designer
+func (h *AgencyHandler) GetWorkItemExplanationsHTML(c *gin.Context) {
+	h.getExplanationsHTML(c, agency.ExplanationEntityWorkItem, c.Param("key"))
+}
+
+func (h *AgencyHandler) getExplanations(c *gin.Context, entityType agency.ExplanationEntityType, entityKey string) {
+	id := c.Param("id")
+
+	explanations, err := h.service.GetExplanations(c.Request.Context(), id, entityType, entityKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanations)
+}
+
+func (h *AgencyHandler) getExplanationsHTML(c *gin.Context, entityType agency.ExplanationEntityType, entityKey string) {
+	id := c.Param("id")
+
+	explanations, err := h.service.GetExplanations(c.Request.Context(), id, entityType, entityKey)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error loading explanations")
+		return
+	}
+
+	component := agency_designer.ExplanationHistory(explanations)
+	c.Header("Content-Type", "text/html")
+	component.Render(c.Request.Context(), c.Writer)
+}
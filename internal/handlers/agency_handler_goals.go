@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"sort"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
 	"github.com/aosanya/CodeValdCortex/internal/web/pages/agency_designer"
@@ -22,6 +22,49 @@ func (h *AgencyHandler) GetGoals(c *gin.Context) {
 	c.JSON(http.StatusOK, goals)
 }
 
+// ConsolidateGoals handles POST /api/v1/agencies/:id/goals/consolidations
+// Replaces goals with consolidated ones in one step. The response is the
+// consolidation record, whose key undoes the change.
+func (h *AgencyHandler) ConsolidateGoals(c *gin.Context) {
+	id := c.Param("id")
+
+	var req agency.ConsolidateGoalsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	consolidation, err := h.service.ConsolidateGoals(c.Request.Context(), id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, consolidation)
+}
+
+// UndoGoalConsolidation handles POST /api/v1/agencies/:id/goals/consolidations/:txId/undo
+// Restores the goals a consolidation replaced and removes the goals it created
+func (h *AgencyHandler) UndoGoalConsolidation(c *gin.Context) {
+	id := c.Param("id")
+	txID := c.Param("txId")
+
+	consolidation, err := h.service.UndoGoalConsolidation(c.Request.Context(), id, txID)
+	if err != nil {
+		switch {
+		case errors.Is(err, agency.ErrGoalConsolidationNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, agency.ErrGoalConsolidationUndone):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, consolidation)
+}
+
 // GetGoalsHTML handles GET /api/v1/agencies/:id/goals/html
 // Returns rendered HTML fragment for HTMX/JavaScript rendering
 func (h *AgencyHandler) GetGoalsHTML(c *gin.Context) {
@@ -33,10 +76,8 @@ func (h *AgencyHandler) GetGoalsHTML(c *gin.Context) {
 		return
 	}
 
-	// Sort goals by Code
-	sort.Slice(goals, func(i, j int) bool {
-		return goals[i].Code < goals[j].Code
-	})
+	// Ranked goals first in their accepted order, then the rest by code
+	agency.SortGoalsByRank(goals)
 
 	// Render the goals list template
 	component := agency_designer.GoalsList(goals)
@@ -54,30 +95,62 @@ func (h *AgencyHandler) CreateGoal(c *gin.Context) {
 		return
 	}
 
-	goal, err := h.service.CreateGoal(c.Request.Context(), id, req.Code, req.Description)
+	ctx, notify := h.withGoalChange(c.Request.Context(), id, "created", map[string]interface{}{"code": req.Code, "description": req.Description})
+	goal, err := h.service.CreateGoal(ctx, id, req.Code, req.Description)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	notify()
 
 	c.JSON(http.StatusCreated, goal)
 }
 
 // UpdateGoal handles PUT /api/v1/agencies/:id/goals/:goalKey
+// Only the fields present in the body change; the others keep their stored
+// values, so the designer can send just the code and description.
 func (h *AgencyHandler) UpdateGoal(c *gin.Context) {
 	id := c.Param("id")
 	goalKey := c.Param("goalKey")
 
-	var req agency.UpdateGoalRequest
+	goal, err := h.service.GetGoal(c.Request.Context(), id, goalKey)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decoding over the stored fields leaves the absent ones unchanged
+	req := agency.UpdateGoalRequest{
+		Code:           goal.Code,
+		Description:    goal.Description,
+		Scope:          goal.Scope,
+		SuccessMetrics: goal.SuccessMetrics,
+		Priority:       goal.Priority,
+		Status:         goal.Status,
+		Category:       goal.Category,
+		Tags:           goal.Tags,
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
 		return
 	}
 
-	if err := h.service.UpdateGoal(c.Request.Context(), id, goalKey, req.Code, req.Description); err != nil {
+	ctx, notify := h.withGoalChange(c.Request.Context(), id, "updated", map[string]interface{}{"code": req.Code, "description": req.Description})
+	if err := h.service.UpdateGoalFull(ctx, id, goalKey, req); err != nil {
+		if errors.Is(err, agency.ErrReadOnlyLink) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	notify()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Goal updated successfully"})
 }
@@ -87,10 +160,37 @@ func (h *AgencyHandler) DeleteGoal(c *gin.Context) {
 	id := c.Param("id")
 	goalKey := c.Param("goalKey")
 
-	if err := h.service.DeleteGoal(c.Request.Context(), id, goalKey); err != nil {
+	ctx, notify := h.withGoalChange(c.Request.Context(), id, "deleted", nil)
+	if err := h.service.DeleteGoal(ctx, id, goalKey); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	notify()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Goal deleted successfully"})
 }
+
+// ReprioritizeGoals handles PUT /api/v1/agencies/:id/goals/priorities
+// Stores an accepted goal ordering, either the AI ranking as is or after
+// adjusting it, and returns the goals in their new order
+func (h *AgencyHandler) ReprioritizeGoals(c *gin.Context) {
+	id := c.Param("id")
+
+	var req agency.ReprioritizeGoalsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	goals, err := h.service.ReprioritizeGoals(c.Request.Context(), id, req.Priorities)
+	if err != nil {
+		if errors.Is(err, agency.ErrInvalidGoalPriorities) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, goals)
+}
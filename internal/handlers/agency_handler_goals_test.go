@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// goalStore keeps one agency's goals in memory; the agency service methods
+// the goal routes do not use are left to the embedded interface
+type goalStore struct {
+	agency.Service
+	goals map[string]*agency.Goal
+}
+
+func (s *goalStore) GetGoal(ctx context.Context, agencyID string, key string) (*agency.Goal, error) {
+	goal, ok := s.goals[key]
+	if !ok {
+		return nil, errors.New("goal not found")
+	}
+	copied := *goal
+	return &copied, nil
+}
+
+func (s *goalStore) UpdateGoalFull(ctx context.Context, agencyID string, key string, req agency.UpdateGoalRequest) error {
+	goal := s.goals[key]
+	goal.Code = req.Code
+	goal.Description = req.Description
+	goal.Scope = req.Scope
+	goal.SuccessMetrics = req.SuccessMetrics
+	goal.Priority = req.Priority
+	goal.Status = req.Status
+	goal.Category = req.Category
+	goal.Tags = req.Tags
+	return nil
+}
+
+func TestUpdateGoal_PartialBodyKeepsOtherFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	store := &goalStore{goals: map[string]*agency.Goal{
+		"g1": {
+			Key:            "g1",
+			AgencyID:       "a1",
+			Code:           "G001",
+			Description:    "Reduce outages",
+			Scope:          "Distribution network",
+			SuccessMetrics: []string{"Outage minutes down 30%"},
+			Priority:       "High",
+			Status:         "Active",
+			Category:       "Operational",
+			Tags:           []string{"reliability"},
+		},
+	}}
+	router := gin.New()
+	NewAgencyHandler(store, nil, logger).RegisterRoutes(router.Group("/api/v1"))
+
+	put := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The designer sends only the code and description
+	w := put("/api/v1/agencies/a1/goals/g1", `{"code":"G002","description":"Reduce unplanned outages"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	goal := store.goals["g1"]
+	assert.Equal(t, "G002", goal.Code)
+	assert.Equal(t, "Reduce unplanned outages", goal.Description)
+	assert.Equal(t, "Distribution network", goal.Scope)
+	assert.Equal(t, []string{"Outage minutes down 30%"}, goal.SuccessMetrics)
+	assert.Equal(t, "High", goal.Priority)
+	assert.Equal(t, "Active", goal.Status)
+	assert.Equal(t, "Operational", goal.Category)
+	assert.Equal(t, []string{"reliability"}, goal.Tags)
+
+	// Fields that are present change, even to empty values
+	w = put("/api/v1/agencies/a1/goals/g1", `{"priority":"Low","tags":[]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Low", goal.Priority)
+	assert.Empty(t, goal.Tags)
+	assert.Equal(t, "G002", goal.Code)
+	assert.Equal(t, "Distribution network", goal.Scope)
+
+	assert.Equal(t, http.StatusBadRequest, put("/api/v1/agencies/a1/goals/g1", `{"description":""}`).Code)
+	assert.Equal(t, http.StatusNotFound, put("/api/v1/agencies/a1/goals/missing", `{"code":"G003"}`).Code)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// MaterializeAgency handles GET /api/v1/agencies/:id/materialize
+// Converts the agency design (goals, work items, roles and RACI assignments)
+// into the files of a use case directory: role definitions in config/agents
+// and a seed agency in config/agencies. Pointing USECASE_CONFIG_DIR at the
+// extracted files launches the agency. Set "format=zip" to download the files
+// as an archive instead of previewing them as JSON.
+func (h *AgencyHandler) MaterializeAgency(c *gin.Context) {
+	id := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	design, err := h.agencyDesign(c.Request.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).WithField("agency_id", id).Error("Failed to load agency design")
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	materialized, err := usecase.Materialize(design)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "zip" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", design.Agency.Name+".usecase.zip"))
+		c.Header("Content-Type", "application/zip")
+		if err := materialized.WriteZip(c.Writer); err != nil {
+			h.logger.WithError(err).WithField("agency_id", id).Error("Failed to write use case archive")
+		}
+		return
+	}
+
+	files, err := materialized.Files()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	preview := make(map[string]json.RawMessage, len(files))
+	for name, data := range files {
+		preview[name] = data
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"agency_id": id,
+		"files":     preview,
+	})
+}
+
+// agencyDesign gathers an agency and everything the designer built for it
+func (h *AgencyHandler) agencyDesign(ctx context.Context, agencyID string) (*usecase.Design, error) {
+	ag, err := h.service.GetAgency(ctx, agencyID)
+	if err != nil {
+		return nil, err
+	}
+	design := &usecase.Design{Agency: ag}
+
+	if overview, err := h.service.GetAgencyOverview(ctx, agencyID); err == nil && overview != nil {
+		design.Introduction = overview.Introduction
+	}
+	if design.Goals, err = h.service.GetGoals(ctx, agencyID); err != nil {
+		return nil, fmt.Errorf("failed to load goals: %w", err)
+	}
+	if design.WorkItems, err = h.service.GetWorkItems(ctx, agencyID); err != nil {
+		return nil, fmt.Errorf("failed to load work items: %w", err)
+	}
+	if design.Roles, err = h.agencyRoles(ctx, agencyID); err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	if design.Assignments, err = h.service.GetAllRACIAssignments(ctx, agencyID); err != nil {
+		return nil, fmt.Errorf("failed to load RACI assignments: %w", err)
+	}
+
+	return design, nil
+}
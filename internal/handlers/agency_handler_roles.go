@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"sort"
 
@@ -11,18 +12,26 @@ import (
 
 // GetAgencyRoles handles GET /api/v1/agencies/:id/roles
 func (h *AgencyHandler) GetAgencyRoles(c *gin.Context) {
-	// For now, list all roles from the registry
-	// TODO: Filter by agency when agency-specific roles are implemented
-	roles, err := h.roleService.ListTypes(c.Request.Context())
+	userRoles, err := h.agencyRoles(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Filter out system roles (core, monitoring, etc.)
+	c.JSON(http.StatusOK, userRoles)
+}
+
+// agencyRoles returns the user-defined roles visible to an agency, sorted by ID
+func (h *AgencyHandler) agencyRoles(ctx context.Context, agencyID string) ([]*registry.Role, error) {
+	roles, err := h.roleService.ListTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter out system roles (core, monitoring, etc.) and other agencies' roles
 	userRoles := make([]*registry.Role, 0)
 	for _, role := range roles {
-		if !role.IsSystemType {
+		if !role.IsSystemType && role.VisibleTo(agencyID) {
 			userRoles = append(userRoles, role)
 		}
 	}
@@ -32,34 +41,19 @@ func (h *AgencyHandler) GetAgencyRoles(c *gin.Context) {
 		return userRoles[i].ID < userRoles[j].ID
 	})
 
-	c.JSON(http.StatusOK, userRoles)
+	return userRoles, nil
 }
 
 // GetAgencyRolesHTML handles GET /api/v1/agencies/:id/roles/html
 // Returns rendered HTML fragment for HTMX/JavaScript rendering
 func (h *AgencyHandler) GetAgencyRolesHTML(c *gin.Context) {
-	// For now, list all roles from the registry
-	// TODO: Filter by agency when agency-specific roles are implemented
-	roles, err := h.roleService.ListTypes(c.Request.Context())
+	userRoles, err := h.agencyRoles(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to list roles")
 		c.String(http.StatusInternalServerError, "Error loading roles")
 		return
 	}
 
-	// Filter out system roles (core, monitoring, etc.)
-	userRoles := make([]*registry.Role, 0)
-	for _, role := range roles {
-		if !role.IsSystemType {
-			userRoles = append(userRoles, role)
-		}
-	}
-
-	// Sort roles by ID (code)
-	sort.Slice(userRoles, func(i, j int) bool {
-		return userRoles[i].ID < userRoles[j].ID
-	})
-
 	h.logger.Infof("Returning %d user-defined roles for HTML rendering", len(userRoles))
 
 	// Render the roles list template
@@ -79,7 +73,16 @@ func (h *AgencyHandler) CreateAgencyRole(c *gin.Context) {
 		return
 	}
 
+	// Role IDs are global, so an ID in use by any agency cannot be reused
+	if role.ID != "" {
+		if existing, err := h.roleService.GetType(c.Request.Context(), role.ID); err == nil && existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A role with this ID already exists"})
+			return
+		}
+	}
+
 	// Set creation metadata
+	role.AgencyID = agencyID
 	role.CreatedBy = "api"
 	role.IsSystemType = false
 	role.IsEnabled = true
@@ -100,7 +103,7 @@ func (h *AgencyHandler) GetAgencyRole(c *gin.Context) {
 	key := c.Param("key")
 
 	role, err := h.roleService.GetType(c.Request.Context(), key)
-	if err != nil {
+	if err != nil || !role.VisibleTo(c.Param("id")) {
 		h.logger.WithError(err).Error("Failed to get role", "key", key)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
@@ -116,7 +119,7 @@ func (h *AgencyHandler) UpdateAgencyRole(c *gin.Context) {
 
 	// Check if role exists and is not a system role
 	existingRole, err := h.roleService.GetType(c.Request.Context(), key)
-	if err != nil {
+	if err != nil || !existingRole.VisibleTo(agencyID) {
 		h.logger.WithError(err).Error("Failed to get role for update", "key", key)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
@@ -136,9 +139,10 @@ func (h *AgencyHandler) UpdateAgencyRole(c *gin.Context) {
 		return
 	}
 
-	// Set the key/ID
+	// Set the key/ID and keep the role in its agency
 	role.Key = key
 	role.ID = key
+	role.AgencyID = existingRole.AgencyID
 
 	// Update the role
 	if err := h.roleService.UpdateType(c.Request.Context(), &role); err != nil {
@@ -158,7 +162,7 @@ func (h *AgencyHandler) DeleteAgencyRole(c *gin.Context) {
 
 	// Check if role exists and is not a system role
 	role, err := h.roleService.GetType(c.Request.Context(), key)
-	if err != nil {
+	if err != nil || !role.VisibleTo(agencyID) {
 		h.logger.WithError(err).Error("Failed to get role for deletion", "key", key)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
 		return
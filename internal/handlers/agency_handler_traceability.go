@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/web/pages/agency_designer"
+	"github.com/gin-gonic/gin"
+)
+
+// GetTraceability handles GET /api/v1/agencies/:id/traceability
+// Lists, per goal, the linked work items and their completion, and flags
+// goals without work items and work items linked to deleted goals.
+func (h *AgencyHandler) GetTraceability(c *gin.Context) {
+	id := c.Param("id")
+
+	report, err := h.service.GetTraceability(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTraceabilityHTML handles GET /api/v1/agencies/:id/traceability/html
+// Returns the traceability report as an HTML fragment for the designer
+func (h *AgencyHandler) GetTraceabilityHTML(c *gin.Context) {
+	id := c.Param("id")
+
+	report, err := h.service.GetTraceability(c.Request.Context(), id)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error loading traceability")
+		return
+	}
+
+	component := agency_designer.TraceabilityReport(report)
+	c.Header("Content-Type", "text/html")
+	component.Render(c.Request.Context(), c.Writer)
+}
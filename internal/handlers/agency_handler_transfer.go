@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CopyItems handles POST /api/v1/agencies/:id/items/copy
+// Copies selected goals and work items from another agency into this agency.
+// Set "adapt" to have the AI rewrite the copies for this agency's context.
+func (h *AgencyHandler) CopyItems(c *gin.Context) {
+	id := c.Param("id")
+
+	var req agency.TransferItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	if req.Adapt && h.itemAdapter == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI adaptation is not available"})
+		return
+	}
+
+	result, err := h.service.CopyItems(c.Request.Context(), id, req, h.itemAdapter)
+	if err != nil {
+		h.transferFailed(c, "copy", id, req, result, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"source_agency_id": req.SourceAgencyID,
+		"target_agency_id": id,
+		"goals":            len(result.Goals),
+		"work_items":       len(result.WorkItems),
+		"adapt":            req.Adapt,
+	}).Info("Copied items between agencies")
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// LinkItems handles POST /api/v1/agencies/:id/items/link
+// Creates read-only links in this agency to goals and work items of another agency.
+func (h *AgencyHandler) LinkItems(c *gin.Context) {
+	id := c.Param("id")
+
+	var req agency.TransferItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	result, err := h.service.LinkItems(c.Request.Context(), id, req)
+	if err != nil {
+		h.transferFailed(c, "link", id, req, result, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"source_agency_id": req.SourceAgencyID,
+		"target_agency_id": id,
+		"goals":            len(result.Goals),
+		"work_items":       len(result.WorkItems),
+	}).Info("Linked items between agencies")
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// transferFailed reports a failed copy/link, including any items created before the failure
+func (h *AgencyHandler) transferFailed(c *gin.Context, op string, id string, req agency.TransferItemsRequest, result *agency.TransferResult, err error) {
+	h.logger.WithError(err).WithFields(logrus.Fields{
+		"source_agency_id": req.SourceAgencyID,
+		"target_agency_id": id,
+	}).Errorf("Failed to %s items between agencies", op)
+
+	if result == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "partial": result})
+}
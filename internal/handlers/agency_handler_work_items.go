@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 
@@ -56,6 +58,9 @@ func (h *AgencyHandler) CreateWorkItem(c *gin.Context) {
 
 	workItem, err := h.service.CreateWorkItem(c.Request.Context(), id, req)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,6 +80,13 @@ func (h *AgencyHandler) UpdateWorkItem(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateWorkItem(c.Request.Context(), id, key, req); err != nil {
+		if errors.Is(err, agency.ErrReadOnlyLink) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -89,6 +101,70 @@ func (h *AgencyHandler) UpdateWorkItem(c *gin.Context) {
 	c.JSON(http.StatusOK, workItem)
 }
 
+// TransitionWorkItem handles PATCH /api/v1/agencies/:id/work-items/:key/status
+// Moves the work item to another Kanban status (todo, in_progress, blocked, done).
+func (h *AgencyHandler) TransitionWorkItem(c *gin.Context) {
+	id := c.Param("id")
+	key := c.Param("key")
+
+	var req agency.TransitionWorkItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if !req.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status %q", req.Status)})
+		return
+	}
+
+	workItem, err := h.service.TransitionWorkItem(c.Request.Context(), id, key, req)
+	if err != nil {
+		if errors.Is(err, agency.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workItem)
+}
+
+// AssignWorkItem handles PATCH /api/v1/agencies/:id/work-items/:key/assignee
+// Assigns the work item to an agent. An empty agent_id unassigns it.
+func (h *AgencyHandler) AssignWorkItem(c *gin.Context) {
+	id := c.Param("id")
+	key := c.Param("key")
+
+	var req agency.AssignWorkItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	workItem, err := h.service.AssignWorkItem(c.Request.Context(), id, key, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workItem)
+}
+
+// GetWorkload handles GET /api/v1/agencies/:id/workload
+// Summarizes the open work items assigned to each agent.
+func (h *AgencyHandler) GetWorkload(c *gin.Context) {
+	id := c.Param("id")
+
+	workload, err := h.service.GetWorkload(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, workload)
+}
+
 // DeleteWorkItem handles DELETE /api/v1/agencies/:id/work-items/:key
 func (h *AgencyHandler) DeleteWorkItem(c *gin.Context) {
 	id := c.Param("id")
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/changefeed"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AgentCommandHandler handles HTTP requests for agent lifecycle commands
+type AgentCommandHandler struct {
+	commander *runtime.Commander
+	runtime   *runtime.Manager
+	logger    *logrus.Logger
+}
+
+// NewAgentCommandHandler creates a new agent command handler
+func NewAgentCommandHandler(commander *runtime.Commander, runtime *runtime.Manager, logger *logrus.Logger) *AgentCommandHandler {
+	return &AgentCommandHandler{
+		commander: commander,
+		runtime:   runtime,
+		logger:    logger,
+	}
+}
+
+// AgentCommandRequest represents the request body for sending a lifecycle command
+type AgentCommandRequest struct {
+	Command runtime.Command `json:"command" binding:"required,oneof=stop restart drain"`
+	Reason  string          `json:"reason"`
+}
+
+// FleetCommandRequest represents the request body for sending a lifecycle
+// command to several agents, by ID or type
+type FleetCommandRequest struct {
+	AgentCommandRequest
+	AgentIDs  []string `json:"agent_ids"`
+	AgentType string   `json:"agent_type"`
+}
+
+// FleetCommandResponse reports the commands sent to a fleet of agents and the
+// agents a command could not be sent to
+type FleetCommandResponse struct {
+	Commands []*runtime.AgentCommand `json:"commands"`
+	Errors   map[string]string       `json:"errors,omitempty"`
+}
+
+// SendCommand godoc
+// @Summary Send a lifecycle command to an agent
+// @Description Stops, restarts or drains an agent (drained agents finish their current tasks, accept no new ones, then stop). The command is delivered to the agent as a command message, whose acknowledgement is tracked.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param command body AgentCommandRequest true "Command"
+// @Success 202 {object} runtime.AgentCommand
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /agents/{id}/commands [post]
+func (h *AgentCommandHandler) SendCommand(c *gin.Context) {
+	var req AgentCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cmd, err := h.commander.Issue(c.Request.Context(), c.Param("id"), req.Command, req.Reason, commandIssuer(c))
+	if err != nil {
+		h.writeIssueError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, cmd)
+}
+
+// SendFleetCommand godoc
+// @Summary Send a lifecycle command to several agents
+// @Description Sends a stop, restart or drain command to the listed agents, or to every agent of a type
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param command body FleetCommandRequest true "Command and target agents"
+// @Success 202 {object} FleetCommandResponse
+// @Failure 400 {object} map[string]string
+// @Router /agents/commands [post]
+func (h *AgentCommandHandler) SendFleetCommand(c *gin.Context) {
+	var req FleetCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.AgentIDs) == 0 && req.AgentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agent_ids or agent_type is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	agentIDs := req.AgentIDs
+	if len(agentIDs) == 0 {
+		for _, a := range h.runtime.ListAgents() {
+			if a.Type == req.AgentType && tenant.AllowsID(ctx, a.ID) {
+				agentIDs = append(agentIDs, a.ID)
+			}
+		}
+	}
+
+	response := FleetCommandResponse{Commands: []*runtime.AgentCommand{}, Errors: map[string]string{}}
+	for _, agentID := range agentIDs {
+		if !tenant.AllowsID(ctx, agentID) {
+			response.Errors[agentID] = agent.ErrAgentNotFound.Error()
+			continue
+		}
+		cmd, err := h.commander.Issue(ctx, agentID, req.Command, req.Reason, commandIssuer(c))
+		if err != nil {
+			response.Errors[agentID] = err.Error()
+			continue
+		}
+		response.Commands = append(response.Commands, cmd)
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ListCommands godoc
+// @Summary List an agent's lifecycle commands
+// @Description Lists the lifecycle commands sent to an agent, newest first, with their delivery and acknowledgement status
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {array} runtime.AgentCommand
+// @Router /agents/{id}/commands [get]
+func (h *AgentCommandHandler) ListCommands(c *gin.Context) {
+	c.JSON(http.StatusOK, h.commander.List(c.Request.Context(), c.Param("id")))
+}
+
+// GetCommand godoc
+// @Summary Get a lifecycle command
+// @Description Retrieves a lifecycle command with its delivery and acknowledgement status
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param commandId path string true "Command ID"
+// @Success 200 {object} runtime.AgentCommand
+// @Failure 404 {object} map[string]string
+// @Router /agents/{id}/commands/{commandId} [get]
+func (h *AgentCommandHandler) GetCommand(c *gin.Context) {
+	cmd, err := h.commander.Get(c.Request.Context(), c.Param("commandId"))
+	if err != nil || cmd.AgentID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+		return
+	}
+	c.JSON(http.StatusOK, cmd)
+}
+
+// AcknowledgeCommand godoc
+// @Summary Acknowledge a lifecycle command
+// @Description Records that the agent received a lifecycle command
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param commandId path string true "Command ID"
+// @Success 200 {object} runtime.AgentCommand
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /agents/{id}/commands/{commandId}/ack [post]
+func (h *AgentCommandHandler) AcknowledgeCommand(c *gin.Context) {
+	ctx := c.Request.Context()
+	commandID := c.Param("commandId")
+
+	if cmd, err := h.commander.Get(ctx, commandID); err != nil || cmd.AgentID != c.Param("id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "command not found"})
+		return
+	}
+
+	cmd, err := h.commander.Acknowledge(ctx, commandID)
+	if err != nil {
+		h.logger.WithError(err).WithField("command_id", commandID).Error("Failed to acknowledge command")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge command"})
+		return
+	}
+	c.JSON(http.StatusOK, cmd)
+}
+
+// writeIssueError maps an error issuing a command onto a response
+func (h *AgentCommandHandler) writeIssueError(c *gin.Context, err error) {
+	var violationErr *communication.GuardrailViolationError
+	switch {
+	case errors.Is(err, agent.ErrAgentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+	case errors.As(err, &violationErr):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "Command blocked by guardrail policy",
+			"violation": violationErr.Violation,
+		})
+	case otherTenant(c, err):
+	default:
+		h.logger.WithError(err).WithField("agent_id", c.Param("id")).Error("Failed to send lifecycle command")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send command"})
+	}
+}
+
+// commandIssuer identifies the operator sending a command: the user, else
+// the API key
+func commandIssuer(c *gin.Context) string {
+	ctx := c.Request.Context()
+	if actor := changefeed.ActorFromContext(ctx); actor.UserID != "" {
+		return actor.UserID
+	}
+	if key, ok := auth.KeyFromContext(ctx); ok {
+		return key.Name
+	}
+	return ""
+}
+
+// RegisterRoutes registers the agent command routes
+func (h *AgentCommandHandler) RegisterRoutes(router *gin.Engine) {
+	agents := router.Group("/api/v1/agents")
+	{
+		agents.POST("/commands", h.SendFleetCommand)
+		agents.POST("/:id/commands", h.SendCommand)
+		agents.GET("/:id/commands", h.ListCommands)
+		agents.GET("/:id/commands/:commandId", h.GetCommand)
+		agents.POST("/:id/commands/:commandId/ack", h.AcknowledgeCommand)
+	}
+}
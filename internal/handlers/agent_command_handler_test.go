@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandMessenger keeps command messages in memory
+type commandMessenger struct {
+	mu       sync.Mutex
+	messages map[string]*communication.Message
+}
+
+func (m *commandMessenger) SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := fmt.Sprintf("msg-%d", len(m.messages)+1)
+	m.messages[id] = &communication.Message{ID: id, ToAgentID: toAgentID, MessageType: msgType, Payload: payload, Status: communication.MessageStatusPending}
+	return id, nil
+}
+
+func (m *commandMessenger) GetMessage(ctx context.Context, messageID string) (*communication.Message, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *m.messages[messageID]
+	return &copied, nil
+}
+
+func (m *commandMessenger) AcknowledgeMessage(ctx context.Context, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.messages[messageID].AcknowledgedAt = &now
+	return nil
+}
+
+func TestAgentCommands(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	manager := runtime.NewManager(logger, runtime.ManagerConfig{}, nil)
+	defer manager.Shutdown()
+
+	router := gin.New()
+	NewAgentHandler(manager, logger).RegisterRoutes(router)
+	commander := runtime.NewCommander(manager, &commandMessenger{messages: map[string]*communication.Message{}}, logger)
+	NewAgentCommandHandler(commander, manager, logger).RegisterRoutes(router)
+
+	var pumps []*agent.Agent
+	for _, name := range []string{"pump-1", "pump-2"} {
+		a, err := manager.CreateAgent(name, "pump", agent.Config{})
+		require.NoError(t, err)
+		require.NoError(t, manager.StartAgent(a.ID))
+		pumps = append(pumps, a)
+	}
+	sensor, err := manager.CreateAgent("sensor-1", "sensor", agent.Config{})
+	require.NoError(t, err)
+	require.NoError(t, manager.StartAgent(sensor.ID))
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/v1/agents/"+sensor.ID+"/commands", `{"command":"stop","reason":"recalibration"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var cmd runtime.AgentCommand
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cmd))
+	assert.Equal(t, runtime.CommandStatusCompleted, cmd.Status)
+	assert.Equal(t, communication.MessageStatusPending, cmd.Delivery)
+	assert.Equal(t, agent.StateStopped, sensor.GetState())
+
+	w = send(http.MethodPost, "/api/v1/agents/"+sensor.ID+"/commands/"+cmd.ID+"/ack", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &cmd))
+	assert.True(t, cmd.Acknowledged)
+
+	// Commands are only found under their agent
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/api/v1/agents/"+pumps[0].ID+"/commands/"+cmd.ID, "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/api/v1/agents/"+sensor.ID+"/commands/"+cmd.ID, "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/api/v1/agents/"+sensor.ID+"/commands", `{"command":"explode"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/api/v1/agents/missing/commands", `{"command":"stop"}`).Code)
+
+	// Fleet-wide drain of every pump
+	w = send(http.MethodPost, "/api/v1/agents/commands", `{"command":"drain","agent_type":"pump"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var fleet FleetCommandResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fleet))
+	assert.Len(t, fleet.Commands, 2)
+	assert.Empty(t, fleet.Errors)
+	for _, pump := range pumps {
+		require.Eventually(t, func() bool { return pump.GetState() == agent.StateStopped }, 2*time.Second, 10*time.Millisecond)
+	}
+	assert.Equal(t, agent.StateStopped, sensor.GetState())
+
+	w = send(http.MethodGet, "/api/v1/agents/"+pumps[0].ID+"/commands", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var commands []runtime.AgentCommand
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &commands))
+	require.Len(t, commands, 1)
+	assert.Equal(t, runtime.CommandDrain, commands[0].Command)
+	assert.Equal(t, runtime.CommandStatusCompleted, commands[0].Status)
+}
@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
 	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -16,6 +21,7 @@ import (
 // AgentHandler handles HTTP requests for agent operations
 type AgentHandler struct {
 	runtime *runtime.Manager
+	scores  *health.HealthScoreService
 	logger  *logrus.Logger
 }
 
@@ -27,6 +33,12 @@ func NewAgentHandler(runtime *runtime.Manager, logger *logrus.Logger) *AgentHand
 	}
 }
 
+// SetHealthScores sets the service whose latest scores are included in
+// agent responses
+func (h *AgentHandler) SetHealthScores(scores *health.HealthScoreService) {
+	h.scores = scores
+}
+
 // CreateAgentRequest represents the request body for creating an agent
 type CreateAgentRequest struct {
 	Name   string       `json:"name" binding:"required"`
@@ -45,11 +57,27 @@ type AgentResponse struct {
 	UpdatedAt     time.Time         `json:"updated_at"`
 	LastHeartbeat time.Time         `json:"last_heartbeat"`
 	IsHealthy     bool              `json:"is_healthy"`
+	Status        registry.Status   `json:"status"`
+	StatusReason  string            `json:"status_reason,omitempty"`
+	LastSeen      time.Time         `json:"last_seen"`
+	Capabilities  []string          `json:"capabilities"`
+
+	// HealthScore and HealthFlags are from the agent's latest health score,
+	// absent until it has been scored
+	HealthScore *int                 `json:"health_score,omitempty"`
+	HealthFlags []health.AnomalyFlag `json:"health_flags,omitempty"`
 }
 
-// toAgentResponse converts an agent to a response model
-func toAgentResponse(a *agent.Agent) AgentResponse {
-	return AgentResponse{
+// toAgentResponse converts an agent, its liveness and its latest health score
+// to a response model
+func (h *AgentHandler) toAgentResponse(ctx context.Context, a *agent.Agent) AgentResponse {
+	liveness := h.runtime.Liveness().Get(a.ID)
+	capabilities := liveness.Capabilities
+	if len(capabilities) == 0 {
+		capabilities = a.Capabilities()
+	}
+
+	response := AgentResponse{
 		ID:            a.ID,
 		Name:          a.Name,
 		Type:          a.Type,
@@ -59,7 +87,22 @@ func toAgentResponse(a *agent.Agent) AgentResponse {
 		UpdatedAt:     a.UpdatedAt,
 		LastHeartbeat: a.LastHeartbeat,
 		IsHealthy:     a.IsHealthy(),
+		Status:        liveness.Status,
+		StatusReason:  liveness.Reason,
+		LastSeen:      liveness.LastSeen,
+		Capabilities:  capabilities,
 	}
+
+	if h.scores != nil {
+		score, err := h.scores.LatestScore(ctx, a.ID)
+		if err != nil {
+			h.logger.WithError(err).WithField("agent_id", a.ID).Warn("Failed to get health score")
+		} else if score != nil {
+			response.HealthScore = &score.Score
+			response.HealthFlags = score.Flags
+		}
+	}
+	return response
 }
 
 // CreateAgent godoc
@@ -94,13 +137,26 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 		req.Config.TaskTimeout = 5 * time.Minute
 	}
 
+	// Agents created for a tenant live in its namespace
+	if tenantID := tenant.FromContext(c.Request.Context()); tenantID != "" {
+		a := agent.New(req.Name, req.Type, req.Config)
+		a.ID = tenant.Qualify(tenantID, a.ID)
+		a.Metadata["tenant_id"] = tenantID
+		if err := h.runtime.RegisterAgent(a); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, h.toAgentResponse(c.Request.Context(), a))
+		return
+	}
+
 	a, err := h.runtime.CreateAgent(req.Name, req.Type, req.Config)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, toAgentResponse(a))
+	c.JSON(http.StatusCreated, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // StartAgent godoc
@@ -126,7 +182,7 @@ func (h *AgentHandler) StartAgent(c *gin.Context) {
 	}
 
 	a, _ := h.runtime.GetAgent(agentID)
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // StopAgent godoc
@@ -152,7 +208,7 @@ func (h *AgentHandler) StopAgent(c *gin.Context) {
 	}
 
 	a, _ := h.runtime.GetAgent(agentID)
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // PauseAgent godoc
@@ -179,7 +235,7 @@ func (h *AgentHandler) PauseAgent(c *gin.Context) {
 	}
 
 	a, _ := h.runtime.GetAgent(agentID)
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // ResumeAgent godoc
@@ -206,7 +262,7 @@ func (h *AgentHandler) ResumeAgent(c *gin.Context) {
 	}
 
 	a, _ := h.runtime.GetAgent(agentID)
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // RestartAgent godoc
@@ -232,7 +288,7 @@ func (h *AgentHandler) RestartAgent(c *gin.Context) {
 	}
 
 	a, _ := h.runtime.GetAgent(agentID)
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // GetAgent godoc
@@ -253,15 +309,17 @@ func (h *AgentHandler) GetAgent(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, toAgentResponse(a))
+	c.JSON(http.StatusOK, h.toAgentResponse(c.Request.Context(), a))
 }
 
 // ListAgents godoc
 // @Summary List all agents
-// @Description Retrieves a list of all agents
+// @Description Retrieves a list of all agents with their liveness status (online, degraded or offline), last heartbeat, capabilities and latest health score with its anomaly flags
 // @Tags agents
 // @Produce json
+// @Param status query string false "Only agents with this status: online, degraded or offline"
 // @Success 200 {array} AgentResponse
+// @Failure 400 {object} map[string]string
 // @Router /agents [get]
 func (h *AgentHandler) ListAgents(c *gin.Context) {
 	// Support optional pagination query params: page and limit
@@ -280,8 +338,23 @@ func (h *AgentHandler) ListAgents(c *gin.Context) {
 		}
 	}
 
+	status := registry.Status(c.Query("status"))
+	switch status {
+	case "", registry.StatusOnline, registry.StatusDegraded, registry.StatusOffline:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be online, degraded or offline"})
+		return
+	}
+
 	allAgents := h.runtime.ListAgents()
 
+	// Tenants only see agents in their namespace
+	ctx := c.Request.Context()
+	liveness := h.runtime.Liveness()
+	allAgents = slices.DeleteFunc(allAgents, func(a *agent.Agent) bool {
+		return !tenant.AllowsID(ctx, a.ID) || (status != "" && liveness.Get(a.ID).Status != status)
+	})
+
 	// Safety: ensure deterministic ordering before pagination
 	sort.Slice(allAgents, func(i, j int) bool { return allAgents[i].ID < allAgents[j].ID })
 
@@ -300,7 +373,7 @@ func (h *AgentHandler) ListAgents(c *gin.Context) {
 
 	response := make([]AgentResponse, 0, len(agents))
 	for _, a := range agents {
-		response = append(response, toAgentResponse(a))
+		response = append(response, h.toAgentResponse(c.Request.Context(), a))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -369,6 +442,91 @@ func (h *AgentHandler) SubmitTask(c *gin.Context) {
 	})
 }
 
+// RegisterAgentRequest represents the request body for registering a started agent
+type RegisterAgentRequest struct {
+	Capabilities []string `json:"capabilities"`
+}
+
+// RegisterAgent godoc
+// @Summary Register a started agent
+// @Description Records that an agent running outside the framework started, with its capabilities. The agent must then heartbeat periodically to stay online.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param registration body RegisterAgentRequest false "Agent capabilities"
+// @Success 200 {object} registry.AgentLiveness
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /agents/{id}/register [post]
+func (h *AgentHandler) RegisterAgent(c *gin.Context) {
+	agentID := c.Param("id")
+
+	var req RegisterAgentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	a, err := h.runtime.GetAgent(agentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "agent not found"})
+		return
+	}
+
+	capabilities := req.Capabilities
+	if len(capabilities) == 0 {
+		capabilities = a.Capabilities()
+	}
+	c.JSON(http.StatusOK, h.runtime.Liveness().Register(agentID, capabilities))
+}
+
+// HeartbeatRequest represents the request body of an agent heartbeat
+type HeartbeatRequest struct {
+	Status string `json:"status"` // online (default) or degraded
+	Reason string `json:"reason"`
+}
+
+// Heartbeat godoc
+// @Summary Record an agent heartbeat
+// @Description Records a heartbeat of a registered agent. Agents that are not registered, for example after a restart of the framework, get 404 and must register again.
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param heartbeat body HeartbeatRequest false "Reported status"
+// @Success 200 {object} registry.AgentLiveness
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /agents/{id}/heartbeat [post]
+func (h *AgentHandler) Heartbeat(c *gin.Context) {
+	agentID := c.Param("id")
+
+	var req HeartbeatRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Status != "" && req.Status != string(registry.StatusOnline) && req.Status != string(registry.StatusDegraded) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be online or degraded"})
+		return
+	}
+
+	liveness, err := h.runtime.Liveness().Heartbeat(agentID, registry.Heartbeat{
+		Degraded: req.Status == string(registry.StatusDegraded),
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, liveness)
+}
+
 // GetMetrics godoc
 // @Summary Get runtime metrics
 // @Description Retrieves runtime metrics for all agents
@@ -399,6 +557,8 @@ func (h *AgentHandler) RegisterRoutes(router *gin.Engine) {
 		agents.POST("/:id/resume", h.ResumeAgent)
 		agents.POST("/:id/restart", h.RestartAgent)
 		agents.POST("/:id/tasks", h.SubmitTask)
+		agents.POST("/:id/register", h.RegisterAgent)
+		agents.POST("/:id/heartbeat", h.Heartbeat)
 	}
 
 	metrics := router.Group("/api/v1/metrics")
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
 	"github.com/aosanya/CodeValdCortex/internal/runtime"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -42,6 +44,8 @@ func setupTestRouter() (*gin.Engine, *runtime.Manager) {
 			agents.POST("/:id/start", handler.StartAgent)
 			agents.POST("/:id/stop", handler.StopAgent)
 			agents.POST("/:id/tasks", handler.SubmitTask)
+			agents.POST("/:id/register", handler.RegisterAgent)
+			agents.POST("/:id/heartbeat", handler.Heartbeat)
 		}
 		v1.GET("/metrics", handler.GetMetrics)
 	}
@@ -292,3 +296,101 @@ func TestGetMetrics(t *testing.T) {
 	assert.NotNil(t, response["current_active_agents"])
 	assert.NotNil(t, response["current_running_tasks"])
 }
+
+func TestAgentLiveness(t *testing.T) {
+	router, manager := setupTestRouter()
+	defer manager.Shutdown()
+
+	config := agent.Config{MaxConcurrentTasks: 1, TaskQueueSize: 10}
+	remote, err := manager.CreateAgent("remote", "sensor", config)
+	require.NoError(t, err)
+	_, err = manager.CreateAgent("idle", "sensor", config)
+	require.NoError(t, err)
+
+	listByStatus := func(status string) []AgentResponse {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/agents?status="+status, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response []AgentResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Agents that never registered are offline and cannot heartbeat
+	assert.Len(t, listByStatus("offline"), 2)
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/agents/"+remote.ID+"/heartbeat", `{}`).Code)
+
+	w := post("/api/v1/agents/"+remote.ID+"/register", `{"capabilities":["pressure","flow"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	online := listByStatus("online")
+	require.Len(t, online, 1)
+	assert.Equal(t, remote.ID, online[0].ID)
+	assert.Equal(t, []string{"pressure", "flow"}, online[0].Capabilities)
+	assert.False(t, online[0].LastSeen.IsZero())
+
+	w = post("/api/v1/agents/"+remote.ID+"/heartbeat", `{"status":"degraded","reason":"battery low"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	degraded := listByStatus("degraded")
+	require.Len(t, degraded, 1)
+	assert.Equal(t, "battery low", degraded[0].StatusReason)
+
+	assert.Equal(t, http.StatusBadRequest, post("/api/v1/agents/"+remote.ID+"/heartbeat", `{"status":"offline"}`).Code)
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/agents/missing/register", `{}`).Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/agents?status=asleep", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAgentHealthScore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	manager := runtime.NewManager(logger, runtime.ManagerConfig{}, nil)
+	defer manager.Shutdown()
+	scores := health.NewHealthScoreService(health.NewInMemoryHealthScoreRepository(), nil, nil, manager, health.ScoreConfig{}, logger)
+	manager.OnTaskResult(scores.RecordTaskResult)
+
+	handler := NewAgentHandler(manager, logger)
+	handler.SetHealthScores(scores)
+	handler.RegisterRoutes(router)
+
+	a, err := manager.CreateAgent("pump", "pump", agent.Config{MaxConcurrentTasks: 1, TaskQueueSize: 10})
+	require.NoError(t, err)
+
+	getAgent := func() AgentResponse {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/agents/"+a.ID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response AgentResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Unscored agents have no health score
+	assert.Nil(t, getAgent().HealthScore)
+
+	for i := 0; i < 5; i++ {
+		scores.RecordTaskResult(&agent.TaskResult{AgentID: a.ID, Success: false, CompletedAt: time.Now().UTC()})
+	}
+	_, err = scores.ScoreAgent(context.Background(), a.ID)
+	require.NoError(t, err)
+
+	response := getAgent()
+	require.NotNil(t, response.HealthScore)
+	assert.Equal(t, 70, *response.HealthScore)
+	assert.Equal(t, []health.AnomalyFlag{health.FlagTaskFailures}, response.HealthFlags)
+}
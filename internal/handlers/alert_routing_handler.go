@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/alertrouting"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AlertRoutingHandler handles HTTP requests for alert routing policies
+type AlertRoutingHandler struct {
+	router *alertrouting.Service
+	logger *logrus.Logger
+}
+
+// NewAlertRoutingHandler creates a new alert routing handler
+func NewAlertRoutingHandler(router *alertrouting.Service, logger *logrus.Logger) *AlertRoutingHandler {
+	return &AlertRoutingHandler{
+		router: router,
+		logger: logger,
+	}
+}
+
+// EvaluateAlertRequest is a hypothetical alert to evaluate
+type EvaluateAlertRequest struct {
+	Severity  string `json:"severity"`
+	Zone      string `json:"zone"`
+	AlertType string `json:"alert_type" binding:"required"`
+
+	// At evaluates schedules at this time instead of now
+	At *time.Time `json:"at,omitempty"`
+}
+
+// ListPolicies godoc
+// @Summary List alert routing policies
+// @Description Lists the routing policies in evaluation order and the default destinations
+// @Tags alerts
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/alert-routing/policies [get]
+func (h *AlertRoutingHandler) ListPolicies(c *gin.Context) {
+	policies := h.router.Policies()
+	c.JSON(http.StatusOK, gin.H{
+		"policies":             policies.List(),
+		"default_destinations": policies.Defaults(),
+	})
+}
+
+// EvaluateAlert godoc
+// @Summary Evaluate where an alert would be routed
+// @Description Shows the matching policies, active schedules and destinations for a hypothetical alert without delivering it
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Param alert body EvaluateAlertRequest true "Hypothetical alert"
+// @Success 200 {object} alertrouting.Decision
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/alert-routing/evaluate [post]
+func (h *AlertRoutingHandler) EvaluateAlert(c *gin.Context) {
+	var req EvaluateAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	alert := &alertrouting.Alert{
+		Severity:  strings.ToUpper(req.Severity),
+		Zone:      req.Zone,
+		AlertType: req.AlertType,
+		Timestamp: at,
+	}
+
+	c.JSON(http.StatusOK, h.router.Evaluate(alert, at))
+}
+
+// RegisterRoutes registers the alert routing routes
+func (h *AlertRoutingHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/alert-routing/policies", h.ListPolicies)
+	router.POST("/api/v1/alert-routing/evaluate", h.EvaluateAlert)
+}
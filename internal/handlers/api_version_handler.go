@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APIVersionHandler serves the API version and deprecation registry
+type APIVersionHandler struct {
+	registry *apiversion.Registry
+	logger   *logrus.Logger
+}
+
+// NewAPIVersionHandler creates a new API version handler
+func NewAPIVersionHandler(registry *apiversion.Registry, logger *logrus.Logger) *APIVersionHandler {
+	return &APIVersionHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// ListDeprecations godoc
+// @Summary List API versions and deprecations
+// @Description Lists the supported API versions and every deprecated version, route and renamed field with its removal timeline
+// @Tags api
+// @Produce json
+// @Success 200 {object} apiversion.Listing
+// @Router /api/v1/deprecations [get]
+func (h *APIVersionHandler) ListDeprecations(c *gin.Context) {
+	c.JSON(http.StatusOK, h.registry.List())
+}
+
+// RegisterRoutes registers the API version routes. Later versions are served
+// by the v1 route through the version fallback.
+func (h *APIVersionHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET(apiversion.V1.Prefix()+"/deprecations", h.ListDeprecations)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler serves the audit log of mutating API operations
+type AuditHandler struct {
+	log    *audit.Log
+	logger *logrus.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(log *audit.Log, logger *logrus.Logger) *AuditHandler {
+	return &AuditHandler{
+		log:    log,
+		logger: logger,
+	}
+}
+
+// ListAuditRecords godoc
+// @Summary Query the API audit log
+// @Description Returns recorded POST, PUT, PATCH and DELETE requests, newest first. Records can be filtered by method, path_prefix, key_id, user_id, tenant, min_status and an RFC3339 since/until range.
+// @Tags audit
+// @Produce json
+// @Param method query string false "HTTP method"
+// @Param path_prefix query string false "Start of the request path"
+// @Param key_id query string false "API key ID"
+// @Param user_id query string false "User ID"
+// @Param tenant query string false "Tenant"
+// @Param min_status query int false "Lowest response status, e.g. 400 for failures"
+// @Param since query string false "RFC3339 start time"
+// @Param until query string false "RFC3339 end time"
+// @Param limit query int false "Maximum records (default 100, at most 1000)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/audit [get]
+func (h *AuditHandler) ListAuditRecords(c *gin.Context) {
+	query := audit.Query{
+		Method:     strings.ToUpper(c.Query("method")),
+		PathPrefix: c.Query("path_prefix"),
+		KeyID:      c.Query("key_id"),
+		UserID:     c.Query("user_id"),
+		Tenant:     c.Query("tenant"),
+	}
+	var err error
+	if raw := c.Query("min_status"); raw != "" {
+		if query.MinStatus, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min_status"})
+			return
+		}
+	}
+	if raw := c.Query("since"); raw != "" {
+		if query.Since, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since: use RFC3339"})
+			return
+		}
+	}
+	if raw := c.Query("until"); raw != "" {
+		if query.Until, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid until: use RFC3339"})
+			return
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	records, err := h.log.Query(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to query audit log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"count":   len(records),
+	})
+}
+
+// RegisterRoutes registers audit log routes
+func (h *AuditHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/audit", h.ListAuditRecords)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthHandler manages API keys and web UI sessions
+type AuthHandler struct {
+	service *auth.Service
+	logger  *logrus.Logger
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(service *auth.Service, logger *logrus.Logger) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Returns keys defined in configuration and created through the API, without their secrets
+// @Tags auth
+// @Produce json
+// @Success 200 {array} auth.APIKey
+// @Router /api/v1/auth/keys [get]
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListKeys(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list API keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a key with the given scopes (read, communications, unmask, guardrail-override, designer-admin). The secret is only returned in this response.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body auth.CreateKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/auth/keys [post]
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req auth.CreateKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, secret, err := h.service.CreateKey(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidKeyRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to create API key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":    key,
+		"secret": secret,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revokes a key created through the API; keys defined in configuration cannot be revoked
+// @Tags auth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} auth.APIKey
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/auth/keys/{id} [delete]
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	key, err := h.service.RevokeKey(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrKeyNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, auth.ErrStaticKey):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.WithError(err).Error("Failed to revoke API key")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// WhoAmI godoc
+// @Summary Describe the authenticated API key
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/auth/whoami [get]
+func (h *AuthHandler) WhoAmI(c *gin.Context) {
+	key, ok := auth.KeyFromContext(c.Request.Context())
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"authenticated": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authenticated": true,
+		"key":           key,
+	})
+}
+
+// CreateSession godoc
+// @Summary Sign in the web UI
+// @Description Checks an API key and stores it in an HTTP-only session cookie, so the web UI can call the API
+// @Tags auth
+// @Accept json
+// @Param request body map[string]string true "The API key as api_key"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/auth/session [post]
+func (h *AuthHandler) CreateSession(c *gin.Context) {
+	var req struct {
+		APIKey string `json:"api_key" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.service.Authenticate(c.Request.Context(), req.APIKey); err != nil {
+		if errors.Is(err, auth.ErrInvalidKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to authenticate API key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		return
+	}
+
+	h.setSessionCookie(c, req.APIKey, 0)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteSession godoc
+// @Summary Sign out the web UI
+// @Tags auth
+// @Success 204
+// @Router /api/v1/auth/session [delete]
+func (h *AuthHandler) DeleteSession(c *gin.Context) {
+	h.setSessionCookie(c, "", -1)
+	c.Status(http.StatusNoContent)
+}
+
+// setSessionCookie sets the session cookie, which lasts for the browser
+// session when maxAge is 0 and is removed when it is negative
+func (h *AuthHandler) setSessionCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(auth.SessionCookie, value, maxAge, "/", "", c.Request.TLS != nil, true)
+}
+
+// RegisterRoutes registers API key and session routes
+func (h *AuthHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/auth/keys", h.ListAPIKeys)
+	router.POST("/api/v1/auth/keys", h.CreateAPIKey)
+	router.DELETE("/api/v1/auth/keys/:id", h.RevokeAPIKey)
+	router.GET("/api/v1/auth/whoami", h.WhoAmI)
+	router.POST("/api/v1/auth/session", h.CreateSession)
+	router.DELETE("/api/v1/auth/session", h.DeleteSession)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/bootstrap"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// BootstrapHandler handles HTTP requests for environment bootstrap
+type BootstrapHandler struct {
+	service *bootstrap.Service
+	logger  *logrus.Logger
+}
+
+// NewBootstrapHandler creates a new bootstrap handler
+func NewBootstrapHandler(service *bootstrap.Service, logger *logrus.Logger) *BootstrapHandler {
+	return &BootstrapHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Bootstrap godoc
+// @Summary Idempotently provision agents, topics and subscriptions
+// @Description Ensures every declared resource exists (create-if-missing, update-if-changed) and reports the action taken for each
+// @Tags bootstrap
+// @Accept json
+// @Produce json
+// @Param manifest body bootstrap.Manifest true "Environment manifest"
+// @Success 200 {object} bootstrap.Result
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/bootstrap [post]
+func (h *BootstrapHandler) Bootstrap(c *gin.Context) {
+	var manifest bootstrap.Manifest
+	if err := c.ShouldBindJSON(&manifest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.Apply(c.Request.Context(), &manifest)
+	if err != nil {
+		if errors.Is(err, bootstrap.ErrInvalidManifest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to apply bootstrap manifest")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bootstrap manifest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RegisterRoutes registers the bootstrap routes
+func (h *BootstrapHandler) RegisterRoutes(router *gin.Engine) {
+	router.POST("/api/v1/bootstrap", h.Bootstrap)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/capability"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CapabilityHandler handles HTTP requests for the capability catalog and
+// agency capability gap analysis
+type CapabilityHandler struct {
+	capabilities *capability.Service
+	logger       *logrus.Logger
+}
+
+// NewCapabilityHandler creates a new capability handler
+func NewCapabilityHandler(capabilities *capability.Service, logger *logrus.Logger) *CapabilityHandler {
+	return &CapabilityHandler{
+		capabilities: capabilities,
+		logger:       logger,
+	}
+}
+
+// ListCapabilities godoc
+// @Summary List capabilities
+// @Description Returns registered capabilities and those declared by agent types and templates
+// @Tags capabilities
+// @Produce json
+// @Success 200 {array} capability.CatalogEntry
+// @Router /api/v1/capabilities [get]
+func (h *CapabilityHandler) ListCapabilities(c *gin.Context) {
+	catalog, err := h.capabilities.Catalog(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list capabilities")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list capabilities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
+
+// GetCapability godoc
+// @Summary Get a capability
+// @Tags capabilities
+// @Produce json
+// @Param id path string true "Capability ID"
+// @Success 200 {object} capability.Capability
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/capabilities/{id} [get]
+func (h *CapabilityHandler) GetCapability(c *gin.Context) {
+	result, err := h.capabilities.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get capability")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateCapability godoc
+// @Summary Register a capability
+// @Tags capabilities
+// @Accept json
+// @Produce json
+// @Param capability body capability.Capability true "Capability"
+// @Success 201 {object} capability.Capability
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/capabilities [post]
+func (h *CapabilityHandler) CreateCapability(c *gin.Context) {
+	var req capability.Capability
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.capabilities.Register(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to register capability")
+		return
+	}
+
+	c.JSON(http.StatusCreated, req)
+}
+
+// UpdateCapability godoc
+// @Summary Update a capability
+// @Tags capabilities
+// @Accept json
+// @Produce json
+// @Param id path string true "Capability ID"
+// @Param capability body capability.Capability true "Capability"
+// @Success 200 {object} capability.Capability
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/capabilities/{id} [put]
+func (h *CapabilityHandler) UpdateCapability(c *gin.Context) {
+	var req capability.Capability
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ID = c.Param("id")
+
+	if err := h.capabilities.Update(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to update capability")
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// DeleteCapability godoc
+// @Summary Delete a capability
+// @Tags capabilities
+// @Param id path string true "Capability ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/capabilities/{id} [delete]
+func (h *CapabilityHandler) DeleteCapability(c *gin.Context) {
+	if err := h.capabilities.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to delete capability")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCapabilityGaps godoc
+// @Summary Analyze an agency's capability gaps
+// @Description Lists work items and workflow tasks whose required capabilities no deployed agent of the agency provides
+// @Tags capabilities
+// @Produce json
+// @Param id path string true "Agency ID"
+// @Success 200 {object} capability.GapAnalysis
+// @Router /api/v1/agencies/{id}/capabilities/gaps [get]
+func (h *CapabilityHandler) GetCapabilityGaps(c *gin.Context) {
+	analysis, err := h.capabilities.Analyze(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.logger.WithError(err).WithField("agency_id", c.Param("id")).Error("Failed to analyze capability gaps")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to analyze capability gaps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+func (h *CapabilityHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, capability.ErrInvalidCapability):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, capability.ErrCapabilityNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, capability.ErrCapabilityExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers capability routes
+func (h *CapabilityHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/capabilities", h.ListCapabilities)
+	router.POST("/api/v1/capabilities", h.CreateCapability)
+	router.GET("/api/v1/capabilities/:id", h.GetCapability)
+	router.PUT("/api/v1/capabilities/:id", h.UpdateCapability)
+	router.DELETE("/api/v1/capabilities/:id", h.DeleteCapability)
+	router.GET("/api/v1/agencies/:id/capabilities/gaps", h.GetCapabilityGaps)
+}
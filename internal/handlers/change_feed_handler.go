@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/changefeed"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// changeStreamRetryMillis is how long browsers wait before reconnecting
+const changeStreamRetryMillis = 3000
+
+// ChangeFeedHandler streams agency design changes to designer sessions
+type ChangeFeedHandler struct {
+	feed   *changefeed.Feed
+	logger *logrus.Logger
+}
+
+// NewChangeFeedHandler creates a new change feed handler
+func NewChangeFeedHandler(feed *changefeed.Feed, logger *logrus.Logger) *ChangeFeedHandler {
+	return &ChangeFeedHandler{
+		feed:   feed,
+		logger: logger,
+	}
+}
+
+// StreamChanges godoc
+// @Summary Stream agency changes
+// @Description Server-sent events stream of goal, work item, overview and designer conversation changes of an agency. Each "change" event has the change's sequence as its id; reconnecting with Last-Event-ID (or cursor) replays missed changes. A "reset" event means changes were missed and the client must reload.
+// @Tags agencies
+// @Produce text/event-stream
+// @Param id path string true "Agency ID"
+// @Param cursor query int false "Sequence of the last change seen (Last-Event-ID takes precedence)"
+// @Success 200 {object} changefeed.Change
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/agencies/{id}/changes/stream [get]
+func (h *ChangeFeedHandler) StreamChanges(c *gin.Context) {
+	agencyID := c.Param("id")
+	cursor := c.GetHeader("Last-Event-ID")
+	if cursor == "" {
+		cursor = c.Query("cursor")
+	}
+	after, ok := parseChangeCursor(c, cursor)
+	if !ok {
+		return
+	}
+
+	sub := h.feed.Subscribe(agencyID, after)
+	defer sub.Close()
+
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Debug("Could not clear write deadline for change stream")
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", changeStreamRetryMillis)
+
+	if sub.Reset {
+		h.writeReset(c, "history")
+	} else {
+		for _, change := range sub.Backlog {
+			h.writeChange(c, change)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.feed.Heartbeat())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change, open := <-sub.C:
+			if !open {
+				if sub.Lagged() {
+					h.writeReset(c, "lagged")
+					c.Writer.Flush()
+				}
+				return
+			}
+			h.writeChange(c, change)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeChange writes a change event
+func (h *ChangeFeedHandler) writeChange(c *gin.Context, change changefeed.Change) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to encode change")
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\nevent: change\ndata: %s\n\n", change.Seq, data)
+}
+
+// writeReset tells the client to reload. Its id is the latest sequence so
+// that the browser's next reconnect resumes from there.
+func (h *ChangeFeedHandler) writeReset(c *gin.Context, reason string) {
+	seq := h.feed.Seq()
+	data, _ := json.Marshal(gin.H{"reason": reason, "seq": seq})
+	fmt.Fprintf(c.Writer, "id: %d\nevent: reset\ndata: %s\n\n", seq, data)
+}
+
+// ListChanges godoc
+// @Summary List agency changes
+// @Description Returns an agency's changes after a cursor for clients that poll instead of streaming. reset is true when changes after the cursor are no longer kept.
+// @Tags agencies
+// @Produce json
+// @Param id path string true "Agency ID"
+// @Param after query int false "Sequence of the last change seen"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/agencies/{id}/changes [get]
+func (h *ChangeFeedHandler) ListChanges(c *gin.Context) {
+	after, ok := parseChangeCursor(c, c.Query("after"))
+	if !ok {
+		return
+	}
+
+	changes, reset := h.feed.Since(c.Param("id"), after)
+	if changes == nil {
+		changes = []changefeed.Change{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"changes": changes,
+		"reset":   reset,
+		"seq":     h.feed.Seq(),
+	})
+}
+
+// parseChangeCursor reads a change sequence, responding with 400 when it is
+// invalid
+func parseChangeCursor(c *gin.Context, value string) (uint64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	after, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor must be a change sequence number"})
+		return 0, false
+	}
+	return after, true
+}
+
+// RegisterRoutes registers change feed routes
+func (h *ChangeFeedHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/agencies/:id/changes", h.ListChanges)
+	router.GET("/api/v1/agencies/:id/changes/stream", h.StreamChanges)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/gin-gonic/gin"
+)
+
+// ViolationDecisionRequest represents an operator decision on a blocked command
+type ViolationDecisionRequest struct {
+	DecidedBy string `json:"decided_by" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+}
+
+// ListGuardrailPolicies godoc
+// @Summary List guardrail policies
+// @Description Returns the policies commands are checked against before delivery
+// @Tags communication
+// @Produce json
+// @Success 200 {array} communication.GuardrailPolicy
+// @Router /api/v1/communications/guardrails/policies [get]
+func (h *CommunicationHandler) ListGuardrailPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, h.messageService.GuardrailPolicies())
+}
+
+// ListGuardrailViolations godoc
+// @Summary List guardrail violations
+// @Description Lists blocked commands, newest first, optionally filtered by sender agent and status
+// @Tags communication
+// @Produce json
+// @Param agent_id query string false "Sender agent ID"
+// @Param status query string false "blocked, overridden or dismissed"
+// @Param limit query int false "Maximum number of violations" default(100)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/guardrails/violations [get]
+func (h *CommunicationHandler) ListGuardrailViolations(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	violations := h.messageService.ListViolations(communication.ViolationFilter{
+		AgentID: c.Query("agent_id"),
+		Status:  communication.ViolationStatus(c.Query("status")),
+		Limit:   limit,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"violations": h.maskViolations(c, violations...),
+		"count":      len(violations),
+	})
+}
+
+// GetGuardrailViolation godoc
+// @Summary Get a guardrail violation
+// @Tags communication
+// @Produce json
+// @Param id path string true "Violation ID"
+// @Success 200 {object} communication.GuardrailViolation
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/guardrails/violations/{id} [get]
+func (h *CommunicationHandler) GetGuardrailViolation(c *gin.Context) {
+	violation, err := h.messageService.GetViolation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.maskViolations(c, violation)[0])
+}
+
+// OverrideGuardrailViolation godoc
+// @Summary Override a guardrail violation
+// @Description Delivers a blocked command after an operator approves it
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param id path string true "Violation ID"
+// @Param decision body ViolationDecisionRequest true "Approver and reason"
+// @Success 200 {object} communication.GuardrailViolation
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/communications/guardrails/violations/{id}/override [post]
+func (h *CommunicationHandler) OverrideGuardrailViolation(c *gin.Context) {
+	var req ViolationDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	violation, err := h.messageService.OverrideViolation(c.Request.Context(), c.Param("id"), req.DecidedBy, req.Reason)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to override guardrail violation")
+		c.JSON(violationDecisionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logger.WithField("violation_id", violation.ID).WithField("approved_by", req.DecidedBy).Warn("Guardrail violation overridden")
+	c.JSON(http.StatusOK, h.maskViolations(c, violation)[0])
+}
+
+// DismissGuardrailViolation godoc
+// @Summary Dismiss a guardrail violation
+// @Description Confirms a blocked command should not be delivered
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param id path string true "Violation ID"
+// @Param decision body ViolationDecisionRequest true "Reviewer and reason"
+// @Success 200 {object} communication.GuardrailViolation
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/communications/guardrails/violations/{id}/dismiss [post]
+func (h *CommunicationHandler) DismissGuardrailViolation(c *gin.Context) {
+	var req ViolationDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	violation, err := h.messageService.DismissViolation(c.Param("id"), req.DecidedBy, req.Reason)
+	if err != nil {
+		c.JSON(violationDecisionStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.maskViolations(c, violation)[0])
+}
+
+// violationDecisionStatus maps override and dismissal errors to HTTP status codes
+func violationDecisionStatus(err error) int {
+	switch {
+	case errors.Is(err, communication.ErrViolationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, communication.ErrViolationResolved):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -1,17 +1,35 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// otherTenant reports a request naming another tenant's agents, messages or
+// subscriptions as not found, so tenants cannot probe for them
+func otherTenant(c *gin.Context, err error) bool {
+	if !errors.Is(err, tenant.ErrForbidden) {
+		return false
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	return true
+}
+
 // CommunicationHandler handles HTTP requests for communication operations
 type CommunicationHandler struct {
 	messageService *communication.MessageService
 	pubSubService  *communication.PubSubService
+	expiry         *communication.ExpirySweeper
 	logger         *logrus.Logger
 }
 
@@ -24,6 +42,11 @@ func NewCommunicationHandler(messageService *communication.MessageService, pubSu
 	}
 }
 
+// SetExpirySweeper sets the sweeper whose counts are reported at /expiry
+func (h *CommunicationHandler) SetExpirySweeper(expiry *communication.ExpirySweeper) {
+	h.expiry = expiry
+}
+
 // SendMessageRequest represents the request body for sending a message
 type SendMessageRequest struct {
 	FromAgentID   string                 `json:"from_agent_id" binding:"required"`
@@ -37,6 +60,19 @@ type SendMessageRequest struct {
 	Metadata      map[string]string      `json:"metadata"`
 }
 
+// Reply timeouts of request/reply messages
+const (
+	defaultReplyTimeoutSeconds = 30
+	maxReplyTimeoutSeconds     = 300
+)
+
+// RequestMessageRequest represents the request body for sending a message and
+// waiting for its reply
+type RequestMessageRequest struct {
+	SendMessageRequest
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
 // PublishMessageRequest represents the request body for publishing a message
 type PublishMessageRequest struct {
 	PublisherAgentID   string                 `json:"publisher_agent_id" binding:"required"`
@@ -48,6 +84,36 @@ type PublishMessageRequest struct {
 	Metadata           map[string]string      `json:"metadata"`
 }
 
+// SubscriptionRequest represents the request body for creating or updating a subscription
+type SubscriptionRequest struct {
+	SubscriberAgentID   string                 `json:"subscriber_agent_id"`
+	SubscriberAgentType string                 `json:"subscriber_agent_type"`
+	EventPattern        string                 `json:"event_pattern"`
+	DeliveryMode        string                 `json:"delivery_mode"`
+	FilterExpression    string                 `json:"filter_expression"`
+	FilterConditions    map[string]interface{} `json:"filter_conditions"`
+	PublisherAgentID    *string                `json:"publisher_agent_id"`
+	PublisherAgentType  *string                `json:"publisher_agent_type"`
+	PublicationTypes    []string               `json:"publication_types"`
+	Metadata            map[string]string      `json:"metadata"`
+}
+
+// filters converts the request into subscription filters
+func (r *SubscriptionRequest) filters() *communication.SubscriptionFilters {
+	filters := &communication.SubscriptionFilters{
+		PublisherID:   r.PublisherAgentID,
+		PublisherType: r.PublisherAgentType,
+		Conditions:    r.FilterConditions,
+		Expression:    r.FilterExpression,
+		DeliveryMode:  communication.DeliveryMode(r.DeliveryMode),
+		Metadata:      r.Metadata,
+	}
+	for _, t := range r.PublicationTypes {
+		filters.Types = append(filters.Types, communication.PublicationType(t))
+	}
+	return filters
+}
+
 // SendMessage godoc
 // @Summary Send a direct message between agents
 // @Description Sends a direct message from one agent to another
@@ -57,6 +123,7 @@ type PublishMessageRequest struct {
 // @Param message body SendMessageRequest true "Message details"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/communications/messages [post]
 func (h *CommunicationHandler) SendMessage(c *gin.Context) {
@@ -79,6 +146,17 @@ func (h *CommunicationHandler) SendMessage(c *gin.Context) {
 	ctx := c.Request.Context()
 	msgType := communication.MessageType(req.MessageType)
 	messageID, err := h.messageService.SendMessage(ctx, req.FromAgentID, req.ToAgentID, msgType, req.Payload, opts)
+	var violationErr *communication.GuardrailViolationError
+	if errors.As(err, &violationErr) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "Command blocked by guardrail policy",
+			"violation": violationErr.Violation,
+		})
+		return
+	}
+	if otherTenant(c, err) {
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to send message")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
@@ -91,6 +169,80 @@ func (h *CommunicationHandler) SendMessage(c *gin.Context) {
 	})
 }
 
+// RequestMessage godoc
+// @Summary Send a message and wait for its reply
+// @Description Sends a direct message and blocks until a message with the same correlation ID is sent to reply_to (the sender by default), or timeout_seconds (default 30, at most 300) passes. A correlation ID is generated unless one is given. The reply is marked delivered.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param message body RequestMessageRequest true "Request message details"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]interface{}
+// @Failure 504 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/messages/request [post]
+func (h *CommunicationHandler) RequestMessage(c *gin.Context) {
+	var req RequestMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = defaultReplyTimeoutSeconds
+	}
+	if req.TimeoutSeconds < 0 || req.TimeoutSeconds > maxReplyTimeoutSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("timeout_seconds must be between 1 and %d", maxReplyTimeoutSeconds)})
+		return
+	}
+	if req.ReplyTo == req.ToAgentID || (req.ReplyTo == "" && req.FromAgentID == req.ToAgentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reply_to must differ from to_agent_id"})
+		return
+	}
+
+	opts := &communication.MessageOptions{
+		Priority:      req.Priority,
+		CorrelationID: req.CorrelationID,
+		ReplyTo:       req.ReplyTo,
+		TTL:           req.TTL,
+		Metadata:      req.Metadata,
+	}
+
+	// Long waits outlive the server's write timeout
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second)); err != nil {
+		h.logger.WithError(err).Debug("Could not extend write deadline for request message")
+	}
+
+	msgType := communication.MessageType(req.MessageType)
+	reply, err := h.messageService.SendAndWait(c.Request.Context(), req.FromAgentID, req.ToAgentID, msgType, req.Payload, opts, timeout)
+	var violationErr *communication.GuardrailViolationError
+	var timeoutErr *communication.ReplyTimeoutError
+	switch {
+	case errors.As(err, &violationErr):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":     "Command blocked by guardrail policy",
+			"violation": violationErr.Violation,
+		})
+	case errors.Is(err, tenant.ErrForbidden):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+	case errors.As(err, &timeoutErr):
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":          "No reply before the timeout",
+			"request_id":     timeoutErr.RequestID,
+			"correlation_id": timeoutErr.CorrelationID,
+		})
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to send request message")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send request message"})
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"correlation_id": reply.CorrelationID,
+			"reply":          h.maskMessage(c, reply),
+		})
+	}
+}
+
 // PublishMessage godoc
 // @Summary Publish a message to a topic
 // @Description Publishes an event or status update that subscribers can receive
@@ -128,6 +280,9 @@ func (h *CommunicationHandler) PublishMessage(c *gin.Context) {
 	// Publish event
 	ctx := c.Request.Context()
 	pubID, err := h.pubSubService.Publish(ctx, req.PublisherAgentID, agentType, req.EventName, req.Payload, opts)
+	if otherTenant(c, err) {
+		return
+	}
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to publish message")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish message"})
@@ -140,14 +295,480 @@ func (h *CommunicationHandler) PublishMessage(c *gin.Context) {
 	})
 }
 
+// PublishBatch godoc
+// @Summary Publish a batch of messages
+// @Description Publishes an array of messages atomically: either every message is stored and delivered or none is. Responds with the status of each message in request order; when a message is invalid the others are reported as rejected.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param publications body []PublishMessageRequest true "Publications"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/communications/publish/batch [post]
+func (h *CommunicationHandler) PublishBatch(c *gin.Context) {
+	// Messages are validated by the service so each one gets its own status
+	var reqs []PublishMessageRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(reqs) == 0 || len(reqs) > communication.MaxPublishBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch must have between 1 and %d messages", communication.MaxPublishBatch)})
+		return
+	}
+
+	batch := make([]communication.BatchPublication, len(reqs))
+	for i, req := range reqs {
+		opts := &communication.PublicationOptions{
+			TTLSeconds: req.TTLSeconds,
+			Metadata:   req.Metadata,
+		}
+		if req.PublicationType != "" {
+			opts.Type = communication.PublicationType(req.PublicationType)
+		}
+
+		agentType := req.PublisherAgentType
+		if agentType == "" {
+			agentType = "unknown"
+		}
+
+		batch[i] = communication.BatchPublication{
+			PublisherAgentID:   req.PublisherAgentID,
+			PublisherAgentType: agentType,
+			EventName:          req.EventName,
+			Payload:            req.Payload,
+			Options:            opts,
+		}
+	}
+
+	results, err := h.pubSubService.PublishBatch(c.Request.Context(), batch)
+	if err != nil {
+		// Without invalid messages the batch failed to store
+		status := http.StatusInternalServerError
+		for _, result := range results {
+			if result.Status == communication.BatchStatusInvalid {
+				status = http.StatusUnprocessableEntity
+				break
+			}
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "results": results, "published": 0})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"published": len(results),
+	})
+}
+
+// CreateSubscription godoc
+// @Summary Register a topic subscription
+// @Description Creates a subscription with a topic pattern, delivery mode (push/pull) and optional payload filter expression
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param subscription body SubscriptionRequest true "Subscription details"
+// @Success 201 {object} communication.Subscription
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/subscriptions [post]
+func (h *CommunicationHandler) CreateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agentType := req.SubscriberAgentType
+	if agentType == "" {
+		agentType = "unknown"
+	}
+
+	ctx := c.Request.Context()
+	subID, err := h.pubSubService.Subscribe(ctx, req.SubscriberAgentID, agentType, req.EventPattern, req.filters())
+	if otherTenant(c, err) {
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to create subscription")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.pubSubService.GetSubscription(ctx, subID)
+	if err != nil {
+		c.JSON(http.StatusCreated, gin.H{"subscription_id": subID})
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// ListSubscriptions godoc
+// @Summary List an agent's subscriptions
+// @Description Lists the active subscriptions registered by an agent
+// @Tags communication
+// @Produce json
+// @Param agent_id query string true "Subscriber agent ID"
+// @Success 200 {array} communication.Subscription
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/subscriptions [get]
+func (h *CommunicationHandler) ListSubscriptions(c *gin.Context) {
+	agentID := c.Query("agent_id")
+	if agentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agent_id query parameter is required"})
+		return
+	}
+
+	subs, err := h.pubSubService.GetActiveSubscriptions(c.Request.Context(), agentID)
+	if otherTenant(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list subscriptions"})
+		return
+	}
+	if subs == nil {
+		subs = []*communication.Subscription{}
+	}
+
+	c.JSON(http.StatusOK, subs)
+}
+
+// GetSubscription godoc
+// @Summary Get a subscription
+// @Tags communication
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} communication.Subscription
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/subscriptions/{id} [get]
+func (h *CommunicationHandler) GetSubscription(c *gin.Context) {
+	sub, err := h.pubSubService.GetSubscription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateSubscription godoc
+// @Summary Update a subscription
+// @Description Replaces the pattern, filters and delivery mode of a subscription
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param subscription body SubscriptionRequest true "Subscription details"
+// @Success 200 {object} communication.Subscription
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/subscriptions/{id} [put]
+func (h *CommunicationHandler) UpdateSubscription(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.pubSubService.UpdateSubscription(c.Request.Context(), c.Param("id"), req.EventPattern, req.filters())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteSubscription godoc
+// @Summary Delete a subscription
+// @Tags communication
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/subscriptions/{id} [delete]
+func (h *CommunicationHandler) DeleteSubscription(c *gin.Context) {
+	if err := h.pubSubService.DeleteSubscription(c.Request.Context(), c.Param("id")); err != nil {
+		if otherTenant(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// PullPublications godoc
+// @Summary Pull publications for an agent
+// @Description Returns publications matching the agent's pull subscriptions since the given time
+// @Tags communication
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param since query string false "RFC3339 timestamp (default: 1 hour ago)"
+// @Success 200 {array} communication.Publication
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/agents/{id}/publications [get]
+func (h *CommunicationHandler) PullPublications(c *gin.Context) {
+	since := time.Now().Add(-time.Hour)
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		since = parsed
+	}
+
+	pubs, err := h.pubSubService.GetMatchingPublications(c.Request.Context(), c.Param("id"), since)
+	if otherTenant(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get publications"})
+		return
+	}
+
+	h.maskPublications(c, pubs)
+	c.JSON(http.StatusOK, pubs)
+}
+
+// ListMessages godoc
+// @Summary Query the direct message history
+// @Description Lists stored direct messages, newest first and whatever their delivery status, filtered by sender, recipient, type, creation time and priority
+// @Tags communication
+// @Produce json
+// @Param from_agent_id query string false "Sender agent ID"
+// @Param to_agent_id query string false "Recipient agent ID"
+// @Param message_type query string false "Message type"
+// @Param since query string false "RFC3339 start of the creation time range"
+// @Param until query string false "RFC3339 end of the creation time range"
+// @Param min_priority query int false "Lowest priority (1-10)"
+// @Param max_priority query int false "Highest priority (1-10)"
+// @Param limit query int false "Maximum number of messages (at most 1000)" default(100)
+// @Param offset query int false "Number of matching messages to skip" default(0)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/messages [get]
+func (h *CommunicationHandler) ListMessages(c *gin.Context) {
+	query := communication.MessageQuery{
+		FromAgentID: c.Query("from_agent_id"),
+		ToAgentID:   c.Query("to_agent_id"),
+		MessageType: communication.MessageType(c.Query("message_type")),
+	}
+
+	for name, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if raw := c.Query(name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC3339 timestamp"})
+				return
+			}
+			*target = parsed
+		}
+	}
+	for name, target := range map[string]*int{
+		"min_priority": &query.MinPriority,
+		"max_priority": &query.MaxPriority,
+		"limit":        &query.Limit,
+		"offset":       &query.Offset,
+	} {
+		if raw := c.Query(name); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an integer"})
+				return
+			}
+			*target = parsed
+		}
+	}
+	if c.Query("limit") != "" && query.Limit == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	messages, err := h.messageService.QueryMessages(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, communication.ErrInvalidMessageQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.WithError(err).Error("Failed to query message history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query messages"})
+		return
+	}
+
+	for i, msg := range messages {
+		messages[i] = h.maskMessage(c, msg)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"count":    len(messages),
+	})
+}
+
+// GetMessageTrace godoc
+// @Summary Get the ordered message delivery trace of an agent
+// @Description Returns the sequence state of the agent's ordered messages and recent delivery events, including held back gaps and messages processed out of order
+// @Tags communication
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} communication.MessageTrace
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/agents/{id}/messages/trace [get]
+func (h *CommunicationHandler) GetMessageTrace(c *gin.Context) {
+	trace, err := h.messageService.MessageTrace(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
+
+// ReplayTrafficRequest represents the request body for replaying captured traffic
+type ReplayTrafficRequest struct {
+	Capture communication.TrafficCapture `json:"capture" binding:"required"`
+	Speed   float64                      `json:"speed"`
+	IDMap   map[string]string            `json:"id_map"`
+}
+
+// CaptureTraffic godoc
+// @Summary Capture pub/sub traffic
+// @Description Records publications on the given topics within a time window as a portable capture file
+// @Tags communication
+// @Produce json
+// @Param topics query string true "Comma-separated event patterns (e.g. alert.*,reading.*)"
+// @Param since query string true "RFC3339 window start"
+// @Param until query string false "RFC3339 window end (default: now)"
+// @Success 200 {object} communication.TrafficCapture
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/capture [get]
+func (h *CommunicationHandler) CaptureTraffic(c *gin.Context) {
+	var topics []string
+	for _, topic := range strings.Split(c.Query("topics"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+	if len(topics) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "topics query parameter is required"})
+		return
+	}
+
+	since, err := time.Parse(time.RFC3339, c.Query("since"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+		return
+	}
+
+	until := time.Now()
+	if raw := c.Query("until"); raw != "" {
+		if until, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 timestamp"})
+			return
+		}
+	}
+
+	capture, err := h.pubSubService.CaptureTraffic(c.Request.Context(), topics, since, until)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to capture traffic")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.maskCapture(c, capture)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=traffic-%s.json", since.UTC().Format("20060102T150405Z")))
+	c.JSON(http.StatusOK, capture)
+}
+
+// ReplayTraffic godoc
+// @Summary Replay captured pub/sub traffic
+// @Description Republishes a capture into this instance with adjustable speed and agent ID remapping. The request completes when the replay finishes.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param replay body ReplayTrafficRequest true "Capture and replay options"
+// @Success 200 {object} communication.ReplayReport
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/replay [post]
+func (h *CommunicationHandler) ReplayTraffic(c *gin.Context) {
+	var req ReplayTrafficRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := communication.ReplayTraffic(c.Request.Context(), h.pubSubService, &req.Capture, communication.ReplayOptions{
+		Speed: req.Speed,
+		IDMap: req.IDMap,
+	})
+	if err != nil {
+		h.logger.WithError(err).Warn("Traffic replay interrupted")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // RegisterRoutes registers the communication routes
 func (h *CommunicationHandler) RegisterRoutes(router *gin.Engine) {
 	v1 := router.Group("/api/v1/communications")
 	{
 		// Direct messaging
 		v1.POST("/messages", h.SendMessage)
+		v1.GET("/messages", h.ListMessages)
+		v1.POST("/messages/request", h.RequestMessage)
+		v1.GET("/messages/:id", h.GetMessage)
+		v1.POST("/messages/:id/unmask", h.UnmaskMessage)
+		v1.GET("/agents/:id/messages/trace", h.GetMessageTrace)
 
 		// Pub/sub messaging
 		v1.POST("/publish", h.PublishMessage)
+		v1.POST("/publish/batch", h.PublishBatch)
+		v1.GET("/subscribe", h.StreamPublications)
+
+		// Subscription management
+		v1.POST("/subscriptions", h.CreateSubscription)
+		v1.GET("/subscriptions", h.ListSubscriptions)
+		v1.GET("/subscriptions/:id", h.GetSubscription)
+		v1.PUT("/subscriptions/:id", h.UpdateSubscription)
+		v1.DELETE("/subscriptions/:id", h.DeleteSubscription)
+		v1.GET("/agents/:id/publications", h.PullPublications)
+		v1.GET("/publications/:id", h.GetPublication)
+		v1.POST("/publications/:id/unmask", h.UnmaskPublication)
+
+		// Traffic capture and replay
+		v1.GET("/capture", h.CaptureTraffic)
+		v1.POST("/replay", h.ReplayTraffic)
+
+		// Topic aliases, retention and renaming
+		v1.GET("/stats", h.GetTopicStats)
+		v1.GET("/expiry", h.GetExpiryStats)
+		v1.GET("/topics/aliases", h.ListTopicAliases)
+		v1.POST("/topics/aliases", h.AddTopicAlias)
+		v1.DELETE("/topics/aliases/:alias", h.RemoveTopicAlias)
+		v1.GET("/topics/retention", h.ListRetentionPolicies)
+		v1.PUT("/topics/retention", h.SetRetentionPolicy)
+		v1.DELETE("/topics/retention/:topic", h.RemoveRetentionPolicy)
+		v1.POST("/topics/rename", h.RenameTopics)
+
+		// Command guardrails
+		v1.GET("/guardrails/policies", h.ListGuardrailPolicies)
+		v1.GET("/guardrails/violations", h.ListGuardrailViolations)
+		v1.GET("/guardrails/violations/:id", h.GetGuardrailViolation)
+		v1.POST("/guardrails/violations/:id/override", h.OverrideGuardrailViolation)
+		v1.POST("/guardrails/violations/:id/dismiss", h.DismissGuardrailViolation)
+
+		// Payload masking
+		v1.GET("/masking/rules", h.ListMaskingRules)
+		v1.GET("/masking/audit", h.ListUnmaskAudit)
 	}
 }
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/gin-gonic/gin"
+)
+
+// UnmaskRequest gives the reason for revealing masked payload fields
+type UnmaskRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// callerPermissions returns the permissions granted to the API key the caller
+// authenticated with; unauthenticated callers hold none
+func callerPermissions(c *gin.Context) communication.Permissions {
+	permissions := make(communication.Permissions)
+	if key, ok := auth.KeyFromContext(c.Request.Context()); ok {
+		for _, permission := range key.Permissions {
+			permissions[permission] = true
+		}
+	}
+	return permissions
+}
+
+// maskPublications masks the publications' payload fields the caller lacks permission for
+func (h *CommunicationHandler) maskPublications(c *gin.Context, publications []*communication.Publication) {
+	masking := h.pubSubService.Masking()
+	if masking == nil {
+		return
+	}
+	permissions := callerPermissions(c)
+	for _, pub := range publications {
+		pub.Payload, _ = masking.Mask(pub.EventName, pub.Payload, permissions)
+	}
+}
+
+// maskCapture masks the captured payload fields the caller lacks permission for
+func (h *CommunicationHandler) maskCapture(c *gin.Context, capture *communication.TrafficCapture) {
+	masking := h.pubSubService.Masking()
+	if masking == nil {
+		return
+	}
+	permissions := callerPermissions(c)
+	for i := range capture.Publications {
+		pub := &capture.Publications[i]
+		pub.Payload, _ = masking.Mask(pub.EventName, pub.Payload, permissions)
+	}
+}
+
+// maskMessage returns a copy of the message with the payload fields the caller lacks permission for masked
+func (h *CommunicationHandler) maskMessage(c *gin.Context, msg *communication.Message) *communication.Message {
+	masking := h.messageService.Masking()
+	if masking == nil || msg == nil {
+		return msg
+	}
+	masked := *msg
+	masked.Payload, _ = masking.Mask(string(msg.MessageType), msg.Payload, callerPermissions(c))
+	return &masked
+}
+
+// maskViolations returns copies of the violations with their blocked messages masked
+func (h *CommunicationHandler) maskViolations(c *gin.Context, violations ...*communication.GuardrailViolation) []*communication.GuardrailViolation {
+	if h.messageService.Masking() == nil {
+		return violations
+	}
+	masked := make([]*communication.GuardrailViolation, len(violations))
+	for i, violation := range violations {
+		copied := *violation
+		copied.Message = h.maskMessage(c, violation.Message)
+		masked[i] = &copied
+	}
+	return masked
+}
+
+// GetMessage godoc
+// @Summary Get a direct message
+// @Description Returns a message with sensitive payload fields masked unless the caller's API key holds the masking rule's permission
+// @Tags communication
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} communication.Message
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/messages/{id} [get]
+func (h *CommunicationHandler) GetMessage(c *gin.Context) {
+	msg, err := h.messageService.GetMessage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.maskMessage(c, msg))
+}
+
+// GetPublication godoc
+// @Summary Get a publication
+// @Description Returns a publication with sensitive payload fields masked unless the caller's API key holds the masking rule's permission
+// @Tags communication
+// @Produce json
+// @Param id path string true "Publication ID"
+// @Success 200 {object} communication.Publication
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/publications/{id} [get]
+func (h *CommunicationHandler) GetPublication(c *gin.Context) {
+	pub, err := h.pubSubService.GetPublication(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.maskPublications(c, []*communication.Publication{pub})
+	c.JSON(http.StatusOK, pub)
+}
+
+// UnmaskMessage godoc
+// @Summary Unmask a direct message
+// @Description Returns the message payload in the clear if the caller's API key holds the permissions of its masked fields. Every attempt is audited.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param request body UnmaskRequest true "Reason for unmasking"
+// @Success 200 {object} communication.Message
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/messages/{id}/unmask [post]
+func (h *CommunicationHandler) UnmaskMessage(c *gin.Context) {
+	actor, req, ok := h.bindUnmask(c)
+	if !ok {
+		return
+	}
+
+	msg, err := h.messageService.UnmaskMessage(c.Request.Context(), c.Param("id"), actor, req.Reason, callerPermissions(c))
+	if err != nil {
+		c.JSON(unmaskStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, msg)
+}
+
+// UnmaskPublication godoc
+// @Summary Unmask a publication
+// @Description Returns the publication payload in the clear if the caller's API key holds the permissions of its masked fields. Every attempt is audited.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param id path string true "Publication ID"
+// @Param request body UnmaskRequest true "Reason for unmasking"
+// @Success 200 {object} communication.Publication
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/publications/{id}/unmask [post]
+func (h *CommunicationHandler) UnmaskPublication(c *gin.Context) {
+	actor, req, ok := h.bindUnmask(c)
+	if !ok {
+		return
+	}
+
+	pub, err := h.pubSubService.UnmaskPublication(c.Request.Context(), c.Param("id"), actor, req.Reason, callerPermissions(c))
+	if err != nil {
+		c.JSON(unmaskStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pub)
+}
+
+// bindUnmask reads the caller and reason of an unmask request. Only callers
+// authenticated with an API key can unmask.
+func (h *CommunicationHandler) bindUnmask(c *gin.Context) (string, UnmaskRequest, bool) {
+	var req UnmaskRequest
+	actor := auth.Principal(c.Request.Context())
+	if actor == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "an API key is required to unmask payloads"})
+		return "", req, false
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", req, false
+	}
+	return actor, req, true
+}
+
+// ListMaskingRules godoc
+// @Summary List payload masking rules
+// @Tags communication
+// @Produce json
+// @Success 200 {array} communication.MaskingRule
+// @Router /api/v1/communications/masking/rules [get]
+func (h *CommunicationHandler) ListMaskingRules(c *gin.Context) {
+	masking := h.pubSubService.Masking()
+	if masking == nil {
+		c.JSON(http.StatusOK, []communication.MaskingRule{})
+		return
+	}
+	c.JSON(http.StatusOK, masking.Rules())
+}
+
+// ListUnmaskAudit godoc
+// @Summary List unmask operations
+// @Description Returns allowed and denied unmask requests, newest first
+// @Tags communication
+// @Produce json
+// @Param actor query string false "Filter by caller, e.g. key:<id>"
+// @Param limit query int false "Maximum number of records" default(100)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/communications/masking/audit [get]
+func (h *CommunicationHandler) ListUnmaskAudit(c *gin.Context) {
+	limit := 100
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	records := []*communication.UnmaskRecord{}
+	if masking := h.pubSubService.Masking(); masking != nil {
+		var err error
+		if records, err = masking.AuditLog(c.Request.Context(), c.Query("actor"), limit); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query unmask audit log"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"count":   len(records),
+	})
+}
+
+// unmaskStatus maps unmask errors to HTTP status codes
+func unmaskStatus(err error) int {
+	switch {
+	case errors.Is(err, communication.ErrUnmaskForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, communication.ErrMaskingDisabled):
+		return http.StatusConflict
+	}
+	return http.StatusNotFound
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maskedMessageRepo keeps messages in memory; the methods the unmask routes
+// do not use are left to the embedded interface
+type maskedMessageRepo struct {
+	communication.MessageRepository
+	mu       sync.Mutex
+	messages map[string]*communication.Message
+}
+
+func (r *maskedMessageRepo) CreateMessage(ctx context.Context, msg *communication.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[msg.ID] = msg
+	return nil
+}
+
+func (r *maskedMessageRepo) GetMessage(ctx context.Context, id string) (*communication.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msg, ok := r.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("message %s not found", id)
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func TestUnmaskMessage_PermissionsComeFromAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	masking, err := communication.NewPayloadMasking(communication.MaskingConfig{
+		Key:   []byte("0123456789abcdef0123456789abcdef"),
+		Rules: []communication.MaskingRule{{Topics: []string{"work_order"}, Fields: []string{"assignee"}}},
+	})
+	require.NoError(t, err)
+	messageService := communication.NewMessageService(&maskedMessageRepo{messages: map[string]*communication.Message{}})
+	messageService.SetMasking(masking)
+	pubSubService := communication.NewPubSubService(nil)
+	pubSubService.SetMasking(masking)
+	id, err := messageService.SendMessage(context.Background(), "dispatcher", "crew-1", communication.MessageType("work_order"), map[string]interface{}{"assignee": "J. Smith"}, nil)
+	require.NoError(t, err)
+
+	keys := map[string]*auth.APIKey{
+		"auditor": {ID: "auditor", Name: "auditor", Permissions: []string{communication.DefaultUnmaskPermission}},
+		"viewer":  {ID: "viewer", Name: "viewer"},
+	}
+	router := gin.New()
+	// Stands in for the authentication middleware
+	router.Use(func(c *gin.Context) {
+		if key, ok := keys[strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")]; ok {
+			c.Request = c.Request.WithContext(auth.WithKey(c.Request.Context(), key))
+		}
+	})
+	NewCommunicationHandler(messageService, pubSubService, logger).RegisterRoutes(router)
+
+	unmask := func(secret string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/communications/messages/"+id+"/unmask", strings.NewReader(`{"reason":"crew audit"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	forged := map[string]string{"X-User-ID": "admin", "X-Permissions": "*"}
+	assert.Equal(t, http.StatusUnauthorized, unmask("", forged).Code)
+	assert.Equal(t, http.StatusForbidden, unmask("viewer", forged).Code, "headers do not grant permissions")
+
+	w := unmask("auditor", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var msg communication.Message
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &msg))
+	assert.Equal(t, "J. Smith", msg.Payload["assignee"])
+
+	records, err := masking.AuditLog(context.Background(), "", 0)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "key:auditor", records[0].Actor)
+	assert.Equal(t, "key:viewer", records[1].Actor)
+}
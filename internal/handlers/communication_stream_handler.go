@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// publicationStreamRetryMillis is how long browsers wait before reconnecting
+	publicationStreamRetryMillis = 3000
+
+	// publicationStreamHeartbeat keeps idle streams open through proxies
+	publicationStreamHeartbeat = 15 * time.Second
+)
+
+// StreamPublications godoc
+// @Summary Subscribe to live publications
+// @Description Server-sent events stream of the publications whose topic matches any of the patterns (e.g. zone.north.* or zone.*.pump.#), as they are published. Each "publication" event carries the publication with its id as the event id. Nothing is replayed on reconnect; use pull subscriptions for guaranteed delivery. A "lagged" event is sent before the stream closes when the client reads too slowly. Payload fields are masked unless the caller holds the permission revealing them.
+// @Tags communication
+// @Produce text/event-stream
+// @Param topic query []string true "Topic patterns; repeat the parameter or separate them with commas" collectionFormat(multi)
+// @Success 200 {object} communication.Publication
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/subscribe [get]
+func (h *CommunicationHandler) StreamPublications(c *gin.Context) {
+	var patterns []string
+	for _, value := range c.QueryArray("topic") {
+		for _, pattern := range strings.Split(value, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+	}
+
+	stream, err := h.pubSubService.Stream(patterns, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer stream.Close()
+
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Debug("Could not clear write deadline for publication stream")
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", publicationStreamRetryMillis)
+	c.Writer.Flush()
+
+	masking := h.pubSubService.Masking()
+	permissions := callerPermissions(c)
+
+	heartbeat := time.NewTicker(publicationStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case pub, open := <-stream.C:
+			if !open {
+				if stream.Lagged() {
+					fmt.Fprint(c.Writer, "event: lagged\ndata: {}\n\n")
+					c.Writer.Flush()
+				}
+				return
+			}
+
+			// Publications are shared between streams
+			masked := *pub
+			if masking != nil {
+				masked.Payload, _ = masking.Mask(pub.EventName, pub.Payload, permissions)
+			}
+			data, err := json.Marshal(&masked)
+			if err != nil {
+				h.logger.WithError(err).Error("Failed to encode publication")
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: publication\ndata: %s\n\n", pub.ID, data)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
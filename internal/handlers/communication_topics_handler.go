@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/gin-gonic/gin"
+)
+
+// TopicAliasRequest represents the request body for adding a topic alias
+type TopicAliasRequest struct {
+	Alias  string `json:"alias" binding:"required"`
+	Target string `json:"target" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// RetentionPolicyRequest represents the request body for setting a topic retention policy
+type RetentionPolicyRequest struct {
+	Topic      string `json:"topic" binding:"required"`
+	TTLSeconds int    `json:"ttl_seconds" binding:"required"`
+}
+
+// GetTopicStats godoc
+// @Summary Get pub/sub topic statistics
+// @Description Returns publication counts per topic, topic aliases, retention policies and deprecation warnings for publications made to aliased topic names
+// @Tags communication
+// @Produce json
+// @Success 200 {object} communication.TopicStatsReport
+// @Router /api/v1/communications/stats [get]
+func (h *CommunicationHandler) GetTopicStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pubSubService.TopicStats())
+}
+
+// GetExpiryStats godoc
+// @Summary Get publication expiry statistics
+// @Description Returns how many expired publications the expiry sweeper removed. Counts per topic are reported by /api/v1/communications/stats.
+// @Tags communication
+// @Produce json
+// @Success 200 {object} communication.ExpiryStats
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/expiry [get]
+func (h *CommunicationHandler) GetExpiryStats(c *gin.Context) {
+	if h.expiry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Publication expiry is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, h.expiry.Stats())
+}
+
+// ListTopicAliases godoc
+// @Summary List topic aliases
+// @Tags communication
+// @Produce json
+// @Success 200 {array} communication.TopicAlias
+// @Router /api/v1/communications/topics/aliases [get]
+func (h *CommunicationHandler) ListTopicAliases(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pubSubService.ListTopicAliases())
+}
+
+// AddTopicAlias godoc
+// @Summary Add a topic alias
+// @Description Redirects publications to the alias, and topics beneath it, to the target topic
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param alias body TopicAliasRequest true "Alias details"
+// @Success 201 {object} communication.TopicAlias
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/topics/aliases [post]
+func (h *CommunicationHandler) AddTopicAlias(c *gin.Context) {
+	var req TopicAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := h.pubSubService.AddTopicAlias(c.Request.Context(), req.Alias, req.Target, req.Reason)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to add topic alias")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// RemoveTopicAlias godoc
+// @Summary Remove a topic alias
+// @Tags communication
+// @Produce json
+// @Param alias path string true "Alias"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/topics/aliases/{alias} [delete]
+func (h *CommunicationHandler) RemoveTopicAlias(c *gin.Context) {
+	if err := h.pubSubService.RemoveTopicAlias(c.Request.Context(), c.Param("alias")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListRetentionPolicies godoc
+// @Summary List topic retention policies
+// @Tags communication
+// @Produce json
+// @Success 200 {array} communication.RetentionPolicy
+// @Router /api/v1/communications/topics/retention [get]
+func (h *CommunicationHandler) ListRetentionPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pubSubService.ListRetentionPolicies())
+}
+
+// SetRetentionPolicy godoc
+// @Summary Set a topic retention policy
+// @Description Sets the default TTL of publications on a topic and the topics beneath it
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param policy body RetentionPolicyRequest true "Retention policy"
+// @Success 200 {object} communication.RetentionPolicy
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/topics/retention [put]
+func (h *CommunicationHandler) SetRetentionPolicy(c *gin.Context) {
+	var req RetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.pubSubService.SetRetentionPolicy(c.Request.Context(), req.Topic, req.TTLSeconds)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// RemoveRetentionPolicy godoc
+// @Summary Remove a topic retention policy
+// @Tags communication
+// @Produce json
+// @Param topic path string true "Topic"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/communications/topics/retention/{topic} [delete]
+func (h *CommunicationHandler) RemoveRetentionPolicy(c *gin.Context) {
+	if err := h.pubSubService.RemoveRetentionPolicy(c.Request.Context(), c.Param("topic")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// RenameTopics godoc
+// @Summary Bulk rename topics
+// @Description Renames topics and the topics beneath them across subscriptions, retention policies and aliases in one step. Old names keep working as deprecated aliases unless drop_aliases is set.
+// @Tags communication
+// @Accept json
+// @Produce json
+// @Param rename body communication.TopicRenameRequest true "Renames"
+// @Success 200 {object} communication.TopicRenameResult
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/communications/topics/rename [post]
+func (h *CommunicationHandler) RenameTopics(c *gin.Context) {
+	var req communication.TopicRenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.pubSubService.RenameTopics(c.Request.Context(), req)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to rename topics")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
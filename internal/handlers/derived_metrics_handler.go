@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/derivedmetrics"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DerivedMetricsHandler exposes operator-defined derived metrics
+type DerivedMetricsHandler struct {
+	metrics *derivedmetrics.Service
+	logger  *logrus.Logger
+}
+
+// NewDerivedMetricsHandler creates a new derived metrics handler
+func NewDerivedMetricsHandler(metrics *derivedmetrics.Service, logger *logrus.Logger) *DerivedMetricsHandler {
+	return &DerivedMetricsHandler{
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// ListDerivedMetrics godoc
+// @Summary List derived metrics
+// @Description Returns every derived metric definition with its latest ingested value
+// @Tags metrics
+// @Produce json
+// @Success 200 {array} derivedmetrics.MetricStatus
+// @Router /api/v1/metrics/derived [get]
+func (h *DerivedMetricsHandler) ListDerivedMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, h.metrics.Definitions())
+}
+
+// GetDerivedMetric godoc
+// @Summary Get a derived metric's current value
+// @Description Ingest metrics return their latest value; query metrics are computed from recorded traffic
+// @Tags metrics
+// @Produce json
+// @Param name path string true "Metric name"
+// @Success 200 {object} derivedmetrics.Value
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/metrics/derived/{name} [get]
+func (h *DerivedMetricsHandler) GetDerivedMetric(c *gin.Context) {
+	value, err := h.metrics.Evaluate(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.respondError(c, err, "Failed to evaluate derived metric")
+		return
+	}
+
+	c.JSON(http.StatusOK, value)
+}
+
+// GetDerivedMetricHistory godoc
+// @Summary Get a derived metric's values over time
+// @Tags metrics
+// @Produce json
+// @Param name path string true "Metric name"
+// @Param since query string false "RFC3339 start (defaults to 24 hours ago)"
+// @Param until query string false "RFC3339 end (defaults to now)"
+// @Success 200 {array} derivedmetrics.Value
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/metrics/derived/{name}/history [get]
+func (h *DerivedMetricsHandler) GetDerivedMetricHistory(c *gin.Context) {
+	until := time.Now().UTC()
+	since := until.Add(-24 * time.Hour)
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		since = t
+	}
+	if v := c.Query("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 timestamp"})
+			return
+		}
+		until = t
+	}
+
+	values, err := h.metrics.History(c.Request.Context(), c.Param("name"), since, until)
+	if err != nil {
+		h.respondError(c, err, "Failed to get derived metric history")
+		return
+	}
+
+	c.JSON(http.StatusOK, values)
+}
+
+// EvaluateDerivedMetricRequest is an ad hoc expression to evaluate
+type EvaluateDerivedMetricRequest struct {
+	Expression string `json:"expression" binding:"required"`
+}
+
+// EvaluateDerivedMetric godoc
+// @Summary Evaluate an expression
+// @Description Computes an expression from recorded traffic, for trying out a formula before defining it
+// @Tags metrics
+// @Accept json
+// @Produce json
+// @Param request body EvaluateDerivedMetricRequest true "Expression"
+// @Success 200 {object} derivedmetrics.Value
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/metrics/derived/evaluate [post]
+func (h *DerivedMetricsHandler) EvaluateDerivedMetric(c *gin.Context) {
+	var req EvaluateDerivedMetricRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, err := h.metrics.EvaluateExpression(c.Request.Context(), req.Expression)
+	if err != nil {
+		h.respondError(c, err, "Failed to evaluate expression")
+		return
+	}
+
+	c.JSON(http.StatusOK, value)
+}
+
+func (h *DerivedMetricsHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, derivedmetrics.ErrInvalidMetric):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, derivedmetrics.ErrMetricNotFound), errors.Is(err, derivedmetrics.ErrNoData):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers derived metric routes
+func (h *DerivedMetricsHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/metrics/derived", h.ListDerivedMetrics)
+	router.POST("/api/v1/metrics/derived/evaluate", h.EvaluateDerivedMetric)
+	router.GET("/api/v1/metrics/derived/:name", h.GetDerivedMetric)
+	router.GET("/api/v1/metrics/derived/:name/history", h.GetDerivedMetricHistory)
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// executionStreamHeartbeat is how often an idle execution event stream sends
+// a comment, so that proxies keep it open
+const executionStreamHeartbeat = 15 * time.Second
+
+// ExecutionHandler exposes the workflow versions and executions of the
+// workflow orchestration engine
+type ExecutionHandler struct {
+	engine orchestration.WorkflowEngine
+	logger *logrus.Logger
+}
+
+// NewExecutionHandler creates a new workflow execution handler
+func NewExecutionHandler(engine orchestration.WorkflowEngine, logger *logrus.Logger) *ExecutionHandler {
+	return &ExecutionHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// ListExecutions godoc
+// @Summary List workflow executions
+// @Description Returns workflow executions matching the filters, newest first
+// @Tags executions
+// @Produce json
+// @Param workflow_id query string false "Filter by workflow ID"
+// @Param status query string false "Filter by status; repeat or comma-separate for several"
+// @Param triggered_by query string false "Filter by trigger source"
+// @Param agent_id query string false "Filter by an agent used in the execution"
+// @Param started_after query string false "Earliest start time (RFC3339)"
+// @Param started_before query string false "Latest start time (RFC3339)"
+// @Param limit query int false "Maximum executions (default 50, at most 500)"
+// @Param offset query int false "Executions to skip"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/executions [get]
+func (h *ExecutionHandler) ListExecutions(c *gin.Context) {
+	filters, ok := executionFiltersFromQuery(c)
+	if !ok {
+		return
+	}
+
+	executions, err := h.engine.ListExecutions(c.Request.Context(), filters)
+	if err != nil {
+		if errors.Is(err, orchestration.ErrInvalidExecutionFilters) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list workflow executions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workflow executions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+		"count":      len(executions),
+		"offset":     filters.Offset,
+	})
+}
+
+// executionFiltersFromQuery reads execution filters from query parameters,
+// responding with 400 when they are invalid
+func executionFiltersFromQuery(c *gin.Context) (orchestration.ExecutionFilters, bool) {
+	filters := orchestration.ExecutionFilters{
+		WorkflowID:  c.Query("workflow_id"),
+		TriggeredBy: c.Query("triggered_by"),
+		AgentID:     c.Query("agent_id"),
+	}
+	for _, raw := range c.QueryArray("status") {
+		for _, status := range strings.Split(raw, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filters.Status = append(filters.Status, orchestration.WorkflowStatus(status))
+			}
+		}
+	}
+	for param, target := range map[string]**time.Time{
+		"started_after":  &filters.StartTimeAfter,
+		"started_before": &filters.StartTimeBefore,
+	} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC3339 time"})
+			return filters, false
+		}
+		*target = &t
+	}
+	for param, target := range map[string]*int{"limit": &filters.Limit, "offset": &filters.Offset} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an integer"})
+			return filters, false
+		}
+		*target = n
+	}
+	return filters, true
+}
+
+// StreamExecutionEvents godoc
+// @Summary Stream workflow execution events
+// @Description Server-sent events stream of an execution: a "snapshot" of the execution, then each task transition, progress update and the final execution event. The stream ends once the execution stops; a finished execution gets only its snapshot.
+// @Tags executions
+// @Produce text/event-stream
+// @Param id path string true "Execution ID"
+// @Success 200 {object} orchestration.ExecutionEvent
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/executions/{id}/events [get]
+func (h *ExecutionHandler) StreamExecutionEvents(c *gin.Context) {
+	// Subscribe before reading the snapshot so that no transition falls in between
+	executionID := c.Param("id")
+	events, err := h.engine.WatchExecution(c.Request.Context(), executionID)
+	if err != nil && !errors.Is(err, orchestration.ErrExecutionNotMonitored) {
+		h.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to watch workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to watch workflow execution"})
+		return
+	}
+	execution, err := h.engine.GetExecution(c.Request.Context(), executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow execution not found"})
+		return
+	}
+
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Debug("Could not clear write deadline for execution event stream")
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	h.writeEvent(c, "snapshot", execution)
+	c.Writer.Flush()
+	if events == nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(executionStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			h.writeEvent(c, string(event.Type), event)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// ListWorkflowVersions godoc
+// @Summary List workflow versions
+// @Description Returns every version of an orchestration workflow, newest first
+// @Tags executions
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/workflows/{id}/versions [get]
+func (h *ExecutionHandler) ListWorkflowVersions(c *gin.Context) {
+	workflowID := c.Param("id")
+	versions, err := h.engine.ListWorkflowVersions(c.Request.Context(), workflowID)
+	if err != nil {
+		h.logger.WithError(err).WithField("workflow_id", workflowID).Error("Failed to list workflow versions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workflow versions"})
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow_id": workflowID,
+		"versions":    versions,
+		"count":       len(versions),
+	})
+}
+
+// MigrateExecution godoc
+// @Summary Migrate a paused workflow execution
+// @Description Moves a paused execution to another version of its workflow. Every task the execution started must exist in that version; the execution stays paused until resumed.
+// @Tags executions
+// @Accept json
+// @Produce json
+// @Param id path string true "Execution ID"
+// @Param request body map[string]string true "Target version"
+// @Success 200 {object} orchestration.WorkflowExecution
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/executions/{id}/migrate [post]
+func (h *ExecutionHandler) MigrateExecution(c *gin.Context) {
+	var req struct {
+		Version string `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	executionID := c.Param("id")
+	execution, err := h.engine.MigrateExecution(c.Request.Context(), executionID, req.Version)
+	if err != nil {
+		if errors.Is(err, orchestration.ErrExecutionNotPaused) || errors.Is(err, orchestration.ErrIncompatibleVersion) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to migrate workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to migrate workflow execution"})
+		return
+	}
+
+	c.JSON(http.StatusOK, execution)
+}
+
+// ApproveTask godoc
+// @Summary Approve or reject a manual approval task
+// @Description Records the caller's decision on a task awaiting approval and releases the execution waiting on it. The approver is the API key the request is authenticated with.
+// @Tags executions
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param id path string true "Execution ID"
+// @Param taskId path string true "Task ID"
+// @Param request body map[string]interface{} true "Decision and optional comment"
+// @Success 200 {object} orchestration.TaskApproval
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/executions/{id}/tasks/{taskId}/approve [post]
+func (h *ExecutionHandler) ApproveTask(c *gin.Context) {
+	approver := auth.Principal(c.Request.Context())
+	if approver == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "an API key is required to approve tasks"})
+		return
+	}
+
+	var req struct {
+		Approved *bool  `json:"approved" form:"approved" binding:"required"`
+		Comment  string `json:"comment" form:"comment"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	executionID, taskID := c.Param("id"), c.Param("taskId")
+	decision := orchestration.TaskApproval{Approved: *req.Approved, Approver: approver, Comment: req.Comment}
+	if err := h.engine.ApproveTask(c.Request.Context(), executionID, taskID, decision); err != nil {
+		if errors.Is(err, orchestration.ErrApprovalNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"execution_id": executionID,
+			"task_id":      taskID,
+		}).Error("Failed to record task approval")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record task approval"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution_id": executionID,
+		"task_id":      taskID,
+		"approved":     decision.Approved,
+		"approver":     approver,
+		"comment":      decision.Comment,
+	})
+}
+
+// ExportExecutionFixture godoc
+// @Summary Export an execution fixture
+// @Description Exports a finished execution with the workflow version it ran and the recorded outcome of each task, for replay
+// @Tags executions
+// @Produce json
+// @Param id path string true "Execution ID"
+// @Param download query bool false "Return the fixture as a file attachment"
+// @Success 200 {object} orchestration.ExecutionFixture
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/executions/{id}/fixture [get]
+func (h *ExecutionHandler) ExportExecutionFixture(c *gin.Context) {
+	executionID := c.Param("id")
+	fixture, err := h.engine.ExportFixture(c.Request.Context(), executionID)
+	if err != nil {
+		if errors.Is(err, orchestration.ErrExecutionNotFinished) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).WithField("execution_id", executionID).Error("Failed to export execution fixture")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export execution fixture"})
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=execution-%s.fixture.json", executionID))
+	}
+	c.JSON(http.StatusOK, fixture)
+}
+
+// ReplayExecutionFixture godoc
+// @Summary Replay an execution fixture
+// @Description Runs the fixture's workflow on an engine whose agents answer with the recorded task outcomes and reports where the replay diverges from the recording
+// @Tags executions
+// @Accept json
+// @Produce json
+// @Param request body orchestration.ExecutionFixture true "Exported fixture"
+// @Success 200 {object} orchestration.ReplayResult
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/workflows/replay [post]
+func (h *ExecutionHandler) ReplayExecutionFixture(c *gin.Context) {
+	var fixture orchestration.ExecutionFixture
+	if err := c.ShouldBindJSON(&fixture); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := orchestration.ReplayFixture(c.Request.Context(), &fixture, h.logger)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func (h *ExecutionHandler) writeEvent(c *gin.Context, name string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		h.logger.WithError(err).WithField("event", name).Error("Failed to encode execution event")
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, payload)
+}
+
+// RegisterRoutes registers workflow execution routes
+func (h *ExecutionHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/executions", h.ListExecutions)
+	router.GET("/api/v1/executions/:id/events", h.StreamExecutionEvents)
+	router.POST("/api/v1/executions/:id/migrate", h.MigrateExecution)
+	router.GET("/api/v1/executions/:id/fixture", h.ExportExecutionFixture)
+	router.POST("/api/v1/executions/:id/tasks/:taskId/approve", h.ApproveTask)
+	router.GET("/api/v1/workflows/:id/versions", h.ListWorkflowVersions)
+	router.POST("/api/v1/workflows/replay", h.ReplayExecutionFixture)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HealthScoreHandler handles HTTP requests for agent health scores
+type HealthScoreHandler struct {
+	scores  *health.HealthScoreService
+	runtime *runtime.Manager
+	logger  *logrus.Logger
+}
+
+// NewHealthScoreHandler creates a new health score handler
+func NewHealthScoreHandler(scores *health.HealthScoreService, runtime *runtime.Manager, logger *logrus.Logger) *HealthScoreHandler {
+	return &HealthScoreHandler{
+		scores:  scores,
+		runtime: runtime,
+		logger:  logger,
+	}
+}
+
+// GetHealthScore godoc
+// @Summary Get agent health score
+// @Description Returns the agent's most recent 0-100 health score with its components
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} health.HealthScore
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/health-score [get]
+func (h *HealthScoreHandler) GetHealthScore(c *gin.Context) {
+	agentID := c.Param("id")
+
+	if _, err := h.runtime.GetAgent(agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	score, err := h.scores.LatestScore(c.Request.Context(), agentID)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to get health score")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get health score"})
+		return
+	}
+	if score == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent has not been scored yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// ComputeHealthScore godoc
+// @Summary Compute agent health score
+// @Description Computes and stores the agent's health score now, evaluating score rules
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} health.HealthScore
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/health-score [post]
+func (h *HealthScoreHandler) ComputeHealthScore(c *gin.Context) {
+	agentID := c.Param("id")
+
+	if _, err := h.runtime.GetAgent(agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	score, err := h.scores.ScoreAgent(c.Request.Context(), agentID)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to compute health score")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute health score"})
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// GetHealthScoreHistory godoc
+// @Summary Get agent health score history
+// @Description Returns the agent's health scores computed within a window, oldest first
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param since query string false "Window start (RFC3339), defaults to 7 days ago"
+// @Param until query string false "Window end (RFC3339), defaults to now"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/health-score/history [get]
+func (h *HealthScoreHandler) GetHealthScoreHistory(c *gin.Context) {
+	agentID := c.Param("id")
+
+	if _, err := h.runtime.GetAgent(agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	until := time.Now().UTC()
+	if u := c.Query("until"); u != "" {
+		parsed, err := time.Parse(time.RFC3339, u)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 timestamp"})
+			return
+		}
+		until = parsed.UTC()
+	}
+
+	since := until.Add(-defaultStatusHistoryWindow)
+	if s := c.Query("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		since = parsed.UTC()
+	}
+
+	if !until.After(since) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be after since"})
+		return
+	}
+
+	scores, err := h.scores.GetHistory(c.Request.Context(), agentID, since, until)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to get health score history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get health score history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id": agentID,
+		"since":    since,
+		"until":    until,
+		"scores":   scores,
+	})
+}
+
+// RegisterRoutes registers the health score routes
+func (h *HealthScoreHandler) RegisterRoutes(router *gin.Engine) {
+	agents := router.Group("/api/v1/agents")
+	{
+		agents.GET("/:id/health-score", h.GetHealthScore)
+		agents.POST("/:id/health-score", h.ComputeHealthScore)
+		agents.GET("/:id/health-score/history", h.GetHealthScoreHistory)
+	}
+}
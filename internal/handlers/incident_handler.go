@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/incident"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// IncidentHandler handles HTTP requests for incidents
+type IncidentHandler struct {
+	incidents *incident.Service
+	logger    *logrus.Logger
+}
+
+// NewIncidentHandler creates a new incident handler
+func NewIncidentHandler(incidentService *incident.Service, logger *logrus.Logger) *IncidentHandler {
+	return &IncidentHandler{
+		incidents: incidentService,
+		logger:    logger,
+	}
+}
+
+// IncidentStateRequest moves an incident to another state. It is also
+// accepted as form values, as posted by the incidents page.
+type IncidentStateRequest struct {
+	State string `json:"state" form:"state" binding:"required"`
+	Actor string `json:"actor" form:"actor"`
+	Note  string `json:"note" form:"note"`
+}
+
+// IncidentSeverityRequest changes the severity of an incident
+type IncidentSeverityRequest struct {
+	Severity string `json:"severity" binding:"required"`
+	Actor    string `json:"actor"`
+	Note     string `json:"note"`
+}
+
+// IncidentLinksRequest links agents and messages to an incident
+type IncidentLinksRequest struct {
+	AgentIDs   []string `json:"agent_ids"`
+	MessageIDs []string `json:"message_ids"`
+	Actor      string   `json:"actor"`
+}
+
+// IncidentNoteRequest adds a note to the timeline of an incident
+type IncidentNoteRequest struct {
+	Note  string `json:"note" binding:"required"`
+	Actor string `json:"actor"`
+}
+
+// ListIncidents godoc
+// @Summary List incidents
+// @Description Returns incidents, most recently updated first
+// @Tags incidents
+// @Produce json
+// @Param state query string false "open, acknowledged, contained or resolved"
+// @Param severity query string false "LOW, MEDIUM, HIGH or CRITICAL"
+// @Param agent_id query string false "Only incidents linked to this agent"
+// @Param active query bool false "Only incidents that are not resolved"
+// @Param limit query int false "Maximum number of incidents (default 100)"
+// @Success 200 {array} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/incidents [get]
+func (h *IncidentHandler) ListIncidents(c *gin.Context) {
+	filter := incident.Filter{
+		State:   incident.State(c.Query("state")),
+		AgentID: c.Query("agent_id"),
+		Active:  c.Query("active") == "true",
+	}
+	if filter.State != "" && !filter.State.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown state"})
+		return
+	}
+	if severity := c.Query("severity"); severity != "" {
+		var ok bool
+		if filter.Severity, ok = incident.ParseSeverity(severity); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown severity"})
+			return
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+	filter.Limit = limit
+
+	incidents, err := h.incidents.List(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err, "Failed to list incidents")
+		return
+	}
+	if incidents == nil {
+		incidents = []*incident.Incident{}
+	}
+
+	c.JSON(http.StatusOK, incidents)
+}
+
+// GetIncident godoc
+// @Summary Get an incident
+// @Description Returns an incident with its linked agents and messages and its timeline
+// @Tags incidents
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Success 200 {object} incident.Incident
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/incidents/{id} [get]
+func (h *IncidentHandler) GetIncident(c *gin.Context) {
+	inc, err := h.incidents.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get incident")
+		return
+	}
+
+	c.JSON(http.StatusOK, inc)
+}
+
+// OpenIncident godoc
+// @Summary Open an incident
+// @Tags incidents
+// @Accept json
+// @Produce json
+// @Param incident body incident.OpenRequest true "Incident"
+// @Success 201 {object} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/incidents [post]
+func (h *IncidentHandler) OpenIncident(c *gin.Context) {
+	var req incident.OpenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inc, err := h.incidents.Open(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err, "Failed to open incident")
+		return
+	}
+
+	c.JSON(http.StatusCreated, inc)
+}
+
+// TransitionIncident godoc
+// @Summary Change the state of an incident
+// @Description Moves an incident to acknowledged, contained or resolved, or reopens a resolved incident
+// @Tags incidents
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Param request body IncidentStateRequest true "New state"
+// @Success 200 {object} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/incidents/{id}/state [post]
+func (h *IncidentHandler) TransitionIncident(c *gin.Context) {
+	var req IncidentStateRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inc, err := h.incidents.Transition(c.Request.Context(), c.Param("id"), incident.State(req.State), req.Actor, req.Note)
+	if err != nil {
+		h.respondError(c, err, "Failed to change incident state")
+		return
+	}
+
+	c.JSON(http.StatusOK, inc)
+}
+
+// SetIncidentSeverity godoc
+// @Summary Change the severity of an incident
+// @Tags incidents
+// @Accept json
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Param request body IncidentSeverityRequest true "New severity"
+// @Success 200 {object} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/incidents/{id}/severity [post]
+func (h *IncidentHandler) SetIncidentSeverity(c *gin.Context) {
+	var req IncidentSeverityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	severity, ok := incident.ParseSeverity(req.Severity)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown severity"})
+		return
+	}
+
+	inc, err := h.incidents.SetSeverity(c.Request.Context(), c.Param("id"), severity, req.Actor, req.Note)
+	if err != nil {
+		h.respondError(c, err, "Failed to change incident severity")
+		return
+	}
+
+	c.JSON(http.StatusOK, inc)
+}
+
+// LinkIncident godoc
+// @Summary Link agents and messages to an incident
+// @Tags incidents
+// @Accept json
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Param request body IncidentLinksRequest true "Agents and messages"
+// @Success 200 {object} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/incidents/{id}/links [post]
+func (h *IncidentHandler) LinkIncident(c *gin.Context) {
+	var req IncidentLinksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.AgentIDs) == 0 && len(req.MessageIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "agent_ids or message_ids is required"})
+		return
+	}
+
+	inc, err := h.incidents.Link(c.Request.Context(), c.Param("id"), req.AgentIDs, req.MessageIDs, req.Actor)
+	if err != nil {
+		h.respondError(c, err, "Failed to link incident")
+		return
+	}
+
+	c.JSON(http.StatusOK, inc)
+}
+
+// AddIncidentNote godoc
+// @Summary Add a note to an incident
+// @Tags incidents
+// @Accept json
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Param request body IncidentNoteRequest true "Note"
+// @Success 200 {object} incident.Incident
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/incidents/{id}/notes [post]
+func (h *IncidentHandler) AddIncidentNote(c *gin.Context) {
+	var req IncidentNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inc, err := h.incidents.AddNote(c.Request.Context(), c.Param("id"), req.Actor, req.Note)
+	if err != nil {
+		h.respondError(c, err, "Failed to add incident note")
+		return
+	}
+
+	c.JSON(http.StatusOK, inc)
+}
+
+func (h *IncidentHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, incident.ErrInvalidIncident):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, incident.ErrIncidentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, incident.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers incident routes
+func (h *IncidentHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/incidents", h.ListIncidents)
+	router.POST("/api/v1/incidents", h.OpenIncident)
+	router.GET("/api/v1/incidents/:id", h.GetIncident)
+	router.POST("/api/v1/incidents/:id/state", h.TransitionIncident)
+	router.POST("/api/v1/incidents/:id/severity", h.SetIncidentSeverity)
+	router.POST("/api/v1/incidents/:id/links", h.LinkIncident)
+	router.POST("/api/v1/incidents/:id/notes", h.AddIncidentNote)
+}
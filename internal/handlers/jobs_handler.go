@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// JobsHandler exposes the background job queue for inspection and manual retry
+type JobsHandler struct {
+	queue  *jobs.Queue
+	logger *logrus.Logger
+}
+
+// NewJobsHandler creates a new jobs handler
+func NewJobsHandler(queue *jobs.Queue, logger *logrus.Logger) *JobsHandler {
+	return &JobsHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// ListJobs godoc
+// @Summary List background jobs
+// @Description Returns job history with attempts and last errors, newest first
+// @Tags jobs
+// @Produce json
+// @Param type query string false "Filter by job type"
+// @Param status query string false "Filter by status (queued, running, succeeded, dead, canceled)"
+// @Param limit query int false "Maximum jobs (default 100)"
+// @Success 200 {array} jobs.Job
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/jobs [get]
+func (h *JobsHandler) ListJobs(c *gin.Context) {
+	filter, ok := jobFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.queue.List(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list jobs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// jobFilterFromQuery reads a job filter from query parameters, responding
+// with 400 when they are invalid
+func jobFilterFromQuery(c *gin.Context) (jobs.Filter, bool) {
+	filter := jobs.Filter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Limit:  100,
+	}
+	switch filter.Status {
+	case "", jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusDead, jobs.StatusCanceled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be queued, running, succeeded, dead or canceled"})
+		return filter, false
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return filter, false
+		}
+		filter.Limit = limit
+	}
+	return filter, true
+}
+
+// ListJobTypes godoc
+// @Summary List job types
+// @Description Returns job types with their concurrency, attempts, running jobs and job counts by status
+// @Tags jobs
+// @Produce json
+// @Success 200 {array} jobs.TypeInfo
+// @Router /api/v1/jobs/types [get]
+func (h *JobsHandler) ListJobTypes(c *gin.Context) {
+	types, err := h.queue.Types(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list job types")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list job types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"types":     types,
+		"schedules": h.queue.Schedules(),
+	})
+}
+
+// GetJob godoc
+// @Summary Get a background job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobs.Job
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/jobs/{id} [get]
+func (h *JobsHandler) GetJob(c *gin.Context) {
+	job, err := h.queue.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob godoc
+// @Summary Retry a dead-lettered job
+// @Description Queues a dead job again with fresh attempts
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobs.Job
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/jobs/{id}/retry [post]
+func (h *JobsHandler) RetryJob(c *gin.Context) {
+	job, err := h.queue.Retry(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to retry job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob godoc
+// @Summary Cancel a queued job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobs.Job
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/jobs/{id}/cancel [post]
+func (h *JobsHandler) CancelJob(c *gin.Context) {
+	job, err := h.queue.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to cancel job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// respondError maps job queue errors to HTTP responses
+func (h *JobsHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, jobs.ErrNotDead), errors.Is(err, jobs.ErrNotQueued):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers job queue routes
+func (h *JobsHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/jobs", h.ListJobs)
+	router.GET("/api/v1/jobs/types", h.ListJobTypes)
+	router.GET("/api/v1/jobs/:id", h.GetJob)
+	router.POST("/api/v1/jobs/:id/retry", h.RetryJob)
+	router.POST("/api/v1/jobs/:id/cancel", h.CancelJob)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// LLMCaptureHandler serves captured LLM calls for debugging AI operations
+type LLMCaptureHandler struct {
+	store  *ai.CaptureStore
+	logger *logrus.Logger
+}
+
+// NewLLMCaptureHandler creates a new LLM capture handler
+func NewLLMCaptureHandler(store *ai.CaptureStore, logger *logrus.Logger) *LLMCaptureHandler {
+	return &LLMCaptureHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// ListCaptures godoc
+// @Summary List captured LLM calls
+// @Description Lists captured LLM calls, newest first, optionally filtered by request ID or operation
+// @Tags admin
+// @Produce json
+// @Param request_id query string false "Request ID (X-Request-ID)"
+// @Param operation query string false "Operation name, e.g. goals.refine"
+// @Param limit query int false "Maximum number of captures" default(50)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/admin/llm-captures [get]
+func (h *LLMCaptureHandler) ListCaptures(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+
+	captures := h.store.List(ai.CaptureFilter{
+		RequestID: c.Query("request_id"),
+		Operation: c.Query("operation"),
+		Limit:     limit,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"captures":   captures,
+		"count":      len(captures),
+		"operations": h.store.Operations(),
+	})
+}
+
+// GetCapture godoc
+// @Summary Get a captured LLM call
+// @Tags admin
+// @Produce json
+// @Param id path string true "Capture ID"
+// @Success 200 {object} ai.LLMCapture
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/admin/llm-captures/{id} [get]
+func (h *LLMCaptureHandler) GetCapture(c *gin.Context) {
+	capture, ok := h.store.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "capture not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, capture)
+}
+
+// RegisterRoutes registers the LLM capture routes
+func (h *LLMCaptureHandler) RegisterRoutes(router *gin.Engine) {
+	captures := router.Group("/api/v1/admin/llm-captures")
+	{
+		captures.GET("", h.ListCaptures)
+		captures.GET("/:id", h.GetCapture)
+	}
+}
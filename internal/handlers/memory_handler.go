@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryHandler exposes agent memory export, import, schema migrations, the
+// memory audit log and synchronization between agent instances
+type MemoryHandler struct {
+	service      *memory.Service
+	synchronizer *memory.Synchronizer
+	logger       *logrus.Logger
+}
+
+// NewMemoryHandler creates a new agent memory handler
+func NewMemoryHandler(service *memory.Service, logger *logrus.Logger) *MemoryHandler {
+	return &MemoryHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SetSynchronizer enables the memory sync routes
+func (h *MemoryHandler) SetSynchronizer(synchronizer *memory.Synchronizer) {
+	h.synchronizer = synchronizer
+}
+
+// ExportAgentMemory godoc
+// @Summary Export an agent's memory
+// @Description Returns the agent's working memory, long-term memory and snapshots as a portable archive
+// @Tags memory
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param download query bool false "Serve the archive as a file attachment"
+// @Success 200 {object} memory.MemoryArchive
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/memory/export [get]
+func (h *MemoryHandler) ExportAgentMemory(c *gin.Context) {
+	agentID := c.Param("id")
+
+	archive, err := h.service.ExportAgentMemory(c.Request.Context(), agentID)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to export agent memory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export agent memory"})
+		return
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=memory-%s.json", agentID))
+	}
+	c.JSON(http.StatusOK, archive)
+}
+
+// importMemoryRequest is an archive and how to rewrite it on import
+type importMemoryRequest struct {
+	Archive      *memory.MemoryArchive `json:"archive" binding:"required"`
+	KeyMap       map[string]string     `json:"key_map"`
+	KeyPrefixMap map[string]string     `json:"key_prefix_map"`
+	TagMap       map[string]string     `json:"tag_map"`
+	AddTags      []string              `json:"add_tags"`
+	Overwrite    bool                  `json:"overwrite"`
+}
+
+// ImportAgentMemory godoc
+// @Summary Import a memory archive into an agent
+// @Description Imports an exported archive into the agent in the path, which may differ from the agent it was exported from. Keys and tags can be rewritten on the way in.
+// @Tags memory
+// @Accept json
+// @Produce json
+// @Param id path string true "Target agent ID"
+// @Param request body importMemoryRequest true "Archive and import options"
+// @Success 200 {object} memory.ImportResult
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/agents/{id}/memory/import [post]
+func (h *MemoryHandler) ImportAgentMemory(c *gin.Context) {
+	var req importMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.service.ImportAgentMemory(c.Request.Context(), req.Archive, memory.ImportOptions{
+		TargetAgentID: c.Param("id"),
+		KeyMap:        req.KeyMap,
+		KeyPrefixMap:  req.KeyPrefixMap,
+		TagMap:        req.TagMap,
+		AddTags:       req.AddTags,
+		Overwrite:     req.Overwrite,
+	})
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", c.Param("id")).Warn("Failed to import agent memory")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAgentMemoryMigrations godoc
+// @Summary Get an agent's memory schema migrations
+// @Description Returns the schema versions registered for the agent's memory keys and how many values were migrated on read
+// @Tags memory
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/agents/{id}/memory/migrations [get]
+func (h *MemoryHandler) GetAgentMemoryMigrations(c *gin.Context) {
+	agentID := c.Param("id")
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id":   agentID,
+		"migrations": h.service.Schemas().Stats(agentID),
+	})
+}
+
+// GetAgentMemoryAudit godoc
+// @Summary Query an agent's memory audit log
+// @Description Returns the reads and changes of the agent's memory, newest first
+// @Tags memory
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param key query string false "Filter by memory key"
+// @Param memory_type query string false "Filter by memory type (working, longterm)"
+// @Param operation query string false "Filter by operation (read, write, update, delete, clear, search)"
+// @Param caller query string false "Filter by API caller, e.g. key:<id>"
+// @Param agent_instance query string false "Filter by agent instance"
+// @Param since query string false "Earliest access (RFC3339)"
+// @Param until query string false "Latest access (RFC3339)"
+// @Param limit query int false "Maximum records (default 100, at most 1000)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/agents/{id}/memory/audit [get]
+func (h *MemoryHandler) GetAgentMemoryAudit(c *gin.Context) {
+	agentID := c.Param("id")
+	query := memory.AuditQuery{
+		AgentID:       agentID,
+		Key:           c.Query("key"),
+		MemoryType:    memory.MemoryType(c.Query("memory_type")),
+		Operation:     memory.AuditOperation(c.Query("operation")),
+		AgentInstance: c.Query("agent_instance"),
+		Caller:        c.Query("caller"),
+	}
+	var err error
+	if v := c.Query("since"); v != "" {
+		if query.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 time"})
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if query.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 time"})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be an integer"})
+			return
+		}
+	}
+
+	records, err := h.service.QueryAudit(c.Request.Context(), query)
+	if err != nil {
+		if errors.Is(err, memory.ErrInvalidAuditQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to query memory audit log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query memory audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id": agentID,
+		"records":  records,
+		"count":    len(records),
+	})
+}
+
+// GetAgentMemorySync godoc
+// @Summary Get an agent's memory sync status
+// @Description Returns when the agent's memory was last synchronized with its other instances and the conflicts found
+// @Tags memory
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} memory.SyncStatus
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/memory/sync [get]
+func (h *MemoryHandler) GetAgentMemorySync(c *gin.Context) {
+	agentID := c.Param("id")
+
+	status, err := h.synchronizer.GetStatus(c.Request.Context(), agentID)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to get memory sync status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memory sync status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// SyncAgentMemory godoc
+// @Summary Synchronize an agent's memory
+// @Description Runs a reconciliation round with the agent's other instances instead of waiting for the periodic sync
+// @Tags memory
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Success 200 {object} memory.SyncResult
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/memory/sync [post]
+func (h *MemoryHandler) SyncAgentMemory(c *gin.Context) {
+	agentID := c.Param("id")
+
+	result, err := h.synchronizer.SyncAgent(c.Request.Context(), agentID)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to sync agent memory")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync agent memory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// attributeAccess attributes the memory accesses of a request to its caller
+// in the memory audit log: the API key it authenticated with, or the client
+// address when authentication is disabled
+func attributeAccess(c *gin.Context) {
+	caller := auth.Principal(c.Request.Context())
+	if caller == "" {
+		caller = c.ClientIP()
+	}
+	ctx := memory.WithAccessor(c.Request.Context(), memory.MemoryAccessor{Caller: caller})
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// RegisterRoutes registers agent memory routes
+func (h *MemoryHandler) RegisterRoutes(router *gin.Engine) {
+	memoryRoutes := router.Group("/api/v1/agents/:id/memory", attributeAccess)
+	memoryRoutes.GET("/export", h.ExportAgentMemory)
+	memoryRoutes.POST("/import", h.ImportAgentMemory)
+	memoryRoutes.GET("/migrations", h.GetAgentMemoryMigrations)
+	memoryRoutes.GET("/audit", h.GetAgentMemoryAudit)
+	if h.synchronizer != nil {
+		memoryRoutes.GET("/sync", h.GetAgentMemorySync)
+		memoryRoutes.POST("/sync", h.SyncAgentMemory)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/prometheus"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MetricsHandler serves the Prometheus scrape endpoint, writing the metrics
+// of every registered exporter
+type MetricsHandler struct {
+	exporters []prometheus.Exporter
+	logger    *logrus.Logger
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(logger *logrus.Logger, exporters ...prometheus.Exporter) *MetricsHandler {
+	return &MetricsHandler{
+		exporters: exporters,
+		logger:    logger,
+	}
+}
+
+// AddExporter adds an exporter whose metrics are scraped from /metrics
+func (h *MetricsHandler) AddExporter(exporter prometheus.Exporter) {
+	h.exporters = append(h.exporters, exporter)
+}
+
+// GetPrometheusMetrics godoc
+// @Summary Prometheus metrics
+// @Description Exports the metrics of the API rate limiter and the workflow engine in the Prometheus text format
+// @Tags metrics
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (h *MetricsHandler) GetPrometheusMetrics(c *gin.Context) {
+	c.Header("Content-Type", prometheus.ContentType)
+	c.Status(http.StatusOK)
+	for _, exporter := range h.exporters {
+		if err := exporter.WritePrometheusMetrics(c.Writer); err != nil {
+			h.logger.WithError(err).Error("Failed to write Prometheus metrics")
+		}
+	}
+}
+
+// RegisterRoutes registers the Prometheus scrape route
+func (h *MetricsHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/metrics", h.GetPrometheusMetrics)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// OutboxHandler exposes outbox entries for inspection and manual retry
+type OutboxHandler struct {
+	dispatcher *outbox.Dispatcher
+	logger     *logrus.Logger
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(dispatcher *outbox.Dispatcher, logger *logrus.Logger) *OutboxHandler {
+	return &OutboxHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// ListOutboxEntries godoc
+// @Summary List outbox entries
+// @Description Returns side effects recorded with committed changes and their delivery state, newest first
+// @Tags outbox
+// @Produce json
+// @Param agency_id query string false "Filter by agency"
+// @Param status query string false "Filter by status (pending, delivered, failed)"
+// @Param limit query int false "Maximum entries (default 100)"
+// @Success 200 {array} outbox.Entry
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/outbox [get]
+func (h *OutboxHandler) ListOutboxEntries(c *gin.Context) {
+	filter := outbox.Filter{
+		AgencyID: c.Query("agency_id"),
+		Status:   c.Query("status"),
+		Limit:    100,
+	}
+	switch filter.Status {
+	case "", outbox.StatusPending, outbox.StatusDelivered, outbox.StatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or failed"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.dispatcher.List(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list outbox entries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list outbox entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// RetryOutboxEntry godoc
+// @Summary Retry a failed outbox entry
+// @Description Makes a failed entry pending again and attempts delivery immediately
+// @Tags outbox
+// @Produce json
+// @Param id path string true "Agency ID"
+// @Param entryId path string true "Outbox entry ID"
+// @Success 200 {object} outbox.Entry
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/agencies/{id}/outbox/{entryId}/retry [post]
+func (h *OutboxHandler) RetryOutboxEntry(c *gin.Context) {
+	entry, err := h.dispatcher.Retry(c.Request.Context(), c.Param("id"), c.Param("entryId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, outbox.ErrEntryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, outbox.ErrNotFailed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.WithError(err).Error("Failed to retry outbox entry")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry outbox entry"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// RegisterRoutes registers outbox routes
+func (h *OutboxHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/outbox", h.ListOutboxEntries)
+	router.POST("/api/v1/agencies/:id/outbox/:entryId/retry", h.RetryOutboxEntry)
+}
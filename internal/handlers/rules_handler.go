@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/rules"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RulesHandler handles HTTP requests for alerting rules and their triggers
+type RulesHandler struct {
+	rules  *rules.Service
+	logger *logrus.Logger
+}
+
+// NewRulesHandler creates a new rules handler
+func NewRulesHandler(rulesService *rules.Service, logger *logrus.Logger) *RulesHandler {
+	return &RulesHandler{
+		rules:  rulesService,
+		logger: logger,
+	}
+}
+
+// ListRules godoc
+// @Summary List rules
+// @Tags rules
+// @Produce json
+// @Success 200 {array} rules.Rule
+// @Router /api/v1/rules [get]
+func (h *RulesHandler) ListRules(c *gin.Context) {
+	list, err := h.rules.List(c.Request.Context())
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list rules"})
+		return
+	}
+	if list == nil {
+		list = []*rules.Rule{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetRule godoc
+// @Summary Get a rule
+// @Tags rules
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} rules.Rule
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/rules/{id} [get]
+func (h *RulesHandler) GetRule(c *gin.Context) {
+	rule, err := h.rules.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateRule godoc
+// @Summary Create a rule
+// @Description Creates a rule that triggers actions when telemetry points or publication payloads meet its condition for a number of consecutive readings
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param rule body rules.Rule true "Rule"
+// @Success 201 {object} rules.Rule
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/rules [post]
+func (h *RulesHandler) CreateRule(c *gin.Context) {
+	var req rules.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.rules.Create(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to create rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, req)
+}
+
+// UpdateRule godoc
+// @Summary Update a rule
+// @Description Replaces a rule. Counts of consecutive readings start over.
+// @Tags rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param rule body rules.Rule true "Rule"
+// @Success 200 {object} rules.Rule
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/rules/{id} [put]
+func (h *RulesHandler) UpdateRule(c *gin.Context) {
+	var req rules.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ID = c.Param("id")
+
+	if err := h.rules.Update(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to update rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// DeleteRule godoc
+// @Summary Delete a rule
+// @Tags rules
+// @Param id path string true "Rule ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/rules/{id} [delete]
+func (h *RulesHandler) DeleteRule(c *gin.Context) {
+	if err := h.rules.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to delete rule")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListRuleTriggers godoc
+// @Summary List rule triggers
+// @Description Returns recent triggers of every rule, or of one rule, newest first, with the outcome of their actions
+// @Tags rules
+// @Produce json
+// @Param id path string false "Rule ID"
+// @Param limit query int false "Maximum number of triggers (default 100)"
+// @Success 200 {array} rules.Trigger
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/rules/triggers [get]
+// @Router /api/v1/rules/{id}/triggers [get]
+func (h *RulesHandler) ListRuleTriggers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	ruleID := c.Param("id")
+	if ruleID != "" {
+		if _, err := h.rules.Get(c.Request.Context(), ruleID); err != nil {
+			h.respondError(c, err, "Failed to list rule triggers")
+			return
+		}
+	}
+
+	triggers := h.rules.Triggers(ruleID, limit)
+	if triggers == nil {
+		triggers = []*rules.Trigger{}
+	}
+	c.JSON(http.StatusOK, triggers)
+}
+
+func (h *RulesHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, rules.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, rules.ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers rule routes
+func (h *RulesHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/rules", h.ListRules)
+	router.POST("/api/v1/rules", h.CreateRule)
+	router.GET("/api/v1/rules/triggers", h.ListRuleTriggers)
+	router.GET("/api/v1/rules/:id", h.GetRule)
+	router.PUT("/api/v1/rules/:id", h.UpdateRule)
+	router.DELETE("/api/v1/rules/:id", h.DeleteRule)
+	router.GET("/api/v1/rules/:id/triggers", h.ListRuleTriggers)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SimulationHandler controls the simulated clock
+type SimulationHandler struct {
+	clock  *clock.Virtual
+	logger *logrus.Logger
+}
+
+// NewSimulationHandler creates a new simulation handler
+func NewSimulationHandler(clk *clock.Virtual, logger *logrus.Logger) *SimulationHandler {
+	return &SimulationHandler{
+		clock:  clk,
+		logger: logger,
+	}
+}
+
+// AdvanceClockRequest moves the simulated clock by a duration or to a time
+type AdvanceClockRequest struct {
+	Duration string     `json:"duration,omitempty"` // Go duration, e.g. "90m"
+	To       *time.Time `json:"to,omitempty"`       // Absolute time, must not be in the past
+}
+
+// ClockSpeedRequest sets how fast the simulated clock runs
+type ClockSpeedRequest struct {
+	Speed float64 `json:"speed" binding:"required"` // Simulated seconds per real second, e.g. 3600 for an hour a second
+}
+
+// GetClock godoc
+// @Summary Get the simulated time
+// @Description Returns the simulated time, the speed it runs at and whether it is paused
+// @Tags simulation
+// @Produce json
+// @Success 200 {object} clock.VirtualStatus
+// @Router /api/v1/simulation/clock [get]
+func (h *SimulationHandler) GetClock(c *gin.Context) {
+	c.JSON(http.StatusOK, h.clock.Status())
+}
+
+// AdvanceClock godoc
+// @Summary Advance the simulated time
+// @Description Moves the simulated clock forward by a duration or to an absolute time, firing any timers that fall due
+// @Tags simulation
+// @Accept json
+// @Produce json
+// @Param request body AdvanceClockRequest true "Advance by duration or to a time"
+// @Success 200 {object} clock.VirtualStatus
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/simulation/clock/advance [post]
+func (h *SimulationHandler) AdvanceClock(c *gin.Context) {
+	var req AdvanceClockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var status clock.VirtualStatus
+	switch {
+	case req.To != nil && req.Duration != "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specify either duration or to, not both"})
+		return
+	case req.To != nil:
+		var err error
+		if status, err = h.clock.Set(*req.To); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a non-negative Go duration such as \"90m\""})
+			return
+		}
+		status = h.clock.Advance(d)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "duration or to is required"})
+		return
+	}
+
+	h.logger.WithField("now", status.Now).Info("Simulated clock advanced")
+	c.JSON(http.StatusOK, status)
+}
+
+// PauseClock godoc
+// @Summary Pause the simulated time
+// @Description Stops the simulated clock; it still moves when advanced
+// @Tags simulation
+// @Produce json
+// @Success 200 {object} clock.VirtualStatus
+// @Router /api/v1/simulation/clock/pause [post]
+func (h *SimulationHandler) PauseClock(c *gin.Context) {
+	status := h.clock.Pause()
+	h.logger.WithField("now", status.Now).Info("Simulated clock paused")
+	c.JSON(http.StatusOK, status)
+}
+
+// ResumeClock godoc
+// @Summary Resume the simulated time
+// @Description Runs a paused simulated clock again at its speed
+// @Tags simulation
+// @Produce json
+// @Success 200 {object} clock.VirtualStatus
+// @Router /api/v1/simulation/clock/resume [post]
+func (h *SimulationHandler) ResumeClock(c *gin.Context) {
+	status := h.clock.Resume()
+	h.logger.WithFields(logrus.Fields{"now": status.Now, "speed": status.Speed}).Info("Simulated clock resumed")
+	c.JSON(http.StatusOK, status)
+}
+
+// SetClockSpeed godoc
+// @Summary Set the speed of simulated time
+// @Description Sets how many simulated seconds pass per real second while the clock runs
+// @Tags simulation
+// @Accept json
+// @Produce json
+// @Param request body ClockSpeedRequest true "Speed"
+// @Success 200 {object} clock.VirtualStatus
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/simulation/clock/speed [post]
+func (h *SimulationHandler) SetClockSpeed(c *gin.Context) {
+	var req ClockSpeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.clock.SetSpeed(req.Speed)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.WithField("speed", status.Speed).Info("Simulated clock speed set")
+	c.JSON(http.StatusOK, status)
+}
+
+// RegisterRoutes registers the simulation routes
+func (h *SimulationHandler) RegisterRoutes(router *gin.Engine) {
+	simulation := router.Group("/api/v1/simulation")
+	{
+		simulation.GET("/clock", h.GetClock)
+		simulation.POST("/clock/advance", h.AdvanceClock)
+		simulation.POST("/clock/pause", h.PauseClock)
+		simulation.POST("/clock/resume", h.ResumeClock)
+		simulation.POST("/clock/speed", h.SetClockSpeed)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultStatusHistoryWindow is the lookback used when no "since" is given
+const defaultStatusHistoryWindow = 7 * 24 * time.Hour
+
+// StatusHistoryHandler handles HTTP requests for agent status history
+type StatusHistoryHandler struct {
+	history *health.StatusHistoryService
+	runtime *runtime.Manager
+	logger  *logrus.Logger
+}
+
+// NewStatusHistoryHandler creates a new status history handler
+func NewStatusHistoryHandler(history *health.StatusHistoryService, runtime *runtime.Manager, logger *logrus.Logger) *StatusHistoryHandler {
+	return &StatusHistoryHandler{
+		history: history,
+		runtime: runtime,
+		logger:  logger,
+	}
+}
+
+// RecordStatusRequest represents the request body for recording an agent status
+type RecordStatusRequest struct {
+	Status  string                 `json:"status" binding:"required"`
+	Cause   string                 `json:"cause"`
+	Source  string                 `json:"source"`
+	Details map[string]interface{} `json:"details"`
+}
+
+// GetStatusHistory godoc
+// @Summary Get agent status history
+// @Description Returns status transitions, time spent in each status and transition causes within a window
+// @Tags agents
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param since query string false "Window start (RFC3339), defaults to 7 days ago"
+// @Param until query string false "Window end (RFC3339), defaults to now"
+// @Success 200 {object} health.StatusHistory
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/status-history [get]
+func (h *StatusHistoryHandler) GetStatusHistory(c *gin.Context) {
+	agentID := c.Param("id")
+
+	if _, err := h.runtime.GetAgent(agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	until := time.Now().UTC()
+	if u := c.Query("until"); u != "" {
+		parsed, err := time.Parse(time.RFC3339, u)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 timestamp"})
+			return
+		}
+		until = parsed.UTC()
+	}
+
+	since := until.Add(-defaultStatusHistoryWindow)
+	if s := c.Query("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC3339 timestamp"})
+			return
+		}
+		since = parsed.UTC()
+	}
+
+	if !until.After(since) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until must be after since"})
+		return
+	}
+
+	history, err := h.history.GetHistory(c.Request.Context(), agentID, since, until)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to get status history")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// RecordStatus godoc
+// @Summary Record agent status
+// @Description Records an agent's operational status; a transition is stored only when the status changes
+// @Tags agents
+// @Accept json
+// @Produce json
+// @Param id path string true "Agent ID"
+// @Param status body RecordStatusRequest true "Status details"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/agents/{id}/status [post]
+func (h *StatusHistoryHandler) RecordStatus(c *gin.Context) {
+	agentID := c.Param("id")
+
+	var req RecordStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := health.OperationalStatus(req.Status)
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of NORMAL, WATCH, DEGRADED, CRITICAL"})
+		return
+	}
+
+	if _, err := h.runtime.GetAgent(agentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
+
+	source := req.Source
+	if source == "" {
+		source = "api"
+	}
+
+	transition, err := h.history.RecordStatus(c.Request.Context(), agentID, status, req.Cause, source, req.Details)
+	if err != nil {
+		h.logger.WithError(err).WithField("agent_id", agentID).Error("Failed to record status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id":   agentID,
+		"status":     status,
+		"changed":    transition != nil,
+		"transition": transition,
+	})
+}
+
+// RegisterRoutes registers the status history routes
+func (h *StatusHistoryHandler) RegisterRoutes(router *gin.Engine) {
+	agents := router.Group("/api/v1/agents")
+	{
+		agents.GET("/:id/status-history", h.GetStatusHistory)
+		agents.POST("/:id/status", h.RecordStatus)
+	}
+}
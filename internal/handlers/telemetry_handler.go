@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TelemetryHandler handles HTTP requests for telemetry ingestion and queries
+type TelemetryHandler struct {
+	telemetry *telemetry.Service
+	logger    *logrus.Logger
+}
+
+// NewTelemetryHandler creates a new telemetry handler
+func NewTelemetryHandler(telemetryService *telemetry.Service, logger *logrus.Logger) *TelemetryHandler {
+	return &TelemetryHandler{
+		telemetry: telemetryService,
+		logger:    logger,
+	}
+}
+
+// IngestTelemetryRequest carries telemetry points
+type IngestTelemetryRequest struct {
+	Points []*telemetry.Point `json:"points" binding:"required"`
+}
+
+// IngestTelemetry godoc
+// @Summary Ingest telemetry points
+// @Description Stores time-series points reported by agents. Points without a timestamp are stamped with the current time.
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Param request body IngestTelemetryRequest true "Points"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/telemetry [post]
+func (h *TelemetryHandler) IngestTelemetry(c *gin.Context) {
+	var req IngestTelemetryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.telemetry.Ingest(c.Request.Context(), req.Points); err != nil {
+		if errors.Is(err, telemetry.ErrInvalidPoint) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to ingest telemetry")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest telemetry"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"accepted": len(req.Points)})
+}
+
+// ListTelemetryPoints godoc
+// @Summary List raw telemetry points
+// @Description Returns the raw points of a metric in a time range, oldest first. Points past the raw retention have been downsampled and are only available through the query endpoint.
+// @Tags telemetry
+// @Produce json
+// @Param metric query string true "Metric name"
+// @Param agent_id query string false "Agent ID (all agents if omitted)"
+// @Param from query string false "RFC3339 start time (inclusive)"
+// @Param to query string false "RFC3339 end time (exclusive)"
+// @Param limit query int false "Maximum number of points (default 1000)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/telemetry/points [get]
+func (h *TelemetryHandler) ListTelemetryPoints(c *gin.Context) {
+	from, to, ok := parseTelemetryRange(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "1000"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	points, err := h.telemetry.Points(c.Request.Context(), telemetry.Filter{
+		AgentID: c.Query("agent_id"),
+		Metric:  c.Query("metric"),
+		From:    from,
+		To:      to,
+	}, limit)
+	if err != nil {
+		if errors.Is(err, telemetry.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to list telemetry points")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list telemetry points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"points": points,
+		"count":  len(points),
+	})
+}
+
+// QueryTelemetry godoc
+// @Summary Aggregate telemetry
+// @Description Aggregates a metric over a time range, in buckets of the interval or over the whole range. Covers both raw and downsampled data; defaults to the average over the last 24 hours.
+// @Tags telemetry
+// @Produce json
+// @Param metric query string true "Metric name"
+// @Param agent_id query string false "Agent ID (all agents if omitted)"
+// @Param from query string false "RFC3339 start time (inclusive)"
+// @Param to query string false "RFC3339 end time (exclusive)"
+// @Param aggregate query string false "avg (default), sum, min, max, count or last"
+// @Param interval query string false "Bucket width, e.g. 5m or 1h (whole range if omitted)"
+// @Success 200 {object} telemetry.QueryResult
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/telemetry/query [get]
+func (h *TelemetryHandler) QueryTelemetry(c *gin.Context) {
+	from, to, ok := parseTelemetryRange(c)
+	if !ok {
+		return
+	}
+
+	var interval time.Duration
+	if raw := c.Query("interval"); raw != "" {
+		var err error
+		if interval, err = time.ParseDuration(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interval: use a duration such as 5m"})
+			return
+		}
+	}
+
+	result, err := h.telemetry.Query(c.Request.Context(), telemetry.Query{
+		AgentID:   c.Query("agent_id"),
+		Metric:    c.Query("metric"),
+		From:      from,
+		To:        to,
+		Aggregate: c.Query("aggregate"),
+		Interval:  interval,
+	})
+	if err != nil {
+		if errors.Is(err, telemetry.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to query telemetry")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query telemetry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseTelemetryRange reads the from and to query parameters, writing a 400
+// response if either is invalid
+func parseTelemetryRange(c *gin.Context) (from, to time.Time, ok bool) {
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: use RFC3339"})
+			return from, to, false
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(time.RFC3339, raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: use RFC3339"})
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// RegisterRoutes registers the telemetry routes
+func (h *TelemetryHandler) RegisterRoutes(router *gin.Engine) {
+	router.POST("/api/v1/telemetry", h.IngestTelemetry)
+	router.GET("/api/v1/telemetry/points", h.ListTelemetryPoints)
+	router.GET("/api/v1/telemetry/query", h.QueryTelemetry)
+}
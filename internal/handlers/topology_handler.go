@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/topology"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TopologyHandler handles HTTP requests for the distribution network model,
+// valve isolation impact analysis and the asset graph
+type TopologyHandler struct {
+	topology *topology.Service
+	logger   *logrus.Logger
+}
+
+// NewTopologyHandler creates a new topology handler
+func NewTopologyHandler(topology *topology.Service, logger *logrus.Logger) *TopologyHandler {
+	return &TopologyHandler{
+		topology: topology,
+		logger:   logger,
+	}
+}
+
+// IsolationImpactRequest lists the valves a proposed isolation closes
+type IsolationImpactRequest struct {
+	ClosedValves []string `json:"closed_valves" binding:"required,min=1"`
+}
+
+// GetNetwork godoc
+// @Summary Get the distribution network
+// @Description Returns the pipes, valves and sources built from agent metadata, and the agents that could not be placed on the network
+// @Tags topology
+// @Produce json
+// @Success 200 {object} topology.Network
+// @Router /api/v1/topology/network [get]
+func (h *TopologyHandler) GetNetwork(c *gin.Context) {
+	c.JSON(http.StatusOK, h.topology.Network())
+}
+
+// AnalyzeIsolation godoc
+// @Summary Analyze a proposed isolation
+// @Description Returns the pipes, zones, customers and demand that lose supply when the valves are closed, and normally closed valves that could feed them another way
+// @Tags topology
+// @Accept json
+// @Produce json
+// @Param request body IsolationImpactRequest true "Valves to close"
+// @Success 200 {object} topology.Impact
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/topology/isolation-impact [post]
+func (h *TopologyHandler) AnalyzeIsolation(c *gin.Context) {
+	var req IsolationImpactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	impact, err := h.topology.Analyze(req.ClosedValves)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, impact)
+}
+
+// ListIsolations godoc
+// @Summary Propose isolation sets for a pipe
+// @Description Returns valve sets that cut the pipe off from every source, least affected demand first
+// @Tags topology
+// @Produce json
+// @Param id path string true "Pipe agent ID"
+// @Param inoperable query string false "Comma-separated valves that cannot be operated"
+// @Success 200 {array} topology.Impact
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/topology/pipes/{id}/isolations [get]
+func (h *TopologyHandler) ListIsolations(c *gin.Context) {
+	var inoperable []string
+	for _, valve := range strings.Split(c.Query("inoperable"), ",") {
+		if valve = strings.TrimSpace(valve); valve != "" {
+			inoperable = append(inoperable, valve)
+		}
+	}
+
+	isolations, err := h.topology.Isolations(c.Param("id"), inoperable)
+	if err != nil {
+		c.JSON(isolationStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, isolations)
+}
+
+// ListAssets godoc
+// @Summary List registered assets
+// @Tags topology
+// @Produce json
+// @Param type query string false "Only assets of this type, e.g. valve"
+// @Param zone query string false "Only assets in this zone"
+// @Success 200 {array} topology.Asset
+// @Router /api/v1/topology/assets [get]
+func (h *TopologyHandler) ListAssets(c *gin.Context) {
+	assets, err := h.topology.ListAssets(c.Request.Context(), topology.AssetFilter{
+		Type: c.Query("type"),
+		Zone: c.Query("zone"),
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to list assets")
+		return
+	}
+	if assets == nil {
+		assets = []*topology.Asset{}
+	}
+
+	c.JSON(http.StatusOK, assets)
+}
+
+// GetAsset godoc
+// @Summary Get a registered asset
+// @Tags topology
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {object} topology.Asset
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id} [get]
+func (h *TopologyHandler) GetAsset(c *gin.Context) {
+	asset, err := h.topology.GetAsset(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get asset")
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// RegisterAsset godoc
+// @Summary Register an asset
+// @Description Adds a pipe, valve, pump, sensor or other asset to the asset graph under the given ID
+// @Tags topology
+// @Accept json
+// @Produce json
+// @Param asset body topology.Asset true "Asset"
+// @Success 201 {object} topology.Asset
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/topology/assets [post]
+func (h *TopologyHandler) RegisterAsset(c *gin.Context) {
+	var req topology.Asset
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.topology.RegisterAsset(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to register asset")
+		return
+	}
+
+	c.JSON(http.StatusCreated, req)
+}
+
+// UpdateAsset godoc
+// @Summary Update a registered asset
+// @Description Replaces the details of an asset. Its connections are kept.
+// @Tags topology
+// @Accept json
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Param asset body topology.Asset true "Asset"
+// @Success 200 {object} topology.Asset
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id} [put]
+func (h *TopologyHandler) UpdateAsset(c *gin.Context) {
+	var req topology.Asset
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ID = c.Param("id")
+
+	asset, err := h.topology.UpdateAsset(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update asset")
+		return
+	}
+
+	c.JSON(http.StatusOK, asset)
+}
+
+// DeleteAsset godoc
+// @Summary Remove a registered asset
+// @Description Removes an asset and its connections from the asset graph
+// @Tags topology
+// @Param id path string true "Asset ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id} [delete]
+func (h *TopologyHandler) DeleteAsset(c *gin.Context) {
+	if err := h.topology.DeleteAsset(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to delete asset")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAssetConnections godoc
+// @Summary List the connections of an asset
+// @Tags topology
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {array} topology.Connection
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id}/connections [get]
+func (h *TopologyHandler) ListAssetConnections(c *gin.Context) {
+	connections, err := h.topology.Connections(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to list asset connections")
+		return
+	}
+	if connections == nil {
+		connections = []*topology.Connection{}
+	}
+
+	c.JSON(http.StatusOK, connections)
+}
+
+// Connect godoc
+// @Summary Connect two assets
+// @Description Adds a flow connection from an upstream asset to a downstream one, or a monitors connection from a sensor to the asset it measures
+// @Tags topology
+// @Accept json
+// @Produce json
+// @Param connection body topology.Connection true "Connection"
+// @Success 201 {object} topology.Connection
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/topology/connections [post]
+func (h *TopologyHandler) Connect(c *gin.Context) {
+	var req topology.Connection
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.topology.Connect(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to connect assets")
+		return
+	}
+
+	c.JSON(http.StatusCreated, req)
+}
+
+// Disconnect godoc
+// @Summary Remove a connection
+// @Tags topology
+// @Param id path string true "Connection ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/connections/{id} [delete]
+func (h *TopologyHandler) Disconnect(c *gin.Context) {
+	if err := h.topology.Disconnect(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to remove connection")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetUpstream godoc
+// @Summary List the assets upstream of an asset
+// @Description Returns the assets feeding an asset through flow connections, nearest first, with the path to each
+// @Tags topology
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Param depth query int false "Connections to follow (default and maximum max_traversal_depth)"
+// @Param type query []string false "Only assets of these types"
+// @Success 200 {array} topology.Reached
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id}/upstream [get]
+func (h *TopologyHandler) GetUpstream(c *gin.Context) {
+	h.walk(c, h.topology.Upstream)
+}
+
+// GetDownstream godoc
+// @Summary List the assets downstream of an asset
+// @Description Returns the assets fed by an asset through flow connections, nearest first, with the path to each
+// @Tags topology
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Param depth query int false "Connections to follow (default and maximum max_traversal_depth)"
+// @Param type query []string false "Only assets of these types"
+// @Success 200 {array} topology.Reached
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id}/downstream [get]
+func (h *TopologyHandler) GetDownstream(c *gin.Context) {
+	h.walk(c, h.topology.Downstream)
+}
+
+// GetIsolationValves godoc
+// @Summary Find the isolation valves of an asset
+// @Description Returns the first valves on every path upstream and downstream of an asset. Closing them isolates it.
+// @Tags topology
+// @Produce json
+// @Param id path string true "Asset ID"
+// @Success 200 {object} topology.ValveBoundary
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/topology/assets/{id}/isolation-valves [get]
+func (h *TopologyHandler) GetIsolationValves(c *gin.Context) {
+	boundary, err := h.topology.IsolationValves(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to find isolation valves")
+		return
+	}
+
+	c.JSON(http.StatusOK, boundary)
+}
+
+// SyncNetwork godoc
+// @Summary Sync the asset graph from the distribution network
+// @Description Registers the network's pipes, valves and sources as assets connected in the direction of flow, and removes synced assets and connections no longer in the network. Runs every sync_interval_seconds as well.
+// @Tags topology
+// @Produce json
+// @Success 200 {object} topology.SyncResult
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/topology/sync [post]
+func (h *TopologyHandler) SyncNetwork(c *gin.Context) {
+	result, err := h.topology.SyncNetwork(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err, "Failed to sync the asset graph")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// walk answers an upstream or downstream query
+func (h *TopologyHandler) walk(c *gin.Context, walk func(ctx context.Context, assetID string, maxDepth int, types ...string) ([]*topology.Reached, error)) {
+	depth := 0
+	if raw := c.Query("depth"); raw != "" {
+		var err error
+		if depth, err = strconv.Atoi(raw); err != nil || depth <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be a positive integer"})
+			return
+		}
+	}
+
+	reached, err := walk(c.Request.Context(), c.Param("id"), depth, c.QueryArray("type")...)
+	if err != nil {
+		h.respondError(c, err, "Failed to traverse assets")
+		return
+	}
+
+	c.JSON(http.StatusOK, reached)
+}
+
+func (h *TopologyHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, topology.ErrInvalidAsset):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, topology.ErrAssetNotFound), errors.Is(err, topology.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, topology.ErrAssetExists), errors.Is(err, topology.ErrConnectionExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers topology routes
+func (h *TopologyHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/topology/network", h.GetNetwork)
+	router.POST("/api/v1/topology/isolation-impact", h.AnalyzeIsolation)
+	router.GET("/api/v1/topology/pipes/:id/isolations", h.ListIsolations)
+	router.POST("/api/v1/topology/sync", h.SyncNetwork)
+
+	router.GET("/api/v1/topology/assets", h.ListAssets)
+	router.POST("/api/v1/topology/assets", h.RegisterAsset)
+	router.GET("/api/v1/topology/assets/:id", h.GetAsset)
+	router.PUT("/api/v1/topology/assets/:id", h.UpdateAsset)
+	router.DELETE("/api/v1/topology/assets/:id", h.DeleteAsset)
+	router.GET("/api/v1/topology/assets/:id/connections", h.ListAssetConnections)
+	router.GET("/api/v1/topology/assets/:id/upstream", h.GetUpstream)
+	router.GET("/api/v1/topology/assets/:id/downstream", h.GetDownstream)
+	router.GET("/api/v1/topology/assets/:id/isolation-valves", h.GetIsolationValves)
+	router.POST("/api/v1/topology/connections", h.Connect)
+	router.DELETE("/api/v1/topology/connections/:id", h.Disconnect)
+}
+
+// isolationStatus maps isolation errors to HTTP status codes
+func isolationStatus(err error) int {
+	switch {
+	case errors.Is(err, topology.ErrUnknownPipe):
+		return http.StatusNotFound
+	case errors.Is(err, topology.ErrNotIsolatable):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
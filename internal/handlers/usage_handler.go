@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/usage"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultUsageDays is the range reported when no dates are given
+const defaultUsageDays = 30
+
+// UsageHandler handles HTTP requests for tenant usage and billing export
+type UsageHandler struct {
+	usage  *usage.Service
+	logger *logrus.Logger
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(usageService *usage.Service, logger *logrus.Logger) *UsageHandler {
+	return &UsageHandler{
+		usage:  usageService,
+		logger: logger,
+	}
+}
+
+// GetUsage godoc
+// @Summary Get daily tenant usage
+// @Description Returns daily usage totals per tenant. Defaults to the last 30 days for all tenants.
+// @Tags usage
+// @Produce json
+// @Param tenant query string false "Tenant (agency) ID"
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/usage [get]
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	tenantID, from, to, err := h.parseRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.usage.Usage(c.Request.Context(), tenantID, from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get usage")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"metrics": usage.Metrics,
+		"usage":   records,
+	})
+}
+
+// ExportUsage godoc
+// @Summary Export daily tenant usage for billing
+// @Description Exports daily usage totals as CSV or JSON
+// @Tags usage
+// @Produce json,text/csv
+// @Param format query string false "Export format: csv (default) or json"
+// @Param tenant query string false "Tenant (agency) ID"
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/usage/export [get]
+func (h *UsageHandler) ExportUsage(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	tenantID, from, to, err := h.parseRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := h.usage.Usage(c.Request.Context(), tenantID, from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to export usage")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export usage"})
+		return
+	}
+
+	filename := fmt.Sprintf("usage-%s-%s.%s", from, to, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, records)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := usage.WriteCSV(c.Writer, records); err != nil {
+		h.logger.WithError(err).Error("Failed to write usage export")
+	}
+}
+
+// GetLLMUsage godoc
+// @Summary Get LLM usage
+// @Description Returns LLM token usage, latency and estimated cost in total, per agency and per builder, with the most recent calls. Defaults to the last 30 days for all agencies.
+// @Tags usage
+// @Produce json
+// @Param tenant query string false "Tenant (agency) ID"
+// @Param builder query string false "Builder, e.g. goals or work_items"
+// @Param request_id query string false "Request ID (X-Request-ID)"
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD)"
+// @Param limit query int false "Number of recent calls to list (default 50)"
+// @Success 200 {object} usage.LLMUsageReport
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/ai/usage [get]
+func (h *UsageHandler) GetLLMUsage(c *gin.Context) {
+	tenantID, from, to, err := h.parseRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+			return
+		}
+	}
+
+	// Days are inclusive, so the range ends at the start of the day after to
+	fromTime, _ := time.Parse(usage.DateFormat, from)
+	toTime, _ := time.Parse(usage.DateFormat, to)
+
+	report, err := h.usage.LLMUsage(c.Request.Context(), usage.LLMCallFilter{
+		TenantID:  tenantID,
+		Builder:   c.Query("builder"),
+		RequestID: c.Query("request_id"),
+		From:      fromTime,
+		To:        toTime.AddDate(0, 0, 1),
+		Limit:     limit,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get LLM usage")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get LLM usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseRange reads the tenant and date range query parameters
+func (h *UsageHandler) parseRange(c *gin.Context) (tenantID, from, to string, err error) {
+	now := time.Now()
+	from = c.DefaultQuery("from", usage.Day(now.AddDate(0, 0, -(defaultUsageDays-1))))
+	to = c.DefaultQuery("to", usage.Day(now))
+
+	if _, err := time.Parse(usage.DateFormat, from); err != nil {
+		return "", "", "", fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	if _, err := time.Parse(usage.DateFormat, to); err != nil {
+		return "", "", "", fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+	if from > to {
+		return "", "", "", fmt.Errorf("from date must not be after to date")
+	}
+
+	return c.Query("tenant"), from, to, nil
+}
+
+// RegisterRoutes registers the usage routes
+func (h *UsageHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/usage", h.GetUsage)
+	router.GET("/api/v1/usage/export", h.ExportUsage)
+	router.GET("/api/v1/ai/usage", h.GetLLMUsage)
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aosanya/CodeValdCortex/internal/workorder"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// WorkOrderHandler handles HTTP requests for maintenance work orders
+type WorkOrderHandler struct {
+	workOrders *workorder.Service
+	logger     *logrus.Logger
+}
+
+// NewWorkOrderHandler creates a new work order handler
+func NewWorkOrderHandler(workOrderService *workorder.Service, logger *logrus.Logger) *WorkOrderHandler {
+	return &WorkOrderHandler{
+		workOrders: workOrderService,
+		logger:     logger,
+	}
+}
+
+// ApproveWorkOrderRequest approves a pending work order
+type ApproveWorkOrderRequest struct {
+	ApprovedBy string `json:"approved_by" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// WorkOrderStatusRequest moves a work order to another status
+type WorkOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Actor  string `json:"actor"`
+	Note   string `json:"note"`
+}
+
+// CompleteWorkOrderTaskRequest marks a task as done
+type CompleteWorkOrderTaskRequest struct {
+	Actor string `json:"actor"`
+}
+
+// ListWorkOrders godoc
+// @Summary List work orders
+// @Description Returns work orders, most recently updated first
+// @Tags work-orders
+// @Produce json
+// @Param status query string false "pending, approved, in_progress, completed or cancelled"
+// @Param asset_id query string false "Only work orders on this asset"
+// @Param assigned_to query string false "Only work orders assigned to this crew or agent"
+// @Param open query bool false "Only work orders that are not completed or cancelled"
+// @Param limit query int false "Maximum number of work orders (default 100)"
+// @Success 200 {array} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/work-orders [get]
+func (h *WorkOrderHandler) ListWorkOrders(c *gin.Context) {
+	filter := workorder.Filter{
+		Status:     workorder.Status(c.Query("status")),
+		AssetID:    c.Query("asset_id"),
+		AssignedTo: c.Query("assigned_to"),
+		Open:       c.Query("open") == "true",
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown status"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+	filter.Limit = limit
+
+	workOrders, err := h.workOrders.List(c.Request.Context(), filter)
+	if err != nil {
+		h.respondError(c, err, "Failed to list work orders")
+		return
+	}
+	if workOrders == nil {
+		workOrders = []*workorder.WorkOrder{}
+	}
+
+	c.JSON(http.StatusOK, workOrders)
+}
+
+// GetWorkOrder godoc
+// @Summary Get a work order
+// @Tags work-orders
+// @Produce json
+// @Param id path string true "Work order ID"
+// @Success 200 {object} workorder.WorkOrder
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/work-orders/{id} [get]
+func (h *WorkOrderHandler) GetWorkOrder(c *gin.Context) {
+	workOrder, err := h.workOrders.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get work order")
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+// CreateWorkOrder godoc
+// @Summary Create a work order
+// @Description Creates a pending work order. A workflow_id names the workflow started when it is approved.
+// @Tags work-orders
+// @Accept json
+// @Produce json
+// @Param workOrder body workorder.WorkOrder true "Work order"
+// @Success 201 {object} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/work-orders [post]
+func (h *WorkOrderHandler) CreateWorkOrder(c *gin.Context) {
+	var req workorder.WorkOrder
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.workOrders.Create(c.Request.Context(), &req); err != nil {
+		h.respondError(c, err, "Failed to create work order")
+		return
+	}
+
+	c.JSON(http.StatusCreated, req)
+}
+
+// UpdateWorkOrder godoc
+// @Summary Update a work order
+// @Description Replaces the details of an open work order. Its status and history are kept.
+// @Tags work-orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Work order ID"
+// @Param workOrder body workorder.WorkOrder true "Work order"
+// @Success 200 {object} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/work-orders/{id} [put]
+func (h *WorkOrderHandler) UpdateWorkOrder(c *gin.Context) {
+	var req workorder.WorkOrder
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ID = c.Param("id")
+
+	workOrder, err := h.workOrders.Update(c.Request.Context(), &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update work order")
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+// ApproveWorkOrder godoc
+// @Summary Approve a work order
+// @Description Approves a pending work order and starts its workflow, if it names one
+// @Tags work-orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Work order ID"
+// @Param request body ApproveWorkOrderRequest true "Approval"
+// @Success 200 {object} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/work-orders/{id}/approve [post]
+func (h *WorkOrderHandler) ApproveWorkOrder(c *gin.Context) {
+	var req ApproveWorkOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workOrder, err := h.workOrders.Approve(c.Request.Context(), c.Param("id"), req.ApprovedBy, req.Note)
+	if err != nil {
+		h.respondError(c, err, "Failed to approve work order")
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+// TransitionWorkOrder godoc
+// @Summary Change the status of a work order
+// @Description Starts, completes or cancels a work order
+// @Tags work-orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Work order ID"
+// @Param request body WorkOrderStatusRequest true "New status"
+// @Success 200 {object} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/work-orders/{id}/status [post]
+func (h *WorkOrderHandler) TransitionWorkOrder(c *gin.Context) {
+	var req WorkOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workOrder, err := h.workOrders.Transition(c.Request.Context(), c.Param("id"), workorder.Status(req.Status), req.Actor, req.Note)
+	if err != nil {
+		h.respondError(c, err, "Failed to change work order status")
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+// CompleteWorkOrderTask godoc
+// @Summary Mark a work order task as done
+// @Tags work-orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Work order ID"
+// @Param index path int true "Task number, from zero"
+// @Param request body CompleteWorkOrderTaskRequest false "Actor"
+// @Success 200 {object} workorder.WorkOrder
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/work-orders/{id}/tasks/{index}/complete [post]
+func (h *WorkOrderHandler) CompleteWorkOrderTask(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task index must be an integer"})
+		return
+	}
+	var req CompleteWorkOrderTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	workOrder, err := h.workOrders.CompleteTask(c.Request.Context(), c.Param("id"), index, req.Actor)
+	if err != nil {
+		h.respondError(c, err, "Failed to complete work order task")
+		return
+	}
+
+	c.JSON(http.StatusOK, workOrder)
+}
+
+func (h *WorkOrderHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, workorder.ErrInvalidWorkOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, workorder.ErrWorkOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, workorder.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, workorder.ErrWorkflowStart):
+		h.logger.WithError(err).Warn(message)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// RegisterRoutes registers work order routes
+func (h *WorkOrderHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/v1/work-orders", h.ListWorkOrders)
+	router.POST("/api/v1/work-orders", h.CreateWorkOrder)
+	router.GET("/api/v1/work-orders/:id", h.GetWorkOrder)
+	router.PUT("/api/v1/work-orders/:id", h.UpdateWorkOrder)
+	router.POST("/api/v1/work-orders/:id/approve", h.ApproveWorkOrder)
+	router.POST("/api/v1/work-orders/:id/status", h.TransitionWorkOrder)
+	router.POST("/api/v1/work-orders/:id/tasks/:index/complete", h.CompleteWorkOrderTask)
+}
@@ -154,6 +154,28 @@ func (m *Monitor) RegisterAgent(agentInstance *agent.Agent) {
 	m.agents[agentInstance.ID] = agentInstance
 }
 
+// FollowState monitors agents while they run: an agent is registered and
+// monitored when it starts running, and no longer monitored once it stops or
+// fails. It is registered with runtime.Manager.OnStateChange.
+func (m *Monitor) FollowState(a *agent.Agent, from, to agent.State) {
+	switch to {
+	case agent.StateRunning:
+		m.mu.RLock()
+		_, monitoring := m.monitoring[a.ID]
+		m.mu.RUnlock()
+		if monitoring {
+			return
+		}
+		m.RegisterAgent(a)
+		if err := m.StartMonitoring(m.ctx, a.ID); err != nil {
+			m.logger.WithError(err).WithField("agent_id", a.ID).Warn("Failed to start health monitoring")
+		}
+	case agent.StateStopped, agent.StateFailed:
+		// Agents that were never monitored report an error, which is ignored
+		_ = m.StopMonitoring(a.ID)
+	}
+}
+
 // UnregisterAgent removes an agent from the monitor
 func (m *Monitor) UnregisterAgent(agentID string) {
 	m.mu.Lock()
@@ -0,0 +1,389 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+)
+
+// ScoreSignals are the inputs to an agent's health score, collected over a window
+type ScoreSignals struct {
+	// Anomalies is the number of metric anomalies reported for the agent
+	Anomalies int `json:"anomalies"`
+
+	// AlertsBySeverity counts alerts keyed by upper-cased severity (e.g. "CRITICAL")
+	AlertsBySeverity map[string]int `json:"alerts_by_severity"`
+
+	// MaintenanceEvents is the number of maintenance records (work orders, repairs)
+	MaintenanceEvents int `json:"maintenance_events"`
+
+	// LastMaintenanceAt is when the most recent maintenance record was published
+	LastMaintenanceAt *time.Time `json:"last_maintenance_at,omitempty"`
+
+	// Status is the agent's current operational status (empty if never recorded)
+	Status OperationalStatus `json:"status,omitempty"`
+
+	// Liveness is the agent's heartbeat status, empty if heartbeats are not
+	// tracked, the agent never registered or it was stopped
+	Liveness registry.Status `json:"liveness,omitempty"`
+
+	// HeartbeatLatencySeconds is the time since the agent's last heartbeat
+	HeartbeatLatencySeconds float64 `json:"heartbeat_latency_seconds"`
+
+	// TasksExecuted is the number of task results, TasksFailed those that failed
+	TasksExecuted int `json:"tasks_executed"`
+	TasksFailed   int `json:"tasks_failed"`
+
+	// MessagesReceived is the number of messages sent to the agent,
+	// MessageErrors those that failed or expired undelivered
+	MessagesReceived int `json:"messages_received"`
+	MessageErrors    int `json:"message_errors"`
+}
+
+// TotalAlerts returns the number of alerts across all severities
+func (s ScoreSignals) TotalAlerts() int {
+	total := 0
+	for _, n := range s.AlertsBySeverity {
+		total += n
+	}
+	return total
+}
+
+// TaskFailureRate returns the fraction of tasks that failed, 0 without tasks
+func (s ScoreSignals) TaskFailureRate() float64 {
+	return rate(s.TasksFailed, s.TasksExecuted)
+}
+
+// MessageErrorRate returns the fraction of messages that failed or expired,
+// 0 without messages
+func (s ScoreSignals) MessageErrorRate() float64 {
+	return rate(s.MessageErrors, s.MessagesReceived)
+}
+
+// ScoreWeights controls how much each signal lowers the score
+type ScoreWeights struct {
+	// AnomalyPenalty is deducted per anomaly, up to AnomalyMax
+	AnomalyPenalty int `json:"anomaly_penalty"`
+	AnomalyMax     int `json:"anomaly_max"`
+
+	// AlertPenalties is deducted per alert by severity; DefaultAlertPenalty is
+	// used for unknown or missing severities. The total is capped at AlertMax.
+	AlertPenalties      map[string]int `json:"alert_penalties"`
+	DefaultAlertPenalty int            `json:"default_alert_penalty"`
+	AlertMax            int            `json:"alert_max"`
+
+	// MaintenancePenalty is deducted per maintenance record, up to MaintenanceMax.
+	// Repeated maintenance inside the window indicates a recurring problem.
+	MaintenancePenalty int `json:"maintenance_penalty"`
+	MaintenanceMax     int `json:"maintenance_max"`
+
+	// StatusPenalties is deducted for the current operational status
+	StatusPenalties map[OperationalStatus]int `json:"status_penalties"`
+
+	// HeartbeatPenalty is deducted per HeartbeatIntervalSeconds of heartbeat
+	// latency, up to HeartbeatMax
+	HeartbeatIntervalSeconds int `json:"heartbeat_interval_seconds"`
+	HeartbeatPenalty         int `json:"heartbeat_penalty"`
+	HeartbeatMax             int `json:"heartbeat_max"`
+
+	// TaskFailureMax and MessageErrorMax are deducted for a 100% failure rate,
+	// proportionally for lower rates. Rates over fewer than MinRateSamples
+	// tasks or messages are ignored.
+	TaskFailureMax  int `json:"task_failure_max"`
+	MessageErrorMax int `json:"message_error_max"`
+	MinRateSamples  int `json:"min_rate_samples"`
+}
+
+// DefaultScoreWeights returns the default scoring weights
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		AnomalyPenalty: 5,
+		AnomalyMax:     25,
+		AlertPenalties: map[string]int{
+			"CRITICAL": 20,
+			"HIGH":     12,
+			"MEDIUM":   8,
+			"LOW":      4,
+		},
+		DefaultAlertPenalty: 6,
+		AlertMax:            40,
+		MaintenancePenalty:  5,
+		MaintenanceMax:      15,
+		StatusPenalties: map[OperationalStatus]int{
+			OperationalStatusNormal:   0,
+			OperationalStatusWatch:    10,
+			OperationalStatusDegraded: 25,
+			OperationalStatusCritical: 40,
+		},
+		HeartbeatIntervalSeconds: 30,
+		HeartbeatPenalty:         10,
+		HeartbeatMax:             30,
+		TaskFailureMax:           30,
+		MessageErrorMax:          20,
+		MinRateSamples:           5,
+	}
+}
+
+// missedHeartbeats returns how many heartbeat intervals passed since the
+// agent's last heartbeat
+func (w ScoreWeights) missedHeartbeats(signals ScoreSignals) int {
+	if signals.Liveness == "" || w.HeartbeatIntervalSeconds <= 0 {
+		return 0
+	}
+	return int(signals.HeartbeatLatencySeconds) / w.HeartbeatIntervalSeconds
+}
+
+// AnomalyFlag marks framework behaviour that indicates a degrading agent
+type AnomalyFlag string
+
+const (
+	// FlagHeartbeatLate indicates the agent missed heartbeats
+	FlagHeartbeatLate AnomalyFlag = "heartbeat_late"
+	// FlagTaskFailures indicates a high task failure rate
+	FlagTaskFailures AnomalyFlag = "task_failures"
+	// FlagMessageErrors indicates a high rate of failed or expired messages
+	FlagMessageErrors AnomalyFlag = "message_errors"
+	// FlagScoreDrop indicates the score dropped sharply since the previous one
+	FlagScoreDrop AnomalyFlag = "score_drop"
+)
+
+// AnomalyThresholds decide when anomaly flags are raised
+type AnomalyThresholds struct {
+	// MissedHeartbeats raises FlagHeartbeatLate
+	MissedHeartbeats int `json:"missed_heartbeats"`
+
+	// TaskFailureRate raises FlagTaskFailures (0-1)
+	TaskFailureRate float64 `json:"task_failure_rate"`
+
+	// MessageErrorRate raises FlagMessageErrors (0-1)
+	MessageErrorRate float64 `json:"message_error_rate"`
+
+	// ScoreDrop raises FlagScoreDrop when the score fell by at least this much
+	ScoreDrop int `json:"score_drop"`
+}
+
+// DefaultAnomalyThresholds returns the default anomaly thresholds
+func DefaultAnomalyThresholds() AnomalyThresholds {
+	return AnomalyThresholds{
+		MissedHeartbeats: 2,
+		TaskFailureRate:  0.2,
+		MessageErrorRate: 0.1,
+		ScoreDrop:        20,
+	}
+}
+
+// DetectAnomalies returns the anomaly flags for a score given the previous
+// score (nil if none)
+func DetectAnomalies(signals ScoreSignals, score int, previous *HealthScore, weights ScoreWeights, thresholds AnomalyThresholds) []AnomalyFlag {
+	var flags []AnomalyFlag
+	if weights.missedHeartbeats(signals) >= thresholds.MissedHeartbeats {
+		flags = append(flags, FlagHeartbeatLate)
+	}
+	if signals.TasksExecuted >= weights.MinRateSamples && signals.TaskFailureRate() >= thresholds.TaskFailureRate {
+		flags = append(flags, FlagTaskFailures)
+	}
+	if signals.MessagesReceived >= weights.MinRateSamples && signals.MessageErrorRate() >= thresholds.MessageErrorRate {
+		flags = append(flags, FlagMessageErrors)
+	}
+	if previous != nil && previous.Score-score >= thresholds.ScoreDrop {
+		flags = append(flags, FlagScoreDrop)
+	}
+	return flags
+}
+
+// ScoreComponent is one signal's contribution to a health score
+type ScoreComponent struct {
+	Name    string `json:"name"`
+	Penalty int    `json:"penalty"`
+	Detail  string `json:"detail"`
+}
+
+// HealthScore is an agent's 0-100 health score at a point in time
+type HealthScore struct {
+	// ID is the unique score identifier (ArangoDB _key)
+	ID string `json:"_key,omitempty"`
+
+	AgentID string `json:"agent_id"`
+
+	// Score is 100 for a perfectly healthy agent and 0 for the worst
+	Score int `json:"score"`
+
+	// Components explain the deductions that produced the score
+	Components []ScoreComponent `json:"components"`
+
+	Signals ScoreSignals `json:"signals"`
+
+	// Since and Until bound the window the signals were collected over
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	// TriggeredRules are the rules that fired for this score
+	TriggeredRules []string `json:"triggered_rules,omitempty"`
+
+	// Flags mark framework behaviour that indicates a degrading agent
+	Flags []AnomalyFlag `json:"flags,omitempty"`
+
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// ComputeScore turns signals into a 0-100 score and the components behind it
+func ComputeScore(signals ScoreSignals, weights ScoreWeights) (int, []ScoreComponent) {
+	components := []ScoreComponent{
+		{
+			Name:    "anomalies",
+			Penalty: capped(signals.Anomalies*weights.AnomalyPenalty, weights.AnomalyMax),
+			Detail:  fmt.Sprintf("%d anomalies", signals.Anomalies),
+		},
+		alertComponent(signals, weights),
+		{
+			Name:    "maintenance",
+			Penalty: capped(signals.MaintenanceEvents*weights.MaintenancePenalty, weights.MaintenanceMax),
+			Detail:  fmt.Sprintf("%d maintenance records", signals.MaintenanceEvents),
+		},
+		{
+			Name:    "status",
+			Penalty: weights.StatusPenalties[signals.Status],
+			Detail:  statusDetail(signals.Status),
+		},
+		heartbeatComponent(signals, weights),
+		{
+			Name:    "tasks",
+			Penalty: ratePenalty(signals.TasksFailed, signals.TasksExecuted, weights.TaskFailureMax, weights.MinRateSamples),
+			Detail:  fmt.Sprintf("%d of %d tasks failed", signals.TasksFailed, signals.TasksExecuted),
+		},
+		{
+			Name:    "messages",
+			Penalty: ratePenalty(signals.MessageErrors, signals.MessagesReceived, weights.MessageErrorMax, weights.MinRateSamples),
+			Detail:  fmt.Sprintf("%d of %d messages failed or expired", signals.MessageErrors, signals.MessagesReceived),
+		},
+	}
+
+	score := 100
+	for _, c := range components {
+		score -= c.Penalty
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	return score, components
+}
+
+// alertComponent sums alert penalties by severity
+func alertComponent(signals ScoreSignals, weights ScoreWeights) ScoreComponent {
+	penalty := 0
+	for severity, count := range signals.AlertsBySeverity {
+		p, ok := weights.AlertPenalties[severity]
+		if !ok {
+			p = weights.DefaultAlertPenalty
+		}
+		penalty += count * p
+	}
+
+	return ScoreComponent{
+		Name:    "alerts",
+		Penalty: capped(penalty, weights.AlertMax),
+		Detail:  fmt.Sprintf("%d alerts", signals.TotalAlerts()),
+	}
+}
+
+// heartbeatComponent penalizes heartbeat latency
+func heartbeatComponent(signals ScoreSignals, weights ScoreWeights) ScoreComponent {
+	if signals.Liveness == "" {
+		return ScoreComponent{Name: "heartbeat", Detail: "heartbeats not tracked"}
+	}
+
+	return ScoreComponent{
+		Name:    "heartbeat",
+		Penalty: capped(weights.missedHeartbeats(signals)*weights.HeartbeatPenalty, weights.HeartbeatMax),
+		Detail:  fmt.Sprintf("last heartbeat %.0fs ago", signals.HeartbeatLatencySeconds),
+	}
+}
+
+// ratePenalty scales max by the failure rate, ignoring small samples
+func ratePenalty(failed, total, max, minSamples int) int {
+	if total == 0 || total < minSamples {
+		return 0
+	}
+	return int(math.Round(rate(failed, total) * float64(max)))
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func statusDetail(status OperationalStatus) string {
+	if status == "" {
+		return "no status recorded"
+	}
+	return "status " + string(status)
+}
+
+func capped(value, max int) int {
+	if max > 0 && value > max {
+		return max
+	}
+	return value
+}
+
+// ScoreRule fires when an agent's score drops below a threshold. A rule fires
+// once per crossing: it does not fire again until the score has recovered.
+type ScoreRule struct {
+	Name string `json:"name"`
+
+	// Below is the threshold; the rule fires when the score is below it
+	Below int `json:"below"`
+
+	// EventName is published as an alert when the rule fires
+	EventName string `json:"event_name"`
+
+	// WorkflowID is started when the rule fires (optional)
+	WorkflowID string `json:"workflow_id,omitempty"`
+}
+
+// crossed reports whether the rule fires for a score given the previous score (nil if none)
+func (r ScoreRule) crossed(score int, previous *HealthScore) bool {
+	if score >= r.Below {
+		return false
+	}
+	return previous == nil || previous.Score >= r.Below
+}
+
+// HealthScoreRepository persists computed health scores
+type HealthScoreRepository interface {
+	// RecordScore stores a computed score
+	RecordScore(ctx context.Context, score *HealthScore) error
+
+	// LatestScore returns the agent's most recent score, or nil
+	LatestScore(ctx context.Context, agentID string) (*HealthScore, error)
+
+	// ListScores returns scores computed in (since, until], oldest first
+	ListScores(ctx context.Context, agentID string, since, until time.Time) ([]*HealthScore, error)
+}
+
+// matchesAny reports whether an event name matches any topic pattern
+func matchesAny(eventName string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if communication.MatchTopic(pattern, eventName) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSeverity upper-cases a payload severity, returning "UNKNOWN" if absent
+func normalizeSeverity(value interface{}) string {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return "UNKNOWN"
+	}
+	return strings.ToUpper(s)
+}
@@ -0,0 +1,197 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionHealthScores is the health scores collection name
+	CollectionHealthScores = "agent_health_scores"
+)
+
+// ArangoHealthScoreRepository persists health scores in ArangoDB
+type ArangoHealthScoreRepository struct {
+	db         driver.Database
+	router     *database.QueryRouter
+	collection driver.Collection
+}
+
+// NewArangoHealthScoreRepository creates a new ArangoDB-backed health score repository
+func NewArangoHealthScoreRepository(dbClient *database.ArangoClient) (*ArangoHealthScoreRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionHealthScores)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionHealthScores)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionHealthScores, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionHealthScores).Info("Created new collection")
+	}
+
+	_, _, err = col.EnsurePersistentIndex(ctx, []string{"agent_id", "computed_at"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_health_scores_agent_time",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoHealthScoreRepository{
+		db:         db,
+		router:     dbClient.Router(),
+		collection: col,
+	}, nil
+}
+
+// RecordScore stores a computed score
+func (r *ArangoHealthScoreRepository) RecordScore(ctx context.Context, score *HealthScore) error {
+	meta, err := r.collection.CreateDocument(ctx, score)
+	if err != nil {
+		return fmt.Errorf("failed to create health score: %w", err)
+	}
+
+	score.ID = meta.Key
+	return nil
+}
+
+// LatestScore returns the agent's most recent score
+func (r *ArangoHealthScoreRepository) LatestScore(ctx context.Context, agentID string) (*HealthScore, error) {
+	query := `
+		FOR s IN @@collection
+			FILTER s.agent_id == @agentID
+			SORT s.computed_at DESC
+			LIMIT 1
+			RETURN s
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionHealthScores,
+		"agentID":     agentID,
+	}
+
+	scores, err := r.query(ctx, query, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return nil, nil
+	}
+	return scores[0], nil
+}
+
+// ListScores returns scores computed in (since, until], oldest first
+func (r *ArangoHealthScoreRepository) ListScores(ctx context.Context, agentID string, since, until time.Time) ([]*HealthScore, error) {
+	query := `
+		FOR s IN @@collection
+			FILTER s.agent_id == @agentID AND s.computed_at > @since AND s.computed_at <= @until
+			SORT s.computed_at ASC
+			RETURN s
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionHealthScores,
+		"agentID":     agentID,
+		"since":       since,
+		"until":       until,
+	}
+
+	return r.query(ctx, query, bindVars)
+}
+
+// query executes an AQL query and returns health scores
+func (r *ArangoHealthScoreRepository) query(ctx context.Context, query string, bindVars map[string]interface{}) ([]*HealthScore, error) {
+	cursor, err := r.router.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query health scores: %w", err)
+	}
+	defer cursor.Close()
+
+	var scores []*HealthScore
+	for {
+		var s HealthScore
+		_, err := cursor.ReadDocument(ctx, &s)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read health score: %w", err)
+		}
+		scores = append(scores, &s)
+	}
+
+	return scores, nil
+}
+
+// InMemoryHealthScoreRepository keeps each agent's health scores in memory,
+// ordered by computation time. Score trends start over on restart.
+type InMemoryHealthScoreRepository struct {
+	mu     sync.RWMutex
+	scores map[string][]*HealthScore
+}
+
+// NewInMemoryHealthScoreRepository creates a new in-memory health score repository
+func NewInMemoryHealthScoreRepository() *InMemoryHealthScoreRepository {
+	return &InMemoryHealthScoreRepository{
+		scores: make(map[string][]*HealthScore),
+	}
+}
+
+// RecordScore stores a computed score
+func (r *InMemoryHealthScoreRepository) RecordScore(ctx context.Context, score *HealthScore) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := append(r.scores[score.AgentID], score)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].ComputedAt.Before(list[j].ComputedAt)
+	})
+	r.scores[score.AgentID] = list
+	return nil
+}
+
+// LatestScore returns the agent's most recent score
+func (r *InMemoryHealthScoreRepository) LatestScore(ctx context.Context, agentID string) (*HealthScore, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := r.scores[agentID]
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[len(list)-1], nil
+}
+
+// ListScores returns scores computed in (since, until], oldest first
+func (r *InMemoryHealthScoreRepository) ListScores(ctx context.Context, agentID string, since, until time.Time) ([]*HealthScore, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*HealthScore
+	for _, s := range r.scores[agentID] {
+		if s.ComputedAt.After(since) && !s.ComputedAt.After(until) {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+// Ensure repositories implement the interface
+var _ HealthScoreRepository = (*ArangoHealthScoreRepository)(nil)
+var _ HealthScoreRepository = (*InMemoryHealthScoreRepository)(nil)
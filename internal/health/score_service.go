@@ -0,0 +1,613 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ScorePublisherAgentID is the agent ID score rule events are published under
+	ScorePublisherAgentID = "health-scoring"
+
+	// DefaultScoreRuleEvent is published when a rule without an event name fires
+	DefaultScoreRuleEvent = "health.score.low"
+
+	// AnomalyFlagEvent is published when anomaly flags are newly raised for an agent
+	AnomalyFlagEvent = "health.anomaly"
+
+	// QueryRouteScoreHistory is the query route of score history reads, which may be served by a read replica
+	QueryRouteScoreHistory = "health.score_history"
+
+	defaultScoreInterval = 15 * time.Minute
+	defaultScoreLookback = 24 * time.Hour
+
+	// maxTaskOutcomes bounds the task results kept for each agent
+	maxTaskOutcomes = 1000
+)
+
+var (
+	// DefaultAssetPayloadKeys attribute a publication to an agent in addition to its publisher
+	DefaultAssetPayloadKeys = []string{"agent_id", "asset_id"}
+
+	// DefaultAnomalyTopics are event patterns counted as metric anomalies
+	DefaultAnomalyTopics = []string{"anomaly.#", "#.anomaly", "#.anomalies"}
+
+	// DefaultMaintenanceTopics are event patterns counted as maintenance history
+	DefaultMaintenanceTopics = []string{"maintenance.#", "#.maintenance.#", "#.workorders"}
+)
+
+// TrafficSource reads recorded pub/sub traffic. PubSubService implements it.
+type TrafficSource interface {
+	CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error)
+}
+
+// AgentLister lists the agents to score. runtime.Manager implements it.
+type AgentLister interface {
+	ListAgents() []*agent.Agent
+}
+
+// LivenessSource reports agents' heartbeats. registry.Liveness implements it.
+type LivenessSource interface {
+	Get(agentID string) registry.AgentLiveness
+}
+
+// MessageSource reads message history. MessageService implements it.
+type MessageSource interface {
+	QueryMessages(ctx context.Context, query communication.MessageQuery) ([]*communication.Message, error)
+}
+
+// WorkflowStarter runs workflows on the orchestration engine and returns the
+// ID of the execution. orchestration.DesignLauncher implements it.
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error)
+}
+
+// ScoreConfig configures health scoring
+type ScoreConfig struct {
+	Interval          time.Duration
+	Lookback          time.Duration
+	AssetPayloadKeys  []string
+	AnomalyTopics     []string
+	MaintenanceTopics []string
+	Weights           ScoreWeights
+	Anomalies         AnomalyThresholds
+	Rules             []ScoreRule
+}
+
+// ScoreConfigFromConfig converts application config into a ScoreConfig
+func ScoreConfigFromConfig(cfg config.HealthScoringConfig) ScoreConfig {
+	rules := make([]ScoreRule, 0, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rules = append(rules, ScoreRule{
+			Name:       r.Name,
+			Below:      r.Below,
+			EventName:  r.EventName,
+			WorkflowID: r.WorkflowID,
+		})
+	}
+
+	return ScoreConfig{
+		Interval:          time.Duration(cfg.IntervalSeconds) * time.Second,
+		Lookback:          time.Duration(cfg.LookbackSeconds) * time.Second,
+		AssetPayloadKeys:  cfg.AssetPayloadKeys,
+		AnomalyTopics:     cfg.AnomalyTopics,
+		MaintenanceTopics: cfg.MaintenanceTopics,
+		Anomalies: AnomalyThresholds{
+			MissedHeartbeats: cfg.AnomalyFlags.MissedHeartbeats,
+			TaskFailureRate:  cfg.AnomalyFlags.TaskFailureRate,
+			MessageErrorRate: cfg.AnomalyFlags.MessageErrorRate,
+			ScoreDrop:        cfg.AnomalyFlags.ScoreDrop,
+		},
+		Rules: rules,
+	}
+}
+
+// withDefaults fills unset fields
+func (c ScoreConfig) withDefaults() ScoreConfig {
+	if c.Interval <= 0 {
+		c.Interval = defaultScoreInterval
+	}
+	if c.Lookback <= 0 {
+		c.Lookback = defaultScoreLookback
+	}
+	if len(c.AssetPayloadKeys) == 0 {
+		c.AssetPayloadKeys = DefaultAssetPayloadKeys
+	}
+	if len(c.AnomalyTopics) == 0 {
+		c.AnomalyTopics = DefaultAnomalyTopics
+	}
+	if len(c.MaintenanceTopics) == 0 {
+		c.MaintenanceTopics = DefaultMaintenanceTopics
+	}
+	if c.Weights.StatusPenalties == nil {
+		c.Weights = DefaultScoreWeights()
+	}
+	anomalies := DefaultAnomalyThresholds()
+	if c.Anomalies.MissedHeartbeats <= 0 {
+		c.Anomalies.MissedHeartbeats = anomalies.MissedHeartbeats
+	}
+	if c.Anomalies.TaskFailureRate <= 0 {
+		c.Anomalies.TaskFailureRate = anomalies.TaskFailureRate
+	}
+	if c.Anomalies.MessageErrorRate <= 0 {
+		c.Anomalies.MessageErrorRate = anomalies.MessageErrorRate
+	}
+	if c.Anomalies.ScoreDrop <= 0 {
+		c.Anomalies.ScoreDrop = anomalies.ScoreDrop
+	}
+	for i := range c.Rules {
+		if c.Rules[i].EventName == "" {
+			c.Rules[i].EventName = DefaultScoreRuleEvent
+		}
+	}
+	return c
+}
+
+// taskOutcome is the result of one task an agent executed
+type taskOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// HealthScoreService computes, stores and acts on agent health scores.
+// Scores combine anomalies, alerts and maintenance records from pub/sub
+// traffic and the agent's operational status with how the agent behaves in
+// the framework: heartbeat latency, task failures and message errors.
+type HealthScoreService struct {
+	repo      HealthScoreRepository
+	source    TrafficSource
+	history   *StatusHistoryService
+	agents    AgentLister
+	liveness  LivenessSource
+	messages  MessageSource
+	publisher communication.TrafficPublisher
+	workflows WorkflowStarter
+	config    ScoreConfig
+	logger    *log.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
+
+	outcomesMu sync.Mutex
+	outcomes   map[string][]taskOutcome // Task results by agent, oldest first
+
+	latestMu sync.RWMutex
+	latest   map[string]*HealthScore // Most recent score by agent
+}
+
+// NewHealthScoreService creates a new health score service. source, history
+// and agents may be nil, in which case the corresponding signals are skipped
+// and scheduled scoring has nothing to score.
+func NewHealthScoreService(repo HealthScoreRepository, source TrafficSource, history *StatusHistoryService, agents AgentLister, cfg ScoreConfig, logger *log.Logger) *HealthScoreService {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &HealthScoreService{
+		repo:    repo,
+		source:  source,
+		history: history,
+		agents:  agents,
+		config:  cfg.withDefaults(),
+		logger:  logger,
+
+		outcomes: make(map[string][]taskOutcome),
+		latest:   make(map[string]*HealthScore),
+	}
+}
+
+// SetPublisher sets the publisher used for score rule events
+func (s *HealthScoreService) SetPublisher(publisher communication.TrafficPublisher) {
+	s.publisher = publisher
+}
+
+// SetLivenessSource sets the source of agent heartbeats
+func (s *HealthScoreService) SetLivenessSource(liveness LivenessSource) {
+	s.liveness = liveness
+}
+
+// SetMessageSource sets the source of agent message history
+func (s *HealthScoreService) SetMessageSource(messages MessageSource) {
+	s.messages = messages
+}
+
+// SetWorkflowStarter sets the workflow starter used by score rules
+func (s *HealthScoreService) SetWorkflowStarter(workflows WorkflowStarter) {
+	s.workflows = workflows
+}
+
+// Rules returns the configured score rules
+func (s *HealthScoreService) Rules() []ScoreRule {
+	return s.config.Rules
+}
+
+// RecordTaskResult records the outcome of a task for the task failure rate.
+// Register it with runtime.Manager.OnTaskResult.
+func (s *HealthScoreService) RecordTaskResult(result *agent.TaskResult) {
+	at := result.CompletedAt
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	s.outcomesMu.Lock()
+	defer s.outcomesMu.Unlock()
+
+	outcomes := append(s.outcomes[result.AgentID], taskOutcome{at: at, failed: !result.Success})
+	if len(outcomes) > maxTaskOutcomes {
+		outcomes = outcomes[len(outcomes)-maxTaskOutcomes:]
+	}
+	s.outcomes[result.AgentID] = outcomes
+}
+
+// Start scores all agents on the configured interval until Stop is called
+func (s *HealthScoreService) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.ScoreAll(ctx)
+			}
+		}
+	}()
+
+	s.logger.WithField("interval", s.config.Interval).Info("Health scoring started")
+}
+
+// Stop stops scheduled scoring and waits for an in-flight run to finish
+func (s *HealthScoreService) Stop() {
+	s.mu.Lock()
+	cancel, stopped := s.cancel, s.stopped
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-stopped
+}
+
+// ScoreAll scores every listed agent, logging failures
+func (s *HealthScoreService) ScoreAll(ctx context.Context) {
+	if s.agents == nil {
+		return
+	}
+
+	for _, a := range s.agents.ListAgents() {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.ScoreAgent(ctx, a.ID); err != nil {
+			s.logger.WithError(err).WithField("agent_id", a.ID).Warn("Failed to compute health score")
+		}
+	}
+}
+
+// ScoreAgent computes, stores and evaluates rules for the agent's current score
+func (s *HealthScoreService) ScoreAgent(ctx context.Context, agentID string) (*HealthScore, error) {
+	if agentID == "" {
+		return nil, fmt.Errorf("agent_id is required")
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-s.config.Lookback)
+
+	signals, err := s.collectSignals(ctx, agentID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.repo.LatestScore(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous score: %w", err)
+	}
+
+	value, components := ComputeScore(signals, s.config.Weights)
+	score := &HealthScore{
+		ID:         fmt.Sprintf("hs-%s", uuid.New().String()),
+		AgentID:    agentID,
+		Score:      value,
+		Components: components,
+		Signals:    signals,
+		Since:      since,
+		Until:      until,
+		ComputedAt: until,
+	}
+
+	for _, rule := range s.config.Rules {
+		if rule.crossed(value, previous) {
+			score.TriggeredRules = append(score.TriggeredRules, rule.Name)
+		}
+	}
+	score.Flags = DetectAnomalies(signals, value, previous, s.config.Weights, s.config.Anomalies)
+
+	if err := s.repo.RecordScore(ctx, score); err != nil {
+		return nil, fmt.Errorf("failed to record health score: %w", err)
+	}
+	s.cacheLatest(score)
+
+	for _, rule := range s.config.Rules {
+		if rule.crossed(value, previous) {
+			s.fireRule(ctx, rule, score)
+		}
+	}
+	if raised := raisedFlags(score, previous); len(raised) > 0 {
+		s.publishAnomalies(ctx, score, raised)
+	}
+
+	return score, nil
+}
+
+// LatestScore returns the agent's most recent score, or nil if never scored.
+// Scores are cached, so the agents API and dashboard can show them cheaply.
+func (s *HealthScoreService) LatestScore(ctx context.Context, agentID string) (*HealthScore, error) {
+	s.latestMu.RLock()
+	score, cached := s.latest[agentID]
+	s.latestMu.RUnlock()
+	if cached {
+		return score, nil
+	}
+
+	score, err := s.repo.LatestScore(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest score: %w", err)
+	}
+	if score != nil {
+		s.cacheLatest(score)
+	}
+	return score, nil
+}
+
+// cacheLatest records an agent's most recent score
+func (s *HealthScoreService) cacheLatest(score *HealthScore) {
+	s.latestMu.Lock()
+	defer s.latestMu.Unlock()
+
+	if current, exists := s.latest[score.AgentID]; !exists || !score.ComputedAt.Before(current.ComputedAt) {
+		s.latest[score.AgentID] = score
+	}
+}
+
+// GetHistory returns the agent's scores computed within [since, until], oldest first
+func (s *HealthScoreService) GetHistory(ctx context.Context, agentID string, since, until time.Time) ([]*HealthScore, error) {
+	if !until.After(since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+
+	scores, err := s.repo.ListScores(database.WithQueryRoute(ctx, QueryRouteScoreHistory), agentID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list health scores: %w", err)
+	}
+	if scores == nil {
+		scores = []*HealthScore{}
+	}
+	return scores, nil
+}
+
+// collectSignals gathers score inputs for the agent from traffic and status history
+func (s *HealthScoreService) collectSignals(ctx context.Context, agentID string, since, until time.Time) (ScoreSignals, error) {
+	signals := ScoreSignals{AlertsBySeverity: make(map[string]int)}
+
+	if s.history != nil {
+		status, err := s.history.CurrentStatus(ctx, agentID)
+		if err != nil {
+			return signals, err
+		}
+		signals.Status = status
+	}
+
+	if s.liveness != nil {
+		liveness := s.liveness.Get(agentID)
+		if !liveness.RegisteredAt.IsZero() && liveness.Reason != registry.ReasonAgentStopped {
+			signals.Liveness = liveness.Status
+			signals.HeartbeatLatencySeconds = max(0, until.Sub(liveness.LastSeen).Seconds())
+		}
+	}
+
+	signals.TasksExecuted, signals.TasksFailed = s.taskOutcomes(agentID, since, until)
+
+	if s.messages != nil {
+		received, errored, err := s.messageErrors(ctx, agentID, since, until)
+		if err != nil {
+			return signals, err
+		}
+		signals.MessagesReceived, signals.MessageErrors = received, errored
+	}
+
+	if s.source == nil {
+		return signals, nil
+	}
+
+	capture, err := s.source.CaptureTraffic(ctx, []string{"#"}, since, until)
+	if err != nil {
+		return signals, fmt.Errorf("failed to read traffic: %w", err)
+	}
+
+	for _, pub := range capture.Publications {
+		if !s.concernsAgent(pub, agentID) {
+			continue
+		}
+
+		switch {
+		case pub.PublicationType == communication.PublicationTypeAlert:
+			signals.AlertsBySeverity[normalizeSeverity(pub.Payload["severity"])]++
+		case matchesAny(pub.EventName, s.config.MaintenanceTopics):
+			signals.MaintenanceEvents++
+			publishedAt := pub.PublishedAt
+			if signals.LastMaintenanceAt == nil || publishedAt.After(*signals.LastMaintenanceAt) {
+				signals.LastMaintenanceAt = &publishedAt
+			}
+		case matchesAny(pub.EventName, s.config.AnomalyTopics) || pub.Payload["anomaly"] == true:
+			signals.Anomalies++
+		}
+	}
+
+	return signals, nil
+}
+
+// taskOutcomes counts the agent's task results within (since, until]
+func (s *HealthScoreService) taskOutcomes(agentID string, since, until time.Time) (executed, failed int) {
+	s.outcomesMu.Lock()
+	defer s.outcomesMu.Unlock()
+
+	outcomes := s.outcomes[agentID]
+	kept := outcomes[:0]
+	for _, o := range outcomes {
+		if !o.at.After(since) {
+			continue // Outside the lookback, never counted again
+		}
+		kept = append(kept, o)
+		if o.at.After(until) {
+			continue
+		}
+		executed++
+		if o.failed {
+			failed++
+		}
+	}
+	s.outcomes[agentID] = kept
+	return executed, failed
+}
+
+// messageErrors counts the messages sent to the agent within [since, until]
+// and those that failed or expired undelivered. Only the most recent
+// MaxMessageQueryLimit messages are sampled.
+func (s *HealthScoreService) messageErrors(ctx context.Context, agentID string, since, until time.Time) (received, errored int, err error) {
+	messages, err := s.messages.QueryMessages(ctx, communication.MessageQuery{
+		ToAgentID: agentID,
+		Since:     since,
+		Until:     until,
+		Limit:     communication.MaxMessageQueryLimit,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	for _, msg := range messages {
+		received++
+		switch {
+		case msg.Status == communication.MessageStatusFailed,
+			msg.Status == communication.MessageStatusExpired,
+			msg.Status == communication.MessageStatusPending && msg.ExpiresAt != nil && msg.ExpiresAt.Before(until):
+			errored++
+		}
+	}
+	return received, errored, nil
+}
+
+// concernsAgent reports whether a publication was published by or about the agent
+func (s *HealthScoreService) concernsAgent(pub communication.CapturedPublication, agentID string) bool {
+	if pub.PublisherAgentID == agentID {
+		return true
+	}
+	for _, key := range s.config.AssetPayloadKeys {
+		if id, ok := pub.Payload[key].(string); ok && id == agentID {
+			return true
+		}
+	}
+	return false
+}
+
+// raisedFlags returns the score's flags the previous score did not have
+func raisedFlags(score, previous *HealthScore) []AnomalyFlag {
+	var raised []AnomalyFlag
+	for _, flag := range score.Flags {
+		if previous == nil || !slices.Contains(previous.Flags, flag) {
+			raised = append(raised, flag)
+		}
+	}
+	return raised
+}
+
+// publishAnomalies publishes newly raised anomaly flags as an alert
+func (s *HealthScoreService) publishAnomalies(ctx context.Context, score *HealthScore, raised []AnomalyFlag) {
+	s.logger.WithFields(log.Fields{
+		"agent_id": score.AgentID,
+		"score":    score.Score,
+		"flags":    raised,
+	}).Warn("Agent health anomalies detected")
+
+	if s.publisher == nil {
+		return
+	}
+	_, err := s.publisher.Publish(ctx, ScorePublisherAgentID, ScorePublisherAgentID, AnomalyFlagEvent, map[string]interface{}{
+		"agent_id":   score.AgentID,
+		"score":      score.Score,
+		"flags":      raised,
+		"score_id":   score.ID,
+		"components": score.Components,
+	}, &communication.PublicationOptions{
+		Type: communication.PublicationTypeAlert,
+	})
+	if err != nil {
+		s.logger.WithError(err).WithField("agent_id", score.AgentID).Warn("Failed to publish health anomaly event")
+	}
+}
+
+// fireRule publishes the rule's event and starts its workflow
+func (s *HealthScoreService) fireRule(ctx context.Context, rule ScoreRule, score *HealthScore) {
+	logger := s.logger.WithFields(log.Fields{
+		"agent_id": score.AgentID,
+		"score":    score.Score,
+		"rule":     rule.Name,
+	})
+	logger.Info("Health score rule triggered")
+
+	details := map[string]interface{}{
+		"agent_id":   score.AgentID,
+		"score":      score.Score,
+		"threshold":  rule.Below,
+		"rule":       rule.Name,
+		"score_id":   score.ID,
+		"components": score.Components,
+	}
+
+	if s.publisher != nil {
+		_, err := s.publisher.Publish(ctx, ScorePublisherAgentID, ScorePublisherAgentID, rule.EventName, details, &communication.PublicationOptions{
+			Type: communication.PublicationTypeAlert,
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to publish health score event")
+		}
+	}
+
+	if rule.WorkflowID != "" {
+		if s.workflows == nil {
+			logger.WithField("workflow_id", rule.WorkflowID).Warn("Workflow starter unavailable, skipping rule workflow")
+			return
+		}
+		if _, err := s.workflows.StartWorkflow(ctx, rule.WorkflowID, ScorePublisherAgentID, details); err != nil {
+			logger.WithError(err).WithField("workflow_id", rule.WorkflowID).Warn("Failed to start rule workflow")
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTrafficSource struct {
+	publications []communication.CapturedPublication
+}
+
+func (f *fakeTrafficSource) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error) {
+	return &communication.TrafficCapture{Publications: f.publications}, nil
+}
+
+type fakeLiveness struct {
+	liveness registry.AgentLiveness
+}
+
+func (f *fakeLiveness) Get(agentID string) registry.AgentLiveness {
+	return f.liveness
+}
+
+type fakeMessages struct {
+	messages []*communication.Message
+}
+
+func (f *fakeMessages) QueryMessages(ctx context.Context, query communication.MessageQuery) ([]*communication.Message, error) {
+	return f.messages, nil
+}
+
+type recordingPublisher struct {
+	events []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *communication.PublicationOptions) (string, error) {
+	p.events = append(p.events, eventName)
+	return "pub-1", nil
+}
+
+type recordingWorkflowStarter struct {
+	started []string
+}
+
+func (w *recordingWorkflowStarter) StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error) {
+	w.started = append(w.started, workflowID)
+	return "exec-1", nil
+}
+
+func TestComputeScore(t *testing.T) {
+	weights := DefaultScoreWeights()
+
+	score, components := ComputeScore(ScoreSignals{Status: OperationalStatusNormal}, weights)
+	assert.Equal(t, 100, score)
+	assert.Len(t, components, 7)
+
+	score, _ = ComputeScore(ScoreSignals{
+		Anomalies:         2,
+		AlertsBySeverity:  map[string]int{"LOW": 1, "MEDIUM": 1},
+		MaintenanceEvents: 1,
+		Status:            OperationalStatusWatch,
+	}, weights)
+	assert.Equal(t, 100-10-12-5-10, score)
+
+	// Penalties are capped per component and the score never goes negative
+	score, components = ComputeScore(ScoreSignals{
+		Anomalies:         100,
+		AlertsBySeverity:  map[string]int{"CRITICAL": 10},
+		MaintenanceEvents: 10,
+		Status:            OperationalStatusCritical,
+	}, weights)
+	assert.Equal(t, 0, score)
+	assert.Equal(t, weights.AnomalyMax, components[0].Penalty)
+	assert.Equal(t, weights.AlertMax, components[1].Penalty)
+}
+
+func TestHealthScoreService_ScoreAgentAndRules(t *testing.T) {
+	logger := log.New()
+	logger.SetLevel(log.ErrorLevel)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	history := NewStatusHistoryService(NewInMemoryStatusHistoryRepository(), logger)
+	_, err := history.RecordStatus(ctx, "PUMP-002", OperationalStatusDegraded, "efficiency drop", "api", nil)
+	require.NoError(t, err)
+
+	source := &fakeTrafficSource{publications: []communication.CapturedPublication{
+		{PublisherAgentID: "PUMP-002", EventName: "zone.north.maintenance.alerts", PublicationType: communication.PublicationTypeAlert, Payload: map[string]interface{}{"severity": "critical"}},
+		{PublisherAgentID: "COORD-NORTH", EventName: "zone.north.maintenance.workorders", PublicationType: communication.PublicationTypeEvent, Payload: map[string]interface{}{"pump_id": "PUMP-002"}, PublishedAt: now},
+		{PublisherAgentID: "PUMP-002", EventName: "pump.vibration.anomaly", PublicationType: communication.PublicationTypeMetric},
+		{PublisherAgentID: "PUMP-001", EventName: "pump.vibration.anomaly", PublicationType: communication.PublicationTypeMetric},
+	}}
+
+	repo := NewInMemoryHealthScoreRepository()
+	svc := NewHealthScoreService(repo, source, history, nil, ScoreConfig{
+		AssetPayloadKeys: []string{"agent_id", "pump_id"},
+		Rules:            []ScoreRule{{Name: "inspection", Below: 60, WorkflowID: "wf-inspect"}},
+	}, logger)
+	publisher := &recordingPublisher{}
+	workflows := &recordingWorkflowStarter{}
+	svc.SetPublisher(publisher)
+	svc.SetWorkflowStarter(workflows)
+
+	score, err := svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, score.Signals.Anomalies)
+	assert.Equal(t, map[string]int{"CRITICAL": 1}, score.Signals.AlertsBySeverity)
+	assert.Equal(t, 1, score.Signals.MaintenanceEvents)
+	assert.NotNil(t, score.Signals.LastMaintenanceAt)
+	assert.Equal(t, 100-5-20-5-25, score.Score)
+	assert.Equal(t, []string{"inspection"}, score.TriggeredRules)
+	assert.Equal(t, []string{DefaultScoreRuleEvent}, publisher.events)
+	assert.Equal(t, []string{"wf-inspect"}, workflows.started)
+
+	// Still below the threshold: the rule does not fire again
+	second, err := svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Empty(t, second.TriggeredRules)
+	assert.Len(t, workflows.started, 1)
+
+	latest, err := svc.LatestScore(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, latest.ID)
+
+	scores, err := svc.GetHistory(ctx, "PUMP-002", now.Add(-time.Minute), time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, scores, 2)
+}
+
+func TestHealthScoreService_FrameworkSignalsAndFlags(t *testing.T) {
+	logger := log.New()
+	logger.SetLevel(log.ErrorLevel)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	liveness := &fakeLiveness{liveness: registry.AgentLiveness{
+		AgentID:      "PUMP-002",
+		Status:       registry.StatusOnline,
+		RegisteredAt: now.Add(-time.Hour),
+		LastSeen:     now,
+	}}
+	messages := &fakeMessages{}
+	publisher := &recordingPublisher{}
+
+	svc := NewHealthScoreService(NewInMemoryHealthScoreRepository(), nil, nil, nil, ScoreConfig{}, logger)
+	svc.SetLivenessSource(liveness)
+	svc.SetMessageSource(messages)
+	svc.SetPublisher(publisher)
+
+	for i := 0; i < 10; i++ {
+		svc.RecordTaskResult(&agent.TaskResult{AgentID: "PUMP-002", Success: true, CompletedAt: now.Add(-time.Minute)})
+	}
+	svc.RecordTaskResult(&agent.TaskResult{AgentID: "PUMP-001", Success: false, CompletedAt: now.Add(-time.Minute)})
+
+	healthy, err := svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Equal(t, 100, healthy.Score)
+	assert.Equal(t, 10, healthy.Signals.TasksExecuted)
+	assert.Empty(t, healthy.Flags)
+	assert.Empty(t, publisher.events)
+
+	// PUMP-002 degrades: late heartbeats, failing tasks and expiring messages
+	liveness.liveness.Status = registry.StatusDegraded
+	liveness.liveness.LastSeen = now.Add(-100 * time.Second)
+	for i := 0; i < 10; i++ {
+		svc.RecordTaskResult(&agent.TaskResult{AgentID: "PUMP-002", Success: false, CompletedAt: now})
+	}
+	expired := now.Add(-time.Minute)
+	messages.messages = []*communication.Message{
+		{ToAgentID: "PUMP-002", Status: communication.MessageStatusDelivered},
+		{ToAgentID: "PUMP-002", Status: communication.MessageStatusDelivered},
+		{ToAgentID: "PUMP-002", Status: communication.MessageStatusDelivered},
+		{ToAgentID: "PUMP-002", Status: communication.MessageStatusFailed},
+		{ToAgentID: "PUMP-002", Status: communication.MessageStatusPending, ExpiresAt: &expired},
+	}
+
+	degraded, err := svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Equal(t, registry.StatusDegraded, degraded.Signals.Liveness)
+	assert.Equal(t, 20, degraded.Signals.TasksExecuted)
+	assert.Equal(t, 10, degraded.Signals.TasksFailed)
+	assert.Equal(t, 2, degraded.Signals.MessageErrors)
+	// 3 missed heartbeats, half the tasks failed, 2 of 5 messages errored
+	assert.Equal(t, 100-30-15-8, degraded.Score)
+	assert.Equal(t, []AnomalyFlag{FlagHeartbeatLate, FlagTaskFailures, FlagMessageErrors, FlagScoreDrop}, degraded.Flags)
+	assert.Equal(t, []string{AnomalyFlagEvent}, publisher.events)
+
+	// Flags that stay raised are not published again
+	_, err = svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Len(t, publisher.events, 1)
+
+	latest, err := svc.LatestScore(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Contains(t, latest.Flags, FlagTaskFailures)
+
+	// Stopped agents are not penalized for missing heartbeats
+	liveness.liveness.Status = registry.StatusOffline
+	liveness.liveness.Reason = registry.ReasonAgentStopped
+	stopped, err := svc.ScoreAgent(ctx, "PUMP-002")
+	require.NoError(t, err)
+	assert.Empty(t, stopped.Signals.Liveness)
+	assert.NotContains(t, stopped.Flags, FlagHeartbeatLate)
+}
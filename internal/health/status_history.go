@@ -0,0 +1,341 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// QueryRouteStatusHistory is the query route of status history reads, which may be served by a read replica
+const QueryRouteStatusHistory = "health.status_history"
+
+// OperationalStatus is the coarse-grained status of an agent or asset as
+// reported to operators. Unlike HealthStatus it describes the monitored
+// asset rather than the agent process.
+type OperationalStatus string
+
+const (
+	// OperationalStatusNormal indicates the asset is operating within limits
+	OperationalStatusNormal OperationalStatus = "NORMAL"
+	// OperationalStatusWatch indicates early warning signs worth observing
+	OperationalStatusWatch OperationalStatus = "WATCH"
+	// OperationalStatusDegraded indicates reduced performance requiring action
+	OperationalStatusDegraded OperationalStatus = "DEGRADED"
+	// OperationalStatusCritical indicates failure or imminent failure
+	OperationalStatusCritical OperationalStatus = "CRITICAL"
+)
+
+// IsValid reports whether the status is one of the known operational statuses
+func (s OperationalStatus) IsValid() bool {
+	switch s {
+	case OperationalStatusNormal, OperationalStatusWatch, OperationalStatusDegraded, OperationalStatusCritical:
+		return true
+	}
+	return false
+}
+
+// OperationalStatusFromHealth maps a health monitor status to an operational status.
+// The second return value is false when the health status carries no operational meaning.
+func OperationalStatusFromHealth(status HealthStatus) (OperationalStatus, bool) {
+	switch status {
+	case HealthStatusHealthy:
+		return OperationalStatusNormal, true
+	case HealthStatusDegraded:
+		return OperationalStatusWatch, true
+	case HealthStatusUnhealthy:
+		return OperationalStatusDegraded, true
+	case HealthStatusCritical:
+		return OperationalStatusCritical, true
+	}
+	return "", false
+}
+
+// OperationalStatusFromState maps an agent lifecycle state to an operational status.
+// The second return value is false for states with no operational meaning.
+func OperationalStatusFromState(state agent.State) (OperationalStatus, bool) {
+	switch state {
+	case agent.StateRunning:
+		return OperationalStatusNormal, true
+	case agent.StatePaused, agent.StateDraining:
+		return OperationalStatusWatch, true
+	case agent.StateStopped:
+		return OperationalStatusDegraded, true
+	case agent.StateFailed:
+		return OperationalStatusCritical, true
+	}
+	return "", false
+}
+
+// StatusTransition records a change in an agent's operational status
+type StatusTransition struct {
+	// ID is the unique transition identifier (ArangoDB _key)
+	ID string `json:"_key,omitempty"`
+
+	// AgentID identifies the agent whose status changed
+	AgentID string `json:"agent_id"`
+
+	// FromStatus is the previous status (empty for the first recorded status)
+	FromStatus OperationalStatus `json:"from_status,omitempty"`
+
+	// ToStatus is the new status
+	ToStatus OperationalStatus `json:"to_status"`
+
+	// Cause explains why the transition happened
+	Cause string `json:"cause"`
+
+	// Source identifies what produced the transition (e.g. "health_monitor", "rules_engine", "api")
+	Source string `json:"source"`
+
+	// Details contains additional structured context such as triggering readings
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// Timestamp is when the transition occurred
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StatusPeriod is a contiguous span of time spent in one status
+type StatusPeriod struct {
+	Status          OperationalStatus `json:"status"`
+	Cause           string            `json:"cause"`
+	StartedAt       time.Time         `json:"started_at"`
+	EndedAt         *time.Time        `json:"ended_at,omitempty"`
+	DurationSeconds float64           `json:"duration_seconds"`
+}
+
+// StatusHistory summarises an agent's status transitions over a time window
+type StatusHistory struct {
+	AgentID       string            `json:"agent_id"`
+	Since         time.Time         `json:"since"`
+	Until         time.Time         `json:"until"`
+	CurrentStatus OperationalStatus `json:"current_status,omitempty"`
+
+	// Transitions are the transitions that occurred inside the window, oldest first
+	Transitions []*StatusTransition `json:"transitions"`
+
+	// Periods are the spans spent in each status, clipped to the window
+	Periods []StatusPeriod `json:"periods"`
+
+	// TimeInStatus is the total seconds spent in each status within the window
+	TimeInStatus map[OperationalStatus]float64 `json:"time_in_status"`
+}
+
+// StatusHistoryRepository persists operational status transitions
+type StatusHistoryRepository interface {
+	// RecordTransition stores a new transition
+	RecordTransition(ctx context.Context, transition *StatusTransition) error
+
+	// LatestTransitionBefore returns the most recent transition at or before the given time, or nil
+	LatestTransitionBefore(ctx context.Context, agentID string, at time.Time) (*StatusTransition, error)
+
+	// ListTransitions returns transitions in (since, until], oldest first
+	ListTransitions(ctx context.Context, agentID string, since, until time.Time) ([]*StatusTransition, error)
+}
+
+// StatusHistoryService records operational status transitions and builds history reports
+type StatusHistoryService struct {
+	repo   StatusHistoryRepository
+	logger *log.Logger
+}
+
+// NewStatusHistoryService creates a new status history service
+func NewStatusHistoryService(repo StatusHistoryRepository, logger *log.Logger) *StatusHistoryService {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &StatusHistoryService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// RecordStatus records the agent's current status. A transition is only stored
+// when the status differs from the last recorded one, so callers may report the
+// same status repeatedly. It returns the stored transition or nil if unchanged.
+func (s *StatusHistoryService) RecordStatus(ctx context.Context, agentID string, status OperationalStatus, cause, source string, details map[string]interface{}) (*StatusTransition, error) {
+	if agentID == "" {
+		return nil, fmt.Errorf("agent_id is required")
+	}
+	if !status.IsValid() {
+		return nil, fmt.Errorf("invalid operational status: %s", status)
+	}
+
+	now := time.Now().UTC()
+
+	latest, err := s.repo.LatestTransitionBefore(ctx, agentID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest status: %w", err)
+	}
+
+	if latest != nil && latest.ToStatus == status {
+		return nil, nil
+	}
+
+	transition := &StatusTransition{
+		ID:        fmt.Sprintf("st-%s", uuid.New().String()),
+		AgentID:   agentID,
+		ToStatus:  status,
+		Cause:     cause,
+		Source:    source,
+		Details:   details,
+		Timestamp: now,
+	}
+	if latest != nil {
+		transition.FromStatus = latest.ToStatus
+	}
+
+	if err := s.repo.RecordTransition(ctx, transition); err != nil {
+		return nil, fmt.Errorf("failed to record status transition: %w", err)
+	}
+
+	s.logger.WithFields(log.Fields{
+		"agent_id": agentID,
+		"from":     transition.FromStatus,
+		"to":       transition.ToStatus,
+		"cause":    cause,
+	}).Info("Agent status transition recorded")
+
+	return transition, nil
+}
+
+// CurrentStatus returns the agent's most recently recorded status, or empty if none
+func (s *StatusHistoryService) CurrentStatus(ctx context.Context, agentID string) (OperationalStatus, error) {
+	latest, err := s.repo.LatestTransitionBefore(ctx, agentID, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest status: %w", err)
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.ToStatus, nil
+}
+
+// GetHistory builds the status history for an agent within [since, until]
+func (s *StatusHistoryService) GetHistory(ctx context.Context, agentID string, since, until time.Time) (*StatusHistory, error) {
+	if !until.After(since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+	ctx = database.WithQueryRoute(ctx, QueryRouteStatusHistory)
+
+	// The status in effect at the start of the window
+	initial, err := s.repo.LatestTransitionBefore(ctx, agentID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial status: %w", err)
+	}
+
+	transitions, err := s.repo.ListTransitions(ctx, agentID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list status transitions: %w", err)
+	}
+
+	history := &StatusHistory{
+		AgentID:      agentID,
+		Since:        since,
+		Until:        until,
+		Transitions:  transitions,
+		Periods:      []StatusPeriod{},
+		TimeInStatus: make(map[OperationalStatus]float64),
+	}
+	if history.Transitions == nil {
+		history.Transitions = []*StatusTransition{}
+	}
+
+	// Walk the transitions, closing each period at the next transition
+	var current *StatusTransition
+	periodStart := since
+	if initial != nil {
+		current = initial
+	}
+
+	for _, t := range transitions {
+		if current != nil {
+			history.addPeriod(current, periodStart, t.Timestamp, true)
+		}
+		current = t
+		periodStart = t.Timestamp
+	}
+
+	if current != nil {
+		// The last period is still open unless the window ends in the past
+		closed := until.Before(time.Now().UTC())
+		history.addPeriod(current, periodStart, until, closed)
+		history.CurrentStatus = current.ToStatus
+	}
+
+	return history, nil
+}
+
+// addPeriod appends a status period and accumulates its duration
+func (h *StatusHistory) addPeriod(t *StatusTransition, start, end time.Time, closed bool) {
+	duration := end.Sub(start).Seconds()
+	period := StatusPeriod{
+		Status:          t.ToStatus,
+		Cause:           t.Cause,
+		StartedAt:       start,
+		DurationSeconds: duration,
+	}
+	if closed {
+		endedAt := end
+		period.EndedAt = &endedAt
+	}
+
+	h.Periods = append(h.Periods, period)
+	h.TimeInStatus[t.ToStatus] += duration
+}
+
+// StatusHistoryPublisher records operational status transitions derived from
+// health events before forwarding them to the next publisher (if any)
+type StatusHistoryPublisher struct {
+	history *StatusHistoryService
+	next    HealthEventPublisher
+}
+
+// NewStatusHistoryPublisher wraps a health event publisher with status history recording
+func NewStatusHistoryPublisher(history *StatusHistoryService, next HealthEventPublisher) *StatusHistoryPublisher {
+	return &StatusHistoryPublisher{
+		history: history,
+		next:    next,
+	}
+}
+
+// PublishHealthEvent records the status transition and forwards the event
+func (p *StatusHistoryPublisher) PublishHealthEvent(ctx context.Context, event *HealthEvent) error {
+	if status, ok := OperationalStatusFromHealth(event.CurrentStatus); ok {
+		details := map[string]interface{}{
+			"health_event_type": string(event.Type),
+			"previous_health":   string(event.PreviousStatus),
+			"current_health":    string(event.CurrentStatus),
+		}
+		if _, err := p.history.RecordStatus(ctx, event.AgentID, status, event.Message, "health_monitor", details); err != nil {
+			p.history.logger.WithError(err).WithField("agent_id", event.AgentID).Warn("Failed to record status transition")
+		}
+	}
+
+	if p.next != nil {
+		return p.next.PublishHealthEvent(ctx, event)
+	}
+	return nil
+}
+
+// RecordStateChange records the operational status of an agent that changed
+// lifecycle state. It is registered with runtime.Manager.OnStateChange.
+func (s *StatusHistoryService) RecordStateChange(a *agent.Agent, from, to agent.State) {
+	status, ok := OperationalStatusFromState(to)
+	if !ok {
+		return
+	}
+
+	details := map[string]interface{}{
+		"previous_state": string(from),
+		"current_state":  string(to),
+	}
+	cause := fmt.Sprintf("Agent %s changed state from %s to %s", a.ID, from, to)
+	if _, err := s.RecordStatus(context.Background(), a.ID, status, cause, "lifecycle", details); err != nil {
+		s.logger.WithError(err).WithField("agent_id", a.ID).Warn("Failed to record status transition")
+	}
+}
@@ -0,0 +1,200 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionStatusTransitions is the status transitions collection name
+	CollectionStatusTransitions = "agent_status_transitions"
+)
+
+// ArangoStatusHistoryRepository persists status transitions in ArangoDB
+type ArangoStatusHistoryRepository struct {
+	db         driver.Database
+	router     *database.QueryRouter
+	collection driver.Collection
+}
+
+// NewArangoStatusHistoryRepository creates a new ArangoDB-backed status history repository
+func NewArangoStatusHistoryRepository(dbClient *database.ArangoClient) (*ArangoStatusHistoryRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionStatusTransitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionStatusTransitions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionStatusTransitions, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionStatusTransitions).Info("Created new collection")
+	}
+
+	_, _, err = col.EnsurePersistentIndex(ctx, []string{"agent_id", "timestamp"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_status_transitions_agent_time",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoStatusHistoryRepository{
+		db:         db,
+		router:     dbClient.Router(),
+		collection: col,
+	}, nil
+}
+
+// RecordTransition stores a new transition
+func (r *ArangoStatusHistoryRepository) RecordTransition(ctx context.Context, transition *StatusTransition) error {
+	meta, err := r.collection.CreateDocument(ctx, transition)
+	if err != nil {
+		return fmt.Errorf("failed to create status transition: %w", err)
+	}
+
+	transition.ID = meta.Key
+	return nil
+}
+
+// LatestTransitionBefore returns the most recent transition at or before the given time
+func (r *ArangoStatusHistoryRepository) LatestTransitionBefore(ctx context.Context, agentID string, at time.Time) (*StatusTransition, error) {
+	query := `
+		FOR t IN @@collection
+			FILTER t.agent_id == @agentID AND t.timestamp <= @at
+			SORT t.timestamp DESC
+			LIMIT 1
+			RETURN t
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionStatusTransitions,
+		"agentID":     agentID,
+		"at":          at,
+	}
+
+	transitions, err := r.query(ctx, query, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	if len(transitions) == 0 {
+		return nil, nil
+	}
+	return transitions[0], nil
+}
+
+// ListTransitions returns transitions in (since, until], oldest first
+func (r *ArangoStatusHistoryRepository) ListTransitions(ctx context.Context, agentID string, since, until time.Time) ([]*StatusTransition, error) {
+	query := `
+		FOR t IN @@collection
+			FILTER t.agent_id == @agentID AND t.timestamp > @since AND t.timestamp <= @until
+			SORT t.timestamp ASC
+			RETURN t
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionStatusTransitions,
+		"agentID":     agentID,
+		"since":       since,
+		"until":       until,
+	}
+
+	return r.query(ctx, query, bindVars)
+}
+
+// query executes an AQL query and returns status transitions
+func (r *ArangoStatusHistoryRepository) query(ctx context.Context, query string, bindVars map[string]interface{}) ([]*StatusTransition, error) {
+	cursor, err := r.router.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status transitions: %w", err)
+	}
+	defer cursor.Close()
+
+	var transitions []*StatusTransition
+	for {
+		var t StatusTransition
+		_, err := cursor.ReadDocument(ctx, &t)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read status transition: %w", err)
+		}
+		transitions = append(transitions, &t)
+	}
+
+	return transitions, nil
+}
+
+// InMemoryStatusHistoryRepository keeps each agent's status transitions in
+// memory, ordered by time. Uptime is only known since the process started.
+type InMemoryStatusHistoryRepository struct {
+	mu          sync.RWMutex
+	transitions map[string][]*StatusTransition
+}
+
+// NewInMemoryStatusHistoryRepository creates a new in-memory status history repository
+func NewInMemoryStatusHistoryRepository() *InMemoryStatusHistoryRepository {
+	return &InMemoryStatusHistoryRepository{
+		transitions: make(map[string][]*StatusTransition),
+	}
+}
+
+// RecordTransition stores a new transition
+func (r *InMemoryStatusHistoryRepository) RecordTransition(ctx context.Context, transition *StatusTransition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := append(r.transitions[transition.AgentID], transition)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Timestamp.Before(list[j].Timestamp)
+	})
+	r.transitions[transition.AgentID] = list
+	return nil
+}
+
+// LatestTransitionBefore returns the most recent transition at or before the given time
+func (r *InMemoryStatusHistoryRepository) LatestTransitionBefore(ctx context.Context, agentID string, at time.Time) (*StatusTransition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := r.transitions[agentID]
+	for i := len(list) - 1; i >= 0; i-- {
+		if !list[i].Timestamp.After(at) {
+			return list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListTransitions returns transitions in (since, until], oldest first
+func (r *InMemoryStatusHistoryRepository) ListTransitions(ctx context.Context, agentID string, since, until time.Time) ([]*StatusTransition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*StatusTransition
+	for _, t := range r.transitions[agentID] {
+		if t.Timestamp.After(since) && !t.Timestamp.After(until) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// Ensure repositories implement the interface
+var _ StatusHistoryRepository = (*ArangoStatusHistoryRepository)(nil)
+var _ StatusHistoryRepository = (*InMemoryStatusHistoryRepository)(nil)
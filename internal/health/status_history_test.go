@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStatusHistoryService() (*StatusHistoryService, *InMemoryStatusHistoryRepository) {
+	logger := log.New()
+	logger.SetLevel(log.ErrorLevel)
+	repo := NewInMemoryStatusHistoryRepository()
+	return NewStatusHistoryService(repo, logger), repo
+}
+
+func TestStatusHistory_RecordStatusOnlyStoresChanges(t *testing.T) {
+	svc, repo := newTestStatusHistoryService()
+	ctx := context.Background()
+
+	first, err := svc.RecordStatus(ctx, "PUMP-001", OperationalStatusNormal, "initial", "api", nil)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Empty(t, first.FromStatus)
+
+	same, err := svc.RecordStatus(ctx, "PUMP-001", OperationalStatusNormal, "still fine", "api", nil)
+	require.NoError(t, err)
+	assert.Nil(t, same)
+
+	second, err := svc.RecordStatus(ctx, "PUMP-001", OperationalStatusWatch, "vibration rising", "rules_engine", nil)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, OperationalStatusNormal, second.FromStatus)
+	assert.Equal(t, OperationalStatusWatch, second.ToStatus)
+
+	assert.Len(t, repo.transitions["PUMP-001"], 2)
+
+	_, err = svc.RecordStatus(ctx, "PUMP-001", OperationalStatus("BROKEN"), "", "api", nil)
+	assert.Error(t, err)
+}
+
+func TestStatusHistory_GetHistoryComputesDurations(t *testing.T) {
+	svc, repo := newTestStatusHistoryService()
+	ctx := context.Background()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(offset time.Duration, from, to OperationalStatus, cause string) {
+		require.NoError(t, repo.RecordTransition(ctx, &StatusTransition{
+			AgentID:    "PUMP-001",
+			FromStatus: from,
+			ToStatus:   to,
+			Cause:      cause,
+			Timestamp:  base.Add(offset),
+		}))
+	}
+
+	record(0, "", OperationalStatusNormal, "initial")
+	record(48*time.Hour, OperationalStatusNormal, OperationalStatusWatch, "efficiency drop")
+	record(72*time.Hour, OperationalStatusWatch, OperationalStatusDegraded, "bearing temperature")
+	record(78*time.Hour, OperationalStatusDegraded, OperationalStatusCritical, "vibration threshold")
+
+	// Window starts after the initial transition; the NORMAL status is carried in
+	since := base.Add(24 * time.Hour)
+	until := base.Add(96 * time.Hour)
+
+	history, err := svc.GetHistory(ctx, "PUMP-001", since, until)
+	require.NoError(t, err)
+
+	assert.Equal(t, OperationalStatusCritical, history.CurrentStatus)
+	assert.Len(t, history.Transitions, 3)
+	require.Len(t, history.Periods, 4)
+
+	assert.Equal(t, since, history.Periods[0].StartedAt)
+	assert.Equal(t, OperationalStatusNormal, history.Periods[0].Status)
+	assert.Equal(t, "bearing temperature", history.Periods[2].Cause)
+
+	hours := func(s OperationalStatus) float64 { return history.TimeInStatus[s] / 3600 }
+	assert.Equal(t, 24.0, hours(OperationalStatusNormal))
+	assert.Equal(t, 24.0, hours(OperationalStatusWatch))
+	assert.Equal(t, 6.0, hours(OperationalStatusDegraded))
+	assert.Equal(t, 18.0, hours(OperationalStatusCritical))
+
+	// Windows in the past have every period closed
+	assert.NotNil(t, history.Periods[3].EndedAt)
+}
+
+func TestStatusHistory_EmptyHistory(t *testing.T) {
+	svc, _ := newTestStatusHistoryService()
+
+	until := time.Now().UTC()
+	history, err := svc.GetHistory(context.Background(), "PUMP-404", until.Add(-time.Hour), until)
+	require.NoError(t, err)
+
+	assert.Empty(t, history.CurrentStatus)
+	assert.Empty(t, history.Transitions)
+	assert.Empty(t, history.Periods)
+}
+
+func TestStatusHistoryPublisher_RecordsHealthEvents(t *testing.T) {
+	svc, repo := newTestStatusHistoryService()
+	next := &MockEventPublisher{}
+	publisher := NewStatusHistoryPublisher(svc, next)
+
+	err := publisher.PublishHealthEvent(context.Background(), &HealthEvent{
+		Type:           HealthEventAgentCritical,
+		AgentID:        "PUMP-002",
+		PreviousStatus: HealthStatusHealthy,
+		CurrentStatus:  HealthStatusCritical,
+		Message:        "heartbeat lost",
+		Timestamp:      time.Now(),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, repo.transitions["PUMP-002"], 1)
+	assert.Equal(t, OperationalStatusCritical, repo.transitions["PUMP-002"][0].ToStatus)
+	assert.Equal(t, "health_monitor", repo.transitions["PUMP-002"][0].Source)
+	assert.Len(t, next.PublishedEvents, 1)
+}
+
+func TestStatusHistory_RecordStateChange(t *testing.T) {
+	svc, repo := newTestStatusHistoryService()
+	pump := &agent.Agent{ID: "PUMP-003"}
+
+	svc.RecordStateChange(pump, agent.StateCreated, agent.StateRunning)
+	svc.RecordStateChange(pump, agent.StateRunning, agent.StateFailed)
+	svc.RecordStateChange(pump, agent.StateFailed, agent.StateCreated)
+
+	transitions := repo.transitions["PUMP-003"]
+	require.Len(t, transitions, 2)
+	assert.Equal(t, OperationalStatusNormal, transitions[0].ToStatus)
+	assert.Equal(t, OperationalStatusCritical, transitions[1].ToStatus)
+	assert.Equal(t, "lifecycle", transitions[1].Source)
+	assert.Equal(t, "failed", transitions[1].Details["current_state"])
+}
+
+func TestMonitor_FollowState(t *testing.T) {
+	logger := log.New()
+	logger.SetLevel(log.ErrorLevel)
+	monitor := NewMonitor(DefaultHealthMonitorConfig(), nil, logger)
+	defer monitor.Shutdown()
+	pump := &agent.Agent{ID: "PUMP-004"}
+
+	monitor.FollowState(pump, agent.StateCreated, agent.StateRunning)
+	monitor.FollowState(pump, agent.StatePaused, agent.StateRunning)
+	_, err := monitor.GetHealthReport("PUMP-004")
+	require.NoError(t, err, "expected a running agent to be monitored")
+
+	monitor.FollowState(pump, agent.StateRunning, agent.StateStopped)
+	assert.Error(t, monitor.StopMonitoring("PUMP-004"), "expected a stopped agent not to be monitored")
+}
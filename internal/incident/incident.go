@@ -0,0 +1,230 @@
+// Package incident keeps durable records of operational incidents such as
+// leaks and equipment failures.
+//
+// An incident has a severity, moves through the states open, acknowledged,
+// contained and resolved, links the agents and messages involved and keeps a
+// timeline of everything that happened to it. Incidents are opened through
+// the API or, when enabled, from alert publications; later alerts with the
+// same alert type and zone are added to the timeline of the incident they
+// belong to while it is unresolved.
+package incident
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrIncidentNotFound is returned when an incident does not exist
+	ErrIncidentNotFound = errors.New("incident not found")
+
+	// ErrInvalidIncident is returned for incidents and changes that fail validation
+	ErrInvalidIncident = errors.New("invalid incident")
+
+	// ErrInvalidTransition is returned when an incident cannot move to the requested state
+	ErrInvalidTransition = errors.New("invalid incident state transition")
+)
+
+// Severity is how serious an incident is. Values match alert severities.
+type Severity string
+
+const (
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// ParseSeverity reads a severity case-insensitively. WARNING counts as
+// MEDIUM, as it does for alert filters.
+func ParseSeverity(s string) (Severity, bool) {
+	severity := Severity(strings.ToUpper(strings.TrimSpace(s)))
+	if severity == "WARNING" {
+		severity = SeverityMedium
+	}
+	_, ok := severityRanks[severity]
+	return severity, ok
+}
+
+// AtLeast reports whether the severity is at least as serious as other
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// State is where an incident is in its response
+type State string
+
+const (
+	StateOpen         State = "open"
+	StateAcknowledged State = "acknowledged"
+	StateContained    State = "contained"
+	StateResolved     State = "resolved"
+)
+
+// stateTransitions lists the states each state may move to. Resolved
+// incidents can be reopened.
+var stateTransitions = map[State][]State{
+	StateOpen:         {StateAcknowledged, StateContained, StateResolved},
+	StateAcknowledged: {StateContained, StateResolved},
+	StateContained:    {StateResolved},
+	StateResolved:     {StateOpen},
+}
+
+// IsValid reports whether the state is one of the incident states
+func (s State) IsValid() bool {
+	_, exists := stateTransitions[s]
+	return exists
+}
+
+// Transitions returns the states an incident may move to from s
+func (s State) Transitions() []State {
+	return stateTransitions[s]
+}
+
+// CanTransitionTo reports whether an incident may move from s to next
+func (s State) CanTransitionTo(next State) bool {
+	for _, allowed := range stateTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// EntryType identifies a timeline entry
+type EntryType string
+
+const (
+	EntryOpened          EntryType = "opened"
+	EntryStateChanged    EntryType = "state_changed"
+	EntrySeverityChanged EntryType = "severity_changed"
+	EntryAgentLinked     EntryType = "agent_linked"
+	EntryMessageLinked   EntryType = "message_linked"
+	EntryAlert           EntryType = "alert"
+	EntryNote            EntryType = "note"
+)
+
+// TimelineEntry is one thing that happened to an incident
+type TimelineEntry struct {
+	At        time.Time              `json:"at"`
+	Type      EntryType              `json:"type"`
+	Actor     string                 `json:"actor,omitempty"` // Operator or agent responsible
+	Message   string                 `json:"message"`
+	Reference string                 `json:"reference,omitempty"` // ID of the linked agent, message or publication
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Incident is a durable record of an operational incident
+type Incident struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Severity    Severity `json:"severity"`
+	State       State    `json:"state"`
+	Zone        string   `json:"zone,omitempty"`
+
+	// CorrelationKey groups the alerts of an incident opened from alerts
+	CorrelationKey string `json:"correlation_key,omitempty"`
+
+	AgentIDs   []string `json:"agent_ids"`
+	MessageIDs []string `json:"message_ids"`
+
+	Timeline []TimelineEntry `json:"timeline"`
+
+	OpenedBy       string     `json:"opened_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ContainedAt    *time.Time `json:"contained_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// record appends an entry to the timeline
+func (i *Incident) record(entry TimelineEntry) {
+	i.Timeline = append(i.Timeline, entry)
+	i.UpdatedAt = entry.At
+}
+
+// linkAgent links an agent, reporting whether it was not linked yet
+func (i *Incident) linkAgent(agentID string) bool {
+	return appendUnique(&i.AgentIDs, agentID)
+}
+
+// linkMessage links a message, reporting whether it was not linked yet
+func (i *Incident) linkMessage(messageID string) bool {
+	return appendUnique(&i.MessageIDs, messageID)
+}
+
+func appendUnique(values *[]string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range *values {
+		if v == value {
+			return false
+		}
+	}
+	*values = append(*values, value)
+	return true
+}
+
+// Filter selects incidents. Zero fields do not filter.
+type Filter struct {
+	State    State
+	Severity Severity
+	AgentID  string
+	Active   bool // Only incidents that are not resolved
+	Limit    int
+}
+
+// matches reports whether the filter selects an incident
+func (f Filter) matches(i *Incident) bool {
+	if f.State != "" && i.State != f.State {
+		return false
+	}
+	if f.Severity != "" && i.Severity != f.Severity {
+		return false
+	}
+	if f.Active && i.State == StateResolved {
+		return false
+	}
+	if f.AgentID != "" {
+		for _, id := range i.AgentIDs {
+			if id == f.AgentID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Repository stores incidents
+type Repository interface {
+	// Create stores a new incident
+	Create(ctx context.Context, incident *Incident) error
+
+	// Get retrieves an incident by ID
+	Get(ctx context.Context, id string) (*Incident, error)
+
+	// Update replaces an existing incident
+	Update(ctx context.Context, incident *Incident) error
+
+	// List returns the incidents matching the filter, most recently updated first
+	List(ctx context.Context, filter Filter) ([]*Incident, error)
+
+	// FindActive returns the unresolved incident with a correlation key, if any
+	FindActive(ctx context.Context, correlationKey string) (*Incident, error)
+}
@@ -0,0 +1,282 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionIncidents is the incident collection name
+const CollectionIncidents = "incidents"
+
+// ArangoRepository stores incidents in ArangoDB
+type ArangoRepository struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed incident repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionIncidents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionIncidents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionIncidents, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionIncidents).Info("Created new collection")
+	}
+
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"state", "updated_at"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_incidents_state",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"correlation_key"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_incidents_correlation",
+		Sparse: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoRepository{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create stores a new incident
+func (r *ArangoRepository) Create(ctx context.Context, incident *Incident) error {
+	incident.Key = incident.ID
+	if _, err := r.collection.CreateDocument(ctx, incident); err != nil {
+		return fmt.Errorf("failed to create incident: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an incident by ID
+func (r *ArangoRepository) Get(ctx context.Context, id string) (*Incident, error) {
+	var incident Incident
+	if _, err := r.collection.ReadDocument(ctx, id, &incident); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrIncidentNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read incident: %w", err)
+	}
+	return &incident, nil
+}
+
+// Update replaces an existing incident
+func (r *ArangoRepository) Update(ctx context.Context, incident *Incident) error {
+	incident.Key = incident.ID
+	if _, err := r.collection.ReplaceDocument(ctx, incident.ID, incident); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrIncidentNotFound, incident.ID)
+		}
+		return fmt.Errorf("failed to update incident: %w", err)
+	}
+	return nil
+}
+
+// List returns the incidents matching the filter, most recently updated first
+func (r *ArangoRepository) List(ctx context.Context, filter Filter) ([]*Incident, error) {
+	var conditions []string
+	bindVars := map[string]interface{}{
+		"@collection": CollectionIncidents,
+	}
+	if filter.State != "" {
+		conditions = append(conditions, "i.state == @state")
+		bindVars["state"] = filter.State
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "i.severity == @severity")
+		bindVars["severity"] = filter.Severity
+	}
+	if filter.Active {
+		conditions = append(conditions, "i.state != @resolved")
+		bindVars["resolved"] = StateResolved
+	}
+	if filter.AgentID != "" {
+		conditions = append(conditions, "@agentID IN i.agent_ids")
+		bindVars["agentID"] = filter.AgentID
+	}
+
+	filterClause := ""
+	if len(conditions) > 0 {
+		filterClause = "FILTER " + strings.Join(conditions, " AND ")
+	}
+	limitClause := ""
+	if filter.Limit > 0 {
+		limitClause = "LIMIT @limit"
+		bindVars["limit"] = filter.Limit
+	}
+
+	query := fmt.Sprintf(`
+		FOR i IN @@collection
+			%s
+			SORT i.updated_at DESC
+			%s
+			RETURN i
+	`, filterClause, limitClause)
+
+	return r.query(ctx, query, bindVars)
+}
+
+// FindActive returns the unresolved incident with a correlation key, if any
+func (r *ArangoRepository) FindActive(ctx context.Context, correlationKey string) (*Incident, error) {
+	query := `
+		FOR i IN @@collection
+			FILTER i.correlation_key == @key AND i.state != @resolved
+			SORT i.created_at DESC
+			LIMIT 1
+			RETURN i
+	`
+	incidents, err := r.query(ctx, query, map[string]interface{}{
+		"@collection": CollectionIncidents,
+		"key":         correlationKey,
+		"resolved":    StateResolved,
+	})
+	if err != nil || len(incidents) == 0 {
+		return nil, err
+	}
+	return incidents[0], nil
+}
+
+func (r *ArangoRepository) query(ctx context.Context, query string, bindVars map[string]interface{}) ([]*Incident, error) {
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer cursor.Close()
+
+	var incidents []*Incident
+	for {
+		var incident Incident
+		_, err := cursor.ReadDocument(ctx, &incident)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read incident: %w", err)
+		}
+		incidents = append(incidents, &incident)
+	}
+
+	return incidents, nil
+}
+
+// InMemoryRepository keeps incidents and their timelines in memory, stored as
+// copies so callers cannot change them without an update.
+type InMemoryRepository struct {
+	mu        sync.RWMutex
+	incidents map[string]*Incident
+}
+
+// NewInMemoryRepository creates a new in-memory incident repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		incidents: make(map[string]*Incident),
+	}
+}
+
+// Create stores a new incident
+func (r *InMemoryRepository) Create(ctx context.Context, incident *Incident) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	incident.Key = incident.ID
+	r.incidents[incident.ID] = clone(incident)
+	return nil
+}
+
+// Get retrieves an incident by ID
+func (r *InMemoryRepository) Get(ctx context.Context, id string) (*Incident, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	incident, exists := r.incidents[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrIncidentNotFound, id)
+	}
+	return clone(incident), nil
+}
+
+// Update replaces an existing incident
+func (r *InMemoryRepository) Update(ctx context.Context, incident *Incident) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.incidents[incident.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrIncidentNotFound, incident.ID)
+	}
+	incident.Key = incident.ID
+	r.incidents[incident.ID] = clone(incident)
+	return nil
+}
+
+// List returns the incidents matching the filter, most recently updated first
+func (r *InMemoryRepository) List(ctx context.Context, filter Filter) ([]*Incident, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var incidents []*Incident
+	for _, incident := range r.incidents {
+		if filter.matches(incident) {
+			incidents = append(incidents, clone(incident))
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].UpdatedAt.After(incidents[j].UpdatedAt)
+	})
+	if filter.Limit > 0 && len(incidents) > filter.Limit {
+		incidents = incidents[:filter.Limit]
+	}
+	return incidents, nil
+}
+
+// FindActive returns the unresolved incident with a correlation key, if any
+func (r *InMemoryRepository) FindActive(ctx context.Context, correlationKey string) (*Incident, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *Incident
+	for _, incident := range r.incidents {
+		if incident.CorrelationKey != correlationKey || incident.State == StateResolved {
+			continue
+		}
+		if found == nil || incident.CreatedAt.After(found.CreatedAt) {
+			found = incident
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return clone(found), nil
+}
+
+// clone copies an incident so stored incidents are not shared with callers
+func clone(incident *Incident) *Incident {
+	copied := *incident
+	copied.AgentIDs = append([]string{}, incident.AgentIDs...)
+	copied.MessageIDs = append([]string{}, incident.MessageIDs...)
+	copied.Timeline = append([]TimelineEntry(nil), incident.Timeline...)
+	return &copied
+}
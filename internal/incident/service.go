@@ -0,0 +1,395 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/alertrouting"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config configures how incidents are opened from alerts
+type Config struct {
+	// AutoOpen opens incidents from alert publications
+	AutoOpen bool
+
+	// MinSeverity is the least severe alert that opens an incident. Less
+	// severe alerts are still added to the timeline of an open incident.
+	MinSeverity Severity
+
+	// AlertTypes are the alert type patterns that open incidents; empty
+	// matches every alert
+	AlertTypes []string
+}
+
+func (c Config) withDefaults() Config {
+	if _, ok := severityRanks[c.MinSeverity]; !ok {
+		c.MinSeverity = SeverityHigh
+	}
+	return c
+}
+
+// ConfigFromConfig converts application config into an incident Config
+func ConfigFromConfig(cfg config.IncidentsConfig) Config {
+	minSeverity, _ := ParseSeverity(cfg.MinSeverity)
+	return Config{
+		AutoOpen:    cfg.AutoOpen,
+		MinSeverity: minSeverity,
+		AlertTypes:  cfg.AlertTypes,
+	}
+}
+
+// OpenRequest describes a new incident
+type OpenRequest struct {
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"` // Defaults to MEDIUM
+	Zone        string   `json:"zone"`
+	AgentIDs    []string `json:"agent_ids"`
+	MessageIDs  []string `json:"message_ids"`
+	OpenedBy    string   `json:"opened_by"`
+}
+
+// Service manages incidents
+type Service struct {
+	repo   Repository
+	config Config
+	clock  clock.Clock
+	logger *log.Logger
+
+	// mu serializes read-modify-write updates of incidents
+	mu sync.Mutex
+}
+
+// NewService creates a new incident service
+func NewService(repo Repository, cfg Config, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &Service{
+		repo:   repo,
+		config: cfg.withDefaults(),
+		clock:  clock.Real(),
+		logger: logger,
+	}
+}
+
+// SetClock sets the clock used to timestamp incidents and their timelines
+func (s *Service) SetClock(c clock.Clock) {
+	if c != nil {
+		s.clock = c
+	}
+}
+
+// Open opens a new incident
+func (s *Service) Open(ctx context.Context, req OpenRequest) (*Incident, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidIncident)
+	}
+	severity := SeverityMedium
+	if req.Severity != "" {
+		var ok bool
+		if severity, ok = ParseSeverity(req.Severity); !ok {
+			return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidIncident, req.Severity)
+		}
+	}
+
+	now := s.clock.Now().UTC()
+	incident := &Incident{
+		ID:          uuid.New().String(),
+		Title:       title,
+		Description: req.Description,
+		Severity:    severity,
+		State:       StateOpen,
+		Zone:        req.Zone,
+		AgentIDs:    []string{},
+		MessageIDs:  []string{},
+		OpenedBy:    req.OpenedBy,
+		CreatedAt:   now,
+	}
+	incident.record(TimelineEntry{
+		At:      now,
+		Type:    EntryOpened,
+		Actor:   req.OpenedBy,
+		Message: fmt.Sprintf("Incident opened with severity %s", severity),
+	})
+	link(incident, now, req.AgentIDs, req.MessageIDs, req.OpenedBy)
+
+	if err := s.repo.Create(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(log.Fields{
+		"incident_id": incident.ID,
+		"severity":    incident.Severity,
+	}).Info("Incident opened")
+	return incident, nil
+}
+
+// Get retrieves an incident by ID
+func (s *Service) Get(ctx context.Context, id string) (*Incident, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns the incidents matching the filter, most recently updated first
+func (s *Service) List(ctx context.Context, filter Filter) ([]*Incident, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Transition moves an incident to another state, recording the note on its
+// timeline
+func (s *Service) Transition(ctx context.Context, id string, state State, actor, note string) (*Incident, error) {
+	if !state.IsValid() {
+		return nil, fmt.Errorf("%w: unknown state %q", ErrInvalidIncident, state)
+	}
+
+	return s.update(ctx, id, func(incident *Incident, now time.Time) error {
+		if !incident.State.CanTransitionTo(state) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, incident.State, state)
+		}
+
+		message := fmt.Sprintf("State changed from %s to %s", incident.State, state)
+		if note != "" {
+			message += ": " + note
+		}
+		switch state {
+		case StateOpen:
+			incident.AcknowledgedAt = nil
+			incident.ContainedAt = nil
+			incident.ResolvedAt = nil
+		case StateAcknowledged:
+			incident.AcknowledgedAt = &now
+		case StateContained:
+			incident.ContainedAt = &now
+		case StateResolved:
+			incident.ResolvedAt = &now
+		}
+		incident.record(TimelineEntry{
+			At:      now,
+			Type:    EntryStateChanged,
+			Actor:   actor,
+			Message: message,
+			Data:    map[string]interface{}{"from": string(incident.State), "to": string(state)},
+		})
+		incident.State = state
+		return nil
+	})
+}
+
+// SetSeverity changes the severity of an incident
+func (s *Service) SetSeverity(ctx context.Context, id string, severity Severity, actor, note string) (*Incident, error) {
+	if _, ok := severityRanks[severity]; !ok {
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidIncident, severity)
+	}
+
+	return s.update(ctx, id, func(incident *Incident, now time.Time) error {
+		if incident.Severity != severity {
+			changeSeverity(incident, now, severity, actor, note)
+		}
+		return nil
+	})
+}
+
+// Link links agents and messages to an incident. Links already present are
+// ignored.
+func (s *Service) Link(ctx context.Context, id string, agentIDs, messageIDs []string, actor string) (*Incident, error) {
+	return s.update(ctx, id, func(incident *Incident, now time.Time) error {
+		link(incident, now, agentIDs, messageIDs, actor)
+		return nil
+	})
+}
+
+// AddNote adds a note to the timeline of an incident
+func (s *Service) AddNote(ctx context.Context, id, actor, note string) (*Incident, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: note is required", ErrInvalidIncident)
+	}
+
+	return s.update(ctx, id, func(incident *Incident, now time.Time) error {
+		incident.record(TimelineEntry{At: now, Type: EntryNote, Actor: actor, Message: note})
+		return nil
+	})
+}
+
+// update applies a change to an incident and stores it
+func (s *Service) update(ctx context.Context, id string, change func(incident *Incident, now time.Time) error) (*Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(incident, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, incident); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// HandleAlert adds an alert to the unresolved incident of its alert type and
+// zone, raising the incident's severity to the alert's. Without one, an
+// incident is opened if the alert is severe enough and of a configured type.
+// It returns the incident the alert was recorded on, if any.
+func (s *Service) HandleAlert(ctx context.Context, alert *alertrouting.Alert) (*Incident, error) {
+	severity, ok := ParseSeverity(alert.Severity)
+	if !ok {
+		severity = SeverityMedium
+	}
+	key := correlationKey(alert)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, err := s.repo.FindActive(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now().UTC()
+	if incident != nil {
+		if severity.AtLeast(incident.Severity) && severity != incident.Severity {
+			changeSeverity(incident, now, severity, alert.Source, "raised by alert")
+		}
+		recordAlert(incident, now, alert)
+		if err := s.repo.Update(ctx, incident); err != nil {
+			return nil, err
+		}
+		return incident, nil
+	}
+
+	if !severity.AtLeast(s.config.MinSeverity) || !s.watches(alert.AlertType) {
+		return nil, nil
+	}
+
+	incident = &Incident{
+		ID:             uuid.New().String(),
+		Title:          alert.Message,
+		Severity:       severity,
+		State:          StateOpen,
+		Zone:           alert.Zone,
+		CorrelationKey: key,
+		AgentIDs:       []string{},
+		MessageIDs:     []string{},
+		OpenedBy:       alert.Source,
+		CreatedAt:      now,
+	}
+	incident.record(TimelineEntry{
+		At:      now,
+		Type:    EntryOpened,
+		Actor:   alert.Source,
+		Message: fmt.Sprintf("Incident opened from %s alert %s", severity, alert.AlertType),
+	})
+	recordAlert(incident, now, alert)
+
+	if err := s.repo.Create(ctx, incident); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(log.Fields{
+		"incident_id": incident.ID,
+		"severity":    incident.Severity,
+		"alert_type":  alert.AlertType,
+		"zone":        alert.Zone,
+	}).Info("Incident opened from alert")
+	return incident, nil
+}
+
+// watches reports whether an alert type may open incidents
+func (s *Service) watches(alertType string) bool {
+	if len(s.config.AlertTypes) == 0 {
+		return true
+	}
+	for _, pattern := range s.config.AlertTypes {
+		if communication.MatchTopic(pattern, alertType) {
+			return true
+		}
+	}
+	return false
+}
+
+// ObservePublications returns a publish observer that records alert
+// publications on incidents. It does nothing unless AutoOpen is set.
+func (s *Service) ObservePublications() communication.PublishObserver {
+	return func(ctx context.Context, pub *communication.Publication) {
+		if !s.config.AutoOpen || pub.PublicationType != communication.PublicationTypeAlert {
+			return
+		}
+
+		// Recorded asynchronously so storage does not hold up the publisher
+		ctx = context.WithoutCancel(ctx)
+		go func() {
+			if _, err := s.HandleAlert(ctx, alertrouting.AlertFromPublication(pub)); err != nil {
+				s.logger.WithError(err).WithField("publication_id", pub.ID).Warn("Failed to record alert on incident")
+			}
+		}()
+	}
+}
+
+// correlationKey groups alerts of the same type in the same zone, or from the
+// same source when they carry no zone
+func correlationKey(alert *alertrouting.Alert) string {
+	scope := alert.Zone
+	if scope == "" {
+		scope = alert.Source
+	}
+	return alert.AlertType + "/" + scope
+}
+
+func recordAlert(incident *Incident, now time.Time, alert *alertrouting.Alert) {
+	incident.record(TimelineEntry{
+		At:        now,
+		Type:      EntryAlert,
+		Actor:     alert.Source,
+		Message:   alert.Message,
+		Reference: alert.ID,
+		Data: map[string]interface{}{
+			"severity":   alert.Severity,
+			"alert_type": alert.AlertType,
+			"zone":       alert.Zone,
+		},
+	})
+	if incident.linkAgent(alert.Source) {
+		incident.record(TimelineEntry{At: now, Type: EntryAgentLinked, Actor: alert.Source, Message: "Agent " + alert.Source + " linked", Reference: alert.Source})
+	}
+}
+
+func changeSeverity(incident *Incident, now time.Time, severity Severity, actor, note string) {
+	message := fmt.Sprintf("Severity changed from %s to %s", incident.Severity, severity)
+	if note != "" {
+		message += ": " + note
+	}
+	incident.record(TimelineEntry{
+		At:      now,
+		Type:    EntrySeverityChanged,
+		Actor:   actor,
+		Message: message,
+		Data:    map[string]interface{}{"from": string(incident.Severity), "to": string(severity)},
+	})
+	incident.Severity = severity
+}
+
+func link(incident *Incident, now time.Time, agentIDs, messageIDs []string, actor string) {
+	for _, agentID := range agentIDs {
+		if incident.linkAgent(agentID) {
+			incident.record(TimelineEntry{At: now, Type: EntryAgentLinked, Actor: actor, Message: "Agent " + agentID + " linked", Reference: agentID})
+		}
+	}
+	for _, messageID := range messageIDs {
+		if incident.linkMessage(messageID) {
+			incident.record(TimelineEntry{At: now, Type: EntryMessageLinked, Actor: actor, Message: "Message " + messageID + " linked", Reference: messageID})
+		}
+	}
+}
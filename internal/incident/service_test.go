@@ -0,0 +1,187 @@
+package incident
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/alertrouting"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(cfg Config) *Service {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewService(NewInMemoryRepository(), cfg, logger)
+}
+
+func TestService_Lifecycle(t *testing.T) {
+	service := newTestService(Config{})
+	ctx := context.Background()
+
+	incident, err := service.Open(ctx, OpenRequest{
+		Title:      "Leak on main line",
+		Severity:   "high",
+		Zone:       "zone-north",
+		AgentIDs:   []string{"SENSOR-001", "SENSOR-001"},
+		MessageIDs: []string{"msg-1"},
+		OpenedBy:   "operator",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, SeverityHigh, incident.Severity)
+	assert.Equal(t, StateOpen, incident.State)
+	assert.Equal(t, []string{"SENSOR-001"}, incident.AgentIDs)
+	require.Len(t, incident.Timeline, 3)
+	assert.Equal(t, EntryOpened, incident.Timeline[0].Type)
+
+	_, err = service.Transition(ctx, incident.ID, StateAcknowledged, "operator", "crew dispatched")
+	require.NoError(t, err)
+	incident, err = service.Transition(ctx, incident.ID, StateContained, "crew", "valve V-12 closed")
+	require.NoError(t, err)
+	require.NotNil(t, incident.AcknowledgedAt)
+	require.NotNil(t, incident.ContainedAt)
+
+	_, err = service.Transition(ctx, incident.ID, StateAcknowledged, "crew", "")
+	assert.ErrorIs(t, err, ErrInvalidTransition, "contained incidents cannot go back to acknowledged")
+	_, err = service.Transition(ctx, incident.ID, "closed", "crew", "")
+	assert.ErrorIs(t, err, ErrInvalidIncident)
+
+	_, err = service.Link(ctx, incident.ID, []string{"VALVE-012"}, []string{"msg-1", "msg-2"}, "crew")
+	require.NoError(t, err)
+	_, err = service.SetSeverity(ctx, incident.ID, SeverityCritical, "operator", "")
+	require.NoError(t, err)
+	_, err = service.AddNote(ctx, incident.ID, "crew", "pipe section replaced")
+	require.NoError(t, err)
+	incident, err = service.Transition(ctx, incident.ID, StateResolved, "operator", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, SeverityCritical, incident.Severity)
+	assert.Equal(t, []string{"SENSOR-001", "VALVE-012"}, incident.AgentIDs)
+	assert.Equal(t, []string{"msg-1", "msg-2"}, incident.MessageIDs)
+	require.NotNil(t, incident.ResolvedAt)
+
+	var types []EntryType
+	for _, entry := range incident.Timeline {
+		types = append(types, entry.Type)
+	}
+	assert.Equal(t, []EntryType{
+		EntryOpened, EntryAgentLinked, EntryMessageLinked,
+		EntryStateChanged, EntryStateChanged,
+		EntryAgentLinked, EntryMessageLinked,
+		EntrySeverityChanged, EntryNote, EntryStateChanged,
+	}, types)
+
+	// Resolved incidents can be reopened
+	incident, err = service.Transition(ctx, incident.ID, StateOpen, "operator", "leak returned")
+	require.NoError(t, err)
+	assert.Nil(t, incident.ResolvedAt)
+
+	active, err := service.List(ctx, Filter{Active: true, AgentID: "VALVE-012"})
+	require.NoError(t, err)
+	assert.Len(t, active, 1)
+
+	_, err = service.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrIncidentNotFound)
+	_, err = service.Open(ctx, OpenRequest{Title: "Bad", Severity: "urgent"})
+	assert.ErrorIs(t, err, ErrInvalidIncident)
+}
+
+func TestService_HandleAlertCorrelates(t *testing.T) {
+	service := newTestService(Config{AlertTypes: []string{"leak.*"}})
+	ctx := context.Background()
+
+	alert := func(source, severity, alertType, zone string) *communication.Publication {
+		return &communication.Publication{
+			ID:               "pub-" + source,
+			PublisherAgentID: source,
+			PublicationType:  communication.PublicationTypeAlert,
+			EventName:        "alerts",
+			Payload:          map[string]interface{}{"severity": severity, "alert_type": alertType, "zone": zone},
+		}
+	}
+	handle := func(pub *communication.Publication) *Incident {
+		incident, err := service.HandleAlert(ctx, alertrouting.AlertFromPublication(pub))
+		require.NoError(t, err)
+		return incident
+	}
+
+	assert.Nil(t, handle(alert("SENSOR-001", "MEDIUM", "leak.detected", "north")), "below the minimum severity")
+	assert.Nil(t, handle(alert("PUMP-001", "CRITICAL", "pump.failure", "north")), "alert type not watched")
+
+	opened := handle(alert("SENSOR-001", "HIGH", "leak.detected", "north"))
+	require.NotNil(t, opened)
+	assert.Equal(t, "leak.detected/north", opened.CorrelationKey)
+
+	// Later alerts of the incident are added to it, even when less severe
+	added := handle(alert("SENSOR-002", "MEDIUM", "leak.detected", "north"))
+	require.NotNil(t, added)
+	assert.Equal(t, opened.ID, added.ID)
+	added = handle(alert("SENSOR-003", "CRITICAL", "leak.detected", "north"))
+	assert.Equal(t, opened.ID, added.ID)
+	assert.Equal(t, SeverityCritical, added.Severity)
+	assert.Equal(t, []string{"SENSOR-001", "SENSOR-002", "SENSOR-003"}, added.AgentIDs)
+
+	other := handle(alert("SENSOR-009", "HIGH", "leak.detected", "south"))
+	require.NotNil(t, other)
+	assert.NotEqual(t, opened.ID, other.ID)
+
+	// A new incident is opened once the first is resolved
+	_, err := service.Transition(ctx, opened.ID, StateResolved, "operator", "")
+	require.NoError(t, err)
+	reopened := handle(alert("SENSOR-001", "HIGH", "leak.detected", "north"))
+	require.NotNil(t, reopened)
+	assert.NotEqual(t, opened.ID, reopened.ID)
+}
+
+func TestService_ObservePublications(t *testing.T) {
+	start := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	virtual := clock.NewVirtual(clock.VirtualConfig{Start: start, Paused: true})
+
+	service := newTestService(Config{AutoOpen: true})
+	service.SetClock(virtual)
+	ctx := context.Background()
+
+	observe := service.ObservePublications()
+	observe(ctx, &communication.Publication{
+		PublisherAgentID: "SENSOR-001",
+		PublicationType:  communication.PublicationTypeEvent,
+		Payload:          map[string]interface{}{"severity": "CRITICAL"},
+	})
+	observe(ctx, &communication.Publication{
+		PublisherAgentID: "PUMP-002",
+		PublicationType:  communication.PublicationTypeAlert,
+		EventName:        "pump.failure",
+		Payload:          map[string]interface{}{"severity": "CRITICAL", "message": "Pump PUMP-002 stopped"},
+	})
+
+	var incidents []*Incident
+	require.Eventually(t, func() bool {
+		var err error
+		incidents, err = service.List(ctx, Filter{})
+		return err == nil && len(incidents) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Len(t, incidents, 1, "only alert publications are recorded")
+	assert.Equal(t, "Pump PUMP-002 stopped", incidents[0].Title)
+	assert.Equal(t, "pump.failure/PUMP-002", incidents[0].CorrelationKey)
+	assert.Equal(t, start, incidents[0].CreatedAt)
+}
+
+func TestState_CanTransitionTo(t *testing.T) {
+	assert.True(t, StateOpen.CanTransitionTo(StateResolved))
+	assert.True(t, StateAcknowledged.CanTransitionTo(StateContained))
+	assert.False(t, StateContained.CanTransitionTo(StateOpen))
+	assert.False(t, StateResolved.CanTransitionTo(StateAcknowledged))
+	assert.False(t, State("closed").IsValid())
+
+	severity, ok := ParseSeverity("warning")
+	assert.True(t, ok)
+	assert.Equal(t, SeverityMedium, severity)
+	assert.True(t, SeverityCritical.AtLeast(SeverityHigh))
+	assert.False(t, SeverityLow.AtLeast(SeverityMedium))
+}
@@ -0,0 +1,163 @@
+// Package jobs implements a persistent queue for background work such as
+// backfills, archival, purges and report generation.
+//
+// Jobs are stored before they run, so they survive restarts. A Queue claims
+// due jobs of each registered type up to the type's concurrency limit, runs
+// them, and retries failures with backoff. Jobs that fail permanently or run
+// out of attempts are dead-lettered and kept for inspection and manual retry.
+// A claim is a lease: jobs whose worker died are claimed again once the lease
+// expires, so handlers must tolerate running more than once.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	// StatusQueued jobs are waiting for their run time or a free slot
+	StatusQueued = "queued"
+
+	// StatusRunning jobs are claimed by a worker
+	StatusRunning = "running"
+
+	// StatusSucceeded jobs completed
+	StatusSucceeded = "succeeded"
+
+	// StatusDead jobs failed permanently or ran out of attempts
+	StatusDead = "dead"
+
+	// StatusCanceled jobs were canceled before they ran
+	StatusCanceled = "canceled"
+)
+
+var (
+	// ErrJobNotFound is returned for unknown jobs
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobExists is returned when creating a job whose ID is taken
+	ErrJobExists = errors.New("job already exists")
+
+	// ErrUnknownType is returned when enqueuing a job type without a handler
+	ErrUnknownType = errors.New("unknown job type")
+
+	// ErrNotDead is returned when retrying a job that is not dead-lettered
+	ErrNotDead = errors.New("job is not dead-lettered")
+
+	// ErrNotQueued is returned when canceling a job that is not queued
+	ErrNotQueued = errors.New("job is not queued")
+)
+
+// Job is a unit of background work
+type Job struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	ID   string `json:"id"`
+	Type string `json:"type"`
+
+	// Payload is the handler input
+	Payload map[string]interface{} `json:"payload,omitempty"`
+
+	// Result is set by the handler and saved when the job succeeds
+	Result map[string]interface{} `json:"result,omitempty"`
+
+	// Schedule names the recurring schedule that created the job
+	Schedule string `json:"schedule,omitempty"`
+
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	LastError   string `json:"last_error,omitempty"`
+
+	// RunAt is when the job is next due
+	RunAt time.Time `json:"run_at"`
+
+	// Owner and LeaseUntil identify the worker running the job and how long
+	// its claim lasts
+	Owner      string    `json:"owner,omitempty"`
+	LeaseUntil time.Time `json:"lease_until"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// NewJob creates a queued job due at runAt
+func NewJob(jobType string, payload map[string]interface{}, runAt time.Time) *Job {
+	id := uuid.New().String()
+	return &Job{
+		Key:       id,
+		ID:        id,
+		Type:      jobType,
+		Payload:   payload,
+		Status:    StatusQueued,
+		RunAt:     storedTime(runAt),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// storedTime truncates times compared in queries to whole seconds so that
+// their RFC 3339 strings sort chronologically
+func storedTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// Filter selects jobs to list
+type Filter struct {
+	Type   string
+	Status string
+
+	// Limit caps the number of jobs returned (0 for no limit)
+	Limit int
+}
+
+// Store persists jobs
+type Store interface {
+	// Create stores a new job, failing with ErrJobExists if its ID is taken
+	Create(ctx context.Context, job *Job) error
+
+	// Claim marks up to limit jobs of the type as running for the owner and
+	// returns them, oldest run time first. Queued jobs due at now and running
+	// jobs whose lease expired are claimed; each claim counts as an attempt.
+	Claim(ctx context.Context, jobType string, now, leaseUntil time.Time, owner string, limit int) ([]*Job, error)
+
+	// Update saves a job's state
+	Update(ctx context.Context, job *Job) error
+
+	// Get retrieves a job
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// List returns jobs matching the filter, newest first
+	List(ctx context.Context, filter Filter) ([]*Job, error)
+
+	// Counts returns the number of jobs by type and status
+	Counts(ctx context.Context) (map[string]map[string]int, error)
+
+	// Purge removes succeeded and canceled jobs finished before the time and
+	// returns how many were removed
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// permanentError marks handler errors that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error so the job is dead-lettered without further attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,542 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// TypePurgeHistory is the built-in job type removing old finished jobs
+const TypePurgeHistory = "jobs.purge_history"
+
+const (
+	defaultPollInterval   = 2 * time.Second
+	defaultConcurrency    = 1
+	defaultMaxAttempts    = 5
+	defaultTimeout        = 10 * time.Minute
+	defaultBaseBackoff    = 10 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultPurgeFrequency = time.Hour
+
+	// leaseGrace extends a claim past the job timeout so that a slow save of
+	// the outcome does not let another worker claim the job
+	leaseGrace = time.Minute
+)
+
+// Handler runs one job. It may set job.Result. Returning an error schedules
+// a retry unless the error is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// TypeOptions configures a job type. Unset fields take the queue defaults.
+type TypeOptions struct {
+	// Concurrency caps the jobs of the type this process runs at once
+	Concurrency int
+
+	// MaxAttempts is the number of attempts before a job is dead-lettered
+	MaxAttempts int
+
+	// Timeout bounds a single attempt
+	Timeout time.Duration
+}
+
+// Config configures the job queue
+type Config struct {
+	// PollInterval is how often due jobs are claimed
+	PollInterval time.Duration
+
+	// Defaults for job types that do not set their own options
+	Defaults TypeOptions
+
+	// Types override the options of individual job types
+	Types map[string]TypeOptions
+
+	// BaseBackoff is the delay after the first failed attempt; it doubles
+	// with every further attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// Retention is how long succeeded and canceled jobs are kept (0 keeps them)
+	Retention time.Duration
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.JobsConfig) Config {
+	types := make(map[string]TypeOptions, len(cfg.Types))
+	for name, t := range cfg.Types {
+		types[name] = TypeOptions{
+			Concurrency: t.Concurrency,
+			MaxAttempts: t.MaxAttempts,
+			Timeout:     time.Duration(t.TimeoutSeconds) * time.Second,
+		}
+	}
+	return Config{
+		PollInterval: time.Duration(cfg.PollIntervalSeconds) * time.Second,
+		Defaults: TypeOptions{
+			Concurrency: cfg.Concurrency,
+			MaxAttempts: cfg.MaxAttempts,
+			Timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+		},
+		Types:       types,
+		BaseBackoff: time.Duration(cfg.BaseBackoffSeconds) * time.Second,
+		MaxBackoff:  time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+		Retention:   time.Duration(cfg.RetentionHours) * time.Hour,
+	}
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	c.Defaults = c.Defaults.or(TypeOptions{
+		Concurrency: defaultConcurrency,
+		MaxAttempts: defaultMaxAttempts,
+		Timeout:     defaultTimeout,
+	})
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaultBaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	return c
+}
+
+// or fills unset options from fallback
+func (o TypeOptions) or(fallback TypeOptions) TypeOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = fallback.Concurrency
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = fallback.MaxAttempts
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = fallback.Timeout
+	}
+	return o
+}
+
+// TypeInfo describes a registered job type
+type TypeInfo struct {
+	Type           string         `json:"type"`
+	Concurrency    int            `json:"concurrency"`
+	MaxAttempts    int            `json:"max_attempts"`
+	TimeoutSeconds int            `json:"timeout_seconds"`
+	Running        int            `json:"running"`
+	Counts         map[string]int `json:"counts"`
+}
+
+// Schedule enqueues a job of a type at a fixed interval
+type Schedule struct {
+	Name    string                 `json:"name"`
+	Type    string                 `json:"type"`
+	Every   time.Duration          `json:"every"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+
+	next time.Time
+}
+
+// EnqueueOptions configures a single job
+type EnqueueOptions struct {
+	// RunAt delays the job (default now)
+	RunAt time.Time
+
+	// MaxAttempts overrides the type's attempts for this job
+	MaxAttempts int
+
+	// ID makes enqueuing idempotent: a second job with the same ID fails with ErrJobExists
+	ID string
+}
+
+type jobType struct {
+	handler Handler
+	options TypeOptions
+	running int
+}
+
+// Queue runs jobs with the handler registered for their type
+type Queue struct {
+	store  Store
+	config Config
+	logger *log.Logger
+	owner  string
+
+	mu        sync.Mutex
+	types     map[string]*jobType
+	schedules []*Schedule
+	running   sync.WaitGroup
+	cancel    context.CancelFunc
+	stopped   chan struct{}
+}
+
+// NewQueue creates a new job queue. When a retention is configured the
+// queue purges old finished jobs with a built-in recurring job.
+func NewQueue(store Store, cfg Config, logger *log.Logger) *Queue {
+	q := &Queue{
+		store:  store,
+		config: cfg.withDefaults(),
+		logger: logger,
+		owner:  uuid.New().String(),
+		types:  make(map[string]*jobType),
+	}
+
+	if q.config.Retention > 0 {
+		q.Register(TypePurgeHistory, q.purgeHistory, TypeOptions{Concurrency: 1, MaxAttempts: 3})
+		q.Every("purge-history", TypePurgeHistory, defaultPurgeFrequency, nil)
+	}
+	return q
+}
+
+// Register sets the handler for a job type. Options configured for the type
+// take precedence over the ones given here.
+func (q *Queue) Register(name string, handler Handler, options TypeOptions) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	options = q.config.Types[name].or(options).or(q.config.Defaults)
+	q.types[name] = &jobType{handler: handler, options: options}
+}
+
+// Every enqueues a job of the type at the interval. Each interval's job has
+// a deterministic ID, so several processes sharing the store enqueue it once.
+func (q *Queue) Every(name, jobType string, every time.Duration, payload map[string]interface{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.schedules = append(q.schedules, &Schedule{Name: name, Type: jobType, Every: every, Payload: payload})
+}
+
+// Enqueue stores a job for background execution
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload map[string]interface{}, options EnqueueOptions) (*Job, error) {
+	q.mu.Lock()
+	t := q.types[jobType]
+	q.mu.Unlock()
+	if t == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+
+	runAt := options.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	job := NewJob(jobType, payload, runAt)
+	if options.ID != "" {
+		job.Key, job.ID = options.ID, options.ID
+	}
+	job.MaxAttempts = t.options.MaxAttempts
+	if options.MaxAttempts > 0 {
+		job.MaxAttempts = options.MaxAttempts
+	}
+
+	if err := q.store.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get retrieves a job
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	return q.store.Get(ctx, id)
+}
+
+// List returns stored jobs, newest first
+func (q *Queue) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	return q.store.List(ctx, filter)
+}
+
+// Types describes the registered job types with their job counts. Types
+// that only exist in the store are included without options.
+func (q *Queue) Types(ctx context.Context) ([]*TypeInfo, error) {
+	counts, err := q.store.Counts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	q.mu.Lock()
+	infos := make(map[string]*TypeInfo, len(q.types))
+	for name, t := range q.types {
+		infos[name] = &TypeInfo{
+			Type:           name,
+			Concurrency:    t.options.Concurrency,
+			MaxAttempts:    t.options.MaxAttempts,
+			TimeoutSeconds: int(t.options.Timeout / time.Second),
+			Running:        t.running,
+		}
+	}
+	q.mu.Unlock()
+
+	for name, byStatus := range counts {
+		if infos[name] == nil {
+			infos[name] = &TypeInfo{Type: name}
+		}
+		infos[name].Counts = byStatus
+	}
+
+	result := make([]*TypeInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Counts == nil {
+			info.Counts = map[string]int{}
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result, nil
+}
+
+// Schedules returns the recurring schedules
+func (q *Queue) Schedules() []Schedule {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	schedules := make([]Schedule, len(q.schedules))
+	for i, s := range q.schedules {
+		schedules[i] = *s
+	}
+	return schedules
+}
+
+// Retry queues a dead-lettered job again with fresh attempts
+func (q *Queue) Retry(ctx context.Context, id string) (*Job, error) {
+	job, err := q.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusDead {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotDead, id, job.Status)
+	}
+
+	job.Status = StatusQueued
+	job.Attempts = 0
+	job.RunAt = storedTime(time.Now())
+	job.FinishedAt = nil
+	if err := q.store.Update(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	return job, nil
+}
+
+// Cancel cancels a queued job
+func (q *Queue) Cancel(ctx context.Context, id string) (*Job, error) {
+	job, err := q.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusQueued {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotQueued, id, job.Status)
+	}
+
+	now := time.Now().UTC()
+	job.Status = StatusCanceled
+	job.FinishedAt = &now
+	if err := q.store.Update(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return job, nil
+}
+
+// RunDue enqueues due scheduled jobs, then claims due jobs of every type up
+// to its free concurrency and starts them. It returns how many were started.
+func (q *Queue) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	q.enqueueScheduled(ctx, now)
+
+	q.mu.Lock()
+	names := make([]string, 0, len(q.types))
+	for name := range q.types {
+		names = append(names, name)
+	}
+	q.mu.Unlock()
+	sort.Strings(names)
+
+	started := 0
+	var errs []error
+	for _, name := range names {
+		q.mu.Lock()
+		t := q.types[name]
+		free := t.options.Concurrency - t.running
+		timeout := t.options.Timeout
+		q.mu.Unlock()
+		if free <= 0 {
+			continue
+		}
+
+		claimed, err := q.store.Claim(ctx, name, storedTime(now), storedTime(now.Add(timeout+leaseGrace)), q.owner, free)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim %s jobs: %w", name, err))
+			continue
+		}
+		for _, job := range claimed {
+			q.mu.Lock()
+			t.running++
+			q.mu.Unlock()
+
+			q.running.Add(1)
+			go q.run(context.WithoutCancel(ctx), t, job)
+			started++
+		}
+	}
+	return started, errors.Join(errs...)
+}
+
+// enqueueScheduled enqueues the job of every schedule whose interval began
+func (q *Queue) enqueueScheduled(ctx context.Context, now time.Time) {
+	q.mu.Lock()
+	var due []*Schedule
+	for _, s := range q.schedules {
+		if !now.Before(s.next) {
+			due = append(due, s)
+		}
+	}
+	q.mu.Unlock()
+
+	for _, s := range due {
+		slot := now.Truncate(s.Every)
+		_, err := q.Enqueue(ctx, s.Type, s.Payload, EnqueueOptions{ID: fmt.Sprintf("%s-%d", s.Name, slot.Unix())})
+		if err != nil && !errors.Is(err, ErrJobExists) {
+			q.logger.WithError(err).WithField("schedule", s.Name).Warn("Failed to enqueue scheduled job")
+			continue
+		}
+
+		// ErrJobExists means another process enqueued this interval's job
+		q.mu.Lock()
+		s.next = slot.Add(s.Every)
+		q.mu.Unlock()
+	}
+}
+
+// run executes a claimed job and records the outcome
+func (q *Queue) run(ctx context.Context, t *jobType, job *Job) {
+	defer q.running.Done()
+	defer func() {
+		q.mu.Lock()
+		t.running--
+		q.mu.Unlock()
+	}()
+
+	// Jobs claimed again after their worker died may have used up their attempts
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		err = Permanent(errors.New("worker lease expired on the last attempt"))
+	} else {
+		runCtx, cancel := context.WithTimeout(ctx, t.options.Timeout)
+		err = q.call(runCtx, t.handler, job)
+		cancel()
+	}
+
+	now := time.Now().UTC()
+	job.Owner = ""
+	job.LeaseUntil = time.Time{}
+	logger := q.logger.WithFields(log.Fields{
+		"job_id":   job.ID,
+		"type":     job.Type,
+		"attempts": job.Attempts,
+	})
+
+	switch {
+	case err == nil:
+		job.Status = StatusSucceeded
+		job.LastError = ""
+		job.FinishedAt = &now
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		job.Status = StatusDead
+		job.LastError = err.Error()
+		job.FinishedAt = &now
+		logger.WithError(err).Error("Job dead-lettered")
+	default:
+		job.Status = StatusQueued
+		job.LastError = err.Error()
+		job.RunAt = storedTime(now.Add(q.backoff(job.Attempts)))
+		logger.WithError(err).Warn("Job failed, will retry")
+	}
+
+	if updateErr := q.store.Update(ctx, job); updateErr != nil {
+		logger.WithError(updateErr).Error("Failed to save job state")
+	}
+}
+
+// call runs the handler, turning a panic into a job failure
+func (q *Queue) call(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// backoff returns the delay before the next attempt
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := 1; i < attempts && delay < q.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.config.MaxBackoff {
+		delay = q.config.MaxBackoff
+	}
+	return delay
+}
+
+// purgeHistory removes finished jobs older than the retention
+func (q *Queue) purgeHistory(ctx context.Context, job *Job) error {
+	purged, err := q.store.Purge(ctx, time.Now().UTC().Add(-q.config.Retention))
+	if err != nil {
+		return err
+	}
+	job.Result = map[string]interface{}{"purged": purged}
+	return nil
+}
+
+// Start claims and runs due jobs in the background
+func (q *Queue) Start(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	q.cancel = cancel
+	q.stopped = make(chan struct{})
+
+	go func() {
+		defer close(q.stopped)
+
+		ticker := time.NewTicker(q.config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := q.RunDue(ctx); err != nil {
+					q.logger.WithError(err).Warn("Failed to run due jobs")
+				}
+			}
+		}
+	}()
+
+	q.logger.WithField("interval", q.config.PollInterval).Info("Job queue started")
+}
+
+// Stop stops claiming jobs and waits for running jobs to finish
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	cancel, stopped := q.cancel, q.stopped
+	q.cancel = nil
+	q.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-stopped
+	}
+	q.running.Wait()
+}
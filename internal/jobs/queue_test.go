@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestQueue(cfg Config) (*Queue, *InMemoryStore) {
+	logger := log.New()
+	logger.SetLevel(log.PanicLevel)
+	store := NewInMemoryStore()
+	return NewQueue(store, cfg, logger), store
+}
+
+// runDue runs due jobs and waits for them to finish
+func runDue(t *testing.T, q *Queue) int {
+	t.Helper()
+	n, err := q.RunDue(context.Background())
+	if err != nil {
+		t.Fatalf("RunDue: %v", err)
+	}
+	q.running.Wait()
+	return n
+}
+
+func TestQueue_RunsEnqueuedJob(t *testing.T) {
+	q, _ := newTestQueue(Config{})
+	q.Register("report", func(ctx context.Context, job *Job) error {
+		job.Result = map[string]interface{}{"rows": job.Payload["rows"]}
+		return nil
+	}, TypeOptions{})
+
+	job, err := q.Enqueue(context.Background(), "report", map[string]interface{}{"rows": 3}, EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if n := runDue(t, q); n != 1 {
+		t.Fatalf("RunDue started %d jobs, want 1", n)
+	}
+
+	stored, err := q.Get(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != StatusSucceeded || stored.Attempts != 1 || stored.FinishedAt == nil {
+		t.Errorf("stored = %+v, want succeeded after one attempt", stored)
+	}
+	if stored.Result["rows"] != 3 {
+		t.Errorf("result = %v, want rows 3", stored.Result)
+	}
+}
+
+func TestQueue_EnqueueUnknownType(t *testing.T) {
+	q, _ := newTestQueue(Config{})
+	if _, err := q.Enqueue(context.Background(), "missing", nil, EnqueueOptions{}); !errors.Is(err, ErrUnknownType) {
+		t.Errorf("Enqueue err = %v, want ErrUnknownType", err)
+	}
+}
+
+func TestQueue_RetriesThenDeadLetters(t *testing.T) {
+	q, store := newTestQueue(Config{BaseBackoff: time.Minute, MaxBackoff: 3 * time.Minute})
+	q.Register("flaky", func(ctx context.Context, job *Job) error {
+		return errors.New("unavailable")
+	}, TypeOptions{MaxAttempts: 2})
+
+	job, _ := q.Enqueue(context.Background(), "flaky", nil, EnqueueOptions{})
+	start := time.Now().UTC()
+	runDue(t, q)
+
+	stored, _ := store.Get(context.Background(), job.ID)
+	if stored.Status != StatusQueued || stored.LastError != "unavailable" {
+		t.Fatalf("stored = %+v, want queued with last error", stored)
+	}
+	if stored.RunAt.Before(start.Add(time.Minute - time.Second)) {
+		t.Errorf("run at %v is before base backoff", stored.RunAt)
+	}
+
+	// Nothing is due until the backoff has passed
+	if n := runDue(t, q); n != 0 {
+		t.Fatalf("RunDue started %d jobs before backoff, want 0", n)
+	}
+
+	stored.RunAt = storedTime(start)
+	store.Update(context.Background(), stored)
+	runDue(t, q)
+
+	stored, _ = store.Get(context.Background(), job.ID)
+	if stored.Status != StatusDead || stored.Attempts != 2 {
+		t.Errorf("stored = %+v, want dead after two attempts", stored)
+	}
+
+	retried, err := q.Retry(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if retried.Status != StatusQueued || retried.Attempts != 0 {
+		t.Errorf("retried = %+v, want queued with no attempts", retried)
+	}
+}
+
+func TestQueue_PermanentErrorDeadLetters(t *testing.T) {
+	q, store := newTestQueue(Config{})
+	q.Register("bad", func(ctx context.Context, job *Job) error {
+		return Permanent(errors.New("invalid payload"))
+	}, TypeOptions{MaxAttempts: 5})
+
+	job, _ := q.Enqueue(context.Background(), "bad", nil, EnqueueOptions{})
+	runDue(t, q)
+
+	stored, _ := store.Get(context.Background(), job.ID)
+	if stored.Status != StatusDead || stored.Attempts != 1 || stored.LastError != "invalid payload" {
+		t.Errorf("stored = %+v, want dead after one attempt", stored)
+	}
+}
+
+func TestQueue_ConcurrencyLimit(t *testing.T) {
+	q, _ := newTestQueue(Config{})
+	release := make(chan struct{})
+	q.Register("slow", func(ctx context.Context, job *Job) error {
+		<-release
+		return nil
+	}, TypeOptions{Concurrency: 2})
+
+	for i := 0; i < 3; i++ {
+		q.Enqueue(context.Background(), "slow", nil, EnqueueOptions{})
+	}
+
+	n, err := q.RunDue(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("RunDue started %d jobs (err %v), want 2", n, err)
+	}
+	if n, _ := q.RunDue(context.Background()); n != 0 {
+		t.Errorf("RunDue started %d jobs at the limit, want 0", n)
+	}
+
+	close(release)
+	q.running.Wait()
+	if n := runDue(t, q); n != 1 {
+		t.Errorf("RunDue started %d jobs after release, want 1", n)
+	}
+}
+
+func TestQueue_ExpiredLeaseIsReclaimed(t *testing.T) {
+	q, store := newTestQueue(Config{})
+	runs := 0
+	q.Register("work", func(ctx context.Context, job *Job) error {
+		runs++
+		return nil
+	}, TypeOptions{})
+
+	job, _ := q.Enqueue(context.Background(), "work", nil, EnqueueOptions{})
+
+	// Simulate a worker that claimed the job and died
+	past := storedTime(time.Now().Add(-time.Minute))
+	if _, err := store.Claim(context.Background(), "work", storedTime(time.Now()), past, "dead-worker", 1); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	runDue(t, q)
+	stored, _ := store.Get(context.Background(), job.ID)
+	if runs != 1 || stored.Status != StatusSucceeded || stored.Attempts != 2 {
+		t.Errorf("runs = %d, stored = %+v, want one run succeeding on the second attempt", runs, stored)
+	}
+}
+
+func TestQueue_ScheduleEnqueuesOncePerInterval(t *testing.T) {
+	q, store := newTestQueue(Config{})
+	q.Register("tick", func(ctx context.Context, job *Job) error { return nil }, TypeOptions{})
+	q.Every("hourly-tick", "tick", time.Hour, nil)
+
+	runDue(t, q)
+	runDue(t, q)
+
+	jobs, _ := store.List(context.Background(), Filter{Type: "tick"})
+	if len(jobs) != 1 || jobs[0].Status != StatusSucceeded {
+		t.Errorf("jobs = %+v, want one succeeded job", jobs)
+	}
+}
+
+func TestQueue_PurgesFinishedJobs(t *testing.T) {
+	q, store := newTestQueue(Config{Retention: time.Hour})
+	q.Register("work", func(ctx context.Context, job *Job) error { return nil }, TypeOptions{})
+
+	old, _ := q.Enqueue(context.Background(), "work", nil, EnqueueOptions{})
+	runDue(t, q)
+	stored, _ := store.Get(context.Background(), old.ID)
+	finished := time.Now().UTC().Add(-2 * time.Hour)
+	stored.FinishedAt = &finished
+	store.Update(context.Background(), stored)
+
+	purged, err := store.Purge(context.Background(), time.Now().UTC().Add(-time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("Purge = %d, %v, want 1", purged, err)
+	}
+	if _, err := store.Get(context.Background(), old.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get after purge err = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,393 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionJobs is the job collection name
+const CollectionJobs = "jobs"
+
+// ArangoStore persists jobs in ArangoDB
+type ArangoStore struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoStore creates a new ArangoDB-backed job store
+func NewArangoStore(dbClient *database.ArangoClient) (*ArangoStore, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionJobs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionJobs, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionJobs).Info("Created new collection")
+	}
+
+	indexes := map[string][]string{
+		"idx_jobs_claim":   {"type", "status", "run_at"},
+		"idx_jobs_created": {"created_at"},
+	}
+	for name, fields := range indexes {
+		if _, _, err := col.EnsurePersistentIndex(ctx, fields, &driver.EnsurePersistentIndexOptions{Name: name}); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+	}
+
+	return &ArangoStore{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create stores a new job
+func (s *ArangoStore) Create(ctx context.Context, job *Job) error {
+	job.Key = job.ID
+	if _, err := s.collection.CreateDocument(ctx, job); err != nil {
+		if driver.IsConflict(err) {
+			return fmt.Errorf("%w: %s", ErrJobExists, job.ID)
+		}
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+	return nil
+}
+
+// Claim marks due jobs of the type as running for the owner. The revision
+// check makes a concurrent claim of the same job fail with a conflict, in
+// which case nothing is claimed this time.
+func (s *ArangoStore) Claim(ctx context.Context, jobType string, now, leaseUntil time.Time, owner string, limit int) ([]*Job, error) {
+	query := `
+		FOR j IN @@collection
+			FILTER j.type == @type
+			FILTER (j.status == @queued AND j.run_at <= @now) OR (j.status == @running AND j.lease_until <= @now)
+			SORT j.run_at ASC
+			LIMIT @limit
+			UPDATE { _key: j._key, _rev: j._rev } WITH {
+				status: @running,
+				owner: @owner,
+				lease_until: @leaseUntil,
+				attempts: j.attempts + 1,
+				started_at: @now
+			} IN @@collection OPTIONS { ignoreRevs: false }
+			RETURN NEW
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionJobs,
+		"type":        jobType,
+		"queued":      StatusQueued,
+		"running":     StatusRunning,
+		"now":         now,
+		"leaseUntil":  leaseUntil,
+		"owner":       owner,
+		"limit":       limit,
+	}
+
+	jobs, err := s.query(ctx, query, bindVars)
+	if err != nil {
+		if driver.IsConflict(err) || driver.IsPreconditionFailed(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Update saves a job's state
+func (s *ArangoStore) Update(ctx context.Context, job *Job) error {
+	job.Key = job.ID
+	if _, err := s.collection.ReplaceDocument(ctx, job.ID, job); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrJobNotFound, job.ID)
+		}
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a job
+func (s *ArangoStore) Get(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if _, err := s.collection.ReadDocument(ctx, id, &job); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+	return &job, nil
+}
+
+// List returns jobs matching the filter, newest first
+func (s *ArangoStore) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	query := `
+		FOR j IN @@collection
+			FILTER @type == "" OR j.type == @type
+			FILTER @status == "" OR j.status == @status
+			SORT j.created_at DESC
+			LIMIT @limit
+			RETURN j
+	`
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 1 << 30
+	}
+	bindVars := map[string]interface{}{
+		"@collection": CollectionJobs,
+		"type":        filter.Type,
+		"status":      filter.Status,
+		"limit":       limit,
+	}
+	return s.query(ctx, query, bindVars)
+}
+
+// Counts returns the number of jobs by type and status
+func (s *ArangoStore) Counts(ctx context.Context) (map[string]map[string]int, error) {
+	query := `
+		FOR j IN @@collection
+			COLLECT type = j.type, status = j.status WITH COUNT INTO n
+			RETURN { type, status, n }
+	`
+	cursor, err := s.db.Query(ctx, query, map[string]interface{}{"@collection": CollectionJobs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer cursor.Close()
+
+	counts := make(map[string]map[string]int)
+	for {
+		var row struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+			N      int    `json:"n"`
+		}
+		_, err := cursor.ReadDocument(ctx, &row)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job count: %w", err)
+		}
+		if counts[row.Type] == nil {
+			counts[row.Type] = make(map[string]int)
+		}
+		counts[row.Type][row.Status] = row.N
+	}
+	return counts, nil
+}
+
+// Purge removes succeeded and canceled jobs finished before the time
+func (s *ArangoStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		FOR j IN @@collection
+			FILTER j.status IN @statuses AND j.finished_at != null
+			FILTER DATE_TIMESTAMP(j.finished_at) < DATE_TIMESTAMP(@before)
+			REMOVE j IN @@collection
+			COLLECT WITH COUNT INTO n
+			RETURN n
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionJobs,
+		"statuses":    []string{StatusSucceeded, StatusCanceled},
+		"before":      before.UTC(),
+	}
+
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge jobs: %w", err)
+	}
+	defer cursor.Close()
+
+	var purged int
+	if _, err := cursor.ReadDocument(ctx, &purged); err != nil && !driver.IsNoMoreDocuments(err) {
+		return 0, fmt.Errorf("failed to read purge count: %w", err)
+	}
+	return purged, nil
+}
+
+func (s *ArangoStore) query(ctx context.Context, query string, bindVars map[string]interface{}) ([]*Job, error) {
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	var jobs []*Job
+	for {
+		var job Job
+		_, err := cursor.ReadDocument(ctx, &job)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// InMemoryStore keeps jobs in memory. Leases only coordinate workers of one
+// process, and queued jobs are lost on restart.
+type InMemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewInMemoryStore creates a new in-memory job store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Create stores a new job
+func (s *InMemoryStore) Create(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.ID)
+	}
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return nil
+}
+
+// Claim marks due jobs of the type as running for the owner
+func (s *InMemoryStore) Claim(ctx context.Context, jobType string, now, leaseUntil time.Time, owner string, limit int) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*Job
+	for _, job := range s.jobs {
+		if job.Type != jobType {
+			continue
+		}
+		queued := job.Status == StatusQueued && !job.RunAt.After(now)
+		expired := job.Status == StatusRunning && !job.LeaseUntil.After(now)
+		if queued || expired {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Job, 0, len(due))
+	for _, job := range due {
+		started := now
+		job.Status = StatusRunning
+		job.Owner = owner
+		job.LeaseUntil = leaseUntil
+		job.Attempts++
+		job.StartedAt = &started
+		j := *job
+		claimed = append(claimed, &j)
+	}
+	return claimed, nil
+}
+
+// Update saves a job's state
+func (s *InMemoryStore) Update(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, job.ID)
+	}
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return nil
+}
+
+// Get retrieves a job
+func (s *InMemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	j := *job
+	return &j, nil
+}
+
+// List returns jobs matching the filter, newest first
+func (s *InMemoryStore) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []*Job
+	for _, job := range s.jobs {
+		if filter.Type != "" && job.Type != filter.Type {
+			continue
+		}
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		j := *job
+		jobs = append(jobs, &j)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+// Counts returns the number of jobs by type and status
+func (s *InMemoryStore) Counts(ctx context.Context) (map[string]map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]map[string]int)
+	for _, job := range s.jobs {
+		if counts[job.Type] == nil {
+			counts[job.Type] = make(map[string]int)
+		}
+		counts[job.Type][job.Status]++
+	}
+	return counts, nil
+}
+
+// Purge removes succeeded and canceled jobs finished before the time
+func (s *InMemoryStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, job := range s.jobs {
+		finished := job.Status == StatusSucceeded || job.Status == StatusCanceled
+		if finished && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			delete(s.jobs, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// Token is a bearer token clients authenticate with
+type Token struct {
+	Name     string   // Identifies the client in logs
+	Secret   string   // The bearer token itself
+	Write    bool     // Allows the tools that create goals and work items
+	Agencies []string // Agencies the token may access (all when empty)
+	Tenant   string   // Scopes the token's requests to a tenant (none when empty)
+}
+
+// allowsAgency reports whether the token may access an agency
+func (t *Token) allowsAgency(agencyID string) bool {
+	if len(t.Agencies) == 0 {
+		return true
+	}
+	for _, id := range t.Agencies {
+		if id == agencyID {
+			return true
+		}
+	}
+	return false
+}
+
+// unrestricted reports whether the token may access every agency. Agent
+// memory is not tied to an agency, so only such tokens may read it.
+func (t *Token) unrestricted() bool {
+	return len(t.Agencies) == 0
+}
+
+type tokenContextKey struct{}
+
+// withToken attaches the authenticated token to ctx
+func withToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// tokenFromContext returns the authenticated token
+func tokenFromContext(ctx context.Context) *Token {
+	token, _ := ctx.Value(tokenContextKey{}).(*Token)
+	return token
+}
+
+// authenticate returns the token matching the request's bearer token
+func (s *Server) authenticate(r *http.Request) *Token {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		return nil
+	}
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(s.tokens[i].Secret)) == 1 {
+			return &s.tokens[i]
+		}
+	}
+	return nil
+}
+
+// AuthMiddleware rejects requests without a valid bearer token
+func (s *Server) AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := s.authenticate(c.Request)
+		if token == nil {
+			c.Header("WWW-Authenticate", `Bearer realm="mcp"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a valid bearer token is required"})
+			return
+		}
+		ctx := tenant.WithTenant(withToken(c.Request.Context(), token), token.Tenant)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
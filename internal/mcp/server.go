@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxRequestBytes bounds the size of a JSON-RPC request body
+const maxRequestBytes = 1 << 20
+
+// AgencyStore is the part of the agency service the server uses
+type AgencyStore interface {
+	ListAgencies(ctx context.Context, filters agency.AgencyFilters) ([]*agency.Agency, error)
+	GetAgency(ctx context.Context, id string) (*agency.Agency, error)
+	GetAgencyOverview(ctx context.Context, agencyID string) (*agency.Overview, error)
+	GetGoals(ctx context.Context, agencyID string) ([]*agency.Goal, error)
+	GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error)
+	CreateGoal(ctx context.Context, agencyID string, code string, description string) (*agency.Goal, error)
+	CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error)
+}
+
+// MemoryStore is the part of the memory service the server uses
+type MemoryStore interface {
+	Search(ctx context.Context, agentID string, query memory.MemoryQuery) ([]*memory.LongtermMemory, error)
+	ListWorking(ctx context.Context, agentID string, filters memory.MemoryFilters) ([]*memory.WorkingMemory, error)
+}
+
+// Config holds the server's dependencies and settings
+type Config struct {
+	Agencies AgencyStore        // Required
+	Memory   MemoryStore        // Optional: memory tools are not offered without it
+	Outbox   *outbox.Dispatcher // Optional: notifies webhooks of goals created through the server
+	Tokens   []Token            // Bearer tokens accepted by the server
+	Version  string             // Reported to clients in serverInfo
+	Logger   *logrus.Logger
+}
+
+// Server is an MCP server
+type Server struct {
+	agencies AgencyStore
+	memory   MemoryStore
+	outbox   *outbox.Dispatcher
+	tokens   []Token
+	version  string
+	logger   *logrus.Logger
+	tools    []*toolDef
+}
+
+// NewServer creates an MCP server
+func NewServer(cfg Config) (*Server, error) {
+	if cfg.Agencies == nil {
+		return nil, errors.New("mcp: an agency store is required")
+	}
+	if len(cfg.Tokens) == 0 {
+		return nil, errors.New("mcp: at least one token is required")
+	}
+	for _, token := range cfg.Tokens {
+		if token.Secret == "" {
+			return nil, errors.New("mcp: token " + token.Name + " has no secret")
+		}
+		if token.Tenant != "" {
+			if err := tenant.ValidateID(token.Tenant); err != nil {
+				return nil, fmt.Errorf("mcp: token %s: %w", token.Name, err)
+			}
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logrus.StandardLogger()
+	}
+	if cfg.Version == "" {
+		cfg.Version = "dev"
+	}
+
+	s := &Server{
+		agencies: cfg.Agencies,
+		memory:   cfg.Memory,
+		outbox:   cfg.Outbox,
+		tokens:   cfg.Tokens,
+		version:  cfg.Version,
+		logger:   cfg.Logger,
+	}
+	s.tools = s.buildTools()
+	return s, nil
+}
+
+// RegisterRoutes registers the MCP endpoint at path
+func (s *Server) RegisterRoutes(router gin.IRouter, path string) {
+	router.POST(path, s.AuthMiddleware(), s.Handle)
+	// Server-initiated streams and session termination are not supported
+	router.GET(path, s.AuthMiddleware(), methodNotAllowed)
+	router.DELETE(path, s.AuthMiddleware(), methodNotAllowed)
+}
+
+func methodNotAllowed(c *gin.Context) {
+	c.Header("Allow", http.MethodPost)
+	c.Status(http.StatusMethodNotAllowed)
+}
+
+// Handle serves a JSON-RPC message posted by a client. Notifications are
+// acknowledged with 202 and no body; requests get a single JSON response.
+func (s *Server) Handle(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRequestBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(nil, codeParseError, "failed to read request"))
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(http.StatusOK, errorResponse(nil, codeParseError, "invalid JSON"))
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		c.JSON(http.StatusOK, errorResponse(req.ID, codeInvalidRequest, "not a JSON-RPC 2.0 request"))
+		return
+	}
+	if req.isNotification() {
+		c.Status(http.StatusAccepted)
+		return
+	}
+
+	result, rpcErr := s.dispatch(c.Request.Context(), &req)
+	if rpcErr != nil {
+		c.JSON(http.StatusOK, response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr})
+		return
+	}
+	c.JSON(http.StatusOK, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// dispatch runs a request's method
+func (s *Server) dispatch(ctx context.Context, req *request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities: map[string]interface{}{
+				"tools": map[string]interface{}{"listChanged": false},
+			},
+			ServerInfo:   serverInfo{Name: "codevaldcortex", Version: s.version},
+			Instructions: "Query CodeValdCortex agencies, their goals and work items, and agent memory.",
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools(tokenFromContext(ctx))}, nil
+	case "tools/call":
+		var params callToolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "tools/call requires a tool name"}
+		}
+		return s.callTool(ctx, params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
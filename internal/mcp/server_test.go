@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAgencies struct {
+	agencies  []*agency.Agency
+	goals     map[string][]*agency.Goal
+	workItems map[string][]*agency.WorkItem
+}
+
+func (f *fakeAgencies) ListAgencies(ctx context.Context, filters agency.AgencyFilters) ([]*agency.Agency, error) {
+	return f.agencies, nil
+}
+
+func (f *fakeAgencies) GetAgency(ctx context.Context, id string) (*agency.Agency, error) {
+	for _, ag := range f.agencies {
+		if ag.ID == id {
+			return ag, nil
+		}
+	}
+	return nil, fmt.Errorf("agency %s not found", id)
+}
+
+func (f *fakeAgencies) GetAgencyOverview(ctx context.Context, agencyID string) (*agency.Overview, error) {
+	return &agency.Overview{AgencyID: agencyID, Introduction: "Intro"}, nil
+}
+
+func (f *fakeAgencies) GetGoals(ctx context.Context, agencyID string) ([]*agency.Goal, error) {
+	return f.goals[agencyID], nil
+}
+
+func (f *fakeAgencies) GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error) {
+	return f.workItems[agencyID], nil
+}
+
+func (f *fakeAgencies) CreateGoal(ctx context.Context, agencyID, code, description string) (*agency.Goal, error) {
+	goal := &agency.Goal{AgencyID: agencyID, Code: code, Description: description}
+	f.goals[agencyID] = append(f.goals[agencyID], goal)
+	return goal, nil
+}
+
+func (f *fakeAgencies) CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error) {
+	workItem := &agency.WorkItem{AgencyID: agencyID, Title: req.Title, Description: req.Description, Tags: req.Tags}
+	f.workItems[agencyID] = append(f.workItems[agencyID], workItem)
+	return workItem, nil
+}
+
+type fakeMemory struct{}
+
+func (fakeMemory) Search(ctx context.Context, agentID string, query memory.MemoryQuery) ([]*memory.LongtermMemory, error) {
+	return []*memory.LongtermMemory{{AgentID: agentID, Key: "fact"}}, nil
+}
+
+func (fakeMemory) ListWorking(ctx context.Context, agentID string, filters memory.MemoryFilters) ([]*memory.WorkingMemory, error) {
+	return nil, nil
+}
+
+func newTestServer(t *testing.T) (*gin.Engine, *fakeAgencies) {
+	gin.SetMode(gin.TestMode)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	agencies := &fakeAgencies{
+		agencies: []*agency.Agency{{ID: "billing", Name: "billing"}, {ID: "support", Name: "support"}},
+		goals: map[string][]*agency.Goal{
+			"billing": {{AgencyID: "billing", Code: "G001", Description: "Bill on time"}},
+		},
+		workItems: map[string][]*agency.WorkItem{},
+	}
+	server, err := NewServer(Config{
+		Agencies: agencies,
+		Memory:   fakeMemory{},
+		Tokens: []Token{
+			{Name: "reader", Secret: "read-secret"},
+			{Name: "writer", Secret: "write-secret", Write: true},
+			{Name: "billing", Secret: "billing-secret", Write: true, Agencies: []string{"billing"}},
+		},
+		Logger: logger,
+	})
+	require.NoError(t, err)
+
+	router := gin.New()
+	server.RegisterRoutes(router, "/mcp")
+	return router, agencies
+}
+
+// rpc posts a JSON-RPC request and decodes the response
+func rpc(t *testing.T, router http.Handler, token, method string, params interface{}) (int, map[string]interface{}) {
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if rec.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return rec.Code, resp
+}
+
+// callTool calls a tool and returns its result
+func callTool(t *testing.T, router http.Handler, token, name string, args interface{}) (string, bool) {
+	code, resp := rpc(t, router, token, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+	require.Equal(t, http.StatusOK, code)
+	require.Nil(t, resp["error"])
+
+	result := resp["result"].(map[string]interface{})
+	text := result["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	isError, _ := result["isError"].(bool)
+	return text, isError
+}
+
+func toolNames(t *testing.T, router http.Handler, token string) []string {
+	_, resp := rpc(t, router, token, "tools/list", nil)
+	var names []string
+	for _, tool := range resp["result"].(map[string]interface{})["tools"].([]interface{}) {
+		names = append(names, tool.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+func TestServer_RequiresBearerToken(t *testing.T) {
+	router, _ := newTestServer(t)
+
+	code, _ := rpc(t, router, "", "initialize", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = rpc(t, router, "wrong", "initialize", nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, resp := rpc(t, router, "read-secret", "initialize", nil)
+	require.Equal(t, http.StatusOK, code)
+	result := resp["result"].(map[string]interface{})
+	assert.Equal(t, ProtocolVersion, result["protocolVersion"])
+}
+
+func TestServer_NotificationsAreAccepted(t *testing.T) {
+	router, _ := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	req.Header.Set("Authorization", "Bearer read-secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Zero(t, rec.Body.Len())
+}
+
+func TestServer_WriteToolsRequireWriteAccess(t *testing.T) {
+	router, agencies := newTestServer(t)
+
+	assert.NotContains(t, toolNames(t, router, "read-secret"), "create_goal")
+	assert.Contains(t, toolNames(t, router, "write-secret"), "create_goal")
+
+	args := map[string]interface{}{"agency_id": "billing", "code": "G002", "description": "Collect payments"}
+	text, isError := callTool(t, router, "read-secret", "create_goal", args)
+	assert.True(t, isError)
+	assert.Contains(t, text, "write access")
+	assert.Len(t, agencies.goals["billing"], 1)
+
+	_, isError = callTool(t, router, "write-secret", "create_goal", args)
+	assert.False(t, isError)
+	assert.Len(t, agencies.goals["billing"], 2)
+
+	_, isError = callTool(t, router, "write-secret", "create_work_item", map[string]interface{}{
+		"agency_id": "billing", "title": "Send invoices", "description": "Monthly", "tags": []string{"finance"},
+	})
+	assert.False(t, isError)
+	require.Len(t, agencies.workItems["billing"], 1)
+	assert.Equal(t, []string{"finance"}, agencies.workItems["billing"][0].Tags)
+}
+
+func TestServer_ReadTools(t *testing.T) {
+	router, _ := newTestServer(t)
+
+	text, isError := callTool(t, router, "read-secret", "list_goals", map[string]interface{}{"agency_id": "billing"})
+	require.False(t, isError, text)
+	assert.Contains(t, text, "Bill on time")
+
+	text, isError = callTool(t, router, "read-secret", "get_agency", map[string]interface{}{"agency_id": "billing"})
+	require.False(t, isError, text)
+	assert.Contains(t, text, "Intro")
+
+	text, isError = callTool(t, router, "read-secret", "search_memory", map[string]interface{}{"agent_id": "agent-1", "query": "fact"})
+	require.False(t, isError, text)
+	assert.Contains(t, text, "agent-1")
+
+	_, isError = callTool(t, router, "read-secret", "list_goals", map[string]interface{}{"agency_id": "billing", "bogus": true})
+	assert.True(t, isError, "unknown arguments are rejected")
+
+	_, resp := rpc(t, router, "read-secret", "tools/call", map[string]interface{}{"name": "drop_database"})
+	assert.NotNil(t, resp["error"])
+}
+
+func TestServer_AgencyRestrictedTokens(t *testing.T) {
+	router, agencies := newTestServer(t)
+
+	text, isError := callTool(t, router, "billing-secret", "list_agencies", nil)
+	require.False(t, isError, text)
+	assert.Contains(t, text, "billing")
+	assert.NotContains(t, text, "support")
+
+	text, isError = callTool(t, router, "billing-secret", "create_goal", map[string]interface{}{
+		"agency_id": "support", "code": "G001", "description": "Answer tickets",
+	})
+	assert.True(t, isError)
+	assert.Contains(t, text, "not found")
+	assert.Empty(t, agencies.goals["support"])
+
+	_, isError = callTool(t, router, "billing-secret", "search_memory", map[string]interface{}{"agent_id": "agent-1"})
+	assert.True(t, isError, "memory is not tied to an agency")
+}
@@ -0,0 +1,451 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// toolDef is a tool together with its handler
+type toolDef struct {
+	Tool
+	write   bool // Only offered to tokens with write access
+	handler func(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error)
+}
+
+// buildTools returns the tools the server's dependencies support
+func (s *Server) buildTools() []*toolDef {
+	tools := []*toolDef{
+		{
+			Tool: readTool("list_agencies", "List agencies, optionally filtered by category, status or a search text.",
+				objectSchema(map[string]interface{}{
+					"category": stringProperty("Only agencies in this category"),
+					"status":   stringProperty("Only agencies with this status (active, inactive, paused, archived)"),
+					"search":   stringProperty("Text to search for in agency names and descriptions"),
+					"limit":    integerProperty(fmt.Sprintf("Maximum number of agencies (default %d, at most %d)", defaultListLimit, maxListLimit)),
+					"offset":   integerProperty("Number of agencies to skip"),
+				})),
+			handler: s.listAgencies,
+		},
+		{
+			Tool: readTool("get_agency", "Get an agency with its overview (introduction).",
+				objectSchema(map[string]interface{}{
+					"agency_id": stringProperty("ID of the agency"),
+				}, "agency_id")),
+			handler: s.getAgency,
+		},
+		{
+			Tool: readTool("list_goals", "List the goals of an agency.",
+				objectSchema(map[string]interface{}{
+					"agency_id": stringProperty("ID of the agency"),
+				}, "agency_id")),
+			handler: s.listGoals,
+		},
+		{
+			Tool: readTool("list_work_items", "List the work items of an agency.",
+				objectSchema(map[string]interface{}{
+					"agency_id": stringProperty("ID of the agency"),
+				}, "agency_id")),
+			handler: s.listWorkItems,
+		},
+		{
+			Tool: writeTool("create_goal", "Create a goal in an agency.",
+				objectSchema(map[string]interface{}{
+					"agency_id":   stringProperty("ID of the agency"),
+					"code":        stringProperty("Short unique code of the goal, e.g. G001"),
+					"description": stringProperty("What the goal is"),
+				}, "agency_id", "code", "description")),
+			write:   true,
+			handler: s.createGoal,
+		},
+		{
+			Tool: writeTool("create_work_item", "Create a work item in an agency.",
+				objectSchema(map[string]interface{}{
+					"agency_id":             stringProperty("ID of the agency"),
+					"title":                 stringProperty("Title of the work item"),
+					"description":           stringProperty("What the work item involves"),
+					"deliverables":          stringArrayProperty("What the work item produces"),
+					"dependencies":          stringArrayProperty("Codes of work items this one depends on"),
+					"tags":                  stringArrayProperty("Tags"),
+					"required_capabilities": stringArrayProperty("Capabilities an agent needs to do the work"),
+					"goal_keys":             stringArrayProperty("Keys of the goals the work item contributes to"),
+				}, "agency_id", "title", "description")),
+			write:   true,
+			handler: s.createWorkItem,
+		},
+	}
+
+	if s.memory != nil {
+		tools = append(tools,
+			&toolDef{
+				Tool: readTool("search_memory", "Search an agent's long-term memory.",
+					objectSchema(map[string]interface{}{
+						"agent_id": stringProperty("ID of the agent"),
+						"query":    stringProperty("Text to search for"),
+						"category": stringProperty("Only memories in this category"),
+						"tags":     stringArrayProperty("Only memories with these tags"),
+						"top_k":    integerProperty("Maximum number of memories (default 10)"),
+					}, "agent_id")),
+				handler: s.searchMemory,
+			},
+			&toolDef{
+				Tool: readTool("list_working_memory", "List an agent's working (short-term) memory.",
+					objectSchema(map[string]interface{}{
+						"agent_id": stringProperty("ID of the agent"),
+						"text":     stringProperty("Only memories whose key or value contains this text"),
+						"tags":     stringArrayProperty("Only memories with these tags"),
+					}, "agent_id")),
+				handler: s.listWorkingMemory,
+			},
+		)
+	}
+
+	return tools
+}
+
+// listTools returns the tools offered to a token
+func (s *Server) listTools(token *Token) []Tool {
+	tools := make([]Tool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		if tool.write && (token == nil || !token.Write) {
+			continue
+		}
+		tools = append(tools, tool.Tool)
+	}
+	return tools
+}
+
+// callTool runs a tool. Unknown tools are protocol errors; everything that
+// goes wrong in a known tool is reported in the result.
+func (s *Server) callTool(ctx context.Context, params callToolParams) (interface{}, *rpcError) {
+	var tool *toolDef
+	for _, candidate := range s.tools {
+		if candidate.Name == params.Name {
+			tool = candidate
+			break
+		}
+	}
+	if tool == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+
+	token := tokenFromContext(ctx)
+	if token == nil {
+		return errorResult("not authenticated"), nil
+	}
+	if tool.write && !token.Write {
+		return errorResult(fmt.Sprintf("%s requires a token with write access", tool.Name)), nil
+	}
+
+	args := params.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := tool.handler(ctx, token, args)
+	if err != nil {
+		s.logger.WithError(err).WithField("tool", tool.Name).WithField("token", token.Name).Warn("MCP tool call failed")
+		return errorResult(toolErrorMessage(err)), nil
+	}
+	if tool.write {
+		s.logger.WithField("tool", tool.Name).WithField("token", token.Name).Info("MCP write tool called")
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: "failed to encode tool result"}
+	}
+	return ToolResult{Content: []Content{{Type: "text", Text: string(data)}}}, nil
+}
+
+func errorResult(message string) ToolResult {
+	return ToolResult{Content: []Content{{Type: "text", Text: message}}, IsError: true}
+}
+
+// toolErrorMessage describes a tool error, including validation violations
+func toolErrorMessage(err error) string {
+	var validationErr *agency.ValidationError
+	if errors.As(err, &validationErr) && len(validationErr.Violations) > 0 {
+		data, _ := json.Marshal(validationErr.Violations)
+		return validationErr.Error() + ": " + string(data)
+	}
+	return err.Error()
+}
+
+// errAgencyForbidden is returned for agencies a token may not access. It
+// reads like a missing agency so tokens cannot probe for agency IDs.
+func errAgencyForbidden(agencyID string) error {
+	return fmt.Errorf("agency %s not found", agencyID)
+}
+
+// decodeArgs decodes tool arguments, rejecting unknown ones
+func decodeArgs(args json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(string(args)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+type agencyArgs struct {
+	AgencyID string `json:"agency_id"`
+}
+
+// agencyID decodes arguments naming an agency the token may access
+func agencyID(token *Token, args json.RawMessage, v interface{ agency() string }) (string, error) {
+	if err := decodeArgs(args, v); err != nil {
+		return "", err
+	}
+	id := v.agency()
+	if id == "" {
+		return "", errors.New("agency_id is required")
+	}
+	if !token.allowsAgency(id) {
+		return "", errAgencyForbidden(id)
+	}
+	return id, nil
+}
+
+func (a *agencyArgs) agency() string { return a.AgencyID }
+
+func (s *Server) listAgencies(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in struct {
+		Category string `json:"category"`
+		Status   string `json:"status"`
+		Search   string `json:"search"`
+		Limit    int    `json:"limit"`
+		Offset   int    `json:"offset"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.Limit <= 0 {
+		in.Limit = defaultListLimit
+	}
+	in.Limit = min(in.Limit, maxListLimit)
+
+	filters := agency.AgencyFilters{
+		Category: in.Category,
+		Status:   agency.AgencyStatus(in.Status),
+		Search:   in.Search,
+		Limit:    in.Limit,
+		Offset:   max(in.Offset, 0),
+	}
+	if !token.unrestricted() {
+		// Restricted tokens see few agencies; filter and page here
+		filters.Limit, filters.Offset = 0, 0
+	}
+	agencies, err := s.agencies.ListAgencies(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	if !token.unrestricted() {
+		visible := make([]*agency.Agency, 0, len(agencies))
+		for _, ag := range agencies {
+			if token.allowsAgency(ag.ID) {
+				visible = append(visible, ag)
+			}
+		}
+		start := min(max(in.Offset, 0), len(visible))
+		agencies = visible[start:min(start+in.Limit, len(visible))]
+	}
+	return map[string]interface{}{"agencies": agencies}, nil
+}
+
+func (s *Server) getAgency(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in agencyArgs
+	id, err := agencyID(token, args, &in)
+	if err != nil {
+		return nil, err
+	}
+	ag, err := s.agencies.GetAgency(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{"agency": ag}
+	if overview, err := s.agencies.GetAgencyOverview(ctx, id); err == nil && overview != nil {
+		result["overview"] = overview
+	}
+	return result, nil
+}
+
+func (s *Server) listGoals(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in agencyArgs
+	id, err := agencyID(token, args, &in)
+	if err != nil {
+		return nil, err
+	}
+	goals, err := s.agencies.GetGoals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"goals": goals}, nil
+}
+
+func (s *Server) listWorkItems(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in agencyArgs
+	id, err := agencyID(token, args, &in)
+	if err != nil {
+		return nil, err
+	}
+	workItems, err := s.agencies.GetWorkItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"work_items": workItems}, nil
+}
+
+type createGoalArgs struct {
+	agencyArgs
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+func (s *Server) createGoal(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in createGoalArgs
+	id, err := agencyID(token, args, &in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Code == "" || in.Description == "" {
+		return nil, errors.New("code and description are required")
+	}
+	if _, err := s.agencies.GetAgency(ctx, id); err != nil {
+		return nil, err
+	}
+
+	notify := func() {}
+	if s.outbox != nil {
+		entries := s.outbox.ChangeNotifications(id, "agency.goal.created", map[string]interface{}{"code": in.Code, "description": in.Description})
+		ctx = outbox.WithEntries(ctx, entries...)
+		notify = func() { go s.outbox.Deliver(context.WithoutCancel(ctx), entries...) }
+	}
+	goal, err := s.agencies.CreateGoal(ctx, id, in.Code, in.Description)
+	if err != nil {
+		return nil, err
+	}
+	notify()
+	return map[string]interface{}{"goal": goal}, nil
+}
+
+type createWorkItemArgs struct {
+	agencyArgs
+	agency.CreateWorkItemRequest
+}
+
+func (s *Server) createWorkItem(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in createWorkItemArgs
+	id, err := agencyID(token, args, &in)
+	if err != nil {
+		return nil, err
+	}
+	if in.Title == "" || in.Description == "" {
+		return nil, errors.New("title and description are required")
+	}
+	if _, err := s.agencies.GetAgency(ctx, id); err != nil {
+		return nil, err
+	}
+	workItem, err := s.agencies.CreateWorkItem(ctx, id, in.CreateWorkItemRequest)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"work_item": workItem}, nil
+}
+
+// errMemoryForbidden is returned when a token limited to some agencies asks
+// for agent memory, which is not tied to an agency
+var errMemoryForbidden = errors.New("agent memory requires a token that is not limited to specific agencies")
+
+func (s *Server) searchMemory(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in struct {
+		AgentID  string   `json:"agent_id"`
+		Query    string   `json:"query"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+		TopK     int      `json:"top_k"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.AgentID == "" {
+		return nil, errors.New("agent_id is required")
+	}
+	if !token.unrestricted() {
+		return nil, errMemoryForbidden
+	}
+	memories, err := s.memory.Search(ctx, in.AgentID, memory.MemoryQuery{
+		Query:   in.Query,
+		TopK:    min(max(in.TopK, 0), maxListLimit),
+		Filters: memory.MemoryFilters{Category: in.Category, Tags: in.Tags},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"memories": memories}, nil
+}
+
+func (s *Server) listWorkingMemory(ctx context.Context, token *Token, args json.RawMessage) (interface{}, error) {
+	var in struct {
+		AgentID string   `json:"agent_id"`
+		Text    string   `json:"text"`
+		Tags    []string `json:"tags"`
+	}
+	if err := decodeArgs(args, &in); err != nil {
+		return nil, err
+	}
+	if in.AgentID == "" {
+		return nil, errors.New("agent_id is required")
+	}
+	if !token.unrestricted() {
+		return nil, errMemoryForbidden
+	}
+	memories, err := s.memory.ListWorking(ctx, in.AgentID, memory.MemoryFilters{Text: in.Text, Tags: in.Tags, Limit: maxListLimit})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"memories": memories}, nil
+}
+
+func readTool(name, description string, schema map[string]interface{}) Tool {
+	return Tool{Name: name, Description: description, InputSchema: schema, Annotations: ToolAnnotations{ReadOnlyHint: true}}
+}
+
+func writeTool(name, description string, schema map[string]interface{}) Tool {
+	return Tool{Name: name, Description: description, InputSchema: schema}
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func integerProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description}
+}
+
+func stringArrayProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": description}
+}
@@ -0,0 +1,351 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Memory audit query limits
+const (
+	DefaultAuditQueryLimit = 100
+	MaxAuditQueryLimit     = 1000
+)
+
+// ErrInvalidAuditQuery is returned for audit queries with invalid parameters
+var ErrInvalidAuditQuery = errors.New("invalid audit query")
+
+// AuditOperation is the kind of memory access recorded in the audit log
+type AuditOperation string
+
+const (
+	AuditOpRead   AuditOperation = "read"
+	AuditOpWrite  AuditOperation = "write"
+	AuditOpUpdate AuditOperation = "update"
+	AuditOpDelete AuditOperation = "delete"
+	AuditOpClear  AuditOperation = "clear"
+	AuditOpSearch AuditOperation = "search"
+)
+
+// MemoryAccessor identifies who or what accesses memory
+type MemoryAccessor struct {
+	// AgentInstance is the agent instance acting on its memory
+	AgentInstance string `json:"agent_instance,omitempty"`
+
+	// Caller is the API caller acting on the memory
+	Caller string `json:"caller,omitempty"`
+}
+
+type accessorKey struct{}
+
+// WithAccessor returns a context whose memory accesses are attributed to the accessor
+func WithAccessor(ctx context.Context, accessor MemoryAccessor) context.Context {
+	return context.WithValue(ctx, accessorKey{}, accessor)
+}
+
+// AccessorFromContext returns the accessor memory accesses are attributed to, if any
+func AccessorFromContext(ctx context.Context) MemoryAccessor {
+	if ctx == nil {
+		return MemoryAccessor{}
+	}
+	accessor, _ := ctx.Value(accessorKey{}).(MemoryAccessor)
+	return accessor
+}
+
+// AuditRecord records one access to a memory key
+type AuditRecord struct {
+	ID            string         `json:"id"`
+	AgentID       string         `json:"agent_id"`
+	MemoryType    MemoryType     `json:"memory_type"`
+	Key           string         `json:"key,omitempty"` // Empty for operations on all of an agent's memory
+	Operation     AuditOperation `json:"operation"`
+	AgentInstance string         `json:"agent_instance,omitempty"`
+	Caller        string         `json:"caller,omitempty"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Success       bool           `json:"success"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// AuditQuery selects audit records. Zero fields do not filter.
+type AuditQuery struct {
+	AgentID       string
+	Key           string
+	MemoryType    MemoryType
+	Operation     AuditOperation
+	AgentInstance string
+	Caller        string
+	Since         time.Time
+	Until         time.Time
+	Limit         int // Default 100, at most 1000
+}
+
+// validate applies defaults and rejects invalid parameters
+func (q *AuditQuery) validate() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", ErrInvalidAuditQuery)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultAuditQueryLimit
+	}
+	if q.Limit > MaxAuditQueryLimit {
+		return fmt.Errorf("%w: limit must be at most %d", ErrInvalidAuditQuery, MaxAuditQueryLimit)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && q.Until.Before(q.Since) {
+		return fmt.Errorf("%w: until must not be before since", ErrInvalidAuditQuery)
+	}
+	return nil
+}
+
+// matches reports whether a record is selected by the query
+func (q *AuditQuery) matches(record *AuditRecord) bool {
+	switch {
+	case q.AgentID != "" && record.AgentID != q.AgentID,
+		q.Key != "" && record.Key != q.Key,
+		q.MemoryType != "" && record.MemoryType != q.MemoryType,
+		q.Operation != "" && record.Operation != q.Operation,
+		q.AgentInstance != "" && record.AgentInstance != q.AgentInstance,
+		q.Caller != "" && record.Caller != q.Caller,
+		!q.Since.IsZero() && record.Timestamp.Before(q.Since),
+		!q.Until.IsZero() && record.Timestamp.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// AuditLog stores memory audit records
+type AuditLog interface {
+	// Record stores an audit record
+	Record(ctx context.Context, record *AuditRecord) error
+
+	// Query returns matching audit records, newest first
+	Query(ctx context.Context, query AuditQuery) ([]*AuditRecord, error)
+}
+
+// SetAuditLog makes the service record every read and change of a memory key
+// in the audit log. Failing to record an access is logged and does not fail
+// the access.
+func (s *Service) SetAuditLog(audit AuditLog) {
+	s.audit = audit
+}
+
+// QueryAudit returns the audit records matching the query, newest first
+func (s *Service) QueryAudit(ctx context.Context, query AuditQuery) ([]*AuditRecord, error) {
+	if s.audit == nil {
+		return nil, fmt.Errorf("memory audit log is not configured")
+	}
+	if err := query.validate(); err != nil {
+		return nil, err
+	}
+	return s.audit.Query(ctx, query)
+}
+
+// recordAccess adds an access to the audit log, if one is configured
+func (s *Service) recordAccess(ctx context.Context, agentID string, memoryType MemoryType, key string, op AuditOperation, err error) {
+	if s.audit == nil {
+		return
+	}
+
+	accessor := AccessorFromContext(ctx)
+	record := &AuditRecord{
+		ID:            uuid.New().String(),
+		AgentID:       agentID,
+		MemoryType:    memoryType,
+		Key:           key,
+		Operation:     op,
+		AgentInstance: accessor.AgentInstance,
+		Caller:        accessor.Caller,
+		Timestamp:     s.clock.Now(),
+		Success:       err == nil,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if err := s.audit.Record(ctx, record); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"agent_id":  agentID,
+			"key":       key,
+			"operation": op,
+		}).Warn("Failed to record memory access")
+	}
+}
+
+// InMemoryAuditLog keeps the most recent audit records in memory
+type InMemoryAuditLog struct {
+	mu         sync.RWMutex
+	records    []*AuditRecord
+	maxRecords int
+}
+
+// NewInMemoryAuditLog creates an in-memory audit log keeping at most
+// maxRecords records (default 10000)
+func NewInMemoryAuditLog(maxRecords int) *InMemoryAuditLog {
+	if maxRecords <= 0 {
+		maxRecords = 10000
+	}
+	return &InMemoryAuditLog{maxRecords: maxRecords}
+}
+
+// Record stores an audit record, dropping the oldest beyond the limit
+func (l *InMemoryAuditLog) Record(ctx context.Context, record *AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = append(l.records, record)
+	if drop := len(l.records) - l.maxRecords; drop > 0 {
+		l.records = append([]*AuditRecord(nil), l.records[drop:]...)
+	}
+	return nil
+}
+
+// Query returns matching audit records, newest first
+func (l *InMemoryAuditLog) Query(ctx context.Context, query AuditQuery) ([]*AuditRecord, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	records := make([]*AuditRecord, 0)
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(records) >= query.Limit {
+			break
+		}
+		if query.matches(l.records[i]) {
+			records = append(records, l.records[i])
+		}
+	}
+	return records, nil
+}
+
+// ArangoAuditLog stores audit records in ArangoDB
+type ArangoAuditLog struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoAuditLog creates an ArangoDB-backed audit log
+func NewArangoAuditLog(dbClient *database.ArangoClient) (*ArangoAuditLog, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionMemoryAudit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionMemoryAudit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionMemoryAudit, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionMemoryAudit).Info("Created new collection")
+	}
+
+	indexes := map[string][]string{
+		"idx_memory_audit_key":    {"agent_id", "key", "timestamp"},
+		"idx_memory_audit_agent":  {"agent_id", "timestamp"},
+		"idx_memory_audit_caller": {"caller", "timestamp"},
+	}
+	for name, fields := range indexes {
+		if _, _, err := col.EnsurePersistentIndex(ctx, fields, &driver.EnsurePersistentIndexOptions{Name: name}); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+	}
+
+	return &ArangoAuditLog{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Record stores an audit record
+func (l *ArangoAuditLog) Record(ctx context.Context, record *AuditRecord) error {
+	doc := map[string]interface{}{
+		"_key":           record.ID,
+		"id":             record.ID,
+		"agent_id":       record.AgentID,
+		"memory_type":    record.MemoryType,
+		"key":            record.Key,
+		"operation":      record.Operation,
+		"agent_instance": record.AgentInstance,
+		"caller":         record.Caller,
+		"timestamp":      record.Timestamp,
+		"success":        record.Success,
+		"error":          record.Error,
+	}
+	if _, err := l.collection.CreateDocument(ctx, doc); err != nil {
+		return fmt.Errorf("failed to create audit record: %w", err)
+	}
+	return nil
+}
+
+// Query returns matching audit records, newest first
+func (l *ArangoAuditLog) Query(ctx context.Context, query AuditQuery) ([]*AuditRecord, error) {
+	bindVars := map[string]interface{}{
+		"@collection": CollectionMemoryAudit,
+		"limit":       query.Limit,
+	}
+	filters := ""
+	filter := func(clause, name string, value interface{}) {
+		filters += "\n\t\tFILTER " + clause
+		bindVars[name] = value
+	}
+	if query.AgentID != "" {
+		filter("a.agent_id == @agentID", "agentID", query.AgentID)
+	}
+	if query.Key != "" {
+		filter("a.key == @key", "key", query.Key)
+	}
+	if query.MemoryType != "" {
+		filter("a.memory_type == @memoryType", "memoryType", query.MemoryType)
+	}
+	if query.Operation != "" {
+		filter("a.operation == @operation", "operation", query.Operation)
+	}
+	if query.AgentInstance != "" {
+		filter("a.agent_instance == @agentInstance", "agentInstance", query.AgentInstance)
+	}
+	if query.Caller != "" {
+		filter("a.caller == @caller", "caller", query.Caller)
+	}
+	if !query.Since.IsZero() {
+		filter("a.timestamp >= @since", "since", query.Since)
+	}
+	if !query.Until.IsZero() {
+		filter("a.timestamp <= @until", "until", query.Until)
+	}
+
+	aql := fmt.Sprintf(`
+		FOR a IN @@collection%s
+		SORT a.timestamp DESC
+		LIMIT @limit
+		RETURN a
+	`, filters)
+
+	cursor, err := l.db.Query(ctx, aql, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer cursor.Close()
+
+	records := make([]*AuditRecord, 0)
+	for cursor.HasMore() {
+		var record AuditRecord
+		if _, err := cursor.ReadDocument(ctx, &record); err != nil {
+			return nil, fmt.Errorf("failed to read audit record: %w", err)
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
@@ -0,0 +1,170 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestService_AuditLog(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	service := NewService(repo)
+	service.SetClock(clk)
+
+	if _, err := service.QueryAudit(context.Background(), AuditQuery{}); err == nil {
+		t.Error("Expected an error without an audit log")
+	}
+	service.SetAuditLog(NewInMemoryAuditLog(0))
+
+	agentCtx := WithAccessor(context.Background(), MemoryAccessor{AgentInstance: "PUMP-002"})
+	apiCtx := WithAccessor(context.Background(), MemoryAccessor{Caller: "operator-7"})
+
+	step := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Memory operation failed: %v", err)
+		}
+		clk.Advance(time.Second)
+	}
+	step(service.StoreWorking(agentCtx, "PUMP-002", "setpoint", 4.2, time.Hour))
+	_, err := service.RetrieveWorking(agentCtx, "PUMP-002", "setpoint")
+	step(err)
+	step(service.UpdateWorking(apiCtx, "PUMP-002", "setpoint", 3.9))
+	step(service.Remember(agentCtx, "PUMP-002", "seal.replaced", "2025-02-01", "maintenance", nil))
+	_, err = service.Search(agentCtx, "PUMP-002", MemoryQuery{Filters: MemoryFilters{Category: "maintenance"}})
+	step(err)
+	_, err = service.Recall(apiCtx, "PUMP-002", "missing")
+	if err == nil {
+		t.Fatal("Expected recalling a missing key to fail")
+	}
+	clk.Advance(time.Second)
+	step(service.Forget(apiCtx, "PUMP-002", "seal.replaced"))
+
+	ctx := context.Background()
+	setpoint, err := service.QueryAudit(ctx, AuditQuery{AgentID: "PUMP-002", Key: "setpoint"})
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	if len(setpoint) != 3 {
+		t.Fatalf("Expected 3 records for setpoint, got %d", len(setpoint))
+	}
+	update := setpoint[0]
+	if update.Operation != AuditOpUpdate || update.Caller != "operator-7" || update.MemoryType != MemoryTypeWorking || !update.Success {
+		t.Errorf("Unexpected newest record: %+v", update)
+	}
+	if read := setpoint[1]; read.Operation != AuditOpRead || read.AgentInstance != "PUMP-002" {
+		t.Errorf("Unexpected read record: %+v", read)
+	}
+
+	consulted, _ := service.QueryAudit(ctx, AuditQuery{Key: "seal.replaced", Operation: AuditOpSearch})
+	if len(consulted) != 1 || consulted[0].MemoryType != MemoryTypeLongterm {
+		t.Errorf("Expected the searched memory to be recorded as consulted, got %+v", consulted)
+	}
+
+	byCaller, _ := service.QueryAudit(ctx, AuditQuery{Caller: "operator-7"})
+	if len(byCaller) != 3 {
+		t.Errorf("Expected 3 records by operator-7, got %d", len(byCaller))
+	}
+	for _, record := range byCaller {
+		if record.Key == "missing" && (record.Success || record.Error == "") {
+			t.Errorf("Expected the failed recall to be recorded as failed: %+v", record)
+		}
+	}
+
+	recent, _ := service.QueryAudit(ctx, AuditQuery{Since: clk.Now().Add(-2 * time.Second), Limit: 1})
+	if len(recent) != 1 || recent[0].Operation != AuditOpDelete {
+		t.Errorf("Expected only the forget, got %+v", recent)
+	}
+
+	if _, err := service.QueryAudit(ctx, AuditQuery{Limit: MaxAuditQueryLimit + 1}); !errors.Is(err, ErrInvalidAuditQuery) {
+		t.Errorf("Expected ErrInvalidAuditQuery, got %v", err)
+	}
+}
+
+func TestService_AuditLog_ListRestoreAndCheckpoints(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	service := NewService(repo)
+	service.SetClock(clk)
+	ctx := context.Background()
+
+	for _, key := range []string{"setpoint", "mode"} {
+		if err := service.StoreWorking(ctx, "PUMP-002", key, "auto", time.Hour); err != nil {
+			t.Fatalf("Failed to store working memory: %v", err)
+		}
+	}
+	snapshot, err := service.CreateSnapshot(ctx, "PUMP-002", "manual", "before audit")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	service.SetAuditLog(NewInMemoryAuditLog(0))
+
+	records := func(query AuditQuery) []*AuditRecord {
+		t.Helper()
+		query.AgentID = "PUMP-002"
+		found, err := service.QueryAudit(ctx, query)
+		if err != nil {
+			t.Fatalf("Failed to query audit log: %v", err)
+		}
+		return found
+	}
+
+	if _, err := service.ListWorking(ctx, "PUMP-002", MemoryFilters{}); err != nil {
+		t.Fatalf("Failed to list working memory: %v", err)
+	}
+	if listed := records(AuditQuery{Operation: AuditOpRead}); len(listed) != 1 || listed[0].Key != "" || listed[0].MemoryType != MemoryTypeWorking {
+		t.Errorf("Expected one list read of working memory, got %+v", listed)
+	}
+
+	if _, err := service.RestoreSnapshot(ctx, "PUMP-002", snapshot.ID); err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	if cleared := records(AuditQuery{Operation: AuditOpClear}); len(cleared) != 1 || cleared[0].MemoryType != MemoryTypeWorking {
+		t.Errorf("Expected the restore to record one clear, got %+v", cleared)
+	}
+	restored := map[string]bool{}
+	for _, record := range records(AuditQuery{Operation: AuditOpWrite}) {
+		restored[record.Key] = true
+	}
+	if len(restored) != 2 || !restored["setpoint"] || !restored["mode"] {
+		t.Errorf("Expected the restore to record a write per key, got %v", restored)
+	}
+
+	checkpoint := &TaskCheckpoint{AgentID: "PUMP-002", ExecutionID: "exec-1", TaskID: "flush", Percent: 40}
+	if err := service.SaveCheckpoint(ctx, checkpoint); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	checkpoint.Percent = 80
+	if err := service.SaveCheckpoint(ctx, checkpoint); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	if _, err := service.LoadCheckpoint(ctx, "PUMP-002", "exec-1", "flush"); err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+	if err := service.ClearCheckpoint(ctx, "PUMP-002", "exec-1", "flush"); err != nil {
+		t.Fatalf("Failed to clear checkpoint: %v", err)
+	}
+
+	var operations []AuditOperation
+	for _, record := range records(AuditQuery{Key: checkpointKey("exec-1", "flush")}) {
+		if record.MemoryType != MemoryTypeLongterm || !record.Success {
+			t.Errorf("Unexpected checkpoint record: %+v", record)
+		}
+		operations = append(operations, record.Operation)
+	}
+	want := []AuditOperation{AuditOpDelete, AuditOpRead, AuditOpUpdate, AuditOpWrite}
+	if len(operations) != len(want) {
+		t.Fatalf("Expected checkpoint operations %v, got %v", want, operations)
+	}
+	for i := range want {
+		if operations[i] != want[i] {
+			t.Fatalf("Expected checkpoint operations %v, got %v", want, operations)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+const (
+	// DefaultWorkingCacheEntries is the default number of cached working memory entries
+	DefaultWorkingCacheEntries = 10000
+
+	// DefaultWorkingCacheTTL is the default time an entry is served from the cache
+	DefaultWorkingCacheTTL = 5 * time.Minute
+)
+
+// WorkingCache caches working memory entries in front of a MemoryRepository.
+// LRUWorkingCache is the in-process implementation; a shared cache such as
+// Redis can be used by implementing this interface.
+type WorkingCache interface {
+	// Get returns a cached entry
+	Get(agentID, key string) (*WorkingMemory, bool)
+
+	// Set caches an entry, replacing any cached entry with the same key
+	Set(mem *WorkingMemory)
+
+	// Delete drops a cached entry
+	Delete(agentID, key string)
+
+	// DeleteAgent drops all cached entries of an agent
+	DeleteAgent(agentID string)
+}
+
+// WorkingCacheConfig configures the in-process working memory cache
+type WorkingCacheConfig struct {
+	// MaxEntries is the number of entries kept before the least recently used
+	// is dropped (default 10000)
+	MaxEntries int
+
+	// TTL bounds how long an entry is served from the cache, and with it how
+	// stale a read can be when another process changes the entry (default 5m)
+	TTL time.Duration
+}
+
+// LRUWorkingCache is an in-process, size-bounded working memory cache
+type LRUWorkingCache struct {
+	mu      sync.Mutex
+	config  WorkingCacheConfig
+	clock   clock.Clock
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedWorking struct {
+	id       string
+	mem      *WorkingMemory
+	cachedAt time.Time
+}
+
+// NewLRUWorkingCache creates an in-process working memory cache
+func NewLRUWorkingCache(config WorkingCacheConfig) *LRUWorkingCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultWorkingCacheEntries
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultWorkingCacheTTL
+	}
+	return &LRUWorkingCache{
+		config:  config,
+		clock:   clock.Real(),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// SetClock sets the clock used for the cache TTL and entry expiry
+func (c *LRUWorkingCache) SetClock(clk clock.Clock) {
+	if clk != nil {
+		c.mu.Lock()
+		c.clock = clk
+		c.mu.Unlock()
+	}
+}
+
+func workingCacheID(agentID, key string) string {
+	return agentID + ":" + key
+}
+
+// Get returns a copy of a cached entry. Entries past the cache TTL or their
+// own expiry are dropped rather than returned.
+func (c *LRUWorkingCache) Get(agentID, key string) (*WorkingMemory, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[workingCacheID(agentID, key)]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cachedWorking)
+
+	now := c.clock.Now()
+	stale := now.Sub(entry.cachedAt) >= c.config.TTL
+	expired := !entry.mem.ExpiresAt.IsZero() && !entry.mem.ExpiresAt.After(now)
+	if stale || expired {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return copyWorkingMemory(entry.mem), true
+}
+
+// Set caches a copy of an entry, dropping the least recently used entry when
+// the cache is full
+func (c *LRUWorkingCache) Set(mem *WorkingMemory) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := workingCacheID(mem.AgentID, mem.Key)
+	entry := &cachedWorking{id: id, mem: copyWorkingMemory(mem), cachedAt: c.clock.Now()}
+	if elem, ok := c.entries[id]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(entry)
+	for c.order.Len() > c.config.MaxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete drops a cached entry
+func (c *LRUWorkingCache) Delete(agentID, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[workingCacheID(agentID, key)]; ok {
+		c.remove(elem)
+	}
+}
+
+// DeleteAgent drops all cached entries of an agent
+func (c *LRUWorkingCache) DeleteAgent(agentID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cachedWorking).mem.AgentID == agentID {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// Len returns the number of cached entries
+func (c *LRUWorkingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRUWorkingCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cachedWorking).id)
+}
+
+// copyWorkingMemory copies an entry so callers cannot change a cached one
+func copyWorkingMemory(mem *WorkingMemory) *WorkingMemory {
+	copied := *mem
+	if mem.Metadata != nil {
+		copied.Metadata = make(map[string]interface{}, len(mem.Metadata))
+		for k, v := range mem.Metadata {
+			copied.Metadata[k] = v
+		}
+	}
+	return &copied
+}
+
+// WorkingCacheStats reports the working memory cache hit rate
+type WorkingCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// CachedRepository serves working memory reads from a WorkingCache. Stores
+// write through to the cache; updates and deletes invalidate the cached
+// entry. All other operations go straight to the wrapped repository.
+//
+// Cache hits are not recorded in the repository's access tracking, and
+// changes made through another repository or process are only seen once the
+// cached entry is dropped.
+type CachedRepository struct {
+	MemoryRepository
+	cache WorkingCache
+
+	hits   int64
+	misses int64
+}
+
+// NewCachedRepository wraps a repository with a working memory cache
+func NewCachedRepository(repo MemoryRepository, cache WorkingCache) *CachedRepository {
+	return &CachedRepository{
+		MemoryRepository: repo,
+		cache:            cache,
+	}
+}
+
+// WithWorkingCache wraps a repository with an in-process working memory cache
+// when the cache is enabled in the application config
+func WithWorkingCache(repo MemoryRepository, cfg config.MemoryCacheConfig) MemoryRepository {
+	if !cfg.Enabled {
+		return repo
+	}
+	return NewCachedRepository(repo, NewLRUWorkingCache(WorkingCacheConfig{
+		MaxEntries: cfg.MaxEntries,
+		TTL:        time.Duration(cfg.TTLSeconds) * time.Second,
+	}))
+}
+
+// CacheStats returns the number of cache hits and misses
+func (r *CachedRepository) CacheStats() WorkingCacheStats {
+	return WorkingCacheStats{
+		Hits:   atomic.LoadInt64(&r.hits),
+		Misses: atomic.LoadInt64(&r.misses),
+	}
+}
+
+// StoreWorking stores an entry and caches it
+func (r *CachedRepository) StoreWorking(ctx context.Context, memory *WorkingMemory) error {
+	if err := r.MemoryRepository.StoreWorking(ctx, memory); err != nil {
+		r.cache.Delete(memory.AgentID, memory.Key)
+		return err
+	}
+	r.cache.Set(memory)
+	return nil
+}
+
+// GetWorking returns a cached entry, loading and caching it on a miss
+func (r *CachedRepository) GetWorking(ctx context.Context, agentID, key string) (*WorkingMemory, error) {
+	if mem, ok := r.cache.Get(agentID, key); ok {
+		atomic.AddInt64(&r.hits, 1)
+		return mem, nil
+	}
+	atomic.AddInt64(&r.misses, 1)
+
+	mem, err := r.MemoryRepository.GetWorking(ctx, agentID, key)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.Set(mem)
+	return mem, nil
+}
+
+// UpdateWorking updates an entry and invalidates its cached copy
+func (r *CachedRepository) UpdateWorking(ctx context.Context, memory *WorkingMemory) error {
+	defer r.cache.Delete(memory.AgentID, memory.Key)
+	return r.MemoryRepository.UpdateWorking(ctx, memory)
+}
+
+// DeleteWorking deletes an entry and its cached copy
+func (r *CachedRepository) DeleteWorking(ctx context.Context, agentID, key string) error {
+	defer r.cache.Delete(agentID, key)
+	return r.MemoryRepository.DeleteWorking(ctx, agentID, key)
+}
+
+// ClearWorking clears an agent's working memory and its cached entries
+func (r *CachedRepository) ClearWorking(ctx context.Context, agentID string) error {
+	defer r.cache.DeleteAgent(agentID)
+	return r.MemoryRepository.ClearWorking(ctx, agentID)
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestCachedRepository_WorkingMemory(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	cache := NewLRUWorkingCache(WorkingCacheConfig{MaxEntries: 2, TTL: time.Minute})
+	cache.SetClock(clk)
+	cached := NewCachedRepository(repo, cache)
+	service := NewService(cached)
+	service.SetClock(clk)
+
+	retrieve := func(key string) interface{} {
+		value, err := service.RetrieveWorking(ctx, "PUMP-002", key)
+		if err != nil {
+			t.Fatalf("Failed to retrieve %s: %v", key, err)
+		}
+		return value
+	}
+
+	// Stores write through, so reads never reach the repository
+	if err := service.StoreWorking(ctx, "PUMP-002", "pressure", 4.2, time.Hour); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if value := retrieve("pressure"); value != 4.2 {
+			t.Errorf("Expected 4.2, got %v", value)
+		}
+	}
+	if calls := repo.GetCallCount("GetWorking"); calls != 0 {
+		t.Errorf("Expected no repository reads, got %d", calls)
+	}
+
+	// Updates invalidate the cached entry
+	if err := service.UpdateWorking(ctx, "PUMP-002", "pressure", 4.5); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	if value := retrieve("pressure"); value != 4.5 {
+		t.Errorf("Expected the updated value, got %v", value)
+	}
+	if value := retrieve("pressure"); value != 4.5 {
+		t.Errorf("Expected the updated value, got %v", value)
+	}
+	if calls := repo.GetCallCount("GetWorking"); calls != 1 {
+		t.Errorf("Expected one repository read after the update, got %d", calls)
+	}
+
+	// Deletes invalidate the cached entry
+	if err := service.DeleteWorking(ctx, "PUMP-002", "pressure"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := service.RetrieveWorking(ctx, "PUMP-002", "pressure"); err == nil {
+		t.Error("Expected the deleted entry to be gone")
+	}
+
+	// Entries past the cache TTL are reloaded
+	if err := service.StoreWorking(ctx, "PUMP-002", "flow", 12, time.Hour); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+	before := repo.GetCallCount("GetWorking")
+	clk.Advance(2 * time.Minute)
+	retrieve("flow")
+	if calls := repo.GetCallCount("GetWorking") - before; calls != 1 {
+		t.Errorf("Expected a stale entry to be reloaded, got %d reads", calls)
+	}
+
+	// The least recently used entry is dropped when the cache is full
+	for _, key := range []string{"a", "b", "c"} {
+		if err := service.StoreWorking(ctx, "PUMP-002", key, key, time.Hour); err != nil {
+			t.Fatalf("Failed to store: %v", err)
+		}
+	}
+	if _, ok := cache.Get("PUMP-002", "a"); ok || cache.Len() != 2 {
+		t.Errorf("Expected a to be dropped, %d entries cached", cache.Len())
+	}
+
+	if err := service.ClearWorking(ctx, "PUMP-002"); err != nil {
+		t.Fatalf("Failed to clear: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected clearing to drop cached entries, %d left", cache.Len())
+	}
+
+	stats := cached.CacheStats()
+	if stats.Hits != 5 || stats.Misses != 3 {
+		t.Errorf("Unexpected cache stats: %+v", stats)
+	}
+}
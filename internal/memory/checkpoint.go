@@ -0,0 +1,191 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	log "github.com/sirupsen/logrus"
+)
+
+// CategoryTaskCheckpoint is the long-term memory category task checkpoints are stored under
+const CategoryTaskCheckpoint = "task_checkpoint"
+
+// ErrNoCheckpoint is returned when a task has not recorded a checkpoint
+var ErrNoCheckpoint = errors.New("no checkpoint recorded for task")
+
+// TaskCheckpoint is a progress marker persisted by an agent during a
+// long-running task so the work can be resumed after a restart
+type TaskCheckpoint struct {
+	// AgentID is the agent executing the task
+	AgentID string `json:"agent_id"`
+
+	// ExecutionID and TaskID identify the task execution
+	ExecutionID string `json:"execution_id"`
+	TaskID      string `json:"task_id"`
+
+	// Percent is how much of the task is complete (0-100)
+	Percent float64 `json:"percent"`
+
+	// Cursor is agent-defined state needed to resume, e.g. the last processed offset
+	Cursor map[string]interface{} `json:"cursor,omitempty"`
+
+	// Message describes the current stage of the work
+	Message string `json:"message,omitempty"`
+
+	// Sequence counts the checkpoints recorded for the task execution
+	Sequence int `json:"sequence"`
+
+	// RecordedAt is when the checkpoint was saved
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// checkpointKey returns the long-term memory key of a task execution's checkpoint
+func checkpointKey(executionID, taskID string) string {
+	return fmt.Sprintf("checkpoint:%s:%s", executionID, taskID)
+}
+
+// SaveCheckpoint records the latest progress of a task execution, replacing
+// any earlier checkpoint. Sequence and RecordedAt are set by the service.
+func (s *Service) SaveCheckpoint(ctx context.Context, checkpoint *TaskCheckpoint) error {
+	if checkpoint == nil {
+		return fmt.Errorf("checkpoint is required")
+	}
+	if checkpoint.AgentID == "" {
+		return fmt.Errorf("agent ID is required")
+	}
+	if err := tenant.CheckIDs(ctx, checkpoint.AgentID); err != nil {
+		return err
+	}
+	if checkpoint.ExecutionID == "" || checkpoint.TaskID == "" {
+		return fmt.Errorf("execution ID and task ID are required")
+	}
+	if checkpoint.Percent < 0 || checkpoint.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100, got %v", checkpoint.Percent)
+	}
+
+	key := checkpointKey(checkpoint.ExecutionID, checkpoint.TaskID)
+	existing, err := s.repo.GetLongterm(ctx, checkpoint.AgentID, key)
+	if err != nil {
+		existing = nil
+	}
+
+	checkpoint.Sequence = 1
+	if existing != nil {
+		if previous, err := decodeCheckpoint(existing.Value); err == nil {
+			checkpoint.Sequence = previous.Sequence + 1
+		}
+	}
+	checkpoint.RecordedAt = s.clock.Now()
+
+	value, err := encodeCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		existing.Value = value
+		err := s.repo.UpdateLongterm(ctx, existing)
+		s.recordAccess(ctx, checkpoint.AgentID, MemoryTypeLongterm, key, AuditOpUpdate, err)
+		if err != nil {
+			return fmt.Errorf("failed to update checkpoint: %w", err)
+		}
+	} else {
+		mem := &LongtermMemory{
+			AgentID:  checkpoint.AgentID,
+			Category: CategoryTaskCheckpoint,
+			Key:      key,
+			Value:    value,
+			Metadata: MemoryMetadata{
+				Source:     "task",
+				Importance: 5,
+				Confidence: 1.0,
+				Tags:       []string{checkpoint.ExecutionID, checkpoint.TaskID},
+			},
+			SchemaVersion: s.schemas.CurrentVersion(checkpoint.AgentID, key),
+		}
+		err := s.repo.StoreLongterm(ctx, mem)
+		s.recordAccess(ctx, checkpoint.AgentID, MemoryTypeLongterm, key, AuditOpWrite, err)
+		if err != nil {
+			return fmt.Errorf("failed to store checkpoint: %w", err)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"agent_id":     checkpoint.AgentID,
+		"execution_id": checkpoint.ExecutionID,
+		"task_id":      checkpoint.TaskID,
+		"percent":      checkpoint.Percent,
+		"sequence":     checkpoint.Sequence,
+	}).Debug("Saved task checkpoint")
+
+	return nil
+}
+
+// LoadCheckpoint returns the latest checkpoint of a task execution, or
+// ErrNoCheckpoint if the agent has not recorded one
+func (s *Service) LoadCheckpoint(ctx context.Context, agentID, executionID, taskID string) (*TaskCheckpoint, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
+	if agentID == "" {
+		return nil, fmt.Errorf("agent ID is required")
+	}
+
+	key := checkpointKey(executionID, taskID)
+	mem, err := s.repo.GetLongterm(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, key, AuditOpRead, err)
+	if err != nil {
+		return nil, ErrNoCheckpoint
+	}
+
+	checkpoint, err := decodeCheckpoint(mem.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// ClearCheckpoint removes the checkpoint of a task execution once it is no longer needed
+func (s *Service) ClearCheckpoint(ctx context.Context, agentID, executionID, taskID string) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
+	key := checkpointKey(executionID, taskID)
+	err := s.repo.DeleteLongterm(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, key, AuditOpDelete, err)
+	if err != nil {
+		return fmt.Errorf("failed to clear checkpoint: %w", err)
+	}
+	return nil
+}
+
+// encodeCheckpoint converts a checkpoint to the generic form memory values are stored in
+func encodeCheckpoint(checkpoint *TaskCheckpoint) (map[string]interface{}, error) {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	return value, nil
+}
+
+func decodeCheckpoint(value interface{}) (*TaskCheckpoint, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint TaskCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
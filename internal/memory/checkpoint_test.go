@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestService_TaskCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(NewMockRepository())
+	clk := clock.NewFake(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	service.SetClock(clk)
+
+	if _, err := service.LoadCheckpoint(ctx, "analyst-1", "exec-1", "scan"); !errors.Is(err, ErrNoCheckpoint) {
+		t.Fatalf("expected ErrNoCheckpoint, got %v", err)
+	}
+
+	err := service.SaveCheckpoint(ctx, &TaskCheckpoint{
+		AgentID:     "analyst-1",
+		ExecutionID: "exec-1",
+		TaskID:      "scan",
+		Percent:     25,
+		Cursor:      map[string]interface{}{"offset": 2500},
+		Message:     "scanning logs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(time.Hour)
+	err = service.SaveCheckpoint(ctx, &TaskCheckpoint{
+		AgentID:     "analyst-1",
+		ExecutionID: "exec-1",
+		TaskID:      "scan",
+		Percent:     60,
+		Cursor:      map[string]interface{}{"offset": 6000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := service.LoadCheckpoint(ctx, "analyst-1", "exec-1", "scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Percent != 60 || checkpoint.Sequence != 2 || checkpoint.Cursor["offset"] != float64(6000) {
+		t.Errorf("unexpected checkpoint %+v", checkpoint)
+	}
+	if !checkpoint.RecordedAt.Equal(clk.Now()) {
+		t.Errorf("expected checkpoint recorded at %v, got %v", clk.Now(), checkpoint.RecordedAt)
+	}
+
+	if err := service.SaveCheckpoint(ctx, &TaskCheckpoint{AgentID: "analyst-1", ExecutionID: "exec-1", TaskID: "scan", Percent: 120}); err == nil {
+		t.Error("expected percent above 100 to be rejected")
+	}
+
+	if err := service.ClearCheckpoint(ctx, "analyst-1", "exec-1", "scan"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.LoadCheckpoint(ctx, "analyst-1", "exec-1", "scan"); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("expected checkpoint to be cleared, got %v", err)
+	}
+}
@@ -0,0 +1,575 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VectorClock counts the working memory writes each instance has made to a key.
+// Comparing two clocks tells whether one change happened after the other or
+// whether they were made concurrently by different instances.
+type VectorClock map[string]uint64
+
+// ClockOrder is the causal relationship between two vector clocks
+type ClockOrder string
+
+const (
+	ClockEqual      ClockOrder = "equal"
+	ClockBefore     ClockOrder = "before"
+	ClockAfter      ClockOrder = "after"
+	ClockConcurrent ClockOrder = "concurrent"
+)
+
+// Copy returns an independent copy of the clock
+func (vc VectorClock) Copy() VectorClock {
+	out := make(VectorClock, len(vc))
+	for instance, counter := range vc {
+		out[instance] = counter
+	}
+	return out
+}
+
+// Tick returns a copy of the clock with the instance's counter incremented
+func (vc VectorClock) Tick(instanceID string) VectorClock {
+	out := vc.Copy()
+	out[instanceID]++
+	return out
+}
+
+// Merge returns the element-wise maximum of both clocks
+func (vc VectorClock) Merge(other VectorClock) VectorClock {
+	out := vc.Copy()
+	for instance, counter := range other {
+		if counter > out[instance] {
+			out[instance] = counter
+		}
+	}
+	return out
+}
+
+// Compare reports how vc is ordered relative to other
+func (vc VectorClock) Compare(other VectorClock) ClockOrder {
+	less, greater := false, false
+	for instance, counter := range vc {
+		if counter > other[instance] {
+			greater = true
+		} else if counter < other[instance] {
+			less = true
+		}
+	}
+	for instance, counter := range other {
+		if _, seen := vc[instance]; !seen && counter > 0 {
+			less = true
+		}
+	}
+
+	switch {
+	case less && greater:
+		return ClockConcurrent
+	case less:
+		return ClockBefore
+	case greater:
+		return ClockAfter
+	default:
+		return ClockEqual
+	}
+}
+
+// Sum is the total number of writes recorded in the clock
+func (vc VectorClock) Sum() int {
+	total := 0
+	for _, counter := range vc {
+		total += int(counter)
+	}
+	return total
+}
+
+// MemoryChange is a working memory write exchanged between instances of an agent
+type MemoryChange struct {
+	// Sequence is assigned by the exchange and orders changes for an agent
+	Sequence int64 `json:"sequence,omitempty"`
+
+	AgentID    string      `json:"agent_id"`
+	InstanceID string      `json:"instance_id"`
+	Key        string      `json:"key"`
+	Value      interface{} `json:"value,omitempty"`
+	Deleted    bool        `json:"deleted"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+
+	// Encrypted marks a Value stored encrypted by the Repository
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// Clock is the key's vector clock including this change
+	Clock VectorClock `json:"clock"`
+
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ChangeExchange carries working memory changes between the instances of an
+// agent. Repository implements it on top of ArangoDB; MemoryExchange serves
+// instances running in the same process.
+type ChangeExchange interface {
+	// PublishChanges appends changes to the agent's change log, assigning sequences
+	PublishChanges(ctx context.Context, changes []*MemoryChange) error
+
+	// ChangesSince returns the agent's changes with a sequence greater than
+	// after, in sequence order
+	ChangesSince(ctx context.Context, agentID string, after int64) ([]*MemoryChange, error)
+}
+
+// MemoryExchange is an in-process ChangeExchange
+type MemoryExchange struct {
+	mu      sync.RWMutex
+	changes map[string][]*MemoryChange // key: agentID
+	seq     int64
+}
+
+// NewMemoryExchange creates an empty in-process change exchange
+func NewMemoryExchange() *MemoryExchange {
+	return &MemoryExchange{
+		changes: make(map[string][]*MemoryChange),
+	}
+}
+
+// PublishChanges appends changes to the agents' change logs
+func (e *MemoryExchange) PublishChanges(ctx context.Context, changes []*MemoryChange) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, change := range changes {
+		e.seq++
+		stored := *change
+		stored.Sequence = e.seq
+		stored.Clock = change.Clock.Copy()
+		change.Sequence = e.seq
+		e.changes[change.AgentID] = append(e.changes[change.AgentID], &stored)
+	}
+	return nil
+}
+
+// ChangesSince returns the agent's changes after the given sequence
+func (e *MemoryExchange) ChangesSince(ctx context.Context, agentID string, after int64) ([]*MemoryChange, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	entries := e.changes[agentID]
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].Sequence > after })
+
+	result := make([]*MemoryChange, 0, len(entries)-start)
+	for _, change := range entries[start:] {
+		copied := *change
+		copied.Clock = change.Clock.Copy()
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+// keySyncState is what an instance last exchanged for a working memory key.
+// A local entry whose version or update time differs has changed since.
+type keySyncState struct {
+	Clock     VectorClock
+	Version   int
+	UpdatedAt time.Time
+	Deleted   bool
+
+	// republish forces the local value out on the next sync, e.g. after a
+	// conflict was resolved in its favour
+	republish bool
+}
+
+// heldConflict is a concurrent change waiting for a manual decision. The local
+// value is not published while the conflict is held.
+type heldConflict struct {
+	conflict MemoryConflict
+	remote   *MemoryChange
+}
+
+// agentSyncState is the delta sync state of one agent on this instance
+type agentSyncState struct {
+	cursor    int64
+	keys      map[string]*keySyncState
+	conflicts map[string]*heldConflict
+}
+
+// SetExchange enables delta sync of working memory with other instances of
+// the same agent. Sync state is kept in memory, so a restarted instance
+// replays the change log and reconciles its entries against it.
+func (s *Synchronizer) SetExchange(exchange ChangeExchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchange = exchange
+}
+
+// agentState returns the delta sync state of an agent. Callers hold s.mu.
+func (s *Synchronizer) agentState(agentID string) *agentSyncState {
+	state, exists := s.agents[agentID]
+	if !exists {
+		state = &agentSyncState{
+			keys:      make(map[string]*keySyncState),
+			conflicts: make(map[string]*heldConflict),
+		}
+		s.agents[agentID] = state
+	}
+	return state
+}
+
+// localWorking returns the agent's unexpired working memory by key
+func (s *Synchronizer) localWorking(ctx context.Context, agentID string) (map[string]*WorkingMemory, error) {
+	memories, err := s.repo.ListWorking(ctx, agentID, MemoryFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working memory: %w", err)
+	}
+
+	now := s.service.clock.Now()
+	local := make(map[string]*WorkingMemory, len(memories))
+	for _, mem := range memories {
+		if !mem.ExpiresAt.IsZero() && now.After(mem.ExpiresAt) {
+			continue
+		}
+		local[mem.Key] = mem
+	}
+	return local, nil
+}
+
+// localChanges lists the keys written or deleted on this instance since they
+// were last exchanged. Keys held in a conflict are left out.
+func (s *Synchronizer) localChanges(state *agentSyncState, local map[string]*WorkingMemory) []string {
+	var changed []string
+	for key, mem := range local {
+		if _, held := state.conflicts[key]; held {
+			continue
+		}
+		known, exists := state.keys[key]
+		if !exists || known.Deleted || known.republish || known.Version != mem.Version || !known.UpdatedAt.Equal(mem.UpdatedAt) {
+			changed = append(changed, key)
+		}
+	}
+	for key, known := range state.keys {
+		if _, held := state.conflicts[key]; held {
+			continue
+		}
+		if _, exists := local[key]; !exists && (!known.Deleted || known.republish) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// PendingChanges counts the working memory changes of an agent that have not
+// been exchanged with other instances yet, including those held in conflicts
+func (s *Synchronizer) PendingChanges(ctx context.Context, agentID string) (int, error) {
+	local, err := s.localWorking(ctx, agentID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.agentState(agentID)
+	return len(s.localChanges(state, local)) + len(state.conflicts), nil
+}
+
+// exchangeWorkingMemory runs one round of the delta sync protocol: pull the
+// changes other instances published since the last round, apply those that
+// causally follow the local state, detect concurrent writes as conflicts and
+// publish the local changes. It returns the number of changes applied and
+// published, and the conflicts awaiting a manual decision.
+func (s *Synchronizer) exchangeWorkingMemory(ctx context.Context, agentID string, exchange ChangeExchange) (int, []MemoryConflict, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.agentState(agentID)
+
+	local, err := s.localWorking(ctx, agentID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	remote, err := exchange.ChangesSince(ctx, agentID, state.cursor)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch remote changes: %w", err)
+	}
+
+	// Only the latest remote change to a key matters. The cursor moves on
+	// once the round succeeds so failed changes are fetched again.
+	cursor := state.cursor
+	latest := make(map[string]*MemoryChange)
+	for _, change := range remote {
+		if change.Sequence > cursor {
+			cursor = change.Sequence
+		}
+		if change.InstanceID == s.instanceID {
+			continue
+		}
+		if current, exists := latest[change.Key]; !exists || current.Clock.Compare(change.Clock) != ClockAfter {
+			latest[change.Key] = change
+		}
+	}
+
+	dirty := make(map[string]bool)
+	for _, key := range s.localChanges(state, local) {
+		dirty[key] = true
+	}
+
+	applied := 0
+	keys := make([]string, 0, len(latest))
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		change := latest[key]
+		known := state.keys[key]
+		localClock := VectorClock{}
+		if known != nil {
+			localClock = known.Clock
+		}
+
+		order := change.Clock.Compare(localClock)
+		if order == ClockBefore || order == ClockEqual {
+			// Already reflected locally
+			continue
+		}
+
+		if held, exists := state.conflicts[key]; exists {
+			// A newer remote write replaces the one the conflict is waiting on
+			held.remote = change
+			held.conflict = s.newConflict(key, local[key], localClock, change)
+			continue
+		}
+
+		if order == ClockAfter && !dirty[key] {
+			if err := s.applyRemote(ctx, state, local[key], change); err != nil {
+				return applied, nil, err
+			}
+			applied++
+			continue
+		}
+
+		// Both sides wrote the key without seeing the other's write
+		conflict := s.newConflict(key, local[key], localClock, change)
+		remoteWins, decided := resolveWinner(conflict, s.strategy)
+		if !decided {
+			state.conflicts[key] = &heldConflict{conflict: conflict, remote: change}
+			delete(dirty, key)
+			continue
+		}
+
+		if remoteWins {
+			if err := s.applyRemote(ctx, state, local[key], change); err != nil {
+				return applied, nil, err
+			}
+			delete(dirty, key)
+			applied++
+		} else {
+			s.keepLocal(state, key, change)
+			dirty[key] = true
+		}
+	}
+
+	published, err := s.publishLocal(ctx, agentID, exchange, state, local, dirty)
+	if err != nil {
+		return applied, nil, err
+	}
+
+	state.cursor = cursor
+	return applied + published, state.heldConflicts(), nil
+}
+
+// publishLocal sends the dirty keys to the exchange with ticked clocks
+func (s *Synchronizer) publishLocal(ctx context.Context, agentID string, exchange ChangeExchange, state *agentSyncState, local map[string]*WorkingMemory, dirty map[string]bool) (int, error) {
+	if len(dirty) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(dirty))
+	for key := range dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	now := s.service.clock.Now()
+	changes := make([]*MemoryChange, 0, len(keys))
+	next := make(map[string]*keySyncState, len(keys))
+	for _, key := range keys {
+		clock := VectorClock{}
+		if known, exists := state.keys[key]; exists {
+			clock = known.Clock
+		}
+		clock = clock.Tick(s.instanceID)
+
+		change := &MemoryChange{
+			AgentID:    agentID,
+			InstanceID: s.instanceID,
+			Key:        key,
+			Clock:      clock,
+			ChangedAt:  now,
+		}
+		synced := &keySyncState{Clock: clock}
+
+		if mem, exists := local[key]; exists {
+			change.Value = mem.Value
+			change.ExpiresAt = mem.ExpiresAt
+			synced.Version = mem.Version
+			synced.UpdatedAt = mem.UpdatedAt
+		} else {
+			change.Deleted = true
+			synced.Deleted = true
+		}
+
+		changes = append(changes, change)
+		next[key] = synced
+	}
+
+	if err := exchange.PublishChanges(ctx, changes); err != nil {
+		return 0, fmt.Errorf("failed to publish local changes: %w", err)
+	}
+
+	for key, synced := range next {
+		state.keys[key] = synced
+	}
+	return len(changes), nil
+}
+
+// applyRemote writes a remote change to local working memory and records the
+// result as exchanged so it is not published back
+func (s *Synchronizer) applyRemote(ctx context.Context, state *agentSyncState, mem *WorkingMemory, change *MemoryChange) error {
+	synced := &keySyncState{Clock: change.Clock.Copy()}
+
+	switch {
+	case change.Deleted:
+		if mem != nil {
+			if err := s.repo.DeleteWorking(ctx, change.AgentID, change.Key); err != nil {
+				return fmt.Errorf("failed to apply remote delete of %s: %w", change.Key, err)
+			}
+		}
+		synced.Deleted = true
+
+	case mem != nil:
+		mem.Value = change.Value
+		mem.ExpiresAt = change.ExpiresAt
+		if err := s.repo.UpdateWorking(ctx, mem); err != nil {
+			return fmt.Errorf("failed to apply remote change to %s: %w", change.Key, err)
+		}
+		synced.Version = mem.Version
+		synced.UpdatedAt = mem.UpdatedAt
+
+	default:
+		mem = &WorkingMemory{
+			AgentID:   change.AgentID,
+			Key:       change.Key,
+			Value:     change.Value,
+			Metadata:  map[string]interface{}{"synced_from": change.InstanceID},
+			ExpiresAt: change.ExpiresAt,
+
+			SchemaVersion: s.service.schemas.CurrentVersion(change.AgentID, change.Key),
+		}
+		if err := s.repo.StoreWorking(ctx, mem); err != nil {
+			return fmt.Errorf("failed to apply remote change to %s: %w", change.Key, err)
+		}
+		synced.Version = mem.Version
+		synced.UpdatedAt = mem.UpdatedAt
+	}
+
+	state.keys[change.Key] = synced
+	return nil
+}
+
+// keepLocal makes the local value of a conflicting key supersede the remote
+// change it was concurrent with
+func (s *Synchronizer) keepLocal(state *agentSyncState, key string, change *MemoryChange) {
+	known, exists := state.keys[key]
+	if !exists {
+		known = &keySyncState{Clock: VectorClock{}}
+		state.keys[key] = known
+	}
+	known.Clock = known.Clock.Merge(change.Clock)
+	known.republish = true
+}
+
+// newConflict describes a concurrent write of a key. Versions are the number
+// of writes in each side's vector clock.
+func (s *Synchronizer) newConflict(key string, mem *WorkingMemory, localClock VectorClock, change *MemoryChange) MemoryConflict {
+	conflict := MemoryConflict{
+		Key:           key,
+		MemoryType:    "working",
+		LocalVersion:  localClock.Tick(s.instanceID).Sum(),
+		RemoteVersion: change.Clock.Sum(),
+		RemoteValue:   change.Value,
+		RemoteTime:    change.ChangedAt,
+		DetectedAt:    s.service.clock.Now(),
+	}
+	if mem != nil {
+		conflict.LocalValue = mem.Value
+		conflict.LocalTime = mem.UpdatedAt
+	} else {
+		conflict.LocalTime = s.service.clock.Now()
+	}
+	return conflict
+}
+
+// resolveWinner applies a conflict strategy, reporting whether the remote
+// change wins and whether the strategy could decide at all
+func resolveWinner(conflict MemoryConflict, strategy ConflictStrategy) (remoteWins bool, decided bool) {
+	switch strategy {
+	case ConflictStrategyLastWriteWins:
+		return conflict.RemoteTime.After(conflict.LocalTime), true
+	case ConflictStrategyVersionBased:
+		return conflict.RemoteVersion > conflict.LocalVersion, true
+	case ConflictStrategyLocalWins:
+		return false, true
+	case ConflictStrategyRemoteWins:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// resolveHeld settles a held conflict with the given strategy. It reports
+// whether the synchronizer was holding a conflict for the key.
+func (s *Synchronizer) resolveHeld(ctx context.Context, agentID, key string, strategy ConflictStrategy) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.agentState(agentID)
+	held, exists := state.conflicts[key]
+	if !exists {
+		return false, nil
+	}
+
+	remoteWins, decided := resolveWinner(held.conflict, strategy)
+	if !decided {
+		return true, fmt.Errorf("manual conflict resolution required for key: %s", key)
+	}
+
+	if remoteWins {
+		mem, err := s.repo.GetWorking(ctx, agentID, key)
+		if err != nil {
+			mem = nil
+		}
+		if err := s.applyRemote(ctx, state, mem, held.remote); err != nil {
+			return true, err
+		}
+	} else {
+		s.keepLocal(state, key, held.remote)
+	}
+
+	delete(state.conflicts, key)
+	return true, nil
+}
+
+// heldConflicts returns the conflicts awaiting a decision ordered by key
+func (state *agentSyncState) heldConflicts() []MemoryConflict {
+	conflicts := make([]MemoryConflict, 0, len(state.conflicts))
+	for _, held := range state.conflicts {
+		conflicts = append(conflicts, held.conflict)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Key < conflicts[j].Key })
+	return conflicts
+}
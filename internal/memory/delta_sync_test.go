@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newSyncedInstance creates an instance of an agent with its own working
+// memory store that exchanges changes through the shared exchange
+func newSyncedInstance(exchange ChangeExchange, strategy ConflictStrategy) (*Service, *Synchronizer) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	sync := NewSynchronizer(service, repo, strategy, time.Minute)
+	sync.SetExchange(exchange)
+	return service, sync
+}
+
+func TestVectorClock_Compare(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  VectorClock
+		order ClockOrder
+	}{
+		{"equal", VectorClock{"a": 1, "b": 2}, VectorClock{"a": 1, "b": 2}, ClockEqual},
+		{"empty equal", VectorClock{}, VectorClock{}, ClockEqual},
+		{"before", VectorClock{"a": 1}, VectorClock{"a": 1, "b": 1}, ClockBefore},
+		{"after", VectorClock{"a": 2, "b": 1}, VectorClock{"a": 1}, ClockAfter},
+		{"concurrent", VectorClock{"a": 1}, VectorClock{"b": 1}, ClockConcurrent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if order := tt.a.Compare(tt.b); order != tt.order {
+				t.Errorf("Expected %s, got %s", tt.order, order)
+			}
+		})
+	}
+
+	merged := VectorClock{"a": 2}.Merge(VectorClock{"a": 1, "b": 3})
+	if merged["a"] != 2 || merged["b"] != 3 {
+		t.Errorf("Unexpected merged clock: %v", merged)
+	}
+}
+
+func TestDeltaSync_PropagatesChanges(t *testing.T) {
+	ctx := context.Background()
+	exchange := NewMemoryExchange()
+	serviceA, syncA := newSyncedInstance(exchange, ConflictStrategyManual)
+	serviceB, syncB := newSyncedInstance(exchange, ConflictStrategyManual)
+
+	if err := serviceA.StoreWorking(ctx, "agent-1", "plan", "step-1", time.Hour); err != nil {
+		t.Fatalf("Failed to store: %v", err)
+	}
+
+	pending, err := syncA.PendingChanges(ctx, "agent-1")
+	if err != nil || pending != 1 {
+		t.Fatalf("Expected 1 pending change, got %d (%v)", pending, err)
+	}
+
+	if _, err := syncA.SyncAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("Sync A failed: %v", err)
+	}
+	status, _ := syncA.GetStatus(ctx, "agent-1")
+	if status.PendingChanges != 0 || status.Status != SyncStateSynced {
+		t.Errorf("Expected synced status with no pending changes, got %s with %d", status.Status, status.PendingChanges)
+	}
+
+	if _, err := syncB.SyncAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("Sync B failed: %v", err)
+	}
+	value, err := serviceB.RetrieveWorking(ctx, "agent-1", "plan")
+	if err != nil || value != "step-1" {
+		t.Fatalf("Expected B to receive step-1, got %v (%v)", value, err)
+	}
+
+	// Applied remote changes are not reported back as local changes
+	if pending, _ := syncB.PendingChanges(ctx, "agent-1"); pending != 0 {
+		t.Errorf("Expected no pending changes on B, got %d", pending)
+	}
+
+	// B's later write follows A's, so it replaces it without a conflict
+	if err := serviceB.UpdateWorking(ctx, "agent-1", "plan", "step-2"); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	syncB.SyncAgent(ctx, "agent-1")
+	result, _ := syncA.SyncAgent(ctx, "agent-1")
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("Expected no conflicts, got %v", result.Conflicts)
+	}
+	if value, _ := serviceA.RetrieveWorking(ctx, "agent-1", "plan"); value != "step-2" {
+		t.Errorf("Expected A to receive step-2, got %v", value)
+	}
+
+	// Deletes propagate too
+	if err := serviceA.DeleteWorking(ctx, "agent-1", "plan"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	syncA.SyncAgent(ctx, "agent-1")
+	syncB.SyncAgent(ctx, "agent-1")
+	if _, err := serviceB.RetrieveWorking(ctx, "agent-1", "plan"); err == nil {
+		t.Error("Expected delete to propagate to B")
+	}
+}
+
+func TestDeltaSync_ConcurrentWritesHeldForManualResolution(t *testing.T) {
+	ctx := context.Background()
+	exchange := NewMemoryExchange()
+	serviceA, syncA := newSyncedInstance(exchange, ConflictStrategyManual)
+	serviceB, syncB := newSyncedInstance(exchange, ConflictStrategyManual)
+
+	serviceA.StoreWorking(ctx, "agent-1", "target", "north", time.Hour)
+	serviceB.StoreWorking(ctx, "agent-1", "target", "south", time.Hour)
+
+	syncA.SyncAgent(ctx, "agent-1")
+	result, err := syncB.SyncAgent(ctx, "agent-1")
+	if err != nil {
+		t.Fatalf("Sync B failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(result.Conflicts))
+	}
+
+	conflict := result.Conflicts[0]
+	if conflict.Key != "target" || conflict.MemoryType != "working" {
+		t.Errorf("Unexpected conflict: %+v", conflict)
+	}
+	if conflict.LocalValue != "south" || conflict.RemoteValue != "north" {
+		t.Errorf("Expected local south and remote north, got %v and %v", conflict.LocalValue, conflict.RemoteValue)
+	}
+
+	status, _ := syncB.GetStatus(ctx, "agent-1")
+	if status.Status != SyncStateConflict || len(status.Conflicts) != 1 {
+		t.Errorf("Expected conflict status, got %s with %d conflicts", status.Status, len(status.Conflicts))
+	}
+	if status.PendingChanges != 1 {
+		t.Errorf("Expected the held key to be pending, got %d", status.PendingChanges)
+	}
+
+	// The held local value is not published while undecided
+	syncA.SyncAgent(ctx, "agent-1")
+	if value, _ := serviceA.RetrieveWorking(ctx, "agent-1", "target"); value != "north" {
+		t.Errorf("Expected A to keep north while B's conflict is held, got %v", value)
+	}
+
+	// Without a deciding strategy resolution fails
+	if err := syncB.ResolveConflicts(ctx, "agent-1", result.Conflicts); err == nil {
+		t.Error("Expected manual strategy to require a decision")
+	}
+
+	syncB.SetConflictStrategy(ConflictStrategyRemoteWins)
+	if err := syncB.ResolveConflicts(ctx, "agent-1", result.Conflicts); err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if value, _ := serviceB.RetrieveWorking(ctx, "agent-1", "target"); value != "north" {
+		t.Errorf("Expected remote value north on B, got %v", value)
+	}
+
+	status, _ = syncB.GetStatus(ctx, "agent-1")
+	if status.PendingChanges != 0 || len(status.Conflicts) != 0 {
+		t.Errorf("Expected resolved status, got %d pending and %d conflicts", status.PendingChanges, len(status.Conflicts))
+	}
+}
+
+func TestDeltaSync_LocalWinsPublishesResolvedValue(t *testing.T) {
+	ctx := context.Background()
+	exchange := NewMemoryExchange()
+	serviceA, syncA := newSyncedInstance(exchange, ConflictStrategyManual)
+	serviceB, syncB := newSyncedInstance(exchange, ConflictStrategyLocalWins)
+
+	serviceA.StoreWorking(ctx, "agent-1", "target", "north", time.Hour)
+	serviceB.StoreWorking(ctx, "agent-1", "target", "south", time.Hour)
+
+	syncA.SyncAgent(ctx, "agent-1")
+	result, _ := syncB.SyncAgent(ctx, "agent-1")
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("Expected the conflict to be resolved automatically, got %v", result.Conflicts)
+	}
+
+	// B's value supersedes A's, so A applies it without a conflict
+	result, _ = syncA.SyncAgent(ctx, "agent-1")
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("Expected no conflict on A, got %v", result.Conflicts)
+	}
+	if value, _ := serviceA.RetrieveWorking(ctx, "agent-1", "target"); value != "south" {
+		t.Errorf("Expected A to converge on south, got %v", value)
+	}
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+// sealedValuePrefix marks memory values encrypted at rest
+const sealedValuePrefix = "sealed:v1:"
+
+// ErrValueEncrypted is returned when reading an encrypted memory value
+// without a key able to decrypt it
+var ErrValueEncrypted = errors.New("memory value is encrypted")
+
+// ValueEncryption encrypts memory values at rest with AES-GCM. Values are
+// encrypted with the current key; previous keys are only used to decrypt
+// values stored before a key rotation.
+type ValueEncryption struct {
+	current  cipher.AEAD
+	previous []cipher.AEAD
+}
+
+// NewValueEncryption creates value encryption from AES keys of 16, 24 or 32 bytes
+func NewValueEncryption(key []byte, previousKeys ...[]byte) (*ValueEncryption, error) {
+	current, err := newValueAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory encryption key: %w", err)
+	}
+
+	e := &ValueEncryption{current: current}
+	for i, previousKey := range previousKeys {
+		aead, err := newValueAEAD(previousKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous memory encryption key %d: %w", i+1, err)
+		}
+		e.previous = append(e.previous, aead)
+	}
+	return e, nil
+}
+
+// ValueEncryptionFromConfig creates value encryption from base64 keys in the
+// application config. It returns nil when no key is configured.
+func ValueEncryptionFromConfig(cfg config.MemoryEncryptionConfig) (*ValueEncryption, error) {
+	if cfg.Key == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("memory encryption key must be base64: %w", err)
+	}
+	previousKeys := make([][]byte, 0, len(cfg.PreviousKeys))
+	for _, encoded := range cfg.PreviousKeys {
+		previousKey, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("previous memory encryption key must be base64: %w", err)
+		}
+		previousKeys = append(previousKeys, previousKey)
+	}
+	return NewValueEncryption(key, previousKeys...)
+}
+
+func newValueAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the JSON of a value
+func (e *ValueEncryption) seal(value interface{}) (string, error) {
+	plain, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal memory value: %w", err)
+	}
+	nonce := make([]byte, e.current.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt memory value: %w", err)
+	}
+	sealed := e.current.Seal(nonce, nonce, plain, nil)
+	return sealedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a sealed value with the current key or, failing that, a
+// previous one
+func (e *ValueEncryption) open(sealed string) (interface{}, error) {
+	if !strings.HasPrefix(sealed, sealedValuePrefix) {
+		return nil, fmt.Errorf("memory value is not sealed")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedValuePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed memory value: %w", err)
+	}
+
+	for _, aead := range append([]cipher.AEAD{e.current}, e.previous...) {
+		if len(raw) < aead.NonceSize() {
+			break
+		}
+		nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		plain, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(plain, &value); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal memory value: %w", err)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("%w: no configured key decrypts it", ErrValueEncrypted)
+}
+
+// sealValue returns the value to store and whether it is encrypted
+func (r *Repository) sealValue(value interface{}) (interface{}, bool, error) {
+	if r.encryption == nil {
+		return value, false, nil
+	}
+	sealed, err := r.encryption.seal(value)
+	if err != nil {
+		return nil, false, err
+	}
+	return sealed, true, nil
+}
+
+// openValue returns the plain value of a stored value
+func (r *Repository) openValue(stored interface{}, encrypted bool) (interface{}, error) {
+	if !encrypted {
+		return stored, nil
+	}
+	if r.encryption == nil {
+		return nil, fmt.Errorf("%w: no encryption key is configured", ErrValueEncrypted)
+	}
+	sealed, ok := stored.(string)
+	if !ok {
+		return nil, fmt.Errorf("sealed memory value has unexpected type %T", stored)
+	}
+	return r.encryption.open(sealed)
+}
+
+// sealSnapshotState returns a copy of a snapshot state with the values of its
+// working memory contents encrypted
+func (r *Repository) sealSnapshotState(state map[string]interface{}) (map[string]interface{}, error) {
+	entries, ok := state[snapshotWorkingMemoryKey].([]interface{})
+	if r.encryption == nil || !ok {
+		return state, nil
+	}
+
+	sealed := make(map[string]interface{}, len(state))
+	for k, v := range state {
+		sealed[k] = v
+	}
+	sealedEntries := make([]interface{}, len(entries))
+	for i, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			sealedEntries[i] = e
+			continue
+		}
+		value, encrypted, err := r.sealValue(entry["value"])
+		if err != nil {
+			return nil, err
+		}
+		copied := make(map[string]interface{}, len(entry)+1)
+		for k, v := range entry {
+			copied[k] = v
+		}
+		copied["value"] = value
+		copied["value_encrypted"] = encrypted
+		sealedEntries[i] = copied
+	}
+	sealed[snapshotWorkingMemoryKey] = sealedEntries
+	return sealed, nil
+}
+
+// openSnapshotState decrypts the working memory values of a stored snapshot
+// state in place
+func (r *Repository) openSnapshotState(state map[string]interface{}) error {
+	entries, _ := state[snapshotWorkingMemoryKey].([]interface{})
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		encrypted, _ := entry["value_encrypted"].(bool)
+		if !encrypted {
+			continue
+		}
+		value, err := r.openValue(entry["value"], true)
+		if err != nil {
+			return err
+		}
+		entry["value"] = value
+		delete(entry, "value_encrypted")
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRepository_ValueEncryption(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	// stored converts a document to what ArangoDB hands back
+	stored := func(doc map[string]interface{}) map[string]interface{} {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("Failed to marshal document: %v", err)
+		}
+		if bytes.Contains(data, []byte("hunter2")) {
+			t.Errorf("Expected no plaintext in the stored document: %s", data)
+		}
+		var loaded map[string]interface{}
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			t.Fatalf("Failed to unmarshal document: %v", err)
+		}
+		return loaded
+	}
+
+	encryption, err := NewValueEncryption(oldKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption: %v", err)
+	}
+	r := &Repository{}
+	r.SetValueEncryption(encryption)
+
+	working := &WorkingMemory{AgentID: "PUMP-002", Key: "scada.login", Value: map[string]interface{}{"user": "ops", "password": "hunter2"}}
+	doc, err := r.workingMemoryToDocument(working)
+	if err != nil {
+		t.Fatalf("Failed to convert working memory: %v", err)
+	}
+	if text := doc["search_text"].(string); strings.Contains(text, "ops") {
+		t.Errorf("Expected encrypted values to be left out of search text, got %q", text)
+	}
+	workingDoc := stored(doc)
+
+	longterm := &LongtermMemory{AgentID: "PUMP-002", Key: "vendor.contact", Value: "hunter2"}
+	doc, err = r.longtermMemoryToDocument(longterm)
+	if err != nil {
+		t.Fatalf("Failed to convert longterm memory: %v", err)
+	}
+	longtermDoc := stored(doc)
+
+	snapshot := &StateSnapshot{ID: "snap-1", State: map[string]interface{}{
+		"working_memory": snapshotWorkingMemory([]*WorkingMemory{working}),
+	}}
+	doc, err = r.snapshotToDocument(snapshot)
+	if err != nil {
+		t.Fatalf("Failed to convert snapshot: %v", err)
+	}
+	snapshotDoc := stored(doc)
+
+	// After rotating the key, values stored with the old key still decrypt
+	rotated, err := NewValueEncryption(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("Failed to create encryption: %v", err)
+	}
+	r.SetValueEncryption(rotated)
+
+	loadedWorking, err := r.documentToWorkingMemory(workingDoc)
+	if err != nil {
+		t.Fatalf("Failed to load working memory: %v", err)
+	}
+	if value, _ := loadedWorking.Value.(map[string]interface{}); value["password"] != "hunter2" {
+		t.Errorf("Unexpected decrypted value: %v", loadedWorking.Value)
+	}
+	loadedLongterm, err := r.documentToLongtermMemory(longtermDoc)
+	if err != nil || loadedLongterm.Value != "hunter2" {
+		t.Errorf("Unexpected longterm memory %v (err %v)", loadedLongterm, err)
+	}
+	loadedSnapshot, err := r.documentToSnapshot(snapshotDoc)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	contents, err := restoredWorkingMemory("PUMP-002", loadedSnapshot.State, loadedWorking.CreatedAt)
+	if err != nil || len(contents) != 1 {
+		t.Fatalf("Unexpected snapshot contents %v (err %v)", contents, err)
+	}
+	if value, _ := contents[0].Value.(map[string]interface{}); value["password"] != "hunter2" {
+		t.Errorf("Unexpected snapshot value: %v", contents[0].Value)
+	}
+
+	// Plaintext documents stay readable
+	plain := map[string]interface{}{"agent_id": "PUMP-002", "key": "legacy", "value": "open"}
+	if mem, err := r.documentToWorkingMemory(plain); err != nil || mem.Value != "open" {
+		t.Errorf("Expected a plaintext value to be read as is, got %v (err %v)", mem, err)
+	}
+
+	// Without a key able to decrypt, reads fail rather than return ciphertext
+	other, _ := NewValueEncryption(bytes.Repeat([]byte{3}, 16))
+	r.SetValueEncryption(other)
+	if _, err := r.documentToWorkingMemory(workingDoc); !errors.Is(err, ErrValueEncrypted) {
+		t.Errorf("Expected ErrValueEncrypted for an unknown key, got %v", err)
+	}
+	r.SetValueEncryption(nil)
+	if _, err := r.documentToLongtermMemory(longtermDoc); !errors.Is(err, ErrValueEncrypted) {
+		t.Errorf("Expected ErrValueEncrypted without a key, got %v", err)
+	}
+
+	if _, err := NewValueEncryption([]byte("short")); err == nil {
+		t.Error("Expected an error for an invalid key")
+	}
+}
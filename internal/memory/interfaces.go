@@ -57,7 +57,7 @@ type MemoryService interface {
 
 	// State Snapshots
 	CreateSnapshot(ctx context.Context, agentID string, snapshotType, reason string) (*StateSnapshot, error)
-	RestoreSnapshot(ctx context.Context, agentID, snapshotID string) error
+	RestoreSnapshot(ctx context.Context, agentID, snapshotID string) (*StateSnapshot, error)
 	ListSnapshots(ctx context.Context, agentID string, filters SnapshotFilters) ([]*StateSnapshot, error)
 	DeleteSnapshot(ctx context.Context, snapshotID string) error
 
@@ -69,6 +69,10 @@ type MemoryService interface {
 	// Maintenance
 	CleanupExpired(ctx context.Context) (int, error)
 	GetMemoryStats(ctx context.Context, agentID string) (*MemoryStats, error)
+
+	// Migration
+	ExportAgentMemory(ctx context.Context, agentID string) (*MemoryArchive, error)
+	ImportAgentMemory(ctx context.Context, archive *MemoryArchive, opts ImportOptions) (*ImportResult, error)
 }
 
 // MemorySynchronizer defines the interface for memory synchronization operations
@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// ArchiveFormatVersion identifies the layout of memory archives
+const ArchiveFormatVersion = "1"
+
+// MemoryArchive is a portable export of an agent's complete memory
+type MemoryArchive struct {
+	FormatVersion string    `json:"format_version"`
+	AgentID       string    `json:"agent_id"`
+	ExportedAt    time.Time `json:"exported_at"`
+
+	Working   []*WorkingMemory  `json:"working"`
+	Longterm  []*LongtermMemory `json:"longterm"`
+	Snapshots []*StateSnapshot  `json:"snapshots"`
+}
+
+// ImportOptions controls how an archive is imported
+type ImportOptions struct {
+	// TargetAgentID is the agent that receives the memories
+	TargetAgentID string `json:"target_agent_id"`
+
+	// KeyMap renames memory keys exactly (old key -> new key)
+	KeyMap map[string]string `json:"key_map,omitempty"`
+
+	// KeyPrefixMap rewrites key prefixes (old prefix -> new prefix). It is
+	// applied to keys not matched by KeyMap.
+	KeyPrefixMap map[string]string `json:"key_prefix_map,omitempty"`
+
+	// TagMap rewrites long-term memory tags (old tag -> new tag). Mapping a
+	// tag to "" removes it.
+	TagMap map[string]string `json:"tag_map,omitempty"`
+
+	// AddTags are added to every imported long-term memory
+	AddTags []string `json:"add_tags,omitempty"`
+
+	// Overwrite replaces memories that already exist under the target agent.
+	// Otherwise existing memories are kept and the imported entry is skipped.
+	Overwrite bool `json:"overwrite"`
+}
+
+// ImportResult summarises an archive import
+type ImportResult struct {
+	SourceAgentID string   `json:"source_agent_id"`
+	TargetAgentID string   `json:"target_agent_id"`
+	Working       int      `json:"working"`
+	Longterm      int      `json:"longterm"`
+	Snapshots     int      `json:"snapshots"`
+	Skipped       int      `json:"skipped"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// ExportAgentMemory exports an agent's working memory, long-term memory and
+// snapshots into a portable archive
+func (s *Service) ExportAgentMemory(ctx context.Context, agentID string) (*MemoryArchive, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
+	if agentID == "" {
+		return nil, fmt.Errorf("agent ID is required")
+	}
+
+	// An export reads all of the agent's memory
+	working, err := s.repo.ListWorking(ctx, agentID, MemoryFilters{})
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, "", AuditOpRead, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working memory: %w", err)
+	}
+
+	longterm, err := s.repo.ListLongterm(ctx, agentID, MemoryFilters{})
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, "", AuditOpRead, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list long-term memory: %w", err)
+	}
+
+	snapshots, err := s.repo.ListSnapshots(ctx, agentID, SnapshotFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	archive := &MemoryArchive{
+		FormatVersion: ArchiveFormatVersion,
+		AgentID:       agentID,
+		ExportedAt:    s.clock.Now(),
+		Working:       working,
+		Longterm:      longterm,
+		Snapshots:     snapshots,
+	}
+	if archive.Working == nil {
+		archive.Working = []*WorkingMemory{}
+	}
+	if archive.Longterm == nil {
+		archive.Longterm = []*LongtermMemory{}
+	}
+	if archive.Snapshots == nil {
+		archive.Snapshots = []*StateSnapshot{}
+	}
+
+	log.WithFields(log.Fields{
+		"agent_id":  agentID,
+		"working":   len(archive.Working),
+		"longterm":  len(archive.Longterm),
+		"snapshots": len(archive.Snapshots),
+	}).Info("Exported agent memory")
+
+	return archive, nil
+}
+
+// ImportAgentMemory imports an archive under opts.TargetAgentID, remapping
+// keys and rewriting tags. Imported entries get new IDs; long-term memory
+// references are updated to point at the new IDs. Expired working memory and
+// snapshots are skipped, and snapshots whose state does not match their
+// checksum are reported as errors rather than imported.
+func (s *Service) ImportAgentMemory(ctx context.Context, archive *MemoryArchive, opts ImportOptions) (*ImportResult, error) {
+	if archive == nil {
+		return nil, fmt.Errorf("archive is required")
+	}
+	if archive.FormatVersion != ArchiveFormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %q", archive.FormatVersion)
+	}
+	if opts.TargetAgentID == "" {
+		return nil, fmt.Errorf("target agent ID is required")
+	}
+	if err := tenant.CheckIDs(ctx, opts.TargetAgentID); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{
+		SourceAgentID: archive.AgentID,
+		TargetAgentID: opts.TargetAgentID,
+	}
+	now := s.clock.Now()
+
+	for _, src := range archive.Working {
+		if !src.ExpiresAt.IsZero() && src.ExpiresAt.Before(now) {
+			result.Skipped++
+			continue
+		}
+
+		mem := *src
+		mem.ID = ""
+		mem.AgentID = opts.TargetAgentID
+		mem.Key = opts.remapKey(src.Key)
+		mem.Metadata = make(map[string]interface{}, len(src.Metadata)+1)
+		for k, v := range src.Metadata {
+			mem.Metadata[k] = v
+		}
+		mem.Metadata["imported_from"] = archive.AgentID
+
+		if existing, err := s.repo.GetWorking(ctx, mem.AgentID, mem.Key); err == nil && existing != nil {
+			if !opts.Overwrite {
+				result.Skipped++
+				continue
+			}
+			if err := s.repo.DeleteWorking(ctx, mem.AgentID, mem.Key); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("working %s: %v", mem.Key, err))
+				continue
+			}
+		}
+
+		err := s.repo.StoreWorking(ctx, &mem)
+		s.recordAccess(ctx, mem.AgentID, MemoryTypeWorking, mem.Key, AuditOpWrite, err)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("working %s: %v", mem.Key, err))
+			continue
+		}
+		result.Working++
+	}
+
+	// Assign new IDs up front so references between memories can be remapped
+	idMap := make(map[string]string, len(archive.Longterm))
+	for _, src := range archive.Longterm {
+		idMap[src.ID] = uuid.New().String()
+	}
+
+	for _, src := range archive.Longterm {
+		mem := *src
+		mem.ID = idMap[src.ID]
+		mem.AgentID = opts.TargetAgentID
+		mem.Key = opts.remapKey(src.Key)
+		mem.Metadata.Tags = opts.rewriteTags(src.Metadata.Tags)
+		mem.Metadata.References = make([]string, 0, len(src.Metadata.References))
+		for _, ref := range src.Metadata.References {
+			if newID, ok := idMap[ref]; ok {
+				ref = newID
+			}
+			mem.Metadata.References = append(mem.Metadata.References, ref)
+		}
+
+		if existing, err := s.repo.GetLongterm(ctx, mem.AgentID, mem.Key); err == nil && existing != nil {
+			if !opts.Overwrite {
+				result.Skipped++
+				continue
+			}
+			if err := s.repo.DeleteLongterm(ctx, mem.AgentID, mem.Key); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("longterm %s: %v", mem.Key, err))
+				continue
+			}
+		}
+
+		err := s.repo.StoreLongterm(ctx, &mem)
+		s.recordAccess(ctx, mem.AgentID, MemoryTypeLongterm, mem.Key, AuditOpWrite, err)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("longterm %s: %v", mem.Key, err))
+			continue
+		}
+		result.Longterm++
+	}
+
+	for _, src := range archive.Snapshots {
+		if !src.ExpiresAt.IsZero() && src.ExpiresAt.Before(now) {
+			result.Skipped++
+			continue
+		}
+		if err := verifySnapshot(src); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("snapshot %s: %v", src.ID, err))
+			continue
+		}
+
+		snapshot := *src
+		snapshot.ID = ""
+		snapshot.AgentID = opts.TargetAgentID
+		snapshot.State = opts.remapSnapshotState(src.State)
+		snapshot.Metadata.Trigger = "import"
+		snapshot.Metadata.Reason = fmt.Sprintf("imported from agent %s: %s", archive.AgentID, src.Metadata.Reason)
+
+		if err := s.repo.CreateSnapshot(ctx, &snapshot); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("snapshot %s: %v", src.ID, err))
+			continue
+		}
+		result.Snapshots++
+	}
+
+	log.WithFields(log.Fields{
+		"source_agent_id": result.SourceAgentID,
+		"target_agent_id": result.TargetAgentID,
+		"working":         result.Working,
+		"longterm":        result.Longterm,
+		"snapshots":       result.Snapshots,
+		"skipped":         result.Skipped,
+		"errors":          len(result.Errors),
+	}).Info("Imported agent memory")
+
+	return result, nil
+}
+
+// remapKey applies the exact key map, then the longest matching prefix rewrite
+func (o ImportOptions) remapKey(key string) string {
+	if mapped, ok := o.KeyMap[key]; ok {
+		return mapped
+	}
+
+	bestPrefix := ""
+	for prefix := range o.KeyPrefixMap {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(bestPrefix) {
+			bestPrefix = prefix
+		}
+	}
+	if bestPrefix == "" {
+		return key
+	}
+	return o.KeyPrefixMap[bestPrefix] + strings.TrimPrefix(key, bestPrefix)
+}
+
+// rewriteTags applies the tag map and adds AddTags, dropping duplicates
+func (o ImportOptions) rewriteTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(tags)+len(o.AddTags))
+
+	add := func(tag string) {
+		if tag == "" || seen[tag] {
+			return
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	for _, tag := range tags {
+		if mapped, ok := o.TagMap[tag]; ok {
+			tag = mapped
+		}
+		add(tag)
+	}
+	for _, tag := range o.AddTags {
+		add(tag)
+	}
+	return result
+}
+
+// remapSnapshotState copies snapshot state, remapping the recorded working
+// memory keys and the keys of the working memory contents
+func (o ImportOptions) remapSnapshotState(state map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(state))
+	for k, v := range state {
+		result[k] = v
+	}
+
+	switch keys := state["working_memory_keys"].(type) {
+	case []string:
+		remapped := make([]string, len(keys))
+		for i, key := range keys {
+			remapped[i] = o.remapKey(key)
+		}
+		result["working_memory_keys"] = remapped
+	case []interface{}:
+		remapped := make([]interface{}, len(keys))
+		for i, key := range keys {
+			if s, ok := key.(string); ok {
+				remapped[i] = o.remapKey(s)
+			} else {
+				remapped[i] = key
+			}
+		}
+		result["working_memory_keys"] = remapped
+	}
+
+	if entries, ok := state[snapshotWorkingMemoryKey].([]interface{}); ok {
+		remapped := make([]interface{}, len(entries))
+		for i, e := range entries {
+			entry, ok := e.(map[string]interface{})
+			key, isString := entry["key"].(string)
+			if !ok || !isString {
+				remapped[i] = e
+				continue
+			}
+			copied := make(map[string]interface{}, len(entry))
+			for k, v := range entry {
+				copied[k] = v
+			}
+			copied["key"] = o.remapKey(key)
+			remapped[i] = copied
+		}
+		result[snapshotWorkingMemoryKey] = remapped
+	}
+
+	return result
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func seedSourceAgent(t *testing.T, repo *MockRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := repo.StoreWorking(ctx, &WorkingMemory{
+		ID: "w1", AgentID: "pump-001", Key: "pump-001.current_task", Value: "priming",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreWorking(ctx, &WorkingMemory{
+		ID: "w2", AgentID: "pump-001", Key: "stale", Value: "old",
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreLongterm(ctx, &LongtermMemory{
+		ID: "l1", AgentID: "pump-001", Key: "pump-001.calibration", Category: "fact", Value: 1.5,
+		Metadata: MemoryMetadata{Tags: []string{"env:prod", "pump"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreLongterm(ctx, &LongtermMemory{
+		ID: "l2", AgentID: "pump-001", Key: "maintenance", Category: "experience", Value: "seal replaced",
+		Metadata: MemoryMetadata{Tags: []string{"obsolete"}, References: []string{"l1", "external"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateSnapshot(ctx, &StateSnapshot{
+		AgentID: "pump-001", SnapshotType: "manual",
+		State: map[string]interface{}{
+			"working_memory_keys": []string{"pump-001.current_task"},
+			"working_memory": []interface{}{
+				map[string]interface{}{"key": "pump-001.current_task", "value": "priming"},
+			},
+		},
+		Metadata:  SnapshotMetadata{Reason: "before upgrade"},
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_ExportImportAgentMemory(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+	seedSourceAgent(t, repo)
+
+	archive, err := service.ExportAgentMemory(ctx, "pump-001")
+	if err != nil {
+		t.Fatalf("Failed to export memory: %v", err)
+	}
+	if len(archive.Working) != 2 || len(archive.Longterm) != 2 || len(archive.Snapshots) != 1 {
+		t.Fatalf("Unexpected archive contents: %d working, %d longterm, %d snapshots",
+			len(archive.Working), len(archive.Longterm), len(archive.Snapshots))
+	}
+
+	// Round-trip through JSON as a real migration would
+	data, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatalf("Failed to marshal archive: %v", err)
+	}
+	var portable MemoryArchive
+	if err := json.Unmarshal(data, &portable); err != nil {
+		t.Fatalf("Failed to unmarshal archive: %v", err)
+	}
+
+	result, err := service.ImportAgentMemory(ctx, &portable, ImportOptions{
+		TargetAgentID: "pump-002",
+		KeyMap:        map[string]string{"maintenance": "maintenance_log"},
+		KeyPrefixMap:  map[string]string{"pump-001.": "pump-002."},
+		TagMap:        map[string]string{"env:prod": "env:staging", "obsolete": ""},
+		AddTags:       []string{"cloned"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to import memory: %v", err)
+	}
+
+	if result.Working != 1 || result.Longterm != 2 || result.Snapshots != 1 || result.Skipped != 1 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	task, err := service.RetrieveWorking(ctx, "pump-002", "pump-002.current_task")
+	if err != nil || task != "priming" {
+		t.Errorf("Expected remapped working memory, got %v (err %v)", task, err)
+	}
+
+	calibration, err := repo.GetLongterm(ctx, "pump-002", "pump-002.calibration")
+	if err != nil {
+		t.Fatalf("Expected remapped long-term memory: %v", err)
+	}
+	if got := calibration.Metadata.Tags; len(got) != 3 || got[0] != "env:staging" || got[1] != "pump" || got[2] != "cloned" {
+		t.Errorf("Unexpected rewritten tags: %v", got)
+	}
+
+	maintenance, err := repo.GetLongterm(ctx, "pump-002", "maintenance_log")
+	if err != nil {
+		t.Fatalf("Expected renamed long-term memory: %v", err)
+	}
+	if got := maintenance.Metadata.Tags; len(got) != 1 || got[0] != "cloned" {
+		t.Errorf("Expected removed tag to be dropped, got %v", got)
+	}
+	refs := maintenance.Metadata.References
+	if len(refs) != 2 || refs[0] != calibration.ID || refs[1] != "external" {
+		t.Errorf("Expected references remapped to new IDs, got %v (calibration ID %s)", refs, calibration.ID)
+	}
+
+	snapshots, err := service.ListSnapshots(ctx, "pump-002", SnapshotFilters{})
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("Expected 1 imported snapshot, got %d (err %v)", len(snapshots), err)
+	}
+	keys, _ := snapshots[0].State["working_memory_keys"].([]interface{})
+	if len(keys) != 1 || keys[0] != "pump-002.current_task" {
+		t.Errorf("Expected remapped snapshot keys, got %v", snapshots[0].State["working_memory_keys"])
+	}
+	contents, err := restoredWorkingMemory("pump-002", snapshots[0].State, time.Now())
+	if err != nil || len(contents) != 1 || contents[0].Key != "pump-002.current_task" {
+		t.Errorf("Expected remapped snapshot contents, got %v (err %v)", contents, err)
+	}
+
+	// Source agent is untouched
+	if _, err := repo.GetLongterm(ctx, "pump-001", "pump-001.calibration"); err != nil {
+		t.Errorf("Source memory should remain: %v", err)
+	}
+}
+
+func TestService_ImportAgentMemoryExisting(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+	seedSourceAgent(t, repo)
+
+	archive, err := service.ExportAgentMemory(ctx, "pump-001")
+	if err != nil {
+		t.Fatalf("Failed to export memory: %v", err)
+	}
+
+	if err := service.Remember(ctx, "pump-002", "maintenance", "keep me", "experience", nil); err != nil {
+		t.Fatalf("Failed to seed target: %v", err)
+	}
+
+	result, err := service.ImportAgentMemory(ctx, archive, ImportOptions{TargetAgentID: "pump-002"})
+	if err != nil {
+		t.Fatalf("Failed to import memory: %v", err)
+	}
+	if result.Longterm != 1 || result.Skipped != 2 {
+		t.Errorf("Expected existing memory to be skipped, got %+v", result)
+	}
+	if value, _ := service.Recall(ctx, "pump-002", "maintenance"); value != "keep me" {
+		t.Errorf("Existing memory was overwritten: %v", value)
+	}
+
+	result, err = service.ImportAgentMemory(ctx, archive, ImportOptions{TargetAgentID: "pump-002", Overwrite: true})
+	if err != nil {
+		t.Fatalf("Failed to import memory: %v", err)
+	}
+	if result.Longterm != 2 {
+		t.Errorf("Expected both long-term memories imported, got %+v", result)
+	}
+	if value, _ := service.Recall(ctx, "pump-002", "maintenance"); value != "seal replaced" {
+		t.Errorf("Expected existing memory to be overwritten, got %v", value)
+	}
+
+	// A snapshot altered after export is not imported
+	tampered := *archive.Snapshots[0]
+	tampered.State = map[string]interface{}{"working_memory_keys": []string{"injected"}}
+	result, err = service.ImportAgentMemory(ctx, &MemoryArchive{
+		FormatVersion: ArchiveFormatVersion,
+		AgentID:       "pump-001",
+		Snapshots:     []*StateSnapshot{&tampered},
+	}, ImportOptions{TargetAgentID: "pump-003"})
+	if err != nil {
+		t.Fatalf("Failed to import memory: %v", err)
+	}
+	if result.Snapshots != 0 || len(result.Errors) != 1 {
+		t.Errorf("Expected the tampered snapshot to be rejected, got %+v", result)
+	}
+
+	if _, err := service.ImportAgentMemory(ctx, archive, ImportOptions{}); err == nil {
+		t.Error("Expected error for missing target agent")
+	}
+	if _, err := service.ImportAgentMemory(ctx, &MemoryArchive{FormatVersion: "99"}, ImportOptions{TargetAgentID: "x"}); err == nil {
+		t.Error("Expected error for unsupported format version")
+	}
+}
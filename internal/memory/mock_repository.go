@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"sync"
-	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 )
 
 // MockRepository is a mock implementation of MemoryRepository for testing
@@ -17,11 +18,16 @@ type MockRepository struct {
 	snapshots      map[string]*StateSnapshot  // key: snapshotID
 	syncStatus     map[string]*SyncStatus     // key: agentID:instanceID
 
+	// snapshotSeq makes generated snapshot IDs unique
+	snapshotSeq int
+
 	// Call tracking
 	calls map[string]int
 
 	// Error injection
 	errors map[string]error
+
+	clock clock.Clock
 }
 
 // NewMockRepository creates a new mock repository
@@ -33,6 +39,14 @@ func NewMockRepository() *MockRepository {
 		syncStatus:     make(map[string]*SyncStatus),
 		calls:          make(map[string]int),
 		errors:         make(map[string]error),
+		clock:          clock.Real(),
+	}
+}
+
+// SetClock sets the clock used for timestamps and expiry
+func (m *MockRepository) SetClock(c clock.Clock) {
+	if c != nil {
+		m.clock = c
 	}
 }
 
@@ -85,7 +99,7 @@ func (m *MockRepository) StoreWorking(ctx context.Context, mem *WorkingMemory) e
 	defer m.mu.Unlock()
 
 	key := fmt.Sprintf("%s:%s", mem.AgentID, mem.Key)
-	now := time.Now()
+	now := m.clock.Now()
 	mem.CreatedAt = now
 	mem.UpdatedAt = now
 	mem.Version = 1
@@ -113,7 +127,7 @@ func (m *MockRepository) GetWorking(ctx context.Context, agentID, key string) (*
 		m.mu.Lock()
 		defer m.mu.Unlock()
 		mem.AccessCount++
-		mem.AccessedAt = time.Now()
+		mem.AccessedAt = m.clock.Now()
 	}()
 
 	return mem, nil
@@ -139,7 +153,7 @@ func (m *MockRepository) UpdateWorking(ctx context.Context, mem *WorkingMemory)
 	}
 
 	mem.Version++
-	mem.UpdatedAt = time.Now()
+	mem.UpdatedAt = m.clock.Now()
 	m.workingMemory[key] = mem
 	return nil
 }
@@ -170,7 +184,9 @@ func (m *MockRepository) ListWorking(ctx context.Context, agentID string, filter
 		if mem.AgentID != agentID {
 			continue
 		}
-		// Apply filters if needed
+		if filters.Text != "" && !matchesText(filters.Text, searchText(mem.Key, mem.Value)) {
+			continue
+		}
 		result = append(result, mem)
 	}
 	return result, nil
@@ -205,7 +221,7 @@ func (m *MockRepository) StoreLongterm(ctx context.Context, mem *LongtermMemory)
 	defer m.mu.Unlock()
 
 	key := fmt.Sprintf("%s:%s", mem.AgentID, mem.Key)
-	now := time.Now()
+	now := m.clock.Now()
 	mem.CreatedAt = now
 	mem.UpdatedAt = now
 	mem.Version = 1
@@ -233,7 +249,7 @@ func (m *MockRepository) GetLongterm(ctx context.Context, agentID, key string) (
 		m.mu.Lock()
 		defer m.mu.Unlock()
 		mem.AccessCount++
-		mem.LastAccessed = time.Now()
+		mem.LastAccessed = m.clock.Now()
 	}()
 
 	return mem, nil
@@ -259,7 +275,7 @@ func (m *MockRepository) UpdateLongterm(ctx context.Context, mem *LongtermMemory
 	}
 
 	mem.Version++
-	mem.UpdatedAt = time.Now()
+	mem.UpdatedAt = m.clock.Now()
 	m.longtermMemory[key] = mem
 	return nil
 }
@@ -301,6 +317,9 @@ func (m *MockRepository) ListLongterm(ctx context.Context, agentID string, filte
 		if filters.MinImportance > 0 && mem.Metadata.Importance < filters.MinImportance {
 			continue
 		}
+		if filters.Text != "" && !matchesText(filters.Text, searchText(mem.Key, mem.Value)) {
+			continue
+		}
 
 		result = append(result, mem)
 	}
@@ -325,9 +344,15 @@ func (m *MockRepository) SearchLongterm(ctx context.Context, agentID string, que
 		if query.Filters.Category != "" && mem.Category != query.Filters.Category {
 			continue
 		}
+		if query.Filters.Text != "" && !matchesText(query.Filters.Text, searchText(mem.Key, mem.Value)) {
+			continue
+		}
 
 		result = append(result, mem)
 	}
+	if len(query.Embedding) > 0 {
+		return rankBySimilarity(result, query), nil
+	}
 	return result, nil
 }
 
@@ -345,12 +370,20 @@ func (m *MockRepository) CreateSnapshot(ctx context.Context, snapshot *StateSnap
 
 	// Generate ID if not set
 	if snapshot.ID == "" {
-		snapshot.ID = fmt.Sprintf("snap-%d", time.Now().UnixNano())
+		m.snapshotSeq++
+		snapshot.ID = fmt.Sprintf("snap-%d-%d", m.clock.Now().UnixNano(), m.snapshotSeq)
+	}
+
+	checksum, size, err := snapshotChecksum(snapshot.State)
+	if err != nil {
+		return err
 	}
 
-	now := time.Now()
+	now := m.clock.Now()
 	snapshot.CreatedAt = now
 	snapshot.Version = 1
+	snapshot.Checksum = checksum
+	snapshot.Metadata.SizeBytes = size
 	m.snapshots[snapshot.ID] = snapshot
 	return nil
 }
@@ -426,7 +459,7 @@ func (m *MockRepository) GetSyncStatus(ctx context.Context, agentID, instanceID
 			AgentID:    agentID,
 			InstanceID: instanceID,
 			Status:     SyncStateSynced,
-			LastSyncAt: time.Now(),
+			LastSyncAt: m.clock.Now(),
 		}
 		m.syncStatus[key] = status
 	}
@@ -459,7 +492,7 @@ func (m *MockRepository) CleanupExpired(ctx context.Context) (int, error) {
 	defer m.mu.Unlock()
 
 	count := 0
-	now := time.Now()
+	now := m.clock.Now()
 
 	// Clean working memory
 	for key, mem := range m.workingMemory {
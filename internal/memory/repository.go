@@ -2,11 +2,10 @@ package memory
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	"github.com/aosanya/CodeValdCortex/internal/database"
 	driver "github.com/arangodb/go-driver"
 	"github.com/google/uuid"
@@ -19,6 +18,8 @@ const (
 	CollectionLongtermMemory = "agent_longterm_memory"
 	CollectionSnapshots      = "agent_state_snapshots"
 	CollectionSyncStatus     = "agent_memory_sync"
+	CollectionMemoryChanges  = "agent_memory_changes"
+	CollectionMemoryAudit    = "agent_memory_audit"
 )
 
 // Repository handles memory persistence in ArangoDB
@@ -28,7 +29,16 @@ type Repository struct {
 	longtermMemCol     driver.Collection
 	snapshotsCol       driver.Collection
 	syncStatusCol      driver.Collection
+	changesCol         driver.Collection
 	ensuredCollections bool
+	clock              clock.Clock
+
+	// snapshotCompressionThreshold is the state size above which snapshots
+	// are compressed; zero uses the default and a negative value disables it
+	snapshotCompressionThreshold int
+
+	// encryption encrypts memory values at rest; nil stores them in plaintext
+	encryption *ValueEncryption
 }
 
 // NewRepository creates a new memory repository
@@ -38,7 +48,8 @@ func NewRepository(db *database.ArangoClient) (*Repository, error) {
 	}
 
 	repo := &Repository{
-		db: db,
+		db:    db,
+		clock: clock.Real(),
 	}
 
 	// Ensure collections and indexes exist
@@ -50,6 +61,38 @@ func NewRepository(db *database.ArangoClient) (*Repository, error) {
 	return repo, nil
 }
 
+// SetClock sets the clock used for timestamps and expiry
+func (r *Repository) SetClock(c clock.Clock) {
+	if c != nil {
+		r.clock = c
+	}
+}
+
+// SetSnapshotCompressionThreshold sets the state size in bytes above which
+// snapshots are stored gzip-compressed (default 64 KiB). Zero restores the
+// default and a negative value disables compression.
+func (r *Repository) SetSnapshotCompressionThreshold(bytes int) {
+	r.snapshotCompressionThreshold = bytes
+}
+
+// SetValueEncryption encrypts working and long-term memory values, the
+// working memory contents of snapshots and exchanged memory changes before
+// they are stored. Values stored in plaintext remain readable. Encrypted
+// values are left out of full-text search.
+func (r *Repository) SetValueEncryption(encryption *ValueEncryption) {
+	r.encryption = encryption
+}
+
+// compressSnapshot reports whether a snapshot state of the given size is
+// stored compressed
+func (r *Repository) compressSnapshot(size int64) bool {
+	threshold := r.snapshotCompressionThreshold
+	if threshold == 0 {
+		threshold = DefaultSnapshotCompressionThreshold
+	}
+	return threshold > 0 && size > int64(threshold)
+}
+
 // ensureCollections creates collections and indexes if they don't exist
 func (r *Repository) ensureCollections(ctx context.Context) error {
 	if r.ensuredCollections {
@@ -60,48 +103,61 @@ func (r *Repository) ensureCollections(ctx context.Context) error {
 
 	// Ensure working memory collection
 	var err error
-	r.workingMemCol, err = r.ensureCollection(ctx, db, CollectionWorkingMemory)
+	r.workingMemCol, err = r.ensureCollection(ctx, db, CollectionWorkingMemory, nil)
 	if err != nil {
 		return fmt.Errorf("failed to ensure working memory collection: %w", err)
 	}
 
 	// Ensure long-term memory collection
-	r.longtermMemCol, err = r.ensureCollection(ctx, db, CollectionLongtermMemory)
+	r.longtermMemCol, err = r.ensureCollection(ctx, db, CollectionLongtermMemory, nil)
 	if err != nil {
 		return fmt.Errorf("failed to ensure longterm memory collection: %w", err)
 	}
 
 	// Ensure snapshots collection
-	r.snapshotsCol, err = r.ensureCollection(ctx, db, CollectionSnapshots)
+	r.snapshotsCol, err = r.ensureCollection(ctx, db, CollectionSnapshots, nil)
 	if err != nil {
 		return fmt.Errorf("failed to ensure snapshots collection: %w", err)
 	}
 
 	// Ensure sync status collection
-	r.syncStatusCol, err = r.ensureCollection(ctx, db, CollectionSyncStatus)
+	r.syncStatusCol, err = r.ensureCollection(ctx, db, CollectionSyncStatus, nil)
 	if err != nil {
 		return fmt.Errorf("failed to ensure sync status collection: %w", err)
 	}
 
+	// Ensure the working memory change log, keyed by an increasing sequence
+	r.changesCol, err = r.ensureCollection(ctx, db, CollectionMemoryChanges, &driver.CreateCollectionOptions{
+		KeyOptions: &driver.CollectionKeyOptions{Type: driver.KeyGeneratorAutoIncrement},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure memory changes collection: %w", err)
+	}
+
 	// Create indexes
 	if err := r.ensureIndexes(ctx); err != nil {
 		return fmt.Errorf("failed to ensure indexes: %w", err)
 	}
 
+	// Create the free-text search view
+	if err := r.ensureSearchView(ctx); err != nil {
+		return err
+	}
+
 	r.ensuredCollections = true
-	log.Info("Memory collections and indexes ensured")
+	log.Info("Memory collections, indexes and search view ensured")
 	return nil
 }
 
 // ensureCollection creates a collection if it doesn't exist
-func (r *Repository) ensureCollection(ctx context.Context, db driver.Database, name string) (driver.Collection, error) {
+func (r *Repository) ensureCollection(ctx context.Context, db driver.Database, name string, options *driver.CreateCollectionOptions) (driver.Collection, error) {
 	exists, err := db.CollectionExists(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
 	if !exists {
-		col, err := db.CreateCollection(ctx, name, nil)
+		col, err := db.CreateCollection(ctx, name, options)
 		if err != nil {
 			return nil, err
 		}
@@ -134,6 +190,14 @@ func (r *Repository) ensureIndexes(ctx context.Context) error {
 		return err
 	}
 
+	// Change log index
+	_, _, err := r.changesCol.EnsurePersistentIndex(ctx, []string{"agent_id"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_changes_agent",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create memory changes index: %w", err)
+	}
+
 	return nil
 }
 
@@ -270,14 +334,17 @@ func (r *Repository) StoreWorking(ctx context.Context, memory *WorkingMemory) er
 		memory.ID = uuid.New().String()
 	}
 	if memory.CreatedAt.IsZero() {
-		memory.CreatedAt = time.Now()
+		memory.CreatedAt = r.clock.Now()
 	}
-	memory.UpdatedAt = time.Now()
+	memory.UpdatedAt = r.clock.Now()
 	memory.Version = 1
 
-	doc := r.workingMemoryToDocument(memory)
+	doc, err := r.workingMemoryToDocument(memory)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.workingMemCol.CreateDocument(ctx, doc)
+	_, err = r.workingMemCol.CreateDocument(ctx, doc)
 	if err != nil {
 		return fmt.Errorf("failed to store working memory: %w", err)
 	}
@@ -322,9 +389,13 @@ func (r *Repository) GetWorking(ctx context.Context, agentID, key string) (*Work
 		return nil, fmt.Errorf("failed to read working memory document: %w", err)
 	}
 
+	memory, err := r.documentToWorkingMemory(doc)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update access tracking
-	memory := r.documentToWorkingMemory(doc)
-	memory.AccessedAt = time.Now()
+	memory.AccessedAt = r.clock.Now()
 	memory.AccessCount++
 	go r.updateAccessTracking(context.Background(), memory)
 
@@ -333,10 +404,13 @@ func (r *Repository) GetWorking(ctx context.Context, agentID, key string) (*Work
 
 // UpdateWorking updates an existing working memory entry
 func (r *Repository) UpdateWorking(ctx context.Context, memory *WorkingMemory) error {
-	memory.UpdatedAt = time.Now()
+	memory.UpdatedAt = r.clock.Now()
 	memory.Version++
 
-	doc := r.workingMemoryToDocument(memory)
+	doc, err := r.workingMemoryToDocument(memory)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		FOR m IN @@collection
@@ -400,15 +474,10 @@ func (r *Repository) DeleteWorking(ctx context.Context, agentID, key string) err
 
 // ListWorking retrieves all working memory entries for an agent
 func (r *Repository) ListWorking(ctx context.Context, agentID string, filters MemoryFilters) ([]*WorkingMemory, error) {
-	query := `
-		FOR m IN @@collection
-		FILTER m.agent_id == @agent_id
-	`
-
 	bindVars := map[string]interface{}{
-		"@collection": CollectionWorkingMemory,
-		"agent_id":    agentID,
+		"agent_id": agentID,
 	}
+	query := memorySource(CollectionWorkingMemory, filters.Text, bindVars)
 
 	// Apply filters
 	if len(filters.Tags) > 0 {
@@ -433,6 +502,8 @@ func (r *Repository) ListWorking(ctx context.Context, agentID string, filters Me
 			direction = "DESC"
 		}
 		query += fmt.Sprintf(` SORT m.%s %s`, filters.SortBy, direction)
+	} else if filters.Text != "" {
+		query += ` SORT BM25(m) DESC, m.created_at DESC`
 	} else {
 		query += ` SORT m.created_at DESC`
 	}
@@ -460,7 +531,12 @@ func (r *Repository) ListWorking(ctx context.Context, agentID string, filters Me
 			log.WithError(err).Warn("Failed to read working memory document")
 			continue
 		}
-		memories = append(memories, r.documentToWorkingMemory(doc))
+		mem, err := r.documentToWorkingMemory(doc)
+		if err != nil {
+			log.WithError(err).Warn("Skipping unreadable working memory")
+			continue
+		}
+		memories = append(memories, mem)
 	}
 
 	return memories, nil
@@ -513,14 +589,17 @@ func (r *Repository) StoreLongterm(ctx context.Context, memory *LongtermMemory)
 		memory.ID = uuid.New().String()
 	}
 	if memory.CreatedAt.IsZero() {
-		memory.CreatedAt = time.Now()
+		memory.CreatedAt = r.clock.Now()
 	}
-	memory.UpdatedAt = time.Now()
+	memory.UpdatedAt = r.clock.Now()
 	memory.Version = 1
 
-	doc := r.longtermMemoryToDocument(memory)
+	doc, err := r.longtermMemoryToDocument(memory)
+	if err != nil {
+		return err
+	}
 
-	_, err := r.longtermMemCol.CreateDocument(ctx, doc)
+	_, err = r.longtermMemCol.CreateDocument(ctx, doc)
 	if err != nil {
 		return fmt.Errorf("failed to store longterm memory: %w", err)
 	}
@@ -566,9 +645,13 @@ func (r *Repository) GetLongterm(ctx context.Context, agentID, key string) (*Lon
 		return nil, fmt.Errorf("failed to read longterm memory document: %w", err)
 	}
 
+	memory, err := r.documentToLongtermMemory(doc)
+	if err != nil {
+		return nil, err
+	}
+
 	// Update access tracking
-	memory := r.documentToLongtermMemory(doc)
-	memory.LastAccessed = time.Now()
+	memory.LastAccessed = r.clock.Now()
 	memory.AccessCount++
 	go r.updateLongtermAccessTracking(context.Background(), memory)
 
@@ -577,10 +660,13 @@ func (r *Repository) GetLongterm(ctx context.Context, agentID, key string) (*Lon
 
 // UpdateLongterm updates an existing long-term memory entry
 func (r *Repository) UpdateLongterm(ctx context.Context, memory *LongtermMemory) error {
-	memory.UpdatedAt = time.Now()
+	memory.UpdatedAt = r.clock.Now()
 	memory.Version++
 
-	doc := r.longtermMemoryToDocument(memory)
+	doc, err := r.longtermMemoryToDocument(memory)
+	if err != nil {
+		return err
+	}
 
 	query := `
 		FOR m IN @@collection
@@ -644,17 +730,64 @@ func (r *Repository) DeleteLongterm(ctx context.Context, agentID, key string) er
 
 // ListLongterm retrieves long-term memory entries with filtering
 func (r *Repository) ListLongterm(ctx context.Context, agentID string, filters MemoryFilters) ([]*LongtermMemory, error) {
-	query := `
-		FOR m IN @@collection
-		FILTER m.agent_id == @agent_id
-	`
-
 	bindVars := map[string]interface{}{
-		"@collection": CollectionLongtermMemory,
-		"agent_id":    agentID,
+		"agent_id": agentID,
 	}
+	query := memorySource(CollectionLongtermMemory, filters.Text, bindVars)
+	query += longtermFilterClauses(filters, bindVars)
+
+	// Sorting
+	if filters.SortBy != "" {
+		direction := "ASC"
+		if filters.SortDesc {
+			direction = "DESC"
+		}
+		query += fmt.Sprintf(` SORT m.%s %s`, filters.SortBy, direction)
+	} else if filters.Text != "" {
+		query += ` SORT BM25(m) DESC, m.metadata.importance DESC`
+	} else {
+		query += ` SORT m.metadata.importance DESC, m.created_at DESC`
+	}
+
+	// Pagination
+	if filters.Limit > 0 {
+		query += ` LIMIT @offset, @limit`
+		bindVars["offset"] = filters.Offset
+		bindVars["limit"] = filters.Limit
+	}
+
+	query += ` RETURN m`
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list longterm memory: %w", err)
+	}
+	defer cursor.Close()
+
+	var memories []*LongtermMemory
+	for cursor.HasMore() {
+		var doc map[string]interface{}
+		_, err := cursor.ReadDocument(ctx, &doc)
+		if err != nil {
+			log.WithError(err).Warn("Failed to read longterm memory document")
+			continue
+		}
+		mem, err := r.documentToLongtermMemory(doc)
+		if err != nil {
+			log.WithError(err).Warn("Skipping unreadable longterm memory")
+			continue
+		}
+		memories = append(memories, mem)
+	}
+
+	return memories, nil
+}
+
+// longtermFilterClauses returns the AQL filters for long-term memory m and
+// adds their bind variables
+func longtermFilterClauses(filters MemoryFilters, bindVars map[string]interface{}) string {
+	var query string
 
-	// Apply filters
 	if filters.Category != "" {
 		query += ` FILTER m.category == @category`
 		bindVars["category"] = filters.Category
@@ -680,29 +813,47 @@ func (r *Repository) ListLongterm(ctx context.Context, agentID string, filters M
 		bindVars["before_time"] = filters.BeforeTime
 	}
 
-	// Sorting
-	if filters.SortBy != "" {
-		direction := "ASC"
-		if filters.SortDesc {
-			direction = "DESC"
-		}
-		query += fmt.Sprintf(` SORT m.%s %s`, filters.SortBy, direction)
-	} else {
-		query += ` SORT m.metadata.importance DESC, m.created_at DESC`
-	}
+	return query
+}
 
-	// Pagination
-	if filters.Limit > 0 {
-		query += ` LIMIT @offset, @limit`
-		bindVars["offset"] = filters.Offset
-		bindVars["limit"] = filters.Limit
+// SearchLongterm searches long-term memory. With a query embedding it returns
+// the TopK filtered memories most similar to it by cosine similarity, most
+// similar first; otherwise it lists the memories matching the filters,
+// including the free-text filter.
+func (r *Repository) SearchLongterm(ctx context.Context, agentID string, query MemoryQuery) ([]*LongtermMemory, error) {
+	if len(query.Embedding) == 0 {
+		return r.ListLongterm(ctx, agentID, query.Filters)
 	}
 
-	query += ` RETURN m`
+	topK := query.TopK
+	if topK <= 0 {
+		topK = DefaultTopK
+	}
 
-	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	bindVars := map[string]interface{}{
+		"agent_id":   agentID,
+		"dimensions": len(query.Embedding),
+		"embedding":  query.Embedding,
+		"top_k":      topK,
+	}
+	aql := memorySource(CollectionLongtermMemory, query.Filters.Text, bindVars)
+	aql += ` FILTER LENGTH(m.embedding) == @dimensions`
+	aql += longtermFilterClauses(query.Filters, bindVars)
+
+	// COSINE_SIMILARITY is null for zero vectors
+	aql += ` LET similarity = COSINE_SIMILARITY(m.embedding, @embedding)
+		FILTER similarity != null`
+	if query.MinSimilarity != 0 {
+		aql += ` FILTER similarity >= @min_similarity`
+		bindVars["min_similarity"] = query.MinSimilarity
+	}
+	aql += ` SORT similarity DESC
+		LIMIT @top_k
+		RETURN MERGE(m, { similarity: similarity })`
+
+	cursor, err := r.db.Database().Query(ctx, aql, bindVars)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list longterm memory: %w", err)
+		return nil, fmt.Errorf("failed to search longterm memory: %w", err)
 	}
 	defer cursor.Close()
 
@@ -714,19 +865,18 @@ func (r *Repository) ListLongterm(ctx context.Context, agentID string, filters M
 			log.WithError(err).Warn("Failed to read longterm memory document")
 			continue
 		}
-		memories = append(memories, r.documentToLongtermMemory(doc))
+		mem, err := r.documentToLongtermMemory(doc)
+		if err != nil {
+			log.WithError(err).Warn("Skipping unreadable longterm memory")
+			continue
+		}
+		mem.Similarity, _ = doc["similarity"].(float64)
+		memories = append(memories, mem)
 	}
 
 	return memories, nil
 }
 
-// SearchLongterm performs text-based search (basic implementation, can be enhanced)
-func (r *Repository) SearchLongterm(ctx context.Context, agentID string, query MemoryQuery) ([]*LongtermMemory, error) {
-	// For now, use tag-based and category filtering
-	// Future: implement full-text search or vector similarity search
-	return r.ListLongterm(ctx, agentID, query.Filters)
-}
-
 // ============================================================================
 // State Snapshot Operations
 // ============================================================================
@@ -737,20 +887,23 @@ func (r *Repository) CreateSnapshot(ctx context.Context, snapshot *StateSnapshot
 		snapshot.ID = uuid.New().String()
 	}
 	if snapshot.CreatedAt.IsZero() {
-		snapshot.CreatedAt = time.Now()
+		snapshot.CreatedAt = r.clock.Now()
 	}
 	snapshot.Version = 1
 
 	// Calculate checksum
-	stateJSON, err := json.Marshal(snapshot.State)
+	checksum, size, err := snapshotChecksum(snapshot.State)
 	if err != nil {
-		return fmt.Errorf("failed to marshal snapshot state: %w", err)
+		return err
 	}
-	hash := sha256.Sum256(stateJSON)
-	snapshot.Checksum = fmt.Sprintf("%x", hash)
-	snapshot.Metadata.SizeBytes = int64(len(stateJSON))
+	snapshot.Checksum = checksum
+	snapshot.Metadata.SizeBytes = size
+	snapshot.Metadata.Compressed = r.compressSnapshot(size)
 
-	doc := r.snapshotToDocument(snapshot)
+	doc, err := r.snapshotToDocument(snapshot)
+	if err != nil {
+		return err
+	}
 
 	_, err = r.snapshotsCol.CreateDocument(ctx, doc)
 	if err != nil {
@@ -762,6 +915,7 @@ func (r *Repository) CreateSnapshot(ctx context.Context, snapshot *StateSnapshot
 		"snapshot_id":   snapshot.ID,
 		"snapshot_type": snapshot.SnapshotType,
 		"size_bytes":    snapshot.Metadata.SizeBytes,
+		"stored_bytes":  snapshot.Metadata.StoredBytes,
 	}).Info("Created state snapshot")
 
 	return nil
@@ -797,7 +951,7 @@ func (r *Repository) GetSnapshot(ctx context.Context, snapshotID string) (*State
 		return nil, fmt.Errorf("failed to read snapshot document: %w", err)
 	}
 
-	return r.documentToSnapshot(doc), nil
+	return r.documentToSnapshot(doc)
 }
 
 // ListSnapshots retrieves snapshots for an agent with filtering
@@ -854,7 +1008,12 @@ func (r *Repository) ListSnapshots(ctx context.Context, agentID string, filters
 			log.WithError(err).Warn("Failed to read snapshot document")
 			continue
 		}
-		snapshots = append(snapshots, r.documentToSnapshot(doc))
+		snapshot, err := r.documentToSnapshot(doc)
+		if err != nil {
+			log.WithError(err).Warn("Failed to decompress snapshot document")
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
 	}
 
 	return snapshots, nil
@@ -957,6 +1116,77 @@ func (r *Repository) UpdateSyncStatus(ctx context.Context, status *SyncStatus) e
 	return nil
 }
 
+// ============================================================================
+// Change Exchange Operations
+// ============================================================================
+
+// PublishChanges appends working memory changes to the shared change log. The
+// collection's autoincrement key is the change sequence.
+func (r *Repository) PublishChanges(ctx context.Context, changes []*MemoryChange) error {
+	for _, change := range changes {
+		stored := *change
+		if !change.Deleted {
+			value, encrypted, err := r.sealValue(change.Value)
+			if err != nil {
+				return fmt.Errorf("failed to publish memory change: %w", err)
+			}
+			stored.Value, stored.Encrypted = value, encrypted
+		}
+
+		meta, err := r.changesCol.CreateDocument(ctx, &stored)
+		if err != nil {
+			return fmt.Errorf("failed to publish memory change: %w", err)
+		}
+
+		var sequence int64
+		if _, err := fmt.Sscan(meta.Key, &sequence); err != nil {
+			return fmt.Errorf("invalid memory change key %q: %w", meta.Key, err)
+		}
+		change.Sequence = sequence
+	}
+	return nil
+}
+
+// ChangesSince returns an agent's working memory changes after a sequence
+func (r *Repository) ChangesSince(ctx context.Context, agentID string, after int64) ([]*MemoryChange, error) {
+	query := `
+		FOR c IN @@collection
+		LET sequence = TO_NUMBER(c._key)
+		FILTER c.agent_id == @agent_id AND sequence > @after
+		SORT sequence ASC
+		RETURN MERGE(c, { sequence: sequence })
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": CollectionMemoryChanges,
+		"agent_id":    agentID,
+		"after":       after,
+	}
+
+	cursor, err := r.db.Database().Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memory changes: %w", err)
+	}
+	defer cursor.Close()
+
+	var changes []*MemoryChange
+	for cursor.HasMore() {
+		var change MemoryChange
+		if _, err := cursor.ReadDocument(ctx, &change); err != nil {
+			return nil, fmt.Errorf("failed to read memory change: %w", err)
+		}
+		if change.Encrypted {
+			value, err := r.openValue(change.Value, true)
+			if err != nil {
+				return nil, fmt.Errorf("memory change %d: %w", change.Sequence, err)
+			}
+			change.Value, change.Encrypted = value, false
+		}
+		changes = append(changes, &change)
+	}
+	return changes, nil
+}
+
 // ============================================================================
 // Maintenance Operations
 // ============================================================================
@@ -976,7 +1206,7 @@ func (r *Repository) CleanupExpired(ctx context.Context) (int, error) {
 
 	bindVars := map[string]interface{}{
 		"@collection": CollectionWorkingMemory,
-		"now":         time.Now(),
+		"now":         r.clock.Now(),
 	}
 
 	cursor, err := r.db.Database().Query(ctx, workingQuery, bindVars)
@@ -1128,28 +1358,50 @@ func (r *Repository) GetMemoryStats(ctx context.Context, agentID string) (*Memor
 // Helper Methods - Document Conversion
 // ============================================================================
 
-func (r *Repository) workingMemoryToDocument(m *WorkingMemory) map[string]interface{} {
-	return map[string]interface{}{
-		"id":           m.ID,
-		"agent_id":     m.AgentID,
-		"key":          m.Key,
-		"value":        m.Value,
-		"metadata":     m.Metadata,
-		"created_at":   m.CreatedAt,
-		"updated_at":   m.UpdatedAt,
-		"accessed_at":  m.AccessedAt,
-		"access_count": m.AccessCount,
-		"expires_at":   m.ExpiresAt,
-		"version":      m.Version,
+// workingMemoryToDocument converts working memory to a document, encrypting
+// its value if value encryption is set
+func (r *Repository) workingMemoryToDocument(m *WorkingMemory) (map[string]interface{}, error) {
+	value, encrypted, err := r.sealValue(m.Value)
+	if err != nil {
+		return nil, fmt.Errorf("working memory %s: %w", m.Key, err)
+	}
+	text := m.Value
+	if encrypted {
+		text = nil
 	}
+
+	return map[string]interface{}{
+		"id":              m.ID,
+		"agent_id":        m.AgentID,
+		"key":             m.Key,
+		"value":           value,
+		"value_encrypted": encrypted,
+		"search_text":     searchText(m.Key, text),
+		"metadata":        m.Metadata,
+		"created_at":      m.CreatedAt,
+		"updated_at":      m.UpdatedAt,
+		"accessed_at":     m.AccessedAt,
+		"access_count":    m.AccessCount,
+		"expires_at":      m.ExpiresAt,
+		"version":         m.Version,
+		"schema_version":  m.SchemaVersion,
+	}, nil
 }
 
-func (r *Repository) documentToWorkingMemory(doc map[string]interface{}) *WorkingMemory {
+// documentToWorkingMemory converts a document to working memory, decrypting
+// its value if needed
+func (r *Repository) documentToWorkingMemory(doc map[string]interface{}) (*WorkingMemory, error) {
 	m := &WorkingMemory{}
 	m.ID, _ = doc["id"].(string)
 	m.AgentID, _ = doc["agent_id"].(string)
 	m.Key, _ = doc["key"].(string)
-	m.Value = doc["value"]
+
+	encrypted, _ := doc["value_encrypted"].(bool)
+	value, err := r.openValue(doc["value"], encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("working memory %s/%s: %w", m.AgentID, m.Key, err)
+	}
+	m.Value = value
 	m.Metadata, _ = doc["metadata"].(map[string]interface{})
 	m.CreatedAt, _ = parseTime(doc["created_at"])
 	m.UpdatedAt, _ = parseTime(doc["updated_at"])
@@ -1165,37 +1417,62 @@ func (r *Repository) documentToWorkingMemory(doc map[string]interface{}) *Workin
 	if version, ok := doc["version"].(float64); ok {
 		m.Version = int(version)
 	}
+	if schemaVersion, ok := doc["schema_version"].(float64); ok {
+		m.SchemaVersion = int(schemaVersion)
+	}
 
 	if m.Metadata == nil {
 		m.Metadata = make(map[string]interface{})
 	}
-	return m
+	return m, nil
 }
 
-func (r *Repository) longtermMemoryToDocument(m *LongtermMemory) map[string]interface{} {
-	return map[string]interface{}{
-		"id":            m.ID,
-		"agent_id":      m.AgentID,
-		"category":      m.Category,
-		"key":           m.Key,
-		"value":         m.Value,
-		"embedding":     m.Embedding,
-		"metadata":      m.Metadata,
-		"created_at":    m.CreatedAt,
-		"updated_at":    m.UpdatedAt,
-		"last_accessed": m.LastAccessed,
-		"access_count":  m.AccessCount,
-		"version":       m.Version,
+// longtermMemoryToDocument converts long-term memory to a document,
+// encrypting its value if value encryption is set
+func (r *Repository) longtermMemoryToDocument(m *LongtermMemory) (map[string]interface{}, error) {
+	value, encrypted, err := r.sealValue(m.Value)
+	if err != nil {
+		return nil, fmt.Errorf("longterm memory %s: %w", m.Key, err)
+	}
+	text := m.Value
+	if encrypted {
+		text = nil
 	}
+
+	return map[string]interface{}{
+		"id":              m.ID,
+		"agent_id":        m.AgentID,
+		"category":        m.Category,
+		"key":             m.Key,
+		"value":           value,
+		"value_encrypted": encrypted,
+		"search_text":     searchText(m.Key, text),
+		"embedding":       m.Embedding,
+		"metadata":        m.Metadata,
+		"created_at":      m.CreatedAt,
+		"updated_at":      m.UpdatedAt,
+		"last_accessed":   m.LastAccessed,
+		"access_count":    m.AccessCount,
+		"version":         m.Version,
+		"schema_version":  m.SchemaVersion,
+	}, nil
 }
 
-func (r *Repository) documentToLongtermMemory(doc map[string]interface{}) *LongtermMemory {
+// documentToLongtermMemory converts a document to long-term memory,
+// decrypting its value if needed
+func (r *Repository) documentToLongtermMemory(doc map[string]interface{}) (*LongtermMemory, error) {
 	m := &LongtermMemory{}
 	m.ID, _ = doc["id"].(string)
 	m.AgentID, _ = doc["agent_id"].(string)
 	m.Category, _ = doc["category"].(string)
 	m.Key, _ = doc["key"].(string)
-	m.Value = doc["value"]
+
+	encrypted, _ := doc["value_encrypted"].(bool)
+	value, err := r.openValue(doc["value"], encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("longterm memory %s/%s: %w", m.AgentID, m.Key, err)
+	}
+	m.Value = value
 
 	// Parse embedding if present
 	if embeddingData, ok := doc["embedding"].([]interface{}); ok {
@@ -1241,25 +1518,52 @@ func (r *Repository) documentToLongtermMemory(doc map[string]interface{}) *Longt
 	if version, ok := doc["version"].(float64); ok {
 		m.Version = int(version)
 	}
+	if schemaVersion, ok := doc["schema_version"].(float64); ok {
+		m.SchemaVersion = int(schemaVersion)
+	}
 
-	return m
+	return m, nil
 }
 
-func (r *Repository) snapshotToDocument(s *StateSnapshot) map[string]interface{} {
-	return map[string]interface{}{
+// snapshotToDocument converts a snapshot to a document. A compressed
+// snapshot's state is stored gzipped and base64 encoded in state_compressed.
+// With value encryption, the working memory values in the state are
+// encrypted first.
+func (r *Repository) snapshotToDocument(s *StateSnapshot) (map[string]interface{}, error) {
+	state, err := r.sealSnapshotState(s.State)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", s.ID, err)
+	}
+
+	doc := map[string]interface{}{
 		"id":            s.ID,
 		"agent_id":      s.AgentID,
 		"snapshot_type": s.SnapshotType,
-		"state":         s.State,
 		"checksum":      s.Checksum,
-		"metadata":      s.Metadata,
 		"created_at":    s.CreatedAt,
 		"expires_at":    s.ExpiresAt,
 		"version":       s.Version,
 	}
+
+	s.Metadata.StoredBytes = s.Metadata.SizeBytes
+	if s.Metadata.Compressed {
+		compressed, err := compressSnapshotState(state)
+		if err != nil {
+			return nil, err
+		}
+		s.Metadata.StoredBytes = int64(len(compressed))
+		doc["state_compressed"] = compressed
+	} else {
+		doc["state"] = state
+	}
+	doc["metadata"] = s.Metadata
+
+	return doc, nil
 }
 
-func (r *Repository) documentToSnapshot(doc map[string]interface{}) *StateSnapshot {
+// documentToSnapshot converts a document to a snapshot, decompressing and
+// decrypting its state if needed
+func (r *Repository) documentToSnapshot(doc map[string]interface{}) (*StateSnapshot, error) {
 	s := &StateSnapshot{}
 	s.ID, _ = doc["id"].(string)
 	s.AgentID, _ = doc["agent_id"].(string)
@@ -1274,9 +1578,24 @@ func (r *Repository) documentToSnapshot(doc map[string]interface{}) *StateSnapsh
 		if sizeBytes, ok := metadataDoc["size_bytes"].(float64); ok {
 			s.Metadata.SizeBytes = int64(sizeBytes)
 		}
+		if storedBytes, ok := metadataDoc["stored_bytes"].(float64); ok {
+			s.Metadata.StoredBytes = int64(storedBytes)
+		}
 		s.Metadata.Compressed, _ = metadataDoc["compressed"].(bool)
 	}
 
+	if s.Metadata.Compressed {
+		compressed, _ := doc["state_compressed"].(string)
+		state, err := decompressSnapshotState(compressed)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %w", s.ID, err)
+		}
+		s.State = state
+	}
+	if err := r.openSnapshotState(s.State); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", s.ID, err)
+	}
+
 	s.CreatedAt, _ = parseTime(doc["created_at"])
 	s.ExpiresAt, _ = parseTime(doc["expires_at"])
 	if version, ok := doc["version"].(float64); ok {
@@ -1287,7 +1606,7 @@ func (r *Repository) documentToSnapshot(doc map[string]interface{}) *StateSnapsh
 		s.State = make(map[string]interface{})
 	}
 
-	return s
+	return s, nil
 }
 
 func (r *Repository) syncStatusToDocument(s *SyncStatus) map[string]interface{} {
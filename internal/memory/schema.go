@@ -0,0 +1,283 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BaseSchemaVersion is the schema version of memory values written before any
+// migration was registered for their key. Entries without a schema version are
+// treated as this version.
+const BaseSchemaVersion = 1
+
+// UpconvertFunc converts a memory value from one schema version to the next
+type UpconvertFunc func(value interface{}) (interface{}, error)
+
+// MigrationStats reports the lazy migrations performed for a key pattern
+type MigrationStats struct {
+	// AgentID scopes the migrations to one agent; empty applies to all agents
+	AgentID string `json:"agent_id,omitempty"`
+
+	// KeyPattern is the glob matched against memory keys
+	KeyPattern string `json:"key_pattern"`
+
+	// CurrentVersion is the schema version values are migrated to
+	CurrentVersion int `json:"current_version"`
+
+	// Migrated counts values upconverted on read
+	Migrated int64 `json:"migrated"`
+
+	// Failed counts values whose upconversion failed
+	Failed int64 `json:"failed"`
+
+	// LastMigratedAt is when a value was last upconverted
+	LastMigratedAt *time.Time `json:"last_migrated_at,omitempty"`
+}
+
+// keySchema holds the upconversion chain registered for a key pattern
+type keySchema struct {
+	steps map[int]UpconvertFunc
+	stats MigrationStats
+}
+
+// SchemaRegistry holds the upconversion functions agents register for their
+// memory values. Migrations are applied lazily when values are read.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas []*keySchema
+}
+
+// NewSchemaRegistry creates an empty schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{}
+}
+
+// RegisterMigration registers fn to upconvert values of keys matching
+// keyPattern from fromVersion to fromVersion+1. An empty agentID applies the
+// migration to every agent. The key's current version becomes the highest
+// version reachable through its registered migrations.
+func (r *SchemaRegistry) RegisterMigration(agentID, keyPattern string, fromVersion int, fn UpconvertFunc) error {
+	if keyPattern == "" {
+		return fmt.Errorf("key pattern is required")
+	}
+	if _, err := filepath.Match(keyPattern, ""); err != nil {
+		return fmt.Errorf("invalid key pattern %q: %w", keyPattern, err)
+	}
+	if fromVersion < BaseSchemaVersion {
+		return fmt.Errorf("from version must be at least %d", BaseSchemaVersion)
+	}
+	if fn == nil {
+		return fmt.Errorf("upconvert function is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schema := r.lookupExact(agentID, keyPattern)
+	if schema == nil {
+		schema = &keySchema{
+			steps: make(map[int]UpconvertFunc),
+			stats: MigrationStats{AgentID: agentID, KeyPattern: keyPattern, CurrentVersion: BaseSchemaVersion},
+		}
+		r.schemas = append(r.schemas, schema)
+	}
+
+	if _, exists := schema.steps[fromVersion]; exists {
+		return fmt.Errorf("migration from version %d already registered for %q", fromVersion, keyPattern)
+	}
+	schema.steps[fromVersion] = fn
+	if fromVersion+1 > schema.stats.CurrentVersion {
+		schema.stats.CurrentVersion = fromVersion + 1
+	}
+
+	return nil
+}
+
+// CurrentVersion returns the schema version new values of a key are written
+// with, or 0 when no migration is registered for the key
+func (r *SchemaRegistry) CurrentVersion(agentID, key string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schema := r.lookup(agentID, key)
+	if schema == nil {
+		return 0
+	}
+	return schema.stats.CurrentVersion
+}
+
+// Migrate upconverts a value stored at version to the key's current version.
+// It reports whether the value was migrated.
+func (r *SchemaRegistry) Migrate(agentID, key string, version int, value interface{}) (interface{}, int, bool, error) {
+	r.mu.RLock()
+	schema := r.lookup(agentID, key)
+	r.mu.RUnlock()
+
+	if schema == nil {
+		return value, version, false, nil
+	}
+	if version < BaseSchemaVersion {
+		version = BaseSchemaVersion
+	}
+
+	r.mu.RLock()
+	current := schema.stats.CurrentVersion
+	r.mu.RUnlock()
+	if version >= current {
+		return value, version, false, nil
+	}
+
+	migrated := value
+	for v := version; v < current; v++ {
+		r.mu.RLock()
+		step, ok := schema.steps[v]
+		r.mu.RUnlock()
+
+		var err error
+		if !ok {
+			err = fmt.Errorf("no migration registered from version %d", v)
+		} else {
+			migrated, err = step(migrated)
+		}
+		if err != nil {
+			r.mu.Lock()
+			schema.stats.Failed++
+			r.mu.Unlock()
+			return value, version, false, fmt.Errorf("failed to migrate %q from version %d: %w", key, v, err)
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	schema.stats.Migrated++
+	schema.stats.LastMigratedAt = &now
+	r.mu.Unlock()
+
+	return migrated, current, true, nil
+}
+
+// Stats returns migration counts for the schemas that apply to an agent, or
+// for every schema when agentID is empty
+func (r *SchemaRegistry) Stats(agentID string) []MigrationStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make([]MigrationStats, 0, len(r.schemas))
+	for _, schema := range r.schemas {
+		if agentID != "" && schema.stats.AgentID != "" && schema.stats.AgentID != agentID {
+			continue
+		}
+		stats = append(stats, schema.stats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].AgentID != stats[j].AgentID {
+			return stats[i].AgentID < stats[j].AgentID
+		}
+		return stats[i].KeyPattern < stats[j].KeyPattern
+	})
+	return stats
+}
+
+// lookup returns the schema for a key. Agent-specific schemas take precedence
+// over global ones, then the longest matching pattern wins. Callers hold the lock.
+func (r *SchemaRegistry) lookup(agentID, key string) *keySchema {
+	var best *keySchema
+	for _, schema := range r.schemas {
+		if schema.stats.AgentID != "" && schema.stats.AgentID != agentID {
+			continue
+		}
+		if ok, _ := filepath.Match(schema.stats.KeyPattern, key); !ok {
+			continue
+		}
+		if best == nil || moreSpecific(schema, best) {
+			best = schema
+		}
+	}
+	return best
+}
+
+// lookupExact returns the schema registered for exactly agentID and keyPattern.
+// Callers hold the lock.
+func (r *SchemaRegistry) lookupExact(agentID, keyPattern string) *keySchema {
+	for _, schema := range r.schemas {
+		if schema.stats.AgentID == agentID && schema.stats.KeyPattern == keyPattern {
+			return schema
+		}
+	}
+	return nil
+}
+
+func moreSpecific(a, b *keySchema) bool {
+	if (a.stats.AgentID != "") != (b.stats.AgentID != "") {
+		return a.stats.AgentID != ""
+	}
+	return len(a.stats.KeyPattern) > len(b.stats.KeyPattern)
+}
+
+// migrateWorking upconverts a working memory value to its key's current schema
+// version and writes it back. A failed write-back is logged; the migrated value
+// is still returned and the migration is retried on the next read.
+func (s *Service) migrateWorking(ctx context.Context, mem *WorkingMemory) error {
+	value, version, migrated, err := s.schemas.Migrate(mem.AgentID, mem.Key, mem.SchemaVersion, mem.Value)
+	if err != nil {
+		return err
+	}
+	if !migrated {
+		return nil
+	}
+
+	mem.Value = value
+	mem.SchemaVersion = version
+	if err := s.repo.UpdateWorking(ctx, mem); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"agent_id": mem.AgentID,
+			"key":      mem.Key,
+		}).Warn("Failed to store migrated working memory")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"agent_id":       mem.AgentID,
+		"key":            mem.Key,
+		"schema_version": version,
+	}).Debug("Migrated working memory")
+
+	return nil
+}
+
+// migrateLongterm upconverts a long-term memory value to its key's current
+// schema version and writes it back
+func (s *Service) migrateLongterm(ctx context.Context, mem *LongtermMemory) error {
+	value, version, migrated, err := s.schemas.Migrate(mem.AgentID, mem.Key, mem.SchemaVersion, mem.Value)
+	if err != nil {
+		return err
+	}
+	if !migrated {
+		return nil
+	}
+
+	mem.Value = value
+	mem.SchemaVersion = version
+	if err := s.repo.UpdateLongterm(ctx, mem); err != nil {
+		log.WithError(err).WithFields(log.Fields{
+			"agent_id": mem.AgentID,
+			"key":      mem.Key,
+		}).Warn("Failed to store migrated long-term memory")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"agent_id":       mem.AgentID,
+		"key":            mem.Key,
+		"schema_version": version,
+	}).Debug("Migrated long-term memory")
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestService_LazySchemaMigration(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository()
+	service := NewService(repo)
+
+	// Values written before the schema changed have no schema version
+	if err := repo.StoreLongterm(ctx, &LongtermMemory{
+		AgentID: "pump-001", Key: "calibration.flow", Category: "fact", Value: 1.5,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StoreWorking(ctx, &WorkingMemory{
+		AgentID: "pump-001", Key: "calibration.pressure", Value: 3.0,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// v1 stored a bare number, v2 a map with units, v3 adds a source
+	schemas := service.Schemas()
+	if err := schemas.RegisterMigration("pump-001", "calibration.*", 1, func(v interface{}) (interface{}, error) {
+		return map[string]interface{}{"value": v, "unit": "bar"}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schemas.RegisterMigration("pump-001", "calibration.*", 2, func(v interface{}) (interface{}, error) {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %T", v)
+		}
+		m["source"] = "legacy"
+		return m, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schemas.RegisterMigration("pump-001", "calibration.*", 2, func(v interface{}) (interface{}, error) { return v, nil }); err == nil {
+		t.Error("expected duplicate migration to be rejected")
+	}
+
+	value, err := service.Recall(ctx, "pump-001", "calibration.flow")
+	if err != nil {
+		t.Fatalf("Recall failed: %v", err)
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok || m["value"] != 1.5 || m["unit"] != "bar" || m["source"] != "legacy" {
+		t.Fatalf("unexpected migrated value %#v", value)
+	}
+
+	stored, _ := repo.GetLongterm(ctx, "pump-001", "calibration.flow")
+	if stored.SchemaVersion != 3 {
+		t.Errorf("expected migrated value to be stored at version 3, got %d", stored.SchemaVersion)
+	}
+
+	// Reading again does not migrate twice
+	if _, err := service.Recall(ctx, "pump-001", "calibration.flow"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.RetrieveWorking(ctx, "pump-001", "calibration.pressure"); err != nil {
+		t.Fatalf("RetrieveWorking failed: %v", err)
+	}
+
+	// New values are written at the current version
+	if err := service.Remember(ctx, "pump-001", "calibration.level", map[string]interface{}{"value": 2.0}, "fact", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Recall(ctx, "pump-001", "calibration.level"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := schemas.Stats("pump-001")
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 schema, got %d", len(stats))
+	}
+	if stats[0].CurrentVersion != 3 || stats[0].Migrated != 2 || stats[0].Failed != 0 {
+		t.Errorf("unexpected migration stats %+v", stats[0])
+	}
+
+	// Other agents are not affected by pump-001's migrations
+	if got := schemas.CurrentVersion("pump-002", "calibration.flow"); got != 0 {
+		t.Errorf("expected no schema for another agent, got version %d", got)
+	}
+}
+
+func TestSchemaRegistry_FailedMigration(t *testing.T) {
+	schemas := NewSchemaRegistry()
+	if err := schemas.RegisterMigration("", "settings", 1, func(v interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("cannot convert %v", v)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	value, version, migrated, err := schemas.Migrate("pump-001", "settings", 0, "old")
+	if err == nil || migrated || value != "old" || version != BaseSchemaVersion {
+		t.Errorf("expected failed migration to keep the original value, got %v %d %v %v", value, version, migrated, err)
+	}
+	if stats := schemas.Stats(""); stats[0].Failed != 1 {
+		t.Errorf("expected 1 failed migration, got %d", stats[0].Failed)
+	}
+
+	if err := schemas.RegisterMigration("", "[", 1, func(v interface{}) (interface{}, error) { return v, nil }); err == nil {
+		t.Error("expected invalid key pattern to be rejected")
+	}
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ViewMemorySearch is the ArangoSearch view over working and long-term memory
+	ViewMemorySearch = "agent_memory_search"
+
+	// searchAnalyzer is the built-in analyzer used for memory text: it
+	// tokenizes, lower-cases, removes accents and stems English words
+	searchAnalyzer = "text_en"
+)
+
+// searchText flattens a memory's key and value into the text indexed for
+// free-text search: every string, number and boolean in the value, and the
+// keys of nested objects, in a stable order
+func searchText(key string, value interface{}) string {
+	parts := []string{key}
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			parts = append(parts, v)
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				parts = append(parts, k)
+				collect(v[k])
+			}
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
+			}
+		case []string:
+			parts = append(parts, v...)
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	collect(value)
+	return strings.Join(parts, " ")
+}
+
+// matchesText reports whether every word of the query occurs in the text,
+// ignoring case. It approximates the view's search for in-memory repositories.
+func matchesText(query, text string) bool {
+	words := make(map[string]bool)
+	for _, word := range searchWords(text) {
+		words[word] = true
+	}
+	for _, word := range searchWords(query) {
+		if !words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ensureSearchView creates the ArangoSearch view indexing the search text of
+// both memory collections, and the agent ID for per-agent searches
+func (r *Repository) ensureSearchView(ctx context.Context) error {
+	db := r.db.Database()
+
+	exists, err := db.ViewExists(ctx, ViewMemorySearch)
+	if err != nil {
+		return fmt.Errorf("failed to check memory search view: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	link := driver.ArangoSearchElementProperties{
+		Fields: driver.ArangoSearchFields{
+			"agent_id":    {Analyzers: []string{"identity"}},
+			"search_text": {Analyzers: []string{searchAnalyzer}},
+		},
+	}
+	_, err = db.CreateArangoSearchView(ctx, ViewMemorySearch, &driver.ArangoSearchViewProperties{
+		Links: driver.ArangoSearchLinks{
+			CollectionWorkingMemory:  link,
+			CollectionLongtermMemory: link,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create memory search view: %w", err)
+	}
+
+	log.WithField("view", ViewMemorySearch).Info("Created view")
+	return nil
+}
+
+// memorySource returns the start of a query over an agent's memories in the
+// collection. With a free-text query it searches the view for memories
+// containing every word of the text; the caller must add the agent_id bind
+// variable.
+func memorySource(collection string, text string, bindVars map[string]interface{}) string {
+	if text == "" {
+		bindVars["@collection"] = collection
+		return `
+		FOR m IN @@collection
+		FILTER m.agent_id == @agent_id
+	`
+	}
+
+	bindVars["search_collection"] = collection
+	bindVars["text"] = text
+	return fmt.Sprintf(`
+		FOR m IN %s
+		SEARCH m.agent_id == @agent_id
+			AND ANALYZER(TOKENS(@text, "%s") ALL == m.search_text, "%s")
+		OPTIONS { collections: [@search_collection] }
+	`, ViewMemorySearch, searchAnalyzer, searchAnalyzer)
+}
@@ -5,49 +5,82 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 // Service implements the MemoryService interface
 type Service struct {
-	repo MemoryRepository
+	repo    MemoryRepository
+	schemas *SchemaRegistry
+	clock   clock.Clock
+	quotas  workingQuotas
+	audit   AuditLog
 }
 
 // NewService creates a new memory service
 func NewService(repo MemoryRepository) *Service {
 	return &Service{
-		repo: repo,
+		repo:    repo,
+		schemas: NewSchemaRegistry(),
+		clock:   clock.Real(),
 	}
 }
 
+// SetClock sets the clock used for TTLs, timestamps and sync scheduling
+func (s *Service) SetClock(c clock.Clock) {
+	if c != nil {
+		s.clock = c
+	}
+}
+
+// Schemas returns the registry of memory value migrations
+func (s *Service) Schemas() *SchemaRegistry {
+	return s.schemas
+}
+
 // ============================================================================
 // Working Memory Operations
 // ============================================================================
 
 // StoreWorking stores a value in working memory with TTL
 func (s *Service) StoreWorking(ctx context.Context, agentID, key string, value interface{}, ttl time.Duration) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
 	if key == "" {
 		return fmt.Errorf("key is required")
 	}
+	if err := s.checkWorkingQuota(agentID, value); err != nil {
+		return err
+	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	mem := &WorkingMemory{
 		AgentID:   agentID,
 		Key:       key,
 		Value:     value,
 		Metadata:  make(map[string]interface{}),
 		ExpiresAt: now.Add(ttl),
+
+		SchemaVersion: s.schemas.CurrentVersion(agentID, key),
 	}
 
 	err := s.repo.StoreWorking(ctx, mem)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, key, AuditOpWrite, err)
 	if err != nil {
 		return fmt.Errorf("failed to store working memory: %w", err)
 	}
 
+	if err := s.enforceWorkingQuota(ctx, agentID, key); err != nil {
+		log.WithError(err).WithField("agent_id", agentID).Warn("Failed to enforce working memory quota")
+	}
+
 	log.WithFields(log.Fields{
 		"agent_id": agentID,
 		"key":      key,
@@ -59,6 +92,9 @@ func (s *Service) StoreWorking(ctx context.Context, agentID, key string, value i
 
 // RetrieveWorking retrieves a value from working memory
 func (s *Service) RetrieveWorking(ctx context.Context, agentID, key string) (interface{}, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -67,22 +103,30 @@ func (s *Service) RetrieveWorking(ctx context.Context, agentID, key string) (int
 	}
 
 	mem, err := s.repo.GetWorking(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, key, AuditOpRead, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve working memory: %w", err)
 	}
 
 	// Check if expired
-	if time.Now().After(mem.ExpiresAt) {
+	if s.clock.Now().After(mem.ExpiresAt) {
 		// Clean up expired memory
 		go s.repo.DeleteWorking(context.Background(), agentID, key)
 		return nil, fmt.Errorf("memory expired")
 	}
 
+	if err := s.migrateWorking(ctx, mem); err != nil {
+		return nil, err
+	}
+
 	return mem.Value, nil
 }
 
 // UpdateWorking updates an existing working memory value
 func (s *Service) UpdateWorking(ctx context.Context, agentID, key string, value interface{}) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
@@ -90,6 +134,10 @@ func (s *Service) UpdateWorking(ctx context.Context, agentID, key string, value
 		return fmt.Errorf("key is required")
 	}
 
+	if err := s.checkWorkingQuota(agentID, value); err != nil {
+		return err
+	}
+
 	// Get existing memory
 	mem, err := s.repo.GetWorking(ctx, agentID, key)
 	if err != nil {
@@ -98,13 +146,19 @@ func (s *Service) UpdateWorking(ctx context.Context, agentID, key string, value
 
 	// Update value
 	mem.Value = value
-	mem.UpdatedAt = time.Now()
+	mem.UpdatedAt = s.clock.Now()
+	mem.SchemaVersion = s.schemas.CurrentVersion(agentID, key)
 
 	err = s.repo.UpdateWorking(ctx, mem)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, key, AuditOpUpdate, err)
 	if err != nil {
 		return fmt.Errorf("failed to update working memory: %w", err)
 	}
 
+	if err := s.enforceWorkingQuota(ctx, agentID, key); err != nil {
+		log.WithError(err).WithField("agent_id", agentID).Warn("Failed to enforce working memory quota")
+	}
+
 	log.WithFields(log.Fields{
 		"agent_id": agentID,
 		"key":      key,
@@ -115,6 +169,9 @@ func (s *Service) UpdateWorking(ctx context.Context, agentID, key string, value
 
 // DeleteWorking deletes a working memory entry
 func (s *Service) DeleteWorking(ctx context.Context, agentID, key string) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
@@ -123,6 +180,7 @@ func (s *Service) DeleteWorking(ctx context.Context, agentID, key string) error
 	}
 
 	err := s.repo.DeleteWorking(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, key, AuditOpDelete, err)
 	if err != nil {
 		return fmt.Errorf("failed to delete working memory: %w", err)
 	}
@@ -137,11 +195,15 @@ func (s *Service) DeleteWorking(ctx context.Context, agentID, key string) error
 
 // ClearWorking removes all working memory for an agent
 func (s *Service) ClearWorking(ctx context.Context, agentID string) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
 
 	err := s.repo.ClearWorking(ctx, agentID)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, "", AuditOpClear, err)
 	if err != nil {
 		return fmt.Errorf("failed to clear working memory: %w", err)
 	}
@@ -153,15 +215,25 @@ func (s *Service) ClearWorking(ctx context.Context, agentID string) error {
 
 // ListWorking lists working memory entries with optional filters
 func (s *Service) ListWorking(ctx context.Context, agentID string, filters MemoryFilters) ([]*WorkingMemory, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
 
 	memories, err := s.repo.ListWorking(ctx, agentID, filters)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, "", AuditOpRead, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list working memory: %w", err)
 	}
 
+	for _, mem := range memories {
+		if err := s.migrateWorking(ctx, mem); err != nil {
+			log.WithError(err).WithField("agent_id", agentID).Warn("Returning unmigrated working memory")
+		}
+	}
+
 	return memories, nil
 }
 
@@ -171,6 +243,9 @@ func (s *Service) ListWorking(ctx context.Context, agentID string, filters Memor
 
 // Remember stores a value in long-term memory with metadata
 func (s *Service) Remember(ctx context.Context, agentID, key string, value interface{}, category string, metadata map[string]interface{}) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
@@ -189,6 +264,9 @@ func (s *Service) Remember(ctx context.Context, agentID, key string, value inter
 		Tags:       []string{},
 	}
 
+	// An embedding makes the memory searchable by vector similarity
+	var embedding []float64
+
 	if metadata != nil {
 		if source, ok := metadata["source"].(string); ok {
 			memMetadata.Source = source
@@ -205,17 +283,22 @@ func (s *Service) Remember(ctx context.Context, agentID, key string, value inter
 		if refs, ok := metadata["references"].([]string); ok {
 			memMetadata.References = refs
 		}
+		embedding, _ = metadata["embedding"].([]float64)
 	}
 
 	mem := &LongtermMemory{
-		AgentID:  agentID,
-		Category: category,
-		Key:      key,
-		Value:    value,
-		Metadata: memMetadata,
+		AgentID:   agentID,
+		Category:  category,
+		Key:       key,
+		Value:     value,
+		Embedding: embedding,
+		Metadata:  memMetadata,
+
+		SchemaVersion: s.schemas.CurrentVersion(agentID, key),
 	}
 
 	err := s.repo.StoreLongterm(ctx, mem)
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, key, AuditOpWrite, err)
 	if err != nil {
 		return fmt.Errorf("failed to remember: %w", err)
 	}
@@ -232,6 +315,9 @@ func (s *Service) Remember(ctx context.Context, agentID, key string, value inter
 
 // Recall retrieves a value from long-term memory
 func (s *Service) Recall(ctx context.Context, agentID, key string) (interface{}, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -240,30 +326,60 @@ func (s *Service) Recall(ctx context.Context, agentID, key string) (interface{},
 	}
 
 	mem, err := s.repo.GetLongterm(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, key, AuditOpRead, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to recall: %w", err)
 	}
 
+	if err := s.migrateLongterm(ctx, mem); err != nil {
+		return nil, err
+	}
+
 	return mem.Value, nil
 }
 
-// Search searches long-term memory based on query criteria
+// Search searches long-term memory based on query criteria. A query with an
+// embedding returns the most semantically similar memories first.
 func (s *Service) Search(ctx context.Context, agentID string, query MemoryQuery) ([]*LongtermMemory, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
+	if err := validateVectorQuery(&query); err != nil {
+		return nil, err
+	}
+	if query.Filters.Text == "" {
+		query.Filters.Text = query.Query
+	}
 
 	// Use the repository's search (or list with filters)
 	memories, err := s.repo.SearchLongterm(ctx, agentID, query)
 	if err != nil {
+		s.recordAccess(ctx, agentID, MemoryTypeLongterm, "", AuditOpSearch, err)
 		return nil, fmt.Errorf("failed to search memory: %w", err)
 	}
 
+	// Every memory a search returns counts as consulted
+	for _, mem := range memories {
+		s.recordAccess(ctx, agentID, MemoryTypeLongterm, mem.Key, AuditOpSearch, nil)
+	}
+
+	for _, mem := range memories {
+		if err := s.migrateLongterm(ctx, mem); err != nil {
+			log.WithError(err).WithField("agent_id", agentID).Warn("Returning unmigrated long-term memory")
+		}
+	}
+
 	return memories, nil
 }
 
 // Forget removes a long-term memory entry
 func (s *Service) Forget(ctx context.Context, agentID, key string) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
@@ -272,6 +388,7 @@ func (s *Service) Forget(ctx context.Context, agentID, key string) error {
 	}
 
 	err := s.repo.DeleteLongterm(ctx, agentID, key)
+	s.recordAccess(ctx, agentID, MemoryTypeLongterm, key, AuditOpDelete, err)
 	if err != nil {
 		return fmt.Errorf("failed to forget: %w", err)
 	}
@@ -286,6 +403,9 @@ func (s *Service) Forget(ctx context.Context, agentID, key string) error {
 
 // Archive moves old or low-importance memories to archive/deletion
 func (s *Service) Archive(ctx context.Context, agentID string, criteria ArchiveCriteria) error {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return err
+	}
 	if agentID == "" {
 		return fmt.Errorf("agent ID is required")
 	}
@@ -300,7 +420,7 @@ func (s *Service) Archive(ctx context.Context, agentID string, criteria ArchiveC
 
 			// Get memories matching criteria
 			if criteria.OlderThan > 0 {
-				olderThan := time.Now().Add(-criteria.OlderThan)
+				olderThan := s.clock.Now().Add(-criteria.OlderThan)
 				filters.BeforeTime = &olderThan
 			}
 
@@ -352,7 +472,7 @@ func (s *Service) Archive(ctx context.Context, agentID string, criteria ArchiveC
 	} else {
 		// Archive all categories
 		if criteria.OlderThan > 0 {
-			olderThan := time.Now().Add(-criteria.OlderThan)
+			olderThan := s.clock.Now().Add(-criteria.OlderThan)
 			filters.BeforeTime = &olderThan
 		}
 
@@ -401,6 +521,15 @@ func (s *Service) Archive(ctx context.Context, agentID string, criteria ArchiveC
 
 // CreateSnapshot creates a point-in-time snapshot of agent state
 func (s *Service) CreateSnapshot(ctx context.Context, agentID string, snapshotType, reason string) (*StateSnapshot, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
+	return s.createSnapshot(ctx, agentID, snapshotType, reason, 0)
+}
+
+// createSnapshot creates a snapshot that expires after ttl, or after the
+// snapshot type's default retention when ttl is zero
+func (s *Service) createSnapshot(ctx context.Context, agentID string, snapshotType, reason string, ttl time.Duration) (*StateSnapshot, error) {
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -423,6 +552,7 @@ func (s *Service) CreateSnapshot(ctx context.Context, agentID string, snapshotTy
 		}
 		state["working_memory_keys"] = workingKeys
 		state["working_memory_count"] = len(workingKeys)
+		state[snapshotWorkingMemoryKey] = snapshotWorkingMemory(workingMems)
 	}
 
 	// Include long-term memory summary
@@ -437,19 +567,21 @@ func (s *Service) CreateSnapshot(ctx context.Context, agentID string, snapshotTy
 	}
 
 	// Add timestamp
-	state["snapshot_time"] = time.Now()
+	state["snapshot_time"] = s.clock.Now()
 
 	// Determine expiration based on snapshot type
 	var expiresAt time.Time
-	switch snapshotType {
-	case "periodic":
-		expiresAt = time.Now().Add(7 * 24 * time.Hour) // 7 days
-	case "manual":
-		expiresAt = time.Now().Add(30 * 24 * time.Hour) // 30 days
-	case "pre-update", "pre-shutdown":
-		expiresAt = time.Now().Add(90 * 24 * time.Hour) // 90 days
+	switch {
+	case ttl > 0:
+		expiresAt = s.clock.Now().Add(ttl)
+	case snapshotType == SnapshotTypePeriodic:
+		expiresAt = s.clock.Now().Add(7 * 24 * time.Hour) // 7 days
+	case snapshotType == "manual":
+		expiresAt = s.clock.Now().Add(30 * 24 * time.Hour) // 30 days
+	case snapshotType == "pre-update", snapshotType == "pre-shutdown", snapshotType == SnapshotTypePreRestore:
+		expiresAt = s.clock.Now().Add(90 * 24 * time.Hour) // 90 days
 	default:
-		expiresAt = time.Now().Add(30 * 24 * time.Hour) // Default 30 days
+		expiresAt = s.clock.Now().Add(30 * 24 * time.Hour) // Default 30 days
 	}
 
 	snapshot := &StateSnapshot{
@@ -477,44 +609,75 @@ func (s *Service) CreateSnapshot(ctx context.Context, agentID string, snapshotTy
 	return snapshot, nil
 }
 
-// RestoreSnapshot restores agent state from a snapshot
-func (s *Service) RestoreSnapshot(ctx context.Context, agentID, snapshotID string) error {
+// RestoreSnapshot replaces the agent's working memory with the contents of a
+// snapshot after validating its checksum. The current state is first saved
+// as a "pre-restore" snapshot, which is returned so the restore can be rolled
+// back by restoring it in turn. Entries that have expired since the snapshot
+// was taken are not restored; long-term memory is left unchanged.
+func (s *Service) RestoreSnapshot(ctx context.Context, agentID, snapshotID string) (*StateSnapshot, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
-		return fmt.Errorf("agent ID is required")
+		return nil, fmt.Errorf("agent ID is required")
 	}
 	if snapshotID == "" {
-		return fmt.Errorf("snapshot ID is required")
+		return nil, fmt.Errorf("snapshot ID is required")
 	}
 
 	// Get snapshot
 	snapshot, err := s.repo.GetSnapshot(ctx, snapshotID)
 	if err != nil {
-		return fmt.Errorf("failed to get snapshot: %w", err)
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
 	}
 
 	// Verify agent ID matches
 	if snapshot.AgentID != agentID {
-		return fmt.Errorf("snapshot does not belong to agent %s", agentID)
+		return nil, fmt.Errorf("snapshot does not belong to agent %s", agentID)
 	}
 
-	// For now, we'll just log the restoration
-	// Full restoration would require more complex logic to rebuild state
-	log.WithFields(log.Fields{
-		"agent_id":    agentID,
-		"snapshot_id": snapshotID,
-	}).Info("Snapshot restoration requested (not yet fully implemented)")
+	if err := verifySnapshot(snapshot); err != nil {
+		return nil, err
+	}
 
-	// TODO: Implement full state restoration
-	// This would involve:
-	// 1. Clearing current working memory
-	// 2. Potentially restoring working memory from snapshot
-	// 3. Ensuring long-term memory consistency
+	memories, err := restoredWorkingMemory(agentID, snapshot.State, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", snapshotID, err)
+	}
 
-	return fmt.Errorf("snapshot restoration not yet fully implemented")
+	preRestore, err := s.CreateSnapshot(ctx, agentID, SnapshotTypePreRestore, fmt.Sprintf("before restoring snapshot %s", snapshotID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save state before restore: %w", err)
+	}
+
+	err = s.repo.ClearWorking(ctx, agentID)
+	s.recordAccess(ctx, agentID, MemoryTypeWorking, "", AuditOpClear, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear working memory: %w", err)
+	}
+	for _, mem := range memories {
+		err := s.repo.StoreWorking(ctx, mem)
+		s.recordAccess(ctx, agentID, MemoryTypeWorking, mem.Key, AuditOpWrite, err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore working memory %s (roll back with snapshot %s): %w", mem.Key, preRestore.ID, err)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"agent_id":        agentID,
+		"snapshot_id":     snapshotID,
+		"pre_restore_id":  preRestore.ID,
+		"restored_memory": len(memories),
+	}).Info("Restored state snapshot")
+
+	return preRestore, nil
 }
 
 // ListSnapshots lists snapshots for an agent with filters
 func (s *Service) ListSnapshots(ctx context.Context, agentID string, filters SnapshotFilters) ([]*StateSnapshot, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -532,6 +695,15 @@ func (s *Service) DeleteSnapshot(ctx context.Context, snapshotID string) error {
 	if snapshotID == "" {
 		return fmt.Errorf("snapshot ID is required")
 	}
+	if tenant.FromContext(ctx) != "" {
+		snapshot, err := s.repo.GetSnapshot(ctx, snapshotID)
+		if err != nil {
+			return fmt.Errorf("failed to get snapshot: %w", err)
+		}
+		if err := tenant.CheckIDs(ctx, snapshot.AgentID); err != nil {
+			return err
+		}
+	}
 
 	err := s.repo.DeleteSnapshot(ctx, snapshotID)
 	if err != nil {
@@ -549,6 +721,9 @@ func (s *Service) DeleteSnapshot(ctx context.Context, snapshotID string) error {
 
 // SyncMemory performs a basic synchronization (full implementation requires Synchronizer)
 func (s *Service) SyncMemory(ctx context.Context, agentID string) (*SyncResult, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -556,10 +731,10 @@ func (s *Service) SyncMemory(ctx context.Context, agentID string) (*SyncResult,
 	// This is a simplified version
 	// Full sync logic is in the Synchronizer component
 
-	startTime := time.Now()
+	startTime := s.clock.Now()
 	result := &SyncResult{
 		AgentID:     agentID,
-		SyncedAt:    time.Now(),
+		SyncedAt:    s.clock.Now(),
 		ItemsSynced: 0,
 		Conflicts:   []MemoryConflict{},
 		Errors:      []string{},
@@ -575,7 +750,7 @@ func (s *Service) SyncMemory(ctx context.Context, agentID string) (*SyncResult,
 	}
 
 	// Update sync status
-	status.LastSyncAt = time.Now()
+	status.LastSyncAt = s.clock.Now()
 	status.Status = SyncStateSynced
 	status.PendingChanges = 0
 
@@ -585,7 +760,7 @@ func (s *Service) SyncMemory(ctx context.Context, agentID string) (*SyncResult,
 		return result, err
 	}
 
-	result.DurationMs = time.Since(startTime).Milliseconds()
+	result.DurationMs = s.clock.Since(startTime).Milliseconds()
 	result.Success = true
 
 	log.WithFields(log.Fields{
@@ -598,6 +773,9 @@ func (s *Service) SyncMemory(ctx context.Context, agentID string) (*SyncResult,
 
 // GetSyncStatus retrieves the current synchronization status
 func (s *Service) GetSyncStatus(ctx context.Context, agentID string) (*SyncStatus, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -685,6 +863,9 @@ func (s *Service) CleanupExpired(ctx context.Context) (int, error) {
 
 // GetMemoryStats retrieves memory usage statistics for an agent
 func (s *Service) GetMemoryStats(ctx context.Context, agentID string) (*MemoryStats, error) {
+	if err := tenant.CheckIDs(ctx, agentID); err != nil {
+		return nil, err
+	}
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
@@ -693,6 +874,7 @@ func (s *Service) GetMemoryStats(ctx context.Context, agentID string) (*MemorySt
 	if err != nil {
 		return nil, fmt.Errorf("failed to get memory stats: %w", err)
 	}
+	stats.WorkingMemoryEvictions = s.WorkingMemoryEvictions(agentID)
 
 	return stats, nil
 }
@@ -2,9 +2,13 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 )
 
 func TestService_StoreAndRetrieveWorking(t *testing.T) {
@@ -310,6 +314,127 @@ func TestService_Search(t *testing.T) {
 	}
 }
 
+func TestService_SearchByEmbedding(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	agentID := "test-agent-1"
+
+	memories := []struct {
+		key       string
+		category  string
+		embedding []float64
+	}{
+		{"pump-cavitation", "experiences", []float64{0.9, 0.1, 0}},
+		{"pump-vibration", "experiences", []float64{0.7, 0.7, 0}},
+		{"valve-leak", "experiences", []float64{0, 0.2, 0.9}},
+		{"pump-manual", "facts", []float64{1, 0, 0}},
+		{"unembedded", "experiences", nil},
+		{"other-dimension", "experiences", []float64{1, 0}},
+	}
+
+	for _, mem := range memories {
+		var metadata map[string]interface{}
+		if mem.embedding != nil {
+			metadata = map[string]interface{}{"embedding": mem.embedding}
+		}
+		if err := service.Remember(ctx, agentID, mem.key, mem.key, mem.category, metadata); err != nil {
+			t.Fatalf("Failed to remember: %v", err)
+		}
+	}
+
+	results, err := service.Search(ctx, agentID, MemoryQuery{
+		Embedding: []float64{1, 0, 0},
+		TopK:      2,
+		Filters:   MemoryFilters{Category: "experiences"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+
+	if len(results) != 2 || results[0].Key != "pump-cavitation" || results[1].Key != "pump-vibration" {
+		t.Fatalf("Expected pump-cavitation then pump-vibration, got %v", results)
+	}
+	if results[0].Similarity <= results[1].Similarity || results[0].Similarity > 1 {
+		t.Errorf("Unexpected similarities %f and %f", results[0].Similarity, results[1].Similarity)
+	}
+
+	// MinSimilarity drops unrelated memories
+	results, err = service.Search(ctx, agentID, MemoryQuery{
+		Embedding:     []float64{0, 0, 1},
+		MinSimilarity: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Key != "valve-leak" {
+		t.Errorf("Expected only valve-leak, got %v", results)
+	}
+
+	invalid := []MemoryQuery{
+		{Embedding: []float64{0, 0, 0}},
+		{Embedding: []float64{1, 0, 0}, TopK: -1},
+		{Embedding: []float64{1, 0, 0}, MinSimilarity: 2},
+	}
+	for _, query := range invalid {
+		if _, err := service.Search(ctx, agentID, query); err == nil {
+			t.Errorf("Expected an error for query %+v", query)
+		}
+	}
+}
+
+func TestService_SearchText(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	agentID := "test-agent-1"
+
+	longterm := map[string]interface{}{
+		"pump-002-incident": map[string]interface{}{"summary": "Cavitation at PUMP-002", "pressure_kpa": 180},
+		"valve-maintenance": "Replaced valve seal after leak",
+		"pump-manual":       "Pump startup procedure",
+	}
+	for key, value := range longterm {
+		if err := service.Remember(ctx, agentID, key, value, "experiences", nil); err != nil {
+			t.Fatalf("Failed to remember: %v", err)
+		}
+	}
+
+	results, err := service.Search(ctx, agentID, MemoryQuery{Query: "cavitation pump"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Key != "pump-002-incident" {
+		t.Errorf("Expected only pump-002-incident, got %d results", len(results))
+	}
+
+	// Nested keys and numbers are searchable too
+	results, err = service.Search(ctx, agentID, MemoryQuery{Query: "PRESSURE_KPA 180"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected 1 result for nested fields, got %d", len(results))
+	}
+
+	if err := service.StoreWorking(ctx, agentID, "current-task", "Inspect valve V-12 for leak", time.Hour); err != nil {
+		t.Fatalf("Failed to store working memory: %v", err)
+	}
+	if err := service.StoreWorking(ctx, agentID, "shift", "Night shift", time.Hour); err != nil {
+		t.Fatalf("Failed to store working memory: %v", err)
+	}
+
+	working, err := service.ListWorking(ctx, agentID, MemoryFilters{Text: "valve leak"})
+	if err != nil {
+		t.Fatalf("Failed to list working memory: %v", err)
+	}
+	if len(working) != 1 || working[0].Key != "current-task" {
+		t.Errorf("Expected only current-task, got %d results", len(working))
+	}
+}
+
 func TestService_Archive(t *testing.T) {
 	repo := NewMockRepository()
 	service := NewService(repo)
@@ -398,6 +523,75 @@ func TestService_CreateSnapshot(t *testing.T) {
 	}
 }
 
+func TestService_RestoreSnapshot(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	agentID := "test-agent-1"
+
+	recall := func(key string) interface{} {
+		value, err := service.RetrieveWorking(ctx, agentID, key)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+
+	service.StoreWorking(ctx, agentID, "setpoint", 120.0, time.Hour)
+	service.StoreWorking(ctx, agentID, "mode", "auto", time.Hour)
+
+	snapshot, err := service.CreateSnapshot(ctx, agentID, "manual", "before tuning")
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+
+	// A bad update
+	service.UpdateWorking(ctx, agentID, "setpoint", 900.0)
+	service.DeleteWorking(ctx, agentID, "mode")
+	service.StoreWorking(ctx, agentID, "override", true, time.Hour)
+
+	preRestore, err := service.RestoreSnapshot(ctx, agentID, snapshot.ID)
+	if err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+	if preRestore.SnapshotType != SnapshotTypePreRestore {
+		t.Errorf("Expected a %s snapshot, got %s", SnapshotTypePreRestore, preRestore.SnapshotType)
+	}
+	if recall("setpoint") != 120.0 || recall("mode") != "auto" || recall("override") != nil {
+		t.Errorf("Unexpected restored memory: setpoint=%v mode=%v override=%v", recall("setpoint"), recall("mode"), recall("override"))
+	}
+
+	// Roll the restore back
+	if _, err := service.RestoreSnapshot(ctx, agentID, preRestore.ID); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if recall("setpoint") != 900.0 || recall("override") != true || recall("mode") != nil {
+		t.Errorf("Unexpected rolled back memory: setpoint=%v mode=%v override=%v", recall("setpoint"), recall("mode"), recall("override"))
+	}
+
+	// The checksum survives the JSON round trip of storage
+	stored, err := json.Marshal(snapshot.State)
+	if err != nil {
+		t.Fatalf("Failed to marshal state: %v", err)
+	}
+	snapshot.State = nil
+	if err := json.Unmarshal(stored, &snapshot.State); err != nil {
+		t.Fatalf("Failed to unmarshal state: %v", err)
+	}
+	if err := verifySnapshot(snapshot); err != nil {
+		t.Errorf("Expected a valid checksum after the round trip: %v", err)
+	}
+
+	snapshot.State["working_memory_count"] = 99
+	if _, err := service.RestoreSnapshot(ctx, agentID, snapshot.ID); !errors.Is(err, ErrSnapshotChecksumMismatch) {
+		t.Errorf("Expected ErrSnapshotChecksumMismatch, got %v", err)
+	}
+	if _, err := service.RestoreSnapshot(ctx, "other-agent", preRestore.ID); err == nil {
+		t.Error("Expected an error restoring another agent's snapshot")
+	}
+}
+
 func TestService_ListSnapshots(t *testing.T) {
 	repo := NewMockRepository()
 	service := NewService(repo)
@@ -574,6 +768,37 @@ func TestService_CleanupExpired(t *testing.T) {
 	}
 }
 
+func TestService_WorkingTTLWithFakeClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	service := NewService(repo)
+	service.SetClock(clk)
+	ctx := context.Background()
+
+	if err := service.StoreWorking(ctx, "agent-1", "lease", "held", time.Minute); err != nil {
+		t.Fatalf("Failed to store working memory: %v", err)
+	}
+
+	clk.Advance(59 * time.Second)
+	if _, err := service.RetrieveWorking(ctx, "agent-1", "lease"); err != nil {
+		t.Fatalf("Expected value before TTL, got error: %v", err)
+	}
+
+	clk.Advance(2 * time.Second)
+	if _, err := service.RetrieveWorking(ctx, "agent-1", "lease"); err == nil {
+		t.Error("Expected working memory to expire after TTL")
+	}
+
+	count, err := service.CleanupExpired(ctx)
+	if err != nil {
+		t.Fatalf("Failed to cleanup expired: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 expired entry, got %d", count)
+	}
+}
+
 func TestService_GetMemoryStats(t *testing.T) {
 	repo := NewMockRepository()
 	service := NewService(repo)
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultTopK is the number of memories a vector search returns by default
+const DefaultTopK = 10
+
+// CosineSimilarity returns the cosine similarity of two vectors. It reports
+// false when the vectors differ in dimension or either has no magnitude.
+func CosineSimilarity(a, b []float64) (float64, bool) {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0, false
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0, false
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB)), true
+}
+
+// validateVectorQuery applies the TopK default and checks a vector search
+func validateVectorQuery(query *MemoryQuery) error {
+	if len(query.Embedding) == 0 {
+		return nil
+	}
+	if query.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	if query.TopK == 0 {
+		query.TopK = DefaultTopK
+	}
+	if query.MinSimilarity < -1 || query.MinSimilarity > 1 {
+		return fmt.Errorf("min_similarity must be between -1 and 1")
+	}
+	if _, ok := CosineSimilarity(query.Embedding, query.Embedding); !ok {
+		return fmt.Errorf("embedding must not be a zero vector")
+	}
+	return nil
+}
+
+// rankBySimilarity returns copies of the TopK memories most similar to the
+// query embedding, most similar first, with their similarity set. Memories
+// without a comparable embedding are skipped.
+func rankBySimilarity(memories []*LongtermMemory, query MemoryQuery) []*LongtermMemory {
+	ranked := make([]*LongtermMemory, 0, len(memories))
+	for _, mem := range memories {
+		similarity, ok := CosineSimilarity(query.Embedding, mem.Embedding)
+		if !ok || (query.MinSimilarity != 0 && similarity < query.MinSimilarity) {
+			continue
+		}
+		scored := *mem
+		scored.Similarity = similarity
+		ranked = append(ranked, &scored)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Similarity > ranked[j].Similarity
+	})
+	if query.TopK > 0 && len(ranked) > query.TopK {
+		ranked = ranked[:query.TopK]
+	}
+	return ranked
+}
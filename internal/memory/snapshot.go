@@ -0,0 +1,158 @@
+package memory
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrSnapshotChecksumMismatch is returned when a snapshot's state no longer
+// matches the checksum recorded when it was created
+var ErrSnapshotChecksumMismatch = errors.New("snapshot checksum mismatch")
+
+// SnapshotTypePreRestore is the type of the snapshot taken before a restore,
+// so the restore can be rolled back
+const SnapshotTypePreRestore = "pre-restore"
+
+// DefaultSnapshotCompressionThreshold is the state size in bytes above which
+// snapshots are stored compressed
+const DefaultSnapshotCompressionThreshold = 64 * 1024
+
+// snapshotWorkingMemoryKey is the state entry holding working memory contents
+const snapshotWorkingMemoryKey = "working_memory"
+
+// snapshotChecksum returns the checksum and size of a snapshot state
+func snapshotChecksum(state map[string]interface{}) (string, int64, error) {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to marshal snapshot state: %w", err)
+	}
+	hash := sha256.Sum256(stateJSON)
+	return fmt.Sprintf("%x", hash), int64(len(stateJSON)), nil
+}
+
+// compressSnapshotState gzips the JSON of a snapshot state and encodes it as
+// base64 so it can be stored in a document
+func compressSnapshotState(state map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(state); err != nil {
+		return "", fmt.Errorf("failed to compress snapshot state: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress snapshot state: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompressSnapshotState reverses compressSnapshotState
+func decompressSnapshotState(encoded string) (map[string]interface{}, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode compressed snapshot state: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot state: %w", err)
+	}
+	defer zr.Close()
+
+	stateJSON, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot state: %w", err)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(stateJSON, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot state: %w", err)
+	}
+	return state, nil
+}
+
+// verifySnapshot checks the snapshot state against its checksum
+func verifySnapshot(snapshot *StateSnapshot) error {
+	checksum, _, err := snapshotChecksum(snapshot.State)
+	if err != nil {
+		return err
+	}
+	if snapshot.Checksum == "" || checksum != snapshot.Checksum {
+		return fmt.Errorf("%w: snapshot %s", ErrSnapshotChecksumMismatch, snapshot.ID)
+	}
+	return nil
+}
+
+// snapshotWorkingMemory encodes working memory entries for a snapshot state
+func snapshotWorkingMemory(memories []*WorkingMemory) []interface{} {
+	entries := make([]interface{}, 0, len(memories))
+	for _, mem := range memories {
+		entries = append(entries, map[string]interface{}{
+			"key":            mem.Key,
+			"value":          mem.Value,
+			"metadata":       mem.Metadata,
+			"expires_at":     mem.ExpiresAt,
+			"schema_version": mem.SchemaVersion,
+		})
+	}
+	return entries
+}
+
+// restoredWorkingMemory decodes the working memory entries of a snapshot
+// state. Entries that have expired since the snapshot are left out.
+func restoredWorkingMemory(agentID string, state map[string]interface{}, now time.Time) ([]*WorkingMemory, error) {
+	raw, ok := state[snapshotWorkingMemoryKey]
+	if !ok {
+		return nil, fmt.Errorf("snapshot does not contain working memory contents")
+	}
+
+	var entries []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		entries = v
+	case nil:
+	default:
+		return nil, fmt.Errorf("snapshot working memory has unexpected type %T", raw)
+	}
+
+	memories := make([]*WorkingMemory, 0, len(entries))
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("snapshot working memory entry has unexpected type %T", e)
+		}
+		key, _ := entry["key"].(string)
+		if key == "" {
+			return nil, fmt.Errorf("snapshot working memory entry has no key")
+		}
+
+		expiresAt, _ := parseTime(entry["expires_at"])
+		if !expiresAt.IsZero() && !expiresAt.After(now) {
+			continue
+		}
+
+		metadata, _ := entry["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+
+		mem := &WorkingMemory{
+			AgentID:   agentID,
+			Key:       key,
+			Value:     entry["value"],
+			Metadata:  metadata,
+			ExpiresAt: expiresAt,
+		}
+		switch v := entry["schema_version"].(type) {
+		case int:
+			mem.SchemaVersion = v
+		case float64:
+			mem.SchemaVersion = int(v)
+		}
+		memories = append(memories, mem)
+	}
+	return memories, nil
+}
@@ -0,0 +1,302 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotTypePeriodic is the type of snapshots taken by the SnapshotScheduler
+const SnapshotTypePeriodic = "periodic"
+
+// SnapshotPolicy configures periodic snapshots of one agent type
+type SnapshotPolicy struct {
+	// Interval is the time between snapshots of an agent
+	Interval time.Duration
+
+	// KeepLast is the number of most recent periodic snapshots kept (default 5)
+	KeepLast int
+
+	// KeepDailyDays additionally keeps the newest periodic snapshot of each of
+	// the last KeepDailyDays days (UTC); zero keeps no dailies
+	KeepDailyDays int
+}
+
+// SnapshotTarget is an agent whose state the scheduler snapshots
+type SnapshotTarget struct {
+	AgentID   string
+	AgentType string
+}
+
+// SnapshotTargetLister returns the agents to snapshot
+type SnapshotTargetLister func() []SnapshotTarget
+
+// SnapshotSchedulerConfig configures the SnapshotScheduler
+type SnapshotSchedulerConfig struct {
+	// Policies holds the snapshot policy per agent type. The "*" policy
+	// applies to agent types without their own; agents without a policy are
+	// not snapshotted.
+	Policies map[string]SnapshotPolicy
+
+	// CheckInterval is how often agents are checked for a due snapshot
+	// (default 1m)
+	CheckInterval time.Duration
+}
+
+// SnapshotSchedulerConfigFromConfig converts the snapshot policies of the
+// application config
+func SnapshotSchedulerConfigFromConfig(cfg config.MemorySnapshotsConfig) SnapshotSchedulerConfig {
+	policies := make(map[string]SnapshotPolicy, len(cfg.Policies))
+	for agentType, policy := range cfg.Policies {
+		policies[agentType] = SnapshotPolicy{
+			Interval:      time.Duration(policy.IntervalSeconds) * time.Second,
+			KeepLast:      policy.KeepLast,
+			KeepDailyDays: policy.KeepDailyDays,
+		}
+	}
+	return SnapshotSchedulerConfig{
+		Policies:      policies,
+		CheckInterval: time.Duration(cfg.CheckIntervalSeconds) * time.Second,
+	}
+}
+
+// SnapshotScheduler takes periodic snapshots of agent state and enforces
+// their retention policy
+type SnapshotScheduler struct {
+	service *Service
+	repo    MemoryRepository
+	targets SnapshotTargetLister
+	config  SnapshotSchedulerConfig
+
+	mu       sync.Mutex
+	last     map[string]time.Time
+	running  bool
+	stopChan chan struct{}
+}
+
+// NewSnapshotScheduler creates a snapshot scheduler for the agents returned by targets
+func NewSnapshotScheduler(service *Service, repo MemoryRepository, targets SnapshotTargetLister, config SnapshotSchedulerConfig) (*SnapshotScheduler, error) {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = time.Minute
+	}
+
+	policies := make(map[string]SnapshotPolicy, len(config.Policies))
+	for agentType, policy := range config.Policies {
+		if policy.Interval <= 0 {
+			return nil, fmt.Errorf("snapshot interval for agent type %q must be positive", agentType)
+		}
+		if policy.KeepLast < 0 || policy.KeepDailyDays < 0 {
+			return nil, fmt.Errorf("snapshot retention for agent type %q must not be negative", agentType)
+		}
+		if policy.KeepLast == 0 {
+			policy.KeepLast = 5
+		}
+		policies[agentType] = policy
+	}
+	config.Policies = policies
+
+	return &SnapshotScheduler{
+		service: service,
+		repo:    repo,
+		targets: targets,
+		config:  config,
+		last:    make(map[string]time.Time),
+	}, nil
+}
+
+// Start runs the scheduler until Stop is called or the context is cancelled
+func (s *SnapshotScheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("snapshot scheduler already running")
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+
+	go s.loop(ctx, s.stopChan)
+
+	log.WithFields(log.Fields{
+		"policies":       len(s.config.Policies),
+		"check_interval": s.config.CheckInterval,
+	}).Info("Started snapshot scheduler")
+	return nil
+}
+
+// Stop stops the scheduler
+func (s *SnapshotScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	close(s.stopChan)
+	s.running = false
+	log.Info("Stopped snapshot scheduler")
+}
+
+func (s *SnapshotScheduler) loop(ctx context.Context, stop <-chan struct{}) {
+	ticker := s.service.clock.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			s.RunOnce(ctx)
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunOnce snapshots every agent whose snapshot is due and prunes its periodic
+// snapshots, and returns the number of snapshots taken. Failures are logged
+// per agent so one agent does not hold back the others.
+func (s *SnapshotScheduler) RunOnce(ctx context.Context) int {
+	taken := 0
+	for _, target := range s.targets() {
+		policy, ok := s.policy(target.AgentType)
+		if !ok {
+			continue
+		}
+
+		logger := log.WithFields(log.Fields{
+			"agent_id":   target.AgentID,
+			"agent_type": target.AgentType,
+		})
+
+		due, err := s.due(ctx, target.AgentID, policy)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to check snapshot schedule")
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		snapshot, err := s.service.createSnapshot(ctx, target.AgentID, SnapshotTypePeriodic, "scheduled snapshot", policy.retention())
+		if err != nil {
+			logger.WithError(err).Error("Failed to take scheduled snapshot")
+			continue
+		}
+		taken++
+		s.mu.Lock()
+		s.last[target.AgentID] = s.service.clock.Now()
+		s.mu.Unlock()
+
+		pruned, err := s.prune(ctx, target.AgentID, policy)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to prune periodic snapshots")
+		}
+		logger.WithFields(log.Fields{
+			"snapshot_id": snapshot.ID,
+			"pruned":      pruned,
+		}).Debug("Took scheduled snapshot")
+	}
+	return taken
+}
+
+// policy returns the snapshot policy of an agent type
+func (s *SnapshotScheduler) policy(agentType string) (SnapshotPolicy, bool) {
+	if policy, ok := s.config.Policies[agentType]; ok {
+		return policy, true
+	}
+	policy, ok := s.config.Policies["*"]
+	return policy, ok
+}
+
+// due reports whether an agent's next snapshot is due. The time of the last
+// snapshot is looked up once per agent, so a restart does not snapshot every
+// agent at once.
+func (s *SnapshotScheduler) due(ctx context.Context, agentID string, policy SnapshotPolicy) (bool, error) {
+	s.mu.Lock()
+	last, known := s.last[agentID]
+	s.mu.Unlock()
+
+	if !known {
+		snapshots, err := s.periodicSnapshots(ctx, agentID)
+		if err != nil {
+			return false, err
+		}
+		if len(snapshots) > 0 {
+			last = snapshots[0].CreatedAt
+		}
+		s.mu.Lock()
+		s.last[agentID] = last
+		s.mu.Unlock()
+	}
+
+	return last.IsZero() || !s.service.clock.Now().Before(last.Add(policy.Interval)), nil
+}
+
+// prune deletes the periodic snapshots the policy does not keep and returns
+// how many were deleted
+func (s *SnapshotScheduler) prune(ctx context.Context, agentID string, policy SnapshotPolicy) (int, error) {
+	snapshots, err := s.periodicSnapshots(ctx, agentID)
+	if err != nil {
+		return 0, err
+	}
+
+	keep := make(map[string]bool)
+	for i, snapshot := range snapshots {
+		if i < policy.KeepLast {
+			keep[snapshot.ID] = true
+		}
+	}
+	if policy.KeepDailyDays > 0 {
+		today := s.service.clock.Now().UTC().Truncate(24 * time.Hour)
+		oldest := today.AddDate(0, 0, -(policy.KeepDailyDays - 1))
+		days := make(map[time.Time]bool)
+		for _, snapshot := range snapshots {
+			day := snapshot.CreatedAt.UTC().Truncate(24 * time.Hour)
+			if day.Before(oldest) || days[day] {
+				continue
+			}
+			days[day] = true
+			keep[snapshot.ID] = true
+		}
+	}
+
+	deleted := 0
+	for _, snapshot := range snapshots {
+		if keep[snapshot.ID] {
+			continue
+		}
+		if err := s.repo.DeleteSnapshot(ctx, snapshot.ID); err != nil {
+			return deleted, fmt.Errorf("failed to delete snapshot %s: %w", snapshot.ID, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// periodicSnapshots returns an agent's periodic snapshots, newest first
+func (s *SnapshotScheduler) periodicSnapshots(ctx context.Context, agentID string) ([]*StateSnapshot, error) {
+	snapshots, err := s.repo.ListSnapshots(ctx, agentID, SnapshotFilters{SnapshotType: SnapshotTypePeriodic})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list periodic snapshots: %w", err)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// retention is how long a periodic snapshot must outlive its creation for the
+// policy to be able to keep it; the scheduler prunes earlier than that
+func (p SnapshotPolicy) retention() time.Duration {
+	retention := time.Duration(p.KeepLast) * p.Interval
+	if daily := time.Duration(p.KeepDailyDays) * 24 * time.Hour; daily > retention {
+		retention = daily
+	}
+	return retention + 24*time.Hour
+}
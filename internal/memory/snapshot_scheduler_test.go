@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestSnapshotScheduler_RunOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 1, 0, 30, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	service := NewService(repo)
+	service.SetClock(clk)
+
+	targets := []SnapshotTarget{
+		{AgentID: "PUMP-002", AgentType: "pump"},
+		{AgentID: "COORD-NORTH", AgentType: "coordinator"},
+		{AgentID: "SENSOR-7", AgentType: "sensor"},
+	}
+	scheduler, err := NewSnapshotScheduler(service, repo, func() []SnapshotTarget { return targets }, SnapshotSchedulerConfig{
+		Policies: map[string]SnapshotPolicy{
+			"pump": {Interval: 6 * time.Hour, KeepLast: 2, KeepDailyDays: 3},
+			"*":    {Interval: 24 * time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+
+	// Four days of hourly checks
+	taken := 0
+	for hour := 0; hour < 96; hour++ {
+		taken += scheduler.RunOnce(ctx)
+		clk.Advance(time.Hour)
+	}
+
+	// 16 pump snapshots, 4 each for the two agents under the "*" policy
+	if taken != 24 {
+		t.Errorf("Expected 24 snapshots, got %d", taken)
+	}
+
+	pump, err := service.ListSnapshots(ctx, "PUMP-002", SnapshotFilters{SnapshotType: SnapshotTypePeriodic})
+	if err != nil {
+		t.Fatalf("Failed to list snapshots: %v", err)
+	}
+	// The last two, plus the newest of each of the last three days, one of
+	// which is also among the last two
+	if len(pump) != 4 {
+		t.Errorf("Expected 4 pump snapshots kept, got %d", len(pump))
+	}
+	for _, snapshot := range pump {
+		if snapshot.CreatedAt.Before(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected snapshots older than the daily window to be pruned, got %s", snapshot.CreatedAt)
+		}
+	}
+
+	coordinator, _ := service.ListSnapshots(ctx, "COORD-NORTH", SnapshotFilters{})
+	if len(coordinator) != 4 {
+		t.Errorf("Expected 4 coordinator snapshots under the default policy, got %d", len(coordinator))
+	}
+
+	// Snapshots of other types are never pruned
+	if _, err := service.CreateSnapshot(ctx, "PUMP-002", "manual", "before tuning"); err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	clk.Advance(6 * time.Hour)
+	scheduler.RunOnce(ctx)
+	manual, _ := service.ListSnapshots(ctx, "PUMP-002", SnapshotFilters{SnapshotType: "manual"})
+	if len(manual) != 1 {
+		t.Errorf("Expected the manual snapshot to be kept, got %d", len(manual))
+	}
+
+	// A new scheduler picks up the last snapshot time instead of
+	// snapshotting at once
+	restarted, _ := NewSnapshotScheduler(service, repo, func() []SnapshotTarget { return targets[:1] }, SnapshotSchedulerConfig{
+		Policies: map[string]SnapshotPolicy{"pump": {Interval: 6 * time.Hour}},
+	})
+	if n := restarted.RunOnce(ctx); n != 0 {
+		t.Errorf("Expected no snapshot right after a restart, got %d", n)
+	}
+
+	if _, err := NewSnapshotScheduler(service, repo, nil, SnapshotSchedulerConfig{
+		Policies: map[string]SnapshotPolicy{"pump": {}},
+	}); err == nil {
+		t.Error("Expected an error for a policy without an interval")
+	}
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRepository_SnapshotCompression(t *testing.T) {
+	// storeAndLoad converts a snapshot to a document and back through JSON,
+	// as storing it in ArangoDB does
+	storeAndLoad := func(r *Repository, snapshot *StateSnapshot) (map[string]interface{}, *StateSnapshot) {
+		checksum, size, err := snapshotChecksum(snapshot.State)
+		if err != nil {
+			t.Fatalf("Failed to checksum: %v", err)
+		}
+		snapshot.Checksum = checksum
+		snapshot.Metadata.SizeBytes = size
+		snapshot.Metadata.Compressed = r.compressSnapshot(size)
+
+		doc, err := r.snapshotToDocument(snapshot)
+		if err != nil {
+			t.Fatalf("Failed to convert snapshot: %v", err)
+		}
+		stored, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatalf("Failed to marshal document: %v", err)
+		}
+		var loadedDoc map[string]interface{}
+		if err := json.Unmarshal(stored, &loadedDoc); err != nil {
+			t.Fatalf("Failed to unmarshal document: %v", err)
+		}
+		loaded, err := r.documentToSnapshot(loadedDoc)
+		if err != nil {
+			t.Fatalf("Failed to load snapshot: %v", err)
+		}
+		return loadedDoc, loaded
+	}
+
+	large := &StateSnapshot{
+		ID:      "snap-large",
+		AgentID: "PUMP-002",
+		State: map[string]interface{}{
+			"working_memory_keys": strings.Split(strings.Repeat("reading,", 200), ","),
+			"notes":               strings.Repeat("pressure nominal ", 100),
+		},
+	}
+
+	r := &Repository{}
+	r.SetSnapshotCompressionThreshold(1024)
+	doc, loaded := storeAndLoad(r, large)
+
+	if _, ok := doc["state"]; ok || doc["state_compressed"] == nil {
+		t.Error("Expected the large state to be stored compressed")
+	}
+	if !loaded.Metadata.Compressed || loaded.Metadata.StoredBytes >= loaded.Metadata.SizeBytes {
+		t.Errorf("Unexpected metadata: %+v", loaded.Metadata)
+	}
+	if err := verifySnapshot(loaded); err != nil {
+		t.Errorf("Expected the decompressed state to match the checksum: %v", err)
+	}
+
+	small := &StateSnapshot{ID: "snap-small", State: map[string]interface{}{"working_memory_count": 1}}
+	doc, loaded = storeAndLoad(r, small)
+	if doc["state"] == nil || loaded.Metadata.Compressed || loaded.State["working_memory_count"] != 1.0 {
+		t.Errorf("Expected the small state to be stored as is, got %+v", loaded)
+	}
+
+	r.SetSnapshotCompressionThreshold(-1)
+	if doc, _ = storeAndLoad(r, large); doc["state"] == nil {
+		t.Error("Expected no compression when disabled")
+	}
+
+	corrupt := map[string]interface{}{
+		"id":               "snap-corrupt",
+		"metadata":         map[string]interface{}{"compressed": true},
+		"state_compressed": "not gzip",
+	}
+	if _, err := r.documentToSnapshot(corrupt); err == nil {
+		t.Error("Expected an error for a corrupt compressed state")
+	}
+}
@@ -22,6 +22,10 @@ type Synchronizer struct {
 	running    bool
 	stopChan   chan struct{}
 
+	// Delta sync of working memory with other instances
+	exchange ChangeExchange
+	agents   map[string]*agentSyncState
+
 	// Configuration
 	syncInterval time.Duration
 }
@@ -39,6 +43,7 @@ func NewSynchronizer(service *Service, repo MemoryRepository, strategy ConflictS
 		instanceID:   uuid.New().String(),
 		syncInterval: syncInterval,
 		stopChan:     make(chan struct{}),
+		agents:       make(map[string]*agentSyncState),
 	}
 }
 
@@ -82,12 +87,12 @@ func (s *Synchronizer) StopPeriodicSync() error {
 
 // syncLoop runs the periodic synchronization
 func (s *Synchronizer) syncLoop(ctx context.Context, agentID string) {
-	ticker := time.NewTicker(s.syncInterval)
+	ticker := s.service.clock.NewTicker(s.syncInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			// Perform sync
 			result, err := s.SyncAgent(ctx, agentID)
 			if err != nil {
@@ -118,10 +123,10 @@ func (s *Synchronizer) SyncAgent(ctx context.Context, agentID string) (*SyncResu
 		return nil, fmt.Errorf("agent ID is required")
 	}
 
-	startTime := time.Now()
+	startTime := s.service.clock.Now()
 	result := &SyncResult{
 		AgentID:     agentID,
-		SyncedAt:    time.Now(),
+		SyncedAt:    s.service.clock.Now(),
 		ItemsSynced: 0,
 		Conflicts:   []MemoryConflict{},
 		Errors:      []string{},
@@ -131,16 +136,17 @@ func (s *Synchronizer) SyncAgent(ctx context.Context, agentID string) (*SyncResu
 	log.WithField("agent_id", agentID).Debug("Starting agent memory sync")
 
 	// Get current sync status
-	status, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
+	stored, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to get sync status: %v", err))
 		return result, err
 	}
+	syncing := copySyncStatus(stored)
 
 	// Update status to syncing
-	status.Status = SyncStateSyncing
-	status.LastSyncAt = time.Now()
-	err = s.repo.UpdateSyncStatus(ctx, status)
+	syncing.Status = SyncStateSyncing
+	syncing.LastSyncAt = s.service.clock.Now()
+	err = s.repo.UpdateSyncStatus(ctx, syncing)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to update sync status: %v", err))
 	}
@@ -163,24 +169,28 @@ func (s *Synchronizer) SyncAgent(ctx context.Context, agentID string) (*SyncResu
 		result.Conflicts = append(result.Conflicts, longtermConflicts...)
 	}
 
-	// Update final sync status
+	// Update final sync status on another copy, as the syncing one may now be
+	// stored. Conflicts resolved since the last sync are cleared.
+	status := copySyncStatus(syncing)
+	status.Conflicts = result.Conflicts
 	if len(result.Conflicts) > 0 {
 		status.Status = SyncStateConflict
-		status.Conflicts = result.Conflicts
 	} else if len(result.Errors) > 0 {
 		status.Status = SyncStateError
 	} else {
 		status.Status = SyncStateSynced
 	}
 
-	status.PendingChanges = 0
+	if pending, err := s.PendingChanges(ctx, agentID); err == nil {
+		status.PendingChanges = pending
+	}
 	status.SyncVersion++
 	err = s.repo.UpdateSyncStatus(ctx, status)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("failed to update final sync status: %v", err))
 	}
 
-	result.DurationMs = time.Since(startTime).Milliseconds()
+	result.DurationMs = s.service.clock.Since(startTime).Milliseconds()
 	result.Success = len(result.Errors) == 0
 
 	log.WithFields(log.Fields{
@@ -194,18 +204,23 @@ func (s *Synchronizer) SyncAgent(ctx context.Context, agentID string) (*SyncResu
 	return result, nil
 }
 
-// syncWorkingMemory synchronizes working memory entries
+// syncWorkingMemory exchanges working memory changes with the other instances
+// of the agent when an exchange is configured
 func (s *Synchronizer) syncWorkingMemory(ctx context.Context, agentID string) (int, []MemoryConflict, error) {
-	// For now, just validate that working memory is accessible
-	// Real implementation would compare local vs remote state
+	s.mu.RLock()
+	exchange := s.exchange
+	s.mu.RUnlock()
 
+	if exchange != nil {
+		return s.exchangeWorkingMemory(ctx, agentID, exchange)
+	}
+
+	// Without an exchange there is no other instance to compare against
 	memories, err := s.repo.ListWorking(ctx, agentID, MemoryFilters{})
 	if err != nil {
 		return 0, nil, fmt.Errorf("failed to list working memory: %w", err)
 	}
 
-	// No actual conflicts in simple implementation
-	// Full implementation would compare with remote instance
 	return len(memories), []MemoryConflict{}, nil
 }
 
@@ -279,8 +294,12 @@ func (s *Synchronizer) ResolveConflicts(ctx context.Context, agentID string, con
 	resolved := 0
 	failed := 0
 
+	strategy := s.GetConflictStrategy()
 	for _, conflict := range conflicts {
-		err := s.service.ResolveConflict(ctx, &conflict, s.strategy)
+		held, err := s.resolveHeld(ctx, agentID, conflict.Key, strategy)
+		if !held {
+			err = s.service.ResolveConflict(ctx, &conflict, strategy)
+		}
 		if err != nil {
 			log.WithError(err).WithField("key", conflict.Key).Warn("Failed to resolve conflict")
 			failed++
@@ -300,15 +319,19 @@ func (s *Synchronizer) ResolveConflicts(ctx context.Context, agentID string, con
 	}
 
 	// Clear conflicts from sync status
-	status, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
+	stored, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
 	if err != nil {
 		return fmt.Errorf("failed to get sync status: %w", err)
 	}
+	status := copySyncStatus(stored)
 
 	status.Conflicts = []MemoryConflict{}
 	if status.Status == SyncStateConflict {
 		status.Status = SyncStateSynced
 	}
+	if pending, err := s.PendingChanges(ctx, agentID); err == nil {
+		status.PendingChanges = pending
+	}
 
 	err = s.repo.UpdateSyncStatus(ctx, status)
 	if err != nil {
@@ -333,21 +356,22 @@ func (s *Synchronizer) ForcePush(ctx context.Context, agentID string) error {
 	// 4. Update sync status
 
 	// For now, just update sync status
-	status, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
+	stored, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
 	if err != nil {
 		return fmt.Errorf("failed to get sync status: %w", err)
 	}
+	status := copySyncStatus(stored)
 
 	status.Status = SyncStateSynced
 	status.PendingChanges = 0
 	status.Conflicts = []MemoryConflict{}
-	status.LastSyncAt = time.Now()
+	status.LastSyncAt = s.service.clock.Now()
 	status.SyncVersion++
 
 	if status.Metadata == nil {
 		status.Metadata = make(map[string]interface{})
 	}
-	status.Metadata["last_force_push"] = time.Now()
+	status.Metadata["last_force_push"] = s.service.clock.Now()
 
 	err = s.repo.UpdateSyncStatus(ctx, status)
 	if err != nil {
@@ -375,21 +399,22 @@ func (s *Synchronizer) ForcePull(ctx context.Context, agentID string) error {
 	// 5. Update sync status
 
 	// For now, just update sync status
-	status, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
+	stored, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
 	if err != nil {
 		return fmt.Errorf("failed to get sync status: %w", err)
 	}
+	status := copySyncStatus(stored)
 
 	status.Status = SyncStateSynced
 	status.PendingChanges = 0
 	status.Conflicts = []MemoryConflict{}
-	status.LastSyncAt = time.Now()
+	status.LastSyncAt = s.service.clock.Now()
 	status.SyncVersion++
 
 	if status.Metadata == nil {
 		status.Metadata = make(map[string]interface{})
 	}
-	status.Metadata["last_force_pull"] = time.Now()
+	status.Metadata["last_force_pull"] = s.service.clock.Now()
 
 	err = s.repo.UpdateSyncStatus(ctx, status)
 	if err != nil {
@@ -401,17 +426,38 @@ func (s *Synchronizer) ForcePull(ctx context.Context, agentID string) error {
 	return nil
 }
 
-// GetStatus returns the current synchronization status
+// copySyncStatus copies a sync status before it is changed. Repositories may
+// hand out the stored status, which concurrent syncs and readers share.
+func copySyncStatus(status *SyncStatus) *SyncStatus {
+	copied := *status
+	if status.Metadata != nil {
+		copied.Metadata = make(map[string]interface{}, len(status.Metadata))
+		for key, value := range status.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	return &copied
+}
+
+// GetStatus returns the current synchronization status with the pending
+// changes counted from working memory
 func (s *Synchronizer) GetStatus(ctx context.Context, agentID string) (*SyncStatus, error) {
 	if agentID == "" {
 		return nil, fmt.Errorf("agent ID is required")
 	}
 
-	status, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
+	stored, err := s.repo.GetSyncStatus(ctx, agentID, s.instanceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sync status: %w", err)
 	}
 
+	pending, err := s.PendingChanges(ctx, agentID)
+	if err != nil {
+		return nil, err
+	}
+	status := copySyncStatus(stored)
+	status.PendingChanges = pending
+
 	return status, nil
 }
 
@@ -46,6 +46,9 @@ type WorkingMemory struct {
 
 	// Version is used for optimistic locking and conflict detection
 	Version int `json:"version"`
+
+	// SchemaVersion is the version of the value's structure; 0 when unversioned
+	SchemaVersion int `json:"schema_version,omitempty"`
 }
 
 // LongtermMemory represents persistent knowledge and experiences
@@ -65,9 +68,13 @@ type LongtermMemory struct {
 	// Value is the memory content
 	Value interface{} `json:"value"`
 
-	// Embedding is a vector representation for semantic search (future enhancement)
+	// Embedding is a vector representation for semantic search
 	Embedding []float64 `json:"embedding,omitempty"`
 
+	// Similarity is the cosine similarity to the query embedding; only set by
+	// vector searches and never stored
+	Similarity float64 `json:"similarity,omitempty"`
+
 	// Metadata contains structured information about the memory
 	Metadata MemoryMetadata `json:"metadata"`
 
@@ -85,6 +92,9 @@ type LongtermMemory struct {
 
 	// Version for conflict detection
 	Version int `json:"version"`
+
+	// SchemaVersion is the version of the value's structure; 0 when unversioned
+	SchemaVersion int `json:"schema_version,omitempty"`
 }
 
 // MemoryMetadata contains structured metadata for long-term memories
@@ -146,8 +156,12 @@ type SnapshotMetadata struct {
 	// SizeBytes is the size of the snapshot data
 	SizeBytes int64 `json:"size_bytes"`
 
-	// Compressed indicates if the state data is compressed
+	// Compressed indicates if the state data is stored compressed (gzip)
 	Compressed bool `json:"compressed"`
+
+	// StoredBytes is the size of the stored state data, smaller than
+	// SizeBytes when compressed
+	StoredBytes int64 `json:"stored_bytes,omitempty"`
 }
 
 // SyncStatus tracks memory synchronization state
@@ -272,6 +286,10 @@ type MemoryStats struct {
 	// WorkingMemorySizeBytes is the approximate size of working memory
 	WorkingMemorySizeBytes int64 `json:"working_memory_size_bytes"`
 
+	// WorkingMemoryEvictions is the number of working memory entries evicted
+	// to keep the agent within its quota, since the service started
+	WorkingMemoryEvictions int64 `json:"working_memory_evictions"`
+
 	// LongtermMemoryCount is the number of long-term memory entries
 	LongtermMemoryCount int `json:"longterm_memory_count"`
 
@@ -293,6 +311,11 @@ type MemoryStats struct {
 
 // MemoryFilters defines filtering options for memory queries
 type MemoryFilters struct {
+	// Text is a free-text query: only memories whose key or value contains
+	// every word (after stemming) are returned, best matches first unless
+	// SortBy is set
+	Text string `json:"text,omitempty"`
+
 	// Tags filters by memory tags
 	Tags []string `json:"tags,omitempty"`
 
@@ -323,9 +346,21 @@ type MemoryFilters struct {
 
 // MemoryQuery defines search parameters for memory retrieval
 type MemoryQuery struct {
-	// Query is the search text (future: semantic search)
+	// Query is free-text search text, used as Filters.Text unless that is set
 	Query string `json:"query"`
 
+	// Embedding searches by vector similarity: the TopK memories with an
+	// embedding of the same dimension that are most similar to it (by cosine
+	// similarity) are returned, most similar first. Without it, memories are
+	// only filtered.
+	Embedding []float64 `json:"embedding,omitempty"`
+
+	// TopK is the number of most similar memories returned (default 10)
+	TopK int `json:"top_k,omitempty"`
+
+	// MinSimilarity, if set, excludes memories less similar than this (-1 to 1)
+	MinSimilarity float64 `json:"min_similarity,omitempty"`
+
 	// Filters apply additional constraints
 	Filters MemoryFilters `json:"filters"`
 }
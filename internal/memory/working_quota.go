@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrWorkingMemoryQuota is returned when a single entry exceeds the agent's
+// working memory byte quota, so no eviction can make room for it
+var ErrWorkingMemoryQuota = errors.New("working memory entry exceeds quota")
+
+// WorkingMemoryQuota limits an agent's working memory. Zero fields are unlimited.
+type WorkingMemoryQuota struct {
+	// MaxEntries is the maximum number of entries
+	MaxEntries int
+
+	// MaxBytes is the maximum total size of the entries' JSON values
+	MaxBytes int64
+}
+
+func (q WorkingMemoryQuota) unlimited() bool {
+	return q.MaxEntries == 0 && q.MaxBytes == 0
+}
+
+// WorkingMemoryQuotaConfig configures working memory quotas
+type WorkingMemoryQuotaConfig struct {
+	// Default applies to every agent without its own quota
+	Default WorkingMemoryQuota
+
+	// Agents holds quotas for individual agents by agent ID
+	Agents map[string]WorkingMemoryQuota
+}
+
+// WorkingMemoryQuotaConfigFromConfig converts the working memory quotas of
+// the application config
+func WorkingMemoryQuotaConfigFromConfig(cfg config.MemoryQuotaConfig) WorkingMemoryQuotaConfig {
+	quotas := WorkingMemoryQuotaConfig{
+		Default: WorkingMemoryQuota{MaxEntries: cfg.MaxEntries, MaxBytes: cfg.MaxBytes},
+	}
+	if len(cfg.Agents) > 0 {
+		quotas.Agents = make(map[string]WorkingMemoryQuota, len(cfg.Agents))
+		for _, quota := range cfg.Agents {
+			quotas.Agents[quota.AgentID] = WorkingMemoryQuota{MaxEntries: quota.MaxEntries, MaxBytes: quota.MaxBytes}
+		}
+	}
+	return quotas
+}
+
+// workingQuotas enforces working memory quotas and counts evictions
+type workingQuotas struct {
+	mu        sync.RWMutex
+	config    WorkingMemoryQuotaConfig
+	evictions map[string]int64
+}
+
+// SetWorkingMemoryQuota configures the per-agent working memory quotas. When
+// storing or updating an entry takes an agent over its quota, the least
+// recently accessed entries are evicted (expired ones first).
+func (s *Service) SetWorkingMemoryQuota(config WorkingMemoryQuotaConfig) error {
+	quotas := []WorkingMemoryQuota{config.Default}
+	for _, quota := range config.Agents {
+		quotas = append(quotas, quota)
+	}
+	for _, quota := range quotas {
+		if quota.MaxEntries < 0 || quota.MaxBytes < 0 {
+			return fmt.Errorf("working memory quota must not be negative")
+		}
+	}
+
+	s.quotas.mu.Lock()
+	defer s.quotas.mu.Unlock()
+	s.quotas.config = config
+	return nil
+}
+
+// WorkingMemoryEvictions returns the number of working memory entries
+// evicted for the agent to stay within its quota
+func (s *Service) WorkingMemoryEvictions(agentID string) int64 {
+	s.quotas.mu.RLock()
+	defer s.quotas.mu.RUnlock()
+	return s.quotas.evictions[agentID]
+}
+
+func (s *Service) workingQuota(agentID string) WorkingMemoryQuota {
+	s.quotas.mu.RLock()
+	defer s.quotas.mu.RUnlock()
+
+	if quota, ok := s.quotas.config.Agents[agentID]; ok {
+		return quota
+	}
+	return s.quotas.config.Default
+}
+
+// workingMemorySize approximates the stored size of a working memory value
+func workingMemorySize(value interface{}) int64 {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// checkWorkingQuota rejects a value that could never fit the agent's quota
+func (s *Service) checkWorkingQuota(agentID string, value interface{}) error {
+	quota := s.workingQuota(agentID)
+	if quota.MaxBytes > 0 {
+		if size := workingMemorySize(value); size > quota.MaxBytes {
+			return fmt.Errorf("%w: %d bytes, quota %d bytes", ErrWorkingMemoryQuota, size, quota.MaxBytes)
+		}
+	}
+	return nil
+}
+
+// enforceWorkingQuota evicts the agent's least recently accessed working
+// memory entries, other than keep, until the agent is within its quota.
+// Expired entries are evicted before live ones.
+func (s *Service) enforceWorkingQuota(ctx context.Context, agentID, keep string) error {
+	quota := s.workingQuota(agentID)
+	if quota.unlimited() {
+		return nil
+	}
+
+	memories, err := s.repo.ListWorking(ctx, agentID, MemoryFilters{})
+	if err != nil {
+		return fmt.Errorf("failed to list working memory for quota: %w", err)
+	}
+
+	count := len(memories)
+	var total int64
+	sizes := make(map[string]int64, len(memories))
+	candidates := make([]*WorkingMemory, 0, len(memories))
+	for _, mem := range memories {
+		sizes[mem.Key] = workingMemorySize(mem.Value)
+		total += sizes[mem.Key]
+		if mem.Key != keep {
+			candidates = append(candidates, mem)
+		}
+	}
+
+	now := s.clock.Now()
+	sort.SliceStable(candidates, func(i, j int) bool {
+		expiredI, expiredJ := !candidates[i].ExpiresAt.After(now), !candidates[j].ExpiresAt.After(now)
+		if expiredI != expiredJ {
+			return expiredI
+		}
+		return lastAccess(candidates[i]).Before(lastAccess(candidates[j]))
+	})
+
+	for _, mem := range candidates {
+		overCount := quota.MaxEntries > 0 && count > quota.MaxEntries
+		overBytes := quota.MaxBytes > 0 && total > quota.MaxBytes
+		if !overCount && !overBytes {
+			break
+		}
+
+		err := s.repo.DeleteWorking(ctx, agentID, mem.Key)
+		s.recordAccess(ctx, agentID, MemoryTypeWorking, mem.Key, AuditOpDelete, err)
+		if err != nil {
+			return fmt.Errorf("failed to evict working memory %s: %w", mem.Key, err)
+		}
+		count--
+		total -= sizes[mem.Key]
+
+		s.quotas.mu.Lock()
+		if s.quotas.evictions == nil {
+			s.quotas.evictions = make(map[string]int64)
+		}
+		s.quotas.evictions[agentID]++
+		s.quotas.mu.Unlock()
+
+		log.WithFields(log.Fields{
+			"agent_id":    agentID,
+			"key":         mem.Key,
+			"size_bytes":  sizes[mem.Key],
+			"last_access": lastAccess(mem),
+			"over_count":  overCount,
+			"over_bytes":  overBytes,
+		}).Info("Evicted working memory over quota")
+	}
+
+	return nil
+}
+
+// lastAccess returns when a working memory entry was last read or written
+func lastAccess(mem *WorkingMemory) time.Time {
+	last := mem.CreatedAt
+	if mem.UpdatedAt.After(last) {
+		last = mem.UpdatedAt
+	}
+	if mem.AccessedAt.After(last) {
+		last = mem.AccessedAt
+	}
+	return last
+}
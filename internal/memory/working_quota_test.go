@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+func TestService_WorkingMemoryQuota(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	repo := NewMockRepository()
+	repo.SetClock(clk)
+	service := NewService(repo)
+	service.SetClock(clk)
+
+	err := service.SetWorkingMemoryQuota(WorkingMemoryQuotaConfig{
+		Default: WorkingMemoryQuota{MaxEntries: 3},
+		Agents:  map[string]WorkingMemoryQuota{"COORD-NORTH": {MaxBytes: 100}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	keys := func(agentID string) map[string]bool {
+		memories, err := service.ListWorking(ctx, agentID, MemoryFilters{})
+		if err != nil {
+			t.Fatalf("Failed to list working memory: %v", err)
+		}
+		out := make(map[string]bool)
+		for _, mem := range memories {
+			out[mem.Key] = true
+		}
+		return out
+	}
+	store := func(agentID, key string, value interface{}, ttl time.Duration) {
+		clk.Advance(time.Second)
+		if err := service.StoreWorking(ctx, agentID, key, value, ttl); err != nil {
+			t.Fatalf("Failed to store %s: %v", key, err)
+		}
+	}
+
+	// Count quota: the least recently used entry goes first
+	store("PUMP-002", "a", 1, time.Hour)
+	store("PUMP-002", "b", 2, time.Hour)
+	store("PUMP-002", "c", 3, time.Hour)
+	clk.Advance(time.Second)
+	if err := service.UpdateWorking(ctx, "PUMP-002", "a", 10); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	store("PUMP-002", "d", 4, time.Hour)
+
+	got := keys("PUMP-002")
+	if len(got) != 3 || got["b"] || !got["a"] || !got["d"] {
+		t.Errorf("Expected b to be evicted, got %v", got)
+	}
+
+	// Expired entries are evicted before live ones
+	store("PUMP-002", "short", 5, time.Second)
+	clk.Advance(time.Minute)
+	store("PUMP-002", "e", 6, time.Hour)
+	if got := keys("PUMP-002"); got["short"] || len(got) != 3 {
+		t.Errorf("Expected the expired entry to be evicted, got %v", got)
+	}
+
+	// Byte quota for one agent
+	store("COORD-NORTH", "plan", strings.Repeat("x", 60), time.Hour)
+	store("COORD-NORTH", "route", strings.Repeat("y", 60), time.Hour)
+	if got := keys("COORD-NORTH"); len(got) != 1 || !got["route"] {
+		t.Errorf("Expected only route to remain, got %v", got)
+	}
+	if err := service.StoreWorking(ctx, "COORD-NORTH", "huge", strings.Repeat("z", 200), time.Hour); !errors.Is(err, ErrWorkingMemoryQuota) {
+		t.Errorf("Expected ErrWorkingMemoryQuota, got %v", err)
+	}
+
+	stats, err := service.GetMemoryStats(ctx, "PUMP-002")
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.WorkingMemoryEvictions != 3 || service.WorkingMemoryEvictions("COORD-NORTH") != 1 {
+		t.Errorf("Unexpected evictions: PUMP-002 %d, COORD-NORTH %d", stats.WorkingMemoryEvictions, service.WorkingMemoryEvictions("COORD-NORTH"))
+	}
+
+	if err := service.SetWorkingMemoryQuota(WorkingMemoryQuotaConfig{Default: WorkingMemoryQuota{MaxEntries: -1}}); err == nil {
+		t.Error("Expected an error for a negative quota")
+	}
+}
@@ -0,0 +1,143 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TaskTypeManualApproval is the type of tasks that wait for a person to
+// approve or reject them, such as control-room sign-off on maintenance work
+const TaskTypeManualApproval = "manual_approval"
+
+var (
+	// ErrApprovalNotPending is returned for decisions on a task that is not
+	// awaiting approval
+	ErrApprovalNotPending = errors.New("task is not awaiting approval")
+
+	// ErrTaskRejected fails manual approval tasks that were rejected
+	ErrTaskRejected = errors.New("task rejected")
+
+	// ErrApprovalTimedOut fails manual approval tasks not decided within their timeout
+	ErrApprovalTimedOut = errors.New("approval timed out")
+)
+
+// TaskApproval is a person's decision on a manual approval task
+type TaskApproval struct {
+	Approved  bool      `json:"approved"`
+	Approver  string    `json:"approver"`
+	Comment   string    `json:"comment,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+type approvalKey struct {
+	executionID string
+	taskID      string
+}
+
+// executeApproval waits for a decision on a manual approval task. The batch
+// holding the task waits with it; a task timeout bounds the wait.
+func (e *Engine) executeApproval(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, execution *WorkflowExecution) error {
+	key := approvalKey{executionID: execution.ID, taskID: task.ID}
+	decisions := make(chan TaskApproval, 1)
+	e.approvalMutex.Lock()
+	e.approvals[key] = decisions
+	e.approvalMutex.Unlock()
+	defer func() {
+		e.approvalMutex.Lock()
+		delete(e.approvals, key)
+		e.approvalMutex.Unlock()
+	}()
+
+	e.withState(execution, func() {
+		taskExecution.Status = TaskStatusAwaitingApproval
+		taskExecution.Logs = append(taskExecution.Logs, "Awaiting approval")
+		e.updateExecution(ctx, execution)
+	})
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+	}).Info("Task awaiting approval")
+
+	var timeout <-chan time.Time
+	if task.Timeout > 0 {
+		timeout = e.clock.After(task.Timeout)
+	}
+
+	var decision *TaskApproval
+	var err error
+	select {
+	case approval := <-decisions:
+		decision = &approval
+		if !approval.Approved {
+			err = fmt.Errorf("%w by %s", ErrTaskRejected, approval.Approver)
+			if approval.Comment != "" {
+				err = fmt.Errorf("%w: %s", err, approval.Comment)
+			}
+		}
+	case <-timeout:
+		err = fmt.Errorf("%w after %s", ErrApprovalTimedOut, task.Timeout)
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	}
+
+	e.withState(execution, func() {
+		if decision != nil {
+			taskExecution.Approval = decision
+			taskExecution.Output["approved"] = decision.Approved
+			taskExecution.Output["approver"] = decision.Approver
+			if decision.Approved {
+				taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Approved by %s", decision.Approver))
+			}
+		}
+
+		now := e.clock.Now()
+		taskExecution.EndTime = &now
+		taskExecution.Duration = now.Sub(taskExecution.StartTime)
+		if err != nil {
+			taskExecution.Status = TaskStatusFailed
+			taskExecution.Error = err.Error()
+		} else {
+			taskExecution.Status = TaskStatusCompleted
+		}
+		e.updateExecution(ctx, execution)
+	})
+	return err
+}
+
+// ApproveTask records a person's decision on a manual approval task. The
+// decision is stored with the task and releases the batch waiting on it.
+func (e *Engine) ApproveTask(ctx context.Context, executionID, taskID string, decision TaskApproval) error {
+	if decision.Approver == "" {
+		return fmt.Errorf("approver is required")
+	}
+	if decision.DecidedAt.IsZero() {
+		decision.DecidedAt = e.clock.Now()
+	}
+
+	key := approvalKey{executionID: executionID, taskID: taskID}
+	e.approvalMutex.Lock()
+	decisions, pending := e.approvals[key]
+	if pending {
+		// Only the first decision counts
+		delete(e.approvals, key)
+	}
+	e.approvalMutex.Unlock()
+	if !pending {
+		return fmt.Errorf("%w: task %s of execution %s", ErrApprovalNotPending, taskID, executionID)
+	}
+
+	decisions <- decision
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": executionID,
+		"task_id":      taskID,
+		"approver":     decision.Approver,
+		"approved":     decision.Approved,
+	}).Info("Recorded task approval decision")
+	return nil
+}
@@ -0,0 +1,105 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_ManualApproval(t *testing.T) {
+	ctx := context.Background()
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "valve-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := newSharedRepository()
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	workflow := &Workflow{
+		ID:           "work-order",
+		Tasks:        []WorkflowTask{{ID: "sign_off", Type: TaskTypeManualApproval}, {ID: "close_valve", Type: "actuate"}},
+		Dependencies: map[string][]string{"close_valve": {"sign_off"}},
+	}
+	awaitStored := func(executionID string, done func(*WorkflowExecution) bool) *WorkflowExecution {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			stored, err := repository.GetExecution(ctx, executionID)
+			if err == nil && done(stored) {
+				return stored
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("execution %s did not reach the expected state", executionID)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	awaitingApproval := func(stored *WorkflowExecution) bool {
+		return stored.TaskExecutions["sign_off"].Status == TaskStatusAwaitingApproval
+	}
+	finished := func(stored *WorkflowExecution) bool {
+		return stored.Status == WorkflowStatusCompleted || stored.Status == WorkflowStatusFailed
+	}
+
+	// The batch waits until the task is approved
+	approved, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	awaitStored(approved.ID, awaitingApproval)
+	coordinator.mu.Lock()
+	dispatched := len(coordinator.dispatched)
+	coordinator.mu.Unlock()
+	if dispatched != 0 {
+		t.Errorf("expected no task to run before the approval, got %d", dispatched)
+	}
+	decision := TaskApproval{Approved: true, Approver: "operator-7", Comment: "isolation confirmed"}
+	if err := engine.ApproveTask(ctx, approved.ID, "sign_off", decision); err != nil {
+		t.Fatal(err)
+	}
+	stored := awaitStored(approved.ID, finished)
+	if stored.Status != WorkflowStatusCompleted {
+		t.Errorf("expected the approved execution to complete, got %s (%s)", stored.Status, stored.Error)
+	}
+	approval := stored.TaskExecutions["sign_off"].Approval
+	if approval == nil || approval.Approver != "operator-7" || approval.Comment != "isolation confirmed" || approval.DecidedAt.IsZero() {
+		t.Errorf("expected the decision to be recorded, got %+v", approval)
+	}
+	if err := engine.ApproveTask(ctx, approved.ID, "sign_off", decision); !errors.Is(err, ErrApprovalNotPending) {
+		t.Errorf("expected ErrApprovalNotPending for a decided task, got %v", err)
+	}
+
+	// A rejection fails the task and the tasks after it do not run
+	rejected, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	awaitStored(rejected.ID, awaitingApproval)
+	if err := engine.ApproveTask(ctx, rejected.ID, "sign_off", TaskApproval{Approver: "operator-7", Comment: "pressure too high"}); err != nil {
+		t.Fatal(err)
+	}
+	stored = awaitStored(rejected.ID, finished)
+	signOff := stored.TaskExecutions["sign_off"]
+	if stored.Status != WorkflowStatusFailed || signOff.Status != TaskStatusFailed || !strings.Contains(signOff.Error, "pressure too high") {
+		t.Errorf("expected the rejection to fail the execution, got %s (%s)", stored.Status, signOff.Error)
+	}
+	coordinator.mu.Lock()
+	dispatched = len(coordinator.dispatched)
+	coordinator.mu.Unlock()
+	if dispatched != 1 {
+		t.Errorf("expected only the approved work order to close the valve, got %d dispatches", dispatched)
+	}
+}
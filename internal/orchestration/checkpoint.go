@@ -0,0 +1,174 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckpointStore loads the checkpoints agents save for long-running tasks.
+// memory.Service implements it.
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, agentID, executionID, taskID string) (*memory.TaskCheckpoint, error)
+}
+
+// TaskProgress is the progress of a task execution as reported by its agent's checkpoints
+type TaskProgress struct {
+	// Percent is how much of the task is complete (0-100)
+	Percent float64 `json:"percent"`
+
+	// Message describes the current stage of the work
+	Message string `json:"message,omitempty"`
+
+	// Checkpoints is the number of checkpoints the agent has saved
+	Checkpoints int `json:"checkpoints"`
+
+	// UpdatedAt is when the latest checkpoint was saved
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Resumes counts the attempts that continued from a checkpoint instead of starting over
+	Resumes int `json:"resumes,omitempty"`
+}
+
+// SetCheckpointStore enables checkpoint tracking. Running tasks report the
+// progress of their latest checkpoint and retried tasks resume from it.
+func (e *Engine) SetCheckpointStore(store CheckpointStore) {
+	e.checkpoints = store
+}
+
+// ResumePoint returns the checkpoint a restarted agent should continue a task
+// execution from, or memory.ErrNoCheckpoint if the task must start over
+func (e *Engine) ResumePoint(ctx context.Context, executionID, taskID string) (*memory.TaskCheckpoint, error) {
+	if e.checkpoints == nil {
+		return nil, fmt.Errorf("checkpoints are not enabled")
+	}
+
+	execution, err := e.repository.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	taskExecution, exists := execution.TaskExecutions[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task not found in execution: %s", taskID)
+	}
+	if taskExecution.AgentID == "" {
+		return nil, memory.ErrNoCheckpoint
+	}
+
+	return e.checkpoints.LoadCheckpoint(ctx, taskExecution.AgentID, executionID, taskID)
+}
+
+// refreshProgress updates running tasks from their latest checkpoints and
+// recalculates the overall progress of the execution. It reports whether
+// anything changed.
+func (e *Engine) refreshProgress(ctx context.Context, execution *WorkflowExecution) bool {
+	// Checkpoints are loaded without holding the state lock
+	agentIDs := make(map[string]string)
+	e.withState(execution, func() {
+		for taskID, taskExecution := range execution.TaskExecutions {
+			if taskExecution.AgentID != "" && (taskExecution.Status == TaskStatusRunning || taskExecution.Status == TaskStatusRetrying) {
+				agentIDs[taskID] = taskExecution.AgentID
+			}
+		}
+	})
+
+	checkpoints := make(map[string]*memory.TaskCheckpoint)
+	if e.checkpoints != nil {
+		for taskID, agentID := range agentIDs {
+			checkpoint, err := e.checkpoints.LoadCheckpoint(ctx, agentID, execution.ID, taskID)
+			if err != nil {
+				if !errors.Is(err, memory.ErrNoCheckpoint) {
+					e.logger.WithError(err).WithFields(log.Fields{
+						"execution_id": execution.ID,
+						"task_id":      taskID,
+					}).Warn("Failed to load task checkpoint")
+				}
+				continue
+			}
+			checkpoints[taskID] = checkpoint
+		}
+	}
+
+	changed := false
+	e.withState(execution, func() {
+		for taskID, checkpoint := range checkpoints {
+			taskExecution := execution.TaskExecutions[taskID]
+			if taskExecution.Status != TaskStatusRunning && taskExecution.Status != TaskStatusRetrying {
+				continue
+			}
+			if applyCheckpoint(taskExecution, checkpoint) {
+				changed = true
+			}
+		}
+
+		progress := executionProgress(execution)
+		if progress != execution.Progress {
+			execution.Progress = progress
+			changed = true
+		}
+	})
+	return changed
+}
+
+// resumeFromCheckpoint records that a retried task continues from its latest
+// checkpoint. It returns the checkpoint, or nil if the task starts over.
+func (e *Engine) resumeFromCheckpoint(ctx context.Context, taskExecution *TaskExecution, execution *WorkflowExecution) *memory.TaskCheckpoint {
+	if e.checkpoints == nil {
+		return nil
+	}
+
+	var agentID string
+	e.withState(execution, func() {
+		agentID = taskExecution.AgentID
+	})
+	checkpoint, err := e.checkpoints.LoadCheckpoint(ctx, agentID, execution.ID, taskExecution.TaskID)
+	if err != nil {
+		return nil
+	}
+
+	e.withState(execution, func() {
+		applyCheckpoint(taskExecution, checkpoint)
+		taskExecution.Progress.Resumes++
+		taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Attempt %d resuming from checkpoint %d at %.0f%%", taskExecution.Attempts, checkpoint.Sequence, checkpoint.Percent))
+	})
+	return checkpoint
+}
+
+// applyCheckpoint copies a checkpoint into the task's progress, reporting whether it was new
+func applyCheckpoint(taskExecution *TaskExecution, checkpoint *memory.TaskCheckpoint) bool {
+	if taskExecution.Progress == nil {
+		taskExecution.Progress = &TaskProgress{}
+	} else if taskExecution.Progress.Checkpoints == checkpoint.Sequence {
+		return false
+	}
+
+	taskExecution.Progress.Percent = checkpoint.Percent
+	taskExecution.Progress.Message = checkpoint.Message
+	taskExecution.Progress.Checkpoints = checkpoint.Sequence
+	taskExecution.Progress.UpdatedAt = checkpoint.RecordedAt
+	return true
+}
+
+// executionProgress averages task progress: finished tasks count as complete
+// and running tasks count their checkpointed percent. Callers hold the
+// execution's state lock.
+func executionProgress(execution *WorkflowExecution) float64 {
+	if len(execution.TaskExecutions) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, taskExecution := range execution.TaskExecutions {
+		switch {
+		case taskExecution.Status == TaskStatusCompleted || taskExecution.Status == TaskStatusSkipped:
+			total += 100
+		case taskExecution.Progress != nil:
+			total += taskExecution.Progress.Percent
+		}
+	}
+	return total / float64(len(execution.TaskExecutions))
+}
@@ -0,0 +1,60 @@
+package orchestration
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/memory"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_RefreshProgressFromCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewService(memory.NewMockRepository())
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	engine := NewEngine(OrchestrationConfig{}, nil, nil, nil, logger)
+	engine.SetCheckpointStore(store)
+
+	execution := &WorkflowExecution{
+		ID: "exec-1",
+		TaskExecutions: map[string]*TaskExecution{
+			"fetch":   {TaskID: "fetch", AgentID: "analyst-1", Status: TaskStatusCompleted},
+			"analyze": {TaskID: "analyze", AgentID: "analyst-1", Status: TaskStatusRunning},
+		},
+	}
+
+	if !engine.refreshProgress(ctx, execution) || execution.Progress != 50 {
+		t.Fatalf("expected 50%% progress before checkpoints, got %v", execution.Progress)
+	}
+
+	if err := store.SaveCheckpoint(ctx, &memory.TaskCheckpoint{AgentID: "analyst-1", ExecutionID: "exec-1", TaskID: "analyze", Percent: 40, Message: "batch 4 of 10"}); err != nil {
+		t.Fatal(err)
+	}
+	if !engine.refreshProgress(ctx, execution) {
+		t.Fatal("expected the checkpoint to change progress")
+	}
+	progress := execution.TaskExecutions["analyze"].Progress
+	if progress == nil || progress.Percent != 40 || progress.Message != "batch 4 of 10" || progress.Checkpoints != 1 {
+		t.Fatalf("unexpected task progress %+v", progress)
+	}
+	if execution.Progress != 70 {
+		t.Errorf("expected 70%% overall progress, got %v", execution.Progress)
+	}
+	if engine.refreshProgress(ctx, execution) {
+		t.Error("expected no change without a new checkpoint")
+	}
+
+	// A retried attempt resumes from the checkpoint
+	taskExecution := execution.TaskExecutions["analyze"]
+	taskExecution.Attempts = 2
+	checkpoint := engine.resumeFromCheckpoint(ctx, taskExecution, execution)
+	if checkpoint == nil || checkpoint.Percent != 40 || taskExecution.Progress.Resumes != 1 {
+		t.Fatalf("expected to resume from the checkpoint, got %+v", checkpoint)
+	}
+	if len(taskExecution.Logs) != 1 {
+		t.Errorf("expected the resume to be logged, got %v", taskExecution.Logs)
+	}
+}
@@ -0,0 +1,163 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FailurePolicyRollback makes a failed execution undo its completed tasks by
+// running their compensations in reverse order
+const FailurePolicyRollback = "rollback"
+
+// compensationTask returns the compensation of a task, with its ID defaulted
+func compensationTask(task *WorkflowTask) *WorkflowTask {
+	compensation := *task.Compensation
+	if compensation.ID == "" {
+		compensation.ID = task.ID + "_compensation"
+	}
+	return &compensation
+}
+
+// compensate runs the compensations of completed tasks in reverse dependency
+// order, so a task is undone only after every task that built on it. A failed
+// compensation does not stop the rollback; the failures are returned together.
+func (e *Engine) compensate(ctx context.Context, workflow *Workflow, execution *WorkflowExecution, depGraph *DependencyGraph) error {
+	order, err := depGraph.GetTopologicalOrder()
+	if err != nil {
+		return err
+	}
+	taskMap := make(map[string]*WorkflowTask)
+	for i := range workflow.Tasks {
+		taskMap[workflow.Tasks[i].ID] = &workflow.Tasks[i]
+	}
+
+	e.withState(execution, func() {
+		execution.Status = WorkflowStatusCompensating
+		e.updateExecution(ctx, execution)
+	})
+
+	var failures []string
+	for i := len(order) - 1; i >= 0; i-- {
+		task := taskMap[order[i]]
+		var taskExecution *TaskExecution
+		completed := false
+		e.withState(execution, func() {
+			taskExecution = execution.TaskExecutions[order[i]]
+			completed = taskExecution.Status == TaskStatusCompleted
+		})
+		if task == nil || task.Compensation == nil || !completed {
+			continue
+		}
+
+		if err := e.executeCompensation(ctx, task, taskExecution, execution); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", task.ID, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("compensation failed for %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// executeCompensation runs the compensation of a completed task, on the agent
+// that ran the task unless the compensation selects its own agents
+func (e *Engine) executeCompensation(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, execution *WorkflowExecution) error {
+	compensation := compensationTask(task)
+	compensationExecution := &TaskExecution{
+		TaskID:    compensation.ID,
+		Status:    TaskStatusRunning,
+		StartTime: e.clock.Now(),
+		Output:    make(map[string]interface{}),
+		Logs:      make([]string, 0),
+	}
+
+	err := func() error {
+		var selector AgentSelector
+		var err error
+		e.withState(execution, func() {
+			taskExecution.Compensation = compensationExecution
+
+			// Inputs may refer to the output of the task being undone
+			if len(compensation.InputMapping) > 0 {
+				var inputs map[string]interface{}
+				if inputs, err = resolveInputs(compensation.InputMapping, execution); err != nil {
+					return
+				}
+				compensationExecution.Inputs = inputs
+			}
+
+			selector = resolveAgentSelector(compensation.AgentSelector, execution)
+			if selector.Strategy == "" {
+				selector = AgentSelector{Strategy: AgentSelectionSpecific, SpecificAgents: []string{taskExecution.AgentID}}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve inputs: %w", err)
+		}
+
+		agents, err := e.coordinator.SelectAgents(ctx, selector, 1)
+		if err != nil {
+			return fmt.Errorf("failed to select agent: %w", err)
+		}
+		if len(agents) == 0 {
+			return fmt.Errorf("no available agents")
+		}
+		e.withState(execution, func() {
+			compensationExecution.AgentID = agents[0].ID
+			e.updateExecution(ctx, execution)
+		})
+
+		return e.executeTaskWithRetry(ctx, compensation, compensationExecution, agents[0], execution)
+	}()
+
+	e.withState(execution, func() {
+		now := e.clock.Now()
+		compensationExecution.EndTime = &now
+		compensationExecution.Duration = now.Sub(compensationExecution.StartTime)
+
+		if err != nil {
+			compensationExecution.Status = TaskStatusFailed
+			compensationExecution.Error = err.Error()
+		} else {
+			compensationExecution.Status = TaskStatusCompleted
+			taskExecution.Status = TaskStatusCompensated
+		}
+		e.updateExecution(ctx, execution)
+	})
+
+	if err != nil {
+		e.logger.WithFields(log.Fields{
+			"execution_id": execution.ID,
+			"task_id":      task.ID,
+		}).WithError(err).Error("Task compensation failed")
+		return err
+	}
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+	}).Info("Task compensated")
+	return nil
+}
+
+// validateCompensation checks the compensation of a task
+func validateCompensation(task WorkflowTask, taskIDs map[string]bool) error {
+	compensation := task.Compensation
+	if compensation == nil {
+		return nil
+	}
+	if compensation.Branch != nil || compensation.Loop != nil || compensation.Compensation != nil {
+		return fmt.Errorf("compensation of task %s cannot be a branch or loop, or have its own compensation", task.ID)
+	}
+	for name, value := range compensation.InputMapping {
+		// A compensation may refer to the output of the task it undoes
+		if err := validateReferences(value, task.ID, taskIDs, true); err != nil {
+			return fmt.Errorf("compensation of task %s input %s %w", task.ID, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_RollbackCompensatesInReverseOrder(t *testing.T) {
+	ctx := context.Background()
+
+	var compensated []interface{}
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		payload := task.Payload.(map[string]interface{})
+		parameters, _ := payload["parameters"].(map[string]interface{})
+		switch payload["task_id"] {
+		case "charge":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"payment_id": "pay-7"}}
+		case "ship":
+			return &agent.TaskResult{Error: errors.New("carrier unavailable")}
+		case "refund", "reserve_compensation":
+			compensated = append(compensated, payload["task_id"], parameters["payment_id"])
+		case "unnotify":
+			return &agent.TaskResult{Error: errors.New("mailbox gone")}
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	workflow := &Workflow{
+		ID: "wf-1",
+		Tasks: []WorkflowTask{
+			{ID: "reserve", Type: "inventory", Compensation: &WorkflowTask{Type: "inventory_release"}},
+			{ID: "charge", Type: "payment", Compensation: &WorkflowTask{
+				ID:           "refund",
+				Type:         "payment_refund",
+				InputMapping: map[string]string{"payment_id": "${tasks.charge.output.payment_id}"},
+			}},
+			{ID: "notify", Type: "email", Compensation: &WorkflowTask{ID: "unnotify", Type: "email"}},
+			{ID: "ship", Type: "shipping"},
+		},
+		Dependencies: map[string][]string{
+			"charge": {"reserve"},
+			"notify": {"charge"},
+			"ship":   {"notify"},
+		},
+		Configuration: WorkflowConfiguration{FailurePolicy: FailurePolicy{OnTaskFailure: FailurePolicyRollback}},
+	}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(execution.ID) != WorkflowStatusFailed {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to fail, got %s", repository.status(execution.ID))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Compensations run newest first and a failed one does not stop the rollback
+	if len(compensated) != 4 || compensated[0] != "refund" || compensated[1] != "pay-7" || compensated[2] != "reserve_compensation" {
+		t.Errorf("unexpected compensations %v", compensated)
+	}
+	tasks := execution.TaskExecutions
+	if tasks["reserve"].Status != TaskStatusCompensated || tasks["charge"].Status != TaskStatusCompensated {
+		t.Errorf("expected reserve and charge to be compensated, got %s and %s", tasks["reserve"].Status, tasks["charge"].Status)
+	}
+	if tasks["notify"].Status != TaskStatusCompleted || tasks["notify"].Compensation.Status != TaskStatusFailed {
+		t.Errorf("expected the notify compensation to fail, got %s", tasks["notify"].Status)
+	}
+	if !strings.Contains(execution.Error, "carrier unavailable") || !strings.Contains(execution.Error, "rollback incomplete") {
+		t.Errorf("unexpected execution error %q", execution.Error)
+	}
+}
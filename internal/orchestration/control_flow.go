@@ -0,0 +1,439 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+// Loop iteration bounds
+const (
+	DefaultLoopMaxIterations = 100
+	MaxLoopIterations        = 1000
+)
+
+// comparisonOperators are checked in order, so two-character operators come first
+var comparisonOperators = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// evaluateExpression evaluates a condition such as
+// ${tasks.analyze.output.leak_probability} > 0.7 && ${context.region} == "north".
+// Comparisons may be combined with && and ||, && binding tighter; an operand
+// on its own is tested for truthiness.
+func evaluateExpression(expr string, execution *WorkflowExecution) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return false, fmt.Errorf("empty expression")
+	}
+
+	for _, disjunct := range strings.Split(expr, "||") {
+		holds := true
+		for _, conjunct := range strings.Split(disjunct, "&&") {
+			ok, err := evaluateComparison(strings.TrimSpace(conjunct), execution)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				holds = false
+				break
+			}
+		}
+		if holds {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evaluateComparison(expr string, execution *WorkflowExecution) (bool, error) {
+	for i := 0; i < len(expr); i++ {
+		for _, op := range comparisonOperators {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+			left, err := evaluateOperand(expr[:i], execution)
+			if err != nil {
+				return false, err
+			}
+			right, err := evaluateOperand(expr[i+len(op):], execution)
+			if err != nil {
+				return false, err
+			}
+			return compareValues(left, right, op)
+		}
+	}
+
+	value, err := evaluateOperand(expr, execution)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// evaluateOperand returns the value of a ${...} reference or a literal
+func evaluateOperand(operand string, execution *WorkflowExecution) (interface{}, error) {
+	operand = strings.TrimSpace(operand)
+
+	if match := inputExpression.FindStringSubmatch(operand); match != nil && match[0] == operand {
+		return lookupExpression(match[1], execution)
+	}
+	if len(operand) >= 2 && (operand[0] == '"' || operand[0] == '\'') && operand[len(operand)-1] == operand[0] {
+		return operand[1 : len(operand)-1], nil
+	}
+	switch operand {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if number, err := strconv.ParseFloat(operand, 64); err == nil {
+		return number, nil
+	}
+	return nil, fmt.Errorf("invalid operand %q", operand)
+}
+
+func compareValues(left, right interface{}, op string) (bool, error) {
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			}
+		}
+	}
+
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case ">":
+				return l > r, nil
+			case ">=":
+				return l >= r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			}
+		}
+	}
+
+	switch op {
+	case "==":
+		return fmt.Sprint(left) == fmt.Sprint(right), nil
+	case "!=":
+		return fmt.Sprint(left) != fmt.Sprint(right), nil
+	}
+	return false, fmt.Errorf("cannot compare %v %s %v", left, op, right)
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	if number, ok := toNumber(value); ok {
+		return number != 0
+	}
+	return true
+}
+
+// executeBranch evaluates a branch task and skips the tasks of the path not taken
+func (e *Engine) executeBranch(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, execution *WorkflowExecution) error {
+	var err error
+	e.withState(execution, func() {
+		err = e.takeBranch(ctx, task, taskExecution, execution)
+	})
+	return err
+}
+
+// takeBranch evaluates a branch holding the execution's state lock
+func (e *Engine) takeBranch(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, execution *WorkflowExecution) error {
+	taskExecution.Status = TaskStatusRunning
+	taskExecution.StartTime = e.clock.Now()
+
+	holds, err := evaluateExpression(task.Branch.Condition, execution)
+
+	now := e.clock.Now()
+	taskExecution.EndTime = &now
+	taskExecution.Duration = now.Sub(taskExecution.StartTime)
+	if err != nil {
+		taskExecution.Status = TaskStatusFailed
+		taskExecution.Error = err.Error()
+		e.updateExecution(ctx, execution)
+		return fmt.Errorf("failed to evaluate branch %s: %w", task.ID, err)
+	}
+
+	taken, skipped := "then", task.Branch.Else
+	if !holds {
+		taken, skipped = "else", task.Branch.Then
+	}
+	for _, taskID := range skipped {
+		if skippedExecution, exists := execution.TaskExecutions[taskID]; exists {
+			skippedExecution.Status = TaskStatusSkipped
+			skippedExecution.SkippedByBranch = task.ID
+		}
+	}
+
+	taskExecution.Status = TaskStatusCompleted
+	taskExecution.Output["condition"] = holds
+	taskExecution.Output["branch"] = taken
+	taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Branch %s took the %s path", task.ID, taken))
+	e.updateExecution(ctx, execution)
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+		"branch":       taken,
+	}).Debug("Evaluated branch")
+
+	return nil
+}
+
+// skipAfterBranch skips a task whose dependencies were all skipped by a branch,
+// so the whole untaken path is skipped. It reports whether the task was skipped.
+// Callers hold the execution's state lock.
+func (e *Engine) skipAfterBranch(taskID string, execution *WorkflowExecution, depGraph *DependencyGraph) bool {
+	taskExecution := execution.TaskExecutions[taskID]
+	if taskExecution.Status == TaskStatusSkipped {
+		return taskExecution.SkippedByBranch != ""
+	}
+
+	deps, err := depGraph.GetNodeDependencies(taskID)
+	if err != nil || len(deps) == 0 {
+		return false
+	}
+	branch := ""
+	for _, depID := range deps {
+		dep, exists := execution.TaskExecutions[depID]
+		if !exists || dep.SkippedByBranch == "" {
+			return false
+		}
+		branch = dep.SkippedByBranch
+	}
+
+	taskExecution.Status = TaskStatusSkipped
+	taskExecution.SkippedByBranch = branch
+	return true
+}
+
+// executeLoop runs a loop task once per item of its list, or until its
+// condition holds
+func (e *Engine) executeLoop(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, ag *agent.Agent, execution *WorkflowExecution) error {
+	maxIterations := task.Loop.MaxIterations
+	if maxIterations == 0 {
+		maxIterations = DefaultLoopMaxIterations
+	}
+	var baseInputs map[string]interface{}
+	e.withState(execution, func() {
+		baseInputs = taskExecution.Inputs
+	})
+	defer e.withState(execution, func() { taskExecution.Inputs = baseInputs })
+
+	// iterate runs one iteration with extra inputs
+	iterate := func(inputs map[string]interface{}) error {
+		if err := e.awaitScheduling(ctx, execution); err != nil {
+			return err
+		}
+		e.withState(execution, func() {
+			taskExecution.Inputs = make(map[string]interface{}, len(baseInputs)+len(inputs))
+			for k, v := range baseInputs {
+				taskExecution.Inputs[k] = v
+			}
+			for k, v := range inputs {
+				taskExecution.Inputs[k] = v
+			}
+			taskExecution.Output = make(map[string]interface{})
+			taskExecution.Iterations++
+		})
+		return e.executeTaskWithRetry(ctx, task, taskExecution, ag, execution)
+	}
+
+	if task.Loop.ForEach != "" {
+		var value interface{}
+		var err error
+		e.withState(execution, func() {
+			value, err = resolveInput(task.Loop.ForEach, execution)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resolve loop items: %w", err)
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("loop items %s are not a list", task.Loop.ForEach)
+		}
+		if len(items) > maxIterations {
+			return fmt.Errorf("loop has %d items, more than the %d iterations allowed", len(items), maxIterations)
+		}
+
+		iterations := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			if err := iterate(map[string]interface{}{"item": item, "index": i}); err != nil {
+				return fmt.Errorf("loop iteration %d: %w", i, err)
+			}
+			e.withState(execution, func() {
+				iterations = append(iterations, taskExecution.Output)
+			})
+		}
+		e.withState(execution, func() {
+			taskExecution.Output = map[string]interface{}{"iterations": iterations}
+		})
+		return nil
+	}
+
+	for i := 1; i <= maxIterations; i++ {
+		if err := iterate(map[string]interface{}{"iteration": i}); err != nil {
+			return fmt.Errorf("loop iteration %d: %w", i, err)
+		}
+		var done bool
+		var err error
+		e.withState(execution, func() {
+			done, err = evaluateExpression(task.Loop.Until, loopView(execution, task.ID, taskExecution))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to evaluate loop condition: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+	return fmt.Errorf("loop condition %s not met after %d iterations", task.Loop.Until, maxIterations)
+}
+
+// loopView returns the execution as seen by a loop condition, in which the
+// loop task's latest output is available. Callers hold the execution's state
+// lock.
+func loopView(execution *WorkflowExecution, taskID string, taskExecution *TaskExecution) *WorkflowExecution {
+	view := *execution
+	view.TaskExecutions = make(map[string]*TaskExecution, len(execution.TaskExecutions))
+	for id, te := range execution.TaskExecutions {
+		view.TaskExecutions[id] = te
+	}
+	view.TaskExecutions[taskID] = &TaskExecution{TaskID: taskID, Status: TaskStatusCompleted, Output: taskExecution.Output}
+	return &view
+}
+
+// validateControlFlow checks the branch and loop of a task
+func validateControlFlow(task WorkflowTask, taskIDs map[string]bool) error {
+	if task.Branch != nil && task.Loop != nil {
+		return fmt.Errorf("task %s cannot be both a branch and a loop", task.ID)
+	}
+
+	if branch := task.Branch; branch != nil {
+		if strings.TrimSpace(branch.Condition) == "" {
+			return fmt.Errorf("branch %s requires a condition", task.ID)
+		}
+		if err := validateReferences(branch.Condition, task.ID, taskIDs, false); err != nil {
+			return fmt.Errorf("branch %s condition %w", task.ID, err)
+		}
+		paths := make(map[string]bool)
+		for _, target := range append(append([]string{}, branch.Then...), branch.Else...) {
+			if !taskIDs[target] {
+				return fmt.Errorf("branch %s references unknown task: %s", task.ID, target)
+			}
+			if target == task.ID {
+				return fmt.Errorf("branch %s cannot target itself", task.ID)
+			}
+			if paths[target] {
+				return fmt.Errorf("branch %s lists task %s more than once", task.ID, target)
+			}
+			paths[target] = true
+		}
+	}
+
+	if loop := task.Loop; loop != nil {
+		if (loop.ForEach == "") == (loop.Until == "") {
+			return fmt.Errorf("loop %s requires exactly one of for_each and until", task.ID)
+		}
+		if loop.MaxIterations < 0 || loop.MaxIterations > MaxLoopIterations {
+			return fmt.Errorf("loop %s max iterations must be between 0 and %d", task.ID, MaxLoopIterations)
+		}
+		if err := validateReferences(loop.ForEach, task.ID, taskIDs, false); err != nil {
+			return fmt.Errorf("loop %s items %w", task.ID, err)
+		}
+		if err := validateReferences(loop.Until, task.ID, taskIDs, true); err != nil {
+			return fmt.Errorf("loop %s condition %w", task.ID, err)
+		}
+	}
+
+	for _, condition := range task.Conditions {
+		if condition.Type != "expression" {
+			continue
+		}
+		if err := validateReferences(condition.Expression, task.ID, taskIDs, false); err != nil {
+			return fmt.Errorf("task %s condition %w", task.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// implicitDependencies returns the tasks a task must run after besides its
+// declared dependencies: tasks its expressions refer to, and branches choosing it
+func implicitDependencies(task WorkflowTask, workflow *Workflow) []string {
+	var deps []string
+	for _, value := range task.InputMapping {
+		deps = append(deps, referencedTasks(value, task.ID)...)
+	}
+	for _, condition := range task.Conditions {
+		if condition.Type == "expression" {
+			deps = append(deps, referencedTasks(condition.Expression, task.ID)...)
+		}
+	}
+	if task.Branch != nil {
+		deps = append(deps, referencedTasks(task.Branch.Condition, task.ID)...)
+	}
+	if task.Loop != nil {
+		deps = append(deps, referencedTasks(task.Loop.ForEach, task.ID)...)
+		deps = append(deps, referencedTasks(task.Loop.Until, task.ID)...)
+	}
+
+	for _, other := range workflow.Tasks {
+		if other.Branch == nil {
+			continue
+		}
+		for _, target := range append(append([]string{}, other.Branch.Then...), other.Branch.Else...) {
+			if target == task.ID {
+				deps = append(deps, other.ID)
+			}
+		}
+	}
+	return deps
+}
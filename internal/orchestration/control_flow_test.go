@@ -0,0 +1,156 @@
+package orchestration
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	execution := &WorkflowExecution{
+		Context: map[string]interface{}{"region": "north", "dry_run": false},
+		TaskExecutions: map[string]*TaskExecution{
+			"analyze": {TaskID: "analyze", Status: TaskStatusCompleted, Output: map[string]interface{}{
+				"leak_probability": 0.83,
+				"segments":         []interface{}{"A4"},
+				"count":            3,
+			}},
+		},
+	}
+
+	for expr, want := range map[string]bool{
+		"${tasks.analyze.output.leak_probability} > 0.7":                            true,
+		"${tasks.analyze.output.leak_probability} <= 0.7":                           false,
+		"${tasks.analyze.output.count} == 3":                                        true,
+		"${context.region} == 'north' && ${tasks.analyze.output.count} >= 3":        true,
+		"${context.region} != \"north\" || ${tasks.analyze.output.segments}":        true,
+		"${context.dry_run} || ${context.region} == 'south'":                        false,
+		"${tasks.analyze.status} == 'completed'":                                    true,
+		"${tasks.analyze.output.leak_probability} > 0.9 || ${context.dry_run}":      false,
+		"${tasks.analyze.output.count} < 10 && ${tasks.analyze.output.count} > 2.5": true,
+	} {
+		got, err := evaluateExpression(expr, execution)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", expr, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %v, got %v", expr, want, got)
+		}
+	}
+
+	for _, expr := range []string{"", "${tasks.analyze.output.missing} > 1", "${context.region} > 3", "north == north"} {
+		if _, err := evaluateExpression(expr, execution); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestEngine_BranchesAndLoops(t *testing.T) {
+	ctx := context.Background()
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		payload := task.Payload.(map[string]interface{})
+		parameters, _ := payload["parameters"].(map[string]interface{})
+		switch payload["task_id"] {
+		case "analyze":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{
+				"leak_probability": 0.9,
+				"segments":         []interface{}{"A4", "A5"},
+			}}
+		case "inspect":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"segment": parameters["item"]}}
+		case "settle":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"pressure": 5 - parameters["iteration"].(int)}}
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	workflow := &Workflow{
+		ID: "wf-1",
+		Tasks: []WorkflowTask{
+			{ID: "analyze", Type: "data_processing"},
+			{ID: "decide", Branch: &TaskBranch{
+				Condition: "${tasks.analyze.output.leak_probability} > 0.7",
+				Then:      []string{"inspect"},
+				Else:      []string{"report"},
+			}},
+			{ID: "inspect", Type: "inspection", Loop: &TaskLoop{ForEach: "${tasks.analyze.output.segments}"}},
+			{ID: "report", Type: "report"},
+			{ID: "archive", Type: "archive"},
+			{ID: "settle", Type: "pressure_check", Loop: &TaskLoop{Until: "${tasks.settle.output.pressure} <= 2", MaxIterations: 5}},
+		},
+		// Dependencies on analyze and decide are implied by the expressions and branch
+		Dependencies: map[string][]string{
+			"archive": {"report"},
+			"settle":  {"inspect"},
+		},
+	}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(execution.ID) != WorkflowStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", repository.status(execution.ID))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := execution.TaskExecutions
+	if tasks["decide"].Output["branch"] != "then" {
+		t.Errorf("expected the then path, got %v", tasks["decide"].Output)
+	}
+	if tasks["report"].Status != TaskStatusSkipped || tasks["archive"].Status != TaskStatusSkipped || tasks["archive"].SkippedByBranch != "decide" {
+		t.Errorf("expected the else path to be skipped, got report %s, archive %s", tasks["report"].Status, tasks["archive"].Status)
+	}
+
+	iterations, _ := tasks["inspect"].Output["iterations"].([]interface{})
+	if tasks["inspect"].Status != TaskStatusCompleted || len(iterations) != 2 || iterations[1].(map[string]interface{})["segment"] != "A5" {
+		t.Errorf("expected one inspection per segment, got %v", tasks["inspect"].Output)
+	}
+	if tasks["settle"].Status != TaskStatusCompleted || tasks["settle"].Iterations != 3 || tasks["settle"].Output["pressure"] != 2 {
+		t.Errorf("expected settle to stop once pressure is 2, got %d iterations, output %v", tasks["settle"].Iterations, tasks["settle"].Output)
+	}
+}
+
+func TestValidateControlFlow(t *testing.T) {
+	taskIDs := map[string]bool{"analyze": true, "decide": true, "repair": true, "poll": true}
+
+	for name, task := range map[string]WorkflowTask{
+		"branch without condition": {ID: "decide", Branch: &TaskBranch{Then: []string{"repair"}}},
+		"unknown branch target":    {ID: "decide", Branch: &TaskBranch{Condition: "true", Then: []string{"replace"}}},
+		"target on both paths":     {ID: "decide", Branch: &TaskBranch{Condition: "true", Then: []string{"repair"}, Else: []string{"repair"}}},
+		"loop without items":       {ID: "poll", Loop: &TaskLoop{}},
+		"unbounded loop":           {ID: "poll", Loop: &TaskLoop{Until: "true", MaxIterations: MaxLoopIterations + 1}},
+		"items from itself":        {ID: "poll", Loop: &TaskLoop{ForEach: "${tasks.poll.output.items}"}},
+		"branch and loop":          {ID: "poll", Branch: &TaskBranch{Condition: "true"}, Loop: &TaskLoop{Until: "true"}},
+	} {
+		if err := validateControlFlow(task, taskIDs); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	until := WorkflowTask{ID: "poll", Loop: &TaskLoop{Until: "${tasks.poll.output.done}"}}
+	if err := validateControlFlow(until, taskIDs); err != nil {
+		t.Errorf("expected a loop condition to refer to its own output, got %v", err)
+	}
+}
@@ -3,13 +3,16 @@ package orchestration
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/config"
 	"github.com/aosanya/CodeValdCortex/internal/health"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
 	"github.com/aosanya/CodeValdCortex/internal/runtime"
 	log "github.com/sirupsen/logrus"
 )
@@ -31,6 +34,15 @@ type Coordinator struct {
 	roundRobinIndex map[string]int
 	rrMutex         sync.Mutex
 
+	// Dispatched tasks by task ID, mapped to the executing agent
+	dispatched         map[string]string
+	completionHandlers []func(*agent.TaskResult)
+	dispatchMutex      sync.Mutex
+
+	// Recent assignment times by agent, for assignment rate limits
+	assignments map[string][]time.Time
+	rateMutex   sync.Mutex
+
 	// Context and cancellation
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -38,6 +50,8 @@ type Coordinator struct {
 
 	// Logger
 	logger *log.Logger
+
+	clock clock.Clock
 }
 
 // CoordinatorConfig configures the agent coordinator
@@ -59,6 +73,15 @@ type CoordinatorConfig struct {
 
 	// CapabilityMatching enables strict capability matching
 	CapabilityMatching bool
+
+	// AssignmentRateLimits caps the tasks assigned to a single agent per
+	// minute, by agent type. Slow agents such as valves and pumps are assigned
+	// further tasks only once the last minute's assignments allow it.
+	AssignmentRateLimits map[string]int
+
+	// DefaultAssignmentRateLimit applies to agent types without a rate limit;
+	// zero means unlimited
+	DefaultAssignmentRateLimit int
 }
 
 // DefaultCoordinatorConfig returns default coordinator configuration
@@ -73,19 +96,44 @@ func DefaultCoordinatorConfig() CoordinatorConfig {
 	}
 }
 
+// CoordinatorConfigFromConfig returns the default coordinator configuration
+// with the assignment rate limits of the application config
+func CoordinatorConfigFromConfig(cfg config.OrchestrationConfig) CoordinatorConfig {
+	coordinatorConfig := DefaultCoordinatorConfig()
+	coordinatorConfig.AssignmentRateLimits = cfg.AssignmentRateLimits
+	coordinatorConfig.DefaultAssignmentRateLimit = cfg.DefaultAssignmentRateLimit
+	return coordinatorConfig
+}
+
 // NewCoordinator creates a new agent coordinator
 func NewCoordinator(config CoordinatorConfig, runtimeManager *runtime.Manager, healthMonitor *health.Monitor, logger *log.Logger) *Coordinator {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Coordinator{
+	c := &Coordinator{
 		config:          config,
 		runtimeManager:  runtimeManager,
 		healthMonitor:   healthMonitor,
 		agentLoads:      make(map[string]*AgentLoad),
+		assignments:     make(map[string][]time.Time),
 		roundRobinIndex: make(map[string]int),
+		dispatched:      make(map[string]string),
 		ctx:             ctx,
 		cancel:          cancel,
 		logger:          logger,
+		clock:           clock.Real(),
+	}
+
+	if runtimeManager != nil {
+		runtimeManager.OnTaskResult(c.handleTaskResult)
+	}
+
+	return c
+}
+
+// SetClock sets the clock used for agent load timestamps and the load update interval. It must be called before Start.
+func (c *Coordinator) SetClock(clk clock.Clock) {
+	if clk != nil {
+		c.clock = clk
 	}
 }
 
@@ -142,11 +190,21 @@ func (c *Coordinator) SelectAgents(ctx context.Context, selector AgentSelector,
 		return nil, fmt.Errorf("no agents match selection criteria")
 	}
 
-	// Select agents based on strategy
-	selectedAgents, err := c.selectByStrategy(candidateAgents, selector.Strategy, count)
+	// Select agents based on strategy, taking preferred agents first
+	strategy := selector.Strategy
+	if strategy == "" {
+		strategy = c.config.LoadBalancingStrategy
+	}
+	preferred, others := partitionAgents(candidateAgents, selector.PreferredAgents)
+	selectedAgents, err := c.selectByStrategy(preferred, strategy, count)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select agents by strategy: %w", err)
 	}
+	remaining, err := c.selectByStrategy(others, strategy, count-len(selectedAgents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select agents by strategy: %w", err)
+	}
+	selectedAgents = append(selectedAgents, remaining...)
 
 	c.logger.WithFields(log.Fields{
 		"strategy":     selector.Strategy,
@@ -166,6 +224,11 @@ func (c *Coordinator) AssignTask(ctx context.Context, agentID string, task *Work
 		"execution_id": execution.ID,
 	}).Debug("Assigning task to agent")
 
+	// Wait while the agent is at its assignment rate limit
+	if err := c.awaitAssignmentRate(ctx, agentID); err != nil {
+		return err
+	}
+
 	// Get agent load
 	agentLoad, err := c.GetAgentLoad(ctx, agentID)
 	if err != nil {
@@ -185,8 +248,6 @@ func (c *Coordinator) AssignTask(ctx context.Context, agentID string, task *Work
 	// Update agent load (optimistic)
 	c.updateAgentLoad(agentID, agentLoad.ActiveTasks+1, agentLoad.QueuedTasks+1)
 
-	// In a real implementation, this would submit the task to the agent's task system
-	// For now, we simulate the assignment
 	c.logger.WithFields(log.Fields{
 		"agent_id":  agentID,
 		"task_id":   task.ID,
@@ -196,6 +257,87 @@ func (c *Coordinator) AssignTask(ctx context.Context, agentID string, task *Work
 	return nil
 }
 
+// DispatchTask submits an assigned task to the agent's task queue. The
+// agent's load is released when the task completes or cannot be submitted.
+func (c *Coordinator) DispatchTask(ctx context.Context, agentID string, task agent.Task) error {
+	if c.runtimeManager == nil {
+		c.releaseAgentLoad(agentID)
+		return fmt.Errorf("runtime manager not configured")
+	}
+
+	a, err := c.runtimeManager.GetAgent(agentID)
+	if err != nil {
+		c.releaseAgentLoad(agentID)
+		return fmt.Errorf("failed to get agent %s: %w", agentID, err)
+	}
+	if liveness := c.runtimeManager.Liveness().Get(agentID); liveness.Status == registry.StatusOffline {
+		c.releaseAgentLoad(agentID)
+		return fmt.Errorf("agent %s is offline: %s", agentID, liveness.Reason)
+	}
+
+	c.dispatchMutex.Lock()
+	c.dispatched[task.ID] = agentID
+	c.dispatchMutex.Unlock()
+
+	if err := a.SubmitTask(task); err != nil {
+		c.dispatchMutex.Lock()
+		delete(c.dispatched, task.ID)
+		c.dispatchMutex.Unlock()
+		c.releaseAgentLoad(agentID)
+		return fmt.Errorf("failed to submit task to agent %s: %w", agentID, err)
+	}
+
+	c.logger.WithFields(log.Fields{
+		"agent_id":  agentID,
+		"task_id":   task.ID,
+		"task_type": task.Type,
+	}).Debug("Task dispatched to agent")
+
+	return nil
+}
+
+// OnTaskCompleted registers a handler notified when a dispatched task completes
+func (c *Coordinator) OnTaskCompleted(handler func(*agent.TaskResult)) {
+	c.dispatchMutex.Lock()
+	defer c.dispatchMutex.Unlock()
+	c.completionHandlers = append(c.completionHandlers, handler)
+}
+
+// handleTaskResult releases the load of a dispatched task and notifies the
+// completion handlers. Results of tasks not dispatched by the coordinator are ignored.
+func (c *Coordinator) handleTaskResult(result *agent.TaskResult) {
+	c.dispatchMutex.Lock()
+	agentID, dispatched := c.dispatched[result.TaskID]
+	delete(c.dispatched, result.TaskID)
+	handlers := c.completionHandlers
+	c.dispatchMutex.Unlock()
+
+	if !dispatched {
+		return
+	}
+
+	c.releaseAgentLoad(agentID)
+	for _, handler := range handlers {
+		handler(result)
+	}
+}
+
+// releaseAgentLoad undoes the optimistic load update made by AssignTask
+func (c *Coordinator) releaseAgentLoad(agentID string) {
+	c.loadMutex.RLock()
+	load, exists := c.agentLoads[agentID]
+	var activeTasks, queuedTasks int
+	if exists {
+		activeTasks, queuedTasks = load.ActiveTasks, load.QueuedTasks
+	}
+	c.loadMutex.RUnlock()
+
+	if !exists {
+		return
+	}
+	c.updateAgentLoad(agentID, max(activeTasks-1, 0), max(queuedTasks-1, 0))
+}
+
 // GetAgentLoad returns current load information for an agent
 func (c *Coordinator) GetAgentLoad(ctx context.Context, agentID string) (*AgentLoad, error) {
 	c.loadMutex.RLock()
@@ -203,22 +345,26 @@ func (c *Coordinator) GetAgentLoad(ctx context.Context, agentID string) (*AgentL
 	c.loadMutex.RUnlock()
 
 	if !exists {
-		// Load information not available, create default
-		load = &AgentLoad{
-			AgentID:      agentID,
-			ActiveTasks:  0,
-			QueuedTasks:  0,
-			CPUUsage:     0.0,
-			MemoryUsage:  0.0,
-			HealthScore:  1.0, // Assume healthy by default
-			Capabilities: []string{},
-			LastUpdated:  time.Now(),
-		}
-
 		// Try to get real load information
 		if err := c.refreshAgentLoad(ctx, agentID); err != nil {
 			c.logger.WithError(err).WithField("agent_id", agentID).Warn("Failed to refresh agent load")
+
+			// Load information not available, create default
+			return &AgentLoad{
+				AgentID:      agentID,
+				ActiveTasks:  0,
+				QueuedTasks:  0,
+				CPUUsage:     0.0,
+				MemoryUsage:  0.0,
+				HealthScore:  1.0, // Assume healthy by default
+				Capabilities: []string{},
+				LastUpdated:  c.clock.Now(),
+			}, nil
 		}
+
+		c.loadMutex.RLock()
+		load = c.agentLoads[agentID]
+		c.loadMutex.RUnlock()
 	}
 
 	return load, nil
@@ -229,10 +375,12 @@ func (c *Coordinator) GetAvailableAgents(ctx context.Context) ([]*agent.Agent, e
 	// Get all agents from runtime manager
 	agents := c.runtimeManager.ListAgents()
 
-	// Filter to only running agents
+	// Filter to running agents that heartbeat; agents registered but offline
+	// are not assumed to exist
+	liveness := c.runtimeManager.Liveness()
 	availableAgents := make([]*agent.Agent, 0)
 	for _, ag := range agents {
-		if ag.GetState() == agent.StateRunning {
+		if ag.GetState() == agent.StateRunning && liveness.Get(ag.ID).Status != registry.StatusOffline {
 			availableAgents = append(availableAgents, ag)
 		}
 	}
@@ -338,15 +486,14 @@ func (c *Coordinator) filterAgents(agents []*agent.Agent, selector AgentSelector
 			// For now, assume all agents are in the default pool
 		}
 
+		// Check anti-affinity
+		if slices.Contains(selector.ExcludedAgents, ag.ID) {
+			continue
+		}
+
 		// Check required capabilities
 		if len(selector.RequiredCapabilities) > 0 && c.config.CapabilityMatching {
-			agentLoad, err := c.GetAgentLoad(context.Background(), ag.ID)
-			if err != nil {
-				c.logger.WithError(err).WithField("agent_id", ag.ID).Warn("Failed to get agent capabilities")
-				continue
-			}
-
-			if !c.hasRequiredCapabilities(agentLoad.Capabilities, selector.RequiredCapabilities) {
+			if !c.hasRequiredCapabilities(c.agentCapabilities(ag), selector.RequiredCapabilities) {
 				continue
 			}
 		}
@@ -364,10 +511,22 @@ func (c *Coordinator) filterAgents(agents []*agent.Agent, selector AgentSelector
 			}
 		}
 
-		// Check tags
-		if len(selector.Tags) > 0 {
-			// In a real implementation, would check agent tags
-			// For now, assume all agents match
+		// Check tags against the agent's metadata
+		if !hasTags(ag, selector.Tags) {
+			continue
+		}
+
+		// Skip agents already at capacity
+		if c.config.MaxTasksPerAgent > 0 {
+			agentLoad, err := c.GetAgentLoad(context.Background(), ag.ID)
+			if err != nil {
+				c.logger.WithError(err).WithField("agent_id", ag.ID).Warn("Failed to get agent load")
+				continue
+			}
+
+			if agentLoad.ActiveTasks >= c.config.MaxTasksPerAgent {
+				continue
+			}
 		}
 
 		candidates = append(candidates, ag)
@@ -392,12 +551,9 @@ func (c *Coordinator) selectByStrategy(candidates []*agent.Agent, strategy Agent
 		return c.selectLeastLoaded(candidates, count)
 	case AgentSelectionHealthAware:
 		return c.selectHealthAware(candidates, count)
-	case AgentSelectionSpecific:
-		// For specific selection, just return first N candidates
-		return candidates[:count], nil
-	case AgentSelectionCapabilityBased:
-		// For capability-based, candidates are already filtered
-		return candidates[:count], nil
+	case AgentSelectionSpecific, AgentSelectionCapabilityBased:
+		// Candidates are already filtered; prefer the least loaded of them
+		return c.selectLeastLoaded(candidates, count)
 	default:
 		return c.selectRoundRobin(candidates, count)
 	}
@@ -425,8 +581,9 @@ func (c *Coordinator) selectRoundRobin(candidates []*agent.Agent, count int) ([]
 func (c *Coordinator) selectLeastLoaded(candidates []*agent.Agent, count int) ([]*agent.Agent, error) {
 	// Create slice with load information
 	type agentWithLoad struct {
-		agent *agent.Agent
-		load  int
+		agent  *agent.Agent
+		load   int
+		health float64
 	}
 
 	agentsWithLoad := make([]agentWithLoad, 0, len(candidates))
@@ -439,14 +596,21 @@ func (c *Coordinator) selectLeastLoaded(candidates []*agent.Agent, count int) ([
 		}
 
 		agentsWithLoad = append(agentsWithLoad, agentWithLoad{
-			agent: ag,
-			load:  agentLoad.ActiveTasks + agentLoad.QueuedTasks,
+			agent:  ag,
+			load:   agentLoad.ActiveTasks + agentLoad.QueuedTasks,
+			health: agentLoad.HealthScore,
 		})
 	}
 
-	// Sort by load (ascending)
+	// Sort by load (ascending), then health (descending)
 	sort.Slice(agentsWithLoad, func(i, j int) bool {
-		return agentsWithLoad[i].load < agentsWithLoad[j].load
+		if agentsWithLoad[i].load != agentsWithLoad[j].load {
+			return agentsWithLoad[i].load < agentsWithLoad[j].load
+		}
+		if agentsWithLoad[i].health != agentsWithLoad[j].health {
+			return agentsWithLoad[i].health > agentsWithLoad[j].health
+		}
+		return agentsWithLoad[i].agent.ID < agentsWithLoad[j].agent.ID
 	})
 
 	// Select least loaded agents
@@ -533,48 +697,78 @@ func (c *Coordinator) updateAgentLoad(agentID string, activeTasks, queuedTasks i
 			MemoryUsage:  0.0,
 			HealthScore:  1.0,
 			Capabilities: []string{},
-			LastUpdated:  time.Now(),
+			LastUpdated:  c.clock.Now(),
 		}
 		c.agentLoads[agentID] = load
 	}
 
 	load.ActiveTasks = activeTasks
 	load.QueuedTasks = queuedTasks
-	load.LastUpdated = time.Now()
+	load.LastUpdated = c.clock.Now()
 }
 
+// refreshAgentLoad recomputes an agent's load from the tasks dispatched to it
+// and its task queue, its health from the health monitor and its heartbeats,
+// and its capabilities from its registration or metadata
 func (c *Coordinator) refreshAgentLoad(ctx context.Context, agentID string) error {
-	// In a real implementation, this would:
-	// 1. Query the agent's task manager for current task counts
-	// 2. Get resource usage from the runtime manager
-	// 3. Get health score from the health monitor
-	// 4. Get capabilities from agent metadata
+	if c.runtimeManager == nil {
+		return fmt.Errorf("runtime manager not configured")
+	}
+
+	ag, err := c.runtimeManager.GetAgent(agentID)
+	if err != nil {
+		return fmt.Errorf("failed to get agent %s: %w", agentID, err)
+	}
+
+	// Dispatched tasks awaiting their result are running on the agent
+	activeTasks := 0
+	c.dispatchMutex.Lock()
+	for _, dispatchedTo := range c.dispatched {
+		if dispatchedTo == agentID {
+			activeTasks++
+		}
+	}
+	c.dispatchMutex.Unlock()
+
+	healthScore := 1.0 // Assume healthy without a health report
+	if c.healthMonitor != nil {
+		if report, err := c.healthMonitor.GetHealthReport(agentID); err == nil {
+			if score, known := healthScores[report.OverallStatus]; known {
+				healthScore = score
+			}
+		}
+	}
+
+	if c.runtimeManager.Liveness().Get(agentID).Status != registry.StatusOnline {
+		healthScore = min(healthScore, degradedHealthScore)
+	}
 
-	// For now, simulate load information
 	c.loadMutex.Lock()
 	defer c.loadMutex.Unlock()
 
-	// Simulate some load
-	activeTasks := rand.Intn(5)             // 0-4 active tasks
-	queuedTasks := rand.Intn(3)             // 0-2 queued tasks
-	cpuUsage := rand.Float64() * 100        // 0-100% CPU
-	memoryUsage := rand.Float64() * 100     // 0-100% memory
-	healthScore := 0.7 + rand.Float64()*0.3 // 0.7-1.0 health score
-
 	c.agentLoads[agentID] = &AgentLoad{
 		AgentID:      agentID,
 		ActiveTasks:  activeTasks,
-		QueuedTasks:  queuedTasks,
-		CPUUsage:     cpuUsage,
-		MemoryUsage:  memoryUsage,
+		QueuedTasks:  len(ag.TaskChan()),
 		HealthScore:  healthScore,
-		Capabilities: []string{"http_request", "data_processing", "file_io"}, // Default capabilities
-		LastUpdated:  time.Now(),
+		Capabilities: c.agentCapabilities(ag),
+		LastUpdated:  c.clock.Now(),
 	}
 
 	return nil
 }
 
+// agentCapabilities returns the capabilities an agent registered with, or
+// those declared in its metadata when it registered none
+func (c *Coordinator) agentCapabilities(ag *agent.Agent) []string {
+	if c.runtimeManager != nil {
+		if capabilities := c.runtimeManager.Liveness().Get(ag.ID).Capabilities; len(capabilities) > 0 {
+			return capabilities
+		}
+	}
+	return ag.Capabilities()
+}
+
 // Worker methods
 
 func (c *Coordinator) loadMonitorWorker() {
@@ -582,14 +776,14 @@ func (c *Coordinator) loadMonitorWorker() {
 
 	c.logger.Debug("Load monitor worker started")
 
-	ticker := time.NewTicker(c.config.LoadUpdateInterval)
+	ticker := c.clock.NewTicker(c.config.LoadUpdateInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			c.updateAllAgentLoads()
 		}
 	}
@@ -0,0 +1,206 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestCoordinator_SelectAgents(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	manager := runtime.NewManager(logger, runtime.ManagerConfig{}, nil)
+
+	for id, metadata := range map[string]map[string]string{
+		"pump-1":   {CapabilitiesMetadataKey: "pressure, leak_detection", "zone": "north"},
+		"pump-2":   {CapabilitiesMetadataKey: "leak_detection", "zone": "south"},
+		"pump-3":   {CapabilitiesMetadataKey: "leak_detection", "zone": "north"},
+		"sensor-1": {"zone": "north"},
+	} {
+		ag := agent.New(id, "worker", agent.Config{})
+		ag.ID = id
+		ag.Metadata = metadata
+		ag.SetState(agent.StateRunning)
+		if err := manager.RegisterAgent(ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultCoordinatorConfig()
+	config.MaxTasksPerAgent = 3
+	coordinator := NewCoordinator(config, manager, nil, logger)
+	coordinator.updateAgentLoad("pump-1", 2, 0)
+	coordinator.updateAgentLoad("pump-2", 0, 0)
+	coordinator.updateAgentLoad("pump-3", 3, 0)
+	coordinator.updateAgentLoad("sensor-1", 0, 0)
+
+	tests := []struct {
+		name     string
+		selector AgentSelector
+		want     string
+	}{
+		{"least loaded capable agent", AgentSelector{RequiredCapabilities: []string{"leak_detection"}}, "pump-2"},
+		{"every capability required", AgentSelector{RequiredCapabilities: []string{"leak_detection", "pressure"}}, "pump-1"},
+		{"tags match metadata", AgentSelector{RequiredCapabilities: []string{"leak_detection"}, Tags: map[string]string{"zone": "north"}}, "pump-1"},
+		{"preferred agent wins over load", AgentSelector{RequiredCapabilities: []string{"leak_detection"}, PreferredAgents: []string{"pump-1"}}, "pump-1"},
+		{"excluded agent is never chosen", AgentSelector{RequiredCapabilities: []string{"leak_detection"}, ExcludedAgents: []string{"pump-2"}}, "pump-1"},
+		{"specific agents by load", AgentSelector{Strategy: AgentSelectionSpecific, SpecificAgents: []string{"pump-1", "sensor-1"}}, "sensor-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agents, err := coordinator.SelectAgents(ctx, tt.selector, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(agents) != 1 || agents[0].ID != tt.want {
+				t.Errorf("expected %s, got %v", tt.want, coordinator.getAgentIDs(agents))
+			}
+		})
+	}
+
+	// Agents at capacity are not candidates
+	if _, err := coordinator.SelectAgents(ctx, AgentSelector{SpecificAgents: []string{"pump-3"}}, 1); err == nil {
+		t.Error("expected no agent to match when the only candidate is at capacity")
+	}
+}
+
+func TestResolveAgentSelector(t *testing.T) {
+	execution := &WorkflowExecution{TaskExecutions: map[string]*TaskExecution{
+		"fetch":  {TaskID: "fetch", AgentID: "pump-1"},
+		"backup": {TaskID: "backup", AgentID: "pump-2"},
+		"report": {TaskID: "report"},
+	}}
+
+	selector := resolveAgentSelector(AgentSelector{
+		Affinity:       []string{"fetch", "report"},
+		AntiAffinity:   []string{"backup"},
+		ExcludedAgents: []string{"pump-9"},
+	}, execution)
+	if len(selector.PreferredAgents) != 1 || selector.PreferredAgents[0] != "pump-1" {
+		t.Errorf("expected pump-1 to be preferred, got %v", selector.PreferredAgents)
+	}
+	if len(selector.ExcludedAgents) != 2 || selector.ExcludedAgents[1] != "pump-2" {
+		t.Errorf("expected pump-9 and pump-2 to be excluded, got %v", selector.ExcludedAgents)
+	}
+
+	taskIDs := map[string]bool{"fetch": true, "report": true}
+	if err := validateAffinity(WorkflowTask{ID: "report", AgentSelector: AgentSelector{Affinity: []string{"fetch"}}}, taskIDs); err != nil {
+		t.Errorf("expected valid affinity, got %v", err)
+	}
+	if err := validateAffinity(WorkflowTask{ID: "report", AgentSelector: AgentSelector{AntiAffinity: []string{"missing"}}}, taskIDs); err == nil {
+		t.Error("expected affinity to an unknown task to be rejected")
+	}
+}
+
+func TestCoordinator_AssignmentRateLimit(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	manager := runtime.NewManager(logger, runtime.ManagerConfig{}, nil)
+	for id, agentType := range map[string]string{"valve-1": "valve", "worker-1": "worker"} {
+		ag := agent.New(id, agentType, agent.Config{})
+		ag.ID = id
+		ag.SetState(agent.StateRunning)
+		if err := manager.RegisterAgent(ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+	config := DefaultCoordinatorConfig()
+	config.MaxTasksPerAgent = 100
+	config.AssignmentRateLimits = map[string]int{"valve": 2}
+	coordinator := NewCoordinator(config, manager, nil, logger)
+	coordinator.SetClock(clk)
+
+	task := &WorkflowTask{ID: "open", Type: "actuate"}
+	execution := &WorkflowExecution{ID: "exec-1"}
+	for i := 0; i < 2; i++ {
+		if err := coordinator.AssignTask(ctx, "valve-1", task, execution); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Agent types without a limit are never held back
+	for i := 0; i < 5; i++ {
+		if err := coordinator.AssignTask(ctx, "worker-1", task, execution); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The third valve assignment waits until the first leaves the window
+	assigned := make(chan error, 1)
+	go func() { assigned <- coordinator.AssignTask(ctx, "valve-1", task, execution) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for clk.Waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the assignment to wait")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-assigned:
+		t.Fatalf("expected the assignment to wait, got %v", err)
+	default:
+	}
+	clk.Advance(time.Minute)
+	if err := <-assigned; err != nil {
+		t.Errorf("expected the assignment once the window moved, got %v", err)
+	}
+
+	// A task gives up waiting when its context ends
+	if err := coordinator.AssignTask(ctx, "valve-1", task, execution); err != nil {
+		t.Fatal(err)
+	}
+	waitCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := coordinator.AssignTask(waitCtx, "valve-1", task, execution); !errors.Is(err, ErrAgentRateLimited) {
+		t.Errorf("expected ErrAgentRateLimited, got %v", err)
+	}
+}
+
+func TestCoordinator_SkipsOfflineAgents(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	manager := runtime.NewManager(logger, runtime.ManagerConfig{}, nil)
+
+	// pump-1 runs and heartbeats; pump-2 was loaded as running but never registered
+	for _, id := range []string{"pump-1", "pump-2"} {
+		ag := agent.New(id, "worker", agent.Config{})
+		ag.ID = id
+		ag.Metadata = map[string]string{CapabilitiesMetadataKey: "leak_detection"}
+		if err := manager.RegisterAgent(ag); err != nil {
+			t.Fatal(err)
+		}
+		ag.SetState(agent.StateRunning)
+	}
+	manager.Liveness().Register("pump-1", []string{"leak_detection", "pressure"})
+
+	coordinator := NewCoordinator(DefaultCoordinatorConfig(), manager, nil, logger)
+	agents, err := coordinator.GetAvailableAgents(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0].ID != "pump-1" {
+		t.Fatalf("expected only pump-1 to be available, got %v", coordinator.getAgentIDs(agents))
+	}
+
+	// Capabilities come from the registration
+	selected, err := coordinator.SelectAgents(ctx, AgentSelector{RequiredCapabilities: []string{"pressure"}}, 1)
+	if err != nil || len(selected) != 1 || selected[0].ID != "pump-1" {
+		t.Fatalf("expected pump-1 to be selected for its registered capabilities, got %v (%v)", selected, err)
+	}
+
+	if err := coordinator.DispatchTask(ctx, "pump-2", agent.Task{ID: "task-1", Type: "inspect"}); err == nil {
+		t.Error("expected dispatching to an offline agent to fail")
+	}
+}
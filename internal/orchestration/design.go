@@ -0,0 +1,334 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDesignTaskType is the task type of work items that do not set one
+const DefaultDesignTaskType = "work_item"
+
+// elseLabels mark the edges of a decision that form its else path
+var elseLabels = []string{"no", "false", "else", "otherwise"}
+
+// FromDesign converts a workflow drawn in the designer into a workflow the
+// engine runs. Work items become agent tasks and decisions become branches;
+// start, end and parallel gateway nodes only order the tasks around them.
+//
+// A decision's condition is an engine expression such as
+// ${tasks.inspect.output.leak} == true, or the designer's shorthand
+// "inspect.leak == true" and "region == north", which read the output of the
+// inspect work item and the execution context. The decision's edges labelled
+// no, false or else form the else path and the others the then path. A
+// decision without a condition of its own takes it from its single
+// conditional edge, which is then the then path.
+func FromDesign(design *workflow.Workflow) (*Workflow, error) {
+	g := newDesignGraph(design)
+
+	wf := &Workflow{
+		ID:           design.ID,
+		Name:         design.Name,
+		Description:  design.Description,
+		Version:      designVersion(design),
+		Tasks:        make([]WorkflowTask, 0, len(design.Nodes)),
+		Dependencies: make(map[string][]string),
+		CreatedBy:    design.CreatedBy,
+	}
+
+	for _, node := range design.Nodes {
+		if !g.isTask(node.ID) {
+			continue
+		}
+
+		task := WorkflowTask{ID: node.ID, Name: node.Data.Name}
+		switch node.Type {
+		case workflow.NodeTypeWorkItem:
+			task.Type = node.Data.WorkItemType
+			if task.Type == "" {
+				task.Type = DefaultDesignTaskType
+			}
+			task.AgentSelector.RequiredCapabilities = node.Data.RequiredCapabilities
+			task.Parameters = make(map[string]interface{}, len(node.Data.Parameters)+2)
+			for k, v := range node.Data.Parameters {
+				task.Parameters[k] = v
+			}
+			if node.Data.WorkItemID != "" {
+				task.Parameters["work_item_id"] = node.Data.WorkItemID
+			}
+			if node.Data.Role != "" {
+				task.Parameters["role"] = node.Data.Role
+			}
+
+		case workflow.NodeTypeDecision:
+			branch, err := g.branch(node)
+			if err != nil {
+				return nil, err
+			}
+			task.Type = "branch"
+			task.Branch = branch
+		}
+
+		if deps := g.dependencies(node.ID); len(deps) > 0 {
+			wf.Dependencies[node.ID] = deps
+		}
+		wf.Tasks = append(wf.Tasks, task)
+	}
+
+	if len(wf.Tasks) == 0 {
+		return nil, fmt.Errorf("workflow %s has no work items", design.ID)
+	}
+	return wf, nil
+}
+
+// designVersion labels the engine version of a design. Designs keep their
+// version label across edits, so the time of the last edit tells them apart.
+func designVersion(design *workflow.Workflow) string {
+	if design.UpdatedAt.IsZero() {
+		return design.Version
+	}
+	edited := design.UpdatedAt.UTC().Format("20060102150405")
+	if design.Version == "" {
+		return edited
+	}
+	return design.Version + "-" + edited
+}
+
+// designGraph indexes the nodes and edges of a design
+type designGraph struct {
+	nodes    map[string]workflow.Node
+	outgoing map[string][]workflow.Edge
+	incoming map[string][]workflow.Edge
+}
+
+func newDesignGraph(design *workflow.Workflow) *designGraph {
+	g := &designGraph{
+		nodes:    make(map[string]workflow.Node, len(design.Nodes)),
+		outgoing: make(map[string][]workflow.Edge),
+		incoming: make(map[string][]workflow.Edge),
+	}
+	for _, node := range design.Nodes {
+		g.nodes[node.ID] = node
+	}
+	for _, edge := range design.Edges {
+		g.outgoing[edge.Source] = append(g.outgoing[edge.Source], edge)
+		g.incoming[edge.Target] = append(g.incoming[edge.Target], edge)
+	}
+	return g
+}
+
+// isTask reports whether the node becomes a task of the engine workflow
+func (g *designGraph) isTask(id string) bool {
+	node, exists := g.nodes[id]
+	return exists && (node.Type == workflow.NodeTypeWorkItem || node.Type == workflow.NodeTypeDecision)
+}
+
+// dependencies returns the tasks nearest before the node
+func (g *designGraph) dependencies(id string) []string {
+	var sources []string
+	for _, edge := range g.incoming[id] {
+		sources = append(sources, edge.Source)
+	}
+	return g.nearestTasks(sources, func(id string) []string {
+		var previous []string
+		for _, edge := range g.incoming[id] {
+			previous = append(previous, edge.Source)
+		}
+		return previous
+	})
+}
+
+// successors returns the tasks nearest after an edge
+func (g *designGraph) successors(edge workflow.Edge) []string {
+	return g.nearestTasks([]string{edge.Target}, func(id string) []string {
+		var next []string
+		for _, edge := range g.outgoing[id] {
+			next = append(next, edge.Target)
+		}
+		return next
+	})
+}
+
+// nearestTasks walks from the nodes through start, end and gateway nodes and
+// returns the tasks first met, sorted
+func (g *designGraph) nearestTasks(from []string, next func(string) []string) []string {
+	found := make(map[string]bool)
+	visited := make(map[string]bool)
+	queue := append([]string{}, from...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		if g.isTask(id) {
+			found[id] = true
+			continue
+		}
+		queue = append(queue, next(id)...)
+	}
+
+	tasks := make([]string, 0, len(found))
+	for id := range found {
+		tasks = append(tasks, id)
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+// branch builds the branch of a decision node from its condition and edges
+func (g *designGraph) branch(node workflow.Node) (*TaskBranch, error) {
+	condition := node.Data.Condition
+	var thenEdges, elseEdges []workflow.Edge
+	if condition != "" {
+		for _, edge := range g.outgoing[node.ID] {
+			if isElseEdge(edge) {
+				elseEdges = append(elseEdges, edge)
+			} else {
+				thenEdges = append(thenEdges, edge)
+			}
+		}
+	} else {
+		for _, edge := range g.outgoing[node.ID] {
+			if edge.Data.Condition != "" && !isElseEdge(edge) {
+				thenEdges = append(thenEdges, edge)
+			} else {
+				elseEdges = append(elseEdges, edge)
+			}
+		}
+		if len(thenEdges) != 1 {
+			return nil, fmt.Errorf("decision %s needs a condition or a single conditional edge", node.ID)
+		}
+		condition = thenEdges[0].Data.Condition
+	}
+
+	branch := &TaskBranch{Condition: g.condition(condition)}
+	for _, edge := range thenEdges {
+		branch.Then = append(branch.Then, g.successors(edge)...)
+	}
+	for _, edge := range elseEdges {
+		branch.Else = append(branch.Else, g.successors(edge)...)
+	}
+	return branch, nil
+}
+
+func isElseEdge(edge workflow.Edge) bool {
+	for _, label := range []string{edge.Data.Label, edge.Data.Condition} {
+		for _, elseLabel := range elseLabels {
+			if strings.EqualFold(strings.TrimSpace(label), elseLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// condition converts the designer's condition shorthand into an engine
+// expression; engine expressions are kept as they are
+func (g *designGraph) condition(condition string) string {
+	if strings.Contains(condition, "${") {
+		return condition
+	}
+
+	disjuncts := strings.Split(condition, "||")
+	for i, disjunct := range disjuncts {
+		conjuncts := strings.Split(disjunct, "&&")
+		for j, conjunct := range conjuncts {
+			conjuncts[j] = g.comparison(strings.TrimSpace(conjunct))
+		}
+		disjuncts[i] = strings.Join(conjuncts, " && ")
+	}
+	return strings.Join(disjuncts, " || ")
+}
+
+func (g *designGraph) comparison(expr string) string {
+	for _, op := range comparisonOperators {
+		if left, right, found := strings.Cut(expr, op); found {
+			return g.reference(left) + " " + op + " " + designValue(right)
+		}
+	}
+	return g.reference(expr)
+}
+
+// reference converts a key into an expression: <task>.<key> reads a task's
+// output and any other key the execution context
+func (g *designGraph) reference(key string) string {
+	key = strings.TrimSpace(key)
+	if taskID, output, found := strings.Cut(key, "."); found && g.isTask(taskID) {
+		return "${tasks." + taskID + ".output." + output + "}"
+	}
+	return "${context." + key + "}"
+}
+
+// designValue quotes a bare word compared against, leaving numbers,
+// booleans, null and quoted strings as they are
+func designValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value
+	}
+	switch value {
+	case "true", "false", "null":
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// DesignSource returns workflows drawn in the designer. workflow.Service
+// implements it.
+type DesignSource interface {
+	GetWorkflow(ctx context.Context, id string) (*workflow.Workflow, error)
+}
+
+// DesignLauncher starts designer workflows on the engine. Each version of a
+// design is stored with the engine's workflows the first time it runs, so
+// its executions can be listed by version and migrated.
+type DesignLauncher struct {
+	engine  *Engine
+	designs DesignSource
+}
+
+// NewDesignLauncher creates a launcher of designer workflows
+func NewDesignLauncher(engine *Engine, designs DesignSource) *DesignLauncher {
+	return &DesignLauncher{engine: engine, designs: designs}
+}
+
+// StartWorkflow runs the latest version of a designer workflow with the input
+// as the execution context and returns the execution's ID. The execution
+// outlives ctx, which usually belongs to the request that triggered it.
+func (l *DesignLauncher) StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error) {
+	design, err := l.designs.GetWorkflow(ctx, workflowID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get workflow: %w", err)
+	}
+	wf, err := FromDesign(design)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := l.engine.repository.GetWorkflowVersion(ctx, wf.ID, wf.Version); err != nil {
+		if err := l.engine.repository.StoreWorkflow(ctx, wf); err != nil {
+			return "", fmt.Errorf("failed to store workflow version: %w", err)
+		}
+		l.engine.logger.WithFields(log.Fields{
+			"workflow_id": wf.ID,
+			"version":     wf.Version,
+		}).Info("Stored designer workflow version")
+	}
+
+	execution, err := l.engine.StartExecution(context.WithoutCancel(ctx), wf, startedBy, input)
+	if err != nil {
+		return "", err
+	}
+	return execution.ID, nil
+}
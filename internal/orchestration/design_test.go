@@ -0,0 +1,229 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	log "github.com/sirupsen/logrus"
+)
+
+// leakDesign inspects a main, then repairs it when a leak is found or
+// closes the ticket when none is
+func leakDesign() *workflow.Workflow {
+	return &workflow.Workflow{
+		ID:        "leak-response",
+		Version:   "1.0",
+		UpdatedAt: time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+		Nodes: []workflow.Node{
+			{ID: "start", Type: workflow.NodeTypeStart},
+			{ID: "inspect", Type: workflow.NodeTypeWorkItem, Data: workflow.NodeData{
+				Name: "Inspect main", WorkItemType: "inspection", Role: "field_crew",
+				Parameters: map[string]interface{}{"radius_m": 50},
+			}},
+			{ID: "leak", Type: workflow.NodeTypeDecision, Data: workflow.NodeData{Condition: "inspect.leak == true"}},
+			{ID: "fork", Type: workflow.NodeTypeParallel, Data: workflow.NodeData{GatewayType: "fork"}},
+			{ID: "repair", Type: workflow.NodeTypeWorkItem},
+			{ID: "notify", Type: workflow.NodeTypeWorkItem},
+			{ID: "close", Type: workflow.NodeTypeWorkItem},
+			{ID: "end", Type: workflow.NodeTypeEnd},
+		},
+		Edges: []workflow.Edge{
+			{ID: "e1", Source: "start", Target: "inspect"},
+			{ID: "e2", Source: "inspect", Target: "leak"},
+			{ID: "e3", Source: "leak", Target: "fork", Data: workflow.EdgeData{Label: "yes"}},
+			{ID: "e4", Source: "fork", Target: "repair"},
+			{ID: "e5", Source: "fork", Target: "notify"},
+			{ID: "e6", Source: "leak", Target: "close", Data: workflow.EdgeData{Label: "no"}},
+			{ID: "e7", Source: "repair", Target: "end"},
+			{ID: "e8", Source: "notify", Target: "end"},
+			{ID: "e9", Source: "close", Target: "end"},
+		},
+	}
+}
+
+func TestFromDesign(t *testing.T) {
+	wf, err := FromDesign(leakDesign())
+	if err != nil {
+		t.Fatalf("FromDesign failed: %v", err)
+	}
+	if wf.Version != "1.0-20250301080000" {
+		t.Errorf("expected the version to carry the time of the last edit, got %q", wf.Version)
+	}
+	if len(wf.Tasks) != 5 {
+		t.Fatalf("expected the work items and the decision as tasks, got %d", len(wf.Tasks))
+	}
+
+	inspect := wf.Tasks[0]
+	if inspect.Type != "inspection" || inspect.Parameters["role"] != "field_crew" || inspect.Parameters["radius_m"] != 50 {
+		t.Errorf("unexpected inspect task %+v", inspect)
+	}
+	if repair := wf.Tasks[2]; repair.Type != DefaultDesignTaskType {
+		t.Errorf("expected work items without a type to be %s, got %q", DefaultDesignTaskType, repair.Type)
+	}
+
+	branch := wf.Tasks[1].Branch
+	if branch == nil || branch.Condition != "${tasks.inspect.output.leak} == true" {
+		t.Fatalf("expected the decision to become a branch on the inspection output, got %+v", branch)
+	}
+	if !reflect.DeepEqual(branch.Then, []string{"notify", "repair"}) || !reflect.DeepEqual(branch.Else, []string{"close"}) {
+		t.Errorf("expected the gateway's tasks on the then path and close on the else path, got %+v", branch)
+	}
+
+	// Gateways pass their dependencies through
+	if deps := wf.Dependencies["repair"]; !reflect.DeepEqual(deps, []string{"leak"}) {
+		t.Errorf("expected repair to follow the decision, got %v", deps)
+	}
+	if deps := wf.Dependencies["inspect"]; len(deps) != 0 {
+		t.Errorf("expected inspect to start the workflow, got %v", deps)
+	}
+
+	// The designer's shorthand reads the context when the key is not a task's
+	g := newDesignGraph(leakDesign())
+	if got := g.condition("region == north && inspect.pressure > 2.5"); got != `${context.region} == "north" && ${tasks.inspect.output.pressure} > 2.5` {
+		t.Errorf("unexpected condition %q", got)
+	}
+
+	design := leakDesign()
+	design.Nodes[2].Data.Condition = ""
+	if _, err := FromDesign(design); err == nil {
+		t.Error("expected a decision without a condition to be rejected")
+	}
+}
+
+// designRepository keeps the workflows and executions the engine stores
+type designRepository struct {
+	fakeRepository
+	workflows  map[string]*Workflow
+	executions map[string]*WorkflowExecution
+}
+
+func newDesignRepository() *designRepository {
+	return &designRepository{
+		fakeRepository: fakeRepository{statuses: make(map[string]WorkflowStatus)},
+		workflows:      make(map[string]*Workflow),
+		executions:     make(map[string]*WorkflowExecution),
+	}
+}
+
+func (r *designRepository) StoreWorkflow(ctx context.Context, workflow *Workflow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workflows[workflow.ID+"@"+workflow.Version] = workflow
+	return nil
+}
+
+func (r *designRepository) GetWorkflowVersion(ctx context.Context, workflowID, version string) (*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wf, exists := r.workflows[workflowID+"@"+version]; exists {
+		return wf, nil
+	}
+	return nil, fmt.Errorf("workflow %s version %s not found", workflowID, version)
+}
+
+func (r *designRepository) StoreExecution(ctx context.Context, execution *WorkflowExecution) error {
+	return r.UpdateExecution(ctx, execution)
+}
+
+func (r *designRepository) UpdateExecution(ctx context.Context, execution *WorkflowExecution) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[execution.ID] = execution.Status
+	r.executions[execution.ID] = execution
+	return nil
+}
+
+func (r *designRepository) GetExecution(ctx context.Context, executionID string) (*WorkflowExecution, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if execution, exists := r.executions[executionID]; exists {
+		return execution, nil
+	}
+	return nil, fmt.Errorf("execution %s not found", executionID)
+}
+
+// designs serves a single design
+type designs struct {
+	design *workflow.Workflow
+}
+
+func (d designs) GetWorkflow(ctx context.Context, id string) (*workflow.Workflow, error) {
+	if id != d.design.ID {
+		return nil, fmt.Errorf("workflow %s not found", id)
+	}
+	return d.design, nil
+}
+
+func TestDesignLauncher_StartWorkflow(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "crew-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		payload := task.Payload.(map[string]interface{})
+		mu.Lock()
+		ran = append(ran, payload["task_id"].(string))
+		mu.Unlock()
+		if payload["task_id"] == "inspect" {
+			if context, _ := payload["context"].(map[string]interface{}); context["work_order_id"] != "wo-1" {
+				t.Errorf("expected the execution context in the agent task, got %v", payload["context"])
+			}
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"leak": false}}
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := newDesignRepository()
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	launcher := NewDesignLauncher(engine, designs{design: leakDesign()})
+
+	// The execution outlives the context it was started with
+	ctx, cancel := context.WithCancel(context.Background())
+	executionID, err := launcher.StartWorkflow(ctx, "leak-response", "maintenance", map[string]interface{}{"work_order_id": "wo-1"})
+	cancel()
+	if err != nil {
+		t.Fatalf("StartWorkflow failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(executionID) != WorkflowStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", repository.status(executionID))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	execution, err := engine.GetExecution(context.Background(), executionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.TriggeredBy != "maintenance" || execution.WorkflowVersion != "1.0-20250301080000" {
+		t.Errorf("unexpected execution %+v", execution)
+	}
+	if status := execution.TaskExecutions["repair"].Status; status != TaskStatusSkipped {
+		t.Errorf("expected repair to be skipped when no leak is found, got %s", status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(ran, []string{"inspect", "close"}) {
+		t.Errorf("expected the agents to inspect and close, got %v", ran)
+	}
+
+	// The design version is stored with the engine's workflows
+	if _, err := repository.GetWorkflowVersion(context.Background(), "leak-response", "1.0-20250301080000"); err != nil {
+		t.Errorf("expected the design version to be stored, got %v", err)
+	}
+}
@@ -2,11 +2,13 @@ package orchestration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -23,11 +25,38 @@ type Engine struct {
 
 	// Runtime state
 	activeExecutions map[string]*WorkflowExecution
+	pauseGates       map[string]*pauseGate
+	executionCancels map[string]context.CancelCauseFunc
 	executionMutex   sync.RWMutex
 
+	// Locks on the state of each execution and its task executions, which
+	// the tasks of a batch, the workers and API calls all change. They are
+	// held while the state is read or changed and persisted, never while
+	// waiting on agents.
+	stateLocks map[string]*stateLock
+
 	// Channels for coordination
-	taskQueue       chan *TaskExecution
-	completionQueue chan *TaskExecution
+	taskQueue       chan *queuedTask
+	completionQueue chan *agent.TaskResult
+
+	// Number of tasks rejected by a full task queue
+	rejectedTasks int64
+
+	// Dispatched agent tasks awaiting completion, by agent task ID
+	pendingTasks map[string]chan *agent.TaskResult
+	pendingMutex sync.Mutex
+
+	// Manual approval tasks awaiting a decision, by execution and task ID
+	approvals     map[approvalKey]chan TaskApproval
+	approvalMutex sync.Mutex
+
+	// Task statuses last reported to the monitor, by execution and task ID
+	reportedStatuses map[string]map[string]TaskStatus
+	statusMutex      sync.Mutex
+
+	// Metrics of finished executions, by workflow and final status
+	executionStats map[executionStatsKey]*executionStats
+	statsMutex     sync.Mutex
 
 	// Context and cancellation
 	ctx    context.Context
@@ -36,23 +65,57 @@ type Engine struct {
 
 	// Logger
 	logger *log.Logger
+
+	clock clock.Clock
+
+	// checkpoints loads progress saved by agents during long-running tasks (optional)
+	checkpoints CheckpointStore
+
+	// leases makes this instance the single owner of its executions when
+	// several instances share a database (optional)
+	leases LeaseStore
+	// Executions taken over by another instance, no longer written here
+	lostLeases map[string]bool
 }
 
 // NewEngine creates a new workflow engine instance
 func NewEngine(config OrchestrationConfig, coordinator AgentCoordinator, monitor ExecutionMonitor, repository WorkflowRepository, logger *log.Logger) *Engine {
 	ctx, cancel := context.WithCancel(context.Background())
+	config = withDefaults(config)
 
-	return &Engine{
+	e := &Engine{
 		config:           config,
 		coordinator:      coordinator,
 		monitor:          monitor,
 		repository:       repository,
 		activeExecutions: make(map[string]*WorkflowExecution),
-		taskQueue:        make(chan *TaskExecution, 1000),
-		completionQueue:  make(chan *TaskExecution, 1000),
+		pauseGates:       make(map[string]*pauseGate),
+		executionCancels: make(map[string]context.CancelCauseFunc),
+		stateLocks:       make(map[string]*stateLock),
+		taskQueue:        make(chan *queuedTask, config.TaskQueueSize),
+		completionQueue:  make(chan *agent.TaskResult, config.CompletionQueueSize),
+		pendingTasks:     make(map[string]chan *agent.TaskResult),
+		approvals:        make(map[approvalKey]chan TaskApproval),
+		reportedStatuses: make(map[string]map[string]TaskStatus),
+		lostLeases:       make(map[string]bool),
+		executionStats:   make(map[executionStatsKey]*executionStats),
 		ctx:              ctx,
 		cancel:           cancel,
 		logger:           logger,
+		clock:            clock.Real(),
+	}
+
+	if coordinator != nil {
+		coordinator.OnTaskCompleted(e.HandleTaskResult)
+	}
+
+	return e
+}
+
+// SetClock sets the clock used for execution timestamps, retry delays and timeouts. It must be called before Start.
+func (e *Engine) SetClock(c clock.Clock) {
+	if c != nil {
+		e.clock = c
 	}
 }
 
@@ -61,7 +124,7 @@ func (e *Engine) Start() error {
 	e.logger.Info("Starting workflow engine")
 
 	// Start task processing workers
-	for i := 0; i < 10; i++ { // Configurable worker count
+	for i := 0; i < e.config.WorkerCount; i++ {
 		e.wg.Add(1)
 		go e.taskProcessorWorker(i)
 	}
@@ -74,6 +137,12 @@ func (e *Engine) Start() error {
 	e.wg.Add(1)
 	go e.executionMonitorWorker()
 
+	// Renew the leases of owned executions and take over abandoned ones
+	if e.leases != nil {
+		e.wg.Add(1)
+		go e.leaseWorker()
+	}
+
 	e.logger.Info("Workflow engine started successfully")
 	return nil
 }
@@ -94,6 +163,13 @@ func (e *Engine) Stop() error {
 
 // ExecuteWorkflow starts execution of a workflow
 func (e *Engine) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*WorkflowExecution, error) {
+	return e.StartExecution(ctx, workflow, "api", nil)
+}
+
+// StartExecution starts execution of a workflow with the input as the
+// execution context, recording what triggered it. The execution runs until
+// ctx is cancelled.
+func (e *Engine) StartExecution(ctx context.Context, workflow *Workflow, triggeredBy string, input map[string]interface{}) (*WorkflowExecution, error) {
 	e.logger.WithField("workflow_id", workflow.ID).Info("Starting workflow execution")
 
 	// Validate workflow
@@ -103,19 +179,25 @@ func (e *Engine) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*Work
 
 	// Create execution instance
 	execution := &WorkflowExecution{
-		ID:             uuid.New().String(),
-		WorkflowID:     workflow.ID,
-		Status:         WorkflowStatusPending,
-		StartTime:      time.Now(),
-		TaskExecutions: make(map[string]*TaskExecution),
-		Context:        make(map[string]interface{}),
-		AgentsUsed:     make([]string, 0),
-		TriggeredBy:    "api", // Could be extracted from context
+		ID:              uuid.New().String(),
+		WorkflowID:      workflow.ID,
+		WorkflowVersion: workflow.Version,
+		Status:          WorkflowStatusPending,
+		StartTime:       e.clock.Now(),
+		TaskExecutions:  make(map[string]*TaskExecution),
+		Context:         make(map[string]interface{}, len(input)),
+		AgentsUsed:      make([]string, 0),
+		TriggeredBy:     triggeredBy,
+		Timeout:         e.executionTimeout(workflow),
 		Metrics: ExecutionMetrics{
 			TotalTasks: len(workflow.Tasks),
 		},
 	}
 
+	for k, v := range input {
+		execution.Context[k] = v
+	}
+
 	// Initialize task executions
 	for _, task := range workflow.Tasks {
 		execution.TaskExecutions[task.ID] = &TaskExecution{
@@ -133,9 +215,74 @@ func (e *Engine) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*Work
 		return nil, fmt.Errorf("failed to store execution: %w", err)
 	}
 
-	// Register execution for monitoring
+	// Claim the execution for this instance
+	if e.leases != nil {
+		now := e.clock.Now()
+		acquired, err := e.leases.Acquire(ctx, execution.ID, e.config.InstanceID, now, now.Add(e.config.LeaseTTL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire execution lease: %w", err)
+		}
+		if !acquired {
+			return nil, fmt.Errorf("execution %s is owned by another instance", execution.ID)
+		}
+	}
+
+	e.launchExecution(ctx, workflow, execution)
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"workflow_id":  workflow.ID,
+	}).Info("Workflow execution started")
+
+	return execution, nil
+}
+
+// stateLock is the lock on an execution's state and the number of goroutines
+// holding or waiting for it
+type stateLock struct {
+	sync.Mutex
+	users int
+}
+
+// withState runs fn holding the lock on the state of the execution and its
+// task executions
+func (e *Engine) withState(execution *WorkflowExecution, fn func()) {
+	e.executionMutex.Lock()
+	lock, exists := e.stateLocks[execution.ID]
+	if !exists {
+		lock = &stateLock{}
+		e.stateLocks[execution.ID] = lock
+	}
+	lock.users++
+	e.executionMutex.Unlock()
+
+	lock.Lock()
+	defer func() {
+		lock.Unlock()
+
+		// Locks of finished executions are dropped once no longer in use
+		e.executionMutex.Lock()
+		lock.users--
+		if _, active := e.activeExecutions[execution.ID]; !active && lock.users == 0 {
+			delete(e.stateLocks, execution.ID)
+		}
+		e.executionMutex.Unlock()
+	}()
+	fn()
+}
+
+// launchExecution registers an execution and runs it asynchronously
+func (e *Engine) launchExecution(ctx context.Context, workflow *Workflow, execution *WorkflowExecution) {
+	// Register execution for monitoring; cancelling its context stops its tasks
+	execCtx, cancel := context.WithCancelCause(ctx)
+	gate := newPauseGate()
+	if execution.Status == WorkflowStatusPaused {
+		gate.pause()
+	}
 	e.executionMutex.Lock()
 	e.activeExecutions[execution.ID] = execution
+	e.pauseGates[execution.ID] = gate
+	e.executionCancels[execution.ID] = cancel
 	e.executionMutex.Unlock()
 
 	// Start monitoring
@@ -145,25 +292,23 @@ func (e *Engine) ExecuteWorkflow(ctx context.Context, workflow *Workflow) (*Work
 
 	// Start execution asynchronously
 	e.wg.Add(1)
-	go e.executeWorkflowAsync(ctx, workflow, execution)
-
-	e.logger.WithFields(log.Fields{
-		"execution_id": execution.ID,
-		"workflow_id":  workflow.ID,
-	}).Info("Workflow execution started")
-
-	return execution, nil
+	go e.executeWorkflowAsync(execCtx, workflow, execution)
 }
 
 // executeWorkflowAsync handles the actual workflow execution
 func (e *Engine) executeWorkflowAsync(ctx context.Context, workflow *Workflow, execution *WorkflowExecution) {
 	defer e.wg.Done()
+	defer e.forgetLostLease(execution.ID)
 
 	e.logger.WithField("execution_id", execution.ID).Debug("Starting async workflow execution")
 
-	// Update status to running
-	execution.Status = WorkflowStatusRunning
-	e.updateExecution(ctx, execution)
+	// Update status to running; executions taken over keep their status
+	e.withState(execution, func() {
+		if execution.Status == WorkflowStatusPending {
+			execution.Status = WorkflowStatusRunning
+		}
+		e.updateExecution(ctx, execution)
+	})
 
 	// Build dependency graph
 	depGraph, err := e.buildDependencyGraph(workflow)
@@ -173,7 +318,22 @@ func (e *Engine) executeWorkflowAsync(ctx context.Context, workflow *Workflow, e
 	}
 
 	// Execute tasks in dependency order
-	if err := e.executeTasks(ctx, workflow, execution, depGraph); err != nil {
+	err = e.executeTasks(ctx, workflow, execution, depGraph)
+
+	// Cancelled and timed-out executions were finalized when they were
+	// stopped, executions taken over are finished by their new owner and
+	// migrated executions continue on the new workflow version
+	if cause := context.Cause(ctx); errors.Is(cause, ErrExecutionCancelled) || errors.Is(cause, ErrExecutionTimedOut) ||
+		errors.Is(cause, ErrLeaseLost) || errors.Is(cause, ErrExecutionMigrated) {
+		return
+	}
+	if err != nil {
+		// Undo the tasks that completed before the failure
+		if workflow.Configuration.FailurePolicy.OnTaskFailure == FailurePolicyRollback {
+			if rollbackErr := e.compensate(ctx, workflow, execution, depGraph); rollbackErr != nil {
+				err = fmt.Errorf("%w; rollback incomplete: %v", err, rollbackErr)
+			}
+		}
 		e.failExecution(ctx, execution, err)
 		return
 	}
@@ -200,6 +360,15 @@ func (e *Engine) buildDependencyGraph(workflow *Workflow) (*DependencyGraph, err
 		}
 	}
 
+	// Tasks also run after the tasks they refer to and the branches choosing them
+	for _, task := range workflow.Tasks {
+		for _, depID := range implicitDependencies(task, workflow) {
+			if err := graph.AddEdge(depID, task.ID); err != nil {
+				return nil, fmt.Errorf("invalid dependency %s -> %s: %w", depID, task.ID, err)
+			}
+		}
+	}
+
 	// Validate graph (check for cycles)
 	if err := graph.ValidateAcyclic(); err != nil {
 		return nil, fmt.Errorf("workflow contains circular dependencies: %w", err)
@@ -219,6 +388,11 @@ func (e *Engine) executeTasks(ctx context.Context, workflow *Workflow, execution
 	batches := depGraph.GetExecutionBatches()
 
 	for batchIndex, batch := range batches {
+		// A paused execution starts no new batch until resumed
+		if err := e.awaitScheduling(ctx, execution); err != nil {
+			return err
+		}
+
 		e.logger.WithFields(log.Fields{
 			"execution_id": execution.ID,
 			"batch_index":  batchIndex,
@@ -235,10 +409,24 @@ func (e *Engine) executeTasks(ctx context.Context, workflow *Workflow, execution
 				return fmt.Errorf("task %s not found in workflow", taskID)
 			}
 
+			// Tasks on the path a branch did not take are skipped
+			var skipped bool
+			e.withState(execution, func() {
+				skipped = e.skipAfterBranch(taskID, execution, depGraph)
+			})
+			if skipped {
+				continue
+			}
+
 			batchWg.Add(1)
 			go func(t *WorkflowTask) {
 				defer batchWg.Done()
-				if err := e.executeTask(ctx, t, execution); err != nil {
+				// Tasks of the batch not yet started wait out a pause
+				if err := e.awaitScheduling(ctx, execution); err != nil {
+					batchErrors <- err
+					return
+				}
+				if err := e.runTask(ctx, t, execution); err != nil {
 					batchErrors <- err
 				}
 			}(task)
@@ -258,6 +446,10 @@ func (e *Engine) executeTasks(ctx context.Context, workflow *Workflow, execution
 		}
 
 		if batchErr != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+
 			// Handle failure policy
 			if e.shouldStopOnFailure(workflow, execution) {
 				return batchErr
@@ -267,7 +459,7 @@ func (e *Engine) executeTasks(ctx context.Context, workflow *Workflow, execution
 		// Check if context was cancelled
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return context.Cause(ctx)
 		default:
 		}
 	}
@@ -277,28 +469,75 @@ func (e *Engine) executeTasks(ctx context.Context, workflow *Workflow, execution
 
 // executeTask executes a single workflow task
 func (e *Engine) executeTask(ctx context.Context, task *WorkflowTask, execution *WorkflowExecution) error {
-	taskExecution := execution.TaskExecutions[task.ID]
-
 	e.logger.WithFields(log.Fields{
 		"execution_id": execution.ID,
 		"task_id":      task.ID,
 		"task_type":    task.Type,
 	}).Debug("Executing task")
 
-	// Check task conditions
-	if !e.shouldExecuteTask(task, execution) {
-		taskExecution.Status = TaskStatusSkipped
+	var taskExecution *TaskExecution
+	var run bool
+	e.withState(execution, func() {
+		taskExecution = execution.TaskExecutions[task.ID]
+
+		// Tasks finished before a pause are not run again on resume
+		if taskExecution.Status == TaskStatusCompleted || taskExecution.Status == TaskStatusSkipped {
+			return
+		}
+
+		// Check task conditions
+		if !e.shouldExecuteTask(task, execution) {
+			taskExecution.Status = TaskStatusSkipped
+			e.updateExecution(ctx, execution)
+			return
+		}
+
+		// Update task status
+		taskExecution.Status = TaskStatusQueued
+		taskExecution.StartTime = e.clock.Now()
 		e.updateExecution(ctx, execution)
+		run = true
+	})
+	if !run {
 		return nil
 	}
 
-	// Update task status
-	taskExecution.Status = TaskStatusQueued
-	taskExecution.StartTime = time.Now()
-	e.updateExecution(ctx, execution)
+	// Branches are evaluated by the engine rather than dispatched to an agent
+	if task.Branch != nil {
+		return e.executeBranch(ctx, task, taskExecution, execution)
+	}
+
+	// Manual approvals wait for a person's decision
+	if task.Type == TaskTypeManualApproval {
+		return e.executeApproval(ctx, task, taskExecution, execution)
+	}
+
+	// Resolve inputs piped from the execution context and earlier tasks, and
+	// the agents to run near or away from
+	var selector AgentSelector
+	var err error
+	e.withState(execution, func() {
+		if len(task.InputMapping) > 0 {
+			var inputs map[string]interface{}
+			if inputs, err = resolveInputs(task.InputMapping, execution); err != nil {
+				now := e.clock.Now()
+				taskExecution.EndTime = &now
+				taskExecution.Duration = now.Sub(taskExecution.StartTime)
+				taskExecution.Status = TaskStatusFailed
+				taskExecution.Error = err.Error()
+				e.updateExecution(ctx, execution)
+				return
+			}
+			taskExecution.Inputs = inputs
+		}
+		selector = resolveAgentSelector(task.AgentSelector, execution)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resolve inputs of task %s: %w", task.ID, err)
+	}
 
 	// Select agent for task execution
-	agents, err := e.coordinator.SelectAgents(ctx, task.AgentSelector, 1)
+	agents, err := e.coordinator.SelectAgents(ctx, selector, 1)
 	if err != nil {
 		return fmt.Errorf("failed to select agent for task %s: %w", task.ID, err)
 	}
@@ -308,31 +547,42 @@ func (e *Engine) executeTask(ctx context.Context, task *WorkflowTask, execution
 	}
 
 	selectedAgent := agents[0]
-	taskExecution.AgentID = selectedAgent.ID
+	e.withState(execution, func() {
+		taskExecution.AgentID = selectedAgent.ID
 
-	// Add agent to execution agents list
-	e.addAgentToExecution(execution, selectedAgent.ID)
+		// Add agent to execution agents list
+		e.addAgentToExecution(execution, selectedAgent.ID)
 
-	// Update task status to running
-	taskExecution.Status = TaskStatusRunning
-	e.updateExecution(ctx, execution)
-
-	// Execute task with retry logic
-	err = e.executeTaskWithRetry(ctx, task, taskExecution, selectedAgent, execution)
-
-	// Update end time and duration
-	now := time.Now()
-	taskExecution.EndTime = &now
-	taskExecution.Duration = now.Sub(taskExecution.StartTime)
+		// Update task status to running
+		taskExecution.Status = TaskStatusRunning
+		e.updateExecution(ctx, execution)
+	})
 
-	if err != nil {
-		taskExecution.Status = TaskStatusFailed
-		taskExecution.Error = err.Error()
+	// Execute task with retry logic, once per loop iteration for loops
+	if task.Loop != nil {
+		err = e.executeLoop(ctx, task, taskExecution, selectedAgent, execution)
 	} else {
-		taskExecution.Status = TaskStatusCompleted
+		err = e.executeTaskWithRetry(ctx, task, taskExecution, selectedAgent, execution)
 	}
 
-	e.updateExecution(ctx, execution)
+	e.withState(execution, func() {
+		// Update end time and duration
+		now := e.clock.Now()
+		taskExecution.EndTime = &now
+		taskExecution.Duration = now.Sub(taskExecution.StartTime)
+
+		if err != nil {
+			taskExecution.Status = TaskStatusFailed
+			taskExecution.Error = err.Error()
+		} else {
+			taskExecution.Status = TaskStatusCompleted
+			if taskExecution.Progress != nil {
+				taskExecution.Progress.Percent = 100
+			}
+		}
+
+		e.updateExecution(ctx, execution)
+	})
 	return err
 }
 
@@ -346,10 +596,23 @@ func (e *Engine) executeTaskWithRetry(ctx context.Context, task *WorkflowTask, t
 	}
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		taskExecution.Attempts = attempt
+		e.withState(execution, func() {
+			taskExecution.Attempts = attempt
+		})
+
+		// A retried task waits out a pause, then continues from where the agent last checkpointed
+		if attempt > 1 {
+			if err := e.awaitScheduling(ctx, execution); err != nil {
+				return err
+			}
+			e.resumeFromCheckpoint(ctx, taskExecution, execution)
+		}
 
 		// Create task context with timeout
-		taskCtx, cancel := context.WithTimeout(ctx, task.Timeout)
+		taskCtx, cancel := context.WithCancel(ctx)
+		if task.Timeout > 0 {
+			taskCtx, cancel = context.WithTimeout(ctx, task.Timeout)
+		}
 
 		// Execute the task
 		err := e.assignAndExecuteTask(taskCtx, task, taskExecution, agent, execution)
@@ -361,10 +624,17 @@ func (e *Engine) executeTaskWithRetry(ctx context.Context, task *WorkflowTask, t
 		}
 
 		lastError = err
-		taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Attempt %d failed: %s", attempt, err.Error()))
+		retry := attempt < maxAttempts && e.shouldRetryTask(task, err)
+		e.withState(execution, func() {
+			taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Attempt %d failed: %s", attempt, err.Error()))
+			if retry {
+				taskExecution.Status = TaskStatusRetrying
+				e.updateExecution(ctx, execution)
+			}
+		})
 
 		// Check if we should retry
-		if attempt < maxAttempts && e.shouldRetryTask(task, err) {
+		if retry {
 			// Calculate retry delay
 			delay := e.calculateRetryDelay(task.RetryPolicy, attempt)
 
@@ -375,15 +645,11 @@ func (e *Engine) executeTaskWithRetry(ctx context.Context, task *WorkflowTask, t
 				"delay":        delay,
 			}).Debug("Retrying task after delay")
 
-			// Update status to retrying
-			taskExecution.Status = TaskStatusRetrying
-			e.updateExecution(ctx, execution)
-
 			// Wait for retry delay
 			select {
-			case <-time.After(delay):
+			case <-e.clock.After(delay):
 			case <-ctx.Done():
-				return ctx.Err()
+				return context.Cause(ctx)
 			}
 		}
 	}
@@ -398,48 +664,140 @@ func (e *Engine) assignAndExecuteTask(ctx context.Context, task *WorkflowTask, t
 		return fmt.Errorf("failed to assign task to agent: %w", err)
 	}
 
-	// For now, simulate task execution
-	// In a real implementation, this would delegate to the agent's task system
-	return e.simulateTaskExecution(ctx, task, taskExecution, agent, execution)
+	return e.dispatchTask(ctx, task, taskExecution, agent, execution)
 }
 
-// simulateTaskExecution simulates task execution (placeholder for real implementation)
-func (e *Engine) simulateTaskExecution(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, agent *agent.Agent, execution *WorkflowExecution) error {
-	// This is a simulation - in real implementation, this would:
-	// 1. Convert WorkflowTask to agent.Task
-	// 2. Submit to agent's task system
-	// 3. Wait for completion
-	// 4. Capture results and outputs
+// dispatchTask submits the task to the agent through the coordinator and waits
+// for its completion to be reported on the completion queue
+func (e *Engine) dispatchTask(ctx context.Context, task *WorkflowTask, taskExecution *TaskExecution, ag *agent.Agent, execution *WorkflowExecution) error {
+	var agentTask agent.Task
+	e.withState(execution, func() {
+		agentTask = e.toAgentTask(task, taskExecution, execution)
+	})
+
+	// Register before dispatching so a fast completion is not missed
+	completion := make(chan *agent.TaskResult, 1)
+	e.pendingMutex.Lock()
+	e.pendingTasks[agentTask.ID] = completion
+	e.pendingMutex.Unlock()
+	defer func() {
+		e.pendingMutex.Lock()
+		delete(e.pendingTasks, agentTask.ID)
+		e.pendingMutex.Unlock()
+	}()
+
+	if err := e.coordinator.DispatchTask(ctx, ag.ID, agentTask); err != nil {
+		return fmt.Errorf("failed to dispatch task to agent: %w", err)
+	}
 
 	e.logger.WithFields(log.Fields{
-		"task_id":  task.ID,
-		"agent_id": agent.ID,
-		"type":     task.Type,
-	}).Debug("Simulating task execution")
+		"task_id":       task.ID,
+		"agent_id":      ag.ID,
+		"agent_task_id": agentTask.ID,
+		"type":          task.Type,
+	}).Debug("Waiting for task completion")
 
-	// Simulate processing time
 	select {
-	case <-time.After(100 * time.Millisecond):
+	case result := <-completion:
+		var err error
+		e.withState(execution, func() {
+			err = e.captureTaskResult(task, taskExecution, result)
+		})
+		return err
 	case <-ctx.Done():
-		return ctx.Err()
+		return context.Cause(ctx)
+	}
+}
+
+// toAgentTask converts a workflow task into a task for the agent's queue. The
+// payload carries the execution and task IDs so agents can save checkpoints,
+// and the context the execution was started with. Callers hold the
+// execution's state lock.
+func (e *Engine) toAgentTask(task *WorkflowTask, taskExecution *TaskExecution, execution *WorkflowExecution) agent.Task {
+	// Resolved inputs are passed as parameters, taking precedence over static ones
+	parameters := task.Parameters
+	if len(taskExecution.Inputs) > 0 {
+		parameters = make(map[string]interface{}, len(task.Parameters)+len(taskExecution.Inputs))
+		for k, v := range task.Parameters {
+			parameters[k] = v
+		}
+		for k, v := range taskExecution.Inputs {
+			parameters[k] = v
+		}
+	}
+
+	payload := map[string]interface{}{
+		"workflow_id":  execution.WorkflowID,
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+		"attempt":      taskExecution.Attempts,
+		"parameters":   parameters,
+	}
+	if len(execution.Context) > 0 {
+		payload["context"] = execution.Context
 	}
 
-	// Simulate task results
-	taskExecution.Output["result"] = "success"
-	taskExecution.Output["processed_at"] = time.Now()
-	taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Task %s executed successfully on agent %s", task.ID, agent.ID))
+	return agent.Task{
+		ID:        uuid.New().String(),
+		Type:      task.Type,
+		Payload:   payload,
+		Priority:  task.Priority,
+		Timeout:   task.Timeout,
+		CreatedAt: e.clock.Now(),
+	}
+}
 
-	// Update resource usage
-	taskExecution.ResourceUsage = ResourceUsage{
-		CPU:       task.Resources.CPU,
-		Memory:    task.Resources.Memory,
-		NetworkIO: 1024, // Simulated
-		DiskIO:    512,  // Simulated
+// captureTaskResult records the outputs and resource usage reported by the
+// agent. Callers hold the execution's state lock.
+func (e *Engine) captureTaskResult(task *WorkflowTask, taskExecution *TaskExecution, result *agent.TaskResult) error {
+	if !result.Success {
+		err := result.Error
+		if err == nil {
+			err = fmt.Errorf("agent reported failure")
+		}
+		return fmt.Errorf("task failed on agent %s: %w", result.AgentID, err)
 	}
 
+	// Map outputs are captured by key, through the output mapping when set
+	outputs, isMap := result.Result.(map[string]interface{})
+	switch {
+	case isMap && len(task.OutputMapping) > 0:
+		for name, source := range task.OutputMapping {
+			if value, ok := outputs[source]; ok {
+				taskExecution.Output[name] = value
+			}
+		}
+	case isMap:
+		for key, value := range outputs {
+			taskExecution.Output[key] = value
+		}
+	case result.Result != nil:
+		taskExecution.Output["result"] = result.Result
+	}
+	taskExecution.Output["completed_at"] = result.CompletedAt
+
+	if result.Usage != nil {
+		taskExecution.ResourceUsage = ResourceUsage{
+			CPU:       result.Usage.CPU,
+			Memory:    result.Usage.Memory,
+			NetworkIO: result.Usage.NetworkIO,
+			DiskIO:    result.Usage.DiskIO,
+		}
+	}
+
+	taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Task %s completed on agent %s in %s", task.ID, result.AgentID, result.Duration))
 	return nil
 }
 
+// HandleTaskResult queues the result of a dispatched agent task for the
+// completion processor
+func (e *Engine) HandleTaskResult(result *agent.TaskResult) {
+	select {
+	case e.completionQueue <- result:
+	case <-e.ctx.Done():
+	}
+}
+
 // Helper methods
 
 func (e *Engine) validateWorkflow(workflow *Workflow) error {
@@ -463,6 +821,22 @@ func (e *Engine) validateWorkflow(workflow *Workflow) error {
 		taskIDs[task.ID] = true
 	}
 
+	// Validate input mappings, branches, loops, compensations and affinity rules reference existing tasks
+	for _, task := range workflow.Tasks {
+		if err := validateInputMapping(task, taskIDs); err != nil {
+			return err
+		}
+		if err := validateControlFlow(task, taskIDs); err != nil {
+			return err
+		}
+		if err := validateCompensation(task, taskIDs); err != nil {
+			return err
+		}
+		if err := validateAffinity(task, taskIDs); err != nil {
+			return err
+		}
+	}
+
 	// Validate dependencies reference existing tasks
 	for taskID, deps := range workflow.Dependencies {
 		if !taskIDs[taskID] {
@@ -503,6 +877,13 @@ func (e *Engine) evaluateCondition(condition TaskCondition, execution *WorkflowE
 		expectedValue := condition.Parameters["value"]
 		actualValue := execution.Context[key]
 		return actualValue == expectedValue
+	case "expression":
+		holds, err := evaluateExpression(condition.Expression, execution)
+		if err != nil {
+			e.logger.WithError(err).WithField("expression", condition.Expression).Warn("Failed to evaluate task condition")
+			return false
+		}
+		return holds
 	default:
 		return true
 	}
@@ -519,6 +900,8 @@ func (e *Engine) shouldStopOnFailure(workflow *Workflow, execution *WorkflowExec
 	case "retry_workflow":
 		// Could implement workflow retry logic here
 		return true
+	case FailurePolicyRollback:
+		return true
 	default:
 		return true
 	}
@@ -561,28 +944,36 @@ func (e *Engine) addAgentToExecution(execution *WorkflowExecution, agentID strin
 	execution.AgentsUsed = append(execution.AgentsUsed, agentID)
 }
 
+// updateExecution persists the execution and reports its task transitions.
+// Callers hold the execution's state lock.
 func (e *Engine) updateExecution(ctx context.Context, execution *WorkflowExecution) {
-	if err := e.repository.UpdateExecution(ctx, execution); err != nil {
+	if e.leaseLost(execution.ID) {
+		return
+	}
+	execution.Progress = executionProgress(execution)
+	// Persist the final state of tasks stopped by cancelling the execution context
+	if err := e.repository.UpdateExecution(context.WithoutCancel(ctx), execution); err != nil {
 		e.logger.WithError(err).Error("Failed to update execution")
 	}
+	e.recordTaskTransitions(context.WithoutCancel(ctx), execution)
 }
 
 func (e *Engine) failExecution(ctx context.Context, execution *WorkflowExecution, err error) {
-	execution.Status = WorkflowStatusFailed
-	execution.Error = err.Error()
-	now := time.Now()
-	execution.EndTime = &now
-	execution.Duration = now.Sub(execution.StartTime)
+	e.withState(execution, func() {
+		execution.Status = WorkflowStatusFailed
+		execution.Error = err.Error()
+		now := e.clock.Now()
+		execution.EndTime = &now
+		execution.Duration = now.Sub(execution.StartTime)
 
-	e.updateExecution(ctx, execution)
+		e.updateExecution(ctx, execution)
+	})
 
 	// Remove from active executions
-	e.executionMutex.Lock()
-	delete(e.activeExecutions, execution.ID)
-	e.executionMutex.Unlock()
+	e.stopExecution(execution.ID, WorkflowStatusFailed, nil)
 
 	// Stop monitoring
-	if err := e.monitor.StopMonitoring(ctx, execution.ID); err != nil {
+	if err := e.monitor.StopMonitoring(context.WithoutCancel(ctx), execution.ID); err != nil {
 		e.logger.WithError(err).Error("Failed to stop execution monitoring")
 	}
 
@@ -590,26 +981,26 @@ func (e *Engine) failExecution(ctx context.Context, execution *WorkflowExecution
 }
 
 func (e *Engine) completeExecution(ctx context.Context, execution *WorkflowExecution) {
-	execution.Status = WorkflowStatusCompleted
-	now := time.Now()
-	execution.EndTime = &now
-	execution.Duration = now.Sub(execution.StartTime)
-
-	// Update metrics
-	execution.Metrics.CompletedTasks = e.countTasksByStatus(execution, TaskStatusCompleted)
-	execution.Metrics.FailedTasks = e.countTasksByStatus(execution, TaskStatusFailed)
-	execution.Metrics.SkippedTasks = e.countTasksByStatus(execution, TaskStatusSkipped)
-	execution.Metrics.AgentsUtilized = len(execution.AgentsUsed)
+	e.withState(execution, func() {
+		execution.Status = WorkflowStatusCompleted
+		now := e.clock.Now()
+		execution.EndTime = &now
+		execution.Duration = now.Sub(execution.StartTime)
+
+		// Update metrics
+		execution.Metrics.CompletedTasks = e.countTasksByStatus(execution, TaskStatusCompleted)
+		execution.Metrics.FailedTasks = e.countTasksByStatus(execution, TaskStatusFailed)
+		execution.Metrics.SkippedTasks = e.countTasksByStatus(execution, TaskStatusSkipped)
+		execution.Metrics.AgentsUtilized = len(execution.AgentsUsed)
 
-	e.updateExecution(ctx, execution)
+		e.updateExecution(ctx, execution)
+	})
 
 	// Remove from active executions
-	e.executionMutex.Lock()
-	delete(e.activeExecutions, execution.ID)
-	e.executionMutex.Unlock()
+	e.stopExecution(execution.ID, WorkflowStatusCompleted, nil)
 
 	// Stop monitoring
-	if err := e.monitor.StopMonitoring(ctx, execution.ID); err != nil {
+	if err := e.monitor.StopMonitoring(context.WithoutCancel(ctx), execution.ID); err != nil {
 		e.logger.WithError(err).Error("Failed to stop execution monitoring")
 	}
 
@@ -637,16 +1028,19 @@ func (e *Engine) taskProcessorWorker(workerID int) {
 		select {
 		case <-e.ctx.Done():
 			return
-		case taskExecution := <-e.taskQueue:
-			// Process task execution
+		case queued := <-e.taskQueue:
 			e.logger.WithFields(log.Fields{
 				"worker_id":    workerID,
-				"task_id":      taskExecution.TaskID,
-				"execution_id": "unknown", // Would need to pass execution context
+				"task_id":      queued.task.ID,
+				"execution_id": queued.execution.ID,
 			}).Debug("Processing task")
 
-			// Task processing logic would go here
-			// For now, this is handled directly in executeTask
+			// Tasks of executions stopped while queued are not started
+			if queued.ctx.Err() != nil {
+				queued.done <- context.Cause(queued.ctx)
+				continue
+			}
+			queued.done <- e.executeTask(queued.ctx, queued.task, queued.execution)
 		}
 	}
 }
@@ -660,11 +1054,19 @@ func (e *Engine) completionProcessorWorker() {
 		select {
 		case <-e.ctx.Done():
 			return
-		case taskExecution := <-e.completionQueue:
-			// Process task completion
-			e.logger.WithField("task_id", taskExecution.TaskID).Debug("Processing task completion")
+		case result := <-e.completionQueue:
+			e.logger.WithField("agent_task_id", result.TaskID).Debug("Processing task completion")
 
-			// Completion processing logic would go here
+			e.pendingMutex.Lock()
+			completion, pending := e.pendingTasks[result.TaskID]
+			delete(e.pendingTasks, result.TaskID)
+			e.pendingMutex.Unlock()
+
+			if !pending {
+				e.logger.WithField("agent_task_id", result.TaskID).Debug("Ignoring completion of a task no longer awaited")
+				continue
+			}
+			completion <- result
 		}
 	}
 }
@@ -674,14 +1076,14 @@ func (e *Engine) executionMonitorWorker() {
 
 	e.logger.Debug("Execution monitor worker started")
 
-	ticker := time.NewTicker(10 * time.Second) // Monitor every 10 seconds
+	ticker := e.clock.NewTicker(10 * time.Second) // Monitor every 10 seconds
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-e.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			e.monitorActiveExecutions()
 		}
 	}
@@ -698,96 +1100,133 @@ func (e *Engine) monitorActiveExecutions() {
 	for _, execution := range executions {
 		// Check for timeouts, stuck tasks, etc.
 		e.checkExecutionHealth(execution)
+
+		if e.refreshProgress(e.ctx, execution) {
+			e.withState(execution, func() {
+				e.updateExecution(e.ctx, execution)
+			})
+		}
 	}
 }
 
 func (e *Engine) checkExecutionHealth(execution *WorkflowExecution) {
-	// Check for execution timeout
-	if e.config.DefaultWorkflowTimeout > 0 {
-		if time.Since(execution.StartTime) > e.config.DefaultWorkflowTimeout {
-			e.logger.WithField("execution_id", execution.ID).Warn("Workflow execution timeout")
-			// Could implement timeout handling here
-		}
+	// Stop executions that ran past their timeout
+	if execution.Timeout > 0 && e.clock.Since(execution.StartTime) > execution.Timeout {
+		e.timeoutExecution(e.ctx, execution.ID)
+		return
 	}
 
 	// Check for stuck tasks
-	for taskID, taskExec := range execution.TaskExecutions {
-		if taskExec.Status == TaskStatusRunning {
-			// Check task timeout
-			if time.Since(taskExec.StartTime) > 5*time.Minute { // Configurable
-				e.logger.WithFields(log.Fields{
-					"execution_id": execution.ID,
-					"task_id":      taskID,
-				}).Warn("Task execution appears stuck")
+	e.withState(execution, func() {
+		for taskID, taskExec := range execution.TaskExecutions {
+			if taskExec.Status == TaskStatusRunning {
+				// Check task timeout; recent checkpoints show a long-running task is still making progress
+				lastActivity := taskExec.StartTime
+				if taskExec.Progress != nil && taskExec.Progress.UpdatedAt.After(lastActivity) {
+					lastActivity = taskExec.Progress.UpdatedAt
+				}
+				if e.clock.Since(lastActivity) > 5*time.Minute { // Configurable
+					e.logger.WithFields(log.Fields{
+						"execution_id": execution.ID,
+						"task_id":      taskID,
+					}).Warn("Task execution appears stuck")
+				}
 			}
 		}
-	}
+	})
 }
 
 // Interface implementation methods
 
 func (e *Engine) GetExecution(ctx context.Context, executionID string) (*WorkflowExecution, error) {
+	// Surface the latest checkpoints of running tasks
+	e.executionMutex.RLock()
+	execution, active := e.activeExecutions[executionID]
+	e.executionMutex.RUnlock()
+	if active && e.refreshProgress(ctx, execution) {
+		e.withState(execution, func() {
+			e.updateExecution(ctx, execution)
+		})
+	}
+
 	return e.repository.GetExecution(ctx, executionID)
 }
 
+// ListExecutions returns executions matching the filters from the repository, newest first
 func (e *Engine) ListExecutions(ctx context.Context, filters ExecutionFilters) ([]*WorkflowExecution, error) {
-	// For now, return empty list - would implement repository query
-	return []*WorkflowExecution{}, nil
+	if err := filters.validate(); err != nil {
+		return nil, err
+	}
+	return e.repository.ListExecutions(ctx, filters)
 }
 
 func (e *Engine) CancelExecution(ctx context.Context, executionID string) error {
-	e.executionMutex.Lock()
-	execution, exists := e.activeExecutions[executionID]
-	if !exists {
-		e.executionMutex.Unlock()
+	execution, stopped := e.stopExecution(executionID, WorkflowStatusCancelled, ErrExecutionCancelled)
+	if !stopped {
 		return fmt.Errorf("execution not found: %s", executionID)
 	}
 
-	execution.Status = WorkflowStatusCancelled
-	delete(e.activeExecutions, executionID)
-	e.executionMutex.Unlock()
+	e.withState(execution, func() {
+		now := e.clock.Now()
+		execution.EndTime = &now
+		execution.Duration = now.Sub(execution.StartTime)
 
-	now := time.Now()
-	execution.EndTime = &now
-	execution.Duration = now.Sub(execution.StartTime)
+		e.updateExecution(ctx, execution)
+	})
 
-	e.updateExecution(ctx, execution)
+	if err := e.monitor.StopMonitoring(ctx, executionID); err != nil {
+		e.logger.WithError(err).Error("Failed to stop execution monitoring")
+	}
 
 	e.logger.WithField("execution_id", executionID).Info("Workflow execution cancelled")
 	return nil
 }
 
 func (e *Engine) PauseExecution(ctx context.Context, executionID string) error {
-	e.executionMutex.Lock()
-	execution, exists := e.activeExecutions[executionID]
-	if !exists {
-		e.executionMutex.Unlock()
-		return fmt.Errorf("execution not found: %s", executionID)
-	}
-
-	execution.Status = WorkflowStatusPaused
-	e.executionMutex.Unlock()
-
-	e.updateExecution(ctx, execution)
-
-	e.logger.WithField("execution_id", executionID).Info("Workflow execution paused")
-	return nil
+	return e.setPaused(ctx, executionID, true)
 }
 
 func (e *Engine) ResumeExecution(ctx context.Context, executionID string) error {
-	e.executionMutex.Lock()
+	return e.setPaused(ctx, executionID, false)
+}
+
+// setPaused pauses or resumes an active execution
+func (e *Engine) setPaused(ctx context.Context, executionID string, paused bool) error {
+	e.executionMutex.RLock()
 	execution, exists := e.activeExecutions[executionID]
+	e.executionMutex.RUnlock()
 	if !exists {
-		e.executionMutex.Unlock()
 		return fmt.Errorf("execution not found: %s", executionID)
 	}
 
-	execution.Status = WorkflowStatusRunning
-	e.executionMutex.Unlock()
+	e.withState(execution, func() {
+		// The execution may have finished while waiting for the lock
+		e.executionMutex.RLock()
+		gate, active := e.pauseGates[executionID]
+		e.executionMutex.RUnlock()
+		if !active {
+			exists = false
+			return
+		}
 
-	e.updateExecution(ctx, execution)
+		if paused {
+			execution.Status = WorkflowStatusPaused
+			gate.pause()
+		} else {
+			execution.Status = WorkflowStatusRunning
+			gate.resume()
+		}
+		e.updateExecution(ctx, execution)
+	})
+	if !exists {
+		return fmt.Errorf("execution not found: %s", executionID)
+	}
 
-	e.logger.WithField("execution_id", executionID).Info("Workflow execution resumed")
+	if paused {
+		e.logger.WithField("execution_id", executionID).Info("Workflow execution paused")
+	} else {
+		e.logger.WithField("execution_id", executionID).Info("Workflow execution resumed")
+	}
 	return nil
 }
 
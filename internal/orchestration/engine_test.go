@@ -0,0 +1,368 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	log "github.com/sirupsen/logrus"
+)
+
+// fakeCoordinator completes dispatched tasks with the result of complete
+type fakeCoordinator struct {
+	AgentCoordinator
+	agents     []*agent.Agent
+	complete   func(task agent.Task) *agent.TaskResult
+	handlers   []func(*agent.TaskResult)
+	dispatched []agent.Task
+	mu         sync.Mutex
+}
+
+func (c *fakeCoordinator) SelectAgents(ctx context.Context, selector AgentSelector, count int) ([]*agent.Agent, error) {
+	return c.agents, nil
+}
+
+func (c *fakeCoordinator) AssignTask(ctx context.Context, agentID string, task *WorkflowTask, execution *WorkflowExecution) error {
+	return nil
+}
+
+func (c *fakeCoordinator) DispatchTask(ctx context.Context, agentID string, task agent.Task) error {
+	c.mu.Lock()
+	c.dispatched = append(c.dispatched, task)
+	c.mu.Unlock()
+	result := c.complete(task)
+	if result == nil {
+		return nil
+	}
+	result.TaskID = task.ID
+	result.AgentID = agentID
+	go func() {
+		for _, handler := range c.handlers {
+			handler(result)
+		}
+	}()
+	return nil
+}
+
+func (c *fakeCoordinator) OnTaskCompleted(handler func(*agent.TaskResult)) {
+	c.handlers = append(c.handlers, handler)
+}
+
+func TestEngine_DispatchTask(t *testing.T) {
+	ctx := context.Background()
+	coordinator := &fakeCoordinator{}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	engine := NewEngine(OrchestrationConfig{}, coordinator, nil, nil, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	ag := &agent.Agent{ID: "analyst-1"}
+	execution := &WorkflowExecution{ID: "exec-1", WorkflowID: "wf-1"}
+	newTaskExecution := func() *TaskExecution {
+		return &TaskExecution{TaskID: "analyze", Attempts: 1, Output: make(map[string]interface{})}
+	}
+	task := &WorkflowTask{
+		ID:            "analyze",
+		Type:          "data_processing",
+		Parameters:    map[string]interface{}{"source": "s3://readings"},
+		OutputMapping: map[string]string{"rows": "row_count"},
+	}
+
+	// Outputs and resource usage come from the agent's result
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		return &agent.TaskResult{
+			Success: true,
+			Result:  map[string]interface{}{"row_count": 42, "scratch": "tmp"},
+			Usage:   &agent.TaskUsage{CPU: 250, Memory: 64, NetworkIO: 2048},
+		}
+	}
+	taskExecution := newTaskExecution()
+	if err := engine.assignAndExecuteTask(ctx, task, taskExecution, ag, execution); err != nil {
+		t.Fatalf("expected the task to complete, got %v", err)
+	}
+	if taskExecution.Output["rows"] != 42 {
+		t.Errorf("expected the mapped output, got %v", taskExecution.Output)
+	}
+	if _, ok := taskExecution.Output["scratch"]; ok {
+		t.Errorf("expected unmapped outputs to be left out, got %v", taskExecution.Output)
+	}
+	if taskExecution.ResourceUsage.CPU != 250 || taskExecution.ResourceUsage.NetworkIO != 2048 {
+		t.Errorf("unexpected resource usage %+v", taskExecution.ResourceUsage)
+	}
+
+	dispatched := coordinator.dispatched[0]
+	payload, _ := dispatched.Payload.(map[string]interface{})
+	if dispatched.Type != "data_processing" || payload["execution_id"] != "exec-1" || payload["task_id"] != "analyze" {
+		t.Errorf("unexpected agent task %+v", dispatched)
+	}
+
+	// Failures reported by the agent fail the task
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		return &agent.TaskResult{Error: agent.ErrTaskTimeout}
+	}
+	if err := engine.assignAndExecuteTask(ctx, task, newTaskExecution(), ag, execution); !errors.Is(err, agent.ErrTaskTimeout) {
+		t.Errorf("expected the agent error, got %v", err)
+	}
+
+	// A task that never completes gives up when its context ends
+	coordinator.complete = func(task agent.Task) *agent.TaskResult { return nil }
+	taskCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := engine.assignAndExecuteTask(taskCtx, task, newTaskExecution(), ag, execution); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	engine.pendingMutex.Lock()
+	pending := len(engine.pendingTasks)
+	engine.pendingMutex.Unlock()
+	if pending != 0 {
+		t.Errorf("expected no pending tasks, got %d", pending)
+	}
+}
+
+// fakeRepository keeps the latest status of each execution
+type fakeRepository struct {
+	WorkflowRepository
+	statuses map[string]WorkflowStatus
+	listed   []ExecutionFilters
+	mu       sync.Mutex
+}
+
+func (r *fakeRepository) ListExecutions(ctx context.Context, filters ExecutionFilters) ([]*WorkflowExecution, error) {
+	r.listed = append(r.listed, filters)
+	return []*WorkflowExecution{}, nil
+}
+
+func (r *fakeRepository) StoreExecution(ctx context.Context, execution *WorkflowExecution) error {
+	return r.UpdateExecution(ctx, execution)
+}
+
+func (r *fakeRepository) UpdateExecution(ctx context.Context, execution *WorkflowExecution) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[execution.ID] = execution.Status
+	return nil
+}
+
+func (r *fakeRepository) status(executionID string) WorkflowStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statuses[executionID]
+}
+
+type fakeMonitor struct {
+	ExecutionMonitor
+	stopped []string
+	mu      sync.Mutex
+}
+
+func (m *fakeMonitor) StartMonitoring(ctx context.Context, execution *WorkflowExecution) error {
+	return nil
+}
+
+func (m *fakeMonitor) StopMonitoring(ctx context.Context, executionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = append(m.stopped, executionID)
+	return nil
+}
+
+func (m *fakeMonitor) RecordTaskTransition(ctx context.Context, executionID string, taskExecution *TaskExecution, previous TaskStatus) error {
+	return nil
+}
+
+func TestEngine_PauseMidBatch(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan string, 10)
+	release := map[string]chan struct{}{"fetch": make(chan struct{}), "enrich": make(chan struct{})}
+	var attempts sync.Map
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		taskID := task.Payload.(map[string]interface{})["task_id"].(string)
+		attempt, _ := attempts.LoadOrStore(taskID, new(int))
+		*attempt.(*int)++
+		started <- taskID
+		if ch, ok := release[taskID]; ok && *attempt.(*int) == 1 {
+			<-ch
+		}
+		// The first attempt of enrich fails and is retried
+		if taskID == "enrich" && *attempt.(*int) == 1 {
+			return &agent.TaskResult{Error: agent.ErrTaskTimeout}
+		}
+		return &agent.TaskResult{Success: true}
+	}
+	dispatched := func(taskID string) int {
+		coordinator.mu.Lock()
+		defer coordinator.mu.Unlock()
+		count := 0
+		for _, task := range coordinator.dispatched {
+			if task.Payload.(map[string]interface{})["task_id"] == taskID {
+				count++
+			}
+		}
+		return count
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	workflow := &Workflow{
+		ID: "wf-1",
+		Tasks: []WorkflowTask{
+			{ID: "fetch", Type: "http_request"},
+			{ID: "enrich", Type: "data_processing", RetryPolicy: RetryPolicy{MaxAttempts: 2}},
+			{ID: "report", Type: "data_processing"},
+		},
+		Dependencies: map[string][]string{"report": {"fetch", "enrich"}},
+	}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		<-started
+	}
+
+	// Pausing while the first batch runs lets running tasks finish, but holds
+	// back the retry of the failed task and the next batch
+	if err := engine.PauseExecution(ctx, execution.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(release["enrich"])
+	close(release["fetch"])
+	time.Sleep(100 * time.Millisecond)
+	if n := dispatched("enrich"); n != 1 {
+		t.Errorf("expected the retry to wait for resume, got %d attempts", n)
+	}
+	if n := dispatched("report"); n != 0 {
+		t.Errorf("expected the next batch to wait for resume, got %d dispatches", n)
+	}
+	if status := repository.status(execution.ID); status != WorkflowStatusPaused {
+		t.Errorf("expected the execution to be paused, got %s", status)
+	}
+
+	// Resuming continues where the execution left off
+	if err := engine.ResumeExecution(ctx, execution.ID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(execution.ID) != WorkflowStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", repository.status(execution.ID))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if dispatched("fetch") != 1 || dispatched("enrich") != 2 || dispatched("report") != 1 {
+		t.Errorf("unexpected dispatches: fetch %d, enrich %d, report %d", dispatched("fetch"), dispatched("enrich"), dispatched("report"))
+	}
+}
+
+func TestEngine_WorkflowTimeout(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+
+	// The task never completes
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult { return nil }
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	monitor := &fakeMonitor{}
+	engine := NewEngine(OrchestrationConfig{DefaultWorkflowTimeout: time.Hour}, coordinator, monitor, repository, logger)
+	engine.SetClock(clk)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The workflow's own timeout overrides the engine default
+	workflow := &Workflow{
+		ID:            "wf-1",
+		Tasks:         []WorkflowTask{{ID: "fetch", Type: "http_request"}},
+		Configuration: WorkflowConfiguration{Timeout: 30 * time.Second},
+	}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.Timeout != 30*time.Second {
+		t.Errorf("expected the workflow timeout, got %s", execution.Timeout)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(execution.ID) != WorkflowStatusTimedOut {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to time out, got %s", repository.status(execution.ID))
+		}
+		clk.Advance(10 * time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := engine.CancelExecution(ctx, execution.ID); err == nil {
+		t.Error("expected a timed-out execution to be inactive")
+	}
+
+	// Stopping waits for the execution's tasks to give up
+	if err := engine.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if execution.Status != WorkflowStatusTimedOut || !strings.Contains(execution.Error, "timed out") {
+		t.Errorf("unexpected execution status %s (%s)", execution.Status, execution.Error)
+	}
+	taskExecution := execution.TaskExecutions["fetch"]
+	if taskExecution.Status != TaskStatusFailed || !strings.Contains(taskExecution.Error, ErrExecutionTimedOut.Error()) {
+		t.Errorf("expected the running task to be stopped, got %s (%s)", taskExecution.Status, taskExecution.Error)
+	}
+	if len(engine.pendingTasks) != 0 {
+		t.Errorf("expected no pending tasks, got %d", len(engine.pendingTasks))
+	}
+	if len(monitor.stopped) != 1 || monitor.stopped[0] != execution.ID {
+		t.Errorf("expected monitoring to stop once, got %v", monitor.stopped)
+	}
+}
+
+func TestEngine_ListExecutions(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{}, nil, nil, repository, logger)
+
+	if _, err := engine.ListExecutions(ctx, ExecutionFilters{WorkflowID: "wf-1", Status: []WorkflowStatus{WorkflowStatusFailed}, AgentID: "analyst-1"}); err != nil {
+		t.Fatalf("expected valid filters, got %v", err)
+	}
+	if len(repository.listed) != 1 || repository.listed[0].Limit != DefaultExecutionListLimit || repository.listed[0].AgentID != "analyst-1" {
+		t.Errorf("expected the filters to reach the repository with the default limit, got %+v", repository.listed)
+	}
+
+	after := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	before := after.Add(-time.Hour)
+	for name, filters := range map[string]ExecutionFilters{
+		"negative offset": {Offset: -1},
+		"limit too large": {Limit: MaxExecutionListLimit + 1},
+		"inverted range":  {StartTimeAfter: &after, StartTimeBefore: &before},
+		"unknown status":  {Status: []WorkflowStatus{"finished"}},
+	} {
+		if _, err := engine.ListExecutions(ctx, filters); !errors.Is(err, ErrInvalidExecutionFilters) {
+			t.Errorf("%s: expected ErrInvalidExecutionFilters, got %v", name, err)
+		}
+	}
+	if len(repository.listed) != 1 {
+		t.Errorf("expected invalid filters not to reach the repository, got %d queries", len(repository.listed))
+	}
+}
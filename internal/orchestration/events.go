@@ -0,0 +1,191 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrExecutionNotMonitored is returned when watching an execution that is not running
+var ErrExecutionNotMonitored = errors.New("execution not monitored")
+
+// taskEventTypes maps the status a task moves to onto the event reporting it
+var taskEventTypes = map[TaskStatus]ExecutionEventType{
+	TaskStatusQueued:           EventTaskQueued,
+	TaskStatusRunning:          EventTaskStarted,
+	TaskStatusCompleted:        EventTaskCompleted,
+	TaskStatusFailed:           EventTaskFailed,
+	TaskStatusRetrying:         EventTaskRetried,
+	TaskStatusSkipped:          EventTaskSkipped,
+	TaskStatusCompensated:      EventTaskCompensated,
+	TaskStatusAwaitingApproval: EventTaskAwaitingApproval,
+}
+
+// eventSubscriber receives the events of one execution
+type eventSubscriber struct {
+	events chan *ExecutionEvent
+	done   chan struct{}
+}
+
+// WatchExecution subscribes to the events of a running execution. The channel
+// is closed after the execution stops, or when ctx is done.
+func (m *Monitor) WatchExecution(ctx context.Context, executionID string) (<-chan *ExecutionEvent, error) {
+	sub := &eventSubscriber{
+		events: make(chan *ExecutionEvent, max(m.config.EventBufferSize, 1)),
+		done:   make(chan struct{}),
+	}
+
+	// Registering under the subscriber lock means StopTracking closes the
+	// subscription if the execution stops right after this check
+	m.subscriberMutex.Lock()
+	m.execMutex.RLock()
+	_, tracked := m.executions[executionID]
+	m.execMutex.RUnlock()
+	if !tracked {
+		m.subscriberMutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotMonitored, executionID)
+	}
+	if m.subscribers[executionID] == nil {
+		m.subscribers[executionID] = make(map[*eventSubscriber]struct{})
+	}
+	m.subscribers[executionID][sub] = struct{}{}
+	m.subscriberMutex.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.unsubscribe(executionID, sub)
+		case <-sub.done:
+		}
+	}()
+
+	return sub.events, nil
+}
+
+// RecordTaskTransition emits the event of a task moving to a new status
+func (m *Monitor) RecordTaskTransition(ctx context.Context, executionID string, taskExecution *TaskExecution, previous TaskStatus) error {
+	eventType, known := taskEventTypes[taskExecution.Status]
+	if !known {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"status":          string(taskExecution.Status),
+		"previous_status": string(previous),
+		"attempts":        taskExecution.Attempts,
+	}
+	if taskExecution.Error != "" {
+		data["error"] = taskExecution.Error
+	}
+	if taskExecution.Duration > 0 {
+		data["duration"] = taskExecution.Duration.Seconds()
+	}
+
+	return m.emitEvent(ctx, &ExecutionEvent{
+		ExecutionID: executionID,
+		Type:        string(eventType),
+		TaskID:      taskExecution.TaskID,
+		AgentID:     taskExecution.AgentID,
+		Timestamp:   m.clock.Now(),
+		Data:        data,
+	})
+}
+
+// publish delivers an event to the watchers of its execution without
+// blocking; a watcher whose buffer is full misses the event
+func (m *Monitor) publish(event *ExecutionEvent) {
+	m.subscriberMutex.RLock()
+	defer m.subscriberMutex.RUnlock()
+
+	for sub := range m.subscribers[event.ExecutionID] {
+		select {
+		case sub.events <- event:
+		default:
+			m.logger.WithFields(log.Fields{
+				"execution_id": event.ExecutionID,
+				"event_type":   event.Type,
+			}).Warn("Execution watcher is lagging, dropped event")
+		}
+	}
+}
+
+func (m *Monitor) unsubscribe(executionID string, sub *eventSubscriber) {
+	m.subscriberMutex.Lock()
+	defer m.subscriberMutex.Unlock()
+
+	if _, exists := m.subscribers[executionID][sub]; !exists {
+		return
+	}
+	delete(m.subscribers[executionID], sub)
+	if len(m.subscribers[executionID]) == 0 {
+		delete(m.subscribers, executionID)
+	}
+	close(sub.events)
+	close(sub.done)
+}
+
+// closeSubscribers ends the subscriptions of a stopped execution
+func (m *Monitor) closeSubscribers(executionID string) {
+	m.subscriberMutex.Lock()
+	defer m.subscriberMutex.Unlock()
+
+	for sub := range m.subscribers[executionID] {
+		close(sub.events)
+		close(sub.done)
+	}
+	delete(m.subscribers, executionID)
+}
+
+// recordTaskTransitions reports the tasks whose status changed since the
+// execution was last updated
+func (e *Engine) recordTaskTransitions(ctx context.Context, execution *WorkflowExecution) {
+	type transition struct {
+		taskExecution *TaskExecution
+		previous      TaskStatus
+	}
+
+	e.statusMutex.Lock()
+	// Tasks stopped along with their execution are not reported
+	e.executionMutex.RLock()
+	_, active := e.activeExecutions[execution.ID]
+	e.executionMutex.RUnlock()
+	if !active {
+		e.statusMutex.Unlock()
+		return
+	}
+	reported, exists := e.reportedStatuses[execution.ID]
+	if !exists {
+		reported = make(map[string]TaskStatus, len(execution.TaskExecutions))
+		e.reportedStatuses[execution.ID] = reported
+	}
+	var transitions []transition
+	for taskID, taskExecution := range execution.TaskExecutions {
+		previous, seen := reported[taskID]
+		if !seen {
+			previous = TaskStatusPending
+		}
+		if taskExecution.Status != previous {
+			reported[taskID] = taskExecution.Status
+			snapshot := *taskExecution
+			transitions = append(transitions, transition{&snapshot, previous})
+		}
+	}
+	e.statusMutex.Unlock()
+
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].taskExecution.TaskID < transitions[j].taskExecution.TaskID
+	})
+	for _, t := range transitions {
+		if err := e.monitor.RecordTaskTransition(ctx, execution.ID, t.taskExecution, t.previous); err != nil {
+			e.logger.WithError(err).WithField("task_id", t.taskExecution.TaskID).Warn("Failed to record task transition")
+		}
+	}
+}
+
+// WatchExecution streams the task transitions and progress of a running execution
+func (e *Engine) WatchExecution(ctx context.Context, executionID string) (<-chan *ExecutionEvent, error) {
+	return e.monitor.WatchExecution(ctx, executionID)
+}
@@ -0,0 +1,112 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_WatchExecution(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		if task.Payload.(map[string]interface{})["task_id"] == "fetch" {
+			close(started)
+			<-release
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	monitor := NewMonitor(DefaultMonitorConfig(), logger)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{}, coordinator, monitor, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	if _, err := engine.WatchExecution(ctx, "missing"); !errors.Is(err, ErrExecutionNotMonitored) {
+		t.Errorf("expected ErrExecutionNotMonitored, got %v", err)
+	}
+
+	workflow := &Workflow{
+		ID: "wf-1",
+		Tasks: []WorkflowTask{
+			{ID: "fetch", Type: "http_request"},
+			{ID: "report", Type: "data_processing"},
+		},
+		Dependencies: map[string][]string{"report": {"fetch"}},
+	}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// A watcher that goes away is unsubscribed
+	watchCtx, cancel := context.WithCancel(ctx)
+	abandoned, err := engine.WatchExecution(watchCtx, execution.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	events, err := engine.WatchExecution(ctx, execution.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		select {
+		case event, open := <-events:
+			if !open {
+				done = true
+				break
+			}
+			got = append(got, event.Type+" "+event.TaskID)
+		case <-timeout:
+			t.Fatalf("expected the stream to end with the execution, got %v", got)
+		}
+	}
+
+	want := []string{
+		"task_completed fetch",
+		"task_queued report",
+		"task_started report",
+		"task_completed report",
+		"execution_completed ",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		for range abandoned {
+		}
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Error("expected the abandoned watcher to be closed")
+	}
+}
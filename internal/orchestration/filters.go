@@ -0,0 +1,40 @@
+package orchestration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Execution listing limits
+const (
+	DefaultExecutionListLimit = 50
+	MaxExecutionListLimit     = 500
+)
+
+// ErrInvalidExecutionFilters is returned for execution listings with invalid filters
+var ErrInvalidExecutionFilters = errors.New("invalid execution filters")
+
+// validate applies the default limit and rejects invalid filters
+func (f *ExecutionFilters) validate() error {
+	if f.Limit < 0 || f.Offset < 0 {
+		return fmt.Errorf("%w: limit and offset must not be negative", ErrInvalidExecutionFilters)
+	}
+	if f.Limit == 0 {
+		f.Limit = DefaultExecutionListLimit
+	}
+	if f.Limit > MaxExecutionListLimit {
+		return fmt.Errorf("%w: limit must be at most %d", ErrInvalidExecutionFilters, MaxExecutionListLimit)
+	}
+	if f.StartTimeAfter != nil && f.StartTimeBefore != nil && f.StartTimeBefore.Before(*f.StartTimeAfter) {
+		return fmt.Errorf("%w: started_before must not be before started_after", ErrInvalidExecutionFilters)
+	}
+	for _, status := range f.Status {
+		switch status {
+		case WorkflowStatusPending, WorkflowStatusRunning, WorkflowStatusCompleted, WorkflowStatusFailed,
+			WorkflowStatusCancelled, WorkflowStatusPaused, WorkflowStatusTimedOut, WorkflowStatusCompensating:
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidExecutionFilters, status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+// FixtureFormatVersion identifies the layout of exported execution fixtures
+const FixtureFormatVersion = "2"
+
+// ReplayAgentID is the agent replayed tasks are dispatched to
+const ReplayAgentID = "replay"
+
+// ErrExecutionNotFinished is returned when exporting an execution that is still running
+var ErrExecutionNotFinished = errors.New("execution has not finished")
+
+// errNoRecordedOutcome fails replayed tasks the fixture has no outcome for
+var errNoRecordedOutcome = errors.New("no recorded outcome for task")
+
+// ExecutionFixture is a self-contained snapshot of a finished execution that
+// can be replayed on the engine without contacting any agents
+type ExecutionFixture struct {
+	FormatVersion string    `json:"format_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+
+	// Workflow is the version of the workflow the execution ran
+	Workflow Workflow `json:"workflow"`
+
+	ExecutionID string                 `json:"execution_id"`
+	Status      WorkflowStatus         `json:"status"`
+	TriggeredBy string                 `json:"triggered_by"`
+	StartTime   time.Time              `json:"start_time"`
+	EndTime     *time.Time             `json:"end_time,omitempty"`
+	Context     map[string]interface{} `json:"context"`
+	Error       string                 `json:"error,omitempty"`
+
+	// Tasks are the recorded task executions in the order they started,
+	// followed by the tasks that never started
+	Tasks []TaskRecord `json:"tasks"`
+}
+
+// TaskRecord captures the recorded outcome and timing of one task
+type TaskRecord struct {
+	TaskID     string                 `json:"task_id"`
+	AgentID    string                 `json:"agent_id,omitempty"`
+	Status     TaskStatus             `json:"status"`
+	Attempts   int                    `json:"attempts"`
+	StartTime  time.Time              `json:"start_time"`
+	EndTime    *time.Time             `json:"end_time,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+	Output     map[string]interface{} `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Approval   *TaskApproval          `json:"approval,omitempty"`
+}
+
+// ReplayedTask is the outcome of one task during a replay
+type ReplayedTask struct {
+	TaskID string                 `json:"task_id"`
+	Status TaskStatus             `json:"status"`
+	Output map[string]interface{} `json:"output,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// ReplayDivergence describes a difference between the replay and the recording
+type ReplayDivergence struct {
+	TaskID   string `json:"task_id,omitempty"`
+	Kind     string `json:"kind"` // status_mismatch, output_mismatch, missing_output
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// ReplayResult is the result of replaying a fixture
+type ReplayResult struct {
+	ExecutionID     string             `json:"execution_id"`
+	WorkflowID      string             `json:"workflow_id"`
+	WorkflowVersion string             `json:"workflow_version"`
+	Status          WorkflowStatus     `json:"status"`
+	Tasks           []ReplayedTask     `json:"tasks"`
+	Divergences     []ReplayDivergence `json:"divergences"`
+	Reproduced      bool               `json:"reproduced"`
+}
+
+// ExportFixture exports a finished execution together with the version of the
+// workflow it ran
+func (e *Engine) ExportFixture(ctx context.Context, executionID string) (*ExecutionFixture, error) {
+	execution, err := e.repository.GetExecution(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution: %w", err)
+	}
+	if !isFinished(execution.Status) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrExecutionNotFinished, executionID, execution.Status)
+	}
+
+	workflow, err := e.workflowForExecution(ctx, execution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	fixture := &ExecutionFixture{
+		FormatVersion: FixtureFormatVersion,
+		ExportedAt:    e.clock.Now().UTC(),
+		Workflow:      *workflow,
+		ExecutionID:   execution.ID,
+		Status:        execution.Status,
+		TriggeredBy:   execution.TriggeredBy,
+		StartTime:     execution.StartTime,
+		EndTime:       execution.EndTime,
+		Context:       execution.Context,
+		Error:         execution.Error,
+		Tasks:         make([]TaskRecord, 0, len(execution.TaskExecutions)),
+	}
+	for _, taskExecution := range execution.TaskExecutions {
+		fixture.Tasks = append(fixture.Tasks, TaskRecord{
+			TaskID:     taskExecution.TaskID,
+			AgentID:    taskExecution.AgentID,
+			Status:     taskExecution.Status,
+			Attempts:   taskExecution.Attempts,
+			StartTime:  taskExecution.StartTime,
+			EndTime:    taskExecution.EndTime,
+			DurationMs: taskExecution.Duration.Milliseconds(),
+			Output:     taskExecution.Output,
+			Error:      taskExecution.Error,
+			Approval:   taskExecution.Approval,
+		})
+	}
+	sort.SliceStable(fixture.Tasks, func(i, j int) bool {
+		a, b := fixture.Tasks[i].StartTime, fixture.Tasks[j].StartTime
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero() // Tasks that never started go last
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return fixture.Tasks[i].TaskID < fixture.Tasks[j].TaskID
+	})
+
+	return fixture, nil
+}
+
+// ReplayFixture runs the fixture's workflow on an engine of its own whose
+// agents answer with the recorded task outcomes, and approval tasks with the
+// recorded decisions. Branches, loops, dependencies and retries are decided
+// by the engine as in a live execution; retries are replayed without their
+// delays. Nothing is stored, so fixtures from other environments can be
+// replayed.
+func ReplayFixture(ctx context.Context, fixture *ExecutionFixture, logger *log.Logger) (*ReplayResult, error) {
+	if fixture == nil || fixture.Workflow.ID == "" || len(fixture.Workflow.Tasks) == 0 {
+		return nil, fmt.Errorf("fixture has no workflow definition")
+	}
+
+	workflow := fixture.Workflow
+	workflow.Tasks = make([]WorkflowTask, len(fixture.Workflow.Tasks))
+	for i, task := range fixture.Workflow.Tasks {
+		task.RetryPolicy.InitialDelay = 0
+		task.RetryPolicy.MaxDelay = 0
+		workflow.Tasks[i] = task
+	}
+
+	recorded := make(map[string]TaskRecord, len(fixture.Tasks))
+	for _, record := range fixture.Tasks {
+		recorded[record.TaskID] = record
+	}
+
+	coordinator := newReplayCoordinator(&workflow, recorded)
+	repository := newReplayRepository()
+	engine := NewEngine(OrchestrationConfig{}, coordinator, NewMonitor(DefaultMonitorConfig(), logger), repository, logger)
+	repository.onAwaitingApproval = func(executionID, taskID string) {
+		decision := TaskApproval{Approver: ReplayAgentID, Comment: "no recorded decision"}
+		if record, exists := recorded[taskID]; exists && record.Approval != nil {
+			decision = *record.Approval
+		} else {
+			coordinator.markMissing(taskID)
+		}
+		if err := engine.ApproveTask(context.Background(), executionID, taskID, decision); err != nil {
+			logger.WithError(err).WithField("task_id", taskID).Warn("Failed to replay approval decision")
+		}
+	}
+	if err := engine.Start(); err != nil {
+		return nil, err
+	}
+	defer engine.Stop()
+
+	execution, err := engine.StartExecution(ctx, &workflow, fixture.TriggeredBy, fixture.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start replay: %w", err)
+	}
+
+	var replayed *WorkflowExecution
+	select {
+	case replayed = <-repository.finished:
+	case <-ctx.Done():
+		engine.CancelExecution(context.Background(), execution.ID)
+		return nil, ctx.Err()
+	}
+
+	result := &ReplayResult{
+		ExecutionID:     fixture.ExecutionID,
+		WorkflowID:      workflow.ID,
+		WorkflowVersion: workflow.Version,
+		Status:          replayed.Status,
+		Tasks:           make([]ReplayedTask, 0, len(workflow.Tasks)),
+		Divergences:     []ReplayDivergence{},
+	}
+	missing := coordinator.missingTasks()
+	for _, task := range workflow.Tasks {
+		taskExecution := replayed.TaskExecutions[task.ID]
+		result.Tasks = append(result.Tasks, ReplayedTask{
+			TaskID: task.ID,
+			Status: taskExecution.Status,
+			Output: replayedOutput(taskExecution.Output),
+			Error:  taskExecution.Error,
+		})
+
+		record, wasRecorded := recorded[task.ID]
+		switch {
+		case missing[task.ID]:
+			result.Divergences = append(result.Divergences, ReplayDivergence{TaskID: task.ID, Kind: "missing_output"})
+		case !wasRecorded:
+		case record.Status != taskExecution.Status:
+			result.Divergences = append(result.Divergences, ReplayDivergence{
+				TaskID: task.ID, Kind: "status_mismatch", Expected: string(record.Status), Actual: string(taskExecution.Status),
+			})
+		case record.Status == TaskStatusCompleted:
+			expected, actual := outputJSON(replayedOutput(record.Output)), outputJSON(replayedOutput(taskExecution.Output))
+			if expected != actual {
+				result.Divergences = append(result.Divergences, ReplayDivergence{
+					TaskID: task.ID, Kind: "output_mismatch", Expected: expected, Actual: actual,
+				})
+			}
+		}
+	}
+	if result.Status != fixture.Status {
+		result.Divergences = append(result.Divergences, ReplayDivergence{
+			Kind: "status_mismatch", Expected: string(fixture.Status), Actual: string(result.Status),
+		})
+	}
+	result.Reproduced = len(result.Divergences) == 0
+
+	logger.WithFields(log.Fields{
+		"execution_id": fixture.ExecutionID,
+		"tasks":        len(result.Tasks),
+		"divergences":  len(result.Divergences),
+	}).Info("Replayed execution fixture")
+
+	return result, nil
+}
+
+// isFinished reports whether an execution has reached a final status
+func isFinished(status WorkflowStatus) bool {
+	switch status {
+	case WorkflowStatusCompleted, WorkflowStatusFailed, WorkflowStatusCancelled, WorkflowStatusTimedOut:
+		return true
+	}
+	return false
+}
+
+// replayedOutput leaves out the completion time the engine adds to outputs,
+// which differs between a recording and its replay
+func replayedOutput(output map[string]interface{}) map[string]interface{} {
+	compared := make(map[string]interface{}, len(output))
+	for k, v := range output {
+		if k != "completed_at" {
+			compared[k] = v
+		}
+	}
+	return compared
+}
+
+// outputJSON renders an output for comparison, with its keys sorted
+func outputJSON(output map[string]interface{}) string {
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Sprint(output)
+	}
+	return string(encoded)
+}
+
+// replayCoordinator stands in for the agents of a replayed execution,
+// answering each task with its recorded outcome
+type replayCoordinator struct {
+	tasks    map[string]*WorkflowTask
+	recorded map[string]TaskRecord
+	handlers []func(*agent.TaskResult)
+
+	mu      sync.Mutex
+	missing map[string]bool
+}
+
+func newReplayCoordinator(workflow *Workflow, recorded map[string]TaskRecord) *replayCoordinator {
+	c := &replayCoordinator{
+		tasks:    make(map[string]*WorkflowTask, len(workflow.Tasks)),
+		recorded: recorded,
+		missing:  make(map[string]bool),
+	}
+	for i := range workflow.Tasks {
+		c.tasks[workflow.Tasks[i].ID] = &workflow.Tasks[i]
+	}
+	return c
+}
+
+func (c *replayCoordinator) SelectAgents(ctx context.Context, selector AgentSelector, count int) ([]*agent.Agent, error) {
+	return []*agent.Agent{{ID: ReplayAgentID}}, nil
+}
+
+func (c *replayCoordinator) AssignTask(ctx context.Context, agentID string, task *WorkflowTask, execution *WorkflowExecution) error {
+	return nil
+}
+
+// DispatchTask answers the task with its recorded outcome. Recorded outputs
+// are mapped back to the agent's keys so the task's output mapping gives
+// them their recorded names again.
+func (c *replayCoordinator) DispatchTask(ctx context.Context, agentID string, task agent.Task) error {
+	payload, _ := task.Payload.(map[string]interface{})
+	taskID, _ := payload["task_id"].(string)
+
+	result := &agent.TaskResult{TaskID: task.ID, AgentID: agentID, CompletedAt: time.Now()}
+	record, exists := c.recorded[taskID]
+	switch {
+	case !exists || (record.Status != TaskStatusCompleted && record.Status != TaskStatusFailed):
+		c.markMissing(taskID)
+		result.Error = errNoRecordedOutcome
+	case record.Status == TaskStatusFailed:
+		result.Error = errors.New(record.Error)
+	default:
+		result.Success = true
+		output := replayedOutput(record.Output)
+		if workflowTask := c.tasks[taskID]; workflowTask != nil && len(workflowTask.OutputMapping) > 0 {
+			mapped := make(map[string]interface{}, len(output))
+			for name, source := range workflowTask.OutputMapping {
+				if value, ok := output[name]; ok {
+					mapped[source] = value
+				}
+			}
+			output = mapped
+		}
+		result.Result = output
+	}
+	if exists && record.AgentID != "" {
+		result.AgentID = record.AgentID
+	}
+
+	go func() {
+		for _, handler := range c.handlers {
+			handler(result)
+		}
+	}()
+	return nil
+}
+
+func (c *replayCoordinator) OnTaskCompleted(handler func(*agent.TaskResult)) {
+	c.handlers = append(c.handlers, handler)
+}
+
+func (c *replayCoordinator) GetAgentLoad(ctx context.Context, agentID string) (*AgentLoad, error) {
+	return &AgentLoad{AgentID: agentID, HealthScore: 1, LastUpdated: time.Now()}, nil
+}
+
+func (c *replayCoordinator) GetAvailableAgents(ctx context.Context) ([]*agent.Agent, error) {
+	return []*agent.Agent{{ID: ReplayAgentID}}, nil
+}
+
+func (c *replayCoordinator) RebalanceLoad(ctx context.Context) error {
+	return nil
+}
+
+func (c *replayCoordinator) markMissing(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.missing[taskID] = true
+}
+
+func (c *replayCoordinator) missingTasks() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	missing := make(map[string]bool, len(c.missing))
+	for id := range c.missing {
+		missing[id] = true
+	}
+	return missing
+}
+
+// replayRepository keeps a replayed execution in memory. It reports tasks
+// awaiting approval and hands over a copy of the execution once it finishes.
+type replayRepository struct {
+	WorkflowRepository
+
+	onAwaitingApproval func(executionID, taskID string)
+	finished           chan *WorkflowExecution
+
+	mu         sync.Mutex
+	execution  *WorkflowExecution
+	approvals  map[string]bool
+	isFinished bool
+}
+
+func newReplayRepository() *replayRepository {
+	return &replayRepository{
+		finished:  make(chan *WorkflowExecution, 1),
+		approvals: make(map[string]bool),
+	}
+}
+
+func (r *replayRepository) StoreExecution(ctx context.Context, execution *WorkflowExecution) error {
+	return r.UpdateExecution(ctx, execution)
+}
+
+// UpdateExecution records a copy of the execution. The engine calls it
+// holding the execution's state lock.
+func (r *replayRepository) UpdateExecution(ctx context.Context, execution *WorkflowExecution) error {
+	stored := *execution
+	stored.TaskExecutions = make(map[string]*TaskExecution, len(execution.TaskExecutions))
+	for id, taskExecution := range execution.TaskExecutions {
+		taskCopy := *taskExecution
+		taskCopy.Output = make(map[string]interface{}, len(taskExecution.Output))
+		for k, v := range taskExecution.Output {
+			taskCopy.Output[k] = v
+		}
+		stored.TaskExecutions[id] = &taskCopy
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.execution = &stored
+
+	for id, taskExecution := range stored.TaskExecutions {
+		if taskExecution.Status == TaskStatusAwaitingApproval && !r.approvals[id] {
+			r.approvals[id] = true
+			go r.onAwaitingApproval(stored.ID, id)
+		}
+	}
+	if isFinished(stored.Status) && !r.isFinished {
+		r.isFinished = true
+		r.finished <- &stored
+	}
+	return nil
+}
+
+func (r *replayRepository) GetExecution(ctx context.Context, executionID string) (*WorkflowExecution, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.execution == nil || r.execution.ID != executionID {
+		return nil, fmt.Errorf("execution %s not found", executionID)
+	}
+	return r.execution, nil
+}
@@ -0,0 +1,217 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+// leakResponse inspects a main, signs off and repairs it when a leak is
+// probable, or reports when not
+func leakResponse() *Workflow {
+	return &Workflow{
+		ID:      "leak-response",
+		Version: "1.2",
+		Tasks: []WorkflowTask{
+			{ID: "inspect", Type: "inspection", OutputMapping: map[string]string{"leak": "leak_probability"}},
+			{ID: "decide", Branch: &TaskBranch{
+				Condition: "${tasks.inspect.output.leak} > 0.7",
+				Then:      []string{"sign_off"},
+				Else:      []string{"report"},
+			}},
+			{ID: "sign_off", Type: TaskTypeManualApproval},
+			{ID: "repair", Type: "repair"},
+			{ID: "report", Type: "report"},
+		},
+		Dependencies: map[string][]string{
+			"decide":   {"inspect"},
+			"sign_off": {"decide"},
+			"repair":   {"sign_off"},
+			"report":   {"decide"},
+		},
+	}
+}
+
+// recordExecution runs the leak response on an engine and exports it
+func recordExecution(t *testing.T, leakProbability float64) *ExecutionFixture {
+	t.Helper()
+	ctx := context.Background()
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "crew-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		switch task.Payload.(map[string]interface{})["task_id"] {
+		case "inspect":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"leak_probability": leakProbability, "debug": "dropped"}}
+		case "repair":
+			return &agent.TaskResult{Success: true, Result: map[string]interface{}{"patched": true}}
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := newDesignRepository()
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	workflow := leakResponse()
+	if err := repository.StoreWorkflow(ctx, workflow); err != nil {
+		t.Fatal(err)
+	}
+	execution, err := engine.StartExecution(ctx, workflow, "sensor", map[string]interface{}{"main_id": "m-12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(execution.ID) != WorkflowStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", repository.status(execution.ID))
+		}
+		if leakProbability > 0.7 {
+			// Approval is only pending once the task awaits it
+			_ = engine.ApproveTask(ctx, execution.ID, "sign_off", TaskApproval{Approved: true, Approver: "operator-7"})
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// The test repository shares the execution with the engine until it stops
+	engine.Stop()
+
+	if _, err := engine.ExportFixture(ctx, "missing"); err == nil {
+		t.Error("expected exporting an unknown execution to fail")
+	}
+	fixture, err := engine.ExportFixture(ctx, execution.ID)
+	if err != nil {
+		t.Fatalf("ExportFixture failed: %v", err)
+	}
+
+	// Fixtures are replayed from their JSON form
+	encoded, err := json.Marshal(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ExecutionFixture
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return &decoded
+}
+
+func TestEngine_ExportFixture(t *testing.T) {
+	fixture := recordExecution(t, 0.9)
+	if fixture.FormatVersion != FixtureFormatVersion || fixture.Workflow.Version != "1.2" || fixture.TriggeredBy != "sensor" {
+		t.Errorf("unexpected fixture %+v", fixture)
+	}
+	if fixture.Status != WorkflowStatusCompleted || fixture.Context["main_id"] != "m-12" {
+		t.Errorf("expected the execution's status and context, got %s %v", fixture.Status, fixture.Context)
+	}
+	if len(fixture.Tasks) != 5 || fixture.Tasks[0].TaskID != "inspect" {
+		t.Fatalf("expected the tasks in the order they started, got %+v", fixture.Tasks)
+	}
+	for _, record := range fixture.Tasks {
+		switch record.TaskID {
+		case "inspect":
+			if record.Output["leak"] != 0.9 || record.AgentID != "crew-1" {
+				t.Errorf("expected the mapped agent output, got %+v", record)
+			}
+		case "sign_off":
+			if record.Approval == nil || record.Approval.Approver != "operator-7" {
+				t.Errorf("expected the approval decision, got %+v", record.Approval)
+			}
+		case "report":
+			if record.Status != TaskStatusSkipped {
+				t.Errorf("expected report to be skipped, got %s", record.Status)
+			}
+		}
+	}
+
+	// Running executions cannot be exported
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := newDesignRepository()
+	repository.executions["exec-1"] = &WorkflowExecution{ID: "exec-1", WorkflowID: "leak-response", Status: WorkflowStatusRunning}
+	engine := NewEngine(OrchestrationConfig{}, nil, &fakeMonitor{}, repository, logger)
+	if _, err := engine.ExportFixture(context.Background(), "exec-1"); !errors.Is(err, ErrExecutionNotFinished) {
+		t.Errorf("expected ErrExecutionNotFinished, got %v", err)
+	}
+}
+
+func TestReplayFixture(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for name, leakProbability := range map[string]float64{"repair": 0.9, "report": 0.2} {
+		fixture := recordExecution(t, leakProbability)
+		result, err := ReplayFixture(ctx, fixture, logger)
+		if err != nil {
+			t.Fatalf("%s: ReplayFixture failed: %v", name, err)
+		}
+		if !result.Reproduced || result.Status != WorkflowStatusCompleted {
+			t.Errorf("%s: expected the replay to reproduce the execution, got %+v", name, result.Divergences)
+		}
+		if len(result.Tasks) != 5 || result.ExecutionID != fixture.ExecutionID {
+			t.Errorf("%s: unexpected result %+v", name, result)
+		}
+	}
+
+	// The engine takes the other branch when a recorded output changes
+	fixture := recordExecution(t, 0.9)
+	for i := range fixture.Tasks {
+		if fixture.Tasks[i].TaskID == "inspect" {
+			fixture.Tasks[i].Output["leak"] = 0.1
+		}
+	}
+	result, err := ReplayFixture(ctx, fixture, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Reproduced {
+		t.Fatal("expected the replay to diverge")
+	}
+	diverged := make(map[string]ReplayDivergence)
+	for _, divergence := range result.Divergences {
+		diverged[divergence.TaskID] = divergence
+	}
+	if d := diverged["repair"]; d.Kind != "status_mismatch" || d.Expected != string(TaskStatusCompleted) {
+		t.Errorf("expected repair not to run on replay, got %+v", d)
+	}
+	if d := diverged["report"]; d.Kind != "missing_output" {
+		t.Errorf("expected report to have no recorded outcome, got %+v", d)
+	}
+
+	// Failed tasks fail again with their recorded error
+	fixture = recordExecution(t, 0.2)
+	for i := range fixture.Tasks {
+		if fixture.Tasks[i].TaskID == "report" {
+			fixture.Tasks[i].Status = TaskStatusFailed
+			fixture.Tasks[i].Error = "printer offline"
+		}
+	}
+	fixture.Status = WorkflowStatusFailed
+	result, err = ReplayFixture(ctx, fixture, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != WorkflowStatusFailed {
+		t.Errorf("expected the replay to fail, got %s %+v", result.Status, result.Divergences)
+	}
+	for _, task := range result.Tasks {
+		if task.TaskID == "report" && !strings.HasSuffix(task.Error, "printer offline") {
+			t.Errorf("expected the recorded error, got %q", task.Error)
+		}
+	}
+
+	if _, err := ReplayFixture(ctx, &ExecutionFixture{}, logger); err == nil {
+		t.Error("expected a fixture without a workflow to be rejected")
+	}
+}
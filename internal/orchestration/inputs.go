@@ -0,0 +1,132 @@
+package orchestration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// inputExpression matches ${...} references in task input mappings, e.g.
+// ${tasks.analyze.output.leak_probability} or ${context.site_id}
+var inputExpression = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// resolveInputs evaluates a task's input mapping against the execution
+// context and the outputs of tasks that already ran. A value that is a single
+// expression resolves to the referenced value as is; expressions embedded in
+// text are interpolated.
+func resolveInputs(mapping map[string]string, execution *WorkflowExecution) (map[string]interface{}, error) {
+	inputs := make(map[string]interface{}, len(mapping))
+	for name, value := range mapping {
+		resolved, err := resolveInput(value, execution)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %w", name, err)
+		}
+		inputs[name] = resolved
+	}
+	return inputs, nil
+}
+
+func resolveInput(value string, execution *WorkflowExecution) (interface{}, error) {
+	if match := inputExpression.FindStringSubmatchIndex(value); match != nil && match[0] == 0 && match[1] == len(value) {
+		return lookupExpression(value[match[2]:match[3]], execution)
+	}
+
+	var lookupErr error
+	resolved := inputExpression.ReplaceAllStringFunc(value, func(expr string) string {
+		v, err := lookupExpression(inputExpression.FindStringSubmatch(expr)[1], execution)
+		if err != nil && lookupErr == nil {
+			lookupErr = err
+		}
+		return fmt.Sprint(v)
+	})
+	if lookupErr != nil {
+		return nil, lookupErr
+	}
+	return resolved, nil
+}
+
+// lookupExpression returns the value a path such as tasks.<id>.output.<key>,
+// tasks.<id>.status or context.<key> refers to
+func lookupExpression(expr string, execution *WorkflowExecution) (interface{}, error) {
+	path := strings.Split(strings.TrimSpace(expr), ".")
+
+	switch {
+	case path[0] == "context" && len(path) > 1:
+		return lookupPath(execution.Context, path[1:], expr)
+
+	case path[0] == "tasks" && len(path) > 2:
+		taskExecution, exists := execution.TaskExecutions[path[1]]
+		if !exists {
+			return nil, fmt.Errorf("${%s}: unknown task %s", expr, path[1])
+		}
+		switch {
+		case path[2] == "status" && len(path) == 3:
+			return string(taskExecution.Status), nil
+		case path[2] == "output" && len(path) > 3:
+			if taskExecution.Status != TaskStatusCompleted {
+				return nil, fmt.Errorf("${%s}: task %s has not completed", expr, path[1])
+			}
+			return lookupPath(taskExecution.Output, path[3:], expr)
+		}
+	}
+
+	return nil, fmt.Errorf("${%s}: invalid expression", expr)
+}
+
+func lookupPath(values map[string]interface{}, path []string, expr string) (interface{}, error) {
+	var value interface{} = values
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("${%s}: %s is not an object", expr, key)
+		}
+		if value, ok = m[key]; !ok {
+			return nil, fmt.Errorf("${%s}: %s not found", expr, key)
+		}
+	}
+	return value, nil
+}
+
+// validateInputMapping checks that the expressions of a task's input mapping
+// are well formed and refer to tasks of the workflow
+func validateInputMapping(task WorkflowTask, taskIDs map[string]bool) error {
+	for name, value := range task.InputMapping {
+		if err := validateReferences(value, task.ID, taskIDs, false); err != nil {
+			return fmt.Errorf("task %s input %s %w", task.ID, name, err)
+		}
+	}
+	return nil
+}
+
+// validateReferences checks the ${...} references of a value. allowSelf
+// permits references to the task's own output, as in loop conditions.
+func validateReferences(value, taskID string, taskIDs map[string]bool, allowSelf bool) error {
+	for _, match := range inputExpression.FindAllStringSubmatch(value, -1) {
+		path := strings.Split(strings.TrimSpace(match[1]), ".")
+		switch {
+		case path[0] == "context" && len(path) > 1:
+		case path[0] == "tasks" && (len(path) == 3 && path[2] == "status" || len(path) > 3 && path[2] == "output"):
+			if !taskIDs[path[1]] {
+				return fmt.Errorf("references unknown task: %s", path[1])
+			}
+			if path[1] == taskID && !allowSelf {
+				return fmt.Errorf("references its own output")
+			}
+		default:
+			return fmt.Errorf("has invalid expression: ${%s}", match[1])
+		}
+	}
+	return nil
+}
+
+// referencedTasks returns the other tasks whose status or output a value refers to
+func referencedTasks(value, taskID string) []string {
+	var tasks []string
+	for _, match := range inputExpression.FindAllStringSubmatch(value, -1) {
+		path := strings.Split(strings.TrimSpace(match[1]), ".")
+		if path[0] == "tasks" && len(path) > 2 && path[1] != taskID {
+			tasks = append(tasks, path[1])
+		}
+	}
+	return tasks
+}
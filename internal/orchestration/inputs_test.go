@@ -0,0 +1,73 @@
+package orchestration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveInputs(t *testing.T) {
+	execution := &WorkflowExecution{
+		Context: map[string]interface{}{"site": map[string]interface{}{"id": "north-7"}},
+		TaskExecutions: map[string]*TaskExecution{
+			"analyze": {TaskID: "analyze", Status: TaskStatusCompleted, Output: map[string]interface{}{
+				"leak_probability": 0.83,
+				"segments":         []interface{}{"A4", "A5"},
+			}},
+			"inspect": {TaskID: "inspect", Status: TaskStatusRunning, Output: map[string]interface{}{}},
+		},
+	}
+
+	inputs, err := resolveInputs(map[string]string{
+		"probability": "${tasks.analyze.output.leak_probability}",
+		"segments":    "${ tasks.analyze.output.segments }",
+		"summary":     "Leak risk ${tasks.analyze.output.leak_probability} at ${context.site.id}",
+		"status":      "${tasks.analyze.status}",
+		"literal":     "no expressions",
+	}, execution)
+	if err != nil {
+		t.Fatalf("Failed to resolve inputs: %v", err)
+	}
+	if inputs["probability"] != 0.83 {
+		t.Errorf("Expected a single expression to keep its type, got %#v", inputs["probability"])
+	}
+	if segments, ok := inputs["segments"].([]interface{}); !ok || len(segments) != 2 {
+		t.Errorf("Expected the segments list, got %#v", inputs["segments"])
+	}
+	if inputs["summary"] != "Leak risk 0.83 at north-7" {
+		t.Errorf("Unexpected interpolated input %q", inputs["summary"])
+	}
+	if inputs["status"] != "completed" || inputs["literal"] != "no expressions" {
+		t.Errorf("Unexpected inputs %v", inputs)
+	}
+
+	for expr, want := range map[string]string{
+		"${tasks.inspect.output.result}":         "has not completed",
+		"${tasks.analyze.output.missing}":        "missing not found",
+		"${tasks.analyze.output.segments.first}": "is not an object",
+		"${tasks.unknown.output.x}":              "unknown task",
+		"${agents.pump}":                         "invalid expression",
+	} {
+		if _, err := resolveInputs(map[string]string{"in": expr}, execution); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %s to fail with %q, got %v", expr, want, err)
+		}
+	}
+}
+
+func TestValidateInputMapping(t *testing.T) {
+	taskIDs := map[string]bool{"analyze": true, "report": true}
+
+	valid := WorkflowTask{ID: "report", InputMapping: map[string]string{
+		"probability": "${tasks.analyze.output.leak_probability}",
+		"site":        "Site ${context.site_id}",
+	}}
+	if err := validateInputMapping(valid, taskIDs); err != nil {
+		t.Errorf("Expected a valid mapping, got %v", err)
+	}
+
+	for _, expr := range []string{"${tasks.fetch.output.x}", "${tasks.report.output.x}", "${tasks.analyze.output}", "${}"} {
+		task := WorkflowTask{ID: "report", InputMapping: map[string]string{"in": expr}}
+		if err := validateInputMapping(task, taskIDs); err == nil {
+			t.Errorf("Expected %s to be rejected", expr)
+		}
+	}
+}
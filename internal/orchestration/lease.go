@@ -0,0 +1,308 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// Execution lease defaults
+const (
+	DefaultLeaseTTL = 30 * time.Second
+
+	// CollectionExecutionLeases is the execution lease collection name
+	CollectionExecutionLeases = "workflow_execution_leases"
+)
+
+// ErrLeaseLost is the cause of an execution's context when another engine
+// instance took over its lease
+var ErrLeaseLost = errors.New("execution lease lost")
+
+// ExecutionLease records which engine instance owns an execution. The owner
+// renews the lease while the execution runs; once it expires another instance
+// may take the execution over.
+type ExecutionLease struct {
+	// Key is the ArangoDB document key (the execution ID)
+	Key string `json:"_key,omitempty"`
+
+	ExecutionID string    `json:"execution_id"`
+	Owner       string    `json:"owner"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+}
+
+// LeaseStore persists execution leases shared by engine instances
+type LeaseStore interface {
+	// Acquire claims an execution for the owner until expiresAt. It succeeds if
+	// the execution has no lease, the owner already holds it, or it expired.
+	Acquire(ctx context.Context, executionID, owner string, now, expiresAt time.Time) (bool, error)
+
+	// Renew extends a lease the owner holds, reporting false if it lost it
+	Renew(ctx context.Context, executionID, owner string, expiresAt time.Time) (bool, error)
+
+	// Release gives up a lease the owner holds
+	Release(ctx context.Context, executionID, owner string) error
+
+	// Expired returns up to limit executions whose lease expired by now
+	Expired(ctx context.Context, now time.Time, limit int) ([]string, error)
+}
+
+// leaseTime truncates lease times to whole seconds so that their RFC 3339
+// strings sort chronologically in queries
+func leaseTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// ArangoLeaseStore persists execution leases in ArangoDB
+type ArangoLeaseStore struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoLeaseStore creates an ArangoDB-backed lease store
+func NewArangoLeaseStore(ctx context.Context, db driver.Database) (*ArangoLeaseStore, error) {
+	exists, err := db.CollectionExists(ctx, CollectionExecutionLeases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionExecutionLeases)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionExecutionLeases, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionExecutionLeases).Info("Created new collection")
+	}
+
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"expires_at"}, &driver.EnsurePersistentIndexOptions{Name: "idx_leases_expiry"}); err != nil {
+		return nil, fmt.Errorf("failed to create index idx_leases_expiry: %w", err)
+	}
+
+	return &ArangoLeaseStore{db: db, collection: col}, nil
+}
+
+// Acquire claims an execution's lease. Concurrent claims of the same lease
+// conflict, in which case the loser does not acquire it.
+func (s *ArangoLeaseStore) Acquire(ctx context.Context, executionID, owner string, now, expiresAt time.Time) (bool, error) {
+	query := `
+		UPSERT { _key: @key }
+		INSERT { _key: @key, execution_id: @key, owner: @owner, expires_at: @expiresAt, acquired_at: @now }
+		UPDATE OLD.owner == @owner || OLD.expires_at <= @now
+			? { owner: @owner, expires_at: @expiresAt, acquired_at: OLD.owner == @owner ? OLD.acquired_at : @now }
+			: {}
+		IN @@collection
+		RETURN NEW.owner == @owner
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionExecutionLeases,
+		"key":         executionID,
+		"owner":       owner,
+		"now":         leaseTime(now),
+		"expiresAt":   leaseTime(expiresAt),
+	}
+
+	acquired, err := s.queryBool(ctx, query, bindVars)
+	if err != nil {
+		if driver.IsConflict(err) || driver.IsPreconditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return acquired, nil
+}
+
+// Renew extends a lease the owner holds
+func (s *ArangoLeaseStore) Renew(ctx context.Context, executionID, owner string, expiresAt time.Time) (bool, error) {
+	query := `
+		FOR l IN @@collection
+			FILTER l._key == @key AND l.owner == @owner
+			UPDATE l WITH { expires_at: @expiresAt } IN @@collection
+			RETURN true
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionExecutionLeases,
+		"key":         executionID,
+		"owner":       owner,
+		"expiresAt":   leaseTime(expiresAt),
+	}
+
+	renewed, err := s.queryBool(ctx, query, bindVars)
+	if err != nil {
+		if driver.IsConflict(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	return renewed, nil
+}
+
+// Release removes a lease the owner holds
+func (s *ArangoLeaseStore) Release(ctx context.Context, executionID, owner string) error {
+	query := `
+		FOR l IN @@collection
+			FILTER l._key == @key AND l.owner == @owner
+			REMOVE l IN @@collection
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionExecutionLeases,
+		"key":         executionID,
+		"owner":       owner,
+	}
+
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return cursor.Close()
+}
+
+// Expired returns executions whose lease expired by now, longest expired first
+func (s *ArangoLeaseStore) Expired(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		FOR l IN @@collection
+			FILTER l.expires_at <= @now
+			SORT l.expires_at ASC
+			LIMIT @limit
+			RETURN l.execution_id
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionExecutionLeases,
+		"now":         leaseTime(now),
+		"limit":       limit,
+	}
+
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired leases: %w", err)
+	}
+	defer cursor.Close()
+
+	var executionIDs []string
+	for cursor.HasMore() {
+		var executionID string
+		if _, err := cursor.ReadDocument(ctx, &executionID); err != nil {
+			return nil, fmt.Errorf("failed to read expired lease: %w", err)
+		}
+		executionIDs = append(executionIDs, executionID)
+	}
+	return executionIDs, nil
+}
+
+func (s *ArangoLeaseStore) queryBool(ctx context.Context, query string, bindVars map[string]interface{}) (bool, error) {
+	cursor, err := s.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return false, err
+	}
+	defer cursor.Close()
+
+	var result bool
+	if cursor.HasMore() {
+		if _, err := cursor.ReadDocument(ctx, &result); err != nil {
+			return false, err
+		}
+	}
+	return result, nil
+}
+
+// InMemoryLeaseStore keeps execution leases in memory, for a single process
+// and tests
+type InMemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]*ExecutionLease
+}
+
+// NewInMemoryLeaseStore creates an empty in-memory lease store
+func NewInMemoryLeaseStore() *InMemoryLeaseStore {
+	return &InMemoryLeaseStore{leases: make(map[string]*ExecutionLease)}
+}
+
+// Acquire claims an execution's lease
+func (s *InMemoryLeaseStore) Acquire(ctx context.Context, executionID, owner string, now, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, exists := s.leases[executionID]
+	if exists && lease.Owner != owner && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	if !exists || lease.Owner != owner {
+		lease = &ExecutionLease{Key: executionID, ExecutionID: executionID, Owner: owner, AcquiredAt: now}
+		s.leases[executionID] = lease
+	}
+	lease.ExpiresAt = expiresAt
+	return true, nil
+}
+
+// Renew extends a lease the owner holds
+func (s *InMemoryLeaseStore) Renew(ctx context.Context, executionID, owner string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, exists := s.leases[executionID]
+	if !exists || lease.Owner != owner {
+		return false, nil
+	}
+	lease.ExpiresAt = expiresAt
+	return true, nil
+}
+
+// Release removes a lease the owner holds
+func (s *InMemoryLeaseStore) Release(ctx context.Context, executionID, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lease, exists := s.leases[executionID]; exists && lease.Owner == owner {
+		delete(s.leases, executionID)
+	}
+	return nil
+}
+
+// Expired returns executions whose lease expired by now, longest expired first
+func (s *InMemoryLeaseStore) Expired(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*ExecutionLease
+	for _, lease := range s.leases {
+		if !lease.ExpiresAt.After(now) {
+			expired = append(expired, lease)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(expired[j].ExpiresAt)
+	})
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	executionIDs := make([]string, len(expired))
+	for i, lease := range expired {
+		executionIDs[i] = lease.ExecutionID
+	}
+	return executionIDs, nil
+}
+
+// Lease returns a copy of an execution's lease, if any
+func (s *InMemoryLeaseStore) Lease(executionID string) (*ExecutionLease, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, exists := s.leases[executionID]
+	if !exists {
+		return nil, false
+	}
+	leaseCopy := *lease
+	return &leaseCopy, true
+}
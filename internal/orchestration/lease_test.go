@@ -0,0 +1,251 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	log "github.com/sirupsen/logrus"
+)
+
+// sharedRepository stores copies of workflows and executions, like a
+// database shared by engine instances. Workflow versions are kept oldest first.
+type sharedRepository struct {
+	WorkflowRepository
+	workflows  map[string][]*Workflow
+	executions map[string][]byte
+	mu         sync.Mutex
+}
+
+func newSharedRepository() *sharedRepository {
+	return &sharedRepository{workflows: make(map[string][]*Workflow), executions: make(map[string][]byte)}
+}
+
+func (r *sharedRepository) GetWorkflow(ctx context.Context, workflowID string) (*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.workflows[workflowID]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("workflow not found: %s", workflowID)
+	}
+	return versions[len(versions)-1], nil
+}
+
+func (r *sharedRepository) GetWorkflowVersion(ctx context.Context, workflowID, version string) (*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, workflow := range r.workflows[workflowID] {
+		if workflow.Version == version {
+			return workflow, nil
+		}
+	}
+	return nil, fmt.Errorf("workflow %s version %s not found", workflowID, version)
+}
+
+func (r *sharedRepository) ListWorkflowVersions(ctx context.Context, workflowID string) ([]*Workflow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := make([]*Workflow, 0, len(r.workflows[workflowID]))
+	for i := len(r.workflows[workflowID]) - 1; i >= 0; i-- {
+		versions = append(versions, r.workflows[workflowID][i])
+	}
+	return versions, nil
+}
+
+func (r *sharedRepository) StoreExecution(ctx context.Context, execution *WorkflowExecution) error {
+	return r.UpdateExecution(ctx, execution)
+}
+
+func (r *sharedRepository) UpdateExecution(ctx context.Context, execution *WorkflowExecution) error {
+	data, err := json.Marshal(execution)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executions[execution.ID] = data
+	return nil
+}
+
+func (r *sharedRepository) GetExecution(ctx context.Context, executionID string) (*WorkflowExecution, error) {
+	r.mu.Lock()
+	data, exists := r.executions[executionID]
+	r.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("execution not found: %s", executionID)
+	}
+	var execution WorkflowExecution
+	if err := json.Unmarshal(data, &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+func TestEngine_TakeOverExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		return &agent.TaskResult{Success: true}
+	}
+
+	// Another instance stopped while fetch ran, after it completed seed
+	repository := newSharedRepository()
+	repository.workflows["wf-1"] = []*Workflow{{
+		ID:           "wf-1",
+		Tasks:        []WorkflowTask{{ID: "seed", Type: "data_processing"}, {ID: "fetch", Type: "http_request"}},
+		Dependencies: map[string][]string{"fetch": {"seed"}},
+	}}
+	abandoned := &WorkflowExecution{
+		ID:         "exec-1",
+		WorkflowID: "wf-1",
+		Status:     WorkflowStatusRunning,
+		StartTime:  start,
+		TaskExecutions: map[string]*TaskExecution{
+			"seed":  {TaskID: "seed", Status: TaskStatusCompleted, Attempts: 1, Output: make(map[string]interface{})},
+			"fetch": {TaskID: "fetch", Status: TaskStatusRunning, Attempts: 1, Output: make(map[string]interface{})},
+		},
+		Context: make(map[string]interface{}),
+	}
+	if err := repository.StoreExecution(ctx, abandoned); err != nil {
+		t.Fatal(err)
+	}
+	leases := NewInMemoryLeaseStore()
+	if _, err := leases.Acquire(ctx, "exec-1", "engine-a", start.Add(-time.Minute), start); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	config := OrchestrationConfig{InstanceID: "engine-b", LeaseTTL: 30 * time.Second}
+	engine := NewEngine(config, coordinator, &fakeMonitor{}, repository, logger)
+	engine.SetClock(clk)
+	engine.SetLeaseStore(leases)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	// The next lease check takes the execution over and finishes it
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		execution, err := repository.GetExecution(ctx, "exec-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if execution.Status == WorkflowStatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", execution.Status)
+		}
+		clk.Advance(10 * time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	coordinator.mu.Lock()
+	dispatched := coordinator.dispatched
+	coordinator.mu.Unlock()
+	if len(dispatched) != 1 || dispatched[0].Payload.(map[string]interface{})["task_id"] != "fetch" {
+		t.Errorf("expected only the interrupted task to run again, got %v", dispatched)
+	}
+	if _, held := leases.Lease("exec-1"); held {
+		t.Error("expected the lease to be released once the execution finished")
+	}
+}
+
+func TestEngine_LeaseLost(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC))
+
+	// The task never completes
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult { return nil }
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := newSharedRepository()
+	leases := NewInMemoryLeaseStore()
+	monitor := &fakeMonitor{}
+	config := OrchestrationConfig{InstanceID: "engine-b", LeaseTTL: 30 * time.Second}
+	engine := NewEngine(config, coordinator, monitor, repository, logger)
+	engine.SetClock(clk)
+	engine.SetLeaseStore(leases)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	workflow := &Workflow{ID: "wf-1", Tasks: []WorkflowTask{{ID: "fetch", Type: "http_request"}}}
+	execution, err := engine.ExecuteWorkflow(ctx, workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease, held := leases.Lease(execution.ID); !held || lease.Owner != "engine-b" {
+		t.Fatalf("expected engine-b to hold the lease, got %+v", lease)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, err := repository.GetExecution(ctx, execution.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.TaskExecutions["fetch"].Status == TaskStatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected fetch to start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Another instance takes the execution over
+	if err := leases.Release(ctx, execution.ID, "engine-b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := leases.Acquire(ctx, execution.ID, "engine-c", clk.Now(), clk.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next renewal stops the execution locally
+	for {
+		engine.executionMutex.RLock()
+		_, active := engine.activeExecutions[execution.ID]
+		engine.executionMutex.RUnlock()
+		if !active {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the execution to stop")
+		}
+		clk.Advance(10 * time.Second)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := engine.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new owner's record is left alone
+	stored, err := repository.GetExecution(ctx, execution.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != WorkflowStatusRunning || stored.TaskExecutions["fetch"].Status != TaskStatusRunning {
+		t.Errorf("expected no writes after the lease was lost, got %s (fetch %s)", stored.Status, stored.TaskExecutions["fetch"].Status)
+	}
+	if lease, held := leases.Lease(execution.ID); !held || lease.Owner != "engine-c" {
+		t.Errorf("expected engine-c to keep the lease, got %+v", lease)
+	}
+	if len(monitor.stopped) != 1 {
+		t.Errorf("expected monitoring to stop once, got %v", monitor.stopped)
+	}
+}
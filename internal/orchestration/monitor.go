@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -26,6 +27,10 @@ type Monitor struct {
 	eventHandlers []ExecutionEventHandler
 	handlerMutex  sync.RWMutex
 
+	// Live event subscriptions per execution
+	subscribers     map[string]map[*eventSubscriber]struct{}
+	subscriberMutex sync.RWMutex
+
 	// Progress tracking (add fields not in WorkflowExecution)
 	progressData  map[string]float64
 	progressMutex sync.RWMutex
@@ -37,6 +42,8 @@ type Monitor struct {
 
 	// Logger
 	logger *log.Logger
+
+	clock clock.Clock
 }
 
 // MonitorConfig configures the execution monitor
@@ -55,6 +62,10 @@ type MonitorConfig struct {
 
 	// MaxEventHandlers limits the number of concurrent event handlers
 	MaxEventHandlers int
+
+	// EventBufferSize is the number of events buffered per watcher; a watcher
+	// that falls further behind misses events
+	EventBufferSize int
 }
 
 // DefaultMonitorConfig returns default monitor configuration
@@ -65,6 +76,7 @@ func DefaultMonitorConfig() MonitorConfig {
 		EnableDetailedMetrics:  true,
 		MetricsCleanupInterval: 1 * time.Hour,
 		MaxEventHandlers:       10,
+		EventBufferSize:        64,
 	}
 }
 
@@ -89,17 +101,23 @@ type TaskMetrics struct {
 type ExecutionEventType string
 
 const (
-	EventExecutionStarted   ExecutionEventType = "execution_started"
-	EventExecutionCompleted ExecutionEventType = "execution_completed"
-	EventExecutionFailed    ExecutionEventType = "execution_failed"
-	EventExecutionCancelled ExecutionEventType = "execution_cancelled"
-	EventTaskStarted        ExecutionEventType = "task_started"
-	EventTaskCompleted      ExecutionEventType = "task_completed"
-	EventTaskFailed         ExecutionEventType = "task_failed"
-	EventTaskRetried        ExecutionEventType = "task_retried"
-	EventAgentAssigned      ExecutionEventType = "agent_assigned"
-	EventAgentReleased      ExecutionEventType = "agent_released"
-	EventProgressUpdate     ExecutionEventType = "progress_update"
+	EventExecutionStarted     ExecutionEventType = "execution_started"
+	EventExecutionCompleted   ExecutionEventType = "execution_completed"
+	EventExecutionFailed      ExecutionEventType = "execution_failed"
+	EventExecutionCancelled   ExecutionEventType = "execution_cancelled"
+	EventExecutionTimedOut    ExecutionEventType = "execution_timed_out"
+	EventExecutionReleased    ExecutionEventType = "execution_released"
+	EventTaskQueued           ExecutionEventType = "task_queued"
+	EventTaskStarted          ExecutionEventType = "task_started"
+	EventTaskCompleted        ExecutionEventType = "task_completed"
+	EventTaskFailed           ExecutionEventType = "task_failed"
+	EventTaskRetried          ExecutionEventType = "task_retried"
+	EventTaskSkipped          ExecutionEventType = "task_skipped"
+	EventTaskCompensated      ExecutionEventType = "task_compensated"
+	EventTaskAwaitingApproval ExecutionEventType = "task_awaiting_approval"
+	EventAgentAssigned        ExecutionEventType = "agent_assigned"
+	EventAgentReleased        ExecutionEventType = "agent_released"
+	EventProgressUpdate       ExecutionEventType = "progress_update"
 )
 
 // ExecutionEventHandler processes execution events
@@ -116,10 +134,19 @@ func NewMonitor(config MonitorConfig, logger *log.Logger) *Monitor {
 		executions:    make(map[string]*WorkflowExecution),
 		taskMetrics:   make(map[string]*TaskMetrics),
 		eventHandlers: make([]ExecutionEventHandler, 0),
+		subscribers:   make(map[string]map[*eventSubscriber]struct{}),
 		progressData:  make(map[string]float64),
 		ctx:           ctx,
 		cancel:        cancel,
 		logger:        logger,
+		clock:         clock.Real(),
+	}
+}
+
+// SetClock sets the clock used for event timestamps, metrics retention and the monitoring intervals. It must be called before Start.
+func (m *Monitor) SetClock(c clock.Clock) {
+	if c != nil {
+		m.clock = c
 	}
 }
 
@@ -173,7 +200,7 @@ func (m *Monitor) StartTracking(ctx context.Context, execution *WorkflowExecutio
 	event := &ExecutionEvent{
 		ExecutionID: execution.ID,
 		Type:        string(EventExecutionStarted),
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"workflow_id": execution.WorkflowID,
 			"total_tasks": len(execution.TaskExecutions),
@@ -218,6 +245,12 @@ func (m *Monitor) StopTracking(ctx context.Context, executionID string) error {
 	} else if execution.Status == WorkflowStatusCancelled {
 		eventType = EventExecutionCancelled
 		message = "Workflow execution cancelled"
+	} else if execution.Status == WorkflowStatusTimedOut {
+		eventType = EventExecutionTimedOut
+		message = "Workflow execution timed out"
+	} else if execution.Status == WorkflowStatusRunning || execution.Status == WorkflowStatusPaused {
+		eventType = EventExecutionReleased
+		message = "Workflow execution taken over by another instance"
 	}
 
 	var duration time.Duration
@@ -228,7 +261,7 @@ func (m *Monitor) StopTracking(ctx context.Context, executionID string) error {
 	event := &ExecutionEvent{
 		ExecutionID: executionID,
 		Type:        string(eventType),
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"status":          string(execution.Status),
 			"completion_time": execution.EndTime,
@@ -241,6 +274,9 @@ func (m *Monitor) StopTracking(ctx context.Context, executionID string) error {
 		m.logger.WithError(err).Warn("Failed to emit execution stopped event")
 	}
 
+	// The stopped event is the last one watchers receive
+	m.closeSubscribers(executionID)
+
 	return nil
 }
 
@@ -263,7 +299,7 @@ func (m *Monitor) UpdateProgress(ctx context.Context, executionID string, progre
 	event := &ExecutionEvent{
 		ExecutionID: executionID,
 		Type:        string(EventProgressUpdate),
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"progress":        progress,
 			"completed_tasks": m.countCompletedTasks(execution),
@@ -297,10 +333,10 @@ func (m *Monitor) RecordTaskStart(ctx context.Context, executionID, taskID, agen
 		TaskID:      taskID,
 		ExecutionID: executionID,
 		AgentID:     agentID,
-		StartTime:   time.Now(),
+		StartTime:   m.clock.Now(),
 		Status:      TaskStatusRunning,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   m.clock.Now(),
+		UpdatedAt:   m.clock.Now(),
 	}
 
 	m.metricsMutex.Lock()
@@ -313,7 +349,7 @@ func (m *Monitor) RecordTaskStart(ctx context.Context, executionID, taskID, agen
 		Type:        string(EventTaskStarted),
 		TaskID:      taskID,
 		AgentID:     agentID,
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"message": "Task execution started",
 		},
@@ -332,11 +368,11 @@ func (m *Monitor) RecordTaskCompletion(ctx context.Context, taskID string, statu
 	m.metricsMutex.Lock()
 	metrics, exists := m.taskMetrics[taskID]
 	if exists {
-		endTime := time.Now()
+		endTime := m.clock.Now()
 		metrics.EndTime = &endTime
 		metrics.Duration = endTime.Sub(metrics.StartTime)
 		metrics.Status = status
-		metrics.UpdatedAt = time.Now()
+		metrics.UpdatedAt = m.clock.Now()
 
 		if status == TaskStatusFailed {
 			metrics.ErrorCount++
@@ -364,7 +400,7 @@ func (m *Monitor) RecordTaskCompletion(ctx context.Context, taskID string, statu
 		Type:        string(eventType),
 		TaskID:      taskID,
 		AgentID:     metrics.AgentID,
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"duration":    metrics.Duration.Seconds(),
 			"status":      string(status),
@@ -389,7 +425,7 @@ func (m *Monitor) RecordTaskRetry(ctx context.Context, taskID string, retryCount
 	metrics, exists := m.taskMetrics[taskID]
 	if exists {
 		metrics.RetryCount = retryCount
-		metrics.UpdatedAt = time.Now()
+		metrics.UpdatedAt = m.clock.Now()
 	}
 	m.metricsMutex.Unlock()
 
@@ -404,7 +440,7 @@ func (m *Monitor) RecordTaskRetry(ctx context.Context, taskID string, retryCount
 		Type:        string(EventTaskRetried),
 		TaskID:      taskID,
 		AgentID:     metrics.AgentID,
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		Data: map[string]interface{}{
 			"retry_count": retryCount,
 			"reason":      reason,
@@ -508,6 +544,44 @@ func (m *Monitor) GetExecutionMetrics(ctx context.Context, executionID string) (
 	return metrics, nil
 }
 
+// StartMonitoring begins tracking a workflow execution
+func (m *Monitor) StartMonitoring(ctx context.Context, execution *WorkflowExecution) error {
+	return m.StartTracking(ctx, execution)
+}
+
+// StopMonitoring stops tracking a workflow execution
+func (m *Monitor) StopMonitoring(ctx context.Context, executionID string) error {
+	return m.StopTracking(ctx, executionID)
+}
+
+// GetMetrics returns aggregated metrics for an execution
+func (m *Monitor) GetMetrics(ctx context.Context, executionID string) (*ExecutionMetrics, error) {
+	return m.GetExecutionMetrics(ctx, executionID)
+}
+
+// GetProgress returns the last recorded progress of an execution
+func (m *Monitor) GetProgress(ctx context.Context, executionID string) (*ExecutionProgress, error) {
+	m.execMutex.RLock()
+	execution, exists := m.executions[executionID]
+	m.execMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("execution %s not found", executionID)
+	}
+
+	m.progressMutex.RLock()
+	progress := m.progressData[executionID]
+	m.progressMutex.RUnlock()
+
+	return &ExecutionProgress{
+		ExecutionID:     executionID,
+		OverallProgress: progress * 100,
+		CompletedTasks:  m.countCompletedTasks(execution),
+		TotalTasks:      len(execution.TaskExecutions),
+		CurrentPhase:    string(execution.Status),
+	}, nil
+}
+
 // AddEventHandler adds an event handler
 func (m *Monitor) AddEventHandler(handler ExecutionEventHandler) error {
 	m.handlerMutex.Lock()
@@ -531,6 +605,8 @@ func (m *Monitor) emitEvent(ctx context.Context, event *ExecutionEvent) error {
 	copy(handlers, m.eventHandlers)
 	m.handlerMutex.RUnlock()
 
+	m.publish(event)
+
 	// Process events asynchronously to avoid blocking
 	for _, handler := range handlers {
 		go func(h ExecutionEventHandler) {
@@ -562,14 +638,14 @@ func (m *Monitor) progressMonitorWorker() {
 
 	m.logger.Debug("Progress monitor worker started")
 
-	ticker := time.NewTicker(m.config.ProgressUpdateInterval)
+	ticker := m.clock.NewTicker(m.config.ProgressUpdateInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			m.updateAllProgress()
 		}
 	}
@@ -607,21 +683,21 @@ func (m *Monitor) metricsCleanupWorker() {
 
 	m.logger.Debug("Metrics cleanup worker started")
 
-	ticker := time.NewTicker(m.config.MetricsCleanupInterval)
+	ticker := m.clock.NewTicker(m.config.MetricsCleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			m.cleanupOldMetrics()
 		}
 	}
 }
 
 func (m *Monitor) cleanupOldMetrics() {
-	cutoff := time.Now().Add(-m.config.MetricsRetentionPeriod)
+	cutoff := m.clock.Now().Add(-m.config.MetricsRetentionPeriod)
 
 	m.metricsMutex.Lock()
 	toDelete := make([]string, 0)
@@ -0,0 +1,71 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate holds back task scheduling of a paused execution. Tasks already
+// running on agents finish; no new task or retry attempt starts until resumed.
+type pauseGate struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{}
+}
+
+func newPauseGate() *pauseGate {
+	return &pauseGate{}
+}
+
+// pause closes the gate
+func (g *pauseGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		g.paused = true
+		g.resumed = make(chan struct{})
+	}
+}
+
+// resume opens the gate and releases waiting tasks
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		g.paused = false
+		close(g.resumed)
+	}
+}
+
+// wait blocks while the gate is closed
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	paused, resumed := g.paused, g.resumed
+	g.mu.Unlock()
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// awaitScheduling blocks task scheduling while the execution is paused. It
+// fails once the execution is cancelled or timed out.
+func (e *Engine) awaitScheduling(ctx context.Context, execution *WorkflowExecution) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	e.executionMutex.RLock()
+	gate, exists := e.pauseGates[execution.ID]
+	e.executionMutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	return gate.wait(ctx)
+}
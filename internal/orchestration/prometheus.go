@@ -0,0 +1,150 @@
+package orchestration
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/prometheus"
+)
+
+// executionStatsKey groups finished executions by workflow and final status
+type executionStatsKey struct {
+	workflowID string
+	status     WorkflowStatus
+}
+
+// executionStats accumulates the metrics of finished executions
+type executionStats struct {
+	executions      int64
+	durationSeconds float64
+	agentsUtilized  int64
+	tasks           map[TaskStatus]int64
+}
+
+// recordFinishedExecution adds a finished execution to the exported metrics
+func (e *Engine) recordFinishedExecution(execution *WorkflowExecution) {
+	duration := execution.Duration
+	if execution.EndTime == nil {
+		duration = e.clock.Now().Sub(execution.StartTime)
+	}
+
+	e.statsMutex.Lock()
+	defer e.statsMutex.Unlock()
+
+	key := executionStatsKey{workflowID: execution.WorkflowID, status: execution.Status}
+	stats, exists := e.executionStats[key]
+	if !exists {
+		stats = &executionStats{tasks: make(map[TaskStatus]int64)}
+		e.executionStats[key] = stats
+	}
+	stats.executions++
+	stats.durationSeconds += duration.Seconds()
+	stats.agentsUtilized += int64(len(execution.AgentsUsed))
+	for _, taskExecution := range execution.TaskExecutions {
+		stats.tasks[taskExecution.Status]++
+	}
+}
+
+// WritePrometheusMetrics writes the worker pool gauges, the active executions
+// and the metrics of finished executions in the Prometheus text format.
+// Execution metrics are labelled by workflow ID and status.
+func (e *Engine) WritePrometheusMetrics(w io.Writer) error {
+	metrics := e.GetMetrics()
+
+	e.executionMutex.RLock()
+	executions := make([]*WorkflowExecution, 0, len(e.activeExecutions))
+	for _, execution := range e.activeExecutions {
+		executions = append(executions, execution)
+	}
+	e.executionMutex.RUnlock()
+
+	active := make(map[executionStatsKey]int)
+	for _, execution := range executions {
+		e.withState(execution, func() {
+			active[executionStatsKey{workflowID: execution.WorkflowID, status: execution.Status}]++
+		})
+	}
+
+	e.statsMutex.Lock()
+	finished := make(map[executionStatsKey]executionStats, len(e.executionStats))
+	for key, stats := range e.executionStats {
+		statsCopy := *stats
+		statsCopy.tasks = make(map[TaskStatus]int64, len(stats.tasks))
+		for status, count := range stats.tasks {
+			statsCopy.tasks[status] = count
+		}
+		finished[key] = statsCopy
+	}
+	e.statsMutex.Unlock()
+
+	var b strings.Builder
+
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_workers", "gauge", "Number of task workers")
+	fmt.Fprintf(&b, "cortex_workflow_engine_workers %d\n", metrics.Workers)
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_task_queue_depth", "gauge", "Tasks waiting for a worker")
+	fmt.Fprintf(&b, "cortex_workflow_engine_task_queue_depth %d\n", metrics.TaskQueueDepth)
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_task_queue_capacity", "gauge", "Capacity of the task queue")
+	fmt.Fprintf(&b, "cortex_workflow_engine_task_queue_capacity %d\n", metrics.TaskQueueCapacity)
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_completion_queue_depth", "gauge", "Task completions waiting to be processed")
+	fmt.Fprintf(&b, "cortex_workflow_engine_completion_queue_depth %d\n", metrics.CompletionQueueDepth)
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_completion_queue_capacity", "gauge", "Capacity of the completion queue")
+	fmt.Fprintf(&b, "cortex_workflow_engine_completion_queue_capacity %d\n", metrics.CompletionQueueCapacity)
+	prometheus.WriteHeader(&b, "cortex_workflow_engine_rejected_tasks_total", "counter", "Tasks rejected because the task queue was full")
+	fmt.Fprintf(&b, "cortex_workflow_engine_rejected_tasks_total %d\n", metrics.RejectedTasks)
+
+	prometheus.WriteHeader(&b, "cortex_workflow_executions_active", "gauge", "Executions running on this instance")
+	for _, key := range sortedStatsKeys(active) {
+		fmt.Fprintf(&b, "cortex_workflow_executions_active{%s} %d\n", key.labels(), active[key])
+	}
+
+	finishedKeys := sortedStatsKeys(finished)
+	prometheus.WriteHeader(&b, "cortex_workflow_executions_total", "counter", "Finished executions")
+	for _, key := range finishedKeys {
+		fmt.Fprintf(&b, "cortex_workflow_executions_total{%s} %d\n", key.labels(), finished[key].executions)
+	}
+	prometheus.WriteHeader(&b, "cortex_workflow_execution_duration_seconds", "summary", "Duration of finished executions")
+	for _, key := range finishedKeys {
+		fmt.Fprintf(&b, "cortex_workflow_execution_duration_seconds_sum{%s} %g\n", key.labels(), finished[key].durationSeconds)
+		fmt.Fprintf(&b, "cortex_workflow_execution_duration_seconds_count{%s} %d\n", key.labels(), finished[key].executions)
+	}
+	prometheus.WriteHeader(&b, "cortex_workflow_execution_agents_utilized_total", "counter", "Agents used by finished executions")
+	for _, key := range finishedKeys {
+		fmt.Fprintf(&b, "cortex_workflow_execution_agents_utilized_total{%s} %d\n", key.labels(), finished[key].agentsUtilized)
+	}
+	prometheus.WriteHeader(&b, "cortex_workflow_execution_tasks_total", "counter", "Tasks of finished executions by task status")
+	for _, key := range finishedKeys {
+		tasks := finished[key].tasks
+		taskStatuses := make([]string, 0, len(tasks))
+		for status := range tasks {
+			taskStatuses = append(taskStatuses, string(status))
+		}
+		sort.Strings(taskStatuses)
+		for _, status := range taskStatuses {
+			fmt.Fprintf(&b, "cortex_workflow_execution_tasks_total{%s,task_status=\"%s\"} %d\n",
+				key.labels(), prometheus.EscapeLabelValue(status), tasks[TaskStatus(status)])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (k executionStatsKey) labels() string {
+	return fmt.Sprintf("workflow_id=\"%s\",status=\"%s\"", prometheus.EscapeLabelValue(k.workflowID), prometheus.EscapeLabelValue(string(k.status)))
+}
+
+func sortedStatsKeys[V any](m map[executionStatsKey]V) []executionStatsKey {
+	keys := make([]executionStatsKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].workflowID != keys[j].workflowID {
+			return keys[i].workflowID < keys[j].workflowID
+		}
+		return keys[i].status < keys[j].status
+	})
+	return keys
+}
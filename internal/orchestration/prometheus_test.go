@@ -0,0 +1,68 @@
+package orchestration
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_WritePrometheusMetrics(t *testing.T) {
+	ctx := context.Background()
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		if task.Payload.(map[string]interface{})["task_id"] == "wait" {
+			return nil
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	engine := NewEngine(OrchestrationConfig{WorkerCount: 2, TaskQueueSize: 5}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	// One execution completes and another stays running
+	finished, err := engine.ExecuteWorkflow(ctx, &Workflow{ID: "ingest", Tasks: []WorkflowTask{{ID: "fetch", Type: "http_request"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.ExecuteWorkflow(ctx, &Workflow{ID: "report", Tasks: []WorkflowTask{{ID: "wait", Type: "http_request"}}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.status(finished.ID) != WorkflowStatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the execution to complete, got %s", repository.status(finished.ID))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var b strings.Builder
+	if err := engine.WritePrometheusMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, line := range []string{
+		"# TYPE cortex_workflow_engine_workers gauge",
+		"cortex_workflow_engine_workers 2",
+		"cortex_workflow_engine_task_queue_capacity 5",
+		`cortex_workflow_executions_active{workflow_id="report",status="running"} 1`,
+		`cortex_workflow_executions_total{workflow_id="ingest",status="completed"} 1`,
+		`cortex_workflow_execution_duration_seconds_count{workflow_id="ingest",status="completed"} 1`,
+		`cortex_workflow_execution_agents_utilized_total{workflow_id="ingest",status="completed"} 1`,
+		`cortex_workflow_execution_tasks_total{workflow_id="ingest",status="completed",task_status="completed"} 1`,
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected metric %q in:\n%s", line, output)
+		}
+	}
+}
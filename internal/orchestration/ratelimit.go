@@ -0,0 +1,78 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// assignmentRateWindow is the window assignment rate limits are counted over
+const assignmentRateWindow = time.Minute
+
+// ErrAgentRateLimited is returned when a task gives up waiting for an agent
+// that reached its assignment rate limit
+var ErrAgentRateLimited = errors.New("agent assignment rate limit reached")
+
+// assignmentRateLimit returns the tasks per minute an agent may be assigned,
+// or 0 if it is not limited
+func (c *Coordinator) assignmentRateLimit(agentID string) int {
+	if c.runtimeManager != nil && len(c.config.AssignmentRateLimits) > 0 {
+		if ag, err := c.runtimeManager.GetAgent(agentID); err == nil {
+			if limit, exists := c.config.AssignmentRateLimits[ag.Type]; exists {
+				return limit
+			}
+		}
+	}
+	return c.config.DefaultAssignmentRateLimit
+}
+
+// reserveAssignment records an assignment to the agent if it is under its
+// limit for the last minute. Otherwise it returns how long until it will be.
+func (c *Coordinator) reserveAssignment(agentID string, limit int) time.Duration {
+	c.rateMutex.Lock()
+	defer c.rateMutex.Unlock()
+
+	now := c.clock.Now()
+	recent := c.assignments[agentID]
+	for len(recent) > 0 && !recent[0].After(now.Add(-assignmentRateWindow)) {
+		recent = recent[1:]
+	}
+
+	if len(recent) < limit {
+		c.assignments[agentID] = append(recent, now)
+		return 0
+	}
+	c.assignments[agentID] = recent
+	return recent[0].Add(assignmentRateWindow).Sub(now)
+}
+
+// awaitAssignmentRate waits until the agent may be assigned another task, so
+// that concurrent workflows cannot flood a slow agent
+func (c *Coordinator) awaitAssignmentRate(ctx context.Context, agentID string) error {
+	limit := c.assignmentRateLimit(agentID)
+	if limit <= 0 {
+		return nil
+	}
+
+	for {
+		wait := c.reserveAssignment(agentID, limit)
+		if wait <= 0 {
+			return nil
+		}
+
+		c.logger.WithFields(log.Fields{
+			"agent_id": agentID,
+			"limit":    limit,
+			"wait":     wait,
+		}).Debug("Agent assignment rate limit reached, waiting")
+
+		select {
+		case <-c.clock.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%w for agent %s: %w", ErrAgentRateLimited, agentID, context.Cause(ctx))
+		}
+	}
+}
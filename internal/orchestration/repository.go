@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/clock"
 	"github.com/arangodb/go-driver"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,6 +23,8 @@ type Repository struct {
 
 	// Logger
 	logger *log.Logger
+
+	clock clock.Clock
 }
 
 // RepositoryConfig configures the workflow repository
@@ -54,6 +57,7 @@ func NewRepository(db driver.Database, config RepositoryConfig, logger *log.Logg
 	repo := &Repository{
 		db:     db,
 		logger: logger,
+		clock:  clock.Real(),
 	}
 
 	// Initialize collections
@@ -71,10 +75,17 @@ func NewRepository(db driver.Database, config RepositoryConfig, logger *log.Logg
 	return repo, nil
 }
 
+// SetClock sets the clock used for workflow timestamps
+func (r *Repository) SetClock(c clock.Clock) {
+	if c != nil {
+		r.clock = c
+	}
+}
+
 // Workflow CRUD operations
 
-// CreateWorkflow stores a new workflow definition
-func (r *Repository) CreateWorkflow(ctx context.Context, workflow *Workflow) error {
+// StoreWorkflow stores a new workflow definition or version
+func (r *Repository) StoreWorkflow(ctx context.Context, workflow *Workflow) error {
 	r.logger.WithFields(log.Fields{
 		"workflow_id": workflow.ID,
 		"name":        workflow.Name,
@@ -82,7 +93,7 @@ func (r *Repository) CreateWorkflow(ctx context.Context, workflow *Workflow) err
 	}).Debug("Creating workflow")
 
 	// Set creation timestamp
-	workflow.CreatedAt = time.Now()
+	workflow.CreatedAt = r.clock.Now()
 	workflow.UpdatedAt = workflow.CreatedAt
 
 	// Insert into database
@@ -95,20 +106,106 @@ func (r *Repository) CreateWorkflow(ctx context.Context, workflow *Workflow) err
 	return nil
 }
 
-// GetWorkflow retrieves a workflow by ID
+// GetWorkflow retrieves the latest version of a workflow by ID
 func (r *Repository) GetWorkflow(ctx context.Context, workflowID string) (*Workflow, error) {
 	r.logger.WithField("workflow_id", workflowID).Debug("Retrieving workflow")
 
-	var workflow Workflow
-	_, err := r.workflowsCollection.ReadDocument(ctx, workflowID, &workflow)
+	query := `
+		FOR w IN @@collection
+		FILTER w.id == @workflow_id
+		SORT w.created_at DESC
+		LIMIT 1
+		RETURN w
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": r.workflowsCollection.Name(),
+		"workflow_id": workflowID,
+	}
+
+	workflows, err := r.queryWorkflows(ctx, query, bindVars)
 	if err != nil {
-		if driver.IsNotFound(err) {
-			return nil, fmt.Errorf("workflow %s not found", workflowID)
-		}
 		return nil, fmt.Errorf("failed to get workflow: %w", err)
 	}
+	if len(workflows) == 0 {
+		return nil, fmt.Errorf("workflow %s not found", workflowID)
+	}
 
-	return &workflow, nil
+	return workflows[0], nil
+}
+
+// GetWorkflowVersion retrieves a specific version of a workflow
+func (r *Repository) GetWorkflowVersion(ctx context.Context, workflowID, version string) (*Workflow, error) {
+	r.logger.WithFields(log.Fields{
+		"workflow_id": workflowID,
+		"version":     version,
+	}).Debug("Retrieving workflow version")
+
+	query := `
+		FOR w IN @@collection
+		FILTER w.id == @workflow_id AND w.version == @version
+		LIMIT 1
+		RETURN w
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": r.workflowsCollection.Name(),
+		"workflow_id": workflowID,
+		"version":     version,
+	}
+
+	workflows, err := r.queryWorkflows(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow version: %w", err)
+	}
+	if len(workflows) == 0 {
+		return nil, fmt.Errorf("workflow %s version %s not found", workflowID, version)
+	}
+
+	return workflows[0], nil
+}
+
+// ListWorkflowVersions returns every version of a workflow, newest first
+func (r *Repository) ListWorkflowVersions(ctx context.Context, workflowID string) ([]*Workflow, error) {
+	r.logger.WithField("workflow_id", workflowID).Debug("Listing workflow versions")
+
+	query := `
+		FOR w IN @@collection
+		FILTER w.id == @workflow_id
+		SORT w.created_at DESC
+		RETURN w
+	`
+
+	bindVars := map[string]interface{}{
+		"@collection": r.workflowsCollection.Name(),
+		"workflow_id": workflowID,
+	}
+
+	workflows, err := r.queryWorkflows(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow versions: %w", err)
+	}
+
+	return workflows, nil
+}
+
+func (r *Repository) queryWorkflows(ctx context.Context, query string, bindVars map[string]interface{}) ([]*Workflow, error) {
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	workflows := make([]*Workflow, 0)
+	for cursor.HasMore() {
+		var workflow Workflow
+		if _, err := cursor.ReadDocument(ctx, &workflow); err != nil {
+			return nil, fmt.Errorf("failed to read workflow document: %w", err)
+		}
+		workflows = append(workflows, &workflow)
+	}
+
+	return workflows, nil
 }
 
 // GetWorkflowByName retrieves a workflow by name and version
@@ -154,7 +251,7 @@ func (r *Repository) UpdateWorkflow(ctx context.Context, workflow *Workflow) err
 	r.logger.WithField("workflow_id", workflow.ID).Debug("Updating workflow")
 
 	// Update timestamp
-	workflow.UpdatedAt = time.Now()
+	workflow.UpdatedAt = r.clock.Now()
 
 	// Update document
 	_, err := r.workflowsCollection.UpdateDocument(ctx, workflow.ID, workflow)
@@ -185,41 +282,55 @@ func (r *Repository) DeleteWorkflow(ctx context.Context, workflowID string) erro
 	return nil
 }
 
-// ListWorkflows retrieves workflows with pagination
-func (r *Repository) ListWorkflows(ctx context.Context, limit, offset int) ([]*Workflow, error) {
+// ListWorkflows retrieves the workflows matching the name and creator
+// filters with pagination, newest first. Workflows carry no tags, so the tag
+// filter is not applied.
+func (r *Repository) ListWorkflows(ctx context.Context, filters WorkflowFilters) ([]*Workflow, error) {
 	r.logger.WithFields(log.Fields{
-		"limit":  limit,
-		"offset": offset,
+		"name":       filters.Name,
+		"created_by": filters.CreatedBy,
+		"limit":      filters.Limit,
+		"offset":     filters.Offset,
 	}).Debug("Listing workflows")
 
-	query := `
-		FOR w IN @@collection
-		SORT w.created_at DESC
-		LIMIT @offset, @limit
-		RETURN w
-	`
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = 50
+	}
 
+	var conditions []string
 	bindVars := map[string]interface{}{
 		"@collection": r.workflowsCollection.Name(),
 		"limit":       limit,
-		"offset":      offset,
+		"offset":      filters.Offset,
 	}
 
-	cursor, err := r.db.Query(ctx, query, bindVars)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query workflows: %w", err)
+	if filters.Name != "" {
+		conditions = append(conditions, "CONTAINS(LOWER(w.name), LOWER(@name))")
+		bindVars["name"] = filters.Name
 	}
-	defer cursor.Close()
 
-	var workflows []*Workflow
-	for cursor.HasMore() {
-		var workflow Workflow
-		_, err := cursor.ReadDocument(ctx, &workflow)
-		if err != nil {
-			r.logger.WithError(err).Warn("Failed to read workflow document")
-			continue
-		}
-		workflows = append(workflows, &workflow)
+	if filters.CreatedBy != "" {
+		conditions = append(conditions, "w.created_by == @created_by")
+		bindVars["created_by"] = filters.CreatedBy
+	}
+
+	filterClause := ""
+	if len(conditions) > 0 {
+		filterClause = "FILTER " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		FOR w IN @@collection
+		%s
+		SORT w.created_at DESC
+		LIMIT @offset, @limit
+		RETURN w
+	`, filterClause)
+
+	workflows, err := r.queryWorkflows(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflows: %w", err)
 	}
 
 	return workflows, nil
@@ -277,8 +388,14 @@ func (r *Repository) SearchWorkflows(ctx context.Context, searchTerm string, lim
 
 // Execution CRUD operations
 
-// CreateExecution stores a new workflow execution
-func (r *Repository) CreateExecution(ctx context.Context, execution *WorkflowExecution) error {
+// executionDocument is an execution stored under its ID as the document key
+type executionDocument struct {
+	Key string `json:"_key"`
+	*WorkflowExecution
+}
+
+// StoreExecution stores a new workflow execution
+func (r *Repository) StoreExecution(ctx context.Context, execution *WorkflowExecution) error {
 	r.logger.WithFields(log.Fields{
 		"execution_id": execution.ID,
 		"workflow_id":  execution.WorkflowID,
@@ -286,7 +403,7 @@ func (r *Repository) CreateExecution(ctx context.Context, execution *WorkflowExe
 	}).Debug("Creating execution")
 
 	// Insert into database
-	_, err := r.executionsCollection.CreateDocument(ctx, execution)
+	_, err := r.executionsCollection.CreateDocument(ctx, executionDocument{Key: execution.ID, WorkflowExecution: execution})
 	if err != nil {
 		return fmt.Errorf("failed to create execution: %w", err)
 	}
@@ -311,12 +428,13 @@ func (r *Repository) GetExecution(ctx context.Context, executionID string) (*Wor
 	return &execution, nil
 }
 
-// UpdateExecution updates an existing execution
+// UpdateExecution replaces an existing execution, so that task executions a
+// migration removed are dropped
 func (r *Repository) UpdateExecution(ctx context.Context, execution *WorkflowExecution) error {
 	r.logger.WithField("execution_id", execution.ID).Debug("Updating execution")
 
-	// Update document
-	_, err := r.executionsCollection.UpdateDocument(ctx, execution.ID, execution)
+	// Replace document
+	_, err := r.executionsCollection.ReplaceDocument(ctx, execution.ID, executionDocument{Key: execution.ID, WorkflowExecution: execution})
 	if err != nil {
 		if driver.IsNotFound(err) {
 			return fmt.Errorf("execution %s not found", execution.ID)
@@ -344,36 +462,57 @@ func (r *Repository) DeleteExecution(ctx context.Context, executionID string) er
 	return nil
 }
 
-// ListExecutions retrieves executions with pagination and filtering
-func (r *Repository) ListExecutions(ctx context.Context, workflowID string, status WorkflowStatus, limit, offset int) ([]*WorkflowExecution, error) {
+// ListExecutions retrieves executions with pagination and filtering, newest first
+func (r *Repository) ListExecutions(ctx context.Context, filters ExecutionFilters) ([]*WorkflowExecution, error) {
 	r.logger.WithFields(log.Fields{
-		"workflow_id": workflowID,
-		"status":      status,
-		"limit":       limit,
-		"offset":      offset,
+		"workflow_id": filters.WorkflowID,
+		"status":      filters.Status,
+		"agent_id":    filters.AgentID,
+		"limit":       filters.Limit,
+		"offset":      filters.Offset,
 	}).Debug("Listing executions")
 
 	// Build query with optional filters
-	var filters []string
+	var conditions []string
 	bindVars := map[string]interface{}{
 		"@collection": r.executionsCollection.Name(),
-		"limit":       limit,
-		"offset":      offset,
+		"limit":       filters.Limit,
+		"offset":      filters.Offset,
+	}
+
+	if filters.WorkflowID != "" {
+		conditions = append(conditions, "e.workflow_id == @workflow_id")
+		bindVars["workflow_id"] = filters.WorkflowID
+	}
+
+	if len(filters.Status) > 0 {
+		conditions = append(conditions, "e.status IN @statuses")
+		bindVars["statuses"] = filters.Status
+	}
+
+	if filters.StartTimeAfter != nil {
+		conditions = append(conditions, "e.start_time >= @start_after")
+		bindVars["start_after"] = filters.StartTimeAfter.UTC()
 	}
 
-	if workflowID != "" {
-		filters = append(filters, "e.workflow_id == @workflow_id")
-		bindVars["workflow_id"] = workflowID
+	if filters.StartTimeBefore != nil {
+		conditions = append(conditions, "e.start_time <= @start_before")
+		bindVars["start_before"] = filters.StartTimeBefore.UTC()
 	}
 
-	if status != "" {
-		filters = append(filters, "e.status == @status")
-		bindVars["status"] = string(status)
+	if filters.TriggeredBy != "" {
+		conditions = append(conditions, "e.triggered_by == @triggered_by")
+		bindVars["triggered_by"] = filters.TriggeredBy
+	}
+
+	if filters.AgentID != "" {
+		conditions = append(conditions, "@agent_id IN e.agents_used")
+		bindVars["agent_id"] = filters.AgentID
 	}
 
 	filterClause := ""
-	if len(filters) > 0 {
-		filterClause = "FILTER " + strings.Join(filters, " AND ")
+	if len(conditions) > 0 {
+		filterClause = "FILTER " + strings.Join(conditions, " AND ")
 	}
 
 	query := fmt.Sprintf(`
@@ -390,7 +529,7 @@ func (r *Repository) ListExecutions(ctx context.Context, workflowID string, stat
 	}
 	defer cursor.Close()
 
-	var executions []*WorkflowExecution
+	executions := make([]*WorkflowExecution, 0)
 	for cursor.HasMore() {
 		var execution WorkflowExecution
 		_, err := cursor.ReadDocument(ctx, &execution)
@@ -575,6 +714,11 @@ func (r *Repository) createIndexes() error {
 
 	// Workflow indexes
 	workflowIndexes := []map[string]interface{}{
+		{
+			"type":   "persistent",
+			"fields": []string{"id", "version"},
+			"unique": true,
+		},
 		{
 			"type":   "persistent",
 			"fields": []string{"name", "version"},
@@ -0,0 +1,93 @@
+package orchestration
+
+import (
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
+)
+
+// CapabilitiesMetadataKey is the agent metadata entry listing the agent's
+// capabilities, separated by commas
+const CapabilitiesMetadataKey = agent.CapabilitiesMetadataKey
+
+// healthScores maps an agent's health status onto the score used for
+// selection. Agents without a health report are assumed healthy.
+var healthScores = map[health.HealthStatus]float64{
+	health.HealthStatusHealthy:   1.0,
+	health.HealthStatusDegraded:  0.7,
+	health.HealthStatusUnhealthy: 0.3,
+	health.HealthStatusCritical:  0.0,
+}
+
+// degradedHealthScore caps the health score of agents whose heartbeats are
+// late or who report themselves degraded
+var degradedHealthScore = healthScores[health.HealthStatusDegraded]
+
+// hasTags reports whether an agent's metadata has every tag
+func hasTags(ag *agent.Agent, tags map[string]string) bool {
+	for key, value := range tags {
+		if ag.Metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// partitionAgents splits candidates into the preferred agents and the rest,
+// keeping their order
+func partitionAgents(candidates []*agent.Agent, preferredIDs []string) ([]*agent.Agent, []*agent.Agent) {
+	if len(preferredIDs) == 0 {
+		return nil, candidates
+	}
+	isPreferred := make(map[string]bool, len(preferredIDs))
+	for _, id := range preferredIDs {
+		isPreferred[id] = true
+	}
+
+	var preferred, others []*agent.Agent
+	for _, ag := range candidates {
+		if isPreferred[ag.ID] {
+			preferred = append(preferred, ag)
+		} else {
+			others = append(others, ag)
+		}
+	}
+	return preferred, others
+}
+
+// resolveAgentSelector turns a task's affinity rules into the agents that ran
+// the referenced tasks. Tasks that have not run yet have no agent and are ignored.
+func resolveAgentSelector(selector AgentSelector, execution *WorkflowExecution) AgentSelector {
+	agentsOf := func(taskIDs []string) []string {
+		var agentIDs []string
+		for _, taskID := range taskIDs {
+			if taskExecution, exists := execution.TaskExecutions[taskID]; exists && taskExecution.AgentID != "" {
+				agentIDs = append(agentIDs, taskExecution.AgentID)
+			}
+		}
+		return agentIDs
+	}
+
+	resolved := selector
+	if preferred := agentsOf(selector.Affinity); len(preferred) > 0 {
+		resolved.PreferredAgents = append(append([]string{}, selector.PreferredAgents...), preferred...)
+	}
+	if excluded := agentsOf(selector.AntiAffinity); len(excluded) > 0 {
+		resolved.ExcludedAgents = append(append([]string{}, selector.ExcludedAgents...), excluded...)
+	}
+	return resolved
+}
+
+// validateAffinity checks that a task's affinity rules refer to other tasks of the workflow
+func validateAffinity(task WorkflowTask, taskIDs map[string]bool) error {
+	for _, taskID := range append(append([]string{}, task.AgentSelector.Affinity...), task.AgentSelector.AntiAffinity...) {
+		if !taskIDs[taskID] {
+			return fmt.Errorf("task %s affinity references unknown task: %s", task.ID, taskID)
+		}
+		if taskID == task.ID {
+			return fmt.Errorf("task %s affinity references itself", task.ID)
+		}
+	}
+	return nil
+}
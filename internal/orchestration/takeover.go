@@ -0,0 +1,192 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// takeOverBatchSize limits the expired leases taken over per lease check
+const takeOverBatchSize = 10
+
+// SetLeaseStore makes the engine claim a lease on each execution it runs, so
+// that engine instances sharing a database never run the same execution. The
+// engine renews its leases and takes over executions whose owner stopped
+// renewing. It must be called before Start.
+func (e *Engine) SetLeaseStore(store LeaseStore) {
+	e.leases = store
+}
+
+func (e *Engine) leaseWorker() {
+	defer e.wg.Done()
+
+	e.logger.WithField("instance_id", e.config.InstanceID).Debug("Lease worker started")
+
+	ticker := e.clock.NewTicker(e.config.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C():
+			e.renewLeases(e.ctx)
+			e.takeOverExpired(e.ctx)
+		}
+	}
+}
+
+// renewLeases extends the leases of active executions. An execution whose
+// lease another instance took over is stopped here without further writes.
+func (e *Engine) renewLeases(ctx context.Context) {
+	e.executionMutex.RLock()
+	executionIDs := make([]string, 0, len(e.activeExecutions))
+	for executionID := range e.activeExecutions {
+		executionIDs = append(executionIDs, executionID)
+	}
+	e.executionMutex.RUnlock()
+
+	expiresAt := e.clock.Now().Add(e.config.LeaseTTL)
+	for _, executionID := range executionIDs {
+		renewed, err := e.leases.Renew(ctx, executionID, e.config.InstanceID, expiresAt)
+		if err != nil {
+			// The lease may still be renewed before it expires
+			e.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to renew execution lease")
+			continue
+		}
+		if !renewed {
+			e.loseLease(ctx, executionID)
+		}
+	}
+}
+
+// loseLease stops an execution now owned by another instance
+func (e *Engine) loseLease(ctx context.Context, executionID string) {
+	e.executionMutex.Lock()
+	e.lostLeases[executionID] = true
+	e.executionMutex.Unlock()
+
+	execution, stopped := e.stopExecution(executionID, WorkflowStatusRunning, ErrLeaseLost)
+	if !stopped {
+		// The execution finished as its lease was checked
+		e.forgetLostLease(executionID)
+		return
+	}
+
+	if err := e.monitor.StopMonitoring(ctx, execution.ID); err != nil {
+		e.logger.WithError(err).Error("Failed to stop execution monitoring")
+	}
+
+	e.logger.WithField("execution_id", executionID).Warn("Execution lease lost to another instance, stopped execution")
+}
+
+func (e *Engine) leaseLost(executionID string) bool {
+	e.executionMutex.RLock()
+	defer e.executionMutex.RUnlock()
+	return e.lostLeases[executionID]
+}
+
+func (e *Engine) forgetLostLease(executionID string) {
+	e.executionMutex.Lock()
+	delete(e.lostLeases, executionID)
+	e.executionMutex.Unlock()
+}
+
+// takeOverExpired claims executions whose owner stopped renewing their lease
+func (e *Engine) takeOverExpired(ctx context.Context) {
+	executionIDs, err := e.leases.Expired(ctx, e.clock.Now(), takeOverBatchSize)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to list expired execution leases")
+		return
+	}
+
+	for _, executionID := range executionIDs {
+		e.executionMutex.RLock()
+		_, active := e.activeExecutions[executionID]
+		e.executionMutex.RUnlock()
+		if active {
+			continue
+		}
+
+		if err := e.takeOverExecution(ctx, executionID); err != nil {
+			e.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to take over execution")
+		}
+	}
+}
+
+// takeOverExecution claims an abandoned execution and resumes it. Completed
+// and skipped tasks are kept; tasks that were in flight run again.
+func (e *Engine) takeOverExecution(ctx context.Context, executionID string) error {
+	now := e.clock.Now()
+	acquired, err := e.leases.Acquire(ctx, executionID, e.config.InstanceID, now, now.Add(e.config.LeaseTTL))
+	if err != nil {
+		return fmt.Errorf("failed to acquire execution lease: %w", err)
+	}
+	if !acquired {
+		// Another instance took it over first
+		return nil
+	}
+
+	release := func() {
+		if err := e.leases.Release(ctx, executionID, e.config.InstanceID); err != nil {
+			e.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to release execution lease")
+		}
+	}
+
+	execution, err := e.repository.GetExecution(ctx, executionID)
+	if err != nil {
+		release()
+		return fmt.Errorf("failed to get execution: %w", err)
+	}
+
+	switch execution.Status {
+	case WorkflowStatusPending, WorkflowStatusRunning, WorkflowStatusPaused:
+	case WorkflowStatusCompensating:
+		// A rollback cannot tell which compensations already ran
+		e.abandonExecution(ctx, execution, fmt.Errorf("owner instance stopped during rollback"))
+		release()
+		return nil
+	default:
+		// Finished executions need no owner
+		release()
+		return nil
+	}
+
+	workflow, err := e.workflowForExecution(ctx, execution)
+	if err != nil {
+		e.abandonExecution(ctx, execution, fmt.Errorf("failed to get workflow: %w", err))
+		release()
+		return nil
+	}
+
+	for _, taskExecution := range execution.TaskExecutions {
+		switch taskExecution.Status {
+		case TaskStatusQueued, TaskStatusRunning, TaskStatusRetrying:
+			taskExecution.Status = TaskStatusPending
+			taskExecution.Logs = append(taskExecution.Logs, fmt.Sprintf("Restarted by instance %s after its owner stopped", e.config.InstanceID))
+		}
+	}
+
+	e.launchExecution(e.ctx, workflow, execution)
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"workflow_id":  execution.WorkflowID,
+		"instance_id":  e.config.InstanceID,
+	}).Info("Took over workflow execution")
+	return nil
+}
+
+// abandonExecution fails an execution that cannot be resumed after a takeover
+func (e *Engine) abandonExecution(ctx context.Context, execution *WorkflowExecution, err error) {
+	now := e.clock.Now()
+	execution.Status = WorkflowStatusFailed
+	execution.Error = fmt.Sprintf("execution could not be resumed: %v", err)
+	execution.EndTime = &now
+	execution.Duration = now.Sub(execution.StartTime)
+
+	if err := e.repository.UpdateExecution(ctx, execution); err != nil {
+		e.logger.WithError(err).Error("Failed to update execution")
+	}
+}
@@ -0,0 +1,97 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrExecutionCancelled is the cause of a cancelled execution's context
+	ErrExecutionCancelled = errors.New("execution cancelled")
+
+	// ErrExecutionTimedOut is the cause of a timed-out execution's context
+	ErrExecutionTimedOut = errors.New("execution timed out")
+)
+
+// executionTimeout returns the timeout of a workflow's executions: the
+// workflow's own timeout, or the engine default. Zero means no timeout.
+func (e *Engine) executionTimeout(workflow *Workflow) time.Duration {
+	if workflow.Configuration.Timeout > 0 {
+		return workflow.Configuration.Timeout
+	}
+	return e.config.DefaultWorkflowTimeout
+}
+
+// stopExecution removes an active execution and cancels its context with the
+// cause. Tasks waiting on agents or on a pause give up; it returns false if
+// the execution is no longer active.
+func (e *Engine) stopExecution(executionID string, status WorkflowStatus, cause error) (*WorkflowExecution, bool) {
+	e.executionMutex.Lock()
+	execution, exists := e.activeExecutions[executionID]
+	if !exists {
+		e.executionMutex.Unlock()
+		return nil, false
+	}
+
+	delete(e.activeExecutions, executionID)
+	delete(e.pauseGates, executionID)
+	cancel := e.executionCancels[executionID]
+	delete(e.executionCancels, executionID)
+	e.executionMutex.Unlock()
+
+	e.statusMutex.Lock()
+	delete(e.reportedStatuses, executionID)
+	e.statusMutex.Unlock()
+
+	if cancel != nil {
+		cancel(cause)
+	}
+	// Lost and migrated executions are not finished here
+	lost := errors.Is(cause, ErrLeaseLost) || errors.Is(cause, ErrExecutionMigrated)
+	e.withState(execution, func() {
+		execution.Status = status
+		if !lost {
+			e.recordFinishedExecution(execution)
+		}
+	})
+	if lost {
+		return execution, true
+	}
+	if e.leases != nil {
+		if err := e.leases.Release(context.Background(), executionID, e.config.InstanceID); err != nil {
+			e.logger.WithError(err).WithField("execution_id", executionID).Warn("Failed to release execution lease")
+		}
+	}
+	return execution, true
+}
+
+// timeoutExecution stops an execution that ran past its timeout
+func (e *Engine) timeoutExecution(ctx context.Context, executionID string) {
+	execution, stopped := e.stopExecution(executionID, WorkflowStatusTimedOut, ErrExecutionTimedOut)
+	if !stopped {
+		return
+	}
+
+	e.withState(execution, func() {
+		now := e.clock.Now()
+		execution.EndTime = &now
+		execution.Duration = now.Sub(execution.StartTime)
+		execution.Error = fmt.Sprintf("%s after %s", ErrExecutionTimedOut, execution.Timeout)
+
+		e.updateExecution(ctx, execution)
+	})
+
+	// Stopping monitoring emits the timed-out event
+	if err := e.monitor.StopMonitoring(ctx, execution.ID); err != nil {
+		e.logger.WithError(err).Error("Failed to stop execution monitoring")
+	}
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"timeout":      execution.Timeout,
+	}).Warn("Workflow execution timed out")
+}
@@ -2,6 +2,7 @@ package orchestration
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/aosanya/CodeValdCortex/internal/agent"
@@ -23,6 +24,10 @@ const (
 	WorkflowStatusCancelled WorkflowStatus = "cancelled"
 	// WorkflowStatusPaused indicates workflow execution is paused
 	WorkflowStatusPaused WorkflowStatus = "paused"
+	// WorkflowStatusTimedOut indicates workflow execution ran past its timeout and was stopped
+	WorkflowStatusTimedOut WorkflowStatus = "timed_out"
+	// WorkflowStatusCompensating indicates a failed workflow is rolling back its completed tasks
+	WorkflowStatusCompensating WorkflowStatus = "compensating"
 )
 
 // TaskStatus represents the current state of a workflow task
@@ -43,6 +48,10 @@ const (
 	TaskStatusSkipped TaskStatus = "skipped"
 	// TaskStatusRetrying indicates task is being retried after failure
 	TaskStatusRetrying TaskStatus = "retrying"
+	// TaskStatusCompensated indicates a completed task was undone by its compensation
+	TaskStatusCompensated TaskStatus = "compensated"
+	// TaskStatusAwaitingApproval indicates a manual approval task is waiting for a person's decision
+	TaskStatusAwaitingApproval TaskStatus = "awaiting_approval"
 )
 
 // AgentSelectionStrategy defines how agents are selected for task execution
@@ -131,6 +140,50 @@ type WorkflowTask struct {
 
 	// OutputMapping defines how task outputs are captured
 	OutputMapping map[string]string `json:"output_mapping"`
+
+	// InputMapping defines task inputs from expressions such as
+	// ${tasks.<id>.output.<key>} or ${context.<key>}, resolved before dispatch
+	InputMapping map[string]string `json:"input_mapping,omitempty"`
+
+	// Branch makes the task an if/else node evaluated by the engine instead of an agent
+	Branch *TaskBranch `json:"branch,omitempty"`
+
+	// Loop repeats the task over a list or until a condition holds
+	Loop *TaskLoop `json:"loop,omitempty"`
+
+	// Compensation undoes the task's side effects when a later task fails and
+	// the workflow's failure policy is "rollback". It runs on the agent that
+	// ran the task unless it has its own agent selector.
+	Compensation *WorkflowTask `json:"compensation,omitempty"`
+}
+
+// TaskBranch chooses which of two sets of tasks runs. The tasks of the branch
+// not taken are skipped, as are tasks depending only on skipped tasks.
+type TaskBranch struct {
+	// Condition is an expression over the execution context and task outputs,
+	// e.g. ${tasks.analyze.output.leak_probability} > 0.7
+	Condition string `json:"condition"`
+
+	// Then lists the tasks run when the condition holds
+	Then []string `json:"then,omitempty"`
+
+	// Else lists the tasks run when it does not
+	Else []string `json:"else,omitempty"`
+}
+
+// TaskLoop repeats a task a bounded number of times. Exactly one of ForEach
+// and Until is set.
+type TaskLoop struct {
+	// ForEach is an expression resolving to a list; the task runs once per
+	// item with the item and index as inputs
+	ForEach string `json:"for_each,omitempty"`
+
+	// Until is an expression checked after each run; the task runs again
+	// until it holds. It may refer to the task's own latest output.
+	Until string `json:"until,omitempty"`
+
+	// MaxIterations bounds the loop (default 100, at most 1000)
+	MaxIterations int `json:"max_iterations,omitempty"`
 }
 
 // AgentSelector defines criteria for selecting agents to execute tasks
@@ -152,6 +205,19 @@ type AgentSelector struct {
 
 	// HealthThreshold specifies minimum health score for selection
 	HealthThreshold float64 `json:"health_threshold,omitempty"`
+
+	// Affinity lists tasks of the same execution whose agents are preferred
+	Affinity []string `json:"affinity,omitempty"`
+
+	// AntiAffinity lists tasks of the same execution whose agents must not be used
+	AntiAffinity []string `json:"anti_affinity,omitempty"`
+
+	// PreferredAgents are chosen ahead of other candidates; the engine fills
+	// it from Affinity
+	PreferredAgents []string `json:"preferred_agents,omitempty"`
+
+	// ExcludedAgents are never chosen; the engine fills it from AntiAffinity
+	ExcludedAgents []string `json:"excluded_agents,omitempty"`
 }
 
 // TaskCondition defines when a task should execute
@@ -204,6 +270,9 @@ type WorkflowConfiguration struct {
 	// DefaultTimeout for tasks without explicit timeout
 	DefaultTimeout time.Duration `json:"default_timeout"`
 
+	// Timeout stops executions running longer, overriding the engine's DefaultWorkflowTimeout
+	Timeout time.Duration `json:"timeout,omitempty"`
+
 	// FailurePolicy defines workflow behavior on task failures
 	FailurePolicy FailurePolicy `json:"failure_policy"`
 
@@ -217,7 +286,7 @@ type WorkflowConfiguration struct {
 // FailurePolicy defines workflow failure handling
 type FailurePolicy struct {
 	// OnTaskFailure specifies action when a task fails
-	OnTaskFailure string `json:"on_task_failure"` // "stop", "continue", "retry_workflow"
+	OnTaskFailure string `json:"on_task_failure"` // "stop", "continue", "retry_workflow", "rollback"
 
 	// MaxFailedTasks before stopping workflow
 	MaxFailedTasks int `json:"max_failed_tasks"`
@@ -261,6 +330,10 @@ type WorkflowExecution struct {
 	// WorkflowID references the workflow definition
 	WorkflowID string `json:"workflow_id"`
 
+	// WorkflowVersion is the workflow version the execution runs. It is pinned
+	// when the execution starts and only changes by migration.
+	WorkflowVersion string `json:"workflow_version,omitempty"`
+
 	// Status indicates current execution state
 	Status WorkflowStatus `json:"status"`
 
@@ -290,6 +363,12 @@ type WorkflowExecution struct {
 
 	// AgentsUsed tracks which agents participated in execution
 	AgentsUsed []string `json:"agents_used"`
+
+	// Progress is the overall percent complete, including checkpointed progress of running tasks
+	Progress float64 `json:"progress"`
+
+	// Timeout is how long the execution may run before it is stopped (zero for no limit)
+	Timeout time.Duration `json:"timeout,omitempty"`
 }
 
 // TaskExecution represents the execution of a single workflow task
@@ -324,6 +403,24 @@ type TaskExecution struct {
 	// Logs capture task execution logs
 	Logs []string `json:"logs"`
 
+	// Inputs are the task's resolved input mapping
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+
+	// Iterations is the number of times a loop task ran
+	Iterations int `json:"iterations,omitempty"`
+
+	// SkippedByBranch is the branch task whose untaken path skipped this task
+	SkippedByBranch string `json:"skipped_by_branch,omitempty"`
+
+	// Compensation tracks the compensation run when the workflow rolled back
+	Compensation *TaskExecution `json:"compensation,omitempty"`
+
+	// Progress is the latest checkpoint the agent saved for a long-running task
+	Progress *TaskProgress `json:"progress,omitempty"`
+
+	// Approval records the decision on a manual approval task
+	Approval *TaskApproval `json:"approval,omitempty"`
+
 	// ResourceUsage tracks actual resource consumption
 	ResourceUsage ResourceUsage `json:"resource_usage"`
 }
@@ -392,6 +489,24 @@ type WorkflowEngine interface {
 
 	// RetryExecution restarts a failed workflow execution
 	RetryExecution(ctx context.Context, executionID string) (*WorkflowExecution, error)
+
+	// WatchExecution streams the events of a running workflow execution
+	WatchExecution(ctx context.Context, executionID string) (<-chan *ExecutionEvent, error)
+
+	// ListWorkflowVersions returns every version of a workflow, newest first
+	ListWorkflowVersions(ctx context.Context, workflowID string) ([]*Workflow, error)
+
+	// MigrateExecution moves a paused execution to another version of its workflow
+	MigrateExecution(ctx context.Context, executionID, version string) (*WorkflowExecution, error)
+
+	// ExportFixture exports a finished execution for replay
+	ExportFixture(ctx context.Context, executionID string) (*ExecutionFixture, error)
+
+	// ApproveTask records a person's decision on a manual approval task
+	ApproveTask(ctx context.Context, executionID, taskID string, decision TaskApproval) error
+
+	// WritePrometheusMetrics writes the engine's metrics in the Prometheus text format
+	WritePrometheusMetrics(w io.Writer) error
 }
 
 // AgentCoordinator defines the interface for agent coordination
@@ -402,6 +517,12 @@ type AgentCoordinator interface {
 	// AssignTask assigns a task to a specific agent
 	AssignTask(ctx context.Context, agentID string, task *WorkflowTask, execution *WorkflowExecution) error
 
+	// DispatchTask submits an assigned task to the agent for execution
+	DispatchTask(ctx context.Context, agentID string, task agent.Task) error
+
+	// OnTaskCompleted registers a handler notified when a dispatched task completes
+	OnTaskCompleted(handler func(*agent.TaskResult))
+
 	// GetAgentLoad returns current load information for an agent
 	GetAgentLoad(ctx context.Context, agentID string) (*AgentLoad, error)
 
@@ -428,6 +549,9 @@ type ExecutionMonitor interface {
 
 	// WatchExecution provides real-time execution updates
 	WatchExecution(ctx context.Context, executionID string) (<-chan *ExecutionEvent, error)
+
+	// RecordTaskTransition reports a task's change from its previous status
+	RecordTaskTransition(ctx context.Context, executionID string, taskExecution *TaskExecution, previous TaskStatus) error
 }
 
 // WorkflowRepository defines the interface for workflow persistence
@@ -435,9 +559,15 @@ type WorkflowRepository interface {
 	// StoreWorkflow saves a workflow definition
 	StoreWorkflow(ctx context.Context, workflow *Workflow) error
 
-	// GetWorkflow retrieves a workflow by ID
+	// GetWorkflow retrieves the latest version of a workflow by ID
 	GetWorkflow(ctx context.Context, workflowID string) (*Workflow, error)
 
+	// GetWorkflowVersion retrieves a specific version of a workflow
+	GetWorkflowVersion(ctx context.Context, workflowID, version string) (*Workflow, error)
+
+	// ListWorkflowVersions returns every version of a workflow, newest first
+	ListWorkflowVersions(ctx context.Context, workflowID string) ([]*Workflow, error)
+
 	// ListWorkflows returns workflows with filtering
 	ListWorkflows(ctx context.Context, filters WorkflowFilters) ([]*Workflow, error)
 
@@ -455,6 +585,9 @@ type WorkflowRepository interface {
 
 	// UpdateExecution modifies execution data
 	UpdateExecution(ctx context.Context, execution *WorkflowExecution) error
+
+	// ListExecutions returns executions matching the filters, newest first
+	ListExecutions(ctx context.Context, filters ExecutionFilters) ([]*WorkflowExecution, error)
 }
 
 // ExecutionFilters defines filters for querying workflow executions
@@ -474,7 +607,10 @@ type ExecutionFilters struct {
 	// TriggeredBy filters by trigger source
 	TriggeredBy string
 
-	// Limit limits number of results
+	// AgentID filters by an agent used in the execution
+	AgentID string
+
+	// Limit limits number of results (default 50, at most 500)
 	Limit int
 
 	// Offset for pagination
@@ -553,22 +689,22 @@ type ExecutionProgress struct {
 // ExecutionEvent represents real-time execution events
 type ExecutionEvent struct {
 	// ExecutionID identifies the execution
-	ExecutionID string
+	ExecutionID string `json:"execution_id"`
 
 	// Type specifies the event type
-	Type string
+	Type string `json:"type"`
 
 	// TaskID for task-specific events
-	TaskID string
+	TaskID string `json:"task_id,omitempty"`
 
 	// AgentID for agent-specific events
-	AgentID string
+	AgentID string `json:"agent_id,omitempty"`
 
 	// Data contains event-specific information
-	Data map[string]interface{}
+	Data map[string]interface{} `json:"data,omitempty"`
 
 	// Timestamp when event occurred
-	Timestamp time.Time
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // OrchestrationConfig configures the orchestration system
@@ -585,6 +721,27 @@ type OrchestrationConfig struct {
 	// HealthCheckInterval for monitoring agent health
 	HealthCheckInterval time.Duration
 
+	// WorkerCount is the number of workers running tasks (default DefaultWorkerCount)
+	WorkerCount int
+
+	// TaskQueueSize is the capacity of the queue of tasks ready to run (default DefaultTaskQueueSize)
+	TaskQueueSize int
+
+	// CompletionQueueSize is the capacity of the queue of agent task results (default DefaultCompletionQueueSize)
+	CompletionQueueSize int
+
+	// QueueFullPolicy decides whether a task waits for room in a full task
+	// queue or fails (default QueueFullBlock)
+	QueueFullPolicy QueueFullPolicy
+
+	// InstanceID identifies this engine instance as the owner of execution
+	// leases (default a random ID)
+	InstanceID string
+
+	// LeaseTTL is how long an execution lease lasts without renewal (default
+	// DefaultLeaseTTL). Leases are renewed three times per TTL.
+	LeaseTTL time.Duration
+
 	// MetricsCollection configuration
 	MetricsCollection MetricsConfig
 
@@ -0,0 +1,145 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrExecutionMigrated is the cause of an execution's context when it is
+	// moved to another workflow version
+	ErrExecutionMigrated = errors.New("execution migrated to another workflow version")
+
+	// ErrExecutionNotPaused is returned for migrations of executions that are
+	// not paused on this instance, or still have tasks running
+	ErrExecutionNotPaused = errors.New("execution is not paused")
+
+	// ErrIncompatibleVersion is returned for migrations to a workflow version
+	// missing tasks the execution already started
+	ErrIncompatibleVersion = errors.New("incompatible workflow version")
+)
+
+// ListWorkflowVersions returns every version of a workflow, newest first
+func (e *Engine) ListWorkflowVersions(ctx context.Context, workflowID string) ([]*Workflow, error) {
+	return e.repository.ListWorkflowVersions(ctx, workflowID)
+}
+
+// workflowForExecution loads the workflow version an execution is pinned to.
+// Executions started before versioning run the latest version.
+func (e *Engine) workflowForExecution(ctx context.Context, execution *WorkflowExecution) (*Workflow, error) {
+	if execution.WorkflowVersion == "" {
+		return e.repository.GetWorkflow(ctx, execution.WorkflowID)
+	}
+	return e.repository.GetWorkflowVersion(ctx, execution.WorkflowID, execution.WorkflowVersion)
+}
+
+// MigrateExecution moves a paused execution to another version of its
+// workflow. Every task the execution started must exist in that version;
+// pending tasks the version removed are dropped and tasks it added start
+// pending. The execution stays paused until resumed.
+func (e *Engine) MigrateExecution(ctx context.Context, executionID, version string) (*WorkflowExecution, error) {
+	e.executionMutex.RLock()
+	execution, exists := e.activeExecutions[executionID]
+	e.executionMutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotPaused, executionID)
+	}
+
+	var workflowID, previousVersion string
+	var err error
+	e.withState(execution, func() {
+		workflowID = execution.WorkflowID
+		previousVersion = execution.WorkflowVersion
+		if execution.Status != WorkflowStatusPaused {
+			err = fmt.Errorf("%w: %s", ErrExecutionNotPaused, executionID)
+			return
+		}
+		for taskID, taskExecution := range execution.TaskExecutions {
+			switch taskExecution.Status {
+			case TaskStatusQueued, TaskStatusRunning, TaskStatusRetrying:
+				err = fmt.Errorf("%w: task %s is still %s", ErrExecutionNotPaused, taskID, taskExecution.Status)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workflow, err := e.repository.GetWorkflowVersion(ctx, workflowID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow version: %w", err)
+	}
+	if err := e.validateWorkflow(workflow); err != nil {
+		return nil, fmt.Errorf("workflow validation failed: %w", err)
+	}
+
+	taskIDs := make(map[string]bool, len(workflow.Tasks))
+	for _, task := range workflow.Tasks {
+		taskIDs[task.ID] = true
+	}
+	e.withState(execution, func() {
+		for taskID, taskExecution := range execution.TaskExecutions {
+			if taskExecution.Status != TaskStatusPending && !taskIDs[taskID] {
+				err = fmt.Errorf("%w: version %s has no task %s", ErrIncompatibleVersion, version, taskID)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop the execution's run on the old version, keeping the task statuses
+	// already reported so that finished tasks are not reported again
+	e.statusMutex.Lock()
+	reported := e.reportedStatuses[executionID]
+	e.statusMutex.Unlock()
+
+	execution, stopped := e.stopExecution(executionID, WorkflowStatusPaused, ErrExecutionMigrated)
+	if !stopped {
+		return nil, fmt.Errorf("%w: %s", ErrExecutionNotPaused, executionID)
+	}
+
+	e.withState(execution, func() {
+		for taskID := range execution.TaskExecutions {
+			if !taskIDs[taskID] {
+				delete(execution.TaskExecutions, taskID)
+				delete(reported, taskID)
+			}
+		}
+		for _, task := range workflow.Tasks {
+			if _, exists := execution.TaskExecutions[task.ID]; !exists {
+				execution.TaskExecutions[task.ID] = &TaskExecution{
+					TaskID:        task.ID,
+					Status:        TaskStatusPending,
+					Output:        make(map[string]interface{}),
+					Logs:          make([]string, 0),
+					ResourceUsage: ResourceUsage{},
+				}
+			}
+		}
+		execution.WorkflowVersion = workflow.Version
+		execution.Metrics.TotalTasks = len(workflow.Tasks)
+	})
+
+	if reported != nil {
+		e.statusMutex.Lock()
+		e.reportedStatuses[executionID] = reported
+		e.statusMutex.Unlock()
+	}
+
+	e.launchExecution(e.ctx, workflow, execution)
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": executionID,
+		"workflow_id":  workflowID,
+		"from_version": previousVersion,
+		"to_version":   workflow.Version,
+	}).Info("Workflow execution migrated")
+
+	return execution, nil
+}
@@ -0,0 +1,139 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestEngine_MigrateExecution(t *testing.T) {
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		if task.Payload.(map[string]interface{})["task_id"] == "fetch" {
+			close(started)
+			<-release
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	repository := newSharedRepository()
+	v1 := &Workflow{
+		ID:           "wf-1",
+		Version:      "1",
+		Tasks:        []WorkflowTask{{ID: "fetch", Type: "http_request"}, {ID: "report", Type: "data_processing"}},
+		Dependencies: map[string][]string{"report": {"fetch"}},
+	}
+	v2 := &Workflow{
+		ID:           "wf-1",
+		Version:      "2",
+		Tasks:        []WorkflowTask{{ID: "fetch", Type: "http_request"}, {ID: "notify", Type: "notification"}},
+		Dependencies: map[string][]string{"notify": {"fetch"}},
+	}
+	v3 := &Workflow{ID: "wf-1", Version: "3", Tasks: []WorkflowTask{{ID: "notify", Type: "notification"}}}
+	repository.workflows["wf-1"] = []*Workflow{v1, v2, v3}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	engine := NewEngine(OrchestrationConfig{}, coordinator, &fakeMonitor{}, repository, logger)
+	if err := engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Stop()
+
+	versions, err := engine.ListWorkflowVersions(ctx, "wf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != "3" {
+		t.Errorf("expected the versions newest first, got %d", len(versions))
+	}
+
+	// The execution is pinned to the version it started with
+	execution, err := engine.ExecuteWorkflow(ctx, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.WorkflowVersion != "1" {
+		t.Errorf("expected version 1 to be pinned, got %q", execution.WorkflowVersion)
+	}
+	<-started
+
+	// Only paused executions without running tasks migrate
+	if _, err := engine.MigrateExecution(ctx, execution.ID, "2"); !errors.Is(err, ErrExecutionNotPaused) {
+		t.Errorf("expected ErrExecutionNotPaused for a running execution, got %v", err)
+	}
+	if err := engine.PauseExecution(ctx, execution.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.MigrateExecution(ctx, execution.ID, "2"); !errors.Is(err, ErrExecutionNotPaused) {
+		t.Errorf("expected ErrExecutionNotPaused while fetch runs, got %v", err)
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, err := repository.GetExecution(ctx, execution.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.TaskExecutions["fetch"].Status == TaskStatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected fetch to complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Versions without the tasks already run are incompatible
+	if _, err := engine.MigrateExecution(ctx, execution.ID, "3"); !errors.Is(err, ErrIncompatibleVersion) {
+		t.Errorf("expected ErrIncompatibleVersion, got %v", err)
+	}
+
+	migrated, err := engine.MigrateExecution(ctx, execution.ID, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.WorkflowVersion != "2" || migrated.Status != WorkflowStatusPaused {
+		t.Errorf("expected a paused execution on version 2, got %s on %q", migrated.Status, migrated.WorkflowVersion)
+	}
+	if err := engine.ResumeExecution(ctx, execution.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		stored, err := repository.GetExecution(ctx, execution.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status == WorkflowStatusCompleted {
+			if _, exists := stored.TaskExecutions["report"]; exists {
+				t.Error("expected the removed pending task to be dropped")
+			}
+			if stored.TaskExecutions["notify"].Status != TaskStatusCompleted {
+				t.Errorf("expected the added task to run, got %s", stored.TaskExecutions["notify"].Status)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the migrated execution to complete, got %s", stored.Status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	coordinator.mu.Lock()
+	dispatched := len(coordinator.dispatched)
+	coordinator.mu.Unlock()
+	if dispatched != 2 {
+		t.Errorf("expected fetch and notify to run once each, got %d dispatches", dispatched)
+	}
+}
@@ -0,0 +1,164 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Worker pool defaults
+const (
+	DefaultWorkerCount         = 10
+	DefaultTaskQueueSize       = 1000
+	DefaultCompletionQueueSize = 1000
+)
+
+// QueueFullPolicy decides what happens to a task when the task queue is full
+type QueueFullPolicy string
+
+const (
+	// QueueFullBlock makes the task wait until the queue has room
+	QueueFullBlock QueueFullPolicy = "block"
+
+	// QueueFullReject fails the task with ErrTaskQueueFull
+	QueueFullReject QueueFullPolicy = "reject"
+)
+
+var (
+	// ErrTaskQueueFull is returned for tasks rejected because the task queue is full
+	ErrTaskQueueFull = errors.New("task queue is full")
+
+	// ErrEngineStopped is returned for tasks still queued when the engine stops
+	ErrEngineStopped = errors.New("workflow engine stopped")
+)
+
+// EngineMetrics reports the load of the engine's worker pool
+type EngineMetrics struct {
+	Workers                 int   `json:"workers"`
+	TaskQueueDepth          int   `json:"task_queue_depth"`
+	TaskQueueCapacity       int   `json:"task_queue_capacity"`
+	CompletionQueueDepth    int   `json:"completion_queue_depth"`
+	CompletionQueueCapacity int   `json:"completion_queue_capacity"`
+	ActiveExecutions        int   `json:"active_executions"`
+	RejectedTasks           int64 `json:"rejected_tasks"`
+}
+
+// queuedTask is a task waiting for a worker
+type queuedTask struct {
+	ctx       context.Context
+	task      *WorkflowTask
+	execution *WorkflowExecution
+	done      chan error
+}
+
+// ConfigFromConfig converts application config into the engine's worker pool
+// settings. Unset values take the worker pool defaults.
+func ConfigFromConfig(cfg config.OrchestrationConfig) OrchestrationConfig {
+	return OrchestrationConfig{
+		WorkerCount:         cfg.WorkerCount,
+		TaskQueueSize:       cfg.TaskQueueSize,
+		CompletionQueueSize: cfg.CompletionQueueSize,
+		QueueFullPolicy:     QueueFullPolicy(cfg.QueueFullPolicy),
+	}
+}
+
+// withDefaults fills in unset worker pool and lease settings
+func withDefaults(config OrchestrationConfig) OrchestrationConfig {
+	if config.WorkerCount <= 0 {
+		config.WorkerCount = DefaultWorkerCount
+	}
+	if config.TaskQueueSize <= 0 {
+		config.TaskQueueSize = DefaultTaskQueueSize
+	}
+	if config.CompletionQueueSize <= 0 {
+		config.CompletionQueueSize = DefaultCompletionQueueSize
+	}
+	if config.QueueFullPolicy == "" {
+		config.QueueFullPolicy = QueueFullBlock
+	}
+	if config.InstanceID == "" {
+		config.InstanceID = uuid.New().String()
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DefaultLeaseTTL
+	}
+	return config
+}
+
+// runTask queues a task for the worker pool and waits for it to finish. When
+// the queue is full the task waits for room, or fails under QueueFullReject.
+// Manual approval tasks run outside the pool.
+func (e *Engine) runTask(ctx context.Context, task *WorkflowTask, execution *WorkflowExecution) error {
+	// Approvals wait for a person and would hold a worker for as long
+	if task.Type == TaskTypeManualApproval {
+		return e.executeTask(ctx, task, execution)
+	}
+
+	queued := &queuedTask{ctx: ctx, task: task, execution: execution, done: make(chan error, 1)}
+
+	if e.config.QueueFullPolicy == QueueFullReject {
+		select {
+		case e.taskQueue <- queued:
+		default:
+			atomic.AddInt64(&e.rejectedTasks, 1)
+			err := fmt.Errorf("%w: task %s rejected", ErrTaskQueueFull, task.ID)
+			e.rejectTask(ctx, task, execution, err)
+			return err
+		}
+	} else {
+		select {
+		case e.taskQueue <- queued:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-e.ctx.Done():
+			return ErrEngineStopped
+		}
+	}
+
+	select {
+	case err := <-queued.done:
+		return err
+	case <-e.ctx.Done():
+		return ErrEngineStopped
+	}
+}
+
+// rejectTask fails a task that could not be queued
+func (e *Engine) rejectTask(ctx context.Context, task *WorkflowTask, execution *WorkflowExecution, err error) {
+	e.withState(execution, func() {
+		taskExecution := execution.TaskExecutions[task.ID]
+		now := e.clock.Now()
+		taskExecution.StartTime = now
+		taskExecution.EndTime = &now
+		taskExecution.Status = TaskStatusFailed
+		taskExecution.Error = err.Error()
+		e.updateExecution(ctx, execution)
+	})
+
+	e.logger.WithFields(log.Fields{
+		"execution_id": execution.ID,
+		"task_id":      task.ID,
+	}).Warn("Task queue full, rejected task")
+}
+
+// GetMetrics returns the current worker pool metrics
+func (e *Engine) GetMetrics() EngineMetrics {
+	e.executionMutex.RLock()
+	active := len(e.activeExecutions)
+	e.executionMutex.RUnlock()
+
+	return EngineMetrics{
+		Workers:                 e.config.WorkerCount,
+		TaskQueueDepth:          len(e.taskQueue),
+		TaskQueueCapacity:       cap(e.taskQueue),
+		CompletionQueueDepth:    len(e.completionQueue),
+		CompletionQueueCapacity: cap(e.completionQueue),
+		ActiveExecutions:        active,
+		RejectedTasks:           atomic.LoadInt64(&e.rejectedTasks),
+	}
+}
@@ -0,0 +1,141 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// queueTest is an engine with a single worker busy running fetch and enrich
+// waiting in its full queue
+type queueTest struct {
+	engine    *Engine
+	tasks     map[string]*WorkflowTask
+	execution *WorkflowExecution
+	release   chan struct{}
+	results   chan error
+}
+
+func newQueueTest(t *testing.T, policy QueueFullPolicy) *queueTest {
+	ctx := context.Background()
+	started := make(chan struct{})
+	q := &queueTest{release: make(chan struct{}), results: make(chan error, 2)}
+
+	coordinator := &fakeCoordinator{agents: []*agent.Agent{{ID: "analyst-1"}}}
+	coordinator.complete = func(task agent.Task) *agent.TaskResult {
+		if task.Payload.(map[string]interface{})["task_id"] == "fetch" {
+			close(started)
+			<-q.release
+		}
+		return &agent.TaskResult{Success: true}
+	}
+
+	logger := log.New()
+	logger.SetOutput(io.Discard)
+	repository := &fakeRepository{statuses: make(map[string]WorkflowStatus)}
+	config := OrchestrationConfig{WorkerCount: 1, TaskQueueSize: 1, QueueFullPolicy: policy}
+	q.engine = NewEngine(config, coordinator, nil, repository, logger)
+	if err := q.engine.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.engine.Stop() })
+
+	q.execution = &WorkflowExecution{ID: "exec-1", TaskExecutions: make(map[string]*TaskExecution)}
+	q.tasks = make(map[string]*WorkflowTask)
+	for _, id := range []string{"fetch", "enrich", "report", "archive"} {
+		q.tasks[id] = &WorkflowTask{ID: id, Type: "data_processing"}
+		q.execution.TaskExecutions[id] = &TaskExecution{TaskID: id, Status: TaskStatusPending, Output: make(map[string]interface{})}
+	}
+
+	// The only worker runs fetch and enrich waits in the queue
+	go func() { q.results <- q.engine.runTask(ctx, q.tasks["fetch"], q.execution) }()
+	<-started
+	go func() { q.results <- q.engine.runTask(ctx, q.tasks["enrich"], q.execution) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.engine.GetMetrics().TaskQueueDepth != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected enrich to be queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return q
+}
+
+// finish releases fetch and checks the queued tasks complete
+func (q *queueTest) finish(t *testing.T) {
+	close(q.release)
+	for i := 0; i < 2; i++ {
+		if err := <-q.results; err != nil {
+			t.Errorf("expected queued tasks to complete, got %v", err)
+		}
+	}
+	q.engine.withState(q.execution, func() {
+		if enrich := q.execution.TaskExecutions["enrich"]; enrich.Status != TaskStatusCompleted {
+			t.Errorf("expected enrich to complete, got %s", enrich.Status)
+		}
+	})
+}
+
+func TestEngine_TaskQueueBackpressure(t *testing.T) {
+	q := newQueueTest(t, QueueFullReject)
+
+	// A full queue rejects further tasks
+	if err := q.engine.runTask(context.Background(), q.tasks["report"], q.execution); !errors.Is(err, ErrTaskQueueFull) {
+		t.Errorf("expected ErrTaskQueueFull, got %v", err)
+	}
+	q.engine.withState(q.execution, func() {
+		if report := q.execution.TaskExecutions["report"]; report.Status != TaskStatusFailed {
+			t.Errorf("expected the rejected task to fail, got %s", report.Status)
+		}
+	})
+	metrics := q.engine.GetMetrics()
+	if metrics.RejectedTasks != 1 || metrics.Workers != 1 || metrics.TaskQueueCapacity != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+
+	q.finish(t)
+}
+
+func TestEngine_TaskQueueBlocksWhenFull(t *testing.T) {
+	q := newQueueTest(t, QueueFullBlock)
+
+	// Under the block policy a task waits for room until its context ends
+	waitCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.engine.runTask(waitCtx, q.tasks["archive"], q.execution); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the blocked task to give up with its context, got %v", err)
+	}
+	if metrics := q.engine.GetMetrics(); metrics.RejectedTasks != 0 {
+		t.Errorf("expected no rejected tasks, got %d", metrics.RejectedTasks)
+	}
+
+	q.finish(t)
+}
+
+func TestConfigFromConfig(t *testing.T) {
+	engineConfig := withDefaults(ConfigFromConfig(config.OrchestrationConfig{WorkerCount: 4, QueueFullPolicy: "reject"}))
+	if engineConfig.WorkerCount != 4 || engineConfig.QueueFullPolicy != QueueFullReject {
+		t.Errorf("expected 4 workers rejecting tasks when full, got %d workers and %q", engineConfig.WorkerCount, engineConfig.QueueFullPolicy)
+	}
+	if engineConfig.TaskQueueSize != DefaultTaskQueueSize || engineConfig.CompletionQueueSize != DefaultCompletionQueueSize {
+		t.Errorf("expected default queue sizes, got %d and %d", engineConfig.TaskQueueSize, engineConfig.CompletionQueueSize)
+	}
+
+	coordinatorConfig := CoordinatorConfigFromConfig(config.OrchestrationConfig{
+		AssignmentRateLimits:       map[string]int{"valve": 2, "pump": 4},
+		DefaultAssignmentRateLimit: 30,
+	})
+	if coordinatorConfig.AssignmentRateLimits["valve"] != 2 || coordinatorConfig.DefaultAssignmentRateLimit != 30 {
+		t.Errorf("expected the configured assignment rate limits, got %+v", coordinatorConfig)
+	}
+	if coordinatorConfig.MaxTasksPerAgent != DefaultCoordinatorConfig().MaxTasksPerAgent {
+		t.Errorf("expected the other coordinator settings to keep their defaults, got %+v", coordinatorConfig)
+	}
+}
@@ -0,0 +1,284 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval    = 10 * time.Second
+	defaultBatchSize   = 50
+	defaultMaxAttempts = 10
+	defaultBaseBackoff = 5 * time.Second
+	defaultMaxBackoff  = 15 * time.Minute
+)
+
+// Handler delivers one entry. Returning an error schedules a retry unless
+// the error is wrapped with Permanent.
+type Handler func(ctx context.Context, entry *Entry) error
+
+// Config configures outbox delivery
+type Config struct {
+	// Interval is how often pending entries are retried
+	Interval time.Duration
+
+	// BatchSize caps the entries retried per pass
+	BatchSize int
+
+	// MaxAttempts is the number of attempts before an entry fails
+	MaxAttempts int
+
+	// BaseBackoff is the delay after the first failed attempt; it doubles
+	// with every further attempt up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// ChangeWebhooks are notified of committed agency design changes
+	ChangeWebhooks []string
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.OutboxConfig) Config {
+	return Config{
+		Interval:       time.Duration(cfg.IntervalSeconds) * time.Second,
+		BatchSize:      cfg.BatchSize,
+		MaxAttempts:    cfg.MaxAttempts,
+		BaseBackoff:    time.Duration(cfg.BaseBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+		ChangeWebhooks: cfg.ChangeWebhooks,
+	}
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = defaultInterval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaultBaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	return c
+}
+
+// Dispatcher delivers outbox entries to the handler registered for their kind
+type Dispatcher struct {
+	store  Store
+	config Config
+	logger *log.Logger
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	inflight map[string]bool
+	cancel   context.CancelFunc
+	stopped  chan struct{}
+}
+
+// NewDispatcher creates a new outbox dispatcher
+func NewDispatcher(store Store, cfg Config, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		config:   cfg.withDefaults(),
+		logger:   logger,
+		handlers: make(map[string]Handler),
+		inflight: make(map[string]bool),
+	}
+}
+
+// Register sets the handler for a kind of entry
+func (d *Dispatcher) Register(kind string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = handler
+}
+
+// ChangeNotifications returns webhook entries announcing a committed change
+// to every configured change webhook. Attach them to the change with
+// WithEntries and Deliver them once it succeeds.
+func (d *Dispatcher) ChangeNotifications(agencyID, event string, data map[string]interface{}) []*Entry {
+	entries := make([]*Entry, 0, len(d.config.ChangeWebhooks))
+	for _, url := range d.config.ChangeWebhooks {
+		entries = append(entries, NewWebhookEntry(agencyID, url, event, data))
+	}
+	return entries
+}
+
+// Enqueue stores entries that are not tied to a data change and delivers them
+func (d *Dispatcher) Enqueue(ctx context.Context, entries ...*Entry) error {
+	if err := d.store.Enqueue(ctx, entries...); err != nil {
+		return fmt.Errorf("failed to enqueue outbox entries: %w", err)
+	}
+	d.Deliver(ctx, entries...)
+	return nil
+}
+
+// Deliver makes one delivery attempt for each entry, typically right after
+// the change they were committed with. Entries that fail stay pending and
+// are retried by the background loop.
+func (d *Dispatcher) Deliver(ctx context.Context, entries ...*Entry) {
+	for _, entry := range entries {
+		d.deliver(ctx, entry)
+	}
+}
+
+// DeliverDue retries the pending entries that are due and returns how many
+// were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	entries, err := d.store.Due(ctx, time.Now().UTC(), d.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read due outbox entries: %w", err)
+	}
+	for _, entry := range entries {
+		d.deliver(ctx, entry)
+	}
+	return len(entries), nil
+}
+
+// Retry makes a failed entry pending again and attempts it
+func (d *Dispatcher) Retry(ctx context.Context, agencyID, id string) (*Entry, error) {
+	entry, err := d.store.Get(ctx, agencyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != StatusFailed {
+		return nil, fmt.Errorf("%w: %s is %s", ErrNotFailed, id, entry.Status)
+	}
+
+	entry.Status = StatusPending
+	entry.Attempts = 0
+	entry.NextAttemptAt = time.Now().UTC()
+	d.deliver(ctx, entry)
+	return entry, nil
+}
+
+// List returns stored entries, newest first
+func (d *Dispatcher) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	return d.store.List(ctx, filter)
+}
+
+// deliver runs the entry's handler and records the outcome. Entries already
+// being delivered by another caller are skipped.
+func (d *Dispatcher) deliver(ctx context.Context, entry *Entry) {
+	d.mu.Lock()
+	if d.inflight[entry.ID] {
+		d.mu.Unlock()
+		return
+	}
+	d.inflight[entry.ID] = true
+	handler := d.handlers[entry.Kind]
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.inflight, entry.ID)
+		d.mu.Unlock()
+	}()
+
+	var err error
+	if handler == nil {
+		err = fmt.Errorf("no handler registered for %q entries", entry.Kind)
+	} else {
+		err = handler(ctx, entry)
+	}
+
+	now := time.Now().UTC()
+	entry.Attempts++
+	logger := d.logger.WithFields(log.Fields{
+		"entry_id":  entry.ID,
+		"agency_id": entry.AgencyID,
+		"kind":      entry.Kind,
+		"attempts":  entry.Attempts,
+	})
+
+	switch {
+	case err == nil:
+		entry.Status = StatusDelivered
+		entry.LastError = ""
+		entry.DeliveredAt = &now
+	case IsPermanent(err) || entry.Attempts >= d.config.MaxAttempts:
+		entry.Status = StatusFailed
+		entry.LastError = err.Error()
+		logger.WithError(err).Error("Outbox entry failed")
+	default:
+		entry.LastError = err.Error()
+		entry.NextAttemptAt = now.Add(d.backoff(entry.Attempts))
+		logger.WithError(err).Warn("Outbox delivery failed, will retry")
+	}
+
+	// Use a fresh context so the outcome is saved even if the request ended
+	if updateErr := d.store.Update(context.WithoutCancel(ctx), entry); updateErr != nil {
+		logger.WithError(updateErr).Error("Failed to save outbox entry state")
+	}
+}
+
+// backoff returns the delay before the next attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+	return delay
+}
+
+// Start retries pending entries in the background
+func (d *Dispatcher) Start(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	d.cancel = cancel
+	d.stopped = make(chan struct{})
+
+	go func() {
+		defer close(d.stopped)
+
+		ticker := time.NewTicker(d.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := d.DeliverDue(ctx); err != nil {
+					d.logger.WithError(err).Warn("Failed to retry outbox entries")
+				}
+			}
+		}
+	}()
+
+	d.logger.WithField("interval", d.config.Interval).Info("Outbox dispatcher started")
+}
+
+// Stop stops background retries
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	cancel, stopped := d.cancel, d.stopped
+	d.cancel = nil
+	d.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-stopped
+	}
+}
@@ -0,0 +1,206 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func newTestDispatcher(cfg Config) (*Dispatcher, *InMemoryStore) {
+	logger := log.New()
+	logger.SetLevel(log.PanicLevel)
+	store := NewInMemoryStore()
+	return NewDispatcher(store, cfg, logger), store
+}
+
+func TestDispatcher_EnqueueDelivers(t *testing.T) {
+	d, store := newTestDispatcher(Config{})
+	var delivered []string
+	d.Register("test", func(ctx context.Context, entry *Entry) error {
+		delivered = append(delivered, entry.ID)
+		return nil
+	})
+
+	entry := NewEntry("agency-1", "test", nil)
+	if err := d.Enqueue(context.Background(), entry); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if len(delivered) != 1 || delivered[0] != entry.ID {
+		t.Fatalf("delivered = %v, want [%s]", delivered, entry.ID)
+	}
+	stored, err := store.Get(context.Background(), "agency-1", entry.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != StatusDelivered || stored.DeliveredAt == nil || stored.Attempts != 1 {
+		t.Errorf("stored = %+v, want delivered after one attempt", stored)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	d, store := newTestDispatcher(Config{BaseBackoff: time.Minute, MaxBackoff: 3 * time.Minute, MaxAttempts: 5})
+	failures := 2
+	d.Register("test", func(ctx context.Context, entry *Entry) error {
+		if failures > 0 {
+			failures--
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	entry := NewEntry("agency-1", "test", nil)
+	start := time.Now().UTC()
+	if err := d.Enqueue(context.Background(), entry); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	stored, _ := store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Status != StatusPending || stored.LastError != "unavailable" {
+		t.Fatalf("stored = %+v, want pending with last error", stored)
+	}
+	if stored.NextAttemptAt.Before(start.Add(time.Minute)) {
+		t.Errorf("next attempt %v is before base backoff", stored.NextAttemptAt)
+	}
+
+	// Nothing is due until the backoff has passed
+	if n, _ := d.DeliverDue(context.Background()); n != 0 {
+		t.Fatalf("DeliverDue attempted %d entries before backoff, want 0", n)
+	}
+
+	stored.NextAttemptAt = start
+	store.Update(context.Background(), stored)
+	if n, _ := d.DeliverDue(context.Background()); n != 1 {
+		t.Fatalf("DeliverDue attempted %d entries, want 1", n)
+	}
+	stored, _ = store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Attempts != 2 || stored.NextAttemptAt.Before(start.Add(2*time.Minute)) {
+		t.Errorf("stored = %+v, want second attempt with doubled backoff", stored)
+	}
+
+	stored.NextAttemptAt = start
+	store.Update(context.Background(), stored)
+	d.DeliverDue(context.Background())
+	stored, _ = store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Status != StatusDelivered || stored.Attempts != 3 {
+		t.Errorf("stored = %+v, want delivered on third attempt", stored)
+	}
+}
+
+func TestDispatcher_FailsPermanentlyAndRetry(t *testing.T) {
+	d, store := newTestDispatcher(Config{})
+	permanent := true
+	d.Register("test", func(ctx context.Context, entry *Entry) error {
+		if permanent {
+			return Permanent(errors.New("rejected"))
+		}
+		return nil
+	})
+
+	entry := NewEntry("agency-1", "test", nil)
+	d.Enqueue(context.Background(), entry)
+	stored, _ := store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Status != StatusFailed || stored.Attempts != 1 {
+		t.Fatalf("stored = %+v, want failed after one attempt", stored)
+	}
+
+	permanent = false
+	retried, err := d.Retry(context.Background(), "agency-1", entry.ID)
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if retried.Status != StatusDelivered {
+		t.Errorf("retried status = %s, want delivered", retried.Status)
+	}
+
+	if _, err := d.Retry(context.Background(), "agency-1", entry.ID); !errors.Is(err, ErrNotFailed) {
+		t.Errorf("Retry of delivered entry err = %v, want ErrNotFailed", err)
+	}
+	if _, err := d.Retry(context.Background(), "agency-2", entry.ID); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Retry from other agency err = %v, want ErrEntryNotFound", err)
+	}
+}
+
+func TestDispatcher_FailsAfterMaxAttempts(t *testing.T) {
+	d, store := newTestDispatcher(Config{MaxAttempts: 2})
+	d.Register("test", func(ctx context.Context, entry *Entry) error {
+		return errors.New("unavailable")
+	})
+
+	entry := NewEntry("agency-1", "test", nil)
+	d.Enqueue(context.Background(), entry)
+	d.Deliver(context.Background(), entry)
+
+	stored, _ := store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Status != StatusFailed || stored.Attempts != 2 {
+		t.Errorf("stored = %+v, want failed after two attempts", stored)
+	}
+}
+
+func TestDispatcher_UnknownKindIsRetried(t *testing.T) {
+	d, store := newTestDispatcher(Config{})
+
+	entry := NewEntry("agency-1", "unknown", nil)
+	d.Enqueue(context.Background(), entry)
+
+	stored, _ := store.Get(context.Background(), "agency-1", entry.ID)
+	if stored.Status != StatusPending || stored.LastError == "" {
+		t.Errorf("stored = %+v, want pending with error", stored)
+	}
+}
+
+func TestWithEntries(t *testing.T) {
+	first := NewEntry("agency-1", "test", nil)
+	second := NewEntry("agency-1", "test", nil)
+	third := NewEntry("agency-1", "test", nil)
+
+	base := WithEntries(context.Background(), first)
+	a := WithEntries(base, second)
+	b := WithEntries(base, third)
+
+	if got := EntriesFrom(a); len(got) != 2 || got[1] != second {
+		t.Errorf("EntriesFrom(a) = %v, want [first second]", got)
+	}
+	if got := EntriesFrom(b); len(got) != 2 || got[1] != third {
+		t.Errorf("EntriesFrom(b) = %v, want [first third]", got)
+	}
+	if got := EntriesFrom(context.Background()); got != nil {
+		t.Errorf("EntriesFrom(background) = %v, want nil", got)
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	var idempotencyKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	handler := NewWebhookHandler(server.Client())
+	entry := NewWebhookEntry("agency-1", server.URL, "agency.goal.created", map[string]interface{}{"code": "G1"})
+
+	err := handler(context.Background(), entry)
+	if err == nil || IsPermanent(err) {
+		t.Errorf("503 err = %v, want retryable error", err)
+	}
+	if idempotencyKey != entry.ID {
+		t.Errorf("Idempotency-Key = %q, want %q", idempotencyKey, entry.ID)
+	}
+
+	status = http.StatusBadRequest
+	if err := handler(context.Background(), entry); !IsPermanent(err) {
+		t.Errorf("400 err = %v, want permanent error", err)
+	}
+
+	status = http.StatusNoContent
+	if err := handler(context.Background(), entry); err != nil {
+		t.Errorf("204 err = %v, want nil", err)
+	}
+}
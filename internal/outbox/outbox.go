@@ -0,0 +1,151 @@
+// Package outbox implements a transactional outbox for side effects of data
+// changes, such as designer chat messages and change webhooks.
+//
+// Entries are stored together with the change they belong to (see
+// WithEntries), so they exist if and only if the change was committed. A
+// Dispatcher then delivers them, right after the commit and again with
+// backoff until delivery succeeds. Delivery is at least once; handlers make
+// it exactly once by treating the entry ID as an idempotency key.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry statuses
+const (
+	// StatusPending entries are waiting for (another) delivery attempt
+	StatusPending = "pending"
+
+	// StatusDelivered entries were handled successfully
+	StatusDelivered = "delivered"
+
+	// StatusFailed entries failed permanently or ran out of attempts
+	StatusFailed = "failed"
+)
+
+var (
+	// ErrEntryNotFound is returned for unknown entries
+	ErrEntryNotFound = errors.New("outbox entry not found")
+
+	// ErrNotFailed is returned when retrying an entry that has not failed
+	ErrNotFailed = errors.New("outbox entry has not failed")
+)
+
+// Entry is a side effect waiting to be delivered
+type Entry struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	// ID identifies the entry and is passed to handlers as idempotency key
+	ID string `json:"id"`
+
+	// AgencyID is the agency whose change produced the entry
+	AgencyID string `json:"agency_id"`
+
+	// Kind selects the handler, e.g. "webhook"
+	Kind string `json:"kind"`
+
+	// Payload is the handler input
+	Payload map[string]interface{} `json:"payload"`
+
+	// SubjectKey is the key of the document created or changed together with
+	// the entry. Repositories fill it in when the entry is committed.
+	SubjectKey string `json:"subject_key,omitempty"`
+
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// deliveryGrace delays the first retry of a new entry so it does not race the
+// delivery attempted right after the change is committed
+const deliveryGrace = 30 * time.Second
+
+// NewEntry creates a pending entry
+func NewEntry(agencyID, kind string, payload map[string]interface{}) *Entry {
+	now := time.Now().UTC()
+	id := uuid.New().String()
+	return &Entry{
+		Key:           id,
+		ID:            id,
+		AgencyID:      agencyID,
+		Kind:          kind,
+		Payload:       payload,
+		Status:        StatusPending,
+		NextAttemptAt: now.Add(deliveryGrace),
+		CreatedAt:     now,
+	}
+}
+
+// Filter selects entries to list
+type Filter struct {
+	AgencyID string
+	Status   string
+
+	// Limit caps the number of entries returned (0 for no limit)
+	Limit int
+}
+
+// Store persists outbox entries
+type Store interface {
+	// Enqueue stores entries that are not tied to a data change
+	Enqueue(ctx context.Context, entries ...*Entry) error
+
+	// Due returns pending entries whose next attempt is at or before now, oldest first
+	Due(ctx context.Context, now time.Time, limit int) ([]*Entry, error)
+
+	// Update saves an entry's delivery state
+	Update(ctx context.Context, entry *Entry) error
+
+	// Get retrieves an entry
+	Get(ctx context.Context, agencyID, id string) (*Entry, error)
+
+	// List returns entries matching the filter, newest first
+	List(ctx context.Context, filter Filter) ([]*Entry, error)
+}
+
+type entriesKey struct{}
+
+// WithEntries attaches entries to a data change. Repositories that support
+// the outbox store them in the same transaction as the change made with the
+// returned context; others ignore them.
+func WithEntries(ctx context.Context, entries ...*Entry) context.Context {
+	if len(entries) == 0 {
+		return ctx
+	}
+	attached := append(append([]*Entry{}, EntriesFrom(ctx)...), entries...)
+	return context.WithValue(ctx, entriesKey{}, attached)
+}
+
+// EntriesFrom returns the entries attached with WithEntries
+func EntriesFrom(ctx context.Context) []*Entry {
+	entries, _ := ctx.Value(entriesKey{}).([]*Entry)
+	return entries
+}
+
+// permanentError marks handler errors that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error so the entry fails without further attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryStore keeps outbox entries in memory. Entries are not written in the
+// same transaction as the change that caused them and are lost on restart, so
+// it only backs tests.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+}
+
+// NewInMemoryStore creates a new in-memory outbox store
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		entries: make(map[string]*Entry),
+	}
+}
+
+// Enqueue stores entries, ignoring ones already stored
+func (s *InMemoryStore) Enqueue(ctx context.Context, entries ...*Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range entries {
+		if _, exists := s.entries[entry.ID]; !exists {
+			stored := *entry
+			s.entries[entry.ID] = &stored
+		}
+	}
+	return nil
+}
+
+// Due returns pending entries whose next attempt is at or before now, oldest first
+func (s *InMemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []*Entry
+	for _, entry := range s.entries {
+		if entry.Status == StatusPending && !entry.NextAttemptAt.After(now) {
+			e := *entry
+			due = append(due, &e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Update saves an entry's delivery state. Entries attached to changes are
+// not stored by repositories without outbox support, so unknown entries are
+// added.
+func (s *InMemoryStore) Update(ctx context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *entry
+	s.entries[entry.ID] = &stored
+	return nil
+}
+
+// Get retrieves an entry
+func (s *InMemoryStore) Get(ctx context.Context, agencyID, id string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.entries[id]
+	if !exists || entry.AgencyID != agencyID {
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	}
+	e := *entry
+	return &e, nil
+}
+
+// List returns entries matching the filter, newest first
+func (s *InMemoryStore) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []*Entry
+	for _, entry := range s.entries {
+		if filter.AgencyID != "" && entry.AgencyID != filter.AgencyID {
+			continue
+		}
+		if filter.Status != "" && entry.Status != filter.Status {
+			continue
+		}
+		e := *entry
+		entries = append(entries, &e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// KindWebhook entries POST a JSON event to a URL
+	KindWebhook = "webhook"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// WebhookEvent is the body posted by webhook entries
+type WebhookEvent struct {
+	// ID is the outbox entry ID, also sent as the Idempotency-Key header
+	ID       string                 `json:"id"`
+	Event    string                 `json:"event"`
+	AgencyID string                 `json:"agency_id"`
+	Subject  string                 `json:"subject,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// NewWebhookEntry creates an entry posting an event to a URL
+func NewWebhookEntry(agencyID, url, event string, data map[string]interface{}) *Entry {
+	return NewEntry(agencyID, KindWebhook, map[string]interface{}{
+		"url":   url,
+		"event": event,
+		"data":  data,
+	})
+}
+
+// NewWebhookHandler returns the handler for webhook entries. Receivers should
+// deduplicate on the Idempotency-Key header since a delivery whose response
+// was lost is sent again. Client errors other than 408 and 429 are permanent.
+func NewWebhookHandler(client *http.Client) Handler {
+	if client == nil {
+		client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	return func(ctx context.Context, entry *Entry) error {
+		url, _ := entry.Payload["url"].(string)
+		event, _ := entry.Payload["event"].(string)
+		if url == "" {
+			return Permanent(fmt.Errorf("webhook entry has no url"))
+		}
+		data, _ := entry.Payload["data"].(map[string]interface{})
+
+		body, err := json.Marshal(WebhookEvent{
+			ID:       entry.ID,
+			Event:    event,
+			AgencyID: entry.AgencyID,
+			Subject:  entry.SubjectKey,
+			Data:     data,
+			Time:     entry.CreatedAt,
+		})
+		if err != nil {
+			return Permanent(fmt.Errorf("failed to encode webhook event: %w", err))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return Permanent(fmt.Errorf("failed to create webhook request: %w", err))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", entry.ID)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("webhook request failed: %w", err)
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+		default:
+			return Permanent(fmt.Errorf("webhook rejected event with status %d", resp.StatusCode))
+		}
+	}
+}
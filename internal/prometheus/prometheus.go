@@ -0,0 +1,30 @@
+// Package prometheus holds the helpers shared by the exporters of the
+// Prometheus text format, which are all scraped from the single /metrics
+// endpoint.
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter writes its metrics in the Prometheus text format
+type Exporter interface {
+	WritePrometheusMetrics(w io.Writer) error
+}
+
+// WriteHeader writes the HELP and TYPE lines of a metric
+func WriteHeader(b *strings.Builder, name, metricType, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// EscapeLabelValue escapes a label value for use between double quotes
+func EscapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
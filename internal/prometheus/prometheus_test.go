@@ -0,0 +1,21 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteHeader(t *testing.T) {
+	var b strings.Builder
+	WriteHeader(&b, "cortex_requests_total", "counter", "Requests served")
+	want := "# HELP cortex_requests_total Requests served\n# TYPE cortex_requests_total counter\n"
+	if b.String() != want {
+		t.Errorf("unexpected header %q", b.String())
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if got := EscapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("unexpected escaped value %s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// webAPIPrefix holds the web UI's endpoints, which are limited like the API
+const webAPIPrefix = "/api/web/"
+
+type limitedKey struct{}
+
+type unauthenticatedKey struct{}
+
+// Middleware throttles /api requests, including the web UI's /api/web
+// endpoints. It must run after the authentication middleware so clients with
+// an API key are limited by key rather than by address. Health checks are
+// never throttled.
+func Middleware(l *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		rest, limited := limitedRoute(c.Request.URL.Path)
+		if !limited || ctx.Value(limitedKey{}) != nil {
+			c.Next()
+			return
+		}
+
+		// Requests served through an older version's route pass through
+		// again; only the outer pass takes a token
+		c.Request = c.Request.WithContext(context.WithValue(ctx, limitedKey{}, true))
+
+		route := rest
+		if matched, ok := limitedRoute(c.FullPath()); ok {
+			route = matched
+		}
+
+		decision := l.Allow(clientID(c), c.Request.Method, route)
+		if !decision.Allowed {
+			refuse(c, decision)
+			return
+		}
+		c.Next()
+	}
+}
+
+// UnauthenticatedMiddleware throttles addresses whose requests are refused by
+// authentication. It must run before the authentication middleware: once an
+// address has used up its budget of refused requests, its further requests
+// are refused with 429 before their key is looked up. Requests that
+// authenticate are not counted; they draw from their key's limit instead.
+func UnauthenticatedMiddleware(l *Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if ctx.Value(unauthenticatedKey{}) != nil {
+			c.Next()
+			return
+		}
+		c.Request = c.Request.WithContext(context.WithValue(ctx, unauthenticatedKey{}, true))
+
+		client := "ip:" + c.ClientIP()
+		if decision := l.AllowUnauthenticated(client); !decision.Allowed {
+			refuse(c, decision)
+			return
+		}
+		c.Next()
+
+		if refusedAuthentication(c) {
+			l.RecordUnauthenticated(client)
+		}
+	}
+}
+
+// limitedRoute returns the route a request path is limited under, without
+// its API version prefix, and whether it is limited at all
+func limitedRoute(requestPath string) (string, bool) {
+	if _, rest, ok := apiversion.ParsePath(requestPath); ok {
+		return rest, rest != "/health"
+	}
+	return requestPath, strings.HasPrefix(requestPath, webAPIPrefix)
+}
+
+// refusedAuthentication reports whether the authentication middleware refused
+// a request: API requests with 401 and web UI pages with a redirect to the
+// sign-in page
+func refusedAuthentication(c *gin.Context) bool {
+	switch c.Writer.Status() {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusSeeOther:
+		location := c.Writer.Header().Get("Location")
+		return location == auth.LoginPath || strings.HasPrefix(location, auth.LoginPath+"?")
+	}
+	return false
+}
+
+// refuse answers 429 Too Many Requests for a refused request
+func refuse(c *gin.Context, decision Decision) {
+	retryAfter := retryAfterSeconds(decision.RetryAfter)
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":               "rate_limited",
+		"message":             fmt.Sprintf("Rate limit %q of %d requests exceeded", decision.Rule, decision.Limit),
+		"retry_after_seconds": retryAfter,
+	})
+}
+
+// clientID identifies the client of a request by its API key, or by its
+// address when it did not authenticate with one. The address is only taken
+// from X-Forwarded-For for the router's trusted proxies.
+func clientID(c *gin.Context) string {
+	if key, ok := auth.KeyFromContext(c.Request.Context()); ok {
+		return "key:" + key.ID
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/prometheus"
+)
+
+// WritePrometheusMetrics writes the admitted and throttled request counters
+// and the number of tracked buckets in the Prometheus text format. Throttled
+// requests are labelled by the rule that refused them.
+func (l *Limiter) WritePrometheusMetrics(w io.Writer) error {
+	stats := l.Stats()
+
+	rules := make([]string, 0, len(l.config.Rules)+2)
+	rules = append(rules, DefaultRule, UnauthenticatedRule)
+	for _, rule := range l.config.Rules {
+		rules = append(rules, rule.Name)
+	}
+	sort.Strings(rules)
+
+	var b strings.Builder
+
+	prometheus.WriteHeader(&b, "cortex_api_admitted_requests_total", "counter", "API requests admitted by the rate limiter")
+	fmt.Fprintf(&b, "cortex_api_admitted_requests_total %d\n", stats.Admitted)
+	prometheus.WriteHeader(&b, "cortex_api_throttled_requests_total", "counter", "API requests refused with 429 by rate limit rule")
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "cortex_api_throttled_requests_total{rule=\"%s\"} %d\n", prometheus.EscapeLabelValue(rule), stats.Throttled[rule])
+	}
+	prometheus.WriteHeader(&b, "cortex_api_rate_limit_buckets", "gauge", "Token buckets of clients seen recently")
+	fmt.Fprintf(&b, "cortex_api_rate_limit_buckets %d\n", stats.Clients)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,325 @@
+// Package ratelimit throttles API clients with token buckets.
+//
+// Every client gets a bucket for the whole API and, for endpoints with a rule
+// of their own, a bucket per rule. A request is admitted when each bucket it
+// draws from holds a token; otherwise it is refused with 429 Too Many
+// Requests and a Retry-After header. Clients are identified by their API key
+// when the request authenticated with one, and by IP address otherwise.
+//
+// When authentication is enabled, requests it refuses never reach the
+// per-client limit, so each address also gets a bucket for refused requests
+// that is checked before the key is looked up.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+// defaultRequestsPerSecond is the rate each client may sustain by default.
+// The burst defaults to two seconds' worth of requests.
+const defaultRequestsPerSecond = 20
+
+// DefaultRule names the limit applying to every request
+const DefaultRule = "default"
+
+// UnauthenticatedRule names the limit on an address's requests refused by
+// authentication. It has the rate and burst of the default limit.
+const UnauthenticatedRule = "unauthenticated"
+
+// sweepInterval is how often buckets of idle clients are dropped
+const sweepInterval = time.Minute
+
+// Rule is a limit for the requests to some endpoints
+type Rule struct {
+	// Name identifies the rule in metrics
+	Name string
+
+	// Method limits the rule to one HTTP method; empty matches any
+	Method string
+
+	// Path is the route, without the API version prefix, the rule applies to,
+	// along with the routes below it
+	Path string
+
+	// RequestsPerSecond is the rate tokens are added to a client's bucket
+	RequestsPerSecond float64
+
+	// Burst is the size of a client's bucket
+	Burst int
+}
+
+// matches reports whether the rule applies to a request
+func (r *Rule) matches(method, route string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	return route == r.Path || strings.HasPrefix(route, strings.TrimSuffix(r.Path, "/")+"/")
+}
+
+// Config configures the limiter
+type Config struct {
+	// RequestsPerSecond is the rate each client may sustain across the API
+	RequestsPerSecond float64
+
+	// Burst is the number of requests a client may make at once
+	Burst int
+
+	// Rules are tighter limits for some endpoints. A request draws from the
+	// first matching rule in addition to the default limit.
+	Rules []Rule
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.RateLimitConfig) Config {
+	c := Config{
+		RequestsPerSecond: cfg.RequestsPerSecond,
+		Burst:             cfg.Burst,
+	}
+	for _, endpoint := range cfg.Endpoints {
+		c.Rules = append(c.Rules, Rule{
+			Name:              endpoint.Name,
+			Method:            endpoint.Method,
+			Path:              endpoint.Path,
+			RequestsPerSecond: endpoint.RequestsPerSecond,
+			Burst:             endpoint.Burst,
+		})
+	}
+	return c
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.RequestsPerSecond <= 0 {
+		c.RequestsPerSecond = defaultRequestsPerSecond
+	}
+	if c.Burst <= 0 {
+		c.Burst = max(1, int(math.Ceil(c.RequestsPerSecond*2)))
+	}
+	return c
+}
+
+// validate rejects rules that cannot be enforced
+func (c Config) validate() error {
+	names := make(map[string]bool)
+	for _, rule := range c.Rules {
+		switch {
+		case rule.Name == "" || rule.Name == DefaultRule || rule.Name == UnauthenticatedRule:
+			return fmt.Errorf("rate limit rules need a name other than %q and %q", DefaultRule, UnauthenticatedRule)
+		case names[rule.Name]:
+			return fmt.Errorf("duplicate rate limit rule %q", rule.Name)
+		case !strings.HasPrefix(rule.Path, "/"):
+			return fmt.Errorf("rate limit rule %q: path must start with /", rule.Name)
+		case rule.RequestsPerSecond <= 0 || rule.Burst <= 0:
+			return fmt.Errorf("rate limit rule %q: requests_per_second and burst must be positive", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// bucket is a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens accrued since the last update
+func (b *bucket) refill(now time.Time, rate float64, burst int) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+}
+
+// bucketKey identifies a client's bucket for a rule
+type bucketKey struct {
+	rule   string
+	client string
+}
+
+// Limiter decides which requests are admitted
+type Limiter struct {
+	config Config
+	clock  clock.Clock
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	throttled map[string]int64 // rule -> refused requests
+	admitted  int64
+	lastSweep time.Time
+}
+
+// NewLimiter creates a limiter
+func NewLimiter(cfg Config, c clock.Clock) (*Limiter, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c = clock.OrReal(c)
+	return &Limiter{
+		config:    cfg,
+		clock:     c,
+		buckets:   make(map[bucketKey]*bucket),
+		throttled: make(map[string]int64),
+		lastSweep: c.Now(),
+	}, nil
+}
+
+// Decision is the outcome of a request
+type Decision struct {
+	Allowed    bool
+	Rule       string        // Rule that refused the request
+	Limit      int           // Burst of that rule
+	RetryAfter time.Duration // Until the request would be admitted
+}
+
+// Allow takes a token from each bucket the request draws from, or none when
+// one of them is empty
+func (l *Limiter) Allow(client, method, route string) Decision {
+	type limit struct {
+		name  string
+		rate  float64
+		burst int
+	}
+	limits := []limit{{DefaultRule, l.config.RequestsPerSecond, l.config.Burst}}
+	for _, rule := range l.config.Rules {
+		if rule.matches(method, route) {
+			limits = append(limits, limit{rule.Name, rule.RequestsPerSecond, rule.Burst})
+			break
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+
+	buckets := make([]*bucket, len(limits))
+	refused := Decision{Allowed: true}
+	for i, lim := range limits {
+		key := bucketKey{rule: lim.name, client: client}
+		b, exists := l.buckets[key]
+		if !exists {
+			b = &bucket{tokens: float64(lim.burst), updated: now}
+			l.buckets[key] = b
+		}
+		b.refill(now, lim.rate, lim.burst)
+		buckets[i] = b
+
+		if b.tokens < 1 {
+			wait := time.Duration((1 - b.tokens) / lim.rate * float64(time.Second))
+			if refused.Allowed || wait > refused.RetryAfter {
+				refused = Decision{Rule: lim.name, Limit: lim.burst, RetryAfter: wait}
+			}
+		}
+	}
+	if !refused.Allowed {
+		l.throttled[refused.Rule]++
+		return refused
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+	l.admitted++
+	return Decision{Allowed: true}
+}
+
+// AllowUnauthenticated reports whether an address's requests refused by
+// authentication leave room for another request, without taking a token
+func (l *Limiter) AllowUnauthenticated(client string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.sweep(now)
+	b, exists := l.buckets[bucketKey{rule: UnauthenticatedRule, client: client}]
+	if !exists {
+		return Decision{Allowed: true}
+	}
+	b.refill(now, l.config.RequestsPerSecond, l.config.Burst)
+	if b.tokens >= 1 {
+		return Decision{Allowed: true}
+	}
+	l.throttled[UnauthenticatedRule]++
+	return Decision{
+		Rule:       UnauthenticatedRule,
+		Limit:      l.config.Burst,
+		RetryAfter: time.Duration((1 - b.tokens) / l.config.RequestsPerSecond * float64(time.Second)),
+	}
+}
+
+// RecordUnauthenticated takes a token from an address's bucket for requests
+// refused by authentication
+func (l *Limiter) RecordUnauthenticated(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	key := bucketKey{rule: UnauthenticatedRule, client: client}
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(l.config.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.config.RequestsPerSecond, l.config.Burst)
+	b.tokens = math.Max(0, b.tokens-1)
+}
+
+// sweep drops the buckets that have refilled completely, as a new bucket is
+// equivalent. Callers hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	rates := map[string]Rule{
+		DefaultRule:         {RequestsPerSecond: l.config.RequestsPerSecond, Burst: l.config.Burst},
+		UnauthenticatedRule: {RequestsPerSecond: l.config.RequestsPerSecond, Burst: l.config.Burst},
+	}
+	for _, rule := range l.config.Rules {
+		rates[rule.Name] = rule
+	}
+	for key, b := range l.buckets {
+		rule := rates[key.rule]
+		b.refill(now, rule.RequestsPerSecond, rule.Burst)
+		if b.tokens >= float64(rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Stats is a snapshot of the limiter's counters
+type Stats struct {
+	Admitted  int64            `json:"admitted"`
+	Throttled map[string]int64 `json:"throttled"` // Refused requests by rule
+	Clients   int              `json:"clients"`   // Buckets currently tracked
+}
+
+// Stats returns the limiter's counters
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := Stats{
+		Admitted:  l.admitted,
+		Throttled: make(map[string]int64, len(l.throttled)),
+		Clients:   len(l.buckets),
+	}
+	for rule, count := range l.throttled {
+		stats.Throttled[rule] = count
+	}
+	return stats
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds for the Retry-After header
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Max(1, math.Ceil(wait.Seconds())))
+}
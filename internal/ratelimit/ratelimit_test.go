@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/auth"
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(t *testing.T, cfg Config) (*Limiter, *clock.Fake) {
+	fake := clock.NewFake(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	l, err := NewLimiter(cfg, fake)
+	require.NoError(t, err)
+	return l, fake
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	l, fake := newTestLimiter(t, Config{RequestsPerSecond: 2, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("a", http.MethodGet, "/agents").Allowed, "request %d is within the burst", i)
+	}
+	refused := l.Allow("a", http.MethodGet, "/agents")
+	assert.False(t, refused.Allowed)
+	assert.Equal(t, DefaultRule, refused.Rule)
+	assert.Equal(t, 3, refused.Limit)
+	assert.Equal(t, 500*time.Millisecond, refused.RetryAfter)
+
+	assert.True(t, l.Allow("b", http.MethodGet, "/agents").Allowed, "clients have their own buckets")
+
+	fake.Advance(500 * time.Millisecond)
+	assert.True(t, l.Allow("a", http.MethodGet, "/agents").Allowed)
+	assert.False(t, l.Allow("a", http.MethodGet, "/agents").Allowed)
+
+	stats := l.Stats()
+	assert.Equal(t, int64(5), stats.Admitted)
+	assert.Equal(t, int64(2), stats.Throttled[DefaultRule])
+}
+
+func TestLimiter_EndpointRules(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{
+		RequestsPerSecond: 10,
+		Burst:             3,
+		Rules: []Rule{{
+			Name:              "tasks",
+			Method:            http.MethodPost,
+			Path:              "/agents/:id/tasks",
+			RequestsPerSecond: 1,
+			Burst:             1,
+		}},
+	})
+
+	assert.True(t, l.Allow("a", http.MethodPost, "/agents/:id/tasks").Allowed)
+	refused := l.Allow("a", http.MethodPost, "/agents/:id/tasks")
+	assert.False(t, refused.Allowed)
+	assert.Equal(t, "tasks", refused.Rule)
+	assert.Equal(t, time.Second, refused.RetryAfter)
+
+	// A refused request takes no token from the default bucket
+	assert.True(t, l.Allow("a", http.MethodGet, "/agents/:id/tasks").Allowed)
+	assert.True(t, l.Allow("a", http.MethodGet, "/agents").Allowed)
+	assert.False(t, l.Allow("a", http.MethodGet, "/agents").Allowed)
+}
+
+func TestLimiter_SweepsFullBuckets(t *testing.T) {
+	l, fake := newTestLimiter(t, Config{RequestsPerSecond: 1, Burst: 1})
+
+	l.Allow("a", http.MethodGet, "/agents")
+	l.Allow("b", http.MethodGet, "/agents")
+	assert.Equal(t, 2, l.Stats().Clients)
+
+	fake.Advance(sweepInterval)
+	l.Allow("c", http.MethodGet, "/agents")
+	assert.Equal(t, 1, l.Stats().Clients)
+}
+
+func TestNewLimiter_ValidatesRules(t *testing.T) {
+	cases := map[string]Rule{
+		"unnamed":      {Path: "/agents", RequestsPerSecond: 1, Burst: 1},
+		"default name": {Name: DefaultRule, Path: "/agents", RequestsPerSecond: 1, Burst: 1},
+		"relative":     {Name: "r", Path: "agents", RequestsPerSecond: 1, Burst: 1},
+		"no rate":      {Name: "r", Path: "/agents", Burst: 1},
+	}
+	for name, rule := range cases {
+		_, err := NewLimiter(Config{Rules: []Rule{rule}}, nil)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{RequestsPerSecond: 1, Burst: 1})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stands in for the authentication middleware
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Key"); id != "" {
+			c.Request = c.Request.WithContext(auth.WithKey(c.Request.Context(), &auth.APIKey{ID: id}))
+		}
+	})
+	router.Use(Middleware(l))
+	for _, path := range []string{"/api/v1/agents", "/api/v1/health", "/metrics"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	serve := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/agents", "").Code)
+	w := serve("/api/v1/agents", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"error":"rate_limited"`)
+
+	assert.Equal(t, http.StatusOK, serve("/api/v1/agents", "key-1").Code, "keys are limited apart from addresses")
+	assert.Equal(t, http.StatusOK, serve("/api/v1/health", "").Code)
+	assert.Equal(t, http.StatusOK, serve("/metrics", "").Code)
+
+	var metrics strings.Builder
+	require.NoError(t, l.WritePrometheusMetrics(&metrics))
+	assert.Contains(t, metrics.String(), "cortex_api_admitted_requests_total 2\n")
+	assert.Contains(t, metrics.String(), "cortex_api_throttled_requests_total{rule=\"default\"} 1\n")
+}
+
+func TestMiddleware_LimitsWebEndpoints(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{RequestsPerSecond: 1, Burst: 1})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(l))
+	router.GET("/api/web/agents/json", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/web/agents/json", nil))
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, http.StatusTooManyRequests, serve())
+}
+
+func TestUnauthenticatedMiddleware(t *testing.T) {
+	l, fake := newTestLimiter(t, Config{RequestsPerSecond: 1, Burst: 2})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(UnauthenticatedMiddleware(l))
+	// Stands in for the authentication middleware, counting key lookups
+	lookups := 0
+	router.Use(func(c *gin.Context) {
+		lookups++
+		if c.GetHeader("X-Key") != "valid" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Request = c.Request.WithContext(auth.WithKey(c.Request.Context(), &auth.APIKey{ID: "key-1"}))
+	})
+	router.GET("/api/v1/agents", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil)
+		req.Header.Set("X-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("valid"), "authenticated requests are not counted")
+	}
+	assert.Equal(t, http.StatusUnauthorized, serve("bad"))
+	assert.Equal(t, http.StatusUnauthorized, serve("bad"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("bad"))
+	assert.Equal(t, 5, lookups, "throttled requests are refused before the key is looked up")
+
+	fake.Advance(time.Second)
+	assert.Equal(t, http.StatusUnauthorized, serve("bad"))
+	assert.Equal(t, int64(1), l.Stats().Throttled[UnauthenticatedRule])
+}
@@ -0,0 +1,192 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+)
+
+// ErrAgentNotRegistered is returned for heartbeats of agents that have not
+// registered since the framework started; they must register again
+var ErrAgentNotRegistered = errors.New("agent not registered")
+
+// Status is an agent's liveness as seen by the framework
+type Status string
+
+const (
+	// StatusOnline indicates the agent heartbeats on time
+	StatusOnline Status = "online"
+	// StatusDegraded indicates late heartbeats, or an agent reporting itself degraded
+	StatusDegraded Status = "degraded"
+	// StatusOffline indicates the agent stopped heartbeating, left, or never registered
+	StatusOffline Status = "offline"
+)
+
+// ReasonAgentStopped is the reason reported for agents that deregistered
+const ReasonAgentStopped = "agent stopped"
+
+// LivenessConfig configures when agents are considered degraded and offline
+type LivenessConfig struct {
+	// DegradedAfter is how long after its last heartbeat an agent is degraded
+	DegradedAfter time.Duration
+
+	// OfflineAfter is how long after its last heartbeat an agent is offline
+	OfflineAfter time.Duration
+}
+
+// DefaultLivenessConfig returns the default liveness thresholds, suited to
+// the default heartbeat interval of 30 seconds
+func DefaultLivenessConfig() LivenessConfig {
+	return LivenessConfig{
+		DegradedAfter: 60 * time.Second,
+		OfflineAfter:  150 * time.Second,
+	}
+}
+
+func (c LivenessConfig) withDefaults() LivenessConfig {
+	defaults := DefaultLivenessConfig()
+	if c.DegradedAfter <= 0 {
+		c.DegradedAfter = defaults.DegradedAfter
+	}
+	if c.OfflineAfter <= 0 {
+		c.OfflineAfter = max(defaults.OfflineAfter, c.DegradedAfter)
+	}
+	return c
+}
+
+// Heartbeat is what an agent reports with each heartbeat
+type Heartbeat struct {
+	// Degraded reports that the agent runs with reduced capacity
+	Degraded bool
+
+	// Reason explains a degraded heartbeat
+	Reason string
+}
+
+// AgentLiveness is an agent's registration and liveness status
+type AgentLiveness struct {
+	AgentID      string    `json:"agent_id"`
+	Status       Status    `json:"status"`
+	Reason       string    `json:"reason,omitempty"`
+	Capabilities []string  `json:"capabilities"`
+	RegisteredAt time.Time `json:"registered_at"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// registration is an agent's entry in the liveness tracker
+type registration struct {
+	capabilities []string
+	registeredAt time.Time
+	lastSeen     time.Time
+	heartbeat    Heartbeat
+	left         bool
+}
+
+// Liveness tracks which agents are alive. Agents register when they start
+// and heartbeat periodically; their status follows from the time since their
+// last heartbeat. Registrations are not persisted: after a restart every
+// agent is offline until it registers again.
+type Liveness struct {
+	config LivenessConfig
+	clock  clock.Clock
+
+	mu     sync.RWMutex
+	agents map[string]*registration
+}
+
+// NewLiveness creates a liveness tracker. A nil clock uses the real clock.
+func NewLiveness(config LivenessConfig, clk clock.Clock) *Liveness {
+	return &Liveness{
+		config: config.withDefaults(),
+		clock:  clock.OrReal(clk),
+		agents: make(map[string]*registration),
+	}
+}
+
+// Register records that an agent started with the given capabilities. It
+// counts as the agent's first heartbeat.
+func (l *Liveness) Register(agentID string, capabilities []string) AgentLiveness {
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reg := &registration{
+		capabilities: append([]string{}, capabilities...),
+		registeredAt: now,
+		lastSeen:     now,
+	}
+	l.agents[agentID] = reg
+	return l.liveness(agentID, reg, now)
+}
+
+// Heartbeat records a heartbeat of a registered agent
+func (l *Liveness) Heartbeat(agentID string, heartbeat Heartbeat) (AgentLiveness, error) {
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reg, exists := l.agents[agentID]
+	if !exists || reg.left {
+		return AgentLiveness{}, ErrAgentNotRegistered
+	}
+	reg.lastSeen = now
+	reg.heartbeat = heartbeat
+	return l.liveness(agentID, reg, now), nil
+}
+
+// Deregister records that an agent stopped. The agent is offline at once
+// and its last heartbeat is kept.
+func (l *Liveness) Deregister(agentID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if reg, exists := l.agents[agentID]; exists {
+		reg.left = true
+	}
+}
+
+// Get returns an agent's liveness. Agents that never registered are offline.
+func (l *Liveness) Get(agentID string) AgentLiveness {
+	now := l.clock.Now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	reg, exists := l.agents[agentID]
+	if !exists {
+		return AgentLiveness{AgentID: agentID, Status: StatusOffline, Capabilities: []string{}}
+	}
+	return l.liveness(agentID, reg, now)
+}
+
+// liveness computes an agent's status at the given time; l.mu must be held
+func (l *Liveness) liveness(agentID string, reg *registration, now time.Time) AgentLiveness {
+	result := AgentLiveness{
+		AgentID:      agentID,
+		Status:       StatusOnline,
+		Capabilities: append([]string{}, reg.capabilities...),
+		RegisteredAt: reg.registeredAt,
+		LastSeen:     reg.lastSeen,
+	}
+
+	silence := now.Sub(reg.lastSeen)
+	switch {
+	case reg.left:
+		result.Status = StatusOffline
+		result.Reason = ReasonAgentStopped
+	case silence >= l.config.OfflineAfter:
+		result.Status = StatusOffline
+		result.Reason = "no heartbeat"
+	case silence >= l.config.DegradedAfter:
+		result.Status = StatusDegraded
+		result.Reason = "late heartbeat"
+	case reg.heartbeat.Degraded:
+		result.Status = StatusDegraded
+		result.Reason = reg.heartbeat.Reason
+	}
+	return result
+}
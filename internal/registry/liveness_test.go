@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveness_StatusFollowsHeartbeats(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC))
+	liveness := NewLiveness(LivenessConfig{DegradedAfter: time.Minute, OfflineAfter: 3 * time.Minute}, clk)
+
+	assert.Equal(t, StatusOffline, liveness.Get("pump-1").Status, "unregistered agents are offline")
+	_, err := liveness.Heartbeat("pump-1", Heartbeat{})
+	assert.ErrorIs(t, err, ErrAgentNotRegistered)
+
+	registered := liveness.Register("pump-1", []string{"pressure"})
+	assert.Equal(t, StatusOnline, registered.Status)
+	assert.Equal(t, []string{"pressure"}, registered.Capabilities)
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, StatusDegraded, liveness.Get("pump-1").Status)
+
+	_, err = liveness.Heartbeat("pump-1", Heartbeat{})
+	require.NoError(t, err)
+	assert.Equal(t, StatusOnline, liveness.Get("pump-1").Status)
+
+	clk.Advance(3 * time.Minute)
+	offline := liveness.Get("pump-1")
+	assert.Equal(t, StatusOffline, offline.Status)
+	assert.Equal(t, clk.Now().Add(-3*time.Minute), offline.LastSeen)
+
+	// A late heartbeat brings the agent back
+	_, err = liveness.Heartbeat("pump-1", Heartbeat{})
+	require.NoError(t, err)
+	assert.Equal(t, StatusOnline, liveness.Get("pump-1").Status)
+}
+
+func TestLiveness_ReportedDegradation(t *testing.T) {
+	liveness := NewLiveness(LivenessConfig{}, nil)
+	liveness.Register("valve-1", nil)
+
+	result, err := liveness.Heartbeat("valve-1", Heartbeat{Degraded: true, Reason: "actuator slow"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusDegraded, result.Status)
+	assert.Equal(t, "actuator slow", result.Reason)
+
+	result, err = liveness.Heartbeat("valve-1", Heartbeat{})
+	require.NoError(t, err)
+	assert.Equal(t, StatusOnline, result.Status)
+}
+
+func TestLiveness_Deregister(t *testing.T) {
+	liveness := NewLiveness(LivenessConfig{}, nil)
+	liveness.Register("valve-1", nil)
+	liveness.Deregister("valve-1")
+
+	assert.Equal(t, StatusOffline, liveness.Get("valve-1").Status)
+	_, err := liveness.Heartbeat("valve-1", Heartbeat{})
+	assert.ErrorIs(t, err, ErrAgentNotRegistered, "stopped agents must register again")
+
+	assert.Equal(t, StatusOnline, liveness.Register("valve-1", nil).Status)
+}
@@ -21,6 +21,15 @@ type Role struct {
 	// Description provides context about this role
 	Description string `json:"description,omitempty"`
 
+	// AgencyID is the agency this role belongs to (empty for roles shared by all agencies)
+	AgencyID string `json:"agency_id,omitempty"`
+
+	// Responsibilities lists what agents in this role are accountable for
+	Responsibilities []string `json:"responsibilities,omitempty"`
+
+	// GoalKeys links the role to the agency goals it works towards
+	GoalKeys []string `json:"goal_keys,omitempty"`
+
 	// Tags for categorizing and filtering roles
 	Tags []string `json:"tags,omitempty"`
 
@@ -76,6 +85,12 @@ type Role struct {
 	CreatedBy string `json:"created_by,omitempty"`
 }
 
+// VisibleTo reports whether an agency can see and manage the role. Roles
+// without an agency are shared by all agencies.
+func (r *Role) VisibleTo(agencyID string) bool {
+	return r.AgencyID == "" || r.AgencyID == agencyID
+}
+
 // ValidationRule defines a custom validation rule for a role
 type ValidationRule struct {
 	// Field is the configuration field this rule applies to
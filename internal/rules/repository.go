@@ -0,0 +1,230 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionRules is the rule collection name
+const CollectionRules = "rules"
+
+// ArangoRepository stores rules in ArangoDB
+type ArangoRepository struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed rule repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionRules, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionRules).Info("Created new collection")
+	}
+
+	return &ArangoRepository{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create stores a new rule
+func (r *ArangoRepository) Create(ctx context.Context, rule *Rule) error {
+	now := time.Now()
+	rule.Key = rule.ID
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if _, err := r.collection.CreateDocument(ctx, rule); err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a rule by ID
+func (r *ArangoRepository) Get(ctx context.Context, id string) (*Rule, error) {
+	var rule Rule
+	if _, err := r.collection.ReadDocument(ctx, id, &rule); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// Update replaces an existing rule
+func (r *ArangoRepository) Update(ctx context.Context, rule *Rule) error {
+	existing, err := r.Get(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	rule.Key = rule.ID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	if _, err := r.collection.ReplaceDocument(ctx, rule.ID, rule); err != nil {
+		return fmt.Errorf("failed to update rule: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a rule
+func (r *ArangoRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.collection.RemoveDocument(ctx, id); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+		}
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	return nil
+}
+
+// List returns every rule ordered by name
+func (r *ArangoRepository) List(ctx context.Context) ([]*Rule, error) {
+	query := `
+		FOR r IN @@collection
+			SORT r.name ASC, r.id ASC
+			RETURN r
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionRules,
+	}
+
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rules: %w", err)
+	}
+	defer cursor.Close()
+
+	var rules []*Rule
+	for {
+		var rule Rule
+		_, err := cursor.ReadDocument(ctx, &rule)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rule: %w", err)
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// InMemoryRepository keeps rules in memory. Rules created through the API are
+// lost on restart.
+type InMemoryRepository struct {
+	mu    sync.RWMutex
+	rules map[string]*Rule
+}
+
+// NewInMemoryRepository creates a new in-memory rule repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		rules: make(map[string]*Rule),
+	}
+}
+
+// Create stores a new rule
+func (r *InMemoryRepository) Create(ctx context.Context, rule *Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	rule.Key = rule.ID
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	stored := *rule
+	r.rules[rule.ID] = &stored
+	return nil
+}
+
+// Get retrieves a rule by ID
+func (r *InMemoryRepository) Get(ctx context.Context, id string) (*Rule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, exists := r.rules[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	result := *rule
+	return &result, nil
+}
+
+// Update replaces an existing rule
+func (r *InMemoryRepository) Update(ctx context.Context, rule *Rule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.rules[rule.ID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrRuleNotFound, rule.ID)
+	}
+
+	rule.Key = rule.ID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	stored := *rule
+	r.rules[rule.ID] = &stored
+	return nil
+}
+
+// Delete removes a rule
+func (r *InMemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.rules[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+	}
+	delete(r.rules, id)
+	return nil
+}
+
+// List returns every rule ordered by name
+func (r *InMemoryRepository) List(ctx context.Context) ([]*Rule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]*Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Name != rules[j].Name {
+			return rules[i].Name < rules[j].Name
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules, nil
+}
@@ -0,0 +1,234 @@
+// Package rules triggers actions when telemetry or publication payloads meet
+// a condition.
+//
+// A rule watches one source: telemetry points of a metric, or publications
+// of an event pattern. Each reading from a matching agent is checked against
+// the rule's condition, a payload filter expression such as
+// "pressure_bar < 5.5". Once the condition holds for the configured number of
+// consecutive readings of an agent the rule triggers its actions: publishing
+// an alert, sending a direct message or starting a workflow. It triggers
+// again only after a reading from that agent no longer meets the condition.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+)
+
+var (
+	// ErrRuleNotFound is returned when a rule does not exist
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrInvalidRule is returned for rules that fail validation
+	ErrInvalidRule = errors.New("invalid rule")
+)
+
+// Source identifies the readings a rule watches
+type Source string
+
+const (
+	// SourceTelemetry watches points posted to the telemetry API
+	SourceTelemetry Source = "telemetry"
+	// SourcePublication watches pub/sub publications
+	SourcePublication Source = "publication"
+)
+
+// ActionType identifies what a rule does when it triggers
+type ActionType string
+
+const (
+	// ActionPublish publishes the trigger as an alert under a topic
+	ActionPublish ActionType = "publish"
+	// ActionMessage sends the trigger as a direct message to an agent
+	ActionMessage ActionType = "message"
+	// ActionWorkflow runs a workflow on the orchestration engine with the
+	// trigger as context
+	ActionWorkflow ActionType = "workflow"
+)
+
+// Action is something a rule does when it triggers. The trigger details are
+// sent as the payload, merged with the action's own payload fields.
+type Action struct {
+	Type ActionType `json:"type"`
+
+	// Topic is the event name published by publish actions
+	Topic string `json:"topic,omitempty"`
+
+	// AgentID is the recipient of message actions
+	AgentID string `json:"agent_id,omitempty"`
+
+	// MessageType is the type of message actions (default notification)
+	MessageType communication.MessageType `json:"message_type,omitempty"`
+
+	// WorkflowID is the workflow started by workflow actions
+	WorkflowID string `json:"workflow_id,omitempty"`
+
+	// Payload holds extra fields sent with the trigger, e.g. severity
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Target returns what the action is sent to
+func (a Action) Target() string {
+	switch a.Type {
+	case ActionPublish:
+		return a.Topic
+	case ActionMessage:
+		return a.AgentID
+	case ActionWorkflow:
+		return a.WorkflowID
+	}
+	return ""
+}
+
+// Validate checks the action has a target
+func (a Action) Validate() error {
+	var field string
+	switch a.Type {
+	case ActionPublish:
+		field = "topic"
+	case ActionMessage:
+		field = "agent_id"
+	case ActionWorkflow:
+		field = "workflow_id"
+	default:
+		return fmt.Errorf("%w: unknown action type %q", ErrInvalidRule, a.Type)
+	}
+	if a.Target() == "" {
+		return fmt.Errorf("%w: %s action requires %s", ErrInvalidRule, a.Type, field)
+	}
+	return nil
+}
+
+// Rule is a condition over a telemetry or publication source and the actions
+// taken when it holds
+type Rule struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+
+	Source Source `json:"source"`
+
+	// Metric is the metric watched by telemetry rules; topic patterns are
+	// allowed. The point value is available to the condition both under the
+	// metric name and as "value", together with the point tags.
+	Metric string `json:"metric,omitempty"`
+
+	// Event is the event pattern watched by publication rules
+	Event string `json:"event,omitempty"`
+
+	// Agents are patterns of the agent IDs the rule watches; empty watches every agent
+	Agents []string `json:"agents,omitempty"`
+
+	// Condition is a payload filter expression, e.g. "pressure_bar < 5.5"
+	Condition string `json:"condition"`
+
+	// Consecutive is how many readings in a row must meet the condition (default 1)
+	Consecutive int `json:"consecutive,omitempty"`
+
+	// CooldownSeconds is the least time between two triggers for one agent
+	CooldownSeconds int `json:"cooldown_seconds,omitempty"`
+
+	Actions []Action `json:"actions"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the rule's source, condition and actions
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	var pattern string
+	switch r.Source {
+	case SourceTelemetry:
+		if r.Metric == "" {
+			return fmt.Errorf("%w: telemetry rules require a metric", ErrInvalidRule)
+		}
+		pattern = r.Metric
+	case SourcePublication:
+		if r.Event == "" {
+			return fmt.Errorf("%w: publication rules require an event", ErrInvalidRule)
+		}
+		pattern = r.Event
+	default:
+		return fmt.Errorf("%w: source must be %s or %s", ErrInvalidRule, SourceTelemetry, SourcePublication)
+	}
+	if err := communication.ValidateTopicPattern(pattern); err != nil {
+		return fmt.Errorf("%w: invalid pattern %q: %v", ErrInvalidRule, pattern, err)
+	}
+	for _, agent := range r.Agents {
+		if err := communication.ValidateTopicPattern(agent); err != nil {
+			return fmt.Errorf("%w: invalid agent pattern %q: %v", ErrInvalidRule, agent, err)
+		}
+	}
+
+	if _, err := communication.ParseFilterExpression(r.Condition); err != nil {
+		return fmt.Errorf("%w: condition: %v", ErrInvalidRule, err)
+	}
+	if r.Consecutive < 0 || r.CooldownSeconds < 0 {
+		return fmt.Errorf("%w: consecutive and cooldown_seconds must not be negative", ErrInvalidRule)
+	}
+
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("%w: at least one action is required", ErrInvalidRule)
+	}
+	for _, action := range r.Actions {
+		if err := action.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watches reports whether the rule applies to a reading's source, name and agent
+func (r *Rule) watches(reading *Reading) bool {
+	if r.Disabled || r.Source != reading.Source {
+		return false
+	}
+
+	pattern := r.Metric
+	if r.Source == SourcePublication {
+		pattern = r.Event
+	}
+	if !communication.MatchTopic(pattern, reading.Name) {
+		return false
+	}
+
+	if len(r.Agents) == 0 {
+		return true
+	}
+	for _, agent := range r.Agents {
+		if communication.MatchTopic(agent, reading.AgentID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository stores rules
+type Repository interface {
+	// Create stores a new rule
+	Create(ctx context.Context, rule *Rule) error
+
+	// Get retrieves a rule by ID
+	Get(ctx context.Context, id string) (*Rule, error)
+
+	// Update replaces an existing rule
+	Update(ctx context.Context, rule *Rule) error
+
+	// Delete removes a rule
+	Delete(ctx context.Context, id string) error
+
+	// List returns every rule ordered by name
+	List(ctx context.Context) ([]*Rule, error)
+}
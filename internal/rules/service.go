@@ -0,0 +1,474 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// EngineAgentID is the agent ID rule actions are sent under
+	EngineAgentID = "rules-engine"
+
+	// AlertType is the alert_type of alerts published by rules unless an
+	// action's payload sets another
+	AlertType = "rule_triggered"
+
+	historySize = 500
+)
+
+// Reading is one set of values from a source, evaluated against the rules
+// watching it
+type Reading struct {
+	Source  Source
+	AgentID string
+	Name    string // Metric or event name
+	Values  map[string]interface{}
+	At      time.Time
+}
+
+// ReadingFromPoint reads a telemetry point. The value is available under
+// the metric name and as "value", next to the agent_id and point tags.
+func ReadingFromPoint(p *telemetry.Point) *Reading {
+	values := make(map[string]interface{}, len(p.Tags)+3)
+	for k, v := range p.Tags {
+		values[k] = v
+	}
+	values["agent_id"] = p.AgentID
+	values["value"] = p.Value
+	values[p.Metric] = p.Value
+
+	return &Reading{Source: SourceTelemetry, AgentID: p.AgentID, Name: p.Metric, Values: values, At: p.Timestamp}
+}
+
+// ReadingFromPublication reads the payload of a publication
+func ReadingFromPublication(pub *communication.Publication) *Reading {
+	return &Reading{
+		Source:  SourcePublication,
+		AgentID: pub.PublisherAgentID,
+		Name:    pub.EventName,
+		Values:  pub.Payload,
+		At:      pub.PublishedAt,
+	}
+}
+
+// ActionResult is the outcome of one action of a trigger
+type ActionResult struct {
+	Type      ActionType `json:"type"`
+	Target    string     `json:"target"`
+	Succeeded bool       `json:"succeeded"`
+	Reference string     `json:"reference,omitempty"` // ID of the publication, message or workflow execution
+	Error     string     `json:"error,omitempty"`
+}
+
+// Trigger records a rule whose condition held for an agent
+type Trigger struct {
+	ID          string                 `json:"id"`
+	RuleID      string                 `json:"rule_id"`
+	RuleName    string                 `json:"rule_name"`
+	AgentID     string                 `json:"agent_id"`
+	Source      Source                 `json:"source"`
+	Name        string                 `json:"name"`
+	Condition   string                 `json:"condition"`
+	Consecutive int                    `json:"consecutive"`
+	Values      map[string]interface{} `json:"values"`
+	TriggeredAt time.Time              `json:"triggered_at"`
+	Actions     []ActionResult         `json:"actions"`
+
+	actions []Action
+}
+
+// payload returns the trigger details sent by actions
+func (t *Trigger) payload(extra map[string]interface{}) map[string]interface{} {
+	payload := map[string]interface{}{
+		"trigger_id":   t.ID,
+		"rule_id":      t.RuleID,
+		"rule_name":    t.RuleName,
+		"agent_id":     t.AgentID,
+		"source":       string(t.Source),
+		"name":         t.Name,
+		"condition":    t.Condition,
+		"consecutive":  t.Consecutive,
+		"values":       t.Values,
+		"triggered_at": t.TriggeredAt,
+		"alert_type":   AlertType,
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	return payload
+}
+
+// AgentMessenger sends direct messages to agents. MessageService implements it.
+type AgentMessenger interface {
+	SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error)
+}
+
+// WorkflowStarter runs workflows on the orchestration engine and returns the
+// ID of the execution. orchestration.DesignLauncher implements it.
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error)
+}
+
+// compiledRule is a rule with its parsed condition
+type compiledRule struct {
+	*Rule
+	condition *communication.FilterExpression
+}
+
+// streak tracks the consecutive readings of an agent meeting a rule's condition
+type streak struct {
+	count     int
+	fired     bool
+	lastFired time.Time
+}
+
+// Service manages rules and evaluates readings against them
+type Service struct {
+	repo      Repository
+	messenger AgentMessenger
+	publisher communication.TrafficPublisher
+	workflows WorkflowStarter
+	logger    *log.Logger
+
+	mu       sync.Mutex
+	rules    map[string]*compiledRule
+	streaks  map[string]map[string]*streak // By rule, then agent
+	triggers []*Trigger                    // Oldest first, at most historySize
+}
+
+// NewService creates a new rule service
+func NewService(repo Repository, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &Service{
+		repo:    repo,
+		logger:  logger,
+		rules:   make(map[string]*compiledRule),
+		streaks: make(map[string]map[string]*streak),
+	}
+}
+
+// SetMessenger sets the service used by message actions
+func (s *Service) SetMessenger(messenger AgentMessenger) {
+	s.messenger = messenger
+}
+
+// SetPublisher sets the service used by publish actions
+func (s *Service) SetPublisher(publisher communication.TrafficPublisher) {
+	s.publisher = publisher
+}
+
+// SetWorkflows sets the service used by workflow actions
+func (s *Service) SetWorkflows(workflows WorkflowStarter) {
+	s.workflows = workflows
+}
+
+// Load reads the stored rules into the evaluation engine
+func (s *Service) Load(ctx context.Context) error {
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = make(map[string]*compiledRule, len(stored))
+	s.streaks = make(map[string]map[string]*streak)
+	for _, rule := range stored {
+		compiled, err := compile(rule)
+		if err != nil {
+			s.logger.WithError(err).WithField("rule_id", rule.ID).Warn("Skipping invalid rule")
+			continue
+		}
+		s.rules[rule.ID] = compiled
+	}
+
+	s.logger.WithField("rules", len(s.rules)).Info("Rules loaded")
+	return nil
+}
+
+// compile validates a rule and parses its condition
+func compile(rule *Rule) (*compiledRule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	condition, err := communication.ParseFilterExpression(rule.Condition)
+	if err != nil {
+		return nil, err
+	}
+	copied := *rule
+	return &compiledRule{Rule: &copied, condition: condition}, nil
+}
+
+// Create stores a new rule and starts evaluating it
+func (s *Service) Create(ctx context.Context, rule *Rule) error {
+	rule.ID = uuid.New().String()
+	compiled, err := compile(rule)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return err
+	}
+
+	s.activate(compiled)
+	s.logger.WithFields(log.Fields{"rule_id": rule.ID, "name": rule.Name}).Info("Created rule")
+	return nil
+}
+
+// Get returns a rule
+func (s *Service) Get(ctx context.Context, id string) (*Rule, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns every rule ordered by name
+func (s *Service) List(ctx context.Context) ([]*Rule, error) {
+	return s.repo.List(ctx)
+}
+
+// Update replaces a rule. Consecutive reading counts start over.
+func (s *Service) Update(ctx context.Context, rule *Rule) error {
+	compiled, err := compile(rule)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, rule); err != nil {
+		return err
+	}
+
+	s.activate(compiled)
+	return nil
+}
+
+// Delete removes a rule and stops evaluating it. Its triggers stay in the history.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rules, id)
+	delete(s.streaks, id)
+	return nil
+}
+
+// activate replaces the evaluated version of a rule and resets its streaks
+func (s *Service) activate(compiled *compiledRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[compiled.ID] = compiled
+	delete(s.streaks, compiled.ID)
+}
+
+// Triggers returns the most recent triggers, newest first, optionally of one rule
+func (s *Service) Triggers(ruleID string, limit int) []*Trigger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var triggers []*Trigger
+	for i := len(s.triggers) - 1; i >= 0; i-- {
+		if limit > 0 && len(triggers) == limit {
+			break
+		}
+		t := s.triggers[i]
+		if ruleID != "" && t.RuleID != ruleID {
+			continue
+		}
+		copied := *t
+		copied.Actions = append([]ActionResult(nil), t.Actions...)
+		triggers = append(triggers, &copied)
+	}
+	return triggers
+}
+
+// Observe evaluates a reading and runs the actions of the rules it triggers
+func (s *Service) Observe(ctx context.Context, reading *Reading) []*Trigger {
+	triggers := s.evaluate(reading)
+	for _, t := range triggers {
+		s.execute(ctx, t)
+	}
+	return triggers
+}
+
+// observeAsync evaluates a reading in order with the readings before it but
+// runs the actions in the background, so observers do not block publishers
+func (s *Service) observeAsync(ctx context.Context, reading *Reading) {
+	triggers := s.evaluate(reading)
+	if len(triggers) == 0 {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		for _, t := range triggers {
+			s.execute(ctx, t)
+		}
+	}()
+}
+
+// ObservePublications returns a publish observer that evaluates publication rules
+func (s *Service) ObservePublications() communication.PublishObserver {
+	return func(ctx context.Context, pub *communication.Publication) {
+		// Alerts published by rules are not evaluated again
+		if pub.PublisherAgentID == EngineAgentID {
+			return
+		}
+		s.observeAsync(ctx, ReadingFromPublication(pub))
+	}
+}
+
+// ObserveTelemetry returns an ingest observer that evaluates telemetry rules.
+// The points of an ingestion are evaluated oldest first.
+func (s *Service) ObserveTelemetry() telemetry.IngestObserver {
+	return func(ctx context.Context, points []*telemetry.Point) {
+		ordered := append([]*telemetry.Point(nil), points...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+		})
+		for _, p := range ordered {
+			s.observeAsync(ctx, ReadingFromPoint(p))
+		}
+	}
+}
+
+// evaluate updates the streaks of the rules watching a reading and returns
+// the triggers of those whose condition now holds for enough readings
+func (s *Service) evaluate(reading *Reading) []*Trigger {
+	if reading.At.IsZero() {
+		reading.At = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var triggers []*Trigger
+	for _, rule := range s.rules {
+		if !rule.watches(reading) {
+			continue
+		}
+
+		agents, ok := s.streaks[rule.ID]
+		if !ok {
+			agents = make(map[string]*streak)
+			s.streaks[rule.ID] = agents
+		}
+		st, ok := agents[reading.AgentID]
+		if !ok {
+			st = &streak{}
+			agents[reading.AgentID] = st
+		}
+
+		if !rule.condition.Matches(reading.Values) {
+			st.count = 0
+			st.fired = false
+			continue
+		}
+
+		st.count++
+		if st.fired || st.count < max(rule.Consecutive, 1) {
+			continue
+		}
+		cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+		if !st.lastFired.IsZero() && reading.At.Sub(st.lastFired) < cooldown {
+			continue
+		}
+
+		st.fired = true
+		st.lastFired = reading.At
+		t := &Trigger{
+			ID:          uuid.New().String(),
+			RuleID:      rule.ID,
+			RuleName:    rule.Name,
+			AgentID:     reading.AgentID,
+			Source:      reading.Source,
+			Name:        reading.Name,
+			Condition:   rule.Condition,
+			Consecutive: st.count,
+			Values:      reading.Values,
+			TriggeredAt: reading.At,
+			actions:     rule.Actions,
+		}
+		triggers = append(triggers, t)
+		s.triggers = append(s.triggers, t)
+	}
+
+	if excess := len(s.triggers) - historySize; excess > 0 {
+		s.triggers = append([]*Trigger(nil), s.triggers[excess:]...)
+	}
+	return triggers
+}
+
+// execute runs the actions of a trigger and records their outcomes
+func (s *Service) execute(ctx context.Context, t *Trigger) {
+	results := make([]ActionResult, 0, len(t.actions))
+	for _, action := range t.actions {
+		result := ActionResult{Type: action.Type, Target: action.Target(), Succeeded: true}
+		reference, err := s.run(ctx, action, t.payload(action.Payload))
+		result.Reference = reference
+		if err != nil {
+			result.Succeeded = false
+			result.Error = err.Error()
+			s.logger.WithError(err).WithFields(log.Fields{
+				"rule_id": t.RuleID,
+				"action":  action.Type,
+				"target":  result.Target,
+			}).Warn("Rule action failed")
+		}
+		results = append(results, result)
+	}
+
+	s.mu.Lock()
+	t.Actions = results
+	s.mu.Unlock()
+
+	s.logger.WithFields(log.Fields{
+		"rule_id":  t.RuleID,
+		"agent_id": t.AgentID,
+		"actions":  len(results),
+	}).Info("Rule triggered")
+}
+
+// run performs one action and returns the ID of what it created
+func (s *Service) run(ctx context.Context, action Action, payload map[string]interface{}) (string, error) {
+	switch action.Type {
+	case ActionPublish:
+		if s.publisher == nil {
+			return "", fmt.Errorf("pub/sub is not available")
+		}
+		return s.publisher.Publish(ctx, EngineAgentID, EngineAgentID, action.Topic, payload, &communication.PublicationOptions{
+			Type: communication.PublicationTypeAlert,
+		})
+
+	case ActionMessage:
+		if s.messenger == nil {
+			return "", fmt.Errorf("agent messaging is not available")
+		}
+		msgType := action.MessageType
+		if msgType == "" {
+			msgType = communication.MessageTypeNotification
+		}
+		return s.messenger.SendMessage(ctx, EngineAgentID, action.AgentID, msgType, payload, nil)
+
+	case ActionWorkflow:
+		if s.workflows == nil {
+			return "", fmt.Errorf("workflows are not available")
+		}
+		return s.workflows.StartWorkflow(ctx, action.WorkflowID, EngineAgentID, payload)
+	}
+
+	return "", fmt.Errorf("unknown action type: %s", action.Type)
+}
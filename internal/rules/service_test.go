@@ -0,0 +1,238 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/telemetry"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type published struct {
+	topic   string
+	payload map[string]interface{}
+	opts    *communication.PublicationOptions
+}
+
+// fakeActions records the actions rules run
+type fakeActions struct {
+	mu         sync.Mutex
+	published  chan published
+	messages   []string
+	workflows  []string
+	messageErr error
+}
+
+func newFakeActions() *fakeActions {
+	return &fakeActions{published: make(chan published, 10)}
+}
+
+func (f *fakeActions) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *communication.PublicationOptions) (string, error) {
+	f.published <- published{eventName, payload, opts}
+	return "pub-1", nil
+}
+
+func (f *fakeActions) SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, toAgentID+":"+string(msgType))
+	return "msg-1", f.messageErr
+}
+
+func (f *fakeActions) StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.workflows = append(f.workflows, workflowID+":"+input["agent_id"].(string))
+	return "exec-1", nil
+}
+
+func newTestService(t *testing.T) (*Service, *fakeActions) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	actions := newFakeActions()
+	service := NewService(NewInMemoryRepository(), logger)
+	service.SetPublisher(actions)
+	service.SetMessenger(actions)
+	service.SetWorkflows(actions)
+	return service, actions
+}
+
+func pressure(agentID string, value float64, at time.Time) *Reading {
+	return ReadingFromPoint(&telemetry.Point{AgentID: agentID, Metric: "pressure_bar", Value: value, Timestamp: at})
+}
+
+func TestService_ConsecutiveReadings(t *testing.T) {
+	service, actions := newTestService(t)
+	ctx := context.Background()
+
+	rule := &Rule{
+		Name:            "Low pressure",
+		Source:          SourceTelemetry,
+		Metric:          "pressure_bar",
+		Agents:          []string{"SENSOR-*"},
+		Condition:       "pressure_bar < 5.5",
+		Consecutive:     3,
+		CooldownSeconds: 3600,
+		Actions: []Action{
+			{Type: ActionPublish, Topic: "zone.north.pressure.alerts", Payload: map[string]interface{}{"severity": "HIGH"}},
+		},
+	}
+	require.NoError(t, service.Create(ctx, rule))
+	require.NotEmpty(t, rule.ID)
+
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	observe := func(minute int, value float64) []*Trigger {
+		return service.Observe(ctx, pressure("SENSOR-001", value, start.Add(time.Duration(minute)*time.Minute)))
+	}
+
+	// A reading above the threshold breaks the streak
+	assert.Empty(t, observe(0, 5.4))
+	assert.Empty(t, observe(1, 5.3))
+	assert.Empty(t, observe(2, 5.6))
+	assert.Empty(t, observe(3, 5.2))
+	assert.Empty(t, observe(4, 5.1))
+	assert.Empty(t, service.Observe(ctx, pressure("PUMP-001", 1, start)), "agent is not watched")
+
+	triggers := observe(5, 5.0)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, 3, triggers[0].Consecutive)
+	assert.Equal(t, "SENSOR-001", triggers[0].AgentID)
+	require.Len(t, triggers[0].Actions, 1)
+	assert.True(t, triggers[0].Actions[0].Succeeded)
+	assert.Equal(t, "pub-1", triggers[0].Actions[0].Reference)
+
+	alert := <-actions.published
+	assert.Equal(t, "zone.north.pressure.alerts", alert.topic)
+	assert.Equal(t, communication.PublicationTypeAlert, alert.opts.Type)
+	assert.Equal(t, "HIGH", alert.payload["severity"])
+	assert.Equal(t, AlertType, alert.payload["alert_type"])
+	assert.Equal(t, rule.ID, alert.payload["rule_id"])
+
+	// Still low: no new trigger until the condition clears
+	assert.Empty(t, observe(6, 4.9))
+	assert.Empty(t, observe(7, 6.0))
+	for minute := 8; minute < 11; minute++ {
+		assert.Empty(t, observe(minute, 5.0), "within the cooldown")
+	}
+	assert.Len(t, observe(65, 5.0), 1, "cooldown passed while the condition held")
+
+	history := service.Triggers(rule.ID, 0)
+	require.Len(t, history, 2)
+	assert.True(t, history[0].TriggeredAt.After(history[1].TriggeredAt), "newest first")
+}
+
+func TestService_PublicationRuleActions(t *testing.T) {
+	service, actions := newTestService(t)
+	actions.messageErr = errors.New("recipient offline")
+	ctx := context.Background()
+
+	require.NoError(t, service.Create(ctx, &Rule{
+		Name:      "Pump degradation",
+		Source:    SourcePublication,
+		Event:     "zone.*.pump.diagnostics",
+		Condition: "severity >= HIGH && efficiency_drop > 10",
+		Actions: []Action{
+			{Type: ActionMessage, AgentID: "COORD-NORTH"},
+			{Type: ActionWorkflow, WorkflowID: "wf-maintenance"},
+		},
+	}))
+
+	pub := &communication.Publication{
+		PublisherAgentID: "PUMP-002",
+		EventName:        "zone.north.pump.diagnostics",
+		Payload:          map[string]interface{}{"severity": "MEDIUM", "efficiency_drop": 13.9},
+		PublishedAt:      time.Now(),
+	}
+	assert.Empty(t, service.Observe(ctx, ReadingFromPublication(pub)))
+
+	pub.Payload = map[string]interface{}{"severity": "CRITICAL", "efficiency_drop": 13.9}
+	triggers := service.Observe(ctx, ReadingFromPublication(pub))
+	require.Len(t, triggers, 1)
+	require.Len(t, triggers[0].Actions, 2)
+	assert.False(t, triggers[0].Actions[0].Succeeded)
+	assert.Equal(t, "recipient offline", triggers[0].Actions[0].Error)
+	assert.True(t, triggers[0].Actions[1].Succeeded)
+	assert.Equal(t, "exec-1", triggers[0].Actions[1].Reference)
+
+	assert.Equal(t, []string{"COORD-NORTH:notification"}, actions.messages)
+	assert.Equal(t, []string{"wf-maintenance:PUMP-002"}, actions.workflows)
+}
+
+func TestService_ObserversAndLifecycle(t *testing.T) {
+	service, actions := newTestService(t)
+	ctx := context.Background()
+
+	rule := &Rule{
+		Name:      "Any low pressure",
+		Source:    SourceTelemetry,
+		Metric:    "pressure_bar",
+		Condition: "value < 5.5",
+		Actions:   []Action{{Type: ActionPublish, Topic: "pressure.low"}},
+	}
+	require.NoError(t, service.Create(ctx, rule))
+
+	ingest := telemetry.NewService(telemetry.NewInMemoryRepository(), telemetry.Config{}, nil)
+	ingest.AddIngestObserver(service.ObserveTelemetry())
+	require.NoError(t, ingest.Ingest(ctx, []*telemetry.Point{{AgentID: "SENSOR-002", Metric: "pressure_bar", Value: 4.8}}))
+
+	select {
+	case alert := <-actions.published:
+		assert.Equal(t, "pressure.low", alert.topic)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the ingest observer to trigger the rule")
+	}
+
+	rule.Disabled = true
+	require.NoError(t, service.Update(ctx, rule))
+	assert.Empty(t, service.Observe(ctx, pressure("SENSOR-003", 1, time.Now())))
+
+	// Rules are evaluated again after a restart
+	reloaded := NewService(service.repo, nil)
+	rule.Disabled = false
+	require.NoError(t, service.Update(ctx, rule))
+	require.NoError(t, reloaded.Load(ctx))
+	assert.Len(t, reloaded.Observe(ctx, pressure("SENSOR-003", 1, time.Now())), 1)
+
+	require.NoError(t, service.Delete(ctx, rule.ID))
+	assert.Empty(t, service.Observe(ctx, pressure("SENSOR-004", 1, time.Now())))
+	_, err := service.Get(ctx, rule.ID)
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{
+		Name:      "Low pressure",
+		Source:    SourceTelemetry,
+		Metric:    "pressure_bar",
+		Condition: "pressure_bar < 5.5",
+		Actions:   []Action{{Type: ActionPublish, Topic: "alerts"}},
+	}
+	require.NoError(t, valid.Validate())
+
+	for name, mutate := range map[string]func(r *Rule){
+		"no name":            func(r *Rule) { r.Name = "" },
+		"unknown source":     func(r *Rule) { r.Source = "logs" },
+		"no metric":          func(r *Rule) { r.Metric = "" },
+		"no event":           func(r *Rule) { r.Source = SourcePublication },
+		"empty condition":    func(r *Rule) { r.Condition = "" },
+		"bad condition":      func(r *Rule) { r.Condition = "pressure_bar" },
+		"negative count":     func(r *Rule) { r.Consecutive = -1 },
+		"no actions":         func(r *Rule) { r.Actions = nil },
+		"action target":      func(r *Rule) { r.Actions = []Action{{Type: ActionMessage}} },
+		"unknown action":     func(r *Rule) { r.Actions = []Action{{Type: "email", Topic: "x"}} },
+		"bad agent pattern":  func(r *Rule) { r.Agents = []string{"PUMP-["} },
+		"bad metric pattern": func(r *Rule) { r.Metric = "pressure_[" },
+	} {
+		rule := valid
+		mutate(&rule)
+		assert.ErrorIs(t, rule.Validate(), ErrInvalidRule, name)
+	}
+}
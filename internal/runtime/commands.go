@@ -0,0 +1,268 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// CommandSenderID is the sender of the messages delivering lifecycle commands.
+// Commands to a tenant's agents are sent from the tenant's namespace.
+const CommandSenderID = "lifecycle-commander"
+
+// maxCommandsPerAgent bounds the command history kept for each agent
+const maxCommandsPerAgent = 100
+
+// ErrCommandNotFound is returned for unknown command IDs
+var ErrCommandNotFound = errors.New("command not found")
+
+// Command is a lifecycle command operators send to an agent
+type Command string
+
+const (
+	// CommandStop stops the agent at once
+	CommandStop Command = "stop"
+	// CommandRestart stops the agent and starts it again
+	CommandRestart Command = "restart"
+	// CommandDrain finishes the agent's current tasks, accepting no new ones, then stops it
+	CommandDrain Command = "drain"
+)
+
+// CommandStatus is the progress of a lifecycle command on the framework side
+type CommandStatus string
+
+const (
+	// CommandStatusExecuting indicates the command is being carried out, e.g. an agent draining
+	CommandStatusExecuting CommandStatus = "executing"
+	// CommandStatusCompleted indicates the command was carried out
+	CommandStatusCompleted CommandStatus = "completed"
+	// CommandStatusFailed indicates the command could not be carried out
+	CommandStatusFailed CommandStatus = "failed"
+)
+
+// AgentCommand is a lifecycle command sent to an agent. The command is
+// carried out by the runtime manager and delivered to the agent as a command
+// message, which the agent acknowledges.
+type AgentCommand struct {
+	// ID is also the ID of the message delivering the command
+	ID       string        `json:"id"`
+	AgentID  string        `json:"agent_id"`
+	Command  Command       `json:"command"`
+	Reason   string        `json:"reason,omitempty"`
+	IssuedBy string        `json:"issued_by,omitempty"`
+	Status   CommandStatus `json:"status"`
+	Error    string        `json:"error,omitempty"`
+
+	// Delivery is the status of the command message: pending until the agent
+	// fetched it, then delivered
+	Delivery       communication.MessageStatus `json:"delivery"`
+	DeliveredAt    *time.Time                  `json:"delivered_at,omitempty"`
+	Acknowledged   bool                        `json:"acknowledged"`
+	AcknowledgedAt *time.Time                  `json:"acknowledged_at,omitempty"`
+
+	IssuedAt    time.Time  `json:"issued_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CommandMessenger delivers command messages and tracks their
+// acknowledgement. MessageService implements it.
+type CommandMessenger interface {
+	SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error)
+	GetMessage(ctx context.Context, messageID string) (*communication.Message, error)
+	AcknowledgeMessage(ctx context.Context, messageID string) error
+}
+
+// Commander sends lifecycle commands to agents and tracks them
+type Commander struct {
+	manager   *Manager
+	messenger CommandMessenger
+	logger    *logrus.Logger
+
+	mu       sync.RWMutex
+	commands map[string]*AgentCommand
+	byAgent  map[string][]string // Command IDs by agent, oldest first
+}
+
+// NewCommander creates a commander for the manager's agents
+func NewCommander(manager *Manager, messenger CommandMessenger, logger *logrus.Logger) *Commander {
+	return &Commander{
+		manager:   manager,
+		messenger: messenger,
+		logger:    logger,
+		commands:  make(map[string]*AgentCommand),
+		byAgent:   make(map[string][]string),
+	}
+}
+
+// Issue delivers a command to an agent and carries it out. Stop and restart
+// complete before Issue returns; a drain keeps executing until the agent has
+// finished its tasks.
+func (c *Commander) Issue(ctx context.Context, agentID string, command Command, reason, issuedBy string) (*AgentCommand, error) {
+	switch command {
+	case CommandStop, CommandRestart, CommandDrain:
+	default:
+		return nil, fmt.Errorf("unknown command %q: must be stop, restart or drain", command)
+	}
+
+	if _, err := c.manager.GetAgent(agentID); err != nil {
+		return nil, err
+	}
+
+	payload := map[string]interface{}{"command": string(command)}
+	if reason != "" {
+		payload["reason"] = reason
+	}
+	senderID := tenant.Qualify(tenant.Owner(agentID), CommandSenderID)
+	messageID, err := c.messenger.SendMessage(ctx, senderID, agentID, communication.MessageTypeCommand, payload, &communication.MessageOptions{
+		Priority: 10,
+		Metadata: map[string]string{"command": string(command)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to deliver command: %w", err)
+	}
+
+	cmd := &AgentCommand{
+		ID:       messageID,
+		AgentID:  agentID,
+		Command:  command,
+		Reason:   reason,
+		IssuedBy: issuedBy,
+		Status:   CommandStatusExecuting,
+		Delivery: communication.MessageStatusPending,
+		IssuedAt: time.Now().UTC(),
+	}
+	c.store(cmd)
+
+	c.logger.WithFields(logrus.Fields{
+		"command_id": cmd.ID,
+		"agent_id":   agentID,
+		"command":    command,
+	}).Info("Lifecycle command issued")
+
+	switch command {
+	case CommandStop:
+		c.complete(cmd.ID, c.manager.StopAgent(agentID))
+	case CommandRestart:
+		c.complete(cmd.ID, c.manager.RestartAgent(agentID))
+	case CommandDrain:
+		drained, err := c.manager.DrainAgent(c.manager.ctx, agentID)
+		if err != nil {
+			c.complete(cmd.ID, err)
+			break
+		}
+		go func() {
+			c.complete(cmd.ID, <-drained)
+		}()
+	}
+
+	return c.Get(ctx, cmd.ID)
+}
+
+// Get returns a command with the current delivery status of its message
+func (c *Commander) Get(ctx context.Context, commandID string) (*AgentCommand, error) {
+	c.mu.RLock()
+	stored, exists := c.commands[commandID]
+	var cmd AgentCommand
+	if exists {
+		cmd = *stored
+	}
+	c.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrCommandNotFound
+	}
+
+	msg, err := c.messenger.GetMessage(ctx, commandID)
+	if err != nil {
+		// Expired messages may have been cleaned up; keep the last known status
+		c.logger.WithError(err).WithField("command_id", commandID).Debug("Failed to get command message")
+		return &cmd, nil
+	}
+	cmd.Delivery = msg.Status
+	cmd.DeliveredAt = msg.DeliveredAt
+	cmd.Acknowledged = msg.AcknowledgedAt != nil
+	cmd.AcknowledgedAt = msg.AcknowledgedAt
+
+	c.mu.Lock()
+	stored.Delivery, stored.DeliveredAt = cmd.Delivery, cmd.DeliveredAt
+	stored.Acknowledged, stored.AcknowledgedAt = cmd.Acknowledged, cmd.AcknowledgedAt
+	c.mu.Unlock()
+
+	return &cmd, nil
+}
+
+// List returns the commands sent to an agent, newest first
+func (c *Commander) List(ctx context.Context, agentID string) []*AgentCommand {
+	c.mu.RLock()
+	ids := append([]string{}, c.byAgent[agentID]...)
+	c.mu.RUnlock()
+
+	commands := make([]*AgentCommand, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		if cmd, err := c.Get(ctx, ids[i]); err == nil {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// Acknowledge records that the agent received a command
+func (c *Commander) Acknowledge(ctx context.Context, commandID string) (*AgentCommand, error) {
+	c.mu.RLock()
+	_, exists := c.commands[commandID]
+	c.mu.RUnlock()
+
+	if !exists {
+		return nil, ErrCommandNotFound
+	}
+	if err := c.messenger.AcknowledgeMessage(ctx, commandID); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge command: %w", err)
+	}
+	return c.Get(ctx, commandID)
+}
+
+// store records a new command, dropping the agent's oldest beyond the limit
+func (c *Commander) store(cmd *AgentCommand) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.commands[cmd.ID] = cmd
+	ids := append(c.byAgent[cmd.AgentID], cmd.ID)
+	if len(ids) > maxCommandsPerAgent {
+		for _, id := range ids[:len(ids)-maxCommandsPerAgent] {
+			delete(c.commands, id)
+		}
+		ids = ids[len(ids)-maxCommandsPerAgent:]
+	}
+	c.byAgent[cmd.AgentID] = ids
+}
+
+// complete records the outcome of carrying out a command
+func (c *Commander) complete(commandID string, err error) {
+	now := time.Now().UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cmd, exists := c.commands[commandID]
+	if !exists {
+		return
+	}
+	cmd.CompletedAt = &now
+	cmd.Status = CommandStatusCompleted
+	if err != nil {
+		cmd.Status = CommandStatusFailed
+		cmd.Error = err.Error()
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"command_id": commandID,
+			"agent_id":   cmd.AgentID,
+			"command":    cmd.Command,
+		}).Warn("Lifecycle command failed")
+	}
+}
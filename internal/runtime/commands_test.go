@@ -0,0 +1,146 @@
+package runtime_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMessenger stores command messages in memory
+type fakeMessenger struct {
+	mu       sync.Mutex
+	messages map[string]*communication.Message
+}
+
+func (f *fakeMessenger) SendMessage(ctx context.Context, fromAgentID, toAgentID string, msgType communication.MessageType, payload map[string]interface{}, opts *communication.MessageOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := fmt.Sprintf("msg-%d", len(f.messages)+1)
+	f.messages[id] = &communication.Message{
+		ID:          id,
+		FromAgentID: fromAgentID,
+		ToAgentID:   toAgentID,
+		MessageType: msgType,
+		Payload:     payload,
+		Status:      communication.MessageStatusPending,
+	}
+	return id, nil
+}
+
+func (f *fakeMessenger) GetMessage(ctx context.Context, messageID string) (*communication.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	msg, exists := f.messages[messageID]
+	if !exists {
+		return nil, fmt.Errorf("message not found")
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func (f *fakeMessenger) AcknowledgeMessage(ctx context.Context, messageID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	f.messages[messageID].Status = communication.MessageStatusDelivered
+	f.messages[messageID].AcknowledgedAt = &now
+	return nil
+}
+
+func newTestCommander(t *testing.T) (*runtime.Commander, *runtime.Manager, *fakeMessenger) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	manager := newTestManager(logger, runtime.ManagerConfig{})
+	t.Cleanup(func() { manager.Shutdown() })
+	messenger := &fakeMessenger{messages: make(map[string]*communication.Message)}
+	return runtime.NewCommander(manager, messenger, logger), manager, messenger
+}
+
+func TestCommander_StopAndRestart(t *testing.T) {
+	ctx := context.Background()
+	commander, manager, messenger := newTestCommander(t)
+
+	a, err := manager.CreateAgent("pump", "pump", agent.Config{})
+	require.NoError(t, err)
+	require.NoError(t, manager.StartAgent(a.ID))
+
+	cmd, err := commander.Issue(ctx, a.ID, runtime.CommandStop, "maintenance", "operator-1")
+	require.NoError(t, err)
+	assert.Equal(t, runtime.CommandStatusCompleted, cmd.Status)
+	assert.Equal(t, agent.StateStopped, a.GetState())
+
+	// The command was delivered as a command message
+	msg, err := messenger.GetMessage(ctx, cmd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, communication.MessageTypeCommand, msg.MessageType)
+	assert.Equal(t, "stop", msg.Payload["command"])
+	assert.Equal(t, runtime.CommandSenderID, msg.FromAgentID)
+
+	cmd, err = commander.Issue(ctx, a.ID, runtime.CommandRestart, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, runtime.CommandStatusCompleted, cmd.Status)
+	assert.Equal(t, agent.StateRunning, a.GetState())
+	assert.NoError(t, a.SubmitTask(agent.Task{ID: "after-restart"}))
+
+	commands := commander.List(ctx, a.ID)
+	require.Len(t, commands, 2)
+	assert.Equal(t, runtime.CommandRestart, commands[0].Command, "newest first")
+}
+
+func TestCommander_Drain(t *testing.T) {
+	ctx := context.Background()
+	commander, manager, _ := newTestCommander(t)
+
+	a, err := manager.CreateAgent("sensor", "sensor", agent.Config{MaxConcurrentTasks: 1})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, a.SubmitTask(agent.Task{ID: fmt.Sprintf("task-%d", i), Timeout: time.Second}))
+	}
+	require.NoError(t, manager.StartAgent(a.ID))
+
+	cmd, err := commander.Issue(ctx, a.ID, runtime.CommandDrain, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, runtime.CommandStatusExecuting, cmd.Status)
+	assert.ErrorIs(t, a.SubmitTask(agent.Task{ID: "late"}), agent.ErrAgentDraining)
+
+	require.Eventually(t, func() bool {
+		cmd, err = commander.Get(ctx, cmd.ID)
+		return err == nil && cmd.Status == runtime.CommandStatusCompleted
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, agent.StateStopped, a.GetState())
+	assert.Equal(t, int64(3), manager.GetMetrics().TotalTasksExecuted, "queued tasks finish before the agent stops")
+}
+
+func TestCommander_Acknowledge(t *testing.T) {
+	ctx := context.Background()
+	commander, manager, _ := newTestCommander(t)
+
+	a, err := manager.CreateAgent("valve", "valve", agent.Config{})
+	require.NoError(t, err)
+
+	cmd, err := commander.Issue(ctx, a.ID, runtime.CommandDrain, "", "")
+	require.NoError(t, err)
+	assert.False(t, cmd.Acknowledged)
+
+	cmd, err = commander.Acknowledge(ctx, cmd.ID)
+	require.NoError(t, err)
+	assert.True(t, cmd.Acknowledged)
+	assert.NotNil(t, cmd.AcknowledgedAt)
+
+	// Agents that are not running cannot drain
+	assert.Equal(t, runtime.CommandStatusFailed, cmd.Status)
+
+	_, err = commander.Issue(ctx, "missing", runtime.CommandStop, "", "")
+	assert.ErrorIs(t, err, agent.ErrAgentNotFound)
+	_, err = commander.Get(ctx, "msg-unknown")
+	assert.ErrorIs(t, err, runtime.ErrCommandNotFound)
+}
@@ -12,6 +12,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// drainPollInterval is how often a draining agent is checked for remaining tasks
+const drainPollInterval = 50 * time.Millisecond
+
 // Manager manages the lifecycle of all agents in the system
 type Manager struct {
 	// agents maps agent ID to agent instance (in-memory cache)
@@ -20,6 +23,9 @@ type Manager struct {
 	// registry provides persistent storage for agents
 	registry *registry.Repository
 
+	// liveness tracks which agents heartbeat
+	liveness *registry.Liveness
+
 	// mu protects concurrent access to agents map
 	mu sync.RWMutex
 
@@ -40,6 +46,13 @@ type Manager struct {
 
 	// metrics tracks runtime metrics
 	metrics *metricsHolder
+
+	// resultHandlers are notified of every task result
+	resultHandlers []func(*agent.TaskResult)
+
+	// stateHandlers are notified of every agent state change
+	stateHandlers []func(a *agent.Agent, from, to agent.State)
+	handlersMu    sync.RWMutex
 }
 
 // ManagerConfig holds runtime manager configuration
@@ -55,6 +68,9 @@ type ManagerConfig struct {
 
 	// EnableMetrics toggles metrics collection
 	EnableMetrics bool
+
+	// Liveness configures when agents without heartbeats are degraded and offline
+	Liveness registry.LivenessConfig
 }
 
 // Metrics tracks runtime statistics
@@ -91,6 +107,7 @@ func NewManager(logger *logrus.Logger, config ManagerConfig, reg *registry.Repos
 	m := &Manager{
 		agents:   make(map[string]*agent.Agent),
 		registry: reg,
+		liveness: registry.NewLiveness(config.Liveness, nil),
 		ctx:      ctx,
 		cancel:   cancel,
 		logger:   logger,
@@ -182,6 +199,84 @@ func (m *Manager) CreateAgent(name, agentType string, config agent.Config) (*age
 	return a, nil
 }
 
+// RegisterAgent registers a pre-built agent, preserving its ID.
+// Used when callers need deterministic agent IDs (e.g. scenario bootstrap).
+func (m *Manager) RegisterAgent(a *agent.Agent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.agents[a.ID]; exists {
+		return fmt.Errorf("agent already exists: %s", a.ID)
+	}
+
+	// Check agent limit
+	if len(m.agents) >= m.config.MaxAgents {
+		return fmt.Errorf("agent limit reached: %d", m.config.MaxAgents)
+	}
+
+	// Persist to registry if available
+	if m.registry != nil {
+		if err := m.registry.Create(m.ctx, a); err != nil {
+			return fmt.Errorf("failed to persist agent to registry: %w", err)
+		}
+	}
+
+	m.agents[a.ID] = a
+
+	// A pre-built agent that is already running has started
+	if a.GetState() == agent.StateRunning {
+		m.liveness.Register(a.ID, a.Capabilities())
+	}
+
+	// Update metrics
+	m.metrics.mu.Lock()
+	m.metrics.metrics.TotalAgentsCreated++
+	m.metrics.metrics.CurrentActiveAgents++
+	m.metrics.mu.Unlock()
+
+	m.logger.WithFields(logrus.Fields{
+		"agent_id":   a.ID,
+		"agent_name": a.Name,
+		"agent_type": a.Type,
+	}).Info("Agent registered")
+
+	return nil
+}
+
+// UpdateAgent persists the descriptive fields of a (name, type and metadata)
+// and applies them to the registered agent. a is usually a copy of the
+// registered agent; the running instance is kept.
+func (m *Manager) UpdateAgent(a *agent.Agent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registered, exists := m.agents[a.ID]
+	if !exists {
+		return agent.ErrAgentNotFound
+	}
+
+	a.UpdatedAt = time.Now().UTC()
+
+	if m.registry != nil {
+		if err := m.registry.Update(m.ctx, a); err != nil {
+			return fmt.Errorf("failed to persist agent to registry: %w", err)
+		}
+	}
+
+	metadata := make(map[string]string, len(a.Metadata))
+	for k, v := range a.Metadata {
+		metadata[k] = v
+	}
+	registered.Name = a.Name
+	registered.Type = a.Type
+	registered.Metadata = metadata
+	registered.UpdatedAt = a.UpdatedAt
+
+	m.logger.WithField("agent_id", a.ID).Debug("Agent updated")
+
+	return nil
+}
+
 // StartAgent starts an agent and begins processing tasks
 func (m *Manager) StartAgent(agentID string) error {
 	m.mu.RLock()
@@ -192,14 +287,16 @@ func (m *Manager) StartAgent(agentID string) error {
 		return agent.ErrAgentNotFound
 	}
 
-	// Check current state
+	// Check current state; stopped agents start again with a fresh context
 	currentState := a.GetState()
-	if currentState != agent.StateCreated && currentState != agent.StatePaused {
+	if currentState == agent.StateStopped {
+		a.Reset()
+	} else if currentState != agent.StateCreated && currentState != agent.StatePaused {
 		return fmt.Errorf("cannot start agent in state: %s", currentState)
 	}
 
 	// Update state to running
-	a.SetState(agent.StateRunning)
+	m.setState(a, agent.StateRunning)
 
 	// Persist state change to registry
 	if m.registry != nil {
@@ -208,6 +305,8 @@ func (m *Manager) StartAgent(agentID string) error {
 		}
 	}
 
+	m.liveness.Register(a.ID, a.Capabilities())
+
 	// Start agent worker goroutines
 	m.wg.Add(1)
 	go m.runAgent(a)
@@ -221,6 +320,9 @@ func (m *Manager) StartAgent(agentID string) error {
 func (m *Manager) runAgent(a *agent.Agent) {
 	defer m.wg.Done()
 
+	// The context of this run; a restarted agent gets a new one
+	ctx := a.Context()
+
 	// Create worker pool based on agent config
 	workerCount := a.Config.MaxConcurrentTasks
 	taskResults := make(chan *agent.TaskResult, workerCount*2)
@@ -228,24 +330,26 @@ func (m *Manager) runAgent(a *agent.Agent) {
 	// Start worker goroutines
 	for i := 0; i < workerCount; i++ {
 		m.wg.Add(1)
-		go m.agentWorker(a, i, taskResults)
+		go m.agentWorker(ctx, a, i, taskResults)
 	}
 
 	// Start heartbeat goroutine
 	m.wg.Add(1)
-	go m.agentHeartbeat(a)
+	go m.agentHeartbeat(ctx, a)
 
 	// Process task results
 	for {
 		select {
 		case <-m.ctx.Done():
 			m.logger.WithField("agent_id", a.ID).Info("Manager shutting down, stopping agent")
-			a.SetState(agent.StateStopped)
+			m.setState(a, agent.StateStopped)
 			return
 
-		case <-a.Context().Done():
+		case <-ctx.Done():
 			m.logger.WithField("agent_id", a.ID).Info("Agent context cancelled, stopping")
-			a.SetState(agent.StateStopped)
+			if a.Context() == ctx {
+				m.setState(a, agent.StateStopped)
+			}
 			return
 
 		case err := <-a.Errors():
@@ -253,7 +357,7 @@ func (m *Manager) runAgent(a *agent.Agent) {
 				"agent_id": a.ID,
 				"error":    err,
 			}).Error("Agent error")
-			a.SetState(agent.StateFailed)
+			m.setState(a, agent.StateFailed)
 			return
 
 		case result := <-taskResults:
@@ -263,7 +367,7 @@ func (m *Manager) runAgent(a *agent.Agent) {
 }
 
 // agentWorker processes tasks from the agent's task channel
-func (m *Manager) agentWorker(a *agent.Agent, workerID int, results chan<- *agent.TaskResult) {
+func (m *Manager) agentWorker(ctx context.Context, a *agent.Agent, workerID int, results chan<- *agent.TaskResult) {
 	defer m.wg.Done()
 
 	m.logger.WithFields(logrus.Fields{
@@ -275,10 +379,11 @@ func (m *Manager) agentWorker(a *agent.Agent, workerID int, results chan<- *agen
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-a.Context().Done():
+		case <-ctx.Done():
 			return
 		case task := <-a.TaskChan():
 			result := m.executeTask(a, task)
+			a.TaskFinished()
 			results <- result
 		}
 	}
@@ -326,14 +431,13 @@ func (m *Manager) executeTask(a *agent.Agent, task agent.Task) *agent.TaskResult
 		// TODO: Implement actual task execution logic
 		// For now, simulate work
 		time.Sleep(100 * time.Millisecond)
-		result.Success = true
-		result.Result = "Task completed successfully"
 		close(done)
 	}()
 
 	select {
 	case <-done:
-		// Task completed successfully
+		result.Success = true
+		result.Result = "Task completed successfully"
 	case <-ctx.Done():
 		result.Success = false
 		result.Error = agent.ErrTaskTimeout
@@ -343,7 +447,7 @@ func (m *Manager) executeTask(a *agent.Agent, task agent.Task) *agent.TaskResult
 }
 
 // agentHeartbeat maintains agent health status
-func (m *Manager) agentHeartbeat(a *agent.Agent) {
+func (m *Manager) agentHeartbeat(ctx context.Context, a *agent.Agent) {
 	defer m.wg.Done()
 
 	ticker := time.NewTicker(a.Config.HeartbeatInterval)
@@ -353,10 +457,11 @@ func (m *Manager) agentHeartbeat(a *agent.Agent) {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-a.Context().Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
 			a.UpdateHeartbeat()
+			m.liveness.Heartbeat(a.ID, registry.Heartbeat{})
 		}
 	}
 }
@@ -378,7 +483,46 @@ func (m *Manager) handleTaskResult(a *agent.Agent, result *agent.TaskResult) {
 		}).Error("Task failed")
 	}
 
-	// TODO: Send result to result storage or event bus
+	m.handlersMu.RLock()
+	handlers := m.resultHandlers
+	m.handlersMu.RUnlock()
+	for _, handler := range handlers {
+		handler(result)
+	}
+}
+
+// OnTaskResult registers a handler notified of the result of every task the
+// manager's agents execute. Handlers run on the agent's result loop and
+// should not block.
+func (m *Manager) OnTaskResult(handler func(*agent.TaskResult)) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.resultHandlers = append(m.resultHandlers, handler)
+}
+
+// OnStateChange registers a handler notified whenever one of the manager's
+// agents changes state. Handlers run on the goroutine changing the state and
+// should not block.
+func (m *Manager) OnStateChange(handler func(a *agent.Agent, from, to agent.State)) {
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.stateHandlers = append(m.stateHandlers, handler)
+}
+
+// setState changes an agent's state and notifies the state change handlers
+func (m *Manager) setState(a *agent.Agent, state agent.State) {
+	from := a.GetState()
+	a.SetState(state)
+	if from == state {
+		return
+	}
+
+	m.handlersMu.RLock()
+	handlers := m.stateHandlers
+	m.handlersMu.RUnlock()
+	for _, handler := range handlers {
+		handler(a, from, state)
+	}
 }
 
 // StopAgent gracefully stops an agent
@@ -393,9 +537,10 @@ func (m *Manager) StopAgent(agentID string) error {
 
 	// Cancel agent context
 	a.Cancel()
+	m.liveness.Deregister(agentID)
 
 	// Update state
-	a.SetState(agent.StateStopped)
+	m.setState(a, agent.StateStopped)
 
 	// Persist state change to registry
 	if m.registry != nil {
@@ -432,7 +577,7 @@ func (m *Manager) PauseAgent(agentID string) error {
 	}
 
 	// Update state to paused
-	a.SetState(agent.StatePaused)
+	m.setState(a, agent.StatePaused)
 
 	// Persist state change to registry
 	if m.registry != nil {
@@ -463,7 +608,7 @@ func (m *Manager) ResumeAgent(agentID string) error {
 	}
 
 	// Update state to running
-	a.SetState(agent.StateRunning)
+	m.setState(a, agent.StateRunning)
 
 	// Persist state change to registry
 	if m.registry != nil {
@@ -477,6 +622,57 @@ func (m *Manager) ResumeAgent(agentID string) error {
 	return nil
 }
 
+// DrainAgent starts draining an agent: it finishes its queued and running
+// tasks, accepting no new ones, and then stops. The returned channel receives
+// the outcome of stopping the agent, or ctx's error when ctx ends first, in
+// which case the agent keeps draining.
+func (m *Manager) DrainAgent(ctx context.Context, agentID string) (<-chan error, error) {
+	m.mu.RLock()
+	a, exists := m.agents[agentID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, agent.ErrAgentNotFound
+	}
+
+	currentState := a.GetState()
+	if currentState != agent.StateRunning {
+		return nil, fmt.Errorf("cannot drain agent in state: %s", currentState)
+	}
+
+	m.setState(a, agent.StateDraining)
+
+	// Persist state change to registry
+	if m.registry != nil {
+		if err := m.registry.Update(m.ctx, a); err != nil {
+			m.logger.WithError(err).Warn("Failed to persist agent state to registry")
+		}
+	}
+
+	m.logger.WithField("agent_id", agentID).Info("Agent draining")
+
+	drained := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+
+		for a.UnfinishedTasks() > 0 {
+			select {
+			case <-ctx.Done():
+				drained <- ctx.Err()
+				return
+			case <-m.ctx.Done():
+				drained <- m.ctx.Err()
+				return
+			case <-ticker.C:
+			}
+		}
+		drained <- m.StopAgent(agentID)
+	}()
+
+	return drained, nil
+}
+
 // RestartAgent stops and restarts an agent
 func (m *Manager) RestartAgent(agentID string) error {
 	// Stop the agent
@@ -558,6 +754,13 @@ func (m *Manager) ListAgentsFromRegistry() ([]*agent.Agent, error) {
 	return agents, nil
 }
 
+// Liveness returns the tracker of which agents heartbeat. Agents run by the
+// manager register when started and heartbeat on their heartbeat interval;
+// agents running elsewhere register and heartbeat through the API.
+func (m *Manager) Liveness() *registry.Liveness {
+	return m.liveness
+}
+
 // healthCheckLoop periodically checks agent health
 func (m *Manager) healthCheckLoop() {
 	defer m.wg.Done()
@@ -1,6 +1,7 @@
 package runtime_test
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -311,3 +312,31 @@ func TestConcurrentOperations(t *testing.T) {
 	agents := manager.ListAgents()
 	assert.Equal(t, len(agentIDs), len(agents))
 }
+
+func TestOnStateChange(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	manager := newTestManager(logger, runtime.ManagerConfig{})
+	defer manager.Shutdown()
+
+	var mu sync.Mutex
+	var changes []string
+	manager.OnStateChange(func(a *agent.Agent, from, to agent.State) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, string(from)+"->"+string(to))
+	})
+
+	a, err := manager.CreateAgent("test-agent", "worker", agent.Config{})
+	require.NoError(t, err)
+	require.NoError(t, manager.StartAgent(a.ID))
+	require.NoError(t, manager.PauseAgent(a.ID))
+	require.NoError(t, manager.ResumeAgent(a.ID))
+	require.NoError(t, manager.StopAgent(a.ID))
+
+	// The agent's run loop may see the stop first; it is reported once
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"created->running", "running->paused", "paused->running", "running->stopped"}, changes)
+}
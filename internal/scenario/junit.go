@@ -0,0 +1,98 @@
+package scenario
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// junitSuite is a JUnit XML test suite
+type junitSuite struct {
+	XMLName   xml.Name    `xml:"testsuite"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+	Output    string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML with a test case per step, so CI
+// systems can show scenario runs as integration test results
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:      r.Scenario,
+		Tests:     len(r.Steps) + len(r.Skipped),
+		Skipped:   len(r.Skipped),
+		Time:      seconds(r.Duration),
+		Timestamp: r.StartedAt.UTC().Format(time.RFC3339),
+	}
+
+	for _, step := range r.Steps {
+		tc := junitCase{
+			Name:      fmt.Sprintf("%d. %s", step.Index, step.Name),
+			ClassName: r.Scenario,
+			Time:      seconds(step.Duration),
+			Output:    step.Detail,
+		}
+		if !step.Passed {
+			suite.Failures++
+			tc.Failure = &junitFailure{Message: step.Error, Text: failureText(step)}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	for i, name := range r.Skipped {
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      fmt.Sprintf("%d. %s", len(r.Steps)+i+1, name),
+			ClassName: r.Scenario,
+			Time:      seconds(0),
+			Skipped:   &struct{}{},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// failureText lists a failed step's assertions
+func failureText(step StepResult) string {
+	lines := []string{step.Error}
+	for _, a := range step.Assertions {
+		mark := "PASS"
+		outcome := a.Detail
+		if !a.Passed {
+			mark, outcome = "FAIL", a.Error
+		}
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", mark, a.Description, outcome))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,684 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/pkg/client"
+	"github.com/google/uuid"
+)
+
+// defaultPollInterval is how often unmet expectations are checked again
+const defaultPollInterval = 500 * time.Millisecond
+
+// API is the part of the framework API scenarios drive. client.Client
+// implements it.
+type API interface {
+	Publish(ctx context.Context, req client.PublishRequest) (*client.PublishResponse, error)
+	SendMessage(ctx context.Context, req client.SendMessageRequest) (*client.SendMessageResponse, error)
+	ListMessages(ctx context.Context, query client.MessageQuery) ([]client.Message, error)
+	CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*client.TrafficCapture, error)
+	ListAgents(ctx context.Context, status string) ([]client.Agent, error)
+	GetClock(ctx context.Context) (*client.ClockStatus, error)
+	AdvanceClock(ctx context.Context, d time.Duration) (*client.ClockStatus, error)
+	PauseClock(ctx context.Context) (*client.ClockStatus, error)
+	ResumeClock(ctx context.Context) (*client.ClockStatus, error)
+	SetClockSpeed(ctx context.Context, speed float64) (*client.ClockStatus, error)
+}
+
+// RunnerConfig configures a scenario runner
+type RunnerConfig struct {
+	// Vars override the scenario's variables, e.g. from the command line
+	Vars map[string]string
+
+	// PollInterval is how often unmet expectations are checked again (default 500ms)
+	PollInterval time.Duration
+
+	// KeepGoing runs the remaining steps after a step fails
+	KeepGoing bool
+
+	// Clock times the steps (default: the real clock)
+	Clock clock.Clock
+
+	// OnStep is called with the result of every step as it completes
+	OnStep func(StepResult)
+}
+
+func (c RunnerConfig) withDefaults() RunnerConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	c.Clock = clock.OrReal(c.Clock)
+	return c
+}
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Index     int           `json:"index"` // 1-based
+	Name      string        `json:"name"`
+	Action    string        `json:"action"` // publish, message, expect, clock or wait
+	Passed    bool          `json:"passed"`
+	Error     string        `json:"error,omitempty"`
+	Detail    string        `json:"detail,omitempty"` // e.g. the publication ID
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+
+	// SimulatedAt is the simulated time the step ran at, on a simulated clock
+	SimulatedAt *time.Time `json:"simulated_at,omitempty"`
+
+	// Assertions are the outcomes of the step's Verify expectations
+	Assertions []AssertionResult `json:"assertions,omitempty"`
+}
+
+// AssertionResult is the outcome of one verified expectation
+type AssertionResult struct {
+	Description string        `json:"description"` // e.g. message to=VALVE-001 type=ISOLATION_COMMAND
+	Passed      bool          `json:"passed"`
+	Error       string        `json:"error,omitempty"`
+	Detail      string        `json:"detail,omitempty"` // What matched
+	Duration    time.Duration `json:"duration"`         // Until it was met or failed
+}
+
+// Report is the outcome of a scenario run
+type Report struct {
+	Scenario   string        `json:"scenario"`
+	RunID      string        `json:"run_id"`
+	Passed     bool          `json:"passed"`
+	Steps      []StepResult  `json:"steps"`
+	Skipped    []string      `json:"skipped,omitempty"` // Steps not run after a failure
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Runner runs scenarios against the framework API
+type Runner struct {
+	api    API
+	config RunnerConfig
+}
+
+// NewRunner creates a scenario runner
+func NewRunner(api API, config RunnerConfig) *Runner {
+	return &Runner{
+		api:    api,
+		config: config.withDefaults(),
+	}
+}
+
+// run is the state of one scenario run
+type run struct {
+	*Runner
+	scenario *Scenario
+	vars     map[string]string
+	start    time.Time
+	previous time.Time // When the previous step finished
+
+	// The same in simulated time, when the scenario runs on the simulated clock
+	simStart    time.Time
+	simPrevious time.Time
+}
+
+// Run runs the scenario's steps in order. It stops at the first failed step
+// unless KeepGoing is set, and returns the context's error if it is
+// cancelled. Failed steps are reported, not returned as errors.
+func (r *Runner) Run(ctx context.Context, s *Scenario) (*Report, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	start := r.config.Clock.Now()
+	report := &Report{
+		Scenario:  s.Name,
+		RunID:     uuid.New().String()[:8],
+		Passed:    true,
+		Steps:     []StepResult{},
+		StartedAt: start,
+	}
+
+	vars := map[string]string{VarScenario: s.Name, VarRunID: report.RunID}
+	for name, value := range s.Vars {
+		vars[name] = value
+	}
+	for name, value := range r.config.Vars {
+		vars[name] = value
+	}
+
+	rn := &run{Runner: r, scenario: s, vars: vars, start: start, previous: start}
+	if s.Clock != nil {
+		status, err := rn.startClock(ctx, s.Clock)
+		if err != nil {
+			return report, fmt.Errorf("failed to set up the simulated clock: %w", err)
+		}
+		rn.simStart, rn.simPrevious = status.Now, status.Now
+	}
+
+	for i, step := range s.Steps {
+		if err := rn.wait(ctx, step); err != nil {
+			return report, err
+		}
+
+		result := rn.runStep(ctx, i+1, step)
+		report.Steps = append(report.Steps, result)
+		if r.config.OnStep != nil {
+			r.config.OnStep(result)
+		}
+		rn.previous = r.config.Clock.Now()
+		if s.Clock != nil {
+			if status, err := r.api.GetClock(ctx); err == nil {
+				rn.simPrevious = status.Now
+			}
+		}
+
+		if !result.Passed {
+			report.Passed = false
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			if !r.config.KeepGoing {
+				for _, skipped := range s.Steps[i+1:] {
+					report.Skipped = append(report.Skipped, stepName(skipped, vars))
+				}
+				break
+			}
+		}
+	}
+
+	report.FinishedAt = r.config.Clock.Now()
+	report.Duration = report.FinishedAt.Sub(start)
+	return report, nil
+}
+
+// wait sleeps until the step is due
+func (rn *run) wait(ctx context.Context, step Step) error {
+	if rn.scenario.Clock != nil {
+		return rn.waitSimulated(ctx, step)
+	}
+
+	due := rn.previous.Add(step.After)
+	if step.At > 0 {
+		due = rn.start.Add(step.At)
+	}
+	return rn.sleep(ctx, due.Sub(rn.config.Clock.Now()))
+}
+
+// waitSimulated lets simulated time pass until the step is due: a paused
+// clock is advanced at once, a running one is waited for
+func (rn *run) waitSimulated(ctx context.Context, step Step) error {
+	if step.At == 0 && step.After == 0 {
+		return ctx.Err()
+	}
+
+	status, err := rn.api.GetClock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the simulated clock: %w", err)
+	}
+	due := rn.simPrevious.Add(step.After)
+	if step.At > 0 {
+		due = rn.simStart.Add(step.At)
+	}
+
+	d := due.Sub(status.Now)
+	switch {
+	case d <= 0:
+		return ctx.Err()
+	case status.Paused:
+		if _, err := rn.api.AdvanceClock(ctx, d); err != nil {
+			return fmt.Errorf("failed to advance the simulated clock: %w", err)
+		}
+		return nil
+	default:
+		return rn.sleep(ctx, time.Duration(float64(d)/status.Speed))
+	}
+}
+
+func (rn *run) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-rn.config.Clock.After(d):
+		return nil
+	}
+}
+
+// runStep runs one step and reports its outcome
+func (rn *run) runStep(ctx context.Context, index int, step Step) StepResult {
+	result := StepResult{
+		Index:     index,
+		Name:      step.Name,
+		StartedAt: rn.config.Clock.Now(),
+	}
+
+	// Every step sees the time it runs at
+	vars := make(map[string]string, len(rn.vars)+1)
+	for name, value := range rn.vars {
+		vars[name] = value
+	}
+	now := result.StartedAt
+	if rn.scenario.Clock != nil {
+		if status, err := rn.api.GetClock(ctx); err == nil {
+			now = status.Now
+			result.SimulatedAt = &now
+		}
+	}
+	vars[VarNow] = now.UTC().Format(time.RFC3339)
+	result.Name = stepName(step, vars)
+
+	var err error
+	switch {
+	case step.Publish != nil:
+		result.Action = "publish"
+		result.Detail, err = rn.publish(ctx, step.Publish, vars)
+	case step.Message != nil:
+		result.Action = "message"
+		result.Detail, err = rn.message(ctx, step.Message, vars)
+	case step.Expect != nil:
+		result.Action = "expect"
+		assertion := rn.verify(ctx, []Expectation{*step.Expect}, vars, rn.start)[0]
+		result.Detail = assertion.Detail
+		if !assertion.Passed {
+			err = errors.New(assertion.Error)
+		}
+	case step.Clock != nil:
+		result.Action = "clock"
+		result.Detail, err = rn.setClock(ctx, step.Clock)
+	default:
+		result.Action = "wait"
+	}
+
+	if err == nil && len(step.Verify) > 0 {
+		result.Assertions = rn.verify(ctx, step.Verify, vars, result.StartedAt)
+		failed := 0
+		for _, a := range result.Assertions {
+			if !a.Passed {
+				failed++
+			}
+		}
+		if failed > 0 {
+			err = fmt.Errorf("%d of %d assertions failed", failed, len(result.Assertions))
+		}
+	}
+
+	result.Passed = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Duration = rn.config.Clock.Since(result.StartedAt)
+	return result
+}
+
+// publish sends a publish step
+func (rn *run) publish(ctx context.Context, step *PublishStep, vars map[string]string) (string, error) {
+	fields, err := expandAll(vars, step.Publisher, step.PublisherType, step.Event, step.Type)
+	if err != nil {
+		return "", err
+	}
+	payload, err := expandPayload(step.Payload, vars)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := rn.api.Publish(ctx, client.PublishRequest{
+		PublisherAgentID:   fields[0],
+		PublisherAgentType: fields[1],
+		EventName:          fields[2],
+		PublicationType:    fields[3],
+		Payload:            payload,
+		TTLSeconds:         step.TTLSeconds,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to publish %s: %w", fields[2], err)
+	}
+	return "publication " + resp.PublicationID, nil
+}
+
+// message sends a message step
+func (rn *run) message(ctx context.Context, step *MessageStep, vars map[string]string) (string, error) {
+	fields, err := expandAll(vars, step.From, step.To, step.Type, step.CorrelationID)
+	if err != nil {
+		return "", err
+	}
+	payload, err := expandPayload(step.Payload, vars)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := rn.api.SendMessage(ctx, client.SendMessageRequest{
+		FromAgentID:   fields[0],
+		ToAgentID:     fields[1],
+		MessageType:   fields[2],
+		CorrelationID: fields[3],
+		Payload:       payload,
+		Priority:      step.Priority,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send %s to %s: %w", fields[2], fields[1], err)
+	}
+	return "message " + resp.MessageID, nil
+}
+
+// startClock sets the simulated clock up for the scenario
+func (rn *run) startClock(ctx context.Context, config *ClockConfig) (*client.ClockStatus, error) {
+	if config.Speed > 0 {
+		if _, err := rn.api.SetClockSpeed(ctx, config.Speed); err != nil {
+			return nil, err
+		}
+	}
+	if config.Paused {
+		return rn.api.PauseClock(ctx)
+	}
+	return rn.api.ResumeClock(ctx)
+}
+
+// setClock runs a clock step. Validation makes sure it does something.
+func (rn *run) setClock(ctx context.Context, step *ClockStep) (string, error) {
+	var status *client.ClockStatus
+	var err error
+	if step.Speed > 0 {
+		if status, err = rn.api.SetClockSpeed(ctx, step.Speed); err != nil {
+			return "", fmt.Errorf("failed to set the clock speed: %w", err)
+		}
+	}
+	switch {
+	case step.Pause:
+		if status, err = rn.api.PauseClock(ctx); err != nil {
+			return "", fmt.Errorf("failed to pause the clock: %w", err)
+		}
+	case step.Resume:
+		if status, err = rn.api.ResumeClock(ctx); err != nil {
+			return "", fmt.Errorf("failed to resume the clock: %w", err)
+		}
+	}
+	if step.Advance > 0 {
+		if status, err = rn.api.AdvanceClock(ctx, step.Advance); err != nil {
+			return "", fmt.Errorf("failed to advance the clock: %w", err)
+		}
+	}
+
+	state := fmt.Sprintf("running at %gx", status.Speed)
+	if status.Paused {
+		state = "paused"
+	}
+	return fmt.Sprintf("simulated time %s, %s", status.Now.UTC().Format(time.RFC3339), state), nil
+}
+
+// errNotMet reports an expectation that has not been met yet
+var errNotMet = errors.New("not met")
+
+// assertion is an expectation being verified
+type assertion struct {
+	check    func(context.Context) (string, error)
+	within   time.Duration
+	absent   bool
+	deadline time.Time
+	result   *AssertionResult
+}
+
+// verify polls the expectations together until each is met or its time is
+// up. Only traffic since the given time is considered.
+func (rn *run) verify(ctx context.Context, expectations []Expectation, vars map[string]string, since time.Time) []AssertionResult {
+	start := rn.config.Clock.Now()
+	results := make([]AssertionResult, len(expectations))
+	var pending []*assertion
+	for i := range expectations {
+		e := &expectations[i]
+		check, description, err := rn.checker(e, vars, since)
+		results[i].Description = description
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		pending = append(pending, &assertion{
+			check:    check,
+			within:   e.within(),
+			absent:   e.Absent,
+			deadline: start.Add(e.within()),
+			result:   &results[i],
+		})
+	}
+
+	for len(pending) > 0 {
+		var unresolved []*assertion
+		for _, a := range pending {
+			if !rn.poll(ctx, a, start) {
+				unresolved = append(unresolved, a)
+			}
+		}
+		pending = unresolved
+		if len(pending) == 0 {
+			break
+		}
+
+		wait := rn.config.PollInterval
+		for _, a := range pending {
+			wait = min(wait, a.deadline.Sub(rn.config.Clock.Now()))
+		}
+		if err := rn.sleep(ctx, wait); err != nil {
+			for _, a := range pending {
+				a.result.Error = err.Error()
+				a.result.Duration = rn.config.Clock.Since(start)
+			}
+			break
+		}
+	}
+	return results
+}
+
+// poll checks an assertion once and reports whether it is resolved
+func (rn *run) poll(ctx context.Context, a *assertion, start time.Time) bool {
+	detail, err := a.check(ctx)
+	timedOut := !rn.config.Clock.Now().Before(a.deadline)
+
+	switch {
+	case err != nil && !errors.Is(err, errNotMet):
+		a.result.Error = err.Error()
+	case err == nil && a.absent:
+		a.result.Error = fmt.Sprintf("expected no %s within %s, found %s", a.result.Description, a.within, detail)
+	case err == nil:
+		a.result.Passed, a.result.Detail = true, detail
+	case !timedOut:
+		return false
+	case a.absent:
+		a.result.Passed, a.result.Detail = true, "none within "+a.within.String()
+	default:
+		a.result.Error = fmt.Sprintf("expected %s within %s", a.result.Description, a.within)
+	}
+	a.result.Duration = rn.config.Clock.Since(start)
+	return true
+}
+
+// checker returns the check of an expectation and its description. Checks
+// only consider traffic since the given time.
+func (rn *run) checker(e *Expectation, vars map[string]string, since time.Time) (func(context.Context) (string, error), string, error) {
+	switch {
+	case e.Publication != nil:
+		fields, err := expandAll(vars, e.Publication.Event, e.Publication.Publisher, e.Publication.Type)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err := expandPayload(e.Publication.Payload, vars)
+		if err != nil {
+			return nil, "", err
+		}
+		match := PublicationMatch{Event: fields[0], Publisher: fields[1], Type: fields[2], Payload: payload}
+		return func(ctx context.Context) (string, error) {
+			return rn.checkPublication(ctx, match, since)
+		}, describe("publication", "event", match.Event, "publisher", match.Publisher, "type", match.Type), nil
+
+	case e.Message != nil:
+		fields, err := expandAll(vars, e.Message.From, e.Message.To, e.Message.Type, e.Message.Status)
+		if err != nil {
+			return nil, "", err
+		}
+		payload, err := expandPayload(e.Message.Payload, vars)
+		if err != nil {
+			return nil, "", err
+		}
+		match := MessageMatch{From: fields[0], To: fields[1], Type: fields[2], Status: fields[3], Payload: payload}
+		return func(ctx context.Context) (string, error) {
+			return rn.checkMessage(ctx, match, since)
+		}, describe("message", "from", match.From, "to", match.To, "type", match.Type, "status", match.Status), nil
+
+	default:
+		fields, err := expandAll(vars, e.Agent.ID, e.Agent.Status, e.Agent.State)
+		if err != nil {
+			return nil, "", err
+		}
+		match := AgentMatch{ID: fields[0], Status: fields[1], State: fields[2]}
+		return func(ctx context.Context) (string, error) {
+			return rn.checkAgent(ctx, match)
+		}, describe("agent "+match.ID, "status", match.Status, "state", match.State), nil
+	}
+}
+
+// checkPublication looks for a matching publication since the given time.
+// Publications are captured with second precision, so ones up to a second
+// earlier may match.
+func (rn *run) checkPublication(ctx context.Context, match PublicationMatch, since time.Time) (string, error) {
+	topic := match.Event
+	if topic == "" {
+		topic = "#"
+	}
+	capture, err := rn.api.CaptureTraffic(ctx, []string{topic}, since, time.Time{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read publications: %w", err)
+	}
+
+	for _, pub := range capture.Publications {
+		switch {
+		case pub.PublishedAt.Before(since.Truncate(time.Second)),
+			match.Event != "" && !communication.MatchTopic(match.Event, pub.EventName),
+			match.Publisher != "" && pub.PublisherAgentID != match.Publisher,
+			match.Type != "" && pub.PublicationType != match.Type,
+			!payloadMatches(match.Payload, pub.Payload):
+			continue
+		}
+		return fmt.Sprintf("publication %s on %s", pub.OriginalID, pub.EventName), nil
+	}
+	return "", errNotMet
+}
+
+// checkMessage looks for a matching message since the given time
+func (rn *run) checkMessage(ctx context.Context, match MessageMatch, since time.Time) (string, error) {
+	messages, err := rn.api.ListMessages(ctx, client.MessageQuery{
+		FromAgentID: match.From,
+		ToAgentID:   match.To,
+		MessageType: match.Type,
+		Since:       since,
+		Limit:       communication.MaxMessageQueryLimit,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	for _, msg := range messages {
+		if statusMatches(match.Status, msg) && payloadMatches(match.Payload, msg.Payload) {
+			return fmt.Sprintf("message %s from %s, %s", msg.ID, msg.FromAgentID, msg.Status), nil
+		}
+	}
+	return "", errNotMet
+}
+
+// statusMatches reports whether a message has the expected status
+func statusMatches(status string, msg client.Message) bool {
+	switch status {
+	case "":
+		return true
+	case MessageAcknowledged:
+		return msg.AcknowledgedAt != nil
+	case MessageDelivered:
+		return msg.DeliveredAt != nil || msg.Status == MessageDelivered
+	default:
+		return msg.Status == status
+	}
+}
+
+// checkAgent checks an agent's liveness status and state
+func (rn *run) checkAgent(ctx context.Context, match AgentMatch) (string, error) {
+	agents, err := rn.api.ListAgents(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to list agents: %w", err)
+	}
+
+	for _, a := range agents {
+		if a.ID != match.ID {
+			continue
+		}
+		if (match.Status != "" && a.Status != match.Status) || (match.State != "" && a.State != match.State) {
+			return "", errNotMet
+		}
+		return fmt.Sprintf("agent %s %s, %s", a.ID, a.Status, a.State), nil
+	}
+	return "", errNotMet
+}
+
+// payloadMatches reports whether actual has every value of expected. Values
+// are compared by their text, so 9 matches 9.0 decoded from JSON; nested
+// objects match recursively.
+func payloadMatches(expected, actual map[string]interface{}) bool {
+	for key, want := range expected {
+		got, ok := actual[key]
+		if !ok {
+			return false
+		}
+		wantMap, wantIsMap := want.(map[string]interface{})
+		gotMap, gotIsMap := got.(map[string]interface{})
+		switch {
+		case wantIsMap && gotIsMap:
+			if !payloadMatches(wantMap, gotMap) {
+				return false
+			}
+		case wantIsMap || gotIsMap:
+			return false
+		case reflect.DeepEqual(want, got):
+		case fmt.Sprint(want) != fmt.Sprint(got):
+			return false
+		}
+	}
+	return true
+}
+
+// stepName expands the variables in a step's name, leaving it as written
+// if one is undefined
+func stepName(step Step, vars map[string]string) string {
+	if name, err := expand(step.Name, vars); err == nil {
+		return name
+	}
+	return step.Name
+}
+
+// expandAll expands variables in each string
+func expandAll(vars map[string]string, values ...string) ([]string, error) {
+	out := make([]string, len(values))
+	for i, value := range values {
+		expanded, err := expand(value, vars)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = expanded
+	}
+	return out, nil
+}
+
+// describe names an expectation and its non-empty fields, given as name,
+// value pairs
+func describe(what string, fields ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] != "" {
+			parts = append(parts, fields[i]+"="+fields[i+1])
+		}
+	}
+	if len(parts) == 0 {
+		return what
+	}
+	return what + " " + strings.Join(parts, " ")
+}
@@ -0,0 +1,373 @@
+// Package scenario runs declarative use-case simulations against a running
+// framework. A scenario is a YAML or JSON file of timed steps that publish
+// events, send direct messages and assert on what the agents did, so a new
+// use case does not need its own simulation program. Scenarios with a clock
+// section run on the framework's simulated clock, so weeks of operation can
+// be stepped through in seconds.
+//
+//	name: leak-detection
+//	vars:
+//	  sensor: SENSOR-001
+//	steps:
+//	  - name: Sensor reports a pressure drop
+//	    publish:
+//	      publisher: ${sensor}
+//	      event: zone.north.leak.detected
+//	      type: alert
+//	      payload: {pressure_current: 4.5, run: "${run_id}"}
+//	  - name: Pipe closes the valve
+//	    message: {from: PIPE-001, to: VALVE-001, type: ISOLATION_COMMAND}
+//	    verify:
+//	      - message: {to: VALVE-001, type: ISOLATION_COMMAND, status: delivered}
+//	        within: 5s
+//	  - name: Pipe confirms the leak
+//	    expect:
+//	      publication: {event: zone.north.leak.confirmed, publisher: PIPE-001}
+//	      within: 30s
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidScenario is returned for scenario definitions that cannot run
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// Built-in variables, available in every scenario
+const (
+	VarNow      = "now"      // Time the step runs, RFC3339
+	VarRunID    = "run_id"   // Unique per run, to tell this run's traffic from earlier runs
+	VarScenario = "scenario" // Scenario name
+)
+
+// defaultExpectWithin is how long an expectation waits to be met by default
+const defaultExpectWithin = 10 * time.Second
+
+// Scenario is a declarative simulation: steps run in order, each after an
+// optional delay
+type Scenario struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Vars        map[string]string `yaml:"vars"` // Expanded as ${name} in step fields and payloads
+	Steps       []Step            `yaml:"steps"`
+
+	// Clock runs the scenario on the framework's simulated clock
+	Clock *ClockConfig `yaml:"clock"`
+}
+
+// ClockConfig runs a scenario on the simulated clock of a framework started
+// with simulation time enabled. Step delays are then simulated time, and
+// ${now} is the simulated time.
+type ClockConfig struct {
+	Speed float64 `yaml:"speed"` // Simulated seconds per real second while running
+
+	// Paused steps through time: delays advance the clock at once instead of
+	// waiting for it to run
+	Paused bool `yaml:"paused"`
+}
+
+// ClockStep controls the simulated clock. Speed and pause or resume apply
+// before Advance.
+type ClockStep struct {
+	Advance time.Duration `yaml:"advance"` // Step simulated time forward, e.g. 168h
+	Speed   float64       `yaml:"speed"`
+	Pause   bool          `yaml:"pause"`
+	Resume  bool          `yaml:"resume"`
+}
+
+// Step is one action of a scenario. A step has exactly one of Publish,
+// Message, Expect and Clock, or none to only wait, and may verify its
+// outcome.
+type Step struct {
+	Name string `yaml:"name"`
+
+	// At runs the step at this offset from the scenario start; After runs it
+	// this long after the previous step. At takes precedence.
+	At    time.Duration `yaml:"at"`
+	After time.Duration `yaml:"after"`
+
+	Publish *PublishStep `yaml:"publish"`
+	Message *MessageStep `yaml:"message"`
+	Expect  *Expectation `yaml:"expect"`
+	Clock   *ClockStep   `yaml:"clock"`
+
+	// Verify asserts the outcome of the step once its action succeeded. The
+	// assertions are checked together and only see traffic since the step
+	// started; each waits up to its own Within.
+	Verify []Expectation `yaml:"verify"`
+}
+
+// PublishStep publishes an event on behalf of an agent
+type PublishStep struct {
+	Publisher     string                 `yaml:"publisher"`
+	PublisherType string                 `yaml:"publisher_type"`
+	Event         string                 `yaml:"event"`
+	Type          string                 `yaml:"type"` // status_change, event, metric, alert or broadcast
+	Payload       map[string]interface{} `yaml:"payload"`
+	TTLSeconds    int                    `yaml:"ttl_seconds"`
+}
+
+// MessageStep sends a direct message between agents
+type MessageStep struct {
+	From          string                 `yaml:"from"`
+	To            string                 `yaml:"to"`
+	Type          string                 `yaml:"type"`
+	Payload       map[string]interface{} `yaml:"payload"`
+	Priority      int                    `yaml:"priority"`
+	CorrelationID string                 `yaml:"correlation_id"`
+}
+
+// Expectation asserts that something happened since the scenario started, or
+// since its step started when used in Verify. It has exactly one of
+// Publication, Message and Agent, and is retried until it is met or Within
+// elapses.
+type Expectation struct {
+	Publication *PublicationMatch `yaml:"publication"`
+	Message     *MessageMatch     `yaml:"message"`
+	Agent       *AgentMatch       `yaml:"agent"`
+
+	// Within is how long to wait for the expectation (default 10s). It is
+	// real time, also on a simulated clock, as agents react in real time.
+	Within time.Duration `yaml:"within"`
+
+	// Absent inverts the expectation: it is met if nothing matches for the
+	// whole of Within
+	Absent bool `yaml:"absent"`
+}
+
+// PublicationMatch matches publications. Empty fields match anything;
+// Payload matches publications whose payload has at least these values.
+type PublicationMatch struct {
+	Event     string                 `yaml:"event"` // Event name or pattern, e.g. zone.*.leak.confirmed
+	Publisher string                 `yaml:"publisher"`
+	Type      string                 `yaml:"type"`
+	Payload   map[string]interface{} `yaml:"payload"`
+}
+
+// MessageMatch matches direct messages. Empty fields match anything.
+type MessageMatch struct {
+	From    string                 `yaml:"from"`
+	To      string                 `yaml:"to"`
+	Type    string                 `yaml:"type"`
+	Status  string                 `yaml:"status"` // pending, delivered, acknowledged, failed or expired
+	Payload map[string]interface{} `yaml:"payload"`
+}
+
+// Message statuses an expectation can match besides the stored ones.
+// Delivered also matches acknowledged messages.
+const (
+	MessageDelivered    = "delivered"
+	MessageAcknowledged = "acknowledged"
+)
+
+// AgentMatch matches an agent's liveness status and lifecycle state
+type AgentMatch struct {
+	ID     string `yaml:"id"`
+	Status string `yaml:"status"` // online, degraded or offline
+	State  string `yaml:"state"`  // created, running, paused, stopped or failed
+}
+
+// Load reads a scenario from a YAML or JSON file
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML or JSON scenario definition. Durations
+// are written like 2s or 1m30s.
+func Parse(data []byte) (*Scenario, error) {
+	var s Scenario
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScenario, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that every step can run
+func (s *Scenario) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidScenario)
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidScenario)
+	}
+	if s.Clock != nil && s.Clock.Speed < 0 {
+		return fmt.Errorf("%w: clock speed must not be negative", ErrInvalidScenario)
+	}
+	for i, step := range s.Steps {
+		err := step.validate()
+		if err == nil && step.Clock != nil && s.Clock == nil {
+			err = errors.New("clock steps need the scenario clock")
+		}
+		if err != nil {
+			return fmt.Errorf("%w: step %d (%s): %v", ErrInvalidScenario, i+1, step.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s Step) validate() error {
+	if s.At < 0 || s.After < 0 {
+		return errors.New("at and after must not be negative")
+	}
+
+	actions := 0
+	if s.Publish != nil {
+		actions++
+		if s.Publish.Publisher == "" || s.Publish.Event == "" {
+			return errors.New("publish needs a publisher and an event")
+		}
+	}
+	if s.Message != nil {
+		actions++
+		if s.Message.From == "" || s.Message.To == "" || s.Message.Type == "" {
+			return errors.New("message needs from, to and a type")
+		}
+	}
+	if s.Expect != nil {
+		actions++
+		if err := s.Expect.validate(); err != nil {
+			return err
+		}
+	}
+	if s.Clock != nil {
+		actions++
+		if err := s.Clock.validate(); err != nil {
+			return err
+		}
+	}
+	for i, e := range s.Verify {
+		if err := e.validate(); err != nil {
+			return fmt.Errorf("verify %d: %w", i+1, err)
+		}
+	}
+
+	switch {
+	case actions > 1:
+		return errors.New("only one of publish, message, expect and clock is allowed")
+	case actions == 0 && s.At == 0 && s.After == 0 && len(s.Verify) == 0:
+		return errors.New("one of publish, message, expect, clock, verify or a delay is required")
+	}
+	return nil
+}
+
+func (e Expectation) validate() error {
+	matches := 0
+	if e.Publication != nil {
+		matches++
+	}
+	if e.Message != nil {
+		matches++
+		switch e.Message.Status {
+		case "", string(communication.MessageStatusPending), MessageDelivered, MessageAcknowledged,
+			string(communication.MessageStatusFailed), string(communication.MessageStatusExpired):
+		default:
+			return fmt.Errorf("unknown message status %q", e.Message.Status)
+		}
+	}
+	if e.Agent != nil {
+		matches++
+		if e.Agent.ID == "" || (e.Agent.Status == "" && e.Agent.State == "") {
+			return errors.New("expect agent needs an id and a status or state")
+		}
+	}
+	if matches != 1 {
+		return errors.New("expect needs exactly one of publication, message and agent")
+	}
+	if e.Within < 0 {
+		return errors.New("within must not be negative")
+	}
+	return nil
+}
+
+func (c ClockStep) validate() error {
+	switch {
+	case c.Pause && c.Resume:
+		return errors.New("clock cannot pause and resume at once")
+	case c.Advance < 0 || c.Speed < 0:
+		return errors.New("clock advance and speed must not be negative")
+	case c.Advance == 0 && c.Speed == 0 && !c.Pause && !c.Resume:
+		return errors.New("clock needs advance, speed, pause or resume")
+	}
+	return nil
+}
+
+// within returns how long the expectation waits
+func (e Expectation) within() time.Duration {
+	if e.Within == 0 {
+		return defaultExpectWithin
+	}
+	return e.Within
+}
+
+// varPattern matches ${name} references
+var varPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// expand replaces ${name} references in a string. Unknown variables are an
+// error, to catch typos before anything is sent.
+func expand(s string, vars map[string]string) (string, error) {
+	var missing string
+	expanded := varPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := varPattern.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable %q", missing)
+	}
+	return expanded, nil
+}
+
+// expandValue expands variables in the strings of a decoded payload value
+func expandValue(value interface{}, vars map[string]string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return expand(v, vars)
+	case map[string]interface{}:
+		return expandPayload(v, vars)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := expandValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandPayload expands variables in a payload, returning a copy
+func expandPayload(payload map[string]interface{}, vars map[string]string) (map[string]interface{}, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		expanded, err := expandValue(value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		out[key] = expanded
+	}
+	return out, nil
+}
@@ -0,0 +1,406 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI records what a scenario sends and serves it back to expectations
+type fakeAPI struct {
+	mu           sync.Mutex
+	publications []client.CapturedPublication
+	messages     []client.Message
+	agents       []client.Agent
+	publishErr   error
+	clock        *clock.Virtual
+}
+
+func (f *fakeAPI) Publish(ctx context.Context, req client.PublishRequest) (*client.PublishResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.publishErr != nil {
+		return nil, f.publishErr
+	}
+	f.publications = append(f.publications, client.CapturedPublication{
+		OriginalID:       "pub-1",
+		PublisherAgentID: req.PublisherAgentID,
+		PublicationType:  req.PublicationType,
+		EventName:        req.EventName,
+		Payload:          req.Payload,
+		PublishedAt:      time.Now(),
+	})
+	return &client.PublishResponse{PublicationID: "pub-1"}, nil
+}
+
+func (f *fakeAPI) SendMessage(ctx context.Context, req client.SendMessageRequest) (*client.SendMessageResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, client.Message{
+		ID:          "msg-1",
+		FromAgentID: req.FromAgentID,
+		ToAgentID:   req.ToAgentID,
+		MessageType: req.MessageType,
+		Payload:     req.Payload,
+		Status:      "pending",
+		CreatedAt:   time.Now(),
+	})
+	return &client.SendMessageResponse{MessageID: "msg-1"}, nil
+}
+
+func (f *fakeAPI) ListMessages(ctx context.Context, query client.MessageQuery) ([]client.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []client.Message
+	for _, msg := range f.messages {
+		if (query.ToAgentID == "" || msg.ToAgentID == query.ToAgentID) && (query.MessageType == "" || msg.MessageType == query.MessageType) &&
+			!msg.CreatedAt.Before(query.Since) {
+			matched = append(matched, msg)
+		}
+	}
+	return matched, nil
+}
+
+// deliver marks the messages to an agent delivered, as if it fetched them
+func (f *fakeAPI) deliver(agentID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for i := range f.messages {
+		if f.messages[i].ToAgentID == agentID {
+			f.messages[i].Status = "delivered"
+			f.messages[i].DeliveredAt = &now
+		}
+	}
+}
+
+func (f *fakeAPI) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*client.TrafficCapture, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &client.TrafficCapture{Publications: append([]client.CapturedPublication{}, f.publications...)}, nil
+}
+
+func (f *fakeAPI) GetClock(ctx context.Context) (*client.ClockStatus, error) {
+	return clockStatus(f.clock.Status()), nil
+}
+
+func (f *fakeAPI) AdvanceClock(ctx context.Context, d time.Duration) (*client.ClockStatus, error) {
+	return clockStatus(f.clock.Advance(d)), nil
+}
+
+func (f *fakeAPI) PauseClock(ctx context.Context) (*client.ClockStatus, error) {
+	return clockStatus(f.clock.Pause()), nil
+}
+
+func (f *fakeAPI) ResumeClock(ctx context.Context) (*client.ClockStatus, error) {
+	return clockStatus(f.clock.Resume()), nil
+}
+
+func (f *fakeAPI) SetClockSpeed(ctx context.Context, speed float64) (*client.ClockStatus, error) {
+	status, err := f.clock.SetSpeed(speed)
+	if err != nil {
+		return nil, err
+	}
+	return clockStatus(status), nil
+}
+
+func clockStatus(status clock.VirtualStatus) *client.ClockStatus {
+	return &client.ClockStatus{Now: status.Now, Speed: status.Speed, Paused: status.Paused}
+}
+
+func (f *fakeAPI) ListAgents(ctx context.Context, status string) ([]client.Agent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.agents, nil
+}
+
+const leakScenario = `
+name: leak
+vars:
+  pipe: PIPE-001
+steps:
+  - name: Sensor alert
+    publish:
+      publisher: SENSOR-001
+      event: zone.north.leak.detected
+      type: alert
+      payload: {pressure: 4.5, run: "${run_id}", pipe: "${pipe}"}
+  - name: Close ${valve}
+    after: 20ms
+    message:
+      from: ${pipe}
+      to: ${valve}
+      type: ISOLATION_COMMAND
+      priority: 10
+      payload: {command: CLOSE, valves: ["${valve}"]}
+  - name: Alert was published
+    expect:
+      publication:
+        event: zone.*.leak.detected
+        publisher: SENSOR-001
+        payload: {pressure: 4.5, run: "${run_id}"}
+  - name: Valve was told to close
+    expect:
+      message: {to: "${valve}", type: ISOLATION_COMMAND, payload: {command: CLOSE}}
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(leakScenario))
+	require.NoError(t, err)
+	assert.Equal(t, "leak", s.Name)
+	require.Len(t, s.Steps, 4)
+	assert.Equal(t, 20*time.Millisecond, s.Steps[1].After)
+	assert.Equal(t, 10, s.Steps[1].Message.Priority)
+
+	// JSON is accepted too
+	s, err = Parse([]byte(`{"name": "json", "steps": [{"name": "pause", "after": "1s"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, s.Steps[0].After)
+
+	invalid := map[string]string{
+		"no name":          `steps: [{name: a, after: 1s}]`,
+		"no steps":         `name: x`,
+		"two actions":      `{name: x, steps: [{publish: {publisher: a, event: b}, message: {from: a, to: b, type: c}}]}`,
+		"nothing to do":    `{name: x, steps: [{name: idle}]}`,
+		"publish no event": `{name: x, steps: [{publish: {publisher: a}}]}`,
+		"empty expect":     `{name: x, steps: [{expect: {within: 1s}}]}`,
+		"agent no status":  `{name: x, steps: [{expect: {agent: {id: a}}}]}`,
+		"bad duration":     `{name: x, steps: [{after: soon}]}`,
+		"bad status":       `{name: x, steps: [{expect: {message: {to: a, status: read}}}]}`,
+		"bad verify":       `{name: x, steps: [{publish: {publisher: a, event: b}, verify: [{within: 1s}]}]}`,
+		"no clock":         `{name: x, steps: [{clock: {advance: 1h}}]}`,
+		"idle clock step":  `{name: x, clock: {paused: true}, steps: [{clock: {}}]}`,
+	}
+	for name, definition := range invalid {
+		_, err := Parse([]byte(definition))
+		assert.ErrorIs(t, err, ErrInvalidScenario, name)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	s, err := Parse([]byte(leakScenario))
+	require.NoError(t, err)
+
+	api := &fakeAPI{}
+	var reported []StepResult
+	runner := NewRunner(api, RunnerConfig{
+		Vars:         map[string]string{"valve": "VALVE-001"},
+		PollInterval: 5 * time.Millisecond,
+		OnStep:       func(r StepResult) { reported = append(reported, r) },
+	})
+
+	report, err := runner.Run(context.Background(), s)
+	require.NoError(t, err)
+	assert.True(t, report.Passed, "%+v", report.Steps)
+	assert.Len(t, reported, 4)
+	assert.Equal(t, "Close VALVE-001", report.Steps[1].Name)
+	assert.GreaterOrEqual(t, report.Steps[1].StartedAt.Sub(report.Steps[0].StartedAt), 20*time.Millisecond)
+
+	require.Len(t, api.publications, 1)
+	assert.Equal(t, report.RunID, api.publications[0].Payload["run"])
+	assert.Equal(t, "PIPE-001", api.publications[0].Payload["pipe"])
+	require.Len(t, api.messages, 1)
+	assert.Equal(t, "VALVE-001", api.messages[0].ToAgentID)
+	assert.Equal(t, []interface{}{"VALVE-001"}, api.messages[0].Payload["valves"])
+}
+
+func TestRunner_Failures(t *testing.T) {
+	ctx := context.Background()
+	api := &fakeAPI{agents: []client.Agent{{ID: "PUMP-002", Status: "online", State: "running"}}}
+	runner := NewRunner(api, RunnerConfig{PollInterval: 5 * time.Millisecond})
+
+	// An unmet expectation fails after its timeout and stops the run
+	s, err := Parse([]byte(`
+name: failing
+steps:
+  - {name: degraded, expect: {agent: {id: PUMP-002, status: degraded}, within: 30ms}}
+  - {name: never runs, publish: {publisher: a, event: b}}
+`))
+	require.NoError(t, err)
+	report, err := runner.Run(ctx, s)
+	require.NoError(t, err)
+	assert.False(t, report.Passed)
+	require.Len(t, report.Steps, 1)
+	assert.Contains(t, report.Steps[0].Error, "expected agent PUMP-002 status=degraded within 30ms")
+
+	// Met expectations pass at once
+	s.Steps[0].Expect.Agent.Status = "online"
+	report, err = runner.Run(ctx, s)
+	require.NoError(t, err)
+	assert.True(t, report.Passed)
+
+	// Undefined variables and API errors fail the step
+	s, err = Parse([]byte(`{name: vars, steps: [{publish: {publisher: "${missing}", event: b}}]}`))
+	require.NoError(t, err)
+	report, err = runner.Run(ctx, s)
+	require.NoError(t, err)
+	assert.Contains(t, report.Steps[0].Error, `undefined variable "missing"`)
+
+	api.publishErr = errors.New("boom")
+	s, err = Parse([]byte(`
+name: keep-going
+steps:
+  - {publish: {publisher: a, event: b}}
+  - {publish: {publisher: a, event: c}}
+`))
+	require.NoError(t, err)
+	report, err = NewRunner(api, RunnerConfig{KeepGoing: true}).Run(ctx, s)
+	require.NoError(t, err)
+	assert.False(t, report.Passed)
+	assert.Len(t, report.Steps, 2)
+}
+
+func TestPayloadMatches(t *testing.T) {
+	actual := map[string]interface{}{
+		"priority": float64(9),
+		"status":   "CONTAINED",
+		"pipe":     map[string]interface{}{"id": "PIPE-001", "zone": "north"},
+		"valves":   []interface{}{"VALVE-001"},
+	}
+
+	assert.True(t, payloadMatches(nil, actual))
+	assert.True(t, payloadMatches(map[string]interface{}{"priority": 9, "status": "CONTAINED"}, actual))
+	assert.True(t, payloadMatches(map[string]interface{}{"pipe": map[string]interface{}{"id": "PIPE-001"}}, actual))
+	assert.True(t, payloadMatches(map[string]interface{}{"valves": []interface{}{"VALVE-001"}}, actual))
+	assert.False(t, payloadMatches(map[string]interface{}{"status": "OPEN"}, actual))
+	assert.False(t, payloadMatches(map[string]interface{}{"missing": 1}, actual))
+	assert.False(t, payloadMatches(map[string]interface{}{"status": map[string]interface{}{"a": 1}}, actual))
+}
+
+func TestRunner_Verify(t *testing.T) {
+	s, err := Parse([]byte(`
+name: isolation
+steps:
+  - name: Close the valve
+    message: {from: PIPE-001, to: VALVE-001, type: ISOLATION_COMMAND}
+    verify:
+      - message: {to: VALVE-001, type: ISOLATION_COMMAND, status: delivered}
+        within: 1s
+      - publication: {event: zone.*.leak.detected}
+        absent: true
+        within: 30ms
+`))
+	require.NoError(t, err)
+
+	api := &fakeAPI{}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		api.deliver("VALVE-001")
+	}()
+
+	report, err := NewRunner(api, RunnerConfig{PollInterval: 5 * time.Millisecond}).Run(context.Background(), s)
+	require.NoError(t, err)
+	assert.True(t, report.Passed, "%+v", report.Steps)
+	require.Len(t, report.Steps[0].Assertions, 2)
+
+	delivered := report.Steps[0].Assertions[0]
+	assert.True(t, delivered.Passed)
+	assert.Equal(t, "message to=VALVE-001 type=ISOLATION_COMMAND status=delivered", delivered.Description)
+	assert.Equal(t, "message msg-1 from PIPE-001, delivered", delivered.Detail)
+	assert.GreaterOrEqual(t, delivered.Duration, 20*time.Millisecond)
+
+	absent := report.Steps[0].Assertions[1]
+	assert.True(t, absent.Passed)
+	assert.Equal(t, "none within 30ms", absent.Detail)
+}
+
+func TestRunner_VerifyFailures(t *testing.T) {
+	s, err := Parse([]byte(`
+name: isolation
+steps:
+  - name: Detect
+    publish: {publisher: SENSOR-001, event: zone.north.leak.detected}
+    verify:
+      - publication: {event: zone.*.leak.detected}
+        absent: true
+        within: 30ms
+      - message: {to: VALVE-001, status: acknowledged}
+        within: 30ms
+  - {name: Close the valve, message: {from: PIPE-001, to: VALVE-001, type: ISOLATION_COMMAND}}
+`))
+	require.NoError(t, err)
+
+	report, err := NewRunner(&fakeAPI{}, RunnerConfig{PollInterval: 5 * time.Millisecond}).Run(context.Background(), s)
+	require.NoError(t, err)
+	assert.False(t, report.Passed)
+	require.Len(t, report.Steps, 1)
+	assert.Equal(t, "2 of 2 assertions failed", report.Steps[0].Error)
+	assert.Equal(t, "expected no publication event=zone.*.leak.detected within 30ms, found publication pub-1 on zone.north.leak.detected",
+		report.Steps[0].Assertions[0].Error)
+	assert.Equal(t, "expected message to=VALVE-001 status=acknowledged within 30ms", report.Steps[0].Assertions[1].Error)
+	assert.Equal(t, []string{"Close the valve"}, report.Skipped)
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	report := &Report{
+		Scenario: "leak",
+		Duration: 1500 * time.Millisecond,
+		Steps: []StepResult{
+			{Index: 1, Name: "Detect", Passed: true, Detail: "publication pub-1", Duration: 10 * time.Millisecond},
+			{Index: 2, Name: "Close", Error: "1 of 1 assertions failed", Assertions: []AssertionResult{
+				{Description: "message to=VALVE-001", Error: "expected message to=VALVE-001 within 5s"},
+			}},
+		},
+		Skipped: []string{"Escalate"},
+	}
+
+	var buf strings.Builder
+	require.NoError(t, report.WriteJUnit(&buf))
+	out := buf.String()
+	assert.Contains(t, out, `<testsuite name="leak" tests="3" failures="1" skipped="1" time="1.500"`)
+	assert.Contains(t, out, `<testcase name="1. Detect" classname="leak" time="0.010">`)
+	assert.Contains(t, out, `<failure message="1 of 1 assertions failed">1 of 1 assertions failed&#xA;[FAIL] message to=VALVE-001: expected message to=VALVE-001 within 5s</failure>`)
+	assert.Contains(t, out, `<testcase name="3. Escalate" classname="leak" time="0.000">`)
+}
+
+func TestRunner_SimulatedClock(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	api := &fakeAPI{clock: clock.NewVirtual(clock.VirtualConfig{Start: start, Paused: true})}
+
+	s, err := Parse([]byte(`
+name: four-weeks
+clock: {paused: true}
+steps:
+  - name: Week 1
+    publish: {publisher: PUMP-002, event: pumps.efficiency, payload: {measured_at: "${now}"}}
+  - name: Week 2
+    after: 168h
+    publish: {publisher: PUMP-002, event: pumps.efficiency, payload: {measured_at: "${now}"}}
+  - name: Week 4
+    at: 504h
+    publish: {publisher: PUMP-002, event: pumps.efficiency, payload: {measured_at: "${now}"}}
+  - name: Speed up
+    clock: {resume: true, speed: 3600000}
+  - name: A day later
+    after: 24h
+    publish: {publisher: PUMP-002, event: pumps.efficiency, payload: {measured_at: "${now}"}}
+`))
+	require.NoError(t, err)
+
+	report, err := NewRunner(api, RunnerConfig{PollInterval: 5 * time.Millisecond}).Run(context.Background(), s)
+	require.NoError(t, err)
+	assert.True(t, report.Passed, "%+v", report.Steps)
+	assert.Less(t, report.Duration, 5*time.Second)
+
+	require.Len(t, api.publications, 4)
+	assert.Equal(t, "2025-01-01T00:00:00Z", api.publications[0].Payload["measured_at"])
+	assert.Equal(t, "2025-01-08T00:00:00Z", api.publications[1].Payload["measured_at"])
+	assert.Equal(t, "2025-01-22T00:00:00Z", api.publications[2].Payload["measured_at"])
+	assert.Equal(t, start.Add(504*time.Hour), *report.Steps[2].SimulatedAt)
+	assert.Equal(t, "clock", report.Steps[3].Action)
+	assert.Contains(t, report.Steps[3].Detail, "running at 3.6e+06x")
+
+	// A day at 1000 simulated hours per second takes about 24ms of real time
+	dayLater, err := time.Parse(time.RFC3339, api.publications[3].Payload["measured_at"].(string))
+	require.NoError(t, err)
+	assert.False(t, dayLater.Before(start.Add(504*time.Hour+24*time.Hour)))
+	assert.False(t, api.clock.Status().Paused)
+}
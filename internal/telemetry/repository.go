@@ -0,0 +1,530 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionPoints is the raw telemetry point collection name
+	CollectionPoints = "telemetry_points"
+
+	// CollectionRollups is the downsampled telemetry collection name
+	CollectionRollups = "telemetry_rollups"
+)
+
+// ArangoRepository persists telemetry in ArangoDB
+type ArangoRepository struct {
+	db      driver.Database
+	router  *database.QueryRouter
+	points  driver.Collection
+	rollups driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed telemetry repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	points, err := ensureCollection(ctx, db, CollectionPoints)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := ensureCollection(ctx, db, CollectionRollups)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := points.EnsurePersistentIndex(ctx, []string{"metric", "agent_id", "timestamp"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_telemetry_points_series",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	if _, _, err := rollups.EnsurePersistentIndex(ctx, []string{"metric", "agent_id", "start"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_telemetry_rollups_series",
+		Unique: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoRepository{
+		db:      db,
+		router:  dbClient.Router(),
+		points:  points,
+		rollups: rollups,
+	}, nil
+}
+
+func ensureCollection(ctx context.Context, db driver.Database, name string) (driver.Collection, error) {
+	exists, err := db.CollectionExists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	if exists {
+		col, err := db.Collection(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+		return col, nil
+	}
+
+	col, err := db.CreateCollection(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	log.WithField("collection", name).Info("Created new collection")
+	return col, nil
+}
+
+// AddPoints stores raw points
+func (r *ArangoRepository) AddPoints(ctx context.Context, points []*Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	_, errs, err := r.points.CreateDocuments(ctx, points)
+	if err == nil {
+		err = errs.FirstNonNil()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store telemetry points: %w", err)
+	}
+	return nil
+}
+
+// filterConditions builds the AQL filter of a filter on a document variable
+// and its time field
+func filterConditions(filter Filter, doc, timeField string, bindVars map[string]interface{}) string {
+	conditions := []string{doc + ".metric == @metric"}
+	bindVars["metric"] = filter.Metric
+
+	if filter.AgentID != "" {
+		conditions = append(conditions, doc+".agent_id == @agentID")
+		bindVars["agentID"] = filter.AgentID
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("DATE_TIMESTAMP(%s.%s) >= DATE_TIMESTAMP(@from)", doc, timeField))
+		bindVars["from"] = filter.From.UTC()
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("DATE_TIMESTAMP(%s.%s) < DATE_TIMESTAMP(@to)", doc, timeField))
+		bindVars["to"] = filter.To.UTC()
+	}
+	return "FILTER " + strings.Join(conditions, " AND ")
+}
+
+// ListPoints returns the raw points matching the filter, oldest first
+func (r *ArangoRepository) ListPoints(ctx context.Context, filter Filter, limit int) ([]*Point, error) {
+	bindVars := map[string]interface{}{"@points": CollectionPoints, "limit": limit}
+	query := fmt.Sprintf(`
+		FOR p IN @@points
+			%s
+			SORT DATE_TIMESTAMP(p.timestamp) ASC
+			LIMIT @limit
+			RETURN p
+	`, filterConditions(filter, "p", "timestamp", bindVars))
+
+	cursor, err := r.router.Query(database.WithQueryRoute(ctx, QueryRouteTelemetry), query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry points: %w", err)
+	}
+	defer cursor.Close()
+
+	points := []*Point{}
+	for {
+		var p Point
+		_, err := cursor.ReadDocument(ctx, &p)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read telemetry point: %w", err)
+		}
+		points = append(points, &p)
+	}
+	return points, nil
+}
+
+// LatestPoints returns the most recent raw point of every agent and metric
+// reported at or after since
+func (r *ArangoRepository) LatestPoints(ctx context.Context, since time.Time) ([]*Point, error) {
+	query := `
+		FOR p IN @@points
+			FILTER DATE_TIMESTAMP(p.timestamp) >= DATE_TIMESTAMP(@since)
+			COLLECT agentID = p.agent_id, metric = p.metric INTO series = p
+			RETURN FIRST(FOR s IN series SORT DATE_TIMESTAMP(s.timestamp) DESC LIMIT 1 RETURN s)
+	`
+	bindVars := map[string]interface{}{"@points": CollectionPoints, "since": since.UTC()}
+
+	cursor, err := r.router.Query(database.WithQueryRoute(ctx, QueryRouteTelemetry), query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest telemetry points: %w", err)
+	}
+	defer cursor.Close()
+
+	points := []*Point{}
+	for {
+		var p Point
+		_, err := cursor.ReadDocument(ctx, &p)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read telemetry point: %w", err)
+		}
+		points = append(points, &p)
+	}
+	return points, nil
+}
+
+// Summarize aggregates the raw points and rollups matching the filter into
+// buckets of the interval. Rollups are selected by their start.
+func (r *ArangoRepository) Summarize(ctx context.Context, filter Filter, interval time.Duration) (map[time.Time]Summary, error) {
+	bindVars := map[string]interface{}{
+		"@points":  CollectionPoints,
+		"@rollups": CollectionRollups,
+		"interval": interval.Milliseconds(),
+	}
+	query := fmt.Sprintf(`
+		LET raw = (
+			FOR p IN @@points
+				%s
+				LET at = DATE_TIMESTAMP(p.timestamp)
+				RETURN { at: at, count: 1, sum: p.value, min: p.value, max: p.value, last: p.value, last_at: at }
+		)
+		LET rolled = (
+			FOR r IN @@rollups
+				%s
+				RETURN { at: DATE_TIMESTAMP(r.start), count: r.count, sum: r.sum, min: r.min, max: r.max, last: r.last, last_at: DATE_TIMESTAMP(r.last_at) }
+		)
+		FOR s IN UNION(raw, rolled)
+			COLLECT bucket = (@interval > 0 ? FLOOR(s.at / @interval) * @interval : null) INTO group = s
+			LET latest = FIRST(FOR g IN group SORT g.last_at DESC LIMIT 1 RETURN g)
+			RETURN {
+				bucket: bucket,
+				count: SUM(group[*].count),
+				sum: SUM(group[*].sum),
+				min: MIN(group[*].min),
+				max: MAX(group[*].max),
+				last: latest.last,
+				last_at: latest.last_at
+			}
+	`, filterConditions(filter, "p", "timestamp", bindVars), filterConditions(filter, "r", "start", bindVars))
+
+	cursor, err := r.router.Query(database.WithQueryRoute(ctx, QueryRouteTelemetry), query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize telemetry: %w", err)
+	}
+	defer cursor.Close()
+
+	buckets := make(map[time.Time]Summary)
+	for {
+		var row struct {
+			Bucket *int64  `json:"bucket"`
+			Count  int64   `json:"count"`
+			Sum    float64 `json:"sum"`
+			Min    float64 `json:"min"`
+			Max    float64 `json:"max"`
+			Last   float64 `json:"last"`
+			LastAt int64   `json:"last_at"`
+		}
+		_, err := cursor.ReadDocument(ctx, &row)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read telemetry summary: %w", err)
+		}
+
+		var start time.Time
+		if row.Bucket != nil {
+			start = time.UnixMilli(*row.Bucket).UTC()
+		}
+		buckets[start] = Summary{
+			Count:  row.Count,
+			Sum:    row.Sum,
+			Min:    row.Min,
+			Max:    row.Max,
+			Last:   row.Last,
+			LastAt: time.UnixMilli(row.LastAt).UTC(),
+		}
+	}
+	return buckets, nil
+}
+
+// Downsample rolls raw points older than before into rollups and removes
+// them in a single stream transaction, so no point is counted twice
+func (r *ArangoRepository) Downsample(ctx context.Context, before time.Time, interval time.Duration, limit int) (int, error) {
+	query := `
+		FOR p IN @@points
+			FILTER DATE_TIMESTAMP(p.timestamp) < DATE_TIMESTAMP(@before)
+			LIMIT @limit
+			RETURN p
+	`
+	cursor, err := r.db.Query(ctx, query, map[string]interface{}{
+		"@points": CollectionPoints,
+		"before":  before.UTC(),
+		"limit":   limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired telemetry points: %w", err)
+	}
+	var points []*Point
+	var keys []string
+	for {
+		var p Point
+		_, err := cursor.ReadDocument(ctx, &p)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			cursor.Close()
+			return 0, fmt.Errorf("failed to read telemetry point: %w", err)
+		}
+		points = append(points, &p)
+		keys = append(keys, p.ID)
+	}
+	cursor.Close()
+	if len(points) == 0 {
+		return 0, nil
+	}
+
+	tid, err := r.db.BeginTransaction(ctx, driver.TransactionCollections{
+		Write: []string{CollectionPoints, CollectionRollups},
+	}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	txCtx := driver.WithTransactionID(ctx, tid)
+
+	if err := r.mergeRollups(txCtx, rollUp(points, interval), keys); err != nil {
+		if abortErr := r.db.AbortTransaction(ctx, tid, nil); abortErr != nil {
+			log.WithError(abortErr).Error("Failed to abort telemetry downsampling transaction")
+		}
+		return 0, err
+	}
+	if err := r.db.CommitTransaction(ctx, tid, nil); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(points), nil
+}
+
+// mergeRollups adds rollups to the stored ones and removes the points they
+// were computed from
+func (r *ArangoRepository) mergeRollups(ctx context.Context, rollups []*Rollup, pointKeys []string) error {
+	upsert := `
+		FOR r IN @rollups
+			UPSERT { metric: r.metric, agent_id: r.agent_id, start: r.start }
+			INSERT r
+			UPDATE {
+				count: OLD.count + r.count,
+				sum: OLD.sum + r.sum,
+				min: MIN([OLD.min, r.min]),
+				max: MAX([OLD.max, r.max]),
+				last: DATE_TIMESTAMP(r.last_at) >= DATE_TIMESTAMP(OLD.last_at) ? r.last : OLD.last,
+				last_at: DATE_TIMESTAMP(r.last_at) >= DATE_TIMESTAMP(OLD.last_at) ? r.last_at : OLD.last_at
+			}
+			IN @@rollups
+	`
+	cursor, err := r.db.Query(ctx, upsert, map[string]interface{}{
+		"@rollups": CollectionRollups,
+		"rollups":  rollups,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store telemetry rollups: %w", err)
+	}
+	cursor.Close()
+
+	remove := `
+		FOR key IN @keys
+			REMOVE key IN @@points
+	`
+	cursor, err = r.db.Query(ctx, remove, map[string]interface{}{
+		"@points": CollectionPoints,
+		"keys":    pointKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove downsampled telemetry points: %w", err)
+	}
+	return cursor.Close()
+}
+
+// DeleteRollupsBefore removes rollups that start before the time
+func (r *ArangoRepository) DeleteRollupsBefore(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		FOR r IN @@rollups
+			FILTER DATE_TIMESTAMP(r.start) < DATE_TIMESTAMP(@before)
+			REMOVE r IN @@rollups
+			COLLECT WITH COUNT INTO n
+			RETURN n
+	`
+	cursor, err := r.db.Query(ctx, query, map[string]interface{}{
+		"@rollups": CollectionRollups,
+		"before":   before.UTC(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete telemetry rollups: %w", err)
+	}
+	defer cursor.Close()
+
+	var removed int
+	if _, err := cursor.ReadDocument(ctx, &removed); err != nil && !driver.IsNoMoreDocuments(err) {
+		return 0, fmt.Errorf("failed to read removed count: %w", err)
+	}
+	return removed, nil
+}
+
+// InMemoryRepository keeps raw telemetry points, in arrival order, and their
+// rollups in memory. Queries scan every stored point.
+type InMemoryRepository struct {
+	mu      sync.RWMutex
+	points  []*Point
+	rollups map[string]*Rollup // By agent, metric and start
+}
+
+// NewInMemoryRepository creates a new in-memory telemetry repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		rollups: make(map[string]*Rollup),
+	}
+}
+
+// AddPoints stores raw points
+func (r *InMemoryRepository) AddPoints(ctx context.Context, points []*Point) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range points {
+		if p.ID == "" {
+			p.ID = uuid.New().String()
+		}
+		copied := *p
+		r.points = append(r.points, &copied)
+	}
+	return nil
+}
+
+// ListPoints returns the raw points matching the filter, oldest first
+func (r *InMemoryRepository) ListPoints(ctx context.Context, filter Filter, limit int) ([]*Point, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	points := []*Point{}
+	for _, p := range r.points {
+		if filter.matches(p.AgentID, p.Metric, p.Timestamp) {
+			copied := *p
+			points = append(points, &copied)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	if limit > 0 && len(points) > limit {
+		points = points[:limit]
+	}
+	return points, nil
+}
+
+// LatestPoints returns the most recent raw point of every agent and metric
+// reported at or after since
+func (r *InMemoryRepository) LatestPoints(ctx context.Context, since time.Time) ([]*Point, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	latest := make(map[[2]string]*Point)
+	for _, p := range r.points {
+		key := [2]string{p.AgentID, p.Metric}
+		if p.Timestamp.Before(since) {
+			continue
+		}
+		if current, ok := latest[key]; !ok || p.Timestamp.After(current.Timestamp) {
+			latest[key] = p
+		}
+	}
+
+	points := make([]*Point, 0, len(latest))
+	for _, p := range latest {
+		copied := *p
+		points = append(points, &copied)
+	}
+	return points, nil
+}
+
+// Summarize aggregates the raw points and rollups matching the filter into
+// buckets of the interval. Rollups are selected by their start.
+func (r *InMemoryRepository) Summarize(ctx context.Context, filter Filter, interval time.Duration) (map[time.Time]Summary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	buckets := make(map[time.Time]Summary)
+	add := func(at time.Time, s Summary) {
+		start := bucketStart(at, interval)
+		bucket := buckets[start]
+		bucket.Merge(s)
+		buckets[start] = bucket
+	}
+	for _, p := range r.points {
+		if filter.matches(p.AgentID, p.Metric, p.Timestamp) {
+			var s Summary
+			s.Add(p.Value, p.Timestamp.UTC())
+			add(p.Timestamp, s)
+		}
+	}
+	for _, rollup := range r.rollups {
+		if filter.matches(rollup.AgentID, rollup.Metric, rollup.Start) {
+			add(rollup.Start, rollup.Summary)
+		}
+	}
+	return buckets, nil
+}
+
+// Downsample rolls raw points older than before into rollups and removes them
+func (r *InMemoryRepository) Downsample(ctx context.Context, before time.Time, interval time.Duration, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired, kept []*Point
+	for _, p := range r.points {
+		if p.Timestamp.Before(before) && len(expired) < limit {
+			expired = append(expired, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+
+	for _, rollup := range rollUp(expired, interval) {
+		key := rollup.AgentID + "|" + rollup.Metric + "|" + rollup.Start.Format(time.RFC3339)
+		if stored, ok := r.rollups[key]; ok {
+			stored.Merge(rollup.Summary)
+			continue
+		}
+		rollup.ID = uuid.New().String()
+		r.rollups[key] = rollup
+	}
+	r.points = kept
+	return len(expired), nil
+}
+
+// DeleteRollupsBefore removes rollups that start before the time
+func (r *InMemoryRepository) DeleteRollupsBefore(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for key, rollup := range r.rollups {
+		if rollup.Start.Before(before) {
+			delete(r.rollups, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,361 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// QueryRouteTelemetry is the query route of telemetry range and aggregate queries, which may be served by a read replica
+	QueryRouteTelemetry = "telemetry.query"
+
+	defaultQueryRange = 24 * time.Hour
+	maxListLimit      = 10000
+)
+
+// Config configures telemetry storage
+type Config struct {
+	// RawRetention is how long raw points are kept before they are
+	// downsampled into rollups of DownsampleInterval
+	RawRetention       time.Duration
+	DownsampleInterval time.Duration
+
+	// Retention is how long rollups are kept
+	Retention time.Duration
+
+	SweepInterval       time.Duration
+	SweepBatchSize      int
+	MaxPointsPerRequest int
+	MaxBuckets          int // Most buckets a query may return
+}
+
+func (c Config) withDefaults() Config {
+	if c.RawRetention <= 0 {
+		c.RawRetention = 24 * time.Hour
+	}
+	if c.DownsampleInterval <= 0 {
+		c.DownsampleInterval = 5 * time.Minute
+	}
+	if c.Retention <= 0 {
+		c.Retention = 90 * 24 * time.Hour
+	}
+	if c.SweepInterval <= 0 {
+		c.SweepInterval = 5 * time.Minute
+	}
+	if c.SweepBatchSize <= 0 {
+		c.SweepBatchSize = 5000
+	}
+	if c.MaxPointsPerRequest <= 0 {
+		c.MaxPointsPerRequest = 1000
+	}
+	if c.MaxBuckets <= 0 {
+		c.MaxBuckets = 2000
+	}
+	return c
+}
+
+// ConfigFromConfig converts application config into a telemetry Config
+func ConfigFromConfig(cfg config.TelemetryConfig) Config {
+	return Config{
+		RawRetention:        time.Duration(cfg.RawRetentionHours) * time.Hour,
+		DownsampleInterval:  time.Duration(cfg.DownsampleIntervalSeconds) * time.Second,
+		Retention:           time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		SweepInterval:       time.Duration(cfg.SweepIntervalSeconds) * time.Second,
+		SweepBatchSize:      cfg.SweepBatchSize,
+		MaxPointsPerRequest: cfg.MaxPointsPerRequest,
+		MaxBuckets:          cfg.MaxBuckets,
+	}
+}
+
+// Query selects a series and how to aggregate it
+type Query struct {
+	AgentID   string // Empty aggregates every agent reporting the metric
+	Metric    string
+	From      time.Time     // Defaults to a day before To
+	To        time.Time     // Defaults to now
+	Aggregate string        // Defaults to avg
+	Interval  time.Duration // Bucket width; zero aggregates the whole range
+}
+
+// Bucket is the aggregate of a series over one interval
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Value float64   `json:"value"`
+	Count int64     `json:"count"` // Points the value was computed from
+}
+
+// QueryResult is the answer to a query
+type QueryResult struct {
+	AgentID         string    `json:"agent_id,omitempty"`
+	Metric          string    `json:"metric"`
+	Aggregate       string    `json:"aggregate"`
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	IntervalSeconds int64     `json:"interval_seconds,omitempty"`
+	Buckets         []Bucket  `json:"buckets"`
+}
+
+// IngestObserver is notified of the points of each ingestion after they have
+// been stored. It receives the ingesting context and must not block.
+type IngestObserver func(ctx context.Context, points []*Point)
+
+// Service ingests telemetry points, answers queries over them and applies
+// retention and downsampling
+type Service struct {
+	repo   Repository
+	config Config
+	clock  clock.Clock
+	logger *log.Logger
+
+	observersMu sync.RWMutex
+	observers   []IngestObserver
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewService creates a new telemetry service
+func NewService(repo Repository, cfg Config, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &Service{
+		repo:   repo,
+		config: cfg.withDefaults(),
+		clock:  clock.Real(),
+		logger: logger,
+	}
+}
+
+// SetClock sets the clock used for default timestamps and retention
+func (s *Service) SetClock(c clock.Clock) {
+	if c != nil {
+		s.clock = c
+	}
+}
+
+// AddIngestObserver registers an observer notified of every ingestion
+func (s *Service) AddIngestObserver(observer IngestObserver) {
+	s.observersMu.Lock()
+	defer s.observersMu.Unlock()
+
+	s.observers = append(s.observers, observer)
+}
+
+// Ingest validates and stores points. Points without a timestamp are
+// stamped with the current time.
+func (s *Service) Ingest(ctx context.Context, points []*Point) error {
+	if len(points) == 0 {
+		return fmt.Errorf("%w: no points", ErrInvalidPoint)
+	}
+	if len(points) > s.config.MaxPointsPerRequest {
+		return fmt.Errorf("%w: at most %d points may be sent at once", ErrInvalidPoint, s.config.MaxPointsPerRequest)
+	}
+
+	now := s.clock.Now().UTC()
+	for i, p := range points {
+		switch {
+		case p == nil:
+			return fmt.Errorf("%w: point %d is empty", ErrInvalidPoint, i)
+		case p.AgentID == "":
+			return fmt.Errorf("%w: point %d has no agent_id", ErrInvalidPoint, i)
+		case p.Metric == "":
+			return fmt.Errorf("%w: point %d has no metric", ErrInvalidPoint, i)
+		case math.IsNaN(p.Value) || math.IsInf(p.Value, 0):
+			return fmt.Errorf("%w: point %d has a non-finite value", ErrInvalidPoint, i)
+		}
+		p.ID = ""
+		if p.Timestamp.IsZero() {
+			p.Timestamp = now
+		}
+		p.Timestamp = p.Timestamp.UTC()
+	}
+
+	if err := s.repo.AddPoints(ctx, points); err != nil {
+		return err
+	}
+
+	s.observersMu.RLock()
+	observers := s.observers
+	s.observersMu.RUnlock()
+	for _, observer := range observers {
+		observer(ctx, points)
+	}
+	return nil
+}
+
+// Points returns the raw points of a series in a range, oldest first.
+// Points older than the raw retention have been downsampled and are only
+// available through Query.
+func (s *Service) Points(ctx context.Context, filter Filter, limit int) ([]*Point, error) {
+	if filter.Metric == "" {
+		return nil, fmt.Errorf("%w: metric is required", ErrInvalidQuery)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+	return s.repo.ListPoints(database.WithQueryRoute(ctx, QueryRouteTelemetry), filter, limit)
+}
+
+// Latest returns the most recent raw point of every agent and metric reported
+// at or after since, the current operating values of the agents
+func (s *Service) Latest(ctx context.Context, since time.Time) ([]*Point, error) {
+	return s.repo.LatestPoints(database.WithQueryRoute(ctx, QueryRouteTelemetry), since.UTC())
+}
+
+// Query aggregates a series over a range, in buckets of the query interval.
+// Downsampled data counts towards the bucket its rollup starts in, so
+// intervals shorter than the downsampling interval are only exact within the
+// raw retention.
+func (s *Service) Query(ctx context.Context, q Query) (*QueryResult, error) {
+	if q.Metric == "" {
+		return nil, fmt.Errorf("%w: metric is required", ErrInvalidQuery)
+	}
+	switch q.Aggregate {
+	case "":
+		q.Aggregate = AggregateAvg
+	case AggregateAvg, AggregateSum, AggregateMin, AggregateMax, AggregateCount, AggregateLast:
+	default:
+		return nil, fmt.Errorf("%w: unknown aggregate %q", ErrInvalidQuery, q.Aggregate)
+	}
+	if q.Interval < 0 || (q.Interval > 0 && q.Interval < time.Second) {
+		return nil, fmt.Errorf("%w: interval must be at least 1s", ErrInvalidQuery)
+	}
+	if q.To.IsZero() {
+		q.To = s.clock.Now()
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-defaultQueryRange)
+	}
+	q.From, q.To = q.From.UTC(), q.To.UTC()
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if q.Interval > 0 {
+		if buckets := q.To.Sub(q.From) / q.Interval; buckets >= time.Duration(s.config.MaxBuckets) {
+			return nil, fmt.Errorf("%w: the range spans more than %d intervals", ErrInvalidQuery, s.config.MaxBuckets)
+		}
+	}
+
+	filter := Filter{AgentID: q.AgentID, Metric: q.Metric, From: q.From, To: q.To}
+	summaries, err := s.repo.Summarize(database.WithQueryRoute(ctx, QueryRouteTelemetry), filter, q.Interval)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult{
+		AgentID:         q.AgentID,
+		Metric:          q.Metric,
+		Aggregate:       q.Aggregate,
+		From:            q.From,
+		To:              q.To,
+		IntervalSeconds: int64(q.Interval / time.Second),
+		Buckets:         make([]Bucket, 0, len(summaries)),
+	}
+	for start, summary := range summaries {
+		if q.Interval == 0 {
+			start = q.From
+		}
+		result.Buckets = append(result.Buckets, Bucket{Start: start, Value: summary.Value(q.Aggregate), Count: summary.Count})
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		return result.Buckets[i].Start.Before(result.Buckets[j].Start)
+	})
+	return result, nil
+}
+
+// Sweep downsamples raw points past the raw retention and removes rollups
+// past the retention. It returns how many points were downsampled and how
+// many rollups were removed.
+func (s *Service) Sweep(ctx context.Context) (int, int, error) {
+	now := s.clock.Now()
+
+	downsampled := 0
+	for {
+		n, err := s.repo.Downsample(ctx, now.Add(-s.config.RawRetention), s.config.DownsampleInterval, s.config.SweepBatchSize)
+		downsampled += n
+		if err != nil {
+			return downsampled, 0, fmt.Errorf("failed to downsample telemetry: %w", err)
+		}
+		if n < s.config.SweepBatchSize {
+			break
+		}
+	}
+
+	removed, err := s.repo.DeleteRollupsBefore(ctx, now.Add(-s.config.Retention))
+	if err != nil {
+		return downsampled, 0, fmt.Errorf("failed to remove expired telemetry: %w", err)
+	}
+
+	if downsampled > 0 || removed > 0 {
+		s.logger.WithFields(log.Fields{
+			"downsampled": downsampled,
+			"removed":     removed,
+		}).Info("Applied telemetry retention")
+	}
+	return downsampled, removed, nil
+}
+
+// Start applies retention on the configured interval until Stop is called
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.config.SweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, _, err := s.Sweep(ctx); err != nil {
+					s.logger.WithError(err).Warn("Failed to apply telemetry retention")
+				}
+			}
+		}
+	}()
+
+	s.logger.WithFields(log.Fields{
+		"raw_retention": s.config.RawRetention,
+		"retention":     s.config.Retention,
+	}).Info("Telemetry retention started")
+}
+
+// Stop stops scheduled retention
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel, stopped := s.cancel, s.stopped
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-stopped
+	}
+}
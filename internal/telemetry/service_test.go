@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestService_IngestAndQuery(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start.Add(time.Hour))
+	service := NewService(NewInMemoryRepository(), Config{MaxPointsPerRequest: 10}, testLogger())
+	service.SetClock(clk)
+	ctx := context.Background()
+
+	require.NoError(t, service.Ingest(ctx, []*Point{
+		{AgentID: "PUMP-001", Metric: "efficiency_percent", Value: 90, Timestamp: start},
+		{AgentID: "PUMP-001", Metric: "efficiency_percent", Value: 80, Timestamp: start.Add(10 * time.Minute)},
+		{AgentID: "PUMP-002", Metric: "efficiency_percent", Value: 70, Timestamp: start.Add(20 * time.Minute)},
+		{AgentID: "PUMP-001", Metric: "efficiency_percent", Value: 60}, // Stamped with the clock
+		{AgentID: "PUMP-001", Metric: "pressure_bar", Value: 4.2, Timestamp: start},
+	}))
+
+	points, err := service.Points(ctx, Filter{AgentID: "PUMP-001", Metric: "efficiency_percent"}, 0)
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	assert.Equal(t, 90.0, points[0].Value)
+	assert.True(t, points[2].Timestamp.Equal(start.Add(time.Hour)))
+
+	result, err := service.Query(ctx, Query{Metric: "efficiency_percent", From: start, To: start.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, result.Buckets, 1)
+	assert.Equal(t, AggregateAvg, result.Aggregate)
+	assert.Equal(t, 75.0, result.Buckets[0].Value)
+	assert.Equal(t, int64(4), result.Buckets[0].Count)
+
+	result, err = service.Query(ctx, Query{AgentID: "PUMP-001", Metric: "efficiency_percent", From: start, To: start.Add(2 * time.Hour), Aggregate: AggregateMax, Interval: 30 * time.Minute})
+	require.NoError(t, err)
+	require.Len(t, result.Buckets, 2)
+	assert.True(t, result.Buckets[0].Start.Equal(start))
+	assert.Equal(t, 90.0, result.Buckets[0].Value)
+	assert.True(t, result.Buckets[1].Start.Equal(start.Add(time.Hour)))
+	assert.Equal(t, 60.0, result.Buckets[1].Value)
+
+	latest, err := service.Latest(ctx, start.Add(5*time.Minute))
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, p := range latest {
+		values[p.AgentID+"/"+p.Metric] = p.Value
+	}
+	assert.Equal(t, map[string]float64{"PUMP-001/efficiency_percent": 60, "PUMP-002/efficiency_percent": 70}, values,
+		"expected the latest reading of each series since the time")
+
+	assert.ErrorIs(t, service.Ingest(ctx, []*Point{{AgentID: "PUMP-001"}}), ErrInvalidPoint)
+	assert.ErrorIs(t, service.Ingest(ctx, make([]*Point, 11)), ErrInvalidPoint)
+	_, err = service.Query(ctx, Query{Metric: "efficiency_percent", Aggregate: "median"})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = service.Query(ctx, Query{Metric: "efficiency_percent", Interval: time.Second})
+	assert.ErrorIs(t, err, ErrInvalidQuery, "a day of 1s buckets exceeds the bucket limit")
+}
+
+func TestService_SweepDownsamplesAndExpires(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	repo := NewInMemoryRepository()
+	service := NewService(repo, Config{
+		RawRetention:       time.Hour,
+		DownsampleInterval: 5 * time.Minute,
+		Retention:          24 * time.Hour,
+		SweepBatchSize:     2,
+	}, testLogger())
+	service.SetClock(clk)
+	ctx := context.Background()
+
+	for i, value := range []float64{10, 20, 30, 40, 50} {
+		require.NoError(t, service.Ingest(ctx, []*Point{
+			{AgentID: "PUMP-001", Metric: "pressure_bar", Value: value, Timestamp: start.Add(time.Duration(i) * time.Minute)},
+		}))
+	}
+	query := Query{Metric: "pressure_bar", From: start, To: start.Add(time.Hour)}
+	before, err := service.Query(ctx, query)
+	require.NoError(t, err)
+
+	clk.Advance(2 * time.Hour)
+	downsampled, removed, err := service.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, downsampled, "batches continue until the backlog is drained")
+	assert.Zero(t, removed)
+
+	points, err := service.Points(ctx, Filter{Metric: "pressure_bar"}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	// Rollups from several batches merge into one bucket with the same aggregates
+	for _, aggregate := range []string{AggregateAvg, AggregateMin, AggregateMax, AggregateCount, AggregateLast} {
+		query.Aggregate = aggregate
+		after, err := service.Query(ctx, query)
+		require.NoError(t, err)
+		require.Len(t, after.Buckets, 1)
+		assert.Equal(t, before.Buckets[0].Count, after.Buckets[0].Count)
+		assert.Equal(t, Summary{Count: 5, Sum: 150, Min: 10, Max: 50, Last: 50}.Value(aggregate), after.Buckets[0].Value, aggregate)
+	}
+
+	clk.Advance(24 * time.Hour)
+	_, removed, err = service.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
+
+func TestBucketStart(t *testing.T) {
+	at := time.Date(2025, 10, 1, 13, 47, 12, 0, time.FixedZone("EAT", 3*60*60))
+	assert.Equal(t, time.Date(2025, 10, 1, 10, 45, 0, 0, time.UTC), bucketStart(at, 5*time.Minute))
+	assert.True(t, bucketStart(at, 0).IsZero())
+
+	// Week buckets align to the Unix epoch, a Thursday, like database buckets
+	week := 7 * 24 * time.Hour
+	assert.Equal(t, time.Date(2025, 9, 25, 0, 0, 0, 0, time.UTC), bucketStart(at, week))
+}
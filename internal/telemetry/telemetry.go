@@ -0,0 +1,202 @@
+// Package telemetry stores the numeric time series agents report, such as
+// pump efficiency or pressure readings, and answers range and aggregate
+// queries over them.
+//
+// Raw points are kept for the raw retention period. Older points are rolled
+// up into downsampled buckets holding the count, sum, minimum, maximum and
+// last value per agent, metric and interval, which are kept for the longer
+// retention period, so long ranges stay cheap to store and query.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Aggregates a query can compute over each bucket
+const (
+	AggregateAvg   = "avg"
+	AggregateSum   = "sum"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+	AggregateLast  = "last"
+)
+
+var (
+	// ErrInvalidPoint is returned for points that cannot be stored
+	ErrInvalidPoint = errors.New("invalid telemetry point")
+
+	// ErrInvalidQuery is returned for queries that cannot be answered
+	ErrInvalidQuery = errors.New("invalid telemetry query")
+)
+
+// Point is one reading of a metric reported by an agent
+type Point struct {
+	ID        string            `json:"_key,omitempty"`
+	AgentID   string            `json:"agent_id"`
+	Metric    string            `json:"metric"` // e.g. efficiency_percent
+	Value     float64           `json:"value"`
+	Timestamp time.Time         `json:"timestamp"`
+	Tags      map[string]string `json:"tags,omitempty"` // e.g. unit or zone
+}
+
+// Rollup summarizes the points of one agent and metric in a downsampling
+// interval
+type Rollup struct {
+	ID       string    `json:"_key,omitempty"`
+	AgentID  string    `json:"agent_id"`
+	Metric   string    `json:"metric"`
+	Start    time.Time `json:"start"`
+	Interval int64     `json:"interval_seconds"`
+	Summary
+}
+
+// Summary aggregates a set of points
+type Summary struct {
+	Count  int64     `json:"count"`
+	Sum    float64   `json:"sum"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Last   float64   `json:"last"`
+	LastAt time.Time `json:"last_at"`
+}
+
+// Add adds a value observed at a time to the summary
+func (s *Summary) Add(value float64, at time.Time) {
+	s.Merge(Summary{Count: 1, Sum: value, Min: value, Max: value, Last: value, LastAt: at})
+}
+
+// Merge adds another summary to the summary
+func (s *Summary) Merge(other Summary) {
+	if other.Count == 0 {
+		return
+	}
+	if s.Count == 0 {
+		*s = other
+		return
+	}
+	s.Count += other.Count
+	s.Sum += other.Sum
+	s.Min = min(s.Min, other.Min)
+	s.Max = max(s.Max, other.Max)
+	if !other.LastAt.Before(s.LastAt) {
+		s.Last, s.LastAt = other.Last, other.LastAt
+	}
+}
+
+// Value returns the aggregate of the summary
+func (s Summary) Value(aggregate string) float64 {
+	switch aggregate {
+	case AggregateSum:
+		return s.Sum
+	case AggregateMin:
+		return s.Min
+	case AggregateMax:
+		return s.Max
+	case AggregateCount:
+		return float64(s.Count)
+	case AggregateLast:
+		return s.Last
+	default:
+		if s.Count == 0 {
+			return 0
+		}
+		return s.Sum / float64(s.Count)
+	}
+}
+
+// Filter selects the points of a metric in a time range. An empty AgentID
+// selects every agent reporting the metric.
+type Filter struct {
+	AgentID string
+	Metric  string
+	From    time.Time // Inclusive; zero means unbounded
+	To      time.Time // Exclusive; zero means unbounded
+}
+
+// matches reports whether the filter selects a series value at a time
+func (f Filter) matches(agentID, metric string, at time.Time) bool {
+	return (f.AgentID == "" || agentID == f.AgentID) &&
+		metric == f.Metric &&
+		(f.From.IsZero() || !at.Before(f.From)) &&
+		(f.To.IsZero() || at.Before(f.To))
+}
+
+// Repository stores telemetry points and their rollups
+type Repository interface {
+	// AddPoints stores raw points
+	AddPoints(ctx context.Context, points []*Point) error
+
+	// ListPoints returns the raw points matching the filter, oldest first,
+	// at most limit of them
+	ListPoints(ctx context.Context, filter Filter, limit int) ([]*Point, error)
+
+	// LatestPoints returns the most recent raw point of every agent and
+	// metric reported at or after since
+	LatestPoints(ctx context.Context, since time.Time) ([]*Point, error)
+
+	// Summarize aggregates the raw points and rollups matching the filter
+	// into buckets of the interval aligned to the Unix epoch, or into one
+	// bucket if the interval is zero. Buckets are keyed by their start.
+	Summarize(ctx context.Context, filter Filter, interval time.Duration) (map[time.Time]Summary, error)
+
+	// Downsample rolls up to limit raw points older than before into
+	// rollups of the interval, merging them with existing rollups, and
+	// removes them. It returns how many points were rolled up.
+	Downsample(ctx context.Context, before time.Time, interval time.Duration, limit int) (int, error)
+
+	// DeleteRollupsBefore removes rollups that start before the time and
+	// returns how many were removed
+	DeleteRollupsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// bucketStart returns the start of the interval containing t, aligned to
+// the Unix epoch like the database buckets, or the zero time for a zero
+// interval
+func bucketStart(t time.Time, interval time.Duration) time.Time {
+	step := interval.Milliseconds()
+	if step <= 0 {
+		return time.Time{}
+	}
+	ms := t.UnixMilli()
+	return time.UnixMilli(ms - ms%step).UTC()
+}
+
+// rollUp summarizes points into rollups of the interval, ordered by agent,
+// metric and start
+func rollUp(points []*Point, interval time.Duration) []*Rollup {
+	type key struct {
+		agentID, metric string
+		start           time.Time
+	}
+
+	byKey := make(map[key]*Rollup)
+	for _, p := range points {
+		k := key{p.AgentID, p.Metric, bucketStart(p.Timestamp, interval)}
+		r, ok := byKey[k]
+		if !ok {
+			r = &Rollup{AgentID: p.AgentID, Metric: p.Metric, Start: k.start, Interval: int64(interval / time.Second)}
+			byKey[k] = r
+		}
+		r.Add(p.Value, p.Timestamp.UTC())
+	}
+
+	rollups := make([]*Rollup, 0, len(byKey))
+	for _, r := range byKey {
+		rollups = append(rollups, r)
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i], rollups[j]
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		return a.Start.Before(b.Start)
+	})
+	return rollups
+}
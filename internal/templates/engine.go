@@ -42,6 +42,9 @@ type Template struct {
 	// Labels for categorization and selection
 	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 
+	// Capabilities lists capability IDs that agents created from this template provide
+	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+
 	// CreatedAt timestamp
 	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
 
@@ -0,0 +1,332 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+)
+
+// agencyService confines tenant-scoped callers to their own agencies
+type agencyService struct {
+	agency.Service
+}
+
+// WrapAgencyService returns an agency service that only lists the agencies of
+// the caller's tenant and fails with ErrForbidden when a tenant-scoped caller
+// names another tenant's agency. Unscoped callers are passed through.
+func WrapAgencyService(service agency.Service) agency.Service {
+	return &agencyService{Service: service}
+}
+
+// check returns ErrForbidden unless ctx may access the agency
+func (s *agencyService) check(ctx context.Context, agencyIDs ...string) error {
+	if FromContext(ctx) == "" {
+		return nil
+	}
+	for _, agencyID := range agencyIDs {
+		ag, err := s.Service.GetAgency(ctx, agencyID)
+		if err != nil {
+			return err
+		}
+		if !Allows(ctx, ag.TenantID) {
+			return fmt.Errorf("%w: agency %s", ErrForbidden, agencyID)
+		}
+	}
+	return nil
+}
+
+func (s *agencyService) GetAgency(ctx context.Context, id string) (*agency.Agency, error) {
+	ag, err := s.Service.GetAgency(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !Allows(ctx, ag.TenantID) {
+		return nil, fmt.Errorf("%w: agency %s", ErrForbidden, id)
+	}
+	return ag, nil
+}
+
+func (s *agencyService) ListAgencies(ctx context.Context, filters agency.AgencyFilters) ([]*agency.Agency, error) {
+	if tenantID := FromContext(ctx); tenantID != "" {
+		filters.TenantID = tenantID
+	}
+	return s.Service.ListAgencies(ctx, filters)
+}
+
+func (s *agencyService) UpdateAgency(ctx context.Context, id string, updates agency.AgencyUpdates) error {
+	if err := s.check(ctx, id); err != nil {
+		return err
+	}
+	return s.Service.UpdateAgency(ctx, id, updates)
+}
+
+func (s *agencyService) DeleteAgency(ctx context.Context, id string) error {
+	if err := s.check(ctx, id); err != nil {
+		return err
+	}
+	return s.Service.DeleteAgency(ctx, id)
+}
+
+func (s *agencyService) SetActiveAgency(ctx context.Context, id string) error {
+	if err := s.check(ctx, id); err != nil {
+		return err
+	}
+	return s.Service.SetActiveAgency(ctx, id)
+}
+
+func (s *agencyService) GetActiveAgency(ctx context.Context) (*agency.Agency, error) {
+	ag, err := s.Service.GetActiveAgency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !Allows(ctx, ag.TenantID) {
+		return nil, fmt.Errorf("%w: agency %s", ErrForbidden, ag.ID)
+	}
+	return ag, nil
+}
+
+func (s *agencyService) GetAgencyStatistics(ctx context.Context, id string) (*agency.AgencyStatistics, error) {
+	if err := s.check(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.Service.GetAgencyStatistics(ctx, id)
+}
+
+func (s *agencyService) GetAgencyOverview(ctx context.Context, agencyID string) (*agency.Overview, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetAgencyOverview(ctx, agencyID)
+}
+
+func (s *agencyService) UpdateAgencyOverview(ctx context.Context, agencyID string, introduction string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.UpdateAgencyOverview(ctx, agencyID, introduction)
+}
+
+func (s *agencyService) CreateGoal(ctx context.Context, agencyID string, code string, description string) (*agency.Goal, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.CreateGoal(ctx, agencyID, code, description)
+}
+
+func (s *agencyService) GetGoals(ctx context.Context, agencyID string) ([]*agency.Goal, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetGoals(ctx, agencyID)
+}
+
+func (s *agencyService) GetGoal(ctx context.Context, agencyID string, key string) (*agency.Goal, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetGoal(ctx, agencyID, key)
+}
+
+func (s *agencyService) UpdateGoal(ctx context.Context, agencyID string, key string, code string, description string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.UpdateGoal(ctx, agencyID, key, code, description)
+}
+
+func (s *agencyService) UpdateGoalFull(ctx context.Context, agencyID string, key string, req agency.UpdateGoalRequest) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.UpdateGoalFull(ctx, agencyID, key, req)
+}
+
+func (s *agencyService) DeleteGoal(ctx context.Context, agencyID string, key string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.DeleteGoal(ctx, agencyID, key)
+}
+
+func (s *agencyService) ReprioritizeGoals(ctx context.Context, agencyID string, priorities []agency.GoalPriority) ([]*agency.Goal, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.ReprioritizeGoals(ctx, agencyID, priorities)
+}
+
+func (s *agencyService) ConsolidateGoals(ctx context.Context, agencyID string, req agency.ConsolidateGoalsRequest) (*agency.GoalConsolidation, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.ConsolidateGoals(ctx, agencyID, req)
+}
+
+func (s *agencyService) UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.UndoGoalConsolidation(ctx, agencyID, key)
+}
+
+func (s *agencyService) CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.CreateWorkItem(ctx, agencyID, req)
+}
+
+func (s *agencyService) GetWorkItems(ctx context.Context, agencyID string) ([]*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetWorkItems(ctx, agencyID)
+}
+
+func (s *agencyService) GetWorkItem(ctx context.Context, agencyID string, key string) (*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetWorkItem(ctx, agencyID, key)
+}
+
+func (s *agencyService) GetWorkItemByCode(ctx context.Context, agencyID string, code string) (*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetWorkItemByCode(ctx, agencyID, code)
+}
+
+func (s *agencyService) UpdateWorkItem(ctx context.Context, agencyID string, key string, req agency.UpdateWorkItemRequest) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.UpdateWorkItem(ctx, agencyID, key, req)
+}
+
+func (s *agencyService) DeleteWorkItem(ctx context.Context, agencyID string, key string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.DeleteWorkItem(ctx, agencyID, key)
+}
+
+func (s *agencyService) ValidateWorkItemDependencies(ctx context.Context, agencyID string, workItemCode string, dependencies []string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.ValidateWorkItemDependencies(ctx, agencyID, workItemCode, dependencies)
+}
+
+func (s *agencyService) TransitionWorkItem(ctx context.Context, agencyID string, key string, req agency.TransitionWorkItemRequest) (*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.TransitionWorkItem(ctx, agencyID, key, req)
+}
+
+func (s *agencyService) AssignWorkItem(ctx context.Context, agencyID string, key string, req agency.AssignWorkItemRequest) (*agency.WorkItem, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.AssignWorkItem(ctx, agencyID, key, req)
+}
+
+func (s *agencyService) GetWorkload(ctx context.Context, agencyID string) (*agency.Workload, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetWorkload(ctx, agencyID)
+}
+
+func (s *agencyService) RecordExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	if err := s.check(ctx, explanation.AgencyID); err != nil {
+		return err
+	}
+	return s.Service.RecordExplanation(ctx, explanation)
+}
+
+func (s *agencyService) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetExplanations(ctx, agencyID, entityType, entityKey)
+}
+
+func (s *agencyService) CopyItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest, adapter agency.ItemAdapter) (*agency.TransferResult, error) {
+	if err := s.check(ctx, targetAgencyID, req.SourceAgencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.CopyItems(ctx, targetAgencyID, req, adapter)
+}
+
+func (s *agencyService) LinkItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest) (*agency.TransferResult, error) {
+	if err := s.check(ctx, targetAgencyID, req.SourceAgencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.LinkItems(ctx, targetAgencyID, req)
+}
+
+func (s *agencyService) ExportAgency(ctx context.Context, agencyID string) (*agency.AgencyBundle, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.ExportAgency(ctx, agencyID)
+}
+
+func (s *agencyService) GetTraceability(ctx context.Context, agencyID string) (*agency.TraceabilityReport, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetTraceability(ctx, agencyID)
+}
+
+func (s *agencyService) CreateRACIAssignment(ctx context.Context, agencyID string, assignment *agency.RACIAssignment) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.CreateRACIAssignment(ctx, agencyID, assignment)
+}
+
+func (s *agencyService) GetRACIAssignmentsForWorkItem(ctx context.Context, agencyID string, workItemKey string) ([]*agency.RACIAssignment, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetRACIAssignmentsForWorkItem(ctx, agencyID, workItemKey)
+}
+
+func (s *agencyService) GetRACIAssignmentsForRole(ctx context.Context, agencyID string, roleID string) ([]*agency.RACIAssignment, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetRACIAssignmentsForRole(ctx, agencyID, roleID)
+}
+
+func (s *agencyService) GetAllRACIAssignments(ctx context.Context, agencyID string) ([]*agency.RACIAssignment, error) {
+	if err := s.check(ctx, agencyID); err != nil {
+		return nil, err
+	}
+	return s.Service.GetAllRACIAssignments(ctx, agencyID)
+}
+
+func (s *agencyService) UpdateRACIAssignment(ctx context.Context, agencyID string, key string, assignment *agency.RACIAssignment) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.UpdateRACIAssignment(ctx, agencyID, key, assignment)
+}
+
+func (s *agencyService) DeleteRACIAssignment(ctx context.Context, agencyID string, key string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.DeleteRACIAssignment(ctx, agencyID, key)
+}
+
+func (s *agencyService) DeleteRACIAssignmentsForWorkItem(ctx context.Context, agencyID string, workItemKey string) error {
+	if err := s.check(ctx, agencyID); err != nil {
+		return err
+	}
+	return s.Service.DeleteRACIAssignmentsForWorkItem(ctx, agencyID, workItemKey)
+}
@@ -0,0 +1,123 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/apiversion"
+	"github.com/gin-gonic/gin"
+)
+
+// tenantRoutes are the API routes, without the version prefix, open to
+// tenant-scoped requests, with the routes below them. The rest of the API
+// manages state shared by every tenant and is reserved for operators.
+var tenantRoutes = []string{
+	"/agencies",
+	"/agents",
+	"/communications/messages",
+	"/communications/publish",
+	"/communications/subscriptions",
+	"/communications/publications",
+	"/communications/agents",
+	"/auth",
+	"/health",
+	"/status",
+}
+
+// webRoutes are the web UI routes open to tenant-scoped requests, with the
+// routes below them. Their handlers only show the tenant's agents and
+// agencies; the other pages show state shared by every tenant.
+var webRoutes = []string{
+	"/",
+	"/dashboard",
+	"/agencies",
+	"/topology",
+	"/geo-network",
+	"/api/web/agents",
+	"/api/web/topology",
+}
+
+// agentQueryParams are query parameters naming agents
+var agentQueryParams = []string{"agent_id", "from_agent_id", "to_agent_id"}
+
+// AgencyLookup finds agencies by ID
+type AgencyLookup interface {
+	GetAgency(ctx context.Context, id string) (*agency.Agency, error)
+}
+
+// Middleware confines tenant-scoped API and web UI requests to their
+// tenant's routes, agencies and agents. It checks the agency or agent named in the path, the
+// agency_id query parameter and X-Agency-ID header, and agent query
+// parameters; IDs in request bodies are checked by the services. Other
+// tenants' data is reported as not found. It must run after the middleware
+// that scopes requests to a tenant.
+func Middleware(agencies AgencyLookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if FromContext(ctx) == "" {
+			c.Next()
+			return
+		}
+		fullPath := c.FullPath()
+		if fullPath == "" {
+			c.Next()
+			return
+		}
+		_, route, ok := apiversion.ParsePath(fullPath)
+		allowed := ok && underAny(route, tenantRoutes)
+		if !ok {
+			route = fullPath
+			allowed = underAny(route, webRoutes)
+		}
+
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "This endpoint is not available to tenant API keys",
+			})
+			return
+		}
+
+		agencyIDs := []string{c.Query("agency_id"), c.GetHeader("X-Agency-ID")}
+		agentIDs := make([]string, 0, len(agentQueryParams)+1)
+		for _, name := range agentQueryParams {
+			agentIDs = append(agentIDs, c.Query(name))
+		}
+		switch {
+		case strings.HasPrefix(route, "/agencies/:id"):
+			agencyIDs = append(agencyIDs, c.Param("id"))
+		case strings.HasPrefix(route, "/agents/:id"), strings.HasPrefix(route, "/communications/agents/:id"),
+			strings.HasPrefix(route, "/api/web/agents/:id"):
+			agentIDs = append(agentIDs, c.Param("id"))
+		}
+
+		for _, agentID := range agentIDs {
+			if agentID != "" && !AllowsID(ctx, agentID) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+				return
+			}
+		}
+		for _, agencyID := range agencyIDs {
+			if agencyID == "" {
+				continue
+			}
+			if ag, err := agencies.GetAgency(ctx, agencyID); err != nil || !Allows(ctx, ag.TenantID) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Agency not found"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// underAny reports whether a route is one of the routes or below one
+func underAny(route string, routes []string) bool {
+	for _, prefix := range routes {
+		if route == prefix || strings.HasPrefix(route, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Package tenant isolates tenants sharing one deployment.
+//
+// A request's tenant comes from the API key it authenticated with and is
+// carried in its context. Requests without a tenant are operator requests and
+// see everything; internal callers such as the runtime and background jobs
+// are unscoped as well.
+//
+// Tenants own agencies and agents. Agencies record their owner in TenantID.
+// Agents are namespaced by ID: an agent of tenant "acme" has an ID such as
+// "acme--4f1c...", so modules that only see agent IDs (messages, pub/sub,
+// memory) can tell which tenant data belongs to. IDs without a tenant prefix
+// belong to no tenant and are only visible to operators.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Separator separates a tenant ID from the rest of a namespaced ID
+const Separator = "--"
+
+// ErrForbidden is returned when a request touches another tenant's data.
+// HTTP handlers report it as not found so tenants cannot probe for IDs.
+var ErrForbidden = errors.New("resource belongs to another tenant")
+
+// idPattern is the format of tenant IDs: lowercase letters, digits and single
+// hyphens, starting with a letter, so namespaced IDs stay valid ArangoDB keys
+var idPattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// maxIDLength bounds tenant IDs
+const maxIDLength = 32
+
+// ValidateID checks the format of a tenant ID
+func ValidateID(id string) error {
+	if len(id) > maxIDLength || !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid tenant ID %q: use up to %d lowercase letters, digits and single hyphens, starting with a letter", id, maxIDLength)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// WithTenant scopes ctx to a tenant. An empty ID leaves ctx unscoped.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	if tenantID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant ctx is scoped to, or "" for operators and
+// internal callers
+func FromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(contextKey{}).(string)
+	return tenantID
+}
+
+// Qualify namespaces an ID for a tenant. IDs already in the tenant's
+// namespace and IDs for no tenant are returned unchanged.
+func Qualify(tenantID, id string) string {
+	if tenantID == "" || strings.HasPrefix(id, tenantID+Separator) {
+		return id
+	}
+	return tenantID + Separator + id
+}
+
+// Owner returns the tenant a namespaced ID belongs to, or "" for IDs
+// outside any tenant's namespace
+func Owner(id string) string {
+	owner, _, found := strings.Cut(id, Separator)
+	if !found || ValidateID(owner) != nil {
+		return ""
+	}
+	return owner
+}
+
+// SameTenant reports whether two namespaced IDs belong to the same tenant
+func SameTenant(a, b string) bool {
+	return Owner(a) == Owner(b)
+}
+
+// Allows reports whether ctx may access data owned by ownerID ("" for data
+// outside any tenant)
+func Allows(ctx context.Context, ownerID string) bool {
+	tenantID := FromContext(ctx)
+	return tenantID == "" || tenantID == ownerID
+}
+
+// AllowsID reports whether ctx may access the data behind a namespaced ID
+func AllowsID(ctx context.Context, id string) bool {
+	return Allows(ctx, Owner(id))
+}
+
+// CheckIDs returns ErrForbidden unless ctx may access every namespaced ID
+func CheckIDs(ctx context.Context, ids ...string) error {
+	for _, id := range ids {
+		if !AllowsID(ctx, id) {
+			return fmt.Errorf("%w: %s", ErrForbidden, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateID(t *testing.T) {
+	assert.NoError(t, ValidateID("acme"))
+	assert.NoError(t, ValidateID("acme-2"))
+	assert.Error(t, ValidateID(""))
+	assert.Error(t, ValidateID("Acme"))
+	assert.Error(t, ValidateID("2acme"))
+	assert.Error(t, ValidateID("acme--corp"))
+	assert.Error(t, ValidateID("a-very-long-tenant-name-over-limit"))
+}
+
+func TestNamespacedIDs(t *testing.T) {
+	id := Qualify("acme", "4f1c")
+	assert.Equal(t, "acme--4f1c", id)
+	assert.Equal(t, id, Qualify("acme", id))
+	assert.Equal(t, "4f1c", Qualify("", "4f1c"))
+
+	assert.Equal(t, "acme", Owner(id))
+	assert.Equal(t, "", Owner("4f1c"))
+	assert.True(t, SameTenant("acme--a", "acme--b"))
+	assert.False(t, SameTenant("acme--a", "globex--a"))
+	assert.False(t, SameTenant("acme--a", "a"))
+
+	acme := WithTenant(context.Background(), "acme")
+	assert.True(t, AllowsID(acme, "acme--a"))
+	assert.False(t, AllowsID(acme, "globex--a"))
+	assert.False(t, AllowsID(acme, "a"))
+	assert.True(t, AllowsID(context.Background(), "globex--a"))
+	assert.ErrorIs(t, CheckIDs(acme, "acme--a", "a"), ErrForbidden)
+}
+
+// fakeAgencies serves agencies from a map
+type fakeAgencies struct {
+	agency.Service
+	agencies map[string]*agency.Agency
+}
+
+func (f *fakeAgencies) GetAgency(ctx context.Context, id string) (*agency.Agency, error) {
+	ag, ok := f.agencies[id]
+	if !ok {
+		return nil, fmt.Errorf("agency %s not found", id)
+	}
+	return ag, nil
+}
+
+func (f *fakeAgencies) ListAgencies(ctx context.Context, filters agency.AgencyFilters) ([]*agency.Agency, error) {
+	var agencies []*agency.Agency
+	for _, ag := range f.agencies {
+		if filters.TenantID == "" || ag.TenantID == filters.TenantID {
+			agencies = append(agencies, ag)
+		}
+	}
+	return agencies, nil
+}
+
+func newFakeAgencies() *fakeAgencies {
+	return &fakeAgencies{agencies: map[string]*agency.Agency{
+		"agency_acme":   {ID: "agency_acme", TenantID: "acme"},
+		"agency_globex": {ID: "agency_globex", TenantID: "globex"},
+		"agency_shared": {ID: "agency_shared"},
+	}}
+}
+
+func TestWrapAgencyService(t *testing.T) {
+	service := WrapAgencyService(newFakeAgencies())
+	acme := WithTenant(context.Background(), "acme")
+
+	_, err := service.GetAgency(acme, "agency_acme")
+	assert.NoError(t, err)
+	_, err = service.GetAgency(acme, "agency_globex")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.GetAgency(context.Background(), "agency_globex")
+	assert.NoError(t, err)
+
+	agencies, err := service.ListAgencies(acme, agency.AgencyFilters{})
+	require.NoError(t, err)
+	require.Len(t, agencies, 1)
+	assert.Equal(t, "agency_acme", agencies[0].ID)
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		ctx := WithTenant(c.Request.Context(), c.GetHeader("X-Test-Tenant"))
+		c.Request = c.Request.WithContext(ctx)
+	})
+	router.Use(Middleware(newFakeAgencies()))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/agencies/:id", ok)
+	router.GET("/api/v1/agents/:id", ok)
+	router.GET("/api/v1/communications/messages", ok)
+	router.GET("/api/v1/communications/stats", ok)
+	router.GET("/dashboard", ok)
+	router.GET("/jobs", ok)
+	router.GET("/api/web/agents/json", ok)
+	router.POST("/api/web/agents/:id/:action", ok)
+	router.POST("/api/web/roles/:id/:action", ok)
+
+	tests := []struct {
+		name   string
+		tenant string
+		method string
+		path   string
+		want   int
+	}{
+		{"operators see everything", "", http.MethodGet, "/api/v1/agencies/agency_globex", http.StatusOK},
+		{"own agency", "acme", http.MethodGet, "/api/v1/agencies/agency_acme", http.StatusOK},
+		{"another tenant's agency", "acme", http.MethodGet, "/api/v1/agencies/agency_globex", http.StatusNotFound},
+		{"agency without a tenant", "acme", http.MethodGet, "/api/v1/agencies/agency_shared", http.StatusNotFound},
+		{"unknown agency", "acme", http.MethodGet, "/api/v1/agencies/agency_missing", http.StatusNotFound},
+		{"own agent", "acme", http.MethodGet, "/api/v1/agents/acme--a", http.StatusOK},
+		{"another tenant's agent", "acme", http.MethodGet, "/api/v1/agents/globex--a", http.StatusNotFound},
+		{"agent query parameter", "acme", http.MethodGet, "/api/v1/communications/messages?to_agent_id=globex--a", http.StatusNotFound},
+		{"agency query parameter", "acme", http.MethodGet, "/api/v1/communications/messages?agency_id=agency_globex", http.StatusNotFound},
+		{"shared endpoints are reserved for operators", "acme", http.MethodGet, "/api/v1/communications/stats", http.StatusForbidden},
+		{"dashboard page", "acme", http.MethodGet, "/dashboard", http.StatusOK},
+		{"shared pages are reserved for operators", "acme", http.MethodGet, "/jobs", http.StatusForbidden},
+		{"web agent list", "acme", http.MethodGet, "/api/web/agents/json", http.StatusOK},
+		{"action on own agent", "acme", http.MethodPost, "/api/web/agents/acme--a/stop", http.StatusOK},
+		{"action on another tenant's agent", "acme", http.MethodPost, "/api/web/agents/globex--a/stop", http.StatusNotFound},
+		{"operators act on any agent", "", http.MethodPost, "/api/web/agents/globex--a/stop", http.StatusOK},
+		{"shared web actions are reserved for operators", "acme", http.MethodPost, "/api/web/roles/r1/enable", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Test-Tenant", tt.tenant)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+		})
+	}
+}
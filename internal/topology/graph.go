@@ -0,0 +1,161 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrAssetNotFound is returned when a registered asset does not exist
+	ErrAssetNotFound = errors.New("asset not found")
+
+	// ErrAssetExists is returned when an asset ID is already registered
+	ErrAssetExists = errors.New("asset already exists")
+
+	// ErrConnectionNotFound is returned when a connection does not exist
+	ErrConnectionNotFound = errors.New("connection not found")
+
+	// ErrConnectionExists is returned when two assets are already connected the same way
+	ErrConnectionExists = errors.New("connection already exists")
+
+	// ErrInvalidAsset is returned for assets and connections that fail validation
+	ErrInvalidAsset = errors.New("invalid asset")
+)
+
+// Asset types commonly registered in the asset graph. Other types are
+// accepted too.
+const (
+	AssetPipe      = "pipe"
+	AssetValve     = "valve"
+	AssetPump      = "pump"
+	AssetSensor    = "sensor"
+	AssetReservoir = "reservoir"
+)
+
+// ConnectionKind is how two assets are connected
+type ConnectionKind string
+
+const (
+	// ConnectionFlow carries water from the From asset to the To asset
+	ConnectionFlow ConnectionKind = "flow"
+
+	// ConnectionMonitors attaches the From asset, a sensor, to the asset it measures
+	ConnectionMonitors ConnectionKind = "monitors"
+)
+
+// Direction is the way a traversal follows flow connections
+type Direction string
+
+const (
+	Upstream   Direction = "upstream"
+	Downstream Direction = "downstream"
+)
+
+// assetIDPattern allows the characters of ArangoDB document keys except the
+// colon, which separates the parts of connection IDs
+var assetIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-.@()+,=;$!*'%]{1,200}$`)
+
+// Asset is a physical asset registered in the asset graph. Its ID is the ID
+// agents and workflows refer to it by, usually that of the agent representing
+// it.
+type Asset struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name,omitempty"`
+	Zone       string            `json:"zone,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"` // e.g. diameter_mm or normally_closed
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// Validate checks the asset's ID and type
+func (a *Asset) Validate() error {
+	if !assetIDPattern.MatchString(a.ID) {
+		return fmt.Errorf("%w: id %q must be 1-200 letters, digits or _-.@()+,=;$!*'%%", ErrInvalidAsset, a.ID)
+	}
+	if strings.TrimSpace(a.Type) == "" {
+		return fmt.Errorf("%w: type is required", ErrInvalidAsset)
+	}
+	return nil
+}
+
+// Connection joins two assets
+type Connection struct {
+	ID         string            `json:"id"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Kind       ConnectionKind    `json:"kind"`
+	Properties map[string]string `json:"properties,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// connectionID identifies a connection by its kind and assets, so the same
+// assets cannot be connected the same way twice
+func connectionID(kind ConnectionKind, from, to string) string {
+	return string(kind) + ":" + from + ":" + to
+}
+
+// AssetFilter selects assets. Zero fields do not filter.
+type AssetFilter struct {
+	Type string
+	Zone string
+}
+
+// matches reports whether the filter selects an asset
+func (f AssetFilter) matches(a *Asset) bool {
+	return (f.Type == "" || a.Type == f.Type) && (f.Zone == "" || a.Zone == f.Zone)
+}
+
+// Traversal walks flow connections from an asset
+type Traversal struct {
+	Start     string
+	Direction Direction
+	MaxDepth  int
+
+	// StopAt lists asset types the walk reaches but does not pass through
+	StopAt []string
+}
+
+// Reached is an asset found by a traversal
+type Reached struct {
+	Asset *Asset   `json:"asset"`
+	Depth int      `json:"depth"` // Flow connections between the start and the asset
+	Path  []string `json:"path"`  // Asset IDs from the start to the asset
+}
+
+// Graph stores the asset graph and walks it
+type Graph interface {
+	// CreateAsset registers a new asset
+	CreateAsset(ctx context.Context, asset *Asset) error
+
+	// GetAsset retrieves an asset by ID
+	GetAsset(ctx context.Context, id string) (*Asset, error)
+
+	// UpdateAsset replaces a registered asset
+	UpdateAsset(ctx context.Context, asset *Asset) error
+
+	// DeleteAsset removes an asset and its connections
+	DeleteAsset(ctx context.Context, id string) error
+
+	// ListAssets returns the assets matching the filter, by ID
+	ListAssets(ctx context.Context, filter AssetFilter) ([]*Asset, error)
+
+	// CreateConnection connects two assets
+	CreateConnection(ctx context.Context, connection *Connection) error
+
+	// DeleteConnection removes a connection
+	DeleteConnection(ctx context.Context, id string) error
+
+	// Connections returns the connections from and to an asset
+	Connections(ctx context.Context, assetID string) ([]*Connection, error)
+
+	// Traverse returns the assets reached from the start, nearest first
+	Traverse(ctx context.Context, traversal Traversal) ([]*Reached, error)
+}
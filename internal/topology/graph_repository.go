@@ -0,0 +1,533 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionAssets is the vertex collection of the asset graph
+	CollectionAssets = "topology_assets"
+
+	// CollectionConnections is the edge collection of the asset graph
+	CollectionConnections = "topology_connections"
+)
+
+// connectionDocument is a connection as stored in the edge collection
+type connectionDocument struct {
+	Key        string `json:"_key"`
+	ArangoFrom string `json:"_from"`
+	ArangoTo   string `json:"_to"`
+	*Connection
+}
+
+// ArangoGraph stores the asset graph in an ArangoDB vertex collection of
+// assets and an edge collection of connections, and walks it with AQL graph
+// traversals
+type ArangoGraph struct {
+	db          driver.Database
+	assets      driver.Collection
+	connections driver.Collection
+}
+
+// NewArangoGraph creates a new ArangoDB-backed asset graph
+func NewArangoGraph(dbClient *database.ArangoClient) (*ArangoGraph, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	assets, err := ensureCollection(ctx, db, CollectionAssets, false)
+	if err != nil {
+		return nil, err
+	}
+	connections, err := ensureCollection(ctx, db, CollectionConnections, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := assets.EnsurePersistentIndex(ctx, []string{"type", "zone"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_topology_assets_type",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoGraph{
+		db:          db,
+		assets:      assets,
+		connections: connections,
+	}, nil
+}
+
+func ensureCollection(ctx context.Context, db driver.Database, name string, isEdge bool) (driver.Collection, error) {
+	exists, err := db.CollectionExists(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+	if exists {
+		col, err := db.Collection(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+		return col, nil
+	}
+
+	options := &driver.CreateCollectionOptions{}
+	if isEdge {
+		options.Type = driver.CollectionTypeEdge
+	}
+	col, err := db.CreateCollection(ctx, name, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	log.WithField("collection", name).Info("Created new collection")
+	return col, nil
+}
+
+// CreateAsset registers a new asset
+func (g *ArangoGraph) CreateAsset(ctx context.Context, asset *Asset) error {
+	asset.Key = asset.ID
+	if _, err := g.assets.CreateDocument(ctx, asset); err != nil {
+		if driver.IsConflict(err) {
+			return fmt.Errorf("%w: %s", ErrAssetExists, asset.ID)
+		}
+		return fmt.Errorf("failed to create asset: %w", err)
+	}
+	return nil
+}
+
+// GetAsset retrieves an asset by ID
+func (g *ArangoGraph) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	var asset Asset
+	if _, err := g.assets.ReadDocument(ctx, id, &asset); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+	return &asset, nil
+}
+
+// UpdateAsset replaces a registered asset
+func (g *ArangoGraph) UpdateAsset(ctx context.Context, asset *Asset) error {
+	asset.Key = asset.ID
+	if _, err := g.assets.ReplaceDocument(ctx, asset.ID, asset); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrAssetNotFound, asset.ID)
+		}
+		return fmt.Errorf("failed to update asset: %w", err)
+	}
+	return nil
+}
+
+// DeleteAsset removes an asset and its connections
+func (g *ArangoGraph) DeleteAsset(ctx context.Context, id string) error {
+	if _, err := g.assets.RemoveDocument(ctx, id); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrAssetNotFound, id)
+		}
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+
+	query := `
+		FOR c IN @@connections
+			FILTER c._from == @vertex OR c._to == @vertex
+			REMOVE c IN @@connections
+	`
+	cursor, err := g.db.Query(ctx, query, map[string]interface{}{
+		"@connections": CollectionConnections,
+		"vertex":       vertexID(id),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete asset connections: %w", err)
+	}
+	return cursor.Close()
+}
+
+// ListAssets returns the assets matching the filter, by ID
+func (g *ArangoGraph) ListAssets(ctx context.Context, filter AssetFilter) ([]*Asset, error) {
+	var conditions []string
+	bindVars := map[string]interface{}{
+		"@collection": CollectionAssets,
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "a.type == @type")
+		bindVars["type"] = filter.Type
+	}
+	if filter.Zone != "" {
+		conditions = append(conditions, "a.zone == @zone")
+		bindVars["zone"] = filter.Zone
+	}
+
+	filterClause := ""
+	if len(conditions) > 0 {
+		filterClause = "FILTER " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		FOR a IN @@collection
+			%s
+			SORT a.id
+			RETURN a
+	`, filterClause)
+
+	cursor, err := g.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assets: %w", err)
+	}
+	defer cursor.Close()
+
+	var assets []*Asset
+	for {
+		var asset Asset
+		_, err := cursor.ReadDocument(ctx, &asset)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read asset: %w", err)
+		}
+		assets = append(assets, &asset)
+	}
+
+	return assets, nil
+}
+
+// CreateConnection connects two assets
+func (g *ArangoGraph) CreateConnection(ctx context.Context, connection *Connection) error {
+	doc := connectionDocument{
+		Key:        connection.ID,
+		ArangoFrom: vertexID(connection.From),
+		ArangoTo:   vertexID(connection.To),
+		Connection: connection,
+	}
+	if _, err := g.connections.CreateDocument(ctx, doc); err != nil {
+		if driver.IsConflict(err) {
+			return fmt.Errorf("%w: %s", ErrConnectionExists, connection.ID)
+		}
+		return fmt.Errorf("failed to create connection: %w", err)
+	}
+	return nil
+}
+
+// DeleteConnection removes a connection
+func (g *ArangoGraph) DeleteConnection(ctx context.Context, id string) error {
+	if _, err := g.connections.RemoveDocument(ctx, id); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+		}
+		return fmt.Errorf("failed to delete connection: %w", err)
+	}
+	return nil
+}
+
+// Connections returns the connections from and to an asset
+func (g *ArangoGraph) Connections(ctx context.Context, assetID string) ([]*Connection, error) {
+	query := `
+		FOR c IN @@connections
+			FILTER c._from == @vertex OR c._to == @vertex
+			SORT c._key
+			RETURN c
+	`
+	cursor, err := g.db.Query(ctx, query, map[string]interface{}{
+		"@connections": CollectionConnections,
+		"vertex":       vertexID(assetID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query connections: %w", err)
+	}
+	defer cursor.Close()
+
+	var connections []*Connection
+	for {
+		doc := connectionDocument{Connection: &Connection{}}
+		_, err := cursor.ReadDocument(ctx, &doc)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read connection: %w", err)
+		}
+		connections = append(connections, doc.Connection)
+	}
+
+	return connections, nil
+}
+
+// Traverse walks flow connections breadth first, so each asset is reached
+// along a shortest path. Assets of a StopAt type are returned but not passed
+// through, and connections of other kinds are not followed.
+func (g *ArangoGraph) Traverse(ctx context.Context, traversal Traversal) ([]*Reached, error) {
+	var direction string
+	switch traversal.Direction {
+	case Upstream:
+		direction = "INBOUND"
+	case Downstream:
+		direction = "OUTBOUND"
+	default:
+		return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalidAsset, traversal.Direction)
+	}
+	stopAt := traversal.StopAt
+	if stopAt == nil {
+		stopAt = []string{}
+	}
+
+	query := fmt.Sprintf(`
+		FOR v, e, p IN 1..@depth %s @start @@connections
+			PRUNE e != null AND (e.kind != @kind OR v.type IN @stopAt)
+			OPTIONS {order: "bfs", uniqueVertices: "global"}
+			FILTER p.edges[*].kind ALL == @kind
+			RETURN {asset: v, depth: LENGTH(p.edges), path: p.vertices[*].id}
+	`, direction)
+
+	cursor, err := g.db.Query(ctx, query, map[string]interface{}{
+		"@connections": CollectionConnections,
+		"start":        vertexID(traversal.Start),
+		"depth":        traversal.MaxDepth,
+		"kind":         ConnectionFlow,
+		"stopAt":       stopAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to traverse assets: %w", err)
+	}
+	defer cursor.Close()
+
+	var reached []*Reached
+	for {
+		var r Reached
+		_, err := cursor.ReadDocument(ctx, &r)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read traversal result: %w", err)
+		}
+		reached = append(reached, &r)
+	}
+
+	return reached, nil
+}
+
+// vertexID is the document handle of an asset, as used in _from and _to
+func vertexID(assetID string) string {
+	return CollectionAssets + "/" + assetID
+}
+
+// InMemoryGraph keeps the asset graph in memory, with the connections of each
+// asset indexed in both directions for traversals.
+type InMemoryGraph struct {
+	mu          sync.RWMutex
+	assets      map[string]*Asset
+	connections map[string]*Connection
+}
+
+// NewInMemoryGraph creates a new in-memory asset graph
+func NewInMemoryGraph() *InMemoryGraph {
+	return &InMemoryGraph{
+		assets:      make(map[string]*Asset),
+		connections: make(map[string]*Connection),
+	}
+}
+
+// CreateAsset registers a new asset
+func (g *InMemoryGraph) CreateAsset(ctx context.Context, asset *Asset) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.assets[asset.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrAssetExists, asset.ID)
+	}
+	asset.Key = asset.ID
+	g.assets[asset.ID] = cloneAsset(asset)
+	return nil
+}
+
+// GetAsset retrieves an asset by ID
+func (g *InMemoryGraph) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	asset, exists := g.assets[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, id)
+	}
+	return cloneAsset(asset), nil
+}
+
+// UpdateAsset replaces a registered asset
+func (g *InMemoryGraph) UpdateAsset(ctx context.Context, asset *Asset) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.assets[asset.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrAssetNotFound, asset.ID)
+	}
+	asset.Key = asset.ID
+	g.assets[asset.ID] = cloneAsset(asset)
+	return nil
+}
+
+// DeleteAsset removes an asset and its connections
+func (g *InMemoryGraph) DeleteAsset(ctx context.Context, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.assets[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrAssetNotFound, id)
+	}
+	delete(g.assets, id)
+	for key, connection := range g.connections {
+		if connection.From == id || connection.To == id {
+			delete(g.connections, key)
+		}
+	}
+	return nil
+}
+
+// ListAssets returns the assets matching the filter, by ID
+func (g *InMemoryGraph) ListAssets(ctx context.Context, filter AssetFilter) ([]*Asset, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var assets []*Asset
+	for _, asset := range g.assets {
+		if filter.matches(asset) {
+			assets = append(assets, cloneAsset(asset))
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].ID < assets[j].ID })
+	return assets, nil
+}
+
+// CreateConnection connects two assets
+func (g *InMemoryGraph) CreateConnection(ctx context.Context, connection *Connection) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.connections[connection.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrConnectionExists, connection.ID)
+	}
+	g.connections[connection.ID] = cloneConnection(connection)
+	return nil
+}
+
+// DeleteConnection removes a connection
+func (g *InMemoryGraph) DeleteConnection(ctx context.Context, id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.connections[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrConnectionNotFound, id)
+	}
+	delete(g.connections, id)
+	return nil
+}
+
+// Connections returns the connections from and to an asset
+func (g *InMemoryGraph) Connections(ctx context.Context, assetID string) ([]*Connection, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var connections []*Connection
+	for _, connection := range g.connections {
+		if connection.From == assetID || connection.To == assetID {
+			connections = append(connections, cloneConnection(connection))
+		}
+	}
+	sort.Slice(connections, func(i, j int) bool { return connections[i].ID < connections[j].ID })
+	return connections, nil
+}
+
+// Traverse walks flow connections breadth first, so each asset is reached
+// along a shortest path. Assets of a StopAt type are returned but not passed
+// through.
+func (g *InMemoryGraph) Traverse(ctx context.Context, traversal Traversal) ([]*Reached, error) {
+	if traversal.Direction != Upstream && traversal.Direction != Downstream {
+		return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalidAsset, traversal.Direction)
+	}
+	stop := make(map[string]bool, len(traversal.StopAt))
+	for _, assetType := range traversal.StopAt {
+		stop[assetType] = true
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// Flow neighbours in the traversal direction, in connection ID order
+	ids := make([]string, 0, len(g.connections))
+	for id := range g.connections {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	next := make(map[string][]string)
+	for _, id := range ids {
+		connection := g.connections[id]
+		if connection.Kind != ConnectionFlow {
+			continue
+		}
+		if traversal.Direction == Downstream {
+			next[connection.From] = append(next[connection.From], connection.To)
+		} else {
+			next[connection.To] = append(next[connection.To], connection.From)
+		}
+	}
+
+	var reached []*Reached
+	visited := map[string]bool{traversal.Start: true}
+	queue := [][]string{{traversal.Start}}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		depth := len(path) - 1
+		if depth >= traversal.MaxDepth {
+			continue
+		}
+		current := path[depth]
+		if asset, exists := g.assets[current]; depth > 0 && exists && stop[asset.Type] {
+			continue
+		}
+
+		for _, id := range next[current] {
+			asset, exists := g.assets[id]
+			if visited[id] || !exists {
+				continue
+			}
+			visited[id] = true
+			extended := append(append([]string{}, path...), id)
+			reached = append(reached, &Reached{Asset: cloneAsset(asset), Depth: depth + 1, Path: extended})
+			queue = append(queue, extended)
+		}
+	}
+
+	return reached, nil
+}
+
+// cloneAsset copies an asset so stored assets are not shared with callers
+func cloneAsset(asset *Asset) *Asset {
+	copied := *asset
+	copied.Properties = cloneProperties(asset.Properties)
+	return &copied
+}
+
+// cloneConnection copies a connection so stored connections are not shared with callers
+func cloneConnection(connection *Connection) *Connection {
+	copied := *connection
+	copied.Properties = cloneProperties(connection.Properties)
+	return &copied
+}
+
+func cloneProperties(properties map[string]string) map[string]string {
+	if properties == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(properties))
+	for key, value := range properties {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,136 @@
+package topology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGraphService registers a small network:
+//
+//	RES-001 → PUMP-001 → VALVE-001 → PIPE-001 → VALVE-002 → PIPE-002
+//	                                     ↓
+//	                                 PIPE-003 → VALVE-003 → PIPE-004
+//
+// with SENSOR-001 monitoring PIPE-001
+func newGraphService(t *testing.T) *Service {
+	t.Helper()
+	service := NewService(nil, Config{})
+	ctx := context.Background()
+
+	for id, assetType := range map[string]string{
+		"RES-001":    AssetReservoir,
+		"PUMP-001":   AssetPump,
+		"VALVE-001":  AssetValve,
+		"VALVE-002":  AssetValve,
+		"VALVE-003":  AssetValve,
+		"PIPE-001":   AssetPipe,
+		"PIPE-002":   AssetPipe,
+		"PIPE-003":   AssetPipe,
+		"PIPE-004":   AssetPipe,
+		"SENSOR-001": AssetSensor,
+	} {
+		require.NoError(t, service.RegisterAsset(ctx, &Asset{ID: id, Type: assetType, Zone: "north"}))
+	}
+	for _, pair := range [][2]string{
+		{"RES-001", "PUMP-001"},
+		{"PUMP-001", "VALVE-001"},
+		{"VALVE-001", "PIPE-001"},
+		{"PIPE-001", "VALVE-002"},
+		{"VALVE-002", "PIPE-002"},
+		{"PIPE-001", "PIPE-003"},
+		{"PIPE-003", "VALVE-003"},
+		{"VALVE-003", "PIPE-004"},
+	} {
+		require.NoError(t, service.Connect(ctx, &Connection{From: pair[0], To: pair[1]}))
+	}
+	require.NoError(t, service.Connect(ctx, &Connection{From: "SENSOR-001", To: "PIPE-001", Kind: ConnectionMonitors}))
+	return service
+}
+
+func reachedIDs(reached []*Reached) []string {
+	ids := make([]string, len(reached))
+	for i, r := range reached {
+		ids[i] = r.Asset.ID
+	}
+	return ids
+}
+
+func TestService_IsolationValves(t *testing.T) {
+	service := newGraphService(t)
+
+	boundary, err := service.IsolationValves(context.Background(), "PIPE-001")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VALVE-001"}, reachedIDs(boundary.Upstream))
+	assert.ElementsMatch(t, []string{"VALVE-002", "VALVE-003"}, reachedIDs(boundary.Downstream))
+	for _, valve := range boundary.Downstream {
+		if valve.Asset.ID == "VALVE-003" {
+			assert.Equal(t, 2, valve.Depth)
+			assert.Equal(t, []string{"PIPE-001", "PIPE-003", "VALVE-003"}, valve.Path)
+		}
+	}
+
+	_, err = service.IsolationValves(context.Background(), "PIPE-999")
+	assert.ErrorIs(t, err, ErrAssetNotFound)
+}
+
+func TestService_UpstreamDownstream(t *testing.T) {
+	service := newGraphService(t)
+	ctx := context.Background()
+
+	upstream, err := service.Upstream(ctx, "PIPE-001", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VALVE-001", "PUMP-001", "RES-001"}, reachedIDs(upstream), "monitoring sensors are not upstream")
+	assert.Equal(t, 3, upstream[2].Depth)
+
+	near, err := service.Upstream(ctx, "PIPE-001", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"VALVE-001", "PUMP-001"}, reachedIDs(near))
+
+	pipes, err := service.Downstream(ctx, "PIPE-001", 0, AssetPipe)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"PIPE-002", "PIPE-003", "PIPE-004"}, reachedIDs(pipes))
+
+	none, err := service.Downstream(ctx, "PIPE-004", 0)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestService_AssetGraphChanges(t *testing.T) {
+	service := newGraphService(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, service.RegisterAsset(ctx, &Asset{ID: "PIPE-001", Type: AssetPipe}), ErrAssetExists)
+	assert.ErrorIs(t, service.RegisterAsset(ctx, &Asset{ID: "PIPE/005", Type: AssetPipe}), ErrInvalidAsset)
+	assert.ErrorIs(t, service.RegisterAsset(ctx, &Asset{ID: "PIPE-005"}), ErrInvalidAsset)
+	assert.ErrorIs(t, service.Connect(ctx, &Connection{From: "PIPE-001", To: "VALVE-002"}), ErrConnectionExists)
+	assert.ErrorIs(t, service.Connect(ctx, &Connection{From: "PIPE-001", To: "PIPE-999"}), ErrInvalidAsset)
+	assert.ErrorIs(t, service.Connect(ctx, &Connection{From: "PIPE-001", To: "PIPE-001"}), ErrInvalidAsset)
+
+	connections, err := service.Connections(ctx, "PIPE-001")
+	require.NoError(t, err)
+	assert.Len(t, connections, 4)
+
+	// Bypassing VALVE-001 leaves PIPE-001 fed straight from the pump
+	require.NoError(t, service.DeleteAsset(ctx, "VALVE-001"))
+	require.NoError(t, service.Connect(ctx, &Connection{From: "PUMP-001", To: "PIPE-001"}))
+	boundary, err := service.IsolationValves(ctx, "PIPE-001")
+	require.NoError(t, err)
+	assert.Empty(t, boundary.Upstream)
+
+	require.NoError(t, service.Disconnect(ctx, connectionID(ConnectionFlow, "PUMP-001", "PIPE-001")))
+	assert.ErrorIs(t, service.Disconnect(ctx, "flow:PUMP-001:PIPE-001"), ErrConnectionNotFound)
+
+	updated, err := service.UpdateAsset(ctx, &Asset{ID: "PIPE-001", Type: AssetPipe, Name: "Main St trunk"})
+	require.NoError(t, err)
+	assert.False(t, updated.CreatedAt.IsZero())
+	connections, err = service.Connections(ctx, "PIPE-001")
+	require.NoError(t, err)
+	assert.Len(t, connections, 3, "updating an asset keeps its connections")
+
+	valves, err := service.ListAssets(ctx, AssetFilter{Type: AssetValve})
+	require.NoError(t, err)
+	assert.Len(t, valves, 2)
+}
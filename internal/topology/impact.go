@@ -0,0 +1,360 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUnknownValve is returned for valves that are not on the network
+	ErrUnknownValve = errors.New("unknown valve")
+
+	// ErrUnknownPipe is returned for pipes that are not on the network
+	ErrUnknownPipe = errors.New("unknown pipe")
+
+	// ErrNotIsolatable is returned when no set of operable valves cuts a pipe off from every source
+	ErrNotIsolatable = errors.New("pipe cannot be isolated")
+)
+
+// maxIsolationSearch bounds the number of valve sets explored per pipe
+const maxIsolationSearch = 64
+
+// ZoneImpact summarizes the supply lost in one zone
+type ZoneImpact struct {
+	Zone               string  `json:"zone"`
+	PipesWithoutSupply int     `json:"pipes_without_supply"`
+	PipesTotal         int     `json:"pipes_total"`
+	Customers          float64 `json:"customers"`
+	DemandM3PerHour    float64 `json:"demand_m3_per_hour"`
+	FullOutage         bool    `json:"full_outage"`
+}
+
+// AlternativeRoute is a normally closed valve whose opening restores supply
+// to some of the pipes an isolation cuts off
+type AlternativeRoute struct {
+	OpenValve               string   `json:"open_valve"`
+	RestoredPipes           []string `json:"restored_pipes"`
+	RestoredCustomers       float64  `json:"restored_customers"`
+	RestoredDemandM3PerHour float64  `json:"restored_demand_m3_per_hour"`
+}
+
+// Impact is what loses supply when a set of valves is closed, compared with
+// the network's normal valve positions
+type Impact struct {
+	ClosedValves            []string           `json:"closed_valves"`
+	PipesWithoutSupply      []string           `json:"pipes_without_supply"`
+	Zones                   []ZoneImpact       `json:"zones"`
+	ZonesWithoutSupply      []string           `json:"zones_without_supply"`
+	AffectedCustomers       float64            `json:"affected_customers"`
+	AffectedDemandM3PerHour float64            `json:"affected_demand_m3_per_hour"`
+	AlternativeRoutes       []AlternativeRoute `json:"alternative_routes"`
+}
+
+// Analyze reports the pipes, zones, customers and demand that lose supply
+// when the valves are closed, and the normally closed valves that could be
+// opened to feed them another way. A pipe has supply while either of its
+// ends is reachable from a source without passing a closed valve.
+func (n *Network) Analyze(closed []string) (*Impact, error) {
+	requested := make(map[string]bool, len(closed))
+	for _, id := range closed {
+		if n.Valves[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownValve, id)
+		}
+		requested[id] = true
+	}
+
+	normal := n.normallyClosed()
+	baseline := n.supplied(normal)
+	after := n.supplied(union(normal, requested))
+
+	impact := &Impact{
+		ClosedValves:       keys(requested),
+		PipesWithoutSupply: []string{},
+		Zones:              []ZoneImpact{},
+		ZonesWithoutSupply: []string{},
+		AlternativeRoutes:  []AlternativeRoute{},
+	}
+	lost := make(map[string]bool)
+	for _, id := range n.pipeIDs() {
+		if baseline[id] && !after[id] {
+			lost[id] = true
+			impact.PipesWithoutSupply = append(impact.PipesWithoutSupply, id)
+		}
+	}
+	if len(lost) == 0 {
+		return impact, nil
+	}
+
+	zones := make(map[string]*ZoneImpact)
+	suppliedBefore := make(map[string]int)
+	for _, id := range n.pipeIDs() {
+		pipe := n.Pipes[id]
+		zone := zones[pipe.Zone]
+		if zone == nil {
+			zone = &ZoneImpact{Zone: pipe.Zone}
+			zones[pipe.Zone] = zone
+		}
+		zone.PipesTotal++
+		if baseline[id] {
+			suppliedBefore[pipe.Zone]++
+		}
+		if lost[id] {
+			zone.PipesWithoutSupply++
+			zone.Customers += pipe.Customers
+			zone.DemandM3PerHour += pipe.DemandM3PerHour
+			impact.AffectedCustomers += pipe.Customers
+			impact.AffectedDemandM3PerHour += pipe.DemandM3PerHour
+		}
+	}
+	for _, name := range keys(zones) {
+		zone := zones[name]
+		if zone.PipesWithoutSupply == 0 {
+			continue
+		}
+		zone.FullOutage = zone.PipesWithoutSupply == suppliedBefore[name]
+		if zone.FullOutage {
+			impact.ZonesWithoutSupply = append(impact.ZonesWithoutSupply, name)
+		}
+		impact.Zones = append(impact.Zones, *zone)
+	}
+
+	for _, id := range keys(normal) {
+		if requested[id] {
+			continue
+		}
+		reopened := union(normal, requested)
+		delete(reopened, id)
+		restored := n.supplied(reopened)
+
+		route := AlternativeRoute{OpenValve: id, RestoredPipes: []string{}}
+		for _, pipeID := range impact.PipesWithoutSupply {
+			if restored[pipeID] {
+				route.RestoredPipes = append(route.RestoredPipes, pipeID)
+				route.RestoredCustomers += n.Pipes[pipeID].Customers
+				route.RestoredDemandM3PerHour += n.Pipes[pipeID].DemandM3PerHour
+			}
+		}
+		if len(route.RestoredPipes) > 0 {
+			impact.AlternativeRoutes = append(impact.AlternativeRoutes, route)
+		}
+	}
+	sort.SliceStable(impact.AlternativeRoutes, func(i, j int) bool {
+		return impact.AlternativeRoutes[i].RestoredDemandM3PerHour > impact.AlternativeRoutes[j].RestoredDemandM3PerHour
+	})
+
+	return impact, nil
+}
+
+// Isolations proposes valve sets that cut the pipe off from every source,
+// least affected demand first. The nearest valves around the pipe are tried
+// first, then sets reaching past each of them in turn, which is how a seized
+// or inoperable valve is worked around. Inoperable valves are never closed.
+func (n *Network) Isolations(pipeID string, inoperable []string) ([]*Impact, error) {
+	if n.Pipes[pipeID] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPipe, pipeID)
+	}
+	skip := make(map[string]bool, len(inoperable))
+	for _, id := range inoperable {
+		if n.Valves[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownValve, id)
+		}
+		skip[id] = true
+	}
+
+	seen := make(map[string]bool)
+	var candidates []*Impact
+	queue := []map[string]bool{skip}
+	for explored := 0; len(queue) > 0 && explored < maxIsolationSearch; explored++ {
+		current := queue[0]
+		queue = queue[1:]
+
+		valves, ok := n.boundary(pipeID, current)
+		if !ok {
+			continue
+		}
+		key := strings.Join(valves, ",")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		impact, err := n.Analyze(valves)
+		if err != nil {
+			return nil, err
+		}
+		if contains(impact.PipesWithoutSupply, pipeID) {
+			candidates = append(candidates, impact)
+		}
+		for _, valve := range valves {
+			next := union(current, map[string]bool{valve: true})
+			queue = append(queue, next)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotIsolatable, pipeID)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.AffectedDemandM3PerHour != b.AffectedDemandM3PerHour {
+			return a.AffectedDemandM3PerHour < b.AffectedDemandM3PerHour
+		}
+		if a.AffectedCustomers != b.AffectedCustomers {
+			return a.AffectedCustomers < b.AffectedCustomers
+		}
+		return len(a.ClosedValves) < len(b.ClosedValves)
+	})
+	if len(candidates) > n.config.MaxIsolationCandidates {
+		candidates = candidates[:n.config.MaxIsolationCandidates]
+	}
+	return candidates, nil
+}
+
+// boundary walks out from the pipe to the nearest operable valves in every
+// direction and returns them. Normally closed valves already bound the walk.
+// It fails when the walk reaches a source, which no valve set then keeps out.
+func (n *Network) boundary(pipeID string, skip map[string]bool) ([]string, bool) {
+	normal := n.normallyClosed()
+	valves := make(map[string]bool)
+	visited := map[string]bool{pipeID: true}
+	reached := make(map[string]bool)
+
+	pipe := n.Pipes[pipeID]
+	queue := []string{pipe.From, pipe.To}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if reached[node] {
+			continue
+		}
+		reached[node] = true
+
+		if stop, valve := n.stopAt(n.nodeValves[node], normal, skip); stop {
+			if valve != "" {
+				valves[valve] = true
+			}
+			continue
+		}
+		if n.sourceNodes[node] {
+			return nil, false
+		}
+
+		for _, next := range n.nodePipes[node] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if stop, valve := n.stopAt(n.pipeValves[next], normal, skip); stop {
+				if valve != "" {
+					valves[valve] = true
+				}
+				continue
+			}
+			queue = append(queue, n.Pipes[next].From, n.Pipes[next].To)
+		}
+	}
+	return keys(valves), true
+}
+
+// stopAt reports whether flow can be stopped by the valves, and the operable
+// valve to close when none of them is already normally closed
+func (n *Network) stopAt(valves []string, normal, skip map[string]bool) (bool, string) {
+	operable := ""
+	for _, id := range valves {
+		if normal[id] {
+			return true, ""
+		}
+		if !skip[id] && (operable == "" || id < operable) {
+			operable = id
+		}
+	}
+	return operable != "", operable
+}
+
+// supplied returns the pipes with an end reachable from a source while the
+// given valves are closed
+func (n *Network) supplied(closed map[string]bool) map[string]bool {
+	cut := make(map[string]bool)
+	blocked := make(map[string]bool)
+	for id := range closed {
+		if valve := n.Valves[id]; valve.PipeID != "" {
+			cut[valve.PipeID] = true
+		} else {
+			blocked[valve.NodeID] = true
+		}
+	}
+
+	reached := make(map[string]bool)
+	var queue []string
+	for _, source := range n.Sources {
+		if !blocked[source.NodeID] && !reached[source.NodeID] {
+			reached[source.NodeID] = true
+			queue = append(queue, source.NodeID)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, id := range n.nodePipes[node] {
+			if cut[id] {
+				continue
+			}
+			pipe := n.Pipes[id]
+			for _, next := range []string{pipe.From, pipe.To} {
+				if !blocked[next] && !reached[next] {
+					reached[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	supplied := make(map[string]bool)
+	for id, pipe := range n.Pipes {
+		if reached[pipe.From] || reached[pipe.To] {
+			supplied[id] = true
+		}
+	}
+	return supplied
+}
+
+// normallyClosed returns the valves closed in normal operation
+func (n *Network) normallyClosed() map[string]bool {
+	closed := make(map[string]bool)
+	for id, valve := range n.Valves {
+		if valve.NormallyClosed {
+			closed[id] = true
+		}
+	}
+	return closed
+}
+
+// union returns a new set holding the members of both sets
+func union(a, b map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		out[k] = true
+	}
+	for k := range b {
+		out[k] = true
+	}
+	return out
+}
+
+// keys returns the sorted keys of a map
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// contains reports whether the sorted slice holds the value
+func contains(sorted []string, value string) bool {
+	i := sort.SearchStrings(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
@@ -0,0 +1,177 @@
+package topology
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+)
+
+func testAgent(id, agentType string, metadata map[string]string) *agent.Agent {
+	return &agent.Agent{ID: id, Type: agentType, Metadata: metadata}
+}
+
+func testPipe(id, zone, from, to, length string) *agent.Agent {
+	return testAgent(id, TypePipe, map[string]string{"zone": zone, "from_node": from, "to_node": to, "length_m": length})
+}
+
+// testNetwork is a line fed from A, looped back to A through a normally
+// closed valve:
+//
+//	A -P1(V1)- B -P2(V2)- C -P3- D -P4(V4)- E -P5- F -P6(V6 closed)- A
+func testNetwork() *Network {
+	return Build([]*agent.Agent{
+		testAgent("PUMP-1", "pump", map[string]string{"node_id": "A"}),
+		testPipe("P1", "north", "A", "B", "100"),
+		testPipe("P2", "north", "B", "C", "100"),
+		testPipe("P3", "north", "C", "D", "200"),
+		testPipe("P4", "south", "D", "E", "100"),
+		testPipe("P5", "south", "E", "F", "100"),
+		testPipe("P6", "south", "F", "A", "100"),
+		testAgent("V1", TypeValve, map[string]string{"pipe_id": "P1"}),
+		testAgent("V2", TypeValve, map[string]string{"pipe_id": "P2"}),
+		testAgent("V4", TypeValve, map[string]string{"pipe_id": "P4"}),
+		testAgent("V6", TypeValve, map[string]string{"pipe_id": "P6", "position_percent": "0"}),
+		testAgent("COORD-N", TypeZoneCoordinator, map[string]string{"zone": "north", "population_served": "1000"}),
+		testAgent("COORD-S", TypeZoneCoordinator, map[string]string{"zone": "south", "population_served": "300"}),
+	}, Config{PerCapitaDemandLPD: 240})
+}
+
+func TestBuild_CoordinatesAndDemand(t *testing.T) {
+	n := Build([]*agent.Agent{
+		testAgent("P1", TypePipe, map[string]string{
+			"from_lat": "-1.2900", "from_lng": "36.8", "to_lat": "-1.28", "to_lng": "36.81",
+			"latitude": "-1.285", "longitude": "36.805", "length_m": "300",
+		}),
+		testAgent("P2", TypePipe, map[string]string{
+			"from_lat": "-1.28", "from_lng": "36.81", "to_lat": "-1.27", "to_lng": "36.82", "demand_m3_per_hour": "7",
+		}),
+		testAgent("V1", TypeValve, map[string]string{"latitude": "-1.285", "longitude": "36.805"}),
+		testAgent("V2", TypeValve, map[string]string{"latitude": "-1.28", "longitude": "36.81"}),
+		testAgent("V3", TypeValve, map[string]string{"latitude": "0", "longitude": "0"}),
+		testAgent("PUMP-1", "pump", map[string]string{"latitude": "-1.29", "longitude": "36.80"}),
+		testAgent("PUMP-2", "pump", map[string]string{}),
+	}, Config{})
+
+	if n.Pipes["P1"].To != n.Pipes["P2"].From {
+		t.Errorf("expected pipes sharing endpoint coordinates to share a node, got %q and %q", n.Pipes["P1"].To, n.Pipes["P2"].From)
+	}
+	if n.Valves["V1"].PipeID != "P1" {
+		t.Errorf("expected V1 on the pipe at its coordinates, got %+v", n.Valves["V1"])
+	}
+	if n.Valves["V2"].NodeID != n.Pipes["P2"].From {
+		t.Errorf("expected V2 at the junction at its coordinates, got %+v", n.Valves["V2"])
+	}
+	if len(n.Sources) != 1 || n.Sources[0].NodeID != n.Pipes["P1"].From {
+		t.Errorf("expected PUMP-1 at the start of P1, got %+v", n.Sources)
+	}
+	if !reflect.DeepEqual(n.Unresolved, []string{"PUMP-2", "V3"}) {
+		t.Errorf("expected PUMP-2 and V3 to be unresolved, got %v", n.Unresolved)
+	}
+	if n.Pipes["P2"].DemandM3PerHour != 7 {
+		t.Errorf("expected explicit demand to be kept, got %v", n.Pipes["P2"].DemandM3PerHour)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	n := testNetwork()
+
+	impact, err := n.Analyze([]string{"V2", "V4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(impact.PipesWithoutSupply, []string{"P3", "P4", "P5"}) {
+		t.Errorf("expected P3 to P5 to lose supply, got %v", impact.PipesWithoutSupply)
+	}
+
+	// North's 1000 people are spread by length, so P3 serves 500; south's
+	// 300 are spread over three pipes. 240 litres a day is 0.01 m3 an hour.
+	if math.Abs(impact.AffectedCustomers-700) > 1e-9 || math.Abs(impact.AffectedDemandM3PerHour-7) > 1e-9 {
+		t.Errorf("expected 700 customers and 7 m3/h affected, got %v and %v", impact.AffectedCustomers, impact.AffectedDemandM3PerHour)
+	}
+	if len(impact.Zones) != 2 || impact.Zones[0].Zone != "north" || impact.Zones[0].PipesWithoutSupply != 1 || impact.Zones[0].FullOutage {
+		t.Errorf("expected a partial outage in north, got %+v", impact.Zones)
+	}
+	// P6 is still fed from A, so south keeps partial supply
+	if len(impact.ZonesWithoutSupply) != 0 || impact.Zones[1].PipesWithoutSupply != 2 || impact.Zones[1].FullOutage {
+		t.Errorf("expected a partial outage in south, got %+v", impact.Zones)
+	}
+
+	if len(impact.AlternativeRoutes) != 1 {
+		t.Fatalf("expected one alternative route, got %+v", impact.AlternativeRoutes)
+	}
+	route := impact.AlternativeRoutes[0]
+	if route.OpenValve != "V6" || !reflect.DeepEqual(route.RestoredPipes, []string{"P4", "P5"}) {
+		t.Errorf("expected opening V6 to restore P4 and P5, got %+v", route)
+	}
+
+	if _, err := n.Analyze([]string{"V9"}); !errors.Is(err, ErrUnknownValve) {
+		t.Errorf("expected ErrUnknownValve, got %v", err)
+	}
+}
+
+func TestAnalyze_AlternativeRoute(t *testing.T) {
+	impact, err := testNetwork().Analyze([]string{"V4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impact.PipesWithoutSupply) != 1 || impact.PipesWithoutSupply[0] != "P5" {
+		t.Errorf("expected only P5 beyond V4 to lose supply, got %v", impact.PipesWithoutSupply)
+	}
+	if len(impact.AlternativeRoutes) != 1 || impact.AlternativeRoutes[0].OpenValve != "V6" {
+		t.Errorf("expected V6 to feed P5 from the other side, got %+v", impact.AlternativeRoutes)
+	}
+}
+
+func TestIsolations(t *testing.T) {
+	n := testNetwork()
+
+	isolations, err := n.Isolations("P3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(isolations) < 2 {
+		t.Fatalf("expected several isolation sets, got %d", len(isolations))
+	}
+	// Closing V2 alone isolates the same pipes as V2 and V4, since the loop
+	// beyond P4 is already closed by V6
+	if !reflect.DeepEqual(isolations[0].ClosedValves, []string{"V2"}) {
+		t.Errorf("expected V2 alone to rank first, got %v", isolations[0].ClosedValves)
+	}
+	for i := 1; i < len(isolations); i++ {
+		if isolations[i].AffectedDemandM3PerHour < isolations[i-1].AffectedDemandM3PerHour {
+			t.Errorf("expected isolations ordered by affected demand, got %v before %v",
+				isolations[i-1].AffectedDemandM3PerHour, isolations[i].AffectedDemandM3PerHour)
+		}
+	}
+
+	// A seized V2 is worked around through V1
+	isolations, err = n.Isolations("P3", []string{"V2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, isolation := range isolations {
+		for _, valve := range isolation.ClosedValves {
+			if valve == "V2" {
+				t.Errorf("expected the inoperable valve never to be closed, got %v", isolation.ClosedValves)
+			}
+		}
+	}
+	if !reflect.DeepEqual(isolations[0].ClosedValves, []string{"V1"}) {
+		t.Errorf("expected V1 to rank first, got %v", isolations[0].ClosedValves)
+	}
+}
+
+func TestIsolations_Errors(t *testing.T) {
+	n := testNetwork()
+
+	if _, err := n.Isolations("P9", nil); !errors.Is(err, ErrUnknownPipe) {
+		t.Errorf("expected ErrUnknownPipe, got %v", err)
+	}
+	// P1 touches the source and has no valve between them
+	if _, err := n.Isolations("P1", []string{"V1"}); !errors.Is(err, ErrNotIsolatable) {
+		t.Errorf("expected ErrNotIsolatable, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+// Package topology models the water distribution network formed by pipe,
+// valve and source agents, and analyses which parts of it lose supply when
+// valves are closed.
+package topology
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+)
+
+const (
+	defaultPerCapitaDemandLPD     = 100
+	defaultMaxIsolationCandidates = 5
+	defaultMaxTraversalDepth      = 50
+	defaultSyncInterval           = time.Minute
+)
+
+// Agent types that make up the network
+const (
+	TypePipe            = "pipe"
+	TypeValve           = "valve"
+	TypeZoneCoordinator = "zone_coordinator"
+)
+
+// Config configures how the network is built from agent metadata
+type Config struct {
+	// SourceTypes are the agent types that feed water into the network
+	SourceTypes []string
+
+	// PerCapitaDemandLPD estimates a pipe's demand from the people it serves
+	// when it has no demand_m3_per_hour metadata
+	PerCapitaDemandLPD float64
+
+	// MaxIsolationCandidates is the number of isolation sets proposed per pipe
+	MaxIsolationCandidates int
+
+	// MaxTraversalDepth bounds the connections followed by asset graph queries
+	MaxTraversalDepth int
+
+	// SyncInterval is how often the asset graph is synced from the network
+	SyncInterval time.Duration
+}
+
+// ConfigFromConfig converts application config into a Config
+func ConfigFromConfig(cfg config.TopologyConfig) Config {
+	return Config{
+		SourceTypes:            cfg.SourceTypes,
+		PerCapitaDemandLPD:     cfg.PerCapitaDemandLPD,
+		MaxIsolationCandidates: cfg.MaxIsolationCandidates,
+		MaxTraversalDepth:      cfg.MaxTraversalDepth,
+		SyncInterval:           time.Duration(cfg.SyncIntervalSeconds) * time.Second,
+	}
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if len(c.SourceTypes) == 0 {
+		c.SourceTypes = []string{"pump", "reservoir", "treatment_plant"}
+	}
+	if c.PerCapitaDemandLPD <= 0 {
+		c.PerCapitaDemandLPD = defaultPerCapitaDemandLPD
+	}
+	if c.MaxIsolationCandidates <= 0 {
+		c.MaxIsolationCandidates = defaultMaxIsolationCandidates
+	}
+	if c.MaxTraversalDepth <= 0 {
+		c.MaxTraversalDepth = defaultMaxTraversalDepth
+	}
+	if c.SyncInterval <= 0 {
+		c.SyncInterval = defaultSyncInterval
+	}
+	return c
+}
+
+// Pipe is a network edge between two junction nodes
+type Pipe struct {
+	ID              string  `json:"id"`
+	Zone            string  `json:"zone,omitempty"`
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	LengthM         float64 `json:"length_m"`
+	Customers       float64 `json:"customers"`
+	DemandM3PerHour float64 `json:"demand_m3_per_hour"`
+
+	latitude, longitude string
+}
+
+// Valve cuts flow through the pipe it sits on, or through the junction node
+// it sits at, when closed
+type Valve struct {
+	ID             string `json:"id"`
+	Zone           string `json:"zone,omitempty"`
+	PipeID         string `json:"pipe_id,omitempty"`
+	NodeID         string `json:"node_id,omitempty"`
+	NormallyClosed bool   `json:"normally_closed"`
+}
+
+// Source feeds water into the network at a junction node
+type Source struct {
+	ID                string  `json:"id"`
+	Type              string  `json:"type"`
+	NodeID            string  `json:"node_id"`
+	CapacityM3PerHour float64 `json:"capacity_m3_per_hour,omitempty"`
+}
+
+// Network is the pipe graph with the valves and sources placed on it
+type Network struct {
+	Pipes   map[string]*Pipe  `json:"pipes"`
+	Valves  map[string]*Valve `json:"valves"`
+	Sources []*Source         `json:"sources"`
+
+	// Unresolved lists valves and sources that could not be placed on the network
+	Unresolved []string `json:"unresolved,omitempty"`
+
+	config      Config
+	nodePipes   map[string][]string // node ID -> pipe IDs
+	pipeValves  map[string][]string // pipe ID -> valve IDs
+	nodeValves  map[string][]string // node ID -> valve IDs
+	sourceNodes map[string]bool
+}
+
+// Build builds the network from agent metadata. Pipes are joined through
+// their from_node and to_node metadata, or else through shared endpoint
+// coordinates (from_lat, from_lng, to_lat, to_lng). Valves are placed on the
+// pipe named by pipe_id, at node_id, or by matching their coordinates to a
+// pipe's midpoint or a junction. Sources are placed at node_id or the junction
+// at their coordinates.
+func Build(agents []*agent.Agent, cfg Config) *Network {
+	cfg = cfg.withDefaults()
+	n := &Network{
+		Pipes:       make(map[string]*Pipe),
+		Valves:      make(map[string]*Valve),
+		Sources:     []*Source{},
+		config:      cfg,
+		nodePipes:   make(map[string][]string),
+		pipeValves:  make(map[string][]string),
+		nodeValves:  make(map[string][]string),
+		sourceNodes: make(map[string]bool),
+	}
+
+	sourceTypes := make(map[string]bool, len(cfg.SourceTypes))
+	for _, t := range cfg.SourceTypes {
+		sourceTypes[t] = true
+	}
+
+	// Pipes come first so that valves and sources can be placed on them
+	population := make(map[string]float64)
+	var valves, sources []*agent.Agent
+	for _, a := range agents {
+		switch {
+		case a.Type == TypePipe:
+			n.addPipe(a)
+		case a.Type == TypeValve:
+			valves = append(valves, a)
+		case a.Type == TypeZoneCoordinator:
+			population[a.Metadata["zone"]] += number(a.Metadata, "population_served")
+		case sourceTypes[a.Type]:
+			sources = append(sources, a)
+		}
+	}
+
+	for _, a := range valves {
+		n.addValve(a)
+	}
+	for _, a := range sources {
+		n.addSource(a)
+	}
+	n.estimateDemand(population)
+
+	sort.Slice(n.Sources, func(i, j int) bool { return n.Sources[i].ID < n.Sources[j].ID })
+	sort.Strings(n.Unresolved)
+	return n
+}
+
+// addPipe adds a pipe whose endpoints can be resolved
+func (n *Network) addPipe(a *agent.Agent) {
+	from := nodeID(a.Metadata, "from_node", "from_lat", "from_lng")
+	to := nodeID(a.Metadata, "to_node", "to_lat", "to_lng")
+	if from == "" || to == "" {
+		n.Unresolved = append(n.Unresolved, a.ID)
+		return
+	}
+
+	pipe := &Pipe{
+		ID:              a.ID,
+		Zone:            a.Metadata["zone"],
+		From:            from,
+		To:              to,
+		LengthM:         number(a.Metadata, "length_m"),
+		Customers:       number(a.Metadata, "customers"),
+		DemandM3PerHour: number(a.Metadata, "demand_m3_per_hour"),
+		latitude:        coordinate(a.Metadata["latitude"]),
+		longitude:       coordinate(a.Metadata["longitude"]),
+	}
+	n.Pipes[pipe.ID] = pipe
+	n.nodePipes[from] = append(n.nodePipes[from], pipe.ID)
+	if to != from {
+		n.nodePipes[to] = append(n.nodePipes[to], pipe.ID)
+	}
+}
+
+// addValve places a valve on a pipe or at a junction
+func (n *Network) addValve(a *agent.Agent) {
+	valve := &Valve{
+		ID:             a.ID,
+		Zone:           a.Metadata["zone"],
+		NormallyClosed: a.Metadata["normal_position"] == "closed" || a.Metadata["position_percent"] == "0",
+	}
+
+	switch {
+	case n.Pipes[a.Metadata["pipe_id"]] != nil:
+		valve.PipeID = a.Metadata["pipe_id"]
+	case a.Metadata["node_id"] != "" && n.nodePipes[a.Metadata["node_id"]] != nil:
+		valve.NodeID = a.Metadata["node_id"]
+	default:
+		valve.PipeID = n.pipeAt(a.Metadata)
+		if valve.PipeID == "" {
+			if node := nodeID(a.Metadata, "", "latitude", "longitude"); n.nodePipes[node] != nil {
+				valve.NodeID = node
+			}
+		}
+	}
+	if valve.PipeID == "" && valve.NodeID == "" {
+		n.Unresolved = append(n.Unresolved, a.ID)
+		return
+	}
+
+	n.Valves[valve.ID] = valve
+	if valve.PipeID != "" {
+		n.pipeValves[valve.PipeID] = append(n.pipeValves[valve.PipeID], valve.ID)
+	} else {
+		n.nodeValves[valve.NodeID] = append(n.nodeValves[valve.NodeID], valve.ID)
+	}
+}
+
+// addSource places a source at a junction
+func (n *Network) addSource(a *agent.Agent) {
+	node := nodeID(a.Metadata, "node_id", "latitude", "longitude")
+	if n.nodePipes[node] == nil {
+		n.Unresolved = append(n.Unresolved, a.ID)
+		return
+	}
+
+	n.Sources = append(n.Sources, &Source{
+		ID:                a.ID,
+		Type:              a.Type,
+		NodeID:            node,
+		CapacityM3PerHour: number(a.Metadata, "capacity_m3_per_hour"),
+	})
+	n.sourceNodes[node] = true
+}
+
+// pipeAt returns the pipe whose midpoint is at the agent's coordinates
+func (n *Network) pipeAt(metadata map[string]string) string {
+	lat, lng := coordinate(metadata["latitude"]), coordinate(metadata["longitude"])
+	if lat == "" || lng == "" {
+		return ""
+	}
+	for _, id := range n.pipeIDs() {
+		if pipe := n.Pipes[id]; pipe.latitude == lat && pipe.longitude == lng {
+			return id
+		}
+	}
+	return ""
+}
+
+// estimateDemand spreads each zone's population over the zone's pipes without
+// customer metadata, by length, and derives demand from customers where unset
+func (n *Network) estimateDemand(population map[string]float64) {
+	type share struct {
+		pipes  []*Pipe
+		length float64
+		known  float64
+	}
+	zones := make(map[string]*share)
+	for _, id := range n.pipeIDs() {
+		pipe := n.Pipes[id]
+		z := zones[pipe.Zone]
+		if z == nil {
+			z = &share{}
+			zones[pipe.Zone] = z
+		}
+		if pipe.Customers > 0 {
+			z.known += pipe.Customers
+			continue
+		}
+		z.pipes = append(z.pipes, pipe)
+		z.length += pipe.LengthM
+	}
+
+	for zone, z := range zones {
+		remaining := population[zone] - z.known
+		if remaining <= 0 || len(z.pipes) == 0 {
+			continue
+		}
+		for _, pipe := range z.pipes {
+			if z.length > 0 {
+				pipe.Customers = remaining * pipe.LengthM / z.length
+			} else {
+				pipe.Customers = remaining / float64(len(z.pipes))
+			}
+		}
+	}
+
+	for _, pipe := range n.Pipes {
+		if pipe.DemandM3PerHour == 0 {
+			pipe.DemandM3PerHour = pipe.Customers * n.config.PerCapitaDemandLPD / 1000 / 24
+		}
+	}
+}
+
+// pipeIDs returns the pipe IDs in order
+func (n *Network) pipeIDs() []string {
+	ids := make([]string, 0, len(n.Pipes))
+	for id := range n.Pipes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// nodeID returns the explicit node ID or one derived from the coordinates
+func nodeID(metadata map[string]string, key, latKey, lngKey string) string {
+	if key != "" && metadata[key] != "" {
+		return metadata[key]
+	}
+	lat, lng := coordinate(metadata[latKey]), coordinate(metadata[lngKey])
+	if lat == "" || lng == "" {
+		return ""
+	}
+	return lat + "," + lng
+}
+
+// coordinate normalizes a coordinate so that "-1.29" and "-1.2900" match
+func coordinate(value string) string {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// number parses a numeric metadata value, returning 0 when unset or invalid
+func number(metadata map[string]string, key string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(metadata[key]), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,226 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+)
+
+// AgentSource lists running agents. runtime.Manager implements it.
+type AgentSource interface {
+	ListAgents() []*agent.Agent
+}
+
+// Service analyses valve isolations against the network formed by the
+// running agents. The network is rebuilt on every call so that it follows
+// agents being added, removed or reconfigured.
+//
+// The service also keeps the asset graph: assets and connections registered
+// explicitly, which agents and workflows query for the assets upstream and
+// downstream of an asset and the valves isolating it. Once started, the
+// service syncs the network's pipes, valves and sources into the asset graph.
+type Service struct {
+	agents AgentSource
+	graph  Graph
+	config Config
+
+	syncMu sync.Mutex
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService creates a new topology service. Its asset graph is kept in
+// memory until SetGraph is called.
+func NewService(agents AgentSource, cfg Config) *Service {
+	return &Service{
+		agents: agents,
+		graph:  NewInMemoryGraph(),
+		config: cfg.withDefaults(),
+	}
+}
+
+// SetGraph sets the store of the asset graph
+func (s *Service) SetGraph(graph Graph) {
+	if graph != nil {
+		s.graph = graph
+	}
+}
+
+// Network builds the network from the running agents
+func (s *Service) Network() *Network {
+	return Build(s.agents.ListAgents(), s.config)
+}
+
+// Analyze reports what loses supply when the valves are closed
+func (s *Service) Analyze(closedValves []string) (*Impact, error) {
+	return s.Network().Analyze(closedValves)
+}
+
+// Isolations proposes valve sets isolating the pipe, least impact first
+func (s *Service) Isolations(pipeID string, inoperableValves []string) ([]*Impact, error) {
+	return s.Network().Isolations(pipeID, inoperableValves)
+}
+
+// ValveBoundary is the nearest valves around an asset. Closing them isolates
+// the asset from the rest of the network.
+type ValveBoundary struct {
+	AssetID    string     `json:"asset_id"`
+	Upstream   []*Reached `json:"upstream"`
+	Downstream []*Reached `json:"downstream"`
+}
+
+// RegisterAsset adds an asset to the asset graph
+func (s *Service) RegisterAsset(ctx context.Context, asset *Asset) error {
+	if err := asset.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	asset.CreatedAt = now
+	asset.UpdatedAt = now
+	return s.graph.CreateAsset(ctx, asset)
+}
+
+// GetAsset retrieves a registered asset
+func (s *Service) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	return s.graph.GetAsset(ctx, id)
+}
+
+// ListAssets returns the registered assets matching the filter, by ID
+func (s *Service) ListAssets(ctx context.Context, filter AssetFilter) ([]*Asset, error) {
+	return s.graph.ListAssets(ctx, filter)
+}
+
+// UpdateAsset replaces the details of a registered asset. Its connections are
+// kept.
+func (s *Service) UpdateAsset(ctx context.Context, asset *Asset) (*Asset, error) {
+	if err := asset.Validate(); err != nil {
+		return nil, err
+	}
+	existing, err := s.graph.GetAsset(ctx, asset.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.CreatedAt = existing.CreatedAt
+	asset.UpdatedAt = time.Now().UTC()
+	if err := s.graph.UpdateAsset(ctx, asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// DeleteAsset removes an asset and its connections from the asset graph
+func (s *Service) DeleteAsset(ctx context.Context, id string) error {
+	return s.graph.DeleteAsset(ctx, id)
+}
+
+// Connect connects two registered assets. The kind defaults to flow, from
+// the upstream asset to the downstream one.
+func (s *Service) Connect(ctx context.Context, connection *Connection) error {
+	if connection.Kind == "" {
+		connection.Kind = ConnectionFlow
+	}
+	if connection.Kind != ConnectionFlow && connection.Kind != ConnectionMonitors {
+		return fmt.Errorf("%w: unknown connection kind %q", ErrInvalidAsset, connection.Kind)
+	}
+	if connection.From == connection.To {
+		return fmt.Errorf("%w: an asset cannot be connected to itself", ErrInvalidAsset)
+	}
+	for _, id := range []string{connection.From, connection.To} {
+		if _, err := s.graph.GetAsset(ctx, id); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAsset, err)
+		}
+	}
+
+	connection.ID = connectionID(connection.Kind, connection.From, connection.To)
+	connection.CreatedAt = time.Now().UTC()
+	return s.graph.CreateConnection(ctx, connection)
+}
+
+// Disconnect removes a connection
+func (s *Service) Disconnect(ctx context.Context, id string) error {
+	return s.graph.DeleteConnection(ctx, id)
+}
+
+// Connections returns the connections from and to a registered asset
+func (s *Service) Connections(ctx context.Context, assetID string) ([]*Connection, error) {
+	if _, err := s.graph.GetAsset(ctx, assetID); err != nil {
+		return nil, err
+	}
+	return s.graph.Connections(ctx, assetID)
+}
+
+// Upstream returns the assets feeding an asset, nearest first. A maxDepth of
+// zero or above the configured limit uses the limit, and types, when given,
+// select the assets returned.
+func (s *Service) Upstream(ctx context.Context, assetID string, maxDepth int, types ...string) ([]*Reached, error) {
+	return s.walk(ctx, Upstream, assetID, maxDepth, types)
+}
+
+// Downstream returns the assets fed by an asset, nearest first. A maxDepth of
+// zero or above the configured limit uses the limit, and types, when given,
+// select the assets returned.
+func (s *Service) Downstream(ctx context.Context, assetID string, maxDepth int, types ...string) ([]*Reached, error) {
+	return s.walk(ctx, Downstream, assetID, maxDepth, types)
+}
+
+// IsolationValves returns the first valves met on every path upstream and
+// downstream of an asset, such as the valves to close around PIPE-001
+func (s *Service) IsolationValves(ctx context.Context, assetID string) (*ValveBoundary, error) {
+	boundary := &ValveBoundary{AssetID: assetID}
+	for _, side := range []struct {
+		direction Direction
+		valves    *[]*Reached
+	}{
+		{Upstream, &boundary.Upstream},
+		{Downstream, &boundary.Downstream},
+	} {
+		reached, err := s.traverse(ctx, Traversal{
+			Start:     assetID,
+			Direction: side.direction,
+			MaxDepth:  s.config.MaxTraversalDepth,
+			StopAt:    []string{AssetValve},
+		})
+		if err != nil {
+			return nil, err
+		}
+		*side.valves = ofTypes(reached, []string{AssetValve})
+	}
+	return boundary, nil
+}
+
+func (s *Service) walk(ctx context.Context, direction Direction, assetID string, maxDepth int, types []string) ([]*Reached, error) {
+	if maxDepth <= 0 || maxDepth > s.config.MaxTraversalDepth {
+		maxDepth = s.config.MaxTraversalDepth
+	}
+	reached, err := s.traverse(ctx, Traversal{Start: assetID, Direction: direction, MaxDepth: maxDepth})
+	if err != nil {
+		return nil, err
+	}
+	return ofTypes(reached, types), nil
+}
+
+// traverse walks the asset graph from a registered asset
+func (s *Service) traverse(ctx context.Context, traversal Traversal) ([]*Reached, error) {
+	if _, err := s.graph.GetAsset(ctx, traversal.Start); err != nil {
+		return nil, err
+	}
+	return s.graph.Traverse(ctx, traversal)
+}
+
+// ofTypes keeps the reached assets of the given types; no types keeps all
+func ofTypes(reached []*Reached, types []string) []*Reached {
+	selected := []*Reached{}
+	for _, r := range reached {
+		if len(types) == 0 || slices.Contains(types, r.Asset.Type) {
+			selected = append(selected, r)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,274 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Assets and connections derived from the distribution network carry
+// PropertySyncedFrom = SyncedFromNetwork. Sync keeps them in step with the
+// network and leaves explicitly registered ones alone.
+const (
+	PropertySyncedFrom = "synced_from"
+	SyncedFromNetwork  = "network"
+)
+
+// SyncResult counts the changes a sync made to the asset graph
+type SyncResult struct {
+	AssetsCreated      int `json:"assets_created"`
+	AssetsUpdated      int `json:"assets_updated"`
+	AssetsRemoved      int `json:"assets_removed"`
+	ConnectionsCreated int `json:"connections_created"`
+	ConnectionsRemoved int `json:"connections_removed"`
+
+	// Unresolved lists valves and sources that could not be placed on the network
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// Start syncs the asset graph from the distribution network now and then
+// every SyncInterval, so traversals follow agents being added, removed or
+// reconfigured
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Stop halts periodic syncing and waits for a running sync to finish
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+}
+
+func (s *Service) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SyncNetwork(ctx); err != nil && ctx.Err() == nil {
+			log.WithError(err).Warn("Failed to sync the asset graph from the distribution network")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncNetwork registers the pipes, valves and sources of the distribution
+// network in the asset graph and connects them in the direction of flow.
+// Synced assets and connections no longer in the network are removed.
+// Assets registered explicitly under the same IDs are kept as they are but
+// still connected.
+func (s *Service) SyncNetwork(ctx context.Context) (*SyncResult, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	network := s.Network()
+	assets, connections := network.graph()
+	result := &SyncResult{Unresolved: network.Unresolved}
+
+	existing, err := s.graph.ListAssets(ctx, AssetFilter{})
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]*Asset, len(existing))
+	for _, asset := range existing {
+		registered[asset.ID] = asset
+	}
+
+	now := time.Now().UTC()
+	for _, asset := range assets {
+		current, exists := registered[asset.ID]
+		switch {
+		case !exists:
+			asset.CreatedAt = now
+			asset.UpdatedAt = now
+			if err := s.graph.CreateAsset(ctx, asset); err != nil {
+				return nil, err
+			}
+			result.AssetsCreated++
+		case synced(current.Properties) && !sameAsset(current, asset):
+			asset.CreatedAt = current.CreatedAt
+			asset.UpdatedAt = now
+			if err := s.graph.UpdateAsset(ctx, asset); err != nil {
+				return nil, err
+			}
+			result.AssetsUpdated++
+		}
+	}
+
+	wanted := make(map[string]bool, len(connections))
+	for _, connection := range connections {
+		wanted[connection.ID] = true
+		connection.CreatedAt = now
+		err := s.graph.CreateConnection(ctx, connection)
+		switch {
+		case err == nil:
+			result.ConnectionsCreated++
+		case !errors.Is(err, ErrConnectionExists):
+			return nil, err
+		}
+	}
+
+	// Removing an asset removes its connections, so only the connections of
+	// assets still in the network are checked
+	inNetwork := make(map[string]bool, len(assets))
+	for _, asset := range assets {
+		inNetwork[asset.ID] = true
+	}
+	for _, asset := range existing {
+		if inNetwork[asset.ID] || !synced(asset.Properties) {
+			continue
+		}
+		if err := s.graph.DeleteAsset(ctx, asset.ID); err != nil && !errors.Is(err, ErrAssetNotFound) {
+			return nil, err
+		}
+		result.AssetsRemoved++
+	}
+	for _, asset := range assets {
+		current, err := s.graph.Connections(ctx, asset.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, connection := range current {
+			if connection.From != asset.ID || wanted[connection.ID] || !synced(connection.Properties) {
+				continue
+			}
+			if err := s.graph.DeleteConnection(ctx, connection.ID); err != nil && !errors.Is(err, ErrConnectionNotFound) {
+				return nil, err
+			}
+			result.ConnectionsRemoved++
+		}
+	}
+
+	return result, nil
+}
+
+// graph returns the network as assets and flow connections. Water is taken
+// to flow along a pipe from its from node to its to node, through the valves
+// on the pipe first. At a junction the pipes and sources feeding it connect
+// to the pipes leaving it, through the valves at the junction if it has any.
+func (n *Network) graph() ([]*Asset, []*Connection) {
+	var assets []*Asset
+	var connections []*Connection
+	connect := func(from, to string) {
+		if from != to {
+			connections = append(connections, &Connection{
+				ID:         connectionID(ConnectionFlow, from, to),
+				From:       from,
+				To:         to,
+				Kind:       ConnectionFlow,
+				Properties: map[string]string{PropertySyncedFrom: SyncedFromNetwork},
+			})
+		}
+	}
+
+	// Each pipe is entered through the valves on it, in ID order
+	entry := make(map[string]string, len(n.Pipes))
+	feeders := make(map[string][]string) // node ID -> pipes and sources feeding it
+	leaving := make(map[string][]string) // node ID -> entries of the pipes leaving it
+	for _, id := range keys(n.Pipes) {
+		pipe := n.Pipes[id]
+		assets = append(assets, syncedAsset(pipe.ID, AssetPipe, pipe.Zone, map[string]string{
+			"from_node": pipe.From,
+			"to_node":   pipe.To,
+			"length_m":  strconv.FormatFloat(pipe.LengthM, 'f', -1, 64),
+		}))
+
+		entry[id] = id
+		valves := append([]string(nil), n.pipeValves[id]...)
+		sort.Strings(valves)
+		for i := len(valves) - 1; i >= 0; i-- {
+			connect(valves[i], entry[id])
+			entry[id] = valves[i]
+		}
+		feeders[pipe.To] = append(feeders[pipe.To], id)
+		leaving[pipe.From] = append(leaving[pipe.From], entry[id])
+	}
+
+	for _, id := range keys(n.Valves) {
+		valve := n.Valves[id]
+		properties := map[string]string{"normally_closed": strconv.FormatBool(valve.NormallyClosed)}
+		if valve.PipeID != "" {
+			properties["pipe_id"] = valve.PipeID
+		}
+		if valve.NodeID != "" {
+			properties["node_id"] = valve.NodeID
+		}
+		assets = append(assets, syncedAsset(valve.ID, AssetValve, valve.Zone, properties))
+	}
+
+	for _, source := range n.Sources {
+		properties := map[string]string{"node_id": source.NodeID}
+		if source.CapacityM3PerHour > 0 {
+			properties["capacity_m3_per_hour"] = strconv.FormatFloat(source.CapacityM3PerHour, 'f', -1, 64)
+		}
+		assets = append(assets, syncedAsset(source.ID, source.Type, "", properties))
+		feeders[source.NodeID] = append(feeders[source.NodeID], source.ID)
+	}
+
+	for _, node := range keys(feeders) {
+		valves := append([]string(nil), n.nodeValves[node]...)
+		sort.Strings(valves)
+		for _, from := range feeders[node] {
+			if len(valves) == 0 {
+				for _, to := range leaving[node] {
+					connect(from, to)
+				}
+				continue
+			}
+			for _, valve := range valves {
+				connect(from, valve)
+			}
+		}
+	}
+	for _, node := range keys(leaving) {
+		for _, valve := range n.nodeValves[node] {
+			for _, to := range leaving[node] {
+				connect(valve, to)
+			}
+		}
+	}
+
+	return assets, connections
+}
+
+func syncedAsset(id, assetType, zone string, properties map[string]string) *Asset {
+	properties[PropertySyncedFrom] = SyncedFromNetwork
+	return &Asset{ID: id, Type: assetType, Zone: zone, Properties: properties}
+}
+
+// synced reports whether an asset or connection was derived from the network
+func synced(properties map[string]string) bool {
+	return properties[PropertySyncedFrom] == SyncedFromNetwork
+}
+
+// sameAsset reports whether syncing would leave a registered asset unchanged
+func sameAsset(current, asset *Asset) bool {
+	return current.Type == asset.Type && current.Zone == asset.Zone && maps.Equal(current.Properties, asset.Properties)
+}
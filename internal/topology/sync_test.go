@@ -0,0 +1,82 @@
+package topology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type agentList []*agent.Agent
+
+func (l *agentList) ListAgents() []*agent.Agent {
+	return *l
+}
+
+// syncedNetwork is fed from A, with V1 on P1 and V3 at junction C:
+//
+//	PUMP-1 A -P1(V1)- B -P2- C(V3) -P4- E
+//	                  B -P3- D
+func syncedNetwork() *agentList {
+	return &agentList{
+		testAgent("PUMP-1", "pump", map[string]string{"node_id": "A"}),
+		testPipe("P1", "north", "A", "B", "100"),
+		testPipe("P2", "north", "B", "C", "100"),
+		testPipe("P3", "north", "B", "D", "50"),
+		testPipe("P4", "north", "C", "E", "100"),
+		testAgent("V1", TypeValve, map[string]string{"pipe_id": "P1"}),
+		testAgent("V3", TypeValve, map[string]string{"node_id": "C"}),
+	}
+}
+
+func TestService_SyncNetwork(t *testing.T) {
+	ctx := context.Background()
+	agents := syncedNetwork()
+	service := NewService(agents, Config{})
+	require.NoError(t, service.RegisterAsset(ctx, &Asset{ID: "SENSOR-001", Type: AssetSensor}))
+
+	result, err := service.SyncNetwork(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.AssetsCreated)
+	assert.Equal(t, 6, result.ConnectionsCreated)
+	require.NoError(t, service.Connect(ctx, &Connection{From: "SENSOR-001", To: "P2", Kind: ConnectionMonitors}))
+
+	downstream, err := service.Downstream(ctx, "PUMP-1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"V1", "P1", "P2", "P3", "V3", "P4"}, reachedIDs(downstream))
+
+	boundary, err := service.IsolationValves(ctx, "P2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"V1"}, reachedIDs(boundary.Upstream))
+	assert.Equal(t, []string{"V3"}, reachedIDs(boundary.Downstream))
+
+	// Syncing an unchanged network changes nothing
+	result, err = service.SyncNetwork(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &SyncResult{}, result)
+
+	// Removed pipes leave the graph, and moved pipes are reconnected
+	*agents = append((*agents)[:3], (*agents)[4:]...)
+	(*agents)[3] = testPipe("P4", "north", "B", "E", "100")
+	result, err = service.SyncNetwork(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.AssetsRemoved)
+	assert.Equal(t, 1, result.AssetsUpdated)
+	assert.Equal(t, 1, result.ConnectionsCreated)
+	assert.Equal(t, 1, result.ConnectionsRemoved)
+
+	downstream, err = service.Downstream(ctx, "P1", 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"P2", "P4", "V3"}, reachedIDs(downstream))
+	_, err = service.GetAsset(ctx, "P3")
+	assert.ErrorIs(t, err, ErrAssetNotFound)
+
+	// Explicitly registered assets and connections are kept
+	_, err = service.GetAsset(ctx, "SENSOR-001")
+	require.NoError(t, err)
+	connections, err := service.Connections(ctx, "SENSOR-001")
+	require.NoError(t, err)
+	assert.Len(t, connections, 1)
+}
@@ -0,0 +1,31 @@
+package usage
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes daily usage as CSV with one row per tenant and day and one
+// column per billable metric
+func WriteCSV(w io.Writer, usage []*DailyUsage) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{"date", "tenant_id"}, Metrics...)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, u := range usage {
+		row := []string{u.Date, u.TenantID}
+		for _, metric := range Metrics {
+			row = append(row, strconv.FormatFloat(u.Metrics[metric], 'f', -1, 64))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,104 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+)
+
+// charsPerToken approximates tokens for streamed responses, which report no usage
+const charsPerToken = 4
+
+// MeteredLLMClient records the tokens, latency and cost of each call made by
+// an LLM client, and refuses calls by tenants that have spent their monthly
+// LLM token budget
+type MeteredLLMClient struct {
+	ai.LLMClient
+	usage *Service
+}
+
+// NewMeteredLLMClient wraps an LLM client so its token usage is metered
+func NewMeteredLLMClient(client ai.LLMClient, usage *Service) *MeteredLLMClient {
+	return &MeteredLLMClient{
+		LLMClient: client,
+		usage:     usage,
+	}
+}
+
+// Chat sends messages and records the tokens reported by the provider
+func (c *MeteredLLMClient) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	if err := c.usage.CheckLLMBudget(ctx); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.LLMClient.Chat(ctx, req)
+	call := c.newCall(ctx, req, start, err)
+
+	if resp != nil {
+		if resp.Model != "" {
+			call.Model = resp.Model
+		}
+		if resp.Usage != nil {
+			call.PromptTokens = resp.Usage.PromptTokens
+			call.CompletionTokens = resp.Usage.CompletionTokens
+			call.TotalTokens = resp.Usage.TotalTokens
+		}
+	}
+	if err == nil && resp != nil && resp.Usage != nil {
+		c.usage.Record(ctx, MetricLLMTokens, float64(resp.Usage.TotalTokens))
+	}
+
+	c.usage.RecordLLMCall(ctx, call)
+	return resp, err
+}
+
+// ChatStream streams a response and records an estimate of the tokens used
+func (c *MeteredLLMClient) ChatStream(ctx context.Context, req *ai.ChatRequest, callback ai.StreamCallback) error {
+	if err := c.usage.CheckLLMBudget(ctx); err != nil {
+		return err
+	}
+
+	promptChars, completionChars := 0, 0
+	for _, msg := range req.Messages {
+		promptChars += len(msg.Content)
+	}
+
+	start := time.Now()
+	err := c.LLMClient.ChatStream(ctx, req, func(chunk string) error {
+		completionChars += len(chunk)
+		return callback(chunk)
+	})
+
+	call := c.newCall(ctx, req, start, err)
+	call.Stream = true
+	call.Estimated = true
+	call.PromptTokens = promptChars / charsPerToken
+	call.CompletionTokens = completionChars / charsPerToken
+	call.TotalTokens = (promptChars + completionChars) / charsPerToken
+
+	c.usage.Record(ctx, MetricLLMTokens, float64(call.TotalTokens))
+	c.usage.RecordLLMCall(ctx, call)
+	return err
+}
+
+// newCall starts the usage record of a call that began at start
+func (c *MeteredLLMClient) newCall(ctx context.Context, req *ai.ChatRequest, start time.Time, err error) *LLMCall {
+	model := req.Model
+	if model == "" {
+		model = c.GetModel()
+	}
+
+	call := &LLMCall{
+		Operation: ai.OperationFromContext(ctx),
+		RequestID: ai.RequestIDFromContext(ctx),
+		Provider:  string(c.GetProvider()),
+		Model:     model,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	return call
+}
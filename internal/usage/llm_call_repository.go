@@ -0,0 +1,302 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionLLMCalls is the LLM call collection name
+	CollectionLLMCalls = "llm_calls"
+)
+
+// ArangoLLMCallRepository persists LLM calls in ArangoDB
+type ArangoLLMCallRepository struct {
+	db         driver.Database
+	router     *database.QueryRouter
+	collection driver.Collection
+}
+
+// NewArangoLLMCallRepository creates a new ArangoDB-backed LLM call repository
+func NewArangoLLMCallRepository(dbClient *database.ArangoClient) (*ArangoLLMCallRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionLLMCalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionLLMCalls)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionLLMCalls, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionLLMCalls).Info("Created new collection")
+	}
+
+	indexes := map[string][]string{
+		"idx_llm_calls_tenant_created": {"tenant_id", "created_at"},
+		"idx_llm_calls_builder":        {"builder"},
+		"idx_llm_calls_request":        {"request_id"},
+	}
+	for name, fields := range indexes {
+		if _, _, err := col.EnsurePersistentIndex(ctx, fields, &driver.EnsurePersistentIndexOptions{Name: name}); err != nil {
+			return nil, fmt.Errorf("failed to create index %s: %w", name, err)
+		}
+	}
+
+	return &ArangoLLMCallRepository{
+		db:         db,
+		router:     dbClient.Router(),
+		collection: col,
+	}, nil
+}
+
+// RecordLLMCall stores an LLM call
+func (r *ArangoLLMCallRepository) RecordLLMCall(ctx context.Context, call *LLMCall) error {
+	if call.ID == "" {
+		call.ID = uuid.New().String()
+	}
+	if _, err := r.collection.CreateDocument(ctx, call); err != nil {
+		return fmt.Errorf("failed to record LLM call: %w", err)
+	}
+	return nil
+}
+
+// llmCallConditions builds the AQL filter of an LLM call filter
+func llmCallConditions(filter LLMCallFilter) (string, map[string]interface{}) {
+	var conditions []string
+	bindVars := map[string]interface{}{"@collection": CollectionLLMCalls}
+
+	add := func(condition, name string, value interface{}) {
+		conditions = append(conditions, condition)
+		bindVars[name] = value
+	}
+	if filter.TenantID != "" {
+		add("c.tenant_id == @tenantID", "tenantID", filter.TenantID)
+	}
+	if filter.Builder != "" {
+		add("c.builder == @builder", "builder", filter.Builder)
+	}
+	if filter.RequestID != "" {
+		add("c.request_id == @requestID", "requestID", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		add("DATE_TIMESTAMP(c.created_at) >= DATE_TIMESTAMP(@from)", "from", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		add("DATE_TIMESTAMP(c.created_at) < DATE_TIMESTAMP(@to)", "to", filter.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", bindVars
+	}
+	return "FILTER " + strings.Join(conditions, " AND "), bindVars
+}
+
+// ListLLMCalls returns the calls matching the filter, newest first
+func (r *ArangoLLMCallRepository) ListLLMCalls(ctx context.Context, filter LLMCallFilter) ([]*LLMCall, error) {
+	conditions, bindVars := llmCallConditions(filter)
+	limit := ""
+	if filter.Limit > 0 {
+		limit = "LIMIT @limit"
+		bindVars["limit"] = filter.Limit
+	}
+
+	query := fmt.Sprintf(`
+		FOR c IN @@collection
+			%s
+			SORT c.created_at DESC
+			%s
+			RETURN c
+	`, conditions, limit)
+
+	cursor, err := r.router.Query(database.WithQueryRoute(ctx, QueryRouteUsageReport), query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM calls: %w", err)
+	}
+	defer cursor.Close()
+
+	calls := []*LLMCall{}
+	for {
+		var call LLMCall
+		_, err := cursor.ReadDocument(ctx, &call)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LLM call: %w", err)
+		}
+		calls = append(calls, &call)
+	}
+	return calls, nil
+}
+
+// SumLLMCalls totals the calls matching the filter, grouped by one of the
+// Group fields and ordered by key
+func (r *ArangoLLMCallRepository) SumLLMCalls(ctx context.Context, filter LLMCallFilter, groupBy string) ([]*LLMUsageTotals, error) {
+	conditions, bindVars := llmCallConditions(filter)
+	bindVars["groupBy"] = groupBy
+
+	query := fmt.Sprintf(`
+		FOR c IN @@collection
+			%s
+			COLLECT key = (@groupBy == "" ? "" : c[@groupBy])
+			AGGREGATE
+				calls = LENGTH(1),
+				errors = SUM(c.error ? 1 : 0),
+				prompt = SUM(c.prompt_tokens),
+				completion = SUM(c.completion_tokens),
+				total = SUM(c.total_tokens),
+				cost = SUM(c.cost_usd),
+				latency = AVERAGE(c.latency_ms)
+			SORT key ASC
+			RETURN {
+				key: key,
+				calls: calls,
+				errors: errors,
+				prompt_tokens: prompt,
+				completion_tokens: completion,
+				total_tokens: total,
+				cost_usd: cost,
+				avg_latency_ms: latency
+			}
+	`, conditions)
+
+	cursor, err := r.router.Query(database.WithQueryRoute(ctx, QueryRouteUsageReport), query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum LLM calls: %w", err)
+	}
+	defer cursor.Close()
+
+	totals := []*LLMUsageTotals{}
+	for {
+		var t LLMUsageTotals
+		_, err := cursor.ReadDocument(ctx, &t)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LLM call totals: %w", err)
+		}
+		totals = append(totals, &t)
+	}
+	return totals, nil
+}
+
+// InMemoryLLMCallRepository keeps LLM calls in memory. Budgets are checked
+// against the calls made since the process started.
+type InMemoryLLMCallRepository struct {
+	mu    sync.RWMutex
+	calls []*LLMCall
+}
+
+// NewInMemoryLLMCallRepository creates a new in-memory LLM call repository
+func NewInMemoryLLMCallRepository() *InMemoryLLMCallRepository {
+	return &InMemoryLLMCallRepository{}
+}
+
+// RecordLLMCall stores an LLM call
+func (r *InMemoryLLMCallRepository) RecordLLMCall(ctx context.Context, call *LLMCall) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if call.ID == "" {
+		call.ID = uuid.New().String()
+	}
+	copied := *call
+	r.calls = append(r.calls, &copied)
+	return nil
+}
+
+// matching returns copies of the calls matching the filter, newest first. Callers hold the lock.
+func (r *InMemoryLLMCallRepository) matching(filter LLMCallFilter) []*LLMCall {
+	calls := []*LLMCall{}
+	for _, call := range r.calls {
+		if (filter.TenantID != "" && call.TenantID != filter.TenantID) ||
+			(filter.Builder != "" && call.Builder != filter.Builder) ||
+			(filter.RequestID != "" && call.RequestID != filter.RequestID) ||
+			(!filter.From.IsZero() && call.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !call.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		copied := *call
+		calls = append(calls, &copied)
+	}
+
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].CreatedAt.After(calls[j].CreatedAt)
+	})
+	return calls
+}
+
+// ListLLMCalls returns the calls matching the filter, newest first
+func (r *InMemoryLLMCallRepository) ListLLMCalls(ctx context.Context, filter LLMCallFilter) ([]*LLMCall, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	calls := r.matching(filter)
+	if filter.Limit > 0 && len(calls) > filter.Limit {
+		calls = calls[:filter.Limit]
+	}
+	return calls, nil
+}
+
+// SumLLMCalls totals the calls matching the filter, grouped by one of the
+// Group fields and ordered by key
+func (r *InMemoryLLMCallRepository) SumLLMCalls(ctx context.Context, filter LLMCallFilter, groupBy string) ([]*LLMUsageTotals, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byKey := make(map[string]*LLMUsageTotals)
+	latency := make(map[string]int64)
+	for _, call := range r.matching(filter) {
+		var key string
+		switch groupBy {
+		case GroupTenant:
+			key = call.TenantID
+		case GroupBuilder:
+			key = call.Builder
+		}
+
+		t, ok := byKey[key]
+		if !ok {
+			t = &LLMUsageTotals{Key: key}
+			byKey[key] = t
+		}
+		t.Calls++
+		if call.Error != "" {
+			t.Errors++
+		}
+		t.PromptTokens += call.PromptTokens
+		t.CompletionTokens += call.CompletionTokens
+		t.TotalTokens += call.TotalTokens
+		t.CostUSD += call.CostUSD
+		latency[key] += call.LatencyMs
+	}
+
+	totals := []*LLMUsageTotals{}
+	for key, t := range byKey {
+		t.AvgLatencyMs = float64(latency[key]) / float64(t.Calls)
+		totals = append(totals, t)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Key < totals[j].Key
+	})
+	return totals, nil
+}
@@ -0,0 +1,235 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultLLMCallLimit is the number of recent calls listed in an LLM usage report
+const defaultLLMCallLimit = 50
+
+// UnnamedOperation attributes LLM calls made without an AI operation
+const UnnamedOperation = "unnamed"
+
+// ErrLLMBudgetExceeded is returned for LLM calls by a tenant that has spent
+// its monthly token budget
+var ErrLLMBudgetExceeded = errors.New("monthly LLM token budget exceeded")
+
+// LLMBudgetError describes the budget a tenant has exceeded
+type LLMBudgetError struct {
+	TenantID string
+	Used     float64
+	Budget   float64
+	ResetsAt time.Time
+}
+
+func (e *LLMBudgetError) Error() string {
+	return fmt.Sprintf("agency %s has used %.0f of its %.0f monthly LLM tokens; the budget resets at %s",
+		e.TenantID, e.Used, e.Budget, e.ResetsAt.Format(time.RFC3339))
+}
+
+// Unwrap lets callers match the error with errors.Is(err, ErrLLMBudgetExceeded)
+func (e *LLMBudgetError) Unwrap() error {
+	return ErrLLMBudgetExceeded
+}
+
+// LLMPrice is the price of a model's tokens in US dollars
+type LLMPrice struct {
+	PromptPer1K     float64
+	CompletionPer1K float64
+}
+
+// LLMCall is the usage record of a single LLM call
+type LLMCall struct {
+	ID               string    `json:"_key,omitempty"`
+	TenantID         string    `json:"tenant_id"`
+	Operation        string    `json:"operation"`
+	Builder          string    `json:"builder"`
+	RequestID        string    `json:"request_id,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Stream           bool      `json:"stream"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Estimated        bool      `json:"estimated"` // Tokens were estimated from text length
+	LatencyMs        int64     `json:"latency_ms"`
+	CostUSD          float64   `json:"cost_usd"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// LLMCallFilter selects LLM calls. Empty fields match every call; calls are
+// matched on From <= CreatedAt < To.
+type LLMCallFilter struct {
+	TenantID  string
+	Builder   string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+// LLMUsageTotals sums the LLM calls of a tenant, builder or request
+type LLMUsageTotals struct {
+	Key              string  `json:"key,omitempty"`
+	Calls            int     `json:"calls"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// LLM call groupings of SumLLMCalls
+const (
+	GroupAll     = ""
+	GroupTenant  = "tenant_id"
+	GroupBuilder = "builder"
+)
+
+// LLMCallRepository stores individual LLM calls
+type LLMCallRepository interface {
+	// RecordLLMCall stores an LLM call
+	RecordLLMCall(ctx context.Context, call *LLMCall) error
+
+	// ListLLMCalls returns the calls matching the filter, newest first
+	ListLLMCalls(ctx context.Context, filter LLMCallFilter) ([]*LLMCall, error)
+
+	// SumLLMCalls totals the calls matching the filter, grouped by one of the
+	// Group fields and ordered by key
+	SumLLMCalls(ctx context.Context, filter LLMCallFilter, groupBy string) ([]*LLMUsageTotals, error)
+}
+
+// LLMUsageReport is the LLM usage of a period
+type LLMUsageReport struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Totals    *LLMUsageTotals   `json:"totals"`
+	ByAgency  []*LLMUsageTotals `json:"by_agency"`
+	ByBuilder []*LLMUsageTotals `json:"by_builder"`
+	Calls     []*LLMCall        `json:"calls"`
+}
+
+// Builder returns the builder an AI operation belongs to, e.g. "goals" for
+// "goals.refine"
+func Builder(operation string) string {
+	if operation == "" {
+		return UnnamedOperation
+	}
+	builder, _, _ := strings.Cut(operation, ".")
+	return builder
+}
+
+// LLMCost estimates the cost of a call from the configured model prices
+func (c Config) LLMCost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := c.LLMPricing[model]
+	if !ok {
+		return 0
+	}
+	return float64(promptTokens)/1000*price.PromptPer1K + float64(completionTokens)/1000*price.CompletionPer1K
+}
+
+// RecordLLMCall stores an LLM call for the tenant in the context. Calls are
+// kept even when the request that made them was cancelled.
+func (s *Service) RecordLLMCall(ctx context.Context, call *LLMCall) {
+	if s.calls == nil {
+		return
+	}
+
+	if call.TenantID == "" {
+		tenantID, ok := TenantFromContext(ctx)
+		if !ok {
+			tenantID = UnattributedTenant
+		}
+		call.TenantID = tenantID
+	}
+	if call.Builder == "" {
+		call.Builder = Builder(call.Operation)
+	}
+	if call.CreatedAt.IsZero() {
+		call.CreatedAt = time.Now().UTC()
+	}
+	call.CostUSD = s.config.LLMCost(call.Model, call.PromptTokens, call.CompletionTokens)
+
+	if err := s.calls.RecordLLMCall(context.WithoutCancel(ctx), call); err != nil {
+		s.logger.WithError(err).WithField("operation", call.Operation).Warn("Failed to record LLM call")
+	}
+}
+
+// CheckLLMBudget returns an LLMBudgetError when the tenant in the context has
+// spent its monthly LLM token budget. Failing to read the tenant's usage does
+// not block LLM calls.
+func (s *Service) CheckLLMBudget(ctx context.Context) error {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok || s.calls == nil {
+		return nil
+	}
+	budget, ok := s.config.LLMBudget(tenantID)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	resetsAt := monthStart.AddDate(0, 1, 0)
+
+	totals, err := s.calls.SumLLMCalls(ctx, LLMCallFilter{TenantID: tenantID, From: monthStart, To: resetsAt}, GroupAll)
+	if err != nil {
+		s.logger.WithError(err).WithField("tenant_id", tenantID).Warn("Failed to check LLM budget")
+		return nil
+	}
+
+	var used float64
+	for _, t := range totals {
+		used += float64(t.TotalTokens)
+	}
+	if used < budget {
+		return nil
+	}
+	return &LLMBudgetError{TenantID: tenantID, Used: used, Budget: budget, ResetsAt: resetsAt}
+}
+
+// LLMUsage reports LLM usage matching the filter: overall totals, totals per
+// agency and builder, and the most recent calls
+func (s *Service) LLMUsage(ctx context.Context, filter LLMCallFilter) (*LLMUsageReport, error) {
+	report := &LLMUsageReport{
+		From:      filter.From,
+		To:        filter.To,
+		Totals:    &LLMUsageTotals{},
+		ByAgency:  []*LLMUsageTotals{},
+		ByBuilder: []*LLMUsageTotals{},
+		Calls:     []*LLMCall{},
+	}
+	if s.calls == nil {
+		return report, nil
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLLMCallLimit
+	}
+
+	totals, err := s.calls.SumLLMCalls(ctx, filter, GroupAll)
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) > 0 {
+		report.Totals = totals[0]
+		report.Totals.Key = ""
+	}
+
+	if report.ByAgency, err = s.calls.SumLLMCalls(ctx, filter, GroupTenant); err != nil {
+		return nil, err
+	}
+	if report.ByBuilder, err = s.calls.SumLLMCalls(ctx, filter, GroupBuilder); err != nil {
+		return nil, err
+	}
+	if report.Calls, err = s.calls.ListLLMCalls(ctx, filter); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,28 @@
+package usage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LLMBudgetMiddleware refuses AI requests with 429 Too Many Requests once the
+// request's tenant has spent its monthly LLM token budget, before any LLM
+// call is made. It must run after the tenant middleware.
+func LLMBudgetMiddleware(service *Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var budgetErr *LLMBudgetError
+		if err := service.CheckLLMBudget(c.Request.Context()); errors.As(err, &budgetErr) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":     budgetErr.Error(),
+				"tenant_id": budgetErr.TenantID,
+				"used":      budgetErr.Used,
+				"budget":    budgetErr.Budget,
+				"resets_at": budgetErr.ResetsAt,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,228 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CollectionDailyUsage is the daily usage collection name
+	CollectionDailyUsage = "tenant_daily_usage"
+)
+
+// ArangoRepository persists daily usage in ArangoDB
+type ArangoRepository struct {
+	db         driver.Database
+	router     *database.QueryRouter
+	collection driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed usage repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionDailyUsage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionDailyUsage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionDailyUsage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionDailyUsage).Info("Created new collection")
+	}
+
+	_, _, err = col.EnsurePersistentIndex(ctx, []string{"tenant_id", "date"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_daily_usage_tenant_date",
+		Unique: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoRepository{
+		db:         db,
+		router:     dbClient.Router(),
+		collection: col,
+	}, nil
+}
+
+// AddUsage adds counter amounts to a tenant's daily totals
+func (r *ArangoRepository) AddUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	query := `
+		UPSERT { tenant_id: @tenantID, date: @date }
+			INSERT { tenant_id: @tenantID, date: @date, metrics: @metrics, updated_at: @now }
+			UPDATE {
+				metrics: MERGE(OLD.metrics, MERGE(
+					FOR k IN ATTRIBUTES(@metrics) RETURN { [k]: (OLD.metrics[k] || 0) + @metrics[k] }
+				)),
+				updated_at: @now
+			}
+			IN @@collection
+	`
+	return r.exec(ctx, query, tenantID, date, metrics)
+}
+
+// SetUsage replaces gauge values in a tenant's daily totals
+func (r *ArangoRepository) SetUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	query := `
+		UPSERT { tenant_id: @tenantID, date: @date }
+			INSERT { tenant_id: @tenantID, date: @date, metrics: @metrics, updated_at: @now }
+			UPDATE { metrics: MERGE(OLD.metrics, @metrics), updated_at: @now }
+			IN @@collection
+	`
+	return r.exec(ctx, query, tenantID, date, metrics)
+}
+
+func (r *ArangoRepository) exec(ctx context.Context, query, tenantID, date string, metrics map[string]float64) error {
+	bindVars := map[string]interface{}{
+		"@collection": CollectionDailyUsage,
+		"tenantID":    tenantID,
+		"date":        date,
+		"metrics":     metrics,
+		"now":         time.Now(),
+	}
+
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return fmt.Errorf("failed to update daily usage: %w", err)
+	}
+	return cursor.Close()
+}
+
+// ListUsage returns daily totals in [from, to], ordered by date then tenant
+func (r *ArangoRepository) ListUsage(ctx context.Context, tenantID, from, to string) ([]*DailyUsage, error) {
+	query := `
+		FOR u IN @@collection
+			FILTER (@tenantID == "" OR u.tenant_id == @tenantID) AND u.date >= @from AND u.date <= @to
+			SORT u.date ASC, u.tenant_id ASC
+			RETURN u
+	`
+	bindVars := map[string]interface{}{
+		"@collection": CollectionDailyUsage,
+		"tenantID":    tenantID,
+		"from":        from,
+		"to":          to,
+	}
+
+	cursor, err := r.router.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily usage: %w", err)
+	}
+	defer cursor.Close()
+
+	var usage []*DailyUsage
+	for {
+		var u DailyUsage
+		_, err := cursor.ReadDocument(ctx, &u)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read daily usage: %w", err)
+		}
+		usage = append(usage, &u)
+	}
+
+	return usage, nil
+}
+
+// InMemoryRepository keeps daily usage totals in memory, keyed by tenant and
+// date. Totals not yet exported are lost on restart.
+type InMemoryRepository struct {
+	mu    sync.RWMutex
+	usage map[string]*DailyUsage
+}
+
+// NewInMemoryRepository creates a new in-memory usage repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		usage: make(map[string]*DailyUsage),
+	}
+}
+
+// AddUsage adds counter amounts to a tenant's daily totals
+func (r *InMemoryRepository) AddUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.entry(tenantID, date)
+	for metric, amount := range metrics {
+		u.Metrics[metric] += amount
+	}
+	return nil
+}
+
+// SetUsage replaces gauge values in a tenant's daily totals
+func (r *InMemoryRepository) SetUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := r.entry(tenantID, date)
+	for metric, value := range metrics {
+		u.Metrics[metric] = value
+	}
+	return nil
+}
+
+// entry returns the daily usage for a tenant, creating it if needed. Callers hold the lock.
+func (r *InMemoryRepository) entry(tenantID, date string) *DailyUsage {
+	key := tenantID + "|" + date
+	u, ok := r.usage[key]
+	if !ok {
+		u = &DailyUsage{TenantID: tenantID, Date: date, Metrics: make(map[string]float64)}
+		r.usage[key] = u
+	}
+	u.UpdatedAt = time.Now()
+	return u
+}
+
+// ListUsage returns daily totals in [from, to], ordered by date then tenant
+func (r *InMemoryRepository) ListUsage(ctx context.Context, tenantID, from, to string) ([]*DailyUsage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var usage []*DailyUsage
+	for _, u := range r.usage {
+		if (tenantID == "" || u.TenantID == tenantID) && u.Date >= from && u.Date <= to {
+			copied := *u
+			copied.Metrics = make(map[string]float64, len(u.Metrics))
+			for metric, value := range u.Metrics {
+				copied.Metrics[metric] = value
+			}
+			usage = append(usage, &copied)
+		}
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Date != usage[j].Date {
+			return usage[i].Date < usage[j].Date
+		}
+		return usage[i].TenantID < usage[j].TenantID
+	})
+	return usage, nil
+}
@@ -0,0 +1,417 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// LimitEventName identifies soft limit webhook notifications
+	LimitEventName = "usage.soft_limit_exceeded"
+
+	// QueryRouteUsageReport is the query route of usage reports and exports, which may be served by a read replica
+	QueryRouteUsageReport = "usage.report"
+
+	defaultAggregateInterval = 5 * time.Minute
+	webhookTimeout           = 10 * time.Second
+)
+
+// StorageSource reports the storage currently used by each tenant
+type StorageSource interface {
+	TenantStorageBytes(ctx context.Context) (map[string]int64, error)
+}
+
+// TaskSource reports the workflow task-minutes each tenant used in [start, end)
+type TaskSource interface {
+	TenantTaskMinutes(ctx context.Context, start, end time.Time) (map[string]float64, error)
+}
+
+// Config configures usage metering
+type Config struct {
+	AggregateInterval time.Duration
+	SoftLimits        map[string]float64
+	TenantLimits      map[string]map[string]float64
+	LimitWebhooks     []string
+
+	// LLMMonthlyTokenBudget is the LLM tokens a tenant may use per calendar
+	// month; 0 means unlimited. LLMTenantBudgets overrides it per tenant.
+	LLMMonthlyTokenBudget float64
+	LLMTenantBudgets      map[string]float64
+	LLMPricing            map[string]LLMPrice
+}
+
+// ConfigFromConfig converts application config into a usage Config
+func ConfigFromConfig(cfg config.UsageConfig) Config {
+	pricing := make(map[string]LLMPrice, len(cfg.LLMPricing))
+	for model, price := range cfg.LLMPricing {
+		pricing[model] = LLMPrice{PromptPer1K: price.PromptPer1K, CompletionPer1K: price.CompletionPer1K}
+	}
+
+	return Config{
+		AggregateInterval: time.Duration(cfg.AggregateIntervalSeconds) * time.Second,
+		SoftLimits:        cfg.SoftLimits,
+		TenantLimits:      cfg.TenantLimits,
+		LimitWebhooks:     cfg.LimitWebhooks,
+
+		LLMMonthlyTokenBudget: cfg.LLMMonthlyTokenBudget,
+		LLMTenantBudgets:      cfg.LLMTenantBudgets,
+		LLMPricing:            pricing,
+	}
+}
+
+// Limit returns the daily soft limit of a metric for a tenant, if any
+func (c Config) Limit(tenantID, metric string) (float64, bool) {
+	if limits, ok := c.TenantLimits[tenantID]; ok {
+		if limit, ok := limits[metric]; ok {
+			return limit, true
+		}
+	}
+	limit, ok := c.SoftLimits[metric]
+	return limit, ok
+}
+
+// LLMBudget returns the monthly LLM token budget of a tenant, if it has one
+func (c Config) LLMBudget(tenantID string) (float64, bool) {
+	if budget, ok := c.LLMTenantBudgets[tenantID]; ok {
+		return budget, budget > 0
+	}
+	return c.LLMMonthlyTokenBudget, c.LLMMonthlyTokenBudget > 0
+}
+
+// LimitEvent is sent to the limit webhooks when a tenant crosses a soft limit
+type LimitEvent struct {
+	Event     string    `json:"event"`
+	TenantID  string    `json:"tenant_id"`
+	Metric    string    `json:"metric"`
+	Limit     float64   `json:"limit"`
+	Value     float64   `json:"value"`
+	Date      string    `json:"date"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// dayKey identifies a tenant's usage on one day
+type dayKey struct {
+	tenantID string
+	date     string
+}
+
+// Service meters usage and maintains daily totals
+type Service struct {
+	repo    Repository
+	storage StorageSource
+	tasks   TaskSource
+	calls   LLMCallRepository
+	config  Config
+	client  *http.Client
+	logger  *log.Logger
+
+	pendingMu sync.Mutex
+	pending   map[dayKey]map[string]float64
+	fired     map[string]bool
+	firedDate string
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewService creates a new usage service
+func NewService(repo Repository, cfg Config, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New()
+	}
+	if cfg.AggregateInterval <= 0 {
+		cfg.AggregateInterval = defaultAggregateInterval
+	}
+
+	return &Service{
+		repo:    repo,
+		config:  cfg,
+		client:  &http.Client{Timeout: webhookTimeout},
+		logger:  logger,
+		pending: make(map[dayKey]map[string]float64),
+		fired:   make(map[string]bool),
+	}
+}
+
+// SetStorageSource sets the source sampled for storage bytes
+func (s *Service) SetStorageSource(source StorageSource) {
+	s.storage = source
+}
+
+// SetTaskSource sets the source of workflow task-minutes
+func (s *Service) SetTaskSource(source TaskSource) {
+	s.tasks = source
+}
+
+// SetLLMCallRepository sets where individual LLM calls are recorded. LLM
+// budgets are only enforced when it is set.
+func (s *Service) SetLLMCallRepository(repo LLMCallRepository) {
+	s.calls = repo
+}
+
+// Record adds a counter amount for the tenant in the context
+func (s *Service) Record(ctx context.Context, metric string, amount float64) {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		tenantID = UnattributedTenant
+	}
+	s.RecordTenant(tenantID, metric, amount)
+}
+
+// RecordTenant adds a counter amount for a tenant. Amounts are buffered and
+// written to the daily totals on the next flush.
+func (s *Service) RecordTenant(tenantID, metric string, amount float64) {
+	s.RecordTenantAt(tenantID, metric, amount, time.Now())
+}
+
+// RecordTenantAt adds a counter amount for a tenant to the totals of the
+// day of at, such as when backfilling historical usage
+func (s *Service) RecordTenantAt(tenantID, metric string, amount float64, at time.Time) {
+	if amount == 0 {
+		return
+	}
+
+	key := dayKey{tenantID: tenantID, date: Day(at)}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	metrics, ok := s.pending[key]
+	if !ok {
+		metrics = make(map[string]float64)
+		s.pending[key] = metrics
+	}
+	metrics[metric] += amount
+}
+
+// Flush writes buffered counters to the daily totals. Counters that fail to
+// write are kept for the next flush.
+func (s *Service) Flush(ctx context.Context) error {
+	s.pendingMu.Lock()
+	pending := s.pending
+	s.pending = make(map[dayKey]map[string]float64)
+	s.pendingMu.Unlock()
+
+	var firstErr error
+	for key, metrics := range pending {
+		if err := s.repo.AddUsage(ctx, key.tenantID, key.date, metrics); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			s.pendingMu.Lock()
+			for metric, amount := range metrics {
+				if s.pending[key] == nil {
+					s.pending[key] = make(map[string]float64)
+				}
+				s.pending[key][metric] += amount
+			}
+			s.pendingMu.Unlock()
+		}
+	}
+
+	return firstErr
+}
+
+// Aggregate flushes counters, samples storage and workflow task-minutes into
+// today's totals (and finalizes yesterday's task-minutes), then checks soft limits
+func (s *Service) Aggregate(ctx context.Context) error {
+	if err := s.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush usage counters: %w", err)
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if s.storage != nil {
+		storage, err := s.storage.TenantStorageBytes(ctx)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to sample tenant storage")
+		}
+		for tenantID, used := range storage {
+			if err := s.repo.SetUsage(ctx, tenantID, Day(today), map[string]float64{MetricStorageBytes: float64(used)}); err != nil {
+				return fmt.Errorf("failed to record storage usage: %w", err)
+			}
+		}
+	}
+
+	if s.tasks != nil {
+		for _, start := range []time.Time{today.AddDate(0, 0, -1), today} {
+			minutes, err := s.tasks.TenantTaskMinutes(ctx, start, start.AddDate(0, 0, 1))
+			if err != nil {
+				s.logger.WithError(err).Warn("Failed to compute workflow task-minutes")
+				break
+			}
+			for tenantID, value := range minutes {
+				if err := s.repo.SetUsage(ctx, tenantID, Day(start), map[string]float64{MetricWorkflowTaskMinutes: value}); err != nil {
+					return fmt.Errorf("failed to record workflow usage: %w", err)
+				}
+			}
+		}
+	}
+
+	return s.checkLimits(ctx, Day(today))
+}
+
+// checkLimits fires a limit event the first time a tenant's total crosses a soft limit on a day
+func (s *Service) checkLimits(ctx context.Context, date string) error {
+	if len(s.config.SoftLimits) == 0 && len(s.config.TenantLimits) == 0 {
+		return nil
+	}
+
+	usage, err := s.repo.ListUsage(ctx, "", date, date)
+	if err != nil {
+		return fmt.Errorf("failed to list usage: %w", err)
+	}
+
+	s.pendingMu.Lock()
+	if s.firedDate != date {
+		s.fired = make(map[string]bool)
+		s.firedDate = date
+	}
+	s.pendingMu.Unlock()
+
+	for _, u := range usage {
+		for _, metric := range Metrics {
+			limit, ok := s.config.Limit(u.TenantID, metric)
+			if !ok || u.Metrics[metric] < limit {
+				continue
+			}
+
+			firedKey := u.TenantID + "|" + metric
+			s.pendingMu.Lock()
+			alreadyFired := s.fired[firedKey]
+			s.fired[firedKey] = true
+			s.pendingMu.Unlock()
+			if alreadyFired {
+				continue
+			}
+
+			s.fireLimit(ctx, LimitEvent{
+				Event:     LimitEventName,
+				TenantID:  u.TenantID,
+				Metric:    metric,
+				Limit:     limit,
+				Value:     u.Metrics[metric],
+				Date:      date,
+				Timestamp: time.Now(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// fireLimit logs a limit event and posts it to every limit webhook
+func (s *Service) fireLimit(ctx context.Context, event LimitEvent) {
+	logger := s.logger.WithFields(log.Fields{
+		"tenant_id": event.TenantID,
+		"metric":    event.Metric,
+		"limit":     event.Limit,
+		"value":     event.Value,
+	})
+	logger.Warn("Tenant crossed usage soft limit")
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to encode usage limit event")
+		return
+	}
+
+	for _, url := range s.config.LimitWebhooks {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			logger.WithError(err).WithField("webhook", url).Warn("Failed to create usage limit webhook request")
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			logger.WithError(err).WithField("webhook", url).Warn("Usage limit webhook failed")
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			logger.WithField("webhook", url).WithField("status", resp.StatusCode).Warn("Usage limit webhook rejected event")
+		}
+	}
+}
+
+// Usage returns daily totals in [from, to] for a tenant, or all tenants when
+// tenantID is empty. Buffered counters are flushed first; when a read replica
+// serves the usage.report route, totals may lag by its accepted staleness.
+func (s *Service) Usage(ctx context.Context, tenantID, from, to string) ([]*DailyUsage, error) {
+	if err := s.Flush(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to flush usage counters")
+	}
+
+	usage, err := s.repo.ListUsage(database.WithQueryRoute(ctx, QueryRouteUsageReport), tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = []*DailyUsage{}
+	}
+	return usage, nil
+}
+
+// Start aggregates usage on the configured interval until Stop is called
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.stopped = make(chan struct{})
+
+	go func() {
+		defer close(s.stopped)
+
+		ticker := time.NewTicker(s.config.AggregateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Aggregate(ctx); err != nil {
+					s.logger.WithError(err).Warn("Failed to aggregate usage")
+				}
+			}
+		}
+	}()
+
+	s.logger.WithField("interval", s.config.AggregateInterval).Info("Usage metering started")
+}
+
+// Stop stops scheduled aggregation and flushes buffered counters
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel, stopped := s.cancel, s.stopped
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-stopped
+	}
+
+	if err := s.Flush(context.Background()); err != nil {
+		s.logger.WithError(err).Warn("Failed to flush usage counters")
+	}
+}
@@ -0,0 +1,220 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedStorage reports constant storage usage
+type fixedStorage map[string]int64
+
+func (s fixedStorage) TenantStorageBytes(ctx context.Context) (map[string]int64, error) {
+	return s, nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestService_RecordAndAggregate(t *testing.T) {
+	var mu sync.Mutex
+	var events []LimitEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event LimitEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := NewInMemoryRepository()
+	service := NewService(repo, Config{
+		SoftLimits:    map[string]float64{MetricMessagesPublished: 3},
+		TenantLimits:  map[string]map[string]float64{"agency-b": {MetricMessagesPublished: 100}},
+		LimitWebhooks: []string{server.URL},
+	}, testLogger())
+	service.SetStorageSource(fixedStorage{"agency-a": 2048})
+
+	ctx := tenant.WithTenant(context.Background(), "agency-a")
+	observe := service.ObservePublications()
+	for i := 0; i < 3; i++ {
+		observe(ctx, &communication.Publication{EventName: "reading"})
+	}
+	for i := 0; i < 5; i++ {
+		observe(context.Background(), &communication.Publication{Metadata: map[string]string{"agency_id": "agency-b"}})
+	}
+	observe(context.Background(), &communication.Publication{})
+	service.Record(ctx, MetricLLMTokens, 150)
+
+	require.NoError(t, service.Aggregate(context.Background()))
+	// Limits fire once per tenant, metric and day
+	require.NoError(t, service.Aggregate(context.Background()))
+
+	today := Day(time.Now())
+	usage, err := service.Usage(context.Background(), "", today, today)
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+
+	byTenant := make(map[string]*DailyUsage)
+	for _, u := range usage {
+		byTenant[u.TenantID] = u
+	}
+	assert.Equal(t, 3.0, byTenant["agency-a"].Metrics[MetricMessagesPublished])
+	assert.Equal(t, 150.0, byTenant["agency-a"].Metrics[MetricLLMTokens])
+	assert.Equal(t, 2048.0, byTenant["agency-a"].Metrics[MetricStorageBytes])
+	assert.Equal(t, 5.0, byTenant["agency-b"].Metrics[MetricMessagesPublished])
+	assert.Equal(t, 1.0, byTenant[UnattributedTenant].Metrics[MetricMessagesPublished])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 1, "agency-b has a higher tenant limit and unattributed usage stays under the default")
+	assert.Equal(t, "agency-a", events[0].TenantID)
+	assert.Equal(t, MetricMessagesPublished, events[0].Metric)
+	assert.Equal(t, LimitEventName, events[0].Event)
+}
+
+// fixedLLMClient answers every call with the same token usage
+type fixedLLMClient struct {
+	usage ai.TokenUsage
+}
+
+func (c *fixedLLMClient) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	usage := c.usage
+	return &ai.ChatResponse{Content: "ok", Usage: &usage}, nil
+}
+
+func (c *fixedLLMClient) ChatStream(ctx context.Context, req *ai.ChatRequest, callback ai.StreamCallback) error {
+	return callback("ok")
+}
+
+func (c *fixedLLMClient) GetProvider() ai.Provider { return ai.ProviderOpenAI }
+func (c *fixedLLMClient) GetModel() string         { return "gpt-test" }
+
+func TestMeteredLLMClient_RecordsCallsAndEnforcesBudget(t *testing.T) {
+	service := NewService(NewInMemoryRepository(), Config{
+		LLMMonthlyTokenBudget: 250,
+		LLMTenantBudgets:      map[string]float64{"agency-b": 0},
+		LLMPricing:            map[string]LLMPrice{"gpt-test": {PromptPer1K: 0.01, CompletionPer1K: 0.03}},
+	}, testLogger())
+	service.SetLLMCallRepository(NewInMemoryLLMCallRepository())
+	client := NewMeteredLLMClient(&fixedLLMClient{usage: ai.TokenUsage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}}, service)
+
+	ctx := ai.WithRequestID(ai.WithOperation(tenant.WithTenant(context.Background(), "agency-a"), "goals.refine"), "req-1")
+	_, err := client.Chat(ctx, &ai.ChatRequest{})
+	require.NoError(t, err)
+	_, err = client.Chat(ai.WithOperation(ctx, "work_items.generate"), &ai.ChatRequest{})
+	require.NoError(t, err)
+
+	// agency-a has now used 300 of its 250 tokens
+	_, err = client.Chat(ctx, &ai.ChatRequest{})
+	assert.ErrorIs(t, err, ErrLLMBudgetExceeded)
+	var budgetErr *LLMBudgetError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "agency-a", budgetErr.TenantID)
+	assert.Equal(t, 300.0, budgetErr.Used)
+
+	// A budget of 0 makes agency-b unlimited
+	ctxB := tenant.WithTenant(context.Background(), "agency-b")
+	for i := 0; i < 3; i++ {
+		_, err = client.Chat(ctxB, &ai.ChatRequest{})
+		require.NoError(t, err)
+	}
+
+	report, err := service.LLMUsage(context.Background(), LLMCallFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Totals.Calls)
+	assert.Equal(t, 750, report.Totals.TotalTokens)
+	assert.InDelta(t, 5*0.0025, report.Totals.CostUSD, 1e-9)
+
+	require.Len(t, report.ByAgency, 2)
+	assert.Equal(t, "agency-a", report.ByAgency[0].Key)
+	assert.Equal(t, 300, report.ByAgency[0].TotalTokens)
+
+	require.Len(t, report.ByBuilder, 3)
+	assert.Equal(t, []string{"goals", "unnamed", "work_items"}, []string{report.ByBuilder[0].Key, report.ByBuilder[1].Key, report.ByBuilder[2].Key})
+
+	byRequest, err := service.LLMUsage(context.Background(), LLMCallFilter{RequestID: "req-1", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, byRequest.Totals.Calls)
+	require.Len(t, byRequest.Calls, 1)
+	assert.Equal(t, "gpt-test", byRequest.Calls[0].Model)
+	assert.Equal(t, "req-1", byRequest.Calls[0].RequestID)
+}
+
+func TestLLMBudgetMiddleware_UsesRequestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewService(NewInMemoryRepository(), Config{
+		LLMTenantBudgets: map[string]float64{"acme": 100},
+	}, testLogger())
+	service.SetLLMCallRepository(NewInMemoryLLMCallRepository())
+	service.RecordLLMCall(tenant.WithTenant(context.Background(), "acme"), &LLMCall{Model: "gpt-test", TotalTokens: 150})
+
+	router := gin.New()
+	// Stands in for the authentication middleware
+	router.Use(func(c *gin.Context) {
+		if tenantID := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); tenantID != "" {
+			c.Request = c.Request.WithContext(tenant.WithTenant(c.Request.Context(), tenantID))
+		}
+	})
+	router.POST("/api/v1/agencies/:id/goals/refine", LLMBudgetMiddleware(service), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	refine := func(secret, agencyID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/agencies/"+agencyID+"/goals/refine", nil)
+		req.Header.Set("X-Agency-ID", agencyID)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, refine("acme", "agency-a"))
+	assert.Equal(t, http.StatusOK, refine("globex", "acme"), "the agency named by the request is not the tenant")
+	assert.Equal(t, http.StatusOK, refine("", "acme"))
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, []*DailyUsage{
+		{TenantID: "agency-a", Date: "2026-10-14", Metrics: map[string]float64{MetricMessagesPublished: 12, MetricWorkflowTaskMinutes: 1.5}},
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "date,tenant_id,messages_published,llm_tokens,storage_bytes,workflow_task_minutes", lines[0])
+	assert.Equal(t, "2026-10-14,agency-a,12,0,0,1.5", lines[1])
+}
+
+func TestOverlap(t *testing.T) {
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	end := day.AddDate(0, 0, 1)
+
+	// A node running from 23:30 the previous day to 00:45 counts 45 minutes
+	assert.Equal(t, 45*time.Minute, overlap(day.Add(-30*time.Minute), day.Add(45*time.Minute), day, end))
+	// A node entirely outside the day does not count
+	assert.LessOrEqual(t, overlap(end.Add(time.Hour), end.Add(2*time.Hour), day, end), time.Duration(0))
+}
@@ -0,0 +1,199 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	driver "github.com/arangodb/go-driver"
+)
+
+// AgencyLister lists the agencies usage is attributed to
+type AgencyLister interface {
+	ListAgencies(ctx context.Context, filters agency.AgencyFilters) ([]*agency.Agency, error)
+}
+
+// AgencyTenant returns the tenant an agency's usage is attributed to: the
+// tenant that owns it, or the agency itself for agencies without a tenant
+func AgencyTenant(a *agency.Agency) string {
+	if a.TenantID != "" {
+		return a.TenantID
+	}
+	return a.ID
+}
+
+// ArangoStorageSource measures each agency's database size
+type ArangoStorageSource struct {
+	client   driver.Client
+	agencies AgencyLister
+}
+
+// NewArangoStorageSource creates a storage source over the agency databases
+func NewArangoStorageSource(client driver.Client, agencies AgencyLister) *ArangoStorageSource {
+	return &ArangoStorageSource{
+		client:   client,
+		agencies: agencies,
+	}
+}
+
+// TenantStorageBytes returns the document and index bytes of the agency
+// databases of each tenant. Agencies whose database cannot be read are skipped.
+func (s *ArangoStorageSource) TenantStorageBytes(ctx context.Context) (map[string]int64, error) {
+	agencies, err := s.agencies.ListAgencies(ctx, agency.AgencyFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agencies: %w", err)
+	}
+
+	storage := make(map[string]int64, len(agencies))
+	var firstErr error
+	for _, a := range agencies {
+		used, err := s.databaseBytes(ctx, a)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		storage[AgencyTenant(a)] += used
+	}
+
+	return storage, firstErr
+}
+
+func (s *ArangoStorageSource) databaseBytes(ctx context.Context, a *agency.Agency) (int64, error) {
+	dbName := a.Database
+	if dbName == "" {
+		dbName = a.ID
+	}
+
+	exists, err := s.client.DatabaseExists(ctx, dbName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check database %s: %w", dbName, err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	db, err := s.client.Database(ctx, dbName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database %s: %w", dbName, err)
+	}
+
+	collections, err := db.Collections(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list collections of %s: %w", dbName, err)
+	}
+
+	var total int64
+	for _, col := range collections {
+		stats, err := col.Statistics(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read statistics of %s/%s: %w", dbName, col.Name(), err)
+		}
+		if stats.Figures.DocumentsSize != nil {
+			total += *stats.Figures.DocumentsSize
+		}
+		total += stats.Figures.Indexes.Size
+	}
+
+	return total, nil
+}
+
+// WorkflowStore reads the workflows and executions of an agency
+type WorkflowStore interface {
+	GetWorkflowsByAgency(ctx context.Context, agencyID string) ([]*workflow.Workflow, error)
+	GetExecutionsByWorkflow(ctx context.Context, workflowID string) ([]*workflow.WorkflowExecution, error)
+}
+
+// WorkflowTaskSource derives task-minutes from workflow node executions
+type WorkflowTaskSource struct {
+	agencies  AgencyLister
+	workflows WorkflowStore
+}
+
+// NewWorkflowTaskSource creates a task source over the agency workflows
+func NewWorkflowTaskSource(agencies AgencyLister, workflows WorkflowStore) *WorkflowTaskSource {
+	return &WorkflowTaskSource{
+		agencies:  agencies,
+		workflows: workflows,
+	}
+}
+
+// TenantTaskMinutes returns the minutes the workflow nodes of each tenant's
+// agencies spent running within [start, end). Nodes still running count up to now.
+func (s *WorkflowTaskSource) TenantTaskMinutes(ctx context.Context, start, end time.Time) (map[string]float64, error) {
+	agencies, err := s.agencies.ListAgencies(ctx, agency.AgencyFilters{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agencies: %w", err)
+	}
+
+	now := time.Now()
+	minutes := make(map[string]float64)
+	for _, a := range agencies {
+		workflows, err := s.workflows.GetWorkflowsByAgency(ctx, a.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflows of %s: %w", a.ID, err)
+		}
+
+		for _, wf := range workflows {
+			executions, err := s.workflows.GetExecutionsByWorkflow(ctx, wf.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list executions of %s: %w", wf.ID, err)
+			}
+
+			for _, exec := range executions {
+				for _, node := range exec.NodeExecutions {
+					if node.StartedAt == nil {
+						continue
+					}
+					finished := now
+					if node.CompletedAt != nil {
+						finished = *node.CompletedAt
+					}
+					if overlap := overlap(*node.StartedAt, finished, start, end); overlap > 0 {
+						minutes[AgencyTenant(a)] += overlap.Minutes()
+					}
+				}
+			}
+		}
+	}
+
+	return minutes, nil
+}
+
+// overlap returns how much of [from, to) falls within [start, end)
+func overlap(from, to, start, end time.Time) time.Duration {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	return to.Sub(from)
+}
+
+// PublicationTenant returns the tenant a publication is attributed to: the
+// tenant of the publishing request, then for publications made outside a
+// request, such as by agents, the agency_id metadata or payload field
+func PublicationTenant(ctx context.Context, pub *communication.Publication) string {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		return tenantID
+	}
+	if tenantID := pub.Metadata["agency_id"]; tenantID != "" {
+		return tenantID
+	}
+	if tenantID, ok := pub.Payload["agency_id"].(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return UnattributedTenant
+}
+
+// ObservePublications returns a publish observer that counts published messages
+func (s *Service) ObservePublications() communication.PublishObserver {
+	return func(ctx context.Context, pub *communication.Publication) {
+		s.RecordTenant(PublicationTenant(ctx, pub), MetricMessagesPublished, 1)
+	}
+}
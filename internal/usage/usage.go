@@ -0,0 +1,76 @@
+// Package usage meters billable usage per tenant.
+//
+// Usage is attributed to the tenant of the request's API key; storage and
+// workflow task-minutes are attributed to the tenant that owns each agency, or
+// to the agency itself when it has no tenant. Counters (messages published, LLM tokens) are
+// recorded as they happen and flushed into daily totals; gauges (storage
+// bytes) and derived totals (workflow task-minutes) are sampled each time the
+// daily totals are aggregated. Crossing a soft limit fires the limit webhooks
+// once per tenant, metric and day.
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
+)
+
+// Billable metrics
+const (
+	MetricMessagesPublished   = "messages_published"
+	MetricLLMTokens           = "llm_tokens"
+	MetricStorageBytes        = "storage_bytes"
+	MetricWorkflowTaskMinutes = "workflow_task_minutes"
+)
+
+// Metrics lists the billable metrics in export column order
+var Metrics = []string{
+	MetricMessagesPublished,
+	MetricLLMTokens,
+	MetricStorageBytes,
+	MetricWorkflowTaskMinutes,
+}
+
+// UnattributedTenant receives usage that cannot be attributed to an agency
+const UnattributedTenant = "unattributed"
+
+// DateFormat is the layout of daily usage dates
+const DateFormat = "2006-01-02"
+
+// DailyUsage is a tenant's usage totals for one UTC day
+type DailyUsage struct {
+	ID        string             `json:"_key,omitempty"`
+	TenantID  string             `json:"tenant_id"`
+	Date      string             `json:"date"`
+	Metrics   map[string]float64 `json:"metrics"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// Repository stores daily usage totals
+type Repository interface {
+	// AddUsage adds counter amounts to a tenant's daily totals
+	AddUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error
+
+	// SetUsage replaces gauge values in a tenant's daily totals
+	SetUsage(ctx context.Context, tenantID, date string, metrics map[string]float64) error
+
+	// ListUsage returns daily totals with from <= date <= to, for one tenant or all
+	// tenants when tenantID is empty, ordered by date then tenant
+	ListUsage(ctx context.Context, tenantID, from, to string) ([]*DailyUsage, error)
+}
+
+// TenantFromContext returns the tenant usage is attributed to: the tenant of
+// the request's API key, if any
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenantID := tenant.FromContext(ctx)
+	return tenantID, tenantID != ""
+}
+
+// Day returns the usage date of t
+func Day(t time.Time) string {
+	return t.UTC().Format(DateFormat)
+}
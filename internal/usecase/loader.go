@@ -0,0 +1,432 @@
+// Package usecase loads a use case directory into the framework at startup.
+//
+// A use case directory (USECASE_CONFIG_DIR) may contain:
+//
+//	config/agents/*.json     role (agent type) definitions
+//	data/*.json              agent instances, each file a JSON array
+//	config/agencies/*.json   seed agencies with optional goals and work items
+//	config/workflows/*.json  workflows; agency_id must name an existing or seeded agency
+//	config/templates/*.json  agent configuration templates
+//	config/topics.json       a bootstrap manifest of topics and subscriptions
+//
+// Every file is validated before it is loaded. Loading is idempotent: items
+// that already exist are skipped (roles are updated), so restarting with the
+// same directory changes nothing. Failures are collected in a Report instead
+// of aborting the load.
+//
+// Materialize generates the roles and seed agency of a use case directory
+// from an agency built in the agency designer.
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/bootstrap"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/aosanya/CodeValdCortex/internal/templates"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/sirupsen/logrus"
+)
+
+// AgentRegistry stores agent instances. registry.Repository implements it.
+type AgentRegistry interface {
+	Get(ctx context.Context, id string) (*agent.Agent, error)
+	Create(ctx context.Context, ag *agent.Agent) error
+}
+
+// WorkflowStore stores workflows. workflow.Service implements it.
+type WorkflowStore interface {
+	GetWorkflowsByAgency(ctx context.Context, agencyID string) ([]*workflow.Workflow, error)
+	CreateWorkflow(ctx context.Context, wf *workflow.Workflow) error
+}
+
+// TemplateStore stores agent templates. templates.Engine implements it.
+type TemplateStore interface {
+	GetTemplate(ctx context.Context, id string) (*templates.Template, error)
+	CreateTemplate(ctx context.Context, template *templates.Template) (*templates.Template, error)
+}
+
+// ManifestApplier provisions topics and subscriptions. bootstrap.Service implements it.
+type ManifestApplier interface {
+	Apply(ctx context.Context, manifest *bootstrap.Manifest) (*bootstrap.Result, error)
+}
+
+// Dependencies are the services a use case is loaded into. Any of them may
+// be nil; the corresponding section is then reported as unavailable.
+type Dependencies struct {
+	Roles     registry.RoleService
+	Agents    AgentRegistry
+	Agencies  agency.Service
+	Workflows WorkflowStore
+	Templates TemplateStore
+	Bootstrap ManifestApplier
+}
+
+// Loader loads use case directories
+type Loader struct {
+	deps   Dependencies
+	logger *logrus.Logger
+}
+
+// NewLoader creates a new use case loader
+func NewLoader(deps Dependencies, logger *logrus.Logger) *Loader {
+	return &Loader{
+		deps:   deps,
+		logger: logger,
+	}
+}
+
+// Load validates and loads everything in the use case directory. Sections are
+// loaded in dependency order: roles before the agents that use them and
+// agencies before their workflows. An error is returned only when the
+// directory itself cannot be read.
+func (l *Loader) Load(ctx context.Context, dir string) (*Report, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read use case directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("use case path is not a directory: %s", dir)
+	}
+
+	report := &Report{Dir: dir}
+	report.Sections = append(report.Sections,
+		l.loadRoles(ctx, filepath.Join(dir, "config", "agents")),
+		l.loadAgents(ctx, filepath.Join(dir, "data")),
+		l.loadAgencies(ctx, filepath.Join(dir, "config", "agencies")),
+		l.loadWorkflows(ctx, filepath.Join(dir, "config", "workflows")),
+		l.loadTemplates(ctx, filepath.Join(dir, "config", "templates")),
+		l.loadTopics(ctx, filepath.Join(dir, "config", "topics.json")),
+	)
+
+	return report, nil
+}
+
+// jsonFiles lists the JSON files in a directory; a missing directory has none
+func jsonFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// readJSON decodes a JSON file into v
+func readJSON(file string, v interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return nil
+}
+
+// section starts a section report for the files in dir
+func section(name string, dir string, available bool) (*SectionReport, []string) {
+	report := &SectionReport{Name: name, Unavailable: !available}
+	files, err := jsonFiles(dir)
+	if err != nil {
+		report.fail(filepath.Base(dir), err)
+		return report, nil
+	}
+	report.Files = len(files)
+	return report, files
+}
+
+// loadRoles registers role definitions, updating roles that already exist
+func (l *Loader) loadRoles(ctx context.Context, dir string) *SectionReport {
+	report, files := section(SectionRoles, dir, l.deps.Roles != nil)
+	if report.Unavailable {
+		return report
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var role registry.Role
+		if err := readJSON(file, &role); err != nil {
+			report.fail(name, err)
+			continue
+		}
+		// RegisterType validates the role and updates it if it already exists
+		if err := l.deps.Roles.RegisterType(ctx, &role); err != nil {
+			report.fail(name, fmt.Errorf("failed to register role %s: %w", role.ID, err))
+			continue
+		}
+
+		l.logger.WithFields(logrus.Fields{
+			"id":   role.ID,
+			"name": role.Name,
+			"file": name,
+		}).Debug("Loaded role")
+		report.Loaded++
+	}
+
+	return report
+}
+
+// loadAgents creates agent instances that do not exist yet
+func (l *Loader) loadAgents(ctx context.Context, dir string) *SectionReport {
+	report, files := section(SectionAgents, dir, l.deps.Agents != nil)
+	if report.Unavailable {
+		return report
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var agents []agent.Agent
+		if err := readJSON(file, &agents); err != nil {
+			report.fail(name, err)
+			continue
+		}
+
+		for i := range agents {
+			ag := &agents[i]
+			if err := l.validateAgent(ctx, ag); err != nil {
+				report.fail(name, err)
+				continue
+			}
+
+			if existing, err := l.deps.Agents.Get(ctx, ag.ID); err == nil && existing != nil {
+				report.Skipped++
+				continue
+			}
+
+			if err := l.deps.Agents.Create(ctx, ag); err != nil {
+				report.fail(name, fmt.Errorf("failed to create agent %s: %w", ag.ID, err))
+				continue
+			}
+
+			l.logger.WithFields(logrus.Fields{
+				"id":   ag.ID,
+				"type": ag.Type,
+				"file": name,
+			}).Debug("Loaded agent instance")
+			report.Loaded++
+		}
+	}
+
+	return report
+}
+
+// validateAgent checks an agent instance has an ID and a known type
+func (l *Loader) validateAgent(ctx context.Context, ag *agent.Agent) error {
+	if ag.ID == "" || ag.Type == "" {
+		return fmt.Errorf("agent %q: id and type are required", ag.ID)
+	}
+	if l.deps.Roles == nil {
+		return nil
+	}
+	if _, err := l.deps.Roles.GetType(ctx, ag.Type); err != nil {
+		return fmt.Errorf("agent %s: unknown type %q", ag.ID, ag.Type)
+	}
+	return nil
+}
+
+// loadAgencies creates seed agencies with their introduction, goals and work items
+func (l *Loader) loadAgencies(ctx context.Context, dir string) *SectionReport {
+	report, files := section(SectionAgencies, dir, l.deps.Agencies != nil)
+	if report.Unavailable {
+		return report
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var seed SeedAgency
+		if err := readJSON(file, &seed); err != nil {
+			report.fail(name, err)
+			continue
+		}
+		if seed.Agency.ID == "" || seed.Agency.Name == "" {
+			report.fail(name, errors.New("agency id and name are required"))
+			continue
+		}
+
+		if existing, err := l.deps.Agencies.GetAgency(ctx, seed.Agency.ID); err == nil && existing != nil {
+			report.Skipped++
+			continue
+		}
+
+		if err := l.seedAgency(ctx, &seed); err != nil {
+			report.fail(name, err)
+			continue
+		}
+
+		l.logger.WithFields(logrus.Fields{
+			"id":         seed.Agency.ID,
+			"goals":      len(seed.Goals),
+			"work_items": len(seed.WorkItems),
+			"file":       name,
+		}).Debug("Seeded agency")
+		report.Loaded++
+	}
+
+	return report
+}
+
+// seedAgency creates the agency and its contents
+func (l *Loader) seedAgency(ctx context.Context, seed *SeedAgency) error {
+	agencyID := seed.Agency.ID
+	if err := l.deps.Agencies.CreateAgency(ctx, &seed.Agency); err != nil {
+		return fmt.Errorf("failed to create agency %s: %w", agencyID, err)
+	}
+
+	if seed.Introduction != "" {
+		if err := l.deps.Agencies.UpdateAgencyOverview(ctx, agencyID, seed.Introduction); err != nil {
+			return fmt.Errorf("failed to set introduction for agency %s: %w", agencyID, err)
+		}
+	}
+
+	for _, goal := range seed.Goals {
+		if _, err := l.deps.Agencies.CreateGoal(ctx, agencyID, goal.Code, goal.Description); err != nil {
+			return fmt.Errorf("failed to create goal %s for agency %s: %w", goal.Code, agencyID, err)
+		}
+	}
+
+	for _, req := range seed.WorkItems {
+		if _, err := l.deps.Agencies.CreateWorkItem(ctx, agencyID, req); err != nil {
+			return fmt.Errorf("failed to create work item %q for agency %s: %w", req.Title, agencyID, err)
+		}
+	}
+
+	return nil
+}
+
+// loadWorkflows creates workflows whose agency has no workflow with the same name
+func (l *Loader) loadWorkflows(ctx context.Context, dir string) *SectionReport {
+	report, files := section(SectionWorkflows, dir, l.deps.Workflows != nil)
+	if report.Unavailable {
+		return report
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var wf workflow.Workflow
+		if err := readJSON(file, &wf); err != nil {
+			report.fail(name, err)
+			continue
+		}
+		if wf.Name == "" || wf.AgencyID == "" {
+			report.fail(name, errors.New("workflow name and agency_id are required"))
+			continue
+		}
+		if l.deps.Agencies != nil {
+			if _, err := l.deps.Agencies.GetAgency(ctx, wf.AgencyID); err != nil {
+				report.fail(name, fmt.Errorf("workflow %s: unknown agency %s", wf.Name, wf.AgencyID))
+				continue
+			}
+		}
+
+		existing, err := l.deps.Workflows.GetWorkflowsByAgency(ctx, wf.AgencyID)
+		if err != nil {
+			report.fail(name, fmt.Errorf("failed to list workflows for agency %s: %w", wf.AgencyID, err))
+			continue
+		}
+		if hasWorkflowNamed(existing, wf.Name) {
+			report.Skipped++
+			continue
+		}
+
+		wf.ID = ""
+		if err := l.deps.Workflows.CreateWorkflow(ctx, &wf); err != nil {
+			report.fail(name, fmt.Errorf("failed to create workflow %s: %w", wf.Name, err))
+			continue
+		}
+		report.Loaded++
+	}
+
+	return report
+}
+
+func hasWorkflowNamed(workflows []*workflow.Workflow, name string) bool {
+	for _, wf := range workflows {
+		if wf.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTemplates creates templates whose ID is not already registered
+func (l *Loader) loadTemplates(ctx context.Context, dir string) *SectionReport {
+	report, files := section(SectionTemplates, dir, l.deps.Templates != nil)
+	if report.Unavailable {
+		return report
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var tmpl templates.Template
+		if err := readJSON(file, &tmpl); err != nil {
+			report.fail(name, err)
+			continue
+		}
+		if tmpl.ID == "" {
+			report.fail(name, errors.New("template id is required"))
+			continue
+		}
+
+		if existing, err := l.deps.Templates.GetTemplate(ctx, tmpl.ID); err == nil && existing != nil {
+			report.Skipped++
+			continue
+		}
+
+		if _, err := l.deps.Templates.CreateTemplate(ctx, &tmpl); err != nil {
+			report.fail(name, err)
+			continue
+		}
+		report.Loaded++
+	}
+
+	return report
+}
+
+// loadTopics applies config/topics.json as a bootstrap manifest
+func (l *Loader) loadTopics(ctx context.Context, file string) *SectionReport {
+	report := &SectionReport{Name: SectionTopics, Unavailable: l.deps.Bootstrap == nil}
+	if report.Unavailable {
+		return report
+	}
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return report
+	}
+	report.Files = 1
+	name := filepath.Base(file)
+
+	var manifest bootstrap.Manifest
+	if err := readJSON(file, &manifest); err != nil {
+		report.fail(name, err)
+		return report
+	}
+
+	result, err := l.deps.Bootstrap.Apply(ctx, &manifest)
+	if err != nil {
+		report.fail(name, err)
+		return report
+	}
+
+	report.Loaded = result.Summary.Created + result.Summary.Updated
+	report.Skipped = result.Summary.Unchanged + result.Summary.Noop
+	for _, items := range [][]bootstrap.ItemResult{result.Agents, result.Topics, result.Subscriptions} {
+		for _, item := range items {
+			if item.Action == bootstrap.ActionFailed {
+				report.fail(name, fmt.Errorf("%s: %s", item.ID, item.Message))
+			}
+		}
+	}
+
+	return report
+}
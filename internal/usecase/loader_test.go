@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAgentRegistry is an in-memory AgentRegistry for testing
+type mockAgentRegistry struct {
+	agents map[string]*agent.Agent
+}
+
+func (m *mockAgentRegistry) Get(ctx context.Context, id string) (*agent.Agent, error) {
+	ag, ok := m.agents[id]
+	if !ok {
+		return nil, agent.ErrAgentNotFound
+	}
+	return ag, nil
+}
+
+func (m *mockAgentRegistry) Create(ctx context.Context, ag *agent.Agent) error {
+	m.agents[ag.ID] = ag
+	return nil
+}
+
+// mockWorkflowStore is an in-memory WorkflowStore for testing
+type mockWorkflowStore struct {
+	workflows []*workflow.Workflow
+}
+
+func (m *mockWorkflowStore) GetWorkflowsByAgency(ctx context.Context, agencyID string) ([]*workflow.Workflow, error) {
+	var result []*workflow.Workflow
+	for _, wf := range m.workflows {
+		if wf.AgencyID == agencyID {
+			result = append(result, wf)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockWorkflowStore) CreateWorkflow(ctx context.Context, wf *workflow.Workflow) error {
+	wf.ID = fmt.Sprintf("wf-%d", len(m.workflows)+1)
+	m.workflows = append(m.workflows, wf)
+	return nil
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func newTestLoader(deps Dependencies) *Loader {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewLoader(deps, logger)
+}
+
+func TestLoader_Load(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config", "agents", "pump.json"), `{"id": "pump", "name": "Pump", "version": "1.0.0"}`)
+	writeFile(t, filepath.Join(dir, "config", "agents", "broken.json"), `{"id": "broken"`)
+	writeFile(t, filepath.Join(dir, "data", "pumps.json"), `[
+		{"id": "pump-1", "name": "Pump 1", "type": "pump"},
+		{"id": "valve-1", "name": "Valve 1", "type": "valve"}
+	]`)
+
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	roles := registry.NewRoleService(registry.NewInMemoryRoleRepository(), logger)
+	agents := &mockAgentRegistry{agents: make(map[string]*agent.Agent)}
+
+	loader := newTestLoader(Dependencies{Roles: roles, Agents: agents})
+	report, err := loader.Load(ctx, dir)
+	require.NoError(t, err)
+
+	rolesReport := report.Section(SectionRoles)
+	require.NotNil(t, rolesReport)
+	assert.Equal(t, 2, rolesReport.Files)
+	assert.Equal(t, 1, rolesReport.Loaded)
+	assert.Equal(t, 1, rolesReport.Failed)
+
+	agentsReport := report.Section(SectionAgents)
+	require.NotNil(t, agentsReport)
+	assert.Equal(t, 1, agentsReport.Loaded)
+	assert.Equal(t, 1, agentsReport.Failed, "agent with an unknown type should fail validation")
+	assert.Contains(t, agents.agents, "pump-1")
+
+	assert.True(t, report.Section(SectionAgencies).Unavailable)
+	assert.True(t, report.Section(SectionTopics).Unavailable)
+	assert.Equal(t, 2, report.Failed())
+
+	// Loading again skips what already exists
+	report, err = loader.Load(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Section(SectionAgents).Loaded)
+	assert.Equal(t, 1, report.Section(SectionAgents).Skipped)
+}
+
+func TestLoader_LoadWorkflows(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config", "workflows", "leak.json"), `{"name": "Leak response", "agency_id": "water"}`)
+	writeFile(t, filepath.Join(dir, "config", "workflows", "orphan.json"), `{"name": "No agency"}`)
+
+	ctx := context.Background()
+	workflows := &mockWorkflowStore{}
+	loader := newTestLoader(Dependencies{Workflows: workflows})
+
+	report, err := loader.Load(ctx, dir)
+	require.NoError(t, err)
+	section := report.Section(SectionWorkflows)
+	assert.Equal(t, 1, section.Loaded)
+	assert.Equal(t, 1, section.Failed)
+	require.Len(t, workflows.workflows, 1)
+	assert.Equal(t, "wf-1", workflows.workflows[0].ID)
+
+	report, err = loader.Load(ctx, dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Section(SectionWorkflows).Skipped)
+	assert.Len(t, workflows.workflows, 1)
+}
+
+func TestLoader_MissingDirectory(t *testing.T) {
+	loader := newTestLoader(Dependencies{})
+
+	_, err := loader.Load(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
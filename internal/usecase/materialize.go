@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+)
+
+// DefaultRoleVersion is the version given to materialized roles that have none
+const DefaultRoleVersion = "1.0.0"
+
+// Design is an agency design as built in the agency designer
+type Design struct {
+	Agency       *agency.Agency
+	Introduction string
+	Goals        []*agency.Goal
+	WorkItems    []*agency.WorkItem
+	Roles        []*registry.Role
+	Assignments  []*agency.RACIAssignment
+}
+
+// Materialized is a use case directory generated from a design. Loading it
+// creates the agency with its goals and work items and registers its roles.
+type Materialized struct {
+	Roles  []*registry.Role // Written to config/agents/<id>.json
+	Agency SeedAgency       // Written to config/agencies/<id>.json
+}
+
+// Materialize converts a design into the role definitions and seed agency of
+// a use case directory. Goal and work item keys are installation-specific, so
+// roles refer to goals and work items by code in their metadata:
+// "goal_codes" lists the goals a role works towards and "raci" maps work item
+// codes to the role's RACI letter.
+func Materialize(design *Design) (*Materialized, error) {
+	if design.Agency == nil || design.Agency.ID == "" || design.Agency.Name == "" {
+		return nil, errors.New("agency id and name are required")
+	}
+
+	goalCodes := make(map[string]string, len(design.Goals))
+	for _, goal := range design.Goals {
+		goalCodes[goal.Key] = goal.Code
+	}
+	workItemCodes := make(map[string]string, len(design.WorkItems))
+	for _, workItem := range design.WorkItems {
+		workItemCodes[workItem.Key] = workItem.Code
+	}
+
+	m := &Materialized{Agency: SeedAgency{
+		Agency: agency.Agency{
+			ID:          design.Agency.ID,
+			Name:        design.Agency.Name,
+			DisplayName: design.Agency.DisplayName,
+			Description: design.Agency.Description,
+			Category:    design.Agency.Category,
+			Icon:        design.Agency.Icon,
+			Status:      design.Agency.Status,
+			Metadata:    design.Agency.Metadata,
+			Settings:    design.Agency.Settings,
+		},
+		Introduction: design.Introduction,
+	}}
+
+	for _, goal := range design.Goals {
+		m.Agency.Goals = append(m.Agency.Goals, SeedGoal{Code: goal.Code, Description: goal.Description})
+	}
+	for _, workItem := range design.WorkItems {
+		m.Agency.WorkItems = append(m.Agency.WorkItems, agency.CreateWorkItemRequest{
+			Title:                workItem.Title,
+			Description:          workItem.Description,
+			Deliverables:         workItem.Deliverables,
+			Dependencies:         workItem.Dependencies,
+			Tags:                 workItem.Tags,
+			RequiredCapabilities: workItem.RequiredCapabilities,
+		})
+	}
+
+	raci := make(map[string]map[string]string)
+	for _, assignment := range design.Assignments {
+		code, ok := workItemCodes[assignment.WorkItemKey]
+		if !ok {
+			continue
+		}
+		if raci[assignment.RoleKey] == nil {
+			raci[assignment.RoleKey] = make(map[string]string)
+		}
+		raci[assignment.RoleKey][code] = assignment.RACI
+	}
+
+	seen := make(map[string]bool, len(design.Roles))
+	for _, role := range design.Roles {
+		if role.IsSystemType {
+			continue
+		}
+		if role.ID == "" || role.Name == "" {
+			return nil, fmt.Errorf("role %q: id and name are required", role.ID)
+		}
+		if seen[role.ID] {
+			return nil, fmt.Errorf("role %s is defined more than once", role.ID)
+		}
+		seen[role.ID] = true
+
+		m.Roles = append(m.Roles, materializeRole(role, goalCodes, raci[roleKey(role)]))
+	}
+	sort.Slice(m.Roles, func(i, j int) bool { return m.Roles[i].ID < m.Roles[j].ID })
+
+	return m, nil
+}
+
+// roleKey is the key RACI assignments use to refer to a role
+func roleKey(role *registry.Role) string {
+	if role.Key != "" {
+		return role.Key
+	}
+	return role.ID
+}
+
+// materializeRole copies a role without its installation-specific fields
+func materializeRole(role *registry.Role, goalCodes map[string]string, raci map[string]string) *registry.Role {
+	materialized := &registry.Role{
+		ID:                   role.ID,
+		Name:                 role.Name,
+		Description:          role.Description,
+		AgencyID:             role.AgencyID,
+		Responsibilities:     role.Responsibilities,
+		Tags:                 role.Tags,
+		Version:              role.Version,
+		Schema:               role.Schema,
+		RequiredCapabilities: role.RequiredCapabilities,
+		OptionalCapabilities: role.OptionalCapabilities,
+		DefaultConfig:        role.DefaultConfig,
+		ValidationRules:      role.ValidationRules,
+		AutonomyLevel:        role.AutonomyLevel,
+		RequiredSkills:       role.RequiredSkills,
+		TokenBudget:          role.TokenBudget,
+		Icon:                 role.Icon,
+		Color:                role.Color,
+		IsEnabled:            true,
+		CreatedBy:            "agency-designer",
+	}
+	if materialized.Version == "" {
+		materialized.Version = DefaultRoleVersion
+	}
+
+	metadata := make(map[string]interface{}, len(role.Metadata)+2)
+	for k, v := range role.Metadata {
+		metadata[k] = v
+	}
+	var codes []string
+	for _, key := range role.GoalKeys {
+		if code, ok := goalCodes[key]; ok {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > 0 {
+		metadata["goal_codes"] = codes
+	}
+	if len(raci) > 0 {
+		metadata["raci"] = raci
+	}
+	if len(metadata) > 0 {
+		materialized.Metadata = metadata
+	}
+
+	return materialized
+}
+
+// Files returns the use case files by their slash-separated path relative to
+// the use case directory, formatted like the hand-written use case configs
+func (m *Materialized) Files() (map[string][]byte, error) {
+	files := make(map[string][]byte, len(m.Roles)+1)
+	for _, role := range m.Roles {
+		data, err := json.MarshalIndent(role, "", "    ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode role %s: %w", role.ID, err)
+		}
+		files[path.Join("config", "agents", fileName(role.ID))] = append(data, '\n')
+	}
+
+	data, err := json.MarshalIndent(m.Agency, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode agency: %w", err)
+	}
+	files[path.Join("config", "agencies", fileName(m.Agency.Agency.ID))] = append(data, '\n')
+
+	return files, nil
+}
+
+// fileName turns an ID into a JSON file name that stays inside its directory
+func fileName(id string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(id) + ".json"
+}
+
+// WriteDir writes the use case files under dir, replacing files of the same name
+func (m *Materialized) WriteDir(dir string) error {
+	files, err := m.Files()
+	if err != nil {
+		return err
+	}
+
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteZip writes the use case files as a zip archive
+func (m *Materialized) WriteZip(w io.Writer) error {
+	files, err := m.Files()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		entry, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		if _, err := entry.Write(files[name]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return archive.Close()
+}
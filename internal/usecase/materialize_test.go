@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/registry"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterialize_LoadsAsUseCase(t *testing.T) {
+	design := &Design{
+		Agency:       &agency.Agency{ID: "billing", Name: "billing", DisplayName: "Billing"},
+		Introduction: "Invoices customers",
+		Goals:        []*agency.Goal{{Key: "g-key", Code: "G001", Description: "Bill on time"}},
+		WorkItems: []*agency.WorkItem{
+			{Key: "wi-key", Code: "WI-001", Title: "Send invoices", Description: "Monthly", GoalKeys: []string{"g-key"}},
+		},
+		Roles: []*registry.Role{
+			{Key: "clerk", ID: "clerk", Name: "Billing Clerk", AgencyID: "billing", GoalKeys: []string{"g-key"}, Responsibilities: []string{"Send invoices"}},
+			{ID: "core", Name: "Core", Version: "2.0.0", IsSystemType: true},
+		},
+		Assignments: []*agency.RACIAssignment{{WorkItemKey: "wi-key", RoleKey: "clerk", RACI: "R"}},
+	}
+
+	materialized, err := Materialize(design)
+	require.NoError(t, err)
+	require.Len(t, materialized.Roles, 1, "system roles are not materialized")
+
+	role := materialized.Roles[0]
+	assert.Equal(t, DefaultRoleVersion, role.Version)
+	assert.Equal(t, []string{"G001"}, role.Metadata["goal_codes"])
+	assert.Equal(t, map[string]string{"WI-001": "R"}, role.Metadata["raci"])
+	assert.Equal(t, "Send invoices", materialized.Agency.WorkItems[0].Title)
+
+	dir := t.TempDir()
+	require.NoError(t, materialized.WriteDir(dir))
+
+	var seed SeedAgency
+	require.NoError(t, readJSON(filepath.Join(dir, "config", "agencies", "billing.json"), &seed))
+	assert.Equal(t, "Invoices customers", seed.Introduction)
+	assert.Equal(t, []SeedGoal{{Code: "G001", Description: "Bill on time"}}, seed.Goals)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	roles := registry.NewRoleService(registry.NewInMemoryRoleRepository(), logger)
+	report, err := newTestLoader(Dependencies{Roles: roles}).Load(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Section(SectionRoles).Loaded)
+	assert.Zero(t, report.Failed())
+
+	loaded, err := roles.GetType(context.Background(), "clerk")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Send invoices"}, loaded.Responsibilities)
+}
+
+func TestMaterialize_RequiresAgencyAndRoleIDs(t *testing.T) {
+	_, err := Materialize(&Design{Agency: &agency.Agency{Name: "billing"}})
+	assert.Error(t, err)
+
+	_, err = Materialize(&Design{
+		Agency: &agency.Agency{ID: "billing", Name: "billing"},
+		Roles:  []*registry.Role{{Name: "Nameless"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+)
+
+// Section names used in the load report
+const (
+	SectionRoles     = "roles"
+	SectionAgents    = "agents"
+	SectionAgencies  = "agencies"
+	SectionWorkflows = "workflows"
+	SectionTemplates = "templates"
+	SectionTopics    = "topics"
+)
+
+// SeedAgency is an agency definition in config/agencies/*.json. The agency is
+// created together with its introduction, goals and work items the first
+// time it is loaded; an existing agency is left untouched.
+type SeedAgency struct {
+	Agency       agency.Agency                  `json:"agency"`
+	Introduction string                         `json:"introduction,omitempty"`
+	Goals        []SeedGoal                     `json:"goals,omitempty"`
+	WorkItems    []agency.CreateWorkItemRequest `json:"work_items,omitempty"`
+}
+
+// SeedGoal is a goal declared by a seed agency
+type SeedGoal struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// SectionReport summarizes loading one kind of resource
+type SectionReport struct {
+	Name    string `json:"name"`
+	Files   int    `json:"files"`
+	Loaded  int    `json:"loaded"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+
+	// Errors lists validation and load failures, prefixed with the file name
+	Errors []string `json:"errors,omitempty"`
+
+	// Unavailable is set when the service needed to load this section is not configured
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// fail records a failure for a file
+func (s *SectionReport) fail(file string, err error) {
+	s.Failed++
+	s.Errors = append(s.Errors, fmt.Sprintf("%s: %v", file, err))
+}
+
+// Report summarizes loading a use case directory
+type Report struct {
+	Dir      string           `json:"dir"`
+	Sections []*SectionReport `json:"sections"`
+}
+
+// Section returns the named section report, or nil
+func (r *Report) Section(name string) *SectionReport {
+	for _, s := range r.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Failed returns the total number of failed items across sections
+func (r *Report) Failed() int {
+	failed := 0
+	for _, s := range r.Sections {
+		failed += s.Failed
+	}
+	return failed
+}
+
+// Log writes the report as one summary line per section followed by any errors
+func (r *Report) Log(logger *logrus.Logger) {
+	var parts []string
+	for _, s := range r.Sections {
+		if s.Unavailable {
+			parts = append(parts, fmt.Sprintf("%s: unavailable", s.Name))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d loaded, %d skipped, %d failed", s.Name, s.Loaded, s.Skipped, s.Failed))
+	}
+
+	entry := logger.WithFields(logrus.Fields{
+		"dir":    r.Dir,
+		"failed": r.Failed(),
+	})
+	entry.Infof("Use case loaded (%s)", strings.Join(parts, "; "))
+
+	for _, s := range r.Sections {
+		for _, e := range s.Errors {
+			logger.WithField("section", s.Name).Warn(e)
+		}
+	}
+}
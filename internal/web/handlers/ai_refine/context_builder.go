@@ -11,9 +11,10 @@ import (
 
 // BuilderContextBuilder provides methods to build AI context from agency data
 type BuilderContextBuilder struct {
-	agencyService agency.Service
-	roleService   registry.RoleService
-	logger        *logrus.Logger
+	agencyService        agency.Service
+	roleService          registry.RoleService
+	infrastructureSource builder.InfrastructureSource
+	logger               *logrus.Logger
 }
 
 // NewBuilderContextBuilder creates a new AI context builder
@@ -25,6 +26,12 @@ func NewBuilderContextBuilder(agencyService agency.Service, roleService registry
 	}
 }
 
+// SetInfrastructureSource sets an optional source of topology and KPI summaries.
+// When set, every BuilderContext includes the live asset inventory.
+func (b *BuilderContextBuilder) SetInfrastructureSource(source builder.InfrastructureSource) {
+	b.infrastructureSource = source
+}
+
 // BuildBuilderContext gathers all agency context data and returns it as a structured BuilderContext
 // This is the centralized function used by all AI operations to ensure consistent context
 func (b *BuilderContextBuilder) BuildBuilderContext(ctx context.Context, agencyObj *agency.Agency, currentIntroduction string, userRequest string) (builder.BuilderContext, error) {
@@ -44,11 +51,16 @@ func (b *BuilderContextBuilder) BuildBuilderContext(ctx context.Context, agencyO
 		workItems = []*agency.WorkItem{}
 	}
 
-	// Get all roles for context
-	roles, err := b.roleService.ListTypes(ctx)
+	// Get the roles visible to the agency for context
+	allRoles, err := b.roleService.ListTypes(ctx)
 	if err != nil {
 		b.logger.WithError(err).Warn("Failed to fetch roles, continuing without them")
-		roles = []*registry.Role{}
+	}
+	roles := []*registry.Role{}
+	for _, role := range allRoles {
+		if role.VisibleTo(agencyObj.ID) {
+			roles = append(roles, role)
+		}
 	}
 
 	// Get RACI assignments for context
@@ -73,14 +85,25 @@ func (b *BuilderContextBuilder) BuildBuilderContext(ctx context.Context, agencyO
 		UserInput:    userRequest,
 	}
 
+	// Get infrastructure summary for context (optional)
+	if b.infrastructureSource != nil {
+		infrastructure, err := b.infrastructureSource.InfrastructureContext(ctx)
+		if err != nil {
+			b.logger.WithError(err).Warn("Failed to fetch infrastructure context, continuing without it")
+		} else {
+			builderContext.Infrastructure = infrastructure
+		}
+	}
+
 	b.logger.WithFields(logrus.Fields{
-		"agency_id":         agencyObj.ID,
-		"agency_name":       agencyObj.DisplayName,
-		"goals_count":       len(goals),
-		"work_items_count":  len(workItems),
-		"roles_count":       len(roles),
-		"assignments_count": len(assignments),
-		"has_user_input":    userRequest != "",
+		"agency_id":          agencyObj.ID,
+		"agency_name":        agencyObj.DisplayName,
+		"goals_count":        len(goals),
+		"work_items_count":   len(workItems),
+		"roles_count":        len(roles),
+		"assignments_count":  len(assignments),
+		"has_user_input":     userRequest != "",
+		"has_infrastructure": builderContext.Infrastructure != nil,
 	}).Debug("AI context data built successfully")
 
 	return builderContext, nil
@@ -0,0 +1,30 @@
+package ai_refine
+
+import (
+	"context"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/sirupsen/logrus"
+)
+
+// recordExplanation attaches an AI explanation to the entity it affected.
+// The per-item explanation is preferred; the overall explanation is used
+// when the AI did not give one. Failures are logged and do not interrupt
+// the chat flow.
+func (h *Handler) recordExplanation(ctx context.Context, explanation *agency.Explanation, itemExplanation string, overallExplanation string) {
+	explanation.Text = itemExplanation
+	if explanation.Text == "" {
+		explanation.Text = overallExplanation
+	}
+	if explanation.Text == "" {
+		return
+	}
+
+	if err := h.agencyService.RecordExplanation(ctx, explanation); err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"agency_id":   explanation.AgencyID,
+			"entity_type": explanation.EntityType,
+			"entity_key":  explanation.EntityKey,
+		}).Warn("Failed to record AI explanation")
+	}
+}
@@ -0,0 +1,241 @@
+package ai_refine
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// goalOperationInstructions turns the goal panel's operations into instructions for the AI
+var goalOperationInstructions = map[string]string{
+	"create":      "Generate new goals that are missing from the agency.",
+	"enhance":     "Enhance the selected goals so that they are clear, measurable and well scoped.",
+	"consolidate": "Consolidate the selected goals, merging duplicates and overlapping goals.",
+}
+
+// ProcessAIGoalRequest handles POST /api/v1/agencies/:id/goals/ai-process
+// Runs the selected AI operations (create, enhance, consolidate) on goals.
+// With "preview" set, the proposed changes are returned as a proposal and
+// nothing is persisted; the proposal can be applied later with ConfirmProposal.
+func (h *Handler) ProcessAIGoalRequest(c *gin.Context) {
+	agencyID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req struct {
+		Operations  []string `json:"operations" binding:"required"`
+		GoalKeys    []string `json:"goal_keys"`
+		UserRequest string   `json:"user_request"`
+		Preview     bool     `json:"preview"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	var instructions []string
+	for _, op := range req.Operations {
+		instruction, ok := goalOperationInstructions[op]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown goal operation: " + op})
+			return
+		}
+		instructions = append(instructions, instruction)
+	}
+	if strings.TrimSpace(req.UserRequest) != "" {
+		instructions = append(instructions, strings.TrimSpace(req.UserRequest))
+	}
+	userMessage := strings.Join(instructions, "\n")
+
+	ag, err := h.agencyService.GetAgency(ctx, agencyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agency not found"})
+		return
+	}
+
+	builderContext, err := h.contextBuilder.BuildBuilderContext(ctx, ag, "", userMessage)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to build context")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to gather agency context"})
+		return
+	}
+
+	existingGoals := builderContext.Goals
+	var targetGoals []*agency.Goal
+	if len(req.GoalKeys) > 0 {
+		selected := make(map[string]bool, len(req.GoalKeys))
+		for _, key := range req.GoalKeys {
+			selected[key] = true
+		}
+		for _, goal := range existingGoals {
+			if selected[goal.Key] {
+				targetGoals = append(targetGoals, goal)
+			}
+		}
+	}
+
+	result, err := h.goalRefiner.RefineGoals(ctx, &builder.RefineGoalsRequest{
+		AgencyID:      agencyID,
+		UserMessage:   userMessage,
+		TargetGoals:   targetGoals,
+		ExistingGoals: existingGoals,
+		WorkItems:     builderContext.WorkItems,
+		AgencyContext: ag,
+	}, builderContext)
+	if err != nil {
+		h.logger.WithError(err).Error("AI goal processing failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI processing failed"})
+		return
+	}
+
+	proposal := goalProposal(agencyID, userMessage, existingGoals, result)
+
+	h.logger.WithFields(logrus.Fields{
+		"agency_id":  agencyID,
+		"operations": req.Operations,
+		"action":     result.Action,
+		"changes":    len(proposal.Changes),
+		"preview":    req.Preview,
+	}).Info("Processed AI goal request")
+
+	if req.Preview {
+		h.proposals.Save(proposal)
+		c.JSON(http.StatusOK, gin.H{
+			"preview":     true,
+			"proposal":    proposal,
+			"explanation": result.Explanation,
+			"summary":     h.buildSummaryMessage(result),
+		})
+		return
+	}
+
+	applied := h.applyProposal(ctx, proposal)
+	summary := applied.Summary("goal", "goals")
+
+	// The goal panel reloads the chat to show what the AI did
+	if conv, err := h.designerService.GetConversationByAgencyID(agencyID); err == nil {
+		message := summary
+		if result.Explanation != "" {
+			message += "\n\n" + result.Explanation
+		}
+		if applied.ConsolidationKey != "" {
+			message += "\n\n↩️ Undo with consolidation `" + applied.ConsolidationKey + "`."
+		}
+		h.addAssistantMessage(ctx, agencyID, conv.ID, message)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":      result.Action,
+		"result":      applied,
+		"explanation": result.Explanation,
+		"summary":     summary,
+	})
+}
+
+// goalProposal lists the goal changes of an AI refinement. Changes to goals
+// that do not exist are left out.
+func goalProposal(agencyID, userMessage string, existingGoals []*agency.Goal, result *builder.RefineGoalsResponse) *Proposal {
+	proposal := &Proposal{
+		AgencyID:    agencyID,
+		Entity:      ProposalEntityGoals,
+		Operation:   result.Action,
+		UserMessage: userMessage,
+		Explanation: result.Explanation,
+		Changes:     []ProposedChange{},
+	}
+	if result.NoActionNeeded {
+		return proposal
+	}
+
+	goals := make(map[string]*agency.Goal, len(existingGoals))
+	for _, goal := range existingGoals {
+		goals[goal.Key] = goal
+	}
+
+	for _, rg := range result.RefinedGoals {
+		goal, exists := goals[rg.OriginalKey]
+		if !rg.WasChanged || !exists {
+			continue
+		}
+		code := goal.Code
+		if rg.SuggestedCode != "" {
+			code = rg.SuggestedCode
+		}
+		update := refinedGoalUpdate(goal, code, rg)
+		proposal.Changes = append(proposal.Changes, ProposedChange{
+			Action:      ProposedActionUpdate,
+			Key:         goal.Key,
+			Code:        goal.Code,
+			Before:      goalFields(goal.Code, goal.Description, goal.Scope),
+			After:       goalFields(update.Code, update.Description, update.Scope),
+			Explanation: rg.Explanation,
+			goal:        &update,
+		})
+	}
+
+	for _, g := range result.GeneratedGoals {
+		proposal.Changes = append(proposal.Changes, proposedGoal(agency.UpdateGoalRequest{
+			Code:           g.SuggestedCode,
+			Description:    g.Description,
+			Scope:          g.Scope,
+			SuccessMetrics: g.SuccessMetrics,
+			Priority:       g.SuggestedPriority,
+			Category:       g.SuggestedCategory,
+			Tags:           g.SuggestedTags,
+		}, g.Explanation))
+	}
+
+	if data := result.ConsolidatedData; data != nil {
+		for _, g := range data.ConsolidatedGoals {
+			proposal.Changes = append(proposal.Changes, proposedGoal(agency.UpdateGoalRequest{
+				Code:           g.SuggestedCode,
+				Description:    g.Description,
+				Scope:          g.Scope,
+				SuccessMetrics: g.SuccessMetrics,
+				Priority:       g.SuggestedPriority,
+				Category:       g.SuggestedCategory,
+				Tags:           g.SuggestedTags,
+			}, g.Rationale))
+		}
+
+		// Only goals the AI explicitly removed are deleted
+		removed := make(map[string]bool, len(data.RemovedGoals))
+		for _, key := range data.RemovedGoals {
+			goal, exists := goals[key]
+			if !exists || removed[key] {
+				continue
+			}
+			removed[key] = true
+			proposal.Changes = append(proposal.Changes, ProposedChange{
+				Action: ProposedActionDelete,
+				Key:    goal.Key,
+				Code:   goal.Code,
+				Before: goalFields(goal.Code, goal.Description, goal.Scope),
+			})
+		}
+	}
+
+	return proposal
+}
+
+// proposedGoal proposes creating a goal
+func proposedGoal(req agency.UpdateGoalRequest, explanation string) ProposedChange {
+	return ProposedChange{
+		Action:      ProposedActionCreate,
+		Code:        req.Code,
+		After:       goalFields(req.Code, req.Description, req.Scope),
+		Explanation: explanation,
+		goal:        &req,
+	}
+}
+
+func goalFields(code, description, scope string) map[string]interface{} {
+	fields := map[string]interface{}{"code": code, "description": description}
+	if scope != "" {
+		fields["scope"] = scope
+	}
+	return fields
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/aosanya/CodeValdCortex/internal/agency"
 	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/aosanya/CodeValdCortex/internal/web/pages/agency_designer"
 	"github.com/gin-gonic/gin"
@@ -197,13 +199,27 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 								goalCode = rg.SuggestedCode
 							}
 
-							// Update the goal in the database
-							updateErr := h.agencyService.UpdateGoal(ctx, agencyID, goal.Key, goalCode, rg.RefinedDescription)
+							// Update the goal in the database, keeping any field the AI left empty
+							changeCtx, notify := h.withGoalChange(ctx, agencyID, "updated", map[string]interface{}{"code": goalCode, "description": rg.RefinedDescription})
+							updateErr := h.agencyService.UpdateGoalFull(changeCtx, agencyID, goal.Key, refinedGoalUpdate(goal, goalCode, rg))
 							if updateErr != nil {
 								h.logger.WithError(updateErr).Error("Failed to update refined goal", "goalKey", goal.Key)
 							} else {
+								notify()
 								updatedCount++
 								h.logger.Info("Successfully updated refined goal", "goalKey", goal.Key, "newCode", goalCode)
+								h.recordExplanation(ctx, &agency.Explanation{
+									AgencyID:       agencyID,
+									EntityType:     agency.ExplanationEntityGoal,
+									EntityKey:      goal.Key,
+									EntityCode:     goalCode,
+									Action:         agency.ExplanationActionUpdated,
+									Operation:      result.Action,
+									UserMessage:    userRequest,
+									ConversationID: conv.ID,
+									Before:         map[string]interface{}{"code": goal.Code, "description": goal.Description},
+									After:          map[string]interface{}{"code": goalCode, "description": rg.RefinedDescription},
+								}, rg.Explanation, result.Explanation)
 							}
 							break
 						}
@@ -225,13 +241,26 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 
 			// Create each generated goal
 			for _, gGoal := range result.GeneratedGoals {
-				createdGoal, createErr := h.agencyService.CreateGoal(ctx, agencyID, gGoal.SuggestedCode, gGoal.Description)
+				changeCtx, notify := h.withGoalChange(ctx, agencyID, "created", map[string]interface{}{"code": gGoal.SuggestedCode, "description": gGoal.Description})
+				createdGoal, createErr := h.agencyService.CreateGoal(changeCtx, agencyID, gGoal.SuggestedCode, gGoal.Description)
 				if createErr != nil {
 					h.logger.WithError(createErr).Error("Failed to create generated goal", "goalCode", gGoal.SuggestedCode)
 				} else {
+					notify()
 					createdCount++
 					goalsList = append(goalsList, fmt.Sprintf("**%s**: %s", createdGoal.Code, createdGoal.Description))
 					h.logger.Info("Successfully created generated goal", "goalKey", createdGoal.Key, "goalCode", createdGoal.Code)
+					h.recordExplanation(ctx, &agency.Explanation{
+						AgencyID:       agencyID,
+						EntityType:     agency.ExplanationEntityGoal,
+						EntityKey:      createdGoal.Key,
+						EntityCode:     createdGoal.Code,
+						Action:         agency.ExplanationActionCreated,
+						Operation:      result.Action,
+						UserMessage:    userRequest,
+						ConversationID: conv.ID,
+						After:          map[string]interface{}{"code": createdGoal.Code, "description": createdGoal.Description},
+					}, gGoal.Explanation, result.Explanation)
 				}
 			}
 
@@ -248,37 +277,63 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 		}
 
 	case "remove":
-		// Handle goal removal - actually delete the goals from the database
+		// Handle goal removal - delete the goals in one undoable consolidation
 		if result.ConsolidatedData != nil && len(result.ConsolidatedData.RemovedGoals) > 0 {
-			deletedCount := 0
-			deletedCodes := []string{}
-
-			// Delete each goal
+			// Keys the AI made up are ignored
+			changeCtx := ctx
+			var removeKeys []string
+			var notifications []func()
 			for _, goalKey := range result.ConsolidatedData.RemovedGoals {
-				// Find the goal to get its code for the response message
 				for _, goal := range existingGoals {
 					if goal.Key == goalKey {
-						deleteErr := h.agencyService.DeleteGoal(ctx, agencyID, goalKey)
-						if deleteErr != nil {
-							h.logger.WithError(deleteErr).Error("Failed to delete goal", "goalKey", goalKey, "goalCode", goal.Code)
-						} else {
-							deletedCount++
-							deletedCodes = append(deletedCodes, goal.Code)
-							h.logger.Info("Successfully deleted goal", "goalKey", goalKey, "goalCode", goal.Code)
-						}
+						var notify func()
+						changeCtx, notify = h.withGoalChange(changeCtx, agencyID, "deleted", map[string]interface{}{"code": goal.Code})
+						notifications = append(notifications, notify)
+						removeKeys = append(removeKeys, goalKey)
 						break
 					}
 				}
 			}
+			if len(removeKeys) == 0 {
+				responseMessage = fmt.Sprintf("❌ **Failed to Remove Goals**\n\n%s", result.Explanation)
+				break
+			}
 
-			if deletedCount > 0 {
-				responseMessage = fmt.Sprintf("🗑️ **Removed %d Goal(s)**\n\n**Removed**: %s\n\n%s",
-					deletedCount,
-					strings.Join(deletedCodes, ", "),
-					result.Explanation)
-			} else {
+			consolidation, removeErr := h.agencyService.ConsolidateGoals(changeCtx, agencyID, agency.ConsolidateGoalsRequest{
+				RemoveKeys: removeKeys,
+				Operation:  result.Action,
+			})
+			if removeErr != nil {
+				h.logger.WithError(removeErr).Error("Failed to remove goals", "goalKeys", removeKeys)
 				responseMessage = fmt.Sprintf("❌ **Failed to Remove Goals**\n\n%s", result.Explanation)
+				break
 			}
+
+			for _, notify := range notifications {
+				notify()
+			}
+			deletedCodes := make([]string, 0, len(consolidation.Before))
+			for _, goal := range consolidation.Before {
+				deletedCodes = append(deletedCodes, goal.Code)
+				h.logger.Info("Successfully deleted goal", "goalKey", goal.Key, "goalCode", goal.Code)
+				h.recordExplanation(ctx, &agency.Explanation{
+					AgencyID:       agencyID,
+					EntityType:     agency.ExplanationEntityGoal,
+					EntityKey:      goal.Key,
+					EntityCode:     goal.Code,
+					Action:         agency.ExplanationActionRemoved,
+					Operation:      result.Action,
+					UserMessage:    userRequest,
+					ConversationID: conv.ID,
+					Before:         map[string]interface{}{"code": goal.Code, "description": goal.Description},
+				}, "", result.Explanation)
+			}
+
+			responseMessage = fmt.Sprintf("🗑️ **Removed %d Goal(s)**\n\n**Removed**: %s\n\n%s\n\n↩️ Undo with consolidation `%s`.",
+				len(deletedCodes),
+				strings.Join(deletedCodes, ", "),
+				result.Explanation,
+				consolidation.Key)
 		} else {
 			responseMessage = "ℹ️ " + result.Explanation
 		}
@@ -316,9 +371,7 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 		"no_action", result.NoActionNeeded)
 
 	// Add AI response to conversation
-	if addErr := h.designerService.AddMessage(conv.ID, "assistant", responseMessage); addErr != nil {
-		h.logger.WithError(addErr).Error("Failed to add AI response to conversation")
-	}
+	delivered := h.addAssistantMessage(ctx, agencyID, conv.ID, responseMessage)
 
 	// Render chat messages (user + assistant)
 	c.Header("Content-Type", "text/html")
@@ -331,9 +384,19 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 		return
 	}
 
-	// Render last 2 messages (user + assistant)
+	// Render last 2 messages (user + assistant). A response still waiting in
+	// the outbox is rendered directly and joins the conversation on retry.
 	messageCount := len(updatedConv.Messages)
-	if messageCount >= 2 {
+	if !delivered && messageCount >= 1 {
+		userMsg := &updatedConv.Messages[messageCount-1]
+		if renderErr := agency_designer.UserMessage(*userMsg).Render(ctx, c.Writer); renderErr != nil {
+			h.logger.WithError(renderErr).Error("Failed to render user message")
+		}
+		aiMsg := builder.Message{Role: "assistant", Content: responseMessage, Timestamp: time.Now()}
+		if renderErr := agency_designer.AIMessage(aiMsg).Render(ctx, c.Writer); renderErr != nil {
+			h.logger.WithError(renderErr).Error("Failed to render AI message")
+		}
+	} else if messageCount >= 2 {
 		// Render user message
 		userMsg := &updatedConv.Messages[messageCount-2]
 		if renderErr := agency_designer.UserMessage(*userMsg).Render(ctx, c.Writer); renderErr != nil {
@@ -350,3 +413,34 @@ func (h *Handler) ProcessGoalChatRequest(c *gin.Context) {
 		"agencyID", agencyID,
 		"action", result.Action)
 }
+
+// refinedGoalUpdate builds a full update from an AI refinement. Fields the
+// refinement left empty keep the goal's current values.
+func refinedGoalUpdate(goal *agency.Goal, code string, rg builder.RefinedGoalResult) agency.UpdateGoalRequest {
+	req := agency.UpdateGoalRequest{
+		Code:           code,
+		Description:    rg.RefinedDescription,
+		Scope:          goal.Scope,
+		SuccessMetrics: goal.SuccessMetrics,
+		Priority:       goal.Priority,
+		Status:         goal.Status,
+		Category:       goal.Category,
+		Tags:           goal.Tags,
+	}
+	if rg.RefinedScope != "" {
+		req.Scope = rg.RefinedScope
+	}
+	if len(rg.RefinedMetrics) > 0 {
+		req.SuccessMetrics = rg.RefinedMetrics
+	}
+	if rg.SuggestedPriority != "" {
+		req.Priority = rg.SuggestedPriority
+	}
+	if rg.SuggestedCategory != "" {
+		req.Category = rg.SuggestedCategory
+	}
+	if len(rg.SuggestedTags) > 0 {
+		req.Tags = rg.SuggestedTags
+	}
+	return req
+}
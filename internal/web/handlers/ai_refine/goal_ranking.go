@@ -0,0 +1,46 @@
+package ai_refine
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/gin-gonic/gin"
+)
+
+// RankGoals handles POST /api/v1/agencies/:id/goals/rank
+// Returns a recommended priority ordering of all goals with a rationale per
+// goal. Nothing is saved until the ordering is accepted through
+// PUT /api/v1/agencies/:id/goals/priorities.
+func (h *Handler) RankGoals(c *gin.Context) {
+	agencyID := c.Param("id")
+
+	ag, err := h.agencyService.GetAgency(c.Request.Context(), agencyID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to fetch agency")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agency not found"})
+		return
+	}
+
+	overview, err := h.agencyService.GetAgencyOverview(c.Request.Context(), agencyID)
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to fetch overview")
+		overview = &agency.Overview{AgencyID: agencyID}
+	}
+
+	builderContext, err := h.contextBuilder.BuildBuilderContext(c.Request.Context(), ag, overview.Introduction, "Rank all goals by priority")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to build context")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to gather agency context for AI processing"})
+		return
+	}
+
+	result, err := h.goalRefiner.RankGoals(c.Request.Context(), &builder.RankGoalsRequest{AgencyID: agencyID}, builderContext)
+	if err != nil {
+		h.logger.WithError(err).Error("AI goal ranking failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "The AI service failed to rank the goals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,161 @@
+package ai_refine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamGoalRefinement handles GET /api/v1/agencies/:id/goals/refine-stream
+// Runs a dynamic goal operation from the designer chat and streams the
+// model's output as server-sent events while it is generated:
+//   - "chunk" events carry {"text": ...} as tokens arrive
+//   - "result" carries the applied changes, or "proposal" when preview=true
+//   - "error" ends the stream when the operation fails
+//
+// The message is taken from the "message" query parameter; "goal_keys" is an
+// optional comma-separated list of goals to operate on.
+func (h *Handler) StreamGoalRefinement(c *gin.Context) {
+	agencyID := c.Param("id")
+	ctx := c.Request.Context()
+
+	userMessage := strings.TrimSpace(c.Query("message"))
+	if userMessage == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message is required"})
+		return
+	}
+	var goalKeys []string
+	if keys := c.Query("goal_keys"); keys != "" {
+		goalKeys = strings.Split(keys, ",")
+	}
+	preview := c.Query("preview") == "true"
+
+	ag, err := h.agencyService.GetAgency(ctx, agencyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agency not found"})
+		return
+	}
+
+	conv, err := h.designerService.GetConversationByAgencyID(agencyID)
+	if err != nil {
+		conv, err = h.designerService.StartConversation(ctx, agencyID)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to create conversation")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize conversation"})
+			return
+		}
+	}
+	if err := h.designerService.AddMessage(conv.ID, "user", userMessage); err != nil {
+		h.logger.WithError(err).Error("Failed to add user message to conversation")
+	}
+
+	builderContext, err := h.contextBuilder.BuildBuilderContext(ctx, ag, "", userMessage)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to build context")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to gather agency context"})
+		return
+	}
+
+	existingGoals := builderContext.Goals
+	var targetGoals []*agency.Goal
+	if len(goalKeys) > 0 {
+		selected := make(map[string]bool, len(goalKeys))
+		for _, key := range goalKeys {
+			selected[strings.TrimSpace(key)] = true
+		}
+		for _, goal := range existingGoals {
+			if selected[goal.Key] {
+				targetGoals = append(targetGoals, goal)
+			}
+		}
+	}
+
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.WithError(err).Debug("Could not clear write deadline for goal stream")
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	result, err := h.goalRefiner.RefineGoalsStream(ctx, &builder.RefineGoalsRequest{
+		AgencyID:      agencyID,
+		UserMessage:   userMessage,
+		TargetGoals:   targetGoals,
+		ExistingGoals: existingGoals,
+		WorkItems:     builderContext.WorkItems,
+		AgencyContext: ag,
+	}, builderContext, func(chunk string) error {
+		// Stop generating once the browser has gone away
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h.writeStreamEvent(c, "chunk", gin.H{"text": chunk})
+		return nil
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Streamed goal refinement failed")
+		h.writeStreamEvent(c, "error", gin.H{"error": "AI processing failed"})
+		return
+	}
+
+	proposal := goalProposal(agencyID, userMessage, existingGoals, result)
+
+	h.logger.WithFields(logrus.Fields{
+		"agency_id": agencyID,
+		"action":    result.Action,
+		"changes":   len(proposal.Changes),
+		"preview":   preview,
+	}).Info("Streamed goal refinement completed")
+
+	if preview {
+		h.proposals.Save(proposal)
+		h.writeStreamEvent(c, "proposal", gin.H{
+			"proposal":    proposal,
+			"explanation": result.Explanation,
+			"summary":     h.buildSummaryMessage(result),
+		})
+		return
+	}
+
+	applied := h.applyProposal(ctx, proposal)
+	summary := applied.Summary("goal", "goals")
+
+	message := summary
+	if result.Explanation != "" {
+		message += "\n\n" + result.Explanation
+	}
+	if applied.ConsolidationKey != "" {
+		message += "\n\n↩️ Undo with consolidation `" + applied.ConsolidationKey + "`."
+	}
+	h.addAssistantMessage(ctx, agencyID, conv.ID, message)
+
+	h.writeStreamEvent(c, "result", gin.H{
+		"action":      result.Action,
+		"result":      applied,
+		"explanation": result.Explanation,
+		"summary":     summary,
+	})
+}
+
+// writeStreamEvent writes and flushes a server-sent event with a JSON payload
+func (h *Handler) writeStreamEvent(c *gin.Context, event string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to encode stream event")
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, data)
+	c.Writer.Flush()
+}
@@ -2,7 +2,9 @@ package ai_refine
 
 import (
 	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
 	"github.com/aosanya/CodeValdCortex/internal/registry"
 	"github.com/aosanya/CodeValdCortex/internal/workflow"
 	"github.com/sirupsen/logrus"
@@ -21,6 +23,8 @@ type Handler struct {
 	workflowBuilder     *ai.WorkflowsBuilder
 	designerService     *ai.AgencyDesignerService
 	contextBuilder      *BuilderContextBuilder
+	outbox              *outbox.Dispatcher
+	proposals           *proposalStore
 	logger              *logrus.Logger
 }
 
@@ -53,6 +57,12 @@ func NewHandler(
 		workflowBuilder:     workflowBuilder,
 		designerService:     designerService,
 		contextBuilder:      contextBuilder,
+		proposals:           newProposalStore(proposalTTL),
 		logger:              logger,
 	}
 }
+
+// SetInfrastructureSource enables topology and KPI injection into AI prompt context
+func (h *Handler) SetInfrastructureSource(source builder.InfrastructureSource) {
+	h.contextBuilder.SetInfrastructureSource(source)
+}
@@ -0,0 +1,70 @@
+package ai_refine
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/outbox"
+)
+
+// KindChatMessage entries add a message to a designer conversation
+const KindChatMessage = "designer.chat_message"
+
+// SetOutbox routes the chat messages and change webhooks produced by chat
+// requests through the outbox so they fire once per committed change
+func (h *Handler) SetOutbox(dispatcher *outbox.Dispatcher) {
+	h.outbox = dispatcher
+	dispatcher.Register(KindChatMessage, h.deliverChatMessage)
+}
+
+// deliverChatMessage adds a chat message entry to its conversation, using
+// the entry ID as message ID so redelivery does not duplicate it
+func (h *Handler) deliverChatMessage(ctx context.Context, entry *outbox.Entry) error {
+	conversationID, _ := entry.Payload["conversation_id"].(string)
+	role, _ := entry.Payload["role"].(string)
+	content, _ := entry.Payload["content"].(string)
+
+	err := h.designerService.AddMessageOnce(conversationID, entry.ID, role, content)
+	if err != nil && strings.Contains(err.Error(), "conversation not found") {
+		// Conversations are kept in memory and do not survive a restart
+		return outbox.Permanent(err)
+	}
+	return err
+}
+
+// addAssistantMessage adds the assistant response to the conversation and
+// reports whether it is already there. Without an outbox the message is
+// added directly; with one it is retried until delivered.
+func (h *Handler) addAssistantMessage(ctx context.Context, agencyID, conversationID, content string) bool {
+	if h.outbox == nil {
+		if err := h.designerService.AddMessage(conversationID, "assistant", content); err != nil {
+			h.logger.WithError(err).Error("Failed to add AI response to conversation")
+			return false
+		}
+		return true
+	}
+
+	entry := outbox.NewEntry(agencyID, KindChatMessage, map[string]interface{}{
+		"conversation_id": conversationID,
+		"role":            "assistant",
+		"content":         content,
+	})
+	if err := h.outbox.Enqueue(ctx, entry); err != nil {
+		h.logger.WithError(err).Error("Failed to enqueue AI response")
+		return false
+	}
+	return entry.Status == outbox.StatusDelivered
+}
+
+// withGoalChange attaches change webhooks for a goal event to ctx. The
+// returned function delivers them once the change has been committed.
+func (h *Handler) withGoalChange(ctx context.Context, agencyID, event string, data map[string]interface{}) (context.Context, func()) {
+	if h.outbox == nil {
+		return ctx, func() {}
+	}
+
+	entries := h.outbox.ChangeNotifications(agencyID, "agency.goal."+event, data)
+	return outbox.WithEntries(ctx, entries...), func() {
+		go h.outbox.Deliver(context.WithoutCancel(ctx), entries...)
+	}
+}
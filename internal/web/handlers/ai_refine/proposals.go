@@ -0,0 +1,324 @@
+package ai_refine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// proposalTTL is how long a previewed proposal can be confirmed
+const proposalTTL = time.Hour
+
+// Proposal entities
+const (
+	ProposalEntityGoals     = "goals"
+	ProposalEntityWorkItems = "work_items"
+)
+
+// Proposed change actions
+const (
+	ProposedActionCreate = "create"
+	ProposedActionUpdate = "update"
+	ProposedActionDelete = "delete"
+)
+
+// Proposal holds the changes an AI operation would make. Preview requests
+// return it without persisting anything; confirming it applies the changes.
+type Proposal struct {
+	ID          string           `json:"id"`
+	AgencyID    string           `json:"agency_id"`
+	Entity      string           `json:"entity"`    // goals or work_items
+	Operation   string           `json:"operation"` // AI action, e.g. refine, generate or consolidate
+	UserMessage string           `json:"user_message,omitempty"`
+	Explanation string           `json:"explanation,omitempty"`
+	Changes     []ProposedChange `json:"changes"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+}
+
+// ProposedChange is a single creation, edit or deletion. Before and After
+// show the affected fields; Key is empty for creations.
+type ProposedChange struct {
+	Action      string                 `json:"action"`
+	Key         string                 `json:"key,omitempty"`
+	Code        string                 `json:"code,omitempty"`
+	Before      map[string]interface{} `json:"before,omitempty"`
+	After       map[string]interface{} `json:"after,omitempty"`
+	Explanation string                 `json:"explanation,omitempty"`
+
+	// Exactly one payload is set for creations and edits
+	goal           *agency.UpdateGoalRequest
+	createWorkItem *agency.CreateWorkItemRequest
+	updateWorkItem *agency.UpdateWorkItemRequest
+}
+
+// ProposalResult reports the changes applied from a proposal
+type ProposalResult struct {
+	ProposalID string   `json:"proposal_id"`
+	Created    int      `json:"created"`
+	Updated    int      `json:"updated"`
+	Deleted    int      `json:"deleted"`
+	Errors     []string `json:"errors,omitempty"`
+
+	// ConsolidationKey identifies the goal consolidation that removed goals,
+	// so that the removal can be undone
+	ConsolidationKey string `json:"consolidation_key,omitempty"`
+}
+
+// Summary describes the applied changes in one line
+func (r *ProposalResult) Summary(singular, plural string) string {
+	var parts []string
+	if r.Created > 0 {
+		parts = append(parts, "✓ Created "+pluralize(r.Created, singular, plural))
+	}
+	if r.Updated > 0 {
+		parts = append(parts, "✓ Updated "+pluralize(r.Updated, singular, plural))
+	}
+	if r.Deleted > 0 {
+		parts = append(parts, "✓ Removed "+pluralize(r.Deleted, singular, plural))
+	}
+	if len(parts) == 0 {
+		return "✓ No changes applied"
+	}
+	return strings.Join(parts, " • ")
+}
+
+// proposalStore keeps previewed proposals in memory until they are
+// confirmed or expire
+type proposalStore struct {
+	mu        sync.Mutex
+	proposals map[string]*Proposal
+	ttl       time.Duration
+	now       func() time.Time
+}
+
+func newProposalStore(ttl time.Duration) *proposalStore {
+	return &proposalStore{
+		proposals: make(map[string]*Proposal),
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// Save assigns the proposal an ID and expiry and stores it. Expired
+// proposals are dropped.
+func (s *proposalStore) Save(proposal *Proposal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, p := range s.proposals {
+		if now.After(p.ExpiresAt) {
+			delete(s.proposals, id)
+		}
+	}
+
+	proposal.ID = uuid.New().String()
+	proposal.CreatedAt = now
+	proposal.ExpiresAt = now.Add(s.ttl)
+	s.proposals[proposal.ID] = proposal
+}
+
+// Take removes and returns an unexpired proposal of the agency, so that a
+// proposal is applied at most once
+func (s *proposalStore) Take(agencyID, id string) (*Proposal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	proposal, exists := s.proposals[id]
+	if !exists || proposal.AgencyID != agencyID {
+		return nil, false
+	}
+	delete(s.proposals, id)
+	if s.now().After(proposal.ExpiresAt) {
+		return nil, false
+	}
+	return proposal, true
+}
+
+// ConfirmProposal handles POST /api/v1/agencies/:id/ai/proposals/:proposalId/confirm
+// Applies a proposal returned by a preview request
+func (h *Handler) ConfirmProposal(c *gin.Context) {
+	agencyID := c.Param("id")
+	proposalID := c.Param("proposalId")
+
+	proposal, ok := h.proposals.Take(agencyID, proposalID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proposal not found or expired"})
+		return
+	}
+
+	result := h.applyProposal(c.Request.Context(), proposal)
+
+	h.logger.WithFields(logrus.Fields{
+		"agency_id":   agencyID,
+		"proposal_id": proposalID,
+		"entity":      proposal.Entity,
+		"created":     result.Created,
+		"updated":     result.Updated,
+		"deleted":     result.Deleted,
+		"errors":      len(result.Errors),
+	}).Info("Applied AI proposal")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// applyProposal applies a proposal's changes. Failed changes are reported
+// in the result and do not stop the remaining ones.
+func (h *Handler) applyProposal(ctx context.Context, proposal *Proposal) *ProposalResult {
+	result := &ProposalResult{ProposalID: proposal.ID}
+	if proposal.Entity == ProposalEntityWorkItems {
+		h.applyWorkItemChanges(ctx, proposal, result)
+	} else {
+		h.applyGoalChanges(ctx, proposal, result)
+	}
+	return result
+}
+
+// applyGoalChanges applies goal edits, then replaces deleted goals with the
+// created ones in a single undoable consolidation. Without deletions the
+// goals are created one by one.
+func (h *Handler) applyGoalChanges(ctx context.Context, proposal *Proposal, result *ProposalResult) {
+	explanation := func(change ProposedChange, key, code string, action agency.ExplanationAction) {
+		h.recordExplanation(ctx, &agency.Explanation{
+			AgencyID:    proposal.AgencyID,
+			EntityType:  agency.ExplanationEntityGoal,
+			EntityKey:   key,
+			EntityCode:  code,
+			Action:      action,
+			Operation:   proposal.Operation,
+			UserMessage: proposal.UserMessage,
+			Before:      change.Before,
+			After:       change.After,
+		}, change.Explanation, proposal.Explanation)
+	}
+
+	var creates, deletes []ProposedChange
+	for _, change := range proposal.Changes {
+		switch change.Action {
+		case ProposedActionCreate:
+			creates = append(creates, change)
+		case ProposedActionDelete:
+			deletes = append(deletes, change)
+		case ProposedActionUpdate:
+			changeCtx, notify := h.withGoalChange(ctx, proposal.AgencyID, "updated", change.After)
+			if err := h.agencyService.UpdateGoalFull(changeCtx, proposal.AgencyID, change.Key, *change.goal); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("update %s: %v", change.Code, err))
+				continue
+			}
+			notify()
+			result.Updated++
+			explanation(change, change.Key, change.goal.Code, agency.ExplanationActionUpdated)
+		}
+	}
+
+	if len(deletes) > 0 {
+		req := agency.ConsolidateGoalsRequest{Operation: proposal.Operation}
+		changeCtx := ctx
+		var notifications []func()
+		for _, change := range deletes {
+			var notify func()
+			changeCtx, notify = h.withGoalChange(changeCtx, proposal.AgencyID, "deleted", map[string]interface{}{"code": change.Code})
+			notifications = append(notifications, notify)
+			req.RemoveKeys = append(req.RemoveKeys, change.Key)
+		}
+		for _, change := range creates {
+			var notify func()
+			changeCtx, notify = h.withGoalChange(changeCtx, proposal.AgencyID, "created", change.After)
+			notifications = append(notifications, notify)
+			req.Goals = append(req.Goals, agency.CreateGoalRequest(*change.goal))
+		}
+
+		consolidation, err := h.agencyService.ConsolidateGoals(changeCtx, proposal.AgencyID, req)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("consolidate goals: %v", err))
+			return
+		}
+		for _, notify := range notifications {
+			notify()
+		}
+		result.ConsolidationKey = consolidation.Key
+		for i, goal := range consolidation.Before {
+			result.Deleted++
+			explanation(deletes[i], goal.Key, goal.Code, agency.ExplanationActionRemoved)
+		}
+		for i, goal := range consolidation.After {
+			result.Created++
+			explanation(creates[i], goal.Key, goal.Code, agency.ExplanationActionCreated)
+		}
+		return
+	}
+
+	for _, change := range creates {
+		changeCtx, notify := h.withGoalChange(ctx, proposal.AgencyID, "created", change.After)
+		goal, err := h.agencyService.CreateGoal(changeCtx, proposal.AgencyID, change.goal.Code, change.goal.Description)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("create %s: %v", change.Code, err))
+			continue
+		}
+		notify()
+		result.Created++
+
+		// CreateGoal only takes the code and description
+		req := *change.goal
+		req.Code = goal.Code
+		if err := h.agencyService.UpdateGoalFull(ctx, proposal.AgencyID, goal.Key, req); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("complete %s: %v", goal.Code, err))
+		}
+		explanation(change, goal.Key, goal.Code, agency.ExplanationActionCreated)
+	}
+}
+
+// applyWorkItemChanges applies work item deletions, edits and creations
+func (h *Handler) applyWorkItemChanges(ctx context.Context, proposal *Proposal, result *ProposalResult) {
+	explanation := func(change ProposedChange, key, code string, action agency.ExplanationAction) {
+		h.recordExplanation(ctx, &agency.Explanation{
+			AgencyID:    proposal.AgencyID,
+			EntityType:  agency.ExplanationEntityWorkItem,
+			EntityKey:   key,
+			EntityCode:  code,
+			Action:      action,
+			Operation:   proposal.Operation,
+			UserMessage: proposal.UserMessage,
+			Before:      change.Before,
+			After:       change.After,
+		}, change.Explanation, proposal.Explanation)
+	}
+
+	for _, change := range proposal.Changes {
+		switch change.Action {
+		case ProposedActionDelete:
+			if err := h.agencyService.DeleteWorkItem(ctx, proposal.AgencyID, change.Key); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("delete %s: %v", change.Code, err))
+				continue
+			}
+			result.Deleted++
+			explanation(change, change.Key, change.Code, agency.ExplanationActionRemoved)
+
+		case ProposedActionUpdate:
+			if err := h.agencyService.UpdateWorkItem(ctx, proposal.AgencyID, change.Key, *change.updateWorkItem); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("update %s: %v", change.Code, err))
+				continue
+			}
+			result.Updated++
+			explanation(change, change.Key, change.Code, agency.ExplanationActionUpdated)
+
+		case ProposedActionCreate:
+			workItem, err := h.agencyService.CreateWorkItem(ctx, proposal.AgencyID, *change.createWorkItem)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("create %q: %v", change.createWorkItem.Title, err))
+				continue
+			}
+			result.Created++
+			explanation(change, workItem.Key, workItem.Code, agency.ExplanationActionCreated)
+		}
+	}
+}
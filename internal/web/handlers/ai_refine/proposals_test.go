@@ -0,0 +1,78 @@
+package ai_refine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
+)
+
+func TestGoalProposal(t *testing.T) {
+	existing := []*agency.Goal{
+		{Key: "g1", Code: "G001", Description: "Reduce response times", Priority: "High"},
+		{Key: "g2", Code: "G002", Description: "Respond faster"},
+	}
+	result := &builder.RefineGoalsResponse{
+		Action: "consolidate",
+		RefinedGoals: []builder.RefinedGoalResult{
+			{OriginalKey: "g1", RefinedDescription: "Cut median response time to 1h", WasChanged: true},
+			{OriginalKey: "missing", RefinedDescription: "Ignored", WasChanged: true},
+			{OriginalKey: "g2", RefinedDescription: "Unchanged"},
+		},
+		ConsolidatedData: &builder.ConsolidateGoalsResponse{
+			ConsolidatedGoals: []builder.ConsolidatedGoal{{SuggestedCode: "G003", Description: "Faster support"}},
+			RemovedGoals:      []string{"g2", "g2", "made-up"},
+		},
+	}
+
+	proposal := goalProposal("agency-1", "merge duplicates", existing, result)
+
+	var actions []string
+	for _, change := range proposal.Changes {
+		actions = append(actions, change.Action+":"+change.Code)
+	}
+	want := []string{"update:G001", "create:G003", "delete:G002"}
+	if len(actions) != len(want) {
+		t.Fatalf("expected changes %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("expected changes %v, got %v", want, actions)
+		}
+	}
+
+	// Fields the refinement left empty keep their values
+	if update := proposal.Changes[0].goal; update.Priority != "High" || update.Description != "Cut median response time to 1h" {
+		t.Errorf("unexpected update %+v", update)
+	}
+}
+
+func TestProposalStoreTake(t *testing.T) {
+	store := newProposalStore(time.Hour)
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	proposal := &Proposal{AgencyID: "agency-1", Entity: ProposalEntityGoals}
+	store.Save(proposal)
+	if proposal.ID == "" || !proposal.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected an ID and expiry, got %+v", proposal)
+	}
+
+	if _, ok := store.Take("agency-2", proposal.ID); ok {
+		t.Error("expected another agency's proposal to be rejected")
+	}
+	if _, ok := store.Take("agency-1", proposal.ID); !ok {
+		t.Fatal("expected the proposal to be found")
+	}
+	if _, ok := store.Take("agency-1", proposal.ID); ok {
+		t.Error("expected a proposal to be taken only once")
+	}
+
+	expired := &Proposal{AgencyID: "agency-1"}
+	store.Save(expired)
+	now = now.Add(2 * time.Hour)
+	if _, ok := store.Take("agency-1", expired.ID); ok {
+		t.Error("expected an expired proposal to be rejected")
+	}
+}
@@ -2,8 +2,12 @@ package ai_refine
 
 import (
 	"fmt"
+	"html"
 	"net/http"
+	"strings"
 
+	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -47,7 +51,7 @@ func (h *Handler) ProcessRoleChatRequest(c *gin.Context) {
 
 	// Get agency context
 	ctx := c.Request.Context()
-	_, err := h.agencyService.GetAgency(ctx, agencyID)
+	ag, err := h.agencyService.GetAgency(ctx, agencyID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to fetch agency")
 		c.Header("Content-Type", "text/html")
@@ -96,18 +100,11 @@ func (h *Handler) ProcessRoleChatRequest(c *gin.Context) {
 		h.logger.WithError(err).Error("Failed to add user message to conversation")
 	}
 
-	// For now, return a placeholder response until RefineRoles is implemented in the builder
-	responseMessage := fmt.Sprintf(`
-		<strong>Role Processing Request Received:</strong><br>
-		<em>%s</em><br><br>
-		<strong>Status:</strong> Role processing is under construction.<br>
-		<strong>Planned Capabilities:</strong><br>
-		• Refine specific roles<br>
-		• Generate new roles based on work items<br>
-		• Consolidate duplicate roles<br>
-		• Enhance all roles with AI analysis<br><br>
-		<em>This will be implemented once the RolesBuilder.RefineRoles method is complete.</em>
-	`, userRequest)
+	responseMessage, err := h.roleChatResponse(c, ag, userRequest)
+	if err != nil {
+		h.logger.WithError(err).Error("AI role processing failed")
+		responseMessage = "<strong>Role processing failed.</strong><br>The AI service encountered an error processing your request."
+	}
 
 	// Add AI response to conversation
 	if err := h.designerService.AddMessage(conv.ID, "assistant", responseMessage); err != nil {
@@ -131,3 +128,43 @@ func (h *Handler) ProcessRoleChatRequest(c *gin.Context) {
 
 	c.String(http.StatusOK, responseHTML)
 }
+
+// roleChatResponse asks the role builder to propose roles for a chat request
+// and describes the proposal as HTML
+func (h *Handler) roleChatResponse(c *gin.Context, ag *agency.Agency, userRequest string) (string, error) {
+	if h.roleBuilder == nil {
+		return "", fmt.Errorf("AI role builder is not available")
+	}
+
+	ctx := c.Request.Context()
+	builderContext, err := h.contextBuilder.BuildBuilderContext(ctx, ag, "", userRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to build context: %w", err)
+	}
+
+	result, err := h.roleBuilder.RefineRoles(ctx, &builder.RefineRolesRequest{
+		AgencyID:      ag.ID,
+		UserMessage:   userRequest,
+		ExistingRoles: builderContext.Roles,
+		WorkItems:     builderContext.WorkItems,
+		Goals:         builderContext.Goals,
+		AgencyContext: ag,
+	}, builderContext)
+	if err != nil {
+		return "", err
+	}
+
+	var message strings.Builder
+	if len(result.GeneratedRoles) == 0 {
+		message.WriteString("<strong>No new roles are needed.</strong><br>")
+	} else {
+		message.WriteString("<strong>Suggested roles:</strong><br>")
+		for _, role := range result.GeneratedRoles {
+			message.WriteString(fmt.Sprintf("• <strong>%s</strong>: %s<br>", html.EscapeString(role.Name), html.EscapeString(role.Description)))
+		}
+	}
+	if result.Explanation != "" {
+		message.WriteString("<br>" + html.EscapeString(result.Explanation))
+	}
+	return message.String(), nil
+}
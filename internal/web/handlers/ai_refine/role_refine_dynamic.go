@@ -7,9 +7,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// RefineRoles is the main dynamic router for all role operations
-// It analyzes the user's message to determine what action to take
-// and handles role refinement, generation, consolidation, and enhancement
+// RefineRoles is the main dynamic router for all role operations.
+// Several target roles are consolidated; otherwise new roles are generated.
+// The proposed roles are returned without being persisted.
 func (h *Handler) RefineRoles(c *gin.Context) {
 	agencyID := c.Param("id")
 	if agencyID == "" {
@@ -48,15 +48,23 @@ func (h *Handler) RefineRoles(c *gin.Context) {
 	if req.WorkItems == nil {
 		req.WorkItems = builderContext.WorkItems
 	}
+	if req.Goals == nil {
+		req.Goals = builderContext.Goals
+	}
 	if req.AgencyContext == nil {
 		req.AgencyContext = ag
 	}
 
-	// For now, return a placeholder response until RefineRoles is implemented
-	response := &builder.RefineRolesResponse{
-		Action:         "under_construction",
-		Explanation:    "Role processing is under construction. The following operations will be supported: refine specific roles, generate new roles based on work items, consolidate duplicate roles, enhance all roles with AI analysis.",
-		NoActionNeeded: false,
+	if h.roleBuilder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI role builder is not available"})
+		return
+	}
+
+	response, err := h.roleBuilder.RefineRoles(ctx, &req, builderContext)
+	if err != nil {
+		h.logger.WithError(err).Error("AI role processing failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "AI role processing failed"})
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -1,17 +1,21 @@
 package ai_refine
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 // RefineWorkItems handles POST /api/v1/agencies/:id/work-items/refine-dynamic
-// Dynamically determines and executes the appropriate work item operation based on user message
+// Dynamically determines and executes the appropriate work item operation based on user message.
+// One selected work item is refined, several are consolidated and otherwise new work items are
+// generated. With "preview" set, the proposal is returned as JSON and nothing is persisted.
 func (h *Handler) RefineWorkItems(c *gin.Context) {
 	agencyID := c.Param("id")
 
@@ -21,6 +25,7 @@ func (h *Handler) RefineWorkItems(c *gin.Context) {
 	var req struct {
 		UserMessage  string   `json:"user_message" binding:"required"` // Natural language instruction
 		WorkItemKeys []string `json:"work_item_keys"`                  // Optional: specific work items to operate on
+		Preview      bool     `json:"preview"`                         // Optional: return the proposed changes without applying them
 	}
 
 	// First, check if there's a preset request from wrapper methods
@@ -108,8 +113,9 @@ func (h *Handler) RefineWorkItems(c *gin.Context) {
 		"existing_work_items": len(existingWorkItems),
 	}).Info("Starting dynamic work item refinement")
 
-	// Build AI context data using shared context builder (will be used when RefineWorkItems is implemented)
-	_, err = h.contextBuilder.BuildBuilderContext(c.Request.Context(), ag, "", req.UserMessage)
+	// Build AI context data using shared context builder
+	ctx := c.Request.Context()
+	builderContext, err := h.contextBuilder.BuildBuilderContext(ctx, ag, "", req.UserMessage)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to build context")
 		c.Header("Content-Type", "text/html")
@@ -129,16 +135,57 @@ func (h *Handler) RefineWorkItems(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement RefineWorkItems method in WorkItemsBuilder
-	// For now, provide a simple response
-	h.logger.Info("Work item refinement placeholder - RefineWorkItems method not yet implemented")
+	proposal, err := h.workItemProposal(ctx, ag, req.UserMessage, targetWorkItems, existingWorkItems, builderContext)
+	if err != nil {
+		h.logger.WithError(err).Error("AI work item processing failed")
+		c.Header("Content-Type", "text/html")
+		c.String(http.StatusInternalServerError, `
+			<div class="notification is-danger">
+				<div class="is-flex is-align-items-center">
+					<span class="icon has-text-danger mr-2">
+						<i class="fas fa-exclamation-circle"></i>
+					</span>
+					<div>
+						<strong>AI Processing Failed</strong>
+						<p class="mb-0">The AI service encountered an error processing your request.</p>
+					</div>
+				</div>
+			</div>
+		`)
+		return
+	}
+
+	if req.Preview {
+		h.proposals.Save(proposal)
+		c.JSON(http.StatusOK, gin.H{
+			"preview":  true,
+			"proposal": proposal,
+		})
+		return
+	}
 
-	responseMessage := "🚧 **Work Item Processing**\n\nWork item dynamic processing is being implemented. This will support:\n\n• Refining existing work items for clarity and completeness\n• Generating new work items based on goals\n• Consolidating duplicate or overlapping work items\n• Removing unnecessary work items\n\nRequest received: " + req.UserMessage
+	applied := h.applyProposal(ctx, proposal)
+
+	h.logger.WithFields(logrus.Fields{
+		"agency_id": agencyID,
+		"operation": proposal.Operation,
+		"created":   applied.Created,
+		"updated":   applied.Updated,
+		"deleted":   applied.Deleted,
+		"errors":    len(applied.Errors),
+	}).Info("Dynamic work item refinement completed")
+
+	responseMessage := applied.Summary("work item", "work items")
+	if proposal.Explanation != "" {
+		responseMessage += "\n\n" + proposal.Explanation
+	}
+	for _, applyErr := range applied.Errors {
+		responseMessage += "\n⚠️ " + applyErr
+	}
 
 	// Format response as bullets
 	responseMessage = h.formatExplanationAsBullets(responseMessage)
 
-	// Return success response with placeholder message
 	c.Header("Content-Type", "text/html")
 	c.String(http.StatusOK, fmt.Sprintf(`
 		<div class="notification is-info">
@@ -154,3 +201,145 @@ func (h *Handler) RefineWorkItems(c *gin.Context) {
 		</div>
 	`, strings.ReplaceAll(responseMessage, "\n", "<br>")))
 }
+
+// workItemProposal asks the AI for work item changes. One target work item
+// is refined, several are consolidated and without targets new work items
+// are generated.
+func (h *Handler) workItemProposal(ctx context.Context, ag *agency.Agency, userMessage string, targets, existing []*agency.WorkItem, builderContext builder.BuilderContext) (*Proposal, error) {
+	proposal := &Proposal{
+		AgencyID:    ag.ID,
+		Entity:      ProposalEntityWorkItems,
+		UserMessage: userMessage,
+		Changes:     []ProposedChange{},
+	}
+
+	switch {
+	case len(targets) == 1:
+		workItem := targets[0]
+		result, err := h.workItemBuilder.RefineWorkItem(ctx, &builder.RefineWorkItemRequest{
+			AgencyID:          ag.ID,
+			CurrentWorkItem:   workItem,
+			Title:             workItem.Title,
+			Description:       workItem.Description,
+			Deliverables:      workItem.Deliverables,
+			ExistingWorkItems: existing,
+			Goals:             builderContext.Goals,
+			AgencyContext:     ag,
+		}, builderContext)
+		if err != nil {
+			return nil, err
+		}
+		proposal.Operation = "refine"
+		proposal.Explanation = result.Explanation
+		if result.WasChanged {
+			update := agency.UpdateWorkItemRequest{
+				Title:                result.RefinedTitle,
+				Description:          result.RefinedDescription,
+				Deliverables:         result.RefinedDeliverables,
+				Dependencies:         workItem.Dependencies,
+				Tags:                 workItem.Tags,
+				RequiredCapabilities: workItem.RequiredCapabilities,
+			}
+			if update.Title == "" {
+				update.Title = workItem.Title
+			}
+			if update.Description == "" {
+				update.Description = workItem.Description
+			}
+			if len(update.Deliverables) == 0 {
+				update.Deliverables = workItem.Deliverables
+			}
+			if len(result.SuggestedTags) > 0 {
+				update.Tags = result.SuggestedTags
+			}
+			proposal.Changes = append(proposal.Changes, ProposedChange{
+				Action:         ProposedActionUpdate,
+				Key:            workItem.Key,
+				Code:           workItem.Code,
+				Before:         workItemFields(workItem.Title, workItem.Description, workItem.Deliverables),
+				After:          workItemFields(update.Title, update.Description, update.Deliverables),
+				updateWorkItem: &update,
+			})
+		}
+
+	case len(targets) > 1:
+		result, err := h.workItemBuilder.ConsolidateWorkItems(ctx, &builder.ConsolidateWorkItemsRequest{
+			AgencyID:         ag.ID,
+			AgencyContext:    ag,
+			CurrentWorkItems: targets,
+			Goals:            builderContext.Goals,
+		}, builderContext)
+		if err != nil {
+			return nil, err
+		}
+		proposal.Operation = "consolidate"
+		proposal.Explanation = result.Explanation
+		for _, wi := range result.ConsolidatedWorkItems {
+			proposal.Changes = append(proposal.Changes, proposedWorkItem(agency.CreateWorkItemRequest{
+				Title:        wi.Title,
+				Description:  wi.Description,
+				Deliverables: wi.Deliverables,
+				Tags:         wi.SuggestedTags,
+			}, wi.Rationale))
+		}
+
+		// Only selected work items the AI explicitly removed are deleted
+		removed := make(map[string]bool, len(result.RemovedWorkItems))
+		for _, key := range result.RemovedWorkItems {
+			removed[key] = true
+		}
+		for _, workItem := range targets {
+			if removed[workItem.Key] {
+				proposal.Changes = append(proposal.Changes, ProposedChange{
+					Action: ProposedActionDelete,
+					Key:    workItem.Key,
+					Code:   workItem.Code,
+					Before: workItemFields(workItem.Title, workItem.Description, workItem.Deliverables),
+				})
+			}
+		}
+
+	default:
+		result, err := h.workItemBuilder.GenerateWorkItems(ctx, &builder.GenerateWorkItemRequest{
+			AgencyID:          ag.ID,
+			AgencyContext:     ag,
+			ExistingWorkItems: existing,
+			Goals:             builderContext.Goals,
+			UserInput:         userMessage,
+		}, builderContext)
+		if err != nil {
+			return nil, err
+		}
+		proposal.Operation = "generate"
+		proposal.Explanation = result.Explanation
+		for _, wi := range result.WorkItems {
+			proposal.Changes = append(proposal.Changes, proposedWorkItem(agency.CreateWorkItemRequest{
+				Title:        wi.Title,
+				Description:  wi.Description,
+				Deliverables: wi.Deliverables,
+				Tags:         wi.SuggestedTags,
+				GoalKeys:     wi.GoalKeys,
+			}, wi.Explanation))
+		}
+	}
+
+	return proposal, nil
+}
+
+// proposedWorkItem proposes creating a work item
+func proposedWorkItem(req agency.CreateWorkItemRequest, explanation string) ProposedChange {
+	return ProposedChange{
+		Action:         ProposedActionCreate,
+		After:          workItemFields(req.Title, req.Description, req.Deliverables),
+		Explanation:    explanation,
+		createWorkItem: &req,
+	}
+}
+
+func workItemFields(title, description string, deliverables []string) map[string]interface{} {
+	fields := map[string]interface{}{"title": title, "description": description}
+	if len(deliverables) > 0 {
+		fields["deliverables"] = deliverables
+	}
+	return fields
+}
@@ -52,6 +52,10 @@ type GoalBuilderInterface interface {
 	// Can refine, generate, consolidate, or enhance goals based on natural language input.
 	RefineGoals(c *gin.Context)
 
+	// StreamGoalRefinement handles GET /api/v1/agencies/:id/goals/refine-stream
+	// Runs the same operation as RefineGoals and streams the AI output as server-sent events.
+	StreamGoalRefinement(c *gin.Context)
+
 	// GenerateGoal handles POST /api/v1/agencies/:id/goals/generate
 	// Generates a single goal using AI based on user input.
 	GenerateGoal(c *gin.Context)
@@ -64,8 +68,13 @@ type GoalBuilderInterface interface {
 	// Consolidates multiple goals into a lean, strategic list using AI.
 	ConsolidateGoals(c *gin.Context)
 
-	// ProcessAIGoalRequest handles POST /api/v1/agencies/:id/ai/goals/process
+	// RankGoals handles POST /api/v1/agencies/:id/goals/rank
+	// Recommends a priority ordering of all goals with a rationale per goal.
+	RankGoals(c *gin.Context)
+
+	// ProcessAIGoalRequest handles POST /api/v1/agencies/:id/goals/ai-process
 	// Processes batch AI operations on goals (create, enhance, consolidate).
+	// With "preview" set, returns the proposed changes without applying them.
 	ProcessAIGoalRequest(c *gin.Context)
 }
 
@@ -138,6 +147,13 @@ type RACIBuilderInterface interface {
 	ProcessAIRACIRequest(c *gin.Context)
 }
 
+// ProposalHandlerInterface defines the contract for applying previewed AI proposals.
+type ProposalHandlerInterface interface {
+	// ConfirmProposal handles POST /api/v1/agencies/:id/ai/proposals/:proposalId/confirm
+	// Applies a proposal returned by a preview request. Each proposal can be applied once.
+	ConfirmProposal(c *gin.Context)
+}
+
 // ContextBuilderInterface defines the contract for building AI context from agency data.
 // This interface abstracts the process of gathering all necessary agency data (goals, work items,
 // roles, RACI assignments) and packaging it into a BuilderContext for AI operations.
@@ -173,6 +189,7 @@ type ContextBuilderInterface interface {
 // TODO: Uncomment these as methods are implemented in ai_refine.Handler
 var (
 	_ IntroductionBuilderInterface = (*ai_refine.Handler)(nil)
+	_ ProposalHandlerInterface     = (*ai_refine.Handler)(nil)
 	// _ GoalBuilderInterface         = (*ai_refine.Handler)(nil) // TODO: Implement GenerateGoals
 	// _ WorkItemBuilderInterface     = (*ai_refine.Handler)(nil) // TODO: Implement RefineWorkItem, GenerateWorkItem, GenerateWorkItems, ConsolidateWorkItems
 	// _ RoleBuilderInterface         = (*ai_refine.Handler)(nil) // TODO: Implement RefineRole, GenerateRole, GenerateRoles, ConsolidateRoles
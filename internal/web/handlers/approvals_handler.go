@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/aosanya/CodeValdCortex/internal/web/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ApprovalsWebHandler serves the page of manual approval tasks awaiting
+// control-room sign-off
+type ApprovalsWebHandler struct {
+	engine orchestration.WorkflowEngine
+	logger *logrus.Logger
+}
+
+// NewApprovalsWebHandler creates a new approvals web handler
+func NewApprovalsWebHandler(engine orchestration.WorkflowEngine, logger *logrus.Logger) *ApprovalsWebHandler {
+	return &ApprovalsWebHandler{
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// ShowApprovals renders the tasks awaiting approval, longest waiting first.
+// Tasks are searched in the most recent unfinished executions.
+func (h *ApprovalsWebHandler) ShowApprovals(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	executions, err := h.engine.ListExecutions(ctx, orchestration.ExecutionFilters{
+		Status: []orchestration.WorkflowStatus{orchestration.WorkflowStatusRunning, orchestration.WorkflowStatusPaused},
+		Limit:  orchestration.MaxExecutionListLimit,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list workflow executions")
+		c.String(http.StatusInternalServerError, "Failed to load approvals")
+		return
+	}
+
+	pending := make([]pages.PendingApproval, 0)
+	for _, execution := range executions {
+		for _, task := range execution.TaskExecutions {
+			if task.Status == orchestration.TaskStatusAwaitingApproval {
+				pending = append(pending, pages.PendingApproval{Execution: execution, Task: task})
+			}
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Task.StartTime.Before(pending[j].Task.StartTime)
+	})
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.Approvals(pending).Render(ctx, c.Writer); err != nil {
+		h.logger.Errorf("Failed to render approvals page: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
+	"github.com/aosanya/CodeValdCortex/internal/builder"
 	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
 	"github.com/aosanya/CodeValdCortex/internal/registry"
 	"github.com/aosanya/CodeValdCortex/internal/web/handlers/ai_refine"
@@ -50,6 +51,11 @@ func NewChatHandler(
 	}
 }
 
+// SetInfrastructureSource enables topology and KPI injection into AI prompt context
+func (h *ChatHandler) SetInfrastructureSource(source builder.InfrastructureSource) {
+	h.contextBuilder.SetInfrastructureSource(source)
+}
+
 // SendMessage handles POST /api/v1/conversations/:conversationId/messages/web
 // Returns HTML for HTMX to append to the chat
 func (h *ChatHandler) SendMessage(c *gin.Context) {
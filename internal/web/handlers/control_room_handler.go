@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/web/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultControlRoomAlerts   = "#.*alert*.#"
+	defaultControlRoomInterval = 3
+)
+
+// ControlRoomHandler serves the developer control room used in use case demos
+type ControlRoomHandler struct {
+	logger *logrus.Logger
+}
+
+// NewControlRoomHandler creates a new control room handler
+func NewControlRoomHandler(logger *logrus.Logger) *ControlRoomHandler {
+	return &ControlRoomHandler{
+		logger: logger,
+	}
+}
+
+// ShowControlRoom renders the control room page. The page polls the topic
+// stats, traffic capture and agent APIs itself; the query parameters "alerts"
+// (comma-separated event patterns) and "interval" (seconds) tune what it shows.
+func (h *ControlRoomHandler) ShowControlRoom(c *gin.Context) {
+	options := pages.ControlRoomOptions{
+		AlertPatterns:   []string{defaultControlRoomAlerts},
+		IntervalSeconds: defaultControlRoomInterval,
+	}
+
+	if raw := c.Query("alerts"); raw != "" {
+		var patterns []string
+		for _, pattern := range strings.Split(raw, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				patterns = append(patterns, pattern)
+			}
+		}
+		if len(patterns) > 0 {
+			options.AlertPatterns = patterns
+		}
+	}
+	if raw := c.Query("interval"); raw != "" {
+		if interval, err := strconv.Atoi(raw); err == nil && interval > 0 {
+			options.IntervalSeconds = interval
+		}
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.ControlRoom(options).Render(c.Request.Context(), c.Writer); err != nil {
+		h.logger.Errorf("Failed to render control room: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
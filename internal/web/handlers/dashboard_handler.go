@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
 	"github.com/aosanya/CodeValdCortex/internal/agency"
 	"github.com/aosanya/CodeValdCortex/internal/agent"
+	"github.com/aosanya/CodeValdCortex/internal/health"
 	"github.com/aosanya/CodeValdCortex/internal/runtime"
+	"github.com/aosanya/CodeValdCortex/internal/tenant"
 	"github.com/aosanya/CodeValdCortex/internal/web/components"
 	"github.com/aosanya/CodeValdCortex/internal/web/pages"
 	"github.com/gin-gonic/gin"
@@ -21,6 +24,7 @@ const (
 // DashboardHandler handles web dashboard requests
 type DashboardHandler struct {
 	runtime *runtime.Manager
+	scores  *health.HealthScoreService
 	logger  *logrus.Logger
 }
 
@@ -32,10 +36,15 @@ func NewDashboardHandler(runtime *runtime.Manager, logger *logrus.Logger) *Dashb
 	}
 }
 
+// SetHealthScores sets the service whose latest scores are shown for agents
+func (h *DashboardHandler) SetHealthScores(scores *health.HealthScoreService) {
+	h.scores = scores
+}
+
 // ShowDashboard renders the main dashboard page
 func (h *DashboardHandler) ShowDashboard(c *gin.Context) {
 	// Get all agents
-	allAgents := h.runtime.ListAgents()
+	allAgents := tenantAgents(c.Request.Context(), h.runtime.ListAgents())
 
 	// Filter by agency if one is selected
 	var agents []*agent.Agent
@@ -77,7 +86,7 @@ func (h *DashboardHandler) ShowDashboard(c *gin.Context) {
 // GetAgentsLive returns OOB status updates for all agents without changing pagination
 // React-like component updates: only updates changed data, preserves user interactions
 func (h *DashboardHandler) GetAgentsLive(c *gin.Context) {
-	allAgents := h.runtime.ListAgents()
+	allAgents := tenantAgents(c.Request.Context(), h.runtime.ListAgents())
 
 	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
 
@@ -128,15 +137,20 @@ func (h *DashboardHandler) GetAgentsLive(c *gin.Context) {
 			continue
 		}
 	}
-} // HandleAgentAction handles start/stop/restart/pause/resume actions via HTMX
+}
+
+// HandleAgentAction handles start/stop/restart/pause/resume actions via HTMX
 func (h *DashboardHandler) HandleAgentAction(c *gin.Context) {
 	agentID := c.Param("id")
 	action := c.Param("action")
 
 	h.logger.Infof("Agent action: %s on agent %s", action, agentID)
 
-	// Verify agent exists
+	// Verify agent exists; other tenants' agents are reported as not found
 	_, err := h.runtime.GetAgent(agentID)
+	if err == nil && !tenant.AllowsID(c.Request.Context(), agentID) {
+		err = tenant.ErrForbidden
+	}
 	if err != nil {
 		h.logger.Errorf("Failed to get agent %s: %v", agentID, err)
 		c.String(http.StatusNotFound, "Agent not found")
@@ -182,6 +196,20 @@ func (h *DashboardHandler) HandleAgentAction(c *gin.Context) {
 	}
 }
 
+// tenantAgents returns the agents the request's tenant may see
+func tenantAgents(ctx context.Context, agents []*agent.Agent) []*agent.Agent {
+	if tenant.FromContext(ctx) == "" {
+		return agents
+	}
+	visible := make([]*agent.Agent, 0, len(agents))
+	for _, a := range agents {
+		if tenant.AllowsID(ctx, a.ID) {
+			visible = append(visible, a)
+		}
+	}
+	return visible
+}
+
 func (h *DashboardHandler) calculateStats(agents []*agent.Agent) pages.DashboardStats {
 	stats := pages.DashboardStats{
 		Total: len(agents),
@@ -226,7 +254,7 @@ func (h *DashboardHandler) GetAgentsJSON(c *gin.Context) {
 		}
 	}
 
-	allAgents := h.runtime.ListAgents()
+	allAgents := tenantAgents(c.Request.Context(), h.runtime.ListAgents())
 	totalAgents := len(allAgents)
 
 	// Calculate pagination
@@ -263,6 +291,15 @@ func (h *DashboardHandler) GetAgentsJSON(c *gin.Context) {
 			"created_at":     a.CreatedAt,
 			"last_heartbeat": a.LastHeartbeat,
 		}
+		if h.scores == nil {
+			continue
+		}
+		if score, err := h.scores.LatestScore(c.Request.Context(), a.ID); err != nil {
+			h.logger.Warnf("Failed to get health score for agent %s: %v", a.ID, err)
+		} else if score != nil {
+			agentData[i]["health_score"] = score.Score
+			agentData[i]["health_flags"] = score.Flags
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/incident"
+	"github.com/aosanya/CodeValdCortex/internal/web/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// recentIncidentsShown is the number of incidents listed on the incidents page
+const recentIncidentsShown = 100
+
+// IncidentsWebHandler serves the incidents dashboard page
+type IncidentsWebHandler struct {
+	incidents *incident.Service
+	logger    *logrus.Logger
+}
+
+// NewIncidentsWebHandler creates a new incidents web handler
+func NewIncidentsWebHandler(incidentService *incident.Service, logger *logrus.Logger) *IncidentsWebHandler {
+	return &IncidentsWebHandler{
+		incidents: incidentService,
+		logger:    logger,
+	}
+}
+
+// ShowIncidents renders recent incidents. The "state" query parameter narrows
+// the list, and ?active=true hides resolved incidents.
+func (h *IncidentsWebHandler) ShowIncidents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	incidents, err := h.incidents.List(ctx, incident.Filter{
+		State:  incident.State(c.Query("state")),
+		Active: c.Query("active") == "true",
+		Limit:  recentIncidentsShown,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list incidents")
+		c.String(http.StatusInternalServerError, "Failed to load incidents")
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.Incidents(incidents).Render(ctx, c.Writer); err != nil {
+		h.logger.Errorf("Failed to render incidents page: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
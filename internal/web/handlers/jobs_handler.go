@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aosanya/CodeValdCortex/internal/jobs"
+	"github.com/aosanya/CodeValdCortex/internal/web/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// recentJobsShown is the number of jobs listed on the jobs page
+const recentJobsShown = 100
+
+// JobsWebHandler serves the background job admin page
+type JobsWebHandler struct {
+	queue  *jobs.Queue
+	logger *logrus.Logger
+}
+
+// NewJobsWebHandler creates a new jobs web handler
+func NewJobsWebHandler(queue *jobs.Queue, logger *logrus.Logger) *JobsWebHandler {
+	return &JobsWebHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// ShowJobs renders the job types and recent jobs. The "status" and "type"
+// query parameters narrow the history, e.g. ?status=dead lists failures.
+func (h *JobsWebHandler) ShowJobs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	types, err := h.queue.Types(ctx)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list job types")
+		c.String(http.StatusInternalServerError, "Failed to load jobs")
+		return
+	}
+	recent, err := h.queue.List(ctx, jobs.Filter{
+		Type:   c.Query("type"),
+		Status: c.Query("status"),
+		Limit:  recentJobsShown,
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list jobs")
+		c.String(http.StatusInternalServerError, "Failed to load jobs")
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.Jobs(types, recent).Render(ctx, c.Writer); err != nil {
+		h.logger.Errorf("Failed to render jobs page: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+		return
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aosanya/CodeValdCortex/internal/web/pages"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// LoginWebHandler serves the web UI sign-in page
+type LoginWebHandler struct {
+	logger *logrus.Logger
+}
+
+// NewLoginWebHandler creates a new sign-in web handler
+func NewLoginWebHandler(logger *logrus.Logger) *LoginWebHandler {
+	return &LoginWebHandler{logger: logger}
+}
+
+// ShowLogin renders the sign-in form. The "next" query parameter is the page
+// to return to; only paths on this server are followed.
+func (h *LoginWebHandler) ShowLogin(c *gin.Context) {
+	next := c.Query("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pages.Login(next).Render(c.Request.Context(), c.Writer); err != nil {
+		h.logger.Errorf("Failed to render sign-in page: %v", err)
+		c.String(http.StatusInternalServerError, "Failed to render page")
+	}
+}
@@ -37,7 +37,7 @@ func (h *TopologyVisualizerHandler) ShowGeographicVisualizer(c *gin.Context) {
 
 // GetTopologyData returns agents and edges for visualization
 func (h *TopologyVisualizerHandler) GetTopologyData(c *gin.Context) {
-	agents := h.runtime.ListAgents()
+	agents := tenantAgents(c.Request.Context(), h.runtime.ListAgents())
 
 	// Transform agents to topology nodes
 	nodes := make([]gin.H, len(agents))
@@ -149,10 +149,26 @@ func (m *mockAgencyService) UpdateGoal(ctx context.Context, agencyID string, goa
 	return nil
 }
 
+func (m *mockAgencyService) UpdateGoalFull(ctx context.Context, agencyID string, goalKey string, req agency.UpdateGoalRequest) error {
+	return nil
+}
+
 func (m *mockAgencyService) DeleteGoal(ctx context.Context, agencyID string, goalKey string) error {
 	return nil
 }
 
+func (m *mockAgencyService) ReprioritizeGoals(ctx context.Context, agencyID string, priorities []agency.GoalPriority) ([]*agency.Goal, error) {
+	return nil, nil
+}
+
+func (m *mockAgencyService) ConsolidateGoals(ctx context.Context, agencyID string, req agency.ConsolidateGoalsRequest) (*agency.GoalConsolidation, error) {
+	return &agency.GoalConsolidation{AgencyID: agencyID}, nil
+}
+
+func (m *mockAgencyService) UndoGoalConsolidation(ctx context.Context, agencyID string, key string) (*agency.GoalConsolidation, error) {
+	return &agency.GoalConsolidation{Key: key, AgencyID: agencyID}, nil
+}
+
 func (m *mockAgencyService) CreateWorkItem(ctx context.Context, agencyID string, req agency.CreateWorkItemRequest) (*agency.WorkItem, error) {
 	return &agency.WorkItem{
 		Key:      "WI-001",
@@ -197,6 +213,22 @@ func (m *mockAgencyService) ValidateWorkItemDependencies(ctx context.Context, ag
 	return nil
 }
 
+func (m *mockAgencyService) TransitionWorkItem(ctx context.Context, agencyID string, key string, req agency.TransitionWorkItemRequest) (*agency.WorkItem, error) {
+	return &agency.WorkItem{Key: key, Status: req.Status}, nil
+}
+
+func (m *mockAgencyService) AssignWorkItem(ctx context.Context, agencyID string, key string, req agency.AssignWorkItemRequest) (*agency.WorkItem, error) {
+	return &agency.WorkItem{Key: key, Assignee: req.AgentID}, nil
+}
+
+func (m *mockAgencyService) GetWorkload(ctx context.Context, agencyID string) (*agency.Workload, error) {
+	return &agency.Workload{AgencyID: agencyID}, nil
+}
+
+func (m *mockAgencyService) GetTraceability(ctx context.Context, agencyID string) (*agency.TraceabilityReport, error) {
+	return &agency.TraceabilityReport{AgencyID: agencyID}, nil
+}
+
 func (m *mockAgencyService) CreateRACIAssignment(ctx context.Context, agencyID string, assignment *agency.RACIAssignment) error {
 	return nil
 }
@@ -225,6 +257,30 @@ func (m *mockAgencyService) DeleteRACIAssignmentsForWorkItem(ctx context.Context
 	return nil
 }
 
+func (m *mockAgencyService) CopyItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest, adapter agency.ItemAdapter) (*agency.TransferResult, error) {
+	return &agency.TransferResult{Mode: agency.ProvenanceModeCopy}, nil
+}
+
+func (m *mockAgencyService) LinkItems(ctx context.Context, targetAgencyID string, req agency.TransferItemsRequest) (*agency.TransferResult, error) {
+	return &agency.TransferResult{Mode: agency.ProvenanceModeLink}, nil
+}
+
+func (m *mockAgencyService) ExportAgency(ctx context.Context, agencyID string) (*agency.AgencyBundle, error) {
+	return &agency.AgencyBundle{FormatVersion: agency.BundleFormatVersion}, nil
+}
+
+func (m *mockAgencyService) ImportAgency(ctx context.Context, bundle *agency.AgencyBundle) (*agency.ImportResult, error) {
+	return &agency.ImportResult{}, nil
+}
+
+func (m *mockAgencyService) RecordExplanation(ctx context.Context, explanation *agency.Explanation) error {
+	return nil
+}
+
+func (m *mockAgencyService) GetExplanations(ctx context.Context, agencyID string, entityType agency.ExplanationEntityType, entityKey string) ([]*agency.Explanation, error) {
+	return []*agency.Explanation{}, nil
+}
+
 // setupTestRouter creates a test router with the homepage handlers and middleware
 func setupTestRouter(agencyService agency.Service) *gin.Engine {
 	gin.SetMode(gin.TestMode)
@@ -189,7 +189,15 @@ func GoalDefinitionContent(currentAgency *agency.Agency, conversation *ai.Conver
 			templ_7745c5c3_Var4 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<div class=\"overview-section\"><!-- AI Goal Operations Toolbar --><div class=\"box mb-4 p-4\"><div class=\"is-flex is-flex-direction-column\"><div class=\"is-flex is-justify-content-space-between is-align-items-center mb-2\"><p class=\"has-text-weight-semibold mb-0\">AI Goal Operations:</p><span id=\"goal-selection-count\" class=\"tag is-info is-light\" style=\"display: none;\"></span></div><div class=\"buttons\"><button class=\"button is-small is-info\" onclick=\"processAIGoalOperation(['create'])\" id=\"ai-create-goals-btn\" title=\"Generate new goals from introduction\"><span class=\"icon\"><i class=\"fas fa-sparkles\"></i></span> <span>Create</span></button> <button class=\"button is-small is-link is-static\" onclick=\"processAIGoalOperation(['enhance'])\" id=\"ai-enhance-goals-btn\" title=\"Select goals to enhance\" disabled><span class=\"icon\"><i class=\"fas fa-wand-magic-sparkles\"></i></span> <span>Enhance</span></button> <button class=\"button is-small is-warning is-static\" onclick=\"processAIGoalOperation(['consolidate'])\" id=\"ai-consolidate-goals-btn\" title=\"Select goals to consolidate\" disabled><span class=\"icon\"><i class=\"fas fa-compress\"></i></span> <span>Consolidate</span></button></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<div class=\"overview-section\"><!-- AI Goal Operations Toolbar --><div class=\"box mb-4 p-4\"><div class=\"is-flex is-flex-direction-column\"><div class=\"is-flex is-justify-content-space-between is-align-items-center mb-2\"><p class=\"has-text-weight-semibold mb-0\">AI Goal Operations:</p><span id=\"goal-selection-count\" class=\"tag is-info is-light\" style=\"display: none;\"></span></div><div class=\"buttons\"><button class=\"button is-small is-info\" onclick=\"processAIGoalOperation(['create'])\" id=\"ai-create-goals-btn\" title=\"Generate new goals from introduction\"><span class=\"icon\"><i class=\"fas fa-sparkles\"></i></span> <span>Create</span></button> <button class=\"button is-small is-link is-static\" onclick=\"processAIGoalOperation(['enhance'])\" id=\"ai-enhance-goals-btn\" title=\"Select goals to enhance\" disabled><span class=\"icon\"><i class=\"fas fa-wand-magic-sparkles\"></i></span> <span>Enhance</span></button> <button class=\"button is-small is-warning is-static\" onclick=\"processAIGoalOperation(['consolidate'])\" id=\"ai-consolidate-goals-btn\" title=\"Select goals to consolidate\" disabled><span class=\"icon\"><i class=\"fas fa-compress\"></i></span> <span>Consolidate</span></button> <button class=\"button is-small is-primary\" onclick=\"rankGoalsWithAI()\" id=\"ai-rank-goals-btn\" title=\"Recommend a priority order for all goals\"><span class=\"icon\"><i class=\"fas fa-arrow-down-wide-short\"></i></span> <span>Prioritize</span></button> <button class=\"button is-small is-light\" onclick=\"toggleGoalTraceability()\" id=\"goal-traceability-btn\" title=\"Show the work items linked to each goal\"><span class=\"icon\"><i class=\"fas fa-diagram-project\"></i></span> <span>Traceability</span></button></div></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = GoalRankingCard().Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = GoalTraceabilityCard().Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -67,6 +67,9 @@ type NodeData struct {
 	SLAHours     int                    `json:"sla_hours,omitempty"`
 	Parameters   map[string]interface{} `json:"parameters,omitempty"`
 
+	// RequiredCapabilities lists capability IDs the agent running this task needs
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+
 	// Decision node fields
 	Condition string `json:"condition,omitempty"`
 
@@ -393,6 +393,11 @@ func (s *Service) checkOrphanedNodes(workflow *Workflow, result *ValidationResul
 	}
 }
 
+// GetExecutionsByWorkflow retrieves all executions of a workflow, newest first
+func (s *Service) GetExecutionsByWorkflow(ctx context.Context, workflowID string) ([]*WorkflowExecution, error) {
+	return s.repo.GetExecutionsByWorkflowID(ctx, workflowID)
+}
+
 // StartExecution starts a new workflow execution
 func (s *Service) StartExecution(ctx context.Context, workflowID, startedBy string, context map[string]interface{}) (*WorkflowExecution, error) {
 	// Get workflow
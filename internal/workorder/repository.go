@@ -0,0 +1,242 @@
+package workorder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aosanya/CodeValdCortex/internal/database"
+	driver "github.com/arangodb/go-driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// CollectionWorkOrders is the work order collection name
+const CollectionWorkOrders = "work_orders"
+
+// ArangoRepository stores work orders in ArangoDB
+type ArangoRepository struct {
+	db         driver.Database
+	collection driver.Collection
+}
+
+// NewArangoRepository creates a new ArangoDB-backed work order repository
+func NewArangoRepository(dbClient *database.ArangoClient) (*ArangoRepository, error) {
+	ctx := dbClient.Context()
+	db := dbClient.Database()
+
+	exists, err := db.CollectionExists(ctx, CollectionWorkOrders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check collection existence: %w", err)
+	}
+
+	var col driver.Collection
+	if exists {
+		col, err = db.Collection(ctx, CollectionWorkOrders)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open collection: %w", err)
+		}
+	} else {
+		col, err = db.CreateCollection(ctx, CollectionWorkOrders, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create collection: %w", err)
+		}
+		log.WithField("collection", CollectionWorkOrders).Info("Created new collection")
+	}
+
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"status", "updated_at"}, &driver.EnsurePersistentIndexOptions{
+		Name: "idx_work_orders_status",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+	if _, _, err := col.EnsurePersistentIndex(ctx, []string{"asset_id"}, &driver.EnsurePersistentIndexOptions{
+		Name:   "idx_work_orders_asset",
+		Sparse: true,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	return &ArangoRepository{
+		db:         db,
+		collection: col,
+	}, nil
+}
+
+// Create stores a new work order
+func (r *ArangoRepository) Create(ctx context.Context, workOrder *WorkOrder) error {
+	workOrder.Key = workOrder.ID
+	if _, err := r.collection.CreateDocument(ctx, workOrder); err != nil {
+		return fmt.Errorf("failed to create work order: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a work order by ID
+func (r *ArangoRepository) Get(ctx context.Context, id string) (*WorkOrder, error) {
+	var workOrder WorkOrder
+	if _, err := r.collection.ReadDocument(ctx, id, &workOrder); err != nil {
+		if driver.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrWorkOrderNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to read work order: %w", err)
+	}
+	return &workOrder, nil
+}
+
+// Update replaces an existing work order
+func (r *ArangoRepository) Update(ctx context.Context, workOrder *WorkOrder) error {
+	workOrder.Key = workOrder.ID
+	if _, err := r.collection.ReplaceDocument(ctx, workOrder.ID, workOrder); err != nil {
+		if driver.IsNotFound(err) {
+			return fmt.Errorf("%w: %s", ErrWorkOrderNotFound, workOrder.ID)
+		}
+		return fmt.Errorf("failed to update work order: %w", err)
+	}
+	return nil
+}
+
+// List returns the work orders matching the filter, most recently updated first
+func (r *ArangoRepository) List(ctx context.Context, filter Filter) ([]*WorkOrder, error) {
+	var conditions []string
+	bindVars := map[string]interface{}{
+		"@collection": CollectionWorkOrders,
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "w.status == @status")
+		bindVars["status"] = filter.Status
+	}
+	if filter.AssetID != "" {
+		conditions = append(conditions, "w.asset_id == @assetID")
+		bindVars["assetID"] = filter.AssetID
+	}
+	if filter.AssignedTo != "" {
+		conditions = append(conditions, "w.assigned_to == @assignedTo")
+		bindVars["assignedTo"] = filter.AssignedTo
+	}
+	if filter.Open {
+		conditions = append(conditions, "w.status NOT IN @closed")
+		bindVars["closed"] = []Status{StatusCompleted, StatusCancelled}
+	}
+
+	filterClause := ""
+	if len(conditions) > 0 {
+		filterClause = "FILTER " + strings.Join(conditions, " AND ")
+	}
+	limitClause := ""
+	if filter.Limit > 0 {
+		limitClause = "LIMIT @limit"
+		bindVars["limit"] = filter.Limit
+	}
+
+	query := fmt.Sprintf(`
+		FOR w IN @@collection
+			%s
+			SORT w.updated_at DESC
+			%s
+			RETURN w
+	`, filterClause, limitClause)
+
+	cursor, err := r.db.Query(ctx, query, bindVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query work orders: %w", err)
+	}
+	defer cursor.Close()
+
+	var workOrders []*WorkOrder
+	for {
+		var workOrder WorkOrder
+		_, err := cursor.ReadDocument(ctx, &workOrder)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read work order: %w", err)
+		}
+		workOrders = append(workOrders, &workOrder)
+	}
+
+	return workOrders, nil
+}
+
+// InMemoryRepository keeps work orders in memory, stored as copies so callers
+// cannot change them without an update.
+type InMemoryRepository struct {
+	mu         sync.RWMutex
+	workOrders map[string]*WorkOrder
+}
+
+// NewInMemoryRepository creates a new in-memory work order repository
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		workOrders: make(map[string]*WorkOrder),
+	}
+}
+
+// Create stores a new work order
+func (r *InMemoryRepository) Create(ctx context.Context, workOrder *WorkOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workOrder.Key = workOrder.ID
+	r.workOrders[workOrder.ID] = clone(workOrder)
+	return nil
+}
+
+// Get retrieves a work order by ID
+func (r *InMemoryRepository) Get(ctx context.Context, id string) (*WorkOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workOrder, exists := r.workOrders[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrWorkOrderNotFound, id)
+	}
+	return clone(workOrder), nil
+}
+
+// Update replaces an existing work order
+func (r *InMemoryRepository) Update(ctx context.Context, workOrder *WorkOrder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workOrders[workOrder.ID]; !exists {
+		return fmt.Errorf("%w: %s", ErrWorkOrderNotFound, workOrder.ID)
+	}
+	workOrder.Key = workOrder.ID
+	r.workOrders[workOrder.ID] = clone(workOrder)
+	return nil
+}
+
+// List returns the work orders matching the filter, most recently updated first
+func (r *InMemoryRepository) List(ctx context.Context, filter Filter) ([]*WorkOrder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var workOrders []*WorkOrder
+	for _, workOrder := range r.workOrders {
+		if filter.matches(workOrder) {
+			workOrders = append(workOrders, clone(workOrder))
+		}
+	}
+	sort.Slice(workOrders, func(i, j int) bool {
+		return workOrders[i].UpdatedAt.After(workOrders[j].UpdatedAt)
+	})
+	if filter.Limit > 0 && len(workOrders) > filter.Limit {
+		workOrders = workOrders[:filter.Limit]
+	}
+	return workOrders, nil
+}
+
+// clone copies a work order so stored work orders are not shared with callers
+func clone(workOrder *WorkOrder) *WorkOrder {
+	copied := *workOrder
+	copied.Tasks = append([]Task{}, workOrder.Tasks...)
+	copied.Parts = append([]Part{}, workOrder.Parts...)
+	copied.History = append([]StatusChange{}, workOrder.History...)
+	if workOrder.Schedule != nil {
+		schedule := *workOrder.Schedule
+		copied.Schedule = &schedule
+	}
+	return &copied
+}
@@ -0,0 +1,323 @@
+package workorder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/clock"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// WorkflowStarter runs workflows on the orchestration engine and returns the
+// ID of the execution. orchestration.DesignLauncher implements it.
+type WorkflowStarter interface {
+	StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error)
+}
+
+// Service manages work orders
+type Service struct {
+	repo      Repository
+	workflows WorkflowStarter
+	clock     clock.Clock
+	logger    *log.Logger
+
+	// mu serializes read-modify-write updates of work orders
+	mu sync.Mutex
+}
+
+// NewService creates a new work order service
+func NewService(repo Repository, logger *log.Logger) *Service {
+	if logger == nil {
+		logger = log.New()
+	}
+
+	return &Service{
+		repo:   repo,
+		clock:  clock.Real(),
+		logger: logger,
+	}
+}
+
+// SetWorkflows sets the service that starts the workflows of approved work orders
+func (s *Service) SetWorkflows(workflows WorkflowStarter) {
+	s.workflows = workflows
+}
+
+// SetClock sets the clock used to timestamp work orders
+func (s *Service) SetClock(c clock.Clock) {
+	if c != nil {
+		s.clock = c
+	}
+}
+
+// Create stores a new pending work order. The priority defaults to MEDIUM.
+func (s *Service) Create(ctx context.Context, workOrder *WorkOrder) error {
+	if err := normalize(workOrder); err != nil {
+		return err
+	}
+
+	now := s.clock.Now().UTC()
+	workOrder.ID = uuid.New().String()
+	workOrder.Status = StatusPending
+	workOrder.ExecutionID = ""
+	workOrder.ApprovedBy = ""
+	workOrder.ApprovedAt = nil
+	workOrder.StartedAt = nil
+	workOrder.ClosedAt = nil
+	for i := range workOrder.Tasks {
+		workOrder.Tasks[i] = Task{Description: workOrder.Tasks[i].Description}
+	}
+	workOrder.History = []StatusChange{{To: StatusPending, Actor: workOrder.CreatedBy, At: now}}
+	workOrder.CreatedAt = now
+	workOrder.UpdatedAt = now
+
+	if err := s.repo.Create(ctx, workOrder); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(log.Fields{
+		"work_order_id": workOrder.ID,
+		"asset_id":      workOrder.AssetID,
+		"priority":      workOrder.Priority,
+	}).Info("Work order created")
+	return nil
+}
+
+// Get retrieves a work order by ID
+func (s *Service) Get(ctx context.Context, id string) (*WorkOrder, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// List returns the work orders matching the filter, most recently updated first
+func (s *Service) List(ctx context.Context, filter Filter) ([]*WorkOrder, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Update replaces the details of an open work order: its title, description,
+// type, priority, asset, assignee, tasks, parts and schedule. The workflow
+// can only be changed before approval. Completion of tasks that are kept is
+// preserved.
+func (s *Service) Update(ctx context.Context, changes *WorkOrder) (*WorkOrder, error) {
+	if err := normalize(changes); err != nil {
+		return nil, err
+	}
+
+	return s.update(ctx, changes.ID, func(workOrder *WorkOrder, now time.Time) error {
+		if workOrder.Status.IsFinal() {
+			return fmt.Errorf("%w: %s work orders cannot be changed", ErrInvalidTransition, workOrder.Status)
+		}
+		if workOrder.Status != StatusPending && changes.WorkflowID != workOrder.WorkflowID {
+			return fmt.Errorf("%w: the workflow cannot be changed after approval", ErrInvalidWorkOrder)
+		}
+
+		done := make(map[string]Task, len(workOrder.Tasks))
+		for _, task := range workOrder.Tasks {
+			if task.Done {
+				done[task.Description] = task
+			}
+		}
+		tasks := make([]Task, len(changes.Tasks))
+		for i, task := range changes.Tasks {
+			if previous, ok := done[task.Description]; ok {
+				tasks[i] = previous
+			} else {
+				tasks[i] = Task{Description: task.Description}
+			}
+		}
+
+		workOrder.Reference = changes.Reference
+		workOrder.Title = changes.Title
+		workOrder.Description = changes.Description
+		workOrder.Type = changes.Type
+		workOrder.Priority = changes.Priority
+		workOrder.AssetID = changes.AssetID
+		workOrder.AssignedTo = changes.AssignedTo
+		workOrder.Tasks = tasks
+		workOrder.Parts = changes.Parts
+		workOrder.Schedule = changes.Schedule
+		workOrder.WorkflowID = changes.WorkflowID
+		workOrder.UpdatedAt = now
+		return nil
+	})
+}
+
+// Approve approves a pending work order. When the work order names a
+// workflow, it is run on the orchestration engine with the work order as its
+// context and the execution is recorded on the work order; if it cannot be
+// started the work order stays pending.
+func (s *Service) Approve(ctx context.Context, id, approvedBy, note string) (*WorkOrder, error) {
+	return s.update(ctx, id, func(workOrder *WorkOrder, now time.Time) error {
+		if !workOrder.Status.CanTransitionTo(StatusApproved) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, workOrder.Status, StatusApproved)
+		}
+
+		if workOrder.WorkflowID != "" {
+			if s.workflows == nil {
+				return fmt.Errorf("%w: workflows are not available", ErrWorkflowStart)
+			}
+			executionID, err := s.workflows.StartWorkflow(ctx, workOrder.WorkflowID, approvedBy, workflowContext(workOrder))
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrWorkflowStart, err)
+			}
+			workOrder.ExecutionID = executionID
+
+			s.logger.WithFields(log.Fields{
+				"work_order_id": workOrder.ID,
+				"workflow_id":   workOrder.WorkflowID,
+				"execution_id":  executionID,
+			}).Info("Started work order workflow")
+		}
+
+		workOrder.ApprovedBy = approvedBy
+		workOrder.ApprovedAt = &now
+		changeStatus(workOrder, now, StatusApproved, approvedBy, note)
+		return nil
+	})
+}
+
+// Transition moves a work order to in_progress, completed or cancelled.
+// Approval goes through Approve, and a work order can only be completed once
+// all of its tasks are done.
+func (s *Service) Transition(ctx context.Context, id string, status Status, actor, note string) (*WorkOrder, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWorkOrder, status)
+	}
+	if status == StatusApproved {
+		return s.Approve(ctx, id, actor, note)
+	}
+
+	return s.update(ctx, id, func(workOrder *WorkOrder, now time.Time) error {
+		if !workOrder.Status.CanTransitionTo(status) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, workOrder.Status, status)
+		}
+		if status == StatusCompleted {
+			if open := openTasks(workOrder); open > 0 {
+				return fmt.Errorf("%w: %d tasks are not done", ErrInvalidTransition, open)
+			}
+		}
+
+		switch status {
+		case StatusInProgress:
+			workOrder.StartedAt = &now
+		case StatusCompleted, StatusCancelled:
+			workOrder.ClosedAt = &now
+		}
+		changeStatus(workOrder, now, status, actor, note)
+		return nil
+	})
+}
+
+// CompleteTask marks a task of an in-progress work order as done. Tasks are
+// numbered from zero in the order they are listed.
+func (s *Service) CompleteTask(ctx context.Context, id string, index int, actor string) (*WorkOrder, error) {
+	return s.update(ctx, id, func(workOrder *WorkOrder, now time.Time) error {
+		if index < 0 || index >= len(workOrder.Tasks) {
+			return fmt.Errorf("%w: no task %d", ErrInvalidWorkOrder, index)
+		}
+		if workOrder.Status != StatusInProgress {
+			return fmt.Errorf("%w: tasks are completed while the work order is %s", ErrInvalidTransition, StatusInProgress)
+		}
+
+		task := &workOrder.Tasks[index]
+		if !task.Done {
+			task.Done = true
+			task.CompletedBy = actor
+			task.CompletedAt = &now
+			workOrder.UpdatedAt = now
+		}
+		return nil
+	})
+}
+
+// update applies a change to a work order and stores it
+func (s *Service) update(ctx context.Context, id string, change func(workOrder *WorkOrder, now time.Time) error) (*WorkOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workOrder, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(workOrder, s.clock.Now().UTC()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, workOrder); err != nil {
+		return nil, err
+	}
+	return workOrder, nil
+}
+
+// normalize validates the details of a work order, defaulting and
+// canonicalizing its priority
+func normalize(workOrder *WorkOrder) error {
+	if workOrder.Priority == "" {
+		workOrder.Priority = PriorityMedium
+	}
+	priority, _ := ParsePriority(string(workOrder.Priority))
+	workOrder.Priority = priority
+	if workOrder.Tasks == nil {
+		workOrder.Tasks = []Task{}
+	}
+	if workOrder.Parts == nil {
+		workOrder.Parts = []Part{}
+	}
+	return workOrder.Validate()
+}
+
+func changeStatus(workOrder *WorkOrder, now time.Time, status Status, actor, note string) {
+	workOrder.History = append(workOrder.History, StatusChange{
+		From:  workOrder.Status,
+		To:    status,
+		Actor: actor,
+		Note:  note,
+		At:    now,
+	})
+	workOrder.Status = status
+	workOrder.UpdatedAt = now
+}
+
+func openTasks(workOrder *WorkOrder) int {
+	open := 0
+	for _, task := range workOrder.Tasks {
+		if !task.Done {
+			open++
+		}
+	}
+	return open
+}
+
+// workflowContext is the context the workflow of a work order starts with
+func workflowContext(workOrder *WorkOrder) map[string]interface{} {
+	tasks := make([]string, len(workOrder.Tasks))
+	for i, task := range workOrder.Tasks {
+		tasks[i] = task.Description
+	}
+	parts := make([]map[string]interface{}, len(workOrder.Parts))
+	for i, part := range workOrder.Parts {
+		parts[i] = map[string]interface{}{
+			"name":        part.Name,
+			"part_number": part.PartNumber,
+			"quantity":    part.Quantity,
+		}
+	}
+
+	context := map[string]interface{}{
+		"work_order_id": workOrder.ID,
+		"reference":     workOrder.Reference,
+		"title":         workOrder.Title,
+		"type":          workOrder.Type,
+		"priority":      string(workOrder.Priority),
+		"asset_id":      workOrder.AssetID,
+		"assigned_to":   workOrder.AssignedTo,
+		"tasks":         tasks,
+		"parts":         parts,
+	}
+	if workOrder.Schedule != nil {
+		context["schedule_start"] = workOrder.Schedule.Start
+		context["schedule_end"] = workOrder.Schedule.End
+	}
+	return context
+}
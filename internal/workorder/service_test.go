@@ -0,0 +1,300 @@
+package workorder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/orchestration"
+	"github.com/aosanya/CodeValdCortex/internal/workflow"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorkflows records the workflows started for work orders
+type fakeWorkflows struct {
+	started []map[string]interface{}
+	err     error
+}
+
+func (f *fakeWorkflows) StartWorkflow(ctx context.Context, workflowID, startedBy string, input map[string]interface{}) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.started = append(f.started, input)
+	return "exec-1", nil
+}
+
+func newTestService() (*Service, *fakeWorkflows) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	workflows := &fakeWorkflows{}
+	service := NewService(NewInMemoryRepository(), logger)
+	service.SetWorkflows(workflows)
+	return service, workflows
+}
+
+func pumpWorkOrder() *WorkOrder {
+	start := time.Date(2025, 10, 24, 2, 0, 0, 0, time.UTC)
+	return &WorkOrder{
+		Reference:  "WO-2025-1023-001",
+		Title:      "Replace PUMP-002 bearings",
+		Type:       "PREDICTIVE_MAINTENANCE",
+		Priority:   "critical",
+		AssetID:    "PUMP-002",
+		AssignedTo: "CREW-NORTH",
+		Tasks: []Task{
+			{Description: "Inspect and replace bearings"},
+			{Description: "Check motor alignment"},
+		},
+		Parts:      []Part{{Name: "Bearing set", PartNumber: "SKF 6308", Quantity: 2}},
+		Schedule:   &Window{Start: start, End: start.Add(6 * time.Hour)},
+		WorkflowID: "wf-pump-maintenance",
+		CreatedBy:  "COORD-NORTH",
+	}
+}
+
+func TestService_Lifecycle(t *testing.T) {
+	service, workflows := newTestService()
+	ctx := context.Background()
+
+	workOrder := pumpWorkOrder()
+	require.NoError(t, service.Create(ctx, workOrder))
+	assert.Equal(t, StatusPending, workOrder.Status)
+	assert.Equal(t, PriorityCritical, workOrder.Priority)
+
+	_, err := service.Transition(ctx, workOrder.ID, StatusInProgress, "CREW-NORTH", "")
+	assert.ErrorIs(t, err, ErrInvalidTransition, "work cannot start before approval")
+
+	approved, err := service.Approve(ctx, workOrder.ID, "supervisor", "window confirmed")
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, approved.Status)
+	assert.Equal(t, "exec-1", approved.ExecutionID)
+	require.Len(t, workflows.started, 1)
+	assert.Equal(t, workOrder.ID, workflows.started[0]["work_order_id"])
+	assert.Equal(t, "PUMP-002", workflows.started[0]["asset_id"])
+	assert.Equal(t, []string{"Inspect and replace bearings", "Check motor alignment"}, workflows.started[0]["tasks"])
+
+	_, err = service.Approve(ctx, workOrder.ID, "supervisor", "")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	_, err = service.Transition(ctx, workOrder.ID, StatusInProgress, "CREW-NORTH", "")
+	require.NoError(t, err)
+	_, err = service.CompleteTask(ctx, workOrder.ID, 0, "CREW-NORTH")
+	require.NoError(t, err)
+	_, err = service.CompleteTask(ctx, workOrder.ID, 5, "CREW-NORTH")
+	assert.ErrorIs(t, err, ErrInvalidWorkOrder)
+
+	_, err = service.Transition(ctx, workOrder.ID, StatusCompleted, "CREW-NORTH", "")
+	assert.ErrorIs(t, err, ErrInvalidTransition, "a task is not done")
+
+	// Changing the details keeps completed tasks
+	changes := pumpWorkOrder()
+	changes.ID = workOrder.ID
+	changes.Tasks = append(changes.Tasks, Task{Description: "Replace seals"})
+	updated, err := service.Update(ctx, changes)
+	require.NoError(t, err)
+	require.Len(t, updated.Tasks, 3)
+	assert.True(t, updated.Tasks[0].Done)
+	assert.False(t, updated.Tasks[2].Done)
+
+	changes.WorkflowID = "wf-other"
+	_, err = service.Update(ctx, changes)
+	assert.ErrorIs(t, err, ErrInvalidWorkOrder, "the workflow is fixed after approval")
+
+	for i := 1; i < 3; i++ {
+		_, err = service.CompleteTask(ctx, workOrder.ID, i, "CREW-NORTH")
+		require.NoError(t, err)
+	}
+	completed, err := service.Transition(ctx, workOrder.ID, StatusCompleted, "CREW-NORTH", "pump back in service")
+	require.NoError(t, err)
+	require.NotNil(t, completed.ClosedAt)
+
+	var statuses []Status
+	for _, change := range completed.History {
+		statuses = append(statuses, change.To)
+	}
+	assert.Equal(t, []Status{StatusPending, StatusApproved, StatusInProgress, StatusCompleted}, statuses)
+
+	_, err = service.Update(ctx, changes)
+	assert.ErrorIs(t, err, ErrInvalidTransition, "completed work orders are closed")
+
+	open, err := service.List(ctx, Filter{Open: true})
+	require.NoError(t, err)
+	assert.Empty(t, open)
+	byAsset, err := service.List(ctx, Filter{AssetID: "PUMP-002"})
+	require.NoError(t, err)
+	assert.Len(t, byAsset, 1)
+}
+
+func TestService_ApproveWithoutWorkflow(t *testing.T) {
+	service, workflows := newTestService()
+	ctx := context.Background()
+
+	workOrder := pumpWorkOrder()
+	workOrder.WorkflowID = ""
+	require.NoError(t, service.Create(ctx, workOrder))
+
+	approved, err := service.Transition(ctx, workOrder.ID, StatusApproved, "supervisor", "")
+	require.NoError(t, err)
+	assert.Equal(t, StatusApproved, approved.Status)
+	assert.Empty(t, approved.ExecutionID)
+	assert.Empty(t, workflows.started)
+}
+
+func TestService_ApproveWorkflowFailure(t *testing.T) {
+	service, workflows := newTestService()
+	workflows.err = errors.New("workflow not found")
+	ctx := context.Background()
+
+	workOrder := pumpWorkOrder()
+	require.NoError(t, service.Create(ctx, workOrder))
+
+	_, err := service.Approve(ctx, workOrder.ID, "supervisor", "")
+	assert.ErrorIs(t, err, ErrWorkflowStart)
+
+	stored, err := service.Get(ctx, workOrder.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, stored.Status, "work orders stay pending when their workflow cannot start")
+
+	_, err = service.Transition(ctx, workOrder.ID, StatusCancelled, "supervisor", "")
+	require.NoError(t, err)
+}
+
+func TestWorkOrder_Validate(t *testing.T) {
+	for name, mutate := range map[string]func(w *WorkOrder){
+		"no title":         func(w *WorkOrder) { w.Title = " " },
+		"unknown priority": func(w *WorkOrder) { w.Priority = "URGENT" },
+		"empty task":       func(w *WorkOrder) { w.Tasks = []Task{{}} },
+		"unnamed part":     func(w *WorkOrder) { w.Parts = []Part{{Quantity: 1}} },
+		"no quantity":      func(w *WorkOrder) { w.Parts = []Part{{Name: "Seal"}} },
+		"backwards window": func(w *WorkOrder) { w.Schedule.End = w.Schedule.Start.Add(-time.Hour) },
+		"open window":      func(w *WorkOrder) { w.Schedule.End = time.Time{} },
+	} {
+		workOrder := pumpWorkOrder()
+		workOrder.Priority = PriorityHigh
+		mutate(workOrder)
+		assert.ErrorIs(t, workOrder.Validate(), ErrInvalidWorkOrder, name)
+	}
+}
+
+// engineStore keeps copies of the workflows and executions the engine stores
+type engineStore struct {
+	orchestration.WorkflowRepository
+	workflows  map[string]*orchestration.Workflow
+	executions map[string]*orchestration.WorkflowExecution
+	mu         sync.Mutex
+}
+
+func (s *engineStore) StoreWorkflow(ctx context.Context, wf *orchestration.Workflow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workflows[wf.ID+"@"+wf.Version] = wf
+	return nil
+}
+
+func (s *engineStore) GetWorkflowVersion(ctx context.Context, workflowID, version string) (*orchestration.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wf, exists := s.workflows[workflowID+"@"+version]; exists {
+		return wf, nil
+	}
+	return nil, errors.New("workflow version not found")
+}
+
+func (s *engineStore) StoreExecution(ctx context.Context, execution *orchestration.WorkflowExecution) error {
+	return s.UpdateExecution(ctx, execution)
+}
+
+func (s *engineStore) UpdateExecution(ctx context.Context, execution *orchestration.WorkflowExecution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *execution
+	stored.TaskExecutions = make(map[string]*orchestration.TaskExecution, len(execution.TaskExecutions))
+	for id, taskExecution := range execution.TaskExecutions {
+		taskCopy := *taskExecution
+		stored.TaskExecutions[id] = &taskCopy
+	}
+	s.executions[execution.ID] = &stored
+	return nil
+}
+
+func (s *engineStore) GetExecution(ctx context.Context, executionID string) (*orchestration.WorkflowExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if execution, exists := s.executions[executionID]; exists {
+		return execution, nil
+	}
+	return nil, errors.New("execution not found")
+}
+
+// designs serves the workflows drawn in the designer
+type designs map[string]*workflow.Workflow
+
+func (d designs) GetWorkflow(ctx context.Context, id string) (*workflow.Workflow, error) {
+	if wf, exists := d[id]; exists {
+		return wf, nil
+	}
+	return nil, errors.New("workflow not found")
+}
+
+func TestService_ApproveRunsWorkflowOnEngine(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := &engineStore{
+		workflows:  make(map[string]*orchestration.Workflow),
+		executions: make(map[string]*orchestration.WorkflowExecution),
+	}
+	monitor := orchestration.NewMonitor(orchestration.DefaultMonitorConfig(), logger)
+	engine := orchestration.NewEngine(orchestration.OrchestrationConfig{}, nil, monitor, store, logger)
+	require.NoError(t, engine.Start())
+	defer engine.Stop()
+
+	// The maintenance workflow waits for the crew lead to sign the work off
+	service := NewService(NewInMemoryRepository(), logger)
+	service.SetWorkflows(orchestration.NewDesignLauncher(engine, designs{
+		"wf-pump-maintenance": {
+			ID: "wf-pump-maintenance",
+			Nodes: []workflow.Node{
+				{ID: "start", Type: workflow.NodeTypeStart},
+				{ID: "signoff", Type: workflow.NodeTypeWorkItem, Data: workflow.NodeData{WorkItemType: orchestration.TaskTypeManualApproval}},
+				{ID: "end", Type: workflow.NodeTypeEnd},
+			},
+			Edges: []workflow.Edge{
+				{ID: "e1", Source: "start", Target: "signoff"},
+				{ID: "e2", Source: "signoff", Target: "end"},
+			},
+		},
+	}))
+
+	workOrder := pumpWorkOrder()
+	require.NoError(t, service.Create(ctx, workOrder))
+	approved, err := service.Approve(ctx, workOrder.ID, "supervisor", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, approved.ExecutionID)
+
+	awaiting := func() bool {
+		execution, err := engine.GetExecution(ctx, approved.ExecutionID)
+		return err == nil && execution.TaskExecutions["signoff"].Status == orchestration.TaskStatusAwaitingApproval
+	}
+	require.Eventually(t, awaiting, 2*time.Second, time.Millisecond, "the engine runs the work order's workflow")
+
+	execution, err := engine.GetExecution(ctx, approved.ExecutionID)
+	require.NoError(t, err)
+	assert.Equal(t, "wf-pump-maintenance", execution.WorkflowID)
+	assert.Equal(t, "supervisor", execution.TriggeredBy)
+	assert.Equal(t, workOrder.ID, execution.Context["work_order_id"])
+
+	require.NoError(t, engine.ApproveTask(ctx, approved.ExecutionID, "signoff", orchestration.TaskApproval{Approved: true, Approver: "crew-lead"}))
+	require.Eventually(t, func() bool {
+		execution, err := engine.GetExecution(ctx, approved.ExecutionID)
+		return err == nil && execution.Status == orchestration.WorkflowStatusCompleted
+	}, 2*time.Second, time.Millisecond)
+}
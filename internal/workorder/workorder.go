@@ -0,0 +1,235 @@
+// Package workorder manages maintenance work orders: the tasks to carry out
+// on an asset, the parts they need, the window they are scheduled in and the
+// crew or agent assigned to them.
+//
+// Work orders are created pending and must be approved before work starts.
+// A work order may name a workflow that is started when it is approved, so
+// the orchestration of the work (isolating the asset, dispatching the crew,
+// recommissioning) follows from the approval.
+package workorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWorkOrderNotFound is returned when a work order does not exist
+	ErrWorkOrderNotFound = errors.New("work order not found")
+
+	// ErrInvalidWorkOrder is returned for work orders and changes that fail validation
+	ErrInvalidWorkOrder = errors.New("invalid work order")
+
+	// ErrInvalidTransition is returned when a work order cannot move to the requested status
+	ErrInvalidTransition = errors.New("invalid work order status transition")
+
+	// ErrWorkflowStart is returned when the workflow of an approved work order cannot be started
+	ErrWorkflowStart = errors.New("failed to start work order workflow")
+)
+
+// Priority is how urgent a work order is. Values match alert severities.
+type Priority string
+
+const (
+	PriorityLow      Priority = "LOW"
+	PriorityMedium   Priority = "MEDIUM"
+	PriorityHigh     Priority = "HIGH"
+	PriorityCritical Priority = "CRITICAL"
+)
+
+// ParsePriority reads a priority case-insensitively
+func ParsePriority(s string) (Priority, bool) {
+	priority := Priority(strings.ToUpper(strings.TrimSpace(s)))
+	switch priority {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityCritical:
+		return priority, true
+	}
+	return priority, false
+}
+
+// Status is where a work order is in its lifecycle
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusApproved   Status = "approved"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusCancelled  Status = "cancelled"
+)
+
+// statusTransitions lists the statuses each status may move to. Approval
+// goes through Service.Approve so the work order's workflow is started.
+var statusTransitions = map[Status][]Status{
+	StatusPending:    {StatusApproved, StatusCancelled},
+	StatusApproved:   {StatusInProgress, StatusCancelled},
+	StatusInProgress: {StatusCompleted, StatusCancelled},
+	StatusCompleted:  {},
+	StatusCancelled:  {},
+}
+
+// IsValid reports whether the status is one of the work order statuses
+func (s Status) IsValid() bool {
+	_, exists := statusTransitions[s]
+	return exists
+}
+
+// IsFinal reports whether a work order in this status is closed
+func (s Status) IsFinal() bool {
+	return s == StatusCompleted || s == StatusCancelled
+}
+
+// CanTransitionTo reports whether a work order may move from s to next
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Task is one piece of work in a work order
+type Task struct {
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	CompletedBy string     `json:"completed_by,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Part is a part a work order needs
+type Part struct {
+	Name       string `json:"name"`
+	PartNumber string `json:"part_number,omitempty"`
+	Quantity   int    `json:"quantity"`
+}
+
+// Window is the time a work order is scheduled to be carried out in
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// StatusChange records a work order moving between statuses
+type StatusChange struct {
+	From  Status    `json:"from,omitempty"`
+	To    Status    `json:"to"`
+	Actor string    `json:"actor,omitempty"`
+	Note  string    `json:"note,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// WorkOrder is a planned piece of maintenance work
+type WorkOrder struct {
+	// Key is the ArangoDB document key (same as ID)
+	Key string `json:"_key,omitempty"`
+
+	ID          string   `json:"id"`
+	Reference   string   `json:"reference,omitempty"` // External number, e.g. WO-2025-1023-001
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"` // e.g. PREDICTIVE_MAINTENANCE or REPAIR
+	Priority    Priority `json:"priority"`
+	Status      Status   `json:"status"`
+
+	// AssetID is the agent representing the asset worked on, e.g. a pump
+	AssetID string `json:"asset_id,omitempty"`
+
+	// AssignedTo is the crew or agent carrying out the work
+	AssignedTo string `json:"assigned_to,omitempty"`
+
+	Tasks    []Task  `json:"tasks"`
+	Parts    []Part  `json:"parts"`
+	Schedule *Window `json:"schedule,omitempty"`
+
+	// WorkflowID is the workflow started when the work order is approved;
+	// ExecutionID is the execution it started
+	WorkflowID  string `json:"workflow_id,omitempty"`
+	ExecutionID string `json:"execution_id,omitempty"`
+
+	History []StatusChange `json:"history"`
+
+	CreatedBy  string     `json:"created_by,omitempty"`
+	ApprovedBy string     `json:"approved_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+}
+
+// Validate checks the details of a work order
+func (w *WorkOrder) Validate() error {
+	if strings.TrimSpace(w.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidWorkOrder)
+	}
+	if _, ok := ParsePriority(string(w.Priority)); !ok {
+		return fmt.Errorf("%w: unknown priority %q", ErrInvalidWorkOrder, w.Priority)
+	}
+	for i, task := range w.Tasks {
+		if strings.TrimSpace(task.Description) == "" {
+			return fmt.Errorf("%w: task %d has no description", ErrInvalidWorkOrder, i)
+		}
+	}
+	for i, part := range w.Parts {
+		if strings.TrimSpace(part.Name) == "" {
+			return fmt.Errorf("%w: part %d has no name", ErrInvalidWorkOrder, i)
+		}
+		if part.Quantity <= 0 {
+			return fmt.Errorf("%w: part %q needs a positive quantity", ErrInvalidWorkOrder, part.Name)
+		}
+	}
+	if w.Schedule != nil {
+		if w.Schedule.Start.IsZero() || w.Schedule.End.IsZero() {
+			return fmt.Errorf("%w: schedule needs a start and an end", ErrInvalidWorkOrder)
+		}
+		if !w.Schedule.End.After(w.Schedule.Start) {
+			return fmt.Errorf("%w: schedule must end after it starts", ErrInvalidWorkOrder)
+		}
+	}
+	return nil
+}
+
+// Filter selects work orders. Zero fields do not filter.
+type Filter struct {
+	Status     Status
+	AssetID    string
+	AssignedTo string
+	Open       bool // Only work orders that are not completed or cancelled
+	Limit      int
+}
+
+// matches reports whether the filter selects a work order
+func (f Filter) matches(w *WorkOrder) bool {
+	if f.Status != "" && w.Status != f.Status {
+		return false
+	}
+	if f.AssetID != "" && w.AssetID != f.AssetID {
+		return false
+	}
+	if f.AssignedTo != "" && w.AssignedTo != f.AssignedTo {
+		return false
+	}
+	if f.Open && w.Status.IsFinal() {
+		return false
+	}
+	return true
+}
+
+// Repository stores work orders
+type Repository interface {
+	// Create stores a new work order
+	Create(ctx context.Context, workOrder *WorkOrder) error
+
+	// Get retrieves a work order by ID
+	Get(ctx context.Context, id string) (*WorkOrder, error)
+
+	// Update replaces an existing work order
+	Update(ctx context.Context, workOrder *WorkOrder) error
+
+	// List returns the work orders matching the filter, most recently updated first
+	List(ctx context.Context, filter Filter) ([]*WorkOrder, error)
+}
@@ -0,0 +1,150 @@
+package zonesummary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+)
+
+// Aggregate summarizes the publications that belong to a zone. A publication
+// belongs to the zone when its payload or metadata "zone" field equals it.
+func Aggregate(zone string, publications []communication.CapturedPublication, since, until time.Time) Activity {
+	activity := Activity{
+		Zone:   zone,
+		Since:  since,
+		Until:  until,
+		Events: []EventStats{},
+	}
+
+	type valueStats struct {
+		count         int
+		min, max, sum float64
+	}
+	counts := make(map[string]int)
+	values := make(map[string]*valueStats)
+
+	for _, pub := range publications {
+		if !inZone(pub, zone) {
+			continue
+		}
+		activity.Total++
+		counts[pub.EventName]++
+
+		if v, ok := toFloat(pub.Payload["value"]); ok {
+			vs := values[pub.EventName]
+			if vs == nil {
+				vs = &valueStats{min: v, max: v}
+				values[pub.EventName] = vs
+			}
+			vs.count++
+			vs.sum += v
+			if v < vs.min {
+				vs.min = v
+			}
+			if v > vs.max {
+				vs.max = v
+			}
+		}
+
+		switch {
+		case pub.PublicationType == communication.PublicationTypeAlert || strings.HasPrefix(pub.EventName, "alert."):
+			activity.Alerts = append(activity.Alerts, describe(pub))
+		case strings.HasPrefix(pub.EventName, "adjustment."):
+			activity.Adjustments = append(activity.Adjustments, describe(pub))
+		}
+	}
+
+	for name, count := range counts {
+		stats := EventStats{EventName: name, Count: count}
+		if vs := values[name]; vs != nil {
+			stats.Stats = fmt.Sprintf("min %.2f, max %.2f, avg %.2f", vs.min, vs.max, vs.sum/float64(vs.count))
+		}
+		activity.Events = append(activity.Events, stats)
+	}
+	sort.Slice(activity.Events, func(i, j int) bool {
+		if activity.Events[i].Count != activity.Events[j].Count {
+			return activity.Events[i].Count > activity.Events[j].Count
+		}
+		return activity.Events[i].EventName < activity.Events[j].EventName
+	})
+
+	activity.Alerts = lastN(activity.Alerts, maxRecentEvents)
+	activity.Adjustments = lastN(activity.Adjustments, maxRecentEvents)
+
+	return activity
+}
+
+// FallbackSummary describes activity without the LLM
+func FallbackSummary(activity Activity) string {
+	if activity.Total == 0 {
+		return fmt.Sprintf("Zone %s: no activity between %s and %s.",
+			activity.Zone, activity.Since.Format("15:04"), activity.Until.Format("15:04"))
+	}
+
+	parts := make([]string, 0, len(activity.Events))
+	for _, e := range activity.Events {
+		part := fmt.Sprintf("%d %s", e.Count, e.EventName)
+		if e.Stats != "" {
+			part += " (" + e.Stats + ")"
+		}
+		parts = append(parts, part)
+	}
+
+	text := fmt.Sprintf("Zone %s: %d events between %s and %s: %s.",
+		activity.Zone, activity.Total, activity.Since.Format("15:04"), activity.Until.Format("15:04"), strings.Join(parts, ", "))
+	if len(activity.Alerts) > 0 {
+		text += fmt.Sprintf(" %d alert(s), most recent: %s.", len(activity.Alerts), activity.Alerts[len(activity.Alerts)-1])
+	}
+	return text
+}
+
+func inZone(pub communication.CapturedPublication, zone string) bool {
+	if z, ok := pub.Payload["zone"].(string); ok && z == zone {
+		return true
+	}
+	return pub.Metadata["zone"] == zone
+}
+
+// describe renders a publication as a one-line event description
+func describe(pub communication.CapturedPublication) string {
+	keys := make([]string, 0, len(pub.Payload))
+	for k := range pub.Payload {
+		if k != "zone" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, pub.Payload[k]))
+	}
+
+	return fmt.Sprintf("%s %s from %s: %s",
+		pub.PublishedAt.Format("15:04"), pub.EventName, pub.PublisherAgentID, strings.Join(fields, " "))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func lastN(items []string, n int) []string {
+	if len(items) > n {
+		return items[len(items)-n:]
+	}
+	return items
+}
@@ -0,0 +1,40 @@
+package zonesummary
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notification is an operator-facing zone summary notification
+type Notification struct {
+	Zone      string    `json:"zone"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier delivers zone summary notifications to operators
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct {
+	logger *logrus.Logger
+}
+
+// NewLogNotifier creates a notifier that logs notifications
+func NewLogNotifier(logger *logrus.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Notify logs the notification
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.logger.WithFields(logrus.Fields{
+		"zone":  notification.Zone,
+		"title": notification.Title,
+	}).Info(notification.Message)
+	return nil
+}
@@ -0,0 +1,350 @@
+// Package zonesummary provides the zone coordinator summarization service.
+//
+// For each configured zone the service periodically aggregates recent pub/sub
+// activity (readings, alerts and adjustments), asks the LLM for a short status
+// summary, and publishes it to the zone's summary topic and, optionally, to the
+// notifier.
+package zonesummary
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/aosanya/CodeValdCortex/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PublisherAgentID is the agent ID summaries are published under
+	PublisherAgentID = "zone-coordinator"
+
+	// PublisherAgentType is the agent type summaries are published under
+	PublisherAgentType = "zone_coordinator"
+
+	defaultInterval   = 5 * time.Minute
+	maxRecentEvents   = 10
+	summaryTTL        = 24 * 60 * 60
+	llmRequestTimeout = 60 * time.Second
+)
+
+// DefaultSourceTopics are aggregated when a zone does not configure its own
+var DefaultSourceTopics = []string{"reading.#", "alert.#", "adjustment.#"}
+
+// DefaultPromptTemplate is used when a zone does not configure its own
+const DefaultPromptTemplate = `You are the coordinator for zone {{.Zone}}.
+Summarize the zone's status between {{.Since.Format "15:04"}} and {{.Until.Format "15:04"}} for an operator in 3-5 sentences.
+Lead with anything that needs attention, then overall condition. Do not invent data.
+
+Activity ({{.Total}} events):
+{{range .Events}}- {{.EventName}}: {{.Count}} events{{if .Stats}} ({{.Stats}}){{end}}
+{{end}}
+{{- if .Alerts}}
+Recent alerts:
+{{range .Alerts}}- {{.}}
+{{end}}{{end}}
+{{- if .Adjustments}}
+Recent adjustments:
+{{range .Adjustments}}- {{.}}
+{{end}}{{end}}`
+
+// ActivitySource reads recorded pub/sub traffic. PubSubService implements it.
+type ActivitySource interface {
+	CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error)
+}
+
+// ZoneConfig configures summarization for one zone
+type ZoneConfig struct {
+	Zone           string
+	Interval       time.Duration
+	Lookback       time.Duration
+	SourceTopics   []string
+	SummaryTopic   string
+	PromptTemplate string
+	Notify         bool
+}
+
+// ZoneConfigFromConfig converts application config into a ZoneConfig
+func ZoneConfigFromConfig(cfg config.ZoneSummaryConfig) ZoneConfig {
+	return ZoneConfig{
+		Zone:           cfg.Zone,
+		Interval:       time.Duration(cfg.IntervalSeconds) * time.Second,
+		Lookback:       time.Duration(cfg.LookbackSeconds) * time.Second,
+		SourceTopics:   cfg.SourceTopics,
+		SummaryTopic:   cfg.SummaryTopic,
+		PromptTemplate: cfg.PromptTemplate,
+		Notify:         cfg.Notify,
+	}
+}
+
+// withDefaults fills unset fields
+func (z ZoneConfig) withDefaults() ZoneConfig {
+	if z.Interval <= 0 {
+		z.Interval = defaultInterval
+	}
+	if z.Lookback <= 0 {
+		z.Lookback = z.Interval
+	}
+	if len(z.SourceTopics) == 0 {
+		z.SourceTopics = DefaultSourceTopics
+	}
+	if z.SummaryTopic == "" {
+		z.SummaryTopic = "zone." + z.Zone + ".summary"
+	}
+	if z.PromptTemplate == "" {
+		z.PromptTemplate = DefaultPromptTemplate
+	}
+	return z
+}
+
+// EventStats aggregates publications of one event name
+type EventStats struct {
+	EventName string `json:"event_name"`
+	Count     int    `json:"count"`
+
+	// Stats describes numeric "value" readings, e.g. "min 1.2, max 3.4, avg 2.1"
+	Stats string `json:"stats,omitempty"`
+}
+
+// Activity is the aggregated zone activity passed to the prompt template
+type Activity struct {
+	Zone        string       `json:"zone"`
+	Since       time.Time    `json:"since"`
+	Until       time.Time    `json:"until"`
+	Total       int          `json:"total"`
+	Events      []EventStats `json:"events"`
+	Alerts      []string     `json:"alerts,omitempty"`
+	Adjustments []string     `json:"adjustments,omitempty"`
+}
+
+// Summary is a generated zone status summary
+type Summary struct {
+	Zone          string    `json:"zone"`
+	Text          string    `json:"text"`
+	Activity      Activity  `json:"activity"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	PublicationID string    `json:"publication_id,omitempty"`
+
+	// Fallback is true when the LLM was unavailable and a plain aggregate
+	// summary was produced instead
+	Fallback bool `json:"fallback"`
+}
+
+// Service periodically summarizes zone activity
+type Service struct {
+	source    ActivitySource
+	publisher communication.TrafficPublisher
+	llm       ai.LLMClient
+	notifier  Notifier
+	logger    *logrus.Logger
+
+	mu     sync.Mutex
+	zones  map[string]ZoneConfig
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewService creates a zone summary service. llm may be nil, in which case
+// summaries are plain aggregates; notifier may be nil to disable notifications.
+func NewService(source ActivitySource, publisher communication.TrafficPublisher, llm ai.LLMClient, notifier Notifier, logger *logrus.Logger) *Service {
+	return &Service{
+		source:    source,
+		publisher: publisher,
+		llm:       llm,
+		notifier:  notifier,
+		logger:    logger,
+		zones:     make(map[string]ZoneConfig),
+	}
+}
+
+// AddZone registers a zone for summarization
+func (s *Service) AddZone(zone ZoneConfig) error {
+	if zone.Zone == "" {
+		return fmt.Errorf("zone is required")
+	}
+	zone = zone.withDefaults()
+	if _, err := template.New(zone.Zone).Parse(zone.PromptTemplate); err != nil {
+		return fmt.Errorf("invalid prompt template for zone %s: %w", zone.Zone, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones[zone.Zone] = zone
+	return nil
+}
+
+// Zones returns the configured zones
+func (s *Service) Zones() []ZoneConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zones := make([]ZoneConfig, 0, len(s.zones))
+	for _, z := range s.zones {
+		zones = append(zones, z)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Zone < zones[j].Zone })
+	return zones
+}
+
+// Start begins periodic summarization for all configured zones
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, zone := range s.zones {
+		s.wg.Add(1)
+		go s.run(ctx, zone)
+	}
+
+	s.logger.WithField("zones", len(s.zones)).Info("Zone summary service started")
+}
+
+// Stop halts periodic summarization and waits for running summaries to finish
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	s.wg.Wait()
+	s.logger.Info("Zone summary service stopped")
+}
+
+func (s *Service) run(ctx context.Context, zone ZoneConfig) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(zone.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.summarize(ctx, zone, now); err != nil {
+				s.logger.WithError(err).WithField("zone", zone.Zone).Warn("Zone summary failed")
+			}
+		}
+	}
+}
+
+// SummarizeZone generates and publishes a summary for a configured zone now
+func (s *Service) SummarizeZone(ctx context.Context, zone string) (*Summary, error) {
+	s.mu.Lock()
+	cfg, ok := s.zones[zone]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("zone %s is not configured", zone)
+	}
+	return s.summarize(ctx, cfg, time.Now())
+}
+
+func (s *Service) summarize(ctx context.Context, zone ZoneConfig, now time.Time) (*Summary, error) {
+	since := now.Add(-zone.Lookback)
+	capture, err := s.source.CaptureTraffic(ctx, zone.SourceTopics, since, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone activity: %w", err)
+	}
+
+	activity := Aggregate(zone.Zone, capture.Publications, since, now)
+	summary := &Summary{
+		Zone:        zone.Zone,
+		Activity:    activity,
+		GeneratedAt: now,
+	}
+
+	summary.Text, err = s.generate(ctx, zone, activity)
+	if err != nil {
+		s.logger.WithError(err).WithField("zone", zone.Zone).Warn("LLM summary failed, using aggregate summary")
+		summary.Text = FallbackSummary(activity)
+		summary.Fallback = true
+	}
+
+	payload := map[string]interface{}{
+		"zone":         zone.Zone,
+		"summary":      summary.Text,
+		"since":        since.Format(time.RFC3339),
+		"until":        now.Format(time.RFC3339),
+		"total_events": activity.Total,
+		"alert_count":  len(activity.Alerts),
+		"fallback":     summary.Fallback,
+	}
+	summary.PublicationID, err = s.publisher.Publish(ctx, PublisherAgentID, PublisherAgentType, zone.SummaryTopic, payload, &communication.PublicationOptions{
+		Type:       communication.PublicationTypeStatusChange,
+		TTLSeconds: summaryTTL,
+		Metadata:   map[string]string{"zone": zone.Zone},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish zone summary: %w", err)
+	}
+
+	if zone.Notify && s.notifier != nil {
+		if err := s.notifier.Notify(ctx, Notification{
+			Zone:      zone.Zone,
+			Title:     "Zone " + zone.Zone + " status",
+			Message:   summary.Text,
+			Timestamp: now,
+		}); err != nil {
+			s.logger.WithError(err).WithField("zone", zone.Zone).Warn("Failed to send zone summary notification")
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"zone":     zone.Zone,
+		"events":   activity.Total,
+		"fallback": summary.Fallback,
+	}).Info("Published zone summary")
+
+	return summary, nil
+}
+
+// generate renders the prompt and asks the LLM for a summary
+func (s *Service) generate(ctx context.Context, zone ZoneConfig, activity Activity) (string, error) {
+	if s.llm == nil {
+		return "", fmt.Errorf("no LLM client configured")
+	}
+
+	tmpl, err := template.New(zone.Zone).Parse(zone.PromptTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, activity); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, llmRequestTimeout)
+	defer cancel()
+
+	resp, err := s.llm.Chat(ai.WithOperation(ctx, "zone_summary.summarize"), &ai.ChatRequest{
+		Messages: []ai.Message{
+			{Role: "user", Content: prompt.String(), Timestamp: time.Now()},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(resp.Content)
+	if text == "" {
+		return "", fmt.Errorf("empty LLM response")
+	}
+	return text, nil
+}
@@ -0,0 +1,167 @@
+package zonesummary
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aosanya/CodeValdCortex/internal/builder/ai"
+	"github.com/aosanya/CodeValdCortex/internal/communication"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeSource struct {
+	publications []communication.CapturedPublication
+	topics       []string
+}
+
+func (f *fakeSource) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*communication.TrafficCapture, error) {
+	f.topics = topics
+	return &communication.TrafficCapture{Publications: f.publications}, nil
+}
+
+type published struct {
+	eventName string
+	payload   map[string]interface{}
+}
+
+type fakePublisher struct {
+	published []published
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, publisherAgentID, publisherAgentType, eventName string, payload map[string]interface{}, opts *communication.PublicationOptions) (string, error) {
+	f.published = append(f.published, published{eventName: eventName, payload: payload})
+	return "pub-1", nil
+}
+
+type fakeLLM struct {
+	ai.LLMClient
+	prompt string
+	err    error
+}
+
+func (f *fakeLLM) Chat(ctx context.Context, req *ai.ChatRequest) (*ai.ChatResponse, error) {
+	f.prompt = req.Messages[0].Content
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ai.ChatResponse{Content: "  Zone A is stable.  "}, nil
+}
+
+type fakeNotifier struct {
+	notifications []Notification
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, n Notification) error {
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func zoneActivity() []communication.CapturedPublication {
+	return []communication.CapturedPublication{
+		{EventName: "reading.moisture", PublisherAgentID: "sensor-1", Payload: map[string]interface{}{"zone": "zone-a", "value": 20.0}},
+		{EventName: "reading.moisture", PublisherAgentID: "sensor-2", Payload: map[string]interface{}{"zone": "zone-a", "value": 30.0}},
+		{EventName: "alert.low_moisture", PublisherAgentID: "sensor-1", PublicationType: communication.PublicationTypeAlert, Payload: map[string]interface{}{"zone": "zone-a", "severity": "HIGH"}},
+		{EventName: "adjustment.valve", PublisherAgentID: "valve-1", Metadata: map[string]string{"zone": "zone-a"}, Payload: map[string]interface{}{"open": true}},
+		{EventName: "reading.moisture", PublisherAgentID: "sensor-9", Payload: map[string]interface{}{"zone": "zone-b", "value": 99.0}},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	activity := Aggregate("zone-a", zoneActivity(), time.Now().Add(-time.Hour), time.Now())
+
+	if activity.Total != 4 {
+		t.Fatalf("expected 4 zone events, got %d", activity.Total)
+	}
+	if activity.Events[0].EventName != "reading.moisture" || activity.Events[0].Count != 2 {
+		t.Errorf("unexpected top event: %+v", activity.Events[0])
+	}
+	if activity.Events[0].Stats != "min 20.00, max 30.00, avg 25.00" {
+		t.Errorf("unexpected reading stats: %q", activity.Events[0].Stats)
+	}
+	if len(activity.Alerts) != 1 || !strings.Contains(activity.Alerts[0], "severity=HIGH") {
+		t.Errorf("unexpected alerts: %v", activity.Alerts)
+	}
+	if len(activity.Adjustments) != 1 {
+		t.Errorf("expected 1 adjustment, got %v", activity.Adjustments)
+	}
+}
+
+func TestSummarizeZone_PublishesLLMSummary(t *testing.T) {
+	source := &fakeSource{publications: zoneActivity()}
+	publisher := &fakePublisher{}
+	llm := &fakeLLM{}
+	notifier := &fakeNotifier{}
+
+	svc := NewService(source, publisher, llm, notifier, testLogger())
+	if err := svc.AddZone(ZoneConfig{Zone: "zone-a", Notify: true}); err != nil {
+		t.Fatalf("AddZone failed: %v", err)
+	}
+
+	summary, err := svc.SummarizeZone(context.Background(), "zone-a")
+	if err != nil {
+		t.Fatalf("SummarizeZone failed: %v", err)
+	}
+
+	if summary.Text != "Zone A is stable." || summary.Fallback {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(llm.prompt, "reading.moisture: 2 events") || !strings.Contains(llm.prompt, "Recent alerts:") {
+		t.Errorf("prompt missing activity:\n%s", llm.prompt)
+	}
+	if len(source.topics) != len(DefaultSourceTopics) {
+		t.Errorf("expected default source topics, got %v", source.topics)
+	}
+	if len(publisher.published) != 1 || publisher.published[0].eventName != "zone.zone-a.summary" {
+		t.Fatalf("expected summary on zone.zone-a.summary, got %+v", publisher.published)
+	}
+	if publisher.published[0].payload["summary"] != "Zone A is stable." {
+		t.Errorf("unexpected payload: %v", publisher.published[0].payload)
+	}
+	if len(notifier.notifications) != 1 {
+		t.Errorf("expected 1 notification, got %d", len(notifier.notifications))
+	}
+}
+
+func TestSummarizeZone_FallsBackWithoutLLM(t *testing.T) {
+	publisher := &fakePublisher{}
+	svc := NewService(&fakeSource{publications: zoneActivity()}, publisher, &fakeLLM{err: errors.New("unavailable")}, nil, testLogger())
+	if err := svc.AddZone(ZoneConfig{Zone: "zone-a", SummaryTopic: "status.zone-a"}); err != nil {
+		t.Fatalf("AddZone failed: %v", err)
+	}
+
+	summary, err := svc.SummarizeZone(context.Background(), "zone-a")
+	if err != nil {
+		t.Fatalf("SummarizeZone failed: %v", err)
+	}
+
+	if !summary.Fallback || !strings.Contains(summary.Text, "4 events") {
+		t.Errorf("unexpected fallback summary: %+v", summary)
+	}
+	if publisher.published[0].eventName != "status.zone-a" {
+		t.Errorf("expected configured summary topic, got %s", publisher.published[0].eventName)
+	}
+}
+
+func TestAddZone_Validation(t *testing.T) {
+	svc := NewService(&fakeSource{}, &fakePublisher{}, nil, nil, testLogger())
+
+	if err := svc.AddZone(ZoneConfig{}); err == nil {
+		t.Error("expected error for missing zone")
+	}
+	if err := svc.AddZone(ZoneConfig{Zone: "zone-a", PromptTemplate: "{{.Zone"}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+	if _, err := svc.SummarizeZone(context.Background(), "unknown"); err == nil {
+		t.Error("expected error for unconfigured zone")
+	}
+}
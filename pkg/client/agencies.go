@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Agency is a deployment of agents working towards shared goals
+type Agency struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	DisplayName string         `json:"display_name"`
+	Description string         `json:"description"`
+	Category    string         `json:"category"`
+	Icon        string         `json:"icon"`
+	Status      string         `json:"status"` // active, inactive, paused or archived
+	Database    string         `json:"database"`
+	TenantID    string         `json:"tenant_id,omitempty"`
+	Metadata    AgencyMetadata `json:"metadata"`
+	Settings    AgencySettings `json:"settings"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	CreatedBy   string         `json:"created_by"`
+}
+
+// AgencyMetadata is additional information about an agency
+type AgencyMetadata struct {
+	Location    string   `json:"location,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	TotalAgents int      `json:"total_agents"`
+	Zones       int      `json:"zones,omitempty"`
+	APIEndpoint string   `json:"api_endpoint,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// AgencySettings are the options of an agency
+type AgencySettings struct {
+	AutoStart         bool `json:"auto_start"`
+	MonitoringEnabled bool `json:"monitoring_enabled"`
+	DashboardEnabled  bool `json:"dashboard_enabled"`
+	VisualizerEnabled bool `json:"visualizer_enabled"`
+}
+
+// CreateAgencyRequest is an agency to create. The ID gets the "agency_"
+// prefix when it lacks it.
+type CreateAgencyRequest struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	DisplayName string         `json:"display_name"`
+	Description string         `json:"description,omitempty"`
+	Category    string         `json:"category"`
+	Icon        string         `json:"icon,omitempty"`
+	Metadata    AgencyMetadata `json:"metadata"`
+	Settings    AgencySettings `json:"settings"`
+}
+
+// AgencyFilters filters the agency list. Zero fields do not filter.
+type AgencyFilters struct {
+	Category string
+	Status   string
+	Search   string // Matches names and descriptions
+	Limit    int
+	Offset   int
+}
+
+// Goal is a goal statement an agency is solving
+type Goal struct {
+	Key               string    `json:"_key"`
+	AgencyID          string    `json:"agency_id"`
+	Number            int       `json:"number"`
+	Code              string    `json:"code"`
+	Description       string    `json:"description"`
+	Scope             string    `json:"scope"`
+	SuccessMetrics    []string  `json:"success_metrics"`
+	Priority          string    `json:"priority"`
+	Status            string    `json:"status"`
+	Category          string    `json:"category"`
+	Tags              []string  `json:"tags"`
+	Rank              int       `json:"rank,omitempty"`
+	PriorityRationale string    `json:"priority_rationale,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// GoalRequest creates or replaces a goal
+type GoalRequest struct {
+	Code           string   `json:"code"`
+	Description    string   `json:"description"`
+	Scope          string   `json:"scope,omitempty"`
+	SuccessMetrics []string `json:"success_metrics,omitempty"`
+	Priority       string   `json:"priority,omitempty"` // High, Medium or Low
+	Status         string   `json:"status,omitempty"`   // Draft, Active, Resolved or Archived
+	Category       string   `json:"category,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+// WorkItem is a unit of work of an agency
+type WorkItem struct {
+	Key                  string               `json:"_key"`
+	AgencyID             string               `json:"agency_id"`
+	Number               int                  `json:"number"`
+	Code                 string               `json:"code"`
+	Title                string               `json:"title"`
+	Description          string               `json:"description"`
+	Deliverables         []string             `json:"deliverables"`
+	Dependencies         []string             `json:"dependencies"`
+	Tags                 []string             `json:"tags,omitempty"`
+	RequiredCapabilities []string             `json:"required_capabilities,omitempty"`
+	GoalKeys             []string             `json:"goal_keys,omitempty"`
+	Status               string               `json:"status,omitempty"` // todo, in_progress, blocked or done
+	StatusHistory        []WorkItemTransition `json:"status_history,omitempty"`
+	Assignee             string               `json:"assignee,omitempty"`
+	AssignedAt           *time.Time           `json:"assigned_at,omitempty"`
+	CreatedAt            time.Time            `json:"created_at"`
+	UpdatedAt            time.Time            `json:"updated_at"`
+}
+
+// WorkItemTransition is a change of a work item's status
+type WorkItemTransition struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// WorkItemRequest creates or replaces a work item
+type WorkItemRequest struct {
+	Title                string   `json:"title"`
+	Description          string   `json:"description"`
+	Deliverables         []string `json:"deliverables,omitempty"`
+	Dependencies         []string `json:"dependencies,omitempty"` // Codes of other work items
+	Tags                 []string `json:"tags,omitempty"`
+	RequiredCapabilities []string `json:"required_capabilities,omitempty"`
+	GoalKeys             []string `json:"goal_keys,omitempty"`
+}
+
+// CreateAgency creates an agency
+func (c *Client) CreateAgency(ctx context.Context, req CreateAgencyRequest) (*Agency, error) {
+	var agency Agency
+	if err := c.do(ctx, http.MethodPost, apiPath("agencies"), nil, req, &agency); err != nil {
+		return nil, err
+	}
+	return &agency, nil
+}
+
+// GetAgency returns an agency
+func (c *Client) GetAgency(ctx context.Context, id string) (*Agency, error) {
+	var agency Agency
+	if err := c.do(ctx, http.MethodGet, apiPath("agencies", id), nil, nil, &agency); err != nil {
+		return nil, err
+	}
+	return &agency, nil
+}
+
+// ListAgencies lists agencies
+func (c *Client) ListAgencies(ctx context.Context, filters AgencyFilters) ([]Agency, error) {
+	values := url.Values{}
+	setString(values, "category", filters.Category)
+	setString(values, "status", filters.Status)
+	setString(values, "search", filters.Search)
+	setInt(values, "limit", filters.Limit)
+	setInt(values, "offset", filters.Offset)
+
+	var agencies []Agency
+	if err := c.do(ctx, http.MethodGet, apiPath("agencies"), values, nil, &agencies); err != nil {
+		return nil, err
+	}
+	return agencies, nil
+}
+
+// DeleteAgency deletes an agency
+func (c *Client) DeleteAgency(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, apiPath("agencies", id), nil, nil, nil)
+}
+
+// ActivateAgency makes an agency the active one
+func (c *Client) ActivateAgency(ctx context.Context, id string) (*Agency, error) {
+	var agency Agency
+	if err := c.do(ctx, http.MethodPost, apiPath("agencies", id, "activate"), nil, nil, &agency); err != nil {
+		return nil, err
+	}
+	return &agency, nil
+}
+
+// ListGoals lists an agency's goals
+func (c *Client) ListGoals(ctx context.Context, agencyID string) ([]Goal, error) {
+	var goals []Goal
+	if err := c.do(ctx, http.MethodGet, apiPath("agencies", agencyID, "goals"), nil, nil, &goals); err != nil {
+		return nil, err
+	}
+	return goals, nil
+}
+
+// CreateGoal adds a goal to an agency. Only the code and description are
+// set on creation; use UpdateGoal for the other fields.
+func (c *Client) CreateGoal(ctx context.Context, agencyID string, req GoalRequest) (*Goal, error) {
+	var goal Goal
+	if err := c.do(ctx, http.MethodPost, apiPath("agencies", agencyID, "goals"), nil, req, &goal); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// UpdateGoal replaces a goal
+func (c *Client) UpdateGoal(ctx context.Context, agencyID, goalKey string, req GoalRequest) error {
+	return c.do(ctx, http.MethodPut, apiPath("agencies", agencyID, "goals", goalKey), nil, req, nil)
+}
+
+// DeleteGoal deletes a goal
+func (c *Client) DeleteGoal(ctx context.Context, agencyID, goalKey string) error {
+	return c.do(ctx, http.MethodDelete, apiPath("agencies", agencyID, "goals", goalKey), nil, nil, nil)
+}
+
+// ListWorkItems lists an agency's work items
+func (c *Client) ListWorkItems(ctx context.Context, agencyID string) ([]WorkItem, error) {
+	var items []WorkItem
+	if err := c.do(ctx, http.MethodGet, apiPath("agencies", agencyID, "work-items"), nil, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreateWorkItem adds a work item to an agency
+func (c *Client) CreateWorkItem(ctx context.Context, agencyID string, req WorkItemRequest) (*WorkItem, error) {
+	var item WorkItem
+	if err := c.do(ctx, http.MethodPost, apiPath("agencies", agencyID, "work-items"), nil, req, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateWorkItem replaces a work item
+func (c *Client) UpdateWorkItem(ctx context.Context, agencyID, key string, req WorkItemRequest) (*WorkItem, error) {
+	var item WorkItem
+	if err := c.do(ctx, http.MethodPut, apiPath("agencies", agencyID, "work-items", key), nil, req, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// TransitionWorkItem moves a work item to another status
+func (c *Client) TransitionWorkItem(ctx context.Context, agencyID, key, status, reason string) (*WorkItem, error) {
+	req := struct {
+		Status string `json:"status"`
+		Reason string `json:"reason,omitempty"`
+	}{status, reason}
+
+	var item WorkItem
+	if err := c.do(ctx, http.MethodPatch, apiPath("agencies", agencyID, "work-items", key, "status"), nil, req, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AssignWorkItem assigns a work item to an agent, or unassigns it when
+// agentID is empty
+func (c *Client) AssignWorkItem(ctx context.Context, agencyID, key, agentID string) (*WorkItem, error) {
+	req := struct {
+		AgentID string `json:"agent_id"`
+	}{agentID}
+
+	var item WorkItem
+	if err := c.do(ctx, http.MethodPatch, apiPath("agencies", agencyID, "work-items", key, "assignee"), nil, req, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteWorkItem deletes a work item
+func (c *Client) DeleteWorkItem(ctx context.Context, agencyID, key string) error {
+	return c.do(ctx, http.MethodDelete, apiPath("agencies", agencyID, "work-items", key), nil, nil, nil)
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Agent liveness statuses
+const (
+	AgentOnline   = "online"
+	AgentDegraded = "degraded"
+	AgentOffline  = "offline"
+)
+
+// Agent is an agent known to the framework and its liveness
+type Agent struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	State         string            `json:"state"` // created, running, paused, stopped or failed
+	Metadata      map[string]string `json:"metadata"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Status        string            `json:"status"` // online, degraded or offline
+	StatusReason  string            `json:"status_reason,omitempty"`
+	LastSeen      time.Time         `json:"last_seen"`
+	Capabilities  []string          `json:"capabilities"`
+	LastHeartbeat time.Time         `json:"last_heartbeat"`
+	HealthScore   *int              `json:"health_score,omitempty"` // Latest 0-100 health score, nil until scored
+	HealthFlags   []string          `json:"health_flags,omitempty"` // Anomaly flags such as heartbeat_late or task_failures
+}
+
+// AgentLiveness is an agent's registration and liveness status
+type AgentLiveness struct {
+	AgentID      string    `json:"agent_id"`
+	Status       string    `json:"status"`
+	Reason       string    `json:"reason,omitempty"`
+	Capabilities []string  `json:"capabilities"`
+	RegisteredAt time.Time `json:"registered_at"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// ListAgents lists agents, only those with the given liveness status unless
+// it is empty
+func (c *Client) ListAgents(ctx context.Context, status string) ([]Agent, error) {
+	values := url.Values{}
+	setString(values, "status", status)
+
+	var agents []Agent
+	if err := c.do(ctx, http.MethodGet, apiPath("agents"), values, nil, &agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// RegisterAgent records that an agent started with the given capabilities.
+// Agents register on startup and again whenever a heartbeat is refused
+// with 404, which happens after the framework restarts.
+func (c *Client) RegisterAgent(ctx context.Context, agentID string, capabilities []string) (*AgentLiveness, error) {
+	req := struct {
+		Capabilities []string `json:"capabilities"`
+	}{capabilities}
+
+	var liveness AgentLiveness
+	if err := c.do(ctx, http.MethodPost, apiPath("agents", agentID, "register"), nil, req, &liveness); err != nil {
+		return nil, err
+	}
+	return &liveness, nil
+}
+
+// Heartbeat reports that an agent is alive. A non-empty reason reports the
+// agent as degraded.
+func (c *Client) Heartbeat(ctx context.Context, agentID, degradedReason string) (*AgentLiveness, error) {
+	req := struct {
+		Status string `json:"status,omitempty"`
+		Reason string `json:"reason,omitempty"`
+	}{Reason: degradedReason}
+	if degradedReason != "" {
+		req.Status = AgentDegraded
+	}
+
+	var liveness AgentLiveness
+	if err := c.do(ctx, http.MethodPost, apiPath("agents", agentID, "heartbeat"), nil, req, &liveness); err != nil {
+		return nil, err
+	}
+	return &liveness, nil
+}
+
+// Agent lifecycle commands
+const (
+	CommandStop    = "stop"
+	CommandRestart = "restart"
+	CommandDrain   = "drain" // Finish current tasks, accept no new ones, then stop
+)
+
+// AgentCommand is a lifecycle command sent to an agent
+type AgentCommand struct {
+	ID             string     `json:"id"`
+	AgentID        string     `json:"agent_id"`
+	Command        string     `json:"command"`
+	Reason         string     `json:"reason,omitempty"`
+	IssuedBy       string     `json:"issued_by,omitempty"`
+	Status         string     `json:"status"` // executing, completed or failed
+	Error          string     `json:"error,omitempty"`
+	Delivery       string     `json:"delivery"` // Status of the command message
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	IssuedAt       time.Time  `json:"issued_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// SendAgentCommand stops, restarts or drains an agent
+func (c *Client) SendAgentCommand(ctx context.Context, agentID, command, reason string) (*AgentCommand, error) {
+	req := struct {
+		Command string `json:"command"`
+		Reason  string `json:"reason,omitempty"`
+	}{command, reason}
+
+	var cmd AgentCommand
+	if err := c.do(ctx, http.MethodPost, apiPath("agents", agentID, "commands"), nil, req, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// GetAgentCommand returns a lifecycle command sent to an agent
+func (c *Client) GetAgentCommand(ctx context.Context, agentID, commandID string) (*AgentCommand, error) {
+	var cmd AgentCommand
+	if err := c.do(ctx, http.MethodGet, apiPath("agents", agentID, "commands", commandID), nil, nil, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+// AcknowledgeAgentCommand records that an agent received a lifecycle
+// command. Agents call it for the command messages they receive.
+func (c *Client) AcknowledgeAgentCommand(ctx context.Context, agentID, commandID string) (*AgentCommand, error) {
+	var cmd AgentCommand
+	if err := c.do(ctx, http.MethodPost, apiPath("agents", agentID, "commands", commandID, "ack"), nil, nil, &cmd); err != nil {
+		return nil, err
+	}
+	return &cmd, nil
+}
@@ -0,0 +1,344 @@
+// Package client is a Go client for the CodeValdCortex HTTP API.
+//
+// It wraps the communications, agency, workflow and agent memory endpoints
+// with typed requests and responses. Every call takes a context, and requests
+// refused with 429 Too Many Requests or failing with a network error or a
+// 502, 503 or 504 response are retried with exponential backoff, honouring
+// the server's Retry-After header. Only requests that are safe to repeat are
+// retried after a network error or a gateway failure; 429 responses are
+// always retried, as the server refuses them before handling them.
+//
+//	c, err := client.New(client.Config{BaseURL: "http://localhost:8083", APIKey: key})
+//	if err != nil {
+//		return err
+//	}
+//	sent, err := c.SendMessage(ctx, client.SendMessageRequest{
+//		FromAgentID: "SENSOR-001",
+//		ToAgentID:   "PIPE-001",
+//		MessageType: "alert",
+//		Payload:     map[string]interface{}{"pressure_bar": 4.5},
+//	})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is the path of the API version the client speaks
+const apiPrefix = "/api/v1"
+
+// Defaults of Config
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultMaxAttempts = 3
+	defaultBaseBackoff = 250 * time.Millisecond
+	defaultMaxBackoff  = 10 * time.Second
+)
+
+// Config configures a Client
+type Config struct {
+	// BaseURL is the address of the server, e.g. http://localhost:8083
+	BaseURL string
+
+	// APIKey is sent as a bearer token when set
+	APIKey string
+
+	// HTTPClient sends the requests (default: a client with a 30s timeout)
+	HTTPClient *http.Client
+
+	// MaxAttempts is the number of times a request is tried (default 3).
+	// Set it to 1 to disable retries.
+	MaxAttempts int
+
+	// BaseBackoff is the delay after the first failed attempt; it doubles
+	// with every further attempt up to MaxBackoff. A Retry-After header
+	// overrides it.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// UserAgent identifies the program in the server's logs
+	UserAgent string
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.HTTPClient == nil {
+		c.HTTPClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaultBaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = "codevaldcortex-go-client"
+	}
+	return c
+}
+
+// Client calls the CodeValdCortex API. It is safe for concurrent use.
+type Client struct {
+	config  Config
+	baseURL *url.URL
+}
+
+// New creates a client
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("base URL is required")
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", cfg.BaseURL)
+	}
+	return &Client{
+		config:  cfg.withDefaults(),
+		baseURL: baseURL,
+	}, nil
+}
+
+// APIError is an error response from the server
+type APIError struct {
+	StatusCode int
+	Code       string        // Machine-readable error code, when the server sent one
+	Message    string        // Human-readable description
+	RetryAfter time.Duration // From the Retry-After header, if any
+	Body       []byte        // The raw response body
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" && e.Code != msg {
+		return fmt.Sprintf("codevaldcortex: %d %s: %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("codevaldcortex: %d: %s", e.StatusCode, msg)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsRateLimited reports whether err is a 429 response, once retries ran out
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// newAPIError reads an error response. Handlers answer with either
+// {"error": "...", "message": "..."} or {"error": {"code": ..., "message": ...}}.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		Body:       body,
+	}
+
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Details interface{}     `json:"details"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil || len(parsed.Error) == 0 {
+		apiErr.Message = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	var text string
+	var info struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	switch {
+	case json.Unmarshal(parsed.Error, &text) == nil:
+		if parsed.Message != "" {
+			apiErr.Code, apiErr.Message = text, parsed.Message
+		} else {
+			apiErr.Message = text
+		}
+		if details, ok := parsed.Details.(string); ok && details != "" {
+			apiErr.Message += ": " + details
+		}
+	case json.Unmarshal(parsed.Error, &info) == nil:
+		apiErr.Code, apiErr.Message = info.Code, info.Message
+	}
+	return apiErr
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// apiPath builds the escaped path of an API endpoint from its segments
+func apiPath(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return apiPrefix + "/" + strings.Join(escaped, "/")
+}
+
+// idempotent reports whether requests with the method may be repeated
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay before the next attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.config.BaseBackoff
+	for i := 1; i < attempt && delay < c.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, c.config.MaxBackoff)
+}
+
+// do sends a request to an escaped API path and decodes the JSON response
+// into out, which may be nil. Query values and a JSON body are optional.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send sends a request, retrying it as the retry policy allows, and returns
+// the first successful response. Error responses are returned as *APIError.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+	}
+
+	endpoint := c.baseURL.JoinPath(path)
+	endpoint.RawQuery = query.Encode()
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if body == nil {
+			req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.config.UserAgent)
+		if c.config.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+
+		var failure error
+		var wait time.Duration
+		retryable := false
+		resp, err := c.config.HTTPClient.Do(req)
+		switch {
+		case err != nil:
+			failure = fmt.Errorf("%s %s: %w", method, path, err)
+			retryable = idempotent(method) && ctx.Err() == nil
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return resp, nil
+		default:
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			apiErr := newAPIError(resp, data)
+			failure = apiErr
+			wait = apiErr.RetryAfter
+			switch resp.StatusCode {
+			case http.StatusTooManyRequests:
+				retryable = true
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				retryable = idempotent(method)
+			}
+		}
+
+		if !retryable || attempt >= c.config.MaxAttempts {
+			return nil, failure
+		}
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (last error: %v)", ctx.Err(), failure)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Health reports whether the server is up
+func (c *Client) Health(ctx context.Context) error {
+	resp, err := c.send(ctx, http.MethodGet, "/health", nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// WaitReady polls the health endpoint every interval until the server
+// answers or ctx is done
+func (c *Client) WaitReady(ctx context.Context, interval time.Duration) error {
+	for {
+		err := c.Health(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server not ready: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := New(Config{
+		BaseURL:     server.URL,
+		APIKey:      "secret",
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew_ValidatesBaseURL(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+	_, err = New(Config{BaseURL: "localhost:8083"})
+	assert.Error(t, err)
+	_, err = New(Config{BaseURL: "http://localhost:8083/"})
+	assert.NoError(t, err)
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/communications/messages", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req SendMessageRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "SENSOR-001", req.FromAgentID)
+		assert.Equal(t, 4.5, req.Payload["pressure_bar"])
+
+		w.Write([]byte(`{"message_id":"m-1","status":"sent"}`))
+	})
+
+	resp, err := c.SendMessage(context.Background(), SendMessageRequest{
+		FromAgentID: "SENSOR-001",
+		ToAgentID:   "PIPE-001",
+		MessageType: "alert",
+		Payload:     map[string]interface{}{"pressure_bar": 4.5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "m-1", resp.MessageID)
+}
+
+func TestClient_EscapesPathsAndQueries(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/communications/agents/acme--pipe%2F1/publications", r.URL.EscapedPath())
+		assert.Equal(t, "2026-01-01T00:00:00Z", r.URL.Query().Get("since"))
+		w.Write([]byte(`[{"_key":"p-1","event_name":"zone.north.leak.detected"}]`))
+	})
+
+	pubs, err := c.PullPublications(context.Background(), "acme--pipe/1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, pubs, 1)
+	assert.Equal(t, "p-1", pubs[0].ID)
+}
+
+func TestClient_RetriesIdempotentRequests(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"agency_1","name":"water"}`))
+	})
+
+	agency, err := c.GetAgency(context.Background(), "agency_1")
+	require.NoError(t, err)
+	assert.Equal(t, "water", agency.Name)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestClient_DoesNotRetryFailedPosts(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"Failed to publish message"}`))
+	})
+
+	_, err := c.Publish(context.Background(), PublishRequest{PublisherAgentID: "a", EventName: "e"})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, "Failed to publish message", apiErr.Message)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient_RetriesRateLimitedRequests(t *testing.T) {
+	var attempts atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"rate_limited","message":"Rate limit exceeded","retry_after_seconds":1}`))
+			return
+		}
+		w.Write([]byte(`{"publication_id":"p-1","status":"published"}`))
+	})
+
+	start := time.Now()
+	resp, err := c.Publish(context.Background(), PublishRequest{PublisherAgentID: "a", EventName: "e"})
+	require.NoError(t, err)
+	assert.Equal(t, "p-1", resp.PublicationID)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "Retry-After is honoured")
+}
+
+func TestClient_GivesUpWhenContextEnds(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"rate_limited","message":"Rate limit exceeded"}`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.ListAgencies(ctx, AgencyFilters{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_DecodesErrorResponses(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"success":false,"error":{"code":"IMPORT_FAILED","message":"Failed to import agent memory"}}`))
+	})
+
+	_, err := c.ImportMemory(context.Background(), "agent-1", ImportMemoryRequest{Archive: MemoryArchive(`{}`)})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "IMPORT_FAILED", apiErr.Code)
+	assert.Equal(t, "Failed to import agent memory", apiErr.Message)
+	assert.False(t, IsNotFound(err))
+}
+
+func TestClient_UnwrapsMemoryResponses(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/agents/agent-2/memory/import", r.URL.Path)
+		var req ImportMemoryRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.JSONEq(t, `{"agent_id":"agent-1"}`, string(req.Archive))
+		w.Write([]byte(`{"success":true,"data":{"source_agent_id":"agent-1","target_agent_id":"agent-2","working":3}}`))
+	})
+
+	result, err := c.ImportMemory(context.Background(), "agent-2", ImportMemoryRequest{Archive: MemoryArchive(`{"agent_id":"agent-1"}`)})
+	require.NoError(t, err)
+	assert.Equal(t, "agent-1", result.SourceAgentID)
+	assert.Equal(t, 3, result.Working)
+}
+
+func TestClient_Heartbeat(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/agents/pump-1/heartbeat", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]string{"status": "degraded", "reason": "battery low"}, req)
+		w.Write([]byte(`{"agent_id":"pump-1","status":"degraded","reason":"battery low","capabilities":["pressure"]}`))
+	})
+
+	liveness, err := c.Heartbeat(context.Background(), "pump-1", "battery low")
+	require.NoError(t, err)
+	assert.Equal(t, AgentDegraded, liveness.Status)
+	assert.Equal(t, []string{"pressure"}, liveness.Capabilities)
+}
+
+func TestClient_AdvanceClock(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/simulation/clock/advance", r.URL.Path)
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]string{"duration": "168h0m0s"}, req)
+		w.Write([]byte(`{"now":"2025-01-08T00:00:00Z","speed":1,"paused":true}`))
+	})
+
+	status, err := c.AdvanceClock(context.Background(), 168*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), status.Now)
+	assert.True(t, status.Paused)
+}
+
+func TestClient_QueryTelemetry(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/telemetry/query", r.URL.Path)
+		assert.Equal(t, "efficiency_percent", r.URL.Query().Get("metric"))
+		assert.Equal(t, "2025-10-01T00:00:00Z", r.URL.Query().Get("from"))
+		assert.Equal(t, "min", r.URL.Query().Get("aggregate"))
+		assert.Equal(t, "168h0m0s", r.URL.Query().Get("interval"))
+		w.Write([]byte(`{"metric":"efficiency_percent","aggregate":"min","buckets":[{"start":"2025-09-25T00:00:00Z","value":82.1,"count":3}]}`))
+	})
+
+	result, err := c.QueryTelemetry(context.Background(), TelemetryQuery{
+		Metric:    "efficiency_percent",
+		From:      time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+		Aggregate: "min",
+		Interval:  168 * time.Hour,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Buckets, 1)
+	assert.Equal(t, 82.1, result.Buckets[0].Value)
+}
+
+func TestClient_IsolationValves(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/topology/assets/PIPE-001/isolation-valves", r.URL.Path)
+		w.Write([]byte(`{"asset_id":"PIPE-001","upstream":[{"asset":{"id":"VALVE-001","type":"valve"},"depth":1,"path":["PIPE-001","VALVE-001"]}],"downstream":[]}`))
+	})
+
+	boundary, err := c.IsolationValves(context.Background(), "PIPE-001")
+	require.NoError(t, err)
+	require.Len(t, boundary.Upstream, 1)
+	assert.Equal(t, "VALVE-001", boundary.Upstream[0].Asset.ID)
+	assert.Empty(t, boundary.Downstream)
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a direct message between agents
+type Message struct {
+	ID             string                 `json:"_key"`
+	FromAgentID    string                 `json:"from_agent_id"`
+	ToAgentID      string                 `json:"to_agent_id"`
+	MessageType    string                 `json:"message_type"`
+	Payload        map[string]interface{} `json:"payload"`
+	Status         string                 `json:"status"`
+	Priority       int                    `json:"priority"`
+	CreatedAt      time.Time              `json:"created_at"`
+	DeliveredAt    *time.Time             `json:"delivered_at,omitempty"`
+	AcknowledgedAt *time.Time             `json:"acknowledged_at,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	CorrelationID  string                 `json:"correlation_id,omitempty"`
+	ReplyTo        string                 `json:"reply_to,omitempty"`
+	Metadata       map[string]string      `json:"metadata,omitempty"`
+	Sequence       int64                  `json:"sequence,omitempty"`
+}
+
+// SendMessageRequest is a direct message to send
+type SendMessageRequest struct {
+	FromAgentID   string                 `json:"from_agent_id"`
+	ToAgentID     string                 `json:"to_agent_id"`
+	MessageType   string                 `json:"message_type"`
+	Payload       map[string]interface{} `json:"payload"`
+	Priority      int                    `json:"priority,omitempty"` // 1-10, higher is more important
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	ReplyTo       string                 `json:"reply_to,omitempty"`
+	TTL           int                    `json:"ttl,omitempty"` // Seconds until the message expires
+	Metadata      map[string]string      `json:"metadata,omitempty"`
+}
+
+// SendMessageResponse identifies a sent message
+type SendMessageResponse struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+}
+
+// RequestMessageRequest is a direct message whose reply is awaited
+type RequestMessageRequest struct {
+	SendMessageRequest
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"` // Default 30, at most 300
+}
+
+// RequestMessageResponse is the reply to a request message
+type RequestMessageResponse struct {
+	CorrelationID string   `json:"correlation_id"`
+	Reply         *Message `json:"reply"`
+}
+
+// MessageQuery filters the direct message history. Zero fields do not filter.
+type MessageQuery struct {
+	FromAgentID string
+	ToAgentID   string
+	MessageType string
+	Since       time.Time
+	Until       time.Time
+	MinPriority int
+	MaxPriority int
+	Limit       int // Default 100, at most 1000
+	Offset      int
+}
+
+// values encodes the query as URL parameters
+func (q MessageQuery) values() url.Values {
+	values := url.Values{}
+	setString(values, "from_agent_id", q.FromAgentID)
+	setString(values, "to_agent_id", q.ToAgentID)
+	setString(values, "message_type", q.MessageType)
+	setTime(values, "since", q.Since)
+	setTime(values, "until", q.Until)
+	setInt(values, "min_priority", q.MinPriority)
+	setInt(values, "max_priority", q.MaxPriority)
+	setInt(values, "limit", q.Limit)
+	setInt(values, "offset", q.Offset)
+	return values
+}
+
+// PublishRequest is a publication to a topic
+type PublishRequest struct {
+	PublisherAgentID   string                 `json:"publisher_agent_id"`
+	PublisherAgentType string                 `json:"publisher_agent_type,omitempty"`
+	EventName          string                 `json:"event_name"`
+	Payload            map[string]interface{} `json:"payload"`
+	PublicationType    string                 `json:"publication_type,omitempty"` // status_change, event, metric, alert or broadcast
+	TTLSeconds         int                    `json:"ttl_seconds,omitempty"`
+	Metadata           map[string]string      `json:"metadata,omitempty"`
+}
+
+// PublishResponse identifies a publication
+type PublishResponse struct {
+	PublicationID string `json:"publication_id"`
+	Status        string `json:"status"`
+}
+
+// BatchResult is the outcome of one publication of a batch
+type BatchResult struct {
+	Index         int    `json:"index"`
+	PublicationID string `json:"publication_id,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// PublishBatchResponse is the outcome of a batch, in request order
+type PublishBatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Published int           `json:"published"`
+}
+
+// Publication is a published event or status update
+type Publication struct {
+	ID                 string                 `json:"_key"`
+	PublisherAgentID   string                 `json:"publisher_agent_id"`
+	PublisherAgentType string                 `json:"publisher_agent_type"`
+	PublicationType    string                 `json:"publication_type"`
+	EventName          string                 `json:"event_name"`
+	Payload            map[string]interface{} `json:"payload"`
+	PublishedAt        time.Time              `json:"published_at"`
+	TTLSeconds         int                    `json:"ttl_seconds"`
+	ExpiresAt          time.Time              `json:"expires_at"`
+	Metadata           map[string]string      `json:"metadata,omitempty"`
+}
+
+// CapturedPublication is a publication recorded in a traffic capture
+type CapturedPublication struct {
+	OriginalID         string                 `json:"original_id"`
+	PublisherAgentID   string                 `json:"publisher_agent_id"`
+	PublisherAgentType string                 `json:"publisher_agent_type"`
+	PublicationType    string                 `json:"publication_type"`
+	EventName          string                 `json:"event_name"`
+	Payload            map[string]interface{} `json:"payload"`
+	TTLSeconds         int                    `json:"ttl_seconds"`
+	Metadata           map[string]string      `json:"metadata,omitempty"`
+	PublishedAt        time.Time              `json:"published_at"`
+	OffsetMs           int64                  `json:"offset_ms"` // Time since the start of the window
+}
+
+// TrafficCapture is the publications on a set of topics within a time window
+type TrafficCapture struct {
+	Topics       []string              `json:"topics"`
+	Since        time.Time             `json:"since"`
+	Until        time.Time             `json:"until"`
+	Publications []CapturedPublication `json:"publications"`
+}
+
+// SubscriptionRequest creates or replaces a subscription
+type SubscriptionRequest struct {
+	SubscriberAgentID   string                 `json:"subscriber_agent_id"`
+	SubscriberAgentType string                 `json:"subscriber_agent_type,omitempty"`
+	EventPattern        string                 `json:"event_pattern"`               // Glob of event names, e.g. "zone.*.leak.*"
+	DeliveryMode        string                 `json:"delivery_mode,omitempty"`     // pull (default) or push
+	FilterExpression    string                 `json:"filter_expression,omitempty"` // e.g. "severity >= HIGH && zone == north"
+	FilterConditions    map[string]interface{} `json:"filter_conditions,omitempty"`
+	PublisherAgentID    *string                `json:"publisher_agent_id,omitempty"`
+	PublisherAgentType  *string                `json:"publisher_agent_type,omitempty"`
+	PublicationTypes    []string               `json:"publication_types,omitempty"`
+	Metadata            map[string]string      `json:"metadata,omitempty"`
+}
+
+// Subscription is an agent's subscription to publications
+type Subscription struct {
+	ID                  string                 `json:"_key"`
+	SubscriberAgentID   string                 `json:"subscriber_agent_id"`
+	SubscriberAgentType string                 `json:"subscriber_agent_type"`
+	PublisherAgentID    *string                `json:"publisher_agent_id,omitempty"`
+	PublisherAgentType  *string                `json:"publisher_agent_type,omitempty"`
+	EventPattern        string                 `json:"event_pattern"`
+	PublicationTypes    []string               `json:"publication_types,omitempty"`
+	FilterConditions    map[string]interface{} `json:"filter_conditions,omitempty"`
+	FilterExpression    string                 `json:"filter_expression,omitempty"`
+	DeliveryMode        string                 `json:"delivery_mode,omitempty"`
+	CreatedAt           time.Time              `json:"created_at"`
+	UpdatedAt           time.Time              `json:"updated_at"`
+	Active              bool                   `json:"active"`
+	LastMatchedAt       *time.Time             `json:"last_matched_at,omitempty"`
+	Metadata            map[string]string      `json:"metadata,omitempty"`
+}
+
+// SendMessage sends a direct message from one agent to another
+func (c *Client) SendMessage(ctx context.Context, req SendMessageRequest) (*SendMessageResponse, error) {
+	var resp SendMessageResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("communications", "messages"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RequestMessage sends a direct message and waits for its reply. The server
+// answers 504 Gateway Timeout when no reply arrives in time; the HTTP
+// client's timeout must outlast the request's.
+func (c *Client) RequestMessage(ctx context.Context, req RequestMessageRequest) (*RequestMessageResponse, error) {
+	var resp RequestMessageResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("communications", "messages", "request"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMessage returns a direct message
+func (c *Client) GetMessage(ctx context.Context, id string) (*Message, error) {
+	var msg Message
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "messages", id), nil, nil, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// ListMessages queries the direct message history, newest first
+func (c *Client) ListMessages(ctx context.Context, query MessageQuery) ([]Message, error) {
+	var resp struct {
+		Messages []Message `json:"messages"`
+	}
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "messages"), query.values(), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// Publish publishes an event or status update to a topic
+func (c *Client) Publish(ctx context.Context, req PublishRequest) (*PublishResponse, error) {
+	var resp PublishResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("communications", "publish"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PublishBatch publishes several publications atomically: either all of them
+// are published or none is. When the batch is refused the returned *APIError
+// body holds the status of each publication.
+func (c *Client) PublishBatch(ctx context.Context, reqs []PublishRequest) (*PublishBatchResponse, error) {
+	var resp PublishBatchResponse
+	if err := c.do(ctx, http.MethodPost, apiPath("communications", "publish", "batch"), nil, reqs, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PullPublications returns the publications matching an agent's pull
+// subscriptions published since the given time (the last hour when zero)
+func (c *Client) PullPublications(ctx context.Context, agentID string, since time.Time) ([]Publication, error) {
+	values := url.Values{}
+	setTime(values, "since", since)
+	var pubs []Publication
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "agents", agentID, "publications"), values, nil, &pubs); err != nil {
+		return nil, err
+	}
+	return pubs, nil
+}
+
+// GetPublication returns a publication
+func (c *Client) GetPublication(ctx context.Context, id string) (*Publication, error) {
+	var pub Publication
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "publications", id), nil, nil, &pub); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
+// CaptureTraffic returns the publications on topics matching the event
+// patterns within a time window; a zero until means now. The window is
+// sent with second precision.
+func (c *Client) CaptureTraffic(ctx context.Context, topics []string, since, until time.Time) (*TrafficCapture, error) {
+	values := url.Values{}
+	setString(values, "topics", strings.Join(topics, ","))
+	setTime(values, "since", since)
+	setTime(values, "until", until)
+
+	var capture TrafficCapture
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "capture"), values, nil, &capture); err != nil {
+		return nil, err
+	}
+	return &capture, nil
+}
+
+// CreateSubscription registers a subscription
+func (c *Client) CreateSubscription(ctx context.Context, req SubscriptionRequest) (*Subscription, error) {
+	var sub Subscription
+	if err := c.do(ctx, http.MethodPost, apiPath("communications", "subscriptions"), nil, req, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions lists an agent's active subscriptions
+func (c *Client) ListSubscriptions(ctx context.Context, agentID string) ([]Subscription, error) {
+	var subs []Subscription
+	values := url.Values{"agent_id": {agentID}}
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "subscriptions"), values, nil, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// GetSubscription returns a subscription
+func (c *Client) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var sub Subscription
+	if err := c.do(ctx, http.MethodGet, apiPath("communications", "subscriptions", id), nil, nil, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// UpdateSubscription replaces the pattern, filters and delivery mode of a
+// subscription
+func (c *Client) UpdateSubscription(ctx context.Context, id string, req SubscriptionRequest) (*Subscription, error) {
+	var sub Subscription
+	if err := c.do(ctx, http.MethodPut, apiPath("communications", "subscriptions", id), nil, req, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// DeleteSubscription deletes a subscription
+func (c *Client) DeleteSubscription(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, apiPath("communications", "subscriptions", id), nil, nil, nil)
+}
+
+func setString(values url.Values, name, value string) {
+	if value != "" {
+		values.Set(name, value)
+	}
+}
+
+func setInt(values url.Values, name string, value int) {
+	if value != 0 {
+		values.Set(name, strconv.Itoa(value))
+	}
+}
+
+func setTime(values url.Values, name string, value time.Time) {
+	if !value.IsZero() {
+		values.Set(name, value.UTC().Format(time.RFC3339))
+	}
+}
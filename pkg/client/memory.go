@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// MemoryArchive is an export of an agent's working memory, long-term memory
+// and snapshots. It is kept as the server sent it so that it can be imported
+// unchanged.
+type MemoryArchive = json.RawMessage
+
+// ImportMemoryRequest imports an archive into an agent's memory
+type ImportMemoryRequest struct {
+	Archive      MemoryArchive     `json:"archive"`
+	KeyMap       map[string]string `json:"key_map,omitempty"`        // Renames keys
+	KeyPrefixMap map[string]string `json:"key_prefix_map,omitempty"` // Renames key prefixes
+	TagMap       map[string]string `json:"tag_map,omitempty"`        // Renames tags
+	AddTags      []string          `json:"add_tags,omitempty"`
+	Overwrite    bool              `json:"overwrite,omitempty"` // Replaces existing keys instead of skipping them
+}
+
+// ImportMemoryResult counts the imported memories
+type ImportMemoryResult struct {
+	SourceAgentID string   `json:"source_agent_id"`
+	TargetAgentID string   `json:"target_agent_id"`
+	Working       int      `json:"working"`
+	Longterm      int      `json:"longterm"`
+	Snapshots     int      `json:"snapshots"`
+	Skipped       int      `json:"skipped"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// MemoryAuditQuery filters an agent's memory audit log. Zero fields do not
+// filter.
+type MemoryAuditQuery struct {
+	Key           string
+	MemoryType    string // working or longterm
+	Operation     string
+	AgentInstance string
+	Caller        string
+	Since         time.Time
+	Until         time.Time
+	Limit         int
+}
+
+// MemoryAuditRecord is an access to an agent's memory
+type MemoryAuditRecord struct {
+	ID            string    `json:"id"`
+	AgentID       string    `json:"agent_id"`
+	MemoryType    string    `json:"memory_type"`
+	Key           string    `json:"key,omitempty"`
+	Operation     string    `json:"operation"`
+	AgentInstance string    `json:"agent_instance,omitempty"`
+	Caller        string    `json:"caller,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Success       bool      `json:"success"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// envelope is the {"success": true, "data": ...} wrapper of the agent memory
+// endpoints
+type envelope[T any] struct {
+	Data T `json:"data"`
+}
+
+// ExportMemory exports an agent's memory
+func (c *Client) ExportMemory(ctx context.Context, agentID string) (MemoryArchive, error) {
+	var archive MemoryArchive
+	if err := c.do(ctx, http.MethodGet, apiPath("agents", agentID, "memory", "export"), nil, nil, &archive); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// ImportMemory imports an archive into an agent's memory. The agent may
+// differ from the one the archive was exported from.
+func (c *Client) ImportMemory(ctx context.Context, agentID string, req ImportMemoryRequest) (*ImportMemoryResult, error) {
+	var resp envelope[ImportMemoryResult]
+	if err := c.do(ctx, http.MethodPost, apiPath("agents", agentID, "memory", "import"), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// QueryMemoryAudit returns the recorded accesses to an agent's memory
+func (c *Client) QueryMemoryAudit(ctx context.Context, agentID string, query MemoryAuditQuery) ([]MemoryAuditRecord, error) {
+	values := url.Values{}
+	setString(values, "key", query.Key)
+	setString(values, "memory_type", query.MemoryType)
+	setString(values, "operation", query.Operation)
+	setString(values, "agent_instance", query.AgentInstance)
+	setString(values, "caller", query.Caller)
+	setTime(values, "since", query.Since)
+	setTime(values, "until", query.Until)
+	setInt(values, "limit", query.Limit)
+
+	var resp envelope[struct {
+		Records []MemoryAuditRecord `json:"records"`
+	}]
+	if err := c.do(ctx, http.MethodGet, apiPath("agents", agentID, "memory", "audit"), values, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data.Records, nil
+}
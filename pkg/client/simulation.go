@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ClockStatus is the state of the simulated clock of a framework running
+// with simulation time enabled
+type ClockStatus struct {
+	Now    time.Time `json:"now"`
+	Speed  float64   `json:"speed"` // Simulated seconds per real second while running
+	Paused bool      `json:"paused"`
+}
+
+// GetClock returns the simulated time. It fails with a 404 APIError when
+// simulation time is not enabled.
+func (c *Client) GetClock(ctx context.Context) (*ClockStatus, error) {
+	return c.clockRequest(ctx, http.MethodGet, nil)
+}
+
+// AdvanceClock steps the simulated time forward, firing the timers that fall
+// due, whether or not the clock is paused
+func (c *Client) AdvanceClock(ctx context.Context, d time.Duration) (*ClockStatus, error) {
+	req := struct {
+		Duration string `json:"duration"`
+	}{d.String()}
+	return c.clockRequest(ctx, http.MethodPost, req, "advance")
+}
+
+// PauseClock stops the simulated time until it is resumed
+func (c *Client) PauseClock(ctx context.Context) (*ClockStatus, error) {
+	return c.clockRequest(ctx, http.MethodPost, nil, "pause")
+}
+
+// ResumeClock runs a paused simulated clock again at its speed
+func (c *Client) ResumeClock(ctx context.Context) (*ClockStatus, error) {
+	return c.clockRequest(ctx, http.MethodPost, nil, "resume")
+}
+
+// SetClockSpeed sets how many simulated seconds pass per real second
+func (c *Client) SetClockSpeed(ctx context.Context, speed float64) (*ClockStatus, error) {
+	req := struct {
+		Speed float64 `json:"speed"`
+	}{speed}
+	return c.clockRequest(ctx, http.MethodPost, req, "speed")
+}
+
+// clockRequest calls a simulation clock endpoint, e.g. "pause"
+func (c *Client) clockRequest(ctx context.Context, method string, body interface{}, action ...string) (*ClockStatus, error) {
+	var status ClockStatus
+	path := apiPath(append([]string{"simulation", "clock"}, action...)...)
+	if err := c.do(ctx, method, path, nil, body, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TelemetryPoint is one reading of a metric reported by an agent
+type TelemetryPoint struct {
+	AgentID   string            `json:"agent_id"`
+	Metric    string            `json:"metric"`
+	Value     float64           `json:"value"`
+	Timestamp time.Time         `json:"timestamp"` // Zero stamps the point with the server time
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// TelemetryQuery selects a telemetry series. Zero fields use the server
+// defaults: every agent, the last 24 hours and the average over the range.
+type TelemetryQuery struct {
+	Metric    string
+	AgentID   string
+	From      time.Time
+	To        time.Time
+	Aggregate string        // avg, sum, min, max, count or last
+	Interval  time.Duration // Bucket width; zero aggregates the whole range
+	Limit     int           // Points listed by ListTelemetryPoints (default 1000)
+}
+
+// values encodes the query as URL parameters
+func (q TelemetryQuery) values() url.Values {
+	values := url.Values{}
+	setString(values, "metric", q.Metric)
+	setString(values, "agent_id", q.AgentID)
+	setTime(values, "from", q.From)
+	setTime(values, "to", q.To)
+	return values
+}
+
+// TelemetryBucket is the aggregate of a series over one interval
+type TelemetryBucket struct {
+	Start time.Time `json:"start"`
+	Value float64   `json:"value"`
+	Count int64     `json:"count"`
+}
+
+// TelemetryResult is the answer to a telemetry query
+type TelemetryResult struct {
+	AgentID         string            `json:"agent_id,omitempty"`
+	Metric          string            `json:"metric"`
+	Aggregate       string            `json:"aggregate"`
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"`
+	IntervalSeconds int64             `json:"interval_seconds,omitempty"`
+	Buckets         []TelemetryBucket `json:"buckets"`
+}
+
+// IngestTelemetry stores telemetry points and returns how many were accepted
+func (c *Client) IngestTelemetry(ctx context.Context, points []TelemetryPoint) (int, error) {
+	req := struct {
+		Points []TelemetryPoint `json:"points"`
+	}{points}
+	var resp struct {
+		Accepted int `json:"accepted"`
+	}
+	if err := c.do(ctx, http.MethodPost, apiPath("telemetry"), nil, req, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Accepted, nil
+}
+
+// ListTelemetryPoints returns the raw points of a series, oldest first.
+// Points past the raw retention are only available through QueryTelemetry.
+func (c *Client) ListTelemetryPoints(ctx context.Context, q TelemetryQuery) ([]TelemetryPoint, error) {
+	values := q.values()
+	setInt(values, "limit", q.Limit)
+
+	var resp struct {
+		Points []TelemetryPoint `json:"points"`
+	}
+	if err := c.do(ctx, http.MethodGet, apiPath("telemetry", "points"), values, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Points, nil
+}
+
+// QueryTelemetry aggregates a series over a range, including downsampled data
+func (c *Client) QueryTelemetry(ctx context.Context, q TelemetryQuery) (*TelemetryResult, error) {
+	values := q.values()
+	setString(values, "aggregate", q.Aggregate)
+	if q.Interval > 0 {
+		values.Set("interval", q.Interval.String())
+	}
+
+	var result TelemetryResult
+	if err := c.do(ctx, http.MethodGet, apiPath("telemetry", "query"), values, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
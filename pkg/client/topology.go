@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Asset is a pipe, valve, pump, sensor or other asset in the asset graph
+type Asset struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name,omitempty"`
+	Zone       string            `json:"zone,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// AssetConnection joins two assets. Flow connections run from the upstream
+// asset to the downstream one; monitors connections from a sensor to the
+// asset it measures.
+type AssetConnection struct {
+	ID         string            `json:"id"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Kind       string            `json:"kind,omitempty"` // flow (default) or monitors
+	Properties map[string]string `json:"properties,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ReachedAsset is an asset found upstream or downstream of another
+type ReachedAsset struct {
+	Asset Asset    `json:"asset"`
+	Depth int      `json:"depth"`
+	Path  []string `json:"path"` // Asset IDs from the queried asset to this one
+}
+
+// ValveBoundary is the nearest valves upstream and downstream of an asset
+type ValveBoundary struct {
+	AssetID    string         `json:"asset_id"`
+	Upstream   []ReachedAsset `json:"upstream"`
+	Downstream []ReachedAsset `json:"downstream"`
+}
+
+// RegisterAsset adds an asset to the asset graph
+func (c *Client) RegisterAsset(ctx context.Context, asset Asset) (*Asset, error) {
+	var registered Asset
+	if err := c.do(ctx, http.MethodPost, apiPath("topology", "assets"), nil, asset, &registered); err != nil {
+		return nil, err
+	}
+	return &registered, nil
+}
+
+// ConnectAssets connects two registered assets
+func (c *Client) ConnectAssets(ctx context.Context, connection AssetConnection) (*AssetConnection, error) {
+	var created AssetConnection
+	if err := c.do(ctx, http.MethodPost, apiPath("topology", "connections"), nil, connection, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpstreamAssets returns the assets feeding an asset, nearest first. A zero
+// depth uses the server limit, and types, when given, select the assets
+// returned.
+func (c *Client) UpstreamAssets(ctx context.Context, assetID string, depth int, types ...string) ([]ReachedAsset, error) {
+	return c.walkAssets(ctx, assetID, "upstream", depth, types)
+}
+
+// DownstreamAssets returns the assets fed by an asset, nearest first. A zero
+// depth uses the server limit, and types, when given, select the assets
+// returned.
+func (c *Client) DownstreamAssets(ctx context.Context, assetID string, depth int, types ...string) ([]ReachedAsset, error) {
+	return c.walkAssets(ctx, assetID, "downstream", depth, types)
+}
+
+// IsolationValves returns the valves to close to isolate an asset
+func (c *Client) IsolationValves(ctx context.Context, assetID string) (*ValveBoundary, error) {
+	var boundary ValveBoundary
+	if err := c.do(ctx, http.MethodGet, apiPath("topology", "assets", assetID, "isolation-valves"), nil, nil, &boundary); err != nil {
+		return nil, err
+	}
+	return &boundary, nil
+}
+
+func (c *Client) walkAssets(ctx context.Context, assetID, direction string, depth int, types []string) ([]ReachedAsset, error) {
+	values := url.Values{}
+	setInt(values, "depth", depth)
+	for _, assetType := range types {
+		values.Add("type", assetType)
+	}
+
+	var reached []ReachedAsset
+	if err := c.do(ctx, http.MethodGet, apiPath("topology", "assets", assetID, direction), values, nil, &reached); err != nil {
+		return nil, err
+	}
+	return reached, nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Workflow is a graph of work item tasks an agency carries out
+type Workflow struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Version     string                 `json:"version"`
+	Description string                 `json:"description"`
+	Status      string                 `json:"status"` // draft, active, paused, completed or failed
+	Nodes       []WorkflowNode         `json:"nodes"`
+	Edges       []WorkflowEdge         `json:"edges"`
+	Variables   map[string]interface{} `json:"variables"`
+	AgencyID    string                 `json:"agency_id"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	CreatedBy   string                 `json:"created_by"`
+}
+
+// WorkflowNode is a step of a workflow: start, work_item, decision,
+// parallel or end
+type WorkflowNode struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Position WorkflowPosition `json:"position"`
+	Data     WorkflowNodeData `json:"data"`
+}
+
+// WorkflowPosition places a node on the designer canvas
+type WorkflowPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// WorkflowNodeData configures a node. Which fields apply depends on the
+// node's type.
+type WorkflowNodeData struct {
+	Name                 string                 `json:"name,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Trigger              string                 `json:"trigger,omitempty"` // Start nodes: manual, scheduled, event or api
+	WorkItemID           string                 `json:"work_item_id,omitempty"`
+	WorkItemType         string                 `json:"work_item_type,omitempty"`
+	Role                 string                 `json:"role,omitempty"`
+	SLAHours             int                    `json:"sla_hours,omitempty"`
+	Parameters           map[string]interface{} `json:"parameters,omitempty"`
+	RequiredCapabilities []string               `json:"required_capabilities,omitempty"`
+	Condition            string                 `json:"condition,omitempty"`    // Decision nodes
+	GatewayType          string                 `json:"gateway_type,omitempty"` // Parallel nodes: fork or join
+	Status               string                 `json:"status,omitempty"`       // End nodes: success or failure
+}
+
+// WorkflowEdge connects two nodes: sequential, conditional or dataflow
+type WorkflowEdge struct {
+	ID     string           `json:"id"`
+	Source string           `json:"source"`
+	Target string           `json:"target"`
+	Type   string           `json:"type"`
+	Data   WorkflowEdgeData `json:"data"`
+}
+
+// WorkflowEdgeData configures an edge
+type WorkflowEdgeData struct {
+	Condition string `json:"condition,omitempty"`
+	Label     string `json:"label,omitempty"`
+}
+
+// WorkflowValidation is the outcome of validating a workflow
+type WorkflowValidation struct {
+	Valid  bool `json:"valid"`
+	Errors []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+		NodeID  string `json:"node_id,omitempty"`
+		EdgeID  string `json:"edge_id,omitempty"`
+	} `json:"errors,omitempty"`
+}
+
+// WorkflowExecution is a run of a workflow
+type WorkflowExecution struct {
+	ID              string                 `json:"id"`
+	WorkflowID      string                 `json:"workflow_id"`
+	WorkflowVersion string                 `json:"workflow_version"`
+	Status          string                 `json:"status"`
+	StartedAt       time.Time              `json:"started_at"`
+	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
+	StartedBy       string                 `json:"started_by"`
+	Context         map[string]interface{} `json:"context"`
+	NodeExecutions  []NodeExecution        `json:"node_executions"`
+	Errors          []string               `json:"errors"`
+}
+
+// NodeExecution is the state of a node in an execution
+type NodeExecution struct {
+	NodeID      string                 `json:"node_id"`
+	Status      string                 `json:"status"` // pending, running, waiting, completed, failed or skipped
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Output      map[string]interface{} `json:"output,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	AgentID     string                 `json:"assigned_agent,omitempty"`
+}
+
+// CreateWorkflow adds a workflow to an agency
+func (c *Client) CreateWorkflow(ctx context.Context, agencyID string, wf Workflow) (*Workflow, error) {
+	var created Workflow
+	if err := c.do(ctx, http.MethodPost, apiPath("agencies", agencyID, "workflows"), nil, wf, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListWorkflows lists an agency's workflows
+func (c *Client) ListWorkflows(ctx context.Context, agencyID string) ([]Workflow, error) {
+	var workflows []Workflow
+	if err := c.do(ctx, http.MethodGet, apiPath("agencies", agencyID, "workflows"), nil, nil, &workflows); err != nil {
+		return nil, err
+	}
+	return workflows, nil
+}
+
+// GetWorkflow returns a workflow
+func (c *Client) GetWorkflow(ctx context.Context, id string) (*Workflow, error) {
+	var wf Workflow
+	if err := c.do(ctx, http.MethodGet, apiPath("workflows", id), nil, nil, &wf); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// UpdateWorkflow replaces a workflow
+func (c *Client) UpdateWorkflow(ctx context.Context, id string, wf Workflow) (*Workflow, error) {
+	var updated Workflow
+	if err := c.do(ctx, http.MethodPut, apiPath("workflows", id), nil, wf, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteWorkflow deletes a workflow
+func (c *Client) DeleteWorkflow(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, apiPath("workflows", id), nil, nil, nil)
+}
+
+// ValidateWorkflow checks a workflow without storing it
+func (c *Client) ValidateWorkflow(ctx context.Context, wf Workflow) (*WorkflowValidation, error) {
+	var result WorkflowValidation
+	if err := c.do(ctx, http.MethodPost, apiPath("workflows", "validate"), nil, wf, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StartExecution starts a run of a workflow with the given context variables
+func (c *Client) StartExecution(ctx context.Context, workflowID string, variables map[string]interface{}) (*WorkflowExecution, error) {
+	req := struct {
+		Context map[string]interface{} `json:"context"`
+	}{variables}
+
+	var execution WorkflowExecution
+	if err := c.do(ctx, http.MethodPost, apiPath("workflows", workflowID, "execute"), nil, req, &execution); err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
@@ -225,3 +225,36 @@ export function clearForm(fieldIds, defaults = {}) {
         }
     });
 }
+
+/**
+ * Expand or collapse an entity's AI explanation history row
+ * @param {string} entityType - Type of entity (goals, work-items)
+ * @param {string} entityKey - Key of the entity
+ */
+export async function toggleEntityExplanations(entityType, entityKey) {
+    const agencyId = getCurrentAgencyId();
+    const row = document.getElementById(`${entityType}-explanations-${entityKey}`);
+    if (!agencyId || !row) {
+        return;
+    }
+
+    if (!row.classList.contains('is-hidden')) {
+        row.classList.add('is-hidden');
+        return;
+    }
+
+    const cell = row.querySelector('td');
+    cell.innerHTML = '<p class="has-text-grey is-size-7 py-2"><i class="fas fa-spinner fa-spin"></i> Loading explanations...</p>';
+    row.classList.remove('is-hidden');
+
+    try {
+        const response = await fetch(`/api/v1/agencies/${agencyId}/${entityType}/${entityKey}/explanations/html`);
+        if (!response.ok) {
+            throw new Error('Failed to load explanations');
+        }
+        cell.innerHTML = await response.text();
+    } catch (error) {
+        console.error(`Error loading explanations for ${entityType} ${entityKey}:`, error);
+        cell.innerHTML = '<p class="has-text-danger is-size-7 py-2">Error loading explanations</p>';
+    }
+}
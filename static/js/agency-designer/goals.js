@@ -234,6 +234,268 @@ export function processAIGoalOperation(operations, userRequest = '') {
 // Make functions globally available
 window.processAIGoalOperation = processAIGoalOperation;
 
+// Stream a designer chat goal request, showing the AI's output as it is generated
+export function streamGoalChat(message) {
+    const agencyId = getCurrentAgencyId();
+    const chatContainer = document.getElementById('chat-messages');
+    if (!agencyId || !chatContainer) {
+        return;
+    }
+
+    const bubble = document.createElement('div');
+    bubble.className = 'message ai-message';
+    bubble.innerHTML = `
+        <div class="message-avatar">
+            <span class="icon is-medium has-text-primary">
+                <i class="fas fa-robot"></i>
+            </span>
+        </div>
+        <div class="message-content">
+            <div class="message-bubble"><pre class="ai-stream-output"></pre></div>
+        </div>
+    `;
+    chatContainer.appendChild(bubble);
+    const output = bubble.querySelector('.ai-stream-output');
+
+    const source = new EventSource(`/api/v1/agencies/${agencyId}/goals/refine-stream?message=${encodeURIComponent(message)}`);
+
+    // Closing the source also stops the browser from reconnecting and
+    // running the request again
+    const finish = () => {
+        source.close();
+        const indicator = document.getElementById('typing-indicator');
+        if (indicator) {
+            indicator.style.display = 'none';
+        }
+        if (window.hideAIProcessStatus) {
+            window.hideAIProcessStatus();
+        }
+    };
+
+    source.addEventListener('chunk', evt => {
+        output.textContent += JSON.parse(evt.data).text;
+        scrollToBottom(chatContainer);
+    });
+
+    source.addEventListener('result', async evt => {
+        const data = JSON.parse(evt.data);
+        finish();
+        await loadGoals();
+        await reloadChatMessages();
+        showNotification(data.summary, 'success');
+    });
+
+    source.addEventListener('error', evt => {
+        // Errors sent by the server carry a message; dropped connections do not
+        const errorMessage = evt.data ? JSON.parse(evt.data).error : 'Lost connection while processing goals';
+        finish();
+        showNotification(errorMessage, 'danger');
+    });
+}
+
+// AI goal ranking - recommend an ordering, let the user adjust it, then persist it
+export function rankGoalsWithAI() {
+    const agencyId = getCurrentAgencyId();
+    if (!agencyId) {
+        showNotification('Error: No agency selected', 'error');
+        return;
+    }
+
+    if (window.showAIProcessStatus) {
+        window.showAIProcessStatus('AI is ranking goals by priority...');
+    }
+
+    fetch(`/api/v1/agencies/${agencyId}/goals/rank`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json'
+        }
+    })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to rank goals');
+            }
+            return response.json();
+        })
+        .then(data => {
+            if (window.hideAIProcessStatus) {
+                window.hideAIProcessStatus();
+            }
+
+            const rankings = data.rankings || [];
+            if (rankings.length === 0) {
+                showNotification('There are no goals to rank', 'warning');
+                return;
+            }
+
+            renderGoalRanking(rankings, data.explanation || '');
+        })
+        .catch(error => {
+            console.error('Error ranking goals:', error);
+
+            if (window.hideAIProcessStatus) {
+                window.hideAIProcessStatus();
+            }
+
+            showNotification('Failed to rank goals. Please try again.', 'error');
+        });
+}
+
+function renderGoalRanking(rankings, explanation) {
+    const card = document.getElementById('goal-ranking-card');
+    const list = document.getElementById('goal-ranking-list');
+    if (!card || !list) {
+        return;
+    }
+
+    document.getElementById('goal-ranking-explanation').textContent = explanation;
+    list.innerHTML = '';
+
+    rankings.forEach(ranking => {
+        const row = document.createElement('tr');
+        row.draggable = true;
+        row.dataset.goalKey = ranking.goal_key;
+
+        const handle = document.createElement('td');
+        handle.innerHTML = '<span class="icon has-text-grey-light" style="cursor: move;"><i class="fas fa-grip-vertical"></i></span>';
+
+        const rank = document.createElement('td');
+        rank.className = 'goal-ranking-rank';
+
+        const code = document.createElement('td');
+        code.innerHTML = '<strong></strong>';
+        code.firstChild.textContent = ranking.code;
+
+        const priority = document.createElement('td');
+        const select = document.createElement('select');
+        select.className = 'goal-ranking-priority';
+        ['High', 'Medium', 'Low'].forEach(value => {
+            select.add(new Option(value, value, false, value === ranking.priority));
+        });
+        priority.innerHTML = '<div class="select is-small"></div>';
+        priority.firstChild.appendChild(select);
+
+        const rationale = document.createElement('td');
+        rationale.className = 'goal-ranking-rationale is-size-7';
+        rationale.textContent = ranking.rationale || '';
+
+        row.append(handle, rank, code, priority, rationale);
+        addGoalRankingDragHandlers(row, list);
+        list.appendChild(row);
+    });
+
+    renumberGoalRanking();
+    card.style.display = 'block';
+    card.scrollIntoView({ behavior: 'smooth', block: 'nearest' });
+}
+
+function addGoalRankingDragHandlers(row, list) {
+    row.addEventListener('dragstart', e => {
+        row.classList.add('is-dragging');
+        e.dataTransfer.effectAllowed = 'move';
+    });
+
+    row.addEventListener('dragend', () => {
+        row.classList.remove('is-dragging');
+        renumberGoalRanking();
+    });
+
+    row.addEventListener('dragover', e => {
+        e.preventDefault();
+        const dragging = list.querySelector('.is-dragging');
+        if (!dragging || dragging === row) {
+            return;
+        }
+        const bounds = row.getBoundingClientRect();
+        const after = e.clientY > bounds.top + bounds.height / 2;
+        list.insertBefore(dragging, after ? row.nextSibling : row);
+    });
+}
+
+function renumberGoalRanking() {
+    document.querySelectorAll('#goal-ranking-list tr').forEach((row, index) => {
+        row.querySelector('.goal-ranking-rank').textContent = index + 1;
+    });
+}
+
+export function acceptGoalRanking() {
+    const agencyId = getCurrentAgencyId();
+    if (!agencyId) {
+        showNotification('Error: No agency selected', 'error');
+        return;
+    }
+
+    const priorities = Array.from(document.querySelectorAll('#goal-ranking-list tr')).map((row, index) => ({
+        goal_key: row.dataset.goalKey,
+        rank: index + 1,
+        priority: row.querySelector('.goal-ranking-priority').value,
+        rationale: row.querySelector('.goal-ranking-rationale').textContent
+    }));
+
+    fetch(`/api/v1/agencies/${agencyId}/goals/priorities`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ priorities: priorities })
+    })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to save goal priorities');
+            }
+            return response.json();
+        })
+        .then(() => {
+            cancelGoalRanking();
+            loadGoals();
+            showNotification('Goal priorities updated', 'success');
+        })
+        .catch(error => {
+            console.error('Error saving goal priorities:', error);
+            showNotification('Failed to save goal priorities. Please try again.', 'error');
+        });
+}
+
+export function cancelGoalRanking() {
+    const card = document.getElementById('goal-ranking-card');
+    if (card) {
+        card.style.display = 'none';
+    }
+    const list = document.getElementById('goal-ranking-list');
+    if (list) {
+        list.innerHTML = '';
+    }
+}
+
+// Goal traceability - show, per goal, the linked work items and how many are done
+export async function toggleGoalTraceability() {
+    const agencyId = getCurrentAgencyId();
+    const card = document.getElementById('goal-traceability-card');
+    const content = document.getElementById('goal-traceability-content');
+    if (!agencyId || !card || !content) {
+        return;
+    }
+
+    if (card.style.display !== 'none') {
+        card.style.display = 'none';
+        return;
+    }
+
+    content.innerHTML = '<p class="has-text-grey is-size-7 py-2"><i class="fas fa-spinner fa-spin"></i> Loading traceability...</p>';
+    card.style.display = 'block';
+
+    try {
+        const response = await fetch(`/api/v1/agencies/${agencyId}/traceability/html`);
+        if (!response.ok) {
+            throw new Error('Failed to load traceability');
+        }
+        content.innerHTML = await response.text();
+    } catch (error) {
+        console.error('Error loading goal traceability:', error);
+        content.innerHTML = '<p class="has-text-danger is-size-7 py-2">Error loading traceability</p>';
+    }
+}
+
 // Goal selection management
 function getSelectedGoalKeys() {
     const checkboxes = document.querySelectorAll('.goal-checkbox:checked');
@@ -372,8 +634,13 @@ window.cancelGoalEdit = cancelGoalEdit;
 window.deleteGoal = deleteGoal;
 window.filterGoals = filterGoals;
 window.processAIGoalOperation = processAIGoalOperation;
+window.streamGoalChat = streamGoalChat;
 window.refineGoalDescription = refineGoalDescription;
 window.validateGoalCode = validateGoalCode;
 window.getSelectedGoalKeys = getSelectedGoalKeys;
 window.updateGoalSelectionButtons = updateGoalSelectionButtons;
 window.toggleAllGoals = toggleAllGoals;
+window.rankGoalsWithAI = rankGoalsWithAI;
+window.acceptGoalRanking = acceptGoalRanking;
+window.cancelGoalRanking = cancelGoalRanking;
+window.toggleGoalTraceability = toggleGoalTraceability;
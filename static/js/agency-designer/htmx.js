@@ -133,6 +133,15 @@ export function initializeHTMXEvents() {
             if (chatContainer) {
                 setTimeout(() => scrollToBottom(chatContainer), 100);
             }
+
+            // Goal requests are streamed so the AI's answer shows up as it is generated
+            if (message && window.currentAgencyContext === 'goal-definition' && window.EventSource && window.streamGoalChat) {
+                evt.preventDefault();
+                if (window.ContextManager) {
+                    window.ContextManager.clearSelections();
+                }
+                window.streamGoalChat(message);
+            }
         } else {
             console.log('[HTMX] ❌ Not a chat form, skipping status display');
         }
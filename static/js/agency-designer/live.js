@@ -0,0 +1,88 @@
+// Live collaboration
+// Follows the agency's change stream so that changes made in other designer
+// sessions appear without reloading the page
+
+import { getCurrentAgencyId, showNotification } from './utils.js';
+import { scrollToBottom } from './chat.js';
+
+// Identifies this browser tab so that its own changes are not reloaded twice
+const sessionId = (window.crypto && crypto.randomUUID)
+    ? crypto.randomUUID()
+    : Math.random().toString(36).slice(2);
+
+// Events dispatched on document.body for each changed entity
+const entityEvents = {
+    goal: 'goalsUpdated',
+    work_item: 'workItemsUpdated',
+    overview: 'introductionUpdated',
+    conversation_message: 'conversationUpdated'
+};
+
+let source = null;
+
+// Initialize the live change stream
+export function initializeLiveUpdates() {
+    const agencyId = getCurrentAgencyId();
+    if (!agencyId || !window.EventSource || source) {
+        return;
+    }
+
+    // Tag this session's requests so its changes can be recognised
+    document.body.addEventListener('htmx:configRequest', function (event) {
+        event.detail.headers['X-Designer-Session'] = sessionId;
+    });
+
+    document.body.addEventListener('conversationUpdated', reloadConversation);
+
+    // EventSource reconnects by itself and resumes with Last-Event-ID
+    source = new EventSource(`/api/v1/agencies/${agencyId}/changes/stream`);
+
+    source.addEventListener('change', function (event) {
+        const change = JSON.parse(event.data);
+        if (change.actor && change.actor.session === sessionId) {
+            return;
+        }
+        const eventName = entityEvents[change.entity];
+        if (eventName) {
+            document.body.dispatchEvent(new CustomEvent(eventName, { detail: change }));
+        }
+    });
+
+    // Changes were missed; reload everything
+    source.addEventListener('reset', function () {
+        Object.values(entityEvents).forEach(function (eventName) {
+            document.body.dispatchEvent(new CustomEvent(eventName, { detail: { reset: true } }));
+        });
+    });
+
+    window.addEventListener('beforeunload', function () {
+        source.close();
+    });
+}
+
+// Replace the chat with the page's current messages
+async function reloadConversation() {
+    const chatContainer = document.getElementById('chat-messages');
+    if (!chatContainer) {
+        return;
+    }
+
+    try {
+        const response = await fetch(window.location.href, { headers: { 'X-Designer-Session': sessionId } });
+        if (!response.ok) {
+            throw new Error(`HTTP ${response.status}`);
+        }
+        const page = new DOMParser().parseFromString(await response.text(), 'text/html');
+        const latest = page.getElementById('chat-messages');
+        if (latest) {
+            chatContainer.innerHTML = latest.innerHTML;
+            if (window.htmx) {
+                htmx.process(chatContainer);
+            }
+            scrollToBottom(chatContainer);
+        }
+    } catch (error) {
+        console.error('[Live] Failed to reload conversation:', error);
+        showNotification('The conversation changed in another session. Reload the page to see it.', 'info');
+    }
+}
@@ -23,7 +23,8 @@ import {
     saveWorkItemFromEditor,
     cancelWorkItemEdit,
     deleteWorkItem,
-    filterWorkItems
+    filterWorkItems,
+    loadWorkItems
 } from './work-items.js';
 import {
     showRoleEditor,
@@ -32,8 +33,10 @@ import {
     deleteRole,
     filterRoles
 } from './roles.js';
+import { toggleEntityExplanations } from './crud-helpers.js';
 import { getCurrentAgencyId, showNotification } from './utils.js';
 import { initializeContextSelection } from './context.js';
+import { initializeLiveUpdates } from './live.js';
 
 // Check if DOM is already loaded
 if (document.readyState === 'loading') {
@@ -64,9 +67,16 @@ function initializeAgencyDesigner() {
             }
         });
 
+        // Listen for work item changes made in other designer sessions
+        document.body.addEventListener('workItemsUpdated', function () {
+            console.log('[Main] Work items updated event received - reloading work items list');
+            loadWorkItems();
+        });
+
         loadIntroductionEditor(); // Initialize introduction editor
         initializeAIProcessControls();
         initializeContextSelection(); // Initialize context selection system
+        initializeLiveUpdates(); // Follow changes made in other sessions
     } catch (error) {
         console.error('❌ Error during initialization:', error);
         console.error('Error stack:', error.stack);
@@ -170,6 +180,7 @@ window.saveRoleFromEditor = saveRoleFromEditor;
 window.cancelRoleEdit = cancelRoleEdit;
 window.deleteRole = deleteRole;
 window.filterRoles = filterRoles;
+window.toggleEntityExplanations = toggleEntityExplanations;
 
 // Export AI process control functions
 window.showAIProcessStatus = showAIProcessStatus;
@@ -78,7 +78,8 @@ function populateWorkItemForm(workItem) {
         'work-item-description-editor': workItem.description || '',
         'work-item-deliverables-editor': workItem.deliverables ? workItem.deliverables.join('\n') : '',
         'work-item-dependencies-editor': workItem.dependencies ? workItem.dependencies.join(', ') : '',
-        'work-item-tags-editor': workItem.tags ? workItem.tags.join(', ') : ''
+        'work-item-tags-editor': workItem.tags ? workItem.tags.join(', ') : '',
+        'work-item-capabilities-editor': workItem.required_capabilities ? workItem.required_capabilities.join(', ') : ''
     });
 }
 
@@ -89,7 +90,8 @@ function clearWorkItemForm() {
         'work-item-description-editor',
         'work-item-deliverables-editor',
         'work-item-dependencies-editor',
-        'work-item-tags-editor'
+        'work-item-tags-editor',
+        'work-item-capabilities-editor'
     ]);
 
     workItemEditorState.originalData = {};
@@ -112,6 +114,10 @@ export function saveWorkItemFromEditor() {
         .split(',')
         .map(t => t.trim())
         .filter(t => t.length > 0);
+    const requiredCapabilities = document.getElementById('work-item-capabilities-editor')?.value
+        .split(',')
+        .map(c => c.trim())
+        .filter(c => c.length > 0);
 
     // Validation
     if (!title) {
@@ -131,7 +137,8 @@ export function saveWorkItemFromEditor() {
         description,
         deliverables,
         dependencies,
-        tags
+        tags,
+        required_capabilities: requiredCapabilities
     };
 
     saveEntity('work-items', workItemEditorState.mode, workItemEditorState.workItemKey, data, 'save-work-item-btn', () => {
@@ -147,7 +154,8 @@ export function cancelWorkItemEdit() {
         'work-item-description-editor',
         'work-item-deliverables-editor',
         'work-item-dependencies-editor',
-        'work-item-tags-editor'
+        'work-item-tags-editor',
+        'work-item-capabilities-editor'
     ]);
 
     // Reset state
@@ -0,0 +1,107 @@
+/**
+ * Control room - live topics, alerts and agent status for use case demos.
+ * Everything is polled from the public APIs so the page works against any
+ * running scenario without extra server-side wiring.
+ */
+
+const CONTROL_ROOM_ALERT_WINDOW_MS = 60 * 60 * 1000;
+const CONTROL_ROOM_MAX_ALERTS = 50;
+const CONTROL_ROOM_RECENT_MS = 10 * 1000;
+
+function controlRoom() {
+    return {
+        alertPatterns: [],
+        intervalMs: 3000,
+        paused: false,
+        error: '',
+        updatedAt: '',
+
+        topics: [],
+        deprecations: [],
+        alerts: [],
+        agents: [],
+
+        init() {
+            this.alertPatterns = (this.$el.dataset.alerts || '#.*alert*.#').split(',');
+            this.intervalMs = (parseInt(this.$el.dataset.interval, 10) || 3) * 1000;
+
+            this.refresh();
+            setInterval(() => {
+                if (!this.paused) {
+                    this.refresh();
+                }
+            }, this.intervalMs);
+        },
+
+        async refresh() {
+            const results = await Promise.allSettled([
+                this.loadTopics(),
+                this.loadAlerts(),
+                this.loadAgents(),
+            ]);
+
+            const failed = results.filter(r => r.status === 'rejected');
+            this.error = failed.length > 0 ? failed[0].reason.message : '';
+            this.updatedAt = new Date().toLocaleTimeString();
+        },
+
+        async loadTopics() {
+            const report = await this.fetchJSON('/api/v1/communications/stats');
+            this.topics = (report.topics || [])
+                .slice()
+                .sort((a, b) => new Date(b.last_published_at) - new Date(a.last_published_at));
+            this.deprecations = report.deprecations || [];
+        },
+
+        async loadAlerts() {
+            const since = new Date(Date.now() - CONTROL_ROOM_ALERT_WINDOW_MS).toISOString().replace(/\.\d+Z$/, 'Z');
+            const params = new URLSearchParams({ topics: this.alertPatterns.join(','), since: since });
+            const capture = await this.fetchJSON('/api/v1/communications/capture?' + params.toString());
+            this.alerts = (capture.publications || [])
+                .slice(-CONTROL_ROOM_MAX_ALERTS)
+                .reverse();
+        },
+
+        async loadAgents() {
+            const page = await this.fetchJSON('/api/web/agents/json?size=100');
+            this.agents = page.agents || [];
+        },
+
+        async fetchJSON(url) {
+            const response = await fetch(url, { headers: { 'Accept': 'application/json' } });
+            if (!response.ok) {
+                throw new Error(`${url.split('?')[0]} returned ${response.status}`);
+            }
+            return response.json();
+        },
+
+        unhealthyCount() {
+            return this.agents.filter(a => !a.healthy).length;
+        },
+
+        agentClass(agent) {
+            if (!agent.healthy) {
+                return 'is-danger';
+            }
+            return (agent.health_flags || []).length > 0 ? 'is-warning' : 'is-success';
+        },
+
+        isRecent(timestamp) {
+            return timestamp && Date.now() - new Date(timestamp).getTime() < CONTROL_ROOM_RECENT_MS;
+        },
+
+        ago(timestamp) {
+            if (!timestamp) {
+                return '-';
+            }
+            const seconds = Math.max(0, Math.round((Date.now() - new Date(timestamp).getTime()) / 1000));
+            if (seconds < 60) {
+                return `${seconds}s ago`;
+            }
+            if (seconds < 3600) {
+                return `${Math.floor(seconds / 60)}m ago`;
+            }
+            return `${Math.floor(seconds / 3600)}h ago`;
+        },
+    };
+}
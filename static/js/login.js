@@ -0,0 +1,25 @@
+/**
+ * Sign-in form - exchanges the API key for a session cookie, then returns to
+ * the page that asked for it.
+ */
+document.getElementById('login-form').addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const form = event.target;
+    const error = document.getElementById('login-error');
+    error.textContent = '';
+
+    try {
+        const response = await fetch('/api/v1/auth/session', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ api_key: form.elements.api_key.value }),
+        });
+        if (!response.ok) {
+            error.textContent = response.status === 401 ? 'The API key is not valid.' : 'Sign in failed.';
+            return;
+        }
+        window.location.assign(form.dataset.next || '/');
+    } catch (err) {
+        error.textContent = 'Sign in failed.';
+    }
+});